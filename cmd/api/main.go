@@ -1,7 +1,17 @@
 // @title Fiber Golang Boilerplate API
 // @version 1.0
 // @description REST API boilerplate built with Go Fiber v3, PostgreSQL, and sqlc.
-// @basePath /api/v1
+// @description
+// @description Every rate-limited response carries `X-RateLimit-Limit` (requests allowed per window), `X-RateLimit-Remaining` (requests left) and `X-RateLimit-Reset` (seconds until the window resets). A `429 Too Many Requests` also carries `Retry-After`, the seconds to wait before retrying. Public and auth routes are limited per client IP, authenticated routes per user.
+// @description
+// @description Error messages, including the field messages of validation errors, are translated into the language of the `Accept-Language` header when it is supported (`en`, `vi`); error responses carry the language used in `Content-Language`. Error codes are never translated.
+// @description
+// @description Errors are documented in the default `{"success": false, "error": {...}}` envelope. Servers run with `APP_ERROR_FORMAT=problem` answer them as RFC 7807 `application/problem+json` instead, with the error code, details and request ID in the `code`, `details` and `request_id` members.
+// @description
+// @description Successful responses can be trimmed with the `fields` query param, a comma-separated list of top-level fields such as `?fields=id,email,name`; on list endpoints it applies to each item. Unknown names are ignored.
+// @description
+// @description The API is versioned in the path: `/api/v1` and `/api/v2`. v2 serves every v1 endpoint unchanged except those documented under `/v2`. Once v1 is deprecated, its responses carry the `Deprecation`, `Sunset` and `Link` (`rel="deprecation"`) headers.
+// @basePath /api
 // @securityDefinitions.apikey BearerAuth
 // @in header
 // @name Authorization
@@ -10,8 +20,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -19,22 +32,35 @@ import (
 
 	_ "github.com/joho/godotenv/autoload"
 
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/gofiber/fiber/v3"
 
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/handler"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/router"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/seed"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/service"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/async"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/buildinfo"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/database"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/email"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/errorreport"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/health"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/i18n"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/imaging"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/logger"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/oauth"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/realtime"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/saml"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/shutdown"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/storage"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/telemetry"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/token"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/validator"
 
 	_ "github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/metrics" // register Prometheus metrics
 )
@@ -50,6 +76,57 @@ func main() {
 	// Setup structured logging
 	logger.Setup(cfg.App.Env, cfg.App.LogLevel)
 
+	// Tracing
+	shutdownTracing, err := telemetry.Setup(context.Background(), cfg.Telemetry)
+	if err != nil {
+		slog.Error("failed to set up tracing", slog.Any("error", err))
+		os.Exit(1)
+	}
+	if cfg.Telemetry.Enabled() {
+		slog.Info("tracing enabled", slog.String("service", cfg.Telemetry.ServiceName))
+	}
+
+	// Error reporting
+	errorReporter, err := errorreport.NewReporter(cfg.Reporter, cfg.App.Env)
+	if err != nil {
+		slog.Error("failed to set up error reporting", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	// Password policy
+	passwordPolicy := validator.PasswordPolicy{
+		MinLength:      cfg.Password.MinLength,
+		MaxLength:      cfg.Password.MaxLength,
+		RequireUpper:   cfg.Password.RequireUpper,
+		RequireLower:   cfg.Password.RequireLower,
+		RequireDigit:   cfg.Password.RequireDigit,
+		RequireSpecial: cfg.Password.RequireSpecial,
+	}
+	if cfg.Password.BannedFile != "" {
+		passwordPolicy.Banned, err = validator.LoadBannedPasswords(cfg.Password.BannedFile)
+		if err != nil {
+			slog.Error("failed to load banned passwords", slog.Any("error", err))
+			os.Exit(1)
+		}
+	}
+	if err := validator.SetPasswordPolicy(passwordPolicy); err != nil {
+		slog.Error("invalid password policy", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	// Language of error messages for clients that accept none of the supported ones
+	if err := i18n.SetDefault(cfg.App.DefaultLocale); err != nil {
+		slog.Error("invalid APP_DEFAULT_LOCALE", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	// Error response format, for gateways expecting problem details
+	apperror.SetProblemDetails(cfg.App.ProblemDetails(), cfg.App.ErrorTypeBaseURL)
+
+	// User metadata schema and reserved usernames
+	validator.SetMetadataKeys(cfg.App.AllowedMetadataKeys())
+	validator.SetReservedUsernames(cfg.App.ReservedUsernames())
+
 	// Create database pool
 	ctx := context.Background()
 	pool, err := database.NewPool(ctx, cfg.DB)
@@ -77,13 +154,23 @@ func main() {
 	}
 	slog.Info("storage initialized", slog.String("driver", cfg.Storage.Driver))
 
+	imageVariants, err := imaging.ParseVariants(cfg.Storage.ImageVariants)
+	if err != nil {
+		pool.Close()
+		slog.Error("invalid STORAGE_IMAGE_VARIANTS", slog.Any("error", err))
+		os.Exit(1)
+	}
+
 	// Cache
-	appCache, err := cache.NewCache(cfg.Cache)
+	appCache, err := cache.NewCache(cfg.Cache, cfg.CacheNamespace())
 	if err != nil {
 		pool.Close()
 		slog.Error("failed to initialize cache", slog.Any("error", err))
 		os.Exit(1)
 	}
+	// Record Prometheus metrics of every operation, and count hits and misses for the
+	// admin system info.
+	appCache = cache.NewStatsCache(cache.NewInstrumentedCache(appCache, cfg.Cache.Driver))
 	slog.Info("cache initialized", slog.String("driver", cfg.Cache.Driver))
 
 	// Email
@@ -96,7 +183,7 @@ func main() {
 	slog.Info("email sender initialized", slog.String("driver", cfg.Email.Driver))
 
 	// Google OAuth (optional)
-	var googleOAuth *oauth.GoogleOAuth
+	var googleOAuth oauth.Provider
 	if cfg.OAuth.GoogleClientID != "" {
 		googleOAuth = oauth.NewGoogleOAuth(cfg.OAuth)
 		if err := googleOAuth.ValidateFrontendURL(); err != nil {
@@ -107,6 +194,49 @@ func main() {
 		slog.Info("Google OAuth enabled")
 	}
 
+	// GitHub OAuth (optional)
+	var githubOAuth oauth.Provider
+	if cfg.OAuth.GitHubClientID != "" {
+		githubOAuth = oauth.NewGitHubOAuth(cfg.OAuth)
+		if err := githubOAuth.ValidateFrontendURL(); err != nil {
+			slog.Error("invalid OAuth frontend URL", slog.Any("error", err))
+			pool.Close()
+			os.Exit(1)
+		}
+		slog.Info("GitHub OAuth enabled")
+	}
+
+	// SAML single sign-on (optional)
+	var samlSP *saml.ServiceProvider
+	if cfg.SAML.IDPMetadataURL != "" {
+		samlSP, err = saml.NewServiceProvider(ctx, cfg.SAML, cfg.OAuth.FrontendURL)
+		if err == nil {
+			err = samlSP.ValidateFrontendURL()
+		}
+		if err != nil {
+			slog.Error("invalid SAML config", slog.Any("error", err))
+			pool.Close()
+			os.Exit(1)
+		}
+		slog.Info("SAML enabled", slog.String("idp_metadata_url", cfg.SAML.IDPMetadataURL))
+	}
+
+	// WebAuthn / passkeys (optional)
+	var webAuthn *webauthn.WebAuthn
+	if cfg.WebAuthn.RPID != "" {
+		webAuthn, err = webauthn.New(&webauthn.Config{
+			RPID:          cfg.WebAuthn.RPID,
+			RPDisplayName: cfg.WebAuthn.RPDisplayName,
+			RPOrigins:     cfg.WebAuthn.Origins(),
+		})
+		if err != nil {
+			slog.Error("invalid WebAuthn config", slog.Any("error", err))
+			pool.Close()
+			os.Exit(1)
+		}
+		slog.Info("WebAuthn enabled", slog.String("rp_id", cfg.WebAuthn.RPID))
+	}
+
 	defer pool.Close()
 
 	// Transaction manager
@@ -121,88 +251,390 @@ func main() {
 		return
 	}
 
+	// Access token revocation (bans, password resets, role changes). Cutoffs must outlive
+	// the longest-lived token: user, guest or impersonation.
+	tokenTTL := max(
+		time.Duration(cfg.JWT.ExpireHour)*time.Hour,
+		time.Duration(cfg.JWT.GuestExpireHour)*time.Hour,
+		time.Duration(cfg.JWT.ImpersonateMins)*time.Minute,
+	)
+	revocations := token.NewRevocationStore(appCache, tokenTTL)
+
+	// Audit log of admin changes
+	auditLogSvc := service.NewAuditLogService(repository.NewAuditLogRepository(pool))
+
+	// Drop addresses the provider reported as hard-bounced or complaining before anything,
+	// broadcasts included, is sent to them
+	emailSuppressionSvc := service.NewEmailSuppressionService(
+		repository.NewEmailSuppressionRepository(pool), auditLogSvc, cfg.Email.WebhookSecret,
+	)
+	emailSender = emailSuppressionSvc.Wrap(emailSender)
+
+	// Queue emails in the outbox so a mail server outage delays them instead of losing them.
+	// Broadcasts pace and count their own deliveries, so they keep sending directly.
+	emailOutboxSvc := service.NewEmailOutboxService(
+		repository.NewEmailOutboxRepository(pool), emailSender, auditLogSvc,
+		cfg.Email.OutboxBatchSize, cfg.Email.OutboxMaxAttempts, cfg.Email.OutboxRetentionDays,
+	)
+	var mailer email.Sender = emailSender
+	if cfg.Email.OutboxEnabled {
+		mailer = emailOutboxSvc
+	}
+
+	// Runtime settings admins change without a restart; the environment provides the defaults
+	settingsSvc := service.NewSettingsService(repository.NewSettingRepository(pool), appCache, auditLogSvc, dto.Settings{
+		RegistrationEnabled:      cfg.App.RegistrationEnabled,
+		RequireEmailVerification: cfg.App.RequireEmailVerification,
+		MaxUploadSize:            cfg.Storage.MaxFileSize,
+	})
+
+	// Email domains allowed to sign up via registration, OAuth/SAML and guest upgrade
+	signupDomains := service.EmailDomainPolicy{
+		Allowed: cfg.App.AllowedSignupDomains(),
+		Blocked: cfg.App.BlockedSignupDomains(),
+	}
+
+	// Registration invitations, required to sign up when INVITE_ONLY is set
+	invitationRepo := repository.NewRegistrationInvitationRepository(pool)
+	invitationSvc := service.NewInvitationService(invitationRepo, userRepo, mailer, cfg.App.InviteTTLHours, cfg.App.FrontendURL, auditLogSvc)
+
+	// User settings (locale, timezone, notification preferences)
+	userSettingsRepo := repository.NewUserSettingsRepository(pool)
+	userSettingsSvc := service.NewUserSettingsService(userSettingsRepo)
+
 	refreshTokenRepo := repository.NewRefreshTokenRepository(pool)
-	userSvc := service.NewUserService(userRepo, refreshTokenRepo, cfg.App.RequireEmailVerification, appCache, txManager)
+	userSvc := service.NewUserService(
+		userRepo, refreshTokenRepo, invitationRepo, userSettingsRepo,
+		settingsSvc, cfg.App.InviteOnly, signupDomains, revocations, appCache, txManager,
+	)
 
 	refreshSvc := service.NewRefreshTokenService(refreshTokenRepo, cfg.JWT.RefreshExpireDays)
 
 	// Password reset
 	passwordResetRepo := repository.NewPasswordResetRepository(pool)
 	passwordResetSvc := service.NewPasswordResetService(
-		userRepo, passwordResetRepo, refreshTokenRepo,
-		emailSender, appCache, cfg.App.FrontendURL, txManager,
+		userRepo, passwordResetRepo, refreshTokenRepo, userSettingsRepo,
+		mailer, appCache, cfg.App.FrontendURL, txManager, revocations,
 	)
 
 	// Email verification
 	emailVerifRepo := repository.NewEmailVerificationRepository(pool)
 	emailVerifSvc := service.NewEmailVerificationService(
-		userRepo, emailVerifRepo, emailSender, appCache, cfg.App.FrontendURL,
+		userRepo, emailVerifRepo, userSettingsRepo, mailer, appCache, cfg.App.FrontendURL, auditLogSvc,
 	)
 
+	// Email change confirmation
+	emailChangeRepo := repository.NewEmailChangeRepository(pool)
+	emailChangeSvc := service.NewEmailChangeService(
+		userRepo, emailChangeRepo, mailer, appCache, cfg.App.FrontendURL, txManager,
+	)
+
+	// Passkeys
+	var webauthnSvc service.WebAuthnService
+	if webAuthn != nil {
+		webauthnCredRepo := repository.NewWebAuthnCredentialRepository(pool)
+		webauthnSvc = service.NewWebAuthnService(
+			webAuthn, userRepo, webauthnCredRepo, appCache, settingsSvc,
+		)
+	}
+
+	// Login history
+	loginEventRepo := repository.NewLoginEventRepository(pool)
+	loginEventSvc := service.NewLoginEventService(loginEventRepo, userRepo, userSettingsRepo, mailer, cfg.Auth.NewDeviceEmail)
+
+	// Account activity trail
+	userActivityRepo := repository.NewUserActivityRepository(pool)
+	userActivitySvc := service.NewUserActivityService(userActivityRepo)
+
+	// Per-IP brute-force protection for password logins
+	loginThrottle := service.NewLoginThrottle(
+		appCache,
+		cfg.Auth.IPMaxFailedLogins,
+		time.Duration(cfg.Auth.IPFailureWindow)*time.Second,
+		time.Duration(cfg.Auth.IPMaxBackoff)*time.Second,
+	)
+
+	// TOTP two-factor authentication and recovery codes
+	twoFactorRepo := repository.NewTwoFactorRepository(pool)
+	twoFactorSvc := service.NewTwoFactorService(twoFactorRepo, userRepo, appCache, txManager, cfg.Auth.TOTPIssuer)
+	twoFactorHandler := handler.NewTwoFactorHandler(twoFactorSvc, userActivitySvc)
+
+	// Guest sessions
+	guestSvc := service.NewGuestService(userRepo, invitationRepo, userSettingsRepo, cfg.App.InviteOnly, signupDomains, settingsSvc, appCache)
+
 	authHandler := handler.NewAuthHandler(
 		userSvc, refreshSvc, passwordResetSvc, emailVerifSvc,
-		cfg.JWT.Secret, cfg.JWT.ExpireHour, googleOAuth,
+		cfg.JWT, cfg.JWT.ExpireHour, cfg.Auth.CookieMode, cfg.JWT.RefreshExpireDays,
+		googleOAuth, githubOAuth, samlSP, webauthnSvc, loginEventSvc, emailChangeSvc,
+		guestSvc, cfg.JWT.GuestExpireHour, loginThrottle, revocations, twoFactorSvc,
 	)
-	userHandler := handler.NewUserHandler(userSvc)
 
+	// Self-service account deletion and retention of soft-deleted users
 	fileRepo := repository.NewFileRepository(pool)
-	uploadSvc := service.NewUploadService(fileRepo, store)
-	uploadHandler := handler.NewUploadHandler(uploadSvc, cfg.Storage.MaxFileSize, cfg.Storage.AllowedTypes())
+	accountDeletionRepo := repository.NewAccountDeletionRepository(pool)
+	accountDeletionSvc := service.NewAccountDeletionService(
+		userRepo, fileRepo, accountDeletionRepo, store, mailer, revocations, appCache,
+		cfg.App.DeletionGraceDays, cfg.App.UserRetentionDays, cfg.App.FrontendURL,
+	)
+
+	// Personal data export
+	dataExportRepo := repository.NewDataExportRepository(pool)
+	dataExportSvc := service.NewDataExportService(
+		dataExportRepo, userRepo, fileRepo, loginEventRepo, store, mailer,
+		cfg.App.DataExportTTLHours, cfg.App.FrontendURL,
+	)
+
+	// Right-to-erasure anonymization
+	folderRepo := repository.NewFolderRepository(pool)
+	erasureSvc := service.NewErasureService(
+		userRepo, fileRepo, folderRepo, refreshTokenRepo, emailChangeRepo,
+		repository.NewWebAuthnCredentialRepository(pool), twoFactorRepo, loginEventRepo, userActivityRepo, dataExportRepo,
+		accountDeletionRepo, repository.NewErasureAuditRepository(pool),
+		store, revocations, appCache, txManager,
+	)
+
+	userHandler := handler.NewUserHandler(
+		userSvc, loginEventSvc, emailChangeSvc, accountDeletionSvc, dataExportSvc, erasureSvc, userSettingsSvc, userActivitySvc,
+	)
+
+	// Real-time events, pushed to users over the WebSocket gateway
+	hub := realtime.NewHub(cfg.App.WSMaxConnectionsPerUser)
+	revocations.OnRevokeUser(hub.DisconnectUser)
+
+	imageVariantSvc := service.NewImageVariantService(fileRepo, store, imageVariants)
+	uploadSvc := service.NewUploadService(
+		fileRepo, store, imageVariantSvc, cfg.Storage.ContentAddressed(), cfg.Storage.StripImageMetadata,
+	)
+	resumableUploadSvc := service.NewResumableUploadService(
+		repository.NewUploadSessionRepository(pool), fileRepo, store, imageVariantSvc, appCache,
+		cfg.Storage.UploadSessionTTLHours, cfg.JWT.Secret,
+		cfg.Storage.ContentAddressed(), cfg.Storage.StripImageMetadata, txManager, hub,
+	)
+	fileShareSvc := service.NewFileShareService(
+		repository.NewFileShareRepository(pool), fileRepo, store,
+		cfg.Storage.ShareDefaultTTLHours, cfg.Storage.ShareMaxTTLHours,
+	)
+	uploadHandler := handler.NewUploadHandler(
+		uploadSvc, resumableUploadSvc, fileShareSvc, userActivitySvc,
+		settingsSvc, cfg.Storage.MaxResumableFileSize, cfg.Storage.AllowedTypes(),
+	)
+	folderHandler := handler.NewFolderHandler(service.NewFolderService(folderRepo))
 
 	// Admin
-	adminSvc := service.NewAdminService(userRepo, fileRepo, refreshTokenRepo, store)
-	adminHandler := handler.NewAdminHandler(adminSvc)
+	flagSvc := service.NewFeatureFlagService(repository.NewFeatureFlagRepository(pool), appCache, auditLogSvc)
+	flagHandler := handler.NewFeatureFlagHandler(flagSvc)
+	settingsHandler := handler.NewSettingsHandler(settingsSvc)
+	broadcastHandler := handler.NewBroadcastHandler(service.NewBroadcastService(
+		repository.NewBroadcastRepository(pool), emailSender, auditLogSvc,
+		cfg.Email.BroadcastBatchSize, cfg.Email.BroadcastRate, hub,
+	))
+	roleRepo := repository.NewRoleRepository(pool)
+	adminSvc := service.NewAdminService(userRepo, fileRepo, refreshTokenRepo, roleRepo, store, revocations, appCache, txManager, auditLogSvc, cfg.Storage.FileRetentionDays)
+	reconcileSvc := service.NewStorageReconcileService(fileRepo, store, auditLogSvc)
+	orgRepo := repository.NewOrganizationRepository(pool)
+	orgSvc := service.NewOrganizationService(orgRepo, userRepo, mailer, cfg.App.FrontendURL, txManager)
+	orgHandler := handler.NewOrganizationHandler(orgSvc)
+
+	userImportSvc := service.NewUserImportService(userRepo, passwordResetRepo, mailer, appCache, cfg.App.FrontendURL, txManager, auditLogSvc)
+
+	permissionSvc := service.NewPermissionService(roleRepo, userRepo, appCache, txManager, auditLogSvc)
+	adminHandler := handler.NewAdminHandler(adminSvc, permissionSvc, erasureSvc, emailVerifSvc, userActivitySvc, invitationSvc, userImportSvc, reconcileSvc, auditLogSvc, cfg.JWT, cfg.JWT.ImpersonateMins)
+
+	// Background jobs
+	jobsCtx, stopJobs := context.WithCancel(ctx)
+	async.Every(jobsCtx, time.Duration(cfg.App.PurgeInterval)*time.Second, func(ctx context.Context) {
+		if _, err := accountDeletionSvc.PurgeDue(ctx); err != nil {
+			slog.Error("account purge failed", slog.Any("error", err))
+		}
+		if _, err := accountDeletionSvc.PurgeSoftDeleted(ctx); err != nil {
+			slog.Error("soft-deleted user purge failed", slog.Any("error", err))
+		}
+		if _, err := dataExportSvc.PurgeExpired(ctx); err != nil {
+			slog.Error("data export purge failed", slog.Any("error", err))
+		}
+		if _, err := invitationSvc.PurgeExpired(ctx); err != nil {
+			slog.Error("invitation purge failed", slog.Any("error", err))
+		}
+		if _, err := resumableUploadSvc.PurgeExpired(ctx); err != nil {
+			slog.Error("upload session purge failed", slog.Any("error", err))
+		}
+		if _, err := fileShareSvc.PurgeExpired(ctx); err != nil {
+			slog.Error("file share purge failed", slog.Any("error", err))
+		}
+		if _, err := adminSvc.PurgeDeletedFiles(ctx); err != nil {
+			slog.Error("soft-deleted file purge failed", slog.Any("error", err))
+		}
+		if _, err := emailOutboxSvc.PurgeFinished(ctx); err != nil {
+			slog.Error("email outbox purge failed", slog.Any("error", err))
+		}
+	})
+	// Runs even with the outbox disabled, so emails queued before it was turned off are sent
+	async.Every(jobsCtx, time.Duration(cfg.Email.OutboxPollInterval)*time.Second, func(ctx context.Context) {
+		if _, err := emailOutboxSvc.DeliverDue(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			slog.Error("email delivery failed", slog.Any("error", err))
+		}
+	})
 
 	// Health checker
-	healthChecker := health.NewChecker(pool, appCache)
+	healthChecker := health.NewChecker(pool, appCache, cfg.Cache.Driver, cfg.Storage.Driver)
+	systemHandler := handler.NewSystemHandler(healthChecker)
+	batchHandler := handler.NewBatchHandler(cfg.App.BatchMaxRequests)
+	// Tickets only need to live until the client opens its connection
+	wsTickets := realtime.NewTicketStore(appCache, 30*time.Second)
+	realtimeHandler := handler.NewRealtimeHandler(hub, wsTickets)
 
 	// Create Fiber app
+	// The HTTP/2 front end passes requests on from loopback
+	if cfg.TLS.HTTP2 {
+		trustFrontend(&cfg.App)
+	}
+
 	app := fiber.New(fiber.Config{
 		ServerHeader: "fiber-golang-boilerplate",
 		AppName:      "fiber-golang-boilerplate",
-		ErrorHandler: apperror.FiberErrorHandler,
-		BodyLimit:    cfg.App.BodyLimit,
+		ErrorHandler: apperror.NewErrorHandler(errorReporter),
+		// The largest route limit; middleware.BodyLimit applies each route's own
+		BodyLimit: cfg.MaxBodyLimit(),
+		// Trusted proxies give the scheme and host. The client IP is resolved by
+		// middleware.ClientIP, as Fiber takes the leftmost address of the proxy header,
+		// which the client controls.
+		TrustProxy:       len(cfg.App.TrustedProxyList()) > 0,
+		TrustProxyConfig: fiber.TrustProxyConfig{Proxies: cfg.App.TrustedProxyList()},
 	})
 
 	// Setup routes
 	router.SetupRoutes(app, router.Deps{
-		AuthHandler:   authHandler,
-		UserHandler:   userHandler,
-		UploadHandler: uploadHandler,
-		AdminHandler:  adminHandler,
-		Config:        cfg,
-		Pool:          pool,
-		Health:        healthChecker,
+		AuthHandler:             authHandler,
+		TwoFactorHandler:        twoFactorHandler,
+		UserHandler:             userHandler,
+		UploadHandler:           uploadHandler,
+		AdminHandler:            adminHandler,
+		OrgHandler:              orgHandler,
+		FolderHandler:           folderHandler,
+		FlagHandler:             flagHandler,
+		BroadcastHandler:        broadcastHandler,
+		EmailHandler:            handler.NewEmailOutboxHandler(emailOutboxSvc),
+		EmailSuppressionHandler: handler.NewEmailSuppressionHandler(emailSuppressionSvc),
+		SystemHandler:           systemHandler,
+		SettingsHandler:         settingsHandler,
+		BatchHandler:            batchHandler,
+		RealtimeHandler:         realtimeHandler,
+		Config:                  cfg,
+		Pool:                    pool,
+		Health:                  healthChecker,
+		Revocations:             revocations,
+		WSTickets:               wsTickets,
+		Cache:                   appCache,
+		ErrorReporter:           errorReporter,
+		Permissions:             permissionSvc,
+		Features:                flagSvc,
 	})
 
 	// Graceful shutdown
 	done := make(chan bool, 1)
 
+	// TLS termination, and a plain HTTP listener redirecting to it
+	listenConfig, certManager := tlsListenConfig(cfg.TLS)
+	var redirectServer *http.Server
+	if cfg.TLS.RedirectPort != 0 {
+		redirectServer = newRedirectServer(cfg.TLS.RedirectPort, cfg.App.Port, certManager)
+		go func() {
+			if err := redirectServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("redirect server error", slog.Any("error", err))
+				os.Exit(1)
+			}
+		}()
+	}
+
+	// With TLS_HTTP2 a net/http front end terminates TLS and the app listens on loopback
+	var frontend *http.Server
+	if cfg.TLS.HTTP2 {
+		tlsConfig, err := frontendTLSConfig(cfg.TLS, certManager)
+		if err != nil {
+			slog.Error("failed to load TLS certificate", slog.Any("error", err))
+			os.Exit(1)
+		}
+		ln, err := net.Listen("tcp", frontendUpstream)
+		if err != nil {
+			slog.Error("failed to listen for the HTTP/2 front end", slog.Any("error", err))
+			os.Exit(1)
+		}
+		frontend = newFrontendServer(cfg.App.Port, ln.Addr().String(), tlsConfig)
+		go func() {
+			if err := app.Listener(ln, fiber.ListenConfig{DisableStartupMessage: true}); err != nil {
+				slog.Error("server error", slog.Any("error", err))
+				os.Exit(1)
+			}
+		}()
+	}
+
 	go func() {
 		addr := fmt.Sprintf(":%d", cfg.App.Port)
-		slog.Info("server starting", slog.String("addr", addr), slog.String("env", cfg.App.Env))
-		if err := app.Listen(addr); err != nil {
+		slog.Info("server starting",
+			slog.String("addr", addr),
+			slog.Bool("tls", cfg.TLS.Enabled()),
+			slog.Bool("http2", cfg.TLS.HTTP2),
+			slog.String("env", cfg.App.Env),
+			slog.String("version", buildinfo.Version),
+		)
+		var err error
+		if frontend != nil {
+			err = frontend.ListenAndServeTLS("", "")
+		} else {
+			err = app.Listen(addr, listenConfig)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			slog.Error("server error", slog.Any("error", err))
 			os.Exit(1)
 		}
 	}()
 
+	// Coordinated shutdown: fail readiness, optionally wait for load balancers to notice,
+	// then stop in order within APP_SHUTDOWN_TIMEOUT
+	shutdowner := shutdown.New(
+		time.Duration(cfg.App.ShutdownDelay)*time.Second,
+		time.Duration(cfg.App.ShutdownTimeout)*time.Second,
+	)
+	shutdowner.OnDrain(healthChecker.MarkDraining)
+	shutdowner.Add("jobs", func(context.Context) error {
+		stopJobs()
+		return nil
+	})
+	shutdowner.Add("websocket", func(context.Context) error {
+		hub.Close()
+		return nil
+	})
+	if frontend != nil {
+		shutdowner.Add("https front end", frontend.Shutdown)
+	}
+	shutdowner.Add("http server", app.ShutdownWithContext)
+	if redirectServer != nil {
+		shutdowner.Add("redirect server", redirectServer.Shutdown)
+	}
+	// Fire-and-forget work started by requests, such as emails, finishes before dependencies close
+	shutdowner.Add("background tasks", async.Wait)
+	shutdowner.Add("cache", func(context.Context) error {
+		return appCache.Close()
+	})
+	shutdowner.Add("error reporter", func(context.Context) error {
+		errorReporter.Flush(2 * time.Second)
+		return nil
+	})
+	shutdowner.Add("tracing", shutdownTracing)
+
 	go func() {
-		sigChan := make(chan os.Signal, 1)
+		sigChan := make(chan os.Signal, 2)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 		<-sigChan
 
 		slog.Info("shutting down gracefully, press Ctrl+C again to force")
+		go func() {
+			<-sigChan
+			slog.Warn("forced shutdown")
+			os.Exit(1)
+		}()
 
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
-		if err := app.ShutdownWithContext(ctx); err != nil {
-			slog.Error("server forced to shutdown", slog.Any("error", err))
-		}
-
-		_ = appCache.Close()
-
+		shutdowner.Shutdown()
 		done <- true
 	}()
 
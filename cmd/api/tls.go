@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
+)
+
+// tlsListenConfig returns the listen config serving HTTPS with the configured certificate,
+// or with certificates Let's Encrypt issues for the autocert domains, along with the
+// manager obtaining them. Without TLS settings it serves plain HTTP. Either way the server
+// speaks HTTP/1.1, as fasthttp has no HTTP/2 support; TLS_HTTP2 puts newFrontendServer in
+// front instead.
+func tlsListenConfig(cfg config.TLSConfig) (fiber.ListenConfig, *autocert.Manager) {
+	var listen fiber.ListenConfig
+	if cfg.CertFile != "" {
+		listen.CertFile = cfg.CertFile
+		listen.CertKeyFile = cfg.KeyFile
+		return listen, nil
+	}
+	domains := cfg.AutocertDomainList()
+	if len(domains) == 0 {
+		return listen, nil
+	}
+
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Cache:      autocert.DirCache(cfg.AutocertCacheDir),
+		Email:      cfg.AutocertEmail,
+	}
+	listen.AutoCertManager = manager
+	return listen, manager
+}
+
+// newRedirectServer returns a plain HTTP server on port redirecting every request to the
+// same URL over HTTPS on httpsPort. With a manager, it also answers the ACME HTTP-01
+// challenges of certificate requests.
+func newRedirectServer(port, httpsPort int, manager *autocert.Manager) *http.Server {
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+	if manager != nil {
+		handler = manager.HTTPHandler(handler)
+	}
+	return &http.Server{
+		Addr:              ":" + strconv.Itoa(port),
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
+
+// frontendUpstream is where the app listens behind the HTTP/2 front end.
+const frontendUpstream = "127.0.0.1:0"
+
+// frontendTLSConfig returns the TLS config of the HTTP/2 front end, offering h2 and
+// HTTP/1.1, with the configured certificate or the ones of manager.
+func frontendTLSConfig(cfg config.TLSConfig, manager *autocert.Manager) (*tls.Config, error) {
+	if manager != nil {
+		// Also offers acme-tls/1, so TLS-ALPN challenges are answered
+		return manager.TLSConfig(), nil
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// newFrontendServer returns an HTTPS server on port speaking HTTP/2 and HTTP/1.1 that
+// passes every request to the app at upstream, keeping the Host header and adding the
+// client IP and scheme in X-Forwarded-For and X-Forwarded-Proto. Responses are streamed,
+// and WebSocket upgrades, which clients make over HTTP/1.1, are passed through.
+func newFrontendServer(port int, upstream string, tlsConfig *tls.Config) *http.Server {
+	target := &url.URL{Scheme: "http", Host: upstream}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 100
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(target)
+			r.Out.Host = r.In.Host
+			r.SetXForwarded()
+		},
+		Transport:     transport,
+		FlushInterval: -1,
+	}
+	return &http.Server{
+		Addr:              ":" + strconv.Itoa(port),
+		Handler:           proxy,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
+
+// trustFrontend makes the app take the client IP, scheme and host from the HTTP/2 front
+// end, which connects from loopback and appends the client to X-Forwarded-For.
+func trustFrontend(app *config.AppConfig) {
+	host, _, _ := net.SplitHostPort(frontendUpstream)
+	app.TrustedProxies = strings.Join(append(app.TrustedProxyList(), host), ",")
+	app.ProxyHeader = fiber.HeaderXForwardedFor
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
+)
+
+func TestFrontendServer(t *testing.T) {
+	appCfg := config.AppConfig{ProxyHeader: "X-Real-IP"}
+	trustFrontend(&appCfg)
+	if appCfg.ProxyHeader != fiber.HeaderXForwardedFor || len(appCfg.TrustedProxyPrefixes()) != 1 {
+		t.Fatalf("expected the app to trust the front end, got %+v", appCfg)
+	}
+
+	app := fiber.New(fiber.Config{
+		TrustProxy:       true,
+		TrustProxyConfig: fiber.TrustProxyConfig{Proxies: appCfg.TrustedProxyList()},
+	})
+	app.Get("/", func(c fiber.Ctx) error {
+		return c.SendString(c.Scheme() + " " + c.Host() + " " + c.Get(fiber.HeaderXForwardedFor))
+	})
+	ln, err := net.Listen("tcp", frontendUpstream)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go func() { _ = app.Listener(ln, fiber.ListenConfig{DisableStartupMessage: true}) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+
+	frontend := httptest.NewUnstartedServer(newFrontendServer(0, ln.Addr().String(), nil).Handler)
+	frontend.EnableHTTP2 = true
+	frontend.StartTLS()
+	t.Cleanup(frontend.Close)
+
+	req := httptest.NewRequest(fiber.MethodGet, frontend.URL, nil)
+	req.RequestURI = ""
+	req.Host = "api.example.com"
+	resp, err := frontend.Client().Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.ProtoMajor != 2 {
+		t.Errorf("expected HTTP/2, got %s", resp.Proto)
+	}
+	if string(body) != "https api.example.com 127.0.0.1" {
+		t.Errorf("expected the scheme, host and client passed on, got %q", body)
+	}
+}
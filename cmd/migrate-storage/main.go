@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	_ "github.com/joho/godotenv/autoload"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/service"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/database"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/logger"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/storage"
+)
+
+// targetPrefix prefixes the STORAGE_* variables that configure the storage to migrate to.
+const targetPrefix = "TARGET_"
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report what would be copied without copying or updating anything")
+	flag.Parse()
+
+	if err := run(*dryRun); err != nil {
+		slog.Error("storage migration failed", slog.Any("error", err))
+		os.Exit(1)
+	}
+}
+
+func run(dryRun bool) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	targetCfg, err := config.LoadStorage(targetPrefix)
+	if err != nil {
+		return fmt.Errorf("load target storage config: %w", err)
+	}
+	if sameLocation(cfg.Storage, targetCfg) {
+		return errors.New("the target storage must differ from the source: objects are copied to the same paths")
+	}
+
+	logger.Setup(cfg.App.Env, cfg.App.LogLevel)
+
+	ctx := context.Background()
+	pool, err := database.NewPool(ctx, cfg.DB)
+	if err != nil {
+		return fmt.Errorf("connect to database: %w", err)
+	}
+	defer pool.Close()
+
+	if err := database.RunMigrations(cfg.DB.DSN(), "migrations"); err != nil {
+		return fmt.Errorf("run migrations: %w", err)
+	}
+
+	source, err := storage.NewStorage(cfg.Storage)
+	if err != nil {
+		return fmt.Errorf("initialize source storage: %w", err)
+	}
+	target, err := storage.NewStorage(targetCfg)
+	if err != nil {
+		return fmt.Errorf("initialize target storage: %w", err)
+	}
+
+	slog.Info("storage migration started",
+		slog.String("from", cfg.Storage.Driver),
+		slog.String("to", targetCfg.Driver),
+		slog.String("layout", targetCfg.Layout),
+		slog.Bool("dry_run", dryRun),
+	)
+	svc := service.NewStorageMigrationService(
+		repository.NewFileRepository(pool), source, target, targetCfg.ContentAddressed(), database.NewTxManager(pool),
+	)
+	report, err := svc.Migrate(ctx, dryRun)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("storage migration completed",
+		slog.Int("objects", report.Objects),
+		slog.Int64("bytes", report.Bytes),
+		slog.Int("moved", report.Moved),
+		slog.Int("copied", report.Copied),
+		slog.Bool("dry_run", dryRun),
+	)
+	if !dryRun {
+		slog.Info("set STORAGE_* to the target's settings before starting the API; the source objects were left in place")
+	}
+	return nil
+}
+
+// sameLocation reports whether two storage configurations point at the same objects.
+func sameLocation(a, b config.StorageConfig) bool {
+	if a.Driver != b.Driver {
+		return false
+	}
+	switch a.Driver {
+	case "local":
+		return a.LocalPath == b.LocalPath
+	case "s3", "minio":
+		return a.S3Endpoint == b.S3Endpoint && a.S3Bucket == b.S3Bucket
+	case "gcs":
+		return a.GCSBucket == b.GCSBucket
+	case "azure":
+		return a.AzureAccountName == b.AzureAccountName && a.AzureContainer == b.AzureContainer
+	}
+	return false
+}
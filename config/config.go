@@ -1,8 +1,14 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
+	"math"
+	"net/netip"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 )
@@ -11,10 +17,19 @@ type Config struct {
 	App       AppConfig
 	DB        DBConfig
 	JWT       JWTConfig
+	Auth      AuthConfig
+	Password  PasswordConfig
 	Storage   StorageConfig
 	OAuth     OAuthConfig
+	WebAuthn  WebAuthnConfig
+	SAML      SAMLConfig
 	CORS      CORSConfig
 	RateLimit RateLimitConfig
+	IPFilter  IPFilterConfig
+	API       APIConfig
+	TLS       TLSConfig
+	Telemetry TelemetryConfig
+	Reporter  ErrorReporterConfig
 	Cache     CacheConfig
 	Email     EmailConfig
 	Admin     AdminConfig
@@ -32,15 +47,134 @@ type AppConfig struct {
 	BodyLimit                int    `env:"APP_BODY_LIMIT" envDefault:"4194304"` // 4MB
 	LogLevel                 string `env:"LOG_LEVEL" envDefault:"info"`
 	RequestTimeout           int    `env:"APP_REQUEST_TIMEOUT" envDefault:"30"` // seconds
+	RouteTimeouts            string `env:"APP_ROUTE_TIMEOUTS"`
+	ShutdownTimeout          int    `env:"APP_SHUTDOWN_TIMEOUT" envDefault:"30"`
+	ShutdownDelay            int    `env:"APP_SHUTDOWN_DELAY" envDefault:"0"`
+	RouteBodyLimits          string `env:"APP_ROUTE_BODY_LIMITS" envDefault:"/api/v1/auth=65536,/api/v1/users=65536,/api/v2/auth=65536,/api/v2/users=65536"`
+	CompressionLevel         string `env:"APP_COMPRESSION_LEVEL" envDefault:"default"`
+	CompressionMinSize       int    `env:"APP_COMPRESSION_MIN_SIZE" envDefault:"1024"`
+	BodyLogRoutes            string `env:"BODY_LOG_ROUTES"`
+	BodyLogMaxSize           int    `env:"BODY_LOG_MAX_SIZE" envDefault:"4096"`
+	BodyLogRedactFields      string `env:"BODY_LOG_REDACT_FIELDS"`
 	FrontendURL              string `env:"APP_FRONTEND_URL" envDefault:"http://localhost:3000"`
+	DefaultLocale            string `env:"APP_DEFAULT_LOCALE" envDefault:"en"`
+	ErrorFormat              string `env:"APP_ERROR_FORMAT" envDefault:"default"`
+	ErrorTypeBaseURL         string `env:"APP_ERROR_TYPE_BASE_URL"`
 	RequireEmailVerification bool   `env:"REQUIRE_EMAIL_VERIFICATION" envDefault:"false"`
+	RegistrationEnabled      bool   `env:"REGISTRATION_ENABLED" envDefault:"true"`
+	InviteOnly               bool   `env:"INVITE_ONLY" envDefault:"false"` // registration requires an admin invitation
+	InviteTTLHours           int    `env:"INVITE_TTL_HOURS" envDefault:"168"`
+	DeletionGraceDays        int    `env:"ACCOUNT_DELETION_GRACE_DAYS" envDefault:"30"`
+	PurgeInterval            int    `env:"ACCOUNT_PURGE_INTERVAL" envDefault:"3600"` // seconds
+	UserRetentionDays        int    `env:"USER_RETENTION_DAYS" envDefault:"90"`      // 0 keeps soft-deleted users forever
+	DataExportTTLHours       int    `env:"DATA_EXPORT_TTL_HOURS" envDefault:"48"`
+	IdempotencyTTLHours      int    `env:"IDEMPOTENCY_TTL_HOURS" envDefault:"24"`
+	ResponseCacheTTL         int    `env:"RESPONSE_CACHE_TTL" envDefault:"30"`
+	BatchMaxRequests         int    `env:"BATCH_MAX_REQUESTS" envDefault:"20"`
+	WSMaxConnectionsPerUser  int    `env:"WS_MAX_CONNECTIONS_PER_USER" envDefault:"5"`
+	UserMetadataKeys         string `env:"USER_METADATA_ALLOWED_KEYS"`   // comma-separated; empty allows any key
+	UsernameReservedWords    string `env:"USERNAME_RESERVED_WORDS"`      // comma-separated, added to the built-in list
+	SignupAllowedDomains     string `env:"SIGNUP_ALLOWED_EMAIL_DOMAINS"` // comma-separated; empty allows any domain
+	SignupBlockedDomains     string `env:"SIGNUP_BLOCKED_EMAIL_DOMAINS"` // comma-separated, checked before the allow list
+
+	// Reverse proxies whose client IP header is believed, as IPs and CIDR ranges
+	TrustedProxies string `env:"APP_TRUSTED_PROXIES"`
+	ProxyHeader    string `env:"APP_PROXY_HEADER" envDefault:"X-Forwarded-For"`
+}
+
+// RouteTimeoutMap returns the request timeout of each path prefix in RouteTimeouts, which
+// replaces RequestTimeout for requests below the prefix; 0 disables the timeout.
+func (a AppConfig) RouteTimeoutMap() map[string]time.Duration {
+	timeouts, err := parseRouteTimeouts(a.RouteTimeouts)
+	if err != nil {
+		panic(err) // checked by Validate
+	}
+	return timeouts
+}
+
+// parseRouteTimeouts parses comma-separated prefix=seconds pairs, such as /api/v1/files=300.
+func parseRouteTimeouts(list string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	for _, entry := range splitList(list) {
+		prefix, seconds, ok := strings.Cut(entry, "=")
+		prefix = strings.TrimSpace(prefix)
+		n, err := strconv.Atoi(strings.TrimSpace(seconds))
+		if !ok || err != nil || n < 0 || !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("invalid route timeout %q, want /prefix=seconds", entry)
+		}
+		timeouts[strings.TrimSuffix(prefix, "/")] = time.Duration(n) * time.Second
+	}
+	return timeouts, nil
+}
+
+// parseRouteBodyLimits parses comma-separated prefix=bytes pairs, such as /api/v1/auth=65536.
+func parseRouteBodyLimits(list string) (map[string]int64, error) {
+	limits := make(map[string]int64)
+	for _, entry := range splitList(list) {
+		prefix, size, ok := strings.Cut(entry, "=")
+		prefix = strings.TrimSpace(prefix)
+		n, err := strconv.ParseInt(strings.TrimSpace(size), 10, 64)
+		if !ok || err != nil || n < 1 || !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("invalid route body limit %q, want /prefix=bytes", entry)
+		}
+		limits[strings.TrimSuffix(prefix, "/")] = n
+	}
+	return limits, nil
+}
+
+// ProblemDetails reports whether errors are answered as RFC 7807 problem details.
+func (a AppConfig) ProblemDetails() bool {
+	return a.ErrorFormat == "problem"
+}
+
+// BodyLogRouteList returns the path prefixes whose request and response bodies are logged.
+func (a AppConfig) BodyLogRouteList() []string {
+	return splitList(a.BodyLogRoutes)
+}
+
+// BodyLogRedactFieldList returns the fields redacted from logged bodies on top of the
+// built-in ones, such as passwords and tokens.
+func (a AppConfig) BodyLogRedactFieldList() []string {
+	return splitList(a.BodyLogRedactFields)
+}
+
+// TrustedProxyList returns the proxies whose ProxyHeader gives the client IP. Requests from
+// any other address are attributed to the address they come from.
+func (a AppConfig) TrustedProxyList() []string {
+	return splitList(a.TrustedProxies)
+}
+
+// TrustedProxyPrefixes returns the IPs and CIDR ranges of TrustedProxyList.
+func (a AppConfig) TrustedProxyPrefixes() []netip.Prefix {
+	return mustParsePrefixes(a.TrustedProxies)
+}
+
+// AllowedMetadataKeys returns the configured user metadata keys, or nil when any key is allowed.
+func (a AppConfig) AllowedMetadataKeys() []string {
+	return splitList(a.UserMetadataKeys)
+}
+
+// ReservedUsernames returns the configured reserved usernames.
+func (a AppConfig) ReservedUsernames() []string {
+	return splitList(a.UsernameReservedWords)
+}
+
+// AllowedSignupDomains returns the email domains allowed to sign up, or nil when any domain is.
+func (a AppConfig) AllowedSignupDomains() []string {
+	return splitList(a.SignupAllowedDomains)
+}
+
+// BlockedSignupDomains returns the email domains that may not sign up.
+func (a AppConfig) BlockedSignupDomains() []string {
+	return splitList(a.SignupBlockedDomains)
 }
 
 type CORSConfig struct {
 	AllowOrigins     string `env:"CORS_ALLOW_ORIGINS" envDefault:"*"`
 	AllowMethods     string `env:"CORS_ALLOW_METHODS" envDefault:"GET,POST,PUT,DELETE,OPTIONS"`
-	AllowHeaders     string `env:"CORS_ALLOW_HEADERS" envDefault:"Origin,Content-Type,Accept,Authorization"`
+	AllowHeaders     string `env:"CORS_ALLOW_HEADERS" envDefault:"Origin,Content-Type,Accept,Authorization,X-CSRF-Token,X-Device-Fingerprint,Idempotency-Key"`
 	AllowCredentials bool   `env:"CORS_ALLOW_CREDENTIALS" envDefault:"false"`
+	ExposeHeaders    string `env:"CORS_EXPOSE_HEADERS" envDefault:"X-RateLimit-Limit,X-RateLimit-Remaining,X-RateLimit-Reset,Retry-After,ETag,Idempotent-Replayed,X-Cache,Deprecation,Sunset,Link"`
 }
 
 type RateLimitConfig struct {
@@ -50,6 +184,248 @@ type RateLimitConfig struct {
 	NormalWindow  int `env:"RATE_LIMIT_NORMAL_WINDOW_SECS" envDefault:"60"`
 	RelaxedMax    int `env:"RATE_LIMIT_RELAXED_MAX" envDefault:"120"`
 	RelaxedWindow int `env:"RATE_LIMIT_RELAXED_WINDOW_SECS" envDefault:"60"`
+
+	// Budgets of the same tiers on authenticated routes, counted per user instead of per IP
+	UserStrictMax  int `env:"RATE_LIMIT_USER_STRICT_MAX" envDefault:"10"`
+	UserNormalMax  int `env:"RATE_LIMIT_USER_NORMAL_MAX" envDefault:"120"`
+	UserRelaxedMax int `env:"RATE_LIMIT_USER_RELAXED_MAX" envDefault:"300"`
+
+	// Requests skipping every limiter: from these IPs and CIDR ranges, below these path
+	// prefixes, or carrying one of these tokens in the X-Internal-Token header
+	ExemptIPs    string `env:"RATE_LIMIT_EXEMPT_IPS"`
+	ExemptPaths  string `env:"RATE_LIMIT_EXEMPT_PATHS" envDefault:"/healthz,/readyz,/health,/metrics"`
+	ExemptTokens string `env:"RATE_LIMIT_EXEMPT_TOKENS"`
+}
+
+// ExemptIPList returns the IPs and CIDR ranges exempt from rate limits.
+func (r RateLimitConfig) ExemptIPList() []netip.Prefix {
+	return mustParsePrefixes(r.ExemptIPs)
+}
+
+// ExemptPathList returns the path prefixes exempt from rate limits.
+func (r RateLimitConfig) ExemptPathList() []string {
+	return splitList(r.ExemptPaths)
+}
+
+// ExemptTokenList returns the internal service tokens exempt from rate limits.
+func (r RateLimitConfig) ExemptTokenList() []string {
+	return splitList(r.ExemptTokens)
+}
+
+func (r RateLimitConfig) validate() error {
+	if r.StrictMax < 1 || r.NormalMax < 1 || r.RelaxedMax < 1 ||
+		r.UserStrictMax < 1 || r.UserNormalMax < 1 || r.UserRelaxedMax < 1 {
+		return fmt.Errorf("all RATE_LIMIT_*_MAX values must be at least 1")
+	}
+	if r.StrictWindow < 1 || r.NormalWindow < 1 || r.RelaxedWindow < 1 {
+		return fmt.Errorf("all RATE_LIMIT_*_WINDOW_SECS values must be at least 1")
+	}
+	if _, err := ParsePrefixes(r.ExemptIPs); err != nil {
+		return fmt.Errorf("RATE_LIMIT_EXEMPT_IPS: %w", err)
+	}
+	for _, path := range r.ExemptPathList() {
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("RATE_LIMIT_EXEMPT_PATHS: %q must start with /", path)
+		}
+	}
+	// Tokens bypass every limit, so they must not be guessable
+	for _, token := range r.ExemptTokenList() {
+		if len(token) < 32 {
+			return fmt.Errorf("RATE_LIMIT_EXEMPT_TOKENS: tokens must be at least 32 characters")
+		}
+	}
+	return nil
+}
+
+// IPFilterConfig restricts which client IPs may call the API. Each list holds IPs and CIDR
+// ranges; a deny list wins over an allow list, and an empty allow list allows any IP.
+type IPFilterConfig struct {
+	AllowList      string `env:"IP_ALLOW_LIST"`
+	DenyList       string `env:"IP_DENY_LIST"`
+	AdminAllowList string `env:"ADMIN_IP_ALLOW_LIST"`
+	AdminDenyList  string `env:"ADMIN_IP_DENY_LIST"`
+}
+
+// Global returns the ranges that apply to every route.
+func (f IPFilterConfig) Global() (allow, deny []netip.Prefix) {
+	return mustParsePrefixes(f.AllowList), mustParsePrefixes(f.DenyList)
+}
+
+// Admin returns the ranges that apply to the admin routes, on top of the global ones.
+func (f IPFilterConfig) Admin() (allow, deny []netip.Prefix) {
+	return mustParsePrefixes(f.AdminAllowList), mustParsePrefixes(f.AdminDenyList)
+}
+
+func (f IPFilterConfig) validate() error {
+	lists := []struct{ name, value string }{
+		{"IP_ALLOW_LIST", f.AllowList},
+		{"IP_DENY_LIST", f.DenyList},
+		{"ADMIN_IP_ALLOW_LIST", f.AdminAllowList},
+		{"ADMIN_IP_DENY_LIST", f.AdminDenyList},
+	}
+	for _, list := range lists {
+		if _, err := ParsePrefixes(list.value); err != nil {
+			return fmt.Errorf("%s: %w", list.name, err)
+		}
+	}
+	return nil
+}
+
+// ParsePrefixes parses a comma-separated list of IPs and CIDR ranges. An IP is a range
+// holding only itself.
+func ParsePrefixes(list string) ([]netip.Prefix, error) {
+	entries := splitList(list)
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR range %q", entry)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP address %q", entry)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// mustParsePrefixes parses a list that Validate already checked.
+func mustParsePrefixes(list string) []netip.Prefix {
+	prefixes, err := ParsePrefixes(list)
+	if err != nil {
+		panic(err)
+	}
+	return prefixes
+}
+
+// TLSConfig serves HTTPS directly, for deployments without a reverse proxy terminating TLS,
+// with a certificate from files or from Let's Encrypt for AutocertDomains.
+type TLSConfig struct {
+	CertFile         string `env:"TLS_CERT_FILE"`
+	KeyFile          string `env:"TLS_KEY_FILE"`
+	AutocertDomains  string `env:"TLS_AUTOCERT_DOMAINS"`
+	AutocertEmail    string `env:"TLS_AUTOCERT_EMAIL"`
+	AutocertCacheDir string `env:"TLS_AUTOCERT_CACHE_DIR" envDefault:"./certs"`
+	// Port of a plain HTTP listener redirecting to HTTPS; 0 disables it
+	RedirectPort int `env:"TLS_REDIRECT_PORT"`
+	// Terminate TLS in a net/http front end speaking HTTP/2 and HTTP/1.1, which passes
+	// requests to the app on loopback, as fasthttp only speaks HTTP/1.1
+	HTTP2 bool `env:"TLS_HTTP2"`
+}
+
+// Enabled reports whether the server terminates TLS.
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" || t.AutocertDomains != ""
+}
+
+// AutocertDomainList returns the domains certificates are requested for.
+func (t TLSConfig) AutocertDomainList() []string {
+	return splitList(t.AutocertDomains)
+}
+
+func (t TLSConfig) validate(appPort int) error {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if t.CertFile != "" && t.AutocertDomains != "" {
+		return fmt.Errorf("TLS_CERT_FILE and TLS_AUTOCERT_DOMAINS cannot both be set")
+	}
+	if t.HTTP2 && !t.Enabled() {
+		return fmt.Errorf("TLS_HTTP2 requires TLS_CERT_FILE or TLS_AUTOCERT_DOMAINS")
+	}
+	if t.RedirectPort != 0 {
+		if !t.Enabled() {
+			return fmt.Errorf("TLS_REDIRECT_PORT requires TLS_CERT_FILE or TLS_AUTOCERT_DOMAINS")
+		}
+		if t.RedirectPort < 1 || t.RedirectPort > 65535 || t.RedirectPort == appPort {
+			return fmt.Errorf("TLS_REDIRECT_PORT must be between 1 and 65535 and differ from APP_PORT")
+		}
+	}
+	return nil
+}
+
+// APIConfig announces the retirement of API v1. Once set, every v1 response carries a
+// Deprecation header with the date v1 was deprecated, a Sunset header with the date it
+// stops being served, and a Link to the migration guide. Dates are YYYY-MM-DD or RFC 3339.
+type APIConfig struct {
+	V1DeprecatedAt    string `env:"API_V1_DEPRECATED_AT"`
+	V1SunsetAt        string `env:"API_V1_SUNSET_AT"`
+	V1DeprecationLink string `env:"API_V1_DEPRECATION_LINK"`
+}
+
+// V1Deprecation returns the dates API v1 was deprecated and is retired, zero when unset.
+func (a APIConfig) V1Deprecation() (deprecatedAt, sunsetAt time.Time) {
+	return mustParseDate(a.V1DeprecatedAt), mustParseDate(a.V1SunsetAt)
+}
+
+func (a APIConfig) validate() error {
+	if _, err := parseDate(a.V1DeprecatedAt); err != nil {
+		return fmt.Errorf("API_V1_DEPRECATED_AT: %w", err)
+	}
+	if _, err := parseDate(a.V1SunsetAt); err != nil {
+		return fmt.Errorf("API_V1_SUNSET_AT: %w", err)
+	}
+	if a.V1DeprecationLink != "" {
+		if u, err := url.Parse(a.V1DeprecationLink); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("API_V1_DEPRECATION_LINK must be an absolute URL")
+		}
+	}
+	return nil
+}
+
+// parseDate parses a YYYY-MM-DD date or an RFC 3339 timestamp; an empty string is the
+// zero time.
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, want YYYY-MM-DD or RFC 3339", s)
+	}
+	return t, nil
+}
+
+// mustParseDate parses a date that Validate already checked.
+func mustParseDate(s string) time.Time {
+	t, err := parseDate(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// TelemetryConfig turns on OpenTelemetry tracing. Traces are exported over OTLP/HTTP when
+// an endpoint is set; the exporter also reads the other OTEL_EXPORTER_OTLP_* variables,
+// such as OTEL_EXPORTER_OTLP_HEADERS.
+type TelemetryConfig struct {
+	Endpoint       string  `env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	TracesEndpoint string  `env:"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"`
+	ServiceName    string  `env:"OTEL_SERVICE_NAME" envDefault:"fiber-golang-boilerplate"`
+	SampleRatio    float64 `env:"TRACING_SAMPLE_RATIO" envDefault:"1"`
+}
+
+// Enabled reports whether traces are exported.
+func (t TelemetryConfig) Enabled() bool {
+	return t.Endpoint != "" || t.TracesEndpoint != ""
+}
+
+// ErrorReporterConfig selects where server errors and panics are reported: "none" or
+// "sentry". Environment defaults to APP_ENV.
+type ErrorReporterConfig struct {
+	Driver      string  `env:"ERROR_REPORTER_DRIVER" envDefault:"none"`
+	DSN         string  `env:"ERROR_REPORTER_DSN"`
+	Environment string  `env:"ERROR_REPORTER_ENVIRONMENT"`
+	SampleRate  float64 `env:"ERROR_REPORTER_SAMPLE_RATE" envDefault:"1"`
 }
 
 type DBConfig struct {
@@ -70,11 +446,148 @@ type JWTConfig struct {
 	Secret            string `env:"JWT_SECRET" envDefault:"secret"`
 	ExpireHour        int    `env:"JWT_EXPIRE_HOUR" envDefault:"24"`
 	RefreshExpireDays int    `env:"JWT_REFRESH_EXPIRE_DAYS" envDefault:"30"`
+	ImpersonateMins   int    `env:"JWT_IMPERSONATE_EXPIRE_MINUTES" envDefault:"15"`
+	GuestExpireHour   int    `env:"JWT_GUEST_EXPIRE_HOUR" envDefault:"24"`
+	Issuer            string `env:"JWT_ISSUER" envDefault:"fiber-golang-boilerplate"`
+	Audience          string `env:"JWT_AUDIENCE" envDefault:"fiber-golang-boilerplate-api"`
+	ExtraAudiences    string `env:"JWT_EXTRA_AUDIENCES"` // comma-separated audiences also accepted when parsing
+}
+
+// AcceptedAudiences returns the additional audiences accepted besides Audience.
+func (j JWTConfig) AcceptedAudiences() []string {
+	return splitList(j.ExtraAudiences)
+}
+
+// splitList splits a comma-separated setting, dropping blank entries.
+func splitList(s string) []string {
+	var items []string
+	for _, p := range strings.Split(s, ",") {
+		if t := strings.TrimSpace(p); t != "" {
+			items = append(items, t)
+		}
+	}
+	return items
+}
+
+type AuthConfig struct {
+	CookieMode        bool   `env:"AUTH_COOKIE_MODE" envDefault:"false"`       // deliver refresh tokens via httpOnly cookies
+	NewDeviceEmail    bool   `env:"AUTH_NEW_DEVICE_EMAIL" envDefault:"false"`  // email users on sign-in from an unrecognized device
+	IPMaxFailedLogins int    `env:"AUTH_IP_MAX_FAILED_LOGINS" envDefault:"20"` // failed logins per IP before backoff starts
+	IPFailureWindow   int    `env:"AUTH_IP_FAILURE_WINDOW" envDefault:"900"`   // seconds
+	IPMaxBackoff      int    `env:"AUTH_IP_MAX_BACKOFF" envDefault:"900"`      // seconds
+	TOTPIssuer        string `env:"AUTH_TOTP_ISSUER" envDefault:"Fiber App"`   // account issuer shown in authenticator apps
+}
+
+type PasswordConfig struct {
+	MinLength      int    `env:"PASSWORD_MIN_LENGTH" envDefault:"8"`
+	MaxLength      int    `env:"PASSWORD_MAX_LENGTH" envDefault:"72"` // bcrypt hashes at most 72 bytes
+	RequireUpper   bool   `env:"PASSWORD_REQUIRE_UPPER" envDefault:"true"`
+	RequireLower   bool   `env:"PASSWORD_REQUIRE_LOWER" envDefault:"true"`
+	RequireDigit   bool   `env:"PASSWORD_REQUIRE_DIGIT" envDefault:"true"`
+	RequireSpecial bool   `env:"PASSWORD_REQUIRE_SPECIAL" envDefault:"true"`
+	BannedFile     string `env:"PASSWORD_BANNED_FILE"` // newline-separated list of rejected passwords
 }
 
 type CacheConfig struct {
-	Driver   string `env:"CACHE_DRIVER" envDefault:"memory"`
-	RedisURL string `env:"REDIS_URL"`
+	Driver           string `env:"CACHE_DRIVER" envDefault:"memory"`
+	RedisURL         string `env:"REDIS_URL"`
+	MemcachedServers string `env:"MEMCACHED_SERVERS"`
+
+	// Namespace of the keys in a shared cache: the app name, joined by APP_ENV, and the
+	// version of the cached formats, raised to drop every value written by older releases
+	KeyPrefix     string `env:"CACHE_KEY_PREFIX" envDefault:"fiber-app"`
+	SchemaVersion int    `env:"CACHE_SCHEMA_VERSION" envDefault:"1"`
+
+	// Redis deployment of the redis and tiered drivers: standalone connects to REDIS_URL,
+	// sentinel and cluster to the sentinels or seed nodes in REDIS_ADDRS
+	RedisMode             string `env:"REDIS_MODE" envDefault:"standalone"`
+	RedisAddrs            string `env:"REDIS_ADDRS"`
+	RedisMasterName       string `env:"REDIS_MASTER_NAME"`
+	RedisUsername         string `env:"REDIS_USERNAME"`
+	RedisPassword         string `env:"REDIS_PASSWORD"`
+	RedisSentinelUsername string `env:"REDIS_SENTINEL_USERNAME"`
+	RedisSentinelPassword string `env:"REDIS_SENTINEL_PASSWORD"`
+	RedisDB               int    `env:"REDIS_DB"`
+	RedisTLS              bool   `env:"REDIS_TLS"`
+	RedisTLSCAFile        string `env:"REDIS_TLS_CA_FILE"`
+
+	// Bounds of the memory driver, beyond which the least recently used entries are
+	// evicted; 0 leaves either unbounded
+	MemoryMaxEntries int   `env:"CACHE_MEMORY_MAX_ENTRIES" envDefault:"100000"`
+	MemoryMaxBytes   int64 `env:"CACHE_MEMORY_MAX_BYTES" envDefault:"67108864"`
+
+	// In-process tier of the tiered driver: entries kept, and seconds an entry is served
+	// before being read from Redis again
+	LocalSize int `env:"CACHE_LOCAL_SIZE" envDefault:"10000"`
+	LocalTTL  int `env:"CACHE_LOCAL_TTL" envDefault:"30"`
+}
+
+// RedisAddrList returns the addresses of the Redis sentinels or cluster seed nodes.
+func (c CacheConfig) RedisAddrList() []string {
+	return splitList(c.RedisAddrs)
+}
+
+// MemcachedServerList returns the addresses of the memcached servers.
+func (c CacheConfig) MemcachedServerList() []string {
+	return splitList(c.MemcachedServers)
+}
+
+func (c CacheConfig) validate() error {
+	switch c.Driver {
+	case "memory":
+	case "redis", "tiered":
+		if err := c.validateRedis(); err != nil {
+			return err
+		}
+	case "memcached":
+		if len(c.MemcachedServerList()) == 0 {
+			return fmt.Errorf("MEMCACHED_SERVERS is required for memcached driver")
+		}
+	default:
+		return fmt.Errorf("CACHE_DRIVER must be one of: memory, redis, memcached, tiered (got %q)", c.Driver)
+	}
+	if c.SchemaVersion < 1 {
+		return fmt.Errorf("CACHE_SCHEMA_VERSION must be at least 1")
+	}
+	if strings.ContainsAny(c.KeyPrefix, " \t\r\n") {
+		return fmt.Errorf("CACHE_KEY_PREFIX must not contain whitespace")
+	}
+	if c.MemoryMaxEntries < 0 || c.MemoryMaxBytes < 0 {
+		return fmt.Errorf("CACHE_MEMORY_MAX_ENTRIES and CACHE_MEMORY_MAX_BYTES must not be negative")
+	}
+	if c.Driver == "tiered" && (c.LocalSize < 1 || c.LocalTTL < 1) {
+		return fmt.Errorf("CACHE_LOCAL_SIZE and CACHE_LOCAL_TTL must be at least 1")
+	}
+	return nil
+}
+
+func (c CacheConfig) validateRedis() error {
+	switch c.RedisMode {
+	case "standalone":
+		if c.RedisURL == "" {
+			return fmt.Errorf("REDIS_URL is required for %s driver", c.Driver)
+		}
+	case "sentinel":
+		if len(c.RedisAddrList()) == 0 || c.RedisMasterName == "" {
+			return fmt.Errorf("REDIS_ADDRS and REDIS_MASTER_NAME are required for REDIS_MODE=sentinel")
+		}
+	case "cluster":
+		if len(c.RedisAddrList()) == 0 {
+			return fmt.Errorf("REDIS_ADDRS is required for REDIS_MODE=cluster")
+		}
+		if c.RedisDB != 0 {
+			return fmt.Errorf("REDIS_DB must be 0 for REDIS_MODE=cluster")
+		}
+	default:
+		return fmt.Errorf("REDIS_MODE must be one of: standalone, sentinel, cluster (got %q)", c.RedisMode)
+	}
+	if c.RedisDB < 0 {
+		return fmt.Errorf("REDIS_DB must not be negative")
+	}
+	if c.RedisTLSCAFile != "" && !c.RedisTLS && !strings.HasPrefix(c.RedisURL, "rediss://") {
+		return fmt.Errorf("REDIS_TLS_CA_FILE requires REDIS_TLS or a rediss:// REDIS_URL")
+	}
+	return nil
 }
 
 type EmailConfig struct {
@@ -85,19 +598,115 @@ type EmailConfig struct {
 	SMTPPassword string `env:"SMTP_PASSWORD"`
 	FromAddress  string `env:"EMAIL_FROM_ADDRESS" envDefault:"noreply@localhost"`
 	FromName     string `env:"EMAIL_FROM_NAME" envDefault:"Fiber App"`
+	// Provider webhooks reporting bounces and complaints authenticate with HTTP basic auth
+	// using WebhookSecret as the password; empty disables them.
+	WebhookSecret string `env:"EMAIL_WEBHOOK_SECRET"`
+	// API drivers (sendgrid, ses, mailgun, postmark) give up on a request after
+	// HTTPTimeout seconds; the outbox retries it later.
+	HTTPTimeout    int    `env:"EMAIL_HTTP_TIMEOUT" envDefault:"10"`
+	SendGridAPIKey string `env:"SENDGRID_API_KEY"`
+	// SES is called through its v2 HTTP API with static credentials. SESEndpoint
+	// overrides https://email.<region>.amazonaws.com, e.g. for a VPC endpoint.
+	SESRegion           string `env:"SES_REGION" envDefault:"us-east-1"`
+	SESAccessKeyID      string `env:"SES_ACCESS_KEY_ID"`
+	SESSecretAccessKey  string `env:"SES_SECRET_ACCESS_KEY"`
+	SESSessionToken     string `env:"SES_SESSION_TOKEN"`
+	SESConfigurationSet string `env:"SES_CONFIGURATION_SET"`
+	SESEndpoint         string `env:"SES_ENDPOINT"`
+	// MailgunRegion selects the us or eu API host the domain was created in.
+	MailgunAPIKey string `env:"MAILGUN_API_KEY"`
+	MailgunDomain string `env:"MAILGUN_DOMAIN"`
+	MailgunRegion string `env:"MAILGUN_REGION" envDefault:"us"`
+	// PostmarkMessageStream must be a transactional stream of the server.
+	PostmarkServerToken   string `env:"POSTMARK_SERVER_TOKEN"`
+	PostmarkMessageStream string `env:"POSTMARK_MESSAGE_STREAM" envDefault:"outbound"`
+	// Broadcasts read recipients in batches of BroadcastBatchSize and send at most
+	// BroadcastRate emails per second.
+	BroadcastBatchSize int `env:"EMAIL_BROADCAST_BATCH_SIZE" envDefault:"100"`
+	BroadcastRate      int `env:"EMAIL_BROADCAST_RATE" envDefault:"10"`
+	// Emails other than broadcasts are queued in the outbox table and sent by a worker
+	// polling every OutboxPollInterval seconds, retried with exponential backoff up to
+	// OutboxMaxAttempts times. Sent and dead messages are deleted after OutboxRetentionDays.
+	OutboxEnabled       bool `env:"EMAIL_OUTBOX_ENABLED" envDefault:"true"`
+	OutboxPollInterval  int  `env:"EMAIL_OUTBOX_POLL_INTERVAL" envDefault:"5"`
+	OutboxBatchSize     int  `env:"EMAIL_OUTBOX_BATCH_SIZE" envDefault:"50"`
+	OutboxMaxAttempts   int  `env:"EMAIL_OUTBOX_MAX_ATTEMPTS" envDefault:"8"`
+	OutboxRetentionDays int  `env:"EMAIL_OUTBOX_RETENTION_DAYS" envDefault:"30"`
+}
+
+func (c EmailConfig) validate() error {
+	switch c.Driver {
+	case "console":
+	case "smtp":
+		if c.SMTPHost == "" {
+			return fmt.Errorf("SMTP_HOST is required for smtp driver")
+		}
+	case "sendgrid":
+		if c.SendGridAPIKey == "" {
+			return fmt.Errorf("SENDGRID_API_KEY is required for sendgrid driver")
+		}
+	case "ses":
+		if c.SESRegion == "" || c.SESAccessKeyID == "" || c.SESSecretAccessKey == "" {
+			return fmt.Errorf("SES_REGION, SES_ACCESS_KEY_ID and SES_SECRET_ACCESS_KEY are required for ses driver")
+		}
+		if c.SESEndpoint != "" {
+			if u, err := url.Parse(c.SESEndpoint); err != nil || u.Scheme == "" || u.Host == "" {
+				return fmt.Errorf("SES_ENDPOINT must be an absolute URL")
+			}
+		}
+	case "mailgun":
+		if c.MailgunAPIKey == "" || c.MailgunDomain == "" {
+			return fmt.Errorf("MAILGUN_API_KEY and MAILGUN_DOMAIN are required for mailgun driver")
+		}
+		if c.MailgunRegion != "us" && c.MailgunRegion != "eu" {
+			return fmt.Errorf("MAILGUN_REGION must be one of: us, eu (got %q)", c.MailgunRegion)
+		}
+	case "postmark":
+		if c.PostmarkServerToken == "" || c.PostmarkMessageStream == "" {
+			return fmt.Errorf("POSTMARK_SERVER_TOKEN and POSTMARK_MESSAGE_STREAM are required for postmark driver")
+		}
+	default:
+		return fmt.Errorf("EMAIL_DRIVER must be one of: console, smtp, sendgrid, ses, mailgun, postmark (got %q)", c.Driver)
+	}
+	if c.HTTPTimeout < 1 {
+		return fmt.Errorf("EMAIL_HTTP_TIMEOUT must be at least 1 second")
+	}
+	if c.WebhookSecret != "" && len(c.WebhookSecret) < 32 {
+		return fmt.Errorf("EMAIL_WEBHOOK_SECRET must be at least 32 characters")
+	}
+	return nil
 }
 
 type StorageConfig struct {
-	Driver           string `env:"STORAGE_DRIVER" envDefault:"local"`
-	LocalPath        string `env:"STORAGE_LOCAL_PATH" envDefault:"./uploads"`
-	MaxFileSize      int64  `env:"STORAGE_MAX_FILE_SIZE" envDefault:"10485760"` // 10MB
-	AllowedMIMETypes string `env:"STORAGE_ALLOWED_MIME_TYPES" envDefault:"image/jpeg,image/png,image/gif,image/webp,application/pdf"`
-	S3Endpoint       string `env:"STORAGE_S3_ENDPOINT"`
-	S3Region         string `env:"STORAGE_S3_REGION" envDefault:"us-east-1"`
-	S3Bucket         string `env:"STORAGE_S3_BUCKET" envDefault:"uploads"`
-	S3AccessKey      string `env:"STORAGE_S3_ACCESS_KEY"`
-	S3SecretKey      string `env:"STORAGE_S3_SECRET_KEY"`
-	S3UseSSL         bool   `env:"STORAGE_S3_USE_SSL" envDefault:"false"`
+	Driver                string `env:"STORAGE_DRIVER" envDefault:"local"`
+	LocalPath             string `env:"STORAGE_LOCAL_PATH" envDefault:"./uploads"`
+	MaxFileSize           int64  `env:"STORAGE_MAX_FILE_SIZE" envDefault:"10485760"`             // 10MB
+	MaxResumableFileSize  int64  `env:"STORAGE_MAX_RESUMABLE_FILE_SIZE" envDefault:"1073741824"` // 1GB
+	UploadSessionTTLHours int    `env:"STORAGE_UPLOAD_SESSION_TTL_HOURS" envDefault:"24"`
+	ShareDefaultTTLHours  int    `env:"STORAGE_SHARE_DEFAULT_TTL_HOURS" envDefault:"24"`
+	ShareMaxTTLHours      int    `env:"STORAGE_SHARE_MAX_TTL_HOURS" envDefault:"720"`
+	FileRetentionDays     int    `env:"STORAGE_FILE_RETENTION_DAYS" envDefault:"30"`    // 0 keeps soft-deleted files forever
+	Layout                string `env:"STORAGE_LAYOUT" envDefault:"user"`               // user (<user>/<uuid>) or content (sha256/<hash>)
+	StripImageMetadata    bool   `env:"STORAGE_STRIP_IMAGE_METADATA" envDefault:"true"` // drop EXIF from JPEGs and apply their orientation
+	ImageVariants         string `env:"STORAGE_IMAGE_VARIANTS" envDefault:"thumbnail:200x200:jpeg,medium:800x800:jpeg,webp:800x800:webp"`
+	AllowedMIMETypes      string `env:"STORAGE_ALLOWED_MIME_TYPES" envDefault:"image/jpeg,image/png,image/gif,image/webp,application/pdf"`
+	S3Endpoint            string `env:"STORAGE_S3_ENDPOINT"`
+	S3Region              string `env:"STORAGE_S3_REGION" envDefault:"us-east-1"`
+	S3Bucket              string `env:"STORAGE_S3_BUCKET" envDefault:"uploads"`
+	S3AccessKey           string `env:"STORAGE_S3_ACCESS_KEY"`
+	S3SecretKey           string `env:"STORAGE_S3_SECRET_KEY"`
+	S3UseSSL              bool   `env:"STORAGE_S3_USE_SSL" envDefault:"false"`
+	GCSBucket             string `env:"STORAGE_GCS_BUCKET"`
+	GCSCredentialsFile    string `env:"STORAGE_GCS_CREDENTIALS_FILE"`
+	AzureAccountName      string `env:"STORAGE_AZURE_ACCOUNT_NAME"`
+	AzureAccountKey       string `env:"STORAGE_AZURE_ACCOUNT_KEY"`
+	AzureContainer        string `env:"STORAGE_AZURE_CONTAINER" envDefault:"uploads"`
+	AzureEndpoint         string `env:"STORAGE_AZURE_ENDPOINT"`
+	EncryptionKeys        string `env:"STORAGE_ENCRYPTION_KEYS"` // id:base64key,...; empty disables encryption at rest
+	EncryptionKeyID       string `env:"STORAGE_ENCRYPTION_KEY_ID"`
+	CDNBaseURL            string `env:"STORAGE_CDN_BASE_URL"`       // e.g. https://cdn.example.com; empty links to the storage itself
+	CDNSigningSecret      string `env:"STORAGE_CDN_SIGNING_SECRET"` // empty leaves CDN links unsigned
+	CDNURLTTLMinutes      int    `env:"STORAGE_CDN_URL_TTL_MINUTES" envDefault:"60"`
 }
 
 // AllowedTypes returns the list of allowed MIME types for uploads.
@@ -112,13 +721,86 @@ func (s StorageConfig) AllowedTypes() []string {
 	return types
 }
 
+// ContentAddressed reports whether new objects are stored under the SHA-256 of their
+// content, so identical files share one object across users.
+func (s StorageConfig) ContentAddressed() bool {
+	return s.Layout == "content"
+}
+
+// EncryptionKeySet parses STORAGE_ENCRYPTION_KEYS into 32-byte keys by ID and returns the
+// ID new objects are encrypted with: STORAGE_ENCRYPTION_KEY_ID, or the first key listed.
+func (s StorageConfig) EncryptionKeySet() (map[string][]byte, string, error) {
+	keys := make(map[string][]byte)
+	current := s.EncryptionKeyID
+	for _, entry := range strings.Split(s.EncryptionKeys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" || len(id) > 64 {
+			return nil, "", fmt.Errorf("STORAGE_ENCRYPTION_KEYS entries must be id:base64key with an ID of at most 64 characters")
+		}
+		if _, dup := keys[id]; dup {
+			return nil, "", fmt.Errorf("STORAGE_ENCRYPTION_KEYS lists key %q twice", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			return nil, "", fmt.Errorf("STORAGE_ENCRYPTION_KEYS key %q must be 32 bytes, base64-encoded", id)
+		}
+		keys[id] = key
+		if current == "" {
+			current = id
+		}
+	}
+	if len(keys) == 0 {
+		return nil, "", fmt.Errorf("STORAGE_ENCRYPTION_KEYS has no keys")
+	}
+	if _, ok := keys[current]; !ok {
+		return nil, "", fmt.Errorf("STORAGE_ENCRYPTION_KEY_ID %q is not in STORAGE_ENCRYPTION_KEYS", current)
+	}
+	return keys, current, nil
+}
+
 type OAuthConfig struct {
 	GoogleClientID     string `env:"GOOGLE_CLIENT_ID"`
 	GoogleClientSecret string `env:"GOOGLE_CLIENT_SECRET"`
 	GoogleRedirectURL  string `env:"GOOGLE_REDIRECT_URL" envDefault:"http://localhost:8080/api/v1/auth/google/callback"`
+	GitHubClientID     string `env:"GITHUB_CLIENT_ID"`
+	GitHubClientSecret string `env:"GITHUB_CLIENT_SECRET"`
+	GitHubRedirectURL  string `env:"GITHUB_REDIRECT_URL" envDefault:"http://localhost:8080/api/v1/auth/github/callback"`
 	FrontendURL        string `env:"OAUTH_FRONTEND_URL" envDefault:"http://localhost:3000/auth/callback"`
 }
 
+type WebAuthnConfig struct {
+	RPID          string `env:"WEBAUTHN_RP_ID"`
+	RPDisplayName string `env:"WEBAUTHN_RP_DISPLAY_NAME" envDefault:"Fiber App"`
+	RPOrigins     string `env:"WEBAUTHN_RP_ORIGINS" envDefault:"http://localhost:3000"`
+}
+
+type SAMLConfig struct {
+	IDPMetadataURL    string `env:"SAML_IDP_METADATA_URL"`
+	EntityID          string `env:"SAML_ENTITY_ID"`
+	RootURL           string `env:"SAML_ROOT_URL" envDefault:"http://localhost:8080"`
+	SPCertFile        string `env:"SAML_SP_CERT_FILE"`
+	SPKeyFile         string `env:"SAML_SP_KEY_FILE"`
+	EmailAttribute    string `env:"SAML_EMAIL_ATTRIBUTE" envDefault:"email"`
+	NameAttribute     string `env:"SAML_NAME_ATTRIBUTE" envDefault:"name"`
+	AllowIDPInitiated bool   `env:"SAML_ALLOW_IDP_INITIATED" envDefault:"false"`
+}
+
+// Origins returns the list of origins allowed to perform WebAuthn ceremonies.
+func (w WebAuthnConfig) Origins() []string {
+	parts := strings.Split(w.RPOrigins, ",")
+	origins := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if t := strings.TrimSpace(p); t != "" {
+			origins = append(origins, t)
+		}
+	}
+	return origins
+}
+
 // Origins returns the list of allowed CORS origins.
 func (c CORSConfig) Origins() []string {
 	parts := strings.Split(c.AllowOrigins, ",")
@@ -155,6 +837,11 @@ func (c CORSConfig) Headers() []string {
 	return headers
 }
 
+// ExposedHeaders returns the response headers browsers let scripts read.
+func (c CORSConfig) ExposedHeaders() []string {
+	return splitList(c.ExposeHeaders)
+}
+
 func (db DBConfig) DSN() string {
 	return fmt.Sprintf(
 		"postgres://%s:%s@%s:%d/%s?sslmode=%s&search_path=%s",
@@ -173,10 +860,87 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// LoadStorage reads a storage configuration from the STORAGE_* variables carrying the given
+// prefix, such as TARGET_STORAGE_DRIVER for "TARGET_", for tools that use a second storage.
+func LoadStorage(prefix string) (StorageConfig, error) {
+	var cfg StorageConfig
+	if err := env.ParseWithOptions(&cfg, env.Options{Prefix: prefix}); err != nil {
+		return cfg, fmt.Errorf("failed to parse %sSTORAGE_* config: %w", prefix, err)
+	}
+	if err := cfg.validateBackend(); err != nil {
+		return cfg, fmt.Errorf("invalid %sSTORAGE_* config: %w", prefix, err)
+	}
+	return cfg, nil
+}
+
+// multipartOverhead is room for the multipart headers and form fields sent along a file.
+const multipartOverhead = 64 << 10
+
+// UploadBodyLimit returns the request body limit of the routes taking a file in a single
+// request, which accept STORAGE_MAX_FILE_SIZE along with the multipart fields.
+func (cfg *Config) UploadBodyLimit() int64 {
+	return cfg.Storage.MaxFileSize + multipartOverhead
+}
+
+// RouteBodyLimitMap returns the request body limit APP_ROUTE_BODY_LIMITS sets for each path
+// prefix, which replaces APP_BODY_LIMIT for requests below it.
+func (cfg *Config) RouteBodyLimitMap() map[string]int64 {
+	routes, err := parseRouteBodyLimits(cfg.App.RouteBodyLimits)
+	if err != nil {
+		panic(err) // checked by Validate
+	}
+	return routes
+}
+
+// CacheNamespace returns the prefix of every key written to a shared cache, such as
+// "fiber-app:production:v1:", so apps and environments sharing one server do not read each
+// other's values. An empty CACHE_KEY_PREFIX leaves only the version.
+func (cfg *Config) CacheNamespace() string {
+	version := "v" + strconv.Itoa(cfg.Cache.SchemaVersion) + ":"
+	if cfg.Cache.KeyPrefix == "" {
+		return version
+	}
+	return cfg.Cache.KeyPrefix + ":" + cfg.App.Env + ":" + version
+}
+
+// MaxBodyLimit returns the largest body any route accepts, which the server reads
+// requests up to before the route's own limit is checked.
+func (cfg *Config) MaxBodyLimit() int {
+	largest := max(int64(cfg.App.BodyLimit), cfg.UploadBodyLimit())
+	for _, limit := range cfg.RouteBodyLimitMap() {
+		largest = max(largest, limit)
+	}
+	if largest > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int(largest)
+}
+
 func (cfg *Config) Validate() error {
 	if cfg.App.Port < 1 || cfg.App.Port > 65535 {
 		return fmt.Errorf("APP_PORT must be between 1 and 65535")
 	}
+	if cfg.App.BatchMaxRequests < 1 {
+		return fmt.Errorf("BATCH_MAX_REQUESTS must be at least 1")
+	}
+	if cfg.App.WSMaxConnectionsPerUser < 0 {
+		return fmt.Errorf("WS_MAX_CONNECTIONS_PER_USER must not be negative")
+	}
+	if cfg.App.RequestTimeout < 0 {
+		return fmt.Errorf("APP_REQUEST_TIMEOUT must not be negative")
+	}
+	if cfg.App.ShutdownTimeout < 1 {
+		return fmt.Errorf("APP_SHUTDOWN_TIMEOUT must be at least 1 second")
+	}
+	if cfg.App.ShutdownDelay < 0 {
+		return fmt.Errorf("APP_SHUTDOWN_DELAY must not be negative")
+	}
+	if _, err := parseRouteBodyLimits(cfg.App.RouteBodyLimits); err != nil {
+		return fmt.Errorf("APP_ROUTE_BODY_LIMITS: %w", err)
+	}
+	if _, err := parseRouteTimeouts(cfg.App.RouteTimeouts); err != nil {
+		return fmt.Errorf("APP_ROUTE_TIMEOUTS: %w", err)
+	}
 	if cfg.JWT.Secret == "" || cfg.JWT.Secret == "secret" {
 		if cfg.App.Env != "local" && cfg.App.Env != "test" {
 			return fmt.Errorf("JWT_SECRET must be set to a secure value in %s environment", cfg.App.Env)
@@ -185,41 +949,217 @@ func (cfg *Config) Validate() error {
 	if cfg.JWT.ExpireHour < 1 {
 		return fmt.Errorf("JWT_EXPIRE_HOUR must be at least 1")
 	}
+	if cfg.JWT.ImpersonateMins < 1 {
+		return fmt.Errorf("JWT_IMPERSONATE_EXPIRE_MINUTES must be at least 1")
+	}
+	if cfg.JWT.Issuer == "" || cfg.JWT.Audience == "" {
+		return fmt.Errorf("JWT_ISSUER and JWT_AUDIENCE must not be empty")
+	}
+	if cfg.JWT.GuestExpireHour < 1 {
+		return fmt.Errorf("JWT_GUEST_EXPIRE_HOUR must be at least 1")
+	}
+	if cfg.Auth.IPMaxFailedLogins < 1 {
+		return fmt.Errorf("AUTH_IP_MAX_FAILED_LOGINS must be at least 1")
+	}
+	if cfg.Auth.IPFailureWindow < 1 || cfg.Auth.IPMaxBackoff < 1 {
+		return fmt.Errorf("AUTH_IP_FAILURE_WINDOW and AUTH_IP_MAX_BACKOFF must be at least 1 second")
+	}
+	if cfg.Password.MinLength < 1 {
+		return fmt.Errorf("PASSWORD_MIN_LENGTH must be at least 1")
+	}
+	if cfg.Password.MaxLength > 72 {
+		return fmt.Errorf("PASSWORD_MAX_LENGTH must be at most 72 (bcrypt limit)")
+	}
+	if cfg.Password.MinLength > cfg.Password.MaxLength {
+		return fmt.Errorf("PASSWORD_MIN_LENGTH must not exceed PASSWORD_MAX_LENGTH")
+	}
+	if cfg.App.DeletionGraceDays < 0 {
+		return fmt.Errorf("ACCOUNT_DELETION_GRACE_DAYS must not be negative")
+	}
+	if cfg.App.UserRetentionDays < 0 {
+		return fmt.Errorf("USER_RETENTION_DAYS must not be negative")
+	}
+	if cfg.App.PurgeInterval < 1 {
+		return fmt.Errorf("ACCOUNT_PURGE_INTERVAL must be at least 1 second")
+	}
+	if cfg.App.DataExportTTLHours < 1 {
+		return fmt.Errorf("DATA_EXPORT_TTL_HOURS must be at least 1 hour")
+	}
+	if cfg.App.ResponseCacheTTL < 0 {
+		return fmt.Errorf("RESPONSE_CACHE_TTL must not be negative")
+	}
+	if cfg.App.IdempotencyTTLHours < 1 {
+		return fmt.Errorf("IDEMPOTENCY_TTL_HOURS must be at least 1 hour")
+	}
+	if cfg.App.InviteTTLHours < 1 {
+		return fmt.Errorf("INVITE_TTL_HOURS must be at least 1 hour")
+	}
+	if err := cfg.Email.validate(); err != nil {
+		return err
+	}
+	if cfg.Email.BroadcastBatchSize < 1 {
+		return fmt.Errorf("EMAIL_BROADCAST_BATCH_SIZE must be at least 1")
+	}
+	if cfg.Email.BroadcastRate < 1 {
+		return fmt.Errorf("EMAIL_BROADCAST_RATE must be at least 1 email per second")
+	}
+	if cfg.Email.OutboxPollInterval < 1 || cfg.Email.OutboxBatchSize < 1 || cfg.Email.OutboxMaxAttempts < 1 || cfg.Email.OutboxRetentionDays < 1 {
+		return fmt.Errorf("EMAIL_OUTBOX_POLL_INTERVAL, EMAIL_OUTBOX_BATCH_SIZE, EMAIL_OUTBOX_MAX_ATTEMPTS and EMAIL_OUTBOX_RETENTION_DAYS must be at least 1")
+	}
+	switch cfg.App.CompressionLevel {
+	case "off", "speed", "default", "best":
+	default:
+		return fmt.Errorf("APP_COMPRESSION_LEVEL must be one of: off, speed, default, best (got %q)", cfg.App.CompressionLevel)
+	}
+	switch cfg.App.ErrorFormat {
+	case "default", "problem":
+	default:
+		return fmt.Errorf("APP_ERROR_FORMAT must be one of: default, problem (got %q)", cfg.App.ErrorFormat)
+	}
+	if cfg.App.ErrorTypeBaseURL != "" {
+		if u, err := url.Parse(cfg.App.ErrorTypeBaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("APP_ERROR_TYPE_BASE_URL must be an absolute URL")
+		}
+	}
+	if cfg.App.CompressionMinSize < 0 {
+		return fmt.Errorf("APP_COMPRESSION_MIN_SIZE must not be negative")
+	}
+	if cfg.App.BodyLogMaxSize < 1 {
+		return fmt.Errorf("BODY_LOG_MAX_SIZE must be at least 1 byte")
+	}
 	if cfg.App.BodyLimit < 1 {
 		return fmt.Errorf("APP_BODY_LIMIT must be at least 1 byte")
 	}
-	if cfg.RateLimit.StrictMax < 1 || cfg.RateLimit.NormalMax < 1 || cfg.RateLimit.RelaxedMax < 1 {
-		return fmt.Errorf("all RATE_LIMIT_*_MAX values must be at least 1")
+	if err := cfg.RateLimit.validate(); err != nil {
+		return err
 	}
-	if cfg.RateLimit.StrictWindow < 1 || cfg.RateLimit.NormalWindow < 1 || cfg.RateLimit.RelaxedWindow < 1 {
-		return fmt.Errorf("all RATE_LIMIT_*_WINDOW_SECS values must be at least 1")
+	if err := cfg.Cache.validate(); err != nil {
+		return err
+	}
+	if err := cfg.IPFilter.validate(); err != nil {
+		return err
+	}
+	if err := cfg.API.validate(); err != nil {
+		return err
+	}
+	if err := cfg.TLS.validate(cfg.App.Port); err != nil {
+		return err
+	}
+	for _, proxy := range cfg.App.TrustedProxyList() {
+		if _, err := ParsePrefixes(proxy); err != nil {
+			return fmt.Errorf("APP_TRUSTED_PROXIES: %w", err)
+		}
+	}
+	if len(cfg.App.TrustedProxyList()) > 0 && strings.TrimSpace(cfg.App.ProxyHeader) == "" {
+		return fmt.Errorf("APP_PROXY_HEADER must not be empty when APP_TRUSTED_PROXIES is set")
+	}
+	if cfg.Telemetry.SampleRatio < 0 || cfg.Telemetry.SampleRatio > 1 {
+		return fmt.Errorf("TRACING_SAMPLE_RATIO must be between 0 and 1")
+	}
+	switch cfg.Reporter.Driver {
+	case "none":
+	case "sentry":
+		if cfg.Reporter.DSN == "" {
+			return fmt.Errorf("ERROR_REPORTER_DSN is required for sentry driver")
+		}
+	default:
+		return fmt.Errorf("ERROR_REPORTER_DRIVER must be one of: none, sentry (got %q)", cfg.Reporter.Driver)
+	}
+	if cfg.Reporter.SampleRate <= 0 || cfg.Reporter.SampleRate > 1 {
+		return fmt.Errorf("ERROR_REPORTER_SAMPLE_RATE must be greater than 0 and at most 1")
 	}
 	if cfg.Storage.MaxFileSize < 1 {
 		return fmt.Errorf("STORAGE_MAX_FILE_SIZE must be at least 1 byte")
 	}
+	if cfg.Storage.MaxResumableFileSize < 1 {
+		return fmt.Errorf("STORAGE_MAX_RESUMABLE_FILE_SIZE must be at least 1 byte")
+	}
+	if cfg.Storage.UploadSessionTTLHours < 1 {
+		return fmt.Errorf("STORAGE_UPLOAD_SESSION_TTL_HOURS must be at least 1 hour")
+	}
+	if cfg.Storage.ShareDefaultTTLHours < 1 {
+		return fmt.Errorf("STORAGE_SHARE_DEFAULT_TTL_HOURS must be at least 1 hour")
+	}
+	if cfg.Storage.ShareMaxTTLHours < cfg.Storage.ShareDefaultTTLHours {
+		return fmt.Errorf("STORAGE_SHARE_MAX_TTL_HOURS must not be less than STORAGE_SHARE_DEFAULT_TTL_HOURS")
+	}
+	if cfg.Storage.FileRetentionDays < 0 {
+		return fmt.Errorf("STORAGE_FILE_RETENTION_DAYS must not be negative")
+	}
 	if cfg.OAuth.GoogleClientID != "" && cfg.OAuth.GoogleClientSecret == "" {
 		return fmt.Errorf("GOOGLE_CLIENT_SECRET is required when GOOGLE_CLIENT_ID is set")
 	}
-	switch cfg.Storage.Driver {
+	if cfg.OAuth.GitHubClientID != "" && cfg.OAuth.GitHubClientSecret == "" {
+		return fmt.Errorf("GITHUB_CLIENT_SECRET is required when GITHUB_CLIENT_ID is set")
+	}
+	if cfg.WebAuthn.RPID != "" && len(cfg.WebAuthn.Origins()) == 0 {
+		return fmt.Errorf("WEBAUTHN_RP_ORIGINS is required when WEBAUTHN_RP_ID is set")
+	}
+	if cfg.SAML.IDPMetadataURL != "" && (cfg.SAML.SPCertFile == "" || cfg.SAML.SPKeyFile == "") {
+		return fmt.Errorf("SAML_SP_CERT_FILE and SAML_SP_KEY_FILE are required when SAML_IDP_METADATA_URL is set")
+	}
+	return cfg.Storage.validateBackend()
+}
+
+// validateBackend checks the settings that pick where and how objects are stored: the
+// driver and its connection settings, the layout and the encryption keys.
+func (s StorageConfig) validateBackend() error {
+	if s.Layout != "user" && s.Layout != "content" {
+		return fmt.Errorf("STORAGE_LAYOUT must be one of: user, content (got %q)", s.Layout)
+	}
+	switch s.Driver {
 	case "local":
-		if cfg.Storage.LocalPath == "" {
+		if s.LocalPath == "" {
 			return fmt.Errorf("STORAGE_LOCAL_PATH is required for local driver")
 		}
 	case "s3", "minio":
-		if cfg.Storage.S3Endpoint == "" {
-			return fmt.Errorf("STORAGE_S3_ENDPOINT is required for %s driver", cfg.Storage.Driver)
+		if s.S3Endpoint == "" {
+			return fmt.Errorf("STORAGE_S3_ENDPOINT is required for %s driver", s.Driver)
+		}
+		if s.S3AccessKey == "" {
+			return fmt.Errorf("STORAGE_S3_ACCESS_KEY is required for %s driver", s.Driver)
+		}
+		if s.S3SecretKey == "" {
+			return fmt.Errorf("STORAGE_S3_SECRET_KEY is required for %s driver", s.Driver)
 		}
-		if cfg.Storage.S3AccessKey == "" {
-			return fmt.Errorf("STORAGE_S3_ACCESS_KEY is required for %s driver", cfg.Storage.Driver)
+		if s.S3Bucket == "" {
+			return fmt.Errorf("STORAGE_S3_BUCKET is required for %s driver", s.Driver)
 		}
-		if cfg.Storage.S3SecretKey == "" {
-			return fmt.Errorf("STORAGE_S3_SECRET_KEY is required for %s driver", cfg.Storage.Driver)
+	case "gcs":
+		if s.GCSBucket == "" {
+			return fmt.Errorf("STORAGE_GCS_BUCKET is required for gcs driver")
 		}
-		if cfg.Storage.S3Bucket == "" {
-			return fmt.Errorf("STORAGE_S3_BUCKET is required for %s driver", cfg.Storage.Driver)
+	case "azure":
+		if s.AzureAccountName == "" {
+			return fmt.Errorf("STORAGE_AZURE_ACCOUNT_NAME is required for azure driver")
+		}
+		if s.AzureAccountKey == "" {
+			return fmt.Errorf("STORAGE_AZURE_ACCOUNT_KEY is required for azure driver")
+		}
+		if s.AzureContainer == "" {
+			return fmt.Errorf("STORAGE_AZURE_CONTAINER is required for azure driver")
 		}
 	default:
-		return fmt.Errorf("STORAGE_DRIVER must be one of: local, s3, minio (got %q)", cfg.Storage.Driver)
+		return fmt.Errorf("STORAGE_DRIVER must be one of: local, s3, minio, gcs, azure (got %q)", s.Driver)
+	}
+	if s.EncryptionKeys != "" {
+		if _, _, err := s.EncryptionKeySet(); err != nil {
+			return err
+		}
+	} else if s.EncryptionKeyID != "" {
+		return fmt.Errorf("STORAGE_ENCRYPTION_KEYS is required when STORAGE_ENCRYPTION_KEY_ID is set")
+	}
+	if s.CDNBaseURL != "" {
+		u, err := url.Parse(s.CDNBaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" {
+			return fmt.Errorf("STORAGE_CDN_BASE_URL must be an absolute http(s) URL without a query (got %q)", s.CDNBaseURL)
+		}
+		if s.EncryptionKeys != "" {
+			return fmt.Errorf("STORAGE_CDN_BASE_URL cannot be used with STORAGE_ENCRYPTION_KEYS: the CDN would serve encrypted objects")
+		}
+		if s.CDNURLTTLMinutes < 1 {
+			return fmt.Errorf("STORAGE_CDN_URL_TTL_MINUTES must be at least 1 minute")
+		}
 	}
 	return nil
 }
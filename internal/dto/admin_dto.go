@@ -1,16 +1,223 @@
 package dto
 
+import "time"
+
+// Error codes of the rules that keep admins from locking themselves out and keep at least
+// one active admin account.
+const (
+	ErrorCodeSelfDemotion = "SELF_DEMOTION"
+	ErrorCodeSelfBan      = "SELF_BAN"
+	ErrorCodeLastAdmin    = "LAST_ADMIN"
+)
+
+// BanUserRequest is the optional body of a ban. The reason is shown to the user when they
+// try to sign in.
+type BanUserRequest struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
+
 type UpdateRoleRequest struct {
 	Role string `json:"role" validate:"required,oneof=user admin"`
 }
 
 type AdminStatsResponse struct {
-	ActiveUsers   int64 `json:"active_users"`
+	ActiveUsers   int64 `json:"active_users"` // neither banned nor deleted
+	BannedUsers   int64 `json:"banned_users"`
 	DeletedUsers  int64 `json:"deleted_users"`
 	TotalFiles    int64 `json:"total_files"`
 	TotalFileSize int64 `json:"total_file_size"`
 }
 
+// AdminStatsSeriesQuery selects the window of the daily statistics, ending today (UTC).
+type AdminStatsSeriesQuery struct {
+	Days int `query:"days" validate:"omitempty,min=1,max=365"`
+}
+
+// AdminDailyStats holds one UTC day of activity. StorageBytes is the size of the files
+// stored at the end of the day, so consecutive days chart storage growth.
+type AdminDailyStats struct {
+	Date          string `json:"date" example:"2025-01-31"`
+	Signups       int64  `json:"signups"`
+	ActiveUsers   int64  `json:"active_users"` // distinct users with a successful sign-in
+	Uploads       int64  `json:"uploads"`
+	UploadedBytes int64  `json:"uploaded_bytes"`
+	StorageBytes  int64  `json:"storage_bytes"`
+}
+
+type AdminStatsSeriesResponse struct {
+	From string            `json:"from" example:"2025-01-02"`
+	To   string            `json:"to" example:"2025-01-31"`
+	Days []AdminDailyStats `json:"days"`
+}
+
+// AdminUserDetailResponse is the full account record shown to admins, with the resources
+// linked to it. LinkedProviders lists the external identities the user can sign in with:
+// google, github or saml.
+// AdminUserResponse is a user as admins see them. The ban is kept out of UserResponse,
+// which other users can read.
+type AdminUserResponse struct {
+	UserResponse
+	BannedAt  *time.Time `json:"banned_at,omitempty"`
+	BanReason string     `json:"ban_reason,omitempty"`
+}
+
+type AdminUserDetailResponse struct {
+	AdminUserResponse
+	AuthProvider    string         `json:"auth_provider"`
+	HasPassword     bool           `json:"has_password"`
+	LinkedProviders []string       `json:"linked_providers"`
+	EmailVerifiedAt *time.Time     `json:"email_verified_at,omitempty"`
+	DeletedAt       *time.Time     `json:"deleted_at,omitempty"`
+	ActiveSessions  int64          `json:"active_sessions"`
+	FileCount       int64          `json:"file_count"`
+	FileSize        int64          `json:"file_size"`
+	LastLogin       *LastLoginInfo `json:"last_login,omitempty"`
+}
+
+// Kinds of record an admin search hit refers to.
+const (
+	SearchHitUser = "user"
+	SearchHitFile = "file"
+)
+
+// AdminSearchQuery holds the query params of the admin search. Limit caps the number of
+// hits returned in total.
+type AdminSearchQuery struct {
+	Q     string `query:"q" validate:"required,min=2,max=100"`
+	Type  string `query:"type" validate:"omitempty,oneof=user file"`
+	Limit int    `query:"limit" validate:"omitempty,min=1,max=50"`
+}
+
+// AdminSearchHit is a user or file matching an admin search. Title is the user's name or
+// the file name, Subtitle the user's email or the file owner's; UserID is the user or the
+// file's owner.
+type AdminSearchHit struct {
+	Type      string    `json:"type" enums:"user,file"`
+	ID        int64     `json:"id"`
+	Title     string    `json:"title"`
+	Subtitle  string    `json:"subtitle"`
+	UserID    int64     `json:"user_id"`
+	Role      string    `json:"role,omitempty"`
+	MimeType  string    `json:"mime_type,omitempty"`
+	Size      int64     `json:"size,omitempty"`
+	Deleted   bool      `json:"deleted"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserSessionResponse is one signed-in session of a user: a refresh token family, renewed
+// on every refresh. DeviceBound sessions only refresh with the device fingerprint they
+// were created with.
+type UserSessionResponse struct {
+	ID              string    `json:"id"`
+	StartedAt       time.Time `json:"started_at"`
+	LastRefreshedAt time.Time `json:"last_refreshed_at"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	DeviceBound     bool      `json:"device_bound"`
+}
+
+// LastLoginInfo describes a user's latest successful sign-in.
+type LastLoginInfo struct {
+	At        time.Time `json:"at"`
+	Method    string    `json:"method"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+}
+
+type ImpersonateResponse struct {
+	AccessToken string       `json:"access_token"`
+	ExpiresAt   time.Time    `json:"expires_at"`
+	User        UserResponse `json:"user"`
+}
+
+// Bulk user actions accepted by POST /admin/users/bulk.
+const (
+	BulkActionBan     = "ban"
+	BulkActionUnban   = "unban"
+	BulkActionDelete  = "delete"
+	BulkActionSetRole = "set-role"
+)
+
+type BulkUserActionRequest struct {
+	Action  string  `json:"action" validate:"required,oneof=ban unban delete set-role"`
+	UserIDs []int64 `json:"user_ids" validate:"required,min=1,max=100,unique,dive,gt=0"`
+	Role    string  `json:"role" validate:"required_if=Action set-role,omitempty,oneof=user admin"`
+	Reason  string  `json:"reason" validate:"max=500"` // shown to users banned by the ban action
+}
+
+type BulkUserActionResult struct {
+	UserID  int64  `json:"user_id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+type BulkUserActionResponse struct {
+	Succeeded int                    `json:"succeeded"`
+	Failed    int                    `json:"failed"`
+	Results   []BulkUserActionResult `json:"results"`
+}
+
+// UserImportMaxRows caps the number of rows accepted by POST /admin/users/import.
+const UserImportMaxRows = 1000
+
+// UserImportRow is one account read from an import CSV. Line is its line number in the
+// file, used to report errors.
+type UserImportRow struct {
+	Line  int
+	Email string
+	Name  string
+	Role  string
+}
+
+type UserImportResult struct {
+	Line    int    `json:"line"`
+	Email   string `json:"email"`
+	UserID  int64  `json:"user_id,omitempty"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+type UserImportResponse struct {
+	Succeeded int                `json:"succeeded"`
+	Failed    int                `json:"failed"`
+	Results   []UserImportResult `json:"results"`
+}
+
 type AdminUserQuery struct {
 	PaginationQuery
 }
+
+// UserExportQuery selects the format and filters of a user export. Sort is ignored; exports are ordered by ID.
+type UserExportQuery struct {
+	UserFilterQuery
+	Format string `query:"format" validate:"omitempty,oneof=csv json"`
+}
+
+type CreateRoleRequest struct {
+	Name        string   `json:"name" validate:"required,min=2,max=50,slug"`
+	Description string   `json:"description" validate:"max=255"`
+	Permissions []string `json:"permissions" validate:"required,min=1,dive,required"`
+}
+
+type UpdateUserRolesRequest struct {
+	Roles []string `json:"roles" validate:"dive,required"`
+}
+
+type RoleResponse struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	System      bool      `json:"system"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type PermissionResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type UserRolesResponse struct {
+	UserID      int64    `json:"user_id"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
@@ -0,0 +1,72 @@
+package dto
+
+import "time"
+
+// Actions recorded in the admin audit log.
+const (
+	AuditUserRoleChanged      = "user.role_changed"
+	AuditUserBanned           = "user.banned"
+	AuditUserUnbanned         = "user.unbanned"
+	AuditUserDeleted          = "user.deleted"
+	AuditUserRestored         = "user.restored"
+	AuditUserImpersonated     = "user.impersonated"
+	AuditUserSessionsRevoked  = "user.sessions_revoked"
+	AuditUserRolesAssigned    = "user.roles_assigned"
+	AuditUserEmailVerified    = "user.email_verified"
+	AuditUserVerificationSent = "user.verification_sent"
+	AuditUserImported         = "user.imported"
+	AuditFileDeleted          = "file.deleted"
+	AuditFileRestored         = "file.restored"
+	AuditFilePurged           = "file.purged"
+	AuditFileDownloaded       = "file.downloaded"
+	AuditFlagCreated          = "flag.created"
+	AuditFlagUpdated          = "flag.updated"
+	AuditFlagDeleted          = "flag.deleted"
+	AuditBroadcastSent        = "broadcast.sent"
+	AuditSettingUpdated       = "setting.updated"
+	AuditEmailRetried         = "email.retried"
+	AuditEmailUnsuppressed    = "email.unsuppressed"
+	AuditRoleCreated          = "role.created"
+	AuditRoleDeleted          = "role.deleted"
+	AuditInvitationCreated    = "invitation.created"
+	AuditInvitationRevoked    = "invitation.revoked"
+	AuditStorageCleaned       = "storage.cleaned"
+)
+
+// Kinds of record an audit log entry targets.
+const (
+	AuditTargetUser             = "user"
+	AuditTargetFile             = "file"
+	AuditTargetFlag             = "flag"
+	AuditTargetBroadcast        = "broadcast"
+	AuditTargetSetting          = "setting"
+	AuditTargetEmail            = "email"
+	AuditTargetEmailSuppression = "email_suppression"
+	AuditTargetRole             = "role"
+	AuditTargetInvitation       = "invitation"
+	AuditTargetStorage          = "storage"
+)
+
+// AuditLogQuery holds the filter query params of the audit log listing.
+// Timestamps are RFC 3339.
+type AuditLogQuery struct {
+	ActorID       int64  `query:"actor_id" validate:"omitempty,min=1"`
+	Action        string `query:"action" validate:"omitempty,max=50"`
+	TargetType    string `query:"target_type" validate:"omitempty,oneof=user file flag broadcast setting email email_suppression role invitation storage"`
+	TargetID      int64  `query:"target_id" validate:"omitempty,min=1"`
+	CreatedAfter  string `query:"created_after" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	CreatedBefore string `query:"created_before" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+}
+
+type AuditLogResponse struct {
+	ID         int64          `json:"id"`
+	ActorID    int64          `json:"actor_id"`
+	Action     string         `json:"action"`
+	TargetType string         `json:"target_type"`
+	TargetID   int64          `json:"target_id"`
+	Before     map[string]any `json:"before,omitempty"`
+	After      map[string]any `json:"after,omitempty"`
+	IPAddress  string         `json:"ip_address"`
+	RequestID  string         `json:"request_id"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
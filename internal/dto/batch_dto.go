@@ -0,0 +1,24 @@
+package dto
+
+import "encoding/json"
+
+// BatchRequest holds sub-requests run one after another by POST /batch.
+type BatchRequest struct {
+	Requests []BatchItem `json:"requests" validate:"required,min=1,dive"`
+}
+
+// BatchItem is a request to an API route, such as GET /api/v1/users/me. It is sent with
+// the headers of the batch request, including its Authorization header and cookies.
+type BatchItem struct {
+	Method string          `json:"method" validate:"required,oneof=GET POST PUT PATCH DELETE"`
+	Path   string          `json:"path" validate:"required,startswith=/api/"`
+	Body   json.RawMessage `json:"body,omitempty" swaggertype:"object"`
+}
+
+// BatchResult is the response to a BatchItem. Body holds the JSON response, and is left
+// out for responses of another type, such as file downloads, and for requests that were
+// not run because the batch timed out, which have status 503.
+type BatchResult struct {
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty" swaggertype:"object"`
+}
@@ -0,0 +1,43 @@
+package dto
+
+import "time"
+
+// Broadcast statuses.
+const (
+	BroadcastSending   = "sending"
+	BroadcastCompleted = "completed"
+	BroadcastFailed    = "failed"
+)
+
+// BroadcastRequest is an announcement email. Subject and body are Go templates rendered per
+// recipient with {{.Name}} and {{.Email}}; the body is HTML and values in it are escaped.
+type BroadcastRequest struct {
+	Subject string           `json:"subject" validate:"required,max=200"`
+	Body    string           `json:"body" validate:"required,max=100000"`
+	Segment BroadcastSegment `json:"segment"`
+}
+
+// BroadcastSegment narrows the recipients of a broadcast. Banned and guest users never
+// receive broadcasts; an empty segment selects every other user.
+type BroadcastSegment struct {
+	Search          string `json:"search,omitempty" validate:"omitempty,max=100"`
+	Role            string `json:"role,omitempty" validate:"omitempty,max=50"`
+	EmailVerified   *bool  `json:"email_verified,omitempty"`
+	CreatedAfter    string `json:"created_after,omitempty" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	CreatedBefore   string `json:"created_before,omitempty" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	SubscribersOnly bool   `json:"subscribers_only,omitempty"` // only users with email_product_updates on
+}
+
+type BroadcastResponse struct {
+	ID             int64            `json:"id"`
+	SenderID       int64            `json:"sender_id"`
+	Subject        string           `json:"subject"`
+	Body           string           `json:"body"`
+	Segment        BroadcastSegment `json:"segment"`
+	Status         string           `json:"status"`
+	RecipientCount int32            `json:"recipient_count"`
+	SentCount      int32            `json:"sent_count"`
+	FailedCount    int32            `json:"failed_count"`
+	CreatedAt      time.Time        `json:"created_at"`
+	CompletedAt    *time.Time       `json:"completed_at,omitempty"`
+}
@@ -0,0 +1,21 @@
+package dto
+
+import "time"
+
+// Data export statuses. DataExportExpired is reported for ready archives past their
+// expiry and is never stored.
+const (
+	DataExportPending = "pending"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+	DataExportExpired = "expired"
+)
+
+type DataExportResponse struct {
+	ID          int64      `json:"id"`
+	Status      string     `json:"status"`
+	Size        int64      `json:"size,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
@@ -0,0 +1,30 @@
+package dto
+
+import "time"
+
+// Email outbox statuses.
+const (
+	EmailPending = "pending"
+	EmailSending = "sending"
+	EmailSent    = "sent"
+	EmailDead    = "dead" // failed permanently or ran out of attempts
+)
+
+// EmailOutboxQuery filters the queued email listing.
+type EmailOutboxQuery struct {
+	Status string `query:"status" validate:"omitempty,oneof=pending sending sent dead"`
+}
+
+// EmailOutboxResponse is the delivery state of a queued email. Bodies are never returned,
+// as they may carry password reset and verification links.
+type EmailOutboxResponse struct {
+	ID            int64      `json:"id"`
+	Recipients    []string   `json:"recipients"`
+	Subject       string     `json:"subject"`
+	Status        string     `json:"status"`
+	Attempts      int32      `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+}
@@ -0,0 +1,25 @@
+package dto
+
+import "time"
+
+// EmailSuppressionQuery filters the suppression list.
+type EmailSuppressionQuery struct {
+	Reason string `query:"reason" validate:"omitempty,oneof=bounce complaint"`
+	Search string `query:"search" validate:"omitempty,max=255"`
+}
+
+// EmailSuppressionResponse is an address nothing is sent to, with the provider's report.
+type EmailSuppressionResponse struct {
+	ID        int64     `json:"id"`
+	Email     string    `json:"email"`
+	Reason    string    `json:"reason"`
+	Provider  string    `json:"provider"`
+	Detail    string    `json:"detail,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// EmailWebhookResponse reports how many addresses a provider webhook suppressed.
+type EmailWebhookResponse struct {
+	Suppressed int `json:"suppressed"`
+}
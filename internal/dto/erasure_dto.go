@@ -0,0 +1,23 @@
+package dto
+
+import "time"
+
+// Erasure sources recorded on audit entries.
+const (
+	ErasureSourceSelf  = "self"
+	ErasureSourceAdmin = "admin"
+)
+
+// EraseAccountRequest confirms a self-service erasure by repeating the account's email address.
+type EraseAccountRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ErasureResponse struct {
+	ID           int64     `json:"id"`
+	UserID       int64     `json:"user_id"`
+	ActorID      int64     `json:"actor_id"`
+	Source       string    `json:"source"`
+	FilesDeleted int32     `json:"files_deleted"`
+	CreatedAt    time.Time `json:"created_at"`
+}
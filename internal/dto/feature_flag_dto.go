@@ -0,0 +1,30 @@
+package dto
+
+import "time"
+
+type CreateFeatureFlagRequest struct {
+	Name        string `json:"name" validate:"required,min=2,max=100,slug"`
+	Description string `json:"description" validate:"max=255"`
+	Enabled     bool   `json:"enabled"`
+}
+
+// UpdateFeatureFlagRequest changes the fields that are present; the name is fixed.
+type UpdateFeatureFlagRequest struct {
+	Description *string `json:"description" validate:"omitempty,max=255"`
+	Enabled     *bool   `json:"enabled"`
+}
+
+type FeatureFlagResponse struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Enabled     bool      `json:"enabled"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// FeaturesResponse maps every feature flag's name to whether it is on, for clients to
+// toggle UI with. Flags that are not listed are off.
+type FeaturesResponse struct {
+	Features map[string]bool `json:"features"`
+}
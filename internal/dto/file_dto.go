@@ -2,11 +2,153 @@ package dto
 
 import "time"
 
+// File visibility: public files can be read by any signed-in user, private files only by
+// their owner and users granted access.
+const (
+	FileVisibilityPrivate = "private"
+	FileVisibilityPublic  = "public"
+)
+
 type FileResponse struct {
 	ID           int64     `json:"id"`
 	OriginalName string    `json:"original_name"`
 	MimeType     string    `json:"mime_type"`
 	Size         int64     `json:"size"`
 	URL          string    `json:"url"`
+	Visibility   string    `json:"visibility"`
+	Checksum     string    `json:"checksum,omitempty"`  // hex SHA-256; absent for files uploaded before checksums were recorded
+	FolderID     *int64    `json:"folder_id,omitempty"` // absent when the file is in no folder
+	Tags         []string  `json:"tags,omitempty"`
+	Version      int32     `json:"version"` // starts at 1 and grows each time the content is replaced
 	CreatedAt    time.Time `json:"created_at"`
+	// DeletedAt is set on soft-deleted files, which only admins see. They can be restored
+	// until the retention period has passed.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// Variants are resized copies of an image keyed by variant name. They are rendered
+	// in the background, so they are absent right after upload.
+	Variants map[string]FileVariantResponse `json:"variants,omitempty"`
+}
+
+type FileVariantResponse struct {
+	URL      string `json:"url"`
+	MimeType string `json:"mime_type"`
+	Width    int32  `json:"width"`
+	Height   int32  `json:"height"`
+	Size     int64  `json:"size"`
+}
+
+// FileVersionResponse describes one version of a file's content. The current version is
+// what the file itself downloads; older ones can be downloaded or restored.
+type FileVersionResponse struct {
+	Version   int32     `json:"version"`
+	MimeType  string    `json:"mime_type"`
+	Size      int64     `json:"size"`
+	Checksum  string    `json:"checksum,omitempty"`
+	Current   bool      `json:"current"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FileListQuery narrows a file listing to one folder or tag; folder_id=0 selects files
+// outside any folder and omitting it lists every file.
+type FileListQuery struct {
+	FolderID *int64 `query:"folder_id" validate:"omitempty,min=0"`
+	Tag      string `query:"tag" validate:"omitempty,max=50"`
+}
+
+// AdminFileListQuery narrows the admin file listing to one tag.
+type AdminFileListQuery struct {
+	Tag string `query:"tag" validate:"omitempty,max=50"`
+}
+
+// SetFileTagsRequest replaces a file's tags; an empty list removes them all.
+type SetFileTagsRequest struct {
+	Tags []string `json:"tags" validate:"max=20,dive,max=50"`
+}
+
+// DownloadZipRequest lists the files to bundle into one ZIP archive.
+type DownloadZipRequest struct {
+	FileIDs []int64 `json:"file_ids" validate:"required,min=1,max=100,dive,min=1"`
+}
+
+type RenameFileRequest struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+// MoveFileRequest moves a file into a folder; a null folder_id takes it out of folders.
+type MoveFileRequest struct {
+	FolderID *int64 `json:"folder_id" validate:"omitempty,min=1"`
+}
+
+type UpdateFileVisibilityRequest struct {
+	Visibility string `json:"visibility" validate:"required,oneof=private public"`
+}
+
+// FilePermissionResponse is a user who was granted read access to a file.
+type FilePermissionResponse struct {
+	UserID    int64     `json:"user_id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	GrantedBy *int64    `json:"granted_by,omitempty"` // absent if that account is gone
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateUploadSessionRequest struct {
+	Filename string `json:"filename" validate:"required,max=255"`
+	Size     int64  `json:"size" validate:"required,min=1"`
+}
+
+// UploadSessionResponse reports the progress of a resumable upload. Offset is where the
+// next chunk must start; the upload can be finalized once it equals Size.
+type UploadSessionResponse struct {
+	ID        int64     `json:"id"`
+	Filename  string    `json:"filename"`
+	Size      int64     `json:"size"`
+	Offset    int64     `json:"offset"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	// ProgressURL can be polled without authentication until the upload expires.
+	ProgressURL   string `json:"progress_url"`
+	ProgressToken string `json:"progress_token"`
+}
+
+// Resumable upload progress statuses.
+const (
+	UploadReceiving  = "receiving"
+	UploadAssembling = "assembling"
+	UploadCompleted  = "completed"
+)
+
+// UploadProgressResponse reports the bytes written to storage so far. While receiving it
+// counts the chunks stored plus the one still being written; while assembling it counts
+// the bytes of the final file written. FileID is set once the upload has completed.
+type UploadProgressResponse struct {
+	ID           int64     `json:"id"`
+	Status       string    `json:"status"`
+	Size         int64     `json:"size"`
+	BytesWritten int64     `json:"bytes_written"`
+	Percent      float64   `json:"percent"`
+	FileID       *int64    `json:"file_id,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// CreateFileShareRequest configures a public download link. TTLHours defaults to
+// STORAGE_SHARE_DEFAULT_TTL_HOURS; leaving MaxDownloads unset allows unlimited downloads.
+type CreateFileShareRequest struct {
+	TTLHours     int    `json:"ttl_hours" validate:"omitempty,min=1"`
+	MaxDownloads *int32 `json:"max_downloads" validate:"omitempty,min=1"`
+	Password     string `json:"password" validate:"omitempty,min=4,max=72"`
+}
+
+// FileShareResponse describes a public download link. Token and URL are only returned
+// when the link is created.
+type FileShareResponse struct {
+	ID                int64     `json:"id"`
+	FileID            int64     `json:"file_id"`
+	Token             string    `json:"token,omitempty"`
+	URL               string    `json:"url,omitempty"`
+	PasswordProtected bool      `json:"password_protected"`
+	MaxDownloads      *int32    `json:"max_downloads,omitempty"`
+	Downloads         int32     `json:"downloads"`
+	ExpiresAt         time.Time `json:"expires_at"`
+	CreatedAt         time.Time `json:"created_at"`
 }
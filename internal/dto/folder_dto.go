@@ -0,0 +1,32 @@
+package dto
+
+import "time"
+
+type CreateFolderRequest struct {
+	Name     string `json:"name" validate:"required,max=255"`
+	ParentID *int64 `json:"parent_id" validate:"omitempty,min=1"` // omit to create a top-level folder
+}
+
+type RenameFolderRequest struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+// MoveFolderRequest moves a folder inside another one; a null parent_id moves it to the
+// top level.
+type MoveFolderRequest struct {
+	ParentID *int64 `json:"parent_id" validate:"omitempty,min=1"`
+}
+
+type FolderResponse struct {
+	ID        int64     `json:"id"`
+	ParentID  *int64    `json:"parent_id,omitempty"` // absent for top-level folders
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FolderListQuery selects the folder whose subfolders are listed; omit parent_id for the
+// top level.
+type FolderListQuery struct {
+	ParentID *int64 `query:"parent_id" validate:"omitempty,min=1"`
+}
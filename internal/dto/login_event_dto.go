@@ -0,0 +1,19 @@
+package dto
+
+import "time"
+
+// Login methods recorded in the login history. OAuth logins use the provider name.
+const (
+	LoginMethodPassword = "password"
+	LoginMethodPasskey  = "passkey"
+)
+
+type LoginEventResponse struct {
+	ID            int64     `json:"id"`
+	Method        string    `json:"method"`
+	Success       bool      `json:"success"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	IPAddress     string    `json:"ip_address"`
+	UserAgent     string    `json:"user_agent"`
+	CreatedAt     time.Time `json:"created_at"`
+}
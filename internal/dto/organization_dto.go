@@ -0,0 +1,56 @@
+package dto
+
+import "time"
+
+// Roles a user can hold within an organization.
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name" validate:"required,min=2,max=255"`
+	Slug string `json:"slug" validate:"required,min=2,max=100,slug"`
+}
+
+type UpdateOrganizationRequest struct {
+	Name string `json:"name" validate:"required,min=2,max=255"`
+}
+
+type InviteMemberRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required,oneof=admin member"`
+}
+
+type UpdateMemberRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// OrganizationResponse includes the role of the requesting user in the organization.
+type OrganizationResponse struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type OrganizationMemberResponse struct {
+	UserID   int64     `json:"user_id"`
+	Name     string    `json:"name"`
+	Email    string    `json:"email"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+type OrganizationInvitationResponse struct {
+	ID        int64     `json:"id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
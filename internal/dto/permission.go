@@ -0,0 +1,17 @@
+package dto
+
+// Permissions granted through roles and enforced per route by middleware.RequirePermission.
+// The catalog is seeded by migration; custom roles combine existing permissions.
+const (
+	PermissionStatsRead        = "stats:read"
+	PermissionUsersList        = "users:list"
+	PermissionUsersManage      = "users:manage"
+	PermissionUsersImpersonate = "users:impersonate"
+	PermissionFilesManage      = "files:manage"
+	PermissionRolesManage      = "roles:manage"
+	PermissionAuditRead        = "audit:read"
+	PermissionFlagsManage      = "flags:manage"
+	PermissionBroadcastsSend   = "broadcasts:send"
+	PermissionSettingsManage   = "settings:manage"
+	PermissionEmailsManage     = "emails:manage"
+)
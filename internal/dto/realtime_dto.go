@@ -0,0 +1,24 @@
+package dto
+
+import "time"
+
+// RealtimeTicketResponse holds a ticket opening a WebSocket connection, passed as
+// GET /ws?ticket=... It can be used once, before ExpiresAt.
+type RealtimeTicketResponse struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Types of the events pushed over the WebSocket gateway.
+const (
+	// A resumable upload was finalized; the data is the FileResponse
+	RealtimeEventFileUploaded = "file.uploaded"
+	// An admin broadcast was sent to the user; the data is a BroadcastEvent
+	RealtimeEventBroadcast = "broadcast"
+)
+
+// BroadcastEvent tells a connected user about a broadcast emailed to them.
+type BroadcastEvent struct {
+	ID      int64  `json:"id"`
+	Subject string `json:"subject"`
+}
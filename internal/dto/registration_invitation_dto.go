@@ -0,0 +1,17 @@
+package dto
+
+import "time"
+
+type CreateInvitationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// InvitationResponse describes a pending registration invitation. The token itself is
+// only ever sent to the invited address.
+type InvitationResponse struct {
+	ID        int64     `json:"id"`
+	Email     string    `json:"email"`
+	InvitedBy *int64    `json:"invited_by,omitempty"` // absent if that account is gone
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
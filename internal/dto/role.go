@@ -3,4 +3,5 @@ package dto
 const (
 	RoleUser  = "user"
 	RoleAdmin = "admin"
+	RoleGuest = "guest" // anonymous account issued by POST /auth/guest
 )
@@ -0,0 +1,21 @@
+package dto
+
+// Access token scopes enforced per route by middleware.RequireScope.
+const (
+	ScopeUsersRead  = "users:read"
+	ScopeUsersWrite = "users:write"
+	ScopeFilesRead  = "files:read"
+	ScopeFilesWrite = "files:write"
+)
+
+var roleScopes = map[string][]string{
+	RoleUser:  {ScopeUsersRead, ScopeUsersWrite, ScopeFilesRead, ScopeFilesWrite},
+	RoleAdmin: {ScopeUsersRead, ScopeUsersWrite, ScopeFilesRead, ScopeFilesWrite},
+	RoleGuest: {ScopeUsersRead, ScopeFilesRead, ScopeFilesWrite},
+}
+
+// ScopesForRole returns the scopes granted to access tokens issued for role.
+// Unknown roles get no scopes.
+func ScopesForRole(role string) []string {
+	return roleScopes[role]
+}
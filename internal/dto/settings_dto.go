@@ -0,0 +1,16 @@
+package dto
+
+// Settings are the runtime settings admins can change without a restart. Settings that were
+// never changed keep the value configured in the environment.
+type Settings struct {
+	RegistrationEnabled      bool  `json:"registration_enabled"`
+	RequireEmailVerification bool  `json:"require_email_verification"`
+	MaxUploadSize            int64 `json:"max_upload_size"` // bytes
+}
+
+// UpdateSettingsRequest changes the settings that are present. Its JSON names match Settings.
+type UpdateSettingsRequest struct {
+	RegistrationEnabled      *bool  `json:"registration_enabled,omitempty"`
+	RequireEmailVerification *bool  `json:"require_email_verification,omitempty"`
+	MaxUploadSize            *int64 `json:"max_upload_size,omitempty" validate:"omitempty,min=1"`
+}
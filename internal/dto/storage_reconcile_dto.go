@@ -0,0 +1,50 @@
+package dto
+
+import "time"
+
+// Storage reconciliation statuses.
+const (
+	ReconcileRunning   = "running"
+	ReconcileCompleted = "completed"
+	ReconcileFailed    = "failed"
+)
+
+type StorageReconcileRequest struct {
+	// Cleanup deletes the orphaned objects found instead of only reporting them.
+	Cleanup bool `json:"cleanup"`
+}
+
+// StorageReconcileResponse reports a comparison of the storage backend with the database.
+// The orphaned and missing lists are capped; the counts cover every discrepancy found.
+type StorageReconcileResponse struct {
+	Status          string               `json:"status"`
+	Cleanup         bool                 `json:"cleanup"`
+	StartedAt       time.Time            `json:"started_at"`
+	FinishedAt      *time.Time           `json:"finished_at,omitempty"`
+	Error           string               `json:"error,omitempty"`
+	ObjectsScanned  int64                `json:"objects_scanned"`
+	ObjectsSkipped  int64                `json:"objects_skipped"`
+	OrphanedCount   int64                `json:"orphaned_count"`
+	OrphanedBytes   int64                `json:"orphaned_bytes"`
+	DeletedCount    int64                `json:"deleted_count"`
+	MissingCount    int64                `json:"missing_count"`
+	OrphanedObjects []OrphanedObjectInfo `json:"orphaned_objects"`
+	MissingObjects  []MissingObjectInfo  `json:"missing_objects"`
+}
+
+// OrphanedObjectInfo is a stored object no database row refers to.
+type OrphanedObjectInfo struct {
+	Path       string    `json:"path"`
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modified_at"`
+	Deleted    bool      `json:"deleted"`
+}
+
+// MissingObjectInfo is a database row whose stored object does not exist. Kind is one of
+// file, file_version, file_variant, upload_chunk or data_export, and ID identifies the
+// file, upload session or data export.
+type MissingObjectInfo struct {
+	Kind string `json:"kind"`
+	ID   int64  `json:"id"`
+	Path string `json:"path"`
+}
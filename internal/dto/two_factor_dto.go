@@ -0,0 +1,51 @@
+package dto
+
+import "time"
+
+// RecoveryCodeCount is how many recovery codes are issued at a time.
+const RecoveryCodeCount = 10
+
+// ErrorCodeTwoFactorNotEnabled is the error code of two-factor actions on an account that has
+// not enabled two-factor authentication.
+const ErrorCodeTwoFactorNotEnabled = "TWO_FACTOR_NOT_ENABLED"
+
+type TwoFactorSetupResponse struct {
+	Secret string `json:"secret"`
+	// otpauth:// URI for authenticator apps, usually shown as a QR code
+	URI string `json:"otpauth_uri"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+// TwoFactorConfirmRequest proves possession of the second factor with a TOTP code or an
+// unused recovery code.
+type TwoFactorConfirmRequest struct {
+	Code         string `json:"code" validate:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code" validate:"required_without=Code,omitempty,max=32"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	TwoFactorConfirmRequest
+}
+
+// TwoFactorChallengeResponse answers a password login of an account with two-factor
+// authentication, which completes at POST /auth/login/2fa.
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	ChallengeToken    string    `json:"challenge_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// RecoveryCodesResponse carries newly issued recovery codes, which are shown only once.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type RecoveryCodesStatusResponse struct {
+	Enabled   bool       `json:"enabled"`
+	EnabledAt *time.Time `json:"enabled_at,omitempty"`
+	Remaining int64      `json:"remaining"`
+}
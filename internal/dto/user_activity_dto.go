@@ -0,0 +1,24 @@
+package dto
+
+import "time"
+
+// Actions recorded in a user's activity trail.
+const (
+	ActivityProfileUpdated           = "profile.updated"
+	ActivityPasswordChanged          = "password.changed"
+	ActivityRoleChanged              = "role.changed"
+	ActivityFileUploaded             = "file.uploaded"
+	ActivityTwoFactorEnabled         = "two_factor.enabled"
+	ActivityTwoFactorDisabled        = "two_factor.disabled"
+	ActivityRecoveryCodesRegenerated = "recovery_codes.regenerated"
+	ActivityFileShared               = "file.shared"
+)
+
+type UserActivityResponse struct {
+	ID        int64          `json:"id"`
+	Action    string         `json:"action"`
+	ActorID   *int64         `json:"actor_id,omitempty"` // who made the change; absent if that account is gone
+	Details   map[string]any `json:"details,omitempty"`
+	IPAddress string         `json:"ip_address"`
+	CreatedAt time.Time      `json:"created_at"`
+}
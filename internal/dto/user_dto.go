@@ -6,6 +6,13 @@ type RegisterRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,password"`
 	Name     string `json:"name" validate:"required,min=2"`
+	// Username is an optional public handle, stored lowercase.
+	Username string `json:"username,omitempty" validate:"omitempty,username"`
+	// InviteToken is the token from an invitation email, required when INVITE_ONLY is enabled.
+	InviteToken string `json:"invite_token,omitempty"`
+	// Locale is negotiated from the Accept-Language header and becomes the new user's
+	// email language.
+	Locale string `json:"-"`
 }
 
 type LoginRequest struct {
@@ -20,6 +27,26 @@ type RefreshRequest struct {
 type UpdateUserRequest struct {
 	Name  *string `json:"name" validate:"omitempty,min=2"`
 	Email *string `json:"email" validate:"omitempty,email"`
+	// Username sets or changes the public handle; it cannot be removed once set.
+	Username *string `json:"username" validate:"omitempty,username"`
+	// Metadata is merged into the stored profile metadata; keys set to null are removed.
+	Metadata map[string]any `json:"metadata" validate:"omitempty,max=50,metadata"`
+}
+
+// ErrorCodeAccountBanned is returned when a banned user tries to sign in; the details carry
+// the reason given by the admin.
+const ErrorCodeAccountBanned = "ACCOUNT_BANNED"
+
+// UserFilterQuery holds the search, filter and sort query params of the user list endpoints.
+// Timestamps are RFC 3339; sort takes a field name, prefixed with "-" for descending order.
+type UserFilterQuery struct {
+	Search        string `query:"q" validate:"omitempty,max=100"`
+	Role          string `query:"role" validate:"omitempty,max=50"`
+	EmailVerified *bool  `query:"email_verified"`
+	Banned        *bool  `query:"banned"`
+	CreatedAfter  string `query:"created_after" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	CreatedBefore string `query:"created_before" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Sort          string `query:"sort" validate:"omitempty,oneof=id -id name -name email -email created_at -created_at"`
 }
 
 type ChangePasswordRequest struct {
@@ -40,22 +67,58 @@ type VerifyEmailRequest struct {
 	Token string `json:"token" validate:"required"`
 }
 
+type AccountDeletionResponse struct {
+	ScheduledFor time.Time `json:"scheduled_for"`
+}
+
+type CancelAccountDeletionRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
 type ResendVerificationRequest struct {
 	Email string `json:"email" validate:"required,email"`
 }
 
 type UserResponse struct {
-	ID            int64     `json:"id"`
-	Email         string    `json:"email"`
-	Name          string    `json:"name"`
-	Role          string    `json:"role"`
-	EmailVerified bool      `json:"email_verified"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID            int64          `json:"id"`
+	Email         string         `json:"email"`
+	Username      string         `json:"username,omitempty"`
+	Name          string         `json:"name"`
+	Role          string         `json:"role"`
+	EmailVerified bool           `json:"email_verified"`
+	Metadata      map[string]any `json:"metadata,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+
+	// Settings is only included in the user's own profile (GET /users/me).
+	Settings *UserSettingsResponse `json:"settings,omitempty"`
+}
+
+// PublicUserResponse is the profile returned by username lookups; it leaves out the email
+// address and account details.
+type PublicUserResponse struct {
+	ID        int64     `json:"id"`
+	Username  string    `json:"username"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type PasswordPolicyResponse struct {
+	MinLength      int  `json:"min_length"`
+	MaxLength      int  `json:"max_length"`
+	RequireUpper   bool `json:"require_uppercase"`
+	RequireLower   bool `json:"require_lowercase"`
+	RequireDigit   bool `json:"require_digit"`
+	RequireSpecial bool `json:"require_special"`
+	RejectsCommon  bool `json:"rejects_common_passwords"`
 }
 
 type LoginResponse struct {
 	AccessToken  string       `json:"access_token"`
-	RefreshToken string       `json:"refresh_token"`
+	RefreshToken string       `json:"refresh_token,omitempty"` // empty in cookie mode
 	User         UserResponse `json:"user"`
 }
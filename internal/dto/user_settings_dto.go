@@ -0,0 +1,22 @@
+package dto
+
+// Settings used for users who have never saved any.
+const (
+	DefaultLocale   = "en"
+	DefaultTimezone = "UTC"
+)
+
+type UserSettingsResponse struct {
+	Locale              string `json:"locale"`
+	Timezone            string `json:"timezone"`
+	EmailSecurityAlerts bool   `json:"email_security_alerts"`
+	EmailProductUpdates bool   `json:"email_product_updates"`
+}
+
+// UpdateUserSettingsRequest changes only the fields that are present.
+type UpdateUserSettingsRequest struct {
+	Locale              *string `json:"locale" validate:"omitempty,bcp47_language_tag,max=35"`
+	Timezone            *string `json:"timezone" validate:"omitempty,timezone,max=64"`
+	EmailSecurityAlerts *bool   `json:"email_security_alerts"`
+	EmailProductUpdates *bool   `json:"email_product_updates"`
+}
@@ -0,0 +1,37 @@
+// Package v2 holds the request and response structs of API v2 that differ from v1. Services
+// return the v1 structs in package dto; v2 handlers convert them with the New* functions.
+package v2
+
+import (
+	"time"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+)
+
+// UserResponse is a user's profile. Unlike v1, GET /users/me does not embed the user's
+// settings, which are read from GET /users/me/settings.
+type UserResponse struct {
+	ID            int64          `json:"id"`
+	Email         string         `json:"email"`
+	Username      string         `json:"username,omitempty"`
+	Name          string         `json:"name"`
+	Role          string         `json:"role"`
+	EmailVerified bool           `json:"email_verified"`
+	Metadata      map[string]any `json:"metadata,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+func NewUserResponse(u dto.UserResponse) UserResponse {
+	return UserResponse{
+		ID:            u.ID,
+		Email:         u.Email,
+		Username:      u.Username,
+		Name:          u.Name,
+		Role:          u.Role,
+		EmailVerified: u.EmailVerified,
+		Metadata:      u.Metadata,
+		CreatedAt:     u.CreatedAt,
+		UpdatedAt:     u.UpdatedAt,
+	}
+}
@@ -0,0 +1,19 @@
+package dto
+
+import "encoding/json"
+
+type WebAuthnBeginResponse struct {
+	SessionID string `json:"session_id"`
+	Options   any    `json:"options"`
+}
+
+type WebAuthnRegisterFinishRequest struct {
+	SessionID  string          `json:"session_id" validate:"required"`
+	Name       string          `json:"name" validate:"omitempty,max=255"`
+	Credential json.RawMessage `json:"credential" validate:"required" swaggertype:"object"`
+}
+
+type WebAuthnLoginFinishRequest struct {
+	SessionID  string          `json:"session_id" validate:"required"`
+	Credential json.RawMessage `json:"credential" validate:"required" swaggertype:"object"`
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+)
+
+var (
+	auditLogCSVHeader   = []string{"id", "created_at", "actor_id", "action", "target_type", "target_id", "before", "after", "ip_address", "request_id"}
+	dailyStatsCSVHeader = []string{"date", "signups", "active_users", "uploads", "uploaded_bytes", "storage_bytes"}
+)
+
+// writeAuditLogsCSV streams the entries produced by each to w as CSV. The changed fields
+// before and after are JSON documents, empty when the entry has none.
+func writeAuditLogsCSV(w *bufio.Writer, each func(fn func(dto.AuditLogResponse) error) error) error {
+	out, err := newCSVStream(w, auditLogCSVHeader)
+	if err != nil {
+		return err
+	}
+
+	err = each(func(e dto.AuditLogResponse) error {
+		before, err := csvJSON(e.Before)
+		if err != nil {
+			return err
+		}
+		after, err := csvJSON(e.After)
+		if err != nil {
+			return err
+		}
+		return out.Write([]string{
+			strconv.FormatInt(e.ID, 10),
+			e.CreatedAt.UTC().Format(time.RFC3339),
+			strconv.FormatInt(e.ActorID, 10),
+			e.Action,
+			e.TargetType,
+			strconv.FormatInt(e.TargetID, 10),
+			csvSafe(before),
+			csvSafe(after),
+			csvSafe(e.IPAddress),
+			csvSafe(e.RequestID),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+// writeDailyStatsCSV writes one row per day of the series to w.
+func writeDailyStatsCSV(w *bufio.Writer, days []dto.AdminDailyStats) error {
+	out, err := newCSVStream(w, dailyStatsCSVHeader)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range days {
+		if err := out.Write([]string{
+			d.Date,
+			strconv.FormatInt(d.Signups, 10),
+			strconv.FormatInt(d.ActiveUsers, 10),
+			strconv.FormatInt(d.Uploads, 10),
+			strconv.FormatInt(d.UploadedBytes, 10),
+			strconv.FormatInt(d.StorageBytes, 10),
+		}); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+func csvJSON(data map[string]any) (string, error) {
+	if len(data) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
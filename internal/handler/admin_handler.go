@@ -1,31 +1,75 @@
 package handler
 
 import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
 	"github.com/gofiber/fiber/v3"
 
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/service"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/response"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/token"
 )
 
 type AdminHandler struct {
-	service service.AdminService
+	service        service.AdminService
+	permissionSvc  service.PermissionService
+	erasureSvc     service.ErasureService
+	emailVerifSvc  service.EmailVerificationService
+	activitySvc    service.UserActivityService
+	invitationSvc  service.InvitationService
+	importSvc      service.UserImportService
+	reconcileSvc   service.StorageReconcileService
+	auditLogSvc    service.AuditLogService
+	jwtConfig      config.JWTConfig
+	impersonateTTL time.Duration
 }
 
-func NewAdminHandler(svc service.AdminService) *AdminHandler {
-	return &AdminHandler{service: svc}
+func NewAdminHandler(
+	svc service.AdminService,
+	permissionSvc service.PermissionService,
+	erasureSvc service.ErasureService,
+	emailVerifSvc service.EmailVerificationService,
+	activitySvc service.UserActivityService,
+	invitationSvc service.InvitationService,
+	importSvc service.UserImportService,
+	reconcileSvc service.StorageReconcileService,
+	auditLogSvc service.AuditLogService,
+	jwtConfig config.JWTConfig,
+	impersonateMins int,
+) *AdminHandler {
+	return &AdminHandler{
+		service:        svc,
+		permissionSvc:  permissionSvc,
+		erasureSvc:     erasureSvc,
+		emailVerifSvc:  emailVerifSvc,
+		activitySvc:    activitySvc,
+		invitationSvc:  invitationSvc,
+		importSvc:      importSvc,
+		reconcileSvc:   reconcileSvc,
+		auditLogSvc:    auditLogSvc,
+		jwtConfig:      jwtConfig,
+		impersonateTTL: time.Duration(impersonateMins) * time.Minute,
+	}
 }
 
 // GetStats godoc
 // @Summary Get system statistics
-// @Description Get system-wide statistics (admin only)
+// @Description Get system-wide statistics (requires stats:read)
 // @Tags Admin
 // @Produce json
 // @Security BearerAuth
 // @Success 200 {object} response.Response{data=dto.AdminStatsResponse}
 // @Failure 401 {object} response.Response
 // @Failure 403 {object} response.Response
-// @Router /admin/stats [get]
+// @Router /v1/admin/stats [get]
 func (h *AdminHandler) GetStats(c fiber.Ctx) error {
 	stats, err := h.service.GetStats(c.Context())
 	if err != nil {
@@ -35,25 +79,125 @@ func (h *AdminHandler) GetStats(c fiber.Ctx) error {
 	return response.Success(c, stats)
 }
 
+// GetStatsSeries godoc
+// @Summary Get daily statistics
+// @Description Get signups, active users, uploads and stored bytes per UTC day for charting, over the last days days including today (requires stats:read). Active users are distinct users with a successful sign-in; stored bytes count the files not soft-deleted at the end of each day.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param days query int false "Window in days, ending today" default(30) minimum(1) maximum(365)
+// @Success 200 {object} response.Response{data=dto.AdminStatsSeriesResponse}
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/admin/stats/daily [get]
+func (h *AdminHandler) GetStatsSeries(c fiber.Ctx) error {
+	var query dto.AdminStatsSeriesQuery
+	if err := bindQueryAndValidate(c, &query); err != nil {
+		return err
+	}
+
+	series, err := h.service.GetStatsSeries(c.Context(), query.Days)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, series)
+}
+
+// ExportStats godoc
+// @Summary Export daily statistics
+// @Description Download the daily statistics of the last days days, including today, as CSV with one row per UTC day (requires stats:read)
+// @Tags Admin
+// @Produce text/csv
+// @Security BearerAuth
+// @Param days query int false "Window in days, ending today" default(30) minimum(1) maximum(365)
+// @Success 200 {file} file
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/admin/stats/export [get]
+func (h *AdminHandler) ExportStats(c fiber.Ctx) error {
+	var query dto.AdminStatsSeriesQuery
+	if err := bindQueryAndValidate(c, &query); err != nil {
+		return err
+	}
+
+	series, err := h.service.GetStatsSeries(c.Context(), query.Days)
+	if err != nil {
+		return err
+	}
+
+	c.Attachment(fmt.Sprintf("stats-%s-%s.csv", series.From, series.To))
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		if err := writeDailyStatsCSV(w, series.Days); err != nil {
+			slog.Error("stats export aborted", slog.Any("error", err))
+		}
+	})
+}
+
+// Search godoc
+// @Summary Search users and files
+// @Description Find users by a fragment of their email or name and files by a fragment of their name, including deleted ones (requires users:list and files:manage). Exact matches come first, then prefixes, then other substrings, newest first within each.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param q query string true "Search term, at least 2 characters"
+// @Param type query string false "Only hits of this kind" Enums(user, file)
+// @Param limit query int false "Maximum number of hits" default(20) minimum(1) maximum(50)
+// @Success 200 {object} response.Response{data=[]dto.AdminSearchHit}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/admin/search [get]
+func (h *AdminHandler) Search(c fiber.Ctx) error {
+	var query dto.AdminSearchQuery
+	if err := bindQueryAndValidate(c, &query); err != nil {
+		return err
+	}
+
+	hits, err := h.service.Search(c.Context(), query)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, hits)
+}
+
 // ListUsers godoc
 // @Summary List all users (admin)
-// @Description Get a paginated list of all users including soft-deleted
+// @Description Get a paginated list of all users including soft-deleted, optionally searched, filtered and sorted (requires users:list)
 // @Tags Admin
 // @Produce json
 // @Security BearerAuth
 // @Param page query int false "Page number" default(1)
 // @Param per_page query int false "Items per page" default(10)
-// @Success 200 {object} response.Response{data=[]dto.UserResponse,meta=response.Meta}
+// @Param q query string false "Search name or email (case-insensitive substring)"
+// @Param role query string false "Filter by built-in role"
+// @Param email_verified query bool false "Filter by email verification status"
+// @Param banned query bool false "Filter by ban status"
+// @Param created_after query string false "Only users created at or after this RFC 3339 timestamp"
+// @Param created_before query string false "Only users created before this RFC 3339 timestamp"
+// @Param sort query string false "Sort field, prefix with - for descending" Enums(id, -id, name, -name, email, -email, created_at, -created_at)
+// @Success 200 {object} response.Response{data=[]dto.AdminUserResponse,meta=response.Meta}
+// @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
 // @Failure 403 {object} response.Response
-// @Router /admin/users [get]
+// @Failure 422 {object} response.Response
+// @Router /v1/admin/users [get]
 func (h *AdminHandler) ListUsers(c fiber.Ctx) error {
 	page, perPage, err := paginationQuery(c)
 	if err != nil {
 		return err
 	}
 
-	users, total, err := h.service.ListUsers(c.Context(), page, perPage)
+	var query dto.UserFilterQuery
+	if err := bindQueryAndValidate(c, &query); err != nil {
+		return err
+	}
+
+	users, total, err := h.service.ListUsers(c.Context(), query, page, perPage)
 	if err != nil {
 		return err
 	}
@@ -61,9 +205,120 @@ func (h *AdminHandler) ListUsers(c fiber.Ctx) error {
 	return response.SuccessWithMeta(c, users, response.NewMeta(page, perPage, total))
 }
 
+// ExportUsers godoc
+// @Summary Export users (admin)
+// @Description Download every user matching the filters, including soft-deleted, as CSV or a JSON array ordered by ID (requires users:list). Rows are streamed while they are read, so large exports are not buffered in memory.
+// @Tags Admin
+// @Produce text/csv
+// @Produce json
+// @Security BearerAuth
+// @Param format query string false "Export format" Enums(csv, json) default(csv)
+// @Param q query string false "Search name or email (case-insensitive substring)"
+// @Param role query string false "Filter by built-in role"
+// @Param email_verified query bool false "Filter by email verification status"
+// @Param banned query bool false "Filter by ban status"
+// @Param created_after query string false "Only users created at or after this RFC 3339 timestamp"
+// @Param created_before query string false "Only users created before this RFC 3339 timestamp"
+// @Success 200 {file} file
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/admin/users/export [get]
+func (h *AdminHandler) ExportUsers(c fiber.Ctx) error {
+	var query dto.UserExportQuery
+	if err := bindQueryAndValidate(c, &query); err != nil {
+		return err
+	}
+	format := query.Format
+	if format == "" {
+		format = "csv"
+	}
+
+	// The body is written after the handler returns, so the export must not inherit
+	// the request timeout.
+	ctx := context.WithoutCancel(c.Context())
+	filter := query.UserFilterQuery
+
+	c.Attachment(fmt.Sprintf("users-%s.%s", time.Now().UTC().Format("20060102-150405"), format))
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		err := writeUsers(w, format, func(fn func(dto.AdminUserResponse) error) error {
+			return h.service.ExportUsers(ctx, filter, fn)
+		})
+		if err != nil {
+			slog.Error("user export aborted", slog.Any("error", err))
+		}
+	})
+}
+
+// ImportUsers godoc
+// @Summary Import users from CSV
+// @Description Create up to 1000 accounts from a CSV file whose header names the email and name columns, plus an optional role column (user or admin, default user). Accounts are created without a password and each user is emailed a link to choose one. Rows succeed or fail independently and failures are reported by line (requires users:manage).
+// @Tags Admin
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "CSV file"
+// @Success 200 {object} response.Response{data=dto.UserImportResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /v1/admin/users/import [post]
+func (h *AdminHandler) ImportUsers(c fiber.Ctx) error {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return apperror.NewBadRequest("file is required")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return apperror.NewInternal("failed to open uploaded file")
+	}
+	defer func() { _ = file.Close() }()
+
+	rows, err := readUserImport(file)
+	if err != nil {
+		return err
+	}
+
+	result, err := h.importSvc.Import(auditContext(c), rows)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, result)
+}
+
+// GetUser godoc
+// @Summary Get a user (admin)
+// @Description Get a user's full record, including a banned user's deletion time, their sign-in provider and linked external identities, active session count, file count and total size, and latest successful sign-in (requires users:list)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} response.Response{data=dto.AdminUserDetailResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/admin/users/{id} [get]
+func (h *AdminHandler) GetUser(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	user, err := h.service.GetUser(c.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, user)
+}
+
 // UpdateRole godoc
 // @Summary Update user role
-// @Description Update a user's role (admin only)
+// @Description Update a user's built-in role (requires users:manage). Custom roles are assigned via PUT /admin/users/{id}/roles. Admins cannot demote themselves (SELF_DEMOTION), and the last active admin cannot be demoted (LAST_ADMIN).
 // @Tags Admin
 // @Accept json
 // @Produce json
@@ -75,7 +330,8 @@ func (h *AdminHandler) ListUsers(c fiber.Ctx) error {
 // @Failure 401 {object} response.Response
 // @Failure 403 {object} response.Response
 // @Failure 404 {object} response.Response
-// @Router /admin/users/{id}/role [put]
+// @Failure 409 {object} response.Response
+// @Router /v1/admin/users/{id}/role [put]
 func (h *AdminHandler) UpdateRole(c fiber.Ctx) error {
 	id, err := paramID(c, "id")
 	if err != nil {
@@ -87,43 +343,54 @@ func (h *AdminHandler) UpdateRole(c fiber.Ctx) error {
 		return err
 	}
 
-	user, err := h.service.UpdateRole(c.Context(), id, req.Role)
+	user, err := h.service.UpdateRole(auditContext(c), authUserID(c), id, req.Role)
 	if err != nil {
 		return err
 	}
+	recordActivity(c, h.activitySvc, id, dto.ActivityRoleChanged, map[string]any{"role": req.Role})
 
 	return response.Success(c, user)
 }
 
 // BanUser godoc
 // @Summary Ban a user
-// @Description Soft delete a user (admin only)
+// @Description Block a user from signing in and revoke their sessions (requires users:manage). Unlike a deleted user, a banned user stays listed, and signing in returns 403 ACCOUNT_BANNED with the optional reason. Admins cannot ban themselves (SELF_BAN), and the last active admin cannot be banned (LAST_ADMIN); banning a banned user returns 409.
 // @Tags Admin
+// @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "User ID"
+// @Param request body dto.BanUserRequest false "Ban reason"
 // @Success 204
 // @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
 // @Failure 403 {object} response.Response
 // @Failure 404 {object} response.Response
-// @Router /admin/users/{id}/ban [post]
+// @Failure 409 {object} response.Response
+// @Router /v1/admin/users/{id}/ban [post]
 func (h *AdminHandler) BanUser(c fiber.Ctx) error {
 	id, err := paramID(c, "id")
 	if err != nil {
 		return err
 	}
 
-	if err := h.service.BanUser(c.Context(), id); err != nil {
+	var req dto.BanUserRequest
+	if len(c.Body()) > 0 {
+		if err := bindAndValidate(c, &req); err != nil {
+			return err
+		}
+	}
+
+	if err := h.service.BanUser(auditContext(c), authUserID(c), id, req.Reason); err != nil {
 		return err
 	}
 
 	return response.NoContent(c)
 }
 
-// UnbanUser godoc
-// @Summary Unban a user
-// @Description Restore a soft-deleted user (admin only)
+// VerifyUserEmail godoc
+// @Summary Mark a user's email as verified
+// @Description Mark a user's email address as verified without a verification link and discard any pending links (requires users:manage). Already verified users are returned unchanged.
 // @Tags Admin
 // @Produce json
 // @Security BearerAuth
@@ -133,14 +400,14 @@ func (h *AdminHandler) BanUser(c fiber.Ctx) error {
 // @Failure 401 {object} response.Response
 // @Failure 403 {object} response.Response
 // @Failure 404 {object} response.Response
-// @Router /admin/users/{id}/unban [post]
-func (h *AdminHandler) UnbanUser(c fiber.Ctx) error {
+// @Router /v1/admin/users/{id}/verify-email [post]
+func (h *AdminHandler) VerifyUserEmail(c fiber.Ctx) error {
 	id, err := paramID(c, "id")
 	if err != nil {
 		return err
 	}
 
-	user, err := h.service.UnbanUser(c.Context(), id)
+	user, err := h.emailVerifSvc.MarkVerified(auditContext(c), authUserID(c), id)
 	if err != nil {
 		return err
 	}
@@ -148,28 +415,804 @@ func (h *AdminHandler) UnbanUser(c fiber.Ctx) error {
 	return response.Success(c, user)
 }
 
-// ListFiles godoc
-// @Summary List all files (admin)
-// @Description Get a paginated list of all files
+// SendUserVerification godoc
+// @Summary Send a user a verification email
+// @Description Email a user a fresh verification link, replacing any pending one (requires users:manage). Bypasses the resend cooldown; fails if the email is already verified.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 204
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/admin/users/{id}/send-verification [post]
+func (h *AdminHandler) SendUserVerification(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.emailVerifSvc.SendToUser(auditContext(c), authUserID(c), id); err != nil {
+		return err
+	}
+
+	return response.NoContent(c)
+}
+
+// ListUserActivity godoc
+// @Summary List a user's account activity
+// @Description Get a user's account activity trail, newest first (requires users:manage)
 // @Tags Admin
 // @Produce json
 // @Security BearerAuth
+// @Param id path int true "User ID"
 // @Param page query int false "Page number" default(1)
 // @Param per_page query int false "Items per page" default(10)
-// @Success 200 {object} response.Response{data=[]dto.FileResponse,meta=response.Meta}
+// @Success 200 {object} response.Response{data=[]dto.UserActivityResponse,meta=response.Meta}
+// @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
 // @Failure 403 {object} response.Response
-// @Router /admin/files [get]
-func (h *AdminHandler) ListFiles(c fiber.Ctx) error {
+// @Router /v1/admin/users/{id}/activity [get]
+func (h *AdminHandler) ListUserActivity(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
 	page, perPage, err := paginationQuery(c)
 	if err != nil {
 		return err
 	}
 
-	files, total, err := h.service.ListFiles(c.Context(), page, perPage)
+	activities, total, err := h.activitySvc.ListByUser(c.Context(), id, page, perPage)
 	if err != nil {
 		return err
 	}
 
-	return response.SuccessWithMeta(c, files, response.NewMeta(page, perPage, total))
+	return response.SuccessWithMeta(c, activities, response.NewMeta(page, perPage, total))
+}
+
+// EraseUser godoc
+// @Summary Erase a user's personal data
+// @Description Anonymize a user in place for a right-to-erasure request (requires users:manage): email, name, credentials, linked identities and metadata are scrubbed, the account is soft-deleted, files, tokens, passkeys and data exports are removed, login history is stripped of addresses and user agents, and an erasure audit entry is recorded. This cannot be undone. The last active admin cannot be erased (409 LAST_ADMIN).
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} response.Response{data=dto.ErasureResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /v1/admin/users/{id}/erase [post]
+func (h *AdminHandler) EraseUser(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.erasureSvc.Erase(c.Context(), authUserID(c), id)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, resp)
+}
+
+// ListErasures godoc
+// @Summary List erasure audit entries
+// @Description Get a paginated list of right-to-erasure audit entries, newest first (requires users:manage)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(10)
+// @Success 200 {object} response.Response{data=[]dto.ErasureResponse,meta=response.Meta}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /v1/admin/erasures [get]
+func (h *AdminHandler) ListErasures(c fiber.Ctx) error {
+	page, perPage, err := paginationQuery(c)
+	if err != nil {
+		return err
+	}
+
+	erasures, total, err := h.erasureSvc.List(c.Context(), page, perPage)
+	if err != nil {
+		return err
+	}
+
+	return response.SuccessWithMeta(c, erasures, response.NewMeta(page, perPage, total))
+}
+
+// ListAuditLogs godoc
+// @Summary List audit log entries
+// @Description Get a paginated list of admin changes, newest first, with who made them, their target, the changed fields before and after, and the originating IP and request ID (requires audit:read)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(10)
+// @Param actor_id query int false "Only changes made by this user"
+// @Param action query string false "Only this action, e.g. user.banned"
+// @Param target_type query string false "Only changes to this kind of record" Enums(user, file, flag, broadcast, setting, email, email_suppression, role, invitation, storage)
+// @Param target_id query int false "Only changes to this record"
+// @Param created_after query string false "Only entries created at or after this RFC 3339 timestamp"
+// @Param created_before query string false "Only entries created before this RFC 3339 timestamp"
+// @Success 200 {object} response.Response{data=[]dto.AuditLogResponse,meta=response.Meta}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/admin/audit-logs [get]
+func (h *AdminHandler) ListAuditLogs(c fiber.Ctx) error {
+	page, perPage, err := paginationQuery(c)
+	if err != nil {
+		return err
+	}
+
+	var query dto.AuditLogQuery
+	if err := bindQueryAndValidate(c, &query); err != nil {
+		return err
+	}
+
+	entries, total, err := h.auditLogSvc.List(c.Context(), query, page, perPage)
+	if err != nil {
+		return err
+	}
+
+	return response.SuccessWithMeta(c, entries, response.NewMeta(page, perPage, total))
+}
+
+// ExportAuditLogs godoc
+// @Summary Export audit log entries
+// @Description Download every audit log entry matching the filters as CSV, oldest first, for compliance reporting (requires audit:read). Changed fields are JSON documents. Rows are streamed while they are read, so large exports are not buffered in memory.
+// @Tags Admin
+// @Produce text/csv
+// @Security BearerAuth
+// @Param actor_id query int false "Only changes made by this user"
+// @Param action query string false "Only this action, e.g. user.banned"
+// @Param target_type query string false "Only changes to this kind of record" Enums(user, file, flag, broadcast, setting, email, email_suppression, role, invitation, storage)
+// @Param target_id query int false "Only changes to this record"
+// @Param created_after query string false "Only entries created at or after this RFC 3339 timestamp"
+// @Param created_before query string false "Only entries created before this RFC 3339 timestamp"
+// @Success 200 {file} file
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/admin/audit-logs/export [get]
+func (h *AdminHandler) ExportAuditLogs(c fiber.Ctx) error {
+	var query dto.AuditLogQuery
+	if err := bindQueryAndValidate(c, &query); err != nil {
+		return err
+	}
+
+	// The body is written after the handler returns, so the export must not inherit
+	// the request timeout.
+	ctx := context.WithoutCancel(c.Context())
+
+	c.Attachment(fmt.Sprintf("audit-logs-%s.csv", time.Now().UTC().Format("20060102-150405")))
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		err := writeAuditLogsCSV(w, func(fn func(dto.AuditLogResponse) error) error {
+			return h.auditLogSvc.Export(ctx, query, fn)
+		})
+		if err != nil {
+			slog.Error("audit log export aborted", slog.Any("error", err))
+		}
+	})
+}
+
+// CreateInvitation godoc
+// @Summary Invite someone to register
+// @Description Email a registration link to the address, replacing any pending invitation for it. Needed to sign up when INVITE_ONLY is enabled (requires users:manage)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.CreateInvitationRequest true "Invitation"
+// @Success 201 {object} response.Response{data=dto.InvitationResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /v1/admin/invitations [post]
+func (h *AdminHandler) CreateInvitation(c fiber.Ctx) error {
+	var req dto.CreateInvitationRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	invitation, err := h.invitationSvc.Create(auditContext(c), authUserID(c), req)
+	if err != nil {
+		return err
+	}
+
+	return response.Created(c, invitation)
+}
+
+// ListInvitations godoc
+// @Summary List registration invitations
+// @Description Get a paginated list of pending registration invitations, newest first (requires users:manage)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(10)
+// @Success 200 {object} response.Response{data=[]dto.InvitationResponse,meta=response.Meta}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /v1/admin/invitations [get]
+func (h *AdminHandler) ListInvitations(c fiber.Ctx) error {
+	page, perPage, err := paginationQuery(c)
+	if err != nil {
+		return err
+	}
+
+	invitations, total, err := h.invitationSvc.List(c.Context(), page, perPage)
+	if err != nil {
+		return err
+	}
+
+	return response.SuccessWithMeta(c, invitations, response.NewMeta(page, perPage, total))
+}
+
+// RevokeInvitation godoc
+// @Summary Revoke a registration invitation
+// @Description Delete a pending invitation so its link can no longer be used (requires users:manage)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Invitation ID"
+// @Success 204
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/admin/invitations/{id} [delete]
+func (h *AdminHandler) RevokeInvitation(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.invitationSvc.Revoke(auditContext(c), id); err != nil {
+		return err
+	}
+
+	return response.NoContent(c)
+}
+
+// BulkUsers godoc
+// @Summary Apply an action to many users
+// @Description Ban, unban, delete or change the built-in role of up to 100 users in one transaction (requires users:manage). Deleted users are soft-deleted and signed out, can be restored and are purged after the retention period like through DELETE /users/:id. Each user succeeds or fails independently; failures are reported per item and do not roll back the others. Your own account is always skipped.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.BulkUserActionRequest true "Bulk action"
+// @Success 200 {object} response.Response{data=dto.BulkUserActionResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/admin/users/bulk [post]
+func (h *AdminHandler) BulkUsers(c fiber.Ctx) error {
+	var req dto.BulkUserActionRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	result, err := h.service.BulkUsers(auditContext(c), authUserID(c), req)
+	if err != nil {
+		return err
+	}
+	if req.Action == dto.BulkActionSetRole {
+		for _, r := range result.Results {
+			if r.Success {
+				recordActivity(c, h.activitySvc, r.UserID, dto.ActivityRoleChanged, map[string]any{"role": req.Role})
+			}
+		}
+	}
+
+	return response.Success(c, result)
+}
+
+// UnbanUser godoc
+// @Summary Unban a user
+// @Description Lift a user's ban so they can sign in again (requires users:manage)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} response.Response{data=dto.UserResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/admin/users/{id}/unban [post]
+func (h *AdminHandler) UnbanUser(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	user, err := h.service.UnbanUser(auditContext(c), id)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, user)
+}
+
+// RestoreUser godoc
+// @Summary Restore a deleted user
+// @Description Undelete a soft-deleted user that has not been purged yet; deleted users are purged after USER_RETENTION_DAYS (requires users:manage). Users banned before bans were separated from deletion are soft-deleted and restored this way.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} response.Response{data=dto.UserResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/admin/users/{id}/restore [post]
+func (h *AdminHandler) RestoreUser(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	user, err := h.service.RestoreUser(auditContext(c), id)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, user)
+}
+
+// ListUserSessions godoc
+// @Summary List a user's sessions
+// @Description List the user's signed-in sessions, one per refresh token family, most recently refreshed first (requires users:manage)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} response.Response{data=[]dto.UserSessionResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/admin/users/{id}/sessions [get]
+func (h *AdminHandler) ListUserSessions(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	sessions, err := h.service.ListSessions(c.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, sessions)
+}
+
+// RevokeUserSessions godoc
+// @Summary Revoke a user's sessions
+// @Description Sign the user out everywhere without banning them: all refresh tokens are revoked and issued access tokens stop working (requires users:manage)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 204
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/admin/users/{id}/sessions [delete]
+func (h *AdminHandler) RevokeUserSessions(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.service.RevokeSessions(auditContext(c), id); err != nil {
+		return err
+	}
+
+	return response.NoContent(c)
+}
+
+// ListFiles godoc
+// @Summary List all files (admin)
+// @Description Get a paginated list of all files, including soft-deleted ones with their deleted_at (requires files:manage)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(10)
+// @Param tag query string false "Only files with this tag"
+// @Success 200 {object} response.Response{data=[]dto.FileResponse,meta=response.Meta}
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/admin/files [get]
+func (h *AdminHandler) ListFiles(c fiber.Ctx) error {
+	page, perPage, err := paginationQuery(c)
+	if err != nil {
+		return err
+	}
+
+	var query dto.AdminFileListQuery
+	if err := bindQueryAndValidate(c, &query); err != nil {
+		return err
+	}
+
+	files, total, err := h.service.ListFiles(c.Context(), query, page, perPage)
+	if err != nil {
+		return err
+	}
+
+	return response.SuccessWithMeta(c, files, response.NewMeta(page, perPage, total))
+}
+
+// DeleteFile godoc
+// @Summary Delete a file (admin)
+// @Description Soft delete any user's file (requires files:manage). It can be restored until the retention period, STORAGE_FILE_RETENTION_DAYS, has passed.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "File ID"
+// @Success 204
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/admin/files/{id} [delete]
+func (h *AdminHandler) DeleteFile(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.service.DeleteFile(auditContext(c), id); err != nil {
+		return err
+	}
+
+	return response.NoContent(c)
+}
+
+// RestoreFile godoc
+// @Summary Restore a deleted file (admin)
+// @Description Undelete a soft-deleted file that is still within the retention period, STORAGE_FILE_RETENTION_DAYS (requires files:manage). Files past it are purged in the background.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "File ID"
+// @Success 200 {object} response.Response{data=dto.FileResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/admin/files/{id}/restore [post]
+func (h *AdminHandler) RestoreFile(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	file, err := h.service.RestoreFile(auditContext(c), id)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, file)
+}
+
+// PurgeFile godoc
+// @Summary Purge a file (admin)
+// @Description Permanently delete any user's file, whether or not it is soft-deleted, with its versions and image variants, removing the stored objects no other file uses (requires files:manage). This cannot be undone.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "File ID"
+// @Success 204
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/admin/files/{id}/purge [delete]
+func (h *AdminHandler) PurgeFile(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.service.PurgeFile(auditContext(c), id); err != nil {
+		return err
+	}
+
+	return response.NoContent(c)
+}
+
+// DownloadFile godoc
+// @Summary Download a file (admin)
+// @Description Download any user's file, including a soft-deleted one, for review (requires files:manage). Each download is recorded in the audit log.
+// @Tags Admin
+// @Produce octet-stream
+// @Security BearerAuth
+// @Param id path int true "File ID"
+// @Success 200
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/admin/files/{id}/download [get]
+func (h *AdminHandler) DownloadFile(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	file, reader, err := h.service.DownloadFile(auditContext(c), id)
+	if err != nil {
+		return err
+	}
+	// SendStream closes the reader once the response is written.
+
+	c.Set("Content-Type", file.MimeType)
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.OriginalName))
+	c.Set("Content-Length", strconv.FormatInt(file.Size, 10))
+
+	return c.SendStream(reader)
+}
+
+// ReconcileStorage godoc
+// @Summary Reconcile storage with the database
+// @Description Start comparing the storage backend with the database in the background (requires files:manage). Objects no file, version, variant, upload chunk or data export refers to are reported as orphaned, and deleted when cleanup is true; objects written in the last hour are skipped as their upload may still be in progress. Rows whose object is gone are reported as missing. The body may be omitted to only report.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.StorageReconcileRequest false "Options"
+// @Success 202 {object} response.Response{data=dto.StorageReconcileResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /v1/admin/storage/reconcile [post]
+func (h *AdminHandler) ReconcileStorage(c fiber.Ctx) error {
+	var req dto.StorageReconcileRequest
+	if len(c.Body()) > 0 {
+		if err := bindAndValidate(c, &req); err != nil {
+			return err
+		}
+	}
+
+	resp, err := h.reconcileSvc.Start(auditContext(c), req)
+	if err != nil {
+		return err
+	}
+
+	return response.Accepted(c, resp)
+}
+
+// GetStorageReconciliation godoc
+// @Summary Get the storage reconciliation report
+// @Description Get the status and report of the most recent storage reconciliation (requires files:manage). Counts are filled in once the run finishes; up to 1000 orphaned and missing objects are listed.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=dto.StorageReconcileResponse}
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/admin/storage/reconcile [get]
+func (h *AdminHandler) GetStorageReconciliation(c fiber.Ctx) error {
+	resp, err := h.reconcileSvc.Latest(c.Context())
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, resp)
+}
+
+// Impersonate godoc
+// @Summary Impersonate a user
+// @Description Issue a short-lived access token for the target user with an impersonated_by claim (requires users:impersonate). No refresh token is issued. Admins and users holding a permission the caller lacks cannot be impersonated.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} response.Response{data=dto.ImpersonateResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/admin/users/{id}/impersonate [post]
+func (h *AdminHandler) Impersonate(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	adminID := authUserID(c)
+	user, err := h.service.Impersonate(auditContext(c), adminID, id)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(h.impersonateTTL)
+	accessToken, err := token.GenerateImpersonation(user.ID, user.Email, user.Username.String, user.Role, dto.ScopesForRole(user.Role), adminID, h.jwtConfig, h.impersonateTTL)
+	if err != nil {
+		return apperror.NewInternal("failed to generate access token")
+	}
+
+	return response.Success(c, dto.ImpersonateResponse{
+		AccessToken: accessToken,
+		ExpiresAt:   expiresAt,
+		User:        *service.ToUserResponse(user),
+	})
+}
+
+// ListPermissions godoc
+// @Summary List permissions
+// @Description List every permission that can be granted to a role (requires roles:manage)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]dto.PermissionResponse}
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /v1/admin/permissions [get]
+func (h *AdminHandler) ListPermissions(c fiber.Ctx) error {
+	perms, err := h.permissionSvc.ListPermissions(c.Context())
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, perms)
+}
+
+// ListRoles godoc
+// @Summary List roles
+// @Description List built-in and custom roles with their permissions (requires roles:manage)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]dto.RoleResponse}
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /v1/admin/roles [get]
+func (h *AdminHandler) ListRoles(c fiber.Ctx) error {
+	roles, err := h.permissionSvc.ListRoles(c.Context())
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, roles)
+}
+
+// CreateRole godoc
+// @Summary Create a custom role
+// @Description Create a role granting a set of existing permissions (requires roles:manage)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.CreateRoleRequest true "Role definition"
+// @Success 201 {object} response.Response{data=dto.RoleResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/admin/roles [post]
+func (h *AdminHandler) CreateRole(c fiber.Ctx) error {
+	var req dto.CreateRoleRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	role, err := h.permissionSvc.CreateRole(auditContext(c), req)
+	if err != nil {
+		return err
+	}
+
+	return response.Created(c, role)
+}
+
+// DeleteRole godoc
+// @Summary Delete a custom role
+// @Description Delete a custom role and unassign it from all users; built-in roles cannot be deleted (requires roles:manage)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Role ID"
+// @Success 204
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/admin/roles/{id} [delete]
+func (h *AdminHandler) DeleteRole(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.permissionSvc.DeleteRole(auditContext(c), id); err != nil {
+		return err
+	}
+
+	return response.NoContent(c)
+}
+
+// GetUserRoles godoc
+// @Summary Get a user's roles
+// @Description Get the custom roles assigned to a user and their effective permissions (requires roles:manage)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} response.Response{data=dto.UserRolesResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/admin/users/{id}/roles [get]
+func (h *AdminHandler) GetUserRoles(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	roles, err := h.permissionSvc.GetUserRoles(c.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, roles)
+}
+
+// SetUserRoles godoc
+// @Summary Assign custom roles
+// @Description Replace the custom roles assigned to a user; an empty list removes them all (requires roles:manage)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param request body dto.UpdateUserRolesRequest true "Custom roles"
+// @Success 200 {object} response.Response{data=dto.UserRolesResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/admin/users/{id}/roles [put]
+func (h *AdminHandler) SetUserRoles(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req dto.UpdateUserRolesRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	roles, err := h.permissionSvc.SetUserRoles(auditContext(c), id, req.Roles)
+	if err != nil {
+		return err
+	}
+	recordActivity(c, h.activitySvc, id, dto.ActivityRoleChanged, map[string]any{"roles": req.Roles})
+
+	return response.Success(c, roles)
 }
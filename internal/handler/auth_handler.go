@@ -3,30 +3,60 @@ package handler
 import (
 	"context"
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/hex"
+	"errors"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
 
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/service"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/async"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/clientip"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/oauth"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/response"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/saml"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/token"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/validator"
 )
 
-const oauthStateCookieName = "oauth_state"
+const (
+	oauthStateCookieName    = "oauth_state"
+	samlRequestCookieName   = "saml_request"
+	refreshTokenCookieName  = "refresh_token"
+	csrfTokenCookieName     = "csrf_token"
+	csrfTokenHeader         = "X-CSRF-Token"
+	deviceFingerprintHeader = "X-Device-Fingerprint"
+)
 
 type AuthHandler struct {
-	userSvc       service.UserService
-	refreshSvc    service.RefreshTokenService
-	resetSvc      service.PasswordResetService
-	emailVerifSvc service.EmailVerificationService
-	jwtSecret     string
-	jwtExpireHour int
-	googleOAuth   *oauth.GoogleOAuth
+	userSvc         service.UserService
+	refreshSvc      service.RefreshTokenService
+	resetSvc        service.PasswordResetService
+	emailVerifSvc   service.EmailVerificationService
+	jwtConfig       config.JWTConfig
+	jwtExpireHour   int
+	cookieMode      bool
+	refreshMaxAge   int // seconds, used for refresh cookies
+	googleOAuth     oauth.Provider
+	githubOAuth     oauth.Provider
+	samlSP          *saml.ServiceProvider
+	webauthnSvc     service.WebAuthnService
+	loginEventSvc   service.LoginEventService
+	emailChangeSvc  service.EmailChangeService
+	guestSvc        service.GuestService
+	guestExpireHour int
+	loginThrottle   service.LoginThrottle
+	revocations     *token.RevocationStore
+	twoFactorSvc    service.TwoFactorService
 }
 
 func NewAuthHandler(
@@ -34,39 +64,65 @@ func NewAuthHandler(
 	refreshSvc service.RefreshTokenService,
 	resetSvc service.PasswordResetService,
 	emailVerifSvc service.EmailVerificationService,
-	jwtSecret string,
+	jwtConfig config.JWTConfig,
 	jwtExpireHour int,
-	googleOAuth *oauth.GoogleOAuth,
+	cookieMode bool,
+	refreshExpireDays int,
+	googleOAuth oauth.Provider,
+	githubOAuth oauth.Provider,
+	samlSP *saml.ServiceProvider,
+	webauthnSvc service.WebAuthnService,
+	loginEventSvc service.LoginEventService,
+	emailChangeSvc service.EmailChangeService,
+	guestSvc service.GuestService,
+	guestExpireHour int,
+	loginThrottle service.LoginThrottle,
+	revocations *token.RevocationStore,
+	twoFactorSvc service.TwoFactorService,
 ) *AuthHandler {
 	return &AuthHandler{
-		userSvc:       userSvc,
-		refreshSvc:    refreshSvc,
-		resetSvc:      resetSvc,
-		emailVerifSvc: emailVerifSvc,
-		jwtSecret:     jwtSecret,
-		jwtExpireHour: jwtExpireHour,
-		googleOAuth:   googleOAuth,
+		userSvc:         userSvc,
+		refreshSvc:      refreshSvc,
+		resetSvc:        resetSvc,
+		emailVerifSvc:   emailVerifSvc,
+		jwtConfig:       jwtConfig,
+		jwtExpireHour:   jwtExpireHour,
+		cookieMode:      cookieMode,
+		refreshMaxAge:   refreshExpireDays * 24 * 60 * 60,
+		googleOAuth:     googleOAuth,
+		githubOAuth:     githubOAuth,
+		samlSP:          samlSP,
+		webauthnSvc:     webauthnSvc,
+		loginEventSvc:   loginEventSvc,
+		emailChangeSvc:  emailChangeSvc,
+		guestSvc:        guestSvc,
+		guestExpireHour: guestExpireHour,
+		loginThrottle:   loginThrottle,
+		revocations:     revocations,
+		twoFactorSvc:    twoFactorSvc,
 	}
 }
 
 // Register godoc
 // @Summary Register a new user
-// @Description Create a new user account
+// @Description Create a new user account. When INVITE_ONLY is enabled, invite_token must come from an invitation sent to the same email address.
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param request body dto.RegisterRequest true "Register request"
 // @Success 201 {object} response.Response{data=dto.UserResponse}
 // @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
 // @Failure 422 {object} response.Response
 // @Failure 429 {object} response.Response
 // @Failure 500 {object} response.Response
-// @Router /auth/register [post]
+// @Router /v1/auth/register [post]
 func (h *AuthHandler) Register(c fiber.Ctx) error {
 	var req dto.RegisterRequest
 	if err := bindAndValidate(c, &req); err != nil {
 		return err
 	}
+	req.Locale = fiber.Locals[string](c, "locale")
 
 	user, err := h.userSvc.Register(c.Context(), req)
 	if err != nil {
@@ -85,72 +141,298 @@ func (h *AuthHandler) Register(c fiber.Ctx) error {
 
 // Login godoc
 // @Summary Login
-// @Description Authenticate user and return access + refresh tokens
+// @Description Authenticate user and return access + refresh tokens. In cookie mode the refresh token is set as an httpOnly cookie instead of being returned in the body. Repeated failures from one IP trigger an exponential backoff advertised via Retry-After. Accounts with two-factor authentication get 202 with a challenge token instead, answered at POST /auth/login/2fa.
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param request body dto.LoginRequest true "Login request"
+// @Param X-Device-Fingerprint header string false "Optional device fingerprint; the refresh token is then only accepted from the same device"
 // @Success 200 {object} response.Response{data=dto.LoginResponse}
+// @Success 202 {object} response.Response{data=dto.TwoFactorChallengeResponse}
 // @Failure 401 {object} response.Response
 // @Failure 422 {object} response.Response
 // @Failure 429 {object} response.Response
+// @Header 401,429 {integer} Retry-After "Seconds until the next login attempt from this IP is allowed"
 // @Failure 500 {object} response.Response
-// @Router /auth/login [post]
+// @Router /v1/auth/login [post]
 func (h *AuthHandler) Login(c fiber.Ctx) error {
 	var req dto.LoginRequest
 	if err := bindAndValidate(c, &req); err != nil {
 		return err
 	}
 
+	if err := h.checkLoginThrottle(c); err != nil {
+		return err
+	}
+
 	user, err := h.userSvc.Authenticate(c.Context(), req)
+	if err != nil {
+		h.recordLoginFailure(c, err)
+		h.recordLogin(c, 0, req.Email, dto.LoginMethodPassword, err)
+		return err
+	}
+
+	if h.twoFactorSvc != nil {
+		challenge, err := h.twoFactorSvc.Challenge(c.Context(), user.ID)
+		if err != nil {
+			return err
+		}
+		if challenge != nil {
+			return response.Accepted(c, challenge)
+		}
+	}
+
+	resp, err := h.issueTokens(c.Context(), user, c.Get(deviceFingerprintHeader))
+	if err != nil {
+		return err
+	}
+
+	h.recordLogin(c, user.ID, user.Email, dto.LoginMethodPassword, nil)
+	return h.respondWithTokens(c, resp)
+}
+
+// LoginTwoFactor godoc
+// @Summary Complete a two-factor login
+// @Description Answer the challenge of a password login with a 6-digit code from the authenticator app or an unused recovery code, and return access + refresh tokens. A challenge lasts 5 minutes and accepts 5 attempts; each TOTP code and recovery code is accepted once. Failures count towards the login backoff of the IP.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.TwoFactorLoginRequest true "Challenge token and code"
+// @Param X-Device-Fingerprint header string false "Optional device fingerprint; the refresh token is then only accepted from the same device"
+// @Success 200 {object} response.Response{data=dto.LoginResponse}
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Header 401,429 {integer} Retry-After "Seconds until the next login attempt from this IP is allowed"
+// @Failure 500 {object} response.Response
+// @Router /v1/auth/login/2fa [post]
+func (h *AuthHandler) LoginTwoFactor(c fiber.Ctx) error {
+	var req dto.TwoFactorLoginRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.checkLoginThrottle(c); err != nil {
+		return err
+	}
+
+	user, err := h.twoFactorSvc.Verify(c.Context(), req)
+	if err != nil {
+		h.recordLoginFailure(c, err)
+		return err
+	}
+
+	resp, err := h.issueTokens(c.Context(), user, c.Get(deviceFingerprintHeader))
+	if err != nil {
+		return err
+	}
+
+	h.recordLogin(c, user.ID, user.Email, dto.LoginMethodPassword, nil)
+	return h.respondWithTokens(c, resp)
+}
+
+// PasswordPolicy godoc
+// @Summary Get password policy
+// @Description Returns the password rules enforced on registration, reset and password change so clients can render matching hints
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} response.Response{data=dto.PasswordPolicyResponse}
+// @Failure 429 {object} response.Response
+// @Router /v1/auth/password-policy [get]
+func (h *AuthHandler) PasswordPolicy(c fiber.Ctx) error {
+	p := validator.CurrentPasswordPolicy()
+	return response.Success(c, dto.PasswordPolicyResponse{
+		MinLength:      p.MinLength,
+		MaxLength:      p.MaxLength,
+		RequireUpper:   p.RequireUpper,
+		RequireLower:   p.RequireLower,
+		RequireDigit:   p.RequireDigit,
+		RequireSpecial: p.RequireSpecial,
+		RejectsCommon:  len(p.Banned) > 0,
+	})
+}
+
+// Guest godoc
+// @Summary Start a guest session
+// @Description Create an anonymous guest account and return a short-lived access token with the guest role. No refresh token is issued.
+// @Tags Auth
+// @Produce json
+// @Success 201 {object} response.Response{data=dto.LoginResponse}
+// @Failure 429 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /v1/auth/guest [post]
+func (h *AuthHandler) Guest(c fiber.Ctx) error {
+	user, err := h.guestSvc.Create(c.Context())
 	if err != nil {
 		return err
 	}
 
-	accessToken, err := token.Generate(user.ID, user.Email, user.Role, h.jwtSecret, h.jwtExpireHour)
+	accessToken, err := token.Generate(user.ID, user.Email, user.Username.String, user.Role, dto.ScopesForRole(user.Role), h.jwtConfig, h.guestExpireHour)
 	if err != nil {
 		return apperror.NewInternal("failed to generate access token")
 	}
 
-	refreshToken, err := h.refreshSvc.Create(c.Context(), user.ID)
+	return response.Created(c, &dto.LoginResponse{
+		AccessToken: accessToken,
+		User:        *service.ToUserResponse(user),
+	})
+}
+
+// UpgradeGuest godoc
+// @Summary Upgrade a guest account
+// @Description Convert the authenticated guest account into a registered account, keeping everything the guest owns, and return access + refresh tokens
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.RegisterRequest true "Register request"
+// @Param X-Device-Fingerprint header string false "Optional device fingerprint; the refresh token is then only accepted from the same device"
+// @Success 200 {object} response.Response{data=dto.LoginResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /v1/auth/guest/upgrade [post]
+func (h *AuthHandler) UpgradeGuest(c fiber.Ctx) error {
+	var req dto.RegisterRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+	req.Locale = fiber.Locals[string](c, "locale")
+
+	user, err := h.guestSvc.Upgrade(c.Context(), authUserID(c), req)
 	if err != nil {
 		return err
 	}
 
-	return response.Success(c, dto.LoginResponse{
+	if h.emailVerifSvc != nil {
+		async.Go(func() {
+			_ = h.emailVerifSvc.SendVerification(context.Background(), user.ID, user.Email)
+		})
+	}
+
+	resp, err := h.issueTokens(c.Context(), user, c.Get(deviceFingerprintHeader))
+	if err != nil {
+		return err
+	}
+	return h.respondWithTokens(c, resp)
+}
+
+// checkLoginThrottle rejects the request when the client IP is in a brute-force backoff period.
+func (h *AuthHandler) checkLoginThrottle(c fiber.Ctx) error {
+	if h.loginThrottle == nil {
+		return nil
+	}
+	if wait := h.loginThrottle.Check(c.Context(), clientip.Get(c)); wait > 0 {
+		setRetryAfter(c, wait)
+		return apperror.NewTooManyRequests("too many failed login attempts, please try again later")
+	}
+	return nil
+}
+
+// recordLoginFailure counts invalid credentials against the client IP and advertises
+// the resulting backoff via Retry-After.
+func (h *AuthHandler) recordLoginFailure(c fiber.Ctx, loginErr error) {
+	if h.loginThrottle == nil {
+		return
+	}
+	var appErr *apperror.AppError
+	if !errors.As(loginErr, &appErr) || appErr.Code != fiber.StatusUnauthorized {
+		return
+	}
+	if wait := h.loginThrottle.RecordFailure(c.Context(), clientip.Get(c)); wait > 0 {
+		setRetryAfter(c, wait)
+	}
+}
+
+// setRetryAfter sets the Retry-After header, rounding up to whole seconds.
+func setRetryAfter(c fiber.Ctx, wait time.Duration) {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+}
+
+// recordLogin stores a login attempt in the login history without blocking the response.
+// Request data is copied up front because the fiber context is reused once the handler returns.
+func (h *AuthHandler) recordLogin(c fiber.Ctx, userID int64, email, method string, loginErr error) {
+	if h.loginEventSvc == nil {
+		return
+	}
+
+	attempt := service.LoginAttempt{
+		UserID:    userID,
+		Email:     email,
+		Method:    method,
+		Success:   loginErr == nil,
+		IPAddress: strings.Clone(clientip.Get(c)),
+		UserAgent: strings.Clone(c.Get(fiber.HeaderUserAgent)),
+	}
+	if loginErr != nil {
+		attempt.FailureReason = loginErr.Error()
+	}
+
+	async.Go(func() {
+		if err := h.loginEventSvc.Record(context.Background(), attempt); err != nil {
+			slog.Error("failed to record login event", slog.Any("error", err))
+		}
+	})
+}
+
+// issueTokens generates an access token and a refresh token for an authenticated user.
+// A non-empty fingerprint binds the refresh token to the client device.
+func (h *AuthHandler) issueTokens(ctx context.Context, user *sqlc.User, fingerprint string) (*dto.LoginResponse, error) {
+	accessToken, err := token.Generate(user.ID, user.Email, user.Username.String, user.Role, dto.ScopesForRole(user.Role), h.jwtConfig, h.jwtExpireHour)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to generate access token")
+	}
+
+	refreshToken, err := h.refreshSvc.Create(ctx, user.ID, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.LoginResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		User:         *service.ToUserResponse(user),
-	})
+	}, nil
 }
 
 // Refresh godoc
 // @Summary Refresh access token
-// @Description Exchange a refresh token for a new access token
+// @Description Exchange a refresh token for a new access token and a rotated refresh token. Reusing an already-rotated refresh token revokes all of the user's refresh tokens; device-bound tokens must be presented with the same X-Device-Fingerprint. In cookie mode the refresh token is read from the httpOnly cookie and the X-CSRF-Token header must match the csrf_token cookie.
 // @Tags Auth
 // @Accept json
 // @Produce json
-// @Param request body dto.RefreshRequest true "Refresh request"
+// @Param request body dto.RefreshRequest false "Refresh request (omit in cookie mode)"
+// @Param X-CSRF-Token header string false "CSRF token (cookie mode only)"
+// @Param X-Device-Fingerprint header string false "Device fingerprint the refresh token was bound to at login"
 // @Success 200 {object} response.Response{data=dto.LoginResponse}
 // @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
 // @Failure 422 {object} response.Response
 // @Failure 429 {object} response.Response
 // @Failure 500 {object} response.Response
-// @Router /auth/refresh [post]
+// @Router /v1/auth/refresh [post]
 func (h *AuthHandler) Refresh(c fiber.Ctx) error {
-	var req dto.RefreshRequest
-	if err := bindAndValidate(c, &req); err != nil {
+	refreshToken, err := h.refreshTokenFromRequest(c)
+	if err != nil {
 		return err
 	}
+	if refreshToken == "" {
+		return apperror.NewUnauthorized("missing refresh token")
+	}
 
-	rt, err := h.refreshSvc.Verify(c.Context(), req.RefreshToken)
+	rt, err := h.refreshSvc.Verify(c.Context(), refreshToken, c.Get(deviceFingerprintHeader))
 	if err != nil {
 		return err
 	}
 
-	// Revoke old refresh token — if this fails, do NOT issue new tokens to prevent token reuse attacks
-	if err := h.refreshSvc.Revoke(c.Context(), req.RefreshToken); err != nil {
-		return apperror.NewInternal("failed to revoke refresh token")
+	// Rotate the refresh token — if this fails, do NOT issue new tokens to prevent token reuse attacks
+	newRefreshToken, err := h.refreshSvc.Rotate(c.Context(), rt)
+	if err != nil {
+		return err
 	}
 
 	user, err := h.userSvc.GetByID(c.Context(), rt.UserID)
@@ -158,17 +440,12 @@ func (h *AuthHandler) Refresh(c fiber.Ctx) error {
 		return err
 	}
 
-	accessToken, err := token.Generate(user.ID, user.Email, user.Role, h.jwtSecret, h.jwtExpireHour)
+	accessToken, err := token.Generate(user.ID, user.Email, user.Username, user.Role, dto.ScopesForRole(user.Role), h.jwtConfig, h.jwtExpireHour)
 	if err != nil {
 		return apperror.NewInternal("failed to generate access token")
 	}
 
-	newRefreshToken, err := h.refreshSvc.Create(c.Context(), rt.UserID)
-	if err != nil {
-		return err
-	}
-
-	return response.Success(c, dto.LoginResponse{
+	return h.respondWithTokens(c, &dto.LoginResponse{
 		AccessToken:  accessToken,
 		RefreshToken: newRefreshToken,
 		User:         *user,
@@ -177,25 +454,137 @@ func (h *AuthHandler) Refresh(c fiber.Ctx) error {
 
 // Logout godoc
 // @Summary Logout
-// @Description Revoke a refresh token
+// @Description Revoke a refresh token. In cookie mode the refresh token is read from the httpOnly cookie and the cookies are cleared.
 // @Tags Auth
 // @Accept json
 // @Produce json
-// @Param request body dto.RefreshRequest true "Refresh token to revoke"
+// @Param request body dto.RefreshRequest false "Refresh token to revoke (omit in cookie mode)"
+// @Param X-CSRF-Token header string false "CSRF token (cookie mode only)"
 // @Success 204
+// @Failure 403 {object} response.Response
 // @Failure 422 {object} response.Response
 // @Failure 429 {object} response.Response
-// @Router /auth/logout [post]
+// @Router /v1/auth/logout [post]
 func (h *AuthHandler) Logout(c fiber.Ctx) error {
-	var req dto.RefreshRequest
-	if err := bindAndValidate(c, &req); err != nil {
+	refreshToken, err := h.refreshTokenFromRequest(c)
+	if err != nil {
 		return err
 	}
 
-	_ = h.refreshSvc.Revoke(c.Context(), req.RefreshToken)
+	if refreshToken != "" {
+		_ = h.refreshSvc.Revoke(c.Context(), refreshToken)
+	}
+	if h.cookieMode {
+		h.clearRefreshCookies(c)
+	}
 	return response.NoContent(c)
 }
 
+// LogoutAll godoc
+// @Summary Logout from all devices
+// @Description Revoke every refresh token of the authenticated user, invalidate all access tokens issued to them so far and close their WebSocket connections
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 204
+// @Failure 401 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /v1/auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(c fiber.Ctx) error {
+	userID := authUserID(c)
+
+	if err := h.refreshSvc.RevokeAllByUserID(c.Context(), userID); err != nil {
+		return apperror.NewInternal("failed to revoke refresh tokens")
+	}
+	if h.revocations != nil {
+		if err := h.revocations.RevokeUser(c.Context(), userID); err != nil {
+			return apperror.NewInternal("failed to revoke access tokens")
+		}
+	}
+	if h.cookieMode {
+		h.clearRefreshCookies(c)
+	}
+	return response.NoContent(c)
+}
+
+// refreshTokenFromRequest returns the refresh token from the httpOnly cookie in cookie mode
+// (after verifying the CSRF token), or from the JSON body otherwise.
+func (h *AuthHandler) refreshTokenFromRequest(c fiber.Ctx) (string, error) {
+	if !h.cookieMode {
+		var req dto.RefreshRequest
+		if err := bindAndValidate(c, &req); err != nil {
+			return "", err
+		}
+		return req.RefreshToken, nil
+	}
+
+	// Double-submit cookie: the SPA copies the readable csrf_token cookie into the header
+	csrfCookie := c.Cookies(csrfTokenCookieName)
+	csrfHeader := c.Get(csrfTokenHeader)
+	if csrfCookie == "" || subtle.ConstantTimeCompare([]byte(csrfCookie), []byte(csrfHeader)) != 1 {
+		return "", apperror.NewForbidden("invalid CSRF token")
+	}
+
+	return c.Cookies(refreshTokenCookieName), nil
+}
+
+// respondWithTokens writes the token response, moving the refresh token into
+// httpOnly cookies when cookie mode is enabled.
+func (h *AuthHandler) respondWithTokens(c fiber.Ctx, resp *dto.LoginResponse) error {
+	if h.cookieMode {
+		if err := h.setRefreshCookies(c, resp.RefreshToken); err != nil {
+			return err
+		}
+		resp.RefreshToken = ""
+	}
+	return response.Success(c, resp)
+}
+
+// setRefreshCookies sets the httpOnly refresh token cookie and a fresh JS-readable CSRF cookie.
+func (h *AuthHandler) setRefreshCookies(c fiber.Ctx, refreshToken string) error {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return apperror.NewInternal("failed to generate CSRF token")
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     refreshTokenCookieName,
+		Value:    refreshToken,
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: fiber.CookieSameSiteStrictMode,
+		MaxAge:   h.refreshMaxAge,
+		Path:     "/",
+	})
+	c.Cookie(&fiber.Cookie{
+		Name:     csrfTokenCookieName,
+		Value:    hex.EncodeToString(b),
+		HTTPOnly: false,
+		Secure:   true,
+		SameSite: fiber.CookieSameSiteStrictMode,
+		MaxAge:   h.refreshMaxAge,
+		Path:     "/",
+	})
+	return nil
+}
+
+// clearRefreshCookies expires the refresh token and CSRF cookies.
+func (h *AuthHandler) clearRefreshCookies(c fiber.Ctx) {
+	for _, name := range []string{refreshTokenCookieName, csrfTokenCookieName} {
+		c.Cookie(&fiber.Cookie{
+			Name:     name,
+			Value:    "",
+			HTTPOnly: name == refreshTokenCookieName,
+			Secure:   true,
+			SameSite: fiber.CookieSameSiteStrictMode,
+			MaxAge:   -1,
+			Path:     "/",
+			Expires:  time.Now().Add(-1 * time.Hour),
+		})
+	}
+}
+
 // ForgotPassword godoc
 // @Summary Request password reset
 // @Description Send a password reset email
@@ -207,7 +596,7 @@ func (h *AuthHandler) Logout(c fiber.Ctx) error {
 // @Failure 400 {object} response.Response
 // @Failure 422 {object} response.Response
 // @Failure 429 {object} response.Response
-// @Router /auth/forgot-password [post]
+// @Router /v1/auth/forgot-password [post]
 func (h *AuthHandler) ForgotPassword(c fiber.Ctx) error {
 	var req dto.ForgotPasswordRequest
 	if err := bindAndValidate(c, &req); err != nil {
@@ -232,7 +621,7 @@ func (h *AuthHandler) ForgotPassword(c fiber.Ctx) error {
 // @Failure 400 {object} response.Response
 // @Failure 422 {object} response.Response
 // @Failure 429 {object} response.Response
-// @Router /auth/reset-password [post]
+// @Router /v1/auth/reset-password [post]
 func (h *AuthHandler) ResetPassword(c fiber.Ctx) error {
 	var req dto.ResetPasswordRequest
 	if err := bindAndValidate(c, &req); err != nil {
@@ -257,7 +646,7 @@ func (h *AuthHandler) ResetPassword(c fiber.Ctx) error {
 // @Failure 400 {object} response.Response
 // @Failure 422 {object} response.Response
 // @Failure 429 {object} response.Response
-// @Router /auth/verify-email [post]
+// @Router /v1/auth/verify-email [post]
 func (h *AuthHandler) VerifyEmail(c fiber.Ctx) error {
 	var req dto.VerifyEmailRequest
 	if err := bindAndValidate(c, &req); err != nil {
@@ -271,6 +660,31 @@ func (h *AuthHandler) VerifyEmail(c fiber.Ctx) error {
 	return response.Success(c, fiber.Map{"message": "email verified successfully"})
 }
 
+// ConfirmEmailChange godoc
+// @Summary Confirm email change
+// @Description Apply a pending email change using the token sent to the new address
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.ConfirmEmailChangeRequest true "Confirm email change request"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /v1/auth/confirm-email-change [post]
+func (h *AuthHandler) ConfirmEmailChange(c fiber.Ctx) error {
+	var req dto.ConfirmEmailChangeRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.emailChangeSvc.Confirm(c.Context(), req.Token); err != nil {
+		return err
+	}
+
+	return response.Success(c, fiber.Map{"message": "email changed successfully"})
+}
+
 // ResendVerification godoc
 // @Summary Resend verification email
 // @Description Resend email verification link
@@ -282,7 +696,7 @@ func (h *AuthHandler) VerifyEmail(c fiber.Ctx) error {
 // @Failure 400 {object} response.Response
 // @Failure 422 {object} response.Response
 // @Failure 429 {object} response.Response
-// @Router /auth/resend-verification [post]
+// @Router /v1/auth/resend-verification [post]
 func (h *AuthHandler) ResendVerification(c fiber.Ctx) error {
 	var req dto.ResendVerificationRequest
 	if err := bindAndValidate(c, &req); err != nil {
@@ -304,12 +718,68 @@ func (h *AuthHandler) ResendVerification(c fiber.Ctx) error {
 // @Failure 404 {object} response.Response
 // @Failure 429 {object} response.Response
 // @Failure 500 {object} response.Response
-// @Router /auth/google [get]
+// @Router /v1/auth/google [get]
 func (h *AuthHandler) GoogleRedirect(c fiber.Ctx) error {
 	if h.googleOAuth == nil {
 		return apperror.NewNotFound("Google OAuth not configured")
 	}
+	return h.oauthRedirect(c, h.googleOAuth)
+}
+
+// GoogleCallback godoc
+// @Summary Google OAuth callback
+// @Description Handles the callback from Google OAuth, creates/finds user and redirects with tokens
+// @Tags Auth
+// @Param code query string true "Authorization code"
+// @Success 302
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /v1/auth/google/callback [get]
+func (h *AuthHandler) GoogleCallback(c fiber.Ctx) error {
+	if h.googleOAuth == nil {
+		return apperror.NewNotFound("Google OAuth not configured")
+	}
+	return h.oauthCallback(c, h.googleOAuth, h.userSvc.FindOrCreateByGoogle)
+}
+
+// GitHubRedirect godoc
+// @Summary Redirect to GitHub OAuth
+// @Description Redirects the user to GitHub's OAuth authorization screen
+// @Tags Auth
+// @Success 302
+// @Failure 404 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /v1/auth/github [get]
+func (h *AuthHandler) GitHubRedirect(c fiber.Ctx) error {
+	if h.githubOAuth == nil {
+		return apperror.NewNotFound("GitHub OAuth not configured")
+	}
+	return h.oauthRedirect(c, h.githubOAuth)
+}
+
+// GitHubCallback godoc
+// @Summary GitHub OAuth callback
+// @Description Handles the callback from GitHub OAuth, creates/finds user and redirects with tokens
+// @Tags Auth
+// @Param code query string true "Authorization code"
+// @Success 302
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /v1/auth/github/callback [get]
+func (h *AuthHandler) GitHubCallback(c fiber.Ctx) error {
+	if h.githubOAuth == nil {
+		return apperror.NewNotFound("GitHub OAuth not configured")
+	}
+	return h.oauthCallback(c, h.githubOAuth, h.userSvc.FindOrCreateByGitHub)
+}
 
+// oauthRedirect sets the CSRF state cookie and redirects to the provider's consent screen.
+func (h *AuthHandler) oauthRedirect(c fiber.Ctx, provider oauth.Provider) error {
 	b := make([]byte, 16)
 	if _, err := rand.Read(b); err != nil {
 		return apperror.NewInternal("failed to generate state")
@@ -326,25 +796,16 @@ func (h *AuthHandler) GoogleRedirect(c fiber.Ctx) error {
 		Path:     "/",
 	})
 
-	return c.Redirect().To(h.googleOAuth.AuthURL(state))
+	return c.Redirect().To(provider.AuthURL(state))
 }
 
-// GoogleCallback godoc
-// @Summary Google OAuth callback
-// @Description Handles the callback from Google OAuth, creates/finds user and redirects with tokens
-// @Tags Auth
-// @Param code query string true "Authorization code"
-// @Success 302
-// @Failure 400 {object} response.Response
-// @Failure 404 {object} response.Response
-// @Failure 429 {object} response.Response
-// @Failure 500 {object} response.Response
-// @Router /auth/google/callback [get]
-func (h *AuthHandler) GoogleCallback(c fiber.Ctx) error {
-	if h.googleOAuth == nil {
-		return apperror.NewNotFound("Google OAuth not configured")
-	}
-
+// oauthCallback verifies the CSRF state, exchanges the code, finds or creates the user
+// and redirects to the frontend with tokens.
+func (h *AuthHandler) oauthCallback(
+	c fiber.Ctx,
+	provider oauth.Provider,
+	findOrCreate func(ctx context.Context, providerID, email, name string) (*sqlc.User, error),
+) error {
 	// Verify CSRF state
 	state := c.Query("state")
 	cookieState := c.Cookies(oauthStateCookieName)
@@ -369,26 +830,287 @@ func (h *AuthHandler) GoogleCallback(c fiber.Ctx) error {
 		return apperror.NewBadRequest("missing authorization code")
 	}
 
-	info, err := h.googleOAuth.Exchange(c.Context(), code)
+	info, err := provider.Exchange(c.Context(), code)
 	if err != nil {
 		return apperror.NewBadRequest("failed to exchange authorization code")
 	}
 
-	user, err := h.userSvc.FindOrCreateByGoogle(c.Context(), info.ID, info.Email, info.Name)
+	user, err := findOrCreate(c.Context(), info.ID, info.Email, info.Name)
 	if err != nil {
+		h.recordLogin(c, 0, info.Email, provider.Name(), err)
 		return err
 	}
 
-	accessToken, err := token.Generate(user.ID, user.Email, user.Role, h.jwtSecret, h.jwtExpireHour)
+	return h.redirectWithTokens(c, user, provider.Name(), provider.BuildCallbackURL)
+}
+
+// SAMLMetadata godoc
+// @Summary SAML service provider metadata
+// @Description Returns the SP metadata XML to register with the SAML identity provider
+// @Tags Auth
+// @Produce xml
+// @Success 200 {string} string "SP metadata"
+// @Failure 404 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /v1/auth/saml/metadata [get]
+func (h *AuthHandler) SAMLMetadata(c fiber.Ctx) error {
+	if h.samlSP == nil {
+		return apperror.NewNotFound("SAML not configured")
+	}
+
+	data, err := h.samlSP.Metadata()
+	if err != nil {
+		return apperror.NewInternal("failed to build SAML metadata")
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationXMLCharsetUTF8)
+	return c.Send(data)
+}
+
+// SAMLRedirect godoc
+// @Summary Redirect to SAML identity provider
+// @Description Starts SP-initiated SAML login by redirecting to the identity provider
+// @Tags Auth
+// @Success 302
+// @Failure 404 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /v1/auth/saml/login [get]
+func (h *AuthHandler) SAMLRedirect(c fiber.Ctx) error {
+	if h.samlSP == nil {
+		return apperror.NewNotFound("SAML not configured")
+	}
+
+	redirectURL, requestID, err := h.samlSP.AuthRequest("")
+	if err != nil {
+		return apperror.NewInternal("failed to create SAML request")
+	}
+
+	// The IdP posts the response back cross-site, so the cookie must be SameSite=None
+	c.Cookie(&fiber.Cookie{
+		Name:     samlRequestCookieName,
+		Value:    requestID,
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: fiber.CookieSameSiteNoneMode,
+		MaxAge:   300, // 5 minutes
+		Path:     "/",
+	})
+
+	return c.Redirect().To(redirectURL)
+}
+
+// SAMLACS godoc
+// @Summary SAML assertion consumer service
+// @Description Validates the SAML response posted by the identity provider, creates/finds user and redirects with tokens
+// @Tags Auth
+// @Accept x-www-form-urlencoded
+// @Param SAMLResponse formData string true "Base64-encoded SAML response"
+// @Success 302
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /v1/auth/saml/acs [post]
+func (h *AuthHandler) SAMLACS(c fiber.Ctx) error {
+	if h.samlSP == nil {
+		return apperror.NewNotFound("SAML not configured")
+	}
+
+	samlResponse := c.FormValue("SAMLResponse")
+	if samlResponse == "" {
+		return apperror.NewBadRequest("missing SAML response")
+	}
+
+	var possibleRequestIDs []string
+	if requestID := c.Cookies(samlRequestCookieName); requestID != "" {
+		possibleRequestIDs = append(possibleRequestIDs, requestID)
+	}
+
+	// Clear request cookie
+	c.Cookie(&fiber.Cookie{
+		Name:     samlRequestCookieName,
+		Value:    "",
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: fiber.CookieSameSiteNoneMode,
+		MaxAge:   -1,
+		Path:     "/",
+		Expires:  time.Now().Add(-1 * time.Hour),
+	})
+
+	info, err := h.samlSP.ParseResponse(samlResponse, possibleRequestIDs)
+	if err != nil {
+		slog.Warn("rejected SAML response", slog.Any("error", err))
+		return apperror.NewBadRequest("invalid SAML response")
+	}
+
+	user, err := h.userSvc.FindOrCreateBySAML(c.Context(), info.ID, info.Email, info.Name)
+	if err != nil {
+		h.recordLogin(c, 0, info.Email, h.samlSP.Name(), err)
+		return err
+	}
+
+	return h.redirectWithTokens(c, user, h.samlSP.Name(), h.samlSP.BuildCallbackURL)
+}
+
+// redirectWithTokens issues tokens for a user signed in through an external identity
+// provider, records the login and redirects to the frontend callback URL.
+func (h *AuthHandler) redirectWithTokens(
+	c fiber.Ctx,
+	user *sqlc.User,
+	method string,
+	buildCallbackURL func(accessToken, refreshToken string) string,
+) error {
+	accessToken, err := token.Generate(user.ID, user.Email, user.Username.String, user.Role, dto.ScopesForRole(user.Role), h.jwtConfig, h.jwtExpireHour)
 	if err != nil {
 		return apperror.NewInternal("failed to generate token")
 	}
 
-	refreshToken, err := h.refreshSvc.Create(c.Context(), user.ID)
+	// Browser redirects cannot carry the fingerprint header, so these tokens are not device-bound
+	refreshToken, err := h.refreshSvc.Create(c.Context(), user.ID, "")
 	if err != nil {
 		return apperror.NewInternal("failed to generate refresh token")
 	}
 
-	redirectURL := h.googleOAuth.BuildCallbackURL(accessToken, refreshToken)
-	return c.Redirect().To(redirectURL)
+	// In cookie mode the refresh token never appears in the redirect URL
+	if h.cookieMode {
+		if err := h.setRefreshCookies(c, refreshToken); err != nil {
+			return err
+		}
+		refreshToken = ""
+	}
+
+	h.recordLogin(c, user.ID, user.Email, method, nil)
+
+	return c.Redirect().To(buildCallbackURL(accessToken, refreshToken))
+}
+
+// WebAuthnRegisterBegin godoc
+// @Summary Begin passkey registration
+// @Description Returns WebAuthn credential creation options for the authenticated user
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=dto.WebAuthnBeginResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /v1/auth/webauthn/register/begin [post]
+func (h *AuthHandler) WebAuthnRegisterBegin(c fiber.Ctx) error {
+	if h.webauthnSvc == nil {
+		return apperror.NewNotFound("WebAuthn not configured")
+	}
+
+	options, sessionID, err := h.webauthnSvc.BeginRegistration(c.Context(), authUserID(c))
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, dto.WebAuthnBeginResponse{
+		SessionID: sessionID,
+		Options:   options,
+	})
+}
+
+// WebAuthnRegisterFinish godoc
+// @Summary Finish passkey registration
+// @Description Verifies the authenticator attestation and stores the new passkey
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.WebAuthnRegisterFinishRequest true "Attestation response"
+// @Success 201 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /v1/auth/webauthn/register/finish [post]
+func (h *AuthHandler) WebAuthnRegisterFinish(c fiber.Ctx) error {
+	if h.webauthnSvc == nil {
+		return apperror.NewNotFound("WebAuthn not configured")
+	}
+
+	var req dto.WebAuthnRegisterFinishRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.webauthnSvc.FinishRegistration(c.Context(), authUserID(c), req.SessionID, req.Name, req.Credential); err != nil {
+		return err
+	}
+
+	return response.Created(c, fiber.Map{"message": "passkey registered successfully"})
+}
+
+// WebAuthnLoginBegin godoc
+// @Summary Begin passkey login
+// @Description Returns WebAuthn assertion options for a discoverable (usernameless) passkey login
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} response.Response{data=dto.WebAuthnBeginResponse}
+// @Failure 404 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /v1/auth/webauthn/login/begin [post]
+func (h *AuthHandler) WebAuthnLoginBegin(c fiber.Ctx) error {
+	if h.webauthnSvc == nil {
+		return apperror.NewNotFound("WebAuthn not configured")
+	}
+
+	options, sessionID, err := h.webauthnSvc.BeginLogin(c.Context())
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, dto.WebAuthnBeginResponse{
+		SessionID: sessionID,
+		Options:   options,
+	})
+}
+
+// WebAuthnLoginFinish godoc
+// @Summary Finish passkey login
+// @Description Verifies the authenticator assertion and returns access + refresh tokens
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.WebAuthnLoginFinishRequest true "Assertion response"
+// @Param X-Device-Fingerprint header string false "Optional device fingerprint; the refresh token is then only accepted from the same device"
+// @Success 200 {object} response.Response{data=dto.LoginResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /v1/auth/webauthn/login/finish [post]
+func (h *AuthHandler) WebAuthnLoginFinish(c fiber.Ctx) error {
+	if h.webauthnSvc == nil {
+		return apperror.NewNotFound("WebAuthn not configured")
+	}
+
+	var req dto.WebAuthnLoginFinishRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	user, err := h.webauthnSvc.FinishLogin(c.Context(), req.SessionID, req.Credential)
+	if err != nil {
+		h.recordLogin(c, 0, "", dto.LoginMethodPasskey, err)
+		return err
+	}
+
+	resp, err := h.issueTokens(c.Context(), user, c.Get(deviceFingerprintHeader))
+	if err != nil {
+		return err
+	}
+
+	h.recordLogin(c, user.ID, user.Email, dto.LoginMethodPasskey, nil)
+	return h.respondWithTokens(c, resp)
 }
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/response"
+)
+
+// batchSkippedHeaders are the headers of a batch request that are not passed on to its
+// sub-requests, because they describe the batch body or response, or belong to a single
+// request. Sub-requests are not compressed, so their JSON can be embedded.
+var batchSkippedHeaders = []string{
+	fiber.HeaderContentType,
+	fiber.HeaderContentLength,
+	fiber.HeaderContentEncoding,
+	fiber.HeaderTransferEncoding,
+	fiber.HeaderAcceptEncoding,
+	fiber.HeaderIfNoneMatch,
+	fiber.HeaderIfMatch,
+	fiber.HeaderXRequestID,
+	"Idempotency-Key",
+	"Traceparent",
+	"Tracestate",
+}
+
+type BatchHandler struct {
+	maxRequests int
+	serve       fasthttp.RequestHandler
+}
+
+func NewBatchHandler(maxRequests int) *BatchHandler {
+	return &BatchHandler{maxRequests: maxRequests}
+}
+
+// Bind sets the app that runs sub-requests. It must be called once every route is
+// registered and before the app serves requests.
+func (h *BatchHandler) Bind(app *fiber.App) {
+	h.serve = app.Handler()
+}
+
+// Execute godoc
+// @Summary Run several requests at once
+// @Description Run up to BATCH_MAX_REQUESTS API requests one after another, in order, and return the status and JSON body of each, saving round trips. Each request goes through the same middleware as on its own, including authentication and rate limits, with the headers of the batch request, such as Authorization. A failed request does not stop the others; requests left when the batch times out are not run and have status 503. Requests must target /api/ routes other than /batch; bodies that are not JSON, such as downloads, are left out.
+// @Tags Batch
+// @Accept json
+// @Produce json
+// @Param request body dto.BatchRequest true "Requests to run"
+// @Success 200 {object} response.Response{data=[]dto.BatchResult}
+// @Failure 400 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/batch [post]
+func (h *BatchHandler) Execute(c fiber.Ctx) error {
+	var req dto.BatchRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+	if len(req.Requests) > h.maxRequests {
+		return apperror.NewBadRequest(fmt.Sprintf("a batch can hold at most %d requests", h.maxRequests))
+	}
+	for _, item := range req.Requests {
+		u, err := url.Parse(item.Path)
+		if err != nil {
+			return apperror.NewBadRequest("invalid request path: " + item.Path)
+		}
+		// Checked once cleaned, as the router matches it, so /api/../metrics is rejected
+		if p := path.Clean(u.Path); !strings.HasPrefix(p, "/api/") || path.Base(p) == "batch" {
+			return apperror.NewBadRequest("invalid request path: " + item.Path)
+		}
+	}
+
+	// Sub-requests continue the trace of the batch request.
+	trace := http.Header{}
+	otel.GetTextMapPropagator().Inject(c.Context(), propagation.HeaderCarrier(trace))
+
+	results := make([]dto.BatchResult, len(req.Requests))
+	for i, item := range req.Requests {
+		// Requests left when the batch runs out of time are not run.
+		if c.Context().Err() != nil {
+			results[i] = dto.BatchResult{Status: fiber.StatusServiceUnavailable}
+			continue
+		}
+		results[i] = h.run(c, item, trace)
+	}
+	return response.Success(c, results)
+}
+
+// run sends item through the app, as if it came on the connection of the request c is
+// handling.
+func (h *BatchHandler) run(c fiber.Ctx, item dto.BatchItem, trace http.Header) dto.BatchResult {
+	var sub fasthttp.Request
+	for key, value := range c.Request().Header.All() {
+		if !isBatchSkippedHeader(string(key)) {
+			sub.Header.SetBytesKV(key, value)
+		}
+	}
+	for key := range trace {
+		sub.Header.Set(key, trace.Get(key))
+	}
+	sub.Header.SetMethod(item.Method)
+	sub.SetRequestURI(item.Path)
+	if len(item.Body) > 0 {
+		sub.Header.SetContentType(fiber.MIMEApplicationJSON)
+		sub.SetBody(item.Body)
+	}
+
+	var ctx fasthttp.RequestCtx
+	ctx.Init(&sub, c.RequestCtx().RemoteAddr(), nil)
+	h.serve(&ctx)
+
+	resp := &ctx.Response
+	result := dto.BatchResult{Status: resp.StatusCode()}
+	mediaType, _, _ := mime.ParseMediaType(string(resp.Header.ContentType()))
+	if mediaType != fiber.MIMEApplicationJSON && mediaType != response.MIMEApplicationProblemJSON {
+		_ = resp.CloseBodyStream()
+		return result
+	}
+	if body := resp.Body(); json.Valid(body) {
+		result.Body = append(json.RawMessage(nil), body...)
+	}
+	return result
+}
+
+func isBatchSkippedHeader(key string) bool {
+	for _, skipped := range batchSkippedHeaders {
+		if strings.EqualFold(key, skipped) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/service"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/response"
+)
+
+type BroadcastHandler struct {
+	service service.BroadcastService
+}
+
+func NewBroadcastHandler(svc service.BroadcastService) *BroadcastHandler {
+	return &BroadcastHandler{service: svc}
+}
+
+// Send godoc
+// @Summary Broadcast an email
+// @Description Email an announcement to every active user, or those matching the segment, in the background (requires broadcasts:send). Subject and body are Go templates with {{.Name}} and {{.Email}}; the body is HTML. Banned and guest users are never included, and subscribers_only limits the broadcast to users who opted in to product updates.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.BroadcastRequest true "Broadcast"
+// @Success 202 {object} response.Response{data=dto.BroadcastResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/admin/broadcast [post]
+func (h *BroadcastHandler) Send(c fiber.Ctx) error {
+	var req dto.BroadcastRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	broadcast, err := h.service.Send(auditContext(c), authUserID(c), req)
+	if err != nil {
+		return err
+	}
+
+	return response.Accepted(c, broadcast)
+}
+
+// List godoc
+// @Summary List broadcasts
+// @Description Get a paginated list of broadcasts, newest first, with their delivery progress (requires broadcasts:send)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(10)
+// @Success 200 {object} response.Response{data=[]dto.BroadcastResponse,meta=response.Meta}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /v1/admin/broadcasts [get]
+func (h *BroadcastHandler) List(c fiber.Ctx) error {
+	page, perPage, err := paginationQuery(c)
+	if err != nil {
+		return err
+	}
+
+	broadcasts, total, err := h.service.List(c.Context(), page, perPage)
+	if err != nil {
+		return err
+	}
+
+	return response.SuccessWithMeta(c, broadcasts, response.NewMeta(page, perPage, total))
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/csv"
+	"strings"
+)
+
+// exportFlushEvery is the number of rows buffered before they are flushed to the client.
+// A failed flush means the client went away and aborts the export.
+const exportFlushEvery = 500
+
+// csvStream writes CSV rows to a streamed response body, flushing every exportFlushEvery
+// rows so a long export reaches the client while it is still being read.
+type csvStream struct {
+	w    *bufio.Writer
+	cw   *csv.Writer
+	rows int
+}
+
+// newCSVStream writes the header row and returns a stream for the records that follow.
+func newCSVStream(w *bufio.Writer, header []string) (*csvStream, error) {
+	s := &csvStream{w: w, cw: csv.NewWriter(w)}
+	if err := s.cw.Write(header); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Write adds a record.
+func (s *csvStream) Write(record []string) error {
+	if err := s.cw.Write(record); err != nil {
+		return err
+	}
+	s.rows++
+	if s.rows%exportFlushEvery == 0 {
+		return s.Flush()
+	}
+	return nil
+}
+
+// Flush sends the buffered rows to the client. Call it once after the last record.
+func (s *csvStream) Flush() error {
+	s.cw.Flush()
+	if err := s.cw.Error(); err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+// csvSafe neutralizes values that spreadsheet applications would evaluate as formulas.
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
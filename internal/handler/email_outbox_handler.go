@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/service"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/response"
+)
+
+type EmailOutboxHandler struct {
+	service service.EmailOutboxService
+}
+
+func NewEmailOutboxHandler(svc service.EmailOutboxService) *EmailOutboxHandler {
+	return &EmailOutboxHandler{service: svc}
+}
+
+// List godoc
+// @Summary List queued emails
+// @Description Get a paginated list of emails in the outbox, newest first, with their delivery status, attempts and last error (requires emails:manage). Bodies are not included.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Only emails in this status" Enums(pending, sending, sent, dead)
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(10)
+// @Success 200 {object} response.Response{data=[]dto.EmailOutboxResponse,meta=response.Meta}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/admin/emails [get]
+func (h *EmailOutboxHandler) List(c fiber.Ctx) error {
+	page, perPage, err := paginationQuery(c)
+	if err != nil {
+		return err
+	}
+
+	var query dto.EmailOutboxQuery
+	if err := bindQueryAndValidate(c, &query); err != nil {
+		return err
+	}
+
+	emails, total, err := h.service.List(c.Context(), query, page, perPage)
+	if err != nil {
+		return err
+	}
+
+	return response.SuccessWithMeta(c, emails, response.NewMeta(page, perPage, total))
+}
+
+// Retry godoc
+// @Summary Retry a dead email
+// @Description Queue an email that failed permanently or ran out of attempts again, with a fresh set of attempts (requires emails:manage)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Email ID"
+// @Success 200 {object} response.Response{data=dto.EmailOutboxResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/admin/emails/{id}/retry [post]
+func (h *EmailOutboxHandler) Retry(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	email, err := h.service.Retry(auditContext(c), id)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, email)
+}
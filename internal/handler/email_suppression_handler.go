@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/service"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/response"
+)
+
+type EmailSuppressionHandler struct {
+	service service.EmailSuppressionService
+}
+
+func NewEmailSuppressionHandler(svc service.EmailSuppressionService) *EmailSuppressionHandler {
+	return &EmailSuppressionHandler{service: svc}
+}
+
+// Webhook godoc
+// @Summary Receive email bounces and complaints
+// @Description Endpoint for the email provider's event webhook (sendgrid, ses through an SNS HTTPS subscription, mailgun or postmark). Hard bounces and spam complaints suppress the address; other events are ignored, and an SNS subscription confirmation is confirmed. Authenticate with HTTP basic auth using EMAIL_WEBHOOK_SECRET as the password, e.g. by putting it in the webhook URL.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param provider path string true "Email provider" Enums(sendgrid, ses, mailgun, postmark)
+// @Success 200 {object} response.Response{data=dto.EmailWebhookResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/webhooks/email/{provider} [post]
+func (h *EmailSuppressionHandler) Webhook(c fiber.Ctx) error {
+	n, err := h.service.HandleWebhook(c.Context(), c.Params("provider"), basicAuthPassword(c), c.Body())
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, dto.EmailWebhookResponse{Suppressed: n})
+}
+
+// List godoc
+// @Summary List suppressed emails
+// @Description Get a paginated list of addresses nothing is sent to after a hard bounce or a spam complaint, most recently reported first (requires emails:manage)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param reason query string false "Only addresses suppressed for this reason" Enums(bounce, complaint)
+// @Param search query string false "Only addresses containing this text"
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(10)
+// @Success 200 {object} response.Response{data=[]dto.EmailSuppressionResponse,meta=response.Meta}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/admin/email-suppressions [get]
+func (h *EmailSuppressionHandler) List(c fiber.Ctx) error {
+	page, perPage, err := paginationQuery(c)
+	if err != nil {
+		return err
+	}
+
+	var query dto.EmailSuppressionQuery
+	if err := bindQueryAndValidate(c, &query); err != nil {
+		return err
+	}
+
+	items, total, err := h.service.List(c.Context(), query, page, perPage)
+	if err != nil {
+		return err
+	}
+
+	return response.SuccessWithMeta(c, items, response.NewMeta(page, perPage, total))
+}
+
+// Remove godoc
+// @Summary Remove a suppressed email
+// @Description Let mail reach a suppressed address again, e.g. after the recipient fixed their mailbox (requires emails:manage)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Suppression ID"
+// @Success 204
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/admin/email-suppressions/{id} [delete]
+func (h *EmailSuppressionHandler) Remove(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.service.Remove(auditContext(c), id); err != nil {
+		return err
+	}
+
+	return response.NoContent(c)
+}
+
+// basicAuthPassword returns the password of an HTTP basic Authorization header, or ""
+// when there is none.
+func basicAuthPassword(c fiber.Ctx) string {
+	encoded, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Basic ")
+	if !ok {
+		return ""
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return ""
+	}
+	_, password, _ := strings.Cut(string(decoded), ":")
+	return password
+}
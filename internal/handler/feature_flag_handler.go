@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/service"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/response"
+)
+
+type FeatureFlagHandler struct {
+	service service.FeatureFlagService
+}
+
+func NewFeatureFlagHandler(svc service.FeatureFlagService) *FeatureFlagHandler {
+	return &FeatureFlagHandler{service: svc}
+}
+
+// GetFeatures godoc
+// @Summary Get feature flags
+// @Description Get whether each feature flag is on, for clients to toggle UI with. Flags that are not listed are off. No authentication required.
+// @Tags Features
+// @Produce json
+// @Success 200 {object} response.Response{data=dto.FeaturesResponse}
+// @Router /v1/features [get]
+func (h *FeatureFlagHandler) GetFeatures(c fiber.Ctx) error {
+	features, err := h.service.Features(c.Context())
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, dto.FeaturesResponse{Features: features})
+}
+
+// List godoc
+// @Summary List feature flags
+// @Description List every feature flag by name (requires flags:manage)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=[]dto.FeatureFlagResponse}
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /v1/admin/feature-flags [get]
+func (h *FeatureFlagHandler) List(c fiber.Ctx) error {
+	flags, err := h.service.List(c.Context())
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, flags)
+}
+
+// Create godoc
+// @Summary Create a feature flag
+// @Description Create a feature flag, off unless enabled is true (requires flags:manage). Names are lowercase slugs and unique.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.CreateFeatureFlagRequest true "Feature flag"
+// @Success 201 {object} response.Response{data=dto.FeatureFlagResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/admin/feature-flags [post]
+func (h *FeatureFlagHandler) Create(c fiber.Ctx) error {
+	var req dto.CreateFeatureFlagRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	flag, err := h.service.Create(auditContext(c), req)
+	if err != nil {
+		return err
+	}
+
+	return response.Created(c, flag)
+}
+
+// Update godoc
+// @Summary Update a feature flag
+// @Description Turn a feature flag on or off or change its description; omitted fields are kept (requires flags:manage)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Feature flag ID"
+// @Param request body dto.UpdateFeatureFlagRequest true "Changes"
+// @Success 200 {object} response.Response{data=dto.FeatureFlagResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/admin/feature-flags/{id} [put]
+func (h *FeatureFlagHandler) Update(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req dto.UpdateFeatureFlagRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	flag, err := h.service.Update(auditContext(c), id, req)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, flag)
+}
+
+// Delete godoc
+// @Summary Delete a feature flag
+// @Description Delete a feature flag; features gated by it are off afterwards (requires flags:manage)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Feature flag ID"
+// @Success 204
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/admin/feature-flags/{id} [delete]
+func (h *FeatureFlagHandler) Delete(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.service.Delete(auditContext(c), id); err != nil {
+		return err
+	}
+
+	return response.NoContent(c)
+}
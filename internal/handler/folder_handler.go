@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/service"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/response"
+)
+
+type FolderHandler struct {
+	service service.FolderService
+}
+
+func NewFolderHandler(svc service.FolderService) *FolderHandler {
+	return &FolderHandler{service: svc}
+}
+
+// Create godoc
+// @Summary Create a folder
+// @Description Create a folder at the top level or inside one of your folders. Names must be unique within their parent.
+// @Tags Folders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.CreateFolderRequest true "Folder"
+// @Success 201 {object} response.Response{data=dto.FolderResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/folders [post]
+func (h *FolderHandler) Create(c fiber.Ctx) error {
+	var req dto.CreateFolderRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	folder, err := h.service.Create(c.Context(), authUserID(c), req)
+	if err != nil {
+		return err
+	}
+
+	return response.Created(c, folder)
+}
+
+// List godoc
+// @Summary List folders
+// @Description List the folders directly inside a folder, or your top-level folders when parent_id is omitted
+// @Tags Folders
+// @Produce json
+// @Security BearerAuth
+// @Param parent_id query int false "Parent folder ID"
+// @Success 200 {object} response.Response{data=[]dto.FolderResponse}
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/folders [get]
+func (h *FolderHandler) List(c fiber.Ctx) error {
+	var query dto.FolderListQuery
+	if err := bindQueryAndValidate(c, &query); err != nil {
+		return err
+	}
+
+	folders, err := h.service.List(c.Context(), authUserID(c), query.ParentID)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, folders)
+}
+
+// Get godoc
+// @Summary Get a folder
+// @Description Get one of your folders
+// @Tags Folders
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Folder ID"
+// @Success 200 {object} response.Response{data=dto.FolderResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/folders/{id} [get]
+func (h *FolderHandler) Get(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	folder, err := h.service.Get(c.Context(), id, authUserID(c))
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, folder)
+}
+
+// Rename godoc
+// @Summary Rename a folder
+// @Description Rename one of your folders
+// @Tags Folders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Folder ID"
+// @Param request body dto.RenameFolderRequest true "New name"
+// @Success 200 {object} response.Response{data=dto.FolderResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/folders/{id} [put]
+func (h *FolderHandler) Rename(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req dto.RenameFolderRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	folder, err := h.service.Rename(c.Context(), id, authUserID(c), req)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, folder)
+}
+
+// Move godoc
+// @Summary Move a folder
+// @Description Move a folder and its contents inside another of your folders, or to the top level with a null parent_id
+// @Tags Folders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Folder ID"
+// @Param request body dto.MoveFolderRequest true "New parent"
+// @Success 200 {object} response.Response{data=dto.FolderResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/folders/{id}/move [post]
+func (h *FolderHandler) Move(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req dto.MoveFolderRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	folder, err := h.service.Move(c.Context(), id, authUserID(c), req)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, folder)
+}
+
+// Delete godoc
+// @Summary Delete a folder
+// @Description Delete an empty folder. Move or delete its subfolders and files first.
+// @Tags Folders
+// @Security BearerAuth
+// @Param id path int true "Folder ID"
+// @Success 204
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /v1/folders/{id} [delete]
+func (h *FolderHandler) Delete(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.service.Delete(c.Context(), id, authUserID(c)); err != nil {
+		return err
+	}
+
+	return response.NoContent(c)
+}
@@ -1,32 +1,48 @@
 package handler
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"net"
 	"net/http"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/fasthttp/websocket"
 	"github.com/gofiber/fiber/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/middleware"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/service"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/realtime"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/token"
 )
 
+// testJWTConfig signs and checks the access tokens of handler tests.
+var testJWTConfig = config.JWTConfig{Secret: "test-secret", Issuer: "test-issuer", Audience: "test-api"}
+
 // mockUserService is a manual mock for testing handlers.
 type mockUserService struct {
-	users map[int64]*dto.UserResponse
+	users     map[int64]*dto.UserResponse
+	lastQuery dto.UserFilterQuery
 }
 
 func newMockService() *mockUserService {
 	return &mockUserService{
 		users: map[int64]*dto.UserResponse{
-			1: {ID: 1, Email: "test@example.com", Name: "Test User", Role: "user"},
+			1: {ID: 1, Email: "test@example.com", Username: "tester", Name: "Test User", Role: "user"},
 		},
 	}
 }
@@ -50,7 +66,17 @@ func (m *mockUserService) GetByID(_ context.Context, id int64) (*dto.UserRespons
 	return user, nil
 }
 
-func (m *mockUserService) List(_ context.Context, _, _ int) ([]dto.UserResponse, int64, error) {
+func (m *mockUserService) GetByUsername(_ context.Context, username string) (*dto.PublicUserResponse, error) {
+	for _, u := range m.users {
+		if u.Username != "" && strings.EqualFold(u.Username, username) {
+			return &dto.PublicUserResponse{ID: u.ID, Username: u.Username, Name: u.Name, CreatedAt: u.CreatedAt}, nil
+		}
+	}
+	return nil, apperror.NewNotFound("user not found")
+}
+
+func (m *mockUserService) List(_ context.Context, query dto.UserFilterQuery, _, _ int) ([]dto.UserResponse, int64, error) {
+	m.lastQuery = query
 	users := make([]dto.UserResponse, 0, len(m.users))
 	for _, u := range m.users {
 		users = append(users, *u)
@@ -81,6 +107,14 @@ func (m *mockUserService) FindOrCreateByGoogle(_ context.Context, _, email, name
 	return &sqlc.User{ID: 1, Email: email, Name: name, Role: "user"}, nil
 }
 
+func (m *mockUserService) FindOrCreateByGitHub(_ context.Context, _, email, name string) (*sqlc.User, error) {
+	return &sqlc.User{ID: 1, Email: email, Name: name, Role: "user"}, nil
+}
+
+func (m *mockUserService) FindOrCreateBySAML(_ context.Context, _, email, name string) (*sqlc.User, error) {
+	return &sqlc.User{ID: 1, Email: email, Name: name, Role: "user"}, nil
+}
+
 func (m *mockUserService) ChangePassword(_ context.Context, _ int64, _ dto.ChangePasswordRequest) error {
 	return nil
 }
@@ -88,17 +122,21 @@ func (m *mockUserService) ChangePassword(_ context.Context, _ int64, _ dto.Chang
 // mockRefreshTokenService is a manual mock for testing handlers.
 type mockRefreshTokenService struct{}
 
-func (m *mockRefreshTokenService) Create(_ context.Context, _ int64) (string, error) {
+func (m *mockRefreshTokenService) Create(_ context.Context, _ int64, _ string) (string, error) {
 	return "mock-refresh-token", nil
 }
 
-func (m *mockRefreshTokenService) Verify(_ context.Context, tokenStr string) (*sqlc.RefreshToken, error) {
+func (m *mockRefreshTokenService) Verify(_ context.Context, tokenStr, _ string) (*sqlc.RefreshToken, error) {
 	if tokenStr == "valid-refresh-token" {
 		return &sqlc.RefreshToken{ID: 1, UserID: 1, Token: tokenStr}, nil
 	}
 	return nil, apperror.NewUnauthorized("invalid refresh token")
 }
 
+func (m *mockRefreshTokenService) Rotate(_ context.Context, _ *sqlc.RefreshToken) (string, error) {
+	return "mock-refresh-token", nil
+}
+
 func (m *mockRefreshTokenService) Revoke(_ context.Context, _ string) error {
 	return nil
 }
@@ -133,7 +171,141 @@ func (m *mockEmailVerificationService) ResendVerification(_ context.Context, _ s
 	return nil
 }
 
+func (m *mockEmailVerificationService) MarkVerified(_ context.Context, _, userID int64) (*dto.UserResponse, error) {
+	return &dto.UserResponse{ID: userID, EmailVerified: true}, nil
+}
+
+func (m *mockEmailVerificationService) SendToUser(_ context.Context, _, _ int64) error {
+	return nil
+}
+
+// mockEmailChangeService is a manual mock for testing handlers.
+type mockEmailChangeService struct {
+	pending map[int64]string
+}
+
+func (m *mockEmailChangeService) RequestChange(_ context.Context, userID int64, newEmail string) error {
+	m.pending[userID] = newEmail
+	return nil
+}
+
+func (m *mockEmailChangeService) Confirm(_ context.Context, tokenStr string) error {
+	if tokenStr == "valid-token" {
+		return nil
+	}
+	return apperror.NewBadRequest("invalid or expired email change token")
+}
+
+// mockUserSettingsService is a manual mock for testing handlers.
+type mockUserSettingsService struct {
+	settings map[int64]*dto.UserSettingsResponse
+}
+
+func (m *mockUserSettingsService) Get(_ context.Context, userID int64) (*dto.UserSettingsResponse, error) {
+	if s, ok := m.settings[userID]; ok {
+		return s, nil
+	}
+	return &dto.UserSettingsResponse{Locale: dto.DefaultLocale, Timezone: dto.DefaultTimezone, EmailSecurityAlerts: true}, nil
+}
+
+func (m *mockUserSettingsService) Update(ctx context.Context, userID int64, req dto.UpdateUserSettingsRequest) (*dto.UserSettingsResponse, error) {
+	s, _ := m.Get(ctx, userID)
+	if req.Locale != nil {
+		s.Locale = *req.Locale
+	}
+	if req.Timezone != nil {
+		s.Timezone = *req.Timezone
+	}
+	if req.EmailSecurityAlerts != nil {
+		s.EmailSecurityAlerts = *req.EmailSecurityAlerts
+	}
+	if req.EmailProductUpdates != nil {
+		s.EmailProductUpdates = *req.EmailProductUpdates
+	}
+	m.settings[userID] = s
+	return s, nil
+}
+
+// mockUserActivityService is a manual mock for testing handlers. Activity is recorded
+// asynchronously, so recorded entries are delivered on a buffered channel.
+type mockUserActivityService struct {
+	recorded chan service.Activity
+}
+
+func newMockUserActivityService() *mockUserActivityService {
+	return &mockUserActivityService{recorded: make(chan service.Activity, 10)}
+}
+
+func (m *mockUserActivityService) Record(_ context.Context, activity service.Activity) error {
+	m.recorded <- activity
+	return nil
+}
+
+func (m *mockUserActivityService) ListByUser(_ context.Context, userID int64, _, _ int) ([]dto.UserActivityResponse, int64, error) {
+	return []dto.UserActivityResponse{{ID: 1, Action: dto.ActivityPasswordChanged, ActorID: &userID}}, 1, nil
+}
+
+// mockGuestService is a manual mock for testing handlers.
+type mockGuestService struct{}
+
+func (m *mockGuestService) Create(_ context.Context) (*sqlc.User, error) {
+	return &sqlc.User{ID: 10, Email: "guest-1@guest.invalid", Name: "Guest", Role: "guest"}, nil
+}
+
+func (m *mockGuestService) Upgrade(_ context.Context, guestID int64, req dto.RegisterRequest) (*sqlc.User, error) {
+	return &sqlc.User{ID: guestID, Email: req.Email, Name: req.Name, Role: "user"}, nil
+}
+
+// mockTwoFactorService is a manual mock for testing handlers. User 1 has two-factor
+// authentication enabled, with challenge "challenge" answered by code 123456.
+type mockTwoFactorService struct{}
+
+func (m *mockTwoFactorService) Setup(_ context.Context, _ int64) (*dto.TwoFactorSetupResponse, error) {
+	return &dto.TwoFactorSetupResponse{Secret: "SECRET", URI: "otpauth://totp/Test:test@example.com?secret=SECRET"}, nil
+}
+
+func (m *mockTwoFactorService) Enable(_ context.Context, _ int64, code string) (*dto.RecoveryCodesResponse, error) {
+	if code != "123456" {
+		return nil, apperror.NewUnauthorized("invalid two-factor code")
+	}
+	return &dto.RecoveryCodesResponse{RecoveryCodes: []string{"abcde-fghij"}}, nil
+}
+
+func (m *mockTwoFactorService) Disable(_ context.Context, _ int64, _ dto.TwoFactorConfirmRequest) error {
+	return nil
+}
+
+func (m *mockTwoFactorService) RegenerateRecoveryCodes(_ context.Context, _ int64, _ dto.TwoFactorConfirmRequest) (*dto.RecoveryCodesResponse, error) {
+	return &dto.RecoveryCodesResponse{RecoveryCodes: []string{"abcde-fghij"}}, nil
+}
+
+func (m *mockTwoFactorService) Status(_ context.Context, _ int64) (*dto.RecoveryCodesStatusResponse, error) {
+	return &dto.RecoveryCodesStatusResponse{}, nil
+}
+
+func (m *mockTwoFactorService) Challenge(_ context.Context, userID int64) (*dto.TwoFactorChallengeResponse, error) {
+	if userID != 1 {
+		return nil, nil
+	}
+	return &dto.TwoFactorChallengeResponse{TwoFactorRequired: true, ChallengeToken: "challenge", ExpiresAt: time.Now().Add(time.Minute)}, nil
+}
+
+func (m *mockTwoFactorService) Verify(_ context.Context, req dto.TwoFactorLoginRequest) (*sqlc.User, error) {
+	if req.ChallengeToken != "challenge" || req.Code != "123456" {
+		return nil, apperror.NewUnauthorized("invalid two-factor code")
+	}
+	return &sqlc.User{ID: 1, Email: "test@example.com", Name: "Test User", Role: "user"}, nil
+}
+
 func setupApp(svc *mockUserService) *fiber.App {
+	return setupAppWithCookieMode(svc, false)
+}
+
+func setupAppWithCookieMode(svc *mockUserService, cookieMode bool) *fiber.App {
+	return setupAppWithActivity(svc, cookieMode, newMockUserActivityService())
+}
+
+func setupAppWithActivity(svc *mockUserService, cookieMode bool, activitySvc *mockUserActivityService) *fiber.App {
 	app := fiber.New(fiber.Config{
 		ErrorHandler: apperror.FiberErrorHandler,
 	})
@@ -141,8 +313,10 @@ func setupApp(svc *mockUserService) *fiber.App {
 	refreshSvc := &mockRefreshTokenService{}
 	resetSvc := &mockPasswordResetService{}
 	emailVerifSvc := &mockEmailVerificationService{}
-	authHandler := NewAuthHandler(svc, refreshSvc, resetSvc, emailVerifSvc, "test-secret", 24, nil)
-	userHandler := NewUserHandler(svc)
+	emailChangeSvc := &mockEmailChangeService{pending: map[int64]string{}}
+	authHandler := NewAuthHandler(svc, refreshSvc, resetSvc, emailVerifSvc, testJWTConfig, 24, cookieMode, 30, nil, nil, nil, nil, nil, emailChangeSvc, &mockGuestService{}, 24, nil, nil, nil)
+	settingsSvc := &mockUserSettingsService{settings: map[int64]*dto.UserSettingsResponse{}}
+	userHandler := NewUserHandler(svc, nil, emailChangeSvc, nil, nil, nil, settingsSvc, activitySvc)
 
 	app.Post("/auth/register", authHandler.Register)
 	app.Post("/auth/login", authHandler.Login)
@@ -152,9 +326,20 @@ func setupApp(svc *mockUserService) *fiber.App {
 	app.Post("/auth/reset-password", authHandler.ResetPassword)
 	app.Post("/auth/verify-email", authHandler.VerifyEmail)
 	app.Post("/auth/resend-verification", authHandler.ResendVerification)
-
-	users := app.Group("/users", middleware.JWTAuth("test-secret"))
+	app.Post("/auth/confirm-email-change", authHandler.ConfirmEmailChange)
+	app.Post("/auth/webauthn/login/begin", authHandler.WebAuthnLoginBegin)
+	app.Post("/auth/saml/acs", authHandler.SAMLACS)
+	app.Get("/auth/password-policy", authHandler.PasswordPolicy)
+	app.Post("/auth/guest", authHandler.Guest)
+	app.Post("/auth/guest/upgrade", middleware.JWTAuth(testJWTConfig, nil), middleware.RequireRole(dto.RoleGuest), authHandler.UpgradeGuest)
+
+	users := app.Group("/users", middleware.JWTAuth(testJWTConfig, nil))
+	users.Get("", userHandler.List)
 	users.Get("/me", userHandler.GetMe)
+	users.Get("/me/settings", userHandler.GetSettings)
+	users.Put("/me/settings", userHandler.UpdateSettings)
+	users.Get("/me/activity", userHandler.ListActivity)
+	users.Get("/by-username/:handle", userHandler.GetByUsername)
 	users.Get("/:id", userHandler.GetByID)
 	users.Put("/:id", userHandler.Update)
 	users.Delete("/:id", userHandler.Delete)
@@ -224,7 +409,7 @@ func TestGetMe_Unauthorized(t *testing.T) {
 func TestGetMe_Authorized(t *testing.T) {
 	app := setupApp(newMockService())
 
-	accessToken, _ := token.Generate(1, "test@example.com", "user", "test-secret", 24)
+	accessToken, _ := token.Generate(1, "test@example.com", "", "user", nil, testJWTConfig, 24)
 
 	req, _ := http.NewRequest("GET", "/users/me", http.NoBody)
 	req.Header.Set("Authorization", "Bearer "+accessToken)
@@ -234,10 +419,130 @@ func TestGetMe_Authorized(t *testing.T) {
 	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
 }
 
+func TestGetByUsernameHandler(t *testing.T) {
+	app := setupApp(newMockService())
+
+	accessToken, _ := token.Generate(1, "test@example.com", "tester", "user", nil, testJWTConfig, 24)
+
+	req, _ := http.NewRequest("GET", "/users/by-username/Tester", http.NoBody)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	raw, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(raw), `"username":"tester"`)
+	assert.NotContains(t, string(raw), "test@example.com")
+
+	req, _ = http.NewRequest("GET", "/users/by-username/nobody", http.NoBody)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+func TestUserSettingsHandler(t *testing.T) {
+	app := setupApp(newMockService())
+
+	accessToken, _ := token.Generate(1, "test@example.com", "", "user", nil, testJWTConfig, 24)
+
+	put := func(body string) *http.Response {
+		req, _ := http.NewRequest("PUT", "/users/me/settings", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	assert.Equal(t, fiber.StatusUnprocessableEntity, put(`{"timezone":"Mars/Olympus"}`).StatusCode)
+	assert.Equal(t, fiber.StatusUnprocessableEntity, put(`{"locale":"not a locale"}`).StatusCode)
+	assert.Equal(t, fiber.StatusOK, put(`{"locale":"vi","timezone":"Asia/Ho_Chi_Minh"}`).StatusCode)
+
+	req, _ := http.NewRequest("GET", "/users/me", http.NoBody)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var body struct {
+		Data dto.UserResponse `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.NotNil(t, body.Data.Settings)
+	assert.Equal(t, "vi", body.Data.Settings.Locale)
+	assert.Equal(t, "Asia/Ho_Chi_Minh", body.Data.Settings.Timezone)
+}
+
+func TestUserActivityHandler(t *testing.T) {
+	activitySvc := newMockUserActivityService()
+	app := setupAppWithActivity(newMockService(), false, activitySvc)
+
+	accessToken, _ := token.Generate(2, "admin@example.com", "", "admin", nil, testJWTConfig, 24)
+
+	name := "Updated Name"
+	body, _ := json.Marshal(dto.UpdateUserRequest{Name: &name})
+	req, _ := http.NewRequest("PUT", "/users/1", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	select {
+	case activity := <-activitySvc.recorded:
+		assert.Equal(t, int64(1), activity.UserID)
+		assert.Equal(t, int64(2), activity.ActorID)
+		assert.Equal(t, dto.ActivityProfileUpdated, activity.Action)
+		assert.Equal(t, []string{"name"}, activity.Details["fields"])
+	case <-time.After(time.Second):
+		t.Fatal("expected profile update to be recorded")
+	}
+
+	req, _ = http.NewRequest("GET", "/users/me/activity", http.NoBody)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	raw, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(raw), `"action":"password.changed"`)
+	assert.Contains(t, string(raw), `"total":1`)
+}
+
+func TestListUsers_Filters(t *testing.T) {
+	svc := newMockService()
+	app := setupApp(svc)
+
+	accessToken, _ := token.Generate(1, "test@example.com", "", "user", nil, testJWTConfig, 24)
+
+	req, _ := http.NewRequest("GET", "/users?q=ali&role=admin&email_verified=true&created_after=2026-01-01T00:00:00Z&sort=-created_at", http.NoBody)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "ali", svc.lastQuery.Search)
+	assert.Equal(t, "admin", svc.lastQuery.Role)
+	require.NotNil(t, svc.lastQuery.EmailVerified)
+	assert.True(t, *svc.lastQuery.EmailVerified)
+	assert.Equal(t, "2026-01-01T00:00:00Z", svc.lastQuery.CreatedAfter)
+	assert.Equal(t, "-created_at", svc.lastQuery.Sort)
+
+	for _, query := range []string{"sort=password_hash", "created_before=yesterday"} {
+		req, _ := http.NewRequest("GET", "/users?"+query, http.NoBody)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode, query)
+	}
+}
+
 func TestGetByID_NotFound(t *testing.T) {
 	app := setupApp(newMockService())
 
-	accessToken, _ := token.Generate(1, "test@example.com", "user", "test-secret", 24)
+	accessToken, _ := token.Generate(1, "test@example.com", "", "user", nil, testJWTConfig, 24)
 
 	req, _ := http.NewRequest("GET", "/users/999", http.NoBody)
 	req.Header.Set("Authorization", "Bearer "+accessToken)
@@ -251,7 +556,7 @@ func TestUpdate_Forbidden(t *testing.T) {
 	app := setupApp(newMockService())
 
 	// User 1 trying to update user 2
-	accessToken, _ := token.Generate(1, "test@example.com", "user", "test-secret", 24)
+	accessToken, _ := token.Generate(1, "test@example.com", "", "user", nil, testJWTConfig, 24)
 
 	body, _ := json.Marshal(dto.UpdateUserRequest{})
 	req, _ := http.NewRequest("PUT", "/users/2", bytes.NewReader(body))
@@ -267,7 +572,7 @@ func TestUpdate_AdminBypass(t *testing.T) {
 	app := setupApp(newMockService())
 
 	// Admin trying to update user 1
-	accessToken, _ := token.Generate(2, "admin@example.com", "admin", "test-secret", 24)
+	accessToken, _ := token.Generate(2, "admin@example.com", "", "admin", nil, testJWTConfig, 24)
 
 	name := "Updated Name"
 	body, _ := json.Marshal(dto.UpdateUserRequest{Name: &name})
@@ -284,7 +589,7 @@ func TestDelete_Forbidden(t *testing.T) {
 	app := setupApp(newMockService())
 
 	// User 1 trying to delete user 2
-	accessToken, _ := token.Generate(1, "test@example.com", "user", "test-secret", 24)
+	accessToken, _ := token.Generate(1, "test@example.com", "", "user", nil, testJWTConfig, 24)
 
 	req, _ := http.NewRequest("DELETE", "/users/2", http.NoBody)
 	req.Header.Set("Authorization", "Bearer "+accessToken)
@@ -298,7 +603,7 @@ func TestDelete_AdminBypass(t *testing.T) {
 	app := setupApp(newMockService())
 
 	// Admin trying to delete user 1
-	accessToken, _ := token.Generate(2, "admin@example.com", "admin", "test-secret", 24)
+	accessToken, _ := token.Generate(2, "admin@example.com", "", "admin", nil, testJWTConfig, 24)
 
 	req, _ := http.NewRequest("DELETE", "/users/1", http.NoBody)
 	req.Header.Set("Authorization", "Bearer "+accessToken)
@@ -429,3 +734,649 @@ func TestResendVerificationHandler(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
 }
+
+func TestConfirmEmailChangeHandler(t *testing.T) {
+	app := setupApp(newMockService())
+
+	body, _ := json.Marshal(dto.ConfirmEmailChangeRequest{
+		Token: "valid-token",
+	})
+
+	req, _ := http.NewRequest("POST", "/auth/confirm-email-change", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
+
+func TestConfirmEmailChangeHandler_InvalidToken(t *testing.T) {
+	app := setupApp(newMockService())
+
+	body, _ := json.Marshal(dto.ConfirmEmailChangeRequest{
+		Token: "bad-token",
+	})
+
+	req, _ := http.NewRequest("POST", "/auth/confirm-email-change", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestWebAuthnLoginBegin_NotConfigured(t *testing.T) {
+	app := setupApp(newMockService())
+
+	req, _ := http.NewRequest("POST", "/auth/webauthn/login/begin", nil)
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+func TestSAMLACS_NotConfigured(t *testing.T) {
+	app := setupApp(newMockService())
+
+	req, _ := http.NewRequest("POST", "/auth/saml/acs", strings.NewReader("SAMLResponse=abc"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+func TestPasswordPolicyHandler(t *testing.T) {
+	app := setupApp(newMockService())
+
+	req, _ := http.NewRequest("GET", "/auth/password-policy", nil)
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var result struct {
+		Data dto.PasswordPolicyResponse `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, 8, result.Data.MinLength)
+	assert.Equal(t, 72, result.Data.MaxLength)
+	assert.True(t, result.Data.RequireSpecial)
+}
+
+func TestGuestHandler(t *testing.T) {
+	app := setupApp(newMockService())
+
+	req, _ := http.NewRequest("POST", "/auth/guest", nil)
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	var result struct {
+		Data dto.LoginResponse `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Empty(t, result.Data.RefreshToken)
+
+	claims, err := token.Parse(result.Data.AccessToken, testJWTConfig)
+	require.NoError(t, err)
+	assert.Equal(t, dto.RoleGuest, claims.Role)
+}
+
+func TestUpgradeGuestHandler(t *testing.T) {
+	app := setupApp(newMockService())
+
+	body, _ := json.Marshal(dto.RegisterRequest{
+		Email:    "new@example.com",
+		Password: "Password1!",
+		Name:     "New User",
+	})
+
+	guestToken, _ := token.Generate(10, "guest-1@guest.invalid", "", dto.RoleGuest, nil, testJWTConfig, 24)
+	req, _ := http.NewRequest("POST", "/auth/guest/upgrade", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+guestToken)
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
+
+func TestUpgradeGuestHandler_NotGuest(t *testing.T) {
+	app := setupApp(newMockService())
+
+	body, _ := json.Marshal(dto.RegisterRequest{
+		Email:    "new@example.com",
+		Password: "Password1!",
+		Name:     "New User",
+	})
+
+	userToken, _ := token.Generate(1, "test@example.com", "", dto.RoleUser, nil, testJWTConfig, 24)
+	req, _ := http.NewRequest("POST", "/auth/guest/upgrade", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+userToken)
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+}
+
+func TestLoginHandler_IPThrottle(t *testing.T) {
+	c := cache.NewMemoryCache()
+	t.Cleanup(func() { _ = c.Close() })
+	throttle := service.NewLoginThrottle(c, 1, time.Minute, time.Minute)
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.FiberErrorHandler})
+	authHandler := NewAuthHandler(newMockService(), &mockRefreshTokenService{}, nil, nil, testJWTConfig, 24, false, 30,
+		nil, nil, nil, nil, nil, nil, nil, 24, throttle, nil, nil)
+	app.Post("/auth/login", authHandler.Login)
+
+	login := func(password string) *http.Response {
+		body, _ := json.Marshal(dto.LoginRequest{Email: "test@example.com", Password: password})
+		req, _ := http.NewRequest("POST", "/auth/login", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := login("WrongPassword2@")
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get(fiber.HeaderRetryAfter))
+
+	resp = login("Password1!")
+	assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get(fiber.HeaderRetryAfter))
+}
+
+func TestLogoutAllHandler(t *testing.T) {
+	c := cache.NewMemoryCache()
+	t.Cleanup(func() { _ = c.Close() })
+	revocations := token.NewRevocationStore(c, time.Hour)
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.FiberErrorHandler})
+	authHandler := NewAuthHandler(newMockService(), &mockRefreshTokenService{}, nil, nil, testJWTConfig, 24, false, 30,
+		nil, nil, nil, nil, nil, nil, nil, 24, nil, revocations, nil)
+	jwtAuth := middleware.JWTAuth(testJWTConfig, revocations)
+	app.Post("/auth/logout-all", jwtAuth, authHandler.LogoutAll)
+	app.Get("/protected", jwtAuth, func(c fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	accessToken, _ := token.Generate(1, "test@example.com", "", "user", nil, testJWTConfig, 24)
+	send := func(method, path string) int {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, fiber.StatusOK, send("GET", "/protected"))
+	assert.Equal(t, fiber.StatusNoContent, send("POST", "/auth/logout-all"))
+	assert.Equal(t, fiber.StatusUnauthorized, send("GET", "/protected"))
+}
+
+func TestLoginHandler_TwoFactor(t *testing.T) {
+	c := cache.NewMemoryCache()
+	t.Cleanup(func() { _ = c.Close() })
+	throttle := service.NewLoginThrottle(c, 1, time.Minute, time.Minute)
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.FiberErrorHandler})
+	authHandler := NewAuthHandler(newMockService(), &mockRefreshTokenService{}, nil, nil, testJWTConfig, 24, false, 30,
+		nil, nil, nil, nil, nil, nil, nil, 24, throttle, nil, &mockTwoFactorService{})
+	app.Post("/auth/login", authHandler.Login)
+	app.Post("/auth/login/2fa", authHandler.LoginTwoFactor)
+
+	post := func(path string, payload any) (*http.Response, map[string]any) {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		var result map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&result)
+		return resp, result
+	}
+
+	resp, result := post("/auth/login", dto.LoginRequest{Email: "test@example.com", Password: "Password1!"})
+	require.Equal(t, fiber.StatusAccepted, resp.StatusCode)
+	data := result["data"].(map[string]any)
+	assert.Equal(t, true, data["two_factor_required"])
+	assert.Nil(t, data["access_token"], "expected no tokens before the second factor")
+
+	challenge := func(code string) dto.TwoFactorLoginRequest {
+		return dto.TwoFactorLoginRequest{ChallengeToken: "challenge", TwoFactorConfirmRequest: dto.TwoFactorConfirmRequest{Code: code}}
+	}
+	resp, _ = post("/auth/login/2fa", challenge("123456"))
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	resp, _ = post("/auth/login/2fa", dto.TwoFactorLoginRequest{ChallengeToken: "challenge"})
+	assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode, "expected a code or recovery code required")
+
+	resp, _ = post("/auth/login/2fa", challenge("000000"))
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	resp, _ = post("/auth/login/2fa", challenge("123456"))
+	assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode, "expected wrong codes to count towards the login backoff")
+}
+
+func TestTwoFactorHandler_RecordsActivity(t *testing.T) {
+	activitySvc := newMockUserActivityService()
+	h := NewTwoFactorHandler(&mockTwoFactorService{}, activitySvc)
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.FiberErrorHandler})
+	app.Post("/auth/2fa/enable", middleware.JWTAuth(testJWTConfig, nil), h.Enable)
+
+	accessToken, _ := token.Generate(1, "test@example.com", "", "user", nil, testJWTConfig, 1)
+	body, _ := json.Marshal(dto.TwoFactorCodeRequest{Code: "123456"})
+	req, _ := http.NewRequest("POST", "/auth/2fa/enable", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	select {
+	case activity := <-activitySvc.recorded:
+		assert.Equal(t, dto.ActivityTwoFactorEnabled, activity.Action)
+		assert.Equal(t, int64(1), activity.UserID)
+	case <-time.After(time.Second):
+		t.Fatal("expected the activity recorded")
+	}
+}
+
+func TestLoginHandler_CookieMode(t *testing.T) {
+	app := setupAppWithCookieMode(newMockService(), true)
+
+	body, _ := json.Marshal(dto.LoginRequest{
+		Email:    "test@example.com",
+		Password: "Password1!",
+	})
+
+	req, _ := http.NewRequest("POST", "/auth/login", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	cookies := make(map[string]*http.Cookie)
+	for _, c := range resp.Cookies() {
+		cookies[c.Name] = c
+	}
+	require.Contains(t, cookies, "refresh_token")
+	require.Contains(t, cookies, "csrf_token")
+	assert.Equal(t, "mock-refresh-token", cookies["refresh_token"].Value)
+	assert.True(t, cookies["refresh_token"].HttpOnly)
+	assert.False(t, cookies["csrf_token"].HttpOnly)
+
+	var result struct {
+		Data map[string]any `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.NotContains(t, result.Data, "refresh_token")
+}
+
+func TestRefreshHandler_CookieMode(t *testing.T) {
+	app := setupAppWithCookieMode(newMockService(), true)
+
+	t.Run("missing CSRF header", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/auth/refresh", nil)
+		req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "valid-refresh-token"})
+		req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "csrf-value"})
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("missing refresh cookie", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/auth/refresh", nil)
+		req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "csrf-value"})
+		req.Header.Set("X-CSRF-Token", "csrf-value")
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("valid cookie and CSRF header", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/auth/refresh", nil)
+		req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "valid-refresh-token"})
+		req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "csrf-value"})
+		req.Header.Set("X-CSRF-Token", "csrf-value")
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+}
+
+// mockPermissionChecker grants permissions per user ID.
+type mockPermissionChecker struct {
+	granted map[int64][]string
+	err     error
+}
+
+func (m *mockPermissionChecker) HasPermission(_ context.Context, userID int64, permission string) (bool, error) {
+	if m.err != nil {
+		return false, m.err
+	}
+	return slices.Contains(m.granted[userID], permission), nil
+}
+
+func TestRequirePermission(t *testing.T) {
+	cases := []struct {
+		name    string
+		checker *mockPermissionChecker
+		want    int
+	}{
+		{"granted", &mockPermissionChecker{granted: map[int64][]string{1: {dto.PermissionUsersList}}}, fiber.StatusOK},
+		{"other permission", &mockPermissionChecker{granted: map[int64][]string{1: {dto.PermissionStatsRead}}}, fiber.StatusForbidden},
+		{"other user", &mockPermissionChecker{granted: map[int64][]string{2: {dto.PermissionUsersList}}}, fiber.StatusForbidden},
+		{"lookup error", &mockPermissionChecker{err: errors.New("db down")}, fiber.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := fiber.New(fiber.Config{ErrorHandler: apperror.FiberErrorHandler})
+			app.Get("/guarded",
+				middleware.JWTAuth(testJWTConfig, nil),
+				middleware.RequirePermission(tc.checker, dto.PermissionUsersList),
+				func(c fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) },
+			)
+
+			accessToken, _ := token.Generate(1, "test@example.com", "", "user", nil, testJWTConfig, 24)
+			req, _ := http.NewRequest("GET", "/guarded", nil)
+			req.Header.Set("Authorization", "Bearer "+accessToken)
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, resp.StatusCode)
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.FiberErrorHandler})
+	app.Get("/scoped", middleware.JWTAuth(testJWTConfig, nil), middleware.RequireScope(dto.ScopeFilesWrite), func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	cases := []struct {
+		name   string
+		scopes []string
+		want   int
+	}{
+		{"granted", dto.ScopesForRole(dto.RoleUser), fiber.StatusOK},
+		{"missing scope", []string{dto.ScopeFilesRead}, fiber.StatusForbidden},
+		{"no scopes", nil, fiber.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			accessToken, _ := token.Generate(1, "test@example.com", "", "user", tc.scopes, testJWTConfig, 24)
+			req, _ := http.NewRequest("GET", "/scoped", nil)
+			req.Header.Set("Authorization", "Bearer "+accessToken)
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, resp.StatusCode)
+		})
+	}
+}
+
+func TestWriteUsers(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	users := []dto.AdminUserResponse{
+		{UserResponse: dto.UserResponse{ID: 1, Email: "a@example.com", Username: "alice", Name: "Alice", Role: "admin", EmailVerified: true, CreatedAt: created, UpdatedAt: created}},
+		{UserResponse: dto.UserResponse{ID: 2, Email: "b@example.com", Name: "=HYPERLINK(\"x\")", Role: "user", Metadata: map[string]any{"team": "core"}, CreatedAt: created, UpdatedAt: created},
+			BannedAt: &created, BanReason: "spam"},
+	}
+	each := func(fn func(dto.AdminUserResponse) error) error {
+		for _, u := range users {
+			if err := fn(u); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, writeUsers(bufio.NewWriter(&buf), "csv", each))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 3)
+		assert.Equal(t, "id,email,username,name,role,email_verified,metadata,created_at,updated_at", lines[0])
+		assert.Equal(t, "1,a@example.com,alice,Alice,admin,true,,2026-01-02T03:04:05Z,2026-01-02T03:04:05Z", lines[1])
+		assert.Contains(t, lines[2], `"'=HYPERLINK(""x"")"`, "formula-like values must be neutralized")
+		assert.Contains(t, lines[2], `"{""team"":""core""}"`)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, writeUsers(bufio.NewWriter(&buf), "json", each))
+
+		var decoded []dto.AdminUserResponse
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+		require.Len(t, decoded, 2)
+		assert.Equal(t, int64(2), decoded[1].ID)
+		assert.Equal(t, "core", decoded[1].Metadata["team"])
+		assert.Equal(t, "spam", decoded[1].BanReason)
+	})
+
+	t.Run("empty json", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, writeUsers(bufio.NewWriter(&buf), "json", func(func(dto.AdminUserResponse) error) error { return nil }))
+		assert.Equal(t, "[]\n", buf.String())
+	})
+}
+
+func TestReadUserImport(t *testing.T) {
+	t.Run("columns in any order", func(t *testing.T) {
+		rows, err := readUserImport(strings.NewReader("\ufeffName, Email ,Role,team\nAnn,ann@example.com,Admin,core\n\nBob,bob@example.com\n"))
+		require.NoError(t, err)
+		assert.Equal(t, []dto.UserImportRow{
+			{Line: 2, Email: "ann@example.com", Name: "Ann", Role: "admin"},
+			{Line: 4, Email: "bob@example.com", Name: "Bob"},
+		}, rows)
+	})
+
+	t.Run("invalid files", func(t *testing.T) {
+		for name, body := range map[string]string{
+			"empty":          "",
+			"missing column": "email,role\nann@example.com,user\n",
+			"no users":       "email,name\n",
+			"bad quoting":    "email,name\n\"ann@example.com,Ann\n",
+			"too many rows":  "email,name\n" + strings.Repeat("a@example.com,A\n", dto.UserImportMaxRows+1),
+		} {
+			_, err := readUserImport(strings.NewReader(body))
+			var appErr *apperror.AppError
+			if assert.ErrorAs(t, err, &appErr, name) {
+				assert.Equal(t, fiber.StatusBadRequest, appErr.Code, name)
+			}
+		}
+	})
+}
+
+func TestBatchHandler(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.FiberErrorHandler})
+	userHandler := NewUserHandler(newMockService(), nil, nil, nil, nil, nil, &mockUserSettingsService{settings: map[int64]*dto.UserSettingsResponse{}}, nil)
+	batchHandler := NewBatchHandler(3)
+	app.Post("/api/v1/batch", batchHandler.Execute)
+	app.Get("/api/v1/users/:id", middleware.JWTAuth(testJWTConfig, nil), userHandler.GetByID)
+	app.Get("/api/v1/text", func(c fiber.Ctx) error { return c.SendString("plain") })
+	batchHandler.Bind(app)
+
+	batch := func(t *testing.T, authorized bool, items ...dto.BatchItem) (*http.Response, []dto.BatchResult) {
+		t.Helper()
+		body, _ := json.Marshal(dto.BatchRequest{Requests: items})
+		req, _ := http.NewRequest("POST", "/api/v1/batch", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if authorized {
+			accessToken, _ := token.Generate(1, "test@example.com", "", "user", nil, testJWTConfig, 24)
+			req.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		var out struct {
+			Data []dto.BatchResult `json:"data"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out.Data
+	}
+
+	t.Run("runs each request with the batch's auth", func(t *testing.T) {
+		resp, results := batch(t, true,
+			dto.BatchItem{Method: "GET", Path: "/api/v1/users/1"},
+			dto.BatchItem{Method: "GET", Path: "/api/v1/users/99"},
+			dto.BatchItem{Method: "GET", Path: "/api/v1/text"},
+		)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		require.Len(t, results, 3)
+		assert.Equal(t, fiber.StatusOK, results[0].Status)
+		assert.Contains(t, string(results[0].Body), `"email":"test@example.com"`)
+		assert.Equal(t, fiber.StatusNotFound, results[1].Status)
+		assert.Contains(t, string(results[1].Body), `"NOT_FOUND"`)
+		assert.Equal(t, fiber.StatusOK, results[2].Status)
+		assert.Nil(t, results[2].Body, "non-JSON bodies are left out")
+	})
+
+	t.Run("unauthenticated requests fail on their own", func(t *testing.T) {
+		resp, results := batch(t, false, dto.BatchItem{Method: "GET", Path: "/api/v1/users/1"})
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		require.Len(t, results, 1)
+		assert.Equal(t, fiber.StatusUnauthorized, results[0].Status)
+	})
+
+	t.Run("rejected batches", func(t *testing.T) {
+		item := dto.BatchItem{Method: "GET", Path: "/api/v1/users/1"}
+		for name, items := range map[string][]dto.BatchItem{
+			"too many":        {item, item, item, item},
+			"nested batch":    {{Method: "POST", Path: "/api/v1/batch"}},
+			"outside the API": {{Method: "GET", Path: "/api/../metrics"}},
+		} {
+			resp, _ := batch(t, true, items...)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode, name)
+		}
+		resp, _ := batch(t, true, dto.BatchItem{Method: "TRACE", Path: "/api/v1/users/1"})
+		assert.Equal(t, fiber.StatusUnprocessableEntity, resp.StatusCode)
+	})
+}
+
+func TestRealtimeHandler(t *testing.T) {
+	appCache := cache.NewMemoryCache()
+	t.Cleanup(func() { _ = appCache.Close() })
+	hub := realtime.NewHub(1)
+	t.Cleanup(hub.Close)
+	tickets := realtime.NewTicketStore(appCache, time.Minute)
+	h := NewRealtimeHandler(hub, tickets)
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.FiberErrorHandler})
+	app.Post("/api/v1/realtime/ticket", middleware.JWTAuth(testJWTConfig, nil), h.IssueTicket)
+	app.Get("/ws", middleware.WebSocketAuth(testJWTConfig, nil, tickets), h.Connect)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = app.Listener(ln, fiber.ListenConfig{DisableStartupMessage: true}) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+
+	accessToken, _ := token.Generate(1, "test@example.com", "", "user", nil, testJWTConfig, 24)
+	issueTicket := func(t *testing.T) string {
+		t.Helper()
+		req, _ := http.NewRequest("POST", "/api/v1/realtime/ticket", nil)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+		var out struct {
+			Data dto.RealtimeTicketResponse `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		return out.Data.Ticket
+	}
+	wsURL := "ws://" + ln.Addr().String() + "/ws"
+
+	t.Run("a ticket opens a connection receiving the user's events", func(t *testing.T) {
+		ticket := issueTicket(t)
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?ticket="+ticket, nil)
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+
+		hub.PublishToUser(2, realtime.Event{Type: "other"})
+		hub.PublishToUser(1, realtime.Event{Type: dto.RealtimeEventFileUploaded})
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var event realtime.Event
+		require.NoError(t, conn.ReadJSON(&event))
+		assert.Equal(t, dto.RealtimeEventFileUploaded, event.Type)
+
+		_, resp, err := websocket.DefaultDialer.Dial(wsURL+"?ticket="+ticket, nil)
+		require.Error(t, err, "tickets are single use")
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+
+		// The user already has as many connections as the hub allows
+		_, resp, err = websocket.DefaultDialer.Dial(wsURL, http.Header{"Authorization": {"Bearer " + accessToken}})
+		require.Error(t, err)
+		assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	})
+
+	t.Run("bearer tokens are accepted in the header only", func(t *testing.T) {
+		_, resp, err := websocket.DefaultDialer.Dial(wsURL+"?token="+accessToken, nil)
+		require.Error(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+
+		require.Eventually(t, func() bool {
+			conn, _, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Authorization": {"Bearer " + accessToken}})
+			if err != nil {
+				return false // until the connection above is released
+			}
+			_ = conn.Close()
+			return true
+		}, 2*time.Second, 20*time.Millisecond)
+	})
+
+	t.Run("plain requests are rejected", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/ws", nil)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusUpgradeRequired, resp.StatusCode)
+	})
+}
+
+func TestProblemDetails(t *testing.T) {
+	apperror.SetProblemDetails(true, "https://example.com/errors/")
+	t.Cleanup(func() { apperror.SetProblemDetails(false, "") })
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.FiberErrorHandler})
+	app.Use(middleware.RequestID())
+	app.Get("/missing", func(c fiber.Ctx) error { return apperror.NewNotFound("user not found") })
+	app.Get("/invalid", func(c fiber.Ctx) error {
+		return apperror.NewValidation("validation failed", map[string]string{"email": "email is required"})
+	})
+
+	problem := func(t *testing.T, path string) (*http.Response, map[string]any) {
+		t.Helper()
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("X-Request-ID", "req-1")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return resp, body
+	}
+
+	resp, body := problem(t, "/missing")
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, map[string]any{
+		"type":       "https://example.com/errors/not-found",
+		"title":      "Not Found",
+		"status":     float64(404),
+		"detail":     "user not found",
+		"instance":   "/missing",
+		"code":       "NOT_FOUND",
+		"request_id": "req-1",
+	}, body)
+
+	_, body = problem(t, "/invalid")
+	assert.Equal(t, "VALIDATION_ERROR", body["code"])
+	assert.Equal(t, map[string]any{"email": "email is required"}, body["details"])
+}
@@ -1,13 +1,19 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"log/slog"
+	"strings"
 
 	"github.com/gofiber/fiber/v3"
 
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/service"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/async"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/clientip"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/pagination"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/validator"
 )
@@ -21,6 +27,15 @@ func paramID(c fiber.Ctx, name string) (int64, error) {
 	return id, nil
 }
 
+// paramVersion extracts and validates the file version number path parameter.
+func paramVersion(c fiber.Ctx) (int32, error) {
+	version := fiber.Params[int32](c, "version")
+	if version <= 0 {
+		return 0, apperror.NewBadRequest("invalid version")
+	}
+	return version, nil
+}
+
 // authUserID returns the authenticated user's ID from the JWT context.
 // Key is set by middleware.JWTAuth.
 func authUserID(c fiber.Ctx) int64 {
@@ -32,6 +47,16 @@ func authRole(c fiber.Ctx) string {
 	return fiber.Locals[string](c, "role")
 }
 
+// auditContext returns the request context carrying the authenticated user, client IP and
+// request ID, so admin changes made with it are attributed in the audit log.
+func auditContext(c fiber.Ctx) context.Context {
+	return service.WithAuditActor(c.Context(), service.AuditActor{
+		UserID:    authUserID(c),
+		IPAddress: strings.Clone(clientip.Get(c)),
+		RequestID: fiber.Locals[string](c, "request_id"),
+	})
+}
+
 // bindAndValidate parses the request body and runs struct validation.
 func bindAndValidate(c fiber.Ctx, req any) error {
 	if err := c.Bind().Body(req); err != nil {
@@ -45,6 +70,14 @@ func bindAndValidate(c fiber.Ctx, req any) error {
 	return validator.ValidateStruct(req)
 }
 
+// bindQueryAndValidate parses query params and runs struct validation.
+func bindQueryAndValidate(c fiber.Ctx, req any) error {
+	if err := c.Bind().Query(req); err != nil {
+		return apperror.NewBadRequest("invalid query parameters")
+	}
+	return validator.ValidateStruct(req)
+}
+
 // paginationQuery binds page/per_page query params and normalizes them.
 func paginationQuery(c fiber.Ctx) (page, perPage int, err error) {
 	var q dto.PaginationQuery
@@ -54,3 +87,26 @@ func paginationQuery(c fiber.Ctx) (page, perPage int, err error) {
 	page, perPage = pagination.Normalize(q.Page, q.PerPage)
 	return page, perPage, nil
 }
+
+// recordActivity adds an entry to userID's activity trail without blocking the response,
+// attributing it to the authenticated user. Request data is copied up front because the
+// fiber context is reused once the handler returns.
+func recordActivity(c fiber.Ctx, svc service.UserActivityService, userID int64, action string, details map[string]any) {
+	if svc == nil {
+		return
+	}
+
+	activity := service.Activity{
+		UserID:    userID,
+		ActorID:   authUserID(c),
+		Action:    action,
+		Details:   details,
+		IPAddress: strings.Clone(clientip.Get(c)),
+	}
+
+	async.Go(func() {
+		if err := svc.Record(context.Background(), activity); err != nil {
+			slog.Error("failed to record user activity", slog.String("action", activity.Action), slog.Any("error", err))
+		}
+	})
+}
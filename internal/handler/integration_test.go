@@ -37,7 +37,7 @@ func setupIntegrationApp(t *testing.T) (*fiber.App, func()) {
 
 	fileRepo := repository.NewFileRepository(pool)
 	adminSvc := service.NewAdminService(userRepo, fileRepo, nil)
-	adminHandler := NewAdminHandler(adminSvc)
+	adminHandler := NewAdminHandler(adminSvc, nil, testJWTConfig, 15)
 
 	app := fiber.New(fiber.Config{
 		ErrorHandler: apperror.FiberErrorHandler,
@@ -55,14 +55,14 @@ func setupIntegrationApp(t *testing.T) (*fiber.App, func()) {
 		return response.Created(c, user)
 	})
 
-	users := app.Group("/users", middleware.JWTAuth("integration-secret"))
+	users := app.Group("/users", middleware.JWTAuth(testJWTConfig, nil))
 	users.Get("/me", userHandler.GetMe)
 	users.Get("/:id", userHandler.GetByID)
 	users.Put("/:id", userHandler.Update)
 	users.Delete("/:id", userHandler.Delete)
 
 	admin := app.Group("/admin",
-		middleware.JWTAuth("integration-secret"),
+		middleware.JWTAuth(testJWTConfig, nil),
 		middleware.RequireRole("admin"),
 	)
 	admin.Get("/stats", adminHandler.GetStats)
@@ -102,7 +102,7 @@ func TestIntegration_FullUserFlow(t *testing.T) {
 	userID := userResp.ID
 
 	// 2. Get user (with JWT)
-	accessToken, _ := token.Generate(userID, "integration@test.com", "user", "integration-secret", 24)
+	accessToken, _ := token.Generate(userID, "integration@test.com", "", "user", nil, testJWTConfig, 24)
 
 	req, _ = http.NewRequest("GET", "/users/me", http.NoBody)
 	req.Header.Set("Authorization", "Bearer "+accessToken)
@@ -152,7 +152,7 @@ func TestIntegration_AdminOperations(t *testing.T) {
 	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
 
 	// Admin token (we'll use user ID 999 as admin — doesn't need to exist for token generation)
-	adminToken, _ := token.Generate(999, "admin@test.com", "admin", "integration-secret", 24)
+	adminToken, _ := token.Generate(999, "admin@test.com", "", "admin", nil, testJWTConfig, 24)
 
 	// Get stats
 	req, _ = http.NewRequest("GET", "/admin/stats", http.NoBody)
@@ -177,14 +177,14 @@ func TestIntegration_AdminOperations(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
 
-	// Ban user (soft delete)
+	// Ban user
 	req, _ = http.NewRequest("POST", "/admin/users/1/ban", http.NoBody)
 	req.Header.Set("Authorization", "Bearer "+adminToken)
 	resp, err = app.Test(req)
 	require.NoError(t, err)
 	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
 
-	// Unban user (restore)
+	// Unban user
 	req, _ = http.NewRequest("POST", "/admin/users/1/unban", http.NoBody)
 	req.Header.Set("Authorization", "Bearer "+adminToken)
 	resp, err = app.Test(req)
@@ -192,7 +192,7 @@ func TestIntegration_AdminOperations(t *testing.T) {
 	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
 
 	// Non-admin gets 403
-	userToken, _ := token.Generate(1, "regular@test.com", "user", "integration-secret", 24)
+	userToken, _ := token.Generate(1, "regular@test.com", "", "user", nil, testJWTConfig, 24)
 	req, _ = http.NewRequest("GET", "/admin/stats", http.NoBody)
 	req.Header.Set("Authorization", "Bearer "+userToken)
 	resp, err = app.Test(req)
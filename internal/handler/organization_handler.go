@@ -0,0 +1,325 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/service"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/response"
+)
+
+type OrganizationHandler struct {
+	service service.OrganizationService
+}
+
+func NewOrganizationHandler(svc service.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{service: svc}
+}
+
+// Create godoc
+// @Summary Create an organization
+// @Description Create an organization owned by the authenticated user
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.CreateOrganizationRequest true "Organization"
+// @Success 201 {object} response.Response{data=dto.OrganizationResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/orgs [post]
+func (h *OrganizationHandler) Create(c fiber.Ctx) error {
+	var req dto.CreateOrganizationRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	org, err := h.service.Create(c.Context(), authUserID(c), req)
+	if err != nil {
+		return err
+	}
+
+	return response.Created(c, org)
+}
+
+// List godoc
+// @Summary List my organizations
+// @Description Get a paginated list of the organizations the authenticated user belongs to
+// @Tags Organizations
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(10)
+// @Success 200 {object} response.Response{data=[]dto.OrganizationResponse,meta=response.Meta}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /v1/orgs [get]
+func (h *OrganizationHandler) List(c fiber.Ctx) error {
+	page, perPage, err := paginationQuery(c)
+	if err != nil {
+		return err
+	}
+
+	orgs, total, err := h.service.List(c.Context(), authUserID(c), page, perPage)
+	if err != nil {
+		return err
+	}
+
+	return response.SuccessWithMeta(c, orgs, response.NewMeta(page, perPage, total))
+}
+
+// Get godoc
+// @Summary Get an organization
+// @Description Get an organization the authenticated user belongs to
+// @Tags Organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Success 200 {object} response.Response{data=dto.OrganizationResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/orgs/{id} [get]
+func (h *OrganizationHandler) Get(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	org, err := h.service.Get(c.Context(), authUserID(c), id)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, org)
+}
+
+// Update godoc
+// @Summary Update an organization
+// @Description Rename an organization (owners and admins only)
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param request body dto.UpdateOrganizationRequest true "Update request"
+// @Success 200 {object} response.Response{data=dto.OrganizationResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/orgs/{id} [put]
+func (h *OrganizationHandler) Update(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req dto.UpdateOrganizationRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	org, err := h.service.Update(c.Context(), authUserID(c), id, req)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, org)
+}
+
+// Delete godoc
+// @Summary Delete an organization
+// @Description Delete an organization with its memberships and pending invitations (owners only)
+// @Tags Organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Success 204
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/orgs/{id} [delete]
+func (h *OrganizationHandler) Delete(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.service.Delete(c.Context(), authUserID(c), id); err != nil {
+		return err
+	}
+
+	return response.NoContent(c)
+}
+
+// ListMembers godoc
+// @Summary List organization members
+// @Description Get a paginated list of the members of an organization the authenticated user belongs to
+// @Tags Organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(10)
+// @Success 200 {object} response.Response{data=[]dto.OrganizationMemberResponse,meta=response.Meta}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/orgs/{id}/members [get]
+func (h *OrganizationHandler) ListMembers(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	page, perPage, err := paginationQuery(c)
+	if err != nil {
+		return err
+	}
+
+	members, total, err := h.service.ListMembers(c.Context(), authUserID(c), id, page, perPage)
+	if err != nil {
+		return err
+	}
+
+	return response.SuccessWithMeta(c, members, response.NewMeta(page, perPage, total))
+}
+
+// InviteMember godoc
+// @Summary Invite a member
+// @Description Email an invitation to join the organization (owners and admins only). Inviting the same address again replaces the pending invitation.
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param request body dto.InviteMemberRequest true "Invitation"
+// @Success 201 {object} response.Response{data=dto.OrganizationInvitationResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/orgs/{id}/members [post]
+func (h *OrganizationHandler) InviteMember(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req dto.InviteMemberRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	inv, err := h.service.Invite(c.Context(), authUserID(c), id, req)
+	if err != nil {
+		return err
+	}
+
+	return response.Created(c, inv)
+}
+
+// UpdateMemberRole godoc
+// @Summary Change a member's role
+// @Description Change a member's organization role (owners and admins only; only owners can grant or revoke ownership, and the last owner cannot be demoted)
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param userId path int true "Member user ID"
+// @Param request body dto.UpdateMemberRoleRequest true "Role update request"
+// @Success 200 {object} response.Response{data=dto.OrganizationMemberResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/orgs/{id}/members/{userId} [put]
+func (h *OrganizationHandler) UpdateMemberRole(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+	memberID, err := paramID(c, "userId")
+	if err != nil {
+		return err
+	}
+
+	var req dto.UpdateMemberRoleRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	member, err := h.service.UpdateMemberRole(c.Context(), authUserID(c), id, memberID, req.Role)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, member)
+}
+
+// RemoveMember godoc
+// @Summary Remove a member
+// @Description Remove a member from the organization (owners and admins), or leave it by passing your own user ID. The last owner cannot leave.
+// @Tags Organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param userId path int true "Member user ID"
+// @Success 204
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/orgs/{id}/members/{userId} [delete]
+func (h *OrganizationHandler) RemoveMember(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+	memberID, err := paramID(c, "userId")
+	if err != nil {
+		return err
+	}
+
+	if err := h.service.RemoveMember(c.Context(), authUserID(c), id, memberID); err != nil {
+		return err
+	}
+
+	return response.NoContent(c)
+}
+
+// AcceptInvitation godoc
+// @Summary Accept an invitation
+// @Description Join the organization an invitation token was issued for. The invitation must have been sent to the authenticated user's email address.
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.AcceptInvitationRequest true "Invitation token"
+// @Success 200 {object} response.Response{data=dto.OrganizationResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/orgs/invitations/accept [post]
+func (h *OrganizationHandler) AcceptInvitation(c fiber.Ctx) error {
+	var req dto.AcceptInvitationRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	org, err := h.service.AcceptInvitation(c.Context(), authUserID(c), req.Token)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, org)
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v3"
+	"github.com/valyala/fasthttp"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/realtime"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/response"
+)
+
+type RealtimeHandler struct {
+	hub      *realtime.Hub
+	tickets  *realtime.TicketStore
+	upgrader websocket.FastHTTPUpgrader
+}
+
+func NewRealtimeHandler(hub *realtime.Hub, tickets *realtime.TicketStore) *RealtimeHandler {
+	return &RealtimeHandler{
+		hub:     hub,
+		tickets: tickets,
+		upgrader: websocket.FastHTTPUpgrader{
+			// Connections are authenticated by a bearer token or ticket, never by cookies,
+			// so a page on another origin cannot open one on behalf of a user
+			CheckOrigin: func(*fasthttp.RequestCtx) bool { return true },
+		},
+	}
+}
+
+// IssueTicket godoc
+// @Summary Issue a WebSocket ticket
+// @Description Issue a single-use ticket opening a connection to the WebSocket gateway at GET /ws?ticket=..., for clients such as browsers that cannot send an Authorization header on the handshake. Tokens are not accepted in the query string, where they would be logged. The gateway pushes JSON events {"type", "data"} to the user, such as file.uploaded when a resumable upload is finalized and broadcast when an admin broadcast is sent; it closes the connection when the token the ticket was issued with expires.
+// @Tags Realtime
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} response.Response{data=dto.RealtimeTicketResponse}
+// @Failure 401 {object} response.Response
+// @Router /v1/realtime/ticket [post]
+func (h *RealtimeHandler) IssueTicket(c fiber.Ctx) error {
+	id, err := h.tickets.Issue(c.Context(), realtime.Ticket{
+		UserID:    authUserID(c),
+		ExpiresAt: fiber.Locals[time.Time](c, "token_expires_at"),
+	})
+	if err != nil {
+		slog.Error("failed to issue websocket ticket", slog.Any("error", err))
+		return apperror.NewInternal("failed to issue ticket")
+	}
+
+	return response.Created(c, dto.RealtimeTicketResponse{
+		Ticket:    id,
+		ExpiresAt: time.Now().Add(h.tickets.TTL()).UTC(),
+	})
+}
+
+// Connect upgrades an authenticated request to a WebSocket connection receiving the
+// events published to the user.
+func (h *RealtimeHandler) Connect(c fiber.Ctx) error {
+	if !websocket.FastHTTPIsWebSocketUpgrade(c.RequestCtx()) {
+		return fiber.ErrUpgradeRequired
+	}
+
+	client, err := h.hub.Register(authUserID(c))
+	if errors.Is(err, realtime.ErrTooManyConnections) {
+		return apperror.NewTooManyRequests("too many open connections")
+	}
+	if err != nil {
+		return apperror.NewServiceUnavailable("server is shutting down")
+	}
+
+	// Read before the upgrade, as the request context is released once it is hijacked
+	expiresAt := fiber.Locals[time.Time](c, "token_expires_at")
+	err = h.upgrader.Upgrade(c.RequestCtx(), func(conn *websocket.Conn) {
+		client.Serve(conn, expiresAt)
+	})
+	if err != nil {
+		client.Close()
+		return apperror.NewBadRequest("invalid websocket handshake")
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/service"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/response"
+)
+
+type SettingsHandler struct {
+	service service.SettingsService
+}
+
+func NewSettingsHandler(svc service.SettingsService) *SettingsHandler {
+	return &SettingsHandler{service: svc}
+}
+
+// Get godoc
+// @Summary Get runtime settings
+// @Description Get the settings admins can change without a restart; settings never changed show their default from the environment (requires settings:manage)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=dto.Settings}
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /v1/admin/settings [get]
+func (h *SettingsHandler) Get(c fiber.Ctx) error {
+	settings, err := h.service.Get(c.Context())
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, settings)
+}
+
+// Update godoc
+// @Summary Update runtime settings
+// @Description Change the settings present in the request; omitted settings are kept (requires settings:manage). Every instance applies the change within a minute. max_upload_size is in bytes and cannot raise uploads above APP_BODY_LIMIT.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.UpdateSettingsRequest true "Changes"
+// @Success 200 {object} response.Response{data=dto.Settings}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/admin/settings [put]
+func (h *SettingsHandler) Update(c fiber.Ctx) error {
+	var req dto.UpdateSettingsRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	settings, err := h.service.Update(auditContext(c), req)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, settings)
+}
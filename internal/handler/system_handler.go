@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/health"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/response"
+)
+
+type SystemHandler struct {
+	health *health.Checker
+}
+
+func NewSystemHandler(checker *health.Checker) *SystemHandler {
+	return &SystemHandler{health: checker}
+}
+
+// GetSystem godoc
+// @Summary Get system info
+// @Description Get the build version and commit, Go version, uptime, database pool usage, cache driver and hit rate, and storage driver of the instance serving the request (requires stats:read). Cache counts cover this instance since it started.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=health.SystemInfo}
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /v1/admin/system [get]
+func (h *SystemHandler) GetSystem(c fiber.Ctx) error {
+	return response.Success(c, h.health.System())
+}
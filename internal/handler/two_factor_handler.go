@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/service"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/response"
+)
+
+type TwoFactorHandler struct {
+	service     service.TwoFactorService
+	activitySvc service.UserActivityService
+}
+
+func NewTwoFactorHandler(svc service.TwoFactorService, activitySvc service.UserActivityService) *TwoFactorHandler {
+	return &TwoFactorHandler{service: svc, activitySvc: activitySvc}
+}
+
+// Setup godoc
+// @Summary Start two-factor setup
+// @Description Generate a TOTP secret for an authenticator app. Two-factor authentication stays off until the secret is confirmed at POST /auth/2fa/enable; calling this again replaces a pending secret.
+// @Tags Two-Factor
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=dto.TwoFactorSetupResponse}
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /v1/auth/2fa/setup [post]
+func (h *TwoFactorHandler) Setup(c fiber.Ctx) error {
+	setup, err := h.service.Setup(c.Context(), authUserID(c))
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, setup)
+}
+
+// Enable godoc
+// @Summary Enable two-factor authentication
+// @Description Confirm the pending secret with a code from the authenticator app. Returns the 10 single-use recovery codes, which are shown only this once.
+// @Tags Two-Factor
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.TwoFactorCodeRequest true "Code from the authenticator app"
+// @Success 200 {object} response.Response{data=dto.RecoveryCodesResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /v1/auth/2fa/enable [post]
+func (h *TwoFactorHandler) Enable(c fiber.Ctx) error {
+	var req dto.TwoFactorCodeRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	userID := authUserID(c)
+	codes, err := h.service.Enable(c.Context(), userID, req.Code)
+	if err != nil {
+		return err
+	}
+
+	recordActivity(c, h.activitySvc, userID, dto.ActivityTwoFactorEnabled, nil)
+	return response.Success(c, codes)
+}
+
+// Disable godoc
+// @Summary Disable two-factor authentication
+// @Description Turn off two-factor authentication, deleting the secret and recovery codes. Requires a code from the authenticator app or an unused recovery code.
+// @Tags Two-Factor
+// @Accept json
+// @Security BearerAuth
+// @Param request body dto.TwoFactorConfirmRequest true "TOTP code or recovery code"
+// @Success 204
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /v1/auth/2fa/disable [post]
+func (h *TwoFactorHandler) Disable(c fiber.Ctx) error {
+	var req dto.TwoFactorConfirmRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	userID := authUserID(c)
+	if err := h.service.Disable(c.Context(), userID, req); err != nil {
+		return err
+	}
+
+	recordActivity(c, h.activitySvc, userID, dto.ActivityTwoFactorDisabled, nil)
+	return response.NoContent(c)
+}
+
+// RecoveryCodesStatus godoc
+// @Summary Get two-factor status
+// @Description Report whether two-factor authentication is enabled and how many recovery codes are left unused. The codes themselves are stored hashed and cannot be listed again.
+// @Tags Two-Factor
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=dto.RecoveryCodesStatusResponse}
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /v1/auth/2fa/recovery-codes [get]
+func (h *TwoFactorHandler) RecoveryCodesStatus(c fiber.Ctx) error {
+	status, err := h.service.Status(c.Context(), authUserID(c))
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, status)
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replace every recovery code, used or not, with 10 new ones, which are shown only this once. Requires a code from the authenticator app or an unused recovery code.
+// @Tags Two-Factor
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.TwoFactorConfirmRequest true "TOTP code or recovery code"
+// @Success 200 {object} response.Response{data=dto.RecoveryCodesResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /v1/auth/2fa/recovery-codes [post]
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c fiber.Ctx) error {
+	var req dto.TwoFactorConfirmRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	userID := authUserID(c)
+	codes, err := h.service.RegenerateRecoveryCodes(c.Context(), userID, req)
+	if err != nil {
+		return err
+	}
+
+	recordActivity(c, h.activitySvc, userID, dto.ActivityRecoveryCodesRegenerated, nil)
+	return response.Success(c, codes)
+}
@@ -1,30 +1,70 @@
 package handler
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
 	"fmt"
 	"io"
+	"log/slog"
+	"mime/multipart"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
 
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/service"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/response"
 )
 
+const (
+	// sharedPath is where share links are served, relative to the server's base URL.
+	sharedPath = "/api/v1/shared/"
+	// uploadProgressPath is where upload progress is served, formatted with the upload ID.
+	uploadProgressPath = "/api/v1/files/uploads/%d/progress?token="
+	// storedCacheControl lets browsers, but no shared cache, keep files served by the local
+	// driver's /uploads route. The hour also bounds how long a revoked grant keeps working.
+	storedCacheControl = "private, max-age=3600"
+)
+
 type UploadHandler struct {
-	service     service.UploadService
-	maxFileSize int64
-	allowedMIME map[string]struct{}
+	service          service.UploadService
+	resumableSvc     service.ResumableUploadService
+	shareSvc         service.FileShareService
+	activitySvc      service.UserActivityService
+	settings         service.RuntimeSettings // max_upload_size caps single-request uploads
+	maxResumableSize int64
+	allowedMIME      map[string]struct{}
 }
 
-func NewUploadHandler(svc service.UploadService, maxFileSize int64, allowedTypes []string) *UploadHandler {
+func NewUploadHandler(
+	svc service.UploadService,
+	resumableSvc service.ResumableUploadService,
+	shareSvc service.FileShareService,
+	activitySvc service.UserActivityService,
+	settings service.RuntimeSettings,
+	maxResumableSize int64,
+	allowedTypes []string,
+) *UploadHandler {
 	allowed := make(map[string]struct{}, len(allowedTypes))
 	for _, t := range allowedTypes {
 		allowed[t] = struct{}{}
 	}
-	return &UploadHandler{service: svc, maxFileSize: maxFileSize, allowedMIME: allowed}
+	return &UploadHandler{
+		service:          svc,
+		resumableSvc:     resumableSvc,
+		shareSvc:         shareSvc,
+		activitySvc:      activitySvc,
+		settings:         settings,
+		maxResumableSize: maxResumableSize,
+		allowedMIME:      allowed,
+	}
 }
 
 // Upload godoc
@@ -35,57 +75,174 @@ func NewUploadHandler(svc service.UploadService, maxFileSize int64, allowedTypes
 // @Produce json
 // @Security BearerAuth
 // @Param file formData file true "File to upload"
+// @Param tags formData string false "Comma-separated tags"
 // @Success 201 {object} response.Response{data=dto.FileResponse}
 // @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
 // @Failure 500 {object} response.Response
-// @Router /files/upload [post]
+// @Router /v1/files/upload [post]
 func (h *UploadHandler) Upload(c fiber.Ctx) error {
-	fileHeader, err := c.FormFile("file")
+	fileHeader, file, contentType, err := h.openUpload(c)
 	if err != nil {
-		return apperror.NewBadRequest("file is required")
+		return err
 	}
+	defer func() { _ = file.Close() }()
 
-	if fileHeader.Size > h.maxFileSize {
-		return apperror.NewBadRequest(fmt.Sprintf("file size exceeds %dMB limit", h.maxFileSize/(1<<20)))
+	var tags []string
+	if v := c.FormValue("tags"); v != "" {
+		tags = strings.Split(v, ",")
 	}
 
-	file, err := fileHeader.Open()
+	result, err := h.service.Upload(c.Context(), authUserID(c), fileHeader.Filename, file, fileHeader.Size, contentType, tags)
+	if err != nil {
+		return err
+	}
+	recordActivity(c, h.activitySvc, authUserID(c), dto.ActivityFileUploaded, map[string]any{
+		"file_id": result.ID, "name": result.OriginalName, "size": result.Size,
+	})
+
+	return response.Created(c, result)
+}
+
+// ReplaceContent godoc
+// @Summary Upload a new version of a file
+// @Description Replace a file's content while keeping its ID, name, folder, tags and permissions. The previous content is kept as a version that can be listed, downloaded and restored. Sending the current content again changes nothing.
+// @Tags Files
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "File ID"
+// @Param file formData file true "New content"
+// @Success 200 {object} response.Response{data=dto.FileResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /v1/files/{id}/content [put]
+func (h *UploadHandler) ReplaceContent(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	fileHeader, file, contentType, err := h.openUpload(c)
 	if err != nil {
-		return apperror.NewInternal("failed to open uploaded file")
+		return err
 	}
 	defer func() { _ = file.Close() }()
 
-	// Detect actual MIME type from file content
-	buf := make([]byte, 512)
-	n, err := file.Read(buf)
-	if err != nil && err != io.EOF {
-		return apperror.NewInternal("failed to read uploaded file")
+	result, err := h.service.ReplaceContent(c.Context(), id, authUserID(c), fileHeader.Filename, file, fileHeader.Size, contentType)
+	if err != nil {
+		return err
 	}
-	contentType := http.DetectContentType(buf[:n])
+	recordActivity(c, h.activitySvc, authUserID(c), dto.ActivityFileUploaded, map[string]any{
+		"file_id": result.ID, "name": result.OriginalName, "size": result.Size, "version": result.Version,
+	})
 
-	if len(h.allowedMIME) > 0 {
-		if _, ok := h.allowedMIME[contentType]; !ok {
-			return apperror.NewBadRequest(fmt.Sprintf("file type %q is not allowed", contentType))
-		}
+	return response.Success(c, result)
+}
+
+// ListVersions godoc
+// @Summary List file versions
+// @Description List every version of a file's content, the current one first. Readable by anyone who can read the file.
+// @Tags Files
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "File ID"
+// @Success 200 {object} response.Response{data=[]dto.FileVersionResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/files/{id}/versions [get]
+func (h *UploadHandler) ListVersions(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
 	}
 
-	// Seek back to start so the service reads the full file
-	if _, err := file.Seek(0, io.SeekStart); err != nil {
-		return apperror.NewInternal("failed to process uploaded file")
+	versions, err := h.service.ListVersions(c.Context(), id, authUserID(c))
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, versions)
+}
+
+// DownloadVersion godoc
+// @Summary Download a file version
+// @Description Download one version of a file's content under the file's current name. Readable by anyone who can read the file.
+// @Tags Files
+// @Produce octet-stream
+// @Security BearerAuth
+// @Param id path int true "File ID"
+// @Param version path int true "Version number"
+// @Success 200
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/files/{id}/versions/{version}/download [get]
+func (h *UploadHandler) DownloadVersion(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+	version, err := paramVersion(c)
+	if err != nil {
+		return err
 	}
 
-	result, err := h.service.Upload(c.Context(), authUserID(c), fileHeader.Filename, file, fileHeader.Size, contentType)
+	file, reader, err := h.service.DownloadVersion(c.Context(), id, authUserID(c), version)
 	if err != nil {
 		return err
 	}
+	// SendStream closes the reader once the response is written, as in Download.
 
-	return response.Created(c, result)
+	c.Set("Content-Type", file.MimeType)
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.OriginalName))
+	c.Set("Content-Length", strconv.FormatInt(file.Size, 10))
+
+	return c.SendStream(reader)
+}
+
+// RestoreVersion godoc
+// @Summary Restore a file version
+// @Description Make an older version's content current again. The restore is saved as a new version, so the content it replaces is kept too.
+// @Tags Files
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "File ID"
+// @Param version path int true "Version number"
+// @Success 200 {object} response.Response{data=dto.FileResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /v1/files/{id}/versions/{version}/restore [post]
+func (h *UploadHandler) RestoreVersion(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+	version, err := paramVersion(c)
+	if err != nil {
+		return err
+	}
+
+	file, err := h.service.RestoreVersion(c.Context(), id, authUserID(c), version)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, file)
 }
 
 // GetInfo godoc
 // @Summary Get file info
-// @Description Get file metadata by ID
+// @Description Get file metadata by ID. Readable by the owner, by users granted access, and by any signed-in user if the file is public.
 // @Tags Files
 // @Produce json
 // @Security BearerAuth
@@ -93,8 +250,9 @@ func (h *UploadHandler) Upload(c fiber.Ctx) error {
 // @Success 200 {object} response.Response{data=dto.FileResponse}
 // @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
 // @Failure 404 {object} response.Response
-// @Router /files/{id} [get]
+// @Router /v1/files/{id} [get]
 func (h *UploadHandler) GetInfo(c fiber.Ctx) error {
 	id, err := paramID(c, "id")
 	if err != nil {
@@ -111,7 +269,7 @@ func (h *UploadHandler) GetInfo(c fiber.Ctx) error {
 
 // Download godoc
 // @Summary Download a file
-// @Description Download a file by ID
+// @Description Download a file by ID. Readable by the owner, by users granted access, and by any signed-in user if the file is public.
 // @Tags Files
 // @Produce octet-stream
 // @Security BearerAuth
@@ -119,8 +277,9 @@ func (h *UploadHandler) GetInfo(c fiber.Ctx) error {
 // @Success 200
 // @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
 // @Failure 404 {object} response.Response
-// @Router /files/{id}/download [get]
+// @Router /v1/files/{id}/download [get]
 func (h *UploadHandler) Download(c fiber.Ctx) error {
 	id, err := paramID(c, "id")
 	if err != nil {
@@ -144,6 +303,33 @@ func (h *UploadHandler) Download(c fiber.Ctx) error {
 	return c.SendStream(reader)
 }
 
+// ServeStored serves the local driver's /uploads/<path> URLs to users who can read a file
+// stored at that path. The route is outside the API, so it has no Swagger entry. Objects
+// are never rewritten under the same path, so the path makes a strong ETag.
+func (h *UploadHandler) ServeStored(c fiber.Ctx) error {
+	storagePath := c.Params("*")
+	if storagePath == "" {
+		return apperror.NewNotFound("file not found")
+	}
+
+	obj, reader, err := h.service.OpenStored(c.Context(), storagePath, authUserID(c))
+	if err != nil {
+		return err
+	}
+
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256([]byte(storagePath)))
+	c.Set("Cache-Control", storedCacheControl)
+	c.Set("ETag", etag)
+	if c.Get("If-None-Match") == etag {
+		_ = reader.Close()
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	c.Set("Content-Type", obj.MimeType)
+	c.Set("Content-Length", strconv.FormatInt(obj.Size, 10))
+	return c.SendStream(reader)
+}
+
 // List godoc
 // @Summary List user's files
 // @Description Get a paginated list of the authenticated user's files
@@ -152,17 +338,25 @@ func (h *UploadHandler) Download(c fiber.Ctx) error {
 // @Security BearerAuth
 // @Param page query int false "Page number" default(1)
 // @Param per_page query int false "Items per page" default(10)
+// @Param folder_id query int false "Only files in this folder; 0 for files outside any folder"
+// @Param tag query string false "Only files with this tag"
 // @Success 200 {object} response.Response{data=[]dto.FileResponse,meta=response.Meta}
 // @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
-// @Router /files [get]
+// @Failure 422 {object} response.Response
+// @Router /v1/files [get]
 func (h *UploadHandler) List(c fiber.Ctx) error {
 	page, perPage, err := paginationQuery(c)
 	if err != nil {
 		return err
 	}
 
-	files, total, err := h.service.List(c.Context(), authUserID(c), page, perPage)
+	var query dto.FileListQuery
+	if err := bindQueryAndValidate(c, &query); err != nil {
+		return err
+	}
+
+	files, total, err := h.service.List(c.Context(), authUserID(c), query, page, perPage)
 	if err != nil {
 		return err
 	}
@@ -170,6 +364,153 @@ func (h *UploadHandler) List(c fiber.Ctx) error {
 	return response.SuccessWithMeta(c, files, response.NewMeta(page, perPage, total))
 }
 
+// DownloadZip godoc
+// @Summary Download files as a ZIP archive
+// @Description Bundle up to 100 of your files into one ZIP archive. The archive is assembled while it is sent, so files are never buffered whole in memory. Entries are named after the files, with a counter added to repeated names.
+// @Tags Files
+// @Accept json
+// @Produce application/zip
+// @Security BearerAuth
+// @Param request body dto.DownloadZipRequest true "File IDs"
+// @Success 200 {file} file
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/files/download-zip [post]
+func (h *UploadHandler) DownloadZip(c fiber.Ctx) error {
+	var req dto.DownloadZipRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	files, err := h.service.ArchiveFiles(c.Context(), authUserID(c), req.FileIDs)
+	if err != nil {
+		return err
+	}
+
+	// The body is written after the handler returns, so the archive must not inherit
+	// the request timeout.
+	ctx := context.WithoutCancel(c.Context())
+
+	c.Attachment(fmt.Sprintf("files-%s.zip", time.Now().UTC().Format("20060102-150405")))
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		err := h.service.WriteArchive(ctx, w, files)
+		if err == nil {
+			err = w.Flush()
+		}
+		if err != nil {
+			slog.Error("zip download aborted", slog.Int("files", len(files)), slog.Any("error", err))
+		}
+	})
+}
+
+// Rename godoc
+// @Summary Rename a file
+// @Description Change the name a file is listed and downloaded under
+// @Tags Files
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "File ID"
+// @Param request body dto.RenameFileRequest true "New name"
+// @Success 200 {object} response.Response{data=dto.FileResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/files/{id}/name [put]
+func (h *UploadHandler) Rename(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req dto.RenameFileRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	file, err := h.service.Rename(c.Context(), id, authUserID(c), req.Name)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, file)
+}
+
+// Move godoc
+// @Summary Move a file
+// @Description Move a file into one of your folders, or out of folders with a null folder_id
+// @Tags Files
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "File ID"
+// @Param request body dto.MoveFileRequest true "Target folder"
+// @Success 200 {object} response.Response{data=dto.FileResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/files/{id}/move [post]
+func (h *UploadHandler) Move(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req dto.MoveFileRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	file, err := h.service.Move(c.Context(), id, authUserID(c), req.FolderID)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, file)
+}
+
+// SetTags godoc
+// @Summary Set file tags
+// @Description Replace a file's tags. Tags are trimmed, lowercased and deduplicated; an empty list removes them all.
+// @Tags Files
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "File ID"
+// @Param request body dto.SetFileTagsRequest true "Tags"
+// @Success 200 {object} response.Response{data=dto.FileResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/files/{id}/tags [put]
+func (h *UploadHandler) SetTags(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req dto.SetFileTagsRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	file, err := h.service.SetTags(c.Context(), id, authUserID(c), req.Tags)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, file)
+}
+
 // Delete godoc
 // @Summary Delete a file
 // @Description Delete a file by ID (ownership check)
@@ -181,7 +522,7 @@ func (h *UploadHandler) List(c fiber.Ctx) error {
 // @Failure 401 {object} response.Response
 // @Failure 403 {object} response.Response
 // @Failure 404 {object} response.Response
-// @Router /files/{id} [delete]
+// @Router /v1/files/{id} [delete]
 func (h *UploadHandler) Delete(c fiber.Ctx) error {
 	id, err := paramID(c, "id")
 	if err != nil {
@@ -194,3 +535,495 @@ func (h *UploadHandler) Delete(c fiber.Ctx) error {
 
 	return response.NoContent(c)
 }
+
+// CreateResumable godoc
+// @Summary Start a resumable upload
+// @Description Open an upload session for a large file that is then sent in chunks with PATCH /files/uploads/{id}. Abandoned sessions expire after STORAGE_UPLOAD_SESSION_TTL_HOURS.
+// @Tags Files
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.CreateUploadSessionRequest true "File name and total size in bytes"
+// @Success 201 {object} response.Response{data=dto.UploadSessionResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/files/uploads [post]
+func (h *UploadHandler) CreateResumable(c fiber.Ctx) error {
+	var req dto.CreateUploadSessionRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+	if req.Size > h.maxResumableSize {
+		return apperror.NewBadRequest(fmt.Sprintf("file size exceeds %dMB limit", h.maxResumableSize/(1<<20)))
+	}
+
+	session, err := h.resumableSvc.Create(c.Context(), authUserID(c), req)
+	if err != nil {
+		return err
+	}
+	setProgressURL(c, session)
+
+	return response.Created(c, session)
+}
+
+// ResumableStatus godoc
+// @Summary Get resumable upload progress
+// @Description Report how many bytes have been received, also returned in the Upload-Offset header. A client resumes by sending the next chunk from that offset.
+// @Tags Files
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Upload ID"
+// @Success 200 {object} response.Response{data=dto.UploadSessionResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/files/uploads/{id} [get]
+func (h *UploadHandler) ResumableStatus(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	session, err := h.resumableSvc.Status(c.Context(), id, authUserID(c))
+	if err != nil {
+		return err
+	}
+	setProgressURL(c, session)
+
+	c.Set("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	return response.Success(c, session)
+}
+
+// AppendChunk godoc
+// @Summary Upload a chunk
+// @Description Append the raw request body to a resumable upload. The Upload-Offset header must equal the bytes received so far; a mismatch returns 409 with the expected offset. The file type is checked on the first chunk. Chunks are limited by APP_BODY_LIMIT.
+// @Tags Files
+// @Accept octet-stream
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Upload ID"
+// @Param Upload-Offset header int true "Byte offset of this chunk"
+// @Success 200 {object} response.Response{data=dto.UploadSessionResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /v1/files/uploads/{id} [patch]
+func (h *UploadHandler) AppendChunk(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	offset, err := strconv.ParseInt(c.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return apperror.NewBadRequest("Upload-Offset header must be a non-negative integer")
+	}
+
+	chunk := c.Body()
+	var contentType string
+	if offset == 0 {
+		// The first chunk holds the bytes used to sniff the file type.
+		if contentType, err = h.detectContentType(chunk[:min(len(chunk), 512)]); err != nil {
+			return err
+		}
+	}
+
+	session, err := h.resumableSvc.Append(c.Context(), id, authUserID(c), offset,
+		bytes.NewReader(chunk), int64(len(chunk)), contentType)
+	if err != nil {
+		return err
+	}
+	setProgressURL(c, session)
+
+	c.Set("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	return response.Success(c, session)
+}
+
+// FinalizeResumable godoc
+// @Summary Finish a resumable upload
+// @Description Assemble the received chunks into a file once the whole file has been sent.
+// @Tags Files
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Upload ID"
+// @Success 201 {object} response.Response{data=dto.FileResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /v1/files/uploads/{id}/finalize [post]
+func (h *UploadHandler) FinalizeResumable(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	result, err := h.resumableSvc.Finalize(c.Context(), id, authUserID(c))
+	if err != nil {
+		return err
+	}
+	recordActivity(c, h.activitySvc, authUserID(c), dto.ActivityFileUploaded, map[string]any{
+		"file_id": result.ID, "name": result.OriginalName, "size": result.Size,
+	})
+
+	return response.Created(c, result)
+}
+
+// AbortResumable godoc
+// @Summary Cancel a resumable upload
+// @Description Discard an unfinished upload and the chunks received so far
+// @Tags Files
+// @Security BearerAuth
+// @Param id path int true "Upload ID"
+// @Success 204
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/files/uploads/{id} [delete]
+func (h *UploadHandler) AbortResumable(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.resumableSvc.Abort(c.Context(), id, authUserID(c)); err != nil {
+		return err
+	}
+
+	return response.NoContent(c)
+}
+
+// UploadProgress godoc
+// @Summary Poll resumable upload progress
+// @Description Report the bytes of a resumable upload written to storage, including a chunk still being stored and the assembly of the final file. No login is needed: the progress_token returned with the upload session is the credential, valid until the session expires. Once completed, file_id identifies the new file for a few minutes.
+// @Tags Files
+// @Produce json
+// @Param id path int true "Upload ID"
+// @Param token query string true "Progress token"
+// @Success 200 {object} response.Response{data=dto.UploadProgressResponse}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/files/uploads/{id}/progress [get]
+func (h *UploadHandler) UploadProgress(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	progress, err := h.resumableSvc.Progress(c.Context(), id, c.Query("token"))
+	if err != nil {
+		return err
+	}
+
+	c.Set("Cache-Control", "no-store")
+	return response.Success(c, progress)
+}
+
+// SetVisibility godoc
+// @Summary Change file visibility
+// @Description Make a file public, readable by any signed-in user, or private, readable only by the owner and users granted access
+// @Tags Files
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "File ID"
+// @Param request body dto.UpdateFileVisibilityRequest true "New visibility"
+// @Success 200 {object} response.Response{data=dto.FileResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/files/{id}/visibility [put]
+func (h *UploadHandler) SetVisibility(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req dto.UpdateFileVisibilityRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	file, err := h.service.SetVisibility(c.Context(), id, authUserID(c), req.Visibility)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, file)
+}
+
+// ListPermissions godoc
+// @Summary List file permissions
+// @Description List the users granted read access to a file
+// @Tags Files
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "File ID"
+// @Success 200 {object} response.Response{data=[]dto.FilePermissionResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/files/{id}/permissions [get]
+func (h *UploadHandler) ListPermissions(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	perms, err := h.service.ListPermissions(c.Context(), id, authUserID(c))
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, perms)
+}
+
+// GrantPermission godoc
+// @Summary Grant file access
+// @Description Let another user read a file. Granting access that already exists has no effect.
+// @Tags Files
+// @Security BearerAuth
+// @Param id path int true "File ID"
+// @Param userId path int true "User ID"
+// @Success 204
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/files/{id}/permissions/{userId} [put]
+func (h *UploadHandler) GrantPermission(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+	granteeID, err := paramID(c, "userId")
+	if err != nil {
+		return err
+	}
+
+	if err := h.service.GrantPermission(c.Context(), id, authUserID(c), granteeID); err != nil {
+		return err
+	}
+
+	return response.NoContent(c)
+}
+
+// RevokePermission godoc
+// @Summary Revoke file access
+// @Description Remove a user's read access to a file
+// @Tags Files
+// @Security BearerAuth
+// @Param id path int true "File ID"
+// @Param userId path int true "User ID"
+// @Success 204
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/files/{id}/permissions/{userId} [delete]
+func (h *UploadHandler) RevokePermission(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+	granteeID, err := paramID(c, "userId")
+	if err != nil {
+		return err
+	}
+
+	if err := h.service.RevokePermission(c.Context(), id, authUserID(c), granteeID); err != nil {
+		return err
+	}
+
+	return response.NoContent(c)
+}
+
+// Share godoc
+// @Summary Create a share link
+// @Description Create a public download link for a file. The link expires after ttl_hours (default STORAGE_SHARE_DEFAULT_TTL_HOURS, at most STORAGE_SHARE_MAX_TTL_HOURS), can be limited to max_downloads, and can require a password. The body may be omitted to use the defaults. The token and URL are only returned here.
+// @Tags Files
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "File ID"
+// @Param request body dto.CreateFileShareRequest false "Link options"
+// @Success 201 {object} response.Response{data=dto.FileShareResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/files/{id}/share [post]
+func (h *UploadHandler) Share(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var req dto.CreateFileShareRequest
+	if len(c.Body()) > 0 {
+		if err := bindAndValidate(c, &req); err != nil {
+			return err
+		}
+	}
+
+	share, err := h.shareSvc.Create(c.Context(), id, authUserID(c), req)
+	if err != nil {
+		return err
+	}
+	share.URL = c.BaseURL() + sharedPath + share.Token
+	recordActivity(c, h.activitySvc, authUserID(c), dto.ActivityFileShared, map[string]any{
+		"file_id": id, "share_id": share.ID, "expires_at": share.ExpiresAt,
+	})
+
+	return response.Created(c, share)
+}
+
+// ListShares godoc
+// @Summary List share links
+// @Description List the share links of a file. Expired and used-up links remain listed until the background purger removes them.
+// @Tags Files
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "File ID"
+// @Success 200 {object} response.Response{data=[]dto.FileShareResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/files/{id}/shares [get]
+func (h *UploadHandler) ListShares(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	shares, err := h.shareSvc.List(c.Context(), id, authUserID(c))
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, shares)
+}
+
+// RevokeShare godoc
+// @Summary Revoke a share link
+// @Description Delete a share link so it can no longer be used
+// @Tags Files
+// @Security BearerAuth
+// @Param id path int true "File ID"
+// @Param shareId path int true "Share link ID"
+// @Success 204
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/files/{id}/shares/{shareId} [delete]
+func (h *UploadHandler) RevokeShare(c fiber.Ctx) error {
+	id, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+	shareID, err := paramID(c, "shareId")
+	if err != nil {
+		return err
+	}
+
+	if err := h.shareSvc.Revoke(c.Context(), id, shareID, authUserID(c)); err != nil {
+		return err
+	}
+
+	return response.NoContent(c)
+}
+
+// SharedDownload godoc
+// @Summary Download a shared file
+// @Description Download a file through a share link without signing in. Password-protected links need the password in the X-Share-Password header. Each successful request counts towards the link's download limit.
+// @Tags Files
+// @Produce octet-stream
+// @Param token path string true "Share token"
+// @Param X-Share-Password header string false "Link password"
+// @Success 200
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/shared/{token} [get]
+func (h *UploadHandler) SharedDownload(c fiber.Ctx) error {
+	file, reader, err := h.shareSvc.Open(c.Context(), c.Params("token"), c.Get("X-Share-Password"))
+	if err != nil {
+		return err
+	}
+	// SendStream closes the reader once the response is written, as in Download.
+
+	c.Set("Content-Type", file.MimeType)
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.OriginalName))
+	c.Set("Content-Length", strconv.FormatInt(file.Size, 10))
+	c.Set("Cache-Control", "no-store")
+
+	return c.SendStream(reader)
+}
+
+// setProgressURL fills in the public progress URL of an upload session.
+func setProgressURL(c fiber.Ctx, session *dto.UploadSessionResponse) {
+	session.ProgressURL = c.BaseURL() + fmt.Sprintf(uploadProgressPath, session.ID) + url.QueryEscape(session.ProgressToken)
+}
+
+// openUpload opens the multipart "file" field after checking its size and detecting its
+// type from its content. The caller closes the file.
+func (h *UploadHandler) openUpload(c fiber.Ctx) (*multipart.FileHeader, multipart.File, string, error) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return nil, nil, "", apperror.NewBadRequest("file is required")
+	}
+
+	if maxFileSize := h.settings.Current(c.Context()).MaxUploadSize; fileHeader.Size > maxFileSize {
+		return nil, nil, "", apperror.NewBadRequest(fmt.Sprintf("file size exceeds %dMB limit", maxFileSize/(1<<20)))
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, nil, "", apperror.NewInternal("failed to open uploaded file")
+	}
+
+	// Detect actual MIME type from file content
+	buf := make([]byte, 512)
+	n, err := file.Read(buf)
+	if err != nil && err != io.EOF {
+		_ = file.Close()
+		return nil, nil, "", apperror.NewInternal("failed to read uploaded file")
+	}
+	contentType, err := h.detectContentType(buf[:n])
+	if err != nil {
+		_ = file.Close()
+		return nil, nil, "", err
+	}
+
+	// Seek back to start so the service reads the full file
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		_ = file.Close()
+		return nil, nil, "", apperror.NewInternal("failed to process uploaded file")
+	}
+
+	return fileHeader, file, contentType, nil
+}
+
+// detectContentType sniffs the MIME type from the start of a file and checks it against
+// the allowed types.
+func (h *UploadHandler) detectContentType(head []byte) (string, error) {
+	contentType := http.DetectContentType(head)
+	if len(h.allowedMIME) > 0 {
+		if _, ok := h.allowedMIME[contentType]; !ok {
+			return "", apperror.NewBadRequest(fmt.Sprintf("file type %q is not allowed", contentType))
+		}
+	}
+	return contentType, nil
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+)
+
+var userCSVHeader = []string{"id", "email", "username", "name", "role", "email_verified", "metadata", "created_at", "updated_at"}
+
+// writeUsers streams the users produced by each to w as CSV or a JSON array.
+func writeUsers(w *bufio.Writer, format string, each func(fn func(dto.AdminUserResponse) error) error) error {
+	if format == "json" {
+		return writeUsersJSON(w, each)
+	}
+	return writeUsersCSV(w, each)
+}
+
+func writeUsersCSV(w *bufio.Writer, each func(fn func(dto.AdminUserResponse) error) error) error {
+	out, err := newCSVStream(w, userCSVHeader)
+	if err != nil {
+		return err
+	}
+
+	err = each(func(u dto.AdminUserResponse) error {
+		metadata := ""
+		if len(u.Metadata) > 0 {
+			b, err := json.Marshal(u.Metadata)
+			if err != nil {
+				return err
+			}
+			metadata = string(b)
+		}
+		return out.Write([]string{
+			strconv.FormatInt(u.ID, 10),
+			csvSafe(u.Email),
+			u.Username,
+			csvSafe(u.Name),
+			u.Role,
+			strconv.FormatBool(u.EmailVerified),
+			csvSafe(metadata),
+			u.CreatedAt.UTC().Format(time.RFC3339),
+			u.UpdatedAt.UTC().Format(time.RFC3339),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+func writeUsersJSON(w *bufio.Writer, each func(fn func(dto.AdminUserResponse) error) error) error {
+	if err := w.WriteByte('['); err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	rows := 0
+	err := each(func(u dto.AdminUserResponse) error {
+		if rows > 0 {
+			if err := w.WriteByte(','); err != nil {
+				return err
+			}
+		}
+		if err := enc.Encode(u); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushEvery == 0 {
+			return w.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.WriteString("]\n"); err != nil {
+		return err
+	}
+	return w.Flush()
+}
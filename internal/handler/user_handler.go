@@ -1,6 +1,9 @@
 package handler
 
 import (
+	"fmt"
+	"strconv"
+
 	"github.com/gofiber/fiber/v3"
 
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
@@ -10,29 +13,62 @@ import (
 )
 
 type UserHandler struct {
-	service service.UserService
+	service        service.UserService
+	loginEventSvc  service.LoginEventService
+	emailChangeSvc service.EmailChangeService
+	deletionSvc    service.AccountDeletionService
+	dataExportSvc  service.DataExportService
+	erasureSvc     service.ErasureService
+	settingsSvc    service.UserSettingsService
+	activitySvc    service.UserActivityService
 }
 
-func NewUserHandler(svc service.UserService) *UserHandler {
-	return &UserHandler{service: svc}
+func NewUserHandler(
+	svc service.UserService,
+	loginEventSvc service.LoginEventService,
+	emailChangeSvc service.EmailChangeService,
+	deletionSvc service.AccountDeletionService,
+	dataExportSvc service.DataExportService,
+	erasureSvc service.ErasureService,
+	settingsSvc service.UserSettingsService,
+	activitySvc service.UserActivityService,
+) *UserHandler {
+	return &UserHandler{
+		service:        svc,
+		loginEventSvc:  loginEventSvc,
+		emailChangeSvc: emailChangeSvc,
+		deletionSvc:    deletionSvc,
+		dataExportSvc:  dataExportSvc,
+		erasureSvc:     erasureSvc,
+		settingsSvc:    settingsSvc,
+		activitySvc:    activitySvc,
+	}
 }
 
 // GetMe godoc
 // @Summary Get current user
-// @Description Get the authenticated user's profile
+// @Description Get the authenticated user's profile, including their settings
 // @Tags Users
 // @Produce json
 // @Security BearerAuth
 // @Success 200 {object} response.Response{data=dto.UserResponse}
 // @Failure 401 {object} response.Response
-// @Router /users/me [get]
+// @Router /v1/users/me [get]
 func (h *UserHandler) GetMe(c fiber.Ctx) error {
-	user, err := h.service.GetByID(c.Context(), authUserID(c))
+	userID := authUserID(c)
+	user, err := h.service.GetByID(c.Context(), userID)
 	if err != nil {
 		return err
 	}
 
-	return response.Success(c, user)
+	settings, err := h.settingsSvc.Get(c.Context(), userID)
+	if err != nil {
+		return err
+	}
+	me := *user
+	me.Settings = settings
+
+	return response.Success(c, me)
 }
 
 // GetByID godoc
@@ -46,7 +82,7 @@ func (h *UserHandler) GetMe(c fiber.Ctx) error {
 // @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
 // @Failure 404 {object} response.Response
-// @Router /users/{id} [get]
+// @Router /v1/users/{id} [get]
 func (h *UserHandler) GetByID(c fiber.Ctx) error {
 	id, err := paramID(c, "id")
 	if err != nil {
@@ -61,25 +97,58 @@ func (h *UserHandler) GetByID(c fiber.Ctx) error {
 	return response.Success(c, user)
 }
 
+// GetByUsername godoc
+// @Summary Get user by username
+// @Description Look up a user's public profile by username (case-insensitive). The email address is not included.
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Param handle path string true "Username"
+// @Success 200 {object} response.Response{data=dto.PublicUserResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/users/by-username/{handle} [get]
+func (h *UserHandler) GetByUsername(c fiber.Ctx) error {
+	user, err := h.service.GetByUsername(c.Context(), c.Params("handle"))
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, user)
+}
+
 // List godoc
 // @Summary List users
-// @Description Get a paginated list of users
+// @Description Get a paginated list of users, optionally searched, filtered and sorted
 // @Tags Users
 // @Produce json
 // @Security BearerAuth
 // @Param page query int false "Page number" default(1)
 // @Param per_page query int false "Items per page" default(10)
+// @Param q query string false "Search name or email (case-insensitive substring)"
+// @Param role query string false "Filter by built-in role"
+// @Param email_verified query bool false "Filter by email verification status"
+// @Param created_after query string false "Only users created at or after this RFC 3339 timestamp"
+// @Param created_before query string false "Only users created before this RFC 3339 timestamp"
+// @Param sort query string false "Sort field, prefix with - for descending" Enums(id, -id, name, -name, email, -email, created_at, -created_at)
 // @Success 200 {object} response.Response{data=[]dto.UserResponse,meta=response.Meta}
 // @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
-// @Router /users [get]
+// @Failure 403 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/users [get]
 func (h *UserHandler) List(c fiber.Ctx) error {
 	page, perPage, err := paginationQuery(c)
 	if err != nil {
 		return err
 	}
 
-	users, total, err := h.service.List(c.Context(), page, perPage)
+	var query dto.UserFilterQuery
+	if err := bindQueryAndValidate(c, &query); err != nil {
+		return err
+	}
+
+	users, total, err := h.service.List(c.Context(), query, page, perPage)
 	if err != nil {
 		return err
 	}
@@ -89,7 +158,7 @@ func (h *UserHandler) List(c fiber.Ctx) error {
 
 // UpdateMe godoc
 // @Summary Update current user
-// @Description Update the authenticated user's profile
+// @Description Update the authenticated user's profile. A new email address only takes effect after it is confirmed via the link sent to it.
 // @Tags Users
 // @Accept json
 // @Produce json
@@ -98,24 +167,29 @@ func (h *UserHandler) List(c fiber.Ctx) error {
 // @Success 200 {object} response.Response{data=dto.UserResponse}
 // @Failure 401 {object} response.Response
 // @Failure 422 {object} response.Response
-// @Router /users/me [put]
+// @Router /v1/users/me [put]
 func (h *UserHandler) UpdateMe(c fiber.Ctx) error {
 	var req dto.UpdateUserRequest
 	if err := bindAndValidate(c, &req); err != nil {
 		return err
 	}
 
+	if err := h.requestEmailChange(c, authUserID(c), &req); err != nil {
+		return err
+	}
+
 	user, err := h.service.Update(c.Context(), authUserID(c), req)
 	if err != nil {
 		return err
 	}
+	h.recordProfileUpdate(c, user.ID, req)
 
 	return response.Success(c, user)
 }
 
 // Update godoc
 // @Summary Update user by ID
-// @Description Update a user's profile (admin or self)
+// @Description Update a user's profile (admin or self). Users changing their own email must confirm the new address; admins change other users' emails directly.
 // @Tags Users
 // @Accept json
 // @Produce json
@@ -128,7 +202,7 @@ func (h *UserHandler) UpdateMe(c fiber.Ctx) error {
 // @Failure 403 {object} response.Response
 // @Failure 404 {object} response.Response
 // @Failure 422 {object} response.Response
-// @Router /users/{id} [put]
+// @Router /v1/users/{id} [put]
 func (h *UserHandler) Update(c fiber.Ctx) error {
 	id, err := paramID(c, "id")
 	if err != nil {
@@ -144,17 +218,59 @@ func (h *UserHandler) Update(c fiber.Ctx) error {
 		return err
 	}
 
+	if id == authUserID(c) {
+		if err := h.requestEmailChange(c, id, &req); err != nil {
+			return err
+		}
+	}
+
 	user, err := h.service.Update(c.Context(), id, req)
 	if err != nil {
 		return err
 	}
+	h.recordProfileUpdate(c, id, req)
 
 	return response.Success(c, user)
 }
 
+// recordProfileUpdate logs the names of the fields an update applied, not their values.
+// Emails awaiting confirmation have already been stripped from req.
+func (h *UserHandler) recordProfileUpdate(c fiber.Ctx, userID int64, req dto.UpdateUserRequest) {
+	var fields []string
+	if req.Name != nil {
+		fields = append(fields, "name")
+	}
+	if req.Email != nil {
+		fields = append(fields, "email")
+	}
+	if req.Username != nil {
+		fields = append(fields, "username")
+	}
+	if len(req.Metadata) > 0 {
+		fields = append(fields, "metadata")
+	}
+	if len(fields) == 0 {
+		return
+	}
+	recordActivity(c, h.activitySvc, userID, dto.ActivityProfileUpdated, map[string]any{"fields": fields})
+}
+
+// requestEmailChange starts the confirmation flow for a self-service email change and
+// strips the email from the request so the remaining fields are applied immediately.
+func (h *UserHandler) requestEmailChange(c fiber.Ctx, userID int64, req *dto.UpdateUserRequest) error {
+	if req.Email == nil {
+		return nil
+	}
+	if err := h.emailChangeSvc.RequestChange(c.Context(), userID, *req.Email); err != nil {
+		return err
+	}
+	req.Email = nil
+	return nil
+}
+
 // ChangePassword godoc
 // @Summary Change password
-// @Description Change the authenticated user's password
+// @Description Change the authenticated user's password. Every session is signed out: refresh tokens are deleted and access tokens issued before the change are rejected.
 // @Tags Users
 // @Accept json
 // @Produce json
@@ -164,7 +280,7 @@ func (h *UserHandler) Update(c fiber.Ctx) error {
 // @Failure 400 {object} response.Response
 // @Failure 401 {object} response.Response
 // @Failure 422 {object} response.Response
-// @Router /users/me/password [put]
+// @Router /v1/users/me/password [put]
 func (h *UserHandler) ChangePassword(c fiber.Ctx) error {
 	var req dto.ChangePasswordRequest
 	if err := bindAndValidate(c, &req); err != nil {
@@ -174,13 +290,14 @@ func (h *UserHandler) ChangePassword(c fiber.Ctx) error {
 	if err := h.service.ChangePassword(c.Context(), authUserID(c), req); err != nil {
 		return err
 	}
+	recordActivity(c, h.activitySvc, authUserID(c), dto.ActivityPasswordChanged, nil)
 
 	return response.Success(c, fiber.Map{"message": "password changed successfully"})
 }
 
 // Delete godoc
 // @Summary Delete user
-// @Description Delete a user by ID
+// @Description Delete a user by ID and sign them out of every session. The last active admin cannot be deleted (409 LAST_ADMIN).
 // @Tags Users
 // @Security BearerAuth
 // @Param id path int true "User ID"
@@ -189,7 +306,8 @@ func (h *UserHandler) ChangePassword(c fiber.Ctx) error {
 // @Failure 401 {object} response.Response
 // @Failure 403 {object} response.Response
 // @Failure 404 {object} response.Response
-// @Router /users/{id} [delete]
+// @Failure 409 {object} response.Response
+// @Router /v1/users/{id} [delete]
 func (h *UserHandler) Delete(c fiber.Ctx) error {
 	id, err := paramID(c, "id")
 	if err != nil {
@@ -206,3 +324,238 @@ func (h *UserHandler) Delete(c fiber.Ctx) error {
 
 	return response.NoContent(c)
 }
+
+// ListLogins godoc
+// @Summary List login history
+// @Description Get the authenticated user's recent successful and failed login attempts
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(10)
+// @Success 200 {object} response.Response{data=[]dto.LoginEventResponse,meta=response.Meta}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /v1/users/me/security/logins [get]
+func (h *UserHandler) ListLogins(c fiber.Ctx) error {
+	page, perPage, err := paginationQuery(c)
+	if err != nil {
+		return err
+	}
+
+	events, total, err := h.loginEventSvc.ListByUser(c.Context(), authUserID(c), page, perPage)
+	if err != nil {
+		return err
+	}
+
+	return response.SuccessWithMeta(c, events, response.NewMeta(page, perPage, total))
+}
+
+// ListActivity godoc
+// @Summary List account activity
+// @Description Get the authenticated user's account activity trail (profile updates, password and role changes, file uploads), newest first
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page" default(10)
+// @Success 200 {object} response.Response{data=[]dto.UserActivityResponse,meta=response.Meta}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /v1/users/me/activity [get]
+func (h *UserHandler) ListActivity(c fiber.Ctx) error {
+	page, perPage, err := paginationQuery(c)
+	if err != nil {
+		return err
+	}
+
+	activities, total, err := h.activitySvc.ListByUser(c.Context(), authUserID(c), page, perPage)
+	if err != nil {
+		return err
+	}
+
+	return response.SuccessWithMeta(c, activities, response.NewMeta(page, perPage, total))
+}
+
+// DeleteMe godoc
+// @Summary Schedule account deletion
+// @Description Schedule the authenticated user's account for permanent deletion after the grace period. A cancellation link is emailed to the user. The last active admin cannot schedule it (409 LAST_ADMIN).
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=dto.AccountDeletionResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Router /v1/users/me [delete]
+func (h *UserHandler) DeleteMe(c fiber.Ctx) error {
+	resp, err := h.deletionSvc.Schedule(c.Context(), authUserID(c))
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, resp)
+}
+
+// GetSettings godoc
+// @Summary Get my settings
+// @Description Get the authenticated user's locale, timezone and email notification preferences. Defaults are returned until settings are saved.
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=dto.UserSettingsResponse}
+// @Failure 401 {object} response.Response
+// @Router /v1/users/me/settings [get]
+func (h *UserHandler) GetSettings(c fiber.Ctx) error {
+	settings, err := h.settingsSvc.Get(c.Context(), authUserID(c))
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, settings)
+}
+
+// UpdateSettings godoc
+// @Summary Update my settings
+// @Description Update the authenticated user's locale (BCP 47), timezone (IANA) and email notification preferences. Omitted fields are left unchanged.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.UpdateUserSettingsRequest true "Settings"
+// @Success 200 {object} response.Response{data=dto.UserSettingsResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/users/me/settings [put]
+func (h *UserHandler) UpdateSettings(c fiber.Ctx) error {
+	var req dto.UpdateUserSettingsRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	settings, err := h.settingsSvc.Update(c.Context(), authUserID(c), req)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, settings)
+}
+
+// EraseMe godoc
+// @Summary Erase my personal data
+// @Description Immediately and irreversibly anonymize the authenticated user's account: email, name, credentials, linked identities and metadata are scrubbed, files, tokens, passkeys and data exports are removed, and login history is stripped of addresses and user agents. The request must repeat the account's email address. The last active admin cannot erase their account (409 LAST_ADMIN).
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.EraseAccountRequest true "Confirmation"
+// @Success 200 {object} response.Response{data=dto.ErasureResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 409 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Router /v1/users/me/erase [post]
+func (h *UserHandler) EraseMe(c fiber.Ctx) error {
+	var req dto.EraseAccountRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	resp, err := h.erasureSvc.EraseSelf(c.Context(), authUserID(c), req)
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, resp)
+}
+
+// RequestDataExport godoc
+// @Summary Request a personal data export
+// @Description Start assembling an archive of the authenticated user's profile, file metadata and login history. The user is emailed when it is ready for download.
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 202 {object} response.Response{data=dto.DataExportResponse}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /v1/users/me/data-export [post]
+func (h *UserHandler) RequestDataExport(c fiber.Ctx) error {
+	resp, err := h.dataExportSvc.Request(c.Context(), authUserID(c))
+	if err != nil {
+		return err
+	}
+
+	return response.Accepted(c, resp)
+}
+
+// GetDataExport godoc
+// @Summary Get personal data export status
+// @Description Get the status of the authenticated user's most recent data export
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=dto.DataExportResponse}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/users/me/data-export [get]
+func (h *UserHandler) GetDataExport(c fiber.Ctx) error {
+	resp, err := h.dataExportSvc.Latest(c.Context(), authUserID(c))
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, resp)
+}
+
+// DownloadDataExport godoc
+// @Summary Download personal data export
+// @Description Download the authenticated user's most recent data export as a zip archive. Only ready, unexpired exports can be downloaded.
+// @Tags Users
+// @Produce application/zip
+// @Security BearerAuth
+// @Success 200
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /v1/users/me/data-export/download [get]
+func (h *UserHandler) DownloadDataExport(c fiber.Ctx) error {
+	reader, export, err := h.dataExportSvc.Open(c.Context(), authUserID(c))
+	if err != nil {
+		return err
+	}
+	// SendStream closes the reader once the body has been written.
+
+	c.Set("Content-Type", "application/zip")
+	c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="data-export-%d.zip"`, export.ID))
+	c.Set("Content-Length", strconv.FormatInt(export.Size, 10))
+
+	return c.SendStream(reader)
+}
+
+// CancelDeletion godoc
+// @Summary Cancel account deletion
+// @Description Cancel a scheduled account deletion using the token from the confirmation email
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.CancelAccountDeletionRequest true "Cancel account deletion request"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Failure 429 {object} response.Response
+// @Router /v1/auth/cancel-account-deletion [post]
+func (h *UserHandler) CancelDeletion(c fiber.Ctx) error {
+	var req dto.CancelAccountDeletionRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.deletionSvc.Cancel(c.Context(), req.Token); err != nil {
+		return err
+	}
+
+	return response.Success(c, fiber.Map{"message": "account deletion cancelled"})
+}
@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto/v2"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/response"
+)
+
+// GetMeV2 godoc
+// @Summary Get current user
+// @Description Get the authenticated user's profile. Unlike v1, the settings are not included; read them from GET /users/me/settings
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Response{data=v2.UserResponse}
+// @Failure 401 {object} response.Response
+// @Router /v2/users/me [get]
+func (h *UserHandler) GetMeV2(c fiber.Ctx) error {
+	user, err := h.service.GetByID(c.Context(), authUserID(c))
+	if err != nil {
+		return err
+	}
+
+	return response.Success(c, v2.NewUserResponse(*user))
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+// readUserImport parses an import CSV. The header row must name the email and name
+// columns, in any order; role is optional and other columns are ignored.
+func readUserImport(r io.Reader) ([]dto.UserImportRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, apperror.NewBadRequest("file is empty")
+		}
+		return nil, apperror.NewBadRequest("invalid CSV: " + err.Error())
+	}
+
+	columns := map[string]int{"email": -1, "name": -1, "role": -1}
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		if _, ok := columns[h]; ok {
+			columns[h] = i
+		}
+	}
+	if columns["email"] < 0 || columns["name"] < 0 {
+		return nil, apperror.NewBadRequest("CSV header must include email and name columns")
+	}
+
+	field := func(record []string, column string) string {
+		if i := columns[column]; i >= 0 && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rows []dto.UserImportRow
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, apperror.NewBadRequest("invalid CSV: " + err.Error())
+		}
+		if len(rows) == dto.UserImportMaxRows {
+			return nil, apperror.NewBadRequest(fmt.Sprintf("import is limited to %d users", dto.UserImportMaxRows))
+		}
+
+		line, _ := cr.FieldPos(0)
+		rows = append(rows, dto.UserImportRow{
+			Line:  line,
+			Email: field(record, "email"),
+			Name:  field(record, "name"),
+			Role:  strings.ToLower(field(record, "role")),
+		})
+	}
+	if len(rows) == 0 {
+		return nil, apperror.NewBadRequest("file contains no users")
+	}
+	return rows, nil
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+// bodyLimitKey is the Fiber local holding the body limit set by RouteBodyLimit.
+const bodyLimitKey = "body_limit"
+
+// BodyLimit returns a middleware rejecting requests whose body is larger than the limit
+// RouteBodyLimit set for the route, or else than the limit of the longest prefix in routes
+// that the path is or is below, such as /api/v1/auth, or else than limit, with 413
+// PAYLOAD_TOO_LARGE. The server reads bodies up to the largest of these limits, so small
+// JSON routes do not have to accept what uploads need.
+func BodyLimit(limit int64, routes map[string]int64) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		maxSize := fiber.Locals[int64](c, bodyLimitKey)
+		if maxSize == 0 {
+			maxSize = routeBodyLimit(c.Path(), limit, routes)
+		}
+		if int64(len(c.Request().Body())) > maxSize {
+			return apperror.NewPayloadTooLarge(fmt.Sprintf("request body must be at most %d bytes", maxSize))
+		}
+		return c.Next()
+	}
+}
+
+// routeBodyLimit returns the limit of the longest prefix in routes matching path, or limit
+// when none does.
+func routeBodyLimit(path string, limit int64, routes map[string]int64) int64 {
+	longest := -1
+	for prefix, routeLimit := range routes {
+		if len(prefix) > longest && (path == prefix || strings.HasPrefix(path, prefix+"/")) {
+			longest = len(prefix)
+			limit = routeLimit
+		}
+	}
+	return limit
+}
+
+// RouteBodyLimit returns a middleware setting the body limit of the routes it is registered
+// on to limit bytes, for routes such as /files/:id/content that a path prefix cannot name.
+// It is registered ahead of BodyLimit, which checks the limit, and goes on to the route.
+func RouteBodyLimit(limit int64) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		c.Locals(bodyLimitKey, limit)
+		return c.Next()
+	}
+}
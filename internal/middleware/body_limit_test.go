@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+func TestBodyLimit(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.FiberErrorHandler})
+	app.Put("/api/:version/files/:id/content", RouteBodyLimit(100))
+	app.Use(BodyLimit(10, map[string]int64{"/api/v1/auth": 5}))
+	ok := func(c fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) }
+	app.Put("/api/v1/files/:id/content", ok)
+	app.Put("/api/v1/files/:id/name", ok)
+	app.Post("/api/v1/auth/login", ok)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		size   int
+		want   int
+	}{
+		{"route limit", fiber.MethodPut, "/api/v1/files/7/content", 50, fiber.StatusNoContent},
+		{"over the route limit", fiber.MethodPut, "/api/v1/files/7/content", 101, fiber.StatusRequestEntityTooLarge},
+		{"default limit", fiber.MethodPut, "/api/v1/files/7/name", 10, fiber.StatusNoContent},
+		{"over the default limit", fiber.MethodPut, "/api/v1/files/7/name", 50, fiber.StatusRequestEntityTooLarge},
+		{"over the prefix limit", fiber.MethodPost, "/api/v1/auth/login", 6, fiber.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(strings.Repeat("a", tt.size)))
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
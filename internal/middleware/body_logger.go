@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log/slog"
+	"mime"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+const redacted = "[REDACTED]"
+
+// redactedFragments are redacted in any field whose name contains them, such as
+// new_password, refresh_token or recovery_codes.
+var redactedFragments = []string{"password", "token", "secret", "recovery_code"}
+
+// redactedFields are redacted in fields with exactly these names, which are too short or
+// common to match as fragments. WebAuthn credentials, SAML assertions, two-factor codes and
+// the otpauth URI carrying a TOTP secret are included.
+var redactedFields = []string{"authorization", "api_key", "private_key", "credential", "assertion", "code", "otpauth_uri"}
+
+// BodyLogger returns a middleware that logs the request and response bodies of requests to
+// the given path prefixes, such as /api/v1/admin. JSON and form fields that may hold
+// secrets, such as passwords, tokens and WebAuthn credentials, are redacted, along with extra, and
+// each body is cut to maxSize bytes. Multipart and binary bodies are logged by size only,
+// and streamed responses are not logged. It must run after Compress to see responses
+// before they are compressed.
+func BodyLogger(prefixes []string, maxSize int, extra []string) fiber.Handler {
+	fields := make([]string, 0, len(redactedFields)+len(extra))
+	fields = append(fields, redactedFields...)
+	for _, field := range extra {
+		fields = append(fields, strings.ToLower(field))
+	}
+
+	return func(c fiber.Ctx) error {
+		if !matchesPrefix(c.Path(), prefixes) {
+			return c.Next()
+		}
+
+		err := c.Next()
+
+		resp := c.Response()
+		status := resp.StatusCode()
+		responseBody := "[stream]"
+		switch {
+		case err != nil:
+			// The error handler writes the response once the error is returned, so the
+			// error is logged in place of the body.
+			status = apperror.StatusCode(err)
+			responseBody = "[error: " + err.Error() + "]"
+		case !resp.IsBodyStream():
+			responseBody = sanitizeBody(resp.Body(), string(resp.Header.ContentType()), maxSize, fields)
+		}
+		slog.LogAttrs(c.Context(), slog.LevelInfo, "http body",
+			slog.String("request_id", fiber.Locals[string](c, "request_id")),
+			slog.String("method", c.Method()),
+			slog.String("path", c.Path()),
+			slog.Int("status", status),
+			slog.String("request_body", sanitizeBody(c.Body(), string(c.Request().Header.ContentType()), maxSize, fields)),
+			slog.String("response_body", responseBody),
+		)
+		return err
+	}
+}
+
+// matchesPrefix reports whether path is one of prefixes or below one of them.
+func matchesPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeBody returns body as logged: redacted when it is JSON or a form, and cut to
+// maxSize bytes.
+func sanitizeBody(body []byte, contentType string, maxSize int, fields []string) string {
+	if len(body) == 0 {
+		return ""
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	var text string
+	switch {
+	case mediaType == fiber.MIMEApplicationJSON || strings.HasSuffix(mediaType, "+json"):
+		var v any
+		if err := json.Unmarshal(body, &v); err != nil {
+			return "[" + strconv.Itoa(len(body)) + " bytes of invalid JSON]"
+		}
+		data, _ := json.Marshal(redactJSON(v, fields))
+		text = string(data)
+	case mediaType == fiber.MIMEApplicationForm:
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return "[" + strconv.Itoa(len(body)) + " bytes of invalid form data]"
+		}
+		for key := range values {
+			if isSecretField(key, fields) {
+				values[key] = []string{redacted}
+			}
+		}
+		text = values.Encode()
+	case strings.HasPrefix(mediaType, "text/"):
+		text = string(body)
+	case mediaType == "":
+		return "[" + strconv.Itoa(len(body)) + " bytes]"
+	default:
+		return "[" + strconv.Itoa(len(body)) + " bytes of " + mediaType + "]"
+	}
+
+	if len(text) > maxSize {
+		// Back off to the start of the character at the cut, so none is split
+		cut := maxSize
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		return text[:cut] + "...[truncated " + strconv.Itoa(len(text)-cut) + " bytes]"
+	}
+	return text
+}
+
+// redactJSON replaces the values of secret fields anywhere in v.
+func redactJSON(v any, fields []string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if isSecretField(key, fields) {
+				v[key] = redacted
+			} else {
+				v[key] = redactJSON(value, fields)
+			}
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = redactJSON(value, fields)
+		}
+	}
+	return v
+}
+
+func isSecretField(name string, fields []string) bool {
+	name = strings.ToLower(name)
+	for _, fragment := range redactedFragments {
+		if strings.Contains(name, fragment) {
+			return true
+		}
+	}
+	return slices.Contains(fields, name)
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+func TestSanitizeBody(t *testing.T) {
+	t.Run("redacts secret fields", func(t *testing.T) {
+		got := sanitizeBody([]byte(`{"email":"a@example.com","new_password":"x","nested":{"api_key":"k"}}`), fiber.MIMEApplicationJSON, 1000, redactedFields)
+		if strings.Contains(got, `"x"`) || strings.Contains(got, `"k"`) || !strings.Contains(got, "a@example.com") {
+			t.Errorf("unexpected body %s", got)
+		}
+	})
+
+	t.Run("redacts two-factor codes", func(t *testing.T) {
+		got := sanitizeBody([]byte(`{"code":"123456","recovery_codes":["abcde-fghij"],"otpauth_uri":"otpauth://totp/x?secret=S"}`), fiber.MIMEApplicationJSON, 1000, redactedFields)
+		if strings.Contains(got, "123456") || strings.Contains(got, "abcde") || strings.Contains(got, "otpauth://") {
+			t.Errorf("unexpected body %s", got)
+		}
+	})
+
+	t.Run("truncates on a character boundary", func(t *testing.T) {
+		// "é" is two bytes, so a cut after 3 bytes falls inside the second one
+		got := sanitizeBody([]byte("éééé"), fiber.MIMETextPlain, 3, nil)
+		if got != "é...[truncated 6 bytes]" {
+			t.Errorf("got %q", got)
+		}
+	})
+}
+
+func TestBodyLogger_ReturnsHandlerErrors(t *testing.T) {
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	var handled error
+	app := fiber.New(fiber.Config{ErrorHandler: func(c fiber.Ctx, err error) error {
+		handled = err
+		return apperror.FiberErrorHandler(c, err)
+	}})
+	app.Use(BodyLogger([]string{"/admin"}, 100, nil))
+	app.Get("/admin/missing", func(c fiber.Ctx) error {
+		return apperror.NewNotFound("user not found")
+	})
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/admin/missing", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusNotFound || handled == nil {
+		t.Errorf("expected the error to reach the error handler, got %d (%v)", resp.StatusCode, handled)
+	}
+	if out := logs.String(); !strings.Contains(out, "status=404") || !strings.Contains(out, "user not found") {
+		t.Errorf("expected the error logged, got %s", out)
+	}
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/netip"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/clientip"
+)
+
+// ClientIP returns a middleware resolving the client address of each request for
+// clientip.Get: the rightmost hop of header that is not one of proxies, on requests from
+// proxies, and the address the request comes from otherwise. It runs before anything
+// that reads the address.
+func ClientIP(proxies []netip.Prefix, header string) fiber.Handler {
+	resolver := clientip.NewResolver(proxies, header)
+	return func(c fiber.Ctx) error {
+		var values []string
+		if len(proxies) > 0 && header != "" {
+			for _, v := range c.Request().Header.PeekAll(header) {
+				values = append(values, string(v))
+			}
+		}
+		clientip.Set(c, resolver.Resolve(c.RequestCtx().RemoteIP().String(), values))
+		return c.Next()
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/clientip"
+)
+
+func clientIPApp(proxies []netip.Prefix) *fiber.App {
+	app := fiber.New()
+	app.Use(ClientIP(proxies, "X-Forwarded-For"))
+	app.Get("/", func(c fiber.Ctx) error {
+		return c.SendString(clientip.Get(c))
+	})
+	return app
+}
+
+func resolvedIP(t *testing.T, app *fiber.App, forwardedFor string) string {
+	t.Helper()
+	req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestClientIP(t *testing.T) {
+	remote := resolvedIP(t, clientIPApp(nil), "198.51.100.1")
+	if remote == "198.51.100.1" {
+		t.Fatal("expected the header to be ignored without trusted proxies")
+	}
+
+	trusted := clientIPApp([]netip.Prefix{netip.PrefixFrom(netip.MustParseAddr(remote), 32)})
+	if got := resolvedIP(t, trusted, "1.2.3.4, 198.51.100.1"); got != "198.51.100.1" {
+		t.Errorf("client IP = %q, want the rightmost untrusted hop", got)
+	}
+	if got := resolvedIP(t, trusted, ""); got != remote {
+		t.Errorf("client IP = %q, want the remote address %q", got, remote)
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/valyala/fasthttp"
+)
+
+// Compression levels accepted by Compress.
+const (
+	CompressionOff     = "off"
+	CompressionSpeed   = "speed"
+	CompressionDefault = "default"
+	CompressionBest    = "best"
+)
+
+// compressibleTypes are the media types worth compressing. Anything else, such as images,
+// archives and octet streams, is usually compressed already and is sent as is.
+var compressibleTypes = []string{
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"image/svg+xml",
+}
+
+// Compress returns a middleware that compresses responses with brotli, gzip or deflate,
+// whichever the client prefers in Accept-Encoding. Only text and JSON bodies of at least
+// minSize bytes are compressed; streamed bodies, such as downloads and exports, and
+// responses already carrying a Content-Encoding are left alone.
+func Compress(level string, minSize int) fiber.Handler {
+	noop := func(*fasthttp.RequestCtx) {}
+	var compressor fasthttp.RequestHandler
+	switch level {
+	case CompressionOff:
+		return func(c fiber.Ctx) error {
+			return c.Next()
+		}
+	case CompressionSpeed:
+		compressor = fasthttp.CompressHandlerBrotliLevel(noop, fasthttp.CompressBrotliBestSpeed, fasthttp.CompressBestSpeed)
+	case CompressionBest:
+		compressor = fasthttp.CompressHandlerBrotliLevel(noop, fasthttp.CompressBrotliBestCompression, fasthttp.CompressBestCompression)
+	default:
+		compressor = fasthttp.CompressHandlerBrotliLevel(noop, fasthttp.CompressBrotliDefaultCompression, fasthttp.CompressDefaultCompression)
+	}
+
+	return func(c fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			return err
+		}
+
+		resp := c.Response()
+		if c.Method() == fiber.MethodHead ||
+			resp.StatusCode() == fiber.StatusNoContent ||
+			resp.StatusCode() == fiber.StatusPartialContent ||
+			resp.IsBodyStream() ||
+			len(resp.Header.ContentEncoding()) > 0 ||
+			!compressibleType(string(resp.Header.ContentType())) {
+			return nil
+		}
+		// The response varies with Accept-Encoding whenever it could have been compressed.
+		c.Vary(fiber.HeaderAcceptEncoding)
+		if len(resp.Body()) < minSize {
+			return nil
+		}
+
+		compressor(c.RequestCtx())
+		return nil
+	}
+}
+
+// compressibleType reports whether a Content-Type is text, JSON or XML.
+func compressibleType(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "+json") {
+		return true
+	}
+	return slices.Contains(compressibleTypes, mediaType)
+}
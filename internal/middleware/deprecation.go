@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// Deprecation returns a middleware announcing that the routes it guards are deprecated:
+// the Deprecation header (RFC 9745) carries deprecatedAt, the Sunset header (RFC 8594)
+// the date they stop being served, and a Link with rel="deprecation" points to link, such
+// as a migration guide. Zero dates and an empty link are left out.
+func Deprecation(deprecatedAt, sunsetAt time.Time, link string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if !deprecatedAt.IsZero() {
+			c.Set("Deprecation", "@"+strconv.FormatInt(deprecatedAt.Unix(), 10))
+		}
+		if !sunsetAt.IsZero() {
+			c.Set("Sunset", sunsetAt.UTC().Format(http.TimeFormat))
+		}
+		if link != "" {
+			c.Append(fiber.HeaderLink, "<"+link+`>; rel="deprecation"; type="text/html"`)
+		}
+		return c.Next()
+	}
+}
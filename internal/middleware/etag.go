@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"bytes"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/etag"
+)
+
+// ETag returns a middleware that tags successful GET responses with a weak ETag computed
+// from the body, and answers 304 Not Modified without a body when the request's
+// If-None-Match already holds it. The handler still runs, so this saves bandwidth rather
+// than work. Tags are weak so they stay valid when Compress changes the encoding, and
+// responses are marked private so shared caches do not keep per-user data.
+func ETag() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			return err
+		}
+
+		resp := c.Response()
+		if c.Method() != fiber.MethodGet || resp.StatusCode() != fiber.StatusOK ||
+			resp.IsBodyStream() || len(resp.Body()) == 0 {
+			return nil
+		}
+
+		tag := etag.GenerateWeak(resp.Body())
+		if tag == nil {
+			return nil
+		}
+		c.Set(fiber.HeaderETag, string(tag))
+		if c.GetRespHeader(fiber.HeaderCacheControl) == "" {
+			c.Set(fiber.HeaderCacheControl, "private, no-cache")
+		}
+
+		if etagMatches(c.Request().Header.Peek(fiber.HeaderIfNoneMatch), tag) {
+			resp.ResetBody()
+			c.Status(fiber.StatusNotModified)
+		}
+		return nil
+	}
+}
+
+// etagMatches reports whether an If-None-Match header lists tag, using the weak comparison
+// If-None-Match calls for.
+func etagMatches(header, tag []byte) bool {
+	tag = bytes.TrimPrefix(tag, []byte("W/"))
+	for candidate := range bytes.SplitSeq(header, []byte(",")) {
+		candidate = bytes.TrimSpace(candidate)
+		if bytes.Equal(candidate, []byte("*")) || bytes.Equal(bytes.TrimPrefix(candidate, []byte("W/")), tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+)
+
+const (
+	// IdempotencyKeyHeader carries the client-chosen key of a retryable request.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set to "true" on responses replayed from the cache.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	// Error codes of rejected idempotent requests.
+	ErrorCodeIdempotencyInProgress = "IDEMPOTENCY_KEY_IN_PROGRESS"
+	ErrorCodeIdempotencyMismatch   = "IDEMPOTENCY_KEY_MISMATCH"
+
+	maxIdempotencyKeyLength = 255
+	// idempotencyLockTTL bounds how long a key stays locked when its request never
+	// finishes, such as when the instance handling it stops.
+	idempotencyLockTTL = 5 * time.Minute
+)
+
+// idempotentResponse is the cached state of a key. Status is zero while the first request
+// is still being handled.
+type idempotentResponse struct {
+	Fingerprint string `json:"fingerprint"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Location    string `json:"location,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// Idempotency returns a middleware that makes retries of a request carrying an
+// Idempotency-Key header safe. The first successful response for a key is cached for ttl
+// and replayed to later requests with the same key, method, path and body, so a retried
+// request has no further side effects. Keys are scoped to the signed-in user, so the
+// middleware runs after JWTAuth; anonymous requests are handled as usual, since nothing
+// but the key would tell one client's retry from another client's request. Only the
+// status, body, Content-Type and Location are replayed, so routes that set cookies or
+// issue credentials must not use it.
+//
+// A request reusing a key with a different payload gets 422, and one arriving while the
+// first is still being handled gets 409. Error responses are not cached, so a failed
+// request can be retried with the same key. Requests without the header, and all requests
+// while the cache is unavailable, are handled as usual.
+func Idempotency(store cache.Cache, ttl time.Duration) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		key := c.Get(IdempotencyKeyHeader)
+		userID := fiber.Locals[int64](c, "user_id")
+		if key == "" || userID == 0 {
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return apperror.NewBadRequest("Idempotency-Key must be at most " + strconv.Itoa(maxIdempotencyKeyLength) + " characters")
+		}
+
+		ctx := c.Context()
+		cacheKey := idempotencyCacheKey(userID, key)
+		fingerprint := requestFingerprint(c)
+
+		cached, err := loadIdempotent(ctx, store, cacheKey)
+		if err != nil {
+			slog.Warn("failed to read idempotency key", slog.Any("error", err))
+			return c.Next()
+		}
+		if cached != nil {
+			return answerCached(c, cached, fingerprint)
+		}
+
+		// Lock the key so concurrent retries are turned away. Increment is atomic, so only
+		// one request sees the count go from 0 to 1.
+		lockKey := cacheKey + ":lock"
+		locks, err := store.Increment(ctx, lockKey, 1, idempotencyLockTTL)
+		if err != nil {
+			slog.Warn("failed to lock idempotency key", slog.Any("error", err))
+			return c.Next()
+		}
+		// The request holding the key before may have finished since the lookup
+		cached, _ = loadIdempotent(ctx, store, cacheKey)
+		if cached != nil && cached.Status != 0 {
+			if locks == 1 {
+				unlockIdempotencyKey(ctx, store, lockKey)
+			}
+			return answerCached(c, cached, fingerprint)
+		}
+		if locks > 1 {
+			if cached != nil {
+				return answerCached(c, cached, fingerprint)
+			}
+			return errIdempotencyInProgress()
+		}
+
+		// Recorded while in progress so a retry with another payload is told apart
+		pending, _ := json.Marshal(idempotentResponse{Fingerprint: fingerprint})
+		if err := store.Set(ctx, cacheKey, pending, idempotencyLockTTL); err != nil {
+			slog.Warn("failed to record idempotency key", slog.Any("error", err))
+		}
+
+		err = c.Next()
+		resp := c.Response()
+		if err != nil || resp.StatusCode() >= fiber.StatusInternalServerError || resp.IsBodyStream() {
+			if delErr := store.Delete(ctx, cacheKey); delErr != nil {
+				slog.Error("failed to unlock idempotency key", slog.Any("error", delErr))
+			}
+			unlockIdempotencyKey(ctx, store, lockKey)
+			return err
+		}
+
+		data, _ := json.Marshal(idempotentResponse{
+			Fingerprint: fingerprint,
+			Status:      resp.StatusCode(),
+			ContentType: string(resp.Header.ContentType()),
+			Location:    string(resp.Header.Peek(fiber.HeaderLocation)),
+			Body:        resp.Body(),
+		})
+		if err := store.Set(ctx, cacheKey, data, ttl); err != nil {
+			slog.Error("failed to store idempotent response", slog.Any("error", err))
+		}
+		// Released after the response is stored, so the next request with the key finds it
+		unlockIdempotencyKey(ctx, store, lockKey)
+		return nil
+	}
+}
+
+// cachedResponse returns the state of cacheKey, or nil when it holds none.
+func loadIdempotent(ctx context.Context, store cache.Cache, cacheKey string) (*idempotentResponse, error) {
+	data, err := store.Get(ctx, cacheKey)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var cached idempotentResponse
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, nil
+	}
+	return &cached, nil
+}
+
+// answerCached answers a request whose key is already taken: with 422 when it was used for
+// another request, 409 while that request is in progress, and its response once it is done.
+func answerCached(c fiber.Ctx, cached *idempotentResponse, fingerprint string) error {
+	if cached.Fingerprint != fingerprint {
+		return apperror.NewValidation("Idempotency-Key was already used for a different request", nil).
+			WithErrorCode(ErrorCodeIdempotencyMismatch)
+	}
+	if cached.Status == 0 {
+		return errIdempotencyInProgress()
+	}
+	return replayResponse(c, cached)
+}
+
+func errIdempotencyInProgress() error {
+	return apperror.NewConflict("a request with this Idempotency-Key is still in progress").
+		WithErrorCode(ErrorCodeIdempotencyInProgress)
+}
+
+func unlockIdempotencyKey(ctx context.Context, store cache.Cache, lockKey string) {
+	if err := store.Delete(ctx, lockKey); err != nil {
+		slog.Error("failed to unlock idempotency key", slog.Any("error", err))
+	}
+}
+
+func replayResponse(c fiber.Ctx, cached *idempotentResponse) error {
+	c.Set(IdempotentReplayedHeader, "true")
+	if cached.ContentType != "" {
+		c.Set(fiber.HeaderContentType, cached.ContentType)
+	}
+	if cached.Location != "" {
+		c.Set(fiber.HeaderLocation, cached.Location)
+	}
+	c.Status(cached.Status)
+	return c.Send(cached.Body)
+}
+
+// idempotencyCacheKey scopes key to the user. The key is hashed so clients cannot choose the length or characters of cache keys.
+func idempotencyCacheKey(userID int64, key string) string {
+	sum := sha256.Sum256([]byte(strconv.FormatInt(userID, 10) + ":" + key))
+	return "idempotency:" + hex.EncodeToString(sum[:])
+}
+
+// requestFingerprint identifies what a request asks for, so a key reused for another
+// request is detected.
+func requestFingerprint(c fiber.Ctx) string {
+	h := sha256.New()
+	h.Write([]byte(c.Method() + " " + c.Path() + "\n"))
+	h.Write(c.Body())
+	return hex.EncodeToString(h.Sum(nil))
+}
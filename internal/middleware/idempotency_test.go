@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+)
+
+// idempotentApp serves POST /orders behind Idempotency for user 1, answering with handler.
+func idempotentApp(t *testing.T, handler fiber.Handler) *fiber.App {
+	t.Helper()
+	return idempotentAppFor(t, 1, handler)
+}
+
+// idempotentAppFor serves POST /orders behind Idempotency for userID, or anonymously when
+// it is 0, answering with handler.
+func idempotentAppFor(t *testing.T, userID int64, handler fiber.Handler) *fiber.App {
+	t.Helper()
+	store := cache.NewMemoryCache()
+	t.Cleanup(func() { _ = store.Close() })
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.FiberErrorHandler})
+	signIn := func(c fiber.Ctx) error {
+		if userID != 0 {
+			c.Locals("user_id", userID)
+		}
+		return c.Next()
+	}
+	app.Post("/orders", signIn, Idempotency(store, time.Hour), handler)
+	return app
+}
+
+func postOrder(t *testing.T, app *fiber.App, key, body string) (int, string, string) {
+	t.Helper()
+	req := httptest.NewRequest(fiber.MethodPost, "/orders", strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	req.Header.Set(IdempotencyKeyHeader, key)
+	resp, err := app.Test(req, fiber.TestConfig{Timeout: 0})
+	if err != nil {
+		// Errorf rather than Fatalf, as some requests are sent from other goroutines
+		t.Errorf("request failed: %v", err)
+		return 0, "", ""
+	}
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data), resp.Header.Get(IdempotentReplayedHeader)
+}
+
+func TestIdempotency_ReplaysTheFirstResponse(t *testing.T) {
+	var calls atomic.Int32
+	app := idempotentApp(t, func(c fiber.Ctx) error {
+		n := calls.Add(1)
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{"order": n})
+	})
+
+	status, body, replayed := postOrder(t, app, "k1", `{"item":1}`)
+	if status != fiber.StatusCreated || replayed != "" {
+		t.Fatalf("expected a fresh 201, got %d (replayed %q)", status, replayed)
+	}
+	status, again, replayed := postOrder(t, app, "k1", `{"item":1}`)
+	if status != fiber.StatusCreated || again != body || replayed != "true" {
+		t.Errorf("expected the first response replayed, got %d %s (replayed %q)", status, again, replayed)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected the handler to run once, ran %d times", calls.Load())
+	}
+}
+
+func TestIdempotency_RejectsAKeyReusedForAnotherRequest(t *testing.T) {
+	app := idempotentApp(t, func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
+	postOrder(t, app, "k1", `{"item":1}`)
+	status, body, _ := postOrder(t, app, "k1", `{"item":2}`)
+	if status != fiber.StatusUnprocessableEntity || !strings.Contains(body, ErrorCodeIdempotencyMismatch) {
+		t.Errorf("expected 422 %s, got %d %s", ErrorCodeIdempotencyMismatch, status, body)
+	}
+}
+
+func TestIdempotency_RejectsARetryInFlight(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	app := idempotentApp(t, func(c fiber.Ctx) error {
+		close(entered)
+		<-release
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
+	done := make(chan int)
+	go func() {
+		status, _, _ := postOrder(t, app, "k1", `{"item":1}`)
+		done <- status
+	}()
+	<-entered
+
+	status, body, _ := postOrder(t, app, "k1", `{"item":1}`)
+	if status != fiber.StatusConflict || !strings.Contains(body, ErrorCodeIdempotencyInProgress) {
+		t.Errorf("expected 409 %s, got %d %s", ErrorCodeIdempotencyInProgress, status, body)
+	}
+	close(release)
+	if status := <-done; status != fiber.StatusCreated {
+		t.Errorf("expected the first request to finish with 201, got %d", status)
+	}
+}
+
+func TestIdempotency_ConcurrentRetriesRunOnce(t *testing.T) {
+	var calls atomic.Int32
+	app := idempotentApp(t, func(c fiber.Ctx) error {
+		calls.Add(1)
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
+	const retries = 20
+	statuses := make(chan int, retries)
+	for range retries {
+		go func() {
+			status, _, _ := postOrder(t, app, "k1", `{"item":1}`)
+			statuses <- status
+		}()
+	}
+	for range retries {
+		if status := <-statuses; status != fiber.StatusCreated && status != fiber.StatusConflict {
+			t.Errorf("unexpected status %d", status)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected the handler to run once, ran %d times", calls.Load())
+	}
+}
+
+func TestIdempotency_ErrorsCanBeRetried(t *testing.T) {
+	var calls atomic.Int32
+	app := idempotentApp(t, func(c fiber.Ctx) error {
+		if calls.Add(1) == 1 {
+			return apperror.NewInternal("temporary failure")
+		}
+		return c.SendString(strconv.Itoa(int(calls.Load())))
+	})
+
+	if status, _, _ := postOrder(t, app, "k1", `{}`); status != fiber.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", status)
+	}
+	if status, body, _ := postOrder(t, app, "k1", `{}`); status != fiber.StatusOK || body != "2" {
+		t.Errorf("expected the retry to run, got %d %s", status, body)
+	}
+}
+
+func TestIdempotency_AnonymousRequestsAreNotReplayed(t *testing.T) {
+	var calls atomic.Int32
+	app := idempotentAppFor(t, 0, func(c fiber.Ctx) error {
+		return c.SendString(strconv.Itoa(int(calls.Add(1))))
+	})
+
+	postOrder(t, app, "k1", `{}`)
+	status, body, replayed := postOrder(t, app, "k1", `{}`)
+	if status != fiber.StatusOK || body != "2" || replayed != "" {
+		t.Errorf("expected the second anonymous request to run, got %d %s (replayed %q)", status, body, replayed)
+	}
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/netip"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/clientip"
+)
+
+// ErrorCodeIPNotAllowed is the error code of requests rejected by IPFilter.
+const ErrorCodeIPNotAllowed = "IP_NOT_ALLOWED"
+
+// IPFilter returns a middleware that rejects requests from client IPs in deny, or outside
+// allow when allow is not empty, with 403. The client IP is the one ClientIP resolved: the
+// rightmost proxy header entry that is not a trusted proxy, as entries to its left are
+// whatever the client sent.
+func IPFilter(allow, deny []netip.Prefix) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		// An address that cannot be parsed is in no range, so only an allow list rejects it.
+		addr, _ := netip.ParseAddr(clientip.Get(c))
+		addr = addr.Unmap()
+		if containsAddr(deny, addr) || (len(allow) > 0 && !containsAddr(allow, addr)) {
+			return apperror.NewForbidden("access from this IP address is not allowed").
+				WithErrorCode(ErrorCodeIPNotAllowed)
+		}
+		return c.Next()
+	}
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
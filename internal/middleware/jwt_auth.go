@@ -1,15 +1,20 @@
 package middleware
 
 import (
+	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
 
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/token"
 )
 
-func JWTAuth(secret string) fiber.Handler {
+// JWTAuth validates the bearer token against cfg and stores its claims in Locals.
+// When revocations is non-nil, revoked tokens are rejected; cache errors fail open.
+func JWTAuth(cfg config.JWTConfig, revocations *token.RevocationStore) fiber.Handler {
 	return func(c fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
@@ -21,14 +26,31 @@ func JWTAuth(secret string) fiber.Handler {
 			return apperror.NewUnauthorized("invalid authorization header format")
 		}
 
-		claims, err := token.Parse(parts[1], secret)
+		claims, err := token.Parse(parts[1], cfg)
 		if err != nil {
 			return apperror.NewUnauthorized("invalid or expired token")
 		}
 
+		if revocations != nil {
+			revoked, err := revocations.IsRevoked(c.Context(), claims)
+			if err != nil {
+				slog.Warn("failed to check token revocation", slog.Any("error", err))
+			} else if revoked {
+				return apperror.NewUnauthorized("token has been revoked")
+			}
+		}
+
 		fiber.Locals[int64](c, "user_id", claims.UserID)
 		fiber.Locals[string](c, "email", claims.Email)
+		fiber.Locals[string](c, "username", claims.Username)
 		fiber.Locals[string](c, "role", claims.Role)
+		fiber.Locals[[]string](c, "scopes", claims.Scopes)
+		if claims.ExpiresAt != nil {
+			fiber.Locals[time.Time](c, "token_expires_at", claims.ExpiresAt.Time)
+		}
+		if claims.ImpersonatedBy != 0 {
+			fiber.Locals[int64](c, "impersonated_by", claims.ImpersonatedBy)
+		}
 
 		return c.Next()
 	}
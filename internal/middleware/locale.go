@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/i18n"
+)
+
+// Locale returns a middleware that picks the supported language best matching the
+// Accept-Language header and stores it in the "locale" local, which the error handler
+// translates error messages into.
+func Locale() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		c.Locals("locale", i18n.Negotiate(c.Get(fiber.HeaderAcceptLanguage)))
+		return c.Next()
+	}
+}
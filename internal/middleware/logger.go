@@ -5,6 +5,9 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/clientip"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/telemetry"
 )
 
 func Logger() fiber.Handler {
@@ -26,7 +29,7 @@ func Logger() fiber.Handler {
 			slog.String("path", c.Path()),
 			slog.Int("status", status),
 			slog.Duration("latency", latency),
-			slog.String("ip", c.IP()),
+			slog.String("ip", clientip.Get(c)),
 			slog.String("request_id", fiber.Locals[string](c, "request_id")),
 			slog.Int64("user_id", userID),
 			slog.Int("content_length", c.Request().Header.ContentLength()),
@@ -34,6 +37,13 @@ func Logger() fiber.Handler {
 			slog.String("query", string(c.Request().URI().QueryString())),
 		}
 
+		if traceID := telemetry.TraceID(c.Context()); traceID != "" {
+			attrs = append(attrs, slog.String("trace_id", traceID))
+		}
+		if v := fiber.Locals[int64](c, "impersonated_by"); v != 0 {
+			attrs = append(attrs, slog.Int64("impersonated_by", v))
+		}
+
 		switch {
 		case status >= 500:
 			slog.LogAttrs(c.Context(), slog.LevelError, "request", attrs...)
@@ -1,21 +1,103 @@
 package middleware
 
 import (
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
-	"github.com/gofiber/fiber/v3/middleware/limiter"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/clientip"
 )
 
-func NewLimiter(maxRequests, windowSecs int) fiber.Handler {
-	return limiter.New(limiter.Config{
-		Max:        maxRequests,
-		Expiration: time.Duration(windowSecs) * time.Second,
-		KeyGenerator: func(c fiber.Ctx) string {
-			return c.IP()
-		},
-		LimitReached: func(c fiber.Ctx) error {
-			return fiber.NewError(fiber.StatusTooManyRequests, "too many requests, please try again later")
-		},
+// HeaderInternalToken carries the token of an internal service exempt from rate limits.
+const HeaderInternalToken = "X-Internal-Token"
+
+// LimitExemptions are the requests that no limiter counts: from IPs in IPs, such as
+// monitoring scrapers, for paths that are or are below one of Paths, such as health checks,
+// or carrying one of Tokens in the X-Internal-Token header, such as other internal services.
+type LimitExemptions struct {
+	IPs    []netip.Prefix
+	Paths  []string
+	Tokens []string
+}
+
+// exempt reports whether the request skips rate limiting.
+func (e LimitExemptions) exempt(c fiber.Ctx) bool {
+	if len(e.IPs) > 0 {
+		addr, _ := netip.ParseAddr(clientip.Get(c))
+		if containsAddr(e.IPs, addr.Unmap()) {
+			return true
+		}
+	}
+	if matchesPrefix(c.Path(), e.Paths) {
+		return true
+	}
+	if token := c.Get(HeaderInternalToken); token != "" {
+		for _, exempt := range e.Tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(exempt)) == 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NewLimiter limits each client IP to maxRequests per window.
+func NewLimiter(store cache.Cache, maxRequests, windowSecs int, exempt LimitExemptions) fiber.Handler {
+	return newLimiter(store, maxRequests, windowSecs, exempt, func(c fiber.Ctx) string {
+		return clientip.Get(c)
 	})
 }
+
+// NewUserLimiter limits each signed-in user to maxRequests per window, whichever IP they
+// connect from, so the budget of one account is not shared with others behind the same
+// address. Anonymous requests are counted per IP. It must run after JWTAuth to see the user.
+func NewUserLimiter(store cache.Cache, maxRequests, windowSecs int, exempt LimitExemptions) fiber.Handler {
+	return newLimiter(store, maxRequests, windowSecs, exempt, func(c fiber.Ctx) string {
+		if userID := fiber.Locals[int64](c, "user_id"); userID != 0 {
+			return "user:" + strconv.FormatInt(userID, 10)
+		}
+		return "ip:" + clientip.Get(c)
+	})
+}
+
+// newLimiter builds a fixed window limiter counting requests under key. Counts are kept in
+// store with Increment, so instances sharing a cache share every budget and concurrent
+// requests are never miscounted; limiters with the same limit and window share counters.
+// Responses carry X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset (seconds
+// until the window resets); a 429 also carries Retry-After with the same number of seconds.
+// Exempt requests are neither counted nor limited, and carry none of these headers. While
+// the cache is unavailable requests are let through rather than failed.
+func newLimiter(store cache.Cache, maxRequests, windowSecs int, exempt LimitExemptions, key func(c fiber.Ctx) string) fiber.Handler {
+	window := time.Duration(windowSecs) * time.Second
+	prefix := fmt.Sprintf("ratelimit:%d:%d:", maxRequests, windowSecs)
+	return func(c fiber.Ctx) error {
+		if exempt.exempt(c) {
+			return c.Next()
+		}
+
+		now := time.Now()
+		start := now.Truncate(window)
+		count, err := store.Increment(c.Context(), prefix+key(c)+":"+strconv.FormatInt(start.Unix(), 10), 1, window)
+		if err != nil {
+			slog.Warn("rate limit counter unavailable", slog.Any("error", err))
+			return c.Next()
+		}
+
+		// Whole seconds, rounded up so a client waiting that long finds a new window
+		reset := strconv.FormatInt(int64((start.Add(window).Sub(now)+time.Second-1)/time.Second), 10)
+		c.Set("X-RateLimit-Limit", strconv.Itoa(maxRequests))
+		c.Set("X-RateLimit-Remaining", strconv.FormatInt(max(int64(maxRequests)-count, 0), 10))
+		c.Set("X-RateLimit-Reset", reset)
+		if count > int64(maxRequests) {
+			c.Set(fiber.HeaderRetryAfter, reset)
+			return fiber.NewError(fiber.StatusTooManyRequests, "too many requests, please try again later")
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/clientip"
+)
+
+// limitedApp serves GET / and GET /healthz behind limiter.
+func limitedApp(limiter fiber.Handler) *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.FiberErrorHandler})
+	app.Use(limiter)
+	ok := func(c fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) }
+	app.Get("/", ok)
+	app.Get("/healthz", ok)
+	return app
+}
+
+func doRequest(t *testing.T, app *fiber.App, req *http.Request) *http.Response {
+	t.Helper()
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	return resp
+}
+
+func TestLimiter_SharesCountsThroughTheCache(t *testing.T) {
+	store := cache.NewMemoryCache()
+	t.Cleanup(func() { _ = store.Close() })
+	// Two instances of the app sharing one cache
+	first := limitedApp(NewLimiter(store, 2, 60, LimitExemptions{}))
+	second := limitedApp(NewLimiter(store, 2, 60, LimitExemptions{}))
+
+	resp := doRequest(t, first, httptest.NewRequest(fiber.MethodGet, "/", nil))
+	if resp.StatusCode != fiber.StatusNoContent || resp.Header.Get("X-RateLimit-Remaining") != "1" {
+		t.Fatalf("expected 1 request left, got %d with %q", resp.StatusCode, resp.Header.Get("X-RateLimit-Remaining"))
+	}
+	doRequest(t, second, httptest.NewRequest(fiber.MethodGet, "/", nil))
+
+	resp = doRequest(t, first, httptest.NewRequest(fiber.MethodGet, "/", nil))
+	if resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Fatalf("expected 429 once both instances used the budget, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("X-RateLimit-Limit") != "2" || resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("unexpected limit headers %v", resp.Header)
+	}
+	retry, err := strconv.Atoi(resp.Header.Get(fiber.HeaderRetryAfter))
+	if err != nil || retry < 1 || retry > 60 || resp.Header.Get("X-RateLimit-Reset") != resp.Header.Get(fiber.HeaderRetryAfter) {
+		t.Errorf("expected Retry-After and X-RateLimit-Reset within the window, got %v", resp.Header)
+	}
+
+	// A limiter with another budget counts separately
+	other := limitedApp(NewLimiter(store, 5, 60, LimitExemptions{}))
+	if resp := doRequest(t, other, httptest.NewRequest(fiber.MethodGet, "/", nil)); resp.StatusCode != fiber.StatusNoContent {
+		t.Errorf("expected the other limiter to allow the request, got %d", resp.StatusCode)
+	}
+}
+
+func TestLimiter_Exemptions(t *testing.T) {
+	store := cache.NewMemoryCache()
+	t.Cleanup(func() { _ = store.Close() })
+	limiter := NewLimiter(store, 1, 60, LimitExemptions{
+		IPs:    []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+		Paths:  []string{"/healthz"},
+		Tokens: []string{"internal-secret"},
+	})
+	// The X-Test-IP header stands in for the ClientIP middleware
+	app := limitedApp(func(c fiber.Ctx) error {
+		if ip := c.Get("X-Test-IP"); ip != "" {
+			clientip.Set(c, ip)
+		}
+		return limiter(c)
+	})
+	request := func(path, ip, token string) *http.Request {
+		req := httptest.NewRequest(fiber.MethodGet, path, nil)
+		req.Header.Set("X-Test-IP", ip)
+		if token != "" {
+			req.Header.Set(HeaderInternalToken, token)
+		}
+		return req
+	}
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		exempt bool
+	}{
+		{"exempt IP", request("/", "10.1.2.3", ""), true},
+		{"exempt IPv4-mapped IP", request("/", "::ffff:10.1.2.3", ""), true},
+		{"exempt path", request("/healthz", "192.0.2.1", ""), true},
+		{"exempt token", request("/", "192.0.2.1", "internal-secret"), true},
+		{"other IP", request("/", "192.0.2.1", ""), false},
+		{"wrong token", request("/", "192.0.2.1", "guess"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Twice, so a counted request runs into the limit of 1
+			doRequest(t, app, tt.req.Clone(tt.req.Context()))
+			resp := doRequest(t, app, tt.req)
+			if tt.exempt {
+				if resp.StatusCode != fiber.StatusNoContent || resp.Header.Get("X-RateLimit-Limit") != "" {
+					t.Errorf("expected an uncounted request, got %d with %v", resp.StatusCode, resp.Header)
+				}
+			} else if resp.StatusCode != fiber.StatusTooManyRequests {
+				t.Errorf("expected 429, got %d", resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestUserLimiter(t *testing.T) {
+	store := cache.NewMemoryCache()
+	t.Cleanup(func() { _ = store.Close() })
+	limiter := NewUserLimiter(store, 1, 60, LimitExemptions{})
+	// The X-User and X-Test-IP headers stand in for JWTAuth and the ClientIP middleware
+	app := limitedApp(func(c fiber.Ctx) error {
+		if id, err := strconv.ParseInt(c.Get("X-User"), 10, 64); err == nil {
+			c.Locals("user_id", id)
+		}
+		clientip.Set(c, c.Get("X-Test-IP"))
+		return limiter(c)
+	})
+	status := func(user, ip string) int {
+		req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		req.Header.Set("X-Test-IP", ip)
+		if user != "" {
+			req.Header.Set("X-User", user)
+		}
+		return doRequest(t, app, req).StatusCode
+	}
+
+	if got := status("1", "192.0.2.1"); got != fiber.StatusNoContent {
+		t.Fatalf("expected the first request allowed, got %d", got)
+	}
+	if got := status("2", "192.0.2.1"); got != fiber.StatusNoContent {
+		t.Errorf("expected another user behind the same IP to have their own budget, got %d", got)
+	}
+	if got := status("1", "198.51.100.1"); got != fiber.StatusTooManyRequests {
+		t.Errorf("expected a user's budget to follow them across IPs, got %d", got)
+	}
+
+	if got := status("", "192.0.2.1"); got != fiber.StatusNoContent {
+		t.Errorf("expected anonymous requests counted apart from users on the IP, got %d", got)
+	}
+	if got := status("", "192.0.2.1"); got != fiber.StatusTooManyRequests {
+		t.Errorf("expected anonymous requests limited per IP, got %d", got)
+	}
+	if got := status("", "198.51.100.1"); got != fiber.StatusNoContent {
+		t.Errorf("expected another IP to have its own budget, got %d", got)
+	}
+}
@@ -7,9 +7,12 @@ import (
 	"github.com/gofiber/fiber/v3"
 
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/errorreport"
 )
 
-func Recovery(env string) fiber.Handler {
+// Recovery returns a middleware that turns a panic into a 500 response, and logs and reports
+// it with its stack.
+func Recovery(env string, reporter errorreport.Reporter) fiber.Handler {
 	return func(c fiber.Ctx) (err error) {
 		defer func() {
 			if r := recover(); r != nil {
@@ -19,6 +22,7 @@ func Recovery(env string) fiber.Handler {
 					slog.Any("error", r),
 					slog.String("stack", string(stackTrace)),
 				)
+				errorreport.ReportPanic(c, reporter, r)
 
 				msg := "internal server error"
 				if env == "local" || env == "test" {
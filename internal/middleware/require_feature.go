@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+// FeatureChecker reports whether a feature flag is on.
+type FeatureChecker interface {
+	IsEnabled(ctx context.Context, name string) (bool, error)
+}
+
+// RequireFeature returns a middleware that answers 404 while the named feature flag is off,
+// so routes can ship dark and be switched on without a deploy.
+func RequireFeature(checker FeatureChecker, name string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		ok, err := checker.IsEnabled(c.Context(), name)
+		if err != nil {
+			slog.Error("failed to check feature flag", slog.String("feature", name), slog.Any("error", err))
+			return apperror.NewInternal("failed to check feature flags")
+		}
+		if !ok {
+			return apperror.NewNotFound("feature not available")
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+// stubFeatures reports the flags in enabled as on, or fails every check when err is set.
+type stubFeatures struct {
+	enabled map[string]bool
+	err     error
+}
+
+func (s stubFeatures) IsEnabled(_ context.Context, name string) (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+	return s.enabled[name], nil
+}
+
+func TestRequireFeature(t *testing.T) {
+	tests := []struct {
+		name     string
+		checker  stubFeatures
+		want     int
+		wantNext bool
+	}{
+		{"flag on", stubFeatures{enabled: map[string]bool{"reports": true}}, fiber.StatusNoContent, true},
+		{"flag off", stubFeatures{enabled: map[string]bool{"reports": false}}, fiber.StatusNotFound, false},
+		{"unknown flag", stubFeatures{}, fiber.StatusNotFound, false},
+		{"checker fails", stubFeatures{err: errors.New("db down")}, fiber.StatusInternalServerError, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ran := false
+			app := fiber.New(fiber.Config{ErrorHandler: apperror.FiberErrorHandler})
+			app.Get("/reports", RequireFeature(tt.checker, "reports"), func(c fiber.Ctx) error {
+				ran = true
+				return c.SendStatus(fiber.StatusNoContent)
+			})
+
+			resp := doRequest(t, app, httptest.NewRequest(fiber.MethodGet, "/reports", nil))
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+			if ran != tt.wantNext {
+				t.Errorf("next handler ran = %v, want %v", ran, tt.wantNext)
+			}
+		})
+	}
+}
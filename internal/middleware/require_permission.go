@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+// PermissionChecker reports whether a user has been granted a permission through their roles.
+type PermissionChecker interface {
+	HasPermission(ctx context.Context, userID int64, permission string) (bool, error)
+}
+
+// RequirePermission returns a middleware that checks the authenticated user holds the given permission.
+// Permissions are resolved on every request, so role changes apply without re-authentication.
+// Must be used after JWTAuth middleware.
+func RequirePermission(checker PermissionChecker, permission string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		userID := fiber.Locals[int64](c, "user_id")
+		ok, err := checker.HasPermission(c.Context(), userID, permission)
+		if err != nil {
+			slog.Error("failed to check permission",
+				slog.Int64("user_id", userID),
+				slog.String("permission", permission),
+				slog.Any("error", err),
+			)
+			return apperror.NewInternal("failed to check permissions")
+		}
+		if !ok {
+			return apperror.NewForbidden("insufficient permissions")
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"slices"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+// RequireScope returns a middleware that checks the access token grants every one of the given scopes.
+// Must be used after JWTAuth middleware.
+func RequireScope(scopes ...string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		granted := fiber.Locals[[]string](c, "scopes")
+		for _, s := range scopes {
+			if !slices.Contains(granted, s) {
+				return apperror.NewForbidden("insufficient scope")
+			}
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+)
+
+// ResponseCacheHeader is set to HIT on responses served from the cache and MISS on the
+// ones that were cached.
+const ResponseCacheHeader = "X-Cache"
+
+// cachedResponse is a response stored by CacheResponse.
+type cachedResponse struct {
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// CacheResponse returns a middleware that caches 200 responses of GET requests for ttl and
+// serves repeated requests from the cache without running the handler. Responses are keyed
+// by path, query and signed-in user, so it runs after JWTAuth and the permission checks of
+// the route, and never serves one user's response to another. tags, when not nil, returns
+// the cache tags of a request, so services invalidating a tag, such as the one of a user,
+// drop the responses that show it; others are served until they expire. Responses are also
+// tagged with the signed-in user, so a change to their role or permissions drops the
+// responses the handler built for them. A ttl of 0
+// disables caching, and requests are handled as usual while the cache is unavailable.
+func CacheResponse(store cache.Cache, ttl time.Duration, tags func(c fiber.Ctx) []string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if ttl <= 0 || c.Method() != fiber.MethodGet {
+			return c.Next()
+		}
+
+		ctx := c.Context()
+		key := responseCacheKey(c)
+		data, err := store.Get(ctx, key)
+		if err != nil {
+			slog.Warn("failed to read cached response", slog.Any("error", err))
+		}
+		if data != nil {
+			var cached cachedResponse
+			if err := json.Unmarshal(data, &cached); err == nil {
+				c.Set(ResponseCacheHeader, "HIT")
+				c.Set(fiber.HeaderContentType, cached.ContentType)
+				return c.Send(cached.Body)
+			}
+		}
+
+		if err := c.Next(); err != nil {
+			return err
+		}
+		resp := c.Response()
+		if resp.StatusCode() != fiber.StatusOK || resp.IsBodyStream() {
+			return nil
+		}
+
+		var keyTags []string
+		if tags != nil {
+			keyTags = tags(c)
+		}
+		if userID := fiber.Locals[int64](c, "user_id"); userID != 0 {
+			keyTags = append(keyTags, cache.UserTag(userID))
+		}
+		data, _ = json.Marshal(cachedResponse{
+			ContentType: string(resp.Header.ContentType()),
+			Body:        resp.Body(),
+		})
+		if err := store.SetWithTags(ctx, key, data, ttl, keyTags...); err != nil {
+			slog.Warn("failed to cache response", slog.Any("error", err))
+			return nil
+		}
+		c.Set(ResponseCacheHeader, "MISS")
+		return nil
+	}
+}
+
+// responseCacheKey identifies a request by user, path and query, with the query parameters
+// sorted so their order does not matter. It is hashed so clients cannot choose the length
+// or characters of cache keys.
+func responseCacheKey(c fiber.Ctx) string {
+	query, err := url.ParseQuery(string(c.Request().URI().QueryString()))
+	raw := query.Encode()
+	if err != nil {
+		raw = string(c.Request().URI().QueryString())
+	}
+	userID := strconv.FormatInt(fiber.Locals[int64](c, "user_id"), 10)
+	sum := sha256.Sum256([]byte(userID + "\n" + c.Path() + "?" + raw))
+	return "response:" + hex.EncodeToString(sum[:])
+}
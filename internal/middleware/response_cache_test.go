@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+)
+
+// cachedApp serves GET /stats behind CacheResponse, tagging responses with cache.StatsTag.
+// The X-User header stands in for JWTAuth, and the handler answers with how often it ran.
+func cachedApp(store cache.Cache) *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.FiberErrorHandler})
+	app.Use(func(c fiber.Ctx) error {
+		if id, err := strconv.ParseInt(c.Get("X-User"), 10, 64); err == nil {
+			c.Locals("user_id", id)
+		}
+		return c.Next()
+	})
+	calls := 0
+	app.Get("/stats", CacheResponse(store, time.Minute, func(fiber.Ctx) []string {
+		return []string{cache.StatsTag}
+	}), func(c fiber.Ctx) error {
+		calls++
+		return c.JSON(fiber.Map{"calls": calls})
+	})
+	return app
+}
+
+func TestCacheResponse(t *testing.T) {
+	newFixture := func(t *testing.T) (*fiber.App, cache.Cache) {
+		store := cache.NewMemoryCache()
+		t.Cleanup(func() { _ = store.Close() })
+		return cachedApp(store), store
+	}
+	get := func(t *testing.T, app *fiber.App, target, user string) (string, string) {
+		t.Helper()
+		req := httptest.NewRequest(fiber.MethodGet, target, nil)
+		if user != "" {
+			req.Header.Set("X-User", user)
+		}
+		resp := doRequest(t, app, req)
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("expected 200, got %d", resp.StatusCode)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return resp.Header.Get(ResponseCacheHeader), string(body)
+	}
+
+	t.Run("serves a repeated request from the cache", func(t *testing.T) {
+		app, _ := newFixture(t)
+
+		state, body := get(t, app, "/stats?a=1&b=2", "1")
+		if state != "MISS" || body != `{"calls":1}` {
+			t.Fatalf("expected a cached miss, got %q %s", state, body)
+		}
+		state, body = get(t, app, "/stats?b=2&a=1", "1")
+		if state != "HIT" || body != `{"calls":1}` {
+			t.Errorf("expected the cached response regardless of query order, got %q %s", state, body)
+		}
+		if state, _ = get(t, app, "/stats?a=2", "1"); state != "MISS" {
+			t.Errorf("expected another query to miss, got %q", state)
+		}
+	})
+
+	t.Run("keys responses by user", func(t *testing.T) {
+		app, _ := newFixture(t)
+
+		get(t, app, "/stats", "1")
+		state, body := get(t, app, "/stats", "2")
+		if state != "MISS" || body != `{"calls":2}` {
+			t.Errorf("expected another user to miss, got %q %s", state, body)
+		}
+		if state, _ = get(t, app, "/stats", ""); state != "MISS" {
+			t.Errorf("expected an anonymous request to miss, got %q", state)
+		}
+		if state, _ = get(t, app, "/stats", "1"); state != "HIT" {
+			t.Errorf("expected the first user's response cached, got %q", state)
+		}
+	})
+
+	t.Run("invalidating the requester drops their responses", func(t *testing.T) {
+		app, store := newFixture(t)
+
+		get(t, app, "/stats", "1")
+		get(t, app, "/stats", "2")
+		if err := store.InvalidateTag(t.Context(), cache.UserTag(1)); err != nil {
+			t.Fatalf("invalidate: %v", err)
+		}
+		if state, _ := get(t, app, "/stats", "1"); state != "MISS" {
+			t.Errorf("expected the requester's response dropped, got %q", state)
+		}
+		if state, _ := get(t, app, "/stats", "2"); state != "HIT" {
+			t.Errorf("expected other users' responses kept, got %q", state)
+		}
+	})
+
+	t.Run("invalidating the tag drops responses", func(t *testing.T) {
+		app, store := newFixture(t)
+
+		get(t, app, "/stats", "1")
+		get(t, app, "/stats", "2")
+		if err := store.InvalidateTag(t.Context(), cache.StatsTag); err != nil {
+			t.Fatalf("invalidate: %v", err)
+		}
+		for _, user := range []string{"1", "2"} {
+			if state, _ := get(t, app, "/stats", user); state != "MISS" {
+				t.Errorf("expected user %s to miss after invalidation, got %q", user, state)
+			}
+		}
+	})
+}
@@ -2,18 +2,54 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
 )
 
-// Timeout returns a middleware that sets a timeout on the request context.
-func Timeout(duration time.Duration) fiber.Handler {
+// ErrorCodeRequestTimeout is the error code of requests that ran past their timeout.
+const ErrorCodeRequestTimeout = "REQUEST_TIMEOUT"
+
+// Timeout returns a middleware that cancels the request context after a timeout: duration,
+// or the one of the longest prefix in routes that the path is or is below, such as
+// /api/v1/files. A timeout of 0 disables it. Handlers pass the context to services, so
+// queries, storage calls and other work that honours it stop at the deadline, and a
+// request that fails after its deadline is answered with 503 REQUEST_TIMEOUT.
+func Timeout(duration time.Duration, routes map[string]time.Duration) fiber.Handler {
 	return func(c fiber.Ctx) error {
-		ctx, cancel := context.WithTimeout(c.Context(), duration)
+		timeout := routeTimeout(c.Path(), duration, routes)
+		if timeout <= 0 {
+			return c.Next()
+		}
+
+		ctx, cancel := context.WithTimeout(c.Context(), timeout)
 		defer cancel()
 
 		c.SetContext(ctx)
-		return c.Next()
+		err := c.Next()
+		// The error of a cancelled call is often wrapped or replaced on its way up, so the
+		// context tells whether the deadline caused it.
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return apperror.NewServiceUnavailable("request timed out").
+				WithErrorCode(ErrorCodeRequestTimeout)
+		}
+		return err
+	}
+}
+
+// routeTimeout returns the timeout of the longest prefix in routes matching path, or
+// duration when none does.
+func routeTimeout(path string, duration time.Duration, routes map[string]time.Duration) time.Duration {
+	longest := -1
+	for prefix, timeout := range routes {
+		if len(prefix) > longest && (path == prefix || strings.HasPrefix(path, prefix+"/")) {
+			longest = len(prefix)
+			duration = timeout
+		}
 	}
+	return duration
 }
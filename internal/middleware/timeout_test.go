@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+func TestTimeout(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: apperror.FiberErrorHandler})
+	app.Use(Timeout(20*time.Millisecond, map[string]time.Duration{
+		"/api/v1/files":   time.Second,
+		"/api/v1/reports": 0,
+	}))
+	// slow waits for the deadline of the request, or returns after 100ms like a call that
+	// ignores the context.
+	slow := func(c fiber.Ctx) error {
+		select {
+		case <-c.Context().Done():
+			return c.Context().Err()
+		case <-time.After(100 * time.Millisecond):
+			return c.SendStatus(fiber.StatusNoContent)
+		}
+	}
+	app.Get("/api/v1/users", slow)
+	app.Get("/api/v1/files", slow)
+	app.Get("/api/v1/filesystem", slow)
+	app.Get("/api/v1/reports/daily", slow)
+	app.Get("/api/v1/fast", func(c fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+	app.Get("/api/v1/late", func(c fiber.Ctx) error {
+		<-c.Context().Done()
+		return apperror.NewInternal("query failed")
+	})
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{"cancels at the deadline", "/api/v1/users", fiber.StatusServiceUnavailable},
+		{"error after the deadline", "/api/v1/late", fiber.StatusServiceUnavailable},
+		{"fast request", "/api/v1/fast", fiber.StatusNoContent},
+		{"longer route timeout", "/api/v1/files", fiber.StatusNoContent},
+		{"prefix matches whole segments", "/api/v1/filesystem", fiber.StatusServiceUnavailable},
+		{"route timeout disabled", "/api/v1/reports/daily", fiber.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, tt.path, nil), fiber.TestConfig{Timeout: 2 * time.Second})
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+			if tt.want == fiber.StatusServiceUnavailable {
+				body, _ := io.ReadAll(resp.Body)
+				if !strings.Contains(string(body), ErrorCodeRequestTimeout) {
+					t.Errorf("expected %s in %s", ErrorCodeRequestTimeout, body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/clientip"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/telemetry"
+)
+
+// Tracing returns a middleware that runs each request in a server span, continuing the
+// trace of an incoming traceparent header. The span is stored in the request context, so
+// the spans of the services and queries the handler calls join it. It runs after RequestID
+// to tag spans with the request ID.
+func Tracing() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		ctx := otel.GetTextMapPropagator().Extract(c.Context(), requestHeaderCarrier{c})
+		// Request strings are cloned because fasthttp reuses their memory once the request
+		// is done, while the exporter reads the span later.
+		method := strings.Clone(c.Method())
+		ctx, span := telemetry.Start(ctx, method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(method),
+				semconv.URLPath(strings.Clone(c.Path())),
+				semconv.ClientAddress(strings.Clone(clientip.Get(c))),
+				semconv.UserAgentOriginal(strings.Clone(c.Get(fiber.HeaderUserAgent))),
+				attribute.String("request_id", fiber.Locals[string](c, "request_id")),
+			),
+		)
+		defer span.End()
+		c.SetContext(ctx)
+
+		err := c.Next()
+
+		// Spans are named after the matched route, such as "GET /api/v1/users/:id", to keep
+		// the number of names small.
+		route := c.Route().Path
+		span.SetName(method + " " + route)
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = apperror.StatusCode(err)
+		}
+		span.SetAttributes(semconv.HTTPRoute(route), semconv.HTTPResponseStatusCode(status))
+		if userID := fiber.Locals[int64](c, "user_id"); userID != 0 {
+			span.SetAttributes(attribute.Int64("user_id", userID))
+		}
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, "")
+			if err != nil {
+				span.RecordError(err)
+			}
+		}
+		return err
+	}
+}
+
+// requestHeaderCarrier reads trace context from the request headers.
+type requestHeaderCarrier struct{ c fiber.Ctx }
+
+func (h requestHeaderCarrier) Get(key string) string { return h.c.Get(key) }
+
+func (h requestHeaderCarrier) Set(key, value string) { h.c.Request().Header.Set(key, value) }
+
+func (h requestHeaderCarrier) Keys() []string {
+	headers := h.c.GetReqHeaders()
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	return keys
+}
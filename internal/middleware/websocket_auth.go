@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/realtime"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/token"
+)
+
+// WebSocketAuth authenticates WebSocket handshakes. Browsers cannot set headers on them,
+// so a single-use ticket in the ticket query parameter stands in for the bearer token;
+// handshakes without one are checked by JWTAuth. Tokens are never accepted in the query,
+// where they would end up in access logs.
+func WebSocketAuth(cfg config.JWTConfig, revocations *token.RevocationStore, tickets *realtime.TicketStore) fiber.Handler {
+	jwtAuth := JWTAuth(cfg, revocations)
+	return func(c fiber.Ctx) error {
+		id := c.Query("ticket")
+		if id == "" {
+			return jwtAuth(c)
+		}
+
+		ticket, err := tickets.Redeem(c.Context(), id)
+		if err != nil {
+			slog.Error("failed to redeem websocket ticket", slog.Any("error", err))
+			return apperror.NewInternal("failed to check ticket")
+		}
+		if ticket == nil {
+			return apperror.NewUnauthorized("invalid or expired ticket")
+		}
+
+		fiber.Locals[int64](c, "user_id", ticket.UserID)
+		fiber.Locals[time.Time](c, "token_expires_at", ticket.ExpiresAt)
+		return c.Next()
+	}
+}
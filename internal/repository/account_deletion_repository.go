@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+)
+
+type AccountDeletionRepository interface {
+	Create(ctx context.Context, params sqlc.CreateAccountDeletionRequestParams) (*sqlc.AccountDeletionRequest, error)
+	GetByUserID(ctx context.Context, userID int64) (*sqlc.AccountDeletionRequest, error)
+	DeleteByToken(ctx context.Context, token string) (*sqlc.AccountDeletionRequest, error)
+	ListDue(ctx context.Context, limit int32) ([]sqlc.AccountDeletionRequest, error)
+	DeleteByUserID(ctx context.Context, userID int64) error
+}
+
+type accountDeletionRepository struct {
+	q *sqlc.Queries
+}
+
+func NewAccountDeletionRepository(db sqlc.DBTX) AccountDeletionRepository {
+	return &accountDeletionRepository{q: sqlc.New(db)}
+}
+
+func (r *accountDeletionRepository) Create(ctx context.Context, params sqlc.CreateAccountDeletionRequestParams) (*sqlc.AccountDeletionRequest, error) {
+	req, err := r.q.CreateAccountDeletionRequest(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &req, nil
+}
+
+func (r *accountDeletionRepository) GetByUserID(ctx context.Context, userID int64) (*sqlc.AccountDeletionRequest, error) {
+	req, err := r.q.GetAccountDeletionRequestByUserID(ctx, userID)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &req, nil
+}
+
+func (r *accountDeletionRepository) DeleteByToken(ctx context.Context, token string) (*sqlc.AccountDeletionRequest, error) {
+	req, err := r.q.DeleteAccountDeletionRequestByToken(ctx, token)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &req, nil
+}
+
+func (r *accountDeletionRepository) ListDue(ctx context.Context, limit int32) ([]sqlc.AccountDeletionRequest, error) {
+	return r.q.ListDueAccountDeletionRequests(ctx, limit)
+}
+
+func (r *accountDeletionRepository) DeleteByUserID(ctx context.Context, userID int64) error {
+	return r.q.DeleteAccountDeletionRequestByUserID(ctx, userID)
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+)
+
+// AuditLogFilter narrows an audit log listing. Zero values leave a field unfiltered.
+type AuditLogFilter struct {
+	ActorID       int64
+	Action        string
+	TargetType    string
+	TargetID      int64
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+type AuditLogRepository interface {
+	Create(ctx context.Context, params sqlc.CreateAuditLogParams) (*sqlc.AuditLog, error)
+	List(ctx context.Context, filter AuditLogFilter, limit, offset int32) ([]sqlc.AuditLog, error)
+	Count(ctx context.Context, filter AuditLogFilter) (int64, error)
+	ListAfter(ctx context.Context, filter AuditLogFilter, afterID int64, limit int32) ([]sqlc.AuditLog, error)
+}
+
+type auditLogRepository struct {
+	q *sqlc.Queries
+}
+
+func NewAuditLogRepository(db sqlc.DBTX) AuditLogRepository {
+	return &auditLogRepository{q: sqlc.New(db)}
+}
+
+func (r *auditLogRepository) Create(ctx context.Context, params sqlc.CreateAuditLogParams) (*sqlc.AuditLog, error) {
+	entry, err := r.q.CreateAuditLog(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &entry, nil
+}
+
+func (r *auditLogRepository) List(ctx context.Context, filter AuditLogFilter, limit, offset int32) ([]sqlc.AuditLog, error) {
+	p := filter.params()
+	return r.q.ListAuditLogs(ctx, sqlc.ListAuditLogsParams{
+		ActorID:       p.ActorID,
+		Action:        p.Action,
+		TargetType:    p.TargetType,
+		TargetID:      p.TargetID,
+		CreatedAfter:  p.CreatedAfter,
+		CreatedBefore: p.CreatedBefore,
+		Limit:         limit,
+		Offset:        offset,
+	})
+}
+
+func (r *auditLogRepository) Count(ctx context.Context, filter AuditLogFilter) (int64, error) {
+	return r.q.CountAuditLogs(ctx, filter.params())
+}
+
+// ListAfter returns up to limit entries with IDs greater than afterID, oldest first. Passing
+// the last returned ID back in walks the full result set as a keyset cursor.
+func (r *auditLogRepository) ListAfter(ctx context.Context, filter AuditLogFilter, afterID int64, limit int32) ([]sqlc.AuditLog, error) {
+	p := filter.params()
+	return r.q.ListAuditLogsAfter(ctx, sqlc.ListAuditLogsAfterParams{
+		ActorID:       p.ActorID,
+		Action:        p.Action,
+		TargetType:    p.TargetType,
+		TargetID:      p.TargetID,
+		CreatedAfter:  p.CreatedAfter,
+		CreatedBefore: p.CreatedBefore,
+		AfterID:       afterID,
+		Limit:         limit,
+	})
+}
+
+func (f AuditLogFilter) params() sqlc.CountAuditLogsParams {
+	var p sqlc.CountAuditLogsParams
+	if f.ActorID != 0 {
+		p.ActorID = pgtype.Int8{Int64: f.ActorID, Valid: true}
+	}
+	if f.Action != "" {
+		p.Action = pgtype.Text{String: f.Action, Valid: true}
+	}
+	if f.TargetType != "" {
+		p.TargetType = pgtype.Text{String: f.TargetType, Valid: true}
+	}
+	if f.TargetID != 0 {
+		p.TargetID = pgtype.Int8{Int64: f.TargetID, Valid: true}
+	}
+	if !f.CreatedAfter.IsZero() {
+		p.CreatedAfter = pgtype.Timestamptz{Time: f.CreatedAfter, Valid: true}
+	}
+	if !f.CreatedBefore.IsZero() {
+		p.CreatedBefore = pgtype.Timestamptz{Time: f.CreatedBefore, Valid: true}
+	}
+	return p
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+)
+
+// BroadcastSegment selects the recipients of a broadcast among active, non-guest users.
+// Zero values leave a field unfiltered.
+type BroadcastSegment struct {
+	Search          string // case-insensitive substring of name or email
+	Role            string
+	EmailVerified   *bool
+	CreatedAfter    time.Time // inclusive
+	CreatedBefore   time.Time // exclusive
+	SubscribersOnly bool      // only users who opted in to product update emails
+}
+
+type BroadcastRepository interface {
+	Create(ctx context.Context, params sqlc.CreateBroadcastParams) (*sqlc.Broadcast, error)
+	UpdateProgress(ctx context.Context, id int64, sent, failed int32) error
+	Finish(ctx context.Context, id int64, status string, sent, failed int32) error
+	List(ctx context.Context, limit, offset int32) ([]sqlc.Broadcast, error)
+	Count(ctx context.Context) (int64, error)
+	ListRecipients(ctx context.Context, segment BroadcastSegment, afterID int64, limit int32) ([]sqlc.ListBroadcastRecipientsRow, error)
+	CountRecipients(ctx context.Context, segment BroadcastSegment) (int64, error)
+}
+
+type broadcastRepository struct {
+	q *sqlc.Queries
+}
+
+func NewBroadcastRepository(db sqlc.DBTX) BroadcastRepository {
+	return &broadcastRepository{q: sqlc.New(db)}
+}
+
+func (r *broadcastRepository) Create(ctx context.Context, params sqlc.CreateBroadcastParams) (*sqlc.Broadcast, error) {
+	b, err := r.q.CreateBroadcast(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &b, nil
+}
+
+func (r *broadcastRepository) UpdateProgress(ctx context.Context, id int64, sent, failed int32) error {
+	return r.q.UpdateBroadcastProgress(ctx, sqlc.UpdateBroadcastProgressParams{ID: id, SentCount: sent, FailedCount: failed})
+}
+
+func (r *broadcastRepository) Finish(ctx context.Context, id int64, status string, sent, failed int32) error {
+	return r.q.FinishBroadcast(ctx, sqlc.FinishBroadcastParams{ID: id, Status: status, SentCount: sent, FailedCount: failed})
+}
+
+func (r *broadcastRepository) List(ctx context.Context, limit, offset int32) ([]sqlc.Broadcast, error) {
+	return r.q.ListBroadcasts(ctx, sqlc.ListBroadcastsParams{Limit: limit, Offset: offset})
+}
+
+func (r *broadcastRepository) Count(ctx context.Context) (int64, error) {
+	return r.q.CountBroadcasts(ctx)
+}
+
+// ListRecipients returns up to limit recipients with IDs greater than afterID in ID order.
+// Passing the last returned ID back in walks the whole segment as a keyset cursor.
+func (r *broadcastRepository) ListRecipients(
+	ctx context.Context,
+	segment BroadcastSegment,
+	afterID int64,
+	limit int32,
+) ([]sqlc.ListBroadcastRecipientsRow, error) {
+	p := segment.params()
+	return r.q.ListBroadcastRecipients(ctx, sqlc.ListBroadcastRecipientsParams{
+		Search:          p.Search,
+		Role:            p.Role,
+		EmailVerified:   p.EmailVerified,
+		CreatedAfter:    p.CreatedAfter,
+		CreatedBefore:   p.CreatedBefore,
+		SubscribersOnly: p.SubscribersOnly,
+		AfterID:         afterID,
+		Limit:           limit,
+	})
+}
+
+func (r *broadcastRepository) CountRecipients(ctx context.Context, segment BroadcastSegment) (int64, error) {
+	return r.q.CountBroadcastRecipients(ctx, segment.params())
+}
+
+// params converts the segment into query arguments, escaping LIKE wildcards in the search term.
+func (s BroadcastSegment) params() sqlc.CountBroadcastRecipientsParams {
+	p := sqlc.CountBroadcastRecipientsParams{SubscribersOnly: s.SubscribersOnly}
+	if s.Search != "" {
+		p.Search = pgtype.Text{String: likeEscaper.Replace(s.Search), Valid: true}
+	}
+	if s.Role != "" {
+		p.Role = pgtype.Text{String: s.Role, Valid: true}
+	}
+	if s.EmailVerified != nil {
+		p.EmailVerified = pgtype.Bool{Bool: *s.EmailVerified, Valid: true}
+	}
+	if !s.CreatedAfter.IsZero() {
+		p.CreatedAfter = pgtype.Timestamptz{Time: s.CreatedAfter, Valid: true}
+	}
+	if !s.CreatedBefore.IsZero() {
+		p.CreatedBefore = pgtype.Timestamptz{Time: s.CreatedBefore, Valid: true}
+	}
+	return p
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+)
+
+type DataExportRepository interface {
+	Create(ctx context.Context, userID int64) (*sqlc.DataExport, error)
+	GetLatestByUserID(ctx context.Context, userID int64) (*sqlc.DataExport, error)
+	MarkReady(ctx context.Context, id int64, storagePath string, size int64, expiresAt time.Time) (*sqlc.DataExport, error)
+	MarkFailed(ctx context.Context, id int64, expiresAt time.Time) error
+	ListExpired(ctx context.Context, limit int32) ([]sqlc.DataExport, error)
+	Delete(ctx context.Context, id int64) error
+	DeleteByUserID(ctx context.Context, userID int64) ([]string, error)
+}
+
+type dataExportRepository struct {
+	q *sqlc.Queries
+}
+
+func NewDataExportRepository(db sqlc.DBTX) DataExportRepository {
+	return &dataExportRepository{q: sqlc.New(db)}
+}
+
+func (r *dataExportRepository) Create(ctx context.Context, userID int64) (*sqlc.DataExport, error) {
+	exp, err := r.q.CreateDataExport(ctx, pgtype.Int8{Int64: userID, Valid: true})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &exp, nil
+}
+
+func (r *dataExportRepository) GetLatestByUserID(ctx context.Context, userID int64) (*sqlc.DataExport, error) {
+	exp, err := r.q.GetLatestDataExportByUserID(ctx, pgtype.Int8{Int64: userID, Valid: true})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &exp, nil
+}
+
+func (r *dataExportRepository) MarkReady(
+	ctx context.Context,
+	id int64,
+	storagePath string,
+	size int64,
+	expiresAt time.Time,
+) (*sqlc.DataExport, error) {
+	exp, err := r.q.MarkDataExportReady(ctx, sqlc.MarkDataExportReadyParams{
+		ID:          id,
+		StoragePath: pgtype.Text{String: storagePath, Valid: true},
+		Size:        pgtype.Int8{Int64: size, Valid: true},
+		ExpiresAt:   pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &exp, nil
+}
+
+// MarkFailed records a failed build; the row is swept once expiresAt has passed.
+func (r *dataExportRepository) MarkFailed(ctx context.Context, id int64, expiresAt time.Time) error {
+	return r.q.MarkDataExportFailed(ctx, sqlc.MarkDataExportFailedParams{
+		ID:        id,
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	})
+}
+
+func (r *dataExportRepository) ListExpired(ctx context.Context, limit int32) ([]sqlc.DataExport, error) {
+	return r.q.ListExpiredDataExports(ctx, limit)
+}
+
+func (r *dataExportRepository) Delete(ctx context.Context, id int64) error {
+	return r.q.DeleteDataExport(ctx, id)
+}
+
+// DeleteByUserID removes all of the user's exports and returns the storage paths of their
+// archives so the caller can delete the stored objects.
+func (r *dataExportRepository) DeleteByUserID(ctx context.Context, userID int64) ([]string, error) {
+	paths, err := r.q.DeleteDataExportsByUserID(ctx, pgtype.Int8{Int64: userID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if p.Valid {
+			result = append(result, p.String)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+)
+
+type EmailChangeRepository interface {
+	Create(ctx context.Context, params sqlc.CreateEmailChangeTokenParams) (*sqlc.EmailChangeToken, error)
+	GetByToken(ctx context.Context, token string) (*sqlc.EmailChangeToken, error)
+	Delete(ctx context.Context, token string) error
+	DeleteByUserID(ctx context.Context, userID int64) error
+}
+
+type emailChangeRepository struct {
+	q *sqlc.Queries
+}
+
+func NewEmailChangeRepository(db sqlc.DBTX) EmailChangeRepository {
+	return &emailChangeRepository{q: sqlc.New(db)}
+}
+
+func (r *emailChangeRepository) Create(ctx context.Context, params sqlc.CreateEmailChangeTokenParams) (*sqlc.EmailChangeToken, error) {
+	rt, err := r.q.CreateEmailChangeToken(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &rt, nil
+}
+
+func (r *emailChangeRepository) GetByToken(ctx context.Context, token string) (*sqlc.EmailChangeToken, error) {
+	rt, err := r.q.GetEmailChangeTokenByToken(ctx, token)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &rt, nil
+}
+
+func (r *emailChangeRepository) Delete(ctx context.Context, token string) error {
+	return r.q.DeleteEmailChangeToken(ctx, token)
+}
+
+func (r *emailChangeRepository) DeleteByUserID(ctx context.Context, userID int64) error {
+	return r.q.DeleteEmailChangeTokensByUserID(ctx, userID)
+}
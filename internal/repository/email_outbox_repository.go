@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+)
+
+type EmailOutboxRepository interface {
+	Enqueue(ctx context.Context, params sqlc.EnqueueEmailParams) (*sqlc.EmailOutbox, error)
+	ClaimDue(ctx context.Context, limit int32, lock time.Duration) ([]sqlc.EmailOutbox, error)
+	MarkSent(ctx context.Context, id int64) error
+	MarkFailed(ctx context.Context, id int64, lastError string, nextAttemptAt time.Time) error
+	MarkDead(ctx context.Context, id int64, lastError string) error
+	RetryDead(ctx context.Context, id int64) (*sqlc.EmailOutbox, error)
+	GetByID(ctx context.Context, id int64) (*sqlc.EmailOutbox, error)
+	List(ctx context.Context, status string, limit, offset int32) ([]sqlc.EmailOutbox, error)
+	Count(ctx context.Context, status string) (int64, error)
+	PurgeFinished(ctx context.Context, before time.Time) (int64, error)
+}
+
+type emailOutboxRepository struct {
+	q *sqlc.Queries
+}
+
+func NewEmailOutboxRepository(db sqlc.DBTX) EmailOutboxRepository {
+	return &emailOutboxRepository{q: sqlc.New(db)}
+}
+
+func (r *emailOutboxRepository) Enqueue(ctx context.Context, params sqlc.EnqueueEmailParams) (*sqlc.EmailOutbox, error) {
+	msg, err := r.q.EnqueueEmail(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &msg, nil
+}
+
+// ClaimDue marks up to limit due messages as being sent and locks them for lock, counting
+// the attempt. Messages whose lock expired while sending are claimed again.
+func (r *emailOutboxRepository) ClaimDue(ctx context.Context, limit int32, lock time.Duration) ([]sqlc.EmailOutbox, error) {
+	return r.q.ClaimDueEmails(ctx, sqlc.ClaimDueEmailsParams{LockSeconds: int32(lock.Seconds()), Limit: limit})
+}
+
+func (r *emailOutboxRepository) MarkSent(ctx context.Context, id int64) error {
+	return r.q.MarkEmailSent(ctx, id)
+}
+
+func (r *emailOutboxRepository) MarkFailed(ctx context.Context, id int64, lastError string, nextAttemptAt time.Time) error {
+	return r.q.MarkEmailFailed(ctx, sqlc.MarkEmailFailedParams{
+		ID:            id,
+		LastError:     lastError,
+		NextAttemptAt: pgtype.Timestamptz{Time: nextAttemptAt, Valid: true},
+	})
+}
+
+func (r *emailOutboxRepository) MarkDead(ctx context.Context, id int64, lastError string) error {
+	return r.q.MarkEmailDead(ctx, sqlc.MarkEmailDeadParams{ID: id, LastError: lastError})
+}
+
+// RetryDead queues a dead message again with a fresh set of attempts, returning
+// apperror.ErrNotFound if no dead message has the ID.
+func (r *emailOutboxRepository) RetryDead(ctx context.Context, id int64) (*sqlc.EmailOutbox, error) {
+	msg, err := r.q.RetryDeadEmail(ctx, id)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &msg, nil
+}
+
+func (r *emailOutboxRepository) GetByID(ctx context.Context, id int64) (*sqlc.EmailOutbox, error) {
+	msg, err := r.q.GetEmail(ctx, id)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &msg, nil
+}
+
+// List returns messages newest first, only those in status unless it is empty.
+func (r *emailOutboxRepository) List(ctx context.Context, status string, limit, offset int32) ([]sqlc.EmailOutbox, error) {
+	return r.q.ListEmails(ctx, sqlc.ListEmailsParams{Status: pgtype.Text{String: status, Valid: status != ""}, Limit: limit, Offset: offset})
+}
+
+func (r *emailOutboxRepository) Count(ctx context.Context, status string) (int64, error) {
+	return r.q.CountEmails(ctx, pgtype.Text{String: status, Valid: status != ""})
+}
+
+// PurgeFinished deletes sent and dead messages created before the given time.
+func (r *emailOutboxRepository) PurgeFinished(ctx context.Context, before time.Time) (int64, error) {
+	return r.q.PurgeFinishedEmails(ctx, pgtype.Timestamptz{Time: before, Valid: true})
+}
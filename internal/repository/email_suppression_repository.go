@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+type EmailSuppressionRepository interface {
+	Upsert(ctx context.Context, params sqlc.UpsertEmailSuppressionParams) (*sqlc.EmailSuppression, error)
+	Suppressed(ctx context.Context, emails []string) ([]string, error)
+	GetByID(ctx context.Context, id int64) (*sqlc.EmailSuppression, error)
+	Delete(ctx context.Context, id int64) error
+	List(ctx context.Context, reason, search string, limit, offset int32) ([]sqlc.EmailSuppression, error)
+	Count(ctx context.Context, reason, search string) (int64, error)
+}
+
+type emailSuppressionRepository struct {
+	q *sqlc.Queries
+}
+
+func NewEmailSuppressionRepository(db sqlc.DBTX) EmailSuppressionRepository {
+	return &emailSuppressionRepository{q: sqlc.New(db)}
+}
+
+// Upsert suppresses an address, replacing the reason and detail if it already is.
+func (r *emailSuppressionRepository) Upsert(ctx context.Context, params sqlc.UpsertEmailSuppressionParams) (*sqlc.EmailSuppression, error) {
+	s, err := r.q.UpsertEmailSuppression(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &s, nil
+}
+
+// Suppressed returns which of the given lowercased addresses are suppressed.
+func (r *emailSuppressionRepository) Suppressed(ctx context.Context, emails []string) ([]string, error) {
+	return r.q.ListSuppressedEmails(ctx, emails)
+}
+
+func (r *emailSuppressionRepository) GetByID(ctx context.Context, id int64) (*sqlc.EmailSuppression, error) {
+	s, err := r.q.GetEmailSuppression(ctx, id)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &s, nil
+}
+
+// Delete removes a suppression, returning apperror.ErrNotFound if it does not exist.
+func (r *emailSuppressionRepository) Delete(ctx context.Context, id int64) error {
+	n, err := r.q.DeleteEmailSuppression(ctx, id)
+	if err != nil {
+		return wrapErr(err)
+	}
+	if n == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+// List returns suppressions most recently reported first, filtered by reason and by a
+// substring of the address unless they are empty.
+func (r *emailSuppressionRepository) List(ctx context.Context, reason, search string, limit, offset int32) ([]sqlc.EmailSuppression, error) {
+	return r.q.ListEmailSuppressions(ctx, sqlc.ListEmailSuppressionsParams{
+		Reason: pgtype.Text{String: reason, Valid: reason != ""},
+		Search: pgtype.Text{String: search, Valid: search != ""},
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+func (r *emailSuppressionRepository) Count(ctx context.Context, reason, search string) (int64, error) {
+	return r.q.CountEmailSuppressions(ctx, sqlc.CountEmailSuppressionsParams{
+		Reason: pgtype.Text{String: reason, Valid: reason != ""},
+		Search: pgtype.Text{String: search, Valid: search != ""},
+	})
+}
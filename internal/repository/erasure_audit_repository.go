@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+)
+
+type ErasureAuditRepository interface {
+	Create(ctx context.Context, params sqlc.CreateErasureAuditParams) (*sqlc.ErasureAudit, error)
+	List(ctx context.Context, limit, offset int32) ([]sqlc.ErasureAudit, error)
+	Count(ctx context.Context) (int64, error)
+}
+
+type erasureAuditRepository struct {
+	q *sqlc.Queries
+}
+
+func NewErasureAuditRepository(db sqlc.DBTX) ErasureAuditRepository {
+	return &erasureAuditRepository{q: sqlc.New(db)}
+}
+
+func (r *erasureAuditRepository) Create(ctx context.Context, params sqlc.CreateErasureAuditParams) (*sqlc.ErasureAudit, error) {
+	audit, err := r.q.CreateErasureAudit(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &audit, nil
+}
+
+func (r *erasureAuditRepository) List(ctx context.Context, limit, offset int32) ([]sqlc.ErasureAudit, error) {
+	return r.q.ListErasureAudits(ctx, sqlc.ListErasureAuditsParams{Limit: limit, Offset: offset})
+}
+
+func (r *erasureAuditRepository) Count(ctx context.Context) (int64, error) {
+	return r.q.CountErasureAudits(ctx)
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+type FeatureFlagRepository interface {
+	Create(ctx context.Context, params sqlc.CreateFeatureFlagParams) (*sqlc.FeatureFlag, error)
+	List(ctx context.Context) ([]sqlc.FeatureFlag, error)
+	Update(ctx context.Context, params sqlc.UpdateFeatureFlagParams) (*sqlc.FeatureFlag, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+type featureFlagRepository struct {
+	q *sqlc.Queries
+}
+
+func NewFeatureFlagRepository(db sqlc.DBTX) FeatureFlagRepository {
+	return &featureFlagRepository{q: sqlc.New(db)}
+}
+
+func (r *featureFlagRepository) Create(ctx context.Context, params sqlc.CreateFeatureFlagParams) (*sqlc.FeatureFlag, error) {
+	flag, err := r.q.CreateFeatureFlag(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &flag, nil
+}
+
+func (r *featureFlagRepository) List(ctx context.Context) ([]sqlc.FeatureFlag, error) {
+	return r.q.ListFeatureFlags(ctx)
+}
+
+// Update changes the fields set in params, returning apperror.ErrNotFound if the flag does
+// not exist.
+func (r *featureFlagRepository) Update(ctx context.Context, params sqlc.UpdateFeatureFlagParams) (*sqlc.FeatureFlag, error) {
+	flag, err := r.q.UpdateFeatureFlag(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &flag, nil
+}
+
+// Delete removes a flag, returning apperror.ErrNotFound if it does not exist.
+func (r *featureFlagRepository) Delete(ctx context.Context, id int64) error {
+	n, err := r.q.DeleteFeatureFlag(ctx, id)
+	if err != nil {
+		return wrapErr(err)
+	}
+	if n == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
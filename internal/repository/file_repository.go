@@ -2,19 +2,62 @@ package repository
 
 import (
 	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
 )
 
 type FileRepository interface {
 	Create(ctx context.Context, params sqlc.CreateFileParams) (*sqlc.File, error)
 	GetByID(ctx context.Context, id int64) (*sqlc.File, error)
-	ListByUserID(ctx context.Context, userID int64, limit, offset int32) ([]sqlc.File, error)
-	CountByUserID(ctx context.Context, userID int64) (int64, error)
+	ListByIDs(ctx context.Context, ids []int64) ([]sqlc.File, error)
+	GetByChecksum(ctx context.Context, userID int64, checksum string) (*sqlc.File, error)
+	GetAnyByChecksum(ctx context.Context, checksum string) (*sqlc.File, error)
+	ListByStoragePath(ctx context.Context, storagePath string) ([]sqlc.ListStoredObjectFilesRow, error)
+	ListByUserID(ctx context.Context, userID int64, filter FileFilter, limit, offset int32) ([]sqlc.File, error)
+	ListAllByUserID(ctx context.Context, userID int64) ([]sqlc.File, error)
+	CountByUserID(ctx context.Context, userID int64, filter FileFilter) (int64, error)
 	Delete(ctx context.Context, id int64) (*sqlc.File, error)
-	Restore(ctx context.Context, id int64) (*sqlc.File, error)
-	AdminList(ctx context.Context, limit, offset int32) ([]sqlc.File, error)
-	AdminCount(ctx context.Context) (int64, error)
+	Restore(ctx context.Context, id int64, deletedAfter time.Time) (*sqlc.File, error)
+	ListDeletedBefore(ctx context.Context, before time.Time, limit int32) ([]int64, error)
+	PurgeDeleted(ctx context.Context, id int64, deletedBefore time.Time) ([]string, error)
+	Purge(ctx context.Context, id int64) ([]string, error)
+	Move(ctx context.Context, params sqlc.MoveFileParams) (*sqlc.File, error)
+	Rename(ctx context.Context, id int64, name string) (*sqlc.File, error)
+	ReplaceContent(ctx context.Context, params sqlc.ReplaceFileContentParams) (*sqlc.File, error)
+	GetVersion(ctx context.Context, fileID int64, version int32) (*sqlc.FileVersion, error)
+	ListVersions(ctx context.Context, fileID int64) ([]sqlc.FileVersion, error)
+	ListVersionPathsByUserID(ctx context.Context, userID int64) ([]string, error)
+	AdminGet(ctx context.Context, id int64) (*sqlc.File, error)
+	AdminList(ctx context.Context, tag string, limit, offset int32) ([]sqlc.File, error)
+	AdminCount(ctx context.Context, tag string) (int64, error)
+	AdminSearch(ctx context.Context, term string, limit int32) ([]sqlc.AdminSearchFilesRow, error)
+	PurgeByUserID(ctx context.Context, userID int64) ([]string, error)
+	CreateVariant(ctx context.Context, params sqlc.CreateFileVariantParams) (*sqlc.FileVariant, error)
+	CopyVariants(ctx context.Context, sourceID, targetID int64) (int64, error)
+	DeleteVariants(ctx context.Context, fileID int64) ([]string, error)
+	ListVariantsByFileIDs(ctx context.Context, fileIDs []int64) ([]sqlc.FileVariant, error)
+	ListVariantPathsByUserID(ctx context.Context, userID int64) ([]string, error)
+	ListStorageReferences(ctx context.Context) ([]sqlc.ListStorageReferencesRow, error)
+	ListSharedStoragePaths(ctx context.Context, userID int64, paths []string) ([]string, error)
+	ListStoredObjects(ctx context.Context) ([]sqlc.ListStoredObjectsRow, error)
+	MoveStoredObject(ctx context.Context, oldPath, newPath string, keyID pgtype.Text) error
+	SetVisibility(ctx context.Context, id int64, visibility string) (*sqlc.File, error)
+	GrantPermission(ctx context.Context, params sqlc.GrantFilePermissionParams) (*sqlc.FilePermission, error)
+	ListPermissions(ctx context.Context, fileID int64) ([]sqlc.ListFilePermissionsRow, error)
+	HasPermission(ctx context.Context, fileID, userID int64) (bool, error)
+	RevokePermission(ctx context.Context, fileID, userID int64) error
+	SetTags(ctx context.Context, fileID int64, tags []string) error
+	ListTagsByFileIDs(ctx context.Context, fileIDs []int64) ([]sqlc.FileTag, error)
+}
+
+// FileFilter narrows a user's file listing. Zero values disable the corresponding filter.
+type FileFilter struct {
+	FolderID *int64 // 0 selects the files that are in no folder
+	Tag      string
 }
 
 type fileRepository struct {
@@ -43,16 +86,61 @@ func (r *fileRepository) GetByID(ctx context.Context, id int64) (*sqlc.File, err
 	return &file, nil
 }
 
-func (r *fileRepository) ListByUserID(ctx context.Context, userID int64, limit, offset int32) ([]sqlc.File, error) {
+// ListByIDs returns the files with the given IDs that exist and are not deleted, by ID.
+func (r *fileRepository) ListByIDs(ctx context.Context, ids []int64) ([]sqlc.File, error) {
+	return r.q.ListFilesByIDs(ctx, ids)
+}
+
+// GetByChecksum returns the user's oldest file with the given SHA-256, or
+// apperror.ErrNotFound. Files found this way may share their stored object with others.
+func (r *fileRepository) GetByChecksum(ctx context.Context, userID int64, checksum string) (*sqlc.File, error) {
+	file, err := r.q.GetFileByChecksum(ctx, sqlc.GetFileByChecksumParams{
+		UserID:   userID,
+		Checksum: pgtype.Text{String: checksum, Valid: true},
+	})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &file, nil
+}
+
+// GetAnyByChecksum returns the oldest file of any user with the given SHA-256, or
+// apperror.ErrNotFound.
+func (r *fileRepository) GetAnyByChecksum(ctx context.Context, checksum string) (*sqlc.File, error) {
+	file, err := r.q.GetAnyFileByChecksum(ctx, pgtype.Text{String: checksum, Valid: true})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &file, nil
+}
+
+// ListByStoragePath returns the live files whose content, versions or variants are stored
+// at storagePath, with the type and size recorded for the object.
+func (r *fileRepository) ListByStoragePath(ctx context.Context, storagePath string) ([]sqlc.ListStoredObjectFilesRow, error) {
+	return r.q.ListStoredObjectFiles(ctx, storagePath)
+}
+
+// ListByUserID lists the user's files that match the filter, newest first.
+func (r *fileRepository) ListByUserID(ctx context.Context, userID int64, filter FileFilter, limit, offset int32) ([]sqlc.File, error) {
+	p := filter.params(userID)
 	return r.q.ListFilesByUserID(ctx, sqlc.ListFilesByUserIDParams{
-		UserID: userID,
-		Limit:  limit,
-		Offset: offset,
+		UserID:       p.UserID,
+		FilterFolder: p.FilterFolder,
+		FolderID:     p.FolderID,
+		Tag:          p.Tag,
+		Limit:        limit,
+		Offset:       offset,
 	})
 }
 
-func (r *fileRepository) CountByUserID(ctx context.Context, userID int64) (int64, error) {
-	return r.q.CountFilesByUserID(ctx, userID)
+// ListAllByUserID returns every file owned by the user, including soft-deleted ones.
+func (r *fileRepository) ListAllByUserID(ctx context.Context, userID int64) ([]sqlc.File, error) {
+	return r.q.ListAllFilesByUserID(ctx, userID)
+}
+
+// CountByUserID counts the files ListByUserID would return for the same filter.
+func (r *fileRepository) CountByUserID(ctx context.Context, userID int64, filter FileFilter) (int64, error) {
+	return r.q.CountFilesByUserID(ctx, filter.params(userID))
 }
 
 func (r *fileRepository) Delete(ctx context.Context, id int64) (*sqlc.File, error) {
@@ -63,21 +151,256 @@ func (r *fileRepository) Delete(ctx context.Context, id int64) (*sqlc.File, erro
 	return &file, nil
 }
 
-func (r *fileRepository) Restore(ctx context.Context, id int64) (*sqlc.File, error) {
-	file, err := r.q.RestoreFile(ctx, id)
+// Restore undeletes a file soft-deleted after deletedAfter, or at any time when it is zero.
+// It returns apperror.ErrNotFound if no such file is deleted.
+func (r *fileRepository) Restore(ctx context.Context, id int64, deletedAfter time.Time) (*sqlc.File, error) {
+	file, err := r.q.RestoreFile(ctx, sqlc.RestoreFileParams{
+		ID:           id,
+		DeletedAfter: pgtype.Timestamptz{Time: deletedAfter, Valid: !deletedAfter.IsZero()},
+	})
 	if err != nil {
 		return nil, wrapErr(err)
 	}
 	return &file, nil
 }
 
-func (r *fileRepository) AdminList(ctx context.Context, limit, offset int32) ([]sqlc.File, error) {
+// ListDeletedBefore returns the IDs of files soft-deleted before the given time, oldest first.
+func (r *fileRepository) ListDeletedBefore(ctx context.Context, before time.Time, limit int32) ([]int64, error) {
+	return r.q.ListFileIDsDeletedBefore(ctx, sqlc.ListFileIDsDeletedBeforeParams{
+		DeletedAt: pgtype.Timestamptz{Time: before, Valid: true},
+		Limit:     limit,
+	})
+}
+
+// PurgeDeleted permanently removes a file soft-deleted before deletedBefore with its versions
+// and variants, and returns the storage paths nothing else refers to any more.
+func (r *fileRepository) PurgeDeleted(ctx context.Context, id int64, deletedBefore time.Time) ([]string, error) {
+	return r.q.PurgeDeletedFile(ctx, sqlc.PurgeDeletedFileParams{
+		ID:        id,
+		DeletedAt: pgtype.Timestamptz{Time: deletedBefore, Valid: true},
+	})
+}
+
+// Purge permanently removes a file, whether or not it is soft-deleted, with its versions and
+// variants, and returns the storage paths nothing else refers to any more.
+func (r *fileRepository) Purge(ctx context.Context, id int64) ([]string, error) {
+	return r.q.PurgeFile(ctx, id)
+}
+
+// Move puts a file into a folder, or into no folder when params.FolderID is NULL. It
+// returns apperror.ErrNotFound if the file is gone or the folder is not its owner's.
+func (r *fileRepository) Move(ctx context.Context, params sqlc.MoveFileParams) (*sqlc.File, error) {
+	file, err := r.q.MoveFile(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &file, nil
+}
+
+func (r *fileRepository) Rename(ctx context.Context, id int64, name string) (*sqlc.File, error) {
+	file, err := r.q.RenameFile(ctx, sqlc.RenameFileParams{ID: id, OriginalName: name})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &file, nil
+}
+
+// ReplaceContent archives the file's current content as a version and makes the given
+// content the next version. It returns apperror.ErrNotFound if the file is gone or no
+// longer at params.Version, i.e. it was changed concurrently.
+func (r *fileRepository) ReplaceContent(ctx context.Context, params sqlc.ReplaceFileContentParams) (*sqlc.File, error) {
+	file, err := r.q.ReplaceFileContent(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &file, nil
+}
+
+// GetVersion returns an archived version of a file. The current version lives in the
+// file itself.
+func (r *fileRepository) GetVersion(ctx context.Context, fileID int64, version int32) (*sqlc.FileVersion, error) {
+	v, err := r.q.GetFileVersion(ctx, sqlc.GetFileVersionParams{FileID: fileID, Version: version})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &v, nil
+}
+
+// ListVersions returns the archived versions of a file, newest first.
+func (r *fileRepository) ListVersions(ctx context.Context, fileID int64) ([]sqlc.FileVersion, error) {
+	return r.q.ListFileVersions(ctx, fileID)
+}
+
+// ListVersionPathsByUserID returns the storage paths of every archived version of the
+// user's files.
+func (r *fileRepository) ListVersionPathsByUserID(ctx context.Context, userID int64) ([]string, error) {
+	return r.q.ListFileVersionPathsByUserID(ctx, userID)
+}
+
+// AdminGet returns a file by ID, including a soft-deleted one.
+func (r *fileRepository) AdminGet(ctx context.Context, id int64) (*sqlc.File, error) {
+	file, err := r.q.AdminGetFile(ctx, id)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &file, nil
+}
+
+// AdminList lists every file, newest first, optionally only those carrying tag.
+func (r *fileRepository) AdminList(ctx context.Context, tag string, limit, offset int32) ([]sqlc.File, error) {
 	return r.q.AdminListFiles(ctx, sqlc.AdminListFilesParams{
+		Tag:    optionalTag(tag),
 		Limit:  limit,
 		Offset: offset,
 	})
 }
 
-func (r *fileRepository) AdminCount(ctx context.Context) (int64, error) {
-	return r.q.AdminCountFiles(ctx)
+func (r *fileRepository) AdminCount(ctx context.Context, tag string) (int64, error) {
+	return r.q.AdminCountFiles(ctx, optionalTag(tag))
+}
+
+// AdminSearch returns up to limit files of any user, including deleted ones, whose name
+// contains term, best matches first. LIKE wildcards in term match literally.
+func (r *fileRepository) AdminSearch(ctx context.Context, term string, limit int32) ([]sqlc.AdminSearchFilesRow, error) {
+	return r.q.AdminSearchFiles(ctx, sqlc.AdminSearchFilesParams{Pattern: likeEscaper.Replace(term), Limit: limit})
+}
+
+// PurgeByUserID permanently removes every file record owned by the user and returns their
+// storage paths so the caller can delete the stored objects. Variant and version rows go
+// with their files; list their paths with ListVariantPathsByUserID and
+// ListVersionPathsByUserID first.
+func (r *fileRepository) PurgeByUserID(ctx context.Context, userID int64) ([]string, error) {
+	return r.q.PurgeFilesByUserID(ctx, userID)
+}
+
+func (r *fileRepository) CreateVariant(ctx context.Context, params sqlc.CreateFileVariantParams) (*sqlc.FileVariant, error) {
+	variant, err := r.q.CreateFileVariant(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &variant, nil
+}
+
+// CopyVariants gives targetID the variants of sourceID, pointing at the same stored
+// objects, and returns how many were copied.
+func (r *fileRepository) CopyVariants(ctx context.Context, sourceID, targetID int64) (int64, error) {
+	return r.q.CopyFileVariants(ctx, sqlc.CopyFileVariantsParams{SourceID: sourceID, TargetID: targetID})
+}
+
+// DeleteVariants removes a file's variants and returns the storage paths no other file's
+// variants share, which the caller can delete.
+func (r *fileRepository) DeleteVariants(ctx context.Context, fileID int64) ([]string, error) {
+	return r.q.DeleteFileVariants(ctx, fileID)
+}
+
+// ListVariantsByFileIDs returns the rendered variants of the given files, grouped by file.
+func (r *fileRepository) ListVariantsByFileIDs(ctx context.Context, fileIDs []int64) ([]sqlc.FileVariant, error) {
+	return r.q.ListFileVariantsByFileIDs(ctx, fileIDs)
+}
+
+// ListVariantPathsByUserID returns the storage paths of every variant of the user's files.
+func (r *fileRepository) ListVariantPathsByUserID(ctx context.Context, userID int64) ([]string, error) {
+	return r.q.ListFileVariantPathsByUserID(ctx, userID)
+}
+
+// ListStorageReferences returns every storage path referenced by files, file versions,
+// file variants, upload chunks and data exports.
+func (r *fileRepository) ListStorageReferences(ctx context.Context) ([]sqlc.ListStorageReferencesRow, error) {
+	return r.q.ListStorageReferences(ctx)
+}
+
+// ListSharedStoragePaths returns which of paths are referenced by files, versions or
+// variants of users other than userID.
+func (r *fileRepository) ListSharedStoragePaths(ctx context.Context, userID int64, paths []string) ([]string, error) {
+	return r.q.ListStoragePathsSharedWithOthers(ctx, sqlc.ListStoragePathsSharedWithOthersParams{
+		Paths:  paths,
+		UserID: userID,
+	})
+}
+
+// ListStoredObjects returns the stored objects of every file, file version and file
+// variant, ordered by path. A path shared by several rows is listed once per row.
+func (r *fileRepository) ListStoredObjects(ctx context.Context) ([]sqlc.ListStoredObjectsRow, error) {
+	return r.q.ListStoredObjects(ctx)
+}
+
+// MoveStoredObject points the files, file versions and file variants stored at oldPath to
+// newPath, recording keyID as the key files and versions are encrypted with. Run it in a
+// transaction so the three tables move together.
+func (r *fileRepository) MoveStoredObject(ctx context.Context, oldPath, newPath string, keyID pgtype.Text) error {
+	if _, err := r.q.MoveFileObject(ctx, sqlc.MoveFileObjectParams{
+		NewPath: newPath, EncryptionKeyID: keyID, OldPath: oldPath,
+	}); err != nil {
+		return err
+	}
+	if _, err := r.q.MoveFileVersionObject(ctx, sqlc.MoveFileVersionObjectParams{
+		NewPath: newPath, EncryptionKeyID: keyID, OldPath: oldPath,
+	}); err != nil {
+		return err
+	}
+	_, err := r.q.MoveFileVariantObject(ctx, sqlc.MoveFileVariantObjectParams{NewPath: newPath, OldPath: oldPath})
+	return err
+}
+
+func (r *fileRepository) SetVisibility(ctx context.Context, id int64, visibility string) (*sqlc.File, error) {
+	file, err := r.q.SetFileVisibility(ctx, sqlc.SetFileVisibilityParams{ID: id, Visibility: visibility})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &file, nil
+}
+
+// GrantPermission gives a user read access to a file. It returns apperror.ErrNotFound if
+// the user does not exist or is deleted; granting an existing permission is a no-op.
+func (r *fileRepository) GrantPermission(ctx context.Context, params sqlc.GrantFilePermissionParams) (*sqlc.FilePermission, error) {
+	perm, err := r.q.GrantFilePermission(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &perm, nil
+}
+
+// ListPermissions returns the users granted access to a file, oldest grant first.
+func (r *fileRepository) ListPermissions(ctx context.Context, fileID int64) ([]sqlc.ListFilePermissionsRow, error) {
+	return r.q.ListFilePermissions(ctx, fileID)
+}
+
+func (r *fileRepository) HasPermission(ctx context.Context, fileID, userID int64) (bool, error) {
+	return r.q.HasFilePermission(ctx, sqlc.HasFilePermissionParams{FileID: fileID, UserID: userID})
+}
+
+// RevokePermission removes a user's access, returning apperror.ErrNotFound if they had none.
+func (r *fileRepository) RevokePermission(ctx context.Context, fileID, userID int64) error {
+	n, err := r.q.RevokeFilePermission(ctx, sqlc.RevokeFilePermissionParams{FileID: fileID, UserID: userID})
+	if err != nil {
+		return wrapErr(err)
+	}
+	if n == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+// SetTags replaces the file's tags with the given set.
+func (r *fileRepository) SetTags(ctx context.Context, fileID int64, tags []string) error {
+	return r.q.SetFileTags(ctx, sqlc.SetFileTagsParams{FileID: fileID, Tags: tags})
+}
+
+// ListTagsByFileIDs returns the tags of the given files, grouped by file and sorted.
+func (r *fileRepository) ListTagsByFileIDs(ctx context.Context, fileIDs []int64) ([]sqlc.FileTag, error) {
+	return r.q.ListFileTagsByFileIDs(ctx, fileIDs)
+}
+
+// params converts the filter into query arguments.
+func (f FileFilter) params(userID int64) sqlc.CountFilesByUserIDParams {
+	p := sqlc.CountFilesByUserIDParams{UserID: userID, Tag: optionalTag(f.Tag)}
+	if f.FolderID != nil {
+		p.FilterFolder = true
+		p.FolderID = pgtype.Int8{Int64: *f.FolderID, Valid: *f.FolderID != 0}
+	}
+	return p
+}
+
+func optionalTag(tag string) pgtype.Text {
+	return pgtype.Text{String: tag, Valid: tag != ""}
 }
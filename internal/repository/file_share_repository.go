@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+type FileShareRepository interface {
+	Create(ctx context.Context, params sqlc.CreateFileShareParams) (*sqlc.FileShare, error)
+	GetByToken(ctx context.Context, token string) (*sqlc.FileShare, error)
+	ListByFileID(ctx context.Context, fileID int64) ([]sqlc.FileShare, error)
+	RecordDownload(ctx context.Context, id int64) error
+	Delete(ctx context.Context, id, fileID int64) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type fileShareRepository struct {
+	q *sqlc.Queries
+}
+
+func NewFileShareRepository(db sqlc.DBTX) FileShareRepository {
+	return &fileShareRepository{q: sqlc.New(db)}
+}
+
+func (r *fileShareRepository) Create(ctx context.Context, params sqlc.CreateFileShareParams) (*sqlc.FileShare, error) {
+	share, err := r.q.CreateFileShare(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &share, nil
+}
+
+func (r *fileShareRepository) GetByToken(ctx context.Context, token string) (*sqlc.FileShare, error) {
+	share, err := r.q.GetFileShareByToken(ctx, token)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &share, nil
+}
+
+func (r *fileShareRepository) ListByFileID(ctx context.Context, fileID int64) ([]sqlc.FileShare, error) {
+	return r.q.ListFileSharesByFileID(ctx, fileID)
+}
+
+// RecordDownload counts one download, returning apperror.ErrNotFound if the share is
+// gone, expired or has no downloads left.
+func (r *fileShareRepository) RecordDownload(ctx context.Context, id int64) error {
+	n, err := r.q.RecordFileShareDownload(ctx, id)
+	if err != nil {
+		return wrapErr(err)
+	}
+	if n == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes a share of the file, returning apperror.ErrNotFound if it does not exist.
+func (r *fileShareRepository) Delete(ctx context.Context, id, fileID int64) error {
+	n, err := r.q.DeleteFileShare(ctx, sqlc.DeleteFileShareParams{ID: id, FileID: fileID})
+	if err != nil {
+		return wrapErr(err)
+	}
+	if n == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteExpired removes shares that expired or used up their downloads and returns how
+// many were deleted.
+func (r *fileShareRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	return r.q.DeleteExpiredFileShares(ctx)
+}
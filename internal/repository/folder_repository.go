@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+type FolderRepository interface {
+	Create(ctx context.Context, params sqlc.CreateFolderParams) (*sqlc.Folder, error)
+	GetByID(ctx context.Context, id int64) (*sqlc.Folder, error)
+	ListByParentID(ctx context.Context, userID int64, parentID pgtype.Int8) ([]sqlc.Folder, error)
+	Rename(ctx context.Context, id int64, name string) (*sqlc.Folder, error)
+	Move(ctx context.Context, id int64, parentID pgtype.Int8) (*sqlc.Folder, error)
+	IsAncestor(ctx context.Context, ancestorID, folderID int64) (bool, error)
+	CountChildren(ctx context.Context, id int64) (int64, error)
+	Delete(ctx context.Context, id int64) error
+	DeleteByUserID(ctx context.Context, userID int64) error
+}
+
+type folderRepository struct {
+	q *sqlc.Queries
+}
+
+func NewFolderRepository(db sqlc.DBTX) FolderRepository {
+	return &folderRepository{q: sqlc.New(db)}
+}
+
+func (r *folderRepository) Create(ctx context.Context, params sqlc.CreateFolderParams) (*sqlc.Folder, error) {
+	folder, err := r.q.CreateFolder(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &folder, nil
+}
+
+func (r *folderRepository) GetByID(ctx context.Context, id int64) (*sqlc.Folder, error) {
+	folder, err := r.q.GetFolderByID(ctx, id)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &folder, nil
+}
+
+// ListByParentID returns the user's folders directly inside parentID, or the top-level
+// folders when parentID is NULL, ordered by name.
+func (r *folderRepository) ListByParentID(ctx context.Context, userID int64, parentID pgtype.Int8) ([]sqlc.Folder, error) {
+	return r.q.ListFoldersByParentID(ctx, sqlc.ListFoldersByParentIDParams{UserID: userID, ParentID: parentID})
+}
+
+func (r *folderRepository) Rename(ctx context.Context, id int64, name string) (*sqlc.Folder, error) {
+	folder, err := r.q.RenameFolder(ctx, sqlc.RenameFolderParams{ID: id, Name: name})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &folder, nil
+}
+
+// Move puts a folder inside parentID, or at the top level when parentID is NULL.
+func (r *folderRepository) Move(ctx context.Context, id int64, parentID pgtype.Int8) (*sqlc.Folder, error) {
+	folder, err := r.q.MoveFolder(ctx, sqlc.MoveFolderParams{ID: id, ParentID: parentID})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &folder, nil
+}
+
+// IsAncestor reports whether ancestorID is folderID itself or contains it at any depth.
+func (r *folderRepository) IsAncestor(ctx context.Context, ancestorID, folderID int64) (bool, error) {
+	return r.q.IsFolderAncestor(ctx, sqlc.IsFolderAncestorParams{AncestorID: ancestorID, FolderID: folderID})
+}
+
+// CountChildren counts the subfolders and files (excluding soft-deleted ones) directly in a folder.
+func (r *folderRepository) CountChildren(ctx context.Context, id int64) (int64, error) {
+	return r.q.CountFolderChildren(ctx, id)
+}
+
+// Delete removes a folder, returning apperror.ErrNotFound if it does not exist.
+func (r *folderRepository) Delete(ctx context.Context, id int64) error {
+	n, err := r.q.DeleteFolder(ctx, id)
+	if err != nil {
+		return wrapErr(err)
+	}
+	if n == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteByUserID removes every folder the user owns. Their files stay, outside any folder.
+func (r *folderRepository) DeleteByUserID(ctx context.Context, userID int64) error {
+	return r.q.DeleteFoldersByUserID(ctx, userID)
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+)
+
+type LoginEventRepository interface {
+	Create(ctx context.Context, params sqlc.CreateLoginEventParams) (*sqlc.LoginEvent, error)
+	ListByUserID(ctx context.Context, userID int64, limit, offset int32) ([]sqlc.LoginEvent, error)
+	CountByUserID(ctx context.Context, userID int64) (int64, error)
+	GetDeviceHistory(ctx context.Context, userID int64, userAgent string) (hasLogins, knownDevice bool, err error)
+	AnonymizeByUserID(ctx context.Context, userID int64) error
+}
+
+type loginEventRepository struct {
+	q *sqlc.Queries
+}
+
+func NewLoginEventRepository(db sqlc.DBTX) LoginEventRepository {
+	return &loginEventRepository{q: sqlc.New(db)}
+}
+
+func (r *loginEventRepository) Create(ctx context.Context, params sqlc.CreateLoginEventParams) (*sqlc.LoginEvent, error) {
+	event, err := r.q.CreateLoginEvent(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &event, nil
+}
+
+func (r *loginEventRepository) ListByUserID(ctx context.Context, userID int64, limit, offset int32) ([]sqlc.LoginEvent, error) {
+	return r.q.ListLoginEventsByUserID(ctx, sqlc.ListLoginEventsByUserIDParams{
+		UserID: pgtype.Int8{Int64: userID, Valid: true},
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+func (r *loginEventRepository) CountByUserID(ctx context.Context, userID int64) (int64, error) {
+	return r.q.CountLoginEventsByUserID(ctx, pgtype.Int8{Int64: userID, Valid: true})
+}
+
+// GetDeviceHistory reports whether the user has any successful login and whether
+// one of them came from the given user agent.
+func (r *loginEventRepository) GetDeviceHistory(ctx context.Context, userID int64, userAgent string) (bool, bool, error) {
+	row, err := r.q.GetLoginDeviceHistory(ctx, sqlc.GetLoginDeviceHistoryParams{
+		UserID:    pgtype.Int8{Int64: userID, Valid: true},
+		UserAgent: userAgent,
+	})
+	if err != nil {
+		return false, false, wrapErr(err)
+	}
+	return row.HasLogins, row.KnownDevice, nil
+}
+
+// AnonymizeByUserID clears the email, IP address and user agent recorded on the user's
+// login events, keeping only the method, outcome and time.
+func (r *loginEventRepository) AnonymizeByUserID(ctx context.Context, userID int64) error {
+	return r.q.AnonymizeLoginEventsByUserID(ctx, pgtype.Int8{Int64: userID, Valid: true})
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+type OrganizationRepository interface {
+	Create(ctx context.Context, name, slug string) (*sqlc.Organization, error)
+	GetForMember(ctx context.Context, orgID, userID int64) (*sqlc.GetOrganizationForMemberRow, error)
+	ListByUser(ctx context.Context, userID int64, limit, offset int32) ([]sqlc.ListOrganizationsByUserRow, error)
+	CountByUser(ctx context.Context, userID int64) (int64, error)
+	Update(ctx context.Context, id int64, name string) (*sqlc.Organization, error)
+	Delete(ctx context.Context, id int64) error
+
+	AddMember(ctx context.Context, orgID, userID int64, role string) error
+	GetMember(ctx context.Context, orgID, userID int64) (*sqlc.OrganizationMember, error)
+	ListMembers(ctx context.Context, orgID int64, limit, offset int32) ([]sqlc.ListOrganizationMembersRow, error)
+	CountMembers(ctx context.Context, orgID int64) (int64, error)
+	CountOwners(ctx context.Context, orgID int64) (int64, error)
+	UpdateMemberRole(ctx context.Context, orgID, userID int64, role string) (*sqlc.OrganizationMember, error)
+	RemoveMember(ctx context.Context, orgID, userID int64) error
+
+	UpsertInvitation(ctx context.Context, params sqlc.UpsertOrganizationInvitationParams) (*sqlc.OrganizationInvitation, error)
+	GetInvitationByToken(ctx context.Context, token string) (*sqlc.OrganizationInvitation, error)
+	DeleteInvitation(ctx context.Context, id int64) error
+}
+
+type organizationRepository struct {
+	q *sqlc.Queries
+}
+
+func NewOrganizationRepository(db sqlc.DBTX) OrganizationRepository {
+	return &organizationRepository{q: sqlc.New(db)}
+}
+
+func (r *organizationRepository) Create(ctx context.Context, name, slug string) (*sqlc.Organization, error) {
+	org, err := r.q.CreateOrganization(ctx, sqlc.CreateOrganizationParams{Name: name, Slug: slug})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &org, nil
+}
+
+// GetForMember returns the organization together with the user's role in it.
+// Organizations the user does not belong to are reported as not found.
+func (r *organizationRepository) GetForMember(ctx context.Context, orgID, userID int64) (*sqlc.GetOrganizationForMemberRow, error) {
+	org, err := r.q.GetOrganizationForMember(ctx, sqlc.GetOrganizationForMemberParams{ID: orgID, UserID: userID})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &org, nil
+}
+
+func (r *organizationRepository) ListByUser(ctx context.Context, userID int64, limit, offset int32) ([]sqlc.ListOrganizationsByUserRow, error) {
+	return r.q.ListOrganizationsByUser(ctx, sqlc.ListOrganizationsByUserParams{
+		UserID: userID,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+func (r *organizationRepository) CountByUser(ctx context.Context, userID int64) (int64, error) {
+	return r.q.CountOrganizationsByUser(ctx, userID)
+}
+
+func (r *organizationRepository) Update(ctx context.Context, id int64, name string) (*sqlc.Organization, error) {
+	org, err := r.q.UpdateOrganization(ctx, sqlc.UpdateOrganizationParams{ID: id, Name: name})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &org, nil
+}
+
+func (r *organizationRepository) Delete(ctx context.Context, id int64) error {
+	return r.q.DeleteOrganization(ctx, id)
+}
+
+// AddMember adds the user to the organization; existing memberships are left unchanged.
+func (r *organizationRepository) AddMember(ctx context.Context, orgID, userID int64, role string) error {
+	return r.q.AddOrganizationMember(ctx, sqlc.AddOrganizationMemberParams{
+		OrganizationID: orgID,
+		UserID:         userID,
+		Role:           role,
+	})
+}
+
+func (r *organizationRepository) GetMember(ctx context.Context, orgID, userID int64) (*sqlc.OrganizationMember, error) {
+	m, err := r.q.GetOrganizationMember(ctx, sqlc.GetOrganizationMemberParams{OrganizationID: orgID, UserID: userID})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &m, nil
+}
+
+func (r *organizationRepository) ListMembers(ctx context.Context, orgID int64, limit, offset int32) ([]sqlc.ListOrganizationMembersRow, error) {
+	return r.q.ListOrganizationMembers(ctx, sqlc.ListOrganizationMembersParams{
+		OrganizationID: orgID,
+		Limit:          limit,
+		Offset:         offset,
+	})
+}
+
+func (r *organizationRepository) CountMembers(ctx context.Context, orgID int64) (int64, error) {
+	return r.q.CountOrganizationMembers(ctx, orgID)
+}
+
+func (r *organizationRepository) CountOwners(ctx context.Context, orgID int64) (int64, error) {
+	return r.q.CountOrganizationOwners(ctx, orgID)
+}
+
+func (r *organizationRepository) UpdateMemberRole(ctx context.Context, orgID, userID int64, role string) (*sqlc.OrganizationMember, error) {
+	m, err := r.q.UpdateOrganizationMemberRole(ctx, sqlc.UpdateOrganizationMemberRoleParams{
+		OrganizationID: orgID,
+		UserID:         userID,
+		Role:           role,
+	})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &m, nil
+}
+
+func (r *organizationRepository) RemoveMember(ctx context.Context, orgID, userID int64) error {
+	n, err := r.q.DeleteOrganizationMember(ctx, sqlc.DeleteOrganizationMemberParams{OrganizationID: orgID, UserID: userID})
+	if err != nil {
+		return wrapErr(err)
+	}
+	if n == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+// UpsertInvitation creates an invitation, replacing any pending one for the same address.
+func (r *organizationRepository) UpsertInvitation(
+	ctx context.Context,
+	params sqlc.UpsertOrganizationInvitationParams,
+) (*sqlc.OrganizationInvitation, error) {
+	inv, err := r.q.UpsertOrganizationInvitation(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &inv, nil
+}
+
+func (r *organizationRepository) GetInvitationByToken(ctx context.Context, token string) (*sqlc.OrganizationInvitation, error) {
+	inv, err := r.q.GetOrganizationInvitationByToken(ctx, token)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &inv, nil
+}
+
+func (r *organizationRepository) DeleteInvitation(ctx context.Context, id int64) error {
+	return r.q.DeleteOrganizationInvitation(ctx, id)
+}
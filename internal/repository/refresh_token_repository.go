@@ -9,8 +9,10 @@ import (
 type RefreshTokenRepository interface {
 	Create(ctx context.Context, params sqlc.CreateRefreshTokenParams) (*sqlc.RefreshToken, error)
 	GetByToken(ctx context.Context, token string) (*sqlc.RefreshToken, error)
+	MarkRotated(ctx context.Context, id int64) (*sqlc.RefreshToken, error)
 	Delete(ctx context.Context, token string) error
 	DeleteByUserID(ctx context.Context, userID int64) error
+	ListActiveByUserID(ctx context.Context, userID int64) ([]sqlc.ListActiveRefreshTokensByUserIDRow, error)
 }
 
 type refreshTokenRepository struct {
@@ -37,6 +39,16 @@ func (r *refreshTokenRepository) GetByToken(ctx context.Context, token string) (
 	return &rt, nil
 }
 
+// MarkRotated flags the token as rotated. It returns ErrNotFound if the token
+// was already rotated, so concurrent rotations of the same token cannot both succeed.
+func (r *refreshTokenRepository) MarkRotated(ctx context.Context, id int64) (*sqlc.RefreshToken, error) {
+	rt, err := r.q.MarkRefreshTokenRotated(ctx, id)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &rt, nil
+}
+
 func (r *refreshTokenRepository) Delete(ctx context.Context, token string) error {
 	return r.q.DeleteRefreshToken(ctx, token)
 }
@@ -44,3 +56,9 @@ func (r *refreshTokenRepository) Delete(ctx context.Context, token string) error
 func (r *refreshTokenRepository) DeleteByUserID(ctx context.Context, userID int64) error {
 	return r.q.DeleteRefreshTokensByUserID(ctx, userID)
 }
+
+// ListActiveByUserID returns the current, unexpired token of each of the user's token
+// families, most recently refreshed first. Each family is one signed-in session.
+func (r *refreshTokenRepository) ListActiveByUserID(ctx context.Context, userID int64) ([]sqlc.ListActiveRefreshTokensByUserIDRow, error) {
+	return r.q.ListActiveRefreshTokensByUserID(ctx, userID)
+}
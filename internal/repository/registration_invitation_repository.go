@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+type RegistrationInvitationRepository interface {
+	Upsert(ctx context.Context, params sqlc.UpsertRegistrationInvitationParams) (*sqlc.RegistrationInvitation, error)
+	GetByToken(ctx context.Context, token string) (*sqlc.RegistrationInvitation, error)
+	GetByEmail(ctx context.Context, email string) (*sqlc.RegistrationInvitation, error)
+	List(ctx context.Context, limit, offset int32) ([]sqlc.RegistrationInvitation, error)
+	Count(ctx context.Context) (int64, error)
+	Delete(ctx context.Context, id int64) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type registrationInvitationRepository struct {
+	q *sqlc.Queries
+}
+
+func NewRegistrationInvitationRepository(db sqlc.DBTX) RegistrationInvitationRepository {
+	return &registrationInvitationRepository{q: sqlc.New(db)}
+}
+
+// Upsert creates an invitation, replacing any pending one for the same address.
+func (r *registrationInvitationRepository) Upsert(
+	ctx context.Context,
+	params sqlc.UpsertRegistrationInvitationParams,
+) (*sqlc.RegistrationInvitation, error) {
+	inv, err := r.q.UpsertRegistrationInvitation(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &inv, nil
+}
+
+func (r *registrationInvitationRepository) GetByToken(ctx context.Context, token string) (*sqlc.RegistrationInvitation, error) {
+	inv, err := r.q.GetRegistrationInvitationByToken(ctx, token)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &inv, nil
+}
+
+func (r *registrationInvitationRepository) GetByEmail(ctx context.Context, email string) (*sqlc.RegistrationInvitation, error) {
+	inv, err := r.q.GetRegistrationInvitationByEmail(ctx, email)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &inv, nil
+}
+
+func (r *registrationInvitationRepository) List(ctx context.Context, limit, offset int32) ([]sqlc.RegistrationInvitation, error) {
+	return r.q.ListRegistrationInvitations(ctx, sqlc.ListRegistrationInvitationsParams{
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+func (r *registrationInvitationRepository) Count(ctx context.Context) (int64, error) {
+	return r.q.CountRegistrationInvitations(ctx)
+}
+
+// Delete removes an invitation, returning apperror.ErrNotFound if it does not exist.
+func (r *registrationInvitationRepository) Delete(ctx context.Context, id int64) error {
+	n, err := r.q.DeleteRegistrationInvitation(ctx, id)
+	if err != nil {
+		return wrapErr(err)
+	}
+	if n == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteExpired removes invitations past their expiry and returns how many were deleted.
+func (r *registrationInvitationRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	return r.q.DeleteExpiredRegistrationInvitations(ctx)
+}
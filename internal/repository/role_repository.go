@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+type RoleRepository interface {
+	List(ctx context.Context) ([]sqlc.ListRolesRow, error)
+	Create(ctx context.Context, name, description string) (*sqlc.Role, error)
+	DeleteCustom(ctx context.Context, id int64) error
+	AddPermissions(ctx context.Context, roleID int64, permissions []string) (int64, error)
+	ListPermissions(ctx context.Context) ([]sqlc.Permission, error)
+	ListUserPermissions(ctx context.Context, userID int64) ([]string, error)
+	ListUserRoles(ctx context.Context, userID int64) ([]string, error)
+	ReplaceUserRoles(ctx context.Context, userID int64, roles []string) (int64, error)
+}
+
+type roleRepository struct {
+	q *sqlc.Queries
+}
+
+func NewRoleRepository(db sqlc.DBTX) RoleRepository {
+	return &roleRepository{q: sqlc.New(db)}
+}
+
+func (r *roleRepository) List(ctx context.Context) ([]sqlc.ListRolesRow, error) {
+	return r.q.ListRoles(ctx)
+}
+
+func (r *roleRepository) Create(ctx context.Context, name, description string) (*sqlc.Role, error) {
+	role, err := r.q.CreateRole(ctx, sqlc.CreateRoleParams{Name: name, Description: description})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &role, nil
+}
+
+// DeleteCustom deletes a custom role. System roles are reported as not found.
+func (r *roleRepository) DeleteCustom(ctx context.Context, id int64) error {
+	n, err := r.q.DeleteCustomRole(ctx, id)
+	if err != nil {
+		return wrapErr(err)
+	}
+	if n == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+// AddPermissions grants the named permissions to a role and returns how many were
+// granted; unknown names are skipped.
+func (r *roleRepository) AddPermissions(ctx context.Context, roleID int64, permissions []string) (int64, error) {
+	return r.q.AddRolePermissions(ctx, sqlc.AddRolePermissionsParams{RoleID: roleID, Permissions: permissions})
+}
+
+func (r *roleRepository) ListPermissions(ctx context.Context) ([]sqlc.Permission, error) {
+	return r.q.ListPermissions(ctx)
+}
+
+// ListUserPermissions returns the permissions of the user's built-in role and of
+// every custom role assigned to them.
+func (r *roleRepository) ListUserPermissions(ctx context.Context, userID int64) ([]string, error) {
+	return r.q.ListUserPermissions(ctx, userID)
+}
+
+func (r *roleRepository) ListUserRoles(ctx context.Context, userID int64) ([]string, error) {
+	return r.q.ListUserRoleNames(ctx, userID)
+}
+
+// ReplaceUserRoles swaps the user's custom roles for the named ones and returns how
+// many were assigned; unknown and system role names are skipped. Run it inside a
+// transaction so a failed assignment does not leave the user without roles.
+func (r *roleRepository) ReplaceUserRoles(ctx context.Context, userID int64, roles []string) (int64, error) {
+	if err := r.q.DeleteUserRoles(ctx, userID); err != nil {
+		return 0, err
+	}
+	if len(roles) == 0 {
+		return 0, nil
+	}
+	return r.q.AddUserRoles(ctx, sqlc.AddUserRolesParams{UserID: userID, Roles: roles})
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+)
+
+type SettingRepository interface {
+	List(ctx context.Context) ([]sqlc.Setting, error)
+	Upsert(ctx context.Context, key string, value []byte) (*sqlc.Setting, error)
+}
+
+type settingRepository struct {
+	q *sqlc.Queries
+}
+
+func NewSettingRepository(db sqlc.DBTX) SettingRepository {
+	return &settingRepository{q: sqlc.New(db)}
+}
+
+func (r *settingRepository) List(ctx context.Context) ([]sqlc.Setting, error) {
+	return r.q.ListSettings(ctx)
+}
+
+// Upsert stores the JSON value of a setting, creating its row on first change.
+func (r *settingRepository) Upsert(ctx context.Context, key string, value []byte) (*sqlc.Setting, error) {
+	setting, err := r.q.UpsertSetting(ctx, sqlc.UpsertSettingParams{Key: key, Value: value})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &setting, nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+)
+
+type TwoFactorRepository interface {
+	// SaveSecret stores a pending TOTP secret, replacing a pending one. It returns
+	// apperror.ErrNotFound when two-factor authentication is already enabled.
+	SaveSecret(ctx context.Context, userID int64, secret string) (*sqlc.UserTotp, error)
+	Get(ctx context.Context, userID int64) (*sqlc.UserTotp, error)
+	Enable(ctx context.Context, userID int64) (*sqlc.UserTotp, error)
+	// ReplaceRecoveryCodes deletes the user's recovery codes and stores the given hashes.
+	// Run it in a transaction so a failure keeps the old codes.
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, hashes []string) error
+	// UseRecoveryCode marks an unused code as used and reports whether there was one.
+	UseRecoveryCode(ctx context.Context, userID int64, hash string) (bool, error)
+	CountUnusedRecoveryCodes(ctx context.Context, userID int64) (int64, error)
+	// DeleteByUserID removes the user's TOTP secret and recovery codes.
+	DeleteByUserID(ctx context.Context, userID int64) error
+}
+
+type twoFactorRepository struct {
+	q *sqlc.Queries
+}
+
+func NewTwoFactorRepository(db sqlc.DBTX) TwoFactorRepository {
+	return &twoFactorRepository{q: sqlc.New(db)}
+}
+
+func (r *twoFactorRepository) SaveSecret(ctx context.Context, userID int64, secret string) (*sqlc.UserTotp, error) {
+	totp, err := r.q.SaveTOTPSecret(ctx, sqlc.SaveTOTPSecretParams{UserID: userID, Secret: secret})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &totp, nil
+}
+
+func (r *twoFactorRepository) Get(ctx context.Context, userID int64) (*sqlc.UserTotp, error) {
+	totp, err := r.q.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &totp, nil
+}
+
+func (r *twoFactorRepository) Enable(ctx context.Context, userID int64) (*sqlc.UserTotp, error) {
+	totp, err := r.q.EnableTOTP(ctx, userID)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &totp, nil
+}
+
+func (r *twoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID int64, hashes []string) error {
+	if err := r.q.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return err
+	}
+	return r.q.CreateRecoveryCodes(ctx, sqlc.CreateRecoveryCodesParams{UserID: userID, CodeHashes: hashes})
+}
+
+func (r *twoFactorRepository) UseRecoveryCode(ctx context.Context, userID int64, hash string) (bool, error) {
+	n, err := r.q.UseRecoveryCode(ctx, sqlc.UseRecoveryCodeParams{UserID: userID, CodeHash: hash})
+	return n > 0, err
+}
+
+func (r *twoFactorRepository) CountUnusedRecoveryCodes(ctx context.Context, userID int64) (int64, error) {
+	return r.q.CountUnusedRecoveryCodes(ctx, userID)
+}
+
+func (r *twoFactorRepository) DeleteByUserID(ctx context.Context, userID int64) error {
+	if err := r.q.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return err
+	}
+	return r.q.DeleteTOTP(ctx, userID)
+}
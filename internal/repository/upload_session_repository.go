@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+type UploadSessionRepository interface {
+	Create(ctx context.Context, params sqlc.CreateUploadSessionParams) (*sqlc.UploadSession, error)
+	GetByID(ctx context.Context, id int64) (*sqlc.UploadSession, error)
+	Advance(ctx context.Context, params sqlc.AdvanceUploadSessionParams) (*sqlc.UploadSession, error)
+	CreatePart(ctx context.Context, params sqlc.CreateUploadSessionPartParams) error
+	ListParts(ctx context.Context, sessionID int64) ([]sqlc.UploadSessionPart, error)
+	Delete(ctx context.Context, id int64) error
+	ListExpired(ctx context.Context, limit int32) ([]sqlc.UploadSession, error)
+}
+
+type uploadSessionRepository struct {
+	q *sqlc.Queries
+}
+
+func NewUploadSessionRepository(db sqlc.DBTX) UploadSessionRepository {
+	return &uploadSessionRepository{q: sqlc.New(db)}
+}
+
+func (r *uploadSessionRepository) Create(ctx context.Context, params sqlc.CreateUploadSessionParams) (*sqlc.UploadSession, error) {
+	session, err := r.q.CreateUploadSession(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &session, nil
+}
+
+func (r *uploadSessionRepository) GetByID(ctx context.Context, id int64) (*sqlc.UploadSession, error) {
+	session, err := r.q.GetUploadSessionByID(ctx, id)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &session, nil
+}
+
+// Advance moves the session past a received chunk. It returns apperror.ErrNotFound if the
+// session no longer exists or is not at params.Received anymore.
+func (r *uploadSessionRepository) Advance(ctx context.Context, params sqlc.AdvanceUploadSessionParams) (*sqlc.UploadSession, error) {
+	session, err := r.q.AdvanceUploadSession(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &session, nil
+}
+
+func (r *uploadSessionRepository) CreatePart(ctx context.Context, params sqlc.CreateUploadSessionPartParams) error {
+	return wrapErr(r.q.CreateUploadSessionPart(ctx, params))
+}
+
+func (r *uploadSessionRepository) ListParts(ctx context.Context, sessionID int64) ([]sqlc.UploadSessionPart, error) {
+	return r.q.ListUploadSessionParts(ctx, sessionID)
+}
+
+// Delete removes a session and its part records, returning apperror.ErrNotFound if it
+// does not exist.
+func (r *uploadSessionRepository) Delete(ctx context.Context, id int64) error {
+	n, err := r.q.DeleteUploadSession(ctx, id)
+	if err != nil {
+		return wrapErr(err)
+	}
+	if n == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+func (r *uploadSessionRepository) ListExpired(ctx context.Context, limit int32) ([]sqlc.UploadSession, error) {
+	return r.q.ListExpiredUploadSessions(ctx, limit)
+}
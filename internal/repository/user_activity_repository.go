@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+)
+
+type UserActivityRepository interface {
+	Create(ctx context.Context, params sqlc.CreateUserActivityParams) (*sqlc.UserActivity, error)
+	ListByUserID(ctx context.Context, userID int64, limit, offset int32) ([]sqlc.UserActivity, error)
+	CountByUserID(ctx context.Context, userID int64) (int64, error)
+	AnonymizeByUserID(ctx context.Context, userID int64) error
+}
+
+type userActivityRepository struct {
+	q *sqlc.Queries
+}
+
+func NewUserActivityRepository(db sqlc.DBTX) UserActivityRepository {
+	return &userActivityRepository{q: sqlc.New(db)}
+}
+
+func (r *userActivityRepository) Create(ctx context.Context, params sqlc.CreateUserActivityParams) (*sqlc.UserActivity, error) {
+	activity, err := r.q.CreateUserActivity(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &activity, nil
+}
+
+func (r *userActivityRepository) ListByUserID(ctx context.Context, userID int64, limit, offset int32) ([]sqlc.UserActivity, error) {
+	return r.q.ListUserActivitiesByUserID(ctx, sqlc.ListUserActivitiesByUserIDParams{
+		UserID: userID,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+func (r *userActivityRepository) CountByUserID(ctx context.Context, userID int64) (int64, error) {
+	return r.q.CountUserActivitiesByUserID(ctx, userID)
+}
+
+// AnonymizeByUserID clears the IP addresses recorded on the user's activities.
+func (r *userActivityRepository) AnonymizeByUserID(ctx context.Context, userID int64) error {
+	return r.q.AnonymizeUserActivitiesByUserID(ctx, userID)
+}
@@ -2,32 +2,70 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
 )
 
 type UserRepository interface {
 	GetByID(ctx context.Context, id int64) (*sqlc.User, error)
+	GetDetail(ctx context.Context, id int64) (*sqlc.GetUserDetailRow, error)
 	GetByEmail(ctx context.Context, email string) (*sqlc.User, error)
 	GetByGoogleID(ctx context.Context, googleID string) (*sqlc.User, error)
-	List(ctx context.Context, limit, offset int32) ([]sqlc.User, error)
-	Count(ctx context.Context) (int64, error)
+	GetByGitHubID(ctx context.Context, githubID string) (*sqlc.User, error)
+	GetBySAMLID(ctx context.Context, samlID string) (*sqlc.User, error)
+	GetByUsername(ctx context.Context, username string) (*sqlc.User, error)
+	List(ctx context.Context, filter UserFilter, limit, offset int32) ([]sqlc.User, error)
+	Count(ctx context.Context, filter UserFilter) (int64, error)
+	CountActiveAdminsForUpdate(ctx context.Context) (int64, error)
+	ListAfter(ctx context.Context, filter UserFilter, afterID int64, limit int32) ([]sqlc.User, error)
 	Create(ctx context.Context, params sqlc.CreateUserParams) (*sqlc.User, error)
 	CreateOAuthUser(ctx context.Context, params sqlc.CreateOAuthUserParams) (*sqlc.User, error)
+	CreateGuest(ctx context.Context, params sqlc.CreateGuestUserParams) (*sqlc.User, error)
+	UpgradeGuest(ctx context.Context, params sqlc.UpgradeGuestUserParams) (*sqlc.User, error)
 	Update(ctx context.Context, params sqlc.UpdateUserParams) (*sqlc.User, error)
+	GetMetadata(ctx context.Context, id int64) (map[string]any, error)
+	MergeMetadata(ctx context.Context, id int64, set map[string]any, remove []string) (*sqlc.User, error)
 	UpdatePassword(ctx context.Context, params sqlc.UpdateUserPasswordParams) (*sqlc.User, error)
 	UpdateRole(ctx context.Context, params sqlc.UpdateUserRoleParams) (*sqlc.User, error)
 	VerifyEmail(ctx context.Context, id int64) (*sqlc.User, error)
+	UpdateEmail(ctx context.Context, params sqlc.UpdateUserEmailParams) (*sqlc.User, error)
 	LinkGoogleAccount(ctx context.Context, params sqlc.LinkGoogleAccountParams) (*sqlc.User, error)
+	LinkGitHubAccount(ctx context.Context, params sqlc.LinkGitHubAccountParams) (*sqlc.User, error)
+	LinkSAMLAccount(ctx context.Context, params sqlc.LinkSAMLAccountParams) (*sqlc.User, error)
+	Ban(ctx context.Context, id int64, reason string) (*sqlc.User, error)
+	Unban(ctx context.Context, id int64) (*sqlc.User, error)
 	Delete(ctx context.Context, id int64) (*sqlc.User, error)
 	Restore(ctx context.Context, id int64) (*sqlc.User, error)
-	AdminList(ctx context.Context, limit, offset int32) ([]sqlc.User, error)
-	AdminCount(ctx context.Context) (int64, error)
+	Purge(ctx context.Context, id int64) error
+	ListDeletedBefore(ctx context.Context, before time.Time, limit int32) ([]int64, error)
+	Anonymize(ctx context.Context, id int64, email, name string) (*sqlc.User, error)
 	GetSystemStats(ctx context.Context) (sqlc.GetSystemStatsRow, error)
+	GetDailyStats(ctx context.Context, from, to time.Time) ([]sqlc.GetDailyStatsRow, error)
+	Search(ctx context.Context, term string, limit int32) ([]sqlc.SearchUsersRow, error)
 }
 
+// UserFilter narrows and orders user listings. Zero values disable the corresponding filter.
+type UserFilter struct {
+	Search         string // case-insensitive substring of name or email
+	Role           string
+	EmailVerified  *bool
+	Banned         *bool
+	CreatedAfter   time.Time // inclusive
+	CreatedBefore  time.Time // exclusive
+	SortBy         string    // id, name, email or created_at; defaults to id
+	SortDesc       bool
+	IncludeDeleted bool
+}
+
+// likeEscaper escapes LIKE wildcards so search terms match literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
 type userRepository struct {
 	q *sqlc.Queries
 }
@@ -62,15 +100,100 @@ func (r *userRepository) GetByGoogleID(ctx context.Context, googleID string) (*s
 	return &user, nil
 }
 
-func (r *userRepository) List(ctx context.Context, limit, offset int32) ([]sqlc.User, error) {
+func (r *userRepository) GetByGitHubID(ctx context.Context, githubID string) (*sqlc.User, error) {
+	user, err := r.q.GetUserByGitHubID(ctx, pgtype.Text{String: githubID, Valid: true})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &user, nil
+}
+
+func (r *userRepository) GetBySAMLID(ctx context.Context, samlID string) (*sqlc.User, error) {
+	user, err := r.q.GetUserBySAMLID(ctx, pgtype.Text{String: samlID, Valid: true})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &user, nil
+}
+
+func (r *userRepository) GetByUsername(ctx context.Context, username string) (*sqlc.User, error) {
+	user, err := r.q.GetUserByUsername(ctx, pgtype.Text{String: username, Valid: true})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &user, nil
+}
+
+func (r *userRepository) List(ctx context.Context, filter UserFilter, limit, offset int32) ([]sqlc.User, error) {
+	p := filter.params()
 	return r.q.ListUsers(ctx, sqlc.ListUsersParams{
-		Limit:  limit,
-		Offset: offset,
+		IncludeDeleted: p.IncludeDeleted,
+		Search:         p.Search,
+		Role:           p.Role,
+		EmailVerified:  p.EmailVerified,
+		Banned:         p.Banned,
+		CreatedAfter:   p.CreatedAfter,
+		CreatedBefore:  p.CreatedBefore,
+		SortBy:         filter.SortBy,
+		SortDesc:       filter.SortDesc,
+		Limit:          limit,
+		Offset:         offset,
 	})
 }
 
-func (r *userRepository) Count(ctx context.Context) (int64, error) {
-	return r.q.CountUsers(ctx)
+func (r *userRepository) Count(ctx context.Context, filter UserFilter) (int64, error) {
+	return r.q.CountUsers(ctx, filter.params())
+}
+
+// CountActiveAdminsForUpdate counts the admins who are neither banned nor deleted and locks
+// their rows until the transaction ends.
+func (r *userRepository) CountActiveAdminsForUpdate(ctx context.Context) (int64, error) {
+	ids, err := r.q.LockActiveAdmins(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(ids)), nil
+}
+
+// ListAfter returns up to limit users with IDs greater than afterID in ID order, ignoring the
+// filter's sort. Passing the last returned ID back in walks the full result set as a keyset cursor.
+func (r *userRepository) ListAfter(ctx context.Context, filter UserFilter, afterID int64, limit int32) ([]sqlc.User, error) {
+	p := filter.params()
+	return r.q.ListUsersAfter(ctx, sqlc.ListUsersAfterParams{
+		IncludeDeleted: p.IncludeDeleted,
+		Search:         p.Search,
+		Role:           p.Role,
+		EmailVerified:  p.EmailVerified,
+		Banned:         p.Banned,
+		CreatedAfter:   p.CreatedAfter,
+		CreatedBefore:  p.CreatedBefore,
+		AfterID:        afterID,
+		Limit:          limit,
+	})
+}
+
+// params converts the filter into query arguments, escaping LIKE wildcards in the search term.
+func (f UserFilter) params() sqlc.CountUsersParams {
+	p := sqlc.CountUsersParams{IncludeDeleted: f.IncludeDeleted}
+	if f.Search != "" {
+		p.Search = pgtype.Text{String: likeEscaper.Replace(f.Search), Valid: true}
+	}
+	if f.Role != "" {
+		p.Role = pgtype.Text{String: f.Role, Valid: true}
+	}
+	if f.EmailVerified != nil {
+		p.EmailVerified = pgtype.Bool{Bool: *f.EmailVerified, Valid: true}
+	}
+	if f.Banned != nil {
+		p.Banned = pgtype.Bool{Bool: *f.Banned, Valid: true}
+	}
+	if !f.CreatedAfter.IsZero() {
+		p.CreatedAfter = pgtype.Timestamptz{Time: f.CreatedAfter, Valid: true}
+	}
+	if !f.CreatedBefore.IsZero() {
+		p.CreatedBefore = pgtype.Timestamptz{Time: f.CreatedBefore, Valid: true}
+	}
+	return p
 }
 
 func (r *userRepository) Create(ctx context.Context, params sqlc.CreateUserParams) (*sqlc.User, error) {
@@ -89,6 +212,22 @@ func (r *userRepository) CreateOAuthUser(ctx context.Context, params sqlc.Create
 	return &user, nil
 }
 
+func (r *userRepository) CreateGuest(ctx context.Context, params sqlc.CreateGuestUserParams) (*sqlc.User, error) {
+	user, err := r.q.CreateGuestUser(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &user, nil
+}
+
+func (r *userRepository) UpgradeGuest(ctx context.Context, params sqlc.UpgradeGuestUserParams) (*sqlc.User, error) {
+	user, err := r.q.UpgradeGuestUser(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &user, nil
+}
+
 func (r *userRepository) Update(ctx context.Context, params sqlc.UpdateUserParams) (*sqlc.User, error) {
 	user, err := r.q.UpdateUser(ctx, params)
 	if err != nil {
@@ -97,6 +236,51 @@ func (r *userRepository) Update(ctx context.Context, params sqlc.UpdateUserParam
 	return &user, nil
 }
 
+func (r *userRepository) GetMetadata(ctx context.Context, id int64) (map[string]any, error) {
+	raw, err := r.q.GetUserMetadata(ctx, id)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return DecodeMetadata(raw)
+}
+
+// MergeMetadata sets the given top-level keys and removes the listed ones in a single
+// statement; keys not mentioned keep their stored values.
+func (r *userRepository) MergeMetadata(ctx context.Context, id int64, set map[string]any, remove []string) (*sqlc.User, error) {
+	if set == nil {
+		set = map[string]any{}
+	}
+	if remove == nil {
+		remove = []string{}
+	}
+	patch, err := json.Marshal(set)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := r.q.MergeUserMetadata(ctx, sqlc.MergeUserMetadataParams{
+		ID:         id,
+		Patch:      patch,
+		RemoveKeys: remove,
+	})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &user, nil
+}
+
+// DecodeMetadata converts a stored metadata document into a map. Empty input yields an empty map.
+func DecodeMetadata(raw []byte) (map[string]any, error) {
+	m := map[string]any{}
+	if len(raw) == 0 {
+		return m, nil
+	}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (r *userRepository) LinkGoogleAccount(ctx context.Context, params sqlc.LinkGoogleAccountParams) (*sqlc.User, error) {
 	user, err := r.q.LinkGoogleAccount(ctx, params)
 	if err != nil {
@@ -105,6 +289,22 @@ func (r *userRepository) LinkGoogleAccount(ctx context.Context, params sqlc.Link
 	return &user, nil
 }
 
+func (r *userRepository) LinkGitHubAccount(ctx context.Context, params sqlc.LinkGitHubAccountParams) (*sqlc.User, error) {
+	user, err := r.q.LinkGitHubAccount(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &user, nil
+}
+
+func (r *userRepository) LinkSAMLAccount(ctx context.Context, params sqlc.LinkSAMLAccountParams) (*sqlc.User, error) {
+	user, err := r.q.LinkSAMLAccount(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &user, nil
+}
+
 func (r *userRepository) UpdatePassword(ctx context.Context, params sqlc.UpdateUserPasswordParams) (*sqlc.User, error) {
 	user, err := r.q.UpdateUserPassword(ctx, params)
 	if err != nil {
@@ -129,6 +329,34 @@ func (r *userRepository) VerifyEmail(ctx context.Context, id int64) (*sqlc.User,
 	return &user, nil
 }
 
+func (r *userRepository) UpdateEmail(ctx context.Context, params sqlc.UpdateUserEmailParams) (*sqlc.User, error) {
+	user, err := r.q.UpdateUserEmail(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &user, nil
+}
+
+// Ban marks an active user banned, returning apperror.ErrNotFound if the user does not exist,
+// is deleted or is already banned.
+func (r *userRepository) Ban(ctx context.Context, id int64, reason string) (*sqlc.User, error) {
+	user, err := r.q.BanUser(ctx, sqlc.BanUserParams{ID: id, BanReason: reason})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &user, nil
+}
+
+// Unban lifts a ban, returning apperror.ErrNotFound if the user does not exist, is deleted or
+// is not banned.
+func (r *userRepository) Unban(ctx context.Context, id int64) (*sqlc.User, error) {
+	user, err := r.q.UnbanUser(ctx, id)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &user, nil
+}
+
 func (r *userRepository) Delete(ctx context.Context, id int64) (*sqlc.User, error) {
 	user, err := r.q.DeleteUser(ctx, id)
 	if err != nil {
@@ -145,17 +373,61 @@ func (r *userRepository) Restore(ctx context.Context, id int64) (*sqlc.User, err
 	return &user, nil
 }
 
-func (r *userRepository) AdminList(ctx context.Context, limit, offset int32) ([]sqlc.User, error) {
-	return r.q.AdminListUsers(ctx, sqlc.AdminListUsersParams{
-		Limit:  limit,
-		Offset: offset,
+// Purge permanently removes the user row, soft-deleted or not; dependent rows are removed by
+// ON DELETE CASCADE.
+func (r *userRepository) Purge(ctx context.Context, id int64) error {
+	n, err := r.q.PurgeUser(ctx, id)
+	if err != nil {
+		return wrapErr(err)
+	}
+	if n == 0 {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+// ListDeletedBefore returns the IDs of users soft-deleted before the given time, oldest first.
+func (r *userRepository) ListDeletedBefore(ctx context.Context, before time.Time, limit int32) ([]int64, error) {
+	return r.q.ListUserIDsDeletedBefore(ctx, sqlc.ListUserIDsDeletedBeforeParams{
+		DeletedAt: pgtype.Timestamptz{Time: before, Valid: true},
+		Limit:     limit,
 	})
 }
 
-func (r *userRepository) AdminCount(ctx context.Context) (int64, error) {
-	return r.q.AdminCountUsers(ctx)
+// Anonymize replaces the user's identifying fields with the given placeholders, clears
+// credentials, linked identities and metadata, and soft-deletes the account.
+func (r *userRepository) Anonymize(ctx context.Context, id int64, email, name string) (*sqlc.User, error) {
+	user, err := r.q.AnonymizeUser(ctx, sqlc.AnonymizeUserParams{ID: id, Email: email, Name: name})
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &user, nil
+}
+
+// GetDetail returns a user, including a soft-deleted one, with their session and file
+// counts and latest successful sign-in.
+func (r *userRepository) GetDetail(ctx context.Context, id int64) (*sqlc.GetUserDetailRow, error) {
+	detail, err := r.q.GetUserDetail(ctx, id)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &detail, nil
 }
 
 func (r *userRepository) GetSystemStats(ctx context.Context) (sqlc.GetSystemStatsRow, error) {
 	return r.q.GetSystemStats(ctx)
 }
+
+// GetDailyStats returns one row per UTC day from the day of from to the day of to, inclusive.
+func (r *userRepository) GetDailyStats(ctx context.Context, from, to time.Time) ([]sqlc.GetDailyStatsRow, error) {
+	return r.q.GetDailyStats(ctx, sqlc.GetDailyStatsParams{
+		StartDay: pgtype.Date{Time: from.UTC(), Valid: true},
+		EndDay:   pgtype.Date{Time: to.UTC(), Valid: true},
+	})
+}
+
+// Search returns up to limit users, including deleted ones, whose email or name contains
+// term, best matches first. LIKE wildcards in term match literally.
+func (r *userRepository) Search(ctx context.Context, term string, limit int32) ([]sqlc.SearchUsersRow, error) {
+	return r.q.SearchUsers(ctx, sqlc.SearchUsersParams{Pattern: likeEscaper.Replace(term), Limit: limit})
+}
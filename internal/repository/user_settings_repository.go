@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+)
+
+type UserSettingsRepository interface {
+	Get(ctx context.Context, userID int64) (*sqlc.UserSetting, error)
+	Upsert(ctx context.Context, params sqlc.UpsertUserSettingsParams) (*sqlc.UserSetting, error)
+}
+
+type userSettingsRepository struct {
+	q *sqlc.Queries
+}
+
+func NewUserSettingsRepository(db sqlc.DBTX) UserSettingsRepository {
+	return &userSettingsRepository{q: sqlc.New(db)}
+}
+
+func (r *userSettingsRepository) Get(ctx context.Context, userID int64) (*sqlc.UserSetting, error) {
+	settings, err := r.q.GetUserSettings(ctx, userID)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &settings, nil
+}
+
+// Upsert creates the user's settings or updates the fields that are set in params,
+// leaving null fields at their current (or default) values.
+func (r *userSettingsRepository) Upsert(ctx context.Context, params sqlc.UpsertUserSettingsParams) (*sqlc.UserSetting, error) {
+	settings, err := r.q.UpsertUserSettings(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &settings, nil
+}
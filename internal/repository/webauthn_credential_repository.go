@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+)
+
+type WebAuthnCredentialRepository interface {
+	Create(ctx context.Context, params sqlc.CreateWebAuthnCredentialParams) (*sqlc.WebauthnCredential, error)
+	GetByCredentialID(ctx context.Context, credentialID []byte) (*sqlc.WebauthnCredential, error)
+	ListByUserID(ctx context.Context, userID int64) ([]sqlc.WebauthnCredential, error)
+	UpdateUsage(ctx context.Context, credentialID, credential []byte) error
+	DeleteByUserID(ctx context.Context, userID int64) error
+}
+
+type webAuthnCredentialRepository struct {
+	q *sqlc.Queries
+}
+
+func NewWebAuthnCredentialRepository(db sqlc.DBTX) WebAuthnCredentialRepository {
+	return &webAuthnCredentialRepository{q: sqlc.New(db)}
+}
+
+func (r *webAuthnCredentialRepository) Create(ctx context.Context, params sqlc.CreateWebAuthnCredentialParams) (*sqlc.WebauthnCredential, error) {
+	cred, err := r.q.CreateWebAuthnCredential(ctx, params)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &cred, nil
+}
+
+func (r *webAuthnCredentialRepository) GetByCredentialID(ctx context.Context, credentialID []byte) (*sqlc.WebauthnCredential, error) {
+	cred, err := r.q.GetWebAuthnCredentialByCredentialID(ctx, credentialID)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	return &cred, nil
+}
+
+func (r *webAuthnCredentialRepository) ListByUserID(ctx context.Context, userID int64) ([]sqlc.WebauthnCredential, error) {
+	return r.q.ListWebAuthnCredentialsByUserID(ctx, userID)
+}
+
+func (r *webAuthnCredentialRepository) UpdateUsage(ctx context.Context, credentialID, credential []byte) error {
+	return r.q.UpdateWebAuthnCredentialUsage(ctx, sqlc.UpdateWebAuthnCredentialUsageParams{
+		Credential:   credential,
+		CredentialID: credentialID,
+	})
+}
+
+func (r *webAuthnCredentialRepository) DeleteByUserID(ctx context.Context, userID int64) error {
+	return r.q.DeleteWebAuthnCredentialsByUserID(ctx, userID)
+}
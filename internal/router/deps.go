@@ -5,15 +5,37 @@ import (
 
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/handler"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/middleware"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/errorreport"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/health"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/realtime"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/token"
 )
 
 type Deps struct {
-	AuthHandler   *handler.AuthHandler
-	UserHandler   *handler.UserHandler
-	UploadHandler *handler.UploadHandler
-	AdminHandler  *handler.AdminHandler
-	Config        *config.Config
-	Pool          *pgxpool.Pool
-	Health        *health.Checker
+	AuthHandler             *handler.AuthHandler
+	TwoFactorHandler        *handler.TwoFactorHandler
+	UserHandler             *handler.UserHandler
+	UploadHandler           *handler.UploadHandler
+	AdminHandler            *handler.AdminHandler
+	OrgHandler              *handler.OrganizationHandler
+	FolderHandler           *handler.FolderHandler
+	FlagHandler             *handler.FeatureFlagHandler
+	BroadcastHandler        *handler.BroadcastHandler
+	EmailHandler            *handler.EmailOutboxHandler
+	EmailSuppressionHandler *handler.EmailSuppressionHandler
+	SystemHandler           *handler.SystemHandler
+	SettingsHandler         *handler.SettingsHandler
+	BatchHandler            *handler.BatchHandler
+	RealtimeHandler         *handler.RealtimeHandler
+	Config                  *config.Config
+	Pool                    *pgxpool.Pool
+	Health                  *health.Checker
+	Revocations             *token.RevocationStore
+	WSTickets               *realtime.TicketStore
+	Cache                   cache.Cache
+	Permissions             middleware.PermissionChecker
+	Features                middleware.FeatureChecker
+	ErrorReporter           errorreport.Reporter
 }
@@ -7,34 +7,57 @@ import (
 	"github.com/gofiber/fiber/v3"
 	"github.com/gofiber/fiber/v3/middleware/adaptor"
 	"github.com/gofiber/fiber/v3/middleware/cors"
-	"github.com/gofiber/fiber/v3/middleware/static"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	_ "github.com/chuanghiduoc/fiber-golang-boilerplate/docs"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/middleware"
 )
 
 func SetupRoutes(app *fiber.App, deps Deps) {
 	cfg := deps.Config
 
-	// Serve local uploads as static files
-	if cfg.Storage.Driver == "local" {
-		app.Get("/uploads*", static.New(cfg.Storage.LocalPath))
-	}
-
 	// Global middleware
+	app.Use(middleware.ClientIP(cfg.App.TrustedProxyPrefixes(), cfg.App.ProxyHeader))
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     cfg.CORS.Origins(),
 		AllowMethods:     cfg.CORS.Methods(),
 		AllowHeaders:     cfg.CORS.Headers(),
 		AllowCredentials: cfg.CORS.AllowCredentials,
+		ExposeHeaders:    cfg.CORS.ExposedHeaders(),
 	}))
 	app.Use(middleware.SecurityHeaders(cfg.App.Env))
 	app.Use(middleware.RequestID())
+	app.Use(middleware.Locale())
+	app.Use(middleware.Tracing())
 	app.Use(middleware.Metrics())
 	app.Use(middleware.Logger())
-	app.Use(middleware.Recovery(cfg.App.Env))
-	app.Use(middleware.Timeout(time.Duration(cfg.App.RequestTimeout) * time.Second))
+	if allow, deny := cfg.IPFilter.Global(); len(allow) > 0 || len(deny) > 0 {
+		app.Use(middleware.IPFilter(allow, deny))
+	}
+	// Uploads take a file of STORAGE_MAX_FILE_SIZE. These only set the limit, which
+	// BodyLimit checks, and go on to the API routes of every version.
+	uploadLimit := middleware.RouteBodyLimit(cfg.UploadBodyLimit())
+	app.Post("/api/:version/files/upload", uploadLimit)
+	app.Put("/api/:version/files/:id/content", uploadLimit)
+	app.Use(middleware.BodyLimit(int64(cfg.App.BodyLimit), cfg.RouteBodyLimitMap()))
+	app.Use(middleware.Recovery(cfg.App.Env, deps.ErrorReporter))
+	app.Use(middleware.Compress(cfg.App.CompressionLevel, cfg.App.CompressionMinSize))
+	if routes := cfg.App.BodyLogRouteList(); len(routes) > 0 {
+		app.Use(middleware.BodyLogger(routes, cfg.App.BodyLogMaxSize, cfg.App.BodyLogRedactFieldList()))
+	}
+	// Last, so the middleware above see the timeout error of a request that ran too long
+	app.Use(middleware.Timeout(time.Duration(cfg.App.RequestTimeout)*time.Second, cfg.App.RouteTimeoutMap()))
+
+	// Local uploads, at the URLs the local driver returns, for users who can read them
+	if cfg.Storage.Driver == "local" {
+		app.Get("/uploads/*",
+			middleware.NewLimiter(deps.Cache, cfg.RateLimit.RelaxedMax, cfg.RateLimit.RelaxedWindow, limitExemptions(cfg.RateLimit)),
+			middleware.JWTAuth(cfg.JWT, deps.Revocations),
+			middleware.RequireScope(dto.ScopeFilesRead),
+			deps.UploadHandler.ServeStored,
+		)
+	}
 
 	// Swagger
 	swaggerHandler := swagger.New(swagger.Config{
@@ -49,17 +72,31 @@ func SetupRoutes(app *fiber.App, deps Deps) {
 	app.Get("/healthz", func(c fiber.Ctx) error {
 		return c.JSON(deps.Health.Liveness())
 	})
-	app.Get("/readyz", func(c fiber.Ctx) error {
-		return c.JSON(deps.Health.Readiness(c.Context()))
-	})
+	// A draining instance answers 503 so load balancers stop sending it traffic
+	readiness := func(c fiber.Ctx) error {
+		status := deps.Health.Readiness(c.Context())
+		if !status.Ready() {
+			c.Status(fiber.StatusServiceUnavailable)
+		}
+		return c.JSON(status)
+	}
+	app.Get("/readyz", readiness)
 	// Keep /health as alias for readyz (backward compat)
-	app.Get("/health", func(c fiber.Ctx) error {
-		return c.JSON(deps.Health.Readiness(c.Context()))
-	})
+	app.Get("/health", readiness)
 
 	// Prometheus metrics endpoint
 	app.Get("/metrics", adaptor.HTTPHandler(promhttp.Handler()))
 
-	// API v1
-	RegisterV1Routes(app.Group("/api/v1"), deps)
+	// WebSocket gateway, pushing real-time events to the user
+	app.Get("/ws",
+		middleware.NewLimiter(deps.Cache, cfg.RateLimit.NormalMax, cfg.RateLimit.NormalWindow, limitExemptions(cfg.RateLimit)),
+		middleware.WebSocketAuth(cfg.JWT, deps.Revocations, deps.WSTickets),
+		deps.RealtimeHandler.Connect,
+	)
+
+	// API versions
+	registerAPIVersions(app, deps)
+
+	// Batches run their requests through the routes above, so this comes last
+	deps.BatchHandler.Bind(app)
 }
@@ -7,56 +7,195 @@ import (
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/middleware"
 )
 
-func RegisterV1Routes(v1 fiber.Router, deps Deps) {
+func registerV1Routes(v1 fiber.Router, deps Deps, mw routeMiddleware) {
 	cfg := deps.Config
 
-	// Rate limiters (tiered)
-	rl := cfg.RateLimit
-	strictLimiter := middleware.NewLimiter(rl.StrictMax, rl.StrictWindow)
-	normalLimiter := middleware.NewLimiter(rl.NormalMax, rl.NormalWindow)
-	relaxedLimiter := middleware.NewLimiter(rl.RelaxedMax, rl.RelaxedWindow)
+	strictLimiter, normalLimiter, relaxedLimiter := mw.strictLimiter, mw.normalLimiter, mw.relaxedLimiter
+	userStrictLimiter, userNormalLimiter, userRelaxedLimiter := mw.userStrictLimiter, mw.userNormalLimiter, mw.userRelaxedLimiter
+	jwtAuth, registered, can := mw.jwtAuth, mw.registered, mw.can
+	usersRead, usersWrite, filesRead, filesWrite := mw.usersRead, mw.usersWrite, mw.filesRead, mw.filesWrite
+	idempotent, etag, cachedUser, cachedStats := mw.idempotent, mw.etag, mw.cachedUser, mw.cachedStats
 
 	// Auth routes (public)
 	auth := v1.Group("/auth")
 	auth.Post("/register", strictLimiter, deps.AuthHandler.Register)
 	auth.Post("/login", strictLimiter, deps.AuthHandler.Login)
+	auth.Post("/login/2fa", strictLimiter, deps.AuthHandler.LoginTwoFactor)
 	auth.Post("/refresh", normalLimiter, deps.AuthHandler.Refresh)
 	auth.Post("/logout", normalLimiter, deps.AuthHandler.Logout)
+	auth.Post("/logout-all", normalLimiter, jwtAuth, deps.AuthHandler.LogoutAll)
+	auth.Get("/password-policy", relaxedLimiter, deps.AuthHandler.PasswordPolicy)
 	auth.Post("/forgot-password", strictLimiter, deps.AuthHandler.ForgotPassword)
 	auth.Post("/reset-password", strictLimiter, deps.AuthHandler.ResetPassword)
 	auth.Post("/verify-email", normalLimiter, deps.AuthHandler.VerifyEmail)
 	auth.Post("/resend-verification", normalLimiter, deps.AuthHandler.ResendVerification)
+	auth.Post("/confirm-email-change", normalLimiter, deps.AuthHandler.ConfirmEmailChange)
+	auth.Post("/guest", strictLimiter, deps.AuthHandler.Guest)
+	auth.Post("/guest/upgrade", strictLimiter, jwtAuth, middleware.RequireRole(dto.RoleGuest), deps.AuthHandler.UpgradeGuest)
+	auth.Post("/cancel-account-deletion", normalLimiter, deps.UserHandler.CancelDeletion)
 	auth.Get("/google", normalLimiter, deps.AuthHandler.GoogleRedirect)
 	auth.Get("/google/callback", normalLimiter, deps.AuthHandler.GoogleCallback)
+	auth.Get("/github", normalLimiter, deps.AuthHandler.GitHubRedirect)
+	auth.Get("/github/callback", normalLimiter, deps.AuthHandler.GitHubCallback)
+	auth.Get("/saml/metadata", normalLimiter, deps.AuthHandler.SAMLMetadata)
+	auth.Get("/saml/login", normalLimiter, deps.AuthHandler.SAMLRedirect)
+	auth.Post("/saml/acs", normalLimiter, deps.AuthHandler.SAMLACS)
+	auth.Post("/webauthn/register/begin", normalLimiter, jwtAuth, registered, deps.AuthHandler.WebAuthnRegisterBegin)
+	auth.Post("/webauthn/register/finish", normalLimiter, jwtAuth, registered, deps.AuthHandler.WebAuthnRegisterFinish)
+	auth.Post("/webauthn/login/begin", strictLimiter, deps.AuthHandler.WebAuthnLoginBegin)
+	auth.Post("/webauthn/login/finish", strictLimiter, deps.AuthHandler.WebAuthnLoginFinish)
+	auth.Post("/2fa/setup", normalLimiter, jwtAuth, registered, deps.TwoFactorHandler.Setup)
+	auth.Post("/2fa/enable", strictLimiter, jwtAuth, registered, deps.TwoFactorHandler.Enable)
+	auth.Post("/2fa/disable", strictLimiter, jwtAuth, registered, deps.TwoFactorHandler.Disable)
+	auth.Get("/2fa/recovery-codes", relaxedLimiter, jwtAuth, registered, deps.TwoFactorHandler.RecoveryCodesStatus)
+	auth.Post("/2fa/recovery-codes", strictLimiter, jwtAuth, registered, deps.TwoFactorHandler.RegenerateRecoveryCodes)
 
 	// User routes (protected)
-	users := v1.Group("/users", middleware.JWTAuth(cfg.JWT.Secret))
-	users.Get("/me", relaxedLimiter, deps.UserHandler.GetMe)
-	users.Put("/me", normalLimiter, deps.UserHandler.UpdateMe)
-	users.Put("/me/password", normalLimiter, deps.UserHandler.ChangePassword)
-	users.Get("/:id", relaxedLimiter, deps.UserHandler.GetByID)
-	users.Get("/", relaxedLimiter, middleware.RequireRole(dto.RoleAdmin), deps.UserHandler.List)
-	users.Put("/:id", normalLimiter, deps.UserHandler.Update)
-	users.Delete("/:id", normalLimiter, deps.UserHandler.Delete)
+	users := v1.Group("/users", jwtAuth)
+	users.Get("/me", userRelaxedLimiter, usersRead, etag, deps.UserHandler.GetMe)
+	users.Put("/me", userNormalLimiter, registered, usersWrite, deps.UserHandler.UpdateMe)
+	users.Delete("/me", userNormalLimiter, registered, usersWrite, deps.UserHandler.DeleteMe)
+	users.Post("/me/erase", userStrictLimiter, registered, usersWrite, deps.UserHandler.EraseMe)
+	users.Get("/me/settings", userRelaxedLimiter, registered, usersRead, etag, deps.UserHandler.GetSettings)
+	users.Put("/me/settings", userNormalLimiter, registered, usersWrite, deps.UserHandler.UpdateSettings)
+	users.Put("/me/password", userNormalLimiter, registered, usersWrite, deps.UserHandler.ChangePassword)
+	users.Get("/me/activity", userRelaxedLimiter, registered, usersRead, etag, deps.UserHandler.ListActivity)
+	users.Get("/me/security/logins", userRelaxedLimiter, registered, usersRead, deps.UserHandler.ListLogins)
+	users.Post("/me/data-export", userStrictLimiter, registered, usersRead, idempotent, deps.UserHandler.RequestDataExport)
+	users.Get("/me/data-export", userRelaxedLimiter, registered, usersRead, etag, deps.UserHandler.GetDataExport)
+	users.Get("/me/data-export/download", userNormalLimiter, registered, usersRead, deps.UserHandler.DownloadDataExport)
+	users.Get("/by-username/:handle", userRelaxedLimiter, registered, usersRead, deps.UserHandler.GetByUsername)
+	users.Get("/:id", userRelaxedLimiter, registered, usersRead, etag, cachedUser, deps.UserHandler.GetByID)
+	users.Get("/", userRelaxedLimiter, can(dto.PermissionUsersList), usersRead, etag, deps.UserHandler.List)
+	users.Put("/:id", userNormalLimiter, registered, usersWrite, deps.UserHandler.Update)
+	users.Delete("/:id", userNormalLimiter, registered, usersWrite, deps.UserHandler.Delete)
+
+	// Upload progress (public, the signed token is the credential). Registered before the
+	// protected file routes so their authentication does not run first.
+	v1.Get("/files/uploads/:id/progress", relaxedLimiter, etag, deps.UploadHandler.UploadProgress)
 
 	// File routes (protected)
-	files := v1.Group("/files", middleware.JWTAuth(cfg.JWT.Secret))
-	files.Post("/upload", normalLimiter, deps.UploadHandler.Upload)
-	files.Get("/", relaxedLimiter, deps.UploadHandler.List)
-	files.Get("/:id", relaxedLimiter, deps.UploadHandler.GetInfo)
-	files.Get("/:id/download", relaxedLimiter, deps.UploadHandler.Download)
-	files.Delete("/:id", normalLimiter, deps.UploadHandler.Delete)
-
-	// Admin routes (protected, admin-only)
-	admin := v1.Group("/admin",
-		middleware.JWTAuth(cfg.JWT.Secret),
-		middleware.RequireRole(dto.RoleAdmin),
-		normalLimiter,
-	)
-	admin.Get("/stats", deps.AdminHandler.GetStats)
-	admin.Get("/users", deps.AdminHandler.ListUsers)
-	admin.Put("/users/:id/role", deps.AdminHandler.UpdateRole)
-	admin.Post("/users/:id/ban", deps.AdminHandler.BanUser)
-	admin.Post("/users/:id/unban", deps.AdminHandler.UnbanUser)
-	admin.Get("/files", deps.AdminHandler.ListFiles)
+	files := v1.Group("/files", jwtAuth)
+	files.Post("/upload", userNormalLimiter, filesWrite, idempotent, deps.UploadHandler.Upload)
+	files.Post("/uploads", userNormalLimiter, filesWrite, idempotent, deps.UploadHandler.CreateResumable)
+	files.Get("/uploads/:id", userRelaxedLimiter, filesWrite, deps.UploadHandler.ResumableStatus)
+	files.Patch("/uploads/:id", userRelaxedLimiter, filesWrite, deps.UploadHandler.AppendChunk)
+	files.Post("/uploads/:id/finalize", userNormalLimiter, filesWrite, deps.UploadHandler.FinalizeResumable)
+	files.Delete("/uploads/:id", userNormalLimiter, filesWrite, deps.UploadHandler.AbortResumable)
+	files.Get("/", userRelaxedLimiter, filesRead, etag, deps.UploadHandler.List)
+	files.Post("/download-zip", userNormalLimiter, filesRead, deps.UploadHandler.DownloadZip)
+	files.Get("/:id", userRelaxedLimiter, filesRead, etag, deps.UploadHandler.GetInfo)
+	files.Get("/:id/download", userRelaxedLimiter, filesRead, deps.UploadHandler.Download)
+	files.Put("/:id/name", userNormalLimiter, filesWrite, deps.UploadHandler.Rename)
+	files.Put("/:id/content", userNormalLimiter, filesWrite, deps.UploadHandler.ReplaceContent)
+	files.Get("/:id/versions", userRelaxedLimiter, filesRead, etag, deps.UploadHandler.ListVersions)
+	files.Get("/:id/versions/:version/download", userRelaxedLimiter, filesRead, deps.UploadHandler.DownloadVersion)
+	files.Post("/:id/versions/:version/restore", userNormalLimiter, filesWrite, deps.UploadHandler.RestoreVersion)
+	files.Post("/:id/move", userNormalLimiter, filesWrite, deps.UploadHandler.Move)
+	files.Put("/:id/tags", userNormalLimiter, filesWrite, deps.UploadHandler.SetTags)
+	files.Put("/:id/visibility", userNormalLimiter, registered, filesWrite, deps.UploadHandler.SetVisibility)
+	files.Get("/:id/permissions", userRelaxedLimiter, registered, filesRead, deps.UploadHandler.ListPermissions)
+	files.Put("/:id/permissions/:userId", userNormalLimiter, registered, filesWrite, deps.UploadHandler.GrantPermission)
+	files.Delete("/:id/permissions/:userId", userNormalLimiter, registered, filesWrite, deps.UploadHandler.RevokePermission)
+	files.Post("/:id/share", userNormalLimiter, registered, filesWrite, deps.UploadHandler.Share)
+	files.Get("/:id/shares", userRelaxedLimiter, registered, filesRead, etag, deps.UploadHandler.ListShares)
+	files.Delete("/:id/shares/:shareId", userNormalLimiter, registered, filesWrite, deps.UploadHandler.RevokeShare)
+	files.Delete("/:id", userNormalLimiter, filesWrite, deps.UploadHandler.Delete)
+
+	// Folder routes (protected)
+	folders := v1.Group("/folders", jwtAuth)
+	folders.Post("/", userNormalLimiter, filesWrite, idempotent, deps.FolderHandler.Create)
+	folders.Get("/", userRelaxedLimiter, filesRead, etag, deps.FolderHandler.List)
+	folders.Get("/:id", userRelaxedLimiter, filesRead, etag, deps.FolderHandler.Get)
+	folders.Put("/:id", userNormalLimiter, filesWrite, deps.FolderHandler.Rename)
+	folders.Post("/:id/move", userNormalLimiter, filesWrite, deps.FolderHandler.Move)
+	folders.Delete("/:id", userNormalLimiter, filesWrite, deps.FolderHandler.Delete)
+
+	// Tickets opening a connection to the WebSocket gateway at /ws
+	v1.Post("/realtime/ticket", jwtAuth, userNormalLimiter, deps.RealtimeHandler.IssueTicket)
+
+	// Batched requests (public, each request is authenticated on its own)
+	v1.Post("/batch", normalLimiter, deps.BatchHandler.Execute)
+
+	// Feature flags (public, so clients can toggle UI before signing in)
+	v1.Get("/features", relaxedLimiter, etag, deps.FlagHandler.GetFeatures)
+
+	// Share links (public, the token is the credential)
+	v1.Get("/shared/:token", strictLimiter, deps.UploadHandler.SharedDownload)
+
+	// Email provider bounce and complaint webhooks (public, authenticated with EMAIL_WEBHOOK_SECRET)
+	v1.Post("/webhooks/email/:provider", relaxedLimiter, deps.EmailSuppressionHandler.Webhook)
+
+	// Organization routes (protected, registered users only)
+	orgs := v1.Group("/orgs", jwtAuth, registered)
+	orgs.Post("/invitations/accept", userNormalLimiter, usersWrite, deps.OrgHandler.AcceptInvitation)
+	orgs.Post("/", userNormalLimiter, usersWrite, idempotent, deps.OrgHandler.Create)
+	orgs.Get("/", userRelaxedLimiter, usersRead, etag, deps.OrgHandler.List)
+	orgs.Get("/:id", userRelaxedLimiter, usersRead, etag, deps.OrgHandler.Get)
+	orgs.Put("/:id", userNormalLimiter, usersWrite, deps.OrgHandler.Update)
+	orgs.Delete("/:id", userNormalLimiter, usersWrite, deps.OrgHandler.Delete)
+	orgs.Get("/:id/members", userRelaxedLimiter, usersRead, etag, deps.OrgHandler.ListMembers)
+	orgs.Post("/:id/members", userNormalLimiter, usersWrite, deps.OrgHandler.InviteMember)
+	orgs.Put("/:id/members/:userId", userNormalLimiter, usersWrite, deps.OrgHandler.UpdateMemberRole)
+	orgs.Delete("/:id/members/:userId", userNormalLimiter, usersWrite, deps.OrgHandler.RemoveMember)
+
+	// Admin routes (protected, each gated by a permission granted through roles, and
+	// optionally restricted to ADMIN_IP_ALLOW_LIST)
+	admin := v1.Group("/admin")
+	if allow, deny := cfg.IPFilter.Admin(); len(allow) > 0 || len(deny) > 0 {
+		admin.Use(middleware.IPFilter(allow, deny))
+	}
+	admin.Use(jwtAuth, userNormalLimiter)
+	admin.Get("/stats", can(dto.PermissionStatsRead), cachedStats, deps.AdminHandler.GetStats)
+	admin.Get("/stats/daily", can(dto.PermissionStatsRead), cachedStats, deps.AdminHandler.GetStatsSeries)
+	admin.Get("/stats/export", can(dto.PermissionStatsRead), deps.AdminHandler.ExportStats)
+	admin.Get("/system", can(dto.PermissionStatsRead), deps.SystemHandler.GetSystem)
+	admin.Get("/search", can(dto.PermissionUsersList), can(dto.PermissionFilesManage), deps.AdminHandler.Search)
+	admin.Get("/users", can(dto.PermissionUsersList), etag, deps.AdminHandler.ListUsers)
+	admin.Get("/users/export", can(dto.PermissionUsersList), deps.AdminHandler.ExportUsers)
+	admin.Get("/users/:id", can(dto.PermissionUsersList), deps.AdminHandler.GetUser)
+	admin.Post("/users/bulk", can(dto.PermissionUsersManage), idempotent, deps.AdminHandler.BulkUsers)
+	admin.Post("/users/import", userStrictLimiter, can(dto.PermissionUsersManage), idempotent, deps.AdminHandler.ImportUsers)
+	admin.Put("/users/:id/role", can(dto.PermissionUsersManage), deps.AdminHandler.UpdateRole)
+	admin.Get("/users/:id/roles", can(dto.PermissionRolesManage), deps.AdminHandler.GetUserRoles)
+	admin.Put("/users/:id/roles", can(dto.PermissionRolesManage), deps.AdminHandler.SetUserRoles)
+	admin.Post("/users/:id/ban", can(dto.PermissionUsersManage), deps.AdminHandler.BanUser)
+	admin.Post("/users/:id/unban", can(dto.PermissionUsersManage), deps.AdminHandler.UnbanUser)
+	admin.Post("/users/:id/restore", can(dto.PermissionUsersManage), deps.AdminHandler.RestoreUser)
+	admin.Get("/users/:id/sessions", can(dto.PermissionUsersManage), deps.AdminHandler.ListUserSessions)
+	admin.Delete("/users/:id/sessions", can(dto.PermissionUsersManage), deps.AdminHandler.RevokeUserSessions)
+	admin.Get("/users/:id/activity", can(dto.PermissionUsersManage), deps.AdminHandler.ListUserActivity)
+	admin.Post("/users/:id/verify-email", can(dto.PermissionUsersManage), deps.AdminHandler.VerifyUserEmail)
+	admin.Post("/users/:id/send-verification", userStrictLimiter, can(dto.PermissionUsersManage), deps.AdminHandler.SendUserVerification)
+	admin.Post("/users/:id/erase", can(dto.PermissionUsersManage), deps.AdminHandler.EraseUser)
+	admin.Get("/erasures", can(dto.PermissionUsersManage), deps.AdminHandler.ListErasures)
+	admin.Get("/audit-logs", can(dto.PermissionAuditRead), etag, deps.AdminHandler.ListAuditLogs)
+	admin.Get("/audit-logs/export", can(dto.PermissionAuditRead), deps.AdminHandler.ExportAuditLogs)
+	admin.Get("/invitations", can(dto.PermissionUsersManage), deps.AdminHandler.ListInvitations)
+	admin.Post("/invitations", userStrictLimiter, can(dto.PermissionUsersManage), deps.AdminHandler.CreateInvitation)
+	admin.Delete("/invitations/:id", can(dto.PermissionUsersManage), deps.AdminHandler.RevokeInvitation)
+	admin.Post("/users/:id/impersonate", can(dto.PermissionUsersImpersonate), deps.AdminHandler.Impersonate)
+	admin.Get("/files", can(dto.PermissionFilesManage), etag, deps.AdminHandler.ListFiles)
+	admin.Delete("/files/:id", can(dto.PermissionFilesManage), deps.AdminHandler.DeleteFile)
+	admin.Post("/files/:id/restore", can(dto.PermissionFilesManage), deps.AdminHandler.RestoreFile)
+	admin.Delete("/files/:id/purge", can(dto.PermissionFilesManage), deps.AdminHandler.PurgeFile)
+	admin.Get("/files/:id/download", can(dto.PermissionFilesManage), deps.AdminHandler.DownloadFile)
+	admin.Post("/storage/reconcile", userStrictLimiter, can(dto.PermissionFilesManage), deps.AdminHandler.ReconcileStorage)
+	admin.Get("/storage/reconcile", can(dto.PermissionFilesManage), deps.AdminHandler.GetStorageReconciliation)
+	admin.Get("/roles", can(dto.PermissionRolesManage), deps.AdminHandler.ListRoles)
+	admin.Post("/roles", can(dto.PermissionRolesManage), deps.AdminHandler.CreateRole)
+	admin.Delete("/roles/:id", can(dto.PermissionRolesManage), deps.AdminHandler.DeleteRole)
+	admin.Get("/permissions", can(dto.PermissionRolesManage), deps.AdminHandler.ListPermissions)
+	admin.Get("/feature-flags", can(dto.PermissionFlagsManage), deps.FlagHandler.List)
+	admin.Post("/feature-flags", can(dto.PermissionFlagsManage), deps.FlagHandler.Create)
+	admin.Put("/feature-flags/:id", can(dto.PermissionFlagsManage), deps.FlagHandler.Update)
+	admin.Delete("/feature-flags/:id", can(dto.PermissionFlagsManage), deps.FlagHandler.Delete)
+	admin.Get("/settings", can(dto.PermissionSettingsManage), deps.SettingsHandler.Get)
+	admin.Put("/settings", can(dto.PermissionSettingsManage), deps.SettingsHandler.Update)
+	admin.Post("/broadcast", userStrictLimiter, can(dto.PermissionBroadcastsSend), idempotent, deps.BroadcastHandler.Send)
+	admin.Get("/broadcasts", can(dto.PermissionBroadcastsSend), etag, deps.BroadcastHandler.List)
+	admin.Get("/emails", can(dto.PermissionEmailsManage), deps.EmailHandler.List)
+	admin.Post("/emails/:id/retry", can(dto.PermissionEmailsManage), deps.EmailHandler.Retry)
+	admin.Get("/email-suppressions", can(dto.PermissionEmailsManage), deps.EmailSuppressionHandler.List)
+	admin.Delete("/email-suppressions/:id", can(dto.PermissionEmailsManage), deps.EmailSuppressionHandler.Remove)
 }
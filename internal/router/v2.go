@@ -0,0 +1,15 @@
+package router
+
+import (
+	"github.com/gofiber/fiber/v3"
+)
+
+// registerV2Routes registers the routes that changed in v2, then serves every other
+// route as v1 does. Routes match in the order they are registered, so a v2 route takes
+// the place of the v1 route with the same method and path.
+func registerV2Routes(v2 fiber.Router, deps Deps, mw routeMiddleware) {
+	// The profile no longer embeds the settings
+	v2.Get("/users/me", mw.jwtAuth, mw.userRelaxedLimiter, mw.usersRead, mw.etag, deps.UserHandler.GetMeV2)
+
+	registerV1Routes(v2, deps, mw)
+}
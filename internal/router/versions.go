@@ -0,0 +1,131 @@
+package router
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/middleware"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+)
+
+// apiVersion is a version of the API, served under /api/<name>.
+type apiVersion struct {
+	name     string
+	register func(router fiber.Router, deps Deps, mw routeMiddleware)
+	// Set once the version is deprecated, to announce it on every response
+	deprecatedAt, sunsetAt time.Time
+	deprecationLink        string
+}
+
+// registerAPIVersions mounts every API version. A version registers the routes whose
+// contract changed, with the structs of its package under internal/dto, and serves the
+// others as the version before it does, so a breaking change ships without copying the
+// whole API.
+func registerAPIVersions(app *fiber.App, deps Deps) {
+	cfg := deps.Config
+	v1DeprecatedAt, v1SunsetAt := cfg.API.V1Deprecation()
+	versions := []apiVersion{
+		{
+			name:            "v1",
+			register:        registerV1Routes,
+			deprecatedAt:    v1DeprecatedAt,
+			sunsetAt:        v1SunsetAt,
+			deprecationLink: cfg.API.V1DeprecationLink,
+		},
+		{name: "v2", register: registerV2Routes},
+	}
+
+	mw := newRouteMiddleware(deps)
+	for _, v := range versions {
+		group := app.Group("/api/" + v.name)
+		if !v.deprecatedAt.IsZero() || !v.sunsetAt.IsZero() {
+			group.Use(middleware.Deprecation(v.deprecatedAt, v.sunsetAt, v.deprecationLink))
+		}
+		v.register(group, deps, mw)
+	}
+}
+
+// routeMiddleware is the middleware of API routes. It is built once and shared by every
+// version, so a client has the same rate limit budget whichever version it calls.
+type routeMiddleware struct {
+	// Rate limiters (tiered)
+	strictLimiter  fiber.Handler
+	normalLimiter  fiber.Handler
+	relaxedLimiter fiber.Handler
+	// Routes behind jwtAuth count requests per user instead, so users sharing an IP, such
+	// as behind a corporate NAT, are not throttled together
+	userStrictLimiter  fiber.Handler
+	userNormalLimiter  fiber.Handler
+	userRelaxedLimiter fiber.Handler
+
+	jwtAuth fiber.Handler
+	// Guest tokens are limited to reading their own profile and managing files
+	registered fiber.Handler
+	usersRead  fiber.Handler
+	usersWrite fiber.Handler
+	filesRead  fiber.Handler
+	filesWrite fiber.Handler
+	can        func(permission string) fiber.Handler
+
+	// Replays the response of a retried request carrying an Idempotency-Key. On protected
+	// routes it must run after jwtAuth, which scopes keys to the user.
+	idempotent fiber.Handler
+	// Answers 304 to pollers whose If-None-Match matches the response
+	etag fiber.Handler
+	// Serve hot GET routes from the cache for RESPONSE_CACHE_TTL. cachedUser tags the
+	// response with the user in :id, so changes to that user drop it, and cachedStats with
+	// the admin statistics, which admin changes to users and files drop. Both are also
+	// tagged with the requesting user, so changes to their role drop their responses.
+	cachedUser  fiber.Handler
+	cachedStats fiber.Handler
+}
+
+func newRouteMiddleware(deps Deps) routeMiddleware {
+	cfg := deps.Config
+	rl := cfg.RateLimit
+	exempt := limitExemptions(rl)
+	responseCacheTTL := time.Duration(cfg.App.ResponseCacheTTL) * time.Second
+	return routeMiddleware{
+		strictLimiter:      middleware.NewLimiter(deps.Cache, rl.StrictMax, rl.StrictWindow, exempt),
+		normalLimiter:      middleware.NewLimiter(deps.Cache, rl.NormalMax, rl.NormalWindow, exempt),
+		relaxedLimiter:     middleware.NewLimiter(deps.Cache, rl.RelaxedMax, rl.RelaxedWindow, exempt),
+		userStrictLimiter:  middleware.NewUserLimiter(deps.Cache, rl.UserStrictMax, rl.StrictWindow, exempt),
+		userNormalLimiter:  middleware.NewUserLimiter(deps.Cache, rl.UserNormalMax, rl.NormalWindow, exempt),
+		userRelaxedLimiter: middleware.NewUserLimiter(deps.Cache, rl.UserRelaxedMax, rl.RelaxedWindow, exempt),
+		jwtAuth:            middleware.JWTAuth(cfg.JWT, deps.Revocations),
+		registered:         middleware.RequireRole(dto.RoleUser, dto.RoleAdmin),
+		usersRead:          middleware.RequireScope(dto.ScopeUsersRead),
+		usersWrite:         middleware.RequireScope(dto.ScopeUsersWrite),
+		filesRead:          middleware.RequireScope(dto.ScopeFilesRead),
+		filesWrite:         middleware.RequireScope(dto.ScopeFilesWrite),
+		can: func(permission string) fiber.Handler {
+			return middleware.RequirePermission(deps.Permissions, permission)
+		},
+		idempotent: middleware.Idempotency(deps.Cache, time.Duration(cfg.App.IdempotencyTTLHours)*time.Hour),
+		etag:       middleware.ETag(),
+		cachedUser: middleware.CacheResponse(deps.Cache, responseCacheTTL, func(c fiber.Ctx) []string {
+			id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+			if err != nil {
+				return nil
+			}
+			return []string{cache.UserTag(id)}
+		}),
+		cachedStats: middleware.CacheResponse(deps.Cache, responseCacheTTL, func(fiber.Ctx) []string {
+			return []string{cache.StatsTag}
+		}),
+	}
+}
+
+// limitExemptions returns the requests that skip every rate limiter, set by the
+// RATE_LIMIT_EXEMPT_* variables.
+func limitExemptions(rl config.RateLimitConfig) middleware.LimitExemptions {
+	return middleware.LimitExemptions{
+		IPs:    rl.ExemptIPList(),
+		Paths:  rl.ExemptPathList(),
+		Tokens: rl.ExemptTokenList(),
+	}
+}
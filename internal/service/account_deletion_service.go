@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/email"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/storage"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/token"
+)
+
+// purgeBatchSize caps how many accounts a single PurgeDue or PurgeSoftDeleted run removes.
+const purgeBatchSize = 100
+
+type AccountDeletionService interface {
+	Schedule(ctx context.Context, userID int64) (*dto.AccountDeletionResponse, error)
+	Cancel(ctx context.Context, token string) error
+	PurgeDue(ctx context.Context) (int, error)
+	PurgeSoftDeleted(ctx context.Context) (int, error)
+}
+
+type accountDeletionService struct {
+	userRepo     repository.UserRepository
+	fileRepo     repository.FileRepository
+	deletionRepo repository.AccountDeletionRepository
+	storage      storage.Storage
+	sender       email.Sender
+	revocations  *token.RevocationStore
+	cache        cache.Cache
+	gracePeriod  time.Duration
+	retention    time.Duration // zero keeps soft-deleted users forever
+	frontendURL  string
+}
+
+func NewAccountDeletionService(
+	userRepo repository.UserRepository,
+	fileRepo repository.FileRepository,
+	deletionRepo repository.AccountDeletionRepository,
+	store storage.Storage,
+	sender email.Sender,
+	revocations *token.RevocationStore,
+	appCache cache.Cache,
+	graceDays int,
+	retentionDays int,
+	frontendURL string,
+) AccountDeletionService {
+	return &accountDeletionService{
+		userRepo:     userRepo,
+		fileRepo:     fileRepo,
+		deletionRepo: deletionRepo,
+		storage:      store,
+		sender:       sender,
+		revocations:  revocations,
+		cache:        appCache,
+		gracePeriod:  time.Duration(graceDays) * 24 * time.Hour,
+		retention:    time.Duration(retentionDays) * 24 * time.Hour,
+		frontendURL:  frontendURL,
+	}
+}
+
+// Schedule marks the account for permanent deletion once the grace period has passed
+// and emails the user a link to cancel. The last active admin cannot schedule it.
+func (s *accountDeletionService) Schedule(ctx context.Context, userID int64) (*dto.AccountDeletionResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("user not found")
+		}
+		return nil, apperror.NewInternal("failed to get user")
+	}
+	if err := checkAdminRemoval(ctx, s.userRepo, user); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.deletionRepo.GetByUserID(ctx, userID); err == nil {
+		return nil, apperror.NewBadRequest("account deletion already scheduled")
+	} else if !errors.Is(err, apperror.ErrNotFound) {
+		return nil, apperror.NewInternal("failed to check account deletion")
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, apperror.NewInternal("failed to generate cancellation token")
+	}
+	cancelToken := hex.EncodeToString(b)
+
+	req, err := s.deletionRepo.Create(ctx, sqlc.CreateAccountDeletionRequestParams{
+		UserID:       userID,
+		Token:        cancelToken,
+		ScheduledFor: pgtype.Timestamptz{Time: time.Now().Add(s.gracePeriod), Valid: true},
+	})
+	if err != nil {
+		if repository.IsUniqueViolation(err) {
+			return nil, apperror.NewBadRequest("account deletion already scheduled")
+		}
+		return nil, apperror.NewInternal("failed to schedule account deletion")
+	}
+
+	scheduledFor := req.ScheduledFor.Time
+	cancelURL := fmt.Sprintf("%s/cancel-account-deletion?token=%s", s.frontendURL, cancelToken)
+	if err := s.sender.Send(ctx, email.Message{
+		To:      []string{user.Email},
+		Subject: "Your Account Is Scheduled for Deletion",
+		HTML: fmt.Sprintf(
+			"<p>Your account and all of its files will be permanently deleted on %s.</p>"+
+				"<p>Changed your mind? Click <a href=%q>here</a> to cancel the deletion.</p>",
+			scheduledFor.UTC().Format(time.RFC1123), cancelURL,
+		),
+	}); err != nil {
+		slog.Error("failed to send account deletion email", slog.Any("error", err))
+	}
+
+	return &dto.AccountDeletionResponse{ScheduledFor: scheduledFor}, nil
+}
+
+func (s *accountDeletionService) Cancel(ctx context.Context, token string) error {
+	if _, err := s.deletionRepo.DeleteByToken(ctx, token); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return apperror.NewBadRequest("invalid or expired cancellation token")
+		}
+		return apperror.NewInternal("failed to cancel account deletion")
+	}
+	return nil
+}
+
+// PurgeDue permanently removes accounts whose grace period has passed, along with their
+// stored files. Rows in dependent tables are removed by ON DELETE CASCADE.
+func (s *accountDeletionService) PurgeDue(ctx context.Context) (int, error) {
+	due, err := s.deletionRepo.ListDue(ctx, purgeBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("list due account deletions: %w", err)
+	}
+
+	purged := 0
+	for _, req := range due {
+		if err := s.purgeUser(ctx, req.UserID); err != nil {
+			slog.Error("failed to purge account", slog.Int64("user_id", req.UserID), slog.Any("error", err))
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// PurgeSoftDeleted permanently removes users that were soft-deleted longer ago than the
+// retention period, along with their stored files. It does nothing when retention is disabled.
+func (s *accountDeletionService) PurgeSoftDeleted(ctx context.Context) (int, error) {
+	if s.retention <= 0 {
+		return 0, nil
+	}
+
+	ids, err := s.userRepo.ListDeletedBefore(ctx, time.Now().Add(-s.retention), purgeBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("list expired soft-deleted users: %w", err)
+	}
+
+	purged := 0
+	for _, id := range ids {
+		if err := s.purgeUser(ctx, id); err != nil {
+			slog.Error("failed to purge soft-deleted user", slog.Int64("user_id", id), slog.Any("error", err))
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+func (s *accountDeletionService) purgeUser(ctx context.Context, userID int64) error {
+	files, err := s.fileRepo.ListAllByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("list files: %w", err)
+	}
+	paths, err := s.fileRepo.ListVariantPathsByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("list file variants: %w", err)
+	}
+	for _, f := range files {
+		paths = append(paths, f.StoragePath)
+	}
+	if paths, err = unsharedPaths(ctx, s.fileRepo, userID, paths); err != nil {
+		return fmt.Errorf("check shared files: %w", err)
+	}
+
+	// Orphaned objects are preferable to keeping a user who asked to be deleted
+	for _, path := range paths {
+		if err := s.storage.Delete(ctx, path); err != nil {
+			slog.Error("failed to delete stored file", slog.String("path", path), slog.Any("error", err))
+		}
+	}
+
+	if err := s.userRepo.Purge(ctx, userID); err != nil {
+		return fmt.Errorf("delete user: %w", err)
+	}
+
+	if err := s.revocations.RevokeUser(ctx, userID); err != nil {
+		slog.Error("failed to revoke access tokens", slog.Int64("user_id", userID), slog.Any("error", err))
+	}
+	forgetUser(ctx, s.cache, userID)
+
+	slog.Info("account purged", slog.Int64("user_id", userID), slog.Int("files", len(files)))
+	return nil
+}
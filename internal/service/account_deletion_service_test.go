@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/token"
+)
+
+type accountDeletionFixture struct {
+	svc          AccountDeletionService
+	userRepo     *mockUserRepo
+	fileRepo     *mockFileRepo
+	deletionRepo *mockAccountDeletionRepo
+	store        *mockStorage
+	sender       *mockEmailSender
+}
+
+func newAccountDeletionFixture() *accountDeletionFixture {
+	f := &accountDeletionFixture{
+		userRepo:     newMockUserRepo(),
+		fileRepo:     newMockFileRepo(),
+		deletionRepo: newMockAccountDeletionRepo(),
+		store:        newMockStorage(),
+		sender:       newMockEmailSender(),
+	}
+	f.userRepo.users[1] = &sqlc.User{ID: 1, Email: "user@example.com", Name: "User"}
+	f.svc = NewAccountDeletionService(
+		f.userRepo, f.fileRepo, f.deletionRepo, f.store, f.sender,
+		token.NewRevocationStore(newMockCache(), time.Hour), newMockCache(), 30, 90, "http://localhost:3000",
+	)
+	return f
+}
+
+// ---------------------------------------------------------------------------
+// Schedule
+// ---------------------------------------------------------------------------
+
+func TestScheduleAccountDeletion(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		f := newAccountDeletionFixture()
+
+		resp, err := f.svc.Schedule(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if d := time.Until(resp.ScheduledFor); d < 29*24*time.Hour || d > 30*24*time.Hour {
+			t.Errorf("expected deletion in ~30 days, got %v", d)
+		}
+		if f.sender.sent != 1 {
+			t.Errorf("expected 1 email sent, got %d", f.sender.sent)
+		}
+		if _, ok := f.userRepo.users[1]; !ok {
+			t.Error("user should not be removed before the grace period ends")
+		}
+	})
+
+	t.Run("last admin", func(t *testing.T) {
+		f := newAccountDeletionFixture()
+		f.userRepo.users[1].Role = dto.RoleAdmin
+
+		_, err := f.svc.Schedule(context.Background(), 1)
+		assertErrorCode(t, err, 409, dto.ErrorCodeLastAdmin)
+		if f.sender.sent != 0 {
+			t.Errorf("expected no email sent, got %d", f.sender.sent)
+		}
+	})
+
+	t.Run("already scheduled", func(t *testing.T) {
+		f := newAccountDeletionFixture()
+		_, _ = f.svc.Schedule(context.Background(), 1)
+
+		_, err := f.svc.Schedule(context.Background(), 1)
+		var appErr *apperror.AppError
+		if !errors.As(err, &appErr) {
+			t.Fatalf("expected AppError, got %T", err)
+		}
+		if appErr.Code != 400 {
+			t.Errorf("expected 400, got %d", appErr.Code)
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Cancel
+// ---------------------------------------------------------------------------
+
+func TestCancelAccountDeletion(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		f := newAccountDeletionFixture()
+		_, _ = f.svc.Schedule(context.Background(), 1)
+		cancelToken := f.deletionRepo.requests[1].Token
+
+		if err := f.svc.Cancel(context.Background(), cancelToken); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(f.deletionRepo.requests) != 0 {
+			t.Error("expected deletion request to be removed")
+		}
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		f := newAccountDeletionFixture()
+
+		err := f.svc.Cancel(context.Background(), "nope")
+		var appErr *apperror.AppError
+		if !errors.As(err, &appErr) {
+			t.Fatalf("expected AppError, got %T", err)
+		}
+		if appErr.Code != 400 {
+			t.Errorf("expected 400, got %d", appErr.Code)
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// PurgeDue
+// ---------------------------------------------------------------------------
+
+func TestPurgeDueAccounts(t *testing.T) {
+	f := newAccountDeletionFixture()
+	f.userRepo.users[2] = &sqlc.User{ID: 2, Email: "other@example.com", Name: "Other"}
+	ctx := context.Background()
+
+	f.store.files["uploads/a.txt"] = []byte("a")
+	f.store.files["uploads/a_thumbnail.jpg"] = []byte("thumb")
+	f.fileRepo.files[1] = &sqlc.File{ID: 1, UserID: 1, StoragePath: "uploads/a.txt"}
+	f.fileRepo.variants = []sqlc.FileVariant{{ID: 1, FileID: 1, Name: "thumbnail", StoragePath: "uploads/a_thumbnail.jpg"}}
+
+	// User 1 is past the grace period, user 2 is not
+	f.deletionRepo.requests[1] = &sqlc.AccountDeletionRequest{
+		UserID:       1,
+		Token:        "t1",
+		ScheduledFor: pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true},
+	}
+	f.deletionRepo.requests[2] = &sqlc.AccountDeletionRequest{
+		UserID:       2,
+		Token:        "t2",
+		ScheduledFor: pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+
+	purged, err := f.svc.PurgeDue(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("expected 1 purged account, got %d", purged)
+	}
+	if _, ok := f.userRepo.users[1]; ok {
+		t.Error("expected user 1 to be purged")
+	}
+	if _, ok := f.userRepo.users[2]; !ok {
+		t.Error("user 2 should not be purged yet")
+	}
+	if _, ok := f.store.files["uploads/a.txt"]; ok {
+		t.Error("expected stored file to be deleted")
+	}
+	if _, ok := f.store.files["uploads/a_thumbnail.jpg"]; ok {
+		t.Error("expected stored image variant to be deleted")
+	}
+}
+
+func TestPurgeSoftDeletedUsers(t *testing.T) {
+	f := newAccountDeletionFixture()
+	ctx := context.Background()
+
+	// User 2 was deleted beyond the 90-day window, user 3 recently; user 1 is active
+	f.userRepo.users[2] = &sqlc.User{
+		ID: 2, Email: "old@example.com", Name: "Old",
+		DeletedAt: pgtype.Timestamptz{Time: time.Now().Add(-91 * 24 * time.Hour), Valid: true},
+	}
+	f.userRepo.users[3] = &sqlc.User{
+		ID: 3, Email: "recent@example.com", Name: "Recent",
+		DeletedAt: pgtype.Timestamptz{Time: time.Now().Add(-24 * time.Hour), Valid: true},
+	}
+	f.store.files["uploads/old.txt"] = []byte("old")
+	f.fileRepo.files[1] = &sqlc.File{ID: 1, UserID: 2, StoragePath: "uploads/old.txt"}
+
+	purged, err := f.svc.PurgeSoftDeleted(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("expected 1 purged user, got %d", purged)
+	}
+	if _, ok := f.userRepo.users[2]; ok {
+		t.Error("expected user 2 to be purged")
+	}
+	for _, id := range []int64{1, 3} {
+		if _, ok := f.userRepo.users[id]; !ok {
+			t.Errorf("user %d should be kept", id)
+		}
+	}
+	if _, ok := f.store.files["uploads/old.txt"]; ok {
+		t.Error("expected stored file to be deleted")
+	}
+
+	t.Run("disabled retention keeps everyone", func(t *testing.T) {
+		f := newAccountDeletionFixture()
+		f.userRepo.users[2] = &sqlc.User{
+			ID: 2, Email: "old@example.com", Name: "Old",
+			DeletedAt: pgtype.Timestamptz{Time: time.Now().Add(-365 * 24 * time.Hour), Valid: true},
+		}
+		f.svc.(*accountDeletionService).retention = 0
+
+		purged, err := f.svc.PurgeSoftDeleted(ctx)
+		if err != nil || purged != 0 {
+			t.Errorf("expected nothing purged, got %d (%v)", purged, err)
+		}
+	})
+}
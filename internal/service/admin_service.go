@@ -1,138 +1,588 @@
 package service
 
 import (
+	"cmp"
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/database"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/pagination"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/storage"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/telemetry"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/token"
+)
+
+const (
+	// userExportBatchSize is the number of users fetched per query while exporting.
+	userExportBatchSize = 500
+	// filePurgeBatchSize caps how many files a single PurgeDeletedFiles run removes.
+	filePurgeBatchSize = 100
+	// defaultStatsSeriesDays is the window of GetStatsSeries when none is given.
+	defaultStatsSeriesDays = 30
+	// defaultSearchLimit is the number of hits Search returns when no limit is given.
+	defaultSearchLimit = 20
 )
 
 type AdminService interface {
-	ListUsers(ctx context.Context, page, perPage int) ([]dto.UserResponse, int64, error)
-	UpdateRole(ctx context.Context, id int64, role string) (*dto.UserResponse, error)
-	BanUser(ctx context.Context, id int64) error
+	ListUsers(ctx context.Context, query dto.UserFilterQuery, page, perPage int) ([]dto.AdminUserResponse, int64, error)
+	ExportUsers(ctx context.Context, query dto.UserFilterQuery, fn func(dto.AdminUserResponse) error) error
+	GetUser(ctx context.Context, id int64) (*dto.AdminUserDetailResponse, error)
+	UpdateRole(ctx context.Context, actorID, id int64, role string) (*dto.UserResponse, error)
+	BanUser(ctx context.Context, actorID, id int64, reason string) error
 	UnbanUser(ctx context.Context, id int64) (*dto.UserResponse, error)
-	ListFiles(ctx context.Context, page, perPage int) ([]dto.FileResponse, int64, error)
+	RestoreUser(ctx context.Context, id int64) (*dto.UserResponse, error)
+	ListSessions(ctx context.Context, id int64) ([]dto.UserSessionResponse, error)
+	RevokeSessions(ctx context.Context, id int64) error
+	ListFiles(ctx context.Context, query dto.AdminFileListQuery, page, perPage int) ([]dto.FileResponse, int64, error)
+	DeleteFile(ctx context.Context, id int64) error
+	RestoreFile(ctx context.Context, id int64) (*dto.FileResponse, error)
+	PurgeFile(ctx context.Context, id int64) error
+	DownloadFile(ctx context.Context, id int64) (*sqlc.File, io.ReadCloser, error)
+	PurgeDeletedFiles(ctx context.Context) (int, error)
 	GetStats(ctx context.Context) (*dto.AdminStatsResponse, error)
+	GetStatsSeries(ctx context.Context, days int) (*dto.AdminStatsSeriesResponse, error)
+	Search(ctx context.Context, query dto.AdminSearchQuery) ([]dto.AdminSearchHit, error)
+	Impersonate(ctx context.Context, adminID, targetID int64) (*sqlc.User, error)
+	BulkUsers(ctx context.Context, actorID int64, req dto.BulkUserActionRequest) (*dto.BulkUserActionResponse, error)
 }
 
 type adminService struct {
 	userRepo         repository.UserRepository
 	fileRepo         repository.FileRepository
 	refreshTokenRepo repository.RefreshTokenRepository
+	roleRepo         repository.RoleRepository
 	storage          storage.Storage
+	revocations      *token.RevocationStore
+	cache            cache.Cache
+	txManager        *database.TxManager
+	auditLog         AuditLogService
+	fileRetention    time.Duration // zero keeps soft-deleted files forever
 }
 
 func NewAdminService(
 	userRepo repository.UserRepository,
 	fileRepo repository.FileRepository,
 	refreshTokenRepo repository.RefreshTokenRepository,
+	roleRepo repository.RoleRepository,
 	store storage.Storage,
+	revocations *token.RevocationStore,
+	appCache cache.Cache,
+	txManager *database.TxManager,
+	auditLog AuditLogService,
+	fileRetentionDays int,
 ) AdminService {
 	return &adminService{
 		userRepo: userRepo, fileRepo: fileRepo,
-		refreshTokenRepo: refreshTokenRepo, storage: store,
+		refreshTokenRepo: refreshTokenRepo, roleRepo: roleRepo, storage: store,
+		revocations: revocations, cache: appCache, txManager: txManager, auditLog: auditLog,
+		fileRetention: time.Duration(fileRetentionDays) * 24 * time.Hour,
 	}
 }
 
-func (s *adminService) ListUsers(ctx context.Context, page, perPage int) ([]dto.UserResponse, int64, error) {
+func (s *adminService) ListUsers(ctx context.Context, query dto.UserFilterQuery, page, perPage int) ([]dto.AdminUserResponse, int64, error) {
+	ctx, span := telemetry.Start(ctx, "AdminService.ListUsers")
+	defer span.End()
+
+	filter, err := userFilter(query, true)
+	if err != nil {
+		return nil, 0, err
+	}
 	limit, offset := pagination.LimitOffset(page, perPage)
 
 	// Note: List and Count are separate queries; minor pagination inconsistency is acceptable for read-only operations.
-	users, err := s.userRepo.AdminList(ctx, limit, offset)
+	users, err := s.userRepo.List(ctx, filter, limit, offset)
 	if err != nil {
 		return nil, 0, apperror.NewInternal("failed to list users")
 	}
 
-	total, err := s.userRepo.AdminCount(ctx)
+	total, err := s.userRepo.Count(ctx, filter)
 	if err != nil {
 		return nil, 0, apperror.NewInternal("failed to count users")
 	}
 
-	responses := make([]dto.UserResponse, len(users))
+	responses := make([]dto.AdminUserResponse, len(users))
 	for i, u := range users {
-		responses[i] = *ToUserResponse(&u)
+		responses[i] = *ToAdminUserResponse(&u)
 	}
 
 	return responses, total, nil
 }
 
-func (s *adminService) UpdateRole(ctx context.Context, id int64, role string) (*dto.UserResponse, error) {
-	user, err := s.userRepo.UpdateRole(ctx, sqlc.UpdateUserRoleParams{
-		ID:   id,
-		Role: role,
-	})
+// ExportUsers passes every user matching the filter to fn in ID order. Users are fetched in
+// keyset pages of userExportBatchSize, so the full result set is never held in memory.
+// An error returned by fn stops the export and is returned as is.
+func (s *adminService) ExportUsers(ctx context.Context, query dto.UserFilterQuery, fn func(dto.AdminUserResponse) error) error {
+	filter, err := userFilter(query, true)
+	if err != nil {
+		return err
+	}
+
+	var afterID int64
+	for {
+		users, err := s.userRepo.ListAfter(ctx, filter, afterID, userExportBatchSize)
+		if err != nil {
+			return apperror.NewInternal("failed to export users")
+		}
+		for i := range users {
+			if err := fn(*ToAdminUserResponse(&users[i])); err != nil {
+				return err
+			}
+		}
+		if len(users) < userExportBatchSize {
+			return nil
+		}
+		afterID = users[len(users)-1].ID
+	}
+}
+
+// ToAdminUserResponse converts a user for the admin routes, adding the ban to the profile.
+func ToAdminUserResponse(user *sqlc.User) *dto.AdminUserResponse {
+	resp := &dto.AdminUserResponse{UserResponse: *ToUserResponse(user), BanReason: user.BanReason}
+	if user.BannedAt.Valid {
+		resp.BannedAt = &user.BannedAt.Time
+	}
+	return resp
+}
+
+// GetUser returns a user, including a banned one, with their sign-in methods, active
+// sessions, stored files and latest successful sign-in.
+func (s *adminService) GetUser(ctx context.Context, id int64) (*dto.AdminUserDetailResponse, error) {
+	detail, err := s.userRepo.GetDetail(ctx, id)
 	if err != nil {
 		if errors.Is(err, apperror.ErrNotFound) {
 			return nil, apperror.NewNotFound("user not found")
 		}
-		return nil, apperror.NewInternal("failed to update user role")
+		return nil, apperror.NewInternal("failed to get user")
+	}
+
+	user := &detail.User
+	resp := &dto.AdminUserDetailResponse{
+		AdminUserResponse: *ToAdminUserResponse(user),
+		AuthProvider:      user.AuthProvider,
+		HasPassword:       user.PasswordHash.Valid,
+		LinkedProviders:   []string{},
+		ActiveSessions:    detail.ActiveSessions,
+		FileCount:         detail.FileCount,
+		FileSize:          detail.FileSize,
+	}
+	for _, linked := range []struct {
+		provider string
+		id       pgtype.Text
+	}{{"google", user.GoogleID}, {"github", user.GithubID}, {"saml", user.SamlID}} {
+		if linked.id.Valid {
+			resp.LinkedProviders = append(resp.LinkedProviders, linked.provider)
+		}
+	}
+	if user.EmailVerifiedAt.Valid {
+		resp.EmailVerifiedAt = &user.EmailVerifiedAt.Time
+	}
+	if user.DeletedAt.Valid {
+		resp.DeletedAt = &user.DeletedAt.Time
+	}
+	if detail.LastLoginAt.Valid {
+		resp.LastLogin = &dto.LastLoginInfo{
+			At:        detail.LastLoginAt.Time,
+			Method:    detail.LastLoginMethod,
+			IPAddress: detail.LastLoginIp,
+			UserAgent: detail.LastLoginUserAgent,
+		}
+	}
+	return resp, nil
+}
+
+// UpdateRole sets the built-in role of a user. Admins cannot demote themselves, and the
+// last active admin cannot be demoted by anyone.
+func (s *adminService) UpdateRole(ctx context.Context, actorID, id int64, role string) (*dto.UserResponse, error) {
+	var (
+		user         *sqlc.User
+		previousRole string
+	)
+	update := func(userRepo repository.UserRepository) error {
+		current, err := userRepo.GetByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				return apperror.NewNotFound("user not found")
+			}
+			return apperror.NewInternal("failed to get user")
+		}
+		previousRole = current.Role
+		if previousRole == dto.RoleAdmin && role != dto.RoleAdmin {
+			if id == actorID {
+				return apperror.NewBadRequest("cannot demote yourself").WithErrorCode(dto.ErrorCodeSelfDemotion)
+			}
+			if err := checkAdminRemoval(ctx, userRepo, current); err != nil {
+				return err
+			}
+		}
+
+		user, err = userRepo.UpdateRole(ctx, sqlc.UpdateUserRoleParams{
+			ID:   id,
+			Role: role,
+		})
+		if err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				return apperror.NewNotFound("user not found")
+			}
+			return apperror.NewInternal("failed to update user role")
+		}
+		return nil
+	}
+	if err := s.withUsersTx(ctx, update); err != nil {
+		return nil, err
+	}
+	recordAudit(ctx, s.auditLog, AuditEntry{
+		Action: dto.AuditUserRoleChanged, TargetType: dto.AuditTargetUser, TargetID: id,
+		Before: map[string]any{"role": previousRole}, After: map[string]any{"role": user.Role},
+	})
+	forgetUser(ctx, s.cache, id)
+
+	// Access tokens carry the role claim, so force re-authentication with the new role
+	if err := s.revocations.RevokeUser(ctx, id); err != nil {
+		slog.Error("failed to revoke access tokens", slog.Int64("user_id", id), slog.Any("error", err))
 	}
 
 	return ToUserResponse(user), nil
 }
 
-func (s *adminService) BanUser(ctx context.Context, id int64) error {
-	_, err := s.userRepo.Delete(ctx, id)
-	if err != nil {
-		if errors.Is(err, apperror.ErrNotFound) {
-			return apperror.NewNotFound("user not found or already banned")
+// BanUser blocks a user from signing in and revokes their tokens. Unlike a deleted user, a
+// banned one stays visible to admins and is told the reason when they try to sign in.
+// Admins cannot ban themselves, and the last active admin cannot be banned by anyone.
+func (s *adminService) BanUser(ctx context.Context, actorID, id int64, reason string) error {
+	if id == actorID {
+		return apperror.NewBadRequest("cannot ban yourself").WithErrorCode(dto.ErrorCodeSelfBan)
+	}
+	ban := func(userRepo repository.UserRepository) error {
+		target, err := userRepo.GetByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				return apperror.NewNotFound("user not found")
+			}
+			return apperror.NewInternal("failed to get user")
 		}
-		return apperror.NewInternal("failed to ban user")
+		if target.BannedAt.Valid {
+			return apperror.NewConflict("user is already banned")
+		}
+		if err := checkAdminRemoval(ctx, userRepo, target); err != nil {
+			return err
+		}
+
+		if _, err := userRepo.Ban(ctx, id, reason); err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				return apperror.NewNotFound("user not found or already banned")
+			}
+			return apperror.NewInternal("failed to ban user")
+		}
+		return nil
 	}
+	if err := s.withUsersTx(ctx, ban); err != nil {
+		return err
+	}
+	recordAudit(ctx, s.auditLog, bannedEntry(id, reason))
+	forgetUser(ctx, s.cache, id)
+	forgetStats(ctx, s.cache)
 
-	// Revoke all refresh tokens for banned user
+	// Revoke all refresh and access tokens for banned user
 	_ = s.refreshTokenRepo.DeleteByUserID(ctx, id)
+	if err := s.revocations.RevokeUser(ctx, id); err != nil {
+		slog.Error("failed to revoke access tokens", slog.Int64("user_id", id), slog.Any("error", err))
+	}
 	return nil
 }
 
 func (s *adminService) UnbanUser(ctx context.Context, id int64) (*dto.UserResponse, error) {
-	user, err := s.userRepo.Restore(ctx, id)
+	user, err := s.userRepo.Unban(ctx, id)
 	if err != nil {
 		if errors.Is(err, apperror.ErrNotFound) {
 			return nil, apperror.NewNotFound("user not found or not banned")
 		}
 		return nil, apperror.NewInternal("failed to unban user")
 	}
+	recordAudit(ctx, s.auditLog, unbannedEntry(id))
+	forgetUser(ctx, s.cache, id)
+
+	return ToUserResponse(user), nil
+}
+
+// RestoreUser undeletes a soft-deleted user that has not been purged yet. Users banned
+// before bans were kept apart from deletion are restored this way too.
+func (s *adminService) RestoreUser(ctx context.Context, id int64) (*dto.UserResponse, error) {
+	user, err := s.userRepo.Restore(ctx, id)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("user not found or not deleted")
+		}
+		return nil, apperror.NewInternal("failed to restore user")
+	}
+	recordAudit(ctx, s.auditLog, AuditEntry{
+		Action: dto.AuditUserRestored, TargetType: dto.AuditTargetUser, TargetID: id,
+		Before: map[string]any{"deleted": true}, After: map[string]any{"deleted": false},
+	})
+	forgetUser(ctx, s.cache, id)
+	forgetStats(ctx, s.cache)
 
 	return ToUserResponse(user), nil
 }
 
-func (s *adminService) ListFiles(ctx context.Context, page, perPage int) ([]dto.FileResponse, int64, error) {
+// withUsersTx runs fn against a transactional user repository, or the plain one when no
+// transaction manager is configured (tests).
+func (s *adminService) withUsersTx(ctx context.Context, fn func(users repository.UserRepository) error) error {
+	if s.txManager == nil {
+		return fn(s.userRepo)
+	}
+	return s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		return fn(repository.NewUserRepository(tx))
+	})
+}
+
+// checkAdminRemoval refuses a demotion, ban or deletion of target when it would leave no
+// active admin. Banned admins do not count as active. The active admins stay locked until
+// the transaction of users ends, so it must run in the transaction that makes the change.
+func checkAdminRemoval(ctx context.Context, users repository.UserRepository, target *sqlc.User) error {
+	if target.Role != dto.RoleAdmin || target.BannedAt.Valid {
+		return nil
+	}
+	admins, err := users.CountActiveAdminsForUpdate(ctx)
+	if err != nil {
+		return apperror.NewInternal("failed to count admins")
+	}
+	if admins <= 1 {
+		return apperror.NewConflict("cannot remove the last admin").WithErrorCode(dto.ErrorCodeLastAdmin)
+	}
+	return nil
+}
+
+// ListSessions returns the user's signed-in sessions, most recently refreshed first.
+func (s *adminService) ListSessions(ctx context.Context, id int64) ([]dto.UserSessionResponse, error) {
+	if _, err := s.userRepo.GetByID(ctx, id); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("user not found")
+		}
+		return nil, apperror.NewInternal("failed to get user")
+	}
+
+	rows, err := s.refreshTokenRepo.ListActiveByUserID(ctx, id)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to list sessions")
+	}
+
+	sessions := make([]dto.UserSessionResponse, len(rows))
+	for i, r := range rows {
+		sessions[i] = dto.UserSessionResponse{
+			ID:              r.FamilyID,
+			StartedAt:       r.StartedAt.Time,
+			LastRefreshedAt: r.LastRefreshedAt.Time,
+			ExpiresAt:       r.ExpiresAt.Time,
+			DeviceBound:     r.DeviceBound,
+		}
+	}
+	return sessions, nil
+}
+
+// RevokeSessions signs the user out everywhere without banning them: every refresh token
+// is deleted and access tokens already issued are rejected from now on.
+func (s *adminService) RevokeSessions(ctx context.Context, id int64) error {
+	if _, err := s.userRepo.GetByID(ctx, id); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return apperror.NewNotFound("user not found")
+		}
+		return apperror.NewInternal("failed to get user")
+	}
+
+	active, err := s.refreshTokenRepo.ListActiveByUserID(ctx, id)
+	if err != nil {
+		return apperror.NewInternal("failed to list sessions")
+	}
+	if err := s.refreshTokenRepo.DeleteByUserID(ctx, id); err != nil {
+		return apperror.NewInternal("failed to revoke sessions")
+	}
+	if err := s.revocations.RevokeUser(ctx, id); err != nil {
+		slog.Error("failed to revoke access tokens", slog.Int64("user_id", id), slog.Any("error", err))
+	}
+
+	recordAudit(ctx, s.auditLog, AuditEntry{
+		Action: dto.AuditUserSessionsRevoked, TargetType: dto.AuditTargetUser, TargetID: id,
+		Before: map[string]any{"active_sessions": len(active)}, After: map[string]any{"active_sessions": 0},
+	})
+	return nil
+}
+
+func (s *adminService) ListFiles(ctx context.Context, query dto.AdminFileListQuery, page, perPage int) ([]dto.FileResponse, int64, error) {
 	limit, offset := pagination.LimitOffset(page, perPage)
+	tag := normalizeTag(query.Tag)
 
 	// Note: List and Count are separate queries; minor pagination inconsistency is acceptable for read-only operations.
-	files, err := s.fileRepo.AdminList(ctx, limit, offset)
+	files, err := s.fileRepo.AdminList(ctx, tag, limit, offset)
 	if err != nil {
 		return nil, 0, apperror.NewInternal("failed to list files")
 	}
 
-	total, err := s.fileRepo.AdminCount(ctx)
+	total, err := s.fileRepo.AdminCount(ctx, tag)
 	if err != nil {
 		return nil, 0, apperror.NewInternal("failed to count files")
 	}
 
-	responses := make([]dto.FileResponse, len(files))
-	for i, f := range files {
-		responses[i] = dto.FileResponse{
-			ID:           f.ID,
-			OriginalName: f.OriginalName,
-			MimeType:     f.MimeType,
-			Size:         f.Size,
-			URL:          s.storage.URL(f.StoragePath),
-			CreatedAt:    f.CreatedAt.Time,
-		}
+	responses, err := toFileResponses(ctx, s.fileRepo, s.storage, files)
+	if err != nil {
+		return nil, 0, apperror.NewInternal("failed to list file details")
 	}
 
 	return responses, total, nil
 }
 
+// DeleteFile soft-deletes any user's file. It stays restorable until the retention period
+// has passed.
+func (s *adminService) DeleteFile(ctx context.Context, id int64) error {
+	file, err := s.fileRepo.Delete(ctx, id)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return apperror.NewNotFound("file not found or already deleted")
+		}
+		return apperror.NewInternal("failed to delete file")
+	}
+	recordAudit(ctx, s.auditLog, AuditEntry{
+		Action: dto.AuditFileDeleted, TargetType: dto.AuditTargetFile, TargetID: id,
+		Before: map[string]any{"deleted": false}, After: map[string]any{"deleted": true},
+	})
+	forgetStats(ctx, s.cache)
+
+	slog.Info("file soft-deleted by admin",
+		slog.Int64("file_id", id),
+		slog.String("path", file.StoragePath),
+	)
+	return nil
+}
+
+// RestoreFile undeletes a soft-deleted file that is still within the retention period.
+func (s *adminService) RestoreFile(ctx context.Context, id int64) (*dto.FileResponse, error) {
+	var deletedAfter time.Time
+	if s.fileRetention > 0 {
+		deletedAfter = time.Now().Add(-s.fileRetention)
+	}
+
+	file, err := s.fileRepo.Restore(ctx, id, deletedAfter)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("file not found, not deleted or past its retention period")
+		}
+		return nil, apperror.NewInternal("failed to restore file")
+	}
+	recordAudit(ctx, s.auditLog, AuditEntry{
+		Action: dto.AuditFileRestored, TargetType: dto.AuditTargetFile, TargetID: id,
+		Before: map[string]any{"deleted": true}, After: map[string]any{"deleted": false},
+	})
+	forgetStats(ctx, s.cache)
+
+	responses, err := toFileResponses(ctx, s.fileRepo, s.storage, []sqlc.File{*file})
+	if err != nil {
+		return nil, apperror.NewInternal("failed to get file details")
+	}
+	return &responses[0], nil
+}
+
+// PurgeFile permanently removes a file, deleted or not, with its versions and variants, and
+// deletes the stored objects no other file uses.
+func (s *adminService) PurgeFile(ctx context.Context, id int64) error {
+	file, err := s.fileRepo.AdminGet(ctx, id)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return apperror.NewNotFound("file not found")
+		}
+		return apperror.NewInternal("failed to get file")
+	}
+
+	paths, err := s.fileRepo.Purge(ctx, id)
+	if err != nil {
+		return apperror.NewInternal("failed to purge file")
+	}
+	recordAudit(ctx, s.auditLog, AuditEntry{
+		Action: dto.AuditFilePurged, TargetType: dto.AuditTargetFile, TargetID: id,
+		Before: map[string]any{"owner_id": file.UserID, "size": file.Size, "deleted": file.DeletedAt.Valid},
+	})
+	forgetStats(ctx, s.cache)
+
+	// The rows are gone; an object that fails to delete is left for storage reconciliation
+	for _, p := range paths {
+		if err := s.storage.Delete(ctx, p); err != nil {
+			slog.Error("failed to delete stored file", slog.String("path", p), slog.Any("error", err))
+		}
+	}
+	return nil
+}
+
+// DownloadFile opens the content of any user's file, including a soft-deleted one. The
+// caller closes the reader.
+func (s *adminService) DownloadFile(ctx context.Context, id int64) (*sqlc.File, io.ReadCloser, error) {
+	file, err := s.fileRepo.AdminGet(ctx, id)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, nil, apperror.NewNotFound("file not found")
+		}
+		return nil, nil, apperror.NewInternal("failed to get file")
+	}
+
+	reader, err := s.storage.Get(ctx, file.StoragePath)
+	if err != nil {
+		return nil, nil, apperror.NewInternal("failed to read file from storage")
+	}
+	recordAudit(ctx, s.auditLog, AuditEntry{Action: dto.AuditFileDownloaded, TargetType: dto.AuditTargetFile, TargetID: id})
+
+	return file, reader, nil
+}
+
+// PurgeDeletedFiles permanently removes files that were soft-deleted longer ago than the
+// retention period, with their versions and variants, and deletes the stored objects no
+// other file uses. It does nothing when retention is disabled.
+func (s *adminService) PurgeDeletedFiles(ctx context.Context) (int, error) {
+	if s.fileRetention <= 0 {
+		return 0, nil
+	}
+
+	cutoff := time.Now().Add(-s.fileRetention)
+	ids, err := s.fileRepo.ListDeletedBefore(ctx, cutoff, filePurgeBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("list expired soft-deleted files: %w", err)
+	}
+
+	purged := 0
+	for _, id := range ids {
+		paths, err := s.fileRepo.PurgeDeleted(ctx, id, cutoff)
+		if err != nil {
+			slog.Error("failed to purge soft-deleted file", slog.Int64("file_id", id), slog.Any("error", err))
+			continue
+		}
+		// The rows are gone; an object that fails to delete is left for storage reconciliation
+		for _, p := range paths {
+			if err := s.storage.Delete(ctx, p); err != nil {
+				slog.Error("failed to delete stored file", slog.String("path", p), slog.Any("error", err))
+			}
+		}
+		purged++
+	}
+	if purged > 0 {
+		forgetStats(ctx, s.cache)
+		slog.Info("soft-deleted files purged", slog.Int("count", purged))
+	}
+	return purged, nil
+}
+
 func (s *adminService) GetStats(ctx context.Context) (*dto.AdminStatsResponse, error) {
+	ctx, span := telemetry.Start(ctx, "AdminService.GetStats")
+	defer span.End()
+
 	stats, err := s.userRepo.GetSystemStats(ctx)
 	if err != nil {
 		return nil, apperror.NewInternal("failed to get system stats")
@@ -140,8 +590,362 @@ func (s *adminService) GetStats(ctx context.Context) (*dto.AdminStatsResponse, e
 
 	return &dto.AdminStatsResponse{
 		ActiveUsers:   stats.ActiveUsers,
+		BannedUsers:   stats.BannedUsers,
 		DeletedUsers:  stats.DeletedUsers,
 		TotalFiles:    stats.TotalFiles,
 		TotalFileSize: stats.TotalFileSize,
 	}, nil
 }
+
+// GetStatsSeries returns daily signups, active users, uploads and stored bytes for the last
+// days UTC days, including today, with days without activity reported as zero.
+func (s *adminService) GetStatsSeries(ctx context.Context, days int) (*dto.AdminStatsSeriesResponse, error) {
+	if days <= 0 {
+		days = defaultStatsSeriesDays
+	}
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, 1-days)
+
+	rows, err := s.userRepo.GetDailyStats(ctx, from, to)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to get daily stats")
+	}
+
+	resp := &dto.AdminStatsSeriesResponse{
+		From: from.Format(time.DateOnly),
+		To:   to.Format(time.DateOnly),
+		Days: make([]dto.AdminDailyStats, len(rows)),
+	}
+	for i, row := range rows {
+		resp.Days[i] = dto.AdminDailyStats{
+			Date:          row.Day.Time.Format(time.DateOnly),
+			Signups:       row.Signups,
+			ActiveUsers:   row.ActiveUsers,
+			Uploads:       row.Uploads,
+			UploadedBytes: row.UploadedBytes,
+			StorageBytes:  row.StorageBytes,
+		}
+	}
+	return resp, nil
+}
+
+// Search finds users by email or name and files by name, including deleted ones, from a
+// fragment of any of them. Exact matches come first, then prefixes, then other substrings,
+// newest first within each; up to query.Limit hits are returned.
+func (s *adminService) Search(ctx context.Context, query dto.AdminSearchQuery) ([]dto.AdminSearchHit, error) {
+	ctx, span := telemetry.Start(ctx, "AdminService.Search")
+	defer span.End()
+
+	term := strings.TrimSpace(query.Q)
+	if len([]rune(term)) < 2 {
+		return nil, apperror.NewBadRequest("search term must be at least 2 characters")
+	}
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultSearchLimit
+	}
+
+	type rankedHit struct {
+		hit  dto.AdminSearchHit
+		rank int32
+	}
+	var hits []rankedHit
+
+	if query.Type == "" || query.Type == dto.SearchHitUser {
+		users, err := s.userRepo.Search(ctx, term, int32(limit))
+		if err != nil {
+			return nil, apperror.NewInternal("failed to search users")
+		}
+		for _, u := range users {
+			hits = append(hits, rankedHit{rank: u.Rank, hit: dto.AdminSearchHit{
+				Type: dto.SearchHitUser, ID: u.ID, Title: u.Name, Subtitle: u.Email, UserID: u.ID,
+				Role: u.Role, Deleted: u.DeletedAt.Valid, CreatedAt: u.CreatedAt.Time,
+			}})
+		}
+	}
+	if query.Type == "" || query.Type == dto.SearchHitFile {
+		files, err := s.fileRepo.AdminSearch(ctx, term, int32(limit))
+		if err != nil {
+			return nil, apperror.NewInternal("failed to search files")
+		}
+		for _, f := range files {
+			hits = append(hits, rankedHit{rank: f.Rank, hit: dto.AdminSearchHit{
+				Type: dto.SearchHitFile, ID: f.ID, Title: f.OriginalName, Subtitle: f.OwnerEmail, UserID: f.UserID,
+				MimeType: f.MimeType, Size: f.Size, Deleted: f.DeletedAt.Valid, CreatedAt: f.CreatedAt.Time,
+			}})
+		}
+	}
+
+	slices.SortStableFunc(hits, func(a, b rankedHit) int {
+		if a.rank != b.rank {
+			return cmp.Compare(a.rank, b.rank)
+		}
+		return b.hit.CreatedAt.Compare(a.hit.CreatedAt)
+	})
+
+	result := make([]dto.AdminSearchHit, 0, min(len(hits), limit))
+	for _, h := range hits[:min(len(hits), limit)] {
+		result = append(result, h.hit)
+	}
+	return result, nil
+}
+
+func (s *adminService) Impersonate(ctx context.Context, adminID, targetID int64) (*sqlc.User, error) {
+	if adminID == targetID {
+		return nil, apperror.NewBadRequest("cannot impersonate yourself")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, targetID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("user not found")
+		}
+		return nil, apperror.NewInternal("failed to get user")
+	}
+
+	if user.Role == dto.RoleAdmin {
+		return nil, apperror.NewForbidden("cannot impersonate another admin")
+	}
+	if err := s.checkImpersonationGrants(ctx, adminID, targetID); err != nil {
+		return nil, err
+	}
+
+	slog.Info("admin impersonation started",
+		slog.Int64("admin_id", adminID),
+		slog.Int64("target_user_id", targetID),
+	)
+	recordAudit(ctx, s.auditLog, AuditEntry{Action: dto.AuditUserImpersonated, TargetType: dto.AuditTargetUser, TargetID: targetID})
+
+	return user, nil
+}
+
+// checkImpersonationGrants refuses targets holding a permission the admin lacks, such as a
+// custom role with roles:manage: the impersonation token acts with the target's permissions,
+// so it would otherwise hand them to the admin.
+func (s *adminService) checkImpersonationGrants(ctx context.Context, adminID, targetID int64) error {
+	held, err := s.roleRepo.ListUserPermissions(ctx, adminID)
+	if err != nil {
+		return apperror.NewInternal("failed to check permissions")
+	}
+	granted, err := s.roleRepo.ListUserPermissions(ctx, targetID)
+	if err != nil {
+		return apperror.NewInternal("failed to check permissions")
+	}
+	for _, p := range granted {
+		if !slices.Contains(held, p) {
+			return apperror.NewForbidden("cannot impersonate a user with permissions you lack")
+		}
+	}
+	return nil
+}
+
+// bulkRepos are the repositories a bulk action item runs against.
+type bulkRepos struct {
+	users  repository.UserRepository
+	tokens repository.RefreshTokenRepository
+}
+
+func newBulkRepos(db sqlc.DBTX) bulkRepos {
+	return bulkRepos{
+		users:  repository.NewUserRepository(db),
+		tokens: repository.NewRefreshTokenRepository(db),
+	}
+}
+
+// BulkUsers applies one action to many users inside a single transaction. Each item runs in
+// its own savepoint, so a failing item is rolled back and reported without affecting the
+// others. Access tokens are revoked only after the commit. Deleted users are soft-deleted
+// like through DELETE /users/:id, so they can be restored until the retention job purges
+// them.
+func (s *adminService) BulkUsers(ctx context.Context, actorID int64, req dto.BulkUserActionRequest) (*dto.BulkUserActionResponse, error) {
+	ctx, span := telemetry.Start(ctx, "AdminService.BulkUsers")
+	defer span.End()
+
+	var (
+		results []dto.BulkUserActionResult
+		revoke  []int64
+		audits  []AuditEntry
+	)
+	record := func(id int64, audit AuditEntry, err error) {
+		if err != nil {
+			results = append(results, dto.BulkUserActionResult{UserID: id, Error: bulkErrorMessage(err)})
+			return
+		}
+		results = append(results, dto.BulkUserActionResult{UserID: id, Success: true})
+		audits = append(audits, audit)
+		if req.Action != dto.BulkActionUnban {
+			revoke = append(revoke, id)
+		}
+	}
+
+	if s.txManager == nil {
+		repos := bulkRepos{users: s.userRepo, tokens: s.refreshTokenRepo}
+		for _, id := range req.UserIDs {
+			audit, err := s.applyBulkAction(ctx, repos, actorID, id, req)
+			record(id, audit, err)
+		}
+	} else {
+		err := s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+			for _, id := range req.UserIDs {
+				sp, err := tx.Begin(ctx)
+				if err != nil {
+					return err
+				}
+				audit, err := s.applyBulkAction(ctx, newBulkRepos(sp), actorID, id, req)
+				if err != nil {
+					if rbErr := sp.Rollback(ctx); rbErr != nil {
+						return rbErr
+					}
+				} else if err := sp.Commit(ctx); err != nil {
+					return err
+				}
+				record(id, audit, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, apperror.NewInternal("failed to apply bulk action")
+		}
+	}
+
+	for _, entry := range audits {
+		recordAudit(ctx, s.auditLog, entry)
+	}
+	for _, r := range results {
+		if r.Success {
+			forgetUser(ctx, s.cache, r.UserID)
+		}
+	}
+	if len(audits) > 0 {
+		forgetStats(ctx, s.cache)
+	}
+	for _, id := range revoke {
+		if err := s.revocations.RevokeUser(ctx, id); err != nil {
+			slog.Error("failed to revoke access tokens", slog.Int64("user_id", id), slog.Any("error", err))
+		}
+	}
+	resp := &dto.BulkUserActionResponse{Results: results}
+	for _, r := range results {
+		if r.Success {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+		}
+	}
+	return resp, nil
+}
+
+// applyBulkAction runs the action for one user and returns the audit log entry to record
+// once the transaction has committed.
+func (s *adminService) applyBulkAction(
+	ctx context.Context,
+	repos bulkRepos,
+	actorID, id int64,
+	req dto.BulkUserActionRequest,
+) (AuditEntry, error) {
+	if id == actorID {
+		return AuditEntry{}, apperror.NewBadRequest("cannot apply bulk actions to your own account")
+	}
+
+	// keepAdmin refuses to ban or delete the last active admin. Banned and missing users
+	// are not active admins; the action itself reports them.
+	keepAdmin := func() error {
+		target, err := repos.users.GetByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				return nil
+			}
+			return err
+		}
+		return checkAdminRemoval(ctx, repos.users, target)
+	}
+
+	switch req.Action {
+	case dto.BulkActionBan:
+		if err := keepAdmin(); err != nil {
+			return AuditEntry{}, err
+		}
+		if _, err := repos.users.Ban(ctx, id, req.Reason); err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				return AuditEntry{}, apperror.NewNotFound("user not found or already banned")
+			}
+			return AuditEntry{}, err
+		}
+		return bannedEntry(id, req.Reason), repos.tokens.DeleteByUserID(ctx, id)
+
+	case dto.BulkActionUnban:
+		if _, err := repos.users.Unban(ctx, id); err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				return AuditEntry{}, apperror.NewNotFound("user not found or not banned")
+			}
+			return AuditEntry{}, err
+		}
+		return unbannedEntry(id), nil
+
+	case dto.BulkActionDelete:
+		if err := keepAdmin(); err != nil {
+			return AuditEntry{}, err
+		}
+		if _, err := repos.users.Delete(ctx, id); err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				return AuditEntry{}, apperror.NewNotFound("user not found")
+			}
+			return AuditEntry{}, err
+		}
+		return AuditEntry{
+			Action: dto.AuditUserDeleted, TargetType: dto.AuditTargetUser, TargetID: id,
+			Before: map[string]any{"deleted": false}, After: map[string]any{"deleted": true},
+		}, repos.tokens.DeleteByUserID(ctx, id)
+
+	case dto.BulkActionSetRole:
+		current, err := repos.users.GetByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				return AuditEntry{}, apperror.NewNotFound("user not found")
+			}
+			return AuditEntry{}, err
+		}
+		previousRole := current.Role
+		if previousRole == dto.RoleAdmin && req.Role != dto.RoleAdmin {
+			if err := checkAdminRemoval(ctx, repos.users, current); err != nil {
+				return AuditEntry{}, err
+			}
+		}
+		if _, err := repos.users.UpdateRole(ctx, sqlc.UpdateUserRoleParams{ID: id, Role: req.Role}); err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				return AuditEntry{}, apperror.NewNotFound("user not found")
+			}
+			return AuditEntry{}, err
+		}
+		return AuditEntry{
+			Action: dto.AuditUserRoleChanged, TargetType: dto.AuditTargetUser, TargetID: id,
+			Before: map[string]any{"role": previousRole}, After: map[string]any{"role": req.Role},
+		}, nil
+	}
+
+	return AuditEntry{}, apperror.NewBadRequest("unknown bulk action")
+}
+
+func bannedEntry(id int64, reason string) AuditEntry {
+	return AuditEntry{
+		Action: dto.AuditUserBanned, TargetType: dto.AuditTargetUser, TargetID: id,
+		Before: map[string]any{"banned": false}, After: map[string]any{"banned": true, "reason": reason},
+	}
+}
+
+func unbannedEntry(id int64) AuditEntry {
+	return AuditEntry{
+		Action: dto.AuditUserUnbanned, TargetType: dto.AuditTargetUser, TargetID: id,
+		Before: map[string]any{"banned": true}, After: map[string]any{"banned": false},
+	}
+}
+
+// bulkErrorMessage reports AppError messages as is and hides everything else.
+func bulkErrorMessage(err error) string {
+	var appErr *apperror.AppError
+	if errors.As(err, &appErr) {
+		return appErr.Message
+	}
+	slog.Error("bulk user action failed", slog.Any("error", err))
+	return "internal error"
+}
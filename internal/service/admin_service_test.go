@@ -0,0 +1,1034 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/token"
+)
+
+func newTestAdminService(userRepo *mockUserRepo) AdminService {
+	return NewAdminService(
+		userRepo, newMockFileRepo(), newMockRefreshTokenRepo(), newMockRoleRepo(userRepo), newMockStorage(),
+		token.NewRevocationStore(newMockCache(), time.Hour), newMockCache(), nil, nil, 30,
+	)
+}
+
+// ---------------------------------------------------------------------------
+// Impersonate
+// ---------------------------------------------------------------------------
+
+func TestImpersonate(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		repo := newMockUserRepo()
+		repo.users[2] = &sqlc.User{ID: 2, Email: "user@example.com", Name: "User", Role: "user"}
+		svc := newTestAdminService(repo)
+
+		user, err := svc.Impersonate(context.Background(), 1, 2)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if user.ID != 2 {
+			t.Errorf("expected user ID 2, got %d", user.ID)
+		}
+	})
+
+	t.Run("self", func(t *testing.T) {
+		svc := newTestAdminService(newMockUserRepo())
+
+		_, err := svc.Impersonate(context.Background(), 1, 1)
+		var appErr *apperror.AppError
+		if !errors.As(err, &appErr) {
+			t.Fatalf("expected AppError, got %T", err)
+		}
+		if appErr.Code != 400 {
+			t.Errorf("expected 400, got %d", appErr.Code)
+		}
+	})
+
+	t.Run("another admin", func(t *testing.T) {
+		repo := newMockUserRepo()
+		repo.users[2] = &sqlc.User{ID: 2, Email: "admin2@example.com", Name: "Admin", Role: "admin"}
+		svc := newTestAdminService(repo)
+
+		_, err := svc.Impersonate(context.Background(), 1, 2)
+		var appErr *apperror.AppError
+		if !errors.As(err, &appErr) {
+			t.Fatalf("expected AppError, got %T", err)
+		}
+		if appErr.Code != 403 {
+			t.Errorf("expected 403, got %d", appErr.Code)
+		}
+	})
+
+	t.Run("custom role with permissions the admin lacks", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newMockUserRepo()
+		repo.users[1] = &sqlc.User{ID: 1, Email: "support@example.com", Name: "Support", Role: "user"}
+		repo.users[2] = &sqlc.User{ID: 2, Email: "manager@example.com", Name: "Manager", Role: "user"}
+		roles := newMockRoleRepo(repo)
+		support, _ := roles.Create(ctx, "support", "")
+		_, _ = roles.AddPermissions(ctx, support.ID, []string{dto.PermissionUsersImpersonate})
+		manager, _ := roles.Create(ctx, "manager", "")
+		_, _ = roles.AddPermissions(ctx, manager.ID, []string{dto.PermissionRolesManage, dto.PermissionUsersManage})
+		roles.userRoles[1] = []int64{support.ID}
+		roles.userRoles[2] = []int64{manager.ID}
+		svc := NewAdminService(repo, newMockFileRepo(), newMockRefreshTokenRepo(), roles, newMockStorage(),
+			token.NewRevocationStore(newMockCache(), time.Hour), newMockCache(), nil, nil, 30)
+
+		_, err := svc.Impersonate(ctx, 1, 2)
+		var appErr *apperror.AppError
+		if !errors.As(err, &appErr) {
+			t.Fatalf("expected AppError, got %T", err)
+		}
+		if appErr.Code != 403 {
+			t.Errorf("expected 403, got %d", appErr.Code)
+		}
+
+		roles.userRoles[1] = []int64{support.ID, manager.ID}
+		if _, err := svc.Impersonate(ctx, 1, 2); err != nil {
+			t.Errorf("expected an admin holding the same permissions allowed, got %v", err)
+		}
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		svc := newTestAdminService(newMockUserRepo())
+
+		_, err := svc.Impersonate(context.Background(), 1, 999)
+		var appErr *apperror.AppError
+		if !errors.As(err, &appErr) {
+			t.Fatalf("expected AppError, got %T", err)
+		}
+		if appErr.Code != 404 {
+			t.Errorf("expected 404, got %d", appErr.Code)
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// ExportUsers
+// ---------------------------------------------------------------------------
+
+func TestExportUsers(t *testing.T) {
+	t.Run("walks every page in ID order", func(t *testing.T) {
+		repo := newMockUserRepo()
+		total := userExportBatchSize + 3
+		for id := int64(1); id <= int64(total); id++ {
+			repo.users[id] = &sqlc.User{ID: id, Email: fmt.Sprintf("user%d@example.com", id), Name: "User", Role: "user"}
+		}
+		svc := newTestAdminService(repo)
+
+		var ids []int64
+		err := svc.ExportUsers(context.Background(), dto.UserFilterQuery{}, func(u dto.AdminUserResponse) error {
+			ids = append(ids, u.ID)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(ids) != total || ids[0] != 1 || ids[total-1] != int64(total) {
+			t.Errorf("expected IDs 1..%d, got %d IDs", total, len(ids))
+		}
+		if repo.listAfterCalls != 2 {
+			t.Errorf("expected 2 page queries, got %d", repo.listAfterCalls)
+		}
+	})
+
+	t.Run("includes deleted users and applies filters", func(t *testing.T) {
+		repo := newMockUserRepo()
+		repo.users[1] = &sqlc.User{ID: 1, Email: "a@example.com", Name: "A", Role: "admin"}
+		repo.users[2] = &sqlc.User{ID: 2, Email: "b@example.com", Name: "B", Role: "user", DeletedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}}
+		svc := newTestAdminService(repo)
+
+		var ids []int64
+		_ = svc.ExportUsers(context.Background(), dto.UserFilterQuery{Role: "user"}, func(u dto.AdminUserResponse) error {
+			ids = append(ids, u.ID)
+			return nil
+		})
+		if len(ids) != 1 || ids[0] != 2 {
+			t.Errorf("expected only user 2, got %v", ids)
+		}
+	})
+
+	t.Run("callback error stops the export", func(t *testing.T) {
+		repo := newMockUserRepo()
+		repo.users[1] = &sqlc.User{ID: 1, Email: "a@example.com", Name: "A", Role: "user"}
+		repo.users[2] = &sqlc.User{ID: 2, Email: "b@example.com", Name: "B", Role: "user"}
+		svc := newTestAdminService(repo)
+
+		errClosed := errors.New("client went away")
+		calls := 0
+		err := svc.ExportUsers(context.Background(), dto.UserFilterQuery{}, func(dto.AdminUserResponse) error {
+			calls++
+			return errClosed
+		})
+		if !errors.Is(err, errClosed) || calls != 1 {
+			t.Errorf("expected export to stop after the first error, got %v after %d calls", err, calls)
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// BulkUsers
+// ---------------------------------------------------------------------------
+
+func TestBulkUsers(t *testing.T) {
+	newFixture := func() (AdminService, *mockUserRepo, *mockFileRepo, *mockStorage) {
+		users := newMockUserRepo()
+		for id := int64(1); id <= 3; id++ {
+			users.users[id] = &sqlc.User{ID: id, Email: fmt.Sprintf("user%d@example.com", id), Name: "User", Role: "user"}
+		}
+		files := newMockFileRepo()
+		store := newMockStorage()
+		svc := NewAdminService(users, files, newMockRefreshTokenRepo(), nil, store, token.NewRevocationStore(newMockCache(), time.Hour), newMockCache(), nil, nil, 30)
+		return svc, users, files, store
+	}
+
+	t.Run("reports each item", func(t *testing.T) {
+		svc, users, _, _ := newFixture()
+
+		resp, err := svc.BulkUsers(context.Background(), 1, dto.BulkUserActionRequest{
+			Action:  dto.BulkActionSetRole,
+			UserIDs: []int64{1, 2, 99},
+			Role:    dto.RoleAdmin,
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if resp.Succeeded != 1 || resp.Failed != 2 {
+			t.Errorf("expected 1 success and 2 failures, got %+v", resp)
+		}
+		want := []dto.BulkUserActionResult{
+			{UserID: 1, Error: "cannot apply bulk actions to your own account"},
+			{UserID: 2, Success: true},
+			{UserID: 99, Error: "user not found"},
+		}
+		for i, r := range resp.Results {
+			if r != want[i] {
+				t.Errorf("result %d: expected %+v, got %+v", i, want[i], r)
+			}
+		}
+		if users.users[2].Role != dto.RoleAdmin {
+			t.Errorf("expected user 2 to be promoted, got %q", users.users[2].Role)
+		}
+	})
+
+	t.Run("delete is a soft delete", func(t *testing.T) {
+		svc, users, files, store := newFixture()
+		f, _ := files.Create(context.Background(), sqlc.CreateFileParams{UserID: 2, StoragePath: "uploads/2/a.png"})
+		store.files[f.StoragePath] = []byte("x")
+
+		resp, err := svc.BulkUsers(context.Background(), 1, dto.BulkUserActionRequest{
+			Action:  dto.BulkActionDelete,
+			UserIDs: []int64{2, 3},
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if resp.Succeeded != 2 {
+			t.Errorf("expected 2 successes, got %+v", resp)
+		}
+		if u, ok := users.users[2]; !ok || !u.DeletedAt.Valid {
+			t.Error("expected user 2 to be soft-deleted")
+		}
+		if _, ok := store.files["uploads/2/a.png"]; !ok {
+			t.Error("expected stored files to be left to the retention purge")
+		}
+		if _, err := svc.RestoreUser(context.Background(), 2); err != nil {
+			t.Errorf("expected user 2 to be restorable, got %v", err)
+		}
+	})
+
+	t.Run("delete signs the users out", func(t *testing.T) {
+		users := newMockUserRepo()
+		users.users[1] = &sqlc.User{ID: 1, Email: "admin@example.com", Role: dto.RoleAdmin}
+		users.users[2] = &sqlc.User{ID: 2, Email: "user@example.com", Role: "user"}
+		tokens := newMockRefreshTokenRepo()
+		revocations := token.NewRevocationStore(newMockCache(), time.Hour)
+		svc := NewAdminService(users, newMockFileRepo(), tokens, nil, newMockStorage(), revocations, newMockCache(), nil, nil, 30)
+		issued := &token.Claims{
+			UserID:           2,
+			RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))},
+		}
+
+		_, err := svc.BulkUsers(context.Background(), 1, dto.BulkUserActionRequest{Action: dto.BulkActionDelete, UserIDs: []int64{2}})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !slices.Contains(tokens.deletedUserIDs, 2) {
+			t.Error("expected the refresh tokens of user 2 to be deleted")
+		}
+		if revoked, _ := revocations.IsRevoked(context.Background(), issued); !revoked {
+			t.Error("expected the access tokens of user 2 to be revoked")
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// User detail
+// ---------------------------------------------------------------------------
+
+func TestGetUserDetail(t *testing.T) {
+	now := time.Now()
+	repo := newMockUserRepo()
+	repo.users[2] = &sqlc.User{
+		ID: 2, Email: "user@example.com", Name: "User", Role: "user", AuthProvider: "local",
+		PasswordHash: pgtype.Text{String: "hash", Valid: true},
+		GithubID:     pgtype.Text{String: "42", Valid: true},
+		DeletedAt:    pgtype.Timestamptz{Time: now, Valid: true},
+	}
+	repo.users[3] = &sqlc.User{ID: 3, Email: "new@example.com", Name: "New", Role: "user", AuthProvider: "google",
+		GoogleID: pgtype.Text{String: "g-1", Valid: true}}
+	repo.details = map[int64]sqlc.GetUserDetailRow{2: {
+		ActiveSessions: 2, FileCount: 3, FileSize: 300,
+		LastLoginAt: pgtype.Timestamptz{Time: now, Valid: true}, LastLoginMethod: "password", LastLoginIp: "203.0.113.7",
+	}}
+	svc := newTestAdminService(repo)
+
+	t.Run("linked resources", func(t *testing.T) {
+		resp, err := svc.GetUser(context.Background(), 2)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if resp.ID != 2 || resp.Email != "user@example.com" || !resp.HasPassword || resp.DeletedAt == nil {
+			t.Errorf("unexpected user record %+v", resp)
+		}
+		if !slices.Equal(resp.LinkedProviders, []string{"github"}) {
+			t.Errorf("expected github linked, got %v", resp.LinkedProviders)
+		}
+		if resp.ActiveSessions != 2 || resp.FileCount != 3 || resp.FileSize != 300 {
+			t.Errorf("unexpected counts %+v", resp)
+		}
+		if resp.LastLogin == nil || resp.LastLogin.Method != "password" || resp.LastLogin.IPAddress != "203.0.113.7" {
+			t.Errorf("unexpected last login %+v", resp.LastLogin)
+		}
+	})
+
+	t.Run("never signed in", func(t *testing.T) {
+		resp, err := svc.GetUser(context.Background(), 3)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if resp.HasPassword || resp.LastLogin != nil || resp.DeletedAt != nil || !slices.Equal(resp.LinkedProviders, []string{"google"}) {
+			t.Errorf("unexpected detail %+v", resp)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := svc.GetUser(context.Background(), 99)
+		assertAppErrorCode(t, err, 404)
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Stats series
+// ---------------------------------------------------------------------------
+
+func TestStatsInvalidation(t *testing.T) {
+	deleted := pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true}
+	newFixture := func() (AdminService, *mockCache) {
+		users := newMockUserRepo()
+		users.users[1] = &sqlc.User{ID: 1, Email: "admin@example.com", Role: dto.RoleAdmin}
+		users.users[2] = &sqlc.User{ID: 2, Email: "deleted@example.com", Role: "user", DeletedAt: deleted}
+		users.users[3] = &sqlc.User{ID: 3, Email: "user@example.com", Role: "user"}
+		files := newMockFileRepo()
+		files.files[1] = &sqlc.File{ID: 1, UserID: 3, StoragePath: "3/a.txt"}
+		files.files[2] = &sqlc.File{ID: 2, UserID: 3, StoragePath: "3/b.txt", DeletedAt: deleted}
+		appCache := newMockCache()
+		appCache.cacheStats()
+		svc := NewAdminService(users, files, newMockRefreshTokenRepo(), nil, newMockStorage(),
+			token.NewRevocationStore(newMockCache(), time.Hour), appCache, nil, nil, 30)
+		return svc, appCache
+	}
+	ctx := context.Background()
+
+	changes := map[string]func(AdminService) error{
+		"ban user":     func(svc AdminService) error { return svc.BanUser(ctx, 1, 3, "spam") },
+		"restore user": func(svc AdminService) error { _, err := svc.RestoreUser(ctx, 2); return err },
+		"delete file":  func(svc AdminService) error { return svc.DeleteFile(ctx, 1) },
+		"restore file": func(svc AdminService) error { _, err := svc.RestoreFile(ctx, 2); return err },
+		"purge file":   func(svc AdminService) error { return svc.PurgeFile(ctx, 1) },
+		"bulk delete": func(svc AdminService) error {
+			_, err := svc.BulkUsers(ctx, 1, dto.BulkUserActionRequest{Action: dto.BulkActionDelete, UserIDs: []int64{3}})
+			return err
+		},
+	}
+	for name, change := range changes {
+		t.Run(name, func(t *testing.T) {
+			svc, appCache := newFixture()
+			if err := change(svc); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if appCache.statsCached() {
+				t.Error("expected cached stats to be dropped")
+			}
+		})
+	}
+
+	t.Run("failed change keeps stats", func(t *testing.T) {
+		svc, appCache := newFixture()
+		if err := svc.DeleteFile(ctx, 99); err == nil {
+			t.Fatal("expected an error for a missing file")
+		}
+		_, err := svc.BulkUsers(ctx, 1, dto.BulkUserActionRequest{Action: dto.BulkActionDelete, UserIDs: []int64{1}})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !appCache.statsCached() {
+			t.Error("expected cached stats to be kept")
+		}
+	})
+}
+
+func TestGetStats(t *testing.T) {
+	users := newMockUserRepo()
+	users.users[1] = &sqlc.User{ID: 1, Email: "admin@example.com", Role: dto.RoleAdmin}
+	users.users[2] = &sqlc.User{ID: 2, Email: "user@example.com", Role: dto.RoleUser}
+	users.users[3] = &sqlc.User{ID: 3, Email: "deleted@example.com", Role: dto.RoleUser,
+		DeletedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}}
+	svc := newTestAdminService(users)
+	ctx := context.Background()
+
+	if err := svc.BanUser(ctx, 1, 2, "spam"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	stats, err := svc.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if stats.ActiveUsers != 1 || stats.BannedUsers != 1 || stats.DeletedUsers != 1 {
+		t.Errorf("expected banned users counted apart from active ones, got %+v", stats)
+	}
+}
+
+func TestGetStatsSeries(t *testing.T) {
+	repo := newMockUserRepo()
+	now := time.Now().UTC()
+	repo.users[1] = &sqlc.User{ID: 1, CreatedAt: pgtype.Timestamptz{Time: now, Valid: true}}
+	repo.users[2] = &sqlc.User{ID: 2, CreatedAt: pgtype.Timestamptz{Time: now.AddDate(0, 0, -1), Valid: true}}
+	repo.users[3] = &sqlc.User{ID: 3, CreatedAt: pgtype.Timestamptz{Time: now.AddDate(0, 0, -60), Valid: true}}
+	svc := newTestAdminService(repo)
+
+	t.Run("default window ends today", func(t *testing.T) {
+		resp, err := svc.GetStatsSeries(context.Background(), 0)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(resp.Days) != 30 {
+			t.Fatalf("expected 30 days, got %d", len(resp.Days))
+		}
+		last := resp.Days[len(resp.Days)-1]
+		if resp.To != now.Format(time.DateOnly) || last.Date != resp.To || resp.Days[0].Date != resp.From {
+			t.Errorf("expected days from %s to %s, got %s to %s", resp.From, resp.To, resp.Days[0].Date, last.Date)
+		}
+		if last.Signups != 1 || resp.Days[len(resp.Days)-2].Signups != 1 {
+			t.Errorf("expected one signup today and yesterday, got %+v", resp.Days[len(resp.Days)-2:])
+		}
+	})
+
+	t.Run("custom window", func(t *testing.T) {
+		resp, err := svc.GetStatsSeries(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(resp.Days) != 1 || resp.From != resp.To {
+			t.Errorf("expected only today, got %+v", resp)
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Deleted files
+// ---------------------------------------------------------------------------
+
+func TestDeletedFiles(t *testing.T) {
+	deletedAgo := func(d time.Duration) pgtype.Timestamptz {
+		return pgtype.Timestamptz{Time: time.Now().Add(-d), Valid: true}
+	}
+	newFixture := func(retentionDays int) (AdminService, *mockFileRepo, *mockStorage) {
+		files := newMockFileRepo()
+		store := newMockStorage()
+		svc := NewAdminService(newMockUserRepo(), files, newMockRefreshTokenRepo(), nil, store, token.NewRevocationStore(newMockCache(), time.Hour), newMockCache(), nil, nil, retentionDays)
+		return svc, files, store
+	}
+	const day = 24 * time.Hour
+
+	t.Run("restore within the retention period", func(t *testing.T) {
+		svc, files, _ := newFixture(30)
+		files.files[1] = &sqlc.File{ID: 1, UserID: 1, StoragePath: "1/a.txt", DeletedAt: deletedAgo(29 * day)}
+		files.files[2] = &sqlc.File{ID: 2, UserID: 1, StoragePath: "1/b.txt", DeletedAt: deletedAgo(31 * day)}
+		files.files[3] = &sqlc.File{ID: 3, UserID: 1, StoragePath: "1/c.txt"}
+
+		resp, err := svc.RestoreFile(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if resp.DeletedAt != nil || files.files[1].DeletedAt.Valid {
+			t.Errorf("expected file 1 restored, got %+v", resp)
+		}
+		_, err = svc.RestoreFile(context.Background(), 2)
+		assertAppErrorCode(t, err, 404)
+		_, err = svc.RestoreFile(context.Background(), 3)
+		assertAppErrorCode(t, err, 404)
+	})
+
+	t.Run("purge removes files past the retention period", func(t *testing.T) {
+		svc, files, store := newFixture(30)
+		files.files[1] = &sqlc.File{ID: 1, UserID: 1, StoragePath: "1/old.txt", DeletedAt: deletedAgo(31 * day)}
+		files.files[2] = &sqlc.File{ID: 2, UserID: 1, StoragePath: "1/recent.txt", DeletedAt: deletedAgo(day)}
+		files.files[3] = &sqlc.File{ID: 3, UserID: 1, StoragePath: "1/live.txt"}
+		files.versions = []sqlc.FileVersion{{FileID: 1, Version: 1, StoragePath: "1/old.v1.txt"}}
+		files.variants = []sqlc.FileVariant{{FileID: 1, Name: "thumbnail", StoragePath: "1/old_thumbnail.jpg"}}
+		for _, p := range []string{"1/old.txt", "1/old.v1.txt", "1/old_thumbnail.jpg", "1/recent.txt", "1/live.txt"} {
+			store.files[p] = []byte("x")
+		}
+
+		n, err := svc.PurgeDeletedFiles(context.Background())
+		if err != nil || n != 1 {
+			t.Fatalf("expected 1 file purged, got %d (%v)", n, err)
+		}
+		if _, ok := files.files[1]; ok || len(files.versions) != 0 || len(files.variants) != 0 {
+			t.Error("expected file 1 and its versions and variants removed")
+		}
+		if len(store.files) != 2 || store.files["1/recent.txt"] == nil || store.files["1/live.txt"] == nil {
+			t.Errorf("expected only the other files' objects kept, got %v", slices.Collect(maps.Keys(store.files)))
+		}
+	})
+
+	t.Run("purge keeps objects shared with other files", func(t *testing.T) {
+		svc, files, store := newFixture(30)
+		files.files[1] = &sqlc.File{ID: 1, UserID: 1, StoragePath: "1/shared.txt", DeletedAt: deletedAgo(31 * day)}
+		files.files[2] = &sqlc.File{ID: 2, UserID: 1, StoragePath: "1/shared.txt"}
+		store.files["1/shared.txt"] = []byte("x")
+
+		if n, err := svc.PurgeDeletedFiles(context.Background()); err != nil || n != 1 {
+			t.Fatalf("expected 1 file purged, got %d (%v)", n, err)
+		}
+		if _, ok := store.files["1/shared.txt"]; !ok {
+			t.Error("expected the shared object kept")
+		}
+	})
+
+	t.Run("zero retention keeps deleted files", func(t *testing.T) {
+		svc, files, _ := newFixture(0)
+		files.files[1] = &sqlc.File{ID: 1, UserID: 1, StoragePath: "1/a.txt", DeletedAt: deletedAgo(365 * day)}
+
+		if n, err := svc.PurgeDeletedFiles(context.Background()); err != nil || n != 0 {
+			t.Fatalf("expected nothing purged, got %d (%v)", n, err)
+		}
+		if _, err := svc.RestoreFile(context.Background(), 1); err != nil {
+			t.Errorf("expected an old file to be restorable, got %v", err)
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// File moderation
+// ---------------------------------------------------------------------------
+
+func TestFileModeration(t *testing.T) {
+	newFixture := func() (AdminService, *mockFileRepo, *mockStorage, *mockAuditLogRepo) {
+		files := newMockFileRepo()
+		store := newMockStorage()
+		files.files[1] = &sqlc.File{ID: 1, UserID: 2, StoragePath: "2/a.txt", OriginalName: "a.txt", Size: 5}
+		store.files["2/a.txt"] = []byte("hello")
+		auditRepo := newMockAuditLogRepo()
+		svc := NewAdminService(newMockUserRepo(), files, newMockRefreshTokenRepo(), nil, store,
+			token.NewRevocationStore(newMockCache(), time.Hour), newMockCache(), nil, NewAuditLogService(auditRepo), 30)
+		return svc, files, store, auditRepo
+	}
+	ctx := WithAuditActor(context.Background(), AuditActor{UserID: 1})
+
+	t.Run("delete keeps the object for restore", func(t *testing.T) {
+		svc, files, store, auditRepo := newFixture()
+
+		if err := svc.DeleteFile(ctx, 1); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !files.files[1].DeletedAt.Valid || store.files["2/a.txt"] == nil {
+			t.Error("expected the file soft-deleted and its object kept")
+		}
+		if len(auditRepo.entries) != 1 || auditRepo.entries[0].Action != dto.AuditFileDeleted || auditRepo.entries[0].ActorID != 1 {
+			t.Errorf("expected a file.deleted entry, got %+v", auditRepo.entries)
+		}
+		assertAppErrorCode(t, svc.DeleteFile(ctx, 99), 404)
+	})
+
+	t.Run("purge removes a live file and its object", func(t *testing.T) {
+		svc, files, store, auditRepo := newFixture()
+
+		if err := svc.PurgeFile(ctx, 1); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if _, ok := files.files[1]; ok {
+			t.Error("expected the file row removed")
+		}
+		if _, ok := store.files["2/a.txt"]; ok {
+			t.Error("expected the stored object removed")
+		}
+		if len(auditRepo.entries) != 1 || auditRepo.entries[0].Action != dto.AuditFilePurged {
+			t.Errorf("expected a file.purged entry, got %+v", auditRepo.entries)
+		}
+		assertAppErrorCode(t, svc.PurgeFile(ctx, 1), 404)
+	})
+
+	t.Run("download reads soft-deleted files", func(t *testing.T) {
+		svc, files, _, auditRepo := newFixture()
+		files.files[1].DeletedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+		file, reader, err := svc.DownloadFile(ctx, 1)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		defer func() { _ = reader.Close() }()
+		data, _ := io.ReadAll(reader)
+		if file.OriginalName != "a.txt" || string(data) != "hello" {
+			t.Errorf("unexpected download %q: %q", file.OriginalName, data)
+		}
+		if len(auditRepo.entries) != 1 || auditRepo.entries[0].Action != dto.AuditFileDownloaded {
+			t.Errorf("expected a file.downloaded entry, got %+v", auditRepo.entries)
+		}
+		_, _, err = svc.DownloadFile(ctx, 99)
+		assertAppErrorCode(t, err, 404)
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Admin protection
+// ---------------------------------------------------------------------------
+
+func TestAdminProtection(t *testing.T) {
+	// Users 1 and 2 are admins, 3 is a regular user.
+	newFixture := func() (AdminService, *mockUserRepo) {
+		users := newMockUserRepo()
+		for id := int64(1); id <= 3; id++ {
+			users.users[id] = &sqlc.User{ID: id, Email: fmt.Sprintf("user%d@example.com", id), Name: "User", Role: dto.RoleAdmin}
+		}
+		users.users[3].Role = dto.RoleUser
+		return newTestAdminService(users), users
+	}
+	ctx := context.Background()
+
+	t.Run("cannot demote yourself", func(t *testing.T) {
+		svc, users := newFixture()
+		_, err := svc.UpdateRole(ctx, 1, 1, dto.RoleUser)
+		assertErrorCode(t, err, 400, dto.ErrorCodeSelfDemotion)
+		if users.users[1].Role != dto.RoleAdmin {
+			t.Error("expected the role unchanged")
+		}
+	})
+
+	t.Run("cannot ban yourself", func(t *testing.T) {
+		svc, users := newFixture()
+		assertErrorCode(t, svc.BanUser(ctx, 1, 1, ""), 400, dto.ErrorCodeSelfBan)
+		if users.users[1].BannedAt.Valid {
+			t.Error("expected the user not banned")
+		}
+	})
+
+	t.Run("the last admin is kept", func(t *testing.T) {
+		svc, users := newFixture()
+		// A user holding users:manage through a custom role can act without being an admin.
+		if _, err := svc.UpdateRole(ctx, 3, 2, dto.RoleUser); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		_, err := svc.UpdateRole(ctx, 3, 1, dto.RoleUser)
+		assertErrorCode(t, err, 409, dto.ErrorCodeLastAdmin)
+		assertErrorCode(t, svc.BanUser(ctx, 3, 1, ""), 409, dto.ErrorCodeLastAdmin)
+		if users.users[1].Role != dto.RoleAdmin {
+			t.Error("expected the last admin kept")
+		}
+
+		// Changes that do not remove an admin are unaffected.
+		if _, err := svc.UpdateRole(ctx, 1, 1, dto.RoleAdmin); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+		if err := svc.BanUser(ctx, 1, 2, ""); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("banned admins are not counted", func(t *testing.T) {
+		svc, _ := newFixture()
+		if err := svc.BanUser(ctx, 1, 2, ""); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		assertErrorCode(t, svc.BanUser(ctx, 3, 1, ""), 409, dto.ErrorCodeLastAdmin)
+	})
+
+	t.Run("bulk actions keep the last admin", func(t *testing.T) {
+		for _, req := range []dto.BulkUserActionRequest{
+			{Action: dto.BulkActionBan, UserIDs: []int64{1, 2}},
+			{Action: dto.BulkActionDelete, UserIDs: []int64{1, 2}},
+			{Action: dto.BulkActionSetRole, UserIDs: []int64{1, 2}, Role: dto.RoleUser},
+		} {
+			svc, _ := newFixture()
+			resp, err := svc.BulkUsers(ctx, 3, req)
+			if err != nil {
+				t.Fatalf("%s: expected no error, got %v", req.Action, err)
+			}
+			if resp.Succeeded != 1 || resp.Results[1].Error != "cannot remove the last admin" {
+				t.Errorf("%s: expected the second admin kept, got %+v", req.Action, resp.Results)
+			}
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Bans
+// ---------------------------------------------------------------------------
+
+func TestUserBans(t *testing.T) {
+	newFixture := func() (AdminService, *mockUserRepo) {
+		users := newMockUserRepo()
+		users.users[1] = &sqlc.User{ID: 1, Email: "admin@example.com", Name: "Admin", Role: dto.RoleAdmin}
+		users.users[2] = &sqlc.User{ID: 2, Email: "user@example.com", Name: "User", Role: dto.RoleUser}
+		return newTestAdminService(users), users
+	}
+	ctx := context.Background()
+
+	t.Run("ban keeps the user with the reason and can be lifted", func(t *testing.T) {
+		svc, users := newFixture()
+		if err := svc.BanUser(ctx, 1, 2, "spam"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		u := users.users[2]
+		if u.DeletedAt.Valid || !u.BannedAt.Valid || u.BanReason != "spam" {
+			t.Fatalf("expected the user banned but not deleted, got %+v", u)
+		}
+		assertAppErrorCode(t, svc.BanUser(ctx, 1, 2, ""), 409)
+
+		banned := true
+		list, total, err := svc.ListUsers(ctx, dto.UserFilterQuery{Banned: &banned}, 1, 10)
+		if err != nil || total != 1 || list[0].ID != 2 || list[0].BanReason != "spam" || list[0].BannedAt == nil {
+			t.Errorf("expected only user 2 listed as banned, got %+v (err %v)", list, err)
+		}
+
+		if _, err := svc.UnbanUser(ctx, 2); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if u := users.users[2]; u.BannedAt.Valid || u.BanReason != "" {
+			t.Errorf("expected the ban lifted, got %+v", u)
+		}
+		_, err = svc.UnbanUser(ctx, 2)
+		assertAppErrorCode(t, err, 404)
+	})
+
+	t.Run("profiles leave the ban out", func(t *testing.T) {
+		svc, users := newFixture()
+		if err := svc.BanUser(ctx, 1, 2, "spam"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		data, _ := json.Marshal(ToUserResponse(users.users[2]))
+		if strings.Contains(string(data), "banned_at") || strings.Contains(string(data), "spam") {
+			t.Errorf("expected the profile without the ban, got %s", data)
+		}
+		detail, err := svc.GetUser(ctx, 2)
+		if err != nil || detail.BannedAt == nil || detail.BanReason != "spam" {
+			t.Errorf("expected the admin detail with the ban, got %+v (err %v)", detail, err)
+		}
+	})
+
+	t.Run("restore undeletes a deleted user", func(t *testing.T) {
+		svc, users := newFixture()
+		_, err := svc.RestoreUser(ctx, 2)
+		assertAppErrorCode(t, err, 404)
+
+		users.users[2].DeletedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+		if _, err := svc.RestoreUser(ctx, 2); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if users.users[2].DeletedAt.Valid {
+			t.Error("expected the user restored")
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Audit log
+// ---------------------------------------------------------------------------
+
+func TestAuditLog(t *testing.T) {
+	newFixture := func() (AdminService, AuditLogService, *mockUserRepo, *mockAuditLogRepo) {
+		users := newMockUserRepo()
+		for id := int64(1); id <= 3; id++ {
+			users.users[id] = &sqlc.User{ID: id, Email: fmt.Sprintf("user%d@example.com", id), Name: "User", Role: "user"}
+		}
+		users.users[1].Role = dto.RoleAdmin
+		auditRepo := newMockAuditLogRepo()
+		auditSvc := NewAuditLogService(auditRepo)
+		svc := NewAdminService(users, newMockFileRepo(), newMockRefreshTokenRepo(), nil, newMockStorage(),
+			token.NewRevocationStore(newMockCache(), time.Hour), newMockCache(), nil, auditSvc, 30)
+		return svc, auditSvc, users, auditRepo
+	}
+	ctx := WithAuditActor(context.Background(), AuditActor{UserID: 1, IPAddress: "203.0.113.7", RequestID: "req-1"})
+
+	t.Run("role change records actor, request and changed fields", func(t *testing.T) {
+		svc, auditSvc, _, _ := newFixture()
+
+		if _, err := svc.UpdateRole(ctx, 1, 2, dto.RoleAdmin); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		entries, total, err := auditSvc.List(context.Background(), dto.AuditLogQuery{}, 1, 10)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if total != 1 {
+			t.Fatalf("expected 1 entry, got %d", total)
+		}
+		e := entries[0]
+		if e.ActorID != 1 || e.Action != dto.AuditUserRoleChanged || e.TargetType != dto.AuditTargetUser || e.TargetID != 2 {
+			t.Errorf("unexpected entry %+v", e)
+		}
+		if e.IPAddress != "203.0.113.7" || e.RequestID != "req-1" {
+			t.Errorf("expected the request details recorded, got %+v", e)
+		}
+		if e.Before["role"] != "user" || e.After["role"] != dto.RoleAdmin {
+			t.Errorf("expected role user -> admin, got %v -> %v", e.Before, e.After)
+		}
+	})
+
+	t.Run("failed changes are not recorded", func(t *testing.T) {
+		svc, _, _, auditRepo := newFixture()
+
+		if err := svc.BanUser(ctx, 1, 99, ""); err == nil {
+			t.Fatal("expected error for unknown user")
+		}
+		if len(auditRepo.entries) != 0 {
+			t.Errorf("expected no entries, got %d", len(auditRepo.entries))
+		}
+	})
+
+	t.Run("bulk actions record each successful item", func(t *testing.T) {
+		svc, _, _, auditRepo := newFixture()
+
+		_, err := svc.BulkUsers(ctx, 1, dto.BulkUserActionRequest{Action: dto.BulkActionBan, UserIDs: []int64{1, 2, 3}})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		var targets []int64
+		for _, e := range auditRepo.entries {
+			if e.Action != dto.AuditUserBanned {
+				t.Errorf("expected %s, got %s", dto.AuditUserBanned, e.Action)
+			}
+			targets = append(targets, e.TargetID)
+		}
+		if !slices.Equal(targets, []int64{2, 3}) {
+			t.Errorf("expected entries for users 2 and 3, got %v", targets)
+		}
+	})
+
+	t.Run("listing filters entries", func(t *testing.T) {
+		svc, auditSvc, _, _ := newFixture()
+		if _, err := svc.UpdateRole(ctx, 1, 2, dto.RoleAdmin); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if err := svc.BanUser(ctx, 1, 2, ""); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if err := svc.BanUser(ctx, 1, 3, ""); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		entries, total, err := auditSvc.List(context.Background(), dto.AuditLogQuery{TargetType: dto.AuditTargetUser, TargetID: 2}, 1, 10)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if total != 2 || entries[0].Action != dto.AuditUserBanned || entries[1].Action != dto.AuditUserRoleChanged {
+			t.Errorf("expected user 2's entries newest first, got %+v", entries)
+		}
+
+		_, _, err = auditSvc.List(context.Background(), dto.AuditLogQuery{CreatedAfter: "yesterday"}, 1, 10)
+		assertAppErrorCode(t, err, 400)
+	})
+
+	t.Run("export walks every matching entry oldest first", func(t *testing.T) {
+		svc, auditSvc, _, auditRepo := newFixture()
+		for i := 0; i < auditExportBatchSize+1; i++ {
+			auditRepo.entries = append(auditRepo.entries, sqlc.AuditLog{
+				ID: int64(len(auditRepo.entries) + 1), Action: dto.AuditFileDownloaded, TargetType: dto.AuditTargetFile,
+			})
+		}
+		if err := svc.BanUser(ctx, 1, 2, ""); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		var ids []int64
+		err := auditSvc.Export(context.Background(), dto.AuditLogQuery{TargetType: dto.AuditTargetFile}, func(e dto.AuditLogResponse) error {
+			ids = append(ids, e.ID)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(ids) != auditExportBatchSize+1 || !slices.IsSorted(ids) {
+			t.Errorf("expected %d file entries in ID order, got %d", auditExportBatchSize+1, len(ids))
+		}
+
+		stop := errors.New("client went away")
+		err = auditSvc.Export(context.Background(), dto.AuditLogQuery{}, func(dto.AuditLogResponse) error { return stop })
+		if !errors.Is(err, stop) {
+			t.Errorf("expected the callback error, got %v", err)
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Sessions
+// ---------------------------------------------------------------------------
+
+func TestUserSessions(t *testing.T) {
+	users := newMockUserRepo()
+	users.users[2] = &sqlc.User{ID: 2, Email: "user@example.com", Name: "User", Role: "user"}
+	users.users[3] = &sqlc.User{ID: 3, Email: "other@example.com", Name: "Other", Role: "user"}
+	tokens := newMockRefreshTokenRepo()
+	tokenSvc := NewRefreshTokenService(tokens, 7)
+	revocations := token.NewRevocationStore(newMockCache(), time.Hour)
+	auditRepo := newMockAuditLogRepo()
+	svc := NewAdminService(users, newMockFileRepo(), tokens, nil, newMockStorage(), revocations, newMockCache(), nil,
+		NewAuditLogService(auditRepo), 30)
+	ctx := WithAuditActor(context.Background(), AuditActor{UserID: 1})
+
+	// Two sessions for user 2, one of them refreshed once, and one for user 3.
+	first, _ := tokenSvc.Create(ctx, 2, "")
+	if _, err := tokenSvc.Create(ctx, 2, "device-1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	rt, err := tokenSvc.Verify(ctx, first, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := tokenSvc.Rotate(ctx, rt); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	other, _ := tokenSvc.Create(ctx, 3, "")
+
+	sessions, err := svc.ListSessions(ctx, 2)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(sessions))
+	}
+	bound := 0
+	for _, s := range sessions {
+		if s.DeviceBound {
+			bound++
+		}
+	}
+	if bound != 1 {
+		t.Errorf("expected one device-bound session, got %d", bound)
+	}
+
+	if err := svc.RevokeSessions(ctx, 2); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if sessions, _ := svc.ListSessions(ctx, 2); len(sessions) != 0 {
+		t.Errorf("expected no sessions left, got %d", len(sessions))
+	}
+	if _, err := tokenSvc.Verify(ctx, other, ""); err != nil {
+		t.Errorf("expected other users' sessions kept, got %v", err)
+	}
+	if users.users[2].BannedAt.Valid {
+		t.Error("expected the user not to be banned")
+	}
+
+	revoked, err := revocations.IsRevoked(ctx, &token.Claims{
+		UserID:           2,
+		RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))},
+	})
+	if err != nil || !revoked {
+		t.Errorf("expected issued access tokens revoked, got %v (err %v)", revoked, err)
+	}
+
+	if len(auditRepo.entries) != 1 || auditRepo.entries[0].Action != dto.AuditUserSessionsRevoked {
+		t.Errorf("expected a sessions revoked audit entry, got %+v", auditRepo.entries)
+	}
+
+	_, err = svc.ListSessions(ctx, 99)
+	assertAppErrorCode(t, err, 404)
+	assertAppErrorCode(t, svc.RevokeSessions(ctx, 99), 404)
+}
+
+// ---------------------------------------------------------------------------
+// Search
+// ---------------------------------------------------------------------------
+
+func TestSearch(t *testing.T) {
+	now := time.Now()
+	users := newMockUserRepo()
+	users.users[2] = &sqlc.User{ID: 2, Email: "jane.doe@example.com", Name: "Jane Doe", Role: "user",
+		CreatedAt: pgtype.Timestamptz{Time: now.Add(-2 * time.Hour), Valid: true}}
+	users.users[3] = &sqlc.User{ID: 3, Email: "john@example.com", Name: "John", Role: "user",
+		DeletedAt: pgtype.Timestamptz{Time: now, Valid: true}}
+	files := newMockFileRepo()
+	files.files[10] = &sqlc.File{ID: 10, UserID: 3, OriginalName: "doe-invoice.pdf", MimeType: "application/pdf", Size: 1024,
+		CreatedAt: pgtype.Timestamptz{Time: now, Valid: true}}
+	files.files[11] = &sqlc.File{ID: 11, UserID: 2, OriginalName: "report_100%.pdf", MimeType: "application/pdf",
+		CreatedAt: pgtype.Timestamptz{Time: now.Add(-time.Hour), Valid: true}}
+	svc := NewAdminService(users, files, newMockRefreshTokenRepo(), nil, newMockStorage(),
+		token.NewRevocationStore(newMockCache(), time.Hour), newMockCache(), nil, nil, 30)
+	ctx := context.Background()
+
+	t.Run("typed hits, best matches first", func(t *testing.T) {
+		hits, err := svc.Search(ctx, dto.AdminSearchQuery{Q: "doe"})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(hits) != 2 {
+			t.Fatalf("expected 2 hits, got %+v", hits)
+		}
+		// The file name starts with the term, so it ranks above the user whose name contains it.
+		if hits[0].Type != dto.SearchHitFile || hits[0].ID != 10 || hits[0].UserID != 3 || hits[0].Size != 1024 {
+			t.Errorf("unexpected first hit %+v", hits[0])
+		}
+		if hits[1].Type != dto.SearchHitUser || hits[1].ID != 2 || hits[1].Subtitle != "jane.doe@example.com" {
+			t.Errorf("unexpected second hit %+v", hits[1])
+		}
+	})
+
+	t.Run("includes deleted users", func(t *testing.T) {
+		hits, err := svc.Search(ctx, dto.AdminSearchQuery{Q: "JOHN@example.com"})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(hits) != 1 || hits[0].ID != 3 || !hits[0].Deleted {
+			t.Errorf("unexpected hits %+v", hits)
+		}
+	})
+
+	t.Run("type and limit", func(t *testing.T) {
+		hits, err := svc.Search(ctx, dto.AdminSearchQuery{Q: ".pdf", Type: dto.SearchHitFile, Limit: 1})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(hits) != 1 || hits[0].Type != dto.SearchHitFile || hits[0].ID != 10 {
+			t.Errorf("expected the newest file only, got %+v", hits)
+		}
+
+		hits, _ = svc.Search(ctx, dto.AdminSearchQuery{Q: "doe", Type: dto.SearchHitUser})
+		if len(hits) != 1 || hits[0].Type != dto.SearchHitUser {
+			t.Errorf("expected user hits only, got %+v", hits)
+		}
+	})
+
+	t.Run("blank term", func(t *testing.T) {
+		_, err := svc.Search(ctx, dto.AdminSearchQuery{Q: "  a "})
+		assertAppErrorCode(t, err, 400)
+	})
+}
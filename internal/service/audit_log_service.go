@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/pagination"
+)
+
+// AuditActor identifies who made an admin change and the request it came from.
+type AuditActor struct {
+	UserID    int64
+	IPAddress string
+	RequestID string
+}
+
+// auditExportBatchSize is the number of entries fetched per query while exporting.
+const auditExportBatchSize = 500
+
+type auditActorKey struct{}
+
+// WithAuditActor returns a copy of ctx carrying the actor that admin changes made with it
+// are attributed to in the audit log.
+func WithAuditActor(ctx context.Context, actor AuditActor) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+func auditActorFrom(ctx context.Context) AuditActor {
+	actor, _ := ctx.Value(auditActorKey{}).(AuditActor)
+	return actor
+}
+
+// AuditEntry describes an admin change to be recorded in the audit log. Before and After
+// hold only the fields the change touched, never the full record.
+type AuditEntry struct {
+	Action     string
+	TargetType string
+	TargetID   int64
+	Before     map[string]any
+	After      map[string]any
+}
+
+type AuditLogService interface {
+	Record(ctx context.Context, entry AuditEntry) error
+	List(ctx context.Context, query dto.AuditLogQuery, page, perPage int) ([]dto.AuditLogResponse, int64, error)
+	Export(ctx context.Context, query dto.AuditLogQuery, fn func(dto.AuditLogResponse) error) error
+}
+
+type auditLogService struct {
+	repo repository.AuditLogRepository
+}
+
+func NewAuditLogService(repo repository.AuditLogRepository) AuditLogService {
+	return &auditLogService{repo: repo}
+}
+
+// Record adds an entry attributed to the actor carried by ctx.
+func (s *auditLogService) Record(ctx context.Context, entry AuditEntry) error {
+	before, err := encodeAuditData(entry.Before)
+	if err != nil {
+		return err
+	}
+	after, err := encodeAuditData(entry.After)
+	if err != nil {
+		return err
+	}
+
+	actor := auditActorFrom(ctx)
+	_, err = s.repo.Create(ctx, sqlc.CreateAuditLogParams{
+		ActorID:    actor.UserID,
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		BeforeData: before,
+		AfterData:  after,
+		IpAddress:  actor.IPAddress,
+		RequestID:  actor.RequestID,
+	})
+	if err != nil {
+		return fmt.Errorf("create audit log: %w", err)
+	}
+	return nil
+}
+
+func (s *auditLogService) List(ctx context.Context, query dto.AuditLogQuery, page, perPage int) ([]dto.AuditLogResponse, int64, error) {
+	filter, err := auditLogFilter(query)
+	if err != nil {
+		return nil, 0, err
+	}
+	limit, offset := pagination.LimitOffset(page, perPage)
+
+	entries, err := s.repo.List(ctx, filter, limit, offset)
+	if err != nil {
+		return nil, 0, apperror.NewInternal("failed to list audit logs")
+	}
+
+	total, err := s.repo.Count(ctx, filter)
+	if err != nil {
+		return nil, 0, apperror.NewInternal("failed to count audit logs")
+	}
+
+	result := make([]dto.AuditLogResponse, len(entries))
+	for i := range entries {
+		result[i] = toAuditLogResponse(&entries[i])
+	}
+	return result, total, nil
+}
+
+// Export passes every entry matching the filter to fn, oldest first. Entries are fetched in
+// keyset pages of auditExportBatchSize, so the full result set is never held in memory.
+// An error returned by fn stops the export and is returned as is.
+func (s *auditLogService) Export(ctx context.Context, query dto.AuditLogQuery, fn func(dto.AuditLogResponse) error) error {
+	filter, err := auditLogFilter(query)
+	if err != nil {
+		return err
+	}
+
+	var afterID int64
+	for {
+		entries, err := s.repo.ListAfter(ctx, filter, afterID, auditExportBatchSize)
+		if err != nil {
+			return apperror.NewInternal("failed to export audit logs")
+		}
+		for i := range entries {
+			if err := fn(toAuditLogResponse(&entries[i])); err != nil {
+				return err
+			}
+		}
+		if len(entries) < auditExportBatchSize {
+			return nil
+		}
+		afterID = entries[len(entries)-1].ID
+	}
+}
+
+func auditLogFilter(query dto.AuditLogQuery) (repository.AuditLogFilter, error) {
+	filter := repository.AuditLogFilter{
+		ActorID:    query.ActorID,
+		Action:     query.Action,
+		TargetType: query.TargetType,
+		TargetID:   query.TargetID,
+	}
+	var err error
+	if query.CreatedAfter != "" {
+		if filter.CreatedAfter, err = time.Parse(time.RFC3339, query.CreatedAfter); err != nil {
+			return filter, apperror.NewBadRequest("created_after must be an RFC 3339 timestamp")
+		}
+	}
+	if query.CreatedBefore != "" {
+		if filter.CreatedBefore, err = time.Parse(time.RFC3339, query.CreatedBefore); err != nil {
+			return filter, apperror.NewBadRequest("created_before must be an RFC 3339 timestamp")
+		}
+	}
+	return filter, nil
+}
+
+// recordAudit records entry when an audit log is configured. A failure is logged rather than
+// returned: the change it describes has already been made.
+func recordAudit(ctx context.Context, svc AuditLogService, entry AuditEntry) {
+	if svc == nil {
+		return
+	}
+	if err := svc.Record(ctx, entry); err != nil {
+		slog.Error("failed to record audit log",
+			slog.String("action", entry.Action),
+			slog.Int64("target_id", entry.TargetID),
+			slog.Any("error", err),
+		)
+	}
+}
+
+func encodeAuditData(data map[string]any) ([]byte, error) {
+	if data == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("encode audit data: %w", err)
+	}
+	return raw, nil
+}
+
+func toAuditLogResponse(a *sqlc.AuditLog) dto.AuditLogResponse {
+	resp := dto.AuditLogResponse{
+		ID:         a.ID,
+		ActorID:    a.ActorID,
+		Action:     a.Action,
+		TargetType: a.TargetType,
+		TargetID:   a.TargetID,
+		IPAddress:  a.IpAddress,
+		RequestID:  a.RequestID,
+		CreatedAt:  a.CreatedAt.Time,
+	}
+	// A malformed document is left out of the response rather than failing the request.
+	if before, _ := repository.DecodeMetadata(a.BeforeData); len(before) > 0 {
+		resp.Before = before
+	}
+	if after, _ := repository.DecodeMetadata(a.AfterData); len(after) > 0 {
+		resp.After = after
+	}
+	return resp
+}
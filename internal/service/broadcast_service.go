@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	htmltemplate "html/template"
+	"io"
+	"log/slog"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/async"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/email"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/pagination"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/realtime"
+)
+
+// BroadcastService emails announcements from admins to all users or a segment of them.
+type BroadcastService interface {
+	Send(ctx context.Context, senderID int64, req dto.BroadcastRequest) (*dto.BroadcastResponse, error)
+	List(ctx context.Context, page, perPage int) ([]dto.BroadcastResponse, int64, error)
+}
+
+type broadcastService struct {
+	repo      repository.BroadcastRepository
+	sender    email.Sender
+	auditLog  AuditLogService
+	batchSize int32
+	interval  time.Duration   // minimum gap between two emails; zero sends without pause
+	run       func(fn func()) // delivers broadcasts; async.Go outside tests
+	events    realtime.Publisher
+}
+
+func NewBroadcastService(
+	repo repository.BroadcastRepository,
+	sender email.Sender,
+	auditLog AuditLogService,
+	batchSize int,
+	ratePerSecond int,
+	events realtime.Publisher,
+) BroadcastService {
+	return &broadcastService{
+		repo:      repo,
+		sender:    sender,
+		auditLog:  auditLog,
+		batchSize: int32(batchSize),
+		interval:  time.Second / time.Duration(ratePerSecond),
+		run:       async.Go,
+		events:    events,
+	}
+}
+
+// broadcastRecipient is the data subject and body templates are rendered with.
+type broadcastRecipient struct {
+	Name  string
+	Email string
+}
+
+// broadcastTemplate renders a broadcast for one recipient.
+type broadcastTemplate struct {
+	subject *texttemplate.Template
+	body    *htmltemplate.Template
+}
+
+// parseBroadcastTemplate parses the subject and body and renders them once with sample
+// data, so a template referring to unknown fields is rejected before anything is sent.
+func parseBroadcastTemplate(subject, body string) (*broadcastTemplate, error) {
+	st, err := texttemplate.New("subject").Parse(subject)
+	if err != nil {
+		return nil, apperror.NewBadRequest("invalid subject template: " + err.Error())
+	}
+	bt, err := htmltemplate.New("body").Parse(body)
+	if err != nil {
+		return nil, apperror.NewBadRequest("invalid body template: " + err.Error())
+	}
+
+	sample := broadcastRecipient{Name: "Jane Doe", Email: "jane@example.com"}
+	if err := st.Execute(io.Discard, sample); err != nil {
+		return nil, apperror.NewBadRequest("invalid subject template: " + err.Error())
+	}
+	if err := bt.Execute(io.Discard, sample); err != nil {
+		return nil, apperror.NewBadRequest("invalid body template: " + err.Error())
+	}
+	return &broadcastTemplate{subject: st, body: bt}, nil
+}
+
+// render returns the subject and HTML body for r. Line breaks in the subject are replaced
+// so a recipient's name cannot add headers to the message.
+func (t *broadcastTemplate) render(r broadcastRecipient) (subject, body string, err error) {
+	var sb, bb strings.Builder
+	if err := t.subject.Execute(&sb, r); err != nil {
+		return "", "", err
+	}
+	if err := t.body.Execute(&bb, r); err != nil {
+		return "", "", err
+	}
+	subject = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(sb.String())
+	return subject, bb.String(), nil
+}
+
+// Send records a broadcast to every user in the segment and delivers it in the background,
+// reading recipients in batches and pacing emails to the configured rate. The response
+// reports how many users will receive it; progress is visible through List.
+func (s *broadcastService) Send(ctx context.Context, senderID int64, req dto.BroadcastRequest) (*dto.BroadcastResponse, error) {
+	segment, err := broadcastSegment(req.Segment)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := parseBroadcastTemplate(req.Subject, req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients, err := s.repo.CountRecipients(ctx, segment)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to count recipients")
+	}
+	if recipients == 0 {
+		return nil, apperror.NewBadRequest("no users match the segment")
+	}
+
+	segmentJSON, err := json.Marshal(req.Segment)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to encode segment")
+	}
+	b, err := s.repo.Create(ctx, sqlc.CreateBroadcastParams{
+		SenderID:       senderID,
+		Subject:        req.Subject,
+		Body:           req.Body,
+		Segment:        segmentJSON,
+		RecipientCount: int32(recipients),
+	})
+	if err != nil {
+		return nil, apperror.NewInternal("failed to create broadcast")
+	}
+
+	recordAudit(ctx, s.auditLog, AuditEntry{
+		Action:     dto.AuditBroadcastSent,
+		TargetType: dto.AuditTargetBroadcast,
+		TargetID:   b.ID,
+		After:      map[string]any{"subject": req.Subject, "recipients": recipients},
+	})
+
+	s.run(func() {
+		s.deliver(context.WithoutCancel(ctx), b.ID, segment, tmpl)
+	})
+
+	return toBroadcastResponse(b), nil
+}
+
+// deliver emails every recipient of a broadcast one at a time. A failed email is counted
+// and skipped; the broadcast only fails when recipients can no longer be read.
+func (s *broadcastService) deliver(ctx context.Context, id int64, segment repository.BroadcastSegment, tmpl *broadcastTemplate) {
+	var throttle <-chan time.Time
+	if s.interval > 0 {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	var afterID int64
+	var sent, failed int32
+	for {
+		recipients, err := s.repo.ListRecipients(ctx, segment, afterID, s.batchSize)
+		if err != nil {
+			slog.Error("failed to list broadcast recipients", slog.Int64("broadcast_id", id), slog.Any("error", err))
+			s.finish(ctx, id, dto.BroadcastFailed, sent, failed)
+			return
+		}
+
+		for _, r := range recipients {
+			if throttle != nil {
+				<-throttle
+			}
+			if err := s.sendTo(ctx, id, tmpl, r); err != nil {
+				slog.Warn("failed to send broadcast email",
+					slog.Int64("broadcast_id", id),
+					slog.Int64("user_id", r.ID),
+					slog.Any("error", err),
+				)
+				failed++
+				continue
+			}
+			sent++
+		}
+
+		if len(recipients) < int(s.batchSize) {
+			break
+		}
+		afterID = recipients[len(recipients)-1].ID
+		if err := s.repo.UpdateProgress(ctx, id, sent, failed); err != nil {
+			slog.Error("failed to record broadcast progress", slog.Int64("broadcast_id", id), slog.Any("error", err))
+		}
+	}
+
+	s.finish(ctx, id, dto.BroadcastCompleted, sent, failed)
+}
+
+// sendTo emails the broadcast to r and tells their open connections about it.
+func (s *broadcastService) sendTo(ctx context.Context, id int64, tmpl *broadcastTemplate, r sqlc.ListBroadcastRecipientsRow) error {
+	subject, body, err := tmpl.render(broadcastRecipient{Name: r.Name, Email: r.Email})
+	if err != nil {
+		return err
+	}
+	if err := s.sender.Send(ctx, email.Message{To: []string{r.Email}, Subject: subject, HTML: body}); err != nil {
+		return err
+	}
+	s.events.PublishToUser(r.ID, realtime.Event{
+		Type: dto.RealtimeEventBroadcast,
+		Data: dto.BroadcastEvent{ID: id, Subject: subject},
+	})
+	return nil
+}
+
+func (s *broadcastService) finish(ctx context.Context, id int64, status string, sent, failed int32) {
+	if err := s.repo.Finish(ctx, id, status, sent, failed); err != nil {
+		slog.Error("failed to finish broadcast", slog.Int64("broadcast_id", id), slog.Any("error", err))
+	}
+}
+
+func (s *broadcastService) List(ctx context.Context, page, perPage int) ([]dto.BroadcastResponse, int64, error) {
+	limit, offset := pagination.LimitOffset(page, perPage)
+
+	broadcasts, err := s.repo.List(ctx, limit, offset)
+	if err != nil {
+		return nil, 0, apperror.NewInternal("failed to list broadcasts")
+	}
+	total, err := s.repo.Count(ctx)
+	if err != nil {
+		return nil, 0, apperror.NewInternal("failed to count broadcasts")
+	}
+
+	responses := make([]dto.BroadcastResponse, len(broadcasts))
+	for i := range broadcasts {
+		responses[i] = *toBroadcastResponse(&broadcasts[i])
+	}
+	return responses, total, nil
+}
+
+// broadcastSegment converts the request segment into a repository filter.
+func broadcastSegment(q dto.BroadcastSegment) (repository.BroadcastSegment, error) {
+	segment := repository.BroadcastSegment{
+		Search:          strings.TrimSpace(q.Search),
+		Role:            q.Role,
+		EmailVerified:   q.EmailVerified,
+		SubscribersOnly: q.SubscribersOnly,
+	}
+
+	var err error
+	if q.CreatedAfter != "" {
+		if segment.CreatedAfter, err = time.Parse(time.RFC3339, q.CreatedAfter); err != nil {
+			return segment, apperror.NewBadRequest("created_after must be an RFC 3339 timestamp")
+		}
+	}
+	if q.CreatedBefore != "" {
+		if segment.CreatedBefore, err = time.Parse(time.RFC3339, q.CreatedBefore); err != nil {
+			return segment, apperror.NewBadRequest("created_before must be an RFC 3339 timestamp")
+		}
+	}
+	return segment, nil
+}
+
+func toBroadcastResponse(b *sqlc.Broadcast) *dto.BroadcastResponse {
+	resp := &dto.BroadcastResponse{
+		ID:             b.ID,
+		SenderID:       b.SenderID,
+		Subject:        b.Subject,
+		Body:           b.Body,
+		Status:         b.Status,
+		RecipientCount: b.RecipientCount,
+		SentCount:      b.SentCount,
+		FailedCount:    b.FailedCount,
+		CreatedAt:      b.CreatedAt.Time,
+	}
+	// A malformed segment is left out of the response rather than failing the request.
+	_ = json.Unmarshal(b.Segment, &resp.Segment)
+	if b.CompletedAt.Valid {
+		resp.CompletedAt = &b.CompletedAt.Time
+	}
+	return resp
+}
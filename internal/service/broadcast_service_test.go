@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+)
+
+type broadcastFixture struct {
+	svc     *broadcastService
+	repo    *mockBroadcastRepo
+	sender  *mockEmailSender
+	audit   *mockAuditLogRepo
+	events  *mockPublisher
+	pending []func()
+}
+
+func newBroadcastFixture(t *testing.T, recipients ...sqlc.ListBroadcastRecipientsRow) *broadcastFixture {
+	t.Helper()
+	f := &broadcastFixture{repo: newMockBroadcastRepo(), sender: newMockEmailSender(), audit: newMockAuditLogRepo(), events: &mockPublisher{}}
+	f.repo.recipients = recipients
+	f.svc = NewBroadcastService(f.repo, f.sender, NewAuditLogService(f.audit), 2, 1000, f.events).(*broadcastService)
+	f.svc.interval = 0
+	f.svc.run = func(fn func()) { f.pending = append(f.pending, fn) }
+	return f
+}
+
+func (f *broadcastFixture) drain() {
+	for _, fn := range f.pending {
+		fn()
+	}
+	f.pending = nil
+}
+
+func TestBroadcast(t *testing.T) {
+	ctx := WithAuditActor(context.Background(), AuditActor{UserID: 1})
+	users := []sqlc.ListBroadcastRecipientsRow{
+		{ID: 2, Email: "ann@example.com", Name: "Ann"},
+		{ID: 3, Email: "bob@example.com", Name: "<b>Bob</b>"},
+		{ID: 5, Email: "eve@example.com", Name: "Eve\r\nBcc: x@example.com"},
+		{ID: 8, Email: "kim@example.com", Name: "Kim"},
+		{ID: 9, Email: "lee@example.com", Name: "Lee"},
+	}
+	req := dto.BroadcastRequest{Subject: "Hello {{.Name}}", Body: "<p>Hi {{.Name}}, we have news.</p>"}
+
+	t.Run("renders for each recipient in batches", func(t *testing.T) {
+		f := newBroadcastFixture(t, users...)
+		resp, err := f.svc.Send(ctx, 1, req)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if resp.Status != dto.BroadcastSending || resp.RecipientCount != 5 {
+			t.Errorf("unexpected response %+v", resp)
+		}
+		if f.sender.sent != 0 {
+			t.Fatal("expected delivery to run in the background")
+		}
+
+		f.drain()
+		if f.sender.sent != 5 {
+			t.Fatalf("expected 5 emails, got %d", f.sender.sent)
+		}
+		bob := f.sender.messages[1]
+		if bob.To[0] != "bob@example.com" || !strings.Contains(bob.HTML, "&lt;b&gt;Bob&lt;/b&gt;") {
+			t.Errorf("expected the name escaped in the body, got %q", bob.HTML)
+		}
+		if eve := f.sender.messages[2].Subject; strings.ContainsAny(eve, "\r\n") {
+			t.Errorf("expected line breaks removed from the subject, got %q", eve)
+		}
+		if len(f.events.events) != 5 || f.events.events[0].userID != 2 || f.events.events[0].event.Type != dto.RealtimeEventBroadcast {
+			t.Errorf("expected a broadcast event for each recipient, got %+v", f.events.events)
+		}
+		if len(f.repo.progress) != 2 {
+			t.Errorf("expected progress after each full batch, got %v", f.repo.progress)
+		}
+
+		b := f.repo.broadcasts[0]
+		if b.Status != dto.BroadcastCompleted || b.SentCount != 5 || b.FailedCount != 0 || !b.CompletedAt.Valid {
+			t.Errorf("unexpected broadcast %+v", b)
+		}
+		if len(f.audit.entries) != 1 || f.audit.entries[0].Action != dto.AuditBroadcastSent || f.audit.entries[0].TargetID != b.ID {
+			t.Errorf("expected one broadcast.sent audit entry, got %+v", f.audit.entries)
+		}
+	})
+
+	t.Run("failed emails are counted", func(t *testing.T) {
+		f := newBroadcastFixture(t, users...)
+		f.sender.sendErr = errors.New("smtp down")
+		if _, err := f.svc.Send(ctx, 1, req); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		f.drain()
+
+		b := f.repo.broadcasts[0]
+		if b.Status != dto.BroadcastCompleted || b.SentCount != 0 || b.FailedCount != 5 {
+			t.Errorf("unexpected broadcast %+v", b)
+		}
+		if len(f.events.events) != 0 {
+			t.Errorf("expected no events for failed emails, got %+v", f.events.events)
+		}
+	})
+
+	t.Run("fails when recipients cannot be read", func(t *testing.T) {
+		f := newBroadcastFixture(t, users...)
+		if _, err := f.svc.Send(ctx, 1, req); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		f.repo.listErr = errors.New("connection lost")
+		f.drain()
+
+		if b := f.repo.broadcasts[0]; b.Status != dto.BroadcastFailed {
+			t.Errorf("expected failed, got %q", b.Status)
+		}
+	})
+
+	t.Run("rejects invalid templates", func(t *testing.T) {
+		f := newBroadcastFixture(t, users...)
+		for _, bad := range []dto.BroadcastRequest{
+			{Subject: "Hello {{.Name", Body: "<p>Hi</p>"},
+			{Subject: "Hello", Body: "<p>{{.Password}}</p>"},
+		} {
+			_, err := f.svc.Send(ctx, 1, bad)
+			assertAppErrorCode(t, err, 400)
+		}
+		if len(f.repo.broadcasts) != 0 {
+			t.Error("expected nothing recorded")
+		}
+	})
+
+	t.Run("rejects an empty segment", func(t *testing.T) {
+		f := newBroadcastFixture(t)
+		_, err := f.svc.Send(ctx, 1, req)
+		assertAppErrorCode(t, err, 400)
+	})
+
+	t.Run("lists newest first", func(t *testing.T) {
+		f := newBroadcastFixture(t, users...)
+		for _, subject := range []string{"First", "Second"} {
+			if _, err := f.svc.Send(ctx, 1, dto.BroadcastRequest{
+				Subject: subject, Body: "<p>News</p>",
+				Segment: dto.BroadcastSegment{Role: dto.RoleUser, SubscribersOnly: true},
+			}); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+
+		list, total, err := f.svc.List(ctx, 1, 10)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if total != 2 || list[0].Subject != "Second" {
+			t.Errorf("unexpected list %+v", list)
+		}
+		if seg := list[0].Segment; seg.Role != dto.RoleUser || !seg.SubscribersOnly {
+			t.Errorf("expected the segment returned, got %+v", seg)
+		}
+	})
+}
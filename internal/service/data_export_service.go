@@ -0,0 +1,290 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/async"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/email"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/storage"
+)
+
+const (
+	// dataExportBuildTimeout bounds a single archive build.
+	dataExportBuildTimeout = 10 * time.Minute
+	// dataExportStaleAfter is how long a pending export blocks new requests; older pending
+	// exports are assumed lost to a restart.
+	dataExportStaleAfter = time.Hour
+	// dataExportSweepBatchSize caps how many expired exports a single PurgeExpired run removes.
+	dataExportSweepBatchSize = 100
+	// loginHistoryPageSize is the number of login events read per query while building an archive.
+	loginHistoryPageSize = 1000
+)
+
+type DataExportService interface {
+	Request(ctx context.Context, userID int64) (*dto.DataExportResponse, error)
+	Latest(ctx context.Context, userID int64) (*dto.DataExportResponse, error)
+	Open(ctx context.Context, userID int64) (io.ReadCloser, *dto.DataExportResponse, error)
+	PurgeExpired(ctx context.Context) (int, error)
+}
+
+type dataExportService struct {
+	repo           repository.DataExportRepository
+	userRepo       repository.UserRepository
+	fileRepo       repository.FileRepository
+	loginEventRepo repository.LoginEventRepository
+	storage        storage.Storage
+	sender         email.Sender
+	ttl            time.Duration
+	frontendURL    string
+	run            func(fn func()) // starts archive builds; async.Go outside tests
+}
+
+func NewDataExportService(
+	repo repository.DataExportRepository,
+	userRepo repository.UserRepository,
+	fileRepo repository.FileRepository,
+	loginEventRepo repository.LoginEventRepository,
+	store storage.Storage,
+	sender email.Sender,
+	ttlHours int,
+	frontendURL string,
+) DataExportService {
+	return &dataExportService{
+		repo:           repo,
+		userRepo:       userRepo,
+		fileRepo:       fileRepo,
+		loginEventRepo: loginEventRepo,
+		storage:        store,
+		sender:         sender,
+		ttl:            time.Duration(ttlHours) * time.Hour,
+		frontendURL:    frontendURL,
+		run:            async.Go,
+	}
+}
+
+// Request starts assembling a new archive of the user's personal data in the background.
+// Only one export can be in progress per user.
+func (s *dataExportService) Request(ctx context.Context, userID int64) (*dto.DataExportResponse, error) {
+	latest, err := s.repo.GetLatestByUserID(ctx, userID)
+	switch {
+	case err == nil:
+		if latest.Status == dto.DataExportPending && time.Since(latest.CreatedAt.Time) < dataExportStaleAfter {
+			return nil, apperror.NewBadRequest("a data export is already in progress")
+		}
+	case !errors.Is(err, apperror.ErrNotFound):
+		return nil, apperror.NewInternal("failed to check data exports")
+	}
+
+	exp, err := s.repo.Create(ctx, userID)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to create data export")
+	}
+
+	s.run(func() {
+		buildCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), dataExportBuildTimeout)
+		defer cancel()
+		s.build(buildCtx, exp.ID, userID)
+	})
+
+	return toDataExportResponse(exp), nil
+}
+
+// Latest reports the status of the user's most recent export.
+func (s *dataExportService) Latest(ctx context.Context, userID int64) (*dto.DataExportResponse, error) {
+	exp, err := s.repo.GetLatestByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("no data export requested")
+		}
+		return nil, apperror.NewInternal("failed to get data export")
+	}
+	return toDataExportResponse(exp), nil
+}
+
+// Open returns a reader for the user's most recent archive if it is ready and not expired.
+// The caller must close the reader.
+func (s *dataExportService) Open(ctx context.Context, userID int64) (io.ReadCloser, *dto.DataExportResponse, error) {
+	exp, err := s.repo.GetLatestByUserID(ctx, userID)
+	if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		return nil, nil, apperror.NewInternal("failed to get data export")
+	}
+	if err != nil || toDataExportResponse(exp).Status != dto.DataExportReady {
+		return nil, nil, apperror.NewNotFound("no data export ready for download")
+	}
+
+	reader, err := s.storage.Get(ctx, exp.StoragePath.String)
+	if err != nil {
+		return nil, nil, apperror.NewInternal("failed to read data export")
+	}
+	return reader, toDataExportResponse(exp), nil
+}
+
+// PurgeExpired removes expired archives from storage along with their records.
+func (s *dataExportService) PurgeExpired(ctx context.Context) (int, error) {
+	expired, err := s.repo.ListExpired(ctx, dataExportSweepBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("list expired data exports: %w", err)
+	}
+
+	purged := 0
+	for _, exp := range expired {
+		if exp.StoragePath.Valid {
+			if err := s.storage.Delete(ctx, exp.StoragePath.String); err != nil {
+				slog.Error("failed to delete data export archive", slog.Int64("export_id", exp.ID), slog.Any("error", err))
+				continue
+			}
+		}
+		if err := s.repo.Delete(ctx, exp.ID); err != nil {
+			slog.Error("failed to delete data export", slog.Int64("export_id", exp.ID), slog.Any("error", err))
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// build assembles the archive, stores it and emails the user. Failures are recorded on
+// the export so the user can request a new one.
+func (s *dataExportService) build(ctx context.Context, exportID, userID int64) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		s.fail(ctx, exportID, fmt.Errorf("get user: %w", err))
+		return
+	}
+
+	archive, err := s.assemble(ctx, user)
+	if err != nil {
+		s.fail(ctx, exportID, err)
+		return
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		s.fail(ctx, exportID, fmt.Errorf("generate archive name: %w", err))
+		return
+	}
+	path := fmt.Sprintf("exports/%d/%s.zip", userID, hex.EncodeToString(b))
+	size := int64(archive.Len())
+	if err := s.storage.Put(ctx, path, archive, size, "application/zip"); err != nil {
+		s.fail(ctx, exportID, fmt.Errorf("store archive: %w", err))
+		return
+	}
+
+	expiresAt := time.Now().Add(s.ttl)
+	if _, err := s.repo.MarkReady(ctx, exportID, path, size, expiresAt); err != nil {
+		_ = s.storage.Delete(ctx, path)
+		s.fail(ctx, exportID, fmt.Errorf("mark ready: %w", err))
+		return
+	}
+
+	if err := s.sender.Send(ctx, email.Message{
+		To:      []string{user.Email},
+		Subject: "Your Data Export Is Ready",
+		HTML: fmt.Sprintf(
+			"<p>The copy of your personal data you requested is ready.</p>"+
+				"<p>Click <a href=%q>here</a> to download it. The download is available until %s.</p>",
+			s.frontendURL+"/data-export", expiresAt.UTC().Format(time.RFC1123),
+		),
+	}); err != nil {
+		slog.Error("failed to send data export email", slog.Any("error", err))
+	}
+}
+
+func (s *dataExportService) fail(ctx context.Context, exportID int64, cause error) {
+	slog.Error("data export failed", slog.Int64("export_id", exportID), slog.Any("error", cause))
+	if err := s.repo.MarkFailed(ctx, exportID, time.Now().Add(s.ttl)); err != nil {
+		slog.Error("failed to record data export failure", slog.Int64("export_id", exportID), slog.Any("error", err))
+	}
+}
+
+// assemble writes the user's profile, file metadata and login history as JSON documents
+// into a zip archive.
+func (s *dataExportService) assemble(ctx context.Context, user *sqlc.User) (*bytes.Buffer, error) {
+	files, err := s.fileRepo.ListAllByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list files: %w", err)
+	}
+	fileResponses := make([]dto.FileResponse, len(files))
+	for i, f := range files {
+		fileResponses[i] = dto.FileResponse{
+			ID:           f.ID,
+			OriginalName: f.OriginalName,
+			MimeType:     f.MimeType,
+			Size:         f.Size,
+			URL:          s.storage.URL(f.StoragePath),
+			CreatedAt:    f.CreatedAt.Time,
+		}
+	}
+
+	logins := make([]dto.LoginEventResponse, 0)
+	for offset := int32(0); ; offset += loginHistoryPageSize {
+		events, err := s.loginEventRepo.ListByUserID(ctx, user.ID, loginHistoryPageSize, offset)
+		if err != nil {
+			return nil, fmt.Errorf("list login events: %w", err)
+		}
+		for i := range events {
+			logins = append(logins, toLoginEventResponse(&events[i]))
+		}
+		if len(events) < loginHistoryPageSize {
+			break
+		}
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, doc := range []struct {
+		name string
+		data any
+	}{
+		{"profile.json", ToUserResponse(user)},
+		{"files.json", fileResponses},
+		{"login_history.json", logins},
+	} {
+		w, err := zw.Create(doc.name)
+		if err != nil {
+			return nil, fmt.Errorf("add %s: %w", doc.name, err)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(doc.data); err != nil {
+			return nil, fmt.Errorf("write %s: %w", doc.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("close archive: %w", err)
+	}
+	return &buf, nil
+}
+
+func toDataExportResponse(exp *sqlc.DataExport) *dto.DataExportResponse {
+	resp := &dto.DataExportResponse{
+		ID:        exp.ID,
+		Status:    exp.Status,
+		Size:      exp.Size.Int64,
+		CreatedAt: exp.CreatedAt.Time,
+	}
+	if exp.CompletedAt.Valid {
+		resp.CompletedAt = &exp.CompletedAt.Time
+	}
+	if exp.ExpiresAt.Valid {
+		resp.ExpiresAt = &exp.ExpiresAt.Time
+		if exp.Status == dto.DataExportReady && time.Now().After(exp.ExpiresAt.Time) {
+			resp.Status = dto.DataExportExpired
+		}
+	}
+	return resp
+}
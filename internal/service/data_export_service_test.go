@@ -0,0 +1,185 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+)
+
+type dataExportFixture struct {
+	svc     *dataExportService
+	repo    *mockDataExportRepo
+	store   *mockStorage
+	sender  *mockEmailSender
+	pending []func()
+}
+
+// newTestDataExportService returns a service for user 1 whose archive builds are queued
+// on the fixture instead of running in the background.
+func newTestDataExportService(t *testing.T) *dataExportFixture {
+	t.Helper()
+	users := newMockUserRepo()
+	users.users[1] = &sqlc.User{ID: 1, Email: "user@example.com", Name: "User", Role: dto.RoleUser}
+
+	files := newMockFileRepo()
+	_, _ = files.Create(context.Background(), sqlc.CreateFileParams{
+		UserID: 1, OriginalName: "avatar.png", StoragePath: "uploads/avatar.png", MimeType: "image/png", Size: 42,
+	})
+
+	logins := newMockLoginEventRepo()
+	_, _ = logins.Create(context.Background(), sqlc.CreateLoginEventParams{
+		UserID: pgtype.Int8{Int64: 1, Valid: true}, Email: "user@example.com", Method: "password", Success: true,
+	})
+
+	f := &dataExportFixture{
+		repo:   newMockDataExportRepo(),
+		store:  newMockStorage(),
+		sender: newMockEmailSender(),
+	}
+	f.svc = NewDataExportService(f.repo, users, files, logins, f.store, f.sender, 48, "http://localhost:3000").(*dataExportService)
+	f.svc.run = func(fn func()) { f.pending = append(f.pending, fn) }
+	return f
+}
+
+func (f *dataExportFixture) runPending() {
+	for _, fn := range f.pending {
+		fn()
+	}
+	f.pending = nil
+}
+
+func TestRequestDataExport(t *testing.T) {
+	t.Run("builds archive and emails the user", func(t *testing.T) {
+		f := newTestDataExportService(t)
+		ctx := context.Background()
+
+		resp, err := f.svc.Request(ctx, 1)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if resp.Status != dto.DataExportPending {
+			t.Errorf("expected pending, got %q", resp.Status)
+		}
+
+		f.runPending()
+
+		latest, err := f.svc.Latest(ctx, 1)
+		if err != nil {
+			t.Fatalf("Latest: %v", err)
+		}
+		if latest.Status != dto.DataExportReady || latest.ExpiresAt == nil {
+			t.Fatalf("expected ready export with expiry, got %+v", latest)
+		}
+		if f.sender.sent != 1 || f.sender.last.To[0] != "user@example.com" {
+			t.Errorf("expected ready email to user, got %d sent", f.sender.sent)
+		}
+		if !strings.Contains(f.sender.last.HTML, "http://localhost:3000/data-export") {
+			t.Errorf("expected download link in email, got %q", f.sender.last.HTML)
+		}
+
+		reader, _, err := f.svc.Open(ctx, 1)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		data, _ := io.ReadAll(reader)
+		_ = reader.Close()
+		if int64(len(data)) != latest.Size {
+			t.Errorf("expected %d bytes, got %d", latest.Size, len(data))
+		}
+
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("expected zip archive, got %v", err)
+		}
+		docs := make(map[string][]any)
+		for _, zf := range zr.File {
+			rc, _ := zf.Open()
+			var v any
+			if err := json.NewDecoder(rc).Decode(&v); err != nil {
+				t.Fatalf("%s: %v", zf.Name, err)
+			}
+			_ = rc.Close()
+			if list, ok := v.([]any); ok {
+				docs[zf.Name] = list
+			} else {
+				docs[zf.Name] = []any{v}
+			}
+		}
+		for name, want := range map[string]int{"profile.json": 1, "files.json": 1, "login_history.json": 1} {
+			if len(docs[name]) != want {
+				t.Errorf("expected %d entries in %s, got %d", want, name, len(docs[name]))
+			}
+		}
+	})
+
+	t.Run("rejects while an export is in progress", func(t *testing.T) {
+		f := newTestDataExportService(t)
+		ctx := context.Background()
+
+		_, _ = f.svc.Request(ctx, 1)
+		_, err := f.svc.Request(ctx, 1)
+		assertAppErrorCode(t, err, 400)
+
+		f.runPending()
+		if _, err := f.svc.Request(ctx, 1); err != nil {
+			t.Errorf("expected new export once ready, got %v", err)
+		}
+	})
+
+	t.Run("storage failure marks export failed", func(t *testing.T) {
+		f := newTestDataExportService(t)
+		f.store.putErr = io.ErrShortWrite
+		ctx := context.Background()
+
+		_, _ = f.svc.Request(ctx, 1)
+		f.runPending()
+
+		latest, _ := f.svc.Latest(ctx, 1)
+		if latest.Status != dto.DataExportFailed {
+			t.Errorf("expected failed, got %q", latest.Status)
+		}
+		if f.sender.sent != 0 {
+			t.Errorf("expected no email, got %d", f.sender.sent)
+		}
+		_, _, err := f.svc.Open(ctx, 1)
+		assertAppErrorCode(t, err, 404)
+	})
+}
+
+func TestDataExportExpiry(t *testing.T) {
+	f := newTestDataExportService(t)
+	ctx := context.Background()
+
+	_, err := f.svc.Latest(ctx, 1)
+	assertAppErrorCode(t, err, 404)
+
+	_, _ = f.svc.Request(ctx, 1)
+	f.runPending()
+	f.repo.exports[0].ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true}
+
+	latest, _ := f.svc.Latest(ctx, 1)
+	if latest.Status != dto.DataExportExpired {
+		t.Errorf("expected expired, got %q", latest.Status)
+	}
+	_, _, err = f.svc.Open(ctx, 1)
+	assertAppErrorCode(t, err, 404)
+
+	purged, err := f.svc.PurgeExpired(ctx)
+	if err != nil {
+		t.Fatalf("PurgeExpired: %v", err)
+	}
+	if purged != 1 || len(f.repo.exports) != 0 || len(f.store.files) != 0 {
+		t.Errorf("expected export and archive removed, got purged=%d exports=%d files=%d",
+			purged, len(f.repo.exports), len(f.store.files))
+	}
+}
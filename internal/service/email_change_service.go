@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/database"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/email"
+)
+
+const emailChangeTokenTTL = 24 * time.Hour
+
+type EmailChangeService interface {
+	RequestChange(ctx context.Context, userID int64, newEmail string) error
+	Confirm(ctx context.Context, token string) error
+}
+
+type emailChangeService struct {
+	userRepo    repository.UserRepository
+	changeRepo  repository.EmailChangeRepository
+	sender      email.Sender
+	cache       cache.Cache
+	frontendURL string
+	txManager   *database.TxManager
+}
+
+func NewEmailChangeService(
+	userRepo repository.UserRepository,
+	changeRepo repository.EmailChangeRepository,
+	sender email.Sender,
+	appCache cache.Cache,
+	frontendURL string,
+	txManager *database.TxManager,
+) EmailChangeService {
+	return &emailChangeService{
+		userRepo:    userRepo,
+		changeRepo:  changeRepo,
+		sender:      sender,
+		cache:       appCache,
+		frontendURL: frontendURL,
+		txManager:   txManager,
+	}
+}
+
+// RequestChange stores a pending email change and sends a confirmation link to the new address.
+// The user's email is left untouched until Confirm is called with the token.
+func (s *emailChangeService) RequestChange(ctx context.Context, userID int64, newEmail string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return apperror.NewNotFound("user not found")
+		}
+		return apperror.NewInternal("failed to get user")
+	}
+	if newEmail == user.Email {
+		return nil
+	}
+
+	dup, err := s.userRepo.GetByEmail(ctx, newEmail)
+	if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		return apperror.NewInternal("failed to check email availability")
+	}
+	if dup != nil {
+		return apperror.NewBadRequest("email already in use")
+	}
+
+	// Generate token
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return apperror.NewInternal("failed to generate email change token")
+	}
+	token := hex.EncodeToString(b)
+
+	// Only the most recent request stays valid
+	_ = s.changeRepo.DeleteByUserID(ctx, userID)
+
+	_, err = s.changeRepo.Create(ctx, sqlc.CreateEmailChangeTokenParams{
+		UserID:    userID,
+		NewEmail:  newEmail,
+		Token:     token,
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(emailChangeTokenTTL), Valid: true},
+	})
+	if err != nil {
+		return apperror.NewInternal("failed to create email change token")
+	}
+
+	confirmURL := fmt.Sprintf("%s/confirm-email-change?token=%s", s.frontendURL, token)
+	if err := s.sender.Send(ctx, email.Message{
+		To:      []string{newEmail},
+		Subject: "Confirm Your New Email Address",
+		HTML:    fmt.Sprintf("<p>Click <a href=%q>here</a> to confirm your new email address. This link expires in 24 hours.</p>", confirmURL),
+	}); err != nil {
+		slog.Error("failed to send email change confirmation", slog.Any("error", err))
+	}
+
+	return nil
+}
+
+func (s *emailChangeService) Confirm(ctx context.Context, token string) error {
+	var userID int64
+	doConfirm := func(userRepo repository.UserRepository, changeRepo repository.EmailChangeRepository) error {
+		ct, err := changeRepo.GetByToken(ctx, token)
+		if err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				return apperror.NewBadRequest("invalid or expired email change token")
+			}
+			return apperror.NewInternal("failed to verify email change token")
+		}
+
+		if ct.ExpiresAt.Time.Before(time.Now()) {
+			_ = changeRepo.Delete(ctx, token)
+			return apperror.NewBadRequest("email change token has expired")
+		}
+
+		// The new address proves ownership by following the link, so it is marked verified
+		_, err = userRepo.UpdateEmail(ctx, sqlc.UpdateUserEmailParams{
+			Email: ct.NewEmail,
+			ID:    ct.UserID,
+		})
+		if err != nil {
+			if repository.IsUniqueViolation(err) {
+				return apperror.NewBadRequest("email already in use")
+			}
+			if errors.Is(err, apperror.ErrNotFound) {
+				return apperror.NewNotFound("user not found")
+			}
+			return apperror.NewInternal("failed to update email")
+		}
+
+		_ = changeRepo.DeleteByUserID(ctx, ct.UserID)
+		userID = ct.UserID
+		return nil
+	}
+
+	var err error
+	if s.txManager != nil {
+		err = s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+			return doConfirm(repository.NewUserRepository(tx), repository.NewEmailChangeRepository(tx))
+		})
+	} else {
+		err = doConfirm(s.userRepo, s.changeRepo)
+	}
+	if err != nil {
+		return err
+	}
+	forgetUser(ctx, s.cache, userID)
+	return nil
+}
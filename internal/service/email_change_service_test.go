@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+// ---------------------------------------------------------------------------
+// RequestChange
+// ---------------------------------------------------------------------------
+
+func TestRequestEmailChange(t *testing.T) {
+	t.Run("stores pending change and emails new address", func(t *testing.T) {
+		userRepo := newMockUserRepo()
+		userRepo.users[1] = &sqlc.User{ID: 1, Email: "old@example.com"}
+		changeRepo := newMockEmailChangeRepo()
+		sender := newMockEmailSender()
+		svc := NewEmailChangeService(userRepo, changeRepo, sender, newMockCache(), "http://localhost:3000", nil)
+
+		if err := svc.RequestChange(context.Background(), 1, "new@example.com"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if userRepo.users[1].Email != "old@example.com" {
+			t.Errorf("email changed before confirmation: %s", userRepo.users[1].Email)
+		}
+		if len(changeRepo.tokens) != 1 {
+			t.Fatalf("expected 1 pending token, got %d", len(changeRepo.tokens))
+		}
+		if sender.sent != 1 {
+			t.Errorf("expected 1 email sent, got %d", sender.sent)
+		}
+	})
+
+	t.Run("email already in use", func(t *testing.T) {
+		userRepo := newMockUserRepo()
+		userRepo.users[1] = &sqlc.User{ID: 1, Email: "old@example.com"}
+		userRepo.users[2] = &sqlc.User{ID: 2, Email: "taken@example.com"}
+		svc := NewEmailChangeService(userRepo, newMockEmailChangeRepo(), newMockEmailSender(), newMockCache(), "http://localhost:3000", nil)
+
+		err := svc.RequestChange(context.Background(), 1, "taken@example.com")
+		var appErr *apperror.AppError
+		if !errors.As(err, &appErr) {
+			t.Fatalf("expected AppError, got %T", err)
+		}
+		if appErr.Code != 400 {
+			t.Errorf("expected 400, got %d", appErr.Code)
+		}
+	})
+
+	t.Run("same email is a no-op", func(t *testing.T) {
+		userRepo := newMockUserRepo()
+		userRepo.users[1] = &sqlc.User{ID: 1, Email: "old@example.com"}
+		changeRepo := newMockEmailChangeRepo()
+		sender := newMockEmailSender()
+		svc := NewEmailChangeService(userRepo, changeRepo, sender, newMockCache(), "http://localhost:3000", nil)
+
+		if err := svc.RequestChange(context.Background(), 1, "old@example.com"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(changeRepo.tokens) != 0 || sender.sent != 0 {
+			t.Errorf("expected no pending change, got %d tokens and %d emails", len(changeRepo.tokens), sender.sent)
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Confirm
+// ---------------------------------------------------------------------------
+
+func TestConfirmEmailChange(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		userRepo := newMockUserRepo()
+		userRepo.users[1] = &sqlc.User{ID: 1, Email: "old@example.com"}
+		changeRepo := newMockEmailChangeRepo()
+		changeRepo.tokens["valid"] = &sqlc.EmailChangeToken{
+			UserID:    1,
+			NewEmail:  "new@example.com",
+			Token:     "valid",
+			ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+		}
+		appCache := newMockCache()
+		appCache.cacheUser(1)
+		svc := NewEmailChangeService(userRepo, changeRepo, newMockEmailSender(), appCache, "http://localhost:3000", nil)
+
+		if err := svc.Confirm(context.Background(), "valid"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if appCache.userCached(1) {
+			t.Error("expected the cached profile with the old email dropped")
+		}
+		user := userRepo.users[1]
+		if user.Email != "new@example.com" {
+			t.Errorf("expected new@example.com, got %s", user.Email)
+		}
+		if !user.EmailVerifiedAt.Valid {
+			t.Error("expected new email to be marked verified")
+		}
+		if len(changeRepo.tokens) != 0 {
+			t.Errorf("expected token to be consumed, got %d", len(changeRepo.tokens))
+		}
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		userRepo := newMockUserRepo()
+		userRepo.users[1] = &sqlc.User{ID: 1, Email: "old@example.com"}
+		changeRepo := newMockEmailChangeRepo()
+		changeRepo.tokens["expired"] = &sqlc.EmailChangeToken{
+			UserID:    1,
+			NewEmail:  "new@example.com",
+			Token:     "expired",
+			ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true},
+		}
+		svc := NewEmailChangeService(userRepo, changeRepo, newMockEmailSender(), newMockCache(), "http://localhost:3000", nil)
+
+		err := svc.Confirm(context.Background(), "expired")
+		var appErr *apperror.AppError
+		if !errors.As(err, &appErr) {
+			t.Fatalf("expected AppError, got %T", err)
+		}
+		if appErr.Code != 400 {
+			t.Errorf("expected 400, got %d", appErr.Code)
+		}
+		if userRepo.users[1].Email != "old@example.com" {
+			t.Errorf("email should not change, got %s", userRepo.users[1].Email)
+		}
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		svc := NewEmailChangeService(newMockUserRepo(), newMockEmailChangeRepo(), newMockEmailSender(), newMockCache(), "http://localhost:3000", nil)
+
+		err := svc.Confirm(context.Background(), "nope")
+		var appErr *apperror.AppError
+		if !errors.As(err, &appErr) {
+			t.Fatalf("expected AppError, got %T", err)
+		}
+		if appErr.Code != 400 {
+			t.Errorf("expected 400, got %d", appErr.Code)
+		}
+	})
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+// EmailDomainPolicy restricts which email domains may create new accounts. A listed
+// domain also covers its subdomains. When Allowed is empty every domain not in Blocked
+// may sign up; otherwise only allowed domains may, and Blocked still takes precedence.
+type EmailDomainPolicy struct {
+	Allowed []string
+	Blocked []string
+}
+
+// Permits reports whether the policy lets the email address sign up.
+func (p EmailDomainPolicy) Permits(email string) bool {
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+
+	if matchesDomain(domain, p.Blocked) {
+		return false
+	}
+	return len(p.Allowed) == 0 || matchesDomain(domain, p.Allowed)
+}
+
+// checkSignup returns a forbidden error when the email's domain may not sign up.
+func (p EmailDomainPolicy) checkSignup(email string) error {
+	if !p.Permits(email) {
+		return apperror.NewForbidden("registration is not open to this email domain")
+	}
+	return nil
+}
+
+func matchesDomain(domain string, list []string) bool {
+	for _, d := range list {
+		d = strings.ToLower(strings.TrimPrefix(d, "@"))
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/email"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/pagination"
+)
+
+const (
+	// emailOutboxLock is how long a worker may take to send a claimed message before another
+	// worker assumes it died and claims the message again.
+	emailOutboxLock = 5 * time.Minute
+	// A failed message is retried after emailRetryBase, doubling with each attempt up to
+	// emailRetryMax.
+	emailRetryBase = 30 * time.Second
+	emailRetryMax  = time.Hour
+)
+
+// EmailOutboxService queues emails in the database and delivers them in the background, so a
+// message is not lost when the mail server is briefly unavailable. As an email.Sender it can
+// replace the mail driver for every service: Send only queues the message.
+type EmailOutboxService interface {
+	email.Sender
+	// DeliverDue sends the messages that are due, retrying failures with exponential backoff
+	// and dead-lettering those rejected permanently or out of attempts. It returns how many
+	// were sent.
+	DeliverDue(ctx context.Context) (int, error)
+	// PurgeFinished deletes sent and dead messages older than the retention period.
+	PurgeFinished(ctx context.Context) (int64, error)
+	List(ctx context.Context, query dto.EmailOutboxQuery, page, perPage int) ([]dto.EmailOutboxResponse, int64, error)
+	// Retry queues a dead message again with a fresh set of attempts.
+	Retry(ctx context.Context, id int64) (*dto.EmailOutboxResponse, error)
+}
+
+type emailOutboxService struct {
+	repo        repository.EmailOutboxRepository
+	sender      email.Sender
+	auditLog    AuditLogService
+	batchSize   int32
+	maxAttempts int32
+	retention   time.Duration
+}
+
+// NewEmailOutboxService returns an outbox delivering through sender, the mail driver.
+func NewEmailOutboxService(
+	repo repository.EmailOutboxRepository,
+	sender email.Sender,
+	auditLog AuditLogService,
+	batchSize int,
+	maxAttempts int,
+	retentionDays int,
+) EmailOutboxService {
+	return &emailOutboxService{
+		repo:        repo,
+		sender:      sender,
+		auditLog:    auditLog,
+		batchSize:   int32(batchSize),
+		maxAttempts: int32(maxAttempts),
+		retention:   time.Duration(retentionDays) * 24 * time.Hour,
+	}
+}
+
+func (s *emailOutboxService) Send(ctx context.Context, msg email.Message) error {
+	attachments := []byte("[]")
+	if len(msg.Attachments) > 0 {
+		var err error
+		if attachments, err = json.Marshal(msg.Attachments); err != nil {
+			return err
+		}
+	}
+	_, err := s.repo.Enqueue(ctx, sqlc.EnqueueEmailParams{
+		Recipients:  msg.To,
+		Subject:     msg.Subject,
+		Body:        msg.Body,
+		Html:        msg.HTML,
+		Locale:      msg.Locale,
+		Attachments: attachments,
+	})
+	return err
+}
+
+func (s *emailOutboxService) DeliverDue(ctx context.Context) (int, error) {
+	sent := 0
+	for {
+		msgs, err := s.repo.ClaimDue(ctx, s.batchSize, emailOutboxLock)
+		if err != nil {
+			return sent, err
+		}
+		for i := range msgs {
+			// On shutdown, hand the rest of the batch back instead of leaving it locked
+			if ctx.Err() != nil {
+				s.release(context.WithoutCancel(ctx), msgs[i:])
+				return sent, ctx.Err()
+			}
+			if s.deliver(ctx, &msgs[i]) {
+				sent++
+			}
+		}
+		if len(msgs) < int(s.batchSize) {
+			return sent, nil
+		}
+	}
+}
+
+// deliver sends one claimed message and records the outcome. It reports whether the
+// message was sent.
+func (s *emailOutboxService) deliver(ctx context.Context, msg *sqlc.EmailOutbox) bool {
+	var attachments []email.Attachment
+	err := json.Unmarshal(msg.Attachments, &attachments)
+	if err != nil {
+		err = fmt.Errorf("%w: %v", email.ErrInvalidAttachment, err)
+	} else {
+		err = s.sender.Send(ctx, email.Message{
+			To:          msg.Recipients,
+			Subject:     msg.Subject,
+			Body:        msg.Body,
+			HTML:        msg.Html,
+			Locale:      msg.Locale,
+			Attachments: attachments,
+		})
+	}
+	if err == nil {
+		if err := s.repo.MarkSent(ctx, msg.ID); err != nil {
+			slog.Error("failed to mark email sent", slog.Int64("email_id", msg.ID), slog.Any("error", err))
+		}
+		return true
+	}
+
+	log := slog.With(slog.Int64("email_id", msg.ID), slog.Int("attempts", int(msg.Attempts)), slog.Any("error", err))
+	if email.IsPermanent(err) || msg.Attempts >= s.maxAttempts {
+		log.Error("email dead-lettered")
+		if err := s.repo.MarkDead(ctx, msg.ID, err.Error()); err != nil {
+			slog.Error("failed to dead-letter email", slog.Int64("email_id", msg.ID), slog.Any("error", err))
+		}
+		return false
+	}
+
+	next := time.Now().Add(emailRetryDelay(msg.Attempts))
+	log.Warn("email delivery failed, will retry", slog.Time("next_attempt_at", next))
+	if err := s.repo.MarkFailed(ctx, msg.ID, err.Error(), next); err != nil {
+		slog.Error("failed to reschedule email", slog.Int64("email_id", msg.ID), slog.Any("error", err))
+	}
+	return false
+}
+
+// release makes claimed messages due again without waiting for their lock to expire.
+func (s *emailOutboxService) release(ctx context.Context, msgs []sqlc.EmailOutbox) {
+	for _, msg := range msgs {
+		if err := s.repo.MarkFailed(ctx, msg.ID, "interrupted by shutdown", time.Now()); err != nil {
+			slog.Error("failed to release email", slog.Int64("email_id", msg.ID), slog.Any("error", err))
+		}
+	}
+}
+
+// emailRetryDelay returns the wait after the given number of failed attempts:
+// emailRetryBase * 2^(attempts-1), capped at emailRetryMax.
+func emailRetryDelay(attempts int32) time.Duration {
+	d := emailRetryBase
+	for i := int32(1); i < attempts; i++ {
+		d *= 2
+		if d >= emailRetryMax {
+			return emailRetryMax
+		}
+	}
+	return d
+}
+
+func (s *emailOutboxService) PurgeFinished(ctx context.Context) (int64, error) {
+	return s.repo.PurgeFinished(ctx, time.Now().Add(-s.retention))
+}
+
+func (s *emailOutboxService) List(ctx context.Context, query dto.EmailOutboxQuery, page, perPage int) ([]dto.EmailOutboxResponse, int64, error) {
+	limit, offset := pagination.LimitOffset(page, perPage)
+
+	msgs, err := s.repo.List(ctx, query.Status, limit, offset)
+	if err != nil {
+		return nil, 0, apperror.NewInternal("failed to list emails")
+	}
+	total, err := s.repo.Count(ctx, query.Status)
+	if err != nil {
+		return nil, 0, apperror.NewInternal("failed to count emails")
+	}
+
+	responses := make([]dto.EmailOutboxResponse, len(msgs))
+	for i := range msgs {
+		responses[i] = *toEmailOutboxResponse(&msgs[i])
+	}
+	return responses, total, nil
+}
+
+func (s *emailOutboxService) Retry(ctx context.Context, id int64) (*dto.EmailOutboxResponse, error) {
+	msg, err := s.repo.RetryDead(ctx, id)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("dead email not found")
+		}
+		return nil, apperror.NewInternal("failed to retry email")
+	}
+	recordAudit(ctx, s.auditLog, AuditEntry{
+		Action: dto.AuditEmailRetried, TargetType: dto.AuditTargetEmail, TargetID: id,
+		After: map[string]any{"status": msg.Status},
+	})
+	return toEmailOutboxResponse(msg), nil
+}
+
+func toEmailOutboxResponse(m *sqlc.EmailOutbox) *dto.EmailOutboxResponse {
+	resp := &dto.EmailOutboxResponse{
+		ID:         m.ID,
+		Recipients: m.Recipients,
+		Subject:    m.Subject,
+		Status:     m.Status,
+		Attempts:   m.Attempts,
+		LastError:  m.LastError,
+		CreatedAt:  m.CreatedAt.Time,
+	}
+	if m.Status == dto.EmailPending {
+		resp.NextAttemptAt = &m.NextAttemptAt.Time
+	}
+	if m.SentAt.Valid {
+		resp.SentAt = &m.SentAt.Time
+	}
+	return resp
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/textproto"
+	"testing"
+	"time"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/email"
+)
+
+func newEmailOutboxFixture() (*emailOutboxService, *mockEmailOutboxRepo, *mockEmailSender, *mockAuditLogRepo) {
+	repo, sender, audit := newMockEmailOutboxRepo(), newMockEmailSender(), newMockAuditLogRepo()
+	svc := NewEmailOutboxService(repo, sender, NewAuditLogService(audit), 2, 3, 30).(*emailOutboxService)
+	return svc, repo, sender, audit
+}
+
+func TestEmailOutbox_SendQueues(t *testing.T) {
+	svc, repo, sender, _ := newEmailOutboxFixture()
+	ctx := context.Background()
+
+	msg := email.Message{To: []string{"ann@example.com"}, Subject: "Reset", HTML: "<a>link</a>", Locale: "vi"}
+	if err := svc.Send(ctx, msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if sender.sent != 0 {
+		t.Fatal("expected Send to only queue the message")
+	}
+
+	for range 2 {
+		_ = svc.Send(ctx, msg)
+	}
+	// Batches of 2 are claimed until none are left
+	sent, err := svc.DeliverDue(ctx)
+	if err != nil || sent != 3 {
+		t.Fatalf("DeliverDue = %d, %v, want 3", sent, err)
+	}
+	if sender.last.Locale != "vi" || sender.last.HTML != "<a>link</a>" {
+		t.Errorf("unexpected message sent: %+v", sender.last)
+	}
+	e := repo.emails[0]
+	if e.Status != dto.EmailSent || !e.SentAt.Valid || e.Html != "" {
+		t.Errorf("expected a sent message with its body cleared, got %+v", e)
+	}
+}
+
+func TestEmailOutbox_SendsAttachments(t *testing.T) {
+	svc, repo, sender, _ := newEmailOutboxFixture()
+	ctx := context.Background()
+
+	logo := email.Attachment{Filename: "logo.png", ContentType: "image/png", Data: []byte{0x89, 'P', 'N', 'G'}, ContentID: "logo"}
+	report := email.Attachment{Filename: "report.csv", Data: []byte("id,email\n1,ann@example.com\n")}
+	_ = svc.Send(ctx, email.Message{To: []string{"ann@example.com"}, Subject: "Report", HTML: `<img src="cid:logo">`, Attachments: []email.Attachment{logo, report}})
+	_ = svc.Send(ctx, email.Message{To: []string{"bob@example.com"}, Subject: "Plain"})
+	if string(repo.emails[1].Attachments) != "[]" {
+		t.Errorf("expected an empty attachment list, got %s", repo.emails[1].Attachments)
+	}
+
+	repo.emails[1].NextAttemptAt.Time = time.Now().Add(time.Hour)
+	if sent, err := svc.DeliverDue(ctx); err != nil || sent != 1 {
+		t.Fatalf("DeliverDue = %d, %v, want 1", sent, err)
+	}
+	got := sender.last.Attachments
+	if len(got) != 2 || !got[0].Inline() || string(got[1].Data) != string(report.Data) || got[0].ContentType != "image/png" {
+		t.Errorf("unexpected attachments sent: %+v", got)
+	}
+	if string(repo.emails[0].Attachments) != "[]" {
+		t.Error("expected attachments cleared once sent")
+	}
+}
+
+func TestEmailOutbox_UnreadableAttachmentsAreDeadLettered(t *testing.T) {
+	svc, repo, sender, _ := newEmailOutboxFixture()
+	ctx := context.Background()
+	_ = svc.Send(ctx, email.Message{To: []string{"ann@example.com"}, Subject: "Hi"})
+	repo.emails[0].Attachments = []byte("{")
+
+	_, _ = svc.DeliverDue(ctx)
+	if e := repo.emails[0]; e.Status != dto.EmailDead || sender.sent != 0 {
+		t.Fatalf("expected the message dead without being sent, got %+v", e)
+	}
+}
+
+func TestEmailOutbox_RetriesWithBackoff(t *testing.T) {
+	svc, repo, sender, _ := newEmailOutboxFixture()
+	ctx := context.Background()
+	_ = svc.Send(ctx, email.Message{To: []string{"ann@example.com"}, Subject: "Hi"})
+
+	sender.sendErr = errors.New("connection refused")
+	if sent, _ := svc.DeliverDue(ctx); sent != 0 {
+		t.Fatalf("expected nothing sent, got %d", sent)
+	}
+	e := repo.emails[0]
+	if e.Status != dto.EmailPending || e.LastError != "connection refused" {
+		t.Fatalf("expected the message pending with its error, got %+v", e)
+	}
+	if wait := time.Until(e.NextAttemptAt.Time); wait < 25*time.Second || wait > emailRetryBase {
+		t.Errorf("expected a retry after %v, got %v", emailRetryBase, wait)
+	}
+
+	// Not due yet
+	if sent, _ := svc.DeliverDue(ctx); sent != 0 || repo.emails[0].Attempts != 1 {
+		t.Fatal("expected the message to wait for its next attempt")
+	}
+
+	// Out of attempts after the third failure
+	for range 2 {
+		repo.emails[0].NextAttemptAt.Time = time.Now()
+		_, _ = svc.DeliverDue(ctx)
+	}
+	if e := repo.emails[0]; e.Status != dto.EmailDead || e.Attempts != 3 {
+		t.Fatalf("expected the message dead after 3 attempts, got %+v", e)
+	}
+}
+
+func TestEmailOutbox_PermanentFailureIsDeadLettered(t *testing.T) {
+	svc, repo, sender, _ := newEmailOutboxFixture()
+	ctx := context.Background()
+	_ = svc.Send(ctx, email.Message{To: []string{"nobody@example.com"}, Subject: "Hi"})
+
+	sender.sendErr = &textproto.Error{Code: 550, Msg: "mailbox unavailable"}
+	_, _ = svc.DeliverDue(ctx)
+	if e := repo.emails[0]; e.Status != dto.EmailDead || e.Attempts != 1 {
+		t.Fatalf("expected the message dead after one attempt, got %+v", e)
+	}
+}
+
+func TestEmailOutbox_Retry(t *testing.T) {
+	svc, repo, sender, audit := newEmailOutboxFixture()
+	ctx := WithAuditActor(context.Background(), AuditActor{UserID: 1})
+	_ = svc.Send(ctx, email.Message{To: []string{"ann@example.com"}, Subject: "Hi"})
+
+	// Only dead messages can be retried
+	_, err := svc.Retry(ctx, 1)
+	assertAppErrorCode(t, err, 404)
+
+	sender.sendErr = &textproto.Error{Code: 554, Msg: "rejected"}
+	_, _ = svc.DeliverDue(ctx)
+	sender.sendErr = nil
+
+	resp, err := svc.Retry(ctx, 1)
+	if err != nil {
+		t.Fatalf("Retry: %v", err)
+	}
+	if resp.Status != dto.EmailPending || resp.Attempts != 0 || resp.NextAttemptAt == nil {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Action != dto.AuditEmailRetried {
+		t.Errorf("expected the retry in the audit log, got %+v", audit.entries)
+	}
+
+	if sent, _ := svc.DeliverDue(ctx); sent != 1 || repo.emails[0].Status != dto.EmailSent {
+		t.Error("expected the retried message to be sent")
+	}
+}
+
+func TestEmailOutbox_List(t *testing.T) {
+	svc, _, sender, _ := newEmailOutboxFixture()
+	ctx := context.Background()
+	_ = svc.Send(ctx, email.Message{To: []string{"ann@example.com"}, Subject: "First"})
+	_, _ = svc.DeliverDue(ctx)
+	_ = svc.Send(ctx, email.Message{To: []string{"bob@example.com"}, Subject: "Second"})
+	sender.sendErr = errors.New("timeout")
+	_, _ = svc.DeliverDue(ctx)
+
+	all, total, err := svc.List(ctx, dto.EmailOutboxQuery{}, 1, 10)
+	if err != nil || total != 2 || all[0].Subject != "Second" {
+		t.Fatalf("List = %+v, %d, %v", all, total, err)
+	}
+	pending, total, _ := svc.List(ctx, dto.EmailOutboxQuery{Status: dto.EmailPending}, 1, 10)
+	if total != 1 || pending[0].LastError != "timeout" || pending[0].NextAttemptAt == nil {
+		t.Errorf("unexpected pending emails %+v", pending)
+	}
+}
+
+func TestEmailRetryDelay(t *testing.T) {
+	for attempts, want := range map[int32]time.Duration{1: 30 * time.Second, 2: time.Minute, 4: 4 * time.Minute, 20: time.Hour} {
+		if got := emailRetryDelay(attempts); got != want {
+			t.Errorf("emailRetryDelay(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
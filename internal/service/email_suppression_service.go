@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/email"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/pagination"
+)
+
+// EmailSuppressionService keeps the addresses that hard-bounced or complained, as reported
+// by the email provider's webhook, and stops mail to them so the sending reputation is not
+// damaged by repeatedly mailing dead or unwilling inboxes.
+type EmailSuppressionService interface {
+	// Wrap returns a sender that drops suppressed recipients before sending through sender.
+	// A message left without recipients fails with email.ErrSuppressed.
+	Wrap(sender email.Sender) email.Sender
+	// HandleWebhook suppresses the addresses in a bounce or complaint payload of provider,
+	// after checking secret against the configured webhook secret. It returns how many
+	// addresses were suppressed.
+	HandleWebhook(ctx context.Context, provider, secret string, body []byte) (int, error)
+	List(ctx context.Context, query dto.EmailSuppressionQuery, page, perPage int) ([]dto.EmailSuppressionResponse, int64, error)
+	// Remove lets mail reach a suppressed address again.
+	Remove(ctx context.Context, id int64) error
+}
+
+type emailSuppressionService struct {
+	repo          repository.EmailSuppressionRepository
+	auditLog      AuditLogService
+	webhookSecret string
+	// confirmSNS visits an Amazon SNS subscription confirmation URL.
+	confirmSNS func(ctx context.Context, subscribeURL string) error
+}
+
+// NewEmailSuppressionService returns a suppression list fed by provider webhooks that
+// authenticate with webhookSecret; an empty secret disables the webhooks.
+func NewEmailSuppressionService(repo repository.EmailSuppressionRepository, auditLog AuditLogService, webhookSecret string) EmailSuppressionService {
+	return &emailSuppressionService{
+		repo:          repo,
+		auditLog:      auditLog,
+		webhookSecret: webhookSecret,
+		confirmSNS:    email.ConfirmSNSSubscription,
+	}
+}
+
+func (s *emailSuppressionService) Wrap(sender email.Sender) email.Sender {
+	return &suppressingSender{sender: sender, repo: s.repo}
+}
+
+// suppressingSender checks the recipients of every message against the suppression list.
+type suppressingSender struct {
+	sender email.Sender
+	repo   repository.EmailSuppressionRepository
+}
+
+func (s *suppressingSender) Send(ctx context.Context, msg email.Message) error {
+	addrs := make([]string, len(msg.To))
+	for i, to := range msg.To {
+		addrs[i] = strings.ToLower(to)
+	}
+	suppressed, err := s.repo.Suppressed(ctx, addrs)
+	if err != nil {
+		return err
+	}
+	if len(suppressed) == 0 {
+		return s.sender.Send(ctx, msg)
+	}
+
+	blocked := make(map[string]bool, len(suppressed))
+	for _, addr := range suppressed {
+		blocked[addr] = true
+	}
+	to := make([]string, 0, len(msg.To))
+	for i, addr := range msg.To {
+		if !blocked[addrs[i]] {
+			to = append(to, addr)
+		}
+	}
+	slog.Info("skipped suppressed email recipients",
+		slog.String("subject", msg.Subject), slog.Int("suppressed", len(msg.To)-len(to)))
+	if len(to) == 0 {
+		return email.ErrSuppressed
+	}
+	msg.To = to
+	return s.sender.Send(ctx, msg)
+}
+
+func (s *emailSuppressionService) HandleWebhook(ctx context.Context, provider, secret string, body []byte) (int, error) {
+	if s.webhookSecret == "" {
+		return 0, apperror.NewNotFound("email webhooks not configured")
+	}
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(s.webhookSecret)) != 1 {
+		return 0, apperror.NewUnauthorized("invalid webhook credentials")
+	}
+
+	feedback, confirmURL, err := email.ParseFeedback(provider, body)
+	if err != nil {
+		if errors.Is(err, email.ErrUnknownProvider) {
+			return 0, apperror.NewNotFound("unknown email provider")
+		}
+		return 0, apperror.NewBadRequest("invalid webhook payload")
+	}
+	if confirmURL != "" {
+		if err := s.confirmSNS(ctx, confirmURL); err != nil {
+			slog.Error("failed to confirm SNS subscription", slog.Any("error", err))
+			return 0, apperror.NewBadRequest("failed to confirm subscription")
+		}
+		slog.Info("confirmed SNS subscription for email feedback")
+		return 0, nil
+	}
+
+	suppressed := 0
+	for _, f := range feedback {
+		addr := strings.ToLower(strings.TrimSpace(f.Email))
+		if addr == "" {
+			continue
+		}
+		if _, err := s.repo.Upsert(ctx, sqlc.UpsertEmailSuppressionParams{
+			Email:    addr,
+			Reason:   f.Kind,
+			Provider: provider,
+			Detail:   f.Detail,
+		}); err != nil {
+			return suppressed, apperror.NewInternal("failed to suppress email")
+		}
+		slog.Info("email address suppressed", slog.String("provider", provider), slog.String("reason", f.Kind))
+		suppressed++
+	}
+	return suppressed, nil
+}
+
+func (s *emailSuppressionService) List(ctx context.Context, query dto.EmailSuppressionQuery, page, perPage int) ([]dto.EmailSuppressionResponse, int64, error) {
+	limit, offset := pagination.LimitOffset(page, perPage)
+
+	items, err := s.repo.List(ctx, query.Reason, query.Search, limit, offset)
+	if err != nil {
+		return nil, 0, apperror.NewInternal("failed to list suppressed emails")
+	}
+	total, err := s.repo.Count(ctx, query.Reason, query.Search)
+	if err != nil {
+		return nil, 0, apperror.NewInternal("failed to count suppressed emails")
+	}
+
+	responses := make([]dto.EmailSuppressionResponse, len(items))
+	for i := range items {
+		responses[i] = toEmailSuppressionResponse(&items[i])
+	}
+	return responses, total, nil
+}
+
+func (s *emailSuppressionService) Remove(ctx context.Context, id int64) error {
+	item, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return apperror.NewNotFound("suppressed email not found")
+		}
+		return apperror.NewInternal("failed to remove suppressed email")
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return apperror.NewNotFound("suppressed email not found")
+		}
+		return apperror.NewInternal("failed to remove suppressed email")
+	}
+	recordAudit(ctx, s.auditLog, AuditEntry{
+		Action: dto.AuditEmailUnsuppressed, TargetType: dto.AuditTargetEmailSuppression, TargetID: id,
+		Before: map[string]any{"reason": item.Reason},
+	})
+	return nil
+}
+
+func toEmailSuppressionResponse(s *sqlc.EmailSuppression) dto.EmailSuppressionResponse {
+	return dto.EmailSuppressionResponse{
+		ID:        s.ID,
+		Email:     s.Email,
+		Reason:    s.Reason,
+		Provider:  s.Provider,
+		Detail:    s.Detail,
+		CreatedAt: s.CreatedAt.Time,
+		UpdatedAt: s.UpdatedAt.Time,
+	}
+}
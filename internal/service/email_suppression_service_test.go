@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/email"
+)
+
+const testWebhookSecret = "0123456789abcdef0123456789abcdef"
+
+func newEmailSuppressionFixture() (*emailSuppressionService, *mockEmailSuppressionRepo, *mockAuditLogRepo) {
+	repo, audit := newMockEmailSuppressionRepo(), newMockAuditLogRepo()
+	svc := NewEmailSuppressionService(repo, NewAuditLogService(audit), testWebhookSecret).(*emailSuppressionService)
+	return svc, repo, audit
+}
+
+func TestEmailSuppression_WrapSkipsSuppressedRecipients(t *testing.T) {
+	svc, repo, _ := newEmailSuppressionFixture()
+	ctx := context.Background()
+	_, _ = repo.Upsert(ctx, sqlc.UpsertEmailSuppressionParams{Email: "gone@example.com", Reason: email.FeedbackBounce, Provider: "ses"})
+
+	sender := newMockEmailSender()
+	guarded := svc.Wrap(sender)
+
+	if err := guarded.Send(ctx, email.Message{To: []string{"ann@example.com", "Gone@Example.com"}, Subject: "Hi"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if len(sender.last.To) != 1 || sender.last.To[0] != "ann@example.com" {
+		t.Errorf("expected only the unsuppressed recipient, got %v", sender.last.To)
+	}
+
+	err := guarded.Send(ctx, email.Message{To: []string{"gone@example.com"}, Subject: "Hi"})
+	if !errors.Is(err, email.ErrSuppressed) || !email.IsPermanent(err) {
+		t.Fatalf("expected a permanent ErrSuppressed, got %v", err)
+	}
+	if sender.sent != 1 {
+		t.Errorf("expected nothing sent to a suppressed address, sent %d", sender.sent)
+	}
+}
+
+func TestEmailSuppression_WebhookAuth(t *testing.T) {
+	svc, _, _ := newEmailSuppressionFixture()
+	ctx := context.Background()
+	body := []byte(`{"RecordType":"SpamComplaint","Email":"ann@example.com"}`)
+
+	_, err := svc.HandleWebhook(ctx, "postmark", "wrong", body)
+	assertAppErrorCode(t, err, 401)
+
+	_, err = svc.HandleWebhook(ctx, "smtp", testWebhookSecret, body)
+	assertAppErrorCode(t, err, 404)
+
+	_, err = svc.HandleWebhook(ctx, "postmark", testWebhookSecret, []byte("not json"))
+	assertAppErrorCode(t, err, 400)
+
+	disabled := NewEmailSuppressionService(newMockEmailSuppressionRepo(), nil, "")
+	_, err = disabled.HandleWebhook(ctx, "postmark", "", body)
+	assertAppErrorCode(t, err, 404)
+}
+
+func TestEmailSuppression_WebhookProviders(t *testing.T) {
+	tests := []struct {
+		provider string
+		body     string
+		want     map[string]string // suppressed address -> reason
+	}{
+		{
+			provider: "sendgrid",
+			body: `[{"email":"a@example.com","event":"bounce","type":"bounce","reason":"550 no such user"},
+				{"email":"b@example.com","event":"bounce","type":"blocked"},
+				{"email":"c@example.com","event":"spamreport"},
+				{"email":"d@example.com","event":"delivered"}]`,
+			want: map[string]string{"a@example.com": "bounce", "c@example.com": "complaint"},
+		},
+		{
+			provider: "ses",
+			body:     `{"Type":"Notification","Message":"{\"notificationType\":\"Bounce\",\"bounce\":{\"bounceType\":\"Permanent\",\"bouncedRecipients\":[{\"emailAddress\":\"A@Example.com\"}]}}"}`,
+			want:     map[string]string{"a@example.com": "bounce"},
+		},
+		{
+			provider: "ses",
+			body:     `{"Type":"Notification","Message":"{\"notificationType\":\"Bounce\",\"bounce\":{\"bounceType\":\"Transient\",\"bouncedRecipients\":[{\"emailAddress\":\"a@example.com\"}]}}"}`,
+			want:     map[string]string{},
+		},
+		{
+			provider: "ses",
+			body:     `{"Type":"Notification","Message":"{\"eventType\":\"Complaint\",\"complaint\":{\"complainedRecipients\":[{\"emailAddress\":\"a@example.com\"}]}}"}`,
+			want:     map[string]string{"a@example.com": "complaint"},
+		},
+		{
+			provider: "mailgun",
+			body:     `{"event-data":{"event":"failed","severity":"permanent","recipient":"a@example.com","delivery-status":{"message":"mailbox unavailable"}}}`,
+			want:     map[string]string{"a@example.com": "bounce"},
+		},
+		{
+			provider: "mailgun",
+			body:     `{"event-data":{"event":"failed","severity":"temporary","recipient":"a@example.com"}}`,
+			want:     map[string]string{},
+		},
+		{
+			provider: "postmark",
+			body:     `{"RecordType":"Bounce","Type":"HardBounce","Email":"a@example.com","Description":"unknown user"}`,
+			want:     map[string]string{"a@example.com": "bounce"},
+		},
+		{
+			provider: "postmark",
+			body:     `{"RecordType":"Bounce","Type":"SoftBounce","Email":"a@example.com"}`,
+			want:     map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			svc, repo, _ := newEmailSuppressionFixture()
+			n, err := svc.HandleWebhook(context.Background(), tt.provider, testWebhookSecret, []byte(tt.body))
+			if err != nil {
+				t.Fatalf("HandleWebhook: %v", err)
+			}
+			if n != len(tt.want) || len(repo.items) != len(tt.want) {
+				t.Fatalf("suppressed %d (%d stored), want %d", n, len(repo.items), len(tt.want))
+			}
+			for _, item := range repo.items {
+				if tt.want[item.Email] != item.Reason || item.Provider != tt.provider {
+					t.Errorf("unexpected suppression %+v", item)
+				}
+			}
+		})
+	}
+}
+
+func TestEmailSuppression_WebhookConfirmsSNSSubscription(t *testing.T) {
+	svc, repo, _ := newEmailSuppressionFixture()
+	var confirmed string
+	svc.confirmSNS = func(_ context.Context, u string) error {
+		confirmed = u
+		return nil
+	}
+
+	body := []byte(`{"Type":"SubscriptionConfirmation","SubscribeURL":"https://sns.us-east-1.amazonaws.com/?Action=ConfirmSubscription"}`)
+	if _, err := svc.HandleWebhook(context.Background(), "ses", testWebhookSecret, body); err != nil {
+		t.Fatalf("HandleWebhook: %v", err)
+	}
+	if confirmed != "https://sns.us-east-1.amazonaws.com/?Action=ConfirmSubscription" || len(repo.items) != 0 {
+		t.Errorf("expected the subscription confirmed, got %q", confirmed)
+	}
+}
+
+func TestEmailSuppression_ListAndRemove(t *testing.T) {
+	svc, repo, audit := newEmailSuppressionFixture()
+	ctx := WithAuditActor(context.Background(), AuditActor{UserID: 1})
+	_, _ = repo.Upsert(ctx, sqlc.UpsertEmailSuppressionParams{Email: "ann@example.com", Reason: email.FeedbackBounce, Provider: "ses"})
+	_, _ = repo.Upsert(ctx, sqlc.UpsertEmailSuppressionParams{Email: "bob@example.com", Reason: email.FeedbackComplaint, Provider: "ses"})
+
+	items, total, err := svc.List(ctx, dto.EmailSuppressionQuery{Reason: email.FeedbackComplaint}, 1, 10)
+	if err != nil || total != 1 || items[0].Email != "bob@example.com" {
+		t.Fatalf("List = %+v, %d, %v", items, total, err)
+	}
+
+	if err := svc.Remove(ctx, 1); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if len(repo.items) != 1 {
+		t.Error("expected the suppression removed")
+	}
+	if len(audit.entries) != 1 || audit.entries[0].Action != dto.AuditEmailUnsuppressed {
+		t.Errorf("expected the removal in the audit log, got %+v", audit.entries)
+	}
+	assertAppErrorCode(t, svc.Remove(ctx, 1), 404)
+}
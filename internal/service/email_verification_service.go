@@ -11,6 +11,7 @@ import (
 
 	"github.com/jackc/pgx/v5/pgtype"
 
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
@@ -22,29 +23,37 @@ type EmailVerificationService interface {
 	SendVerification(ctx context.Context, userID int64, userEmail string) error
 	Verify(ctx context.Context, token string) error
 	ResendVerification(ctx context.Context, emailAddr string) error
+	MarkVerified(ctx context.Context, actorID, userID int64) (*dto.UserResponse, error)
+	SendToUser(ctx context.Context, actorID, userID int64) error
 }
 
 type emailVerificationService struct {
-	userRepo  repository.UserRepository
-	verifRepo repository.EmailVerificationRepository
-	sender    email.Sender
-	cache     cache.Cache
-	frontURL  string
+	userRepo     repository.UserRepository
+	verifRepo    repository.EmailVerificationRepository
+	settingsRepo repository.UserSettingsRepository
+	sender       email.Sender
+	cache        cache.Cache
+	frontURL     string
+	auditLog     AuditLogService
 }
 
 func NewEmailVerificationService(
 	userRepo repository.UserRepository,
 	verifRepo repository.EmailVerificationRepository,
+	settingsRepo repository.UserSettingsRepository,
 	sender email.Sender,
 	appCache cache.Cache,
 	frontendURL string,
+	auditLog AuditLogService,
 ) EmailVerificationService {
 	return &emailVerificationService{
-		userRepo:  userRepo,
-		verifRepo: verifRepo,
-		sender:    sender,
-		cache:     appCache,
-		frontURL:  frontendURL,
+		userRepo:     userRepo,
+		verifRepo:    verifRepo,
+		settingsRepo: settingsRepo,
+		sender:       sender,
+		cache:        appCache,
+		frontURL:     frontendURL,
+		auditLog:     auditLog,
 	}
 }
 
@@ -69,13 +78,16 @@ func (s *emailVerificationService) SendVerification(ctx context.Context, userID
 		return fmt.Errorf("create verification token: %w", err)
 	}
 
-	// Send email
-	verifyURL := fmt.Sprintf("%s/verify-email?token=%s", s.frontURL, token)
-	if err := s.sender.Send(ctx, email.Message{
-		To:      []string{userEmail},
-		Subject: "Verify Your Email Address",
-		HTML:    fmt.Sprintf("<p>Click <a href=%q>here</a> to verify your email address. This link expires in 24 hours.</p>", verifyURL),
-	}); err != nil {
+	// Send email in the user's language
+	msg, err := email.Render(email.TemplateVerifyEmail, userLocale(ctx, s.settingsRepo, userID), map[string]any{
+		"URL":   fmt.Sprintf("%s/verify-email?token=%s", s.frontURL, token),
+		"Hours": 24,
+	})
+	if err != nil {
+		return fmt.Errorf("render verification email: %w", err)
+	}
+	msg.To = []string{userEmail}
+	if err := s.sender.Send(ctx, msg); err != nil {
 		slog.Error("failed to send verification email", slog.Any("error", err))
 	}
 
@@ -101,6 +113,7 @@ func (s *emailVerificationService) Verify(ctx context.Context, token string) err
 	if err != nil {
 		return apperror.NewInternal("failed to verify email")
 	}
+	forgetUser(ctx, s.cache, vt.UserID)
 
 	// Delete token
 	_ = s.verifRepo.Delete(ctx, token)
@@ -133,3 +146,62 @@ func (s *emailVerificationService) ResendVerification(ctx context.Context, email
 
 	return s.SendVerification(ctx, user.ID, user.Email)
 }
+
+// MarkVerified verifies a user's email on behalf of an administrator and discards any
+// outstanding verification links. Already verified users keep their original timestamp.
+func (s *emailVerificationService) MarkVerified(ctx context.Context, actorID, userID int64) (*dto.UserResponse, error) {
+	user, err := s.verifiableUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.EmailVerifiedAt.Valid {
+		return ToUserResponse(user), nil
+	}
+
+	user, err = s.userRepo.VerifyEmail(ctx, userID)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to verify email")
+	}
+	forgetUser(ctx, s.cache, userID)
+	_ = s.verifRepo.DeleteByUserID(ctx, userID)
+
+	slog.Info("email marked verified by admin", slog.Int64("user_id", userID), slog.Int64("actor_id", actorID))
+	recordAudit(ctx, s.auditLog, AuditEntry{Action: dto.AuditUserEmailVerified, TargetType: dto.AuditTargetUser, TargetID: userID})
+	return ToUserResponse(user), nil
+}
+
+// SendToUser emails a fresh verification link on behalf of an administrator. Unlike
+// ResendVerification it is not subject to the per-address cooldown.
+func (s *emailVerificationService) SendToUser(ctx context.Context, actorID, userID int64) error {
+	user, err := s.verifiableUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt.Valid {
+		return apperror.NewBadRequest("email is already verified")
+	}
+
+	if err := s.SendVerification(ctx, user.ID, user.Email); err != nil {
+		return apperror.NewInternal("failed to send verification email")
+	}
+
+	slog.Info("verification email sent by admin", slog.Int64("user_id", userID), slog.Int64("actor_id", actorID))
+	recordAudit(ctx, s.auditLog, AuditEntry{Action: dto.AuditUserVerificationSent, TargetType: dto.AuditTargetUser, TargetID: userID})
+	return nil
+}
+
+// verifiableUser loads an active user whose email can be verified; guests only have a
+// placeholder address.
+func (s *emailVerificationService) verifiableUser(ctx context.Context, userID int64) (*sqlc.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("user not found")
+		}
+		return nil, apperror.NewInternal("failed to get user")
+	}
+	if user.Role == dto.RoleGuest {
+		return nil, apperror.NewBadRequest("guest accounts have no email address to verify")
+	}
+	return user, nil
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+)
+
+type emailVerificationFixture struct {
+	svc      EmailVerificationService
+	users    *mockUserRepo
+	tokens   *mockEmailVerificationRepo
+	settings *mockUserSettingsRepo
+	sender   *mockEmailSender
+	audit    *mockAuditLogRepo
+}
+
+// newEmailVerificationFixture seeds an unverified user 1, a verified user 2 and guest 3.
+func newEmailVerificationFixture() *emailVerificationFixture {
+	f := &emailVerificationFixture{
+		users:    newMockUserRepo(),
+		tokens:   newMockEmailVerificationRepo(),
+		settings: newMockUserSettingsRepo(),
+		sender:   newMockEmailSender(),
+		audit:    newMockAuditLogRepo(),
+	}
+	verifiedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	f.users.users[1] = &sqlc.User{ID: 1, Email: "new@example.com", Name: "New", Role: dto.RoleUser}
+	f.users.users[2] = &sqlc.User{
+		ID: 2, Email: "done@example.com", Name: "Done", Role: dto.RoleUser,
+		EmailVerifiedAt: pgtype.Timestamptz{Time: verifiedAt, Valid: true},
+	}
+	f.users.users[3] = &sqlc.User{ID: 3, Email: "guest-x@guest.invalid", Name: "Guest", Role: dto.RoleGuest}
+	f.svc = NewEmailVerificationService(f.users, f.tokens, f.settings, f.sender, newMockCache(), "http://localhost:3000", NewAuditLogService(f.audit))
+	return f
+}
+
+func TestMarkEmailVerified(t *testing.T) {
+	t.Run("verifies and discards pending links", func(t *testing.T) {
+		f := newEmailVerificationFixture()
+		ctx := context.Background()
+		_ = f.svc.SendVerification(ctx, 1, "new@example.com")
+
+		resp, err := f.svc.MarkVerified(ctx, 99, 1)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !resp.EmailVerified {
+			t.Error("expected email to be verified")
+		}
+		if len(f.tokens.tokens) != 0 {
+			t.Errorf("expected pending tokens removed, got %d", len(f.tokens.tokens))
+		}
+		if len(f.audit.entries) != 1 || f.audit.entries[0].Action != dto.AuditUserEmailVerified || f.audit.entries[0].TargetID != 1 {
+			t.Errorf("expected a user.email_verified entry, got %+v", f.audit.entries)
+		}
+	})
+
+	t.Run("already verified keeps the original timestamp", func(t *testing.T) {
+		f := newEmailVerificationFixture()
+		want := f.users.users[2].EmailVerifiedAt.Time
+
+		if _, err := f.svc.MarkVerified(context.Background(), 99, 2); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if got := f.users.users[2].EmailVerifiedAt.Time; !got.Equal(want) {
+			t.Errorf("expected verified_at %v, got %v", want, got)
+		}
+		if len(f.audit.entries) != 0 {
+			t.Errorf("expected nothing audited, got %v", f.audit.actions())
+		}
+	})
+
+	t.Run("guest", func(t *testing.T) {
+		f := newEmailVerificationFixture()
+
+		_, err := f.svc.MarkVerified(context.Background(), 99, 3)
+		assertAppErrorCode(t, err, 400)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		f := newEmailVerificationFixture()
+
+		_, err := f.svc.MarkVerified(context.Background(), 99, 42)
+		assertAppErrorCode(t, err, 404)
+	})
+}
+
+func TestSendVerificationToUser(t *testing.T) {
+	t.Run("sends a fresh link without the resend cooldown", func(t *testing.T) {
+		f := newEmailVerificationFixture()
+		ctx := context.Background()
+
+		for range 2 {
+			if err := f.svc.SendToUser(ctx, 99, 1); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+		if f.sender.sent != 2 || f.sender.last.To[0] != "new@example.com" {
+			t.Errorf("expected 2 emails to the user, got %d", f.sender.sent)
+		}
+		if len(f.tokens.tokens) != 1 {
+			t.Errorf("expected only the latest link to remain, got %d", len(f.tokens.tokens))
+		}
+		if got := f.audit.actions(); len(got) != 2 || got[0] != dto.AuditUserVerificationSent {
+			t.Errorf("expected a user.verification_sent entry per email, got %v", got)
+		}
+	})
+
+	t.Run("already verified", func(t *testing.T) {
+		f := newEmailVerificationFixture()
+
+		err := f.svc.SendToUser(context.Background(), 99, 2)
+		assertAppErrorCode(t, err, 400)
+		if f.sender.sent != 0 {
+			t.Errorf("expected no email, got %d", f.sender.sent)
+		}
+	})
+}
+
+func TestSendVerificationLocale(t *testing.T) {
+	f := newEmailVerificationFixture()
+	ctx := context.Background()
+	_, _ = f.settings.Upsert(ctx, sqlc.UpsertUserSettingsParams{UserID: 1, Locale: pgtype.Text{String: "vi-VN", Valid: true}})
+
+	if err := f.svc.SendVerification(ctx, 1, "new@example.com"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if f.sender.last.Subject != "Xác minh địa chỉ email của bạn" || f.sender.last.Locale != "vi" {
+		t.Errorf("expected a Vietnamese email, got %q in %q", f.sender.last.Subject, f.sender.last.Locale)
+	}
+	if !strings.Contains(f.sender.last.HTML, "http://localhost:3000/verify-email?token=") {
+		t.Errorf("expected the verification link, got %q", f.sender.last.HTML)
+	}
+
+	// Without settings the email is in the default locale
+	if err := f.svc.SendVerification(ctx, 3, "guest-x@guest.invalid"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if f.sender.last.Subject != "Verify Your Email Address" || f.sender.last.Locale != "en" {
+		t.Errorf("expected an English email, got %q in %q", f.sender.last.Subject, f.sender.last.Locale)
+	}
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/database"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/pagination"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/storage"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/token"
+)
+
+// erasedUserName replaces the name of anonymized users.
+const erasedUserName = "Erased User"
+
+type ErasureService interface {
+	EraseSelf(ctx context.Context, userID int64, req dto.EraseAccountRequest) (*dto.ErasureResponse, error)
+	Erase(ctx context.Context, actorID, userID int64) (*dto.ErasureResponse, error)
+	List(ctx context.Context, page, perPage int) ([]dto.ErasureResponse, int64, error)
+}
+
+// erasureRepos groups the repositories an erasure writes to so they can be bound to one transaction.
+type erasureRepos struct {
+	users        repository.UserRepository
+	files        repository.FileRepository
+	folders      repository.FolderRepository
+	tokens       repository.RefreshTokenRepository
+	emailChanges repository.EmailChangeRepository
+	credentials  repository.WebAuthnCredentialRepository
+	twoFactor    repository.TwoFactorRepository
+	loginEvents  repository.LoginEventRepository
+	activities   repository.UserActivityRepository
+	exports      repository.DataExportRepository
+	deletions    repository.AccountDeletionRepository
+	audits       repository.ErasureAuditRepository
+}
+
+func newErasureRepos(db sqlc.DBTX) erasureRepos {
+	return erasureRepos{
+		users:        repository.NewUserRepository(db),
+		files:        repository.NewFileRepository(db),
+		folders:      repository.NewFolderRepository(db),
+		tokens:       repository.NewRefreshTokenRepository(db),
+		emailChanges: repository.NewEmailChangeRepository(db),
+		credentials:  repository.NewWebAuthnCredentialRepository(db),
+		twoFactor:    repository.NewTwoFactorRepository(db),
+		loginEvents:  repository.NewLoginEventRepository(db),
+		activities:   repository.NewUserActivityRepository(db),
+		exports:      repository.NewDataExportRepository(db),
+		deletions:    repository.NewAccountDeletionRepository(db),
+		audits:       repository.NewErasureAuditRepository(db),
+	}
+}
+
+type erasureService struct {
+	repos       erasureRepos
+	storage     storage.Storage
+	revocations *token.RevocationStore
+	cache       cache.Cache
+	txManager   *database.TxManager
+}
+
+func NewErasureService(
+	userRepo repository.UserRepository,
+	fileRepo repository.FileRepository,
+	folderRepo repository.FolderRepository,
+	refreshTokenRepo repository.RefreshTokenRepository,
+	emailChangeRepo repository.EmailChangeRepository,
+	credentialRepo repository.WebAuthnCredentialRepository,
+	twoFactorRepo repository.TwoFactorRepository,
+	loginEventRepo repository.LoginEventRepository,
+	activityRepo repository.UserActivityRepository,
+	dataExportRepo repository.DataExportRepository,
+	deletionRepo repository.AccountDeletionRepository,
+	auditRepo repository.ErasureAuditRepository,
+	store storage.Storage,
+	revocations *token.RevocationStore,
+	appCache cache.Cache,
+	txManager *database.TxManager,
+) ErasureService {
+	return &erasureService{
+		repos: erasureRepos{
+			users:        userRepo,
+			files:        fileRepo,
+			folders:      folderRepo,
+			tokens:       refreshTokenRepo,
+			emailChanges: emailChangeRepo,
+			credentials:  credentialRepo,
+			twoFactor:    twoFactorRepo,
+			loginEvents:  loginEventRepo,
+			activities:   activityRepo,
+			exports:      dataExportRepo,
+			deletions:    deletionRepo,
+			audits:       auditRepo,
+		},
+		storage:     store,
+		revocations: revocations,
+		cache:       appCache,
+		txManager:   txManager,
+	}
+}
+
+// EraseSelf anonymizes the caller's own account once they have confirmed it by repeating
+// their email address.
+func (s *erasureService) EraseSelf(ctx context.Context, userID int64, req dto.EraseAccountRequest) (*dto.ErasureResponse, error) {
+	user, err := s.repos.users.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("user not found")
+		}
+		return nil, apperror.NewInternal("failed to get user")
+	}
+	if !strings.EqualFold(strings.TrimSpace(req.Email), user.Email) {
+		return nil, apperror.NewBadRequest("email does not match your account")
+	}
+
+	return s.erase(ctx, userID, userID, dto.ErasureSourceSelf)
+}
+
+// Erase anonymizes another user's account on behalf of an administrator.
+func (s *erasureService) Erase(ctx context.Context, actorID, userID int64) (*dto.ErasureResponse, error) {
+	if actorID == userID {
+		return nil, apperror.NewBadRequest("use /users/me/erase to erase your own account")
+	}
+	return s.erase(ctx, actorID, userID, dto.ErasureSourceAdmin)
+}
+
+func (s *erasureService) List(ctx context.Context, page, perPage int) ([]dto.ErasureResponse, int64, error) {
+	limit, offset := pagination.LimitOffset(page, perPage)
+
+	audits, err := s.repos.audits.List(ctx, limit, offset)
+	if err != nil {
+		return nil, 0, apperror.NewInternal("failed to list erasures")
+	}
+
+	total, err := s.repos.audits.Count(ctx)
+	if err != nil {
+		return nil, 0, apperror.NewInternal("failed to count erasures")
+	}
+
+	result := make([]dto.ErasureResponse, len(audits))
+	for i := range audits {
+		result[i] = *toErasureResponse(&audits[i])
+	}
+	return result, total, nil
+}
+
+// erase scrubs the user's personal data in place inside one transaction: the account keeps
+// its ID but loses its email, name, credentials and linked identities and is soft-deleted;
+// files, tokens, passkeys and data exports are removed and login history and the activity
+// trail are stripped of addresses and user agents. Stored objects are deleted and access tokens revoked only
+// after the commit. The last active admin cannot be erased.
+func (s *erasureService) erase(ctx context.Context, actorID, userID int64, source string) (*dto.ErasureResponse, error) {
+	var (
+		audit *sqlc.ErasureAudit
+		paths []string
+	)
+	run := func(repos erasureRepos) error {
+		var err error
+		paths, audit, err = s.scrub(ctx, repos, actorID, userID, source)
+		return err
+	}
+
+	var err error
+	if s.txManager == nil {
+		err = run(s.repos)
+	} else {
+		err = s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+			return run(newErasureRepos(tx))
+		})
+	}
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("user not found")
+		}
+		if repository.IsUniqueViolation(err) {
+			return nil, apperror.NewBadRequest("user data has already been erased")
+		}
+		var appErr *apperror.AppError
+		if errors.As(err, &appErr) {
+			return nil, err
+		}
+		slog.Error("failed to erase user", slog.Int64("user_id", userID), slog.Any("error", err))
+		return nil, apperror.NewInternal("failed to erase user")
+	}
+
+	if err := s.revocations.RevokeUser(ctx, userID); err != nil {
+		slog.Error("failed to revoke access tokens", slog.Int64("user_id", userID), slog.Any("error", err))
+	}
+	forgetUser(ctx, s.cache, userID)
+	forgetStats(ctx, s.cache)
+	// Orphaned objects are preferable to failing an erasure that has already committed
+	for _, path := range paths {
+		if err := s.storage.Delete(ctx, path); err != nil {
+			slog.Error("failed to delete stored file", slog.String("path", path), slog.Any("error", err))
+		}
+	}
+
+	slog.Info("user data erased",
+		slog.Int64("user_id", userID), slog.Int64("actor_id", actorID), slog.String("source", source))
+	return toErasureResponse(audit), nil
+}
+
+// scrub performs the database side of an erasure and returns the storage paths to delete.
+func (s *erasureService) scrub(
+	ctx context.Context,
+	repos erasureRepos,
+	actorID, userID int64,
+	source string,
+) ([]string, *sqlc.ErasureAudit, error) {
+	user, err := repos.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkAdminRemoval(ctx, repos.users, user); err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := repos.users.Anonymize(ctx, userID, fmt.Sprintf("erased-%d@erased.invalid", userID), erasedUserName); err != nil {
+		return nil, nil, err
+	}
+
+	variants, err := repos.files.ListVariantPathsByUserID(ctx, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list file variants: %w", err)
+	}
+	versions, err := repos.files.ListVersionPathsByUserID(ctx, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list file versions: %w", err)
+	}
+	files, err := repos.files.PurgeByUserID(ctx, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("purge files: %w", err)
+	}
+	archives, err := repos.exports.DeleteByUserID(ctx, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("delete data exports: %w", err)
+	}
+
+	steps := []struct {
+		name string
+		fn   func(context.Context, int64) error
+	}{
+		{"delete folders", repos.folders.DeleteByUserID},
+		{"delete refresh tokens", repos.tokens.DeleteByUserID},
+		{"delete email change tokens", repos.emailChanges.DeleteByUserID},
+		{"delete passkeys", repos.credentials.DeleteByUserID},
+		{"delete two-factor secrets", repos.twoFactor.DeleteByUserID},
+		{"cancel account deletion", repos.deletions.DeleteByUserID},
+		{"anonymize login events", repos.loginEvents.AnonymizeByUserID},
+		{"anonymize activity", repos.activities.AnonymizeByUserID},
+	}
+	for _, step := range steps {
+		if err := step.fn(ctx, userID); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", step.name, err)
+		}
+	}
+
+	audit, err := repos.audits.Create(ctx, sqlc.CreateErasureAuditParams{
+		UserID:       userID,
+		ActorID:      actorID,
+		Source:       source,
+		FilesDeleted: int32(len(files)),
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("record erasure: %w", err)
+	}
+
+	paths, err := unsharedPaths(ctx, repos.files, userID, slices.Concat(files, variants, versions))
+	if err != nil {
+		return nil, nil, fmt.Errorf("check shared files: %w", err)
+	}
+	return append(paths, archives...), audit, nil
+}
+
+func toErasureResponse(a *sqlc.ErasureAudit) *dto.ErasureResponse {
+	return &dto.ErasureResponse{
+		ID:           a.ID,
+		UserID:       a.UserID,
+		ActorID:      a.ActorID,
+		Source:       a.Source,
+		FilesDeleted: a.FilesDeleted,
+		CreatedAt:    a.CreatedAt.Time,
+	}
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/token"
+)
+
+type erasureFixture struct {
+	svc         ErasureService
+	users       *mockUserRepo
+	files       *mockFileRepo
+	tokens      *mockRefreshTokenRepo
+	loginEvents *mockLoginEventRepo
+	activities  *mockUserActivityRepo
+	exports     *mockDataExportRepo
+	deletions   *mockAccountDeletionRepo
+	audits      *mockErasureAuditRepo
+	store       *mockStorage
+	cache       *mockCache
+}
+
+// newTestErasureService seeds admin 1 and user 2, who owns a stored image with a rendered
+// thumbnail, a data export archive, a login event, an activity entry and a scheduled
+// account deletion.
+func newTestErasureService(t *testing.T) *erasureFixture {
+	t.Helper()
+	ctx := context.Background()
+	f := &erasureFixture{
+		users:       newMockUserRepo(),
+		files:       newMockFileRepo(),
+		tokens:      newMockRefreshTokenRepo(),
+		loginEvents: newMockLoginEventRepo(),
+		activities:  newMockUserActivityRepo(),
+		exports:     newMockDataExportRepo(),
+		deletions:   newMockAccountDeletionRepo(),
+		audits:      newMockErasureAuditRepo(),
+		store:       newMockStorage(),
+		cache:       newMockCache(),
+	}
+	f.users.users[1] = &sqlc.User{ID: 1, Email: "admin@example.com", Name: "Admin", Role: dto.RoleAdmin}
+	f.users.users[2] = &sqlc.User{
+		ID: 2, Email: "jane@example.com", Name: "Jane Doe", Role: dto.RoleUser,
+		GoogleID: pgtype.Text{String: "g-123", Valid: true},
+		Username: pgtype.Text{String: "janedoe", Valid: true},
+		Metadata: []byte(`{"phone":"555-0100"}`),
+	}
+
+	_ = f.store.Put(ctx, "uploads/me.png", strings.NewReader("png"), 3, "image/png")
+	img, _ := f.files.Create(ctx, sqlc.CreateFileParams{UserID: 2, OriginalName: "me.png", StoragePath: "uploads/me.png", MimeType: "image/png"})
+	_ = f.store.Put(ctx, "uploads/me_thumbnail.jpg", strings.NewReader("jpg"), 3, "image/jpeg")
+	_, _ = f.files.CreateVariant(ctx, sqlc.CreateFileVariantParams{FileID: img.ID, Name: "thumbnail", StoragePath: "uploads/me_thumbnail.jpg"})
+
+	_ = f.store.Put(ctx, "exports/2/a.zip", strings.NewReader("zip"), 3, "application/zip")
+	exp, _ := f.exports.Create(ctx, 2)
+	_, _ = f.exports.MarkReady(ctx, exp.ID, "exports/2/a.zip", 10, time.Now().Add(time.Hour))
+
+	_, _ = f.loginEvents.Create(ctx, sqlc.CreateLoginEventParams{
+		UserID: pgtype.Int8{Int64: 2, Valid: true}, Email: "jane@example.com", Method: "password",
+		Success: true, IpAddress: "203.0.113.7", UserAgent: "Firefox",
+	})
+	_, _ = f.activities.Create(ctx, sqlc.CreateUserActivityParams{
+		UserID: 2, ActorID: pgtype.Int8{Int64: 2, Valid: true}, Action: dto.ActivityPasswordChanged, IpAddress: "203.0.113.7",
+	})
+	_, _ = f.deletions.Create(ctx, sqlc.CreateAccountDeletionRequestParams{
+		UserID: 2, Token: "cancel", ScheduledFor: pgtype.Timestamptz{Time: time.Now().Add(time.Hour), Valid: true},
+	})
+
+	f.svc = NewErasureService(
+		f.users, f.files, newMockFolderRepo(), f.tokens, newMockEmailChangeRepo(), newMockWebAuthnCredentialRepo(),
+		newMockTwoFactorRepo(), f.loginEvents, f.activities, f.exports, f.deletions, f.audits, f.store,
+		token.NewRevocationStore(newMockCache(), time.Hour), f.cache, nil,
+	)
+	return f
+}
+
+func TestEraseUser(t *testing.T) {
+	t.Run("admin erasure scrubs personal data and records an audit entry", func(t *testing.T) {
+		f := newTestErasureService(t)
+
+		f.cache.cacheUser(2)
+
+		resp, err := f.svc.Erase(context.Background(), 1, 2)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if f.cache.userCached(2) {
+			t.Error("expected the cached profile dropped")
+		}
+		if resp.UserID != 2 || resp.ActorID != 1 || resp.Source != dto.ErasureSourceAdmin || resp.FilesDeleted != 1 {
+			t.Errorf("unexpected audit entry: %+v", resp)
+		}
+
+		u := f.users.users[2]
+		if u.Email != "erased-2@erased.invalid" || u.Name != erasedUserName {
+			t.Errorf("expected placeholders, got email=%q name=%q", u.Email, u.Name)
+		}
+		if u.GoogleID.Valid || u.Username.Valid || string(u.Metadata) != "{}" || !u.DeletedAt.Valid {
+			t.Errorf("expected identities, username and metadata cleared and account deleted, got %+v", u)
+		}
+		if len(f.files.files) != 0 || len(f.store.files) != 0 {
+			t.Errorf("expected files and stored objects removed, got %d files, %d objects", len(f.files.files), len(f.store.files))
+		}
+		if len(f.exports.exports) != 0 {
+			t.Errorf("expected data exports removed, got %d", len(f.exports.exports))
+		}
+		if len(f.deletions.requests) != 0 {
+			t.Error("expected scheduled deletion removed")
+		}
+		if e := f.loginEvents.events[0]; e.Email != "" || e.IpAddress != "" || e.UserAgent != "" {
+			t.Errorf("expected login event scrubbed, got %+v", e)
+		}
+		if a := f.activities.activities[0]; a.IpAddress != "" {
+			t.Errorf("expected activity IP address scrubbed, got %q", a.IpAddress)
+		}
+		if len(f.tokens.deletedUserIDs) != 1 || f.tokens.deletedUserIDs[0] != 2 {
+			t.Errorf("expected refresh tokens deleted for user 2, got %v", f.tokens.deletedUserIDs)
+		}
+	})
+
+	t.Run("objects shared with other users are kept", func(t *testing.T) {
+		f := newTestErasureService(t)
+		_, _ = f.files.Create(context.Background(), sqlc.CreateFileParams{UserID: 1, OriginalName: "copy.png", StoragePath: "uploads/me.png", MimeType: "image/png"})
+
+		if _, err := f.svc.Erase(context.Background(), 1, 2); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if _, ok := f.store.files["uploads/me.png"]; !ok || len(f.store.files) != 1 {
+			t.Errorf("expected only the shared object kept, storage holds %d", len(f.store.files))
+		}
+	})
+
+	t.Run("already erased", func(t *testing.T) {
+		f := newTestErasureService(t)
+		ctx := context.Background()
+
+		_, _ = f.svc.Erase(ctx, 1, 2)
+		_, err := f.svc.Erase(ctx, 1, 2)
+		assertAppErrorCode(t, err, 400)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		f := newTestErasureService(t)
+
+		_, err := f.svc.Erase(context.Background(), 1, 99)
+		assertAppErrorCode(t, err, 404)
+		if len(f.audits.audits) != 0 {
+			t.Error("expected no audit entry")
+		}
+	})
+
+	t.Run("admins cannot erase themselves", func(t *testing.T) {
+		f := newTestErasureService(t)
+
+		_, err := f.svc.Erase(context.Background(), 1, 1)
+		assertAppErrorCode(t, err, 400)
+	})
+}
+
+func TestEraseSelf(t *testing.T) {
+	t.Run("requires the account email", func(t *testing.T) {
+		f := newTestErasureService(t)
+
+		_, err := f.svc.EraseSelf(context.Background(), 2, dto.EraseAccountRequest{Email: "other@example.com"})
+		assertAppErrorCode(t, err, 400)
+		if f.users.users[2].Email != "jane@example.com" {
+			t.Error("expected account untouched")
+		}
+	})
+
+	t.Run("last admin", func(t *testing.T) {
+		f := newTestErasureService(t)
+
+		_, err := f.svc.EraseSelf(context.Background(), 1, dto.EraseAccountRequest{Email: "admin@example.com"})
+		assertErrorCode(t, err, 409, dto.ErrorCodeLastAdmin)
+		if f.users.users[1].Email != "admin@example.com" {
+			t.Error("expected the last admin untouched")
+		}
+	})
+
+	t.Run("erases own account", func(t *testing.T) {
+		f := newTestErasureService(t)
+		ctx := context.Background()
+
+		resp, err := f.svc.EraseSelf(ctx, 2, dto.EraseAccountRequest{Email: "Jane@Example.com"})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if resp.Source != dto.ErasureSourceSelf || resp.ActorID != 2 {
+			t.Errorf("unexpected audit entry: %+v", resp)
+		}
+
+		audits, total, err := f.svc.List(ctx, 1, 10)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if total != 1 || len(audits) != 1 || audits[0].UserID != 2 {
+			t.Errorf("expected one audit entry for user 2, got %+v (total %d)", audits, total)
+		}
+	})
+}
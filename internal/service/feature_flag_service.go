@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+)
+
+const (
+	// featureFlagsCacheKey holds every flag's state as a JSON object of name to enabled.
+	featureFlagsCacheKey = "feature_flags"
+	// featureFlagsCacheTTL bounds how long an instance whose cache was not invalidated,
+	// such as one using the memory driver, serves a stale state.
+	featureFlagsCacheTTL = time.Minute
+)
+
+// FeatureFlagService manages feature flags and answers whether they are on. Lookups are
+// served from the cache, which every change invalidates. A flag that does not exist is off.
+type FeatureFlagService interface {
+	IsEnabled(ctx context.Context, name string) (bool, error)
+	Features(ctx context.Context) (map[string]bool, error)
+	List(ctx context.Context) ([]dto.FeatureFlagResponse, error)
+	Create(ctx context.Context, req dto.CreateFeatureFlagRequest) (*dto.FeatureFlagResponse, error)
+	Update(ctx context.Context, id int64, req dto.UpdateFeatureFlagRequest) (*dto.FeatureFlagResponse, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+type featureFlagService struct {
+	repo     repository.FeatureFlagRepository
+	cache    cache.Cache
+	auditLog AuditLogService
+}
+
+func NewFeatureFlagService(repo repository.FeatureFlagRepository, appCache cache.Cache, auditLog AuditLogService) FeatureFlagService {
+	return &featureFlagService{repo: repo, cache: appCache, auditLog: auditLog}
+}
+
+func (s *featureFlagService) IsEnabled(ctx context.Context, name string) (bool, error) {
+	features, err := s.Features(ctx)
+	if err != nil {
+		return false, err
+	}
+	return features[name], nil
+}
+
+// Features returns every flag's state by name.
+func (s *featureFlagService) Features(ctx context.Context) (map[string]bool, error) {
+	if data, _ := s.cache.Get(ctx, featureFlagsCacheKey); data != nil {
+		var features map[string]bool
+		if err := json.Unmarshal(data, &features); err == nil {
+			return features, nil
+		}
+	}
+
+	flags, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to list feature flags")
+	}
+	features := make(map[string]bool, len(flags))
+	for _, f := range flags {
+		features[f.Name] = f.Enabled
+	}
+
+	if data, err := json.Marshal(features); err == nil {
+		if err := s.cache.Set(ctx, featureFlagsCacheKey, data, featureFlagsCacheTTL); err != nil {
+			slog.Warn("failed to cache feature flags", slog.Any("error", err))
+		}
+	}
+	return features, nil
+}
+
+func (s *featureFlagService) List(ctx context.Context) ([]dto.FeatureFlagResponse, error) {
+	flags, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to list feature flags")
+	}
+
+	responses := make([]dto.FeatureFlagResponse, len(flags))
+	for i := range flags {
+		responses[i] = *toFeatureFlagResponse(&flags[i])
+	}
+	return responses, nil
+}
+
+func (s *featureFlagService) Create(ctx context.Context, req dto.CreateFeatureFlagRequest) (*dto.FeatureFlagResponse, error) {
+	flag, err := s.repo.Create(ctx, sqlc.CreateFeatureFlagParams{
+		Name:        req.Name,
+		Description: req.Description,
+		Enabled:     req.Enabled,
+	})
+	if err != nil {
+		if repository.IsUniqueViolation(err) {
+			return nil, apperror.NewConflict("a feature flag with this name already exists")
+		}
+		return nil, apperror.NewInternal("failed to create feature flag")
+	}
+	s.invalidate(ctx)
+	recordAudit(ctx, s.auditLog, AuditEntry{
+		Action: dto.AuditFlagCreated, TargetType: dto.AuditTargetFlag, TargetID: flag.ID,
+		After: map[string]any{"name": flag.Name, "enabled": flag.Enabled},
+	})
+
+	return toFeatureFlagResponse(flag), nil
+}
+
+func (s *featureFlagService) Update(ctx context.Context, id int64, req dto.UpdateFeatureFlagRequest) (*dto.FeatureFlagResponse, error) {
+	params := sqlc.UpdateFeatureFlagParams{ID: id}
+	if req.Description != nil {
+		params.Description = pgtype.Text{String: *req.Description, Valid: true}
+	}
+	if req.Enabled != nil {
+		params.Enabled = pgtype.Bool{Bool: *req.Enabled, Valid: true}
+	}
+
+	flag, err := s.repo.Update(ctx, params)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("feature flag not found")
+		}
+		return nil, apperror.NewInternal("failed to update feature flag")
+	}
+	s.invalidate(ctx)
+	changed := map[string]any{}
+	if req.Description != nil {
+		changed["description"] = flag.Description
+	}
+	if req.Enabled != nil {
+		changed["enabled"] = flag.Enabled
+	}
+	recordAudit(ctx, s.auditLog, AuditEntry{Action: dto.AuditFlagUpdated, TargetType: dto.AuditTargetFlag, TargetID: id, After: changed})
+
+	return toFeatureFlagResponse(flag), nil
+}
+
+func (s *featureFlagService) Delete(ctx context.Context, id int64) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return apperror.NewNotFound("feature flag not found")
+		}
+		return apperror.NewInternal("failed to delete feature flag")
+	}
+	s.invalidate(ctx)
+	recordAudit(ctx, s.auditLog, AuditEntry{Action: dto.AuditFlagDeleted, TargetType: dto.AuditTargetFlag, TargetID: id})
+	return nil
+}
+
+// invalidate drops the cached flag states so the next lookup reads the change.
+func (s *featureFlagService) invalidate(ctx context.Context) {
+	if err := s.cache.Delete(ctx, featureFlagsCacheKey); err != nil {
+		slog.Error("failed to invalidate cached feature flags", slog.Any("error", err))
+	}
+}
+
+func toFeatureFlagResponse(f *sqlc.FeatureFlag) *dto.FeatureFlagResponse {
+	return &dto.FeatureFlagResponse{
+		ID:          f.ID,
+		Name:        f.Name,
+		Description: f.Description,
+		Enabled:     f.Enabled,
+		CreatedAt:   f.CreatedAt.Time,
+		UpdatedAt:   f.UpdatedAt.Time,
+	}
+}
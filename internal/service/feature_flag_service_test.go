@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+)
+
+func TestFeatureFlags(t *testing.T) {
+	newFixture := func() (FeatureFlagService, *mockFeatureFlagRepo, *mockAuditLogRepo) {
+		repo := newMockFeatureFlagRepo()
+		auditRepo := newMockAuditLogRepo()
+		return NewFeatureFlagService(repo, newMockCache(), NewAuditLogService(auditRepo)), repo, auditRepo
+	}
+	ctx := context.Background()
+	enabled := func(svc FeatureFlagService, name string) bool {
+		t.Helper()
+		on, err := svc.IsEnabled(ctx, name)
+		if err != nil {
+			t.Fatalf("IsEnabled(%q): %v", name, err)
+		}
+		return on
+	}
+
+	t.Run("lookups are cached until a change", func(t *testing.T) {
+		svc, repo, _ := newFixture()
+		flag, err := svc.Create(ctx, dto.CreateFeatureFlagRequest{Name: "new-dashboard", Enabled: true})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if !enabled(svc, "new-dashboard") || enabled(svc, "unknown") {
+			t.Fatal("expected only the created flag on")
+		}
+		if repo.listCalls != 1 {
+			t.Errorf("expected one database read, got %d", repo.listCalls)
+		}
+
+		off := false
+		if _, err := svc.Update(ctx, flag.ID, dto.UpdateFeatureFlagRequest{Enabled: &off}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if enabled(svc, "new-dashboard") {
+			t.Error("expected the flag off after the update")
+		}
+		if repo.listCalls != 2 {
+			t.Errorf("expected the update to invalidate the cache, got %d reads", repo.listCalls)
+		}
+	})
+
+	t.Run("features lists every flag", func(t *testing.T) {
+		svc, _, _ := newFixture()
+		for _, req := range []dto.CreateFeatureFlagRequest{{Name: "beta", Enabled: true}, {Name: "dark-mode"}} {
+			if _, err := svc.Create(ctx, req); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+
+		features, err := svc.Features(ctx)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(features) != 2 || !features["beta"] || features["dark-mode"] {
+			t.Errorf("unexpected features %v", features)
+		}
+	})
+
+	t.Run("duplicate names conflict", func(t *testing.T) {
+		svc, _, _ := newFixture()
+		if _, err := svc.Create(ctx, dto.CreateFeatureFlagRequest{Name: "beta"}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		_, err := svc.Create(ctx, dto.CreateFeatureFlagRequest{Name: "beta"})
+		assertAppErrorCode(t, err, 409)
+	})
+
+	t.Run("delete turns the feature off and is audited", func(t *testing.T) {
+		svc, _, auditRepo := newFixture()
+		flag, _ := svc.Create(ctx, dto.CreateFeatureFlagRequest{Name: "beta", Enabled: true})
+		_ = enabled(svc, "beta")
+
+		if err := svc.Delete(ctx, flag.ID); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if enabled(svc, "beta") {
+			t.Error("expected a deleted flag to be off")
+		}
+		assertAppErrorCode(t, svc.Delete(ctx, flag.ID), 404)
+
+		var actions []string
+		for _, e := range auditRepo.entries {
+			actions = append(actions, e.Action)
+		}
+		if len(actions) != 2 || actions[0] != dto.AuditFlagCreated || actions[1] != dto.AuditFlagDeleted {
+			t.Errorf("unexpected audit actions %v", actions)
+		}
+	})
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/storage"
+)
+
+// FileShareService manages public download links that let anyone holding the link
+// download a file without signing in.
+type FileShareService interface {
+	Create(ctx context.Context, fileID, userID int64, req dto.CreateFileShareRequest) (*dto.FileShareResponse, error)
+	List(ctx context.Context, fileID, userID int64) ([]dto.FileShareResponse, error)
+	Revoke(ctx context.Context, fileID, shareID, userID int64) error
+	Open(ctx context.Context, token, password string) (*sqlc.File, io.ReadCloser, error)
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+type fileShareService struct {
+	repo       repository.FileShareRepository
+	fileRepo   repository.FileRepository
+	storage    storage.Storage
+	defaultTTL time.Duration
+	maxTTL     time.Duration
+}
+
+func NewFileShareService(
+	repo repository.FileShareRepository,
+	fileRepo repository.FileRepository,
+	store storage.Storage,
+	defaultTTLHours, maxTTLHours int,
+) FileShareService {
+	return &fileShareService{
+		repo:       repo,
+		fileRepo:   fileRepo,
+		storage:    store,
+		defaultTTL: time.Duration(defaultTTLHours) * time.Hour,
+		maxTTL:     time.Duration(maxTTLHours) * time.Hour,
+	}
+}
+
+// Create issues a download link for one of the user's files. The token is returned only
+// here; afterwards the link can be listed and revoked but not recovered.
+func (s *fileShareService) Create(ctx context.Context, fileID, userID int64, req dto.CreateFileShareRequest) (*dto.FileShareResponse, error) {
+	if _, err := s.ownedFile(ctx, fileID, userID); err != nil {
+		return nil, err
+	}
+
+	ttl := s.defaultTTL
+	if req.TTLHours > 0 {
+		ttl = time.Duration(req.TTLHours) * time.Hour
+	}
+	if ttl > s.maxTTL {
+		return nil, apperror.NewBadRequest(fmt.Sprintf("ttl_hours must be at most %d", int(s.maxTTL.Hours())))
+	}
+
+	var passwordHash pgtype.Text
+	if req.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcryptCost)
+		if err != nil {
+			return nil, apperror.NewInternal("failed to hash share password")
+		}
+		passwordHash = pgtype.Text{String: string(hash), Valid: true}
+	}
+	var maxDownloads pgtype.Int4
+	if req.MaxDownloads != nil {
+		maxDownloads = pgtype.Int4{Int32: *req.MaxDownloads, Valid: true}
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, apperror.NewInternal("failed to generate share token")
+	}
+	token := hex.EncodeToString(b)
+
+	share, err := s.repo.Create(ctx, sqlc.CreateFileShareParams{
+		FileID:       fileID,
+		Token:        hashToken(token), // Store hash, not plaintext
+		PasswordHash: passwordHash,
+		MaxDownloads: maxDownloads,
+		ExpiresAt:    pgtype.Timestamptz{Time: time.Now().Add(ttl), Valid: true},
+	})
+	if err != nil {
+		return nil, apperror.NewInternal("failed to create share link")
+	}
+
+	resp := toFileShareResponse(share)
+	resp.Token = token
+	return resp, nil
+}
+
+// List returns the links of one of the user's files, including expired ones that have
+// not been purged yet.
+func (s *fileShareService) List(ctx context.Context, fileID, userID int64) ([]dto.FileShareResponse, error) {
+	if _, err := s.ownedFile(ctx, fileID, userID); err != nil {
+		return nil, err
+	}
+
+	shares, err := s.repo.ListByFileID(ctx, fileID)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to list share links")
+	}
+
+	result := make([]dto.FileShareResponse, len(shares))
+	for i := range shares {
+		result[i] = *toFileShareResponse(&shares[i])
+	}
+	return result, nil
+}
+
+// Revoke deletes a link so it can no longer be used.
+func (s *fileShareService) Revoke(ctx context.Context, fileID, shareID, userID int64) error {
+	if _, err := s.ownedFile(ctx, fileID, userID); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, shareID, fileID); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return apperror.NewNotFound("share link not found")
+		}
+		return apperror.NewInternal("failed to revoke share link")
+	}
+	return nil
+}
+
+// Open checks a link and its password and counts the download. Unknown, expired and
+// used-up links are all reported as not found, so a link's state is not revealed.
+func (s *fileShareService) Open(ctx context.Context, token, password string) (*sqlc.File, io.ReadCloser, error) {
+	share, err := s.repo.GetByToken(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, nil, apperror.NewNotFound("share link not found")
+		}
+		return nil, nil, apperror.NewInternal("failed to get share link")
+	}
+	if share.ExpiresAt.Time.Before(time.Now()) {
+		return nil, nil, apperror.NewNotFound("share link not found")
+	}
+
+	if share.PasswordHash.Valid {
+		if password == "" {
+			return nil, nil, apperror.NewUnauthorized("this link requires a password")
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(share.PasswordHash.String), []byte(password)); err != nil {
+			return nil, nil, apperror.NewUnauthorized("invalid password")
+		}
+	}
+
+	file, err := s.fileRepo.GetByID(ctx, share.FileID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			// The file was deleted after it was shared.
+			return nil, nil, apperror.NewNotFound("share link not found")
+		}
+		return nil, nil, apperror.NewInternal("failed to get file")
+	}
+
+	reader, err := s.storage.Get(ctx, file.StoragePath)
+	if err != nil {
+		return nil, nil, apperror.NewInternal("failed to read file from storage")
+	}
+
+	if err := s.repo.RecordDownload(ctx, share.ID); err != nil {
+		_ = reader.Close()
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, nil, apperror.NewNotFound("share link not found")
+		}
+		return nil, nil, apperror.NewInternal("failed to record download")
+	}
+
+	return file, reader, nil
+}
+
+// PurgeExpired removes links that expired or used up their downloads.
+func (s *fileShareService) PurgeExpired(ctx context.Context) (int64, error) {
+	n, err := s.repo.DeleteExpired(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("delete expired file shares: %w", err)
+	}
+	if n > 0 {
+		slog.Info("purged expired file shares", slog.Int64("count", n))
+	}
+	return n, nil
+}
+
+// ownedFile loads a file that belongs to the user.
+func (s *fileShareService) ownedFile(ctx context.Context, fileID, userID int64) (*sqlc.File, error) {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("file not found")
+		}
+		return nil, apperror.NewInternal("failed to get file")
+	}
+	if file.UserID != userID {
+		return nil, apperror.NewForbidden("you can only share your own files")
+	}
+	return file, nil
+}
+
+func toFileShareResponse(share *sqlc.FileShare) *dto.FileShareResponse {
+	resp := &dto.FileShareResponse{
+		ID:                share.ID,
+		FileID:            share.FileID,
+		PasswordProtected: share.PasswordHash.Valid,
+		Downloads:         share.DownloadCount,
+		ExpiresAt:         share.ExpiresAt.Time,
+		CreatedAt:         share.CreatedAt.Time,
+	}
+	if share.MaxDownloads.Valid {
+		resp.MaxDownloads = &share.MaxDownloads.Int32
+	}
+	return resp
+}
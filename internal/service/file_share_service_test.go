@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+)
+
+type fileShareFixture struct {
+	svc    FileShareService
+	shares *mockFileShareRepo
+	files  *mockFileRepo
+	store  *mockStorage
+	file   *sqlc.File
+}
+
+func newFileShareFixture(t *testing.T) *fileShareFixture {
+	t.Helper()
+	f := &fileShareFixture{
+		shares: newMockFileShareRepo(),
+		files:  newMockFileRepo(),
+		store:  newMockStorage(),
+	}
+	f.svc = NewFileShareService(f.shares, f.files, f.store, 24, 72)
+
+	var err error
+	f.file, err = f.files.Create(context.Background(), sqlc.CreateFileParams{
+		UserID:       1,
+		OriginalName: "report.pdf",
+		StoragePath:  "1/report.pdf",
+		MimeType:     "application/pdf",
+		Size:         8,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.store.files["1/report.pdf"] = []byte("%PDF-1.4")
+	return f
+}
+
+// share creates a link for the fixture's file and returns the token.
+func (f *fileShareFixture) share(t *testing.T, req dto.CreateFileShareRequest) string {
+	t.Helper()
+	resp, err := f.svc.Create(context.Background(), f.file.ID, 1, req)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if resp.Token == "" {
+		t.Fatal("expected token in response")
+	}
+	return resp.Token
+}
+
+func (f *fileShareFixture) download(t *testing.T, token, password string) error {
+	t.Helper()
+	file, reader, err := f.svc.Open(context.Background(), token, password)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = reader.Close() }()
+	data, _ := io.ReadAll(reader)
+	if file.ID != f.file.ID || string(data) != "%PDF-1.4" {
+		t.Errorf("unexpected download of file %d: %q", file.ID, data)
+	}
+	return nil
+}
+
+func TestCreateFileShare(t *testing.T) {
+	t.Run("stores token hash and default expiry", func(t *testing.T) {
+		f := newFileShareFixture(t)
+
+		token := f.share(t, dto.CreateFileShareRequest{})
+		share := f.shares.shares[1]
+		if share.Token != hashToken(token) {
+			t.Error("expected token to be stored hashed")
+		}
+		if d := time.Until(share.ExpiresAt.Time); d < 23*time.Hour || d > 24*time.Hour {
+			t.Errorf("expected default TTL of 24h, got %v", d)
+		}
+		if share.PasswordHash.Valid || share.MaxDownloads.Valid {
+			t.Error("expected no password or download limit")
+		}
+	})
+
+	t.Run("ttl above maximum", func(t *testing.T) {
+		f := newFileShareFixture(t)
+
+		_, err := f.svc.Create(context.Background(), f.file.ID, 1, dto.CreateFileShareRequest{TTLHours: 100})
+		assertAppErrorCode(t, err, 400)
+	})
+
+	t.Run("another user's file", func(t *testing.T) {
+		f := newFileShareFixture(t)
+
+		_, err := f.svc.Create(context.Background(), f.file.ID, 2, dto.CreateFileShareRequest{})
+		assertAppErrorCode(t, err, 403)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		f := newFileShareFixture(t)
+
+		_, err := f.svc.Create(context.Background(), 99, 1, dto.CreateFileShareRequest{})
+		assertAppErrorCode(t, err, 404)
+	})
+}
+
+func TestOpenFileShare(t *testing.T) {
+	t.Run("streams file and counts download", func(t *testing.T) {
+		f := newFileShareFixture(t)
+		token := f.share(t, dto.CreateFileShareRequest{})
+
+		if err := f.download(t, token, ""); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if n := f.shares.shares[1].DownloadCount; n != 1 {
+			t.Errorf("expected 1 download, got %d", n)
+		}
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		f := newFileShareFixture(t)
+
+		assertAppErrorCode(t, f.download(t, "nope", ""), 404)
+	})
+
+	t.Run("password required", func(t *testing.T) {
+		f := newFileShareFixture(t)
+		token := f.share(t, dto.CreateFileShareRequest{Password: "s3cret"})
+
+		assertAppErrorCode(t, f.download(t, token, ""), 401)
+		assertAppErrorCode(t, f.download(t, token, "wrong"), 401)
+		if err := f.download(t, token, "s3cret"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if n := f.shares.shares[1].DownloadCount; n != 1 {
+			t.Errorf("expected only the successful download counted, got %d", n)
+		}
+	})
+
+	t.Run("download limit", func(t *testing.T) {
+		f := newFileShareFixture(t)
+		limit := int32(2)
+		token := f.share(t, dto.CreateFileShareRequest{MaxDownloads: &limit})
+
+		for range limit {
+			if err := f.download(t, token, ""); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+		assertAppErrorCode(t, f.download(t, token, ""), 404)
+	})
+
+	t.Run("expired link", func(t *testing.T) {
+		f := newFileShareFixture(t)
+		token := f.share(t, dto.CreateFileShareRequest{})
+		f.shares.shares[1].ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true}
+
+		assertAppErrorCode(t, f.download(t, token, ""), 404)
+	})
+
+	t.Run("deleted file", func(t *testing.T) {
+		f := newFileShareFixture(t)
+		token := f.share(t, dto.CreateFileShareRequest{})
+		delete(f.files.files, f.file.ID)
+
+		assertAppErrorCode(t, f.download(t, token, ""), 404)
+	})
+}
+
+func TestRevokeFileShare(t *testing.T) {
+	f := newFileShareFixture(t)
+	token := f.share(t, dto.CreateFileShareRequest{})
+
+	assertAppErrorCode(t, f.svc.Revoke(context.Background(), f.file.ID, 1, 2), 403)
+	if err := f.svc.Revoke(context.Background(), f.file.ID, 1, 1); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	assertAppErrorCode(t, f.download(t, token, ""), 404)
+	assertAppErrorCode(t, f.svc.Revoke(context.Background(), f.file.ID, 1, 1), 404)
+
+	shares, err := f.svc.List(context.Background(), f.file.ID, 1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(shares) != 0 {
+		t.Errorf("expected no shares after revoke, got %d", len(shares))
+	}
+}
+
+func TestPurgeExpiredFileShares(t *testing.T) {
+	f := newFileShareFixture(t)
+	limit := int32(1)
+	f.share(t, dto.CreateFileShareRequest{})
+	used := f.share(t, dto.CreateFileShareRequest{MaxDownloads: &limit})
+	f.share(t, dto.CreateFileShareRequest{})
+	f.shares.shares[3].ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true}
+	if err := f.download(t, used, ""); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	n, err := f.svc.PurgeExpired(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 purged, got %d", n)
+	}
+	if _, ok := f.shares.shares[1]; !ok {
+		t.Error("expected active share to remain")
+	}
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+// FolderService manages the folder tree each user organizes their files in.
+type FolderService interface {
+	Create(ctx context.Context, userID int64, req dto.CreateFolderRequest) (*dto.FolderResponse, error)
+	Get(ctx context.Context, id, userID int64) (*dto.FolderResponse, error)
+	List(ctx context.Context, userID int64, parentID *int64) ([]dto.FolderResponse, error)
+	Rename(ctx context.Context, id, userID int64, req dto.RenameFolderRequest) (*dto.FolderResponse, error)
+	Move(ctx context.Context, id, userID int64, req dto.MoveFolderRequest) (*dto.FolderResponse, error)
+	Delete(ctx context.Context, id, userID int64) error
+}
+
+type folderService struct {
+	repo repository.FolderRepository
+}
+
+func NewFolderService(repo repository.FolderRepository) FolderService {
+	return &folderService{repo: repo}
+}
+
+func (s *folderService) Create(ctx context.Context, userID int64, req dto.CreateFolderRequest) (*dto.FolderResponse, error) {
+	name, err := cleanName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	var parentID pgtype.Int8
+	if req.ParentID != nil {
+		if _, err := s.ownedFolder(ctx, *req.ParentID, userID); err != nil {
+			return nil, err
+		}
+		parentID = pgtype.Int8{Int64: *req.ParentID, Valid: true}
+	}
+
+	folder, err := s.repo.Create(ctx, sqlc.CreateFolderParams{UserID: userID, ParentID: parentID, Name: name})
+	if err != nil {
+		return nil, folderWriteError(err, "failed to create folder")
+	}
+	return toFolderResponse(folder), nil
+}
+
+func (s *folderService) Get(ctx context.Context, id, userID int64) (*dto.FolderResponse, error) {
+	folder, err := s.ownedFolder(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return toFolderResponse(folder), nil
+}
+
+// List returns the folders directly inside parentID, or the top-level folders when
+// parentID is nil.
+func (s *folderService) List(ctx context.Context, userID int64, parentID *int64) ([]dto.FolderResponse, error) {
+	var parent pgtype.Int8
+	if parentID != nil {
+		if _, err := s.ownedFolder(ctx, *parentID, userID); err != nil {
+			return nil, err
+		}
+		parent = pgtype.Int8{Int64: *parentID, Valid: true}
+	}
+
+	folders, err := s.repo.ListByParentID(ctx, userID, parent)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to list folders")
+	}
+
+	result := make([]dto.FolderResponse, len(folders))
+	for i := range folders {
+		result[i] = *toFolderResponse(&folders[i])
+	}
+	return result, nil
+}
+
+func (s *folderService) Rename(ctx context.Context, id, userID int64, req dto.RenameFolderRequest) (*dto.FolderResponse, error) {
+	name, err := cleanName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.ownedFolder(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	folder, err := s.repo.Rename(ctx, id, name)
+	if err != nil {
+		return nil, folderWriteError(err, "failed to rename folder")
+	}
+	return toFolderResponse(folder), nil
+}
+
+// Move puts a folder, with everything in it, inside another folder or at the top level.
+func (s *folderService) Move(ctx context.Context, id, userID int64, req dto.MoveFolderRequest) (*dto.FolderResponse, error) {
+	if _, err := s.ownedFolder(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	var parentID pgtype.Int8
+	if req.ParentID != nil {
+		if _, err := s.ownedFolder(ctx, *req.ParentID, userID); err != nil {
+			return nil, err
+		}
+		cycle, err := s.repo.IsAncestor(ctx, id, *req.ParentID)
+		if err != nil {
+			return nil, apperror.NewInternal("failed to move folder")
+		}
+		if cycle {
+			return nil, apperror.NewBadRequest("a folder cannot be moved into itself or one of its subfolders")
+		}
+		parentID = pgtype.Int8{Int64: *req.ParentID, Valid: true}
+	}
+
+	folder, err := s.repo.Move(ctx, id, parentID)
+	if err != nil {
+		return nil, folderWriteError(err, "failed to move folder")
+	}
+	return toFolderResponse(folder), nil
+}
+
+// Delete removes an empty folder. Folders that still hold subfolders or files are kept so
+// nothing is lost by accident.
+func (s *folderService) Delete(ctx context.Context, id, userID int64) error {
+	if _, err := s.ownedFolder(ctx, id, userID); err != nil {
+		return err
+	}
+
+	n, err := s.repo.CountChildren(ctx, id)
+	if err != nil {
+		return apperror.NewInternal("failed to check folder contents")
+	}
+	if n > 0 {
+		return apperror.NewConflict("folder is not empty")
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return apperror.NewNotFound("folder not found")
+		}
+		return apperror.NewInternal("failed to delete folder")
+	}
+	return nil
+}
+
+// ownedFolder loads a folder that belongs to the user.
+func (s *folderService) ownedFolder(ctx context.Context, id, userID int64) (*sqlc.Folder, error) {
+	folder, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("folder not found")
+		}
+		return nil, apperror.NewInternal("failed to get folder")
+	}
+	if folder.UserID != userID {
+		return nil, apperror.NewForbidden("you can only access your own folders")
+	}
+	return folder, nil
+}
+
+// cleanName trims a folder or file name and rejects one that is blank.
+func cleanName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", apperror.NewBadRequest("name must not be blank")
+	}
+	return name, nil
+}
+
+// folderWriteError maps a failed folder insert or update to an API error.
+func folderWriteError(err error, msg string) error {
+	if errors.Is(err, apperror.ErrNotFound) {
+		return apperror.NewNotFound("folder not found")
+	}
+	if repository.IsUniqueViolation(err) {
+		return apperror.NewConflict("a folder with this name already exists here")
+	}
+	return apperror.NewInternal(msg)
+}
+
+func toFolderResponse(f *sqlc.Folder) *dto.FolderResponse {
+	resp := &dto.FolderResponse{
+		ID:        f.ID,
+		Name:      f.Name,
+		CreatedAt: f.CreatedAt.Time,
+		UpdatedAt: f.UpdatedAt.Time,
+	}
+	if f.ParentID.Valid {
+		resp.ParentID = &f.ParentID.Int64
+	}
+	return resp
+}
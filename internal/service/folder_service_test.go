@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+)
+
+type folderFixture struct {
+	svc     FolderService
+	uploads UploadService
+	folders *mockFolderRepo
+	files   *mockFileRepo
+}
+
+func newFolderFixture() *folderFixture {
+	f := &folderFixture{folders: newMockFolderRepo(), files: newMockFileRepo()}
+	f.folders.files = f.files
+	f.files.folders = f.folders
+	f.svc = NewFolderService(f.folders)
+	f.uploads = newTestUploadService(f.files, newMockStorage())
+	return f
+}
+
+func (f *folderFixture) create(t *testing.T, userID int64, name string, parentID *int64) *dto.FolderResponse {
+	t.Helper()
+	folder, err := f.svc.Create(context.Background(), userID, dto.CreateFolderRequest{Name: name, ParentID: parentID})
+	if err != nil {
+		t.Fatalf("create folder %q: %v", name, err)
+	}
+	return folder
+}
+
+func TestFolders(t *testing.T) {
+	t.Run("nested folders are listed per parent", func(t *testing.T) {
+		f := newFolderFixture()
+		docs := f.create(t, 1, " Docs ", nil)
+		f.create(t, 1, "Photos", nil)
+		f.create(t, 1, "2026", &docs.ID)
+		f.create(t, 2, "Other user", nil)
+
+		if docs.Name != "Docs" {
+			t.Errorf("expected trimmed name, got %q", docs.Name)
+		}
+		top, err := f.svc.List(context.Background(), 1, nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(top) != 2 || top[0].Name != "Docs" || top[1].Name != "Photos" {
+			t.Errorf("expected Docs and Photos at the top level, got %+v", top)
+		}
+		children, err := f.svc.List(context.Background(), 1, &docs.ID)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(children) != 1 || *children[0].ParentID != docs.ID {
+			t.Errorf("expected one subfolder of Docs, got %+v", children)
+		}
+	})
+
+	t.Run("duplicate name in the same parent conflicts", func(t *testing.T) {
+		f := newFolderFixture()
+		docs := f.create(t, 1, "Docs", nil)
+		f.create(t, 1, "Docs", &docs.ID)
+
+		_, err := f.svc.Create(context.Background(), 1, dto.CreateFolderRequest{Name: "Docs"})
+		assertAppErrorCode(t, err, 409)
+		_, err = f.svc.Create(context.Background(), 1, dto.CreateFolderRequest{Name: "  "})
+		assertAppErrorCode(t, err, 400)
+	})
+
+	t.Run("folders of other users are off limits", func(t *testing.T) {
+		f := newFolderFixture()
+		docs := f.create(t, 1, "Docs", nil)
+
+		_, err := f.svc.Get(context.Background(), docs.ID, 2)
+		assertAppErrorCode(t, err, 403)
+		_, err = f.svc.Create(context.Background(), 2, dto.CreateFolderRequest{Name: "Mine", ParentID: &docs.ID})
+		assertAppErrorCode(t, err, 403)
+		_, err = f.svc.Rename(context.Background(), docs.ID, 2, dto.RenameFolderRequest{Name: "Taken"})
+		assertAppErrorCode(t, err, 403)
+		assertAppErrorCode(t, f.svc.Delete(context.Background(), docs.ID, 2), 403)
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		f := newFolderFixture()
+		docs := f.create(t, 1, "Docs", nil)
+		f.create(t, 1, "Archive", nil)
+
+		renamed, err := f.svc.Rename(context.Background(), docs.ID, 1, dto.RenameFolderRequest{Name: "Papers"})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if renamed.Name != "Papers" {
+			t.Errorf("expected Papers, got %q", renamed.Name)
+		}
+		_, err = f.svc.Rename(context.Background(), docs.ID, 1, dto.RenameFolderRequest{Name: "Archive"})
+		assertAppErrorCode(t, err, 409)
+	})
+
+	t.Run("move rejects cycles", func(t *testing.T) {
+		f := newFolderFixture()
+		a := f.create(t, 1, "A", nil)
+		b := f.create(t, 1, "B", &a.ID)
+		c := f.create(t, 1, "C", &b.ID)
+
+		_, err := f.svc.Move(context.Background(), a.ID, 1, dto.MoveFolderRequest{ParentID: &c.ID})
+		assertAppErrorCode(t, err, 400)
+		_, err = f.svc.Move(context.Background(), a.ID, 1, dto.MoveFolderRequest{ParentID: &a.ID})
+		assertAppErrorCode(t, err, 400)
+
+		moved, err := f.svc.Move(context.Background(), c.ID, 1, dto.MoveFolderRequest{})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if moved.ParentID != nil {
+			t.Errorf("expected C at the top level, got parent %d", *moved.ParentID)
+		}
+	})
+
+	t.Run("only empty folders are deleted", func(t *testing.T) {
+		f := newFolderFixture()
+		docs := f.create(t, 1, "Docs", nil)
+		sub := f.create(t, 1, "Sub", &docs.ID)
+
+		assertAppErrorCode(t, f.svc.Delete(context.Background(), docs.ID, 1), 409)
+		if err := f.svc.Delete(context.Background(), sub.ID, 1); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		f.files.files[1] = &sqlc.File{ID: 1, UserID: 1, OriginalName: "a.txt", StoragePath: "1/a.txt"}
+		if _, err := f.uploads.Move(context.Background(), 1, 1, &docs.ID); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		assertAppErrorCode(t, f.svc.Delete(context.Background(), docs.ID, 1), 409)
+	})
+}
+
+func TestFileMoveAndRename(t *testing.T) {
+	t.Run("files are listed by folder", func(t *testing.T) {
+		f := newFolderFixture()
+		docs := f.create(t, 1, "Docs", nil)
+		f.files.files[1] = &sqlc.File{ID: 1, UserID: 1, OriginalName: "a.txt", StoragePath: "1/a.txt"}
+		f.files.files[2] = &sqlc.File{ID: 2, UserID: 1, OriginalName: "b.txt", StoragePath: "1/b.txt"}
+
+		moved, err := f.uploads.Move(context.Background(), 1, 1, &docs.ID)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if moved.FolderID == nil || *moved.FolderID != docs.ID {
+			t.Errorf("expected file in folder %d, got %+v", docs.ID, moved.FolderID)
+		}
+
+		none := int64(0)
+		for _, tc := range []struct {
+			folderID *int64
+			want     int64
+		}{{nil, 2}, {&docs.ID, 1}, {&none, 1}} {
+			files, total, err := f.uploads.List(context.Background(), 1, dto.FileListQuery{FolderID: tc.folderID}, 1, 10)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if total != tc.want || int64(len(files)) != tc.want {
+				t.Errorf("folder %v: expected %d files, got %d (total %d)", tc.folderID, tc.want, len(files), total)
+			}
+		}
+
+		out, err := f.uploads.Move(context.Background(), 1, 1, nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if out.FolderID != nil {
+			t.Errorf("expected file outside folders, got %d", *out.FolderID)
+		}
+	})
+
+	t.Run("move into another user's folder is not found", func(t *testing.T) {
+		f := newFolderFixture()
+		theirs := f.create(t, 2, "Theirs", nil)
+		f.files.files[1] = &sqlc.File{ID: 1, UserID: 1, OriginalName: "a.txt", StoragePath: "1/a.txt"}
+
+		_, err := f.uploads.Move(context.Background(), 1, 1, &theirs.ID)
+		assertAppErrorCode(t, err, 404)
+		_, err = f.uploads.Move(context.Background(), 1, 2, &theirs.ID)
+		assertAppErrorCode(t, err, 403)
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		f := newFolderFixture()
+		f.files.files[1] = &sqlc.File{ID: 1, UserID: 1, OriginalName: "a.txt", StoragePath: "1/a.txt"}
+
+		resp, err := f.uploads.Rename(context.Background(), 1, 1, " report.txt ")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if resp.OriginalName != "report.txt" || f.files.files[1].StoragePath != "1/a.txt" {
+			t.Errorf("expected renamed file at the same path, got %+v", f.files.files[1])
+		}
+		_, err = f.uploads.Rename(context.Background(), 1, 2, "mine.txt")
+		assertAppErrorCode(t, err, 403)
+		_, err = f.uploads.Rename(context.Background(), 1, 1, "")
+		assertAppErrorCode(t, err, 400)
+	})
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+)
+
+const (
+	guestName        = "Guest"
+	guestEmailDomain = "guest.invalid" // reserved TLD, never deliverable
+)
+
+type GuestService interface {
+	Create(ctx context.Context) (*sqlc.User, error)
+	Upgrade(ctx context.Context, guestID int64, req dto.RegisterRequest) (*sqlc.User, error)
+}
+
+type guestService struct {
+	userRepo     repository.UserRepository
+	settingsRepo repository.UserSettingsRepository
+	invites      inviteGate
+	domains      EmailDomainPolicy
+	settings     RuntimeSettings
+	cache        cache.Cache
+}
+
+func NewGuestService(
+	userRepo repository.UserRepository,
+	invitationRepo repository.RegistrationInvitationRepository,
+	settingsRepo repository.UserSettingsRepository,
+	inviteOnly bool,
+	domains EmailDomainPolicy,
+	settings RuntimeSettings,
+	appCache cache.Cache,
+) GuestService {
+	return &guestService{
+		userRepo:     userRepo,
+		settingsRepo: settingsRepo,
+		invites:      inviteGate{repo: invitationRepo, required: inviteOnly},
+		domains:      domains,
+		settings:     settings,
+		cache:        appCache,
+	}
+}
+
+// Create inserts an anonymous user with the guest role and a placeholder email.
+func (s *guestService) Create(ctx context.Context) (*sqlc.User, error) {
+	if err := checkRegistrationOpen(ctx, s.settings); err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.CreateGuest(ctx, sqlc.CreateGuestUserParams{
+		Email: fmt.Sprintf("guest-%s@%s", uuid.NewString(), guestEmailDomain),
+		Name:  guestName,
+	})
+	if err != nil {
+		return nil, apperror.NewInternal("failed to create guest user")
+	}
+	return user, nil
+}
+
+// Upgrade turns a guest into a registered local account in place, so everything the
+// guest owns (files, sessions, history) carries over to the new account.
+func (s *guestService) Upgrade(ctx context.Context, guestID int64, req dto.RegisterRequest) (*sqlc.User, error) {
+	if err := checkRegistrationOpen(ctx, s.settings); err != nil {
+		return nil, err
+	}
+	if err := s.domains.checkSignup(req.Email); err != nil {
+		return nil, err
+	}
+	invitation, err := s.invites.check(ctx, req.InviteToken, req.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		return nil, apperror.NewInternal("failed to check existing user")
+	}
+	if existing != nil {
+		return nil, apperror.NewBadRequest("email already registered")
+	}
+
+	username, err := claimUsername(ctx, s.userRepo, req.Username, guestID)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcryptCost)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to hash password")
+	}
+
+	user, err := s.userRepo.UpgradeGuest(ctx, sqlc.UpgradeGuestUserParams{
+		Email:        req.Email,
+		Name:         req.Name,
+		PasswordHash: pgtype.Text{String: string(hash), Valid: true},
+		Username:     username,
+		ID:           guestID,
+	})
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewBadRequest("account is not a guest account")
+		}
+		if repository.IsUniqueViolation(err) {
+			return nil, apperror.NewBadRequest("email or username already registered")
+		}
+		return nil, apperror.NewInternal("failed to upgrade guest user")
+	}
+	s.invites.consume(ctx, invitation)
+	forgetUser(ctx, s.cache, user.ID)
+	rememberSignupLocale(ctx, s.settingsRepo, user.ID, req.Locale)
+	return user, nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+// ---------------------------------------------------------------------------
+// Create
+// ---------------------------------------------------------------------------
+
+func TestCreateGuest(t *testing.T) {
+	repo := newMockUserRepo()
+	svc := NewGuestService(repo, nil, newMockUserSettingsRepo(), false, EmailDomainPolicy{}, openSettings, newMockCache())
+
+	user, err := svc.Create(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if user.Role != dto.RoleGuest {
+		t.Errorf("expected role %q, got %q", dto.RoleGuest, user.Role)
+	}
+	if !strings.HasSuffix(user.Email, "@"+guestEmailDomain) {
+		t.Errorf("expected placeholder email, got %q", user.Email)
+	}
+
+	other, err := svc.Create(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if other.Email == user.Email {
+		t.Error("expected unique placeholder emails")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Upgrade
+// ---------------------------------------------------------------------------
+
+func TestUpgradeGuest(t *testing.T) {
+	req := dto.RegisterRequest{Email: "new@example.com", Password: "Password1!", Name: "New User"}
+
+	t.Run("converts guest in place", func(t *testing.T) {
+		repo := newMockUserRepo()
+		appCache := newMockCache()
+		svc := NewGuestService(repo, nil, newMockUserSettingsRepo(), false, EmailDomainPolicy{}, openSettings, appCache)
+		guest, _ := svc.Create(context.Background())
+		appCache.cacheUser(guest.ID)
+
+		user, err := svc.Upgrade(context.Background(), guest.ID, req)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if appCache.userCached(guest.ID) {
+			t.Error("expected the cached guest profile dropped")
+		}
+		if user.ID != guest.ID {
+			t.Errorf("expected same user ID %d, got %d", guest.ID, user.ID)
+		}
+		if user.Role != dto.RoleUser || user.Email != req.Email {
+			t.Errorf("unexpected upgraded user: role=%q email=%q", user.Role, user.Email)
+		}
+		if !user.PasswordHash.Valid {
+			t.Error("expected password hash to be set")
+		}
+	})
+
+	t.Run("email already registered", func(t *testing.T) {
+		repo := newMockUserRepo()
+		repo.users[1] = &sqlc.User{ID: 1, Email: req.Email, Role: "user"}
+		repo.nextID = 2
+		svc := NewGuestService(repo, nil, newMockUserSettingsRepo(), false, EmailDomainPolicy{}, openSettings, newMockCache())
+		guest, _ := svc.Create(context.Background())
+
+		_, err := svc.Upgrade(context.Background(), guest.ID, req)
+		var appErr *apperror.AppError
+		if !errors.As(err, &appErr) || appErr.Code != 400 {
+			t.Fatalf("expected 400 error, got %v", err)
+		}
+	})
+
+	t.Run("not a guest", func(t *testing.T) {
+		repo := newMockUserRepo()
+		repo.users[1] = &sqlc.User{ID: 1, Email: "user@example.com", Role: "user"}
+		svc := NewGuestService(repo, nil, newMockUserSettingsRepo(), false, EmailDomainPolicy{}, openSettings, newMockCache())
+
+		_, err := svc.Upgrade(context.Background(), 1, req)
+		var appErr *apperror.AppError
+		if !errors.As(err, &appErr) || appErr.Code != 400 {
+			t.Fatalf("expected 400 error, got %v", err)
+		}
+	})
+	t.Run("email domain not allowed", func(t *testing.T) {
+		repo := newMockUserRepo()
+		svc := NewGuestService(repo, nil, newMockUserSettingsRepo(), false, EmailDomainPolicy{Allowed: []string{"corp.example"}}, openSettings, newMockCache())
+		guest, _ := svc.Create(context.Background())
+
+		_, err := svc.Upgrade(context.Background(), guest.ID, req)
+		var appErr *apperror.AppError
+		if !errors.As(err, &appErr) || appErr.Code != 403 {
+			t.Fatalf("expected 403 error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/async"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/imaging"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/storage"
+)
+
+const (
+	// imageVariantTimeout bounds rendering all variants of one image.
+	imageVariantTimeout = 5 * time.Minute
+	// imageVariantWorkers caps how many images are decoded at once, since a decoded
+	// image can take far more memory than its file.
+	imageVariantWorkers = 2
+)
+
+// ImageVariantService renders the configured variants (thumbnails, format conversions)
+// of uploaded images and stores them alongside the original.
+type ImageVariantService interface {
+	Generate(ctx context.Context, file *sqlc.File)
+	Reuse(ctx context.Context, file, source *sqlc.File)
+}
+
+type imageVariantService struct {
+	repo     repository.FileRepository
+	storage  storage.Storage
+	variants []imaging.Variant
+	slots    chan struct{}
+	run      func(fn func()) // renders variants; async.Go outside tests
+}
+
+func NewImageVariantService(repo repository.FileRepository, store storage.Storage, variants []imaging.Variant) ImageVariantService {
+	return &imageVariantService{
+		repo:     repo,
+		storage:  store,
+		variants: variants,
+		slots:    make(chan struct{}, imageVariantWorkers),
+		run:      async.Go,
+	}
+}
+
+// Generate starts rendering the variants of a newly stored file in the background. Files
+// that are not decodable images are ignored.
+func (s *imageVariantService) Generate(ctx context.Context, file *sqlc.File) {
+	if len(s.variants) == 0 || !imaging.Decodable(file.MimeType) {
+		return
+	}
+	s.run(func() {
+		s.slots <- struct{}{}
+		defer func() { <-s.slots }()
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), imageVariantTimeout)
+		defer cancel()
+		s.render(ctx, file)
+	})
+}
+
+// Reuse gives a file that shares source's stored object the variants already rendered for
+// source. When source has none yet, for example because they are still rendering, the
+// file's own are rendered as for a new upload.
+func (s *imageVariantService) Reuse(ctx context.Context, file, source *sqlc.File) {
+	n, err := s.repo.CopyVariants(ctx, source.ID, file.ID)
+	if err != nil {
+		slog.Error("failed to copy image variants",
+			slog.Int64("file_id", file.ID), slog.Int64("source_id", source.ID), slog.Any("error", err))
+	}
+	if n == 0 {
+		s.Generate(ctx, file)
+	}
+}
+
+// render stores each variant next to the original, e.g. 1/<uuid>_thumbnail.jpg. A
+// variant that fails is skipped; the original file is unaffected.
+func (s *imageVariantService) render(ctx context.Context, file *sqlc.File) {
+	reader, err := s.storage.Get(ctx, file.StoragePath)
+	if err != nil {
+		slog.Error("failed to read image for variants", slog.Int64("file_id", file.ID), slog.Any("error", err))
+		return
+	}
+	img, err := imaging.Decode(reader)
+	_ = reader.Close()
+	if err != nil {
+		slog.Warn("skipping image variants", slog.Int64("file_id", file.ID), slog.Any("error", err))
+		return
+	}
+
+	base := strings.TrimSuffix(file.StoragePath, filepath.Ext(file.StoragePath))
+	for _, v := range s.variants {
+		var buf bytes.Buffer
+		dim, err := imaging.Render(&buf, img, v)
+		if err != nil {
+			slog.Error("failed to render image variant",
+				slog.Int64("file_id", file.ID), slog.String("variant", v.Name), slog.Any("error", err))
+			continue
+		}
+
+		path := base + "_" + v.Name + imaging.Extension(v.Format)
+		size := int64(buf.Len())
+		if err := s.storage.Put(ctx, path, &buf, size, imaging.ContentType(v.Format)); err != nil {
+			slog.Error("failed to store image variant",
+				slog.Int64("file_id", file.ID), slog.String("variant", v.Name), slog.Any("error", err))
+			continue
+		}
+
+		_, err = s.repo.CreateVariant(ctx, sqlc.CreateFileVariantParams{
+			FileID:      file.ID,
+			Name:        v.Name,
+			StoragePath: path,
+			MimeType:    imaging.ContentType(v.Format),
+			Width:       int32(dim.X),
+			Height:      int32(dim.Y),
+			Size:        size,
+		})
+		if err != nil {
+			// The file may have been purged while rendering
+			_ = s.storage.Delete(ctx, path)
+			slog.Error("failed to save image variant",
+				slog.Int64("file_id", file.ID), slog.String("variant", v.Name), slog.Any("error", err))
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/imaging"
+)
+
+type imageVariantFixture struct {
+	uploads UploadService
+	files   *mockFileRepo
+	store   *mockStorage
+	pending []func()
+}
+
+func newImageVariantFixture(variants []imaging.Variant) *imageVariantFixture {
+	f := &imageVariantFixture{files: newMockFileRepo(), store: newMockStorage()}
+	variantSvc := NewImageVariantService(f.files, f.store, variants).(*imageVariantService)
+	variantSvc.run = func(fn func()) { f.pending = append(f.pending, fn) }
+	f.uploads = NewUploadService(f.files, f.store, variantSvc, false, false)
+	return f
+}
+
+func (f *imageVariantFixture) drain() {
+	for _, fn := range f.pending {
+		fn()
+	}
+	f.pending = nil
+}
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestImageVariants(t *testing.T) {
+	variants := []imaging.Variant{
+		{Name: "thumbnail", Width: 100, Height: 100, Format: imaging.FormatJPEG},
+		{Name: "webp", Format: imaging.FormatWebP},
+	}
+
+	t.Run("image variants are rendered after upload", func(t *testing.T) {
+		f := newImageVariantFixture(variants)
+		data := testPNG(t, 400, 200)
+
+		resp, err := f.uploads.Upload(context.Background(), 1, "photo.png", bytes.NewReader(data), int64(len(data)), "image/png", nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(resp.Variants) != 0 {
+			t.Errorf("expected no variants before rendering, got %d", len(resp.Variants))
+		}
+		f.drain()
+
+		info, err := f.uploads.GetFileInfo(context.Background(), resp.ID, 1)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		thumb, ok := info.Variants["thumbnail"]
+		if !ok {
+			t.Fatalf("expected thumbnail variant, got %+v", info.Variants)
+		}
+		if thumb.Width != 100 || thumb.Height != 50 || thumb.MimeType != "image/jpeg" {
+			t.Errorf("unexpected thumbnail %+v", thumb)
+		}
+		if webp := info.Variants["webp"]; webp.Width != 400 || webp.MimeType != "image/webp" {
+			t.Errorf("unexpected webp variant %+v", webp)
+		}
+
+		path := f.files.files[resp.ID].StoragePath
+		thumbPath := strings.TrimSuffix(path, ".png") + "_thumbnail.jpg"
+		if _, ok := f.store.files[thumbPath]; !ok {
+			t.Errorf("expected thumbnail stored at %s", thumbPath)
+		}
+
+		list, _, err := f.uploads.List(context.Background(), 1, dto.FileListQuery{}, 1, 10)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(list) != 1 || len(list[0].Variants) != 2 {
+			t.Errorf("expected listed file with 2 variants, got %+v", list)
+		}
+	})
+
+	t.Run("duplicate upload reuses rendered variants", func(t *testing.T) {
+		f := newImageVariantFixture(variants)
+		data := testPNG(t, 400, 200)
+
+		first, err := f.uploads.Upload(context.Background(), 1, "photo.png", bytes.NewReader(data), int64(len(data)), "image/png", nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		f.drain()
+		stored := len(f.store.files)
+
+		second, err := f.uploads.Upload(context.Background(), 1, "again.png", bytes.NewReader(data), int64(len(data)), "image/png", nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(f.pending) != 0 {
+			t.Errorf("expected no rendering for a duplicate, got %d jobs", len(f.pending))
+		}
+		info, err := f.uploads.GetFileInfo(context.Background(), second.ID, 1)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		firstInfo, _ := f.uploads.GetFileInfo(context.Background(), first.ID, 1)
+		if len(info.Variants) != 2 || info.Variants["thumbnail"].URL != firstInfo.Variants["thumbnail"].URL {
+			t.Errorf("expected the first upload's variants, got %+v", info.Variants)
+		}
+		if len(f.store.files) != stored {
+			t.Errorf("expected nothing new stored, storage grew from %d to %d", stored, len(f.store.files))
+		}
+	})
+
+	t.Run("duplicate of an unrendered upload renders its own", func(t *testing.T) {
+		f := newImageVariantFixture(variants)
+		data := testPNG(t, 400, 200)
+
+		for _, name := range []string{"photo.png", "again.png"} {
+			if _, err := f.uploads.Upload(context.Background(), 1, name, bytes.NewReader(data), int64(len(data)), "image/png", nil); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+		if len(f.pending) != 2 {
+			t.Errorf("expected both uploads to render, got %d jobs", len(f.pending))
+		}
+	})
+
+	t.Run("non-image files are skipped", func(t *testing.T) {
+		f := newImageVariantFixture(variants)
+
+		if _, err := f.uploads.Upload(context.Background(), 1, "doc.pdf", strings.NewReader("%PDF-1.4"), 8, "application/pdf", nil); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(f.pending) != 0 {
+			t.Errorf("expected no rendering for a PDF, got %d jobs", len(f.pending))
+		}
+	})
+
+	t.Run("undecodable image leaves the upload intact", func(t *testing.T) {
+		f := newImageVariantFixture(variants)
+
+		resp, err := f.uploads.Upload(context.Background(), 1, "broken.png", strings.NewReader("not a png"), 9, "image/png", nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		f.drain()
+
+		if len(f.files.variants) != 0 {
+			t.Errorf("expected no variants, got %d", len(f.files.variants))
+		}
+		if _, err := f.uploads.GetFileInfo(context.Background(), resp.ID, 1); err != nil {
+			t.Errorf("expected file to remain available, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/email"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/pagination"
+)
+
+// LoginAttempt describes a single login attempt to be recorded.
+// UserID may be zero for failed attempts, in which case the user is looked up by Email.
+type LoginAttempt struct {
+	UserID        int64
+	Email         string
+	Method        string
+	Success       bool
+	FailureReason string
+	IPAddress     string
+	UserAgent     string
+}
+
+type LoginEventService interface {
+	Record(ctx context.Context, attempt LoginAttempt) error
+	ListByUser(ctx context.Context, userID int64, page, perPage int) ([]dto.LoginEventResponse, int64, error)
+}
+
+type loginEventService struct {
+	repo            repository.LoginEventRepository
+	userRepo        repository.UserRepository
+	settingsRepo    repository.UserSettingsRepository
+	sender          email.Sender
+	notifyNewDevice bool
+}
+
+func NewLoginEventService(
+	repo repository.LoginEventRepository,
+	userRepo repository.UserRepository,
+	settingsRepo repository.UserSettingsRepository,
+	sender email.Sender,
+	notifyNewDevice bool,
+) LoginEventService {
+	return &loginEventService{
+		repo:            repo,
+		userRepo:        userRepo,
+		settingsRepo:    settingsRepo,
+		sender:          sender,
+		notifyNewDevice: notifyNewDevice,
+	}
+}
+
+func (s *loginEventService) Record(ctx context.Context, attempt LoginAttempt) error {
+	// Attach failed attempts to the targeted account so its owner can see them
+	if attempt.UserID == 0 && attempt.Email != "" {
+		if user, err := s.userRepo.GetByEmail(ctx, attempt.Email); err == nil {
+			attempt.UserID = user.ID
+		} else if !errors.Is(err, apperror.ErrNotFound) {
+			return fmt.Errorf("find user for login event: %w", err)
+		}
+	}
+
+	// Check device history before inserting so the current login is not counted
+	newDevice := false
+	if s.notifyNewDevice && attempt.Success && attempt.UserID != 0 {
+		hasLogins, knownDevice, err := s.repo.GetDeviceHistory(ctx, attempt.UserID, attempt.UserAgent)
+		if err != nil {
+			slog.Error("failed to check login device history", slog.Any("error", err))
+		}
+		newDevice = err == nil && hasLogins && !knownDevice
+	}
+
+	event, err := s.repo.Create(ctx, sqlc.CreateLoginEventParams{
+		UserID:        pgtype.Int8{Int64: attempt.UserID, Valid: attempt.UserID != 0},
+		Email:         attempt.Email,
+		Method:        attempt.Method,
+		Success:       attempt.Success,
+		FailureReason: attempt.FailureReason,
+		IpAddress:     attempt.IPAddress,
+		UserAgent:     attempt.UserAgent,
+	})
+	if err != nil {
+		return fmt.Errorf("create login event: %w", err)
+	}
+
+	if newDevice {
+		s.sendNewDeviceEmail(ctx, event)
+	}
+	return nil
+}
+
+func (s *loginEventService) sendNewDeviceEmail(ctx context.Context, event *sqlc.LoginEvent) {
+	user, err := s.userRepo.GetByID(ctx, event.UserID.Int64)
+	if err != nil {
+		slog.Error("failed to load user for new device email", slog.Any("error", err))
+		return
+	}
+
+	// Security alerts are too important to drop on a settings lookup failure
+	settings, err := loadUserSettings(ctx, s.settingsRepo, user.ID)
+	if err != nil {
+		slog.Error("failed to load settings for new device email", slog.Any("error", err))
+		settings = &sqlc.UserSetting{EmailSecurityAlerts: true}
+	}
+	if !settings.EmailSecurityAlerts {
+		return
+	}
+
+	if err := s.sender.Send(ctx, email.Message{
+		To:      []string{user.Email},
+		Subject: "New Sign-In to Your Account",
+		Locale:  settings.Locale,
+		HTML: fmt.Sprintf(
+			"<p>Your account was just signed in to from a new device.</p>"+
+				"<p>Time: %s<br>IP address: %s<br>Device: %s<br>Method: %s</p>"+
+				"<p>If this wasn't you, reset your password immediately.</p>",
+			email.FormatTime(event.CreatedAt.Time, settings.Timezone),
+			html.EscapeString(event.IpAddress),
+			html.EscapeString(event.UserAgent),
+			html.EscapeString(event.Method),
+		),
+	}); err != nil {
+		slog.Error("failed to send new device email", slog.Any("error", err))
+	}
+}
+
+func (s *loginEventService) ListByUser(ctx context.Context, userID int64, page, perPage int) ([]dto.LoginEventResponse, int64, error) {
+	limit, offset := pagination.LimitOffset(page, perPage)
+
+	events, err := s.repo.ListByUserID(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, 0, apperror.NewInternal("failed to list login events")
+	}
+
+	total, err := s.repo.CountByUserID(ctx, userID)
+	if err != nil {
+		return nil, 0, apperror.NewInternal("failed to count login events")
+	}
+
+	responses := make([]dto.LoginEventResponse, len(events))
+	for i := range events {
+		responses[i] = toLoginEventResponse(&events[i])
+	}
+
+	return responses, total, nil
+}
+
+func toLoginEventResponse(e *sqlc.LoginEvent) dto.LoginEventResponse {
+	return dto.LoginEventResponse{
+		ID:            e.ID,
+		Method:        e.Method,
+		Success:       e.Success,
+		FailureReason: e.FailureReason,
+		IPAddress:     e.IpAddress,
+		UserAgent:     e.UserAgent,
+		CreatedAt:     e.CreatedAt.Time,
+	}
+}
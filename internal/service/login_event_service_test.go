@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+)
+
+// ---------------------------------------------------------------------------
+// Record
+// ---------------------------------------------------------------------------
+
+func TestRecordLoginEvent(t *testing.T) {
+	t.Run("failed attempt is attached to user by email", func(t *testing.T) {
+		userRepo := newMockUserRepo()
+		userRepo.users[1] = &sqlc.User{ID: 1, Email: "user@example.com"}
+		repo := newMockLoginEventRepo()
+		svc := NewLoginEventService(repo, userRepo, newMockUserSettingsRepo(), newMockEmailSender(), false)
+
+		err := svc.Record(context.Background(), LoginAttempt{
+			Email:         "user@example.com",
+			Method:        "password",
+			FailureReason: "invalid email or password",
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(repo.events) != 1 {
+			t.Fatalf("expected 1 event, got %d", len(repo.events))
+		}
+		if got := repo.events[0].UserID; !got.Valid || got.Int64 != 1 {
+			t.Errorf("expected user_id 1, got %+v", got)
+		}
+		if repo.events[0].Success {
+			t.Error("expected failed event")
+		}
+	})
+
+	t.Run("failed attempt for unknown email has no user", func(t *testing.T) {
+		repo := newMockLoginEventRepo()
+		svc := NewLoginEventService(repo, newMockUserRepo(), newMockUserSettingsRepo(), newMockEmailSender(), false)
+
+		err := svc.Record(context.Background(), LoginAttempt{Email: "nobody@example.com", Method: "password"})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if repo.events[0].UserID.Valid {
+			t.Error("expected null user_id")
+		}
+	})
+
+	t.Run("new device sends email", func(t *testing.T) {
+		userRepo := newMockUserRepo()
+		userRepo.users[1] = &sqlc.User{ID: 1, Email: "user@example.com"}
+		sender := newMockEmailSender()
+		svc := NewLoginEventService(newMockLoginEventRepo(), userRepo, newMockUserSettingsRepo(), sender, true)
+		ctx := context.Background()
+
+		// First ever login does not count as a new device
+		_ = svc.Record(ctx, LoginAttempt{UserID: 1, Method: "password", Success: true, UserAgent: "laptop"})
+		if sender.sent != 0 {
+			t.Fatalf("expected no email on first login, got %d", sender.sent)
+		}
+
+		_ = svc.Record(ctx, LoginAttempt{UserID: 1, Method: "password", Success: true, UserAgent: "laptop"})
+		if sender.sent != 0 {
+			t.Fatalf("expected no email for known device, got %d", sender.sent)
+		}
+
+		_ = svc.Record(ctx, LoginAttempt{UserID: 1, Method: "password", Success: true, UserAgent: "phone"})
+		if sender.sent != 1 {
+			t.Errorf("expected 1 email for new device, got %d", sender.sent)
+		}
+	})
+
+	t.Run("new device email honours user settings", func(t *testing.T) {
+		userRepo := newMockUserRepo()
+		userRepo.users[1] = &sqlc.User{ID: 1, Email: "user@example.com"}
+		userRepo.users[2] = &sqlc.User{ID: 2, Email: "other@example.com"}
+		settingsRepo := newMockUserSettingsRepo()
+		settingsRepo.settings[1] = &sqlc.UserSetting{UserID: 1, Locale: "vi", Timezone: "Asia/Ho_Chi_Minh", EmailSecurityAlerts: true}
+		settingsRepo.settings[2] = &sqlc.UserSetting{UserID: 2, Locale: "en", Timezone: "UTC", EmailSecurityAlerts: false}
+		sender := newMockEmailSender()
+		svc := NewLoginEventService(newMockLoginEventRepo(), userRepo, settingsRepo, sender, true)
+		ctx := context.Background()
+
+		for _, id := range []int64{1, 2} {
+			_ = svc.Record(ctx, LoginAttempt{UserID: id, Method: "password", Success: true, UserAgent: "laptop"})
+			_ = svc.Record(ctx, LoginAttempt{UserID: id, Method: "password", Success: true, UserAgent: "phone"})
+		}
+		if sender.sent != 1 {
+			t.Fatalf("expected only the opted-in user to be emailed, got %d", sender.sent)
+		}
+		if sender.last.Locale != "vi" || !strings.Contains(sender.last.HTML, "+07") {
+			t.Errorf("expected email localized to vi and Asia/Ho_Chi_Minh, got locale %q body %q", sender.last.Locale, sender.last.HTML)
+		}
+	})
+
+	t.Run("new device email disabled", func(t *testing.T) {
+		userRepo := newMockUserRepo()
+		userRepo.users[1] = &sqlc.User{ID: 1, Email: "user@example.com"}
+		sender := newMockEmailSender()
+		svc := NewLoginEventService(newMockLoginEventRepo(), userRepo, newMockUserSettingsRepo(), sender, false)
+		ctx := context.Background()
+
+		_ = svc.Record(ctx, LoginAttempt{UserID: 1, Method: "password", Success: true, UserAgent: "laptop"})
+		_ = svc.Record(ctx, LoginAttempt{UserID: 1, Method: "password", Success: true, UserAgent: "phone"})
+		if sender.sent != 0 {
+			t.Errorf("expected no email, got %d", sender.sent)
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// ListByUser
+// ---------------------------------------------------------------------------
+
+func TestListLoginEventsByUser(t *testing.T) {
+	repo := newMockLoginEventRepo()
+	svc := NewLoginEventService(repo, newMockUserRepo(), newMockUserSettingsRepo(), newMockEmailSender(), false)
+	ctx := context.Background()
+
+	_ = svc.Record(ctx, LoginAttempt{UserID: 1, Method: "password", Success: true})
+	_ = svc.Record(ctx, LoginAttempt{UserID: 1, Method: "passkey", Success: true})
+	_ = svc.Record(ctx, LoginAttempt{UserID: 2, Method: "password", Success: true})
+
+	events, total, err := svc.ListByUser(ctx, 1, 1, 10)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if total != 2 || len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d (total %d)", len(events), total)
+	}
+	if events[0].Method != "passkey" {
+		t.Errorf("expected newest event first, got %q", events[0].Method)
+	}
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+)
+
+const (
+	loginIPFailuresPrefix = "login_ip_failures:"
+	loginIPBlockedPrefix  = "login_ip_blocked:"
+	loginIPBackoffBase    = time.Second
+)
+
+// LoginThrottle limits password login attempts per client IP so credential stuffing
+// across many emails from one address is slowed down exponentially.
+type LoginThrottle interface {
+	// Check returns how long the IP must wait before its next attempt, or zero if it may proceed.
+	Check(ctx context.Context, ip string) time.Duration
+	// RecordFailure counts a failed attempt and returns the resulting backoff, if any.
+	RecordFailure(ctx context.Context, ip string) time.Duration
+}
+
+type loginThrottle struct {
+	cache       cache.Cache
+	maxFailures int
+	window      time.Duration
+	maxBackoff  time.Duration
+}
+
+// NewLoginThrottle returns a LoginThrottle that allows maxFailures failed attempts per IP within
+// window, then blocks the IP for a backoff that doubles with each further failure up to maxBackoff.
+func NewLoginThrottle(appCache cache.Cache, maxFailures int, window, maxBackoff time.Duration) LoginThrottle {
+	return &loginThrottle{
+		cache:       appCache,
+		maxFailures: maxFailures,
+		window:      window,
+		maxBackoff:  maxBackoff,
+	}
+}
+
+func (t *loginThrottle) Check(ctx context.Context, ip string) time.Duration {
+	data, _ := t.cache.Get(ctx, loginIPBlockedPrefix+ip)
+	if data == nil {
+		return 0
+	}
+	until, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return max(time.Until(time.Unix(until, 0)), 0)
+}
+
+func (t *loginThrottle) RecordFailure(ctx context.Context, ip string) time.Duration {
+	// Counted atomically, so a burst of concurrent failures cannot undercount
+	failures, err := t.cache.Increment(ctx, loginIPFailuresPrefix+ip, 1, t.window)
+	if err != nil || failures < int64(t.maxFailures) {
+		return 0
+	}
+
+	backoff := t.backoff(int(failures) - t.maxFailures)
+	until := time.Now().Add(backoff).Unix()
+	_ = t.cache.Set(ctx, loginIPBlockedPrefix+ip, []byte(strconv.FormatInt(until, 10)), backoff)
+	return backoff
+}
+
+// backoff returns base * 2^excess, capped at maxBackoff.
+func (t *loginThrottle) backoff(excess int) time.Duration {
+	d := loginIPBackoffBase
+	for range excess {
+		d *= 2
+		if d >= t.maxBackoff {
+			return t.maxBackoff
+		}
+	}
+	return min(d, t.maxBackoff)
+}
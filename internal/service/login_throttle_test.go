@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+)
+
+func newTestLoginThrottle(t *testing.T, maxFailures int, maxBackoff time.Duration) LoginThrottle {
+	t.Helper()
+	c := cache.NewMemoryCache()
+	t.Cleanup(func() { _ = c.Close() })
+	return NewLoginThrottle(c, maxFailures, time.Minute, maxBackoff)
+}
+
+func TestLoginThrottle(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("allows attempts below threshold", func(t *testing.T) {
+		throttle := newTestLoginThrottle(t, 3, time.Hour)
+
+		for i := 0; i < 2; i++ {
+			if wait := throttle.RecordFailure(ctx, "10.0.0.1"); wait != 0 {
+				t.Fatalf("failure %d: expected no backoff, got %v", i+1, wait)
+			}
+		}
+		if wait := throttle.Check(ctx, "10.0.0.1"); wait != 0 {
+			t.Errorf("expected IP to be allowed, got wait %v", wait)
+		}
+	})
+
+	t.Run("backoff doubles after threshold", func(t *testing.T) {
+		throttle := newTestLoginThrottle(t, 2, time.Hour)
+
+		throttle.RecordFailure(ctx, "10.0.0.2")
+		if wait := throttle.RecordFailure(ctx, "10.0.0.2"); wait != time.Second {
+			t.Errorf("expected 1s backoff at threshold, got %v", wait)
+		}
+		if wait := throttle.RecordFailure(ctx, "10.0.0.2"); wait != 2*time.Second {
+			t.Errorf("expected 2s backoff, got %v", wait)
+		}
+		if wait := throttle.RecordFailure(ctx, "10.0.0.2"); wait != 4*time.Second {
+			t.Errorf("expected 4s backoff, got %v", wait)
+		}
+		if wait := throttle.Check(ctx, "10.0.0.2"); wait <= 0 {
+			t.Error("expected IP to be blocked")
+		}
+		if wait := throttle.Check(ctx, "10.0.0.3"); wait != 0 {
+			t.Errorf("expected other IP to be allowed, got wait %v", wait)
+		}
+	})
+
+	t.Run("backoff is capped", func(t *testing.T) {
+		throttle := newTestLoginThrottle(t, 1, 3*time.Second)
+
+		var wait time.Duration
+		for i := 0; i < 10; i++ {
+			wait = throttle.RecordFailure(ctx, "10.0.0.4")
+		}
+		if wait != 3*time.Second {
+			t.Errorf("expected backoff capped at 3s, got %v", wait)
+		}
+	})
+}
@@ -1,15 +1,28 @@
 package service
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/email"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/realtime"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/storage"
 )
 
 // ---------------------------------------------------------------------------
@@ -17,8 +30,10 @@ import (
 // ---------------------------------------------------------------------------
 
 type mockUserRepo struct {
-	users  map[int64]*sqlc.User
-	nextID int64
+	users          map[int64]*sqlc.User
+	nextID         int64
+	listAfterCalls int
+	details        map[int64]sqlc.GetUserDetailRow // linked resources returned by GetDetail
 }
 
 func newMockUserRepo() *mockUserRepo {
@@ -33,6 +48,16 @@ func (m *mockUserRepo) GetByID(_ context.Context, id int64) (*sqlc.User, error)
 	return u, nil
 }
 
+func (m *mockUserRepo) GetDetail(_ context.Context, id int64) (*sqlc.GetUserDetailRow, error) {
+	u, ok := m.users[id]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	detail := m.details[id]
+	detail.User = *u
+	return &detail, nil
+}
+
 func (m *mockUserRepo) GetByEmail(_ context.Context, addr string) (*sqlc.User, error) {
 	for _, u := range m.users {
 		if u.Email == addr {
@@ -51,11 +76,35 @@ func (m *mockUserRepo) GetByGoogleID(_ context.Context, googleID string) (*sqlc.
 	return nil, apperror.ErrNotFound
 }
 
-func (m *mockUserRepo) List(_ context.Context, limit, offset int32) ([]sqlc.User, error) {
-	all := make([]sqlc.User, 0, len(m.users))
+func (m *mockUserRepo) GetByGitHubID(_ context.Context, githubID string) (*sqlc.User, error) {
 	for _, u := range m.users {
-		all = append(all, *u)
+		if u.GithubID.Valid && u.GithubID.String == githubID {
+			return u, nil
+		}
+	}
+	return nil, apperror.ErrNotFound
+}
+
+func (m *mockUserRepo) GetBySAMLID(_ context.Context, samlID string) (*sqlc.User, error) {
+	for _, u := range m.users {
+		if u.SamlID.Valid && u.SamlID.String == samlID {
+			return u, nil
+		}
+	}
+	return nil, apperror.ErrNotFound
+}
+
+func (m *mockUserRepo) GetByUsername(_ context.Context, username string) (*sqlc.User, error) {
+	for _, u := range m.users {
+		if u.Username.Valid && u.Username.String == username && !u.DeletedAt.Valid {
+			return u, nil
+		}
 	}
+	return nil, apperror.ErrNotFound
+}
+
+func (m *mockUserRepo) List(_ context.Context, filter repository.UserFilter, limit, offset int32) ([]sqlc.User, error) {
+	all := m.filter(filter)
 	start := int(offset)
 	if start > len(all) {
 		return nil, nil
@@ -67,8 +116,49 @@ func (m *mockUserRepo) List(_ context.Context, limit, offset int32) ([]sqlc.User
 	return all[start:end], nil
 }
 
-func (m *mockUserRepo) Count(_ context.Context) (int64, error) {
-	return int64(len(m.users)), nil
+func (m *mockUserRepo) Count(_ context.Context, filter repository.UserFilter) (int64, error) {
+	return int64(len(m.filter(filter))), nil
+}
+
+func (m *mockUserRepo) CountActiveAdminsForUpdate(_ context.Context) (int64, error) {
+	notBanned := false
+	return int64(len(m.filter(repository.UserFilter{Role: dto.RoleAdmin, Banned: &notBanned}))), nil
+}
+
+func (m *mockUserRepo) ListAfter(_ context.Context, filter repository.UserFilter, afterID int64, limit int32) ([]sqlc.User, error) {
+	var page []sqlc.User
+	for _, u := range m.filter(filter) {
+		if u.ID > afterID && len(page) < int(limit) {
+			page = append(page, u)
+		}
+	}
+	m.listAfterCalls++
+	return page, nil
+}
+
+// filter applies the search, role, ban and deletion filters; results are ordered by ID.
+func (m *mockUserRepo) filter(f repository.UserFilter) []sqlc.User {
+	all := make([]sqlc.User, 0, len(m.users))
+	for _, u := range m.users {
+		if u.DeletedAt.Valid && !f.IncludeDeleted {
+			continue
+		}
+		if f.Role != "" && u.Role != f.Role {
+			continue
+		}
+		if f.Banned != nil && u.BannedAt.Valid != *f.Banned {
+			continue
+		}
+		if f.Search != "" {
+			term := strings.ToLower(f.Search)
+			if !strings.Contains(strings.ToLower(u.Name), term) && !strings.Contains(strings.ToLower(u.Email), term) {
+				continue
+			}
+		}
+		all = append(all, *u)
+	}
+	slices.SortFunc(all, func(a, b sqlc.User) int { return int(a.ID - b.ID) })
+	return all
 }
 
 func (m *mockUserRepo) Create(_ context.Context, params sqlc.CreateUserParams) (*sqlc.User, error) {
@@ -77,6 +167,7 @@ func (m *mockUserRepo) Create(_ context.Context, params sqlc.CreateUserParams) (
 		Email:        params.Email,
 		PasswordHash: params.PasswordHash,
 		Name:         params.Name,
+		Username:     params.Username,
 		AuthProvider: "local",
 		Role:         "user",
 		CreatedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
@@ -93,6 +184,8 @@ func (m *mockUserRepo) CreateOAuthUser(_ context.Context, params sqlc.CreateOAut
 		Email:        params.Email,
 		Name:         params.Name,
 		GoogleID:     params.GoogleID,
+		GithubID:     params.GithubID,
+		SamlID:       params.SamlID,
 		AuthProvider: params.AuthProvider,
 		Role:         "user",
 		CreatedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
@@ -103,6 +196,35 @@ func (m *mockUserRepo) CreateOAuthUser(_ context.Context, params sqlc.CreateOAut
 	return u, nil
 }
 
+func (m *mockUserRepo) CreateGuest(_ context.Context, params sqlc.CreateGuestUserParams) (*sqlc.User, error) {
+	u := &sqlc.User{
+		ID:           m.nextID,
+		Email:        params.Email,
+		Name:         params.Name,
+		Role:         "guest",
+		AuthProvider: "guest",
+		CreatedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		UpdatedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	m.users[m.nextID] = u
+	m.nextID++
+	return u, nil
+}
+
+func (m *mockUserRepo) UpgradeGuest(_ context.Context, params sqlc.UpgradeGuestUserParams) (*sqlc.User, error) {
+	u, ok := m.users[params.ID]
+	if !ok || u.Role != "guest" {
+		return nil, apperror.ErrNotFound
+	}
+	u.Email = params.Email
+	u.Name = params.Name
+	u.PasswordHash = params.PasswordHash
+	u.Username = params.Username
+	u.Role = "user"
+	u.AuthProvider = "local"
+	return u, nil
+}
+
 func (m *mockUserRepo) Update(_ context.Context, params sqlc.UpdateUserParams) (*sqlc.User, error) {
 	u, ok := m.users[params.ID]
 	if !ok {
@@ -110,6 +232,34 @@ func (m *mockUserRepo) Update(_ context.Context, params sqlc.UpdateUserParams) (
 	}
 	u.Name = params.Name
 	u.Email = params.Email
+	u.Username = params.Username
+	return u, nil
+}
+
+func (m *mockUserRepo) GetMetadata(_ context.Context, id int64) (map[string]any, error) {
+	u, ok := m.users[id]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	return repository.DecodeMetadata(u.Metadata)
+}
+
+func (m *mockUserRepo) MergeMetadata(_ context.Context, id int64, set map[string]any, remove []string) (*sqlc.User, error) {
+	u, ok := m.users[id]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	meta, err := repository.DecodeMetadata(u.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	maps.Copy(meta, set)
+	for _, k := range remove {
+		delete(meta, k)
+	}
+	if u.Metadata, err = json.Marshal(meta); err != nil {
+		return nil, err
+	}
 	return u, nil
 }
 
@@ -140,6 +290,16 @@ func (m *mockUserRepo) VerifyEmail(_ context.Context, id int64) (*sqlc.User, err
 	return u, nil
 }
 
+func (m *mockUserRepo) UpdateEmail(_ context.Context, params sqlc.UpdateUserEmailParams) (*sqlc.User, error) {
+	u, ok := m.users[params.ID]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	u.Email = params.Email
+	u.EmailVerifiedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return u, nil
+}
+
 func (m *mockUserRepo) LinkGoogleAccount(_ context.Context, params sqlc.LinkGoogleAccountParams) (*sqlc.User, error) {
 	u, ok := m.users[params.ID]
 	if !ok {
@@ -150,34 +310,171 @@ func (m *mockUserRepo) LinkGoogleAccount(_ context.Context, params sqlc.LinkGoog
 	return u, nil
 }
 
+func (m *mockUserRepo) LinkGitHubAccount(_ context.Context, params sqlc.LinkGitHubAccountParams) (*sqlc.User, error) {
+	u, ok := m.users[params.ID]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	u.GithubID = params.GithubID
+	u.AuthProvider = "github"
+	return u, nil
+}
+
+func (m *mockUserRepo) LinkSAMLAccount(_ context.Context, params sqlc.LinkSAMLAccountParams) (*sqlc.User, error) {
+	u, ok := m.users[params.ID]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	u.SamlID = params.SamlID
+	u.AuthProvider = "saml"
+	return u, nil
+}
+
+func (m *mockUserRepo) Ban(_ context.Context, id int64, reason string) (*sqlc.User, error) {
+	u, ok := m.users[id]
+	if !ok || u.BannedAt.Valid {
+		return nil, apperror.ErrNotFound
+	}
+	u.BannedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	u.BanReason = reason
+	return u, nil
+}
+
+func (m *mockUserRepo) Unban(_ context.Context, id int64) (*sqlc.User, error) {
+	u, ok := m.users[id]
+	if !ok || !u.BannedAt.Valid {
+		return nil, apperror.ErrNotFound
+	}
+	u.BannedAt = pgtype.Timestamptz{}
+	u.BanReason = ""
+	return u, nil
+}
+
 func (m *mockUserRepo) Delete(_ context.Context, id int64) (*sqlc.User, error) {
 	u, ok := m.users[id]
-	if !ok {
+	if !ok || u.DeletedAt.Valid {
 		return nil, apperror.ErrNotFound
 	}
-	delete(m.users, id)
+	u.DeletedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
 	return u, nil
 }
 
 func (m *mockUserRepo) Restore(_ context.Context, id int64) (*sqlc.User, error) {
 	u, ok := m.users[id]
-	if !ok {
+	if !ok || !u.DeletedAt.Valid {
 		return nil, apperror.ErrNotFound
 	}
 	u.DeletedAt = pgtype.Timestamptz{}
 	return u, nil
 }
 
-func (m *mockUserRepo) AdminList(ctx context.Context, limit, offset int32) ([]sqlc.User, error) {
-	return m.List(ctx, limit, offset)
+func (m *mockUserRepo) Purge(_ context.Context, id int64) error {
+	if _, ok := m.users[id]; !ok {
+		return apperror.ErrNotFound
+	}
+	delete(m.users, id)
+	return nil
+}
+
+func (m *mockUserRepo) ListDeletedBefore(_ context.Context, before time.Time, limit int32) ([]int64, error) {
+	var ids []int64
+	for id, u := range m.users {
+		if u.DeletedAt.Valid && u.DeletedAt.Time.Before(before) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	if len(ids) > int(limit) {
+		ids = ids[:limit]
+	}
+	return ids, nil
 }
 
-func (m *mockUserRepo) AdminCount(_ context.Context) (int64, error) {
-	return int64(len(m.users)), nil
+func (m *mockUserRepo) Anonymize(_ context.Context, id int64, email, name string) (*sqlc.User, error) {
+	u, ok := m.users[id]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	u.Email = email
+	u.Name = name
+	u.Username = pgtype.Text{}
+	u.PasswordHash = pgtype.Text{}
+	u.GoogleID = pgtype.Text{}
+	u.GithubID = pgtype.Text{}
+	u.SamlID = pgtype.Text{}
+	u.EmailVerifiedAt = pgtype.Timestamptz{}
+	u.Metadata = []byte("{}")
+	if !u.DeletedAt.Valid {
+		u.DeletedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	}
+	return u, nil
 }
 
 func (m *mockUserRepo) GetSystemStats(_ context.Context) (sqlc.GetSystemStatsRow, error) {
-	return sqlc.GetSystemStatsRow{ActiveUsers: int64(len(m.users))}, nil
+	var stats sqlc.GetSystemStatsRow
+	for _, u := range m.users {
+		switch {
+		case u.DeletedAt.Valid:
+			stats.DeletedUsers++
+		case u.BannedAt.Valid:
+			stats.BannedUsers++
+		default:
+			stats.ActiveUsers++
+		}
+	}
+	return stats, nil
+}
+
+// GetDailyStats reports each day's signups; other series are left at zero.
+func (m *mockUserRepo) GetDailyStats(_ context.Context, from, to time.Time) ([]sqlc.GetDailyStatsRow, error) {
+	var rows []sqlc.GetDailyStatsRow
+	for day := from.UTC().Truncate(24 * time.Hour); !day.After(to); day = day.AddDate(0, 0, 1) {
+		row := sqlc.GetDailyStatsRow{Day: pgtype.Date{Time: day, Valid: true}}
+		for _, u := range m.users {
+			if u.CreatedAt.Valid && u.CreatedAt.Time.UTC().Truncate(24*time.Hour).Equal(day) {
+				row.Signups++
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (m *mockUserRepo) Search(_ context.Context, term string, limit int32) ([]sqlc.SearchUsersRow, error) {
+	var rows []sqlc.SearchUsersRow
+	for _, u := range m.users {
+		rank, ok := searchRank(term, u.Email, u.Name)
+		if !ok {
+			continue
+		}
+		rows = append(rows, sqlc.SearchUsersRow{
+			ID: u.ID, Email: u.Email, Name: u.Name, Role: u.Role,
+			DeletedAt: u.DeletedAt, CreatedAt: u.CreatedAt, Rank: rank,
+		})
+	}
+	slices.SortFunc(rows, func(a, b sqlc.SearchUsersRow) int {
+		return cmp.Or(cmp.Compare(a.Rank, b.Rank), b.CreatedAt.Time.Compare(a.CreatedAt.Time), cmp.Compare(b.ID, a.ID))
+	})
+	return rows[:min(len(rows), int(limit))], nil
+}
+
+// searchRank mirrors the ranking of the search queries: 0 for an exact match of any of
+// values, 1 for a prefix and 2 for another substring, ignoring case.
+func searchRank(term string, values ...string) (int32, bool) {
+	term = strings.ToLower(term)
+	best, found := int32(2), false
+	for _, v := range values {
+		v = strings.ToLower(v)
+		switch {
+		case v == term:
+			return 0, true
+		case strings.HasPrefix(v, term):
+			best, found = 1, true
+		case strings.Contains(v, term):
+			found = true
+		}
+	}
+	return best, found
 }
 
 // ---------------------------------------------------------------------------
@@ -187,22 +484,38 @@ func (m *mockUserRepo) GetSystemStats(_ context.Context) (sqlc.GetSystemStatsRow
 type mockRefreshTokenRepo struct {
 	tokens         map[string]*sqlc.RefreshToken
 	deletedUserIDs []int64
+	nextID         int64
 }
 
 func newMockRefreshTokenRepo() *mockRefreshTokenRepo {
-	return &mockRefreshTokenRepo{tokens: make(map[string]*sqlc.RefreshToken)}
+	return &mockRefreshTokenRepo{tokens: make(map[string]*sqlc.RefreshToken), nextID: 1}
 }
 
 func (m *mockRefreshTokenRepo) Create(_ context.Context, params sqlc.CreateRefreshTokenParams) (*sqlc.RefreshToken, error) {
 	rt := &sqlc.RefreshToken{
-		UserID:    params.UserID,
-		Token:     params.Token,
-		ExpiresAt: params.ExpiresAt,
+		ID:                m.nextID,
+		UserID:            params.UserID,
+		Token:             params.Token,
+		ExpiresAt:         params.ExpiresAt,
+		FamilyID:          params.FamilyID,
+		ParentID:          params.ParentID,
+		DeviceFingerprint: params.DeviceFingerprint,
 	}
 	m.tokens[params.Token] = rt
+	m.nextID++
 	return rt, nil
 }
 
+func (m *mockRefreshTokenRepo) MarkRotated(_ context.Context, id int64) (*sqlc.RefreshToken, error) {
+	for _, rt := range m.tokens {
+		if rt.ID == id && !rt.RotatedAt.Valid {
+			rt.RotatedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+			return rt, nil
+		}
+	}
+	return nil, apperror.ErrNotFound
+}
+
 func (m *mockRefreshTokenRepo) GetByToken(_ context.Context, token string) (*sqlc.RefreshToken, error) {
 	rt, ok := m.tokens[token]
 	if !ok {
@@ -226,13 +539,35 @@ func (m *mockRefreshTokenRepo) DeleteByUserID(_ context.Context, userID int64) e
 	return nil
 }
 
+func (m *mockRefreshTokenRepo) ListActiveByUserID(_ context.Context, userID int64) ([]sqlc.ListActiveRefreshTokensByUserIDRow, error) {
+	var rows []sqlc.ListActiveRefreshTokensByUserIDRow
+	for _, rt := range m.tokens {
+		if rt.UserID != userID || rt.RotatedAt.Valid || !rt.ExpiresAt.Time.After(time.Now()) {
+			continue
+		}
+		rows = append(rows, sqlc.ListActiveRefreshTokensByUserIDRow{
+			FamilyID:        rt.FamilyID,
+			StartedAt:       rt.CreatedAt,
+			LastRefreshedAt: rt.CreatedAt,
+			ExpiresAt:       rt.ExpiresAt,
+			DeviceBound:     rt.DeviceFingerprint.Valid,
+		})
+	}
+	return rows, nil
+}
+
 // ---------------------------------------------------------------------------
 // mockFileRepo
 // ---------------------------------------------------------------------------
 
 type mockFileRepo struct {
-	files  map[int64]*sqlc.File
-	nextID int64
+	files       map[int64]*sqlc.File
+	versions    []sqlc.FileVersion
+	variants    []sqlc.FileVariant
+	permissions []sqlc.FilePermission
+	tags        []sqlc.FileTag
+	folders     *mockFolderRepo // consulted by Move; nil means no folders exist
+	nextID      int64
 }
 
 func newMockFileRepo() *mockFileRepo {
@@ -241,14 +576,19 @@ func newMockFileRepo() *mockFileRepo {
 
 func (m *mockFileRepo) Create(_ context.Context, params sqlc.CreateFileParams) (*sqlc.File, error) {
 	f := &sqlc.File{
-		ID:           m.nextID,
-		UserID:       params.UserID,
-		OriginalName: params.OriginalName,
-		StoragePath:  params.StoragePath,
-		MimeType:     params.MimeType,
-		Size:         params.Size,
-		CreatedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		ID:              m.nextID,
+		UserID:          params.UserID,
+		OriginalName:    params.OriginalName,
+		StoragePath:     params.StoragePath,
+		MimeType:        params.MimeType,
+		Size:            params.Size,
+		Checksum:        params.Checksum,
+		EncryptionKeyID: params.EncryptionKeyID,
+		Visibility:      "private",
+		Version:         1,
+		CreatedAt:       pgtype.Timestamptz{Time: time.Now(), Valid: true},
 	}
+	f.VersionCreatedAt = f.CreatedAt
 	m.files[m.nextID] = f
 	m.nextID++
 	return f, nil
@@ -262,10 +602,92 @@ func (m *mockFileRepo) GetByID(_ context.Context, id int64) (*sqlc.File, error)
 	return f, nil
 }
 
-func (m *mockFileRepo) ListByUserID(_ context.Context, userID int64, limit, offset int32) ([]sqlc.File, error) {
+func (m *mockFileRepo) ListByIDs(_ context.Context, ids []int64) ([]sqlc.File, error) {
 	var result []sqlc.File
+	for _, id := range ids {
+		if f, ok := m.files[id]; ok && !f.DeletedAt.Valid {
+			result = append(result, *f)
+		}
+	}
+	return result, nil
+}
+
+// hasTag reports whether the file carries tag; an empty tag matches every file.
+func (m *mockFileRepo) hasTag(f *sqlc.File, tag string) bool {
+	return tag == "" || slices.Contains(m.tags, sqlc.FileTag{FileID: f.ID, Tag: tag})
+}
+
+// matches mirrors the filters of the list queries.
+func (m *mockFileRepo) matches(f *sqlc.File, filter repository.FileFilter) bool {
+	if !m.hasTag(f, filter.Tag) {
+		return false
+	}
+	if filter.FolderID == nil {
+		return true
+	}
+	if *filter.FolderID == 0 {
+		return !f.FolderID.Valid
+	}
+	return f.FolderID.Valid && f.FolderID.Int64 == *filter.FolderID
+}
+
+func (m *mockFileRepo) GetByChecksum(_ context.Context, userID int64, checksum string) (*sqlc.File, error) {
+	var found *sqlc.File
 	for _, f := range m.files {
-		if f.UserID == userID {
+		if f.UserID == userID && f.Checksum.String == checksum && !f.DeletedAt.Valid && (found == nil || f.ID < found.ID) {
+			found = f
+		}
+	}
+	if found == nil {
+		return nil, apperror.ErrNotFound
+	}
+	return found, nil
+}
+
+func (m *mockFileRepo) GetAnyByChecksum(_ context.Context, checksum string) (*sqlc.File, error) {
+	var found *sqlc.File
+	for _, f := range m.files {
+		if f.Checksum.String == checksum && !f.DeletedAt.Valid && (found == nil || f.ID < found.ID) {
+			found = f
+		}
+	}
+	if found == nil {
+		return nil, apperror.ErrNotFound
+	}
+	return found, nil
+}
+
+func (m *mockFileRepo) ListByStoragePath(_ context.Context, storagePath string) ([]sqlc.ListStoredObjectFilesRow, error) {
+	var rows []sqlc.ListStoredObjectFilesRow
+	add := func(fileID int64, mimeType string, size int64) {
+		if f, ok := m.files[fileID]; ok && !f.DeletedAt.Valid {
+			rows = append(rows, sqlc.ListStoredObjectFilesRow{
+				ID: f.ID, UserID: f.UserID, Visibility: f.Visibility, MimeType: mimeType, Size: size,
+			})
+		}
+	}
+	for _, f := range m.files {
+		if f.StoragePath == storagePath {
+			add(f.ID, f.MimeType, f.Size)
+		}
+	}
+	for _, v := range m.versions {
+		if v.StoragePath == storagePath {
+			add(v.FileID, v.MimeType, v.Size)
+		}
+	}
+	for _, v := range m.variants {
+		if v.StoragePath == storagePath {
+			add(v.FileID, v.MimeType, v.Size)
+		}
+	}
+	return rows, nil
+}
+
+func (m *mockFileRepo) ListByUserID(_ context.Context, userID int64, filter repository.FileFilter, limit, offset int32) ([]sqlc.File, error) {
+	var result []sqlc.File
+	for _, f := range m.files {
+		if f.UserID == userID && m.matches(f, filter) {
 			result = append(result, *f)
 		}
 	}
@@ -280,10 +702,20 @@ func (m *mockFileRepo) ListByUserID(_ context.Context, userID int64, limit, offs
 	return result[start:end], nil
 }
 
-func (m *mockFileRepo) CountByUserID(_ context.Context, userID int64) (int64, error) {
-	var count int64
+func (m *mockFileRepo) ListAllByUserID(_ context.Context, userID int64) ([]sqlc.File, error) {
+	var result []sqlc.File
 	for _, f := range m.files {
 		if f.UserID == userID {
+			result = append(result, *f)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockFileRepo) CountByUserID(_ context.Context, userID int64, filter repository.FileFilter) (int64, error) {
+	var count int64
+	for _, f := range m.files {
+		if f.UserID == userID && m.matches(f, filter) {
 			count++
 		}
 	}
@@ -299,19 +731,84 @@ func (m *mockFileRepo) Delete(_ context.Context, id int64) (*sqlc.File, error) {
 	return f, nil
 }
 
-func (m *mockFileRepo) Restore(_ context.Context, id int64) (*sqlc.File, error) {
+func (m *mockFileRepo) Restore(_ context.Context, id int64, deletedAfter time.Time) (*sqlc.File, error) {
 	f, ok := m.files[id]
-	if !ok {
+	if !ok || !f.DeletedAt.Valid || !f.DeletedAt.Time.After(deletedAfter) {
 		return nil, apperror.ErrNotFound
 	}
 	f.DeletedAt = pgtype.Timestamptz{}
 	return f, nil
 }
 
-func (m *mockFileRepo) AdminList(_ context.Context, limit, offset int32) ([]sqlc.File, error) {
+func (m *mockFileRepo) ListDeletedBefore(_ context.Context, before time.Time, limit int32) ([]int64, error) {
+	var deleted []*sqlc.File
+	for _, f := range m.files {
+		if f.DeletedAt.Valid && f.DeletedAt.Time.Before(before) {
+			deleted = append(deleted, f)
+		}
+	}
+	slices.SortFunc(deleted, func(a, b *sqlc.File) int { return a.DeletedAt.Time.Compare(b.DeletedAt.Time) })
+	ids := make([]int64, 0, len(deleted))
+	for _, f := range deleted[:min(len(deleted), int(limit))] {
+		ids = append(ids, f.ID)
+	}
+	return ids, nil
+}
+
+func (m *mockFileRepo) PurgeDeleted(_ context.Context, id int64, deletedBefore time.Time) ([]string, error) {
+	f, ok := m.files[id]
+	if !ok || !f.DeletedAt.Valid || !f.DeletedAt.Time.Before(deletedBefore) {
+		return nil, nil
+	}
+	return m.Purge(context.Background(), id)
+}
+
+func (m *mockFileRepo) Purge(_ context.Context, id int64) ([]string, error) {
+	f, ok := m.files[id]
+	if !ok {
+		return nil, nil
+	}
+	delete(m.files, id)
+	candidates := []string{f.StoragePath}
+	m.versions = slices.DeleteFunc(m.versions, func(v sqlc.FileVersion) bool {
+		if v.FileID == id {
+			candidates = append(candidates, v.StoragePath)
+		}
+		return v.FileID == id
+	})
+	m.variants = slices.DeleteFunc(m.variants, func(v sqlc.FileVariant) bool {
+		if v.FileID == id {
+			candidates = append(candidates, v.StoragePath)
+		}
+		return v.FileID == id
+	})
+
+	var paths []string
+	for _, p := range candidates {
+		shared := slices.ContainsFunc(slices.Collect(maps.Values(m.files)), func(f *sqlc.File) bool { return f.StoragePath == p }) ||
+			slices.ContainsFunc(m.versions, func(v sqlc.FileVersion) bool { return v.StoragePath == p }) ||
+			slices.ContainsFunc(m.variants, func(v sqlc.FileVariant) bool { return v.StoragePath == p })
+		if !shared && !slices.Contains(paths, p) {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
+
+func (m *mockFileRepo) AdminGet(_ context.Context, id int64) (*sqlc.File, error) {
+	f, ok := m.files[id]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	return f, nil
+}
+
+func (m *mockFileRepo) AdminList(_ context.Context, tag string, limit, offset int32) ([]sqlc.File, error) {
 	all := make([]sqlc.File, 0, len(m.files))
 	for _, f := range m.files {
-		all = append(all, *f)
+		if m.hasTag(f, tag) {
+			all = append(all, *f)
+		}
 	}
 	start := int(offset)
 	if start > len(all) {
@@ -324,182 +821,2351 @@ func (m *mockFileRepo) AdminList(_ context.Context, limit, offset int32) ([]sqlc
 	return all[start:end], nil
 }
 
-func (m *mockFileRepo) AdminCount(_ context.Context) (int64, error) {
-	return int64(len(m.files)), nil
+func (m *mockFileRepo) AdminCount(_ context.Context, tag string) (int64, error) {
+	var count int64
+	for _, f := range m.files {
+		if m.hasTag(f, tag) {
+			count++
+		}
+	}
+	return count, nil
 }
 
-// ---------------------------------------------------------------------------
-// mockPasswordResetRepo
-// ---------------------------------------------------------------------------
-
-type mockPasswordResetRepo struct {
-	tokens map[string]*sqlc.PasswordResetToken
+func (m *mockFileRepo) AdminSearch(_ context.Context, term string, limit int32) ([]sqlc.AdminSearchFilesRow, error) {
+	var rows []sqlc.AdminSearchFilesRow
+	for _, f := range m.files {
+		rank, ok := searchRank(term, f.OriginalName)
+		if !ok {
+			continue
+		}
+		rows = append(rows, sqlc.AdminSearchFilesRow{
+			ID: f.ID, UserID: f.UserID, OriginalName: f.OriginalName, MimeType: f.MimeType, Size: f.Size,
+			DeletedAt: f.DeletedAt, CreatedAt: f.CreatedAt, OwnerEmail: fmt.Sprintf("user%d@example.com", f.UserID), Rank: rank,
+		})
+	}
+	slices.SortFunc(rows, func(a, b sqlc.AdminSearchFilesRow) int {
+		return cmp.Or(cmp.Compare(a.Rank, b.Rank), b.CreatedAt.Time.Compare(a.CreatedAt.Time), cmp.Compare(b.ID, a.ID))
+	})
+	return rows[:min(len(rows), int(limit))], nil
+}
+
+func (m *mockFileRepo) PurgeByUserID(_ context.Context, userID int64) ([]string, error) {
+	var paths []string
+	for id, f := range m.files {
+		if f.UserID == userID {
+			paths = append(paths, f.StoragePath)
+			delete(m.files, id)
+		}
+	}
+	m.variants = slices.DeleteFunc(m.variants, func(v sqlc.FileVariant) bool { return m.files[v.FileID] == nil })
+	m.versions = slices.DeleteFunc(m.versions, func(v sqlc.FileVersion) bool { return m.files[v.FileID] == nil })
+	return paths, nil
+}
+
+func (m *mockFileRepo) ReplaceContent(_ context.Context, params sqlc.ReplaceFileContentParams) (*sqlc.File, error) {
+	f, ok := m.files[params.ID]
+	if !ok || f.DeletedAt.Valid || f.Version != params.Version {
+		return nil, apperror.ErrNotFound
+	}
+	m.versions = append(m.versions, sqlc.FileVersion{
+		ID:              int64(len(m.versions) + 1),
+		FileID:          f.ID,
+		Version:         f.Version,
+		StoragePath:     f.StoragePath,
+		MimeType:        f.MimeType,
+		Size:            f.Size,
+		Checksum:        f.Checksum,
+		EncryptionKeyID: f.EncryptionKeyID,
+		CreatedAt:       f.VersionCreatedAt,
+	})
+	f.StoragePath = params.StoragePath
+	f.MimeType = params.MimeType
+	f.Size = params.Size
+	f.Checksum = params.Checksum
+	f.EncryptionKeyID = params.EncryptionKeyID
+	f.Version++
+	f.VersionCreatedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return f, nil
+}
+
+func (m *mockFileRepo) GetVersion(_ context.Context, fileID int64, version int32) (*sqlc.FileVersion, error) {
+	for i := range m.versions {
+		if m.versions[i].FileID == fileID && m.versions[i].Version == version {
+			return &m.versions[i], nil
+		}
+	}
+	return nil, apperror.ErrNotFound
+}
+
+func (m *mockFileRepo) ListVersions(_ context.Context, fileID int64) ([]sqlc.FileVersion, error) {
+	var result []sqlc.FileVersion
+	for _, v := range slices.Backward(m.versions) {
+		if v.FileID == fileID {
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockFileRepo) ListVersionPathsByUserID(_ context.Context, userID int64) ([]string, error) {
+	var paths []string
+	for _, v := range m.versions {
+		if f := m.files[v.FileID]; f != nil && f.UserID == userID {
+			paths = append(paths, v.StoragePath)
+		}
+	}
+	return paths, nil
+}
+
+func (m *mockFileRepo) CreateVariant(_ context.Context, params sqlc.CreateFileVariantParams) (*sqlc.FileVariant, error) {
+	if _, ok := m.files[params.FileID]; !ok {
+		return nil, apperror.ErrNotFound
+	}
+	v := sqlc.FileVariant{
+		ID:          int64(len(m.variants) + 1),
+		FileID:      params.FileID,
+		Name:        params.Name,
+		StoragePath: params.StoragePath,
+		MimeType:    params.MimeType,
+		Width:       params.Width,
+		Height:      params.Height,
+		Size:        params.Size,
+	}
+	m.variants = append(m.variants, v)
+	return &v, nil
+}
+
+func (m *mockFileRepo) CopyVariants(_ context.Context, sourceID, targetID int64) (int64, error) {
+	var n int64
+	for _, v := range m.variants {
+		if v.FileID == sourceID {
+			v.ID = int64(len(m.variants) + 1)
+			v.FileID = targetID
+			m.variants = append(m.variants, v)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *mockFileRepo) DeleteVariants(_ context.Context, fileID int64) ([]string, error) {
+	var removed []sqlc.FileVariant
+	m.variants = slices.DeleteFunc(m.variants, func(v sqlc.FileVariant) bool {
+		if v.FileID == fileID {
+			removed = append(removed, v)
+			return true
+		}
+		return false
+	})
+	var paths []string
+	for _, r := range removed {
+		if !slices.ContainsFunc(m.variants, func(v sqlc.FileVariant) bool { return v.StoragePath == r.StoragePath }) {
+			paths = append(paths, r.StoragePath)
+		}
+	}
+	return paths, nil
+}
+
+func (m *mockFileRepo) ListVariantsByFileIDs(_ context.Context, fileIDs []int64) ([]sqlc.FileVariant, error) {
+	var result []sqlc.FileVariant
+	for _, v := range m.variants {
+		if slices.Contains(fileIDs, v.FileID) {
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockFileRepo) ListVariantPathsByUserID(_ context.Context, userID int64) ([]string, error) {
+	var paths []string
+	for _, v := range m.variants {
+		if f := m.files[v.FileID]; f != nil && f.UserID == userID {
+			paths = append(paths, v.StoragePath)
+		}
+	}
+	return paths, nil
+}
+
+func (m *mockFileRepo) ListStorageReferences(_ context.Context) ([]sqlc.ListStorageReferencesRow, error) {
+	var refs []sqlc.ListStorageReferencesRow
+	for _, id := range slices.Sorted(maps.Keys(m.files)) {
+		refs = append(refs, sqlc.ListStorageReferencesRow{Kind: "file", OwnerID: id, StoragePath: m.files[id].StoragePath})
+	}
+	for _, v := range m.versions {
+		refs = append(refs, sqlc.ListStorageReferencesRow{Kind: "file_version", OwnerID: v.FileID, StoragePath: v.StoragePath})
+	}
+	for _, v := range m.variants {
+		refs = append(refs, sqlc.ListStorageReferencesRow{Kind: "file_variant", OwnerID: v.FileID, StoragePath: v.StoragePath})
+	}
+	return refs, nil
+}
+
+func (m *mockFileRepo) ListStoredObjects(_ context.Context) ([]sqlc.ListStoredObjectsRow, error) {
+	var objects []sqlc.ListStoredObjectsRow
+	for _, f := range m.files {
+		objects = append(objects, sqlc.ListStoredObjectsRow{
+			Kind: "file", StoragePath: f.StoragePath, MimeType: f.MimeType, Size: f.Size, Checksum: f.Checksum,
+		})
+	}
+	for _, v := range m.versions {
+		objects = append(objects, sqlc.ListStoredObjectsRow{
+			Kind: "file_version", StoragePath: v.StoragePath, MimeType: v.MimeType, Size: v.Size, Checksum: v.Checksum,
+		})
+	}
+	for _, v := range m.variants {
+		objects = append(objects, sqlc.ListStoredObjectsRow{
+			Kind: "file_variant", StoragePath: v.StoragePath, MimeType: v.MimeType, Size: v.Size,
+		})
+	}
+	slices.SortFunc(objects, func(a, b sqlc.ListStoredObjectsRow) int {
+		return strings.Compare(a.StoragePath, b.StoragePath)
+	})
+	return objects, nil
+}
+
+func (m *mockFileRepo) MoveStoredObject(_ context.Context, oldPath, newPath string, keyID pgtype.Text) error {
+	for _, f := range m.files {
+		if f.StoragePath == oldPath {
+			f.StoragePath, f.EncryptionKeyID = newPath, keyID
+		}
+	}
+	for i := range m.versions {
+		if m.versions[i].StoragePath == oldPath {
+			m.versions[i].StoragePath, m.versions[i].EncryptionKeyID = newPath, keyID
+		}
+	}
+	for i := range m.variants {
+		if m.variants[i].StoragePath == oldPath {
+			m.variants[i].StoragePath = newPath
+		}
+	}
+	return nil
+}
+
+func (m *mockFileRepo) ListSharedStoragePaths(_ context.Context, userID int64, paths []string) ([]string, error) {
+	others := make(map[string]bool)
+	for _, f := range m.files {
+		if f.UserID != userID {
+			others[f.StoragePath] = true
+		}
+	}
+	for _, v := range m.versions {
+		if f, ok := m.files[v.FileID]; ok && f.UserID != userID {
+			others[v.StoragePath] = true
+		}
+	}
+	for _, v := range m.variants {
+		if f, ok := m.files[v.FileID]; ok && f.UserID != userID {
+			others[v.StoragePath] = true
+		}
+	}
+	var shared []string
+	for _, p := range paths {
+		if others[p] && !slices.Contains(shared, p) {
+			shared = append(shared, p)
+		}
+	}
+	return shared, nil
+}
+
+func (m *mockFileRepo) Move(_ context.Context, params sqlc.MoveFileParams) (*sqlc.File, error) {
+	f, ok := m.files[params.ID]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	if params.FolderID.Valid {
+		if m.folders == nil {
+			return nil, apperror.ErrNotFound
+		}
+		folder, ok := m.folders.folders[params.FolderID.Int64]
+		if !ok || folder.UserID != f.UserID {
+			return nil, apperror.ErrNotFound
+		}
+	}
+	f.FolderID = params.FolderID
+	return f, nil
+}
+
+func (m *mockFileRepo) Rename(_ context.Context, id int64, name string) (*sqlc.File, error) {
+	f, ok := m.files[id]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	f.OriginalName = name
+	return f, nil
+}
+
+func (m *mockFileRepo) SetVisibility(_ context.Context, id int64, visibility string) (*sqlc.File, error) {
+	f, ok := m.files[id]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	f.Visibility = visibility
+	return f, nil
+}
+
+func (m *mockFileRepo) GrantPermission(_ context.Context, params sqlc.GrantFilePermissionParams) (*sqlc.FilePermission, error) {
+	for i := range m.permissions {
+		if m.permissions[i].FileID == params.FileID && m.permissions[i].UserID == params.UserID {
+			return &m.permissions[i], nil
+		}
+	}
+	p := sqlc.FilePermission{
+		FileID:    params.FileID,
+		UserID:    params.UserID,
+		GrantedBy: params.GrantedBy,
+		CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	m.permissions = append(m.permissions, p)
+	return &p, nil
+}
+
+func (m *mockFileRepo) ListPermissions(_ context.Context, fileID int64) ([]sqlc.ListFilePermissionsRow, error) {
+	var result []sqlc.ListFilePermissionsRow
+	for _, p := range m.permissions {
+		if p.FileID == fileID {
+			result = append(result, sqlc.ListFilePermissionsRow{
+				FileID:    p.FileID,
+				UserID:    p.UserID,
+				GrantedBy: p.GrantedBy,
+				CreatedAt: p.CreatedAt,
+			})
+		}
+	}
+	return result, nil
+}
+
+func (m *mockFileRepo) HasPermission(_ context.Context, fileID, userID int64) (bool, error) {
+	return slices.ContainsFunc(m.permissions, func(p sqlc.FilePermission) bool {
+		return p.FileID == fileID && p.UserID == userID
+	}), nil
+}
+
+func (m *mockFileRepo) RevokePermission(_ context.Context, fileID, userID int64) error {
+	n := len(m.permissions)
+	m.permissions = slices.DeleteFunc(m.permissions, func(p sqlc.FilePermission) bool {
+		return p.FileID == fileID && p.UserID == userID
+	})
+	if len(m.permissions) == n {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+func (m *mockFileRepo) SetTags(_ context.Context, fileID int64, tags []string) error {
+	m.tags = slices.DeleteFunc(m.tags, func(t sqlc.FileTag) bool { return t.FileID == fileID })
+	for _, tag := range tags {
+		m.tags = append(m.tags, sqlc.FileTag{FileID: fileID, Tag: tag})
+	}
+	return nil
+}
+
+func (m *mockFileRepo) ListTagsByFileIDs(_ context.Context, fileIDs []int64) ([]sqlc.FileTag, error) {
+	var result []sqlc.FileTag
+	for _, t := range m.tags {
+		if slices.Contains(fileIDs, t.FileID) {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+// ---------------------------------------------------------------------------
+// mockFolderRepo
+// ---------------------------------------------------------------------------
+
+type mockFolderRepo struct {
+	folders map[int64]*sqlc.Folder
+	files   *mockFileRepo // counted as children when set
+	nextID  int64
+}
+
+func newMockFolderRepo() *mockFolderRepo {
+	return &mockFolderRepo{folders: make(map[int64]*sqlc.Folder), nextID: 1}
+}
+
+// nameTaken mirrors the unique index on (user_id, parent_id, name).
+func (m *mockFolderRepo) nameTaken(userID int64, parentID pgtype.Int8, name string, exceptID int64) bool {
+	for _, f := range m.folders {
+		if f.ID != exceptID && f.UserID == userID && f.ParentID == parentID && f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *mockFolderRepo) Create(_ context.Context, params sqlc.CreateFolderParams) (*sqlc.Folder, error) {
+	if m.nameTaken(params.UserID, params.ParentID, params.Name, 0) {
+		return nil, &pgconn.PgError{Code: "23505"}
+	}
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	f := &sqlc.Folder{
+		ID:        m.nextID,
+		UserID:    params.UserID,
+		ParentID:  params.ParentID,
+		Name:      params.Name,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	m.folders[f.ID] = f
+	m.nextID++
+	return f, nil
+}
+
+func (m *mockFolderRepo) GetByID(_ context.Context, id int64) (*sqlc.Folder, error) {
+	f, ok := m.folders[id]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	return f, nil
+}
+
+func (m *mockFolderRepo) ListByParentID(_ context.Context, userID int64, parentID pgtype.Int8) ([]sqlc.Folder, error) {
+	var result []sqlc.Folder
+	for _, f := range m.folders {
+		if f.UserID == userID && f.ParentID == parentID {
+			result = append(result, *f)
+		}
+	}
+	slices.SortFunc(result, func(a, b sqlc.Folder) int { return strings.Compare(a.Name, b.Name) })
+	return result, nil
+}
+
+func (m *mockFolderRepo) Rename(_ context.Context, id int64, name string) (*sqlc.Folder, error) {
+	f, ok := m.folders[id]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	if m.nameTaken(f.UserID, f.ParentID, name, id) {
+		return nil, &pgconn.PgError{Code: "23505"}
+	}
+	f.Name = name
+	return f, nil
+}
+
+func (m *mockFolderRepo) Move(_ context.Context, id int64, parentID pgtype.Int8) (*sqlc.Folder, error) {
+	f, ok := m.folders[id]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	if m.nameTaken(f.UserID, parentID, f.Name, id) {
+		return nil, &pgconn.PgError{Code: "23505"}
+	}
+	f.ParentID = parentID
+	return f, nil
+}
+
+func (m *mockFolderRepo) IsAncestor(_ context.Context, ancestorID, folderID int64) (bool, error) {
+	for id := folderID; ; {
+		if id == ancestorID {
+			return true, nil
+		}
+		f, ok := m.folders[id]
+		if !ok || !f.ParentID.Valid {
+			return false, nil
+		}
+		id = f.ParentID.Int64
+	}
+}
+
+func (m *mockFolderRepo) CountChildren(_ context.Context, id int64) (int64, error) {
+	var count int64
+	for _, f := range m.folders {
+		if f.ParentID.Valid && f.ParentID.Int64 == id {
+			count++
+		}
+	}
+	if m.files != nil {
+		for _, f := range m.files.files {
+			if f.FolderID.Valid && f.FolderID.Int64 == id && !f.DeletedAt.Valid {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+func (m *mockFolderRepo) Delete(_ context.Context, id int64) error {
+	if _, ok := m.folders[id]; !ok {
+		return apperror.ErrNotFound
+	}
+	delete(m.folders, id)
+	return nil
+}
+
+func (m *mockFolderRepo) DeleteByUserID(_ context.Context, userID int64) error {
+	maps.DeleteFunc(m.folders, func(_ int64, f *sqlc.Folder) bool { return f.UserID == userID })
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// mockPasswordResetRepo
+// ---------------------------------------------------------------------------
+
+type mockPasswordResetRepo struct {
+	tokens map[string]*sqlc.PasswordResetToken
+	nextID int64
+}
+
+func newMockPasswordResetRepo() *mockPasswordResetRepo {
+	return &mockPasswordResetRepo{tokens: make(map[string]*sqlc.PasswordResetToken), nextID: 1}
+}
+
+func (m *mockPasswordResetRepo) Create(_ context.Context, params sqlc.CreatePasswordResetTokenParams) (*sqlc.PasswordResetToken, error) {
+	t := &sqlc.PasswordResetToken{
+		ID:        m.nextID,
+		UserID:    params.UserID,
+		Token:     params.Token,
+		ExpiresAt: params.ExpiresAt,
+		CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	m.tokens[params.Token] = t
+	m.nextID++
+	return t, nil
+}
+
+func (m *mockPasswordResetRepo) GetByToken(_ context.Context, token string) (*sqlc.PasswordResetToken, error) {
+	t, ok := m.tokens[token]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	return t, nil
+}
+
+func (m *mockPasswordResetRepo) GetByTokenForUpdate(ctx context.Context, token string) (*sqlc.PasswordResetToken, error) {
+	return m.GetByToken(ctx, token)
+}
+
+func (m *mockPasswordResetRepo) Delete(_ context.Context, token string) error {
+	delete(m.tokens, token)
+	return nil
+}
+
+func (m *mockPasswordResetRepo) DeleteByUserID(_ context.Context, userID int64) error {
+	for k, v := range m.tokens {
+		if v.UserID == userID {
+			delete(m.tokens, k)
+		}
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// mockEmailChangeRepo
+// ---------------------------------------------------------------------------
+
+type mockEmailChangeRepo struct {
+	tokens map[string]*sqlc.EmailChangeToken
+	nextID int64
+}
+
+func newMockEmailChangeRepo() *mockEmailChangeRepo {
+	return &mockEmailChangeRepo{tokens: make(map[string]*sqlc.EmailChangeToken), nextID: 1}
+}
+
+func (m *mockEmailChangeRepo) Create(_ context.Context, params sqlc.CreateEmailChangeTokenParams) (*sqlc.EmailChangeToken, error) {
+	t := &sqlc.EmailChangeToken{
+		ID:        m.nextID,
+		UserID:    params.UserID,
+		NewEmail:  params.NewEmail,
+		Token:     params.Token,
+		ExpiresAt: params.ExpiresAt,
+		CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	m.tokens[params.Token] = t
+	m.nextID++
+	return t, nil
+}
+
+func (m *mockEmailChangeRepo) GetByToken(_ context.Context, token string) (*sqlc.EmailChangeToken, error) {
+	t, ok := m.tokens[token]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	return t, nil
+}
+
+func (m *mockEmailChangeRepo) Delete(_ context.Context, token string) error {
+	delete(m.tokens, token)
+	return nil
+}
+
+func (m *mockEmailChangeRepo) DeleteByUserID(_ context.Context, userID int64) error {
+	for k, v := range m.tokens {
+		if v.UserID == userID {
+			delete(m.tokens, k)
+		}
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// mockUserActivityRepo
+// ---------------------------------------------------------------------------
+
+type mockUserActivityRepo struct {
+	activities []*sqlc.UserActivity
+}
+
+func newMockUserActivityRepo() *mockUserActivityRepo {
+	return &mockUserActivityRepo{}
+}
+
+func (m *mockUserActivityRepo) Create(_ context.Context, params sqlc.CreateUserActivityParams) (*sqlc.UserActivity, error) {
+	a := &sqlc.UserActivity{
+		ID:        int64(len(m.activities) + 1),
+		UserID:    params.UserID,
+		ActorID:   params.ActorID,
+		Action:    params.Action,
+		Details:   params.Details,
+		IpAddress: params.IpAddress,
+		CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	m.activities = append(m.activities, a)
+	return a, nil
+}
+
+func (m *mockUserActivityRepo) ListByUserID(_ context.Context, userID int64, limit, offset int32) ([]sqlc.UserActivity, error) {
+	var result []sqlc.UserActivity
+	for i := len(m.activities) - 1; i >= 0; i-- {
+		if m.activities[i].UserID == userID {
+			result = append(result, *m.activities[i])
+		}
+	}
+	if int(offset) >= len(result) {
+		return nil, nil
+	}
+	return result[offset:min(int(offset+limit), len(result))], nil
+}
+
+func (m *mockUserActivityRepo) CountByUserID(_ context.Context, userID int64) (int64, error) {
+	var n int64
+	for _, a := range m.activities {
+		if a.UserID == userID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *mockUserActivityRepo) AnonymizeByUserID(_ context.Context, userID int64) error {
+	for _, a := range m.activities {
+		if a.UserID == userID {
+			a.IpAddress = ""
+		}
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// mockEmailVerificationRepo
+// ---------------------------------------------------------------------------
+
+type mockEmailVerificationRepo struct {
+	tokens map[string]*sqlc.EmailVerificationToken
+	nextID int64
+}
+
+func newMockEmailVerificationRepo() *mockEmailVerificationRepo {
+	return &mockEmailVerificationRepo{tokens: make(map[string]*sqlc.EmailVerificationToken), nextID: 1}
+}
+
+func (m *mockEmailVerificationRepo) Create(_ context.Context, params sqlc.CreateEmailVerificationTokenParams) (*sqlc.EmailVerificationToken, error) {
+	t := &sqlc.EmailVerificationToken{
+		ID:        m.nextID,
+		UserID:    params.UserID,
+		Token:     params.Token,
+		ExpiresAt: params.ExpiresAt,
+		CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	m.tokens[params.Token] = t
+	m.nextID++
+	return t, nil
+}
+
+func (m *mockEmailVerificationRepo) GetByToken(_ context.Context, token string) (*sqlc.EmailVerificationToken, error) {
+	t, ok := m.tokens[token]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	return t, nil
+}
+
+func (m *mockEmailVerificationRepo) Delete(_ context.Context, token string) error {
+	delete(m.tokens, token)
+	return nil
+}
+
+func (m *mockEmailVerificationRepo) DeleteByUserID(_ context.Context, userID int64) error {
+	for k, v := range m.tokens {
+		if v.UserID == userID {
+			delete(m.tokens, k)
+		}
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// mockAccountDeletionRepo
+// ---------------------------------------------------------------------------
+
+type mockAccountDeletionRepo struct {
+	requests map[int64]*sqlc.AccountDeletionRequest // keyed by user ID
+	nextID   int64
+}
+
+func newMockAccountDeletionRepo() *mockAccountDeletionRepo {
+	return &mockAccountDeletionRepo{requests: make(map[int64]*sqlc.AccountDeletionRequest), nextID: 1}
+}
+
+func (m *mockAccountDeletionRepo) Create(_ context.Context, params sqlc.CreateAccountDeletionRequestParams) (*sqlc.AccountDeletionRequest, error) {
+	r := &sqlc.AccountDeletionRequest{
+		ID:           m.nextID,
+		UserID:       params.UserID,
+		Token:        params.Token,
+		ScheduledFor: params.ScheduledFor,
+		CreatedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	m.requests[params.UserID] = r
+	m.nextID++
+	return r, nil
+}
+
+func (m *mockAccountDeletionRepo) GetByUserID(_ context.Context, userID int64) (*sqlc.AccountDeletionRequest, error) {
+	r, ok := m.requests[userID]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	return r, nil
+}
+
+func (m *mockAccountDeletionRepo) DeleteByToken(_ context.Context, token string) (*sqlc.AccountDeletionRequest, error) {
+	for userID, r := range m.requests {
+		if r.Token == token {
+			delete(m.requests, userID)
+			return r, nil
+		}
+	}
+	return nil, apperror.ErrNotFound
+}
+
+func (m *mockAccountDeletionRepo) DeleteByUserID(_ context.Context, userID int64) error {
+	delete(m.requests, userID)
+	return nil
+}
+
+func (m *mockAccountDeletionRepo) ListDue(_ context.Context, limit int32) ([]sqlc.AccountDeletionRequest, error) {
+	var result []sqlc.AccountDeletionRequest
+	for _, r := range m.requests {
+		if !r.ScheduledFor.Time.After(time.Now()) && len(result) < int(limit) {
+			result = append(result, *r)
+		}
+	}
+	return result, nil
+}
+
+// ---------------------------------------------------------------------------
+// mockWebAuthnCredentialRepo
+// ---------------------------------------------------------------------------
+
+type mockWebAuthnCredentialRepo struct {
+	creds  map[string]*sqlc.WebauthnCredential
+	nextID int64
+}
+
+func newMockWebAuthnCredentialRepo() *mockWebAuthnCredentialRepo {
+	return &mockWebAuthnCredentialRepo{creds: make(map[string]*sqlc.WebauthnCredential), nextID: 1}
+}
+
+func (m *mockWebAuthnCredentialRepo) Create(_ context.Context, params sqlc.CreateWebAuthnCredentialParams) (*sqlc.WebauthnCredential, error) {
+	c := &sqlc.WebauthnCredential{
+		ID:           m.nextID,
+		UserID:       params.UserID,
+		CredentialID: params.CredentialID,
+		Credential:   params.Credential,
+		Name:         params.Name,
+		CreatedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	m.creds[string(params.CredentialID)] = c
+	m.nextID++
+	return c, nil
+}
+
+func (m *mockWebAuthnCredentialRepo) GetByCredentialID(_ context.Context, credentialID []byte) (*sqlc.WebauthnCredential, error) {
+	c, ok := m.creds[string(credentialID)]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	return c, nil
+}
+
+func (m *mockWebAuthnCredentialRepo) ListByUserID(_ context.Context, userID int64) ([]sqlc.WebauthnCredential, error) {
+	var result []sqlc.WebauthnCredential
+	for _, c := range m.creds {
+		if c.UserID == userID {
+			result = append(result, *c)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockWebAuthnCredentialRepo) DeleteByUserID(_ context.Context, userID int64) error {
+	maps.DeleteFunc(m.creds, func(_ string, c *sqlc.WebauthnCredential) bool { return c.UserID == userID })
+	return nil
+}
+
+func (m *mockWebAuthnCredentialRepo) UpdateUsage(_ context.Context, credentialID, credential []byte) error {
+	c, ok := m.creds[string(credentialID)]
+	if !ok {
+		return apperror.ErrNotFound
+	}
+	c.Credential = credential
+	c.LastUsedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// mockLoginEventRepo
+// ---------------------------------------------------------------------------
+
+type mockLoginEventRepo struct {
+	events []sqlc.LoginEvent
+	nextID int64
+}
+
+func newMockLoginEventRepo() *mockLoginEventRepo {
+	return &mockLoginEventRepo{nextID: 1}
+}
+
+func (m *mockLoginEventRepo) Create(_ context.Context, params sqlc.CreateLoginEventParams) (*sqlc.LoginEvent, error) {
+	e := sqlc.LoginEvent{
+		ID:            m.nextID,
+		UserID:        params.UserID,
+		Email:         params.Email,
+		Method:        params.Method,
+		Success:       params.Success,
+		FailureReason: params.FailureReason,
+		IpAddress:     params.IpAddress,
+		UserAgent:     params.UserAgent,
+		CreatedAt:     pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	m.events = append(m.events, e)
+	m.nextID++
+	return &e, nil
+}
+
+func (m *mockLoginEventRepo) ListByUserID(_ context.Context, userID int64, limit, offset int32) ([]sqlc.LoginEvent, error) {
+	var result []sqlc.LoginEvent
+	for i := len(m.events) - 1; i >= 0; i-- {
+		if m.events[i].UserID.Int64 == userID {
+			result = append(result, m.events[i])
+		}
+	}
+	start := int(offset)
+	if start > len(result) {
+		return nil, nil
+	}
+	end := start + int(limit)
+	if end > len(result) {
+		end = len(result)
+	}
+	return result[start:end], nil
+}
+
+func (m *mockLoginEventRepo) CountByUserID(_ context.Context, userID int64) (int64, error) {
+	var count int64
+	for _, e := range m.events {
+		if e.UserID.Int64 == userID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *mockLoginEventRepo) AnonymizeByUserID(_ context.Context, userID int64) error {
+	for i := range m.events {
+		if m.events[i].UserID.Int64 == userID {
+			m.events[i].Email = ""
+			m.events[i].IpAddress = ""
+			m.events[i].UserAgent = ""
+		}
+	}
+	return nil
+}
+
+func (m *mockLoginEventRepo) GetDeviceHistory(_ context.Context, userID int64, userAgent string) (hasLogins, knownDevice bool, err error) {
+	for _, e := range m.events {
+		if e.UserID.Int64 != userID || !e.Success {
+			continue
+		}
+		hasLogins = true
+		if e.UserAgent == userAgent {
+			knownDevice = true
+		}
+	}
+	return hasLogins, knownDevice, nil
+}
+
+// ---------------------------------------------------------------------------
+// mockDataExportRepo
+// ---------------------------------------------------------------------------
+
+type mockDataExportRepo struct {
+	exports []*sqlc.DataExport
+	nextID  int64
+}
+
+func newMockDataExportRepo() *mockDataExportRepo {
+	return &mockDataExportRepo{nextID: 1}
+}
+
+func (m *mockDataExportRepo) Create(_ context.Context, userID int64) (*sqlc.DataExport, error) {
+	e := &sqlc.DataExport{
+		ID:        m.nextID,
+		UserID:    pgtype.Int8{Int64: userID, Valid: true},
+		Status:    dto.DataExportPending,
+		CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	m.exports = append(m.exports, e)
+	m.nextID++
+	return e, nil
+}
+
+func (m *mockDataExportRepo) get(id int64) (*sqlc.DataExport, error) {
+	for _, e := range m.exports {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return nil, apperror.ErrNotFound
+}
+
+func (m *mockDataExportRepo) GetLatestByUserID(_ context.Context, userID int64) (*sqlc.DataExport, error) {
+	for i := len(m.exports) - 1; i >= 0; i-- {
+		if m.exports[i].UserID.Int64 == userID {
+			return m.exports[i], nil
+		}
+	}
+	return nil, apperror.ErrNotFound
+}
+
+func (m *mockDataExportRepo) MarkReady(_ context.Context, id int64, storagePath string, size int64, expiresAt time.Time) (*sqlc.DataExport, error) {
+	e, err := m.get(id)
+	if err != nil {
+		return nil, err
+	}
+	e.Status = dto.DataExportReady
+	e.StoragePath = pgtype.Text{String: storagePath, Valid: true}
+	e.Size = pgtype.Int8{Int64: size, Valid: true}
+	e.ExpiresAt = pgtype.Timestamptz{Time: expiresAt, Valid: true}
+	e.CompletedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return e, nil
+}
+
+func (m *mockDataExportRepo) MarkFailed(_ context.Context, id int64, expiresAt time.Time) error {
+	e, err := m.get(id)
+	if err != nil {
+		return err
+	}
+	e.Status = dto.DataExportFailed
+	e.ExpiresAt = pgtype.Timestamptz{Time: expiresAt, Valid: true}
+	e.CompletedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (m *mockDataExportRepo) ListExpired(_ context.Context, limit int32) ([]sqlc.DataExport, error) {
+	var result []sqlc.DataExport
+	for _, e := range m.exports {
+		if e.ExpiresAt.Valid && !e.ExpiresAt.Time.After(time.Now()) && len(result) < int(limit) {
+			result = append(result, *e)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockDataExportRepo) Delete(_ context.Context, id int64) error {
+	m.exports = slices.DeleteFunc(m.exports, func(e *sqlc.DataExport) bool { return e.ID == id })
+	return nil
+}
+
+func (m *mockDataExportRepo) DeleteByUserID(_ context.Context, userID int64) ([]string, error) {
+	var paths []string
+	m.exports = slices.DeleteFunc(m.exports, func(e *sqlc.DataExport) bool {
+		if e.UserID.Int64 != userID {
+			return false
+		}
+		if e.StoragePath.Valid {
+			paths = append(paths, e.StoragePath.String)
+		}
+		return true
+	})
+	return paths, nil
+}
+
+// ---------------------------------------------------------------------------
+// mockErasureAuditRepo
+// ---------------------------------------------------------------------------
+
+type mockAuditLogRepo struct {
+	entries []sqlc.AuditLog
+}
+
+func newMockAuditLogRepo() *mockAuditLogRepo {
+	return &mockAuditLogRepo{}
+}
+
+// actions returns the actions of the recorded entries, oldest first.
+func (m *mockAuditLogRepo) actions() []string {
+	actions := make([]string, len(m.entries))
+	for i, e := range m.entries {
+		actions[i] = e.Action
+	}
+	return actions
+}
+
+func (m *mockAuditLogRepo) Create(_ context.Context, params sqlc.CreateAuditLogParams) (*sqlc.AuditLog, error) {
+	a := sqlc.AuditLog{
+		ID:         int64(len(m.entries) + 1),
+		ActorID:    params.ActorID,
+		Action:     params.Action,
+		TargetType: params.TargetType,
+		TargetID:   params.TargetID,
+		BeforeData: params.BeforeData,
+		AfterData:  params.AfterData,
+		IpAddress:  params.IpAddress,
+		RequestID:  params.RequestID,
+		CreatedAt:  pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	m.entries = append(m.entries, a)
+	return &a, nil
+}
+
+func (m *mockAuditLogRepo) matching(filter repository.AuditLogFilter) []sqlc.AuditLog {
+	var result []sqlc.AuditLog
+	for i := len(m.entries) - 1; i >= 0; i-- {
+		a := m.entries[i]
+		if (filter.ActorID != 0 && a.ActorID != filter.ActorID) ||
+			(filter.Action != "" && a.Action != filter.Action) ||
+			(filter.TargetType != "" && a.TargetType != filter.TargetType) ||
+			(filter.TargetID != 0 && a.TargetID != filter.TargetID) ||
+			(!filter.CreatedAfter.IsZero() && a.CreatedAt.Time.Before(filter.CreatedAfter)) ||
+			(!filter.CreatedBefore.IsZero() && !a.CreatedAt.Time.Before(filter.CreatedBefore)) {
+			continue
+		}
+		result = append(result, a)
+	}
+	return result
+}
+
+func (m *mockAuditLogRepo) List(_ context.Context, filter repository.AuditLogFilter, limit, offset int32) ([]sqlc.AuditLog, error) {
+	result := m.matching(filter)
+	if int(offset) >= len(result) {
+		return nil, nil
+	}
+	return result[offset:min(int(offset+limit), len(result))], nil
+}
+
+func (m *mockAuditLogRepo) Count(_ context.Context, filter repository.AuditLogFilter) (int64, error) {
+	return int64(len(m.matching(filter))), nil
+}
+
+func (m *mockAuditLogRepo) ListAfter(_ context.Context, filter repository.AuditLogFilter, afterID int64, limit int32) ([]sqlc.AuditLog, error) {
+	var result []sqlc.AuditLog
+	for _, a := range slices.Backward(m.matching(filter)) {
+		if a.ID > afterID && len(result) < int(limit) {
+			result = append(result, a)
+		}
+	}
+	return result, nil
+}
+
+type mockFeatureFlagRepo struct {
+	flags     []sqlc.FeatureFlag
+	nextID    int64
+	listCalls int
+}
+
+func newMockFeatureFlagRepo() *mockFeatureFlagRepo {
+	return &mockFeatureFlagRepo{nextID: 1}
+}
+
+func (m *mockFeatureFlagRepo) Create(_ context.Context, params sqlc.CreateFeatureFlagParams) (*sqlc.FeatureFlag, error) {
+	for _, f := range m.flags {
+		if f.Name == params.Name {
+			return nil, &pgconn.PgError{Code: "23505"}
+		}
+	}
+	f := sqlc.FeatureFlag{ID: m.nextID, Name: params.Name, Description: params.Description, Enabled: params.Enabled}
+	m.nextID++
+	m.flags = append(m.flags, f)
+	return &f, nil
+}
+
+func (m *mockFeatureFlagRepo) List(_ context.Context) ([]sqlc.FeatureFlag, error) {
+	m.listCalls++
+	return slices.Clone(m.flags), nil
+}
+
+func (m *mockFeatureFlagRepo) Update(_ context.Context, params sqlc.UpdateFeatureFlagParams) (*sqlc.FeatureFlag, error) {
+	for i := range m.flags {
+		f := &m.flags[i]
+		if f.ID != params.ID {
+			continue
+		}
+		if params.Description.Valid {
+			f.Description = params.Description.String
+		}
+		if params.Enabled.Valid {
+			f.Enabled = params.Enabled.Bool
+		}
+		updated := *f
+		return &updated, nil
+	}
+	return nil, apperror.ErrNotFound
+}
+
+func (m *mockFeatureFlagRepo) Delete(_ context.Context, id int64) error {
+	n := len(m.flags)
+	m.flags = slices.DeleteFunc(m.flags, func(f sqlc.FeatureFlag) bool { return f.ID == id })
+	if len(m.flags) == n {
+		return apperror.ErrNotFound
+	}
+	return nil
+}
+
+type mockSettingRepo struct {
+	settings  []sqlc.Setting
+	nextID    int64
+	listCalls int
+	listErr   error
+}
+
+func newMockSettingRepo() *mockSettingRepo {
+	return &mockSettingRepo{nextID: 1}
+}
+
+func (m *mockSettingRepo) List(_ context.Context) ([]sqlc.Setting, error) {
+	m.listCalls++
+	if m.listErr != nil {
+		return nil, m.listErr
+	}
+	return slices.Clone(m.settings), nil
+}
+
+func (m *mockSettingRepo) Upsert(_ context.Context, key string, value []byte) (*sqlc.Setting, error) {
+	for i := range m.settings {
+		if m.settings[i].Key == key {
+			m.settings[i].Value = value
+			updated := m.settings[i]
+			return &updated, nil
+		}
+	}
+	setting := sqlc.Setting{ID: m.nextID, Key: key, Value: value}
+	m.nextID++
+	m.settings = append(m.settings, setting)
+	return &setting, nil
+}
+
+// staticSettings serves fixed runtime settings.
+type staticSettings dto.Settings
+
+func (s staticSettings) Current(context.Context) dto.Settings {
+	return dto.Settings(s)
+}
+
+// openSettings lets anyone register.
+var openSettings = staticSettings{RegistrationEnabled: true, MaxUploadSize: 10 << 20}
+
+type mockBroadcastRepo struct {
+	broadcasts []sqlc.Broadcast
+	recipients []sqlc.ListBroadcastRecipientsRow // every user in the segment, in ID order
+	listErr    error
+	progress   [][2]int32 // sent and failed counts of each UpdateProgress call
+}
+
+func newMockBroadcastRepo() *mockBroadcastRepo {
+	return &mockBroadcastRepo{}
+}
+
+func (m *mockBroadcastRepo) Create(_ context.Context, params sqlc.CreateBroadcastParams) (*sqlc.Broadcast, error) {
+	b := sqlc.Broadcast{
+		ID:             int64(len(m.broadcasts) + 1),
+		SenderID:       params.SenderID,
+		Subject:        params.Subject,
+		Body:           params.Body,
+		Segment:        params.Segment,
+		Status:         dto.BroadcastSending,
+		RecipientCount: params.RecipientCount,
+		CreatedAt:      pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	m.broadcasts = append(m.broadcasts, b)
+	return &b, nil
+}
+
+func (m *mockBroadcastRepo) UpdateProgress(_ context.Context, id int64, sent, failed int32) error {
+	m.progress = append(m.progress, [2]int32{sent, failed})
+	b := &m.broadcasts[id-1]
+	b.SentCount, b.FailedCount = sent, failed
+	return nil
+}
+
+func (m *mockBroadcastRepo) Finish(_ context.Context, id int64, status string, sent, failed int32) error {
+	b := &m.broadcasts[id-1]
+	b.Status, b.SentCount, b.FailedCount = status, sent, failed
+	b.CompletedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (m *mockBroadcastRepo) List(_ context.Context, limit, offset int32) ([]sqlc.Broadcast, error) {
+	var out []sqlc.Broadcast
+	for i := len(m.broadcasts) - 1; i >= 0; i-- {
+		out = append(out, m.broadcasts[i])
+	}
+	if int(offset) >= len(out) {
+		return nil, nil
+	}
+	return out[offset:min(int(offset+limit), len(out))], nil
+}
+
+func (m *mockBroadcastRepo) Count(_ context.Context) (int64, error) {
+	return int64(len(m.broadcasts)), nil
+}
+
+func (m *mockBroadcastRepo) ListRecipients(
+	_ context.Context,
+	_ repository.BroadcastSegment,
+	afterID int64,
+	limit int32,
+) ([]sqlc.ListBroadcastRecipientsRow, error) {
+	if m.listErr != nil {
+		return nil, m.listErr
+	}
+	var out []sqlc.ListBroadcastRecipientsRow
+	for _, r := range m.recipients {
+		if r.ID > afterID && len(out) < int(limit) {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockBroadcastRepo) CountRecipients(_ context.Context, _ repository.BroadcastSegment) (int64, error) {
+	return int64(len(m.recipients)), nil
+}
+
+type mockEmailOutboxRepo struct {
+	emails []sqlc.EmailOutbox // by ID - 1
+}
+
+func newMockEmailOutboxRepo() *mockEmailOutboxRepo {
+	return &mockEmailOutboxRepo{}
+}
+
+func (m *mockEmailOutboxRepo) Enqueue(_ context.Context, params sqlc.EnqueueEmailParams) (*sqlc.EmailOutbox, error) {
+	e := sqlc.EmailOutbox{
+		ID:            int64(len(m.emails) + 1),
+		Recipients:    params.Recipients,
+		Subject:       params.Subject,
+		Body:          params.Body,
+		Html:          params.Html,
+		Locale:        params.Locale,
+		Attachments:   params.Attachments,
+		Status:        dto.EmailPending,
+		NextAttemptAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		CreatedAt:     pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	m.emails = append(m.emails, e)
+	return &e, nil
+}
+
+func (m *mockEmailOutboxRepo) ClaimDue(_ context.Context, limit int32, lock time.Duration) ([]sqlc.EmailOutbox, error) {
+	now := time.Now()
+	var out []sqlc.EmailOutbox
+	for i := range m.emails {
+		e := &m.emails[i]
+		due := e.Status == dto.EmailPending && !e.NextAttemptAt.Time.After(now)
+		stale := e.Status == dto.EmailSending && e.LockedUntil.Time.Before(now)
+		if (due || stale) && len(out) < int(limit) {
+			e.Status = dto.EmailSending
+			e.Attempts++
+			e.LockedUntil = pgtype.Timestamptz{Time: now.Add(lock), Valid: true}
+			out = append(out, *e)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockEmailOutboxRepo) MarkSent(_ context.Context, id int64) error {
+	e := &m.emails[id-1]
+	e.Status, e.Body, e.Html, e.LastError, e.Attachments = dto.EmailSent, "", "", "", []byte("[]")
+	e.SentAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (m *mockEmailOutboxRepo) MarkFailed(_ context.Context, id int64, lastError string, nextAttemptAt time.Time) error {
+	e := &m.emails[id-1]
+	e.Status, e.LastError = dto.EmailPending, lastError
+	e.NextAttemptAt = pgtype.Timestamptz{Time: nextAttemptAt, Valid: true}
+	return nil
+}
+
+func (m *mockEmailOutboxRepo) MarkDead(_ context.Context, id int64, lastError string) error {
+	e := &m.emails[id-1]
+	e.Status, e.LastError = dto.EmailDead, lastError
+	return nil
+}
+
+func (m *mockEmailOutboxRepo) RetryDead(_ context.Context, id int64) (*sqlc.EmailOutbox, error) {
+	if id < 1 || int(id) > len(m.emails) || m.emails[id-1].Status != dto.EmailDead {
+		return nil, apperror.ErrNotFound
+	}
+	e := &m.emails[id-1]
+	e.Status, e.Attempts = dto.EmailPending, 0
+	e.NextAttemptAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return e, nil
+}
+
+func (m *mockEmailOutboxRepo) GetByID(_ context.Context, id int64) (*sqlc.EmailOutbox, error) {
+	if id < 1 || int(id) > len(m.emails) {
+		return nil, apperror.ErrNotFound
+	}
+	return &m.emails[id-1], nil
+}
+
+func (m *mockEmailOutboxRepo) List(_ context.Context, status string, limit, offset int32) ([]sqlc.EmailOutbox, error) {
+	var out []sqlc.EmailOutbox
+	for i := len(m.emails) - 1; i >= 0; i-- {
+		if status == "" || m.emails[i].Status == status {
+			out = append(out, m.emails[i])
+		}
+	}
+	if int(offset) >= len(out) {
+		return nil, nil
+	}
+	return out[offset:min(int(offset+limit), len(out))], nil
+}
+
+func (m *mockEmailOutboxRepo) Count(ctx context.Context, status string) (int64, error) {
+	out, _ := m.List(ctx, status, int32(len(m.emails)), 0)
+	return int64(len(out)), nil
+}
+
+func (m *mockEmailOutboxRepo) PurgeFinished(_ context.Context, before time.Time) (int64, error) {
+	var kept []sqlc.EmailOutbox
+	for _, e := range m.emails {
+		if (e.Status == dto.EmailSent || e.Status == dto.EmailDead) && e.CreatedAt.Time.Before(before) {
+			continue
+		}
+		kept = append(kept, e)
+	}
+	n := int64(len(m.emails) - len(kept))
+	m.emails = kept
+	return n, nil
+}
+
+type mockEmailSuppressionRepo struct {
+	items map[int64]*sqlc.EmailSuppression
+	next  int64
+}
+
+func newMockEmailSuppressionRepo() *mockEmailSuppressionRepo {
+	return &mockEmailSuppressionRepo{items: make(map[int64]*sqlc.EmailSuppression)}
+}
+
+func (m *mockEmailSuppressionRepo) Upsert(_ context.Context, params sqlc.UpsertEmailSuppressionParams) (*sqlc.EmailSuppression, error) {
+	for _, item := range m.items {
+		if item.Email == params.Email {
+			item.Reason, item.Provider, item.Detail = params.Reason, params.Provider, params.Detail
+			return item, nil
+		}
+	}
+	m.next++
+	item := &sqlc.EmailSuppression{
+		ID:        m.next,
+		Email:     params.Email,
+		Reason:    params.Reason,
+		Provider:  params.Provider,
+		Detail:    params.Detail,
+		CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		UpdatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	m.items[item.ID] = item
+	return item, nil
+}
+
+func (m *mockEmailSuppressionRepo) Suppressed(_ context.Context, emails []string) ([]string, error) {
+	var out []string
+	for _, e := range emails {
+		for _, item := range m.items {
+			if item.Email == e {
+				out = append(out, e)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (m *mockEmailSuppressionRepo) GetByID(_ context.Context, id int64) (*sqlc.EmailSuppression, error) {
+	item, ok := m.items[id]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	return item, nil
+}
+
+func (m *mockEmailSuppressionRepo) Delete(_ context.Context, id int64) error {
+	if _, ok := m.items[id]; !ok {
+		return apperror.ErrNotFound
+	}
+	delete(m.items, id)
+	return nil
+}
+
+func (m *mockEmailSuppressionRepo) List(_ context.Context, reason, search string, limit, offset int32) ([]sqlc.EmailSuppression, error) {
+	var out []sqlc.EmailSuppression
+	for id := m.next; id > 0; id-- {
+		item, ok := m.items[id]
+		if ok && (reason == "" || item.Reason == reason) && strings.Contains(item.Email, search) {
+			out = append(out, *item)
+		}
+	}
+	if int(offset) >= len(out) {
+		return nil, nil
+	}
+	return out[offset:min(int(offset+limit), len(out))], nil
+}
+
+func (m *mockEmailSuppressionRepo) Count(ctx context.Context, reason, search string) (int64, error) {
+	out, _ := m.List(ctx, reason, search, int32(len(m.items)), 0)
+	return int64(len(out)), nil
+}
+
+type mockErasureAuditRepo struct {
+	audits []sqlc.ErasureAudit
 	nextID int64
 }
 
-func newMockPasswordResetRepo() *mockPasswordResetRepo {
-	return &mockPasswordResetRepo{tokens: make(map[string]*sqlc.PasswordResetToken), nextID: 1}
+func newMockErasureAuditRepo() *mockErasureAuditRepo {
+	return &mockErasureAuditRepo{nextID: 1}
+}
+
+func (m *mockErasureAuditRepo) Create(_ context.Context, params sqlc.CreateErasureAuditParams) (*sqlc.ErasureAudit, error) {
+	for _, a := range m.audits {
+		if a.UserID == params.UserID {
+			return nil, &pgconn.PgError{Code: "23505"}
+		}
+	}
+	a := sqlc.ErasureAudit{
+		ID:           m.nextID,
+		UserID:       params.UserID,
+		ActorID:      params.ActorID,
+		Source:       params.Source,
+		FilesDeleted: params.FilesDeleted,
+		CreatedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	m.audits = append(m.audits, a)
+	m.nextID++
+	return &a, nil
+}
+
+func (m *mockErasureAuditRepo) List(_ context.Context, limit, offset int32) ([]sqlc.ErasureAudit, error) {
+	if int(offset) >= len(m.audits) {
+		return nil, nil
+	}
+	end := min(int(offset+limit), len(m.audits))
+	return m.audits[offset:end], nil
+}
+
+func (m *mockErasureAuditRepo) Count(_ context.Context) (int64, error) {
+	return int64(len(m.audits)), nil
+}
+
+// ---------------------------------------------------------------------------
+// mockUserSettingsRepo
+// ---------------------------------------------------------------------------
+
+type mockUserSettingsRepo struct {
+	settings map[int64]*sqlc.UserSetting
+}
+
+func newMockUserSettingsRepo() *mockUserSettingsRepo {
+	return &mockUserSettingsRepo{settings: make(map[int64]*sqlc.UserSetting)}
+}
+
+func (m *mockUserSettingsRepo) Get(_ context.Context, userID int64) (*sqlc.UserSetting, error) {
+	s, ok := m.settings[userID]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	return s, nil
+}
+
+func (m *mockUserSettingsRepo) Upsert(_ context.Context, params sqlc.UpsertUserSettingsParams) (*sqlc.UserSetting, error) {
+	s, ok := m.settings[params.UserID]
+	if !ok {
+		s = &sqlc.UserSetting{UserID: params.UserID, Locale: dto.DefaultLocale, Timezone: dto.DefaultTimezone, EmailSecurityAlerts: true}
+		m.settings[params.UserID] = s
+	}
+	if params.Locale.Valid {
+		s.Locale = params.Locale.String
+	}
+	if params.Timezone.Valid {
+		s.Timezone = params.Timezone.String
+	}
+	if params.EmailSecurityAlerts.Valid {
+		s.EmailSecurityAlerts = params.EmailSecurityAlerts.Bool
+	}
+	if params.EmailProductUpdates.Valid {
+		s.EmailProductUpdates = params.EmailProductUpdates.Bool
+	}
+	s.UpdatedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return s, nil
+}
+
+// ---------------------------------------------------------------------------
+// mockTwoFactorRepo
+// ---------------------------------------------------------------------------
+
+type mockTwoFactorRepo struct {
+	secrets map[int64]*sqlc.UserTotp
+	codes   map[int64]map[string]bool // recovery code hashes, true once used
+}
+
+func newMockTwoFactorRepo() *mockTwoFactorRepo {
+	return &mockTwoFactorRepo{secrets: make(map[int64]*sqlc.UserTotp), codes: make(map[int64]map[string]bool)}
+}
+
+func (m *mockTwoFactorRepo) SaveSecret(_ context.Context, userID int64, secret string) (*sqlc.UserTotp, error) {
+	if t, ok := m.secrets[userID]; ok && t.EnabledAt.Valid {
+		return nil, apperror.ErrNotFound
+	}
+	t := &sqlc.UserTotp{UserID: userID, Secret: secret, CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}}
+	m.secrets[userID] = t
+	return t, nil
+}
+
+func (m *mockTwoFactorRepo) Get(_ context.Context, userID int64) (*sqlc.UserTotp, error) {
+	t, ok := m.secrets[userID]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	return t, nil
+}
+
+func (m *mockTwoFactorRepo) Enable(_ context.Context, userID int64) (*sqlc.UserTotp, error) {
+	t, ok := m.secrets[userID]
+	if !ok || t.EnabledAt.Valid {
+		return nil, apperror.ErrNotFound
+	}
+	t.EnabledAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	return t, nil
+}
+
+func (m *mockTwoFactorRepo) ReplaceRecoveryCodes(_ context.Context, userID int64, hashes []string) error {
+	m.codes[userID] = make(map[string]bool, len(hashes))
+	for _, h := range hashes {
+		m.codes[userID][h] = false
+	}
+	return nil
+}
+
+func (m *mockTwoFactorRepo) UseRecoveryCode(_ context.Context, userID int64, hash string) (bool, error) {
+	used, ok := m.codes[userID][hash]
+	if !ok || used {
+		return false, nil
+	}
+	m.codes[userID][hash] = true
+	return true, nil
+}
+
+func (m *mockTwoFactorRepo) CountUnusedRecoveryCodes(_ context.Context, userID int64) (int64, error) {
+	var n int64
+	for _, used := range m.codes[userID] {
+		if !used {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *mockTwoFactorRepo) DeleteByUserID(_ context.Context, userID int64) error {
+	delete(m.secrets, userID)
+	delete(m.codes, userID)
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// mockCache
+// ---------------------------------------------------------------------------
+
+type mockCache struct {
+	items map[string][]byte
+	tags  map[string][]string
+}
+
+func newMockCache() *mockCache {
+	return &mockCache{items: make(map[string][]byte), tags: make(map[string][]string)}
+}
+
+func (m *mockCache) Get(_ context.Context, key string) ([]byte, error) {
+	v, ok := m.items[key]
+	if !ok {
+		return nil, nil
+	}
+	return v, nil
+}
+
+func (m *mockCache) Set(_ context.Context, key string, value []byte, _ time.Duration) error {
+	m.items[key] = value
+	return nil
+}
+
+func (m *mockCache) SetWithTags(_ context.Context, key string, value []byte, _ time.Duration, tags ...string) error {
+	m.items[key] = value
+	for _, tag := range tags {
+		m.tags[tag] = append(m.tags[tag], key)
+	}
+	return nil
+}
+
+func (m *mockCache) GetOrSet(ctx context.Context, key string, ttl time.Duration, loader cache.Loader, tags ...string) ([]byte, error) {
+	if v, ok := m.items[key]; ok {
+		return v, nil
+	}
+	v, err := loader(ctx)
+	if err != nil || v == nil {
+		return v, err
+	}
+	return v, m.SetWithTags(ctx, key, v, ttl, tags...)
+}
+
+func (m *mockCache) InvalidateTag(_ context.Context, tag string) error {
+	for _, key := range m.tags[tag] {
+		delete(m.items, key)
+	}
+	delete(m.tags, tag)
+	return nil
+}
+
+func (m *mockCache) Increment(_ context.Context, key string, delta int64, _ time.Duration) (int64, error) {
+	n, _ := strconv.ParseInt(string(m.items[key]), 10, 64)
+	n += delta
+	m.items[key] = []byte(strconv.FormatInt(n, 10))
+	return n, nil
+}
+
+func (m *mockCache) Delete(_ context.Context, key string) error {
+	delete(m.items, key)
+	return nil
+}
+
+func (m *mockCache) Exists(_ context.Context, key string) (bool, error) {
+	_, ok := m.items[key]
+	return ok, nil
+}
+
+func (m *mockCache) Close() error                 { return nil }
+func (m *mockCache) Ping(_ context.Context) error { return nil }
+
+// cacheUser stores a profile for the user the way GetByID does, so a test can check that a
+// write drops it.
+func (m *mockCache) cacheUser(id int64) {
+	_ = m.SetWithTags(context.Background(), userCacheKey(id), []byte("{}"), userCacheTTL, cache.UserTag(id))
+}
+
+func (m *mockCache) userCached(id int64) bool {
+	_, ok := m.items[userCacheKey(id)]
+	return ok
+}
+
+// cacheStats stores a response tagged the way the admin stats route tags it.
+func (m *mockCache) cacheStats() {
+	_ = m.SetWithTags(context.Background(), "response:stats", []byte("{}"), time.Minute, cache.StatsTag)
+}
+
+func (m *mockCache) statsCached() bool {
+	_, ok := m.items["response:stats"]
+	return ok
+}
+
+// ---------------------------------------------------------------------------
+// mockEmailSender implements email.Sender
+// ---------------------------------------------------------------------------
+
+type mockEmailSender struct {
+	sendErr  error
+	sent     int
+	last     email.Message
+	messages []email.Message
+}
+
+func newMockEmailSender() *mockEmailSender {
+	return &mockEmailSender{}
+}
+
+func (m *mockEmailSender) Send(_ context.Context, msg email.Message) error {
+	if m.sendErr != nil {
+		return m.sendErr
+	}
+	m.sent++
+	m.last = msg
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// mockPublisher
+// ---------------------------------------------------------------------------
+
+type publishedEvent struct {
+	userID int64
+	event  realtime.Event
+}
+
+type mockPublisher struct {
+	events []publishedEvent
+}
+
+func (m *mockPublisher) PublishToUser(userID int64, event realtime.Event) {
+	m.events = append(m.events, publishedEvent{userID: userID, event: event})
+}
+
+// ---------------------------------------------------------------------------
+// mockStorage
+// ---------------------------------------------------------------------------
+
+type mockStorage struct {
+	files    map[string][]byte
+	modTimes map[string]time.Time // reported by List; zero when unset
+	putErr   error
+	getErr   error
+	delErr   error
+	listErr  error
+	baseURL  string
+}
+
+func newMockStorage() *mockStorage {
+	return &mockStorage{
+		files:   make(map[string][]byte),
+		baseURL: "http://localhost:8080/files",
+	}
+}
+
+func (m *mockStorage) Put(_ context.Context, path string, reader io.Reader, _ int64, _ string) error {
+	if m.putErr != nil {
+		return m.putErr
+	}
+	data, _ := io.ReadAll(reader)
+	m.files[path] = data
+	return nil
+}
+
+func (m *mockStorage) Get(_ context.Context, path string) (io.ReadCloser, error) {
+	if m.getErr != nil {
+		return nil, m.getErr
+	}
+	data, ok := m.files[path]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	return io.NopCloser(io.NewSectionReader(readerAt(data), 0, int64(len(data)))), nil
+}
+
+func (m *mockStorage) Delete(_ context.Context, path string) error {
+	if m.delErr != nil {
+		return m.delErr
+	}
+	delete(m.files, path)
+	return nil
+}
+
+func (m *mockStorage) List(_ context.Context, fn func(storage.Object) error) error {
+	if m.listErr != nil {
+		return m.listErr
+	}
+	for _, path := range slices.Sorted(maps.Keys(m.files)) {
+		if err := fn(storage.Object{Path: path, Size: int64(len(m.files[path])), ModTime: m.modTimes[path]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mockStorage) URL(path string) string {
+	return m.baseURL + "/" + path
+}
+
+// readerAt wraps []byte to implement io.ReaderAt
+type readerAt []byte
+
+func (r readerAt) ReadAt(p []byte, off int64) (n int, err error) {
+	if off >= int64(len(r)) {
+		return 0, io.EOF
+	}
+	n = copy(p, r[off:])
+	if n < len(p) {
+		err = io.EOF
+	}
+	return
+}
+
+// ---------------------------------------------------------------------------
+// mockRoleRepo
+// ---------------------------------------------------------------------------
+
+type mockRole struct {
+	sqlc.Role
+	permissions []string
 }
 
-func (m *mockPasswordResetRepo) Create(_ context.Context, params sqlc.CreatePasswordResetTokenParams) (*sqlc.PasswordResetToken, error) {
-	t := &sqlc.PasswordResetToken{
-		ID:        m.nextID,
-		UserID:    params.UserID,
-		Token:     params.Token,
-		ExpiresAt: params.ExpiresAt,
-		CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+type mockRoleRepo struct {
+	users       *mockUserRepo
+	roles       map[int64]*mockRole
+	permissions []string
+	userRoles   map[int64][]int64
+	nextID      int64
+}
+
+// newMockRoleRepo seeds the built-in roles and permission catalog like the RBAC migration.
+func newMockRoleRepo(users *mockUserRepo) *mockRoleRepo {
+	m := &mockRoleRepo{
+		users: users,
+		roles: make(map[int64]*mockRole),
+		permissions: []string{
+			dto.PermissionFilesManage, dto.PermissionRolesManage, dto.PermissionStatsRead,
+			dto.PermissionUsersImpersonate, dto.PermissionUsersList, dto.PermissionUsersManage,
+		},
+		userRoles: make(map[int64][]int64),
+		nextID:    1,
 	}
-	m.tokens[params.Token] = t
-	m.nextID++
-	return t, nil
+	for _, name := range []string{dto.RoleAdmin, dto.RoleUser, dto.RoleGuest} {
+		role := &mockRole{Role: sqlc.Role{ID: m.nextID, Name: name, IsSystem: true}}
+		if name == dto.RoleAdmin {
+			role.permissions = slices.Clone(m.permissions)
+		}
+		m.roles[role.ID] = role
+		m.nextID++
+	}
+	return m
 }
 
-func (m *mockPasswordResetRepo) GetByToken(_ context.Context, token string) (*sqlc.PasswordResetToken, error) {
-	t, ok := m.tokens[token]
-	if !ok {
-		return nil, apperror.ErrNotFound
+func (m *mockRoleRepo) roleByName(name string) *mockRole {
+	for _, r := range m.roles {
+		if r.Name == name {
+			return r
+		}
 	}
-	return t, nil
+	return nil
 }
 
-func (m *mockPasswordResetRepo) GetByTokenForUpdate(ctx context.Context, token string) (*sqlc.PasswordResetToken, error) {
-	return m.GetByToken(ctx, token)
+func (m *mockRoleRepo) List(_ context.Context) ([]sqlc.ListRolesRow, error) {
+	rows := make([]sqlc.ListRolesRow, 0, len(m.roles))
+	for id := int64(1); id < m.nextID; id++ {
+		if r, ok := m.roles[id]; ok {
+			rows = append(rows, sqlc.ListRolesRow{ID: r.ID, Name: r.Name, IsSystem: r.IsSystem, Permissions: r.permissions})
+		}
+	}
+	return rows, nil
 }
 
-func (m *mockPasswordResetRepo) Delete(_ context.Context, token string) error {
-	delete(m.tokens, token)
+func (m *mockRoleRepo) Create(_ context.Context, name, description string) (*sqlc.Role, error) {
+	if m.roleByName(name) != nil {
+		return nil, &pgconn.PgError{Code: "23505"}
+	}
+	role := &mockRole{Role: sqlc.Role{ID: m.nextID, Name: name, Description: description}}
+	m.roles[role.ID] = role
+	m.nextID++
+	return &role.Role, nil
+}
+
+func (m *mockRoleRepo) DeleteCustom(_ context.Context, id int64) error {
+	r, ok := m.roles[id]
+	if !ok || r.IsSystem {
+		return apperror.ErrNotFound
+	}
+	delete(m.roles, id)
+	for userID, ids := range m.userRoles {
+		m.userRoles[userID] = slices.DeleteFunc(ids, func(rid int64) bool { return rid == id })
+	}
 	return nil
 }
 
-func (m *mockPasswordResetRepo) DeleteByUserID(_ context.Context, userID int64) error {
-	for k, v := range m.tokens {
-		if v.UserID == userID {
-			delete(m.tokens, k)
+func (m *mockRoleRepo) AddPermissions(_ context.Context, roleID int64, permissions []string) (int64, error) {
+	var n int64
+	for _, p := range permissions {
+		if slices.Contains(m.permissions, p) {
+			m.roles[roleID].permissions = append(m.roles[roleID].permissions, p)
+			n++
 		}
 	}
-	return nil
+	return n, nil
+}
+
+func (m *mockRoleRepo) ListPermissions(_ context.Context) ([]sqlc.Permission, error) {
+	perms := make([]sqlc.Permission, len(m.permissions))
+	for i, p := range m.permissions {
+		perms[i] = sqlc.Permission{ID: int64(i + 1), Name: p}
+	}
+	return perms, nil
+}
+
+func (m *mockRoleRepo) ListUserPermissions(_ context.Context, userID int64) ([]string, error) {
+	var perms []string
+	if u, ok := m.users.users[userID]; ok {
+		if base := m.roleByName(u.Role); base != nil {
+			perms = append(perms, base.permissions...)
+		}
+	}
+	for _, id := range m.userRoles[userID] {
+		perms = append(perms, m.roles[id].permissions...)
+	}
+	slices.Sort(perms)
+	return slices.Compact(perms), nil
+}
+
+func (m *mockRoleRepo) ListUserRoles(_ context.Context, userID int64) ([]string, error) {
+	names := []string{}
+	for _, id := range m.userRoles[userID] {
+		names = append(names, m.roles[id].Name)
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+func (m *mockRoleRepo) ReplaceUserRoles(_ context.Context, userID int64, roles []string) (int64, error) {
+	m.userRoles[userID] = nil
+	for _, name := range roles {
+		if r := m.roleByName(name); r != nil && !r.IsSystem {
+			m.userRoles[userID] = append(m.userRoles[userID], r.ID)
+		}
+	}
+	return int64(len(m.userRoles[userID])), nil
 }
 
 // ---------------------------------------------------------------------------
-// mockCache
+// mockOrganizationRepo
 // ---------------------------------------------------------------------------
 
-type mockCache struct {
-	items map[string][]byte
+type mockOrganizationRepo struct {
+	users       *mockUserRepo
+	orgs        map[int64]*sqlc.Organization
+	members     map[int64]map[int64]*sqlc.OrganizationMember // org ID -> user ID -> membership
+	invitations map[int64]*sqlc.OrganizationInvitation
+	nextID      int64
 }
 
-func newMockCache() *mockCache {
-	return &mockCache{items: make(map[string][]byte)}
+func newMockOrganizationRepo(users *mockUserRepo) *mockOrganizationRepo {
+	return &mockOrganizationRepo{
+		users:       users,
+		orgs:        make(map[int64]*sqlc.Organization),
+		members:     make(map[int64]map[int64]*sqlc.OrganizationMember),
+		invitations: make(map[int64]*sqlc.OrganizationInvitation),
+		nextID:      1,
+	}
 }
 
-func (m *mockCache) Get(_ context.Context, key string) ([]byte, error) {
-	v, ok := m.items[key]
+func (m *mockOrganizationRepo) Create(_ context.Context, name, slug string) (*sqlc.Organization, error) {
+	for _, o := range m.orgs {
+		if o.Slug == slug {
+			return nil, &pgconn.PgError{Code: "23505"}
+		}
+	}
+	org := &sqlc.Organization{ID: m.nextID, Name: name, Slug: slug}
+	m.orgs[org.ID] = org
+	m.members[org.ID] = make(map[int64]*sqlc.OrganizationMember)
+	m.nextID++
+	return org, nil
+}
+
+func (m *mockOrganizationRepo) GetForMember(_ context.Context, orgID, userID int64) (*sqlc.GetOrganizationForMemberRow, error) {
+	org, ok := m.orgs[orgID]
 	if !ok {
-		return nil, nil
+		return nil, apperror.ErrNotFound
 	}
-	return v, nil
+	member, ok := m.members[orgID][userID]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	return &sqlc.GetOrganizationForMemberRow{ID: org.ID, Name: org.Name, Slug: org.Slug, MemberRole: member.Role}, nil
 }
 
-func (m *mockCache) Set(_ context.Context, key string, value []byte, _ time.Duration) error {
-	m.items[key] = value
+func (m *mockOrganizationRepo) ListByUser(_ context.Context, userID int64, limit, offset int32) ([]sqlc.ListOrganizationsByUserRow, error) {
+	var rows []sqlc.ListOrganizationsByUserRow
+	for id := int64(1); id < m.nextID; id++ {
+		if member, ok := m.members[id][userID]; ok {
+			org := m.orgs[id]
+			rows = append(rows, sqlc.ListOrganizationsByUserRow{ID: org.ID, Name: org.Name, Slug: org.Slug, MemberRole: member.Role})
+		}
+	}
+	start := min(int(offset), len(rows))
+	end := min(start+int(limit), len(rows))
+	return rows[start:end], nil
+}
+
+func (m *mockOrganizationRepo) CountByUser(_ context.Context, userID int64) (int64, error) {
+	var n int64
+	for _, members := range m.members {
+		if _, ok := members[userID]; ok {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *mockOrganizationRepo) Update(_ context.Context, id int64, name string) (*sqlc.Organization, error) {
+	org, ok := m.orgs[id]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	org.Name = name
+	return org, nil
+}
+
+func (m *mockOrganizationRepo) Delete(_ context.Context, id int64) error {
+	delete(m.orgs, id)
+	delete(m.members, id)
 	return nil
 }
 
-func (m *mockCache) Delete(_ context.Context, key string) error {
-	delete(m.items, key)
+func (m *mockOrganizationRepo) AddMember(_ context.Context, orgID, userID int64, role string) error {
+	if _, ok := m.members[orgID][userID]; !ok {
+		m.members[orgID][userID] = &sqlc.OrganizationMember{OrganizationID: orgID, UserID: userID, Role: role}
+	}
 	return nil
 }
 
-func (m *mockCache) Exists(_ context.Context, key string) (bool, error) {
-	_, ok := m.items[key]
-	return ok, nil
+func (m *mockOrganizationRepo) GetMember(_ context.Context, orgID, userID int64) (*sqlc.OrganizationMember, error) {
+	member, ok := m.members[orgID][userID]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	return member, nil
 }
 
-func (m *mockCache) Close() error                 { return nil }
-func (m *mockCache) Ping(_ context.Context) error { return nil }
+func (m *mockOrganizationRepo) ListMembers(_ context.Context, orgID int64, limit, offset int32) ([]sqlc.ListOrganizationMembersRow, error) {
+	var rows []sqlc.ListOrganizationMembersRow
+	for userID, member := range m.members[orgID] {
+		u := m.users.users[userID]
+		rows = append(rows, sqlc.ListOrganizationMembersRow{UserID: userID, Name: u.Name, Email: u.Email, Role: member.Role})
+	}
+	slices.SortFunc(rows, func(a, b sqlc.ListOrganizationMembersRow) int { return int(a.UserID - b.UserID) })
+	start := min(int(offset), len(rows))
+	end := min(start+int(limit), len(rows))
+	return rows[start:end], nil
+}
 
-// ---------------------------------------------------------------------------
-// mockEmailSender implements email.Sender
-// ---------------------------------------------------------------------------
+func (m *mockOrganizationRepo) CountMembers(_ context.Context, orgID int64) (int64, error) {
+	return int64(len(m.members[orgID])), nil
+}
 
-type mockEmailSender struct {
-	sendErr error
-	sent    int
+func (m *mockOrganizationRepo) CountOwners(_ context.Context, orgID int64) (int64, error) {
+	var n int64
+	for _, member := range m.members[orgID] {
+		if member.Role == dto.OrgRoleOwner {
+			n++
+		}
+	}
+	return n, nil
 }
 
-func newMockEmailSender() *mockEmailSender {
-	return &mockEmailSender{}
+func (m *mockOrganizationRepo) UpdateMemberRole(_ context.Context, orgID, userID int64, role string) (*sqlc.OrganizationMember, error) {
+	member, ok := m.members[orgID][userID]
+	if !ok {
+		return nil, apperror.ErrNotFound
+	}
+	member.Role = role
+	return member, nil
 }
 
-func (m *mockEmailSender) Send(_ context.Context, _ email.Message) error {
-	if m.sendErr != nil {
-		return m.sendErr
+func (m *mockOrganizationRepo) RemoveMember(_ context.Context, orgID, userID int64) error {
+	if _, ok := m.members[orgID][userID]; !ok {
+		return apperror.ErrNotFound
 	}
-	m.sent++
+	delete(m.members[orgID], userID)
+	return nil
+}
+
+func (m *mockOrganizationRepo) UpsertInvitation(
+	_ context.Context,
+	params sqlc.UpsertOrganizationInvitationParams,
+) (*sqlc.OrganizationInvitation, error) {
+	for id, inv := range m.invitations {
+		if inv.OrganizationID == params.OrganizationID && inv.Email == params.Email {
+			delete(m.invitations, id)
+		}
+	}
+	inv := &sqlc.OrganizationInvitation{
+		ID:             m.nextID,
+		OrganizationID: params.OrganizationID,
+		Email:          params.Email,
+		Role:           params.Role,
+		Token:          params.Token,
+		InvitedBy:      params.InvitedBy,
+		ExpiresAt:      params.ExpiresAt,
+	}
+	m.invitations[inv.ID] = inv
+	m.nextID++
+	return inv, nil
+}
+
+func (m *mockOrganizationRepo) GetInvitationByToken(_ context.Context, token string) (*sqlc.OrganizationInvitation, error) {
+	for _, inv := range m.invitations {
+		if inv.Token == token {
+			return inv, nil
+		}
+	}
+	return nil, apperror.ErrNotFound
+}
+
+func (m *mockOrganizationRepo) DeleteInvitation(_ context.Context, id int64) error {
+	delete(m.invitations, id)
 	return nil
 }
 
 // ---------------------------------------------------------------------------
-// mockStorage
+// mockRegistrationInvitationRepo
 // ---------------------------------------------------------------------------
 
-type mockStorage struct {
-	files   map[string][]byte
-	putErr  error
-	getErr  error
-	delErr  error
-	baseURL string
+type mockRegistrationInvitationRepo struct {
+	invitations map[int64]*sqlc.RegistrationInvitation
+	nextID      int64
 }
 
-func newMockStorage() *mockStorage {
-	return &mockStorage{
-		files:   make(map[string][]byte),
-		baseURL: "http://localhost:8080/files",
+func newMockRegistrationInvitationRepo() *mockRegistrationInvitationRepo {
+	return &mockRegistrationInvitationRepo{invitations: make(map[int64]*sqlc.RegistrationInvitation), nextID: 1}
+}
+
+func (m *mockRegistrationInvitationRepo) Upsert(
+	_ context.Context,
+	params sqlc.UpsertRegistrationInvitationParams,
+) (*sqlc.RegistrationInvitation, error) {
+	for id, inv := range m.invitations {
+		if inv.Email == params.Email {
+			delete(m.invitations, id)
+		}
+	}
+	inv := &sqlc.RegistrationInvitation{
+		ID:        m.nextID,
+		Email:     params.Email,
+		Token:     params.Token,
+		InvitedBy: params.InvitedBy,
+		ExpiresAt: params.ExpiresAt,
+		CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
 	}
+	m.invitations[inv.ID] = inv
+	m.nextID++
+	return inv, nil
 }
 
-func (m *mockStorage) Put(_ context.Context, path string, reader io.Reader, _ int64, _ string) error {
-	if m.putErr != nil {
-		return m.putErr
+func (m *mockRegistrationInvitationRepo) GetByToken(_ context.Context, token string) (*sqlc.RegistrationInvitation, error) {
+	for _, inv := range m.invitations {
+		if inv.Token == token {
+			return inv, nil
+		}
 	}
-	data, _ := io.ReadAll(reader)
-	m.files[path] = data
+	return nil, apperror.ErrNotFound
+}
+
+func (m *mockRegistrationInvitationRepo) GetByEmail(_ context.Context, email string) (*sqlc.RegistrationInvitation, error) {
+	for _, inv := range m.invitations {
+		if inv.Email == strings.ToLower(email) {
+			return inv, nil
+		}
+	}
+	return nil, apperror.ErrNotFound
+}
+
+func (m *mockRegistrationInvitationRepo) List(_ context.Context, limit, offset int32) ([]sqlc.RegistrationInvitation, error) {
+	ids := slices.Sorted(maps.Keys(m.invitations))
+	slices.Reverse(ids)
+	var result []sqlc.RegistrationInvitation
+	for _, id := range ids {
+		result = append(result, *m.invitations[id])
+	}
+	if int(offset) >= len(result) {
+		return nil, nil
+	}
+	return result[offset:min(int(offset+limit), len(result))], nil
+}
+
+func (m *mockRegistrationInvitationRepo) Count(_ context.Context) (int64, error) {
+	return int64(len(m.invitations)), nil
+}
+
+func (m *mockRegistrationInvitationRepo) Delete(_ context.Context, id int64) error {
+	if _, ok := m.invitations[id]; !ok {
+		return apperror.ErrNotFound
+	}
+	delete(m.invitations, id)
 	return nil
 }
 
-func (m *mockStorage) Get(_ context.Context, path string) (io.ReadCloser, error) {
-	if m.getErr != nil {
-		return nil, m.getErr
+func (m *mockRegistrationInvitationRepo) DeleteExpired(_ context.Context) (int64, error) {
+	var n int64
+	for id, inv := range m.invitations {
+		if inv.ExpiresAt.Time.Before(time.Now()) {
+			delete(m.invitations, id)
+			n++
+		}
 	}
-	data, ok := m.files[path]
+	return n, nil
+}
+
+// ---------------------------------------------------------------------------
+// mockUploadSessionRepo
+// ---------------------------------------------------------------------------
+
+type mockUploadSessionRepo struct {
+	sessions map[int64]*sqlc.UploadSession
+	parts    map[int64][]sqlc.UploadSessionPart
+	nextID   int64
+}
+
+func newMockUploadSessionRepo() *mockUploadSessionRepo {
+	return &mockUploadSessionRepo{
+		sessions: make(map[int64]*sqlc.UploadSession),
+		parts:    make(map[int64][]sqlc.UploadSessionPart),
+		nextID:   1,
+	}
+}
+
+func (m *mockUploadSessionRepo) Create(_ context.Context, params sqlc.CreateUploadSessionParams) (*sqlc.UploadSession, error) {
+	s := &sqlc.UploadSession{
+		ID:        m.nextID,
+		UserID:    params.UserID,
+		Filename:  params.Filename,
+		Size:      params.Size,
+		ExpiresAt: params.ExpiresAt,
+		CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	m.sessions[s.ID] = s
+	m.nextID++
+	return s, nil
+}
+
+func (m *mockUploadSessionRepo) GetByID(_ context.Context, id int64) (*sqlc.UploadSession, error) {
+	s, ok := m.sessions[id]
 	if !ok {
 		return nil, apperror.ErrNotFound
 	}
-	return io.NopCloser(io.NewSectionReader(readerAt(data), 0, int64(len(data)))), nil
+	cp := *s
+	return &cp, nil
 }
 
-func (m *mockStorage) Delete(_ context.Context, path string) error {
-	if m.delErr != nil {
-		return m.delErr
+func (m *mockUploadSessionRepo) Advance(_ context.Context, params sqlc.AdvanceUploadSessionParams) (*sqlc.UploadSession, error) {
+	s, ok := m.sessions[params.ID]
+	if !ok || s.Received != params.Received {
+		return nil, apperror.ErrNotFound
 	}
-	delete(m.files, path)
+	s.Received += params.ChunkSize
+	if s.MimeType == "" {
+		s.MimeType = params.MimeType
+	}
+	cp := *s
+	return &cp, nil
+}
+
+func (m *mockUploadSessionRepo) CreatePart(_ context.Context, params sqlc.CreateUploadSessionPartParams) error {
+	m.parts[params.SessionID] = append(m.parts[params.SessionID], sqlc.UploadSessionPart(params))
 	return nil
 }
 
-func (m *mockStorage) URL(path string) string {
-	return m.baseURL + "/" + path
+func (m *mockUploadSessionRepo) ListParts(_ context.Context, sessionID int64) ([]sqlc.UploadSessionPart, error) {
+	return slices.Clone(m.parts[sessionID]), nil
 }
 
-// readerAt wraps []byte to implement io.ReaderAt
-type readerAt []byte
+func (m *mockUploadSessionRepo) Delete(_ context.Context, id int64) error {
+	if _, ok := m.sessions[id]; !ok {
+		return apperror.ErrNotFound
+	}
+	delete(m.sessions, id)
+	delete(m.parts, id)
+	return nil
+}
 
-func (r readerAt) ReadAt(p []byte, off int64) (n int, err error) {
-	if off >= int64(len(r)) {
-		return 0, io.EOF
+func (m *mockUploadSessionRepo) ListExpired(_ context.Context, limit int32) ([]sqlc.UploadSession, error) {
+	var result []sqlc.UploadSession
+	for _, id := range slices.Sorted(maps.Keys(m.sessions)) {
+		if s := m.sessions[id]; s.ExpiresAt.Time.Before(time.Now()) && len(result) < int(limit) {
+			result = append(result, *s)
+		}
 	}
-	n = copy(p, r[off:])
-	if n < len(p) {
-		err = io.EOF
+	return result, nil
+}
+
+// ---------------------------------------------------------------------------
+// mockFileShareRepo
+// ---------------------------------------------------------------------------
+
+type mockFileShareRepo struct {
+	shares map[int64]*sqlc.FileShare
+	nextID int64
+}
+
+func newMockFileShareRepo() *mockFileShareRepo {
+	return &mockFileShareRepo{shares: make(map[int64]*sqlc.FileShare), nextID: 1}
+}
+
+func (m *mockFileShareRepo) Create(_ context.Context, params sqlc.CreateFileShareParams) (*sqlc.FileShare, error) {
+	s := &sqlc.FileShare{
+		ID:           m.nextID,
+		FileID:       params.FileID,
+		Token:        params.Token,
+		PasswordHash: params.PasswordHash,
+		MaxDownloads: params.MaxDownloads,
+		ExpiresAt:    params.ExpiresAt,
+		CreatedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
 	}
-	return
+	m.shares[s.ID] = s
+	m.nextID++
+	return s, nil
+}
+
+func (m *mockFileShareRepo) GetByToken(_ context.Context, token string) (*sqlc.FileShare, error) {
+	for _, s := range m.shares {
+		if s.Token == token {
+			cp := *s
+			return &cp, nil
+		}
+	}
+	return nil, apperror.ErrNotFound
+}
+
+func (m *mockFileShareRepo) ListByFileID(_ context.Context, fileID int64) ([]sqlc.FileShare, error) {
+	var result []sqlc.FileShare
+	for _, id := range slices.Sorted(maps.Keys(m.shares)) {
+		if s := m.shares[id]; s.FileID == fileID {
+			result = append(result, *s)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockFileShareRepo) RecordDownload(_ context.Context, id int64) error {
+	s, ok := m.shares[id]
+	if !ok || s.ExpiresAt.Time.Before(time.Now()) || (s.MaxDownloads.Valid && s.DownloadCount >= s.MaxDownloads.Int32) {
+		return apperror.ErrNotFound
+	}
+	s.DownloadCount++
+	return nil
+}
+
+func (m *mockFileShareRepo) Delete(_ context.Context, id, fileID int64) error {
+	s, ok := m.shares[id]
+	if !ok || s.FileID != fileID {
+		return apperror.ErrNotFound
+	}
+	delete(m.shares, id)
+	return nil
+}
+
+func (m *mockFileShareRepo) DeleteExpired(_ context.Context) (int64, error) {
+	var n int64
+	for id, s := range m.shares {
+		if s.ExpiresAt.Time.Before(time.Now()) || (s.MaxDownloads.Valid && s.DownloadCount >= s.MaxDownloads.Int32) {
+			delete(m.shares, id)
+			n++
+		}
+	}
+	return n, nil
 }
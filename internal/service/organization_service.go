@@ -0,0 +1,466 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/database"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/email"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/pagination"
+)
+
+const orgInvitationTTL = 7 * 24 * time.Hour
+
+// OrganizationService manages multi-user tenants. Every call is made on behalf of an
+// authenticated user; organizations they do not belong to are reported as not found.
+type OrganizationService interface {
+	Create(ctx context.Context, userID int64, req dto.CreateOrganizationRequest) (*dto.OrganizationResponse, error)
+	List(ctx context.Context, userID int64, page, perPage int) ([]dto.OrganizationResponse, int64, error)
+	Get(ctx context.Context, userID, orgID int64) (*dto.OrganizationResponse, error)
+	Update(ctx context.Context, userID, orgID int64, req dto.UpdateOrganizationRequest) (*dto.OrganizationResponse, error)
+	Delete(ctx context.Context, userID, orgID int64) error
+	ListMembers(ctx context.Context, userID, orgID int64, page, perPage int) ([]dto.OrganizationMemberResponse, int64, error)
+	Invite(ctx context.Context, userID, orgID int64, req dto.InviteMemberRequest) (*dto.OrganizationInvitationResponse, error)
+	AcceptInvitation(ctx context.Context, userID int64, token string) (*dto.OrganizationResponse, error)
+	UpdateMemberRole(ctx context.Context, userID, orgID, memberID int64, role string) (*dto.OrganizationMemberResponse, error)
+	RemoveMember(ctx context.Context, userID, orgID, memberID int64) error
+}
+
+type organizationService struct {
+	repo        repository.OrganizationRepository
+	userRepo    repository.UserRepository
+	sender      email.Sender
+	frontendURL string
+	txManager   *database.TxManager
+}
+
+func NewOrganizationService(
+	repo repository.OrganizationRepository,
+	userRepo repository.UserRepository,
+	sender email.Sender,
+	frontendURL string,
+	txManager *database.TxManager,
+) OrganizationService {
+	return &organizationService{
+		repo:        repo,
+		userRepo:    userRepo,
+		sender:      sender,
+		frontendURL: frontendURL,
+		txManager:   txManager,
+	}
+}
+
+func (s *organizationService) Create(ctx context.Context, userID int64, req dto.CreateOrganizationRequest) (*dto.OrganizationResponse, error) {
+	var org *sqlc.Organization
+	create := func(repo repository.OrganizationRepository) error {
+		var err error
+		org, err = repo.Create(ctx, req.Name, req.Slug)
+		if err != nil {
+			if repository.IsUniqueViolation(err) {
+				return apperror.NewBadRequest("organization slug already taken")
+			}
+			return apperror.NewInternal("failed to create organization")
+		}
+		if err := repo.AddMember(ctx, org.ID, userID, dto.OrgRoleOwner); err != nil {
+			return apperror.NewInternal("failed to add organization owner")
+		}
+		return nil
+	}
+
+	if err := s.withTx(ctx, create); err != nil {
+		return nil, err
+	}
+
+	return &dto.OrganizationResponse{
+		ID:        org.ID,
+		Name:      org.Name,
+		Slug:      org.Slug,
+		Role:      dto.OrgRoleOwner,
+		CreatedAt: org.CreatedAt.Time,
+	}, nil
+}
+
+func (s *organizationService) List(ctx context.Context, userID int64, page, perPage int) ([]dto.OrganizationResponse, int64, error) {
+	limit, offset := pagination.LimitOffset(page, perPage)
+
+	orgs, err := s.repo.ListByUser(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, 0, apperror.NewInternal("failed to list organizations")
+	}
+
+	total, err := s.repo.CountByUser(ctx, userID)
+	if err != nil {
+		return nil, 0, apperror.NewInternal("failed to count organizations")
+	}
+
+	responses := make([]dto.OrganizationResponse, len(orgs))
+	for i, o := range orgs {
+		responses[i] = dto.OrganizationResponse{
+			ID:        o.ID,
+			Name:      o.Name,
+			Slug:      o.Slug,
+			Role:      o.MemberRole,
+			CreatedAt: o.CreatedAt.Time,
+		}
+	}
+
+	return responses, total, nil
+}
+
+func (s *organizationService) Get(ctx context.Context, userID, orgID int64) (*dto.OrganizationResponse, error) {
+	org, err := s.membership(ctx, userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return toOrganizationResponse(org), nil
+}
+
+func (s *organizationService) Update(
+	ctx context.Context,
+	userID, orgID int64,
+	req dto.UpdateOrganizationRequest,
+) (*dto.OrganizationResponse, error) {
+	member, err := s.membership(ctx, userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if !canManageOrg(member.MemberRole) {
+		return nil, apperror.NewForbidden("only organization owners and admins can update the organization")
+	}
+
+	org, err := s.repo.Update(ctx, orgID, req.Name)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("organization not found")
+		}
+		return nil, apperror.NewInternal("failed to update organization")
+	}
+
+	return &dto.OrganizationResponse{
+		ID:        org.ID,
+		Name:      org.Name,
+		Slug:      org.Slug,
+		Role:      member.MemberRole,
+		CreatedAt: org.CreatedAt.Time,
+	}, nil
+}
+
+func (s *organizationService) Delete(ctx context.Context, userID, orgID int64) error {
+	member, err := s.membership(ctx, userID, orgID)
+	if err != nil {
+		return err
+	}
+	if member.MemberRole != dto.OrgRoleOwner {
+		return apperror.NewForbidden("only organization owners can delete the organization")
+	}
+
+	if err := s.repo.Delete(ctx, orgID); err != nil {
+		return apperror.NewInternal("failed to delete organization")
+	}
+	return nil
+}
+
+func (s *organizationService) ListMembers(
+	ctx context.Context,
+	userID, orgID int64,
+	page, perPage int,
+) ([]dto.OrganizationMemberResponse, int64, error) {
+	if _, err := s.membership(ctx, userID, orgID); err != nil {
+		return nil, 0, err
+	}
+
+	limit, offset := pagination.LimitOffset(page, perPage)
+
+	members, err := s.repo.ListMembers(ctx, orgID, limit, offset)
+	if err != nil {
+		return nil, 0, apperror.NewInternal("failed to list organization members")
+	}
+
+	total, err := s.repo.CountMembers(ctx, orgID)
+	if err != nil {
+		return nil, 0, apperror.NewInternal("failed to count organization members")
+	}
+
+	responses := make([]dto.OrganizationMemberResponse, len(members))
+	for i, m := range members {
+		responses[i] = dto.OrganizationMemberResponse{
+			UserID:   m.UserID,
+			Name:     m.Name,
+			Email:    m.Email,
+			Role:     m.Role,
+			JoinedAt: m.CreatedAt.Time,
+		}
+	}
+
+	return responses, total, nil
+}
+
+// Invite emails an invitation link to the address. Inviting the same address again
+// replaces the pending invitation, invalidating the previous link.
+func (s *organizationService) Invite(
+	ctx context.Context,
+	userID, orgID int64,
+	req dto.InviteMemberRequest,
+) (*dto.OrganizationInvitationResponse, error) {
+	org, err := s.membership(ctx, userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if !canManageOrg(org.MemberRole) {
+		return nil, apperror.NewForbidden("only organization owners and admins can invite members")
+	}
+
+	invitee, err := s.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		return nil, apperror.NewInternal("failed to look up invitee")
+	}
+	if invitee != nil {
+		if _, err := s.repo.GetMember(ctx, orgID, invitee.ID); err == nil {
+			return nil, apperror.NewBadRequest("user is already a member")
+		}
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, apperror.NewInternal("failed to generate invitation token")
+	}
+	token := hex.EncodeToString(b)
+
+	inv, err := s.repo.UpsertInvitation(ctx, sqlc.UpsertOrganizationInvitationParams{
+		OrganizationID: orgID,
+		Email:          strings.ToLower(req.Email),
+		Role:           req.Role,
+		Token:          hashToken(token), // Store hash, not plaintext
+		InvitedBy:      pgtype.Int8{Int64: userID, Valid: true},
+		ExpiresAt:      pgtype.Timestamptz{Time: time.Now().Add(orgInvitationTTL), Valid: true},
+	})
+	if err != nil {
+		return nil, apperror.NewInternal("failed to create invitation")
+	}
+
+	acceptURL := fmt.Sprintf("%s/accept-invitation?token=%s", s.frontendURL, token)
+	if err := s.sender.Send(ctx, email.Message{
+		To:      []string{inv.Email},
+		Subject: fmt.Sprintf("You're invited to join %s", org.Name),
+		HTML: fmt.Sprintf("<p>You have been invited to join <strong>%s</strong>. Click <a href=%q>here</a> to accept. This link expires in 7 days.</p>",
+			html.EscapeString(org.Name), acceptURL),
+	}); err != nil {
+		slog.Error("failed to send organization invitation", slog.Int64("organization_id", orgID), slog.Any("error", err))
+	}
+
+	return &dto.OrganizationInvitationResponse{
+		ID:        inv.ID,
+		Email:     inv.Email,
+		Role:      inv.Role,
+		ExpiresAt: inv.ExpiresAt.Time,
+	}, nil
+}
+
+// AcceptInvitation adds the user to the organization the token was issued for.
+// The invitation must have been sent to the user's own email address.
+func (s *organizationService) AcceptInvitation(ctx context.Context, userID int64, token string) (*dto.OrganizationResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("user not found")
+		}
+		return nil, apperror.NewInternal("failed to get user")
+	}
+
+	var orgID int64
+	accept := func(repo repository.OrganizationRepository) error {
+		inv, err := repo.GetInvitationByToken(ctx, hashToken(token))
+		if err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				return apperror.NewBadRequest("invalid or expired invitation")
+			}
+			return apperror.NewInternal("failed to verify invitation")
+		}
+
+		if inv.ExpiresAt.Time.Before(time.Now()) {
+			_ = repo.DeleteInvitation(ctx, inv.ID)
+			return apperror.NewBadRequest("invitation has expired")
+		}
+		if !strings.EqualFold(inv.Email, user.Email) {
+			return apperror.NewForbidden("invitation was sent to a different email address")
+		}
+
+		if err := repo.AddMember(ctx, inv.OrganizationID, userID, inv.Role); err != nil {
+			return apperror.NewInternal("failed to join organization")
+		}
+		if err := repo.DeleteInvitation(ctx, inv.ID); err != nil {
+			return apperror.NewInternal("failed to consume invitation")
+		}
+		orgID = inv.OrganizationID
+		return nil
+	}
+
+	if err := s.withTx(ctx, accept); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, userID, orgID)
+}
+
+// UpdateMemberRole changes a member's role. Admins cannot touch owners or grant
+// ownership, and the last owner cannot be demoted.
+func (s *organizationService) UpdateMemberRole(
+	ctx context.Context,
+	userID, orgID, memberID int64,
+	role string,
+) (*dto.OrganizationMemberResponse, error) {
+	actor, err := s.membership(ctx, userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if !canManageOrg(actor.MemberRole) {
+		return nil, apperror.NewForbidden("only organization owners and admins can change member roles")
+	}
+
+	var updated *sqlc.OrganizationMember
+	change := func(repo repository.OrganizationRepository) error {
+		target, err := repo.GetMember(ctx, orgID, memberID)
+		if err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				return apperror.NewNotFound("member not found")
+			}
+			return apperror.NewInternal("failed to get member")
+		}
+
+		if actor.MemberRole != dto.OrgRoleOwner && (target.Role == dto.OrgRoleOwner || role == dto.OrgRoleOwner) {
+			return apperror.NewForbidden("only organization owners can manage ownership")
+		}
+		if target.Role == dto.OrgRoleOwner && role != dto.OrgRoleOwner {
+			if err := ensureAnotherOwner(ctx, repo, orgID); err != nil {
+				return err
+			}
+		}
+
+		updated, err = repo.UpdateMemberRole(ctx, orgID, memberID, role)
+		if err != nil {
+			return apperror.NewInternal("failed to update member role")
+		}
+		return nil
+	}
+
+	if err := s.withTx(ctx, change); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, memberID)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to get member")
+	}
+
+	return &dto.OrganizationMemberResponse{
+		UserID:   updated.UserID,
+		Name:     user.Name,
+		Email:    user.Email,
+		Role:     updated.Role,
+		JoinedAt: updated.CreatedAt.Time,
+	}, nil
+}
+
+// RemoveMember removes a member from the organization. Any member may remove
+// themselves (leave); removing others requires owner or admin, and admins cannot
+// remove owners. The last owner cannot leave.
+func (s *organizationService) RemoveMember(ctx context.Context, userID, orgID, memberID int64) error {
+	actor, err := s.membership(ctx, userID, orgID)
+	if err != nil {
+		return err
+	}
+	if memberID != userID && !canManageOrg(actor.MemberRole) {
+		return apperror.NewForbidden("only organization owners and admins can remove members")
+	}
+
+	remove := func(repo repository.OrganizationRepository) error {
+		target, err := repo.GetMember(ctx, orgID, memberID)
+		if err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				return apperror.NewNotFound("member not found")
+			}
+			return apperror.NewInternal("failed to get member")
+		}
+
+		if target.Role == dto.OrgRoleOwner {
+			if memberID != userID && actor.MemberRole != dto.OrgRoleOwner {
+				return apperror.NewForbidden("only organization owners can remove owners")
+			}
+			if err := ensureAnotherOwner(ctx, repo, orgID); err != nil {
+				return err
+			}
+		}
+
+		if err := repo.RemoveMember(ctx, orgID, memberID); err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				return apperror.NewNotFound("member not found")
+			}
+			return apperror.NewInternal("failed to remove member")
+		}
+		return nil
+	}
+
+	return s.withTx(ctx, remove)
+}
+
+// membership returns the organization with the user's role, or a not found error
+// when the user does not belong to it.
+func (s *organizationService) membership(ctx context.Context, userID, orgID int64) (*sqlc.GetOrganizationForMemberRow, error) {
+	org, err := s.repo.GetForMember(ctx, orgID, userID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("organization not found")
+		}
+		return nil, apperror.NewInternal("failed to get organization")
+	}
+	return org, nil
+}
+
+// withTx runs fn against a transactional repository, or the plain one when no
+// transaction manager is configured (tests).
+func (s *organizationService) withTx(ctx context.Context, fn func(repo repository.OrganizationRepository) error) error {
+	if s.txManager == nil {
+		return fn(s.repo)
+	}
+	return s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		return fn(repository.NewOrganizationRepository(tx))
+	})
+}
+
+func ensureAnotherOwner(ctx context.Context, repo repository.OrganizationRepository, orgID int64) error {
+	owners, err := repo.CountOwners(ctx, orgID)
+	if err != nil {
+		return apperror.NewInternal("failed to count organization owners")
+	}
+	if owners <= 1 {
+		return apperror.NewBadRequest("organization must keep at least one owner")
+	}
+	return nil
+}
+
+func canManageOrg(role string) bool {
+	return role == dto.OrgRoleOwner || role == dto.OrgRoleAdmin
+}
+
+func toOrganizationResponse(org *sqlc.GetOrganizationForMemberRow) *dto.OrganizationResponse {
+	return &dto.OrganizationResponse{
+		ID:        org.ID,
+		Name:      org.Name,
+		Slug:      org.Slug,
+		Role:      org.MemberRole,
+		CreatedAt: org.CreatedAt.Time,
+	}
+}
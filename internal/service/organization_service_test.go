@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+)
+
+var invitationTokenPattern = regexp.MustCompile(`token=([0-9a-f]+)`)
+
+// newTestOrg creates an organization owned by user 1 with user 2 as admin and user 3 as member.
+// User 4 is registered but not a member.
+func newTestOrg(t *testing.T) (OrganizationService, *mockOrganizationRepo, *mockEmailSender, int64) {
+	t.Helper()
+	users := newMockUserRepo()
+	for id, addr := range map[int64]string{1: "owner@example.com", 2: "admin@example.com", 3: "member@example.com", 4: "outsider@example.com"} {
+		users.users[id] = &sqlc.User{ID: id, Email: addr, Name: addr, Role: dto.RoleUser}
+	}
+	repo := newMockOrganizationRepo(users)
+	sender := newMockEmailSender()
+	svc := NewOrganizationService(repo, users, sender, "http://localhost:3000", nil)
+
+	org, err := svc.Create(context.Background(), 1, dto.CreateOrganizationRequest{Name: "Acme", Slug: "acme"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	_ = repo.AddMember(context.Background(), org.ID, 2, dto.OrgRoleAdmin)
+	_ = repo.AddMember(context.Background(), org.ID, 3, dto.OrgRoleMember)
+	return svc, repo, sender, org.ID
+}
+
+// ---------------------------------------------------------------------------
+// Create / Get
+// ---------------------------------------------------------------------------
+
+func TestCreateOrganization(t *testing.T) {
+	svc, _, _, orgID := newTestOrg(t)
+	ctx := context.Background()
+
+	org, err := svc.Get(ctx, 1, orgID)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if org.Role != dto.OrgRoleOwner {
+		t.Errorf("expected creator to be owner, got %q", org.Role)
+	}
+
+	t.Run("duplicate slug", func(t *testing.T) {
+		_, err := svc.Create(ctx, 4, dto.CreateOrganizationRequest{Name: "Other", Slug: "acme"})
+		assertAppErrorCode(t, err, 400)
+	})
+
+	t.Run("non-members cannot see it", func(t *testing.T) {
+		_, err := svc.Get(ctx, 4, orgID)
+		assertAppErrorCode(t, err, 404)
+	})
+}
+
+func TestUpdateAndDeleteOrganization(t *testing.T) {
+	svc, _, _, orgID := newTestOrg(t)
+	ctx := context.Background()
+
+	if _, err := svc.Update(ctx, 2, orgID, dto.UpdateOrganizationRequest{Name: "Acme Inc"}); err != nil {
+		t.Errorf("expected admin to rename, got %v", err)
+	}
+	_, err := svc.Update(ctx, 3, orgID, dto.UpdateOrganizationRequest{Name: "Hijacked"})
+	assertAppErrorCode(t, err, 403)
+
+	assertAppErrorCode(t, svc.Delete(ctx, 2, orgID), 403)
+	if err := svc.Delete(ctx, 1, orgID); err != nil {
+		t.Errorf("expected owner to delete, got %v", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Invitations
+// ---------------------------------------------------------------------------
+
+func TestInviteAndAccept(t *testing.T) {
+	t.Run("invitee joins with the invited role", func(t *testing.T) {
+		svc, repo, sender, orgID := newTestOrg(t)
+		ctx := context.Background()
+
+		if _, err := svc.Invite(ctx, 2, orgID, dto.InviteMemberRequest{Email: "Outsider@Example.com", Role: dto.OrgRoleAdmin}); err != nil {
+			t.Fatalf("Invite: %v", err)
+		}
+		if sender.sent != 1 {
+			t.Fatalf("expected 1 email sent, got %d", sender.sent)
+		}
+		token := invitationTokenPattern.FindStringSubmatch(sender.last.HTML)[1]
+
+		org, err := svc.AcceptInvitation(ctx, 4, token)
+		if err != nil {
+			t.Fatalf("AcceptInvitation: %v", err)
+		}
+		if org.Role != dto.OrgRoleAdmin {
+			t.Errorf("expected admin role, got %q", org.Role)
+		}
+		if len(repo.invitations) != 0 {
+			t.Error("expected invitation to be consumed")
+		}
+	})
+
+	t.Run("members cannot invite", func(t *testing.T) {
+		svc, _, _, orgID := newTestOrg(t)
+
+		_, err := svc.Invite(context.Background(), 3, orgID, dto.InviteMemberRequest{Email: "new@example.com", Role: dto.OrgRoleMember})
+		assertAppErrorCode(t, err, 403)
+	})
+
+	t.Run("existing member", func(t *testing.T) {
+		svc, _, _, orgID := newTestOrg(t)
+
+		_, err := svc.Invite(context.Background(), 1, orgID, dto.InviteMemberRequest{Email: "member@example.com", Role: dto.OrgRoleMember})
+		assertAppErrorCode(t, err, 400)
+	})
+
+	t.Run("invitation for another address", func(t *testing.T) {
+		svc, _, sender, orgID := newTestOrg(t)
+		ctx := context.Background()
+
+		_, _ = svc.Invite(ctx, 1, orgID, dto.InviteMemberRequest{Email: "someone@example.com", Role: dto.OrgRoleMember})
+		token := invitationTokenPattern.FindStringSubmatch(sender.last.HTML)[1]
+
+		_, err := svc.AcceptInvitation(ctx, 4, token)
+		assertAppErrorCode(t, err, 403)
+	})
+
+	t.Run("expired invitation", func(t *testing.T) {
+		svc, repo, sender, orgID := newTestOrg(t)
+		ctx := context.Background()
+
+		_, _ = svc.Invite(ctx, 1, orgID, dto.InviteMemberRequest{Email: "outsider@example.com", Role: dto.OrgRoleMember})
+		token := invitationTokenPattern.FindStringSubmatch(sender.last.HTML)[1]
+		for _, inv := range repo.invitations {
+			inv.ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true}
+		}
+
+		_, err := svc.AcceptInvitation(ctx, 4, token)
+		assertAppErrorCode(t, err, 400)
+	})
+
+	t.Run("re-inviting invalidates the previous link", func(t *testing.T) {
+		svc, _, sender, orgID := newTestOrg(t)
+		ctx := context.Background()
+
+		_, _ = svc.Invite(ctx, 1, orgID, dto.InviteMemberRequest{Email: "outsider@example.com", Role: dto.OrgRoleMember})
+		first := invitationTokenPattern.FindStringSubmatch(sender.last.HTML)[1]
+		_, _ = svc.Invite(ctx, 1, orgID, dto.InviteMemberRequest{Email: "outsider@example.com", Role: dto.OrgRoleMember})
+
+		_, err := svc.AcceptInvitation(ctx, 4, first)
+		assertAppErrorCode(t, err, 400)
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Member management
+// ---------------------------------------------------------------------------
+
+func TestUpdateMemberRole(t *testing.T) {
+	t.Run("admins cannot grant ownership", func(t *testing.T) {
+		svc, _, _, orgID := newTestOrg(t)
+
+		_, err := svc.UpdateMemberRole(context.Background(), 2, orgID, 3, dto.OrgRoleOwner)
+		assertAppErrorCode(t, err, 403)
+	})
+
+	t.Run("last owner cannot be demoted", func(t *testing.T) {
+		svc, _, _, orgID := newTestOrg(t)
+
+		_, err := svc.UpdateMemberRole(context.Background(), 1, orgID, 1, dto.OrgRoleMember)
+		assertAppErrorCode(t, err, 400)
+	})
+
+	t.Run("owner transfers ownership", func(t *testing.T) {
+		svc, _, _, orgID := newTestOrg(t)
+		ctx := context.Background()
+
+		member, err := svc.UpdateMemberRole(ctx, 1, orgID, 2, dto.OrgRoleOwner)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if member.Role != dto.OrgRoleOwner || member.Email != "admin@example.com" {
+			t.Errorf("unexpected member: %+v", member)
+		}
+		if _, err := svc.UpdateMemberRole(ctx, 1, orgID, 1, dto.OrgRoleMember); err != nil {
+			t.Errorf("expected former owner to step down, got %v", err)
+		}
+	})
+}
+
+func TestRemoveMember(t *testing.T) {
+	t.Run("members can leave", func(t *testing.T) {
+		svc, _, _, orgID := newTestOrg(t)
+
+		if err := svc.RemoveMember(context.Background(), 3, orgID, 3); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("members cannot remove others", func(t *testing.T) {
+		svc, _, _, orgID := newTestOrg(t)
+
+		assertAppErrorCode(t, svc.RemoveMember(context.Background(), 3, orgID, 2), 403)
+	})
+
+	t.Run("admins cannot remove owners", func(t *testing.T) {
+		svc, _, _, orgID := newTestOrg(t)
+
+		assertAppErrorCode(t, svc.RemoveMember(context.Background(), 2, orgID, 1), 403)
+	})
+
+	t.Run("last owner cannot leave", func(t *testing.T) {
+		svc, _, _, orgID := newTestOrg(t)
+
+		assertAppErrorCode(t, svc.RemoveMember(context.Background(), 1, orgID, 1), 400)
+	})
+
+	t.Run("member listing reflects removal", func(t *testing.T) {
+		svc, _, _, orgID := newTestOrg(t)
+		ctx := context.Background()
+
+		_ = svc.RemoveMember(ctx, 2, orgID, 3)
+		members, total, err := svc.ListMembers(ctx, 1, orgID, 1, 10)
+		if err != nil {
+			t.Fatalf("ListMembers: %v", err)
+		}
+		if total != 2 || len(members) != 2 {
+			t.Errorf("expected 2 members, got %d (total %d)", len(members), total)
+		}
+	})
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/database"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/email"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/token"
 )
 
 type PasswordResetService interface {
@@ -28,32 +29,38 @@ type PasswordResetService interface {
 }
 
 type passwordResetService struct {
-	userRepo    repository.UserRepository
-	resetRepo   repository.PasswordResetRepository
-	refreshRepo repository.RefreshTokenRepository
-	txManager   *database.TxManager
-	emailSender email.Sender
-	cache       cache.Cache
-	frontendURL string
+	userRepo     repository.UserRepository
+	resetRepo    repository.PasswordResetRepository
+	settingsRepo repository.UserSettingsRepository
+	refreshRepo  repository.RefreshTokenRepository
+	txManager    *database.TxManager
+	emailSender  email.Sender
+	cache        cache.Cache
+	frontendURL  string
+	revocations  *token.RevocationStore
 }
 
 func NewPasswordResetService(
 	userRepo repository.UserRepository,
 	resetRepo repository.PasswordResetRepository,
 	refreshRepo repository.RefreshTokenRepository,
+	settingsRepo repository.UserSettingsRepository,
 	emailSender email.Sender,
 	appCache cache.Cache,
 	frontendURL string,
 	txManager *database.TxManager,
+	revocations *token.RevocationStore,
 ) PasswordResetService {
 	return &passwordResetService{
-		userRepo:    userRepo,
-		resetRepo:   resetRepo,
-		refreshRepo: refreshRepo,
-		txManager:   txManager,
-		emailSender: emailSender,
-		cache:       appCache,
-		frontendURL: frontendURL,
+		userRepo:     userRepo,
+		resetRepo:    resetRepo,
+		refreshRepo:  refreshRepo,
+		settingsRepo: settingsRepo,
+		txManager:    txManager,
+		emailSender:  emailSender,
+		cache:        appCache,
+		frontendURL:  frontendURL,
+		revocations:  revocations,
 	}
 }
 
@@ -97,13 +104,16 @@ func (s *passwordResetService) ForgotPassword(ctx context.Context, req dto.Forgo
 	// Set rate limit
 	_ = s.cache.Set(ctx, cacheKey, []byte("1"), 1*time.Minute)
 
-	// Send email
-	resetURL := fmt.Sprintf("%s/reset-password?token=%s", s.frontendURL, token)
-	if err := s.emailSender.Send(ctx, email.Message{
-		To:      []string{user.Email},
-		Subject: "Password Reset Request",
-		HTML:    fmt.Sprintf("<p>Click <a href=%q>here</a> to reset your password. This link expires in 1 hour.</p>", resetURL),
-	}); err != nil {
+	// Send email in the user's language
+	msg, err := email.Render(email.TemplatePasswordReset, userLocale(ctx, s.settingsRepo, user.ID), map[string]any{
+		"URL":     fmt.Sprintf("%s/reset-password?token=%s", s.frontendURL, token),
+		"Minutes": 60,
+	})
+	if err != nil {
+		return apperror.NewInternal("failed to render reset email")
+	}
+	msg.To = []string{user.Email}
+	if err := s.emailSender.Send(ctx, msg); err != nil {
 		slog.Error("failed to send password reset email", slog.Any("error", err))
 	}
 
@@ -116,6 +126,7 @@ func (s *passwordResetService) ResetPassword(ctx context.Context, req dto.ResetP
 		return apperror.NewInternal("failed to hash password")
 	}
 
+	var userID int64
 	doReset := func(userRepo repository.UserRepository, resetRepo repository.PasswordResetRepository, refreshRepo repository.RefreshTokenRepository, forUpdate bool) error {
 		var rt *sqlc.PasswordResetToken
 		var err error
@@ -151,11 +162,12 @@ func (s *passwordResetService) ResetPassword(ctx context.Context, req dto.ResetP
 		if err := refreshRepo.DeleteByUserID(ctx, rt.UserID); err != nil {
 			return apperror.NewInternal("failed to revoke refresh tokens")
 		}
+		userID = rt.UserID
 		return nil
 	}
 
 	if s.txManager != nil {
-		return s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		err = s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
 			return doReset(
 				repository.NewUserRepository(tx),
 				repository.NewPasswordResetRepository(tx),
@@ -163,7 +175,16 @@ func (s *passwordResetService) ResetPassword(ctx context.Context, req dto.ResetP
 				true,
 			)
 		})
+	} else {
+		err = doReset(s.userRepo, s.resetRepo, s.refreshRepo, false)
+	}
+	if err != nil {
+		return err
 	}
 
-	return doReset(s.userRepo, s.resetRepo, s.refreshRepo, false)
+	// Invalidate access tokens issued before the reset
+	if err := s.revocations.RevokeUser(ctx, userID); err != nil {
+		slog.Error("failed to revoke access tokens", slog.Int64("user_id", userID), slog.Any("error", err))
+	}
+	return nil
 }
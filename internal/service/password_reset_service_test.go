@@ -11,6 +11,7 @@ import (
 
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/token"
 )
 
 func newTestPasswordResetService(
@@ -21,10 +22,11 @@ func newTestPasswordResetService(
 	cache *mockCache,
 ) PasswordResetService {
 	return NewPasswordResetService(
-		userRepo, resetRepo, refreshRepo,
+		userRepo, resetRepo, refreshRepo, newMockUserSettingsRepo(),
 		emailSender, cache,
 		"http://localhost:3000",
 		nil, // no txManager for tests
+		token.NewRevocationStore(cache, time.Hour),
 	)
 }
 
@@ -93,6 +95,26 @@ func TestForgotPassword(t *testing.T) {
 		}
 	})
 
+	t.Run("written in the user's locale", func(t *testing.T) {
+		userRepo := newMockUserRepo()
+		settingsRepo := newMockUserSettingsRepo()
+		emailSender := newMockEmailSender()
+		cache := newMockCache()
+		svc := NewPasswordResetService(
+			userRepo, newMockPasswordResetRepo(), newMockRefreshTokenRepo(), settingsRepo,
+			emailSender, cache, "http://localhost:3000", nil, token.NewRevocationStore(cache, time.Hour),
+		)
+		userRepo.users[1] = &sqlc.User{ID: 1, Email: "test@example.com", Name: "Test", Role: "user"}
+		_, _ = settingsRepo.Upsert(context.Background(), sqlc.UpsertUserSettingsParams{UserID: 1, Locale: pgtype.Text{String: "vi", Valid: true}})
+
+		if err := svc.ForgotPassword(context.Background(), dto.ForgotPasswordRequest{Email: "test@example.com"}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if emailSender.last.Subject != "Yêu cầu đặt lại mật khẩu" || !strings.Contains(emailSender.last.HTML, "60 phút") {
+			t.Errorf("expected a Vietnamese email, got %q: %q", emailSender.last.Subject, emailSender.last.HTML)
+		}
+	})
+
 	t.Run("user not found returns nil (silent fail)", func(t *testing.T) {
 		userRepo := newMockUserRepo()
 		resetRepo := newMockPasswordResetRepo()
@@ -166,6 +188,11 @@ func TestResetPassword(t *testing.T) {
 		if len(resetRepo.tokens) != 0 {
 			t.Errorf("expected reset token to be deleted, got %d tokens", len(resetRepo.tokens))
 		}
+
+		// Verify existing access tokens were revoked
+		if _, ok := cache.items["revoked_user:1"]; !ok {
+			t.Error("expected access tokens to be revoked")
+		}
 	})
 
 	t.Run("expired token", func(t *testing.T) {
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/database"
+)
+
+// PermissionService resolves what users may do from their roles and manages custom roles.
+// A user's permissions are those of their built-in role (users.role) plus those of every
+// custom role assigned to them.
+type PermissionService interface {
+	HasPermission(ctx context.Context, userID int64, permission string) (bool, error)
+	ListPermissions(ctx context.Context) ([]dto.PermissionResponse, error)
+	ListRoles(ctx context.Context) ([]dto.RoleResponse, error)
+	CreateRole(ctx context.Context, req dto.CreateRoleRequest) (*dto.RoleResponse, error)
+	DeleteRole(ctx context.Context, id int64) error
+	GetUserRoles(ctx context.Context, userID int64) (*dto.UserRolesResponse, error)
+	SetUserRoles(ctx context.Context, userID int64, roles []string) (*dto.UserRolesResponse, error)
+}
+
+type permissionService struct {
+	repo      repository.RoleRepository
+	userRepo  repository.UserRepository
+	cache     cache.Cache
+	txManager *database.TxManager
+	auditLog  AuditLogService
+}
+
+func NewPermissionService(
+	repo repository.RoleRepository,
+	userRepo repository.UserRepository,
+	appCache cache.Cache,
+	txManager *database.TxManager,
+	auditLog AuditLogService,
+) PermissionService {
+	return &permissionService{repo: repo, userRepo: userRepo, cache: appCache, txManager: txManager, auditLog: auditLog}
+}
+
+func (s *permissionService) HasPermission(ctx context.Context, userID int64, permission string) (bool, error) {
+	granted, err := s.repo.ListUserPermissions(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(granted, permission), nil
+}
+
+func (s *permissionService) ListPermissions(ctx context.Context) ([]dto.PermissionResponse, error) {
+	perms, err := s.repo.ListPermissions(ctx)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to list permissions")
+	}
+
+	responses := make([]dto.PermissionResponse, len(perms))
+	for i, p := range perms {
+		responses[i] = dto.PermissionResponse{Name: p.Name, Description: p.Description}
+	}
+	return responses, nil
+}
+
+func (s *permissionService) ListRoles(ctx context.Context) ([]dto.RoleResponse, error) {
+	roles, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to list roles")
+	}
+
+	responses := make([]dto.RoleResponse, len(roles))
+	for i, r := range roles {
+		responses[i] = dto.RoleResponse{
+			ID:          r.ID,
+			Name:        r.Name,
+			Description: r.Description,
+			System:      r.IsSystem,
+			Permissions: r.Permissions,
+			CreatedAt:   r.CreatedAt.Time,
+		}
+	}
+	return responses, nil
+}
+
+func (s *permissionService) CreateRole(ctx context.Context, req dto.CreateRoleRequest) (*dto.RoleResponse, error) {
+	permissions := uniqueSorted(req.Permissions)
+
+	var resp *dto.RoleResponse
+	create := func(repo repository.RoleRepository) error {
+		role, err := repo.Create(ctx, req.Name, req.Description)
+		if err != nil {
+			if repository.IsUniqueViolation(err) {
+				return apperror.NewBadRequest("role already exists")
+			}
+			return apperror.NewInternal("failed to create role")
+		}
+
+		granted, err := repo.AddPermissions(ctx, role.ID, permissions)
+		if err != nil {
+			return apperror.NewInternal("failed to grant permissions")
+		}
+		if granted != int64(len(permissions)) {
+			return apperror.NewBadRequest("unknown permission")
+		}
+
+		resp = &dto.RoleResponse{
+			ID:          role.ID,
+			Name:        role.Name,
+			Description: role.Description,
+			Permissions: permissions,
+			CreatedAt:   role.CreatedAt.Time,
+		}
+		return nil
+	}
+
+	if err := s.withTx(ctx, create); err != nil {
+		return nil, err
+	}
+	recordAudit(ctx, s.auditLog, AuditEntry{
+		Action: dto.AuditRoleCreated, TargetType: dto.AuditTargetRole, TargetID: resp.ID,
+		After: map[string]any{"name": resp.Name, "permissions": resp.Permissions},
+	})
+	return resp, nil
+}
+
+func (s *permissionService) DeleteRole(ctx context.Context, id int64) error {
+	if err := s.repo.DeleteCustom(ctx, id); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return apperror.NewNotFound("custom role not found")
+		}
+		return apperror.NewInternal("failed to delete role")
+	}
+	recordAudit(ctx, s.auditLog, AuditEntry{Action: dto.AuditRoleDeleted, TargetType: dto.AuditTargetRole, TargetID: id})
+	return nil
+}
+
+func (s *permissionService) GetUserRoles(ctx context.Context, userID int64) (*dto.UserRolesResponse, error) {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("user not found")
+		}
+		return nil, apperror.NewInternal("failed to get user")
+	}
+
+	return s.userRoles(ctx, userID)
+}
+
+// SetUserRoles replaces the custom roles assigned to a user. Built-in roles are
+// changed through AdminService.UpdateRole instead.
+func (s *permissionService) SetUserRoles(ctx context.Context, userID int64, roles []string) (*dto.UserRolesResponse, error) {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("user not found")
+		}
+		return nil, apperror.NewInternal("failed to get user")
+	}
+
+	before, err := s.repo.ListUserRoles(ctx, userID)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to list user roles")
+	}
+
+	roles = uniqueSorted(roles)
+	replace := func(repo repository.RoleRepository) error {
+		assigned, err := repo.ReplaceUserRoles(ctx, userID, roles)
+		if err != nil {
+			return apperror.NewInternal("failed to assign roles")
+		}
+		if assigned != int64(len(roles)) {
+			return apperror.NewBadRequest("unknown or built-in role")
+		}
+		return nil
+	}
+
+	if err := s.withTx(ctx, replace); err != nil {
+		return nil, err
+	}
+	forgetUser(ctx, s.cache, userID)
+	recordAudit(ctx, s.auditLog, AuditEntry{
+		Action: dto.AuditUserRolesAssigned, TargetType: dto.AuditTargetUser, TargetID: userID,
+		Before: map[string]any{"roles": before},
+		After:  map[string]any{"roles": roles},
+	})
+	return s.userRoles(ctx, userID)
+}
+
+func (s *permissionService) userRoles(ctx context.Context, userID int64) (*dto.UserRolesResponse, error) {
+	roles, err := s.repo.ListUserRoles(ctx, userID)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to list user roles")
+	}
+	permissions, err := s.repo.ListUserPermissions(ctx, userID)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to list user permissions")
+	}
+	return &dto.UserRolesResponse{UserID: userID, Roles: roles, Permissions: permissions}, nil
+}
+
+// withTx runs fn against a transactional repository, or the plain one when no
+// transaction manager is configured (tests).
+func (s *permissionService) withTx(ctx context.Context, fn func(repo repository.RoleRepository) error) error {
+	if s.txManager == nil {
+		return fn(s.repo)
+	}
+	return s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		return fn(repository.NewRoleRepository(tx))
+	})
+}
+
+// uniqueSorted returns a sorted copy of names without duplicates.
+func uniqueSorted(names []string) []string {
+	out := slices.Clone(names)
+	slices.Sort(out)
+	return slices.Compact(out)
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+func newTestPermissionService() (PermissionService, *mockRoleRepo) {
+	users := newMockUserRepo()
+	users.users[1] = &sqlc.User{ID: 1, Email: "admin@example.com", Name: "Admin", Role: dto.RoleAdmin}
+	users.users[2] = &sqlc.User{ID: 2, Email: "user@example.com", Name: "User", Role: dto.RoleUser}
+	roles := newMockRoleRepo(users)
+	return NewPermissionService(roles, users, newMockCache(), nil, nil), roles
+}
+
+func assertAppErrorCode(t *testing.T, err error, code int) {
+	t.Helper()
+	var appErr *apperror.AppError
+	if !errors.As(err, &appErr) {
+		t.Fatalf("expected AppError, got %v", err)
+	}
+	if appErr.Code != code {
+		t.Errorf("expected %d, got %d", code, appErr.Code)
+	}
+}
+
+// assertErrorCode checks both the status and the error_code of an AppError.
+func assertErrorCode(t *testing.T, err error, status int, code string) {
+	t.Helper()
+	assertAppErrorCode(t, err, status)
+	var appErr *apperror.AppError
+	if errors.As(err, &appErr) && appErr.ErrorCode != code {
+		t.Errorf("expected error code %s, got %s", code, appErr.ErrorCode)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// HasPermission
+// ---------------------------------------------------------------------------
+
+func TestHasPermission(t *testing.T) {
+	svc, _ := newTestPermissionService()
+	ctx := context.Background()
+
+	if ok, _ := svc.HasPermission(ctx, 1, dto.PermissionRolesManage); !ok {
+		t.Error("expected admin to hold roles:manage")
+	}
+	if ok, _ := svc.HasPermission(ctx, 2, dto.PermissionUsersList); ok {
+		t.Error("expected plain user to lack users:list")
+	}
+
+	t.Run("custom role grants its permissions", func(t *testing.T) {
+		if _, err := svc.CreateRole(ctx, dto.CreateRoleRequest{
+			Name:        "support",
+			Permissions: []string{dto.PermissionUsersList, dto.PermissionStatsRead},
+		}); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+		if _, err := svc.SetUserRoles(ctx, 2, []string{"support"}); err != nil {
+			t.Fatalf("SetUserRoles: %v", err)
+		}
+
+		if ok, _ := svc.HasPermission(ctx, 2, dto.PermissionUsersList); !ok {
+			t.Error("expected support role to grant users:list")
+		}
+		if ok, _ := svc.HasPermission(ctx, 2, dto.PermissionUsersManage); ok {
+			t.Error("expected support role not to grant users:manage")
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// CreateRole / DeleteRole
+// ---------------------------------------------------------------------------
+
+func TestCreateRole(t *testing.T) {
+	t.Run("deduplicates permissions", func(t *testing.T) {
+		svc, _ := newTestPermissionService()
+
+		role, err := svc.CreateRole(context.Background(), dto.CreateRoleRequest{
+			Name:        "auditor",
+			Permissions: []string{dto.PermissionStatsRead, dto.PermissionFilesManage, dto.PermissionStatsRead},
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		want := []string{dto.PermissionFilesManage, dto.PermissionStatsRead}
+		if !slices.Equal(role.Permissions, want) {
+			t.Errorf("expected %v, got %v", want, role.Permissions)
+		}
+	})
+
+	t.Run("unknown permission", func(t *testing.T) {
+		svc, _ := newTestPermissionService()
+
+		_, err := svc.CreateRole(context.Background(), dto.CreateRoleRequest{
+			Name:        "auditor",
+			Permissions: []string{dto.PermissionStatsRead, "billing:manage"},
+		})
+		assertAppErrorCode(t, err, 400)
+	})
+
+	t.Run("duplicate name", func(t *testing.T) {
+		svc, _ := newTestPermissionService()
+
+		_, err := svc.CreateRole(context.Background(), dto.CreateRoleRequest{
+			Name:        dto.RoleAdmin,
+			Permissions: []string{dto.PermissionStatsRead},
+		})
+		assertAppErrorCode(t, err, 400)
+	})
+}
+
+func TestDeleteRole(t *testing.T) {
+	svc, roles := newTestPermissionService()
+	ctx := context.Background()
+
+	t.Run("built-in roles cannot be deleted", func(t *testing.T) {
+		assertAppErrorCode(t, svc.DeleteRole(ctx, roles.roleByName(dto.RoleAdmin).ID), 404)
+	})
+
+	t.Run("deleting a custom role unassigns it", func(t *testing.T) {
+		role, _ := svc.CreateRole(ctx, dto.CreateRoleRequest{Name: "support", Permissions: []string{dto.PermissionUsersList}})
+		_, _ = svc.SetUserRoles(ctx, 2, []string{"support"})
+
+		if err := svc.DeleteRole(ctx, role.ID); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if ok, _ := svc.HasPermission(ctx, 2, dto.PermissionUsersList); ok {
+			t.Error("expected permission to be gone with the role")
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// SetUserRoles
+// ---------------------------------------------------------------------------
+
+func TestSetUserRoles(t *testing.T) {
+	t.Run("replaces assigned roles", func(t *testing.T) {
+		svc, _ := newTestPermissionService()
+		ctx := context.Background()
+		_, _ = svc.CreateRole(ctx, dto.CreateRoleRequest{Name: "support", Permissions: []string{dto.PermissionUsersList}})
+		_, _ = svc.CreateRole(ctx, dto.CreateRoleRequest{Name: "auditor", Permissions: []string{dto.PermissionStatsRead}})
+
+		_, _ = svc.SetUserRoles(ctx, 2, []string{"support"})
+		resp, err := svc.SetUserRoles(ctx, 2, []string{"auditor"})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !slices.Equal(resp.Roles, []string{"auditor"}) {
+			t.Errorf("expected [auditor], got %v", resp.Roles)
+		}
+		if !slices.Equal(resp.Permissions, []string{dto.PermissionStatsRead}) {
+			t.Errorf("expected [stats:read], got %v", resp.Permissions)
+		}
+	})
+
+	t.Run("drops the cached responses of the user", func(t *testing.T) {
+		users := newMockUserRepo()
+		users.users[2] = &sqlc.User{ID: 2, Email: "user@example.com", Name: "User", Role: dto.RoleUser}
+		appCache := newMockCache()
+		svc := NewPermissionService(newMockRoleRepo(users), users, appCache, nil, nil)
+		ctx := context.Background()
+		_, _ = svc.CreateRole(ctx, dto.CreateRoleRequest{Name: "support", Permissions: []string{dto.PermissionUsersList}})
+
+		appCache.cacheUser(2)
+		if _, err := svc.SetUserRoles(ctx, 2, []string{"support"}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if appCache.userCached(2) {
+			t.Error("expected the cached user dropped")
+		}
+	})
+
+	t.Run("built-in roles are rejected", func(t *testing.T) {
+		svc, _ := newTestPermissionService()
+
+		_, err := svc.SetUserRoles(context.Background(), 2, []string{dto.RoleAdmin})
+		assertAppErrorCode(t, err, 400)
+	})
+
+	t.Run("unknown role", func(t *testing.T) {
+		svc, _ := newTestPermissionService()
+
+		_, err := svc.SetUserRoles(context.Background(), 2, []string{"nope"})
+		assertAppErrorCode(t, err, 400)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		svc, _ := newTestPermissionService()
+
+		_, err := svc.SetUserRoles(context.Background(), 99, nil)
+		assertAppErrorCode(t, err, 404)
+	})
+}
+
+func TestRoleChangesAreAudited(t *testing.T) {
+	users := newMockUserRepo()
+	users.users[2] = &sqlc.User{ID: 2, Email: "user@example.com", Name: "User", Role: dto.RoleUser}
+	audit := newMockAuditLogRepo()
+	svc := NewPermissionService(newMockRoleRepo(users), users, newMockCache(), nil, NewAuditLogService(audit))
+	ctx := context.Background()
+
+	role, err := svc.CreateRole(ctx, dto.CreateRoleRequest{Name: "support", Permissions: []string{dto.PermissionUsersList}})
+	if err != nil {
+		t.Fatalf("CreateRole: %v", err)
+	}
+	if _, err := svc.SetUserRoles(ctx, 2, []string{"support"}); err != nil {
+		t.Fatalf("SetUserRoles: %v", err)
+	}
+	_, _ = svc.SetUserRoles(ctx, 2, []string{"nope"})
+	if err := svc.DeleteRole(ctx, role.ID); err != nil {
+		t.Fatalf("DeleteRole: %v", err)
+	}
+
+	want := []string{dto.AuditRoleCreated, dto.AuditUserRolesAssigned, dto.AuditRoleDeleted}
+	if got := audit.actions(); !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if audit.entries[0].TargetID != role.ID || audit.entries[2].TargetID != role.ID {
+		t.Errorf("expected the role entries to target role %d, got %+v", role.ID, audit.entries)
+	}
+	assigned := audit.entries[1]
+	if assigned.TargetID != 2 || string(assigned.BeforeData) != `{"roles":[]}` || string(assigned.AfterData) != `{"roles":["support"]}` {
+		t.Errorf("unexpected roles_assigned entry %+v", assigned)
+	}
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	"errors"
+	"log/slog"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
@@ -16,8 +19,9 @@ import (
 )
 
 type RefreshTokenService interface {
-	Create(ctx context.Context, userID int64) (string, error)
-	Verify(ctx context.Context, token string) (*sqlc.RefreshToken, error)
+	Create(ctx context.Context, userID int64, fingerprint string) (string, error)
+	Verify(ctx context.Context, token, fingerprint string) (*sqlc.RefreshToken, error)
+	Rotate(ctx context.Context, rt *sqlc.RefreshToken) (string, error)
 	Revoke(ctx context.Context, token string) error
 	RevokeAllByUserID(ctx context.Context, userID int64) error
 }
@@ -36,7 +40,36 @@ func hashToken(token string) string {
 	return hex.EncodeToString(h[:])
 }
 
-func (s *refreshTokenService) Create(ctx context.Context, userID int64) (string, error) {
+// Create issues a refresh token that starts a new token family (e.g. on login).
+// A non-empty fingerprint binds the token and its successors to that device.
+func (s *refreshTokenService) Create(ctx context.Context, userID int64, fingerprint string) (string, error) {
+	var bound pgtype.Text
+	if fingerprint != "" {
+		bound = pgtype.Text{String: hashToken(fingerprint), Valid: true}
+	}
+	return s.issue(ctx, userID, uuid.NewString(), pgtype.Int8{}, bound)
+}
+
+// Rotate marks rt as used and issues its successor in the same token family.
+// If rt was already rotated, the family is treated as compromised.
+func (s *refreshTokenService) Rotate(ctx context.Context, rt *sqlc.RefreshToken) (string, error) {
+	if _, err := s.repo.MarkRotated(ctx, rt.ID); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return "", s.handleReuse(ctx, rt)
+		}
+		return "", apperror.NewInternal("failed to rotate refresh token")
+	}
+
+	return s.issue(ctx, rt.UserID, rt.FamilyID, pgtype.Int8{Int64: rt.ID, Valid: true}, rt.DeviceFingerprint)
+}
+
+func (s *refreshTokenService) issue(
+	ctx context.Context,
+	userID int64,
+	familyID string,
+	parentID pgtype.Int8,
+	deviceFingerprint pgtype.Text,
+) (string, error) {
 	b := make([]byte, 32)
 	if _, err := rand.Read(b); err != nil {
 		return "", apperror.NewInternal("failed to generate refresh token")
@@ -46,9 +79,12 @@ func (s *refreshTokenService) Create(ctx context.Context, userID int64) (string,
 	expiresAt := time.Now().Add(time.Duration(s.expireDays) * 24 * time.Hour)
 
 	_, err := s.repo.Create(ctx, sqlc.CreateRefreshTokenParams{
-		UserID:    userID,
-		Token:     hashToken(plainToken), // Store hash, not plaintext
-		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: true},
+		UserID:            userID,
+		Token:             hashToken(plainToken), // Store hash, not plaintext
+		ExpiresAt:         pgtype.Timestamptz{Time: expiresAt, Valid: true},
+		FamilyID:          familyID,
+		ParentID:          parentID,
+		DeviceFingerprint: deviceFingerprint,
 	})
 	if err != nil {
 		return "", apperror.NewInternal("failed to store refresh token")
@@ -57,7 +93,9 @@ func (s *refreshTokenService) Create(ctx context.Context, userID int64) (string,
 	return plainToken, nil // Return plaintext to client
 }
 
-func (s *refreshTokenService) Verify(ctx context.Context, token string) (*sqlc.RefreshToken, error) {
+// Verify looks up an active refresh token. Tokens bound to a device are only accepted
+// together with the fingerprint they were issued for.
+func (s *refreshTokenService) Verify(ctx context.Context, token, fingerprint string) (*sqlc.RefreshToken, error) {
 	rt, err := s.repo.GetByToken(ctx, hashToken(token)) // Lookup by hash
 	if err != nil {
 		if errors.Is(err, apperror.ErrNotFound) {
@@ -66,14 +104,41 @@ func (s *refreshTokenService) Verify(ctx context.Context, token string) (*sqlc.R
 		return nil, apperror.NewInternal("failed to verify refresh token")
 	}
 
+	// A rotated token should never be presented again; someone is replaying a stolen copy
+	if rt.RotatedAt.Valid {
+		return nil, s.handleReuse(ctx, rt)
+	}
+
 	if rt.ExpiresAt.Time.Before(time.Now()) {
 		_ = s.repo.Delete(ctx, hashToken(token))
 		return nil, apperror.NewUnauthorized("refresh token expired")
 	}
 
+	if rt.DeviceFingerprint.Valid &&
+		subtle.ConstantTimeCompare([]byte(rt.DeviceFingerprint.String), []byte(hashToken(fingerprint))) != 1 {
+		slog.Warn("refresh token presented from a different device",
+			slog.Int64("user_id", rt.UserID),
+			slog.String("family_id", rt.FamilyID),
+		)
+		return nil, apperror.NewUnauthorized("refresh token is bound to another device")
+	}
+
 	return rt, nil
 }
 
+// handleReuse revokes every refresh token of the user once a rotated token is replayed,
+// forcing both the attacker and the legitimate client to log in again.
+func (s *refreshTokenService) handleReuse(ctx context.Context, rt *sqlc.RefreshToken) error {
+	slog.Warn("refresh token reuse detected, revoking token family",
+		slog.Int64("user_id", rt.UserID),
+		slog.String("family_id", rt.FamilyID),
+	)
+	if err := s.RevokeAllByUserID(ctx, rt.UserID); err != nil {
+		slog.Error("failed to revoke refresh tokens", slog.Int64("user_id", rt.UserID), slog.Any("error", err))
+	}
+	return apperror.NewUnauthorized("refresh token reuse detected")
+}
+
 func (s *refreshTokenService) Revoke(ctx context.Context, token string) error {
 	return s.repo.Delete(ctx, hashToken(token)) // Delete by hash
 }
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+// ---------------------------------------------------------------------------
+// Rotate
+// ---------------------------------------------------------------------------
+
+func TestRotateRefreshToken(t *testing.T) {
+	t.Run("successor stays in the same family", func(t *testing.T) {
+		repo := newMockRefreshTokenRepo()
+		svc := NewRefreshTokenService(repo, 30)
+		ctx := context.Background()
+
+		first, _ := svc.Create(ctx, 1, "")
+		rt, err := svc.Verify(ctx, first, "")
+		if err != nil {
+			t.Fatalf("Verify: %v", err)
+		}
+
+		second, err := svc.Rotate(ctx, rt)
+		if err != nil {
+			t.Fatalf("Rotate: %v", err)
+		}
+
+		next, err := svc.Verify(ctx, second, "")
+		if err != nil {
+			t.Fatalf("Verify rotated token: %v", err)
+		}
+		if next.FamilyID != rt.FamilyID {
+			t.Errorf("expected family %s, got %s", rt.FamilyID, next.FamilyID)
+		}
+		if !next.ParentID.Valid || next.ParentID.Int64 != rt.ID {
+			t.Errorf("expected parent %d, got %+v", rt.ID, next.ParentID)
+		}
+	})
+
+	t.Run("new logins start a new family", func(t *testing.T) {
+		repo := newMockRefreshTokenRepo()
+		svc := NewRefreshTokenService(repo, 30)
+		ctx := context.Background()
+
+		a, _ := svc.Create(ctx, 1, "")
+		b, _ := svc.Create(ctx, 1, "")
+		rtA, _ := svc.Verify(ctx, a, "")
+		rtB, _ := svc.Verify(ctx, b, "")
+		if rtA.FamilyID == rtB.FamilyID {
+			t.Error("expected distinct families for separate logins")
+		}
+	})
+
+	t.Run("reusing a rotated token revokes all tokens", func(t *testing.T) {
+		repo := newMockRefreshTokenRepo()
+		svc := NewRefreshTokenService(repo, 30)
+		ctx := context.Background()
+
+		first, _ := svc.Create(ctx, 1, "")
+		rt, _ := svc.Verify(ctx, first, "")
+		second, _ := svc.Rotate(ctx, rt)
+
+		// Replay of the rotated token
+		_, err := svc.Verify(ctx, first, "")
+		var appErr *apperror.AppError
+		if !errors.As(err, &appErr) {
+			t.Fatalf("expected AppError, got %T", err)
+		}
+		if appErr.Code != 401 {
+			t.Errorf("expected 401, got %d", appErr.Code)
+		}
+		if len(repo.deletedUserIDs) != 1 || repo.deletedUserIDs[0] != 1 {
+			t.Errorf("expected tokens of user 1 to be revoked, got %v", repo.deletedUserIDs)
+		}
+
+		// The legitimate successor is revoked too
+		if _, err := svc.Verify(ctx, second, ""); err == nil {
+			t.Error("expected successor token to be revoked")
+		}
+	})
+
+	t.Run("concurrent rotation of the same token is treated as reuse", func(t *testing.T) {
+		repo := newMockRefreshTokenRepo()
+		svc := NewRefreshTokenService(repo, 30)
+		ctx := context.Background()
+
+		first, _ := svc.Create(ctx, 1, "")
+		rt, _ := svc.Verify(ctx, first, "")
+		if _, err := svc.Rotate(ctx, rt); err != nil {
+			t.Fatalf("Rotate: %v", err)
+		}
+
+		if _, err := svc.Rotate(ctx, rt); err == nil {
+			t.Fatal("expected second rotation to fail")
+		}
+		if len(repo.deletedUserIDs) != 1 {
+			t.Errorf("expected user tokens to be revoked, got %v", repo.deletedUserIDs)
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Device binding
+// ---------------------------------------------------------------------------
+
+func TestVerifyRefreshToken_DeviceBinding(t *testing.T) {
+	t.Run("bound token is accepted from the same device", func(t *testing.T) {
+		svc := NewRefreshTokenService(newMockRefreshTokenRepo(), 30)
+		ctx := context.Background()
+
+		tok, _ := svc.Create(ctx, 1, "device-a")
+		if _, err := svc.Verify(ctx, tok, "device-a"); err != nil {
+			t.Fatalf("expected token to verify, got %v", err)
+		}
+	})
+
+	t.Run("bound token is rejected from another device", func(t *testing.T) {
+		svc := NewRefreshTokenService(newMockRefreshTokenRepo(), 30)
+		ctx := context.Background()
+
+		tok, _ := svc.Create(ctx, 1, "device-a")
+		for _, fp := range []string{"device-b", ""} {
+			_, err := svc.Verify(ctx, tok, fp)
+			var appErr *apperror.AppError
+			if !errors.As(err, &appErr) || appErr.Code != 401 {
+				t.Errorf("fingerprint %q: expected 401, got %v", fp, err)
+			}
+		}
+	})
+
+	t.Run("fingerprint is stored hashed", func(t *testing.T) {
+		repo := newMockRefreshTokenRepo()
+		svc := NewRefreshTokenService(repo, 30)
+
+		tok, _ := svc.Create(context.Background(), 1, "device-a")
+		rt := repo.tokens[hashToken(tok)]
+		if !rt.DeviceFingerprint.Valid || rt.DeviceFingerprint.String != hashToken("device-a") {
+			t.Errorf("expected hashed fingerprint, got %+v", rt.DeviceFingerprint)
+		}
+	})
+
+	t.Run("rotated token stays bound to the device", func(t *testing.T) {
+		svc := NewRefreshTokenService(newMockRefreshTokenRepo(), 30)
+		ctx := context.Background()
+
+		first, _ := svc.Create(ctx, 1, "device-a")
+		rt, _ := svc.Verify(ctx, first, "device-a")
+		second, err := svc.Rotate(ctx, rt)
+		if err != nil {
+			t.Fatalf("Rotate: %v", err)
+		}
+		if _, err := svc.Verify(ctx, second, "device-b"); err == nil {
+			t.Error("expected successor to reject another device")
+		}
+		if _, err := svc.Verify(ctx, second, "device-a"); err != nil {
+			t.Errorf("expected successor to verify, got %v", err)
+		}
+	})
+
+	t.Run("unbound token accepts any device", func(t *testing.T) {
+		svc := NewRefreshTokenService(newMockRefreshTokenRepo(), 30)
+		ctx := context.Background()
+
+		tok, _ := svc.Create(ctx, 1, "")
+		if _, err := svc.Verify(ctx, tok, "device-b"); err != nil {
+			t.Errorf("expected unbound token to verify, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/email"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/pagination"
+)
+
+// InvitationService lets admins invite people to register while invite-only mode is enabled.
+type InvitationService interface {
+	Create(ctx context.Context, actorID int64, req dto.CreateInvitationRequest) (*dto.InvitationResponse, error)
+	List(ctx context.Context, page, perPage int) ([]dto.InvitationResponse, int64, error)
+	Revoke(ctx context.Context, id int64) error
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+type invitationService struct {
+	repo        repository.RegistrationInvitationRepository
+	userRepo    repository.UserRepository
+	sender      email.Sender
+	ttl         time.Duration
+	frontendURL string
+	auditLog    AuditLogService
+}
+
+func NewInvitationService(
+	repo repository.RegistrationInvitationRepository,
+	userRepo repository.UserRepository,
+	sender email.Sender,
+	ttlHours int,
+	frontendURL string,
+	auditLog AuditLogService,
+) InvitationService {
+	return &invitationService{
+		repo:        repo,
+		userRepo:    userRepo,
+		sender:      sender,
+		ttl:         time.Duration(ttlHours) * time.Hour,
+		frontendURL: frontendURL,
+		auditLog:    auditLog,
+	}
+}
+
+// Create emails a registration link to the address. Inviting the same address again
+// replaces the pending invitation, invalidating the previous link.
+func (s *invitationService) Create(ctx context.Context, actorID int64, req dto.CreateInvitationRequest) (*dto.InvitationResponse, error) {
+	existing, err := s.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		return nil, apperror.NewInternal("failed to check existing user")
+	}
+	if existing != nil {
+		return nil, apperror.NewBadRequest("email already registered")
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, apperror.NewInternal("failed to generate invitation token")
+	}
+	token := hex.EncodeToString(b)
+
+	inv, err := s.repo.Upsert(ctx, sqlc.UpsertRegistrationInvitationParams{
+		Email:     strings.ToLower(req.Email),
+		Token:     hashToken(token), // Store hash, not plaintext
+		InvitedBy: pgtype.Int8{Int64: actorID, Valid: true},
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(s.ttl), Valid: true},
+	})
+	if err != nil {
+		return nil, apperror.NewInternal("failed to create invitation")
+	}
+
+	registerURL := fmt.Sprintf("%s/register?invite=%s", s.frontendURL, token)
+	if err := s.sender.Send(ctx, email.Message{
+		To:      []string{inv.Email},
+		Subject: "You're invited to create an account",
+		HTML: fmt.Sprintf("<p>You have been invited to create an account. Click <a href=%q>here</a> to register. This link expires on %s.</p>",
+			registerURL, email.FormatTime(inv.ExpiresAt.Time, "")),
+	}); err != nil {
+		slog.Error("failed to send registration invitation", slog.Int64("invitation_id", inv.ID), slog.Any("error", err))
+	}
+	recordAudit(ctx, s.auditLog, AuditEntry{
+		Action: dto.AuditInvitationCreated, TargetType: dto.AuditTargetInvitation, TargetID: inv.ID,
+		After: map[string]any{"email": inv.Email},
+	})
+
+	return toInvitationResponse(inv), nil
+}
+
+func (s *invitationService) List(ctx context.Context, page, perPage int) ([]dto.InvitationResponse, int64, error) {
+	limit, offset := pagination.LimitOffset(page, perPage)
+
+	invitations, err := s.repo.List(ctx, limit, offset)
+	if err != nil {
+		return nil, 0, apperror.NewInternal("failed to list invitations")
+	}
+
+	total, err := s.repo.Count(ctx)
+	if err != nil {
+		return nil, 0, apperror.NewInternal("failed to count invitations")
+	}
+
+	result := make([]dto.InvitationResponse, len(invitations))
+	for i := range invitations {
+		result[i] = *toInvitationResponse(&invitations[i])
+	}
+	return result, total, nil
+}
+
+// Revoke deletes a pending invitation so its link can no longer be used.
+func (s *invitationService) Revoke(ctx context.Context, id int64) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return apperror.NewNotFound("invitation not found")
+		}
+		return apperror.NewInternal("failed to revoke invitation")
+	}
+	recordAudit(ctx, s.auditLog, AuditEntry{Action: dto.AuditInvitationRevoked, TargetType: dto.AuditTargetInvitation, TargetID: id})
+	return nil
+}
+
+// PurgeExpired removes invitations that expired without being used.
+func (s *invitationService) PurgeExpired(ctx context.Context) (int64, error) {
+	n, err := s.repo.DeleteExpired(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("delete expired invitations: %w", err)
+	}
+	if n > 0 {
+		slog.Info("purged expired invitations", slog.Int64("count", n))
+	}
+	return n, nil
+}
+
+func toInvitationResponse(inv *sqlc.RegistrationInvitation) *dto.InvitationResponse {
+	resp := &dto.InvitationResponse{
+		ID:        inv.ID,
+		Email:     inv.Email,
+		ExpiresAt: inv.ExpiresAt.Time,
+		CreatedAt: inv.CreatedAt.Time,
+	}
+	if inv.InvitedBy.Valid {
+		resp.InvitedBy = &inv.InvitedBy.Int64
+	}
+	return resp
+}
+
+// inviteGate enforces invite-only registration on every path that creates an account.
+// When invitations are not required it lets every signup through.
+type inviteGate struct {
+	repo     repository.RegistrationInvitationRepository
+	required bool
+}
+
+// check returns the invitation that lets the email register with the token, or nil
+// when invitations are not required.
+func (g inviteGate) check(ctx context.Context, token, email string) (*sqlc.RegistrationInvitation, error) {
+	if !g.required {
+		return nil, nil
+	}
+	if token == "" {
+		return nil, apperror.NewForbidden("registration requires an invitation")
+	}
+
+	inv, err := g.repo.GetByToken(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewBadRequest("invalid or expired invitation")
+		}
+		return nil, apperror.NewInternal("failed to verify invitation")
+	}
+	if inv.ExpiresAt.Time.Before(time.Now()) {
+		return nil, apperror.NewBadRequest("invitation has expired")
+	}
+	if !strings.EqualFold(inv.Email, email) {
+		return nil, apperror.NewForbidden("invitation was sent to a different email address")
+	}
+	return inv, nil
+}
+
+// checkEmail is check for identity providers, which vouch for the email address
+// instead of presenting a token: any pending invitation for the address is accepted.
+func (g inviteGate) checkEmail(ctx context.Context, email string) (*sqlc.RegistrationInvitation, error) {
+	if !g.required {
+		return nil, nil
+	}
+
+	inv, err := g.repo.GetByEmail(ctx, email)
+	if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		return nil, apperror.NewInternal("failed to verify invitation")
+	}
+	if inv == nil || inv.ExpiresAt.Time.Before(time.Now()) {
+		return nil, apperror.NewForbidden("registration requires an invitation")
+	}
+	return inv, nil
+}
+
+// consume deletes an invitation once its account exists. A failure is only logged: the
+// address is now registered, so the invitation cannot be used again.
+func (g inviteGate) consume(ctx context.Context, inv *sqlc.RegistrationInvitation) {
+	if inv == nil {
+		return
+	}
+	if err := g.repo.Delete(ctx, inv.ID); err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		slog.Error("failed to consume registration invitation", slog.Int64("invitation_id", inv.ID), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+)
+
+var inviteTokenPattern = regexp.MustCompile(`invite=([0-9a-f]+)`)
+
+type invitationFixture struct {
+	svc         InvitationService
+	invitations *mockRegistrationInvitationRepo
+	users       *mockUserRepo
+	sender      *mockEmailSender
+	audit       *mockAuditLogRepo
+}
+
+func newInvitationFixture() *invitationFixture {
+	f := &invitationFixture{
+		invitations: newMockRegistrationInvitationRepo(),
+		users:       newMockUserRepo(),
+		sender:      newMockEmailSender(),
+		audit:       newMockAuditLogRepo(),
+	}
+	f.svc = NewInvitationService(f.invitations, f.users, f.sender, 24, "http://frontend", NewAuditLogService(f.audit))
+	return f
+}
+
+// invite creates an invitation and returns the token from the emailed link.
+func (f *invitationFixture) invite(t *testing.T, email string) string {
+	t.Helper()
+	if _, err := f.svc.Create(context.Background(), 1, dto.CreateInvitationRequest{Email: email}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	m := inviteTokenPattern.FindStringSubmatch(f.sender.last.HTML)
+	if m == nil {
+		t.Fatalf("expected invite link in email, got %q", f.sender.last.HTML)
+	}
+	return m[1]
+}
+
+func TestCreateInvitation(t *testing.T) {
+	t.Run("emails link and stores token hash", func(t *testing.T) {
+		f := newInvitationFixture()
+
+		token := f.invite(t, "New@Example.com")
+		if f.sender.last.To[0] != "new@example.com" {
+			t.Errorf("expected invitation sent to new@example.com, got %v", f.sender.last.To)
+		}
+		inv, err := f.invitations.GetByEmail(context.Background(), "new@example.com")
+		if err != nil {
+			t.Fatalf("expected stored invitation, got %v", err)
+		}
+		if inv.Token != hashToken(token) {
+			t.Error("expected token to be stored hashed")
+		}
+		if inv.InvitedBy.Int64 != 1 {
+			t.Errorf("expected invited_by 1, got %d", inv.InvitedBy.Int64)
+		}
+		if len(f.audit.entries) != 1 || f.audit.entries[0].Action != dto.AuditInvitationCreated || f.audit.entries[0].TargetID != inv.ID {
+			t.Errorf("expected an invitation.created entry, got %+v", f.audit.entries)
+		}
+	})
+
+	t.Run("inviting again replaces the pending invitation", func(t *testing.T) {
+		f := newInvitationFixture()
+
+		first := f.invite(t, "new@example.com")
+		second := f.invite(t, "new@example.com")
+		if first == second {
+			t.Fatal("expected a fresh token")
+		}
+		if len(f.invitations.invitations) != 1 {
+			t.Errorf("expected 1 invitation, got %d", len(f.invitations.invitations))
+		}
+	})
+
+	t.Run("registered email", func(t *testing.T) {
+		f := newInvitationFixture()
+		f.users.users[1] = &sqlc.User{ID: 1, Email: "user@example.com"}
+
+		_, err := f.svc.Create(context.Background(), 1, dto.CreateInvitationRequest{Email: "user@example.com"})
+		assertAppErrorCode(t, err, 400)
+	})
+}
+
+func TestRevokeInvitation(t *testing.T) {
+	f := newInvitationFixture()
+	f.invite(t, "new@example.com")
+
+	if err := f.svc.Revoke(context.Background(), 1); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	assertAppErrorCode(t, f.svc.Revoke(context.Background(), 1), 404)
+	if got := f.audit.actions(); !slices.Equal(got, []string{dto.AuditInvitationCreated, dto.AuditInvitationRevoked}) {
+		t.Errorf("expected the revocation audited once, got %v", got)
+	}
+}
+
+func TestPurgeExpiredInvitations(t *testing.T) {
+	f := newInvitationFixture()
+	f.invite(t, "fresh@example.com")
+	f.invite(t, "stale@example.com")
+	f.invitations.invitations[2].ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true}
+
+	n, err := f.svc.PurgeExpired(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if n != 1 || len(f.invitations.invitations) != 1 {
+		t.Errorf("expected 1 invitation purged, got %d (%d left)", n, len(f.invitations.invitations))
+	}
+}
+
+func TestRegisterInviteOnly(t *testing.T) {
+	setup := func(t *testing.T) (*invitationFixture, UserService, string) {
+		f := newInvitationFixture()
+		token := f.invite(t, "new@example.com")
+		svc := NewUserService(f.users, newMockRefreshTokenRepo(), f.invitations, newMockUserSettingsRepo(), openSettings, true, EmailDomainPolicy{}, nil, newMockCache(), nil)
+		return f, svc, token
+	}
+	register := func(svc UserService, email, token string) error {
+		_, err := svc.Register(context.Background(), dto.RegisterRequest{
+			Email: email, Password: "Password1!", Name: "New User", InviteToken: token,
+		})
+		return err
+	}
+
+	t.Run("valid invitation is consumed", func(t *testing.T) {
+		f, svc, token := setup(t)
+
+		if err := register(svc, "new@example.com", token); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(f.invitations.invitations) != 0 {
+			t.Error("expected invitation to be consumed")
+		}
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		_, svc, _ := setup(t)
+		assertAppErrorCode(t, register(svc, "new@example.com", ""), 403)
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		_, svc, _ := setup(t)
+		assertAppErrorCode(t, register(svc, "new@example.com", "bogus"), 400)
+	})
+
+	t.Run("different email", func(t *testing.T) {
+		_, svc, token := setup(t)
+		assertAppErrorCode(t, register(svc, "other@example.com", token), 403)
+	})
+
+	t.Run("expired invitation", func(t *testing.T) {
+		f, svc, token := setup(t)
+		f.invitations.invitations[1].ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true}
+		assertAppErrorCode(t, register(svc, "new@example.com", token), 400)
+	})
+
+	t.Run("oauth signup needs a pending invitation", func(t *testing.T) {
+		f, svc, _ := setup(t)
+
+		_, err := svc.FindOrCreateByGitHub(context.Background(), "gh-1", "stranger@example.com", "Stranger")
+		assertAppErrorCode(t, err, 403)
+
+		user, err := svc.FindOrCreateByGitHub(context.Background(), "gh-2", "New@Example.com", "New User")
+		if err != nil {
+			t.Fatalf("expected invited user to sign up, got %v", err)
+		}
+		if user.GithubID.String != "gh-2" {
+			t.Errorf("expected github ID gh-2, got %q", user.GithubID.String)
+		}
+		if len(f.invitations.invitations) != 0 {
+			t.Error("expected invitation to be consumed")
+		}
+	})
+}
@@ -0,0 +1,684 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/async"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/database"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/imaging"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/realtime"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/storage"
+)
+
+const (
+	// uploadSessionSweepBatchSize caps how many expired upload sessions a single PurgeExpired run removes.
+	uploadSessionSweepBatchSize = 100
+
+	// uploadProgressPrefix keys the progress of a resumable upload in the cache.
+	uploadProgressPrefix = "upload_progress:"
+	// uploadProgressFlushBytes and uploadProgressFlushInterval bound how often the progress
+	// of a chunk or assembly still being written is saved to the cache.
+	uploadProgressFlushBytes    = 1 << 20
+	uploadProgressFlushInterval = time.Second
+	// uploadProgressCompletedTTL is how long a completed upload still reports its progress.
+	uploadProgressCompletedTTL = 10 * time.Minute
+)
+
+// ResumableUploadService accepts large files in chunks so an interrupted upload can
+// continue from the last received byte instead of starting over. The bytes written to
+// storage are tracked in the cache, where Progress reads them for holders of the signed
+// token returned with the session.
+type ResumableUploadService interface {
+	Create(ctx context.Context, userID int64, req dto.CreateUploadSessionRequest) (*dto.UploadSessionResponse, error)
+	Status(ctx context.Context, id, userID int64) (*dto.UploadSessionResponse, error)
+	Append(ctx context.Context, id, userID, offset int64, chunk io.Reader, size int64, contentType string) (*dto.UploadSessionResponse, error)
+	Finalize(ctx context.Context, id, userID int64) (*dto.FileResponse, error)
+	Abort(ctx context.Context, id, userID int64) error
+	Progress(ctx context.Context, id int64, token string) (*dto.UploadProgressResponse, error)
+	PurgeExpired(ctx context.Context) (int, error)
+}
+
+type resumableUploadService struct {
+	repo             repository.UploadSessionRepository
+	fileRepo         repository.FileRepository
+	storage          storage.Storage
+	variantSvc       ImageVariantService
+	cache            cache.Cache
+	ttl              time.Duration
+	signingKey       []byte
+	contentAddressed bool
+	stripMetadata    bool
+	txManager        *database.TxManager
+	events           realtime.Publisher
+}
+
+func NewResumableUploadService(
+	repo repository.UploadSessionRepository,
+	fileRepo repository.FileRepository,
+	store storage.Storage,
+	variantSvc ImageVariantService,
+	appCache cache.Cache,
+	ttlHours int,
+	signingKey string,
+	contentAddressed bool,
+	stripMetadata bool,
+	txManager *database.TxManager,
+	events realtime.Publisher,
+) ResumableUploadService {
+	return &resumableUploadService{
+		repo:             repo,
+		fileRepo:         fileRepo,
+		storage:          store,
+		variantSvc:       variantSvc,
+		cache:            appCache,
+		ttl:              time.Duration(ttlHours) * time.Hour,
+		signingKey:       []byte(signingKey),
+		contentAddressed: contentAddressed,
+		stripMetadata:    stripMetadata,
+		txManager:        txManager,
+		events:           events,
+	}
+}
+
+// Create opens an upload session for a file of the given size.
+func (s *resumableUploadService) Create(ctx context.Context, userID int64, req dto.CreateUploadSessionRequest) (*dto.UploadSessionResponse, error) {
+	session, err := s.repo.Create(ctx, sqlc.CreateUploadSessionParams{
+		UserID:    userID,
+		Filename:  req.Filename,
+		Size:      req.Size,
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(s.ttl), Valid: true},
+	})
+	if err != nil {
+		return nil, apperror.NewInternal("failed to create upload session")
+	}
+	s.saveProgress(ctx, session, dto.UploadReceiving, 0)
+	return s.response(session), nil
+}
+
+// Status reports how much of the file has been received, so a client that lost its
+// connection knows where to resume.
+func (s *resumableUploadService) Status(ctx context.Context, id, userID int64) (*dto.UploadSessionResponse, error) {
+	session, err := s.session(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.response(session), nil
+}
+
+// Append stores the chunk that starts at offset. The offset must match the bytes already
+// received, so a chunk that is replayed or sent out of order is rejected with a conflict
+// and the client can ask for the current offset. contentType is recorded from the first
+// chunk.
+func (s *resumableUploadService) Append(
+	ctx context.Context,
+	id, userID, offset int64,
+	chunk io.Reader,
+	size int64,
+	contentType string,
+) (*dto.UploadSessionResponse, error) {
+	session, err := s.session(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if offset != session.Received {
+		return nil, apperror.NewConflict(fmt.Sprintf("upload offset mismatch: expected %d", session.Received))
+	}
+	if size < 1 {
+		return nil, apperror.NewBadRequest("chunk is empty")
+	}
+	if offset+size > session.Size {
+		return nil, apperror.NewBadRequest("chunk exceeds the declared upload size")
+	}
+
+	partPath := fmt.Sprintf("uploads/%d/%d/%s.part", userID, id, uuid.New().String())
+	chunk = s.progressReader(ctx, session, dto.UploadReceiving, offset, chunk)
+	if err := s.storage.Put(ctx, partPath, chunk, size, "application/octet-stream"); err != nil {
+		s.clearProgress(ctx, id)
+		return nil, apperror.NewInternal("failed to store chunk")
+	}
+
+	appendPart := func(repo repository.UploadSessionRepository) error {
+		var err error
+		session, err = repo.Advance(ctx, sqlc.AdvanceUploadSessionParams{
+			ChunkSize: size,
+			MimeType:  contentType,
+			ID:        id,
+			Received:  offset,
+		})
+		if err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				// Another request for this offset was accepted first.
+				return apperror.NewConflict("upload offset mismatch")
+			}
+			return apperror.NewInternal("failed to update upload session")
+		}
+		if err := repo.CreatePart(ctx, sqlc.CreateUploadSessionPartParams{
+			SessionID:   id,
+			Offset:      offset,
+			StoragePath: partPath,
+			Size:        size,
+		}); err != nil {
+			return apperror.NewInternal("failed to record chunk")
+		}
+		return nil
+	}
+
+	if s.txManager != nil {
+		err = s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+			return appendPart(repository.NewUploadSessionRepository(tx))
+		})
+	} else {
+		err = appendPart(s.repo)
+	}
+	if err != nil {
+		_ = s.storage.Delete(ctx, partPath)
+		s.clearProgress(ctx, id)
+		return nil, err
+	}
+	s.saveProgress(ctx, session, dto.UploadReceiving, session.Received)
+	return s.response(session), nil
+}
+
+// Finalize assembles the received chunks into a single stored file once every byte has
+// arrived, and ends the session.
+func (s *resumableUploadService) Finalize(ctx context.Context, id, userID int64) (*dto.FileResponse, error) {
+	session, err := s.session(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Received != session.Size {
+		return nil, apperror.NewConflict(fmt.Sprintf("upload is incomplete: received %d of %d bytes", session.Received, session.Size))
+	}
+
+	parts, err := s.repo.ListParts(ctx, id)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to list upload chunks")
+	}
+
+	// Until the file is saved, a failure drops the assembly progress so Progress falls back
+	// to the session, which still holds every chunk.
+	completed := false
+	defer func() {
+		if !completed {
+			s.clearProgress(ctx, id)
+		}
+	}()
+
+	storagePath, checksum, size, source, err := s.assemble(ctx, session, parts)
+	if err != nil {
+		return nil, err
+	}
+	keyID := encryptionKeyID(s.storage)
+	if source != nil {
+		keyID = source.EncryptionKeyID
+	}
+
+	var file *sqlc.File
+	complete := func(repo repository.UploadSessionRepository, fileRepo repository.FileRepository) error {
+		if err := repo.Delete(ctx, id); err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				// A concurrent finalize already turned this session into a file.
+				return apperror.NewNotFound("upload not found")
+			}
+			return apperror.NewInternal("failed to close upload session")
+		}
+		var err error
+		file, err = fileRepo.Create(ctx, sqlc.CreateFileParams{
+			UserID:          userID,
+			OriginalName:    session.Filename,
+			StoragePath:     storagePath,
+			MimeType:        session.MimeType,
+			Size:            size,
+			Checksum:        pgtype.Text{String: checksum, Valid: true},
+			EncryptionKeyID: keyID,
+		})
+		if err != nil {
+			return apperror.NewInternal("failed to save file metadata")
+		}
+		return nil
+	}
+
+	if s.txManager != nil {
+		err = s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+			return complete(repository.NewUploadSessionRepository(tx), repository.NewFileRepository(tx))
+		})
+	} else {
+		err = complete(s.repo, s.fileRepo)
+	}
+	if err != nil {
+		// A content-addressed object may be shared by a concurrent upload of the same
+		// content, so it is left for storage reconciliation.
+		if source == nil && !s.contentAddressed {
+			_ = s.storage.Delete(ctx, storagePath)
+		}
+		return nil, err
+	}
+
+	completed = true
+	s.saveCompleted(ctx, session, file.ID)
+	s.deleteParts(ctx, id, parts)
+	if source != nil {
+		s.variantSvc.Reuse(ctx, file, source)
+	} else {
+		s.variantSvc.Generate(ctx, file)
+	}
+	resp := toFileResponse(s.storage, file, nil, nil)
+	s.events.PublishToUser(userID, realtime.Event{Type: dto.RealtimeEventFileUploaded, Data: resp})
+	return resp, nil
+}
+
+// assemble stores the upload's content as one object and returns its path, checksum and
+// size, along with the file whose object is reused instead, if any. The content is read
+// once to hash it before it is stored when the checksum is needed for the path, with the
+// content-addressed layout, or when the stored size differs from the upload's, for a JPEG
+// whose metadata is stripped. Otherwise the chunks are stored and hashed in a single pass,
+// and an identical file the user already has replaces the new object afterwards.
+func (s *resumableUploadService) assemble(
+	ctx context.Context,
+	session *sqlc.UploadSession,
+	parts []sqlc.UploadSessionPart,
+) (string, string, int64, *sqlc.File, error) {
+	if s.contentAddressed || s.sanitizes(session) {
+		hash := sha256.New()
+		reader := s.content(ctx, session, parts, false)
+		size, err := io.Copy(hash, reader)
+		_ = reader.Close()
+		if err != nil {
+			if errors.Is(err, imaging.ErrInvalidJPEG) {
+				return "", "", 0, nil, apperror.NewBadRequest("file is not a valid JPEG image")
+			}
+			return "", "", 0, nil, apperror.NewInternal("failed to read upload chunks")
+		}
+		checksum := hex.EncodeToString(hash.Sum(nil))
+
+		source, err := findDuplicate(ctx, s.fileRepo, session.UserID, checksum, s.contentAddressed)
+		if err != nil {
+			return "", "", 0, nil, apperror.NewInternal("failed to look up duplicate files")
+		}
+		if source != nil {
+			return source.StoragePath, checksum, size, source, nil
+		}
+
+		storagePath := newObjectPath(s.contentAddressed, session.UserID, 1, session.Filename, checksum)
+		reader = s.content(ctx, session, parts, true)
+		err = s.storage.Put(ctx, storagePath, reader, size, session.MimeType)
+		_ = reader.Close()
+		if err != nil {
+			return "", "", 0, nil, apperror.NewInternal("failed to assemble file")
+		}
+		return storagePath, checksum, size, nil, nil
+	}
+
+	storagePath := newStoragePath(session.UserID, 1, session.Filename)
+	reader := s.content(ctx, session, parts, true)
+	hash := sha256.New()
+	err := s.storage.Put(ctx, storagePath, io.TeeReader(reader, hash), session.Size, session.MimeType)
+	_ = reader.Close()
+	if err != nil {
+		return "", "", 0, nil, apperror.NewInternal("failed to assemble file")
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+
+	source, err := findDuplicate(ctx, s.fileRepo, session.UserID, checksum, false)
+	if err != nil {
+		_ = s.storage.Delete(ctx, storagePath)
+		return "", "", 0, nil, apperror.NewInternal("failed to look up duplicate files")
+	}
+	if source != nil {
+		_ = s.storage.Delete(ctx, storagePath)
+		return source.StoragePath, checksum, session.Size, source, nil
+	}
+	return storagePath, checksum, session.Size, nil, nil
+}
+
+// sanitizes reports whether the upload is a JPEG whose metadata is stripped before it is
+// stored.
+func (s *resumableUploadService) sanitizes(session *sqlc.UploadSession) bool {
+	return s.stripMetadata && session.MimeType == imaging.ContentType(imaging.FormatJPEG)
+}
+
+// content opens the upload's content as it is stored: its chunks in order, sanitized when
+// the upload is a JPEG whose metadata is stripped. With tracked, the chunks read are saved
+// as the assembly progress.
+func (s *resumableUploadService) content(
+	ctx context.Context,
+	session *sqlc.UploadSession,
+	parts []sqlc.UploadSessionPart,
+	tracked bool,
+) io.ReadCloser {
+	chunks := &partsReader{ctx: ctx, storage: s.storage, parts: parts}
+	var r io.Reader = chunks
+	if tracked {
+		r = s.progressReader(ctx, session, dto.UploadAssembling, 0, chunks)
+	}
+	if !s.sanitizes(session) {
+		return &readCloser{Reader: r, Closer: chunks}
+	}
+
+	// Closing the pipe's reader early fails the sanitizer's next write, which ends the
+	// goroutine.
+	pr, pw := io.Pipe()
+	async.Go(func() {
+		err := errors.New("sanitizing JPEG failed")
+		defer func() {
+			_ = chunks.Close()
+			pw.CloseWithError(err)
+		}()
+		err = imaging.SanitizeJPEG(pw, r)
+	})
+	return pr
+}
+
+// Abort cancels an upload and discards the chunks received so far.
+func (s *resumableUploadService) Abort(ctx context.Context, id, userID int64) error {
+	if _, err := s.session(ctx, id, userID); err != nil {
+		return err
+	}
+	parts, err := s.repo.ListParts(ctx, id)
+	if err != nil {
+		return apperror.NewInternal("failed to list upload chunks")
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return apperror.NewNotFound("upload not found")
+		}
+		return apperror.NewInternal("failed to delete upload session")
+	}
+	s.clearProgress(ctx, id)
+	s.deleteParts(ctx, id, parts)
+	return nil
+}
+
+// Progress reports how much of an upload has been written to storage. The token signed
+// when the session was created is the only credential, so a client can poll it cheaply
+// and share it with whatever displays the progress. The cache is read first; when it has
+// no entry, such as after a restart with the memory driver, the session's received bytes
+// are reported instead.
+func (s *resumableUploadService) Progress(ctx context.Context, id int64, token string) (*dto.UploadProgressResponse, error) {
+	expires, signature, ok := strings.Cut(token, ".")
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if !ok || err != nil || !hmac.Equal([]byte(signature), []byte(s.progressSignature(id, expires))) {
+		return nil, apperror.NewForbidden("invalid progress token")
+	}
+	if time.Now().After(time.Unix(unix, 0)) {
+		return nil, apperror.NewNotFound("upload has expired")
+	}
+
+	var progress uploadProgress
+	data, err := s.cache.Get(ctx, uploadProgressPrefix+strconv.FormatInt(id, 10))
+	if err != nil || data == nil || json.Unmarshal(data, &progress) != nil {
+		session, err := s.repo.GetByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				return nil, apperror.NewNotFound("upload not found")
+			}
+			return nil, apperror.NewInternal("failed to get upload session")
+		}
+		progress = uploadProgress{
+			Status:    dto.UploadReceiving,
+			Size:      session.Size,
+			Written:   session.Received,
+			UpdatedAt: session.UpdatedAt.Time,
+		}
+	}
+
+	resp := &dto.UploadProgressResponse{
+		ID:           id,
+		Status:       progress.Status,
+		Size:         progress.Size,
+		BytesWritten: progress.Written,
+		UpdatedAt:    progress.UpdatedAt,
+	}
+	if progress.Size > 0 {
+		// Rounded down so an upload never shows 100% before its last byte is written.
+		resp.Percent = math.Floor(float64(progress.Written)*1000/float64(progress.Size)) / 10
+	}
+	if progress.FileID != 0 {
+		resp.FileID = &progress.FileID
+	}
+	return resp, nil
+}
+
+// PurgeExpired removes abandoned uploads along with the chunks they stored.
+func (s *resumableUploadService) PurgeExpired(ctx context.Context) (int, error) {
+	expired, err := s.repo.ListExpired(ctx, uploadSessionSweepBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("list expired upload sessions: %w", err)
+	}
+
+	purged := 0
+	for _, session := range expired {
+		parts, err := s.repo.ListParts(ctx, session.ID)
+		if err != nil {
+			slog.Error("failed to list upload chunks", slog.Int64("upload_id", session.ID), slog.Any("error", err))
+			continue
+		}
+		if err := s.repo.Delete(ctx, session.ID); err != nil && !errors.Is(err, apperror.ErrNotFound) {
+			slog.Error("failed to delete upload session", slog.Int64("upload_id", session.ID), slog.Any("error", err))
+			continue
+		}
+		s.clearProgress(ctx, session.ID)
+		s.deleteParts(ctx, session.ID, parts)
+		purged++
+	}
+	return purged, nil
+}
+
+// session loads an upload session owned by the user that has not expired yet.
+func (s *resumableUploadService) session(ctx context.Context, id, userID int64) (*sqlc.UploadSession, error) {
+	session, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("upload not found")
+		}
+		return nil, apperror.NewInternal("failed to get upload session")
+	}
+	if session.UserID != userID {
+		return nil, apperror.NewForbidden("you can only access your own uploads")
+	}
+	if session.ExpiresAt.Time.Before(time.Now()) {
+		return nil, apperror.NewNotFound("upload has expired")
+	}
+	return session, nil
+}
+
+// deleteParts removes chunk objects once their session is gone. A failure is only
+// logged: the chunks are no longer referenced by anything.
+func (s *resumableUploadService) deleteParts(ctx context.Context, sessionID int64, parts []sqlc.UploadSessionPart) {
+	for _, p := range parts {
+		if err := s.storage.Delete(ctx, p.StoragePath); err != nil {
+			slog.Error("failed to delete upload chunk",
+				slog.Int64("upload_id", sessionID),
+				slog.String("path", p.StoragePath),
+				slog.Any("error", err),
+			)
+		}
+	}
+}
+
+// uploadProgress is the progress of an upload as stored in the cache.
+type uploadProgress struct {
+	Status    string    `json:"status"`
+	Size      int64     `json:"size"`
+	Written   int64     `json:"written"`
+	FileID    int64     `json:"file_id,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// saveProgress records the bytes written for an upload until its session expires.
+// Progress is informational, so a cache failure never fails the upload.
+func (s *resumableUploadService) saveProgress(ctx context.Context, session *sqlc.UploadSession, status string, written int64) {
+	s.storeProgress(ctx, session.ID, uploadProgress{
+		Status: status, Size: session.Size, Written: written,
+	}, time.Until(session.ExpiresAt.Time))
+}
+
+// saveCompleted records that an upload became the given file. The session is gone by
+// then, so the entry only lives for uploadProgressCompletedTTL.
+func (s *resumableUploadService) saveCompleted(ctx context.Context, session *sqlc.UploadSession, fileID int64) {
+	s.storeProgress(ctx, session.ID, uploadProgress{
+		Status: dto.UploadCompleted, Size: session.Size, Written: session.Size, FileID: fileID,
+	}, uploadProgressCompletedTTL)
+}
+
+func (s *resumableUploadService) storeProgress(ctx context.Context, id int64, progress uploadProgress, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	progress.UpdatedAt = time.Now()
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return
+	}
+	if err := s.cache.Set(ctx, uploadProgressPrefix+strconv.FormatInt(id, 10), data, ttl); err != nil {
+		slog.Warn("failed to save upload progress", slog.Int64("upload_id", id), slog.Any("error", err))
+	}
+}
+
+// clearProgress drops the cached progress of an upload, so Progress falls back to the
+// session or reports the upload as gone.
+func (s *resumableUploadService) clearProgress(ctx context.Context, id int64) {
+	_ = s.cache.Delete(ctx, uploadProgressPrefix+strconv.FormatInt(id, 10))
+}
+
+// progressReader wraps r so the bytes read through it are saved as the upload's progress,
+// starting from base.
+func (s *resumableUploadService) progressReader(
+	ctx context.Context,
+	session *sqlc.UploadSession,
+	status string,
+	base int64,
+	r io.Reader,
+) io.Reader {
+	return &countingReader{
+		r:         r,
+		lastFlush: time.Now(),
+		flush: func(n int64) {
+			s.saveProgress(ctx, session, status, base+n)
+		},
+	}
+}
+
+// response builds the session response with a progress token that is valid until the
+// session expires.
+func (s *resumableUploadService) response(session *sqlc.UploadSession) *dto.UploadSessionResponse {
+	resp := toUploadSessionResponse(session)
+	expires := strconv.FormatInt(session.ExpiresAt.Time.Unix(), 10)
+	resp.ProgressToken = expires + "." + s.progressSignature(session.ID, expires)
+	return resp
+}
+
+// progressSignature signs an upload ID with the Unix time its progress token expires.
+func (s *resumableUploadService) progressSignature(id int64, expires string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	fmt.Fprintf(mac, "upload-progress:%d:%s", id, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func toUploadSessionResponse(session *sqlc.UploadSession) *dto.UploadSessionResponse {
+	return &dto.UploadSessionResponse{
+		ID:        session.ID,
+		Filename:  session.Filename,
+		Size:      session.Size,
+		Offset:    session.Received,
+		ExpiresAt: session.ExpiresAt.Time,
+		CreatedAt: session.CreatedAt.Time,
+	}
+}
+
+// partsReader streams stored chunks one after another, opening each only when the
+// previous one is exhausted so a large upload is never held in memory.
+type partsReader struct {
+	ctx     context.Context
+	storage storage.Storage
+	parts   []sqlc.UploadSessionPart
+	current io.ReadCloser
+}
+
+func (r *partsReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.parts) == 0 {
+				return 0, io.EOF
+			}
+			rc, err := r.storage.Get(r.ctx, r.parts[0].StoragePath)
+			if err != nil {
+				return 0, fmt.Errorf("open chunk at offset %d: %w", r.parts[0].Offset, err)
+			}
+			r.current = rc
+			r.parts = r.parts[1:]
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			_ = r.current.Close()
+			r.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *partsReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	err := r.current.Close()
+	r.current = nil
+	return err
+}
+
+// readCloser reads from one source and closes another, such as the chunks under a
+// progress-tracking reader.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// countingReader counts the bytes read through it and reports the running total to
+// flush after every uploadProgressFlushBytes or uploadProgressFlushInterval.
+type countingReader struct {
+	r         io.Reader
+	read      int64
+	flushed   int64
+	lastFlush time.Time
+	flush     func(n int64)
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.read += int64(n)
+		if r.read-r.flushed >= uploadProgressFlushBytes || time.Since(r.lastFlush) >= uploadProgressFlushInterval {
+			r.flushed = r.read
+			r.lastFlush = time.Now()
+			r.flush(r.read)
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,390 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+)
+
+type resumableUploadFixture struct {
+	svc      ResumableUploadService
+	sessions *mockUploadSessionRepo
+	files    *mockFileRepo
+	store    *mockStorage
+	cache    *mockCache
+	events   *mockPublisher
+}
+
+func newResumableUploadFixture() *resumableUploadFixture {
+	f := &resumableUploadFixture{
+		sessions: newMockUploadSessionRepo(),
+		files:    newMockFileRepo(),
+		store:    newMockStorage(),
+		cache:    newMockCache(),
+		events:   &mockPublisher{},
+	}
+	f.svc = NewResumableUploadService(f.sessions, f.files, f.store, NewImageVariantService(f.files, f.store, nil),
+		f.cache, 24, "test-secret", false, false, nil, f.events)
+	return f
+}
+
+// start opens an upload session for user 1 and returns its ID.
+func (f *resumableUploadFixture) start(t *testing.T, filename string, size int64) int64 {
+	t.Helper()
+	session, err := f.svc.Create(context.Background(), 1, dto.CreateUploadSessionRequest{Filename: filename, Size: size})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return session.ID
+}
+
+func (f *resumableUploadFixture) send(id, offset int64, chunk string) (*dto.UploadSessionResponse, error) {
+	return f.svc.Append(context.Background(), id, 1, offset, strings.NewReader(chunk), int64(len(chunk)), "text/plain")
+}
+
+func TestResumableUpload(t *testing.T) {
+	t.Run("chunks are assembled into a file", func(t *testing.T) {
+		f := newResumableUploadFixture()
+		id := f.start(t, "notes.txt", 11)
+
+		if _, err := f.send(id, 0, "hello "); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		session, err := f.send(id, 6, "world")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if session.Offset != 11 {
+			t.Errorf("expected offset 11, got %d", session.Offset)
+		}
+
+		file, err := f.svc.Finalize(context.Background(), id, 1)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if file.OriginalName != "notes.txt" || file.Size != 11 || file.MimeType != "text/plain" {
+			t.Errorf("unexpected file %+v", file)
+		}
+		stored := f.files.files[file.ID]
+		if got := string(f.store.files[stored.StoragePath]); got != "hello world" {
+			t.Errorf("expected assembled content %q, got %q", "hello world", got)
+		}
+		if len(f.store.files) != 1 {
+			t.Errorf("expected chunks to be deleted, storage holds %d objects", len(f.store.files))
+		}
+		if _, ok := f.sessions.sessions[id]; ok {
+			t.Error("expected session to be removed")
+		}
+		if len(f.events.events) != 1 || f.events.events[0].userID != 1 || f.events.events[0].event.Type != dto.RealtimeEventFileUploaded {
+			t.Errorf("expected a file.uploaded event for the owner, got %+v", f.events.events)
+		}
+	})
+
+	t.Run("status reports the resume offset", func(t *testing.T) {
+		f := newResumableUploadFixture()
+		id := f.start(t, "notes.txt", 11)
+		_, _ = f.send(id, 0, "hello ")
+
+		session, err := f.svc.Status(context.Background(), id, 1)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if session.Offset != 6 {
+			t.Errorf("expected offset 6, got %d", session.Offset)
+		}
+	})
+
+	t.Run("replayed chunk conflicts", func(t *testing.T) {
+		f := newResumableUploadFixture()
+		id := f.start(t, "notes.txt", 11)
+		_, _ = f.send(id, 0, "hello ")
+
+		_, err := f.send(id, 0, "hello ")
+		assertAppErrorCode(t, err, 409)
+		if len(f.store.files) != 1 {
+			t.Errorf("expected rejected chunk not to be stored, storage holds %d objects", len(f.store.files))
+		}
+	})
+
+	t.Run("chunk past declared size", func(t *testing.T) {
+		f := newResumableUploadFixture()
+		id := f.start(t, "notes.txt", 4)
+
+		_, err := f.send(id, 0, "hello")
+		assertAppErrorCode(t, err, 400)
+	})
+
+	t.Run("finalize before all bytes arrive", func(t *testing.T) {
+		f := newResumableUploadFixture()
+		id := f.start(t, "notes.txt", 11)
+		_, _ = f.send(id, 0, "hello ")
+
+		_, err := f.svc.Finalize(context.Background(), id, 1)
+		assertAppErrorCode(t, err, 409)
+	})
+
+	t.Run("other user's upload", func(t *testing.T) {
+		f := newResumableUploadFixture()
+		id := f.start(t, "notes.txt", 11)
+
+		_, err := f.svc.Append(context.Background(), id, 2, 0, strings.NewReader("hello "), 6, "text/plain")
+		assertAppErrorCode(t, err, 403)
+	})
+
+	t.Run("expired upload", func(t *testing.T) {
+		f := newResumableUploadFixture()
+		id := f.start(t, "notes.txt", 11)
+		f.sessions.sessions[id].ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true}
+
+		_, err := f.send(id, 0, "hello ")
+		assertAppErrorCode(t, err, 404)
+	})
+
+	t.Run("abort discards chunks", func(t *testing.T) {
+		f := newResumableUploadFixture()
+		id := f.start(t, "notes.txt", 11)
+		_, _ = f.send(id, 0, "hello ")
+
+		if err := f.svc.Abort(context.Background(), id, 1); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(f.store.files) != 0 {
+			t.Errorf("expected chunks to be deleted, storage holds %d objects", len(f.store.files))
+		}
+		_, err := f.svc.Status(context.Background(), id, 1)
+		assertAppErrorCode(t, err, 404)
+	})
+
+	t.Run("identical file reuses the stored object", func(t *testing.T) {
+		f := newResumableUploadFixture()
+		var files []*dto.FileResponse
+		for range 2 {
+			id := f.start(t, "notes.txt", 11)
+			_, _ = f.send(id, 0, "hello world")
+			file, err := f.svc.Finalize(context.Background(), id, 1)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			files = append(files, file)
+		}
+
+		if files[0].Checksum != "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9" {
+			t.Errorf("unexpected checksum %s", files[0].Checksum)
+		}
+		if files[0].URL != files[1].URL || len(f.store.files) != 1 {
+			t.Errorf("expected one shared object, storage holds %d", len(f.store.files))
+		}
+	})
+}
+
+func TestResumableUploadContentAddressed(t *testing.T) {
+	f := newResumableUploadFixture()
+	f.svc = NewResumableUploadService(f.sessions, f.files, f.store, NewImageVariantService(f.files, f.store, nil),
+		f.cache, 24, "test-secret", true, false, nil, f.events)
+	helloPath := "sha256/b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+
+	id := f.start(t, "notes.txt", 11)
+	_, _ = f.send(id, 0, "hello ")
+	_, _ = f.send(id, 6, "world")
+	file, err := f.svc.Finalize(context.Background(), id, 1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if p := f.files.files[file.ID].StoragePath; p != helloPath || string(f.store.files[p]) != "hello world" {
+		t.Errorf("expected the content stored under its checksum, got %s", p)
+	}
+
+	// Another user's identical upload references the same object.
+	session, _ := f.svc.Create(context.Background(), 2, dto.CreateUploadSessionRequest{Filename: "copy.txt", Size: 11})
+	_, _ = f.svc.Append(context.Background(), session.ID, 2, 0, strings.NewReader("hello world"), 11, "text/plain")
+	other, err := f.svc.Finalize(context.Background(), session.ID, 2)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if p := f.files.files[other.ID].StoragePath; p != helloPath || len(f.store.files) != 1 {
+		t.Errorf("expected one shared object, got path %s and %d objects", p, len(f.store.files))
+	}
+}
+
+func TestResumableUploadStripsImageMetadata(t *testing.T) {
+	f := newResumableUploadFixture()
+	f.svc = NewResumableUploadService(f.sessions, f.files, f.store, NewImageVariantService(f.files, f.store, nil),
+		f.cache, 24, "test-secret", false, true, nil, f.events)
+	data := exifTaggedJPEG(t)
+
+	id := f.start(t, "photo.jpg", int64(len(data)))
+	half := int64(len(data) / 2)
+	for _, offset := range []int64{0, half} {
+		chunk := data[offset:]
+		if offset == 0 {
+			chunk = data[:half]
+		}
+		if _, err := f.svc.Append(context.Background(), id, 1, offset, bytes.NewReader(chunk), int64(len(chunk)), "image/jpeg"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	file, err := f.svc.Finalize(context.Background(), id, 1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	stored := f.store.files[f.files.files[file.ID].StoragePath]
+	if bytes.Contains(stored, []byte("Phone X")) {
+		t.Error("expected the EXIF segment to be stripped")
+	}
+	if file.Size != int64(len(stored)) || file.Size >= int64(len(data)) {
+		t.Errorf("expected the stored size %d recorded, got %d", len(stored), file.Size)
+	}
+}
+
+func TestUploadProgress(t *testing.T) {
+	// token returns the progress token of an upload owned by user 1.
+	token := func(t *testing.T, f *resumableUploadFixture, id int64) string {
+		t.Helper()
+		session, err := f.svc.Status(context.Background(), id, 1)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return session.ProgressToken
+	}
+
+	t.Run("progress follows chunks and completion", func(t *testing.T) {
+		f := newResumableUploadFixture()
+		id := f.start(t, "notes.txt", 11)
+		tok := token(t, f, id)
+
+		progress, err := f.svc.Progress(context.Background(), id, tok)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if progress.Status != dto.UploadReceiving || progress.BytesWritten != 0 || progress.Size != 11 {
+			t.Errorf("unexpected progress %+v", progress)
+		}
+
+		_, _ = f.send(id, 0, "hello ")
+		progress, _ = f.svc.Progress(context.Background(), id, tok)
+		if progress.BytesWritten != 6 || progress.Percent != 54.5 {
+			t.Errorf("expected 6 bytes and 54.5%%, got %+v", progress)
+		}
+
+		_, _ = f.send(id, 6, "world")
+		file, err := f.svc.Finalize(context.Background(), id, 1)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		progress, err = f.svc.Progress(context.Background(), id, tok)
+		if err != nil {
+			t.Fatalf("expected completed progress, got %v", err)
+		}
+		if progress.Status != dto.UploadCompleted || progress.Percent != 100 || progress.FileID == nil || *progress.FileID != file.ID {
+			t.Errorf("unexpected progress %+v", progress)
+		}
+	})
+
+	t.Run("falls back to the session without a cache entry", func(t *testing.T) {
+		f := newResumableUploadFixture()
+		id := f.start(t, "notes.txt", 11)
+		_, _ = f.send(id, 0, "hello ")
+		clear(f.cache.items)
+
+		progress, err := f.svc.Progress(context.Background(), id, token(t, f, id))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if progress.Status != dto.UploadReceiving || progress.BytesWritten != 6 {
+			t.Errorf("unexpected progress %+v", progress)
+		}
+	})
+
+	t.Run("failed chunk is not counted", func(t *testing.T) {
+		f := newResumableUploadFixture()
+		id := f.start(t, "notes.txt", 11)
+		_, _ = f.send(id, 0, "hello ")
+		f.store.putErr = io.ErrUnexpectedEOF
+
+		if _, err := f.send(id, 6, "world"); err == nil {
+			t.Fatal("expected the chunk to fail")
+		}
+		progress, _ := f.svc.Progress(context.Background(), id, token(t, f, id))
+		if progress.BytesWritten != 6 {
+			t.Errorf("expected 6 bytes, got %+v", progress)
+		}
+	})
+
+	t.Run("token is checked", func(t *testing.T) {
+		f := newResumableUploadFixture()
+		id := f.start(t, "notes.txt", 11)
+		other := f.start(t, "other.txt", 11)
+		tok := token(t, f, id)
+
+		_, err := f.svc.Progress(context.Background(), other, tok)
+		assertAppErrorCode(t, err, 403)
+		_, err = f.svc.Progress(context.Background(), id, tok+"x")
+		assertAppErrorCode(t, err, 403)
+		_, err = f.svc.Progress(context.Background(), id, "")
+		assertAppErrorCode(t, err, 403)
+
+		session := *f.sessions.sessions[id]
+		session.ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true}
+		expired := f.svc.(*resumableUploadService).response(&session).ProgressToken
+		_, err = f.svc.Progress(context.Background(), id, expired)
+		assertAppErrorCode(t, err, 404)
+	})
+
+	t.Run("aborted upload is gone", func(t *testing.T) {
+		f := newResumableUploadFixture()
+		id := f.start(t, "notes.txt", 11)
+		tok := token(t, f, id)
+		if err := f.svc.Abort(context.Background(), id, 1); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		_, err := f.svc.Progress(context.Background(), id, tok)
+		assertAppErrorCode(t, err, 404)
+	})
+
+	t.Run("large writes report progress as they go", func(t *testing.T) {
+		var reported []int64
+		r := &countingReader{
+			r:         bytes.NewReader(make([]byte, 3*uploadProgressFlushBytes+10)),
+			lastFlush: time.Now(),
+			flush:     func(n int64) { reported = append(reported, n) },
+		}
+		if _, err := io.Copy(io.Discard, r); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(reported) != 3 || reported[0] != uploadProgressFlushBytes || reported[2] != 3*uploadProgressFlushBytes {
+			t.Errorf("expected a report per MiB, got %v", reported)
+		}
+	})
+}
+
+func TestPurgeExpiredUploadSessions(t *testing.T) {
+	f := newResumableUploadFixture()
+	stale := f.start(t, "stale.txt", 11)
+	active := f.start(t, "active.txt", 11)
+	_, _ = f.send(stale, 0, "hello ")
+	_, _ = f.send(active, 0, "hello ")
+	f.sessions.sessions[stale].ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(-time.Minute), Valid: true}
+
+	n, err := f.svc.PurgeExpired(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 purged upload, got %d", n)
+	}
+	if _, ok := f.sessions.sessions[active]; !ok {
+		t.Error("expected active upload to be kept")
+	}
+	if len(f.store.files) != 1 {
+		t.Errorf("expected only the active upload's chunk to remain, storage holds %d objects", len(f.store.files))
+	}
+}
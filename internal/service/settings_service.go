@@ -0,0 +1,167 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+)
+
+const (
+	// settingsCacheKey holds the effective settings as the JSON of dto.Settings.
+	settingsCacheKey = "settings"
+	// settingsCacheTTL bounds how long an instance whose cache was not invalidated, such as
+	// one using the memory driver, serves a stale value.
+	settingsCacheTTL = time.Minute
+)
+
+// RuntimeSettings returns the current runtime settings. Services consult it on every
+// request instead of taking the values at construction, so changes apply without a restart.
+type RuntimeSettings interface {
+	Current(ctx context.Context) dto.Settings
+}
+
+// SettingsService stores the runtime settings admins change. Each setting is a row keyed by
+// its JSON name in dto.Settings; one without a row keeps its default from the environment.
+type SettingsService interface {
+	RuntimeSettings
+	Get(ctx context.Context) (*dto.Settings, error)
+	Update(ctx context.Context, req dto.UpdateSettingsRequest) (*dto.Settings, error)
+}
+
+type settingsService struct {
+	repo     repository.SettingRepository
+	cache    cache.Cache
+	auditLog AuditLogService
+	defaults dto.Settings
+}
+
+func NewSettingsService(repo repository.SettingRepository, appCache cache.Cache, auditLog AuditLogService, defaults dto.Settings) SettingsService {
+	return &settingsService{repo: repo, cache: appCache, auditLog: auditLog, defaults: defaults}
+}
+
+// Current returns the effective settings, or the defaults when they cannot be read so a
+// database outage does not change how requests are handled.
+func (s *settingsService) Current(ctx context.Context) dto.Settings {
+	settings, err := s.Get(ctx)
+	if err != nil {
+		slog.Warn("failed to load runtime settings, using defaults", slog.Any("error", err))
+		return s.defaults
+	}
+	return *settings
+}
+
+func (s *settingsService) Get(ctx context.Context) (*dto.Settings, error) {
+	if data, _ := s.cache.Get(ctx, settingsCacheKey); data != nil {
+		var settings dto.Settings
+		if err := json.Unmarshal(data, &settings); err == nil {
+			return &settings, nil
+		}
+	}
+
+	rows, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to list settings")
+	}
+
+	values, err := settingValues(s.defaults)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to encode settings")
+	}
+	for _, row := range rows {
+		// Rows of settings that were removed from dto.Settings are ignored.
+		if _, ok := values[row.Key]; ok {
+			values[row.Key] = row.Value
+		}
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to encode settings")
+	}
+	settings := s.defaults
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, apperror.NewInternal("failed to decode settings")
+	}
+
+	if data, err := json.Marshal(settings); err == nil {
+		if err := s.cache.Set(ctx, settingsCacheKey, data, settingsCacheTTL); err != nil {
+			slog.Warn("failed to cache settings", slog.Any("error", err))
+		}
+	}
+	return &settings, nil
+}
+
+// Update stores the settings present in req that differ from their current value, and
+// records each change in the audit log.
+func (s *settingsService) Update(ctx context.Context, req dto.UpdateSettingsRequest) (*dto.Settings, error) {
+	current, err := s.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	before, err := settingValues(*current)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to encode settings")
+	}
+	changes, err := settingValues(req)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to encode settings")
+	}
+
+	changed := false
+	for key, value := range changes {
+		if bytes.Equal(before[key], value) {
+			continue
+		}
+		row, err := s.repo.Upsert(ctx, key, value)
+		if err != nil {
+			s.invalidate(ctx)
+			return nil, apperror.NewInternal("failed to update setting " + key)
+		}
+		changed = true
+		recordAudit(ctx, s.auditLog, AuditEntry{
+			Action: dto.AuditSettingUpdated, TargetType: dto.AuditTargetSetting, TargetID: row.ID,
+			Before: map[string]any{key: before[key]},
+			After:  map[string]any{key: json.RawMessage(value)},
+		})
+	}
+	if !changed {
+		return current, nil
+	}
+
+	s.invalidate(ctx)
+	return s.Get(ctx)
+}
+
+// invalidate drops the cached settings so the next lookup reads the change.
+func (s *settingsService) invalidate(ctx context.Context) {
+	if err := s.cache.Delete(ctx, settingsCacheKey); err != nil {
+		slog.Error("failed to invalidate cached settings", slog.Any("error", err))
+	}
+}
+
+// settingValues returns the JSON value of every field of v by its JSON name.
+func settingValues(v any) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// checkRegistrationOpen rejects new accounts while admins have turned registration off.
+func checkRegistrationOpen(ctx context.Context, settings RuntimeSettings) error {
+	if !settings.Current(ctx).RegistrationEnabled {
+		return apperror.NewForbidden("registration is disabled")
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+)
+
+func TestSettings(t *testing.T) {
+	defaults := dto.Settings{RegistrationEnabled: true, MaxUploadSize: 10 << 20}
+	newFixture := func() (SettingsService, *mockSettingRepo, *mockAuditLogRepo) {
+		repo := newMockSettingRepo()
+		auditRepo := newMockAuditLogRepo()
+		return NewSettingsService(repo, newMockCache(), NewAuditLogService(auditRepo), defaults), repo, auditRepo
+	}
+	ctx := context.Background()
+
+	t.Run("unchanged settings keep their defaults", func(t *testing.T) {
+		svc, _, _ := newFixture()
+		settings, err := svc.Get(ctx)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if *settings != defaults {
+			t.Errorf("expected defaults, got %+v", settings)
+		}
+	})
+
+	t.Run("update stores only changed settings and is audited", func(t *testing.T) {
+		svc, repo, auditRepo := newFixture()
+		off, on := false, true
+		size := int64(1 << 20)
+		settings, err := svc.Update(ctx, dto.UpdateSettingsRequest{
+			RegistrationEnabled:      &off,
+			RequireEmailVerification: &on,
+			MaxUploadSize:            &size,
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		want := dto.Settings{RegistrationEnabled: false, RequireEmailVerification: true, MaxUploadSize: size}
+		if *settings != want {
+			t.Errorf("expected %+v, got %+v", want, settings)
+		}
+		if len(repo.settings) != 3 || len(auditRepo.entries) != 3 {
+			t.Fatalf("expected 3 stored and audited settings, got %d and %d", len(repo.settings), len(auditRepo.entries))
+		}
+		for _, e := range auditRepo.entries {
+			if e.Action != dto.AuditSettingUpdated || e.TargetType != dto.AuditTargetSetting {
+				t.Errorf("unexpected audit entry %s %s", e.Action, e.TargetType)
+			}
+		}
+
+		// Setting a value it already has changes nothing.
+		if _, err := svc.Update(ctx, dto.UpdateSettingsRequest{RegistrationEnabled: &off}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(auditRepo.entries) != 3 {
+			t.Errorf("expected no audit entry for an unchanged setting, got %d entries", len(auditRepo.entries))
+		}
+	})
+
+	t.Run("lookups are cached until a change", func(t *testing.T) {
+		svc, repo, _ := newFixture()
+		_ = svc.Current(ctx)
+		_ = svc.Current(ctx)
+		if repo.listCalls != 1 {
+			t.Errorf("expected one database read, got %d", repo.listCalls)
+		}
+
+		on := true
+		if _, err := svc.Update(ctx, dto.UpdateSettingsRequest{RequireEmailVerification: &on}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !svc.Current(ctx).RequireEmailVerification {
+			t.Error("expected the change to be read after the update")
+		}
+	})
+
+	t.Run("current falls back to defaults when settings cannot be read", func(t *testing.T) {
+		svc, repo, _ := newFixture()
+		repo.listErr = errors.New("connection refused")
+		if got := svc.Current(ctx); got != defaults {
+			t.Errorf("expected defaults, got %+v", got)
+		}
+		_, err := svc.Get(ctx)
+		assertAppErrorCode(t, err, 500)
+	})
+
+	t.Run("closed registration rejects signups", func(t *testing.T) {
+		closed := staticSettings{RegistrationEnabled: false}
+		users := NewUserService(newMockUserRepo(), newMockRefreshTokenRepo(), nil, newMockUserSettingsRepo(), closed, false, EmailDomainPolicy{}, nil, newMockCache(), nil)
+		_, err := users.Register(ctx, dto.RegisterRequest{Email: "new@example.com", Name: "New", Password: "Password1!"})
+		assertAppErrorCode(t, err, 403)
+		_, err = users.FindOrCreateByGoogle(ctx, "g-1", "new@example.com", "New")
+		assertAppErrorCode(t, err, 403)
+
+		guests := NewGuestService(newMockUserRepo(), nil, newMockUserSettingsRepo(), false, EmailDomainPolicy{}, closed, newMockCache())
+		_, err = guests.Create(ctx)
+		assertAppErrorCode(t, err, 403)
+	})
+}
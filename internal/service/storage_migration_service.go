@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/database"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/storage"
+)
+
+// migrationProgressInterval is how often a storage migration logs its progress.
+const migrationProgressInterval = 10 * time.Second
+
+// StorageMigrationService copies the stored objects of files, their versions and variants
+// from one storage to another, such as from local disk to S3, verifies each copy by its
+// SHA-256 and then points the database at the copies in a single transaction. The source
+// objects are left in place. It backs cmd/migrate-storage and is meant to run while the
+// API is stopped: objects stored during a migration are not copied.
+type StorageMigrationService interface {
+	Migrate(ctx context.Context, dryRun bool) (*StorageMigrationReport, error)
+}
+
+// StorageMigrationReport summarizes a storage migration, or what one would do when it is
+// a dry run.
+type StorageMigrationReport struct {
+	Objects int   // distinct stored objects found
+	Bytes   int64 // total size of those objects
+	Moved   int   // objects that get a new path in the target layout
+	Copied  int   // objects written to the target; identical content is written once
+}
+
+type storageMigrationService struct {
+	fileRepo         repository.FileRepository
+	source           storage.Storage
+	target           storage.Storage
+	contentAddressed bool
+	txManager        *database.TxManager
+}
+
+// NewStorageMigrationService creates the storage migration service. With contentAddressed,
+// files and versions with a checksum are stored under sha256/<hash> in the target, as with
+// STORAGE_LAYOUT=content; other objects keep their path.
+func NewStorageMigrationService(
+	fileRepo repository.FileRepository,
+	source, target storage.Storage,
+	contentAddressed bool,
+	txManager *database.TxManager,
+) StorageMigrationService {
+	return &storageMigrationService{
+		fileRepo:         fileRepo,
+		source:           source,
+		target:           target,
+		contentAddressed: contentAddressed,
+		txManager:        txManager,
+	}
+}
+
+// migrationObject is a stored object to copy, with where it goes in the target.
+type migrationObject struct {
+	path     string
+	target   string
+	mimeType string
+	size     int64
+	checksum string // recorded SHA-256, empty when unknown
+}
+
+// Migrate copies every object and then updates the database. Any failure stops the
+// migration before the database is changed, so it can simply be run again.
+func (s *storageMigrationService) Migrate(ctx context.Context, dryRun bool) (*StorageMigrationReport, error) {
+	rows, err := s.fileRepo.ListStoredObjects(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list stored objects: %w", err)
+	}
+	objects := migrationPlan(rows, s.contentAddressed)
+
+	report := &StorageMigrationReport{Objects: len(objects)}
+	for _, o := range objects {
+		report.Bytes += o.size
+		if o.target != o.path {
+			report.Moved++
+		}
+	}
+	if dryRun {
+		return report, nil
+	}
+
+	written := make(map[string]bool) // target paths already written in this run
+	var copiedBytes int64
+	lastLog := time.Now()
+	for i, o := range objects {
+		if !written[o.target] {
+			if err := s.copy(ctx, o); err != nil {
+				return report, fmt.Errorf("copy %s: %w", o.path, err)
+			}
+			written[o.target] = true
+			report.Copied++
+		}
+		copiedBytes += o.size
+
+		if time.Since(lastLog) >= migrationProgressInterval || i == len(objects)-1 {
+			lastLog = time.Now()
+			slog.Info("storage migration progress",
+				slog.Int("objects", i+1),
+				slog.Int("total_objects", len(objects)),
+				slog.Int64("bytes", copiedBytes),
+				slog.Int64("total_bytes", report.Bytes),
+			)
+		}
+	}
+
+	keyID := encryptionKeyID(s.target)
+	update := func(repo repository.FileRepository) error {
+		for _, o := range objects {
+			if err := repo.MoveStoredObject(ctx, o.path, o.target, keyID); err != nil {
+				return fmt.Errorf("update %s: %w", o.path, err)
+			}
+		}
+		return nil
+	}
+	if s.txManager != nil {
+		err = s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+			return update(repository.NewFileRepository(tx))
+		})
+	} else {
+		err = update(s.fileRepo)
+	}
+	if err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// copy writes an object to the target and reads it back, checking that its SHA-256
+// matches the source's and, when known, the one recorded in the database.
+func (s *storageMigrationService) copy(ctx context.Context, o migrationObject) error {
+	rc, err := s.source.Get(ctx, o.path)
+	if err != nil {
+		return fmt.Errorf("read source: %w", err)
+	}
+	hash := sha256.New()
+	err = s.target.Put(ctx, o.target, io.TeeReader(rc, hash), o.size, o.mimeType)
+	_ = rc.Close()
+	if err != nil {
+		return fmt.Errorf("write target: %w", err)
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if o.checksum != "" && sum != o.checksum {
+		return errors.New("source content does not match its recorded checksum")
+	}
+
+	rc, err = s.target.Get(ctx, o.target)
+	if err != nil {
+		return fmt.Errorf("read back target: %w", err)
+	}
+	defer func() { _ = rc.Close() }()
+	hash.Reset()
+	if _, err := io.Copy(hash, rc); err != nil {
+		return fmt.Errorf("read back target: %w", err)
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != sum {
+		return errors.New("copied content does not match the source")
+	}
+	return nil
+}
+
+// migrationPlan turns the stored objects into the distinct objects to copy, ordered by
+// path. With contentAddressed, objects with a known checksum go to sha256/<checksum>.
+func migrationPlan(rows []sqlc.ListStoredObjectsRow, contentAddressed bool) []migrationObject {
+	objects := make([]migrationObject, 0, len(rows))
+	seen := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		if seen[row.StoragePath] {
+			continue
+		}
+		seen[row.StoragePath] = true
+
+		o := migrationObject{
+			path:     row.StoragePath,
+			target:   row.StoragePath,
+			mimeType: row.MimeType,
+			size:     row.Size,
+			checksum: row.Checksum.String,
+		}
+		if contentAddressed && o.checksum != "" {
+			o.target = contentStoragePrefix + o.checksum
+		}
+		objects = append(objects, o)
+	}
+	return objects
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+)
+
+// newMigrationFixture returns a repo with two files sharing one object, an archived
+// version and a variant, all stored in the returned source storage.
+func newMigrationFixture() (*mockFileRepo, *mockStorage) {
+	const helloSum = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	repo := newMockFileRepo()
+	source := newMockStorage()
+	checksum := pgtype.Text{String: helloSum, Valid: true}
+
+	repo.files[1] = &sqlc.File{ID: 1, UserID: 1, StoragePath: "1/a.txt", MimeType: "text/plain", Size: 5, Checksum: checksum,
+		EncryptionKeyID: pgtype.Text{String: "old", Valid: true}}
+	repo.files[2] = &sqlc.File{ID: 2, UserID: 1, StoragePath: "1/a.txt", MimeType: "text/plain", Size: 5, Checksum: checksum}
+	repo.versions = []sqlc.FileVersion{{FileID: 1, Version: 1, StoragePath: "1/a.v1.txt", MimeType: "text/plain", Size: 2}}
+	repo.variants = []sqlc.FileVariant{{FileID: 1, Name: "thumbnail", StoragePath: "1/a_thumbnail.jpg", MimeType: "image/jpeg", Size: 3}}
+	source.files["1/a.txt"] = []byte("hello")
+	source.files["1/a.v1.txt"] = []byte("hi")
+	source.files["1/a_thumbnail.jpg"] = []byte("jpg")
+	return repo, source
+}
+
+func TestStorageMigration(t *testing.T) {
+	t.Run("objects are copied and the database points at them", func(t *testing.T) {
+		repo, source := newMigrationFixture()
+		target := newMockStorage()
+		svc := NewStorageMigrationService(repo, source, target, false, nil)
+
+		report, err := svc.Migrate(context.Background(), false)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if report.Objects != 3 || report.Copied != 3 || report.Moved != 0 || report.Bytes != 10 {
+			t.Errorf("unexpected report %+v", report)
+		}
+		for p, data := range source.files {
+			if string(target.files[p]) != string(data) {
+				t.Errorf("expected %s copied, got %q", p, target.files[p])
+			}
+		}
+		if repo.files[1].StoragePath != "1/a.txt" || repo.files[1].EncryptionKeyID.Valid {
+			t.Errorf("expected the path kept and the plain target's key recorded, got %+v", repo.files[1])
+		}
+		if len(source.files) != 3 {
+			t.Error("expected the source objects left in place")
+		}
+	})
+
+	t.Run("content-addressed target moves files with a checksum", func(t *testing.T) {
+		repo, source := newMigrationFixture()
+		target := newMockStorage()
+		svc := NewStorageMigrationService(repo, source, target, true, nil)
+
+		report, err := svc.Migrate(context.Background(), false)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		want := "sha256/2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+		if report.Moved != 1 {
+			t.Errorf("expected one moved object, got %+v", report)
+		}
+		for _, id := range []int64{1, 2} {
+			if p := repo.files[id].StoragePath; p != want {
+				t.Errorf("file %d: expected %s, got %s", id, want, p)
+			}
+		}
+		if string(target.files[want]) != "hello" {
+			t.Errorf("expected the content under its checksum, got %q", target.files[want])
+		}
+		if repo.versions[0].StoragePath != "1/a.v1.txt" || repo.variants[0].StoragePath != "1/a_thumbnail.jpg" {
+			t.Error("expected objects without a checksum to keep their path")
+		}
+	})
+
+	t.Run("checksum mismatch stops before the database changes", func(t *testing.T) {
+		repo, source := newMigrationFixture()
+		source.files["1/a.txt"] = []byte("HELLO")
+		svc := NewStorageMigrationService(repo, source, newMockStorage(), true, nil)
+
+		if _, err := svc.Migrate(context.Background(), false); err == nil {
+			t.Fatal("expected error for corrupted source content")
+		}
+		if repo.files[1].StoragePath != "1/a.txt" {
+			t.Error("expected the database unchanged")
+		}
+	})
+
+	t.Run("dry run copies nothing", func(t *testing.T) {
+		repo, source := newMigrationFixture()
+		target := newMockStorage()
+		svc := NewStorageMigrationService(repo, source, target, true, nil)
+
+		report, err := svc.Migrate(context.Background(), true)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if report.Objects != 3 || report.Moved != 1 || report.Copied != 0 {
+			t.Errorf("unexpected report %+v", report)
+		}
+		if len(target.files) != 0 || repo.files[1].StoragePath != "1/a.txt" {
+			t.Error("expected nothing copied or updated")
+		}
+	})
+}
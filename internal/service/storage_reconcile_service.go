@@ -0,0 +1,203 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/async"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/storage"
+)
+
+const (
+	// reconcileGracePeriod is how long a new object is left alone: it may belong to an
+	// upload whose database row is not saved yet.
+	reconcileGracePeriod = time.Hour
+	// reconcileTimeout bounds a single reconciliation run.
+	reconcileTimeout = time.Hour
+	// reconcileReportLimit caps the orphaned and missing objects listed in a report.
+	reconcileReportLimit = 1000
+)
+
+// StorageReconcileService compares the storage backend with the database, finding objects
+// no row refers to, such as those left behind by failed uploads, and rows whose object is
+// gone. Orphaned objects can optionally be deleted. Runs happen in the background and the
+// latest report is kept in memory, so each instance reports its own runs.
+type StorageReconcileService interface {
+	Start(ctx context.Context, req dto.StorageReconcileRequest) (*dto.StorageReconcileResponse, error)
+	Latest(ctx context.Context) (*dto.StorageReconcileResponse, error)
+}
+
+type storageReconcileService struct {
+	fileRepo repository.FileRepository
+	storage  storage.Storage
+	auditLog AuditLogService
+	run      func(fn func()) // starts reconciliation runs; async.Go outside tests
+
+	mu     sync.Mutex
+	latest *dto.StorageReconcileResponse
+}
+
+func NewStorageReconcileService(fileRepo repository.FileRepository, store storage.Storage, auditLog AuditLogService) StorageReconcileService {
+	return &storageReconcileService{
+		fileRepo: fileRepo,
+		storage:  store,
+		auditLog: auditLog,
+		run:      async.Go,
+	}
+}
+
+// Start begins a reconciliation run in the background. Only one run can be in progress.
+func (s *storageReconcileService) Start(ctx context.Context, req dto.StorageReconcileRequest) (*dto.StorageReconcileResponse, error) {
+	if _, ok := s.storage.(storage.Lister); !ok {
+		return nil, apperror.NewBadRequest("the storage driver cannot list its objects")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.latest != nil && s.latest.Status == dto.ReconcileRunning {
+		return nil, apperror.NewConflict("a storage reconciliation is already running")
+	}
+
+	report := &dto.StorageReconcileResponse{
+		Status:          dto.ReconcileRunning,
+		Cleanup:         req.Cleanup,
+		StartedAt:       time.Now(),
+		OrphanedObjects: []dto.OrphanedObjectInfo{},
+		MissingObjects:  []dto.MissingObjectInfo{},
+	}
+	s.latest = report
+	resp := *report
+
+	s.run(func() {
+		runCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), reconcileTimeout)
+		defer cancel()
+		s.finish(report, s.reconcile(runCtx, report))
+		if report.Cleanup {
+			// Deleted objects are gone even when the run failed part way
+			recordAudit(context.WithoutCancel(ctx), s.auditLog, AuditEntry{
+				Action: dto.AuditStorageCleaned, TargetType: dto.AuditTargetStorage,
+				After: map[string]any{"orphaned": report.OrphanedCount, "deleted": report.DeletedCount, "status": report.Status},
+			})
+		}
+	})
+
+	return &resp, nil
+}
+
+// Latest returns the report of the most recent run, which may still be in progress.
+func (s *storageReconcileService) Latest(_ context.Context) (*dto.StorageReconcileResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.latest == nil {
+		return nil, apperror.NewNotFound("no storage reconciliation has run")
+	}
+	if s.latest.Status == dto.ReconcileRunning {
+		// The counts are only published once the run finishes
+		return &dto.StorageReconcileResponse{
+			Status:          s.latest.Status,
+			Cleanup:         s.latest.Cleanup,
+			StartedAt:       s.latest.StartedAt,
+			OrphanedObjects: []dto.OrphanedObjectInfo{},
+			MissingObjects:  []dto.MissingObjectInfo{},
+		}, nil
+	}
+	resp := *s.latest
+	return &resp, nil
+}
+
+// finish publishes the outcome of a run.
+func (s *storageReconcileService) finish(report *dto.StorageReconcileResponse, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	report.FinishedAt = &now
+	report.Status = dto.ReconcileCompleted
+	if err != nil {
+		slog.Error("storage reconciliation failed", slog.String("error", err.Error()))
+		report.Status = dto.ReconcileFailed
+		report.Error = "storage reconciliation failed"
+		return
+	}
+	slog.Info("storage reconciliation completed",
+		slog.Int64("scanned", report.ObjectsScanned),
+		slog.Int64("orphaned", report.OrphanedCount),
+		slog.Int64("deleted", report.DeletedCount),
+		slog.Int64("missing", report.MissingCount),
+	)
+}
+
+// reconcile walks the storage and fills in report. Every referenced path is held in memory
+// while the storage is listed. Rows are read first: objects are written before the rows
+// that refer to them, and the grace period covers objects whose row appears meanwhile.
+func (s *storageReconcileService) reconcile(ctx context.Context, report *dto.StorageReconcileResponse) error {
+	refs, err := s.fileRepo.ListStorageReferences(ctx)
+	if err != nil {
+		return fmt.Errorf("list storage references: %w", err)
+	}
+	unseen := make(map[string][]sqlc.ListStorageReferencesRow, len(refs))
+	for _, ref := range refs {
+		unseen[ref.StoragePath] = append(unseen[ref.StoragePath], ref)
+	}
+
+	cutoff := time.Now().Add(-reconcileGracePeriod)
+	err = storage.List(ctx, s.storage, func(obj storage.Object) error {
+		report.ObjectsScanned++
+		if _, ok := unseen[obj.Path]; ok {
+			delete(unseen, obj.Path)
+			return nil
+		}
+		if obj.ModTime.After(cutoff) {
+			report.ObjectsSkipped++
+			return nil
+		}
+
+		report.OrphanedCount++
+		report.OrphanedBytes += obj.Size
+		deleted := false
+		if report.Cleanup {
+			if err := s.storage.Delete(ctx, obj.Path); err != nil {
+				slog.Warn("failed to delete orphaned object",
+					slog.String("path", obj.Path),
+					slog.String("error", err.Error()),
+				)
+			} else {
+				deleted = true
+				report.DeletedCount++
+			}
+		}
+		if len(report.OrphanedObjects) < reconcileReportLimit {
+			report.OrphanedObjects = append(report.OrphanedObjects, dto.OrphanedObjectInfo{
+				Path: obj.Path, Size: obj.Size, ModifiedAt: obj.ModTime, Deleted: deleted,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("list storage objects: %w", err)
+	}
+
+	var missing []sqlc.ListStorageReferencesRow
+	for _, rows := range unseen {
+		missing = append(missing, rows...)
+	}
+	slices.SortFunc(missing, func(a, b sqlc.ListStorageReferencesRow) int {
+		return cmp.Or(cmp.Compare(a.Kind, b.Kind), cmp.Compare(a.OwnerID, b.OwnerID), cmp.Compare(a.StoragePath, b.StoragePath))
+	})
+	report.MissingCount = int64(len(missing))
+	for _, ref := range missing[:min(len(missing), reconcileReportLimit)] {
+		report.MissingObjects = append(report.MissingObjects, dto.MissingObjectInfo{
+			Kind: ref.Kind, ID: ref.OwnerID, Path: ref.StoragePath,
+		})
+	}
+	return nil
+}
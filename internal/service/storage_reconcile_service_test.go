@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+)
+
+type reconcileFixture struct {
+	svc     *storageReconcileService
+	files   *mockFileRepo
+	store   *mockStorage
+	audit   *mockAuditLogRepo
+	pending []func()
+}
+
+func newReconcileFixture() *reconcileFixture {
+	f := &reconcileFixture{files: newMockFileRepo(), store: newMockStorage(), audit: newMockAuditLogRepo()}
+	f.svc = NewStorageReconcileService(f.files, f.store, NewAuditLogService(f.audit)).(*storageReconcileService)
+	f.svc.run = func(fn func()) { f.pending = append(f.pending, fn) }
+	return f
+}
+
+// reconcile starts a run and waits for its report.
+func (f *reconcileFixture) reconcile(t *testing.T, cleanup bool) *dto.StorageReconcileResponse {
+	t.Helper()
+	if _, err := f.svc.Start(context.Background(), dto.StorageReconcileRequest{Cleanup: cleanup}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	for _, fn := range f.pending {
+		fn()
+	}
+	f.pending = nil
+	report, err := f.svc.Latest(context.Background())
+	if err != nil {
+		t.Fatalf("latest: %v", err)
+	}
+	return report
+}
+
+func TestStorageReconcile(t *testing.T) {
+	seed := func(f *reconcileFixture) {
+		f.files.files[1] = &sqlc.File{ID: 1, UserID: 1, StoragePath: "1/a.txt"}
+		f.files.files[2] = &sqlc.File{ID: 2, UserID: 1, StoragePath: "1/gone.txt"}
+		f.files.versions = []sqlc.FileVersion{{FileID: 1, Version: 1, StoragePath: "1/a.v1.txt"}}
+		f.files.variants = []sqlc.FileVariant{{FileID: 1, Name: "thumbnail", StoragePath: "1/a_thumbnail.jpg"}}
+		f.store.files["1/a.txt"] = []byte("a")
+		f.store.files["1/a.v1.txt"] = []byte("old")
+		f.store.files["1/a_thumbnail.jpg"] = []byte("thumb")
+		f.store.files["1/failed-upload.txt"] = []byte("left behind")
+		f.store.files["1/uploading.txt"] = []byte("new")
+		f.store.modTimes = map[string]time.Time{"1/uploading.txt": time.Now()}
+	}
+
+	t.Run("reports orphaned and missing objects", func(t *testing.T) {
+		f := newReconcileFixture()
+		seed(f)
+
+		report := f.reconcile(t, false)
+		if report.Status != dto.ReconcileCompleted || report.FinishedAt == nil {
+			t.Fatalf("expected a completed run, got %+v", report)
+		}
+		if report.ObjectsScanned != 5 || report.ObjectsSkipped != 1 {
+			t.Errorf("expected 5 objects scanned and 1 skipped, got %d and %d", report.ObjectsScanned, report.ObjectsSkipped)
+		}
+		if report.OrphanedCount != 1 || report.OrphanedBytes != 11 || len(report.OrphanedObjects) != 1 ||
+			report.OrphanedObjects[0].Path != "1/failed-upload.txt" || report.OrphanedObjects[0].Deleted {
+			t.Errorf("expected the failed upload as the only orphan, got %+v", report.OrphanedObjects)
+		}
+		if report.MissingCount != 1 || len(report.MissingObjects) != 1 ||
+			report.MissingObjects[0] != (dto.MissingObjectInfo{Kind: "file", ID: 2, Path: "1/gone.txt"}) {
+			t.Errorf("expected file 2 as missing, got %+v", report.MissingObjects)
+		}
+		if _, ok := f.store.files["1/failed-upload.txt"]; !ok || report.DeletedCount != 0 {
+			t.Error("expected nothing deleted without cleanup")
+		}
+		if len(f.audit.entries) != 0 {
+			t.Errorf("expected no audit entry without cleanup, got %v", f.audit.actions())
+		}
+	})
+
+	t.Run("cleanup deletes orphaned objects only", func(t *testing.T) {
+		f := newReconcileFixture()
+		seed(f)
+
+		report := f.reconcile(t, true)
+		if report.DeletedCount != 1 || !report.OrphanedObjects[0].Deleted {
+			t.Errorf("expected the orphan deleted, got %+v", report)
+		}
+		if _, ok := f.store.files["1/failed-upload.txt"]; ok {
+			t.Error("expected the orphaned object removed from storage")
+		}
+		if len(f.store.files) != 4 {
+			t.Errorf("expected the referenced and recent objects kept, storage holds %d", len(f.store.files))
+		}
+		if len(f.audit.entries) != 1 || f.audit.entries[0].Action != dto.AuditStorageCleaned ||
+			!strings.Contains(string(f.audit.entries[0].AfterData), `"deleted":1`) {
+			t.Errorf("expected a storage.cleaned entry, got %+v", f.audit.entries)
+		}
+	})
+
+	t.Run("listing failure fails the run", func(t *testing.T) {
+		f := newReconcileFixture()
+		f.store.listErr = errors.New("bucket unavailable")
+
+		report := f.reconcile(t, true)
+		if report.Status != dto.ReconcileFailed || report.Error == "" {
+			t.Errorf("expected a failed run, got %+v", report)
+		}
+	})
+
+	t.Run("one run at a time", func(t *testing.T) {
+		f := newReconcileFixture()
+		_, err := f.svc.Latest(context.Background())
+		assertAppErrorCode(t, err, 404)
+
+		if _, err := f.svc.Start(context.Background(), dto.StorageReconcileRequest{}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		_, err = f.svc.Start(context.Background(), dto.StorageReconcileRequest{})
+		assertAppErrorCode(t, err, 409)
+
+		running, err := f.svc.Latest(context.Background())
+		if err != nil || running.Status != dto.ReconcileRunning {
+			t.Fatalf("expected a running reconciliation, got %+v (%v)", running, err)
+		}
+		f.pending[0]()
+		if _, err := f.svc.Start(context.Background(), dto.StorageReconcileRequest{}); err != nil {
+			t.Errorf("expected a new run once the previous finished, got %v", err)
+		}
+	})
+}
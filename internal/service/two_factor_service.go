@@ -0,0 +1,355 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/database"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/totp"
+)
+
+const (
+	twoFactorChallengePrefix = "2fa_challenge:"
+	twoFactorChallengeTTL    = 5 * time.Minute
+	// twoFactorMaxAttempts is how many codes a challenge accepts before it is dropped and
+	// the password has to be entered again
+	twoFactorMaxAttempts = 5
+	// totpUsedPrefix marks the time steps whose code a user has used, so it is not accepted
+	// twice
+	totpUsedPrefix = "totp_used:"
+	// recoveryCodeBytes is the entropy of a recovery code: 10 base32 characters
+	recoveryCodeBytes = 5
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactorService manages TOTP two-factor authentication of password sign-ins and the
+// recovery codes standing in for a TOTP code when the authenticator is lost.
+type TwoFactorService interface {
+	// Setup starts enabling two-factor authentication with a new secret, which stays pending
+	// until Enable confirms it.
+	Setup(ctx context.Context, userID int64) (*dto.TwoFactorSetupResponse, error)
+	// Enable confirms the pending secret with a code from the authenticator and issues the
+	// recovery codes.
+	Enable(ctx context.Context, userID int64, code string) (*dto.RecoveryCodesResponse, error)
+	Disable(ctx context.Context, userID int64, req dto.TwoFactorConfirmRequest) error
+	// RegenerateRecoveryCodes replaces every recovery code, used or not, with new ones.
+	RegenerateRecoveryCodes(ctx context.Context, userID int64, req dto.TwoFactorConfirmRequest) (*dto.RecoveryCodesResponse, error)
+	Status(ctx context.Context, userID int64) (*dto.RecoveryCodesStatusResponse, error)
+	// Challenge returns the challenge a user who entered their password answers to finish
+	// signing in, or nil when they have not enabled two-factor authentication.
+	Challenge(ctx context.Context, userID int64) (*dto.TwoFactorChallengeResponse, error)
+	// Verify answers a challenge with a TOTP or recovery code and returns the user signing in.
+	Verify(ctx context.Context, req dto.TwoFactorLoginRequest) (*sqlc.User, error)
+}
+
+type twoFactorService struct {
+	repo      repository.TwoFactorRepository
+	userRepo  repository.UserRepository
+	cache     cache.Cache
+	txManager *database.TxManager
+	issuer    string
+}
+
+// NewTwoFactorService returns a TwoFactorService naming accounts under issuer in
+// authenticator apps.
+func NewTwoFactorService(
+	repo repository.TwoFactorRepository,
+	userRepo repository.UserRepository,
+	appCache cache.Cache,
+	txManager *database.TxManager,
+	issuer string,
+) TwoFactorService {
+	return &twoFactorService{repo: repo, userRepo: userRepo, cache: appCache, txManager: txManager, issuer: issuer}
+}
+
+func (s *twoFactorService) Setup(ctx context.Context, userID int64) (*dto.TwoFactorSetupResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("user not found")
+		}
+		return nil, apperror.NewInternal("failed to get user")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, apperror.NewInternal("failed to generate secret")
+	}
+	if _, err := s.repo.SaveSecret(ctx, userID, secret); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewConflict("two-factor authentication is already enabled")
+		}
+		return nil, apperror.NewInternal("failed to save secret")
+	}
+
+	return &dto.TwoFactorSetupResponse{
+		Secret: secret,
+		URI:    totp.URI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+func (s *twoFactorService) Enable(ctx context.Context, userID int64, code string) (*dto.RecoveryCodesResponse, error) {
+	secret, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewBadRequest("start two-factor setup first")
+		}
+		return nil, apperror.NewInternal("failed to get two-factor settings")
+	}
+	if secret.EnabledAt.Valid {
+		return nil, apperror.NewConflict("two-factor authentication is already enabled")
+	}
+	if err := s.checkCode(ctx, userID, secret.Secret, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err = s.withTx(ctx, func(repo repository.TwoFactorRepository) error {
+		if _, err := repo.Enable(ctx, userID); err != nil {
+			return err
+		}
+		codes, err = replaceRecoveryCodes(ctx, repo, userID)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewConflict("two-factor authentication is already enabled")
+		}
+		return nil, apperror.NewInternal("failed to enable two-factor authentication")
+	}
+	slog.Info("two-factor authentication enabled", slog.Int64("user_id", userID))
+	return &dto.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (s *twoFactorService) Disable(ctx context.Context, userID int64, req dto.TwoFactorConfirmRequest) error {
+	secret, err := s.enabledSecret(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.confirm(ctx, userID, secret, req); err != nil {
+		return err
+	}
+
+	if err := s.withTx(ctx, func(repo repository.TwoFactorRepository) error {
+		return repo.DeleteByUserID(ctx, userID)
+	}); err != nil {
+		return apperror.NewInternal("failed to disable two-factor authentication")
+	}
+	slog.Info("two-factor authentication disabled", slog.Int64("user_id", userID))
+	return nil
+}
+
+func (s *twoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID int64, req dto.TwoFactorConfirmRequest) (*dto.RecoveryCodesResponse, error) {
+	secret, err := s.enabledSecret(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.confirm(ctx, userID, secret, req); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	if err := s.withTx(ctx, func(repo repository.TwoFactorRepository) error {
+		codes, err = replaceRecoveryCodes(ctx, repo, userID)
+		return err
+	}); err != nil {
+		return nil, apperror.NewInternal("failed to regenerate recovery codes")
+	}
+	return &dto.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (s *twoFactorService) Status(ctx context.Context, userID int64) (*dto.RecoveryCodesStatusResponse, error) {
+	secret, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return &dto.RecoveryCodesStatusResponse{}, nil
+		}
+		return nil, apperror.NewInternal("failed to get two-factor settings")
+	}
+	if !secret.EnabledAt.Valid {
+		return &dto.RecoveryCodesStatusResponse{}, nil
+	}
+
+	remaining, err := s.repo.CountUnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to count recovery codes")
+	}
+	return &dto.RecoveryCodesStatusResponse{
+		Enabled:   true,
+		EnabledAt: &secret.EnabledAt.Time,
+		Remaining: remaining,
+	}, nil
+}
+
+// Challenge keeps the user ID in the cache under a random challenge token for
+// twoFactorChallengeTTL. It returns nil for users with no secret or only a pending one.
+func (s *twoFactorService) Challenge(ctx context.Context, userID int64) (*dto.TwoFactorChallengeResponse, error) {
+	secret, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, apperror.NewInternal("failed to get two-factor settings")
+	}
+	if !secret.EnabledAt.Valid {
+		return nil, nil
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, apperror.NewInternal("failed to generate challenge")
+	}
+	challenge := hex.EncodeToString(b)
+	if err := s.cache.Set(ctx, twoFactorChallengePrefix+challenge, []byte(strconv.FormatInt(userID, 10)), twoFactorChallengeTTL); err != nil {
+		return nil, apperror.NewInternal("failed to store challenge")
+	}
+	return &dto.TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    challenge,
+		ExpiresAt:         time.Now().Add(twoFactorChallengeTTL),
+	}, nil
+}
+
+// Verify counts every answer to a challenge, dropping it after twoFactorMaxAttempts, and
+// deletes the challenge once a code is accepted so it cannot be answered twice.
+func (s *twoFactorService) Verify(ctx context.Context, req dto.TwoFactorLoginRequest) (*sqlc.User, error) {
+	key := twoFactorChallengePrefix + req.ChallengeToken
+	data, err := s.cache.Get(ctx, key)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to load challenge")
+	}
+	userID, err := strconv.ParseInt(string(data), 10, 64)
+	if data == nil || err != nil {
+		return nil, apperror.NewUnauthorized("invalid or expired two-factor challenge")
+	}
+
+	// Guessing codes costs the challenge after a few attempts
+	attempts, err := s.cache.Increment(ctx, key+":attempts", 1, twoFactorChallengeTTL)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to count attempts")
+	}
+	if attempts > twoFactorMaxAttempts {
+		_ = s.cache.Delete(ctx, key)
+		return nil, apperror.NewUnauthorized("too many attempts, sign in again")
+	}
+
+	secret, err := s.enabledSecret(ctx, userID)
+	if err != nil {
+		return nil, apperror.NewUnauthorized("invalid or expired two-factor challenge")
+	}
+	if err := s.confirm(ctx, userID, secret, req.TwoFactorConfirmRequest); err != nil {
+		return nil, err
+	}
+	_ = s.cache.Delete(ctx, key)
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewUnauthorized("invalid or expired two-factor challenge")
+		}
+		return nil, apperror.NewInternal("failed to get user")
+	}
+	if err := checkNotBanned(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// enabledSecret returns the secret of a user who has enabled two-factor authentication.
+func (s *twoFactorService) enabledSecret(ctx context.Context, userID int64) (string, error) {
+	secret, err := s.repo.Get(ctx, userID)
+	if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		return "", apperror.NewInternal("failed to get two-factor settings")
+	}
+	if err != nil || !secret.EnabledAt.Valid {
+		return "", apperror.NewBadRequest("two-factor authentication is not enabled").
+			WithErrorCode(dto.ErrorCodeTwoFactorNotEnabled)
+	}
+	return secret.Secret, nil
+}
+
+// confirm checks the TOTP code or spends the recovery code of req.
+func (s *twoFactorService) confirm(ctx context.Context, userID int64, secret string, req dto.TwoFactorConfirmRequest) error {
+	if req.RecoveryCode == "" {
+		return s.checkCode(ctx, userID, secret, req.Code)
+	}
+
+	used, err := s.repo.UseRecoveryCode(ctx, userID, hashToken(normalizeRecoveryCode(req.RecoveryCode)))
+	if err != nil {
+		return apperror.NewInternal("failed to check recovery code")
+	}
+	if !used {
+		return apperror.NewUnauthorized("invalid two-factor code")
+	}
+	slog.Info("recovery code used", slog.Int64("user_id", userID))
+	return nil
+}
+
+// checkCode accepts a TOTP code of secret once: a code is refused when one of the same time
+// step was already accepted for the user.
+func (s *twoFactorService) checkCode(ctx context.Context, userID int64, secret, code string) error {
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return apperror.NewUnauthorized("invalid two-factor code")
+	}
+	// The step is remembered for as long as Validate accepts its code
+	key := totpUsedPrefix + strconv.FormatInt(userID, 10) + ":" + strconv.FormatInt(step, 10)
+	n, err := s.cache.Increment(ctx, key, 1, 3*totp.Period)
+	if err != nil {
+		return apperror.NewInternal("failed to check two-factor code")
+	}
+	if n > 1 {
+		return apperror.NewUnauthorized("invalid two-factor code")
+	}
+	return nil
+}
+
+// withTx runs fn with a repository bound to a transaction when a transaction manager is set.
+func (s *twoFactorService) withTx(ctx context.Context, fn func(repository.TwoFactorRepository) error) error {
+	if s.txManager == nil {
+		return fn(s.repo)
+	}
+	return s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		return fn(repository.NewTwoFactorRepository(tx))
+	})
+}
+
+// replaceRecoveryCodes issues new recovery codes for the user, storing only their hashes.
+func replaceRecoveryCodes(ctx context.Context, repo repository.TwoFactorRepository, userID int64) ([]string, error) {
+	codes := make([]string, dto.RecoveryCodeCount)
+	hashes := make([]string, dto.RecoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashToken(code)
+	}
+	if err := repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode drops the separators and case a user may type a recovery code with.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/totp"
+)
+
+type twoFactorFixture struct {
+	svc   TwoFactorService
+	repo  *mockTwoFactorRepo
+	users *mockUserRepo
+	cache *mockCache
+}
+
+func newTwoFactorFixture() *twoFactorFixture {
+	f := &twoFactorFixture{repo: newMockTwoFactorRepo(), users: newMockUserRepo(), cache: newMockCache()}
+	f.users.users[1] = &sqlc.User{ID: 1, Email: "test@example.com", Name: "Test User", Role: "user"}
+	f.svc = NewTwoFactorService(f.repo, f.users, f.cache, nil, "Test App")
+	return f
+}
+
+// code returns the TOTP code of the user's secret offset steps from now.
+func (f *twoFactorFixture) code(t *testing.T, userID, offset int64) string {
+	t.Helper()
+	code, err := totp.Code(f.repo.secrets[userID].Secret, totp.Step(time.Now())+offset)
+	if err != nil {
+		t.Fatalf("code: %v", err)
+	}
+	return code
+}
+
+// enable turns on two-factor authentication for the user and returns the recovery codes.
+func (f *twoFactorFixture) enable(t *testing.T, userID int64) []string {
+	t.Helper()
+	if _, err := f.svc.Setup(context.Background(), userID); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	resp, err := f.svc.Enable(context.Background(), userID, f.code(t, userID, 0))
+	if err != nil {
+		t.Fatalf("enable: %v", err)
+	}
+	return resp.RecoveryCodes
+}
+
+func expectStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	var appErr *apperror.AppError
+	if !errors.As(err, &appErr) || appErr.Code != status {
+		t.Fatalf("expected %d AppError, got %v", status, err)
+	}
+}
+
+func TestTwoFactorSetup(t *testing.T) {
+	t.Run("pending secret", func(t *testing.T) {
+		f := newTwoFactorFixture()
+
+		resp, err := f.svc.Setup(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if resp.Secret == "" || !strings.Contains(resp.URI, "secret="+resp.Secret) || !strings.Contains(resp.URI, "test@example.com") {
+			t.Errorf("unexpected setup response %+v", resp)
+		}
+		status, _ := f.svc.Status(context.Background(), 1)
+		if status.Enabled {
+			t.Error("expected two-factor authentication off until confirmed")
+		}
+	})
+
+	t.Run("wrong code does not enable", func(t *testing.T) {
+		f := newTwoFactorFixture()
+		_, _ = f.svc.Setup(context.Background(), 1)
+
+		_, err := f.svc.Enable(context.Background(), 1, "000000")
+		expectStatus(t, err, http.StatusUnauthorized)
+		if f.repo.secrets[1].EnabledAt.Valid {
+			t.Error("expected the secret to stay pending")
+		}
+	})
+
+	t.Run("enable issues recovery codes", func(t *testing.T) {
+		f := newTwoFactorFixture()
+
+		codes := f.enable(t, 1)
+		if len(codes) != dto.RecoveryCodeCount {
+			t.Fatalf("expected %d recovery codes, got %d", dto.RecoveryCodeCount, len(codes))
+		}
+		for hash := range f.repo.codes[1] {
+			for _, code := range codes {
+				if strings.Contains(hash, strings.ReplaceAll(code, "-", "")) {
+					t.Fatal("expected recovery codes stored hashed")
+				}
+			}
+		}
+		status, err := f.svc.Status(context.Background(), 1)
+		if err != nil || !status.Enabled || status.EnabledAt == nil || status.Remaining != dto.RecoveryCodeCount {
+			t.Errorf("unexpected status %+v, %v", status, err)
+		}
+	})
+
+	t.Run("setup again once enabled", func(t *testing.T) {
+		f := newTwoFactorFixture()
+		f.enable(t, 1)
+
+		_, err := f.svc.Setup(context.Background(), 1)
+		expectStatus(t, err, http.StatusConflict)
+	})
+}
+
+func TestTwoFactorRecoveryCodes(t *testing.T) {
+	t.Run("regenerate replaces every code", func(t *testing.T) {
+		f := newTwoFactorFixture()
+		old := f.enable(t, 1)
+
+		resp, err := f.svc.RegenerateRecoveryCodes(context.Background(), 1, dto.TwoFactorConfirmRequest{RecoveryCode: old[0]})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(resp.RecoveryCodes) != dto.RecoveryCodeCount || resp.RecoveryCodes[0] == old[0] {
+			t.Fatalf("expected new recovery codes, got %v", resp.RecoveryCodes)
+		}
+		err = f.svc.Disable(context.Background(), 1, dto.TwoFactorConfirmRequest{RecoveryCode: old[1]})
+		expectStatus(t, err, http.StatusUnauthorized)
+		status, _ := f.svc.Status(context.Background(), 1)
+		if status.Remaining != dto.RecoveryCodeCount {
+			t.Errorf("expected %d codes left, got %d", dto.RecoveryCodeCount, status.Remaining)
+		}
+	})
+
+	t.Run("requires two-factor authentication", func(t *testing.T) {
+		f := newTwoFactorFixture()
+
+		_, err := f.svc.RegenerateRecoveryCodes(context.Background(), 1, dto.TwoFactorConfirmRequest{Code: "123456"})
+		var appErr *apperror.AppError
+		if !errors.As(err, &appErr) || appErr.ErrorCode != dto.ErrorCodeTwoFactorNotEnabled {
+			t.Fatalf("expected %s, got %v", dto.ErrorCodeTwoFactorNotEnabled, err)
+		}
+	})
+
+	t.Run("disable removes the secret and codes", func(t *testing.T) {
+		f := newTwoFactorFixture()
+		f.enable(t, 1)
+
+		if err := f.svc.Disable(context.Background(), 1, dto.TwoFactorConfirmRequest{Code: f.code(t, 1, 1)}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(f.repo.secrets) != 0 || len(f.repo.codes) != 0 {
+			t.Error("expected the secret and recovery codes deleted")
+		}
+		challenge, err := f.svc.Challenge(context.Background(), 1)
+		if err != nil || challenge != nil {
+			t.Errorf("expected no challenge once disabled, got %+v, %v", challenge, err)
+		}
+	})
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	challenge := func(t *testing.T, f *twoFactorFixture) string {
+		t.Helper()
+		resp, err := f.svc.Challenge(context.Background(), 1)
+		if err != nil || resp == nil || !resp.TwoFactorRequired {
+			t.Fatalf("expected a challenge, got %+v, %v", resp, err)
+		}
+		return resp.ChallengeToken
+	}
+	verify := func(f *twoFactorFixture, token string, req dto.TwoFactorConfirmRequest) (*sqlc.User, error) {
+		return f.svc.Verify(context.Background(), dto.TwoFactorLoginRequest{ChallengeToken: token, TwoFactorConfirmRequest: req})
+	}
+
+	t.Run("no challenge without two-factor authentication", func(t *testing.T) {
+		f := newTwoFactorFixture()
+		_, _ = f.svc.Setup(context.Background(), 1)
+
+		resp, err := f.svc.Challenge(context.Background(), 1)
+		if err != nil || resp != nil {
+			t.Errorf("expected no challenge for a pending secret, got %+v, %v", resp, err)
+		}
+	})
+
+	t.Run("totp code", func(t *testing.T) {
+		f := newTwoFactorFixture()
+		f.enable(t, 1)
+		token := challenge(t, f)
+
+		user, err := verify(f, token, dto.TwoFactorConfirmRequest{Code: f.code(t, 1, 1)})
+		if err != nil || user.ID != 1 {
+			t.Fatalf("expected user 1, got %+v, %v", user, err)
+		}
+		_, err = verify(f, token, dto.TwoFactorConfirmRequest{Code: f.code(t, 1, 1)})
+		expectStatus(t, err, http.StatusUnauthorized)
+	})
+
+	t.Run("totp code is not accepted twice", func(t *testing.T) {
+		f := newTwoFactorFixture()
+		f.enable(t, 1)
+
+		// The code that enabled two-factor authentication is already used
+		_, err := verify(f, challenge(t, f), dto.TwoFactorConfirmRequest{Code: f.code(t, 1, 0)})
+		expectStatus(t, err, http.StatusUnauthorized)
+	})
+
+	t.Run("recovery code is single use", func(t *testing.T) {
+		f := newTwoFactorFixture()
+		codes := f.enable(t, 1)
+
+		typed := strings.ToUpper(strings.ReplaceAll(codes[3], "-", " "))
+		if _, err := verify(f, challenge(t, f), dto.TwoFactorConfirmRequest{RecoveryCode: typed}); err != nil {
+			t.Fatalf("expected the recovery code accepted, got %v", err)
+		}
+		_, err := verify(f, challenge(t, f), dto.TwoFactorConfirmRequest{RecoveryCode: codes[3]})
+		expectStatus(t, err, http.StatusUnauthorized)
+		status, _ := f.svc.Status(context.Background(), 1)
+		if status.Remaining != dto.RecoveryCodeCount-1 {
+			t.Errorf("expected %d codes left, got %d", dto.RecoveryCodeCount-1, status.Remaining)
+		}
+	})
+
+	t.Run("attempts are limited", func(t *testing.T) {
+		f := newTwoFactorFixture()
+		f.enable(t, 1)
+		token := challenge(t, f)
+
+		for range twoFactorMaxAttempts {
+			_, err := verify(f, token, dto.TwoFactorConfirmRequest{Code: "000000"})
+			expectStatus(t, err, http.StatusUnauthorized)
+		}
+		_, err := verify(f, token, dto.TwoFactorConfirmRequest{Code: f.code(t, 1, 1)})
+		expectStatus(t, err, http.StatusUnauthorized)
+		if _, ok := f.cache.items[twoFactorChallengePrefix+token]; ok {
+			t.Error("expected the challenge dropped")
+		}
+	})
+
+	t.Run("unknown challenge", func(t *testing.T) {
+		f := newTwoFactorFixture()
+		f.enable(t, 1)
+
+		_, err := verify(f, "missing", dto.TwoFactorConfirmRequest{Code: f.code(t, 1, 1)})
+		expectStatus(t, err, http.StatusUnauthorized)
+	})
+
+	t.Run("banned user", func(t *testing.T) {
+		f := newTwoFactorFixture()
+		f.enable(t, 1)
+		token := challenge(t, f)
+		f.users.users[1].BannedAt.Valid = true
+
+		_, err := verify(f, token, dto.TwoFactorConfirmRequest{Code: f.code(t, 1, 1)})
+		expectStatus(t, err, http.StatusForbidden)
+	})
+}
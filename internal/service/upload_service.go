@@ -1,91 +1,179 @@
 package service
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/imaging"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/pagination"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/storage"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/telemetry"
+)
+
+// Limits on the tags of a single file.
+const (
+	maxFileTags      = 20
+	maxFileTagLength = 50
 )
 
 type UploadService interface {
-	Upload(ctx context.Context, userID int64, filename string, reader io.Reader, size int64, contentType string) (*dto.FileResponse, error)
+	Upload(ctx context.Context, userID int64, filename string, reader io.ReadSeeker, size int64, contentType string, tags []string) (*dto.FileResponse, error)
 	GetFileInfo(ctx context.Context, id, userID int64) (*dto.FileResponse, error)
 	Download(ctx context.Context, id, userID int64) (*sqlc.File, io.ReadCloser, error)
-	List(ctx context.Context, userID int64, page, perPage int) ([]dto.FileResponse, int64, error)
+	OpenStored(ctx context.Context, storagePath string, userID int64) (*sqlc.ListStoredObjectFilesRow, io.ReadCloser, error)
+	List(ctx context.Context, userID int64, query dto.FileListQuery, page, perPage int) ([]dto.FileResponse, int64, error)
+	ArchiveFiles(ctx context.Context, userID int64, ids []int64) ([]sqlc.File, error)
+	WriteArchive(ctx context.Context, w io.Writer, files []sqlc.File) error
 	Delete(ctx context.Context, id, userID int64) error
+	Rename(ctx context.Context, id, userID int64, name string) (*dto.FileResponse, error)
+	ReplaceContent(ctx context.Context, id, userID int64, filename string, reader io.ReadSeeker, size int64, contentType string) (*dto.FileResponse, error)
+	ListVersions(ctx context.Context, id, userID int64) ([]dto.FileVersionResponse, error)
+	DownloadVersion(ctx context.Context, id, userID int64, version int32) (*sqlc.File, io.ReadCloser, error)
+	RestoreVersion(ctx context.Context, id, userID int64, version int32) (*dto.FileResponse, error)
+	Move(ctx context.Context, id, userID int64, folderID *int64) (*dto.FileResponse, error)
+	SetTags(ctx context.Context, id, userID int64, tags []string) (*dto.FileResponse, error)
+	SetVisibility(ctx context.Context, id, userID int64, visibility string) (*dto.FileResponse, error)
+	ListPermissions(ctx context.Context, id, userID int64) ([]dto.FilePermissionResponse, error)
+	GrantPermission(ctx context.Context, id, userID, granteeID int64) error
+	RevokePermission(ctx context.Context, id, userID, granteeID int64) error
 }
 
 type uploadService struct {
-	repo    repository.FileRepository
-	storage storage.Storage
+	repo             repository.FileRepository
+	storage          storage.Storage
+	variantSvc       ImageVariantService
+	contentAddressed bool
+	stripMetadata    bool
 }
 
-func NewUploadService(repo repository.FileRepository, store storage.Storage) UploadService {
-	return &uploadService{repo: repo, storage: store}
+// NewUploadService creates the upload service. With contentAddressed, new objects are
+// stored under the SHA-256 of their content and identical files of any user share them.
+// With stripMetadata, JPEGs are stored without their EXIF and other metadata.
+func NewUploadService(
+	repo repository.FileRepository,
+	store storage.Storage,
+	variantSvc ImageVariantService,
+	contentAddressed bool,
+	stripMetadata bool,
+) UploadService {
+	return &uploadService{
+		repo:             repo,
+		storage:          store,
+		variantSvc:       variantSvc,
+		contentAddressed: contentAddressed,
+		stripMetadata:    stripMetadata,
+	}
 }
 
-func (s *uploadService) Upload(ctx context.Context, userID int64, filename string, reader io.Reader, size int64, contentType string) (*dto.FileResponse, error) {
-	ext := filepath.Ext(filename)
-	storagePath := fmt.Sprintf("%d/%s%s", userID, uuid.New().String(), ext)
+// Upload stores a file, or references the stored object of an identical file the user
+// already has, or that anyone has with the content-addressed layout.
+func (s *uploadService) Upload(ctx context.Context, userID int64, filename string, reader io.ReadSeeker, size int64, contentType string, tags []string) (*dto.FileResponse, error) {
+	ctx, span := telemetry.Start(ctx, "UploadService.Upload")
+	defer span.End()
+
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := s.storage.Put(ctx, storagePath, reader, size, contentType); err != nil {
-		return nil, apperror.NewInternal("failed to store file")
+	reader, size, err = sanitizeUpload(reader, size, contentType, s.stripMetadata)
+	if err != nil {
+		return nil, err
+	}
+	checksum, err := checksumOf(reader)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to read uploaded file")
+	}
+
+	// An identical file is referenced instead of stored again.
+	source, err := findDuplicate(ctx, s.repo, userID, checksum, s.contentAddressed)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to look up duplicate files")
+	}
+
+	var storagePath string
+	keyID := encryptionKeyID(s.storage)
+	if source != nil {
+		storagePath, keyID = source.StoragePath, source.EncryptionKeyID
+	} else {
+		storagePath = newObjectPath(s.contentAddressed, userID, 1, filename, checksum)
+		if err := s.storage.Put(ctx, storagePath, reader, size, contentType); err != nil {
+			return nil, apperror.NewInternal("failed to store file")
+		}
+	}
+	// discard removes what this upload stored, leaving shared objects alone. A
+	// content-addressed object may be shared by a concurrent upload of the same content,
+	// so it is left for storage reconciliation.
+	discard := func() {
+		if source == nil && !s.contentAddressed {
+			_ = s.storage.Delete(ctx, storagePath)
+		}
 	}
 
 	file, err := s.repo.Create(ctx, sqlc.CreateFileParams{
-		UserID:       userID,
-		OriginalName: filename,
-		StoragePath:  storagePath,
-		MimeType:     contentType,
-		Size:         size,
+		UserID:          userID,
+		OriginalName:    filename,
+		StoragePath:     storagePath,
+		MimeType:        contentType,
+		Size:            size,
+		Checksum:        pgtype.Text{String: checksum, Valid: true},
+		EncryptionKeyID: keyID,
 	})
 	if err != nil {
 		// Cleanup storage on DB failure
-		_ = s.storage.Delete(ctx, storagePath)
+		discard()
 		return nil, apperror.NewInternal("failed to save file metadata")
 	}
+	if len(tags) > 0 {
+		if err := s.repo.SetTags(ctx, file.ID, tags); err != nil {
+			discard()
+			_, _ = s.repo.Delete(ctx, file.ID)
+			return nil, apperror.NewInternal("failed to save file tags")
+		}
+	}
+	if source != nil {
+		s.variantSvc.Reuse(ctx, file, source)
+	} else {
+		s.variantSvc.Generate(ctx, file)
+	}
 
-	return s.toFileResponse(file), nil
+	return toFileResponse(s.storage, file, nil, tags), nil
 }
 
 func (s *uploadService) GetFileInfo(ctx context.Context, id, userID int64) (*dto.FileResponse, error) {
-	file, err := s.repo.GetByID(ctx, id)
+	file, err := s.readableFile(ctx, id, userID)
 	if err != nil {
-		if errors.Is(err, apperror.ErrNotFound) {
-			return nil, apperror.NewNotFound("file not found")
-		}
-		return nil, apperror.NewInternal("failed to get file")
+		return nil, err
 	}
-
-	if file.UserID != userID {
-		return nil, apperror.NewForbidden("you can only access your own files")
-	}
-
-	return s.toFileResponse(file), nil
+	return s.fileResponse(ctx, file)
 }
 
 func (s *uploadService) Download(ctx context.Context, id, userID int64) (*sqlc.File, io.ReadCloser, error) {
-	file, err := s.repo.GetByID(ctx, id)
-	if err != nil {
-		if errors.Is(err, apperror.ErrNotFound) {
-			return nil, nil, apperror.NewNotFound("file not found")
-		}
-		return nil, nil, apperror.NewInternal("failed to get file")
-	}
+	ctx, span := telemetry.Start(ctx, "UploadService.Download")
+	defer span.End()
 
-	if file.UserID != userID {
-		return nil, nil, apperror.NewForbidden("you can only access your own files")
+	file, err := s.readableFile(ctx, id, userID)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	reader, err := s.storage.Get(ctx, file.StoragePath)
@@ -96,28 +184,136 @@ func (s *uploadService) Download(ctx context.Context, id, userID int64) (*sqlc.F
 	return file, reader, nil
 }
 
-func (s *uploadService) List(ctx context.Context, userID int64, page, perPage int) ([]dto.FileResponse, int64, error) {
+// OpenStored opens a stored object by its path, as linked by the local driver's URLs, for
+// a user who can read a file it belongs to; several files may share one object. An object
+// the user cannot read is reported as not found, so paths cannot be probed to learn what
+// others stored, which the content-addressed layout would otherwise reveal.
+func (s *uploadService) OpenStored(ctx context.Context, storagePath string, userID int64) (*sqlc.ListStoredObjectFilesRow, io.ReadCloser, error) {
+	rows, err := s.repo.ListByStoragePath(ctx, storagePath)
+	if err != nil {
+		return nil, nil, apperror.NewInternal("failed to get file")
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		readable := row.UserID == userID || row.Visibility == dto.FileVisibilityPublic
+		if !readable {
+			if readable, err = s.repo.HasPermission(ctx, row.ID, userID); err != nil {
+				return nil, nil, apperror.NewInternal("failed to check file permission")
+			}
+		}
+		if !readable {
+			continue
+		}
+
+		reader, err := s.storage.Get(ctx, storagePath)
+		if err != nil {
+			return nil, nil, apperror.NewInternal("failed to read file from storage")
+		}
+		return row, reader, nil
+	}
+	return nil, nil, apperror.NewNotFound("file not found")
+}
+
+// List returns the user's files, narrowed to one folder when query.FolderID is set (0 for
+// files in no folder) and to one tag when query.Tag is set.
+func (s *uploadService) List(ctx context.Context, userID int64, query dto.FileListQuery, page, perPage int) ([]dto.FileResponse, int64, error) {
+	ctx, span := telemetry.Start(ctx, "UploadService.List")
+	defer span.End()
+
 	limit, offset := pagination.LimitOffset(page, perPage)
+	filter := repository.FileFilter{FolderID: query.FolderID, Tag: normalizeTag(query.Tag)}
 
 	// Note: List and Count are separate queries; minor pagination inconsistency is acceptable for read-only operations.
-	files, err := s.repo.ListByUserID(ctx, userID, limit, offset)
+	files, err := s.repo.ListByUserID(ctx, userID, filter, limit, offset)
 	if err != nil {
 		return nil, 0, apperror.NewInternal("failed to list files")
 	}
 
-	total, err := s.repo.CountByUserID(ctx, userID)
+	total, err := s.repo.CountByUserID(ctx, userID, filter)
 	if err != nil {
 		return nil, 0, apperror.NewInternal("failed to count files")
 	}
 
-	responses := make([]dto.FileResponse, len(files))
-	for i, f := range files {
-		responses[i] = *s.toFileResponse(&f)
+	responses, err := toFileResponses(ctx, s.repo, s.storage, files)
+	if err != nil {
+		return nil, 0, apperror.NewInternal("failed to list file details")
 	}
 
 	return responses, total, nil
 }
 
+// ArchiveFiles loads the files to bundle into a ZIP archive, in the requested order and
+// without repeats. Every file must exist and belong to the user.
+func (s *uploadService) ArchiveFiles(ctx context.Context, userID int64, ids []int64) ([]sqlc.File, error) {
+	seen := make(map[int64]struct{}, len(ids))
+	unique := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			unique = append(unique, id)
+		}
+	}
+
+	found, err := s.repo.ListByIDs(ctx, unique)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to get files")
+	}
+	byID := make(map[int64]sqlc.File, len(found))
+	for _, f := range found {
+		byID[f.ID] = f
+	}
+
+	files := make([]sqlc.File, len(unique))
+	for i, id := range unique {
+		f, ok := byID[id]
+		if !ok {
+			return nil, apperror.NewNotFound(fmt.Sprintf("file %d not found", id))
+		}
+		if f.UserID != userID {
+			return nil, apperror.NewForbidden("you can only download your own files as an archive")
+		}
+		files[i] = f
+	}
+	return files, nil
+}
+
+// WriteArchive streams files into a ZIP archive written to w. Each file is copied from
+// storage as it is added, so no file is held in memory whole. Entries are named after the
+// files, with a counter added to repeated names.
+func (s *uploadService) WriteArchive(ctx context.Context, w io.Writer, files []sqlc.File) error {
+	ctx, span := telemetry.Start(ctx, "UploadService.WriteArchive")
+	defer span.End()
+
+	zw := zip.NewWriter(w)
+	names := make(map[string]struct{}, len(files))
+	for i := range files {
+		f := &files[i]
+		entry, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     archiveEntryName(names, f.OriginalName),
+			Method:   zip.Deflate,
+			Modified: f.VersionCreatedAt.Time,
+		})
+		if err != nil {
+			return fmt.Errorf("add file %d: %w", f.ID, err)
+		}
+
+		reader, err := s.storage.Get(ctx, f.StoragePath)
+		if err != nil {
+			return fmt.Errorf("read file %d: %w", f.ID, err)
+		}
+		_, err = io.Copy(entry, reader)
+		_ = reader.Close()
+		if err != nil {
+			return fmt.Errorf("write file %d: %w", f.ID, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("close archive: %w", err)
+	}
+	return nil
+}
+
 func (s *uploadService) Delete(ctx context.Context, id, userID int64) error {
 	file, err := s.repo.GetByID(ctx, id)
 	if err != nil {
@@ -144,13 +340,601 @@ func (s *uploadService) Delete(ctx context.Context, id, userID int64) error {
 	return nil
 }
 
-func (s *uploadService) toFileResponse(file *sqlc.File) *dto.FileResponse {
-	return &dto.FileResponse{
+// Rename changes the name a file is listed and downloaded under. The stored object keeps
+// its path.
+func (s *uploadService) Rename(ctx context.Context, id, userID int64, name string) (*dto.FileResponse, error) {
+	name, err := cleanName(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.ownedFile(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	file, err := s.repo.Rename(ctx, id, name)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("file not found")
+		}
+		return nil, apperror.NewInternal("failed to rename file")
+	}
+	return s.fileResponse(ctx, file)
+}
+
+// ReplaceContent uploads new content for an existing file. The previous content is kept
+// as a version that can be listed, downloaded and restored; the file keeps its ID, name,
+// folder, tags and permissions. Sending the current content again changes nothing.
+func (s *uploadService) ReplaceContent(ctx context.Context, id, userID int64, filename string, reader io.ReadSeeker, size int64, contentType string) (*dto.FileResponse, error) {
+	ctx, span := telemetry.Start(ctx, "UploadService.ReplaceContent")
+	defer span.End()
+
+	file, err := s.ownedFile(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, size, err = sanitizeUpload(reader, size, contentType, s.stripMetadata)
+	if err != nil {
+		return nil, err
+	}
+	checksum, err := checksumOf(reader)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to read uploaded file")
+	}
+	if file.Checksum.Valid && file.Checksum.String == checksum {
+		return s.fileResponse(ctx, file)
+	}
+
+	source, err := findDuplicate(ctx, s.repo, userID, checksum, s.contentAddressed)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to look up duplicate files")
+	}
+
+	var storagePath string
+	keyID := encryptionKeyID(s.storage)
+	if source != nil {
+		storagePath, keyID = source.StoragePath, source.EncryptionKeyID
+	} else {
+		storagePath = newObjectPath(s.contentAddressed, userID, file.Version+1, filename, checksum)
+		if err := s.storage.Put(ctx, storagePath, reader, size, contentType); err != nil {
+			return nil, apperror.NewInternal("failed to store file")
+		}
+	}
+
+	updated, err := s.repo.ReplaceContent(ctx, sqlc.ReplaceFileContentParams{
+		ID:              id,
+		Version:         file.Version,
+		StoragePath:     storagePath,
+		MimeType:        contentType,
+		Size:            size,
+		Checksum:        pgtype.Text{String: checksum, Valid: true},
+		EncryptionKeyID: keyID,
+	})
+	if err != nil {
+		if source == nil && !s.contentAddressed {
+			_ = s.storage.Delete(ctx, storagePath)
+		}
+		return nil, versionWriteError(err)
+	}
+
+	s.replaceVariants(ctx, updated, source)
+	return s.fileResponse(ctx, updated)
+}
+
+// ListVersions returns every version of a file, the current one first.
+func (s *uploadService) ListVersions(ctx context.Context, id, userID int64) ([]dto.FileVersionResponse, error) {
+	file, err := s.readableFile(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := s.repo.ListVersions(ctx, id)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to list file versions")
+	}
+
+	result := make([]dto.FileVersionResponse, 0, len(versions)+1)
+	result = append(result, dto.FileVersionResponse{
+		Version:   file.Version,
+		MimeType:  file.MimeType,
+		Size:      file.Size,
+		Checksum:  file.Checksum.String,
+		Current:   true,
+		CreatedAt: file.VersionCreatedAt.Time,
+	})
+	for _, v := range versions {
+		result = append(result, dto.FileVersionResponse{
+			Version:   v.Version,
+			MimeType:  v.MimeType,
+			Size:      v.Size,
+			Checksum:  v.Checksum.String,
+			CreatedAt: v.CreatedAt.Time,
+		})
+	}
+	return result, nil
+}
+
+// DownloadVersion opens one version of a file. The returned file describes that version's
+// content under the file's current name.
+func (s *uploadService) DownloadVersion(ctx context.Context, id, userID int64, version int32) (*sqlc.File, io.ReadCloser, error) {
+	file, err := s.readableFile(ctx, id, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if version != file.Version {
+		v, err := s.fileVersion(ctx, id, version)
+		if err != nil {
+			return nil, nil, err
+		}
+		archived := *file
+		archived.StoragePath = v.StoragePath
+		archived.MimeType = v.MimeType
+		archived.Size = v.Size
+		archived.Checksum = v.Checksum
+		file = &archived
+	}
+
+	reader, err := s.storage.Get(ctx, file.StoragePath)
+	if err != nil {
+		return nil, nil, apperror.NewInternal("failed to read file from storage")
+	}
+
+	return file, reader, nil
+}
+
+// RestoreVersion makes an older version's content current again. Like an upload, this
+// adds a new version, so the content it replaces can itself be restored later.
+func (s *uploadService) RestoreVersion(ctx context.Context, id, userID int64, version int32) (*dto.FileResponse, error) {
+	file, err := s.ownedFile(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if version == file.Version {
+		return s.fileResponse(ctx, file)
+	}
+
+	v, err := s.fileVersion(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.ReplaceContent(ctx, sqlc.ReplaceFileContentParams{
+		ID:              id,
+		Version:         file.Version,
+		StoragePath:     v.StoragePath,
+		MimeType:        v.MimeType,
+		Size:            v.Size,
+		Checksum:        v.Checksum,
+		EncryptionKeyID: v.EncryptionKeyID,
+	})
+	if err != nil {
+		return nil, versionWriteError(err)
+	}
+
+	s.replaceVariants(ctx, updated, nil)
+	return s.fileResponse(ctx, updated)
+}
+
+// Move puts a file into one of the owner's folders, or into no folder when folderID is nil.
+func (s *uploadService) Move(ctx context.Context, id, userID int64, folderID *int64) (*dto.FileResponse, error) {
+	if _, err := s.ownedFile(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	params := sqlc.MoveFileParams{ID: id}
+	if folderID != nil {
+		params.FolderID = pgtype.Int8{Int64: *folderID, Valid: true}
+	}
+	file, err := s.repo.Move(ctx, params)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			// The file was just loaded, so the folder is missing or someone else's.
+			return nil, apperror.NewNotFound("folder not found")
+		}
+		return nil, apperror.NewInternal("failed to move file")
+	}
+	return s.fileResponse(ctx, file)
+}
+
+// SetTags replaces a file's tags. Tags are trimmed, lowercased and deduplicated.
+func (s *uploadService) SetTags(ctx context.Context, id, userID int64, tags []string) (*dto.FileResponse, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	file, err := s.ownedFile(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetTags(ctx, id, tags); err != nil {
+		return nil, apperror.NewInternal("failed to update file tags")
+	}
+	return s.fileResponse(ctx, file)
+}
+
+// SetVisibility makes a file public to every signed-in user or private again.
+func (s *uploadService) SetVisibility(ctx context.Context, id, userID int64, visibility string) (*dto.FileResponse, error) {
+	if _, err := s.ownedFile(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	file, err := s.repo.SetVisibility(ctx, id, visibility)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("file not found")
+		}
+		return nil, apperror.NewInternal("failed to update file visibility")
+	}
+	return s.fileResponse(ctx, file)
+}
+
+// ListPermissions returns the users the owner granted read access to.
+func (s *uploadService) ListPermissions(ctx context.Context, id, userID int64) ([]dto.FilePermissionResponse, error) {
+	if _, err := s.ownedFile(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	perms, err := s.repo.ListPermissions(ctx, id)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to list file permissions")
+	}
+
+	result := make([]dto.FilePermissionResponse, len(perms))
+	for i, p := range perms {
+		result[i] = dto.FilePermissionResponse{
+			UserID:    p.UserID,
+			Email:     p.Email,
+			Name:      p.Name,
+			CreatedAt: p.CreatedAt.Time,
+		}
+		if p.GrantedBy.Valid {
+			result[i].GrantedBy = &p.GrantedBy.Int64
+		}
+	}
+	return result, nil
+}
+
+// GrantPermission lets another user read a private file. Granting twice is a no-op.
+func (s *uploadService) GrantPermission(ctx context.Context, id, userID, granteeID int64) error {
+	if _, err := s.ownedFile(ctx, id, userID); err != nil {
+		return err
+	}
+	if granteeID == userID {
+		return apperror.NewBadRequest("you already own this file")
+	}
+
+	_, err := s.repo.GrantPermission(ctx, sqlc.GrantFilePermissionParams{
+		FileID:    id,
+		UserID:    granteeID,
+		GrantedBy: pgtype.Int8{Int64: userID, Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return apperror.NewNotFound("user not found")
+		}
+		return apperror.NewInternal("failed to grant file permission")
+	}
+	return nil
+}
+
+// RevokePermission removes a user's read access to a file.
+func (s *uploadService) RevokePermission(ctx context.Context, id, userID, granteeID int64) error {
+	if _, err := s.ownedFile(ctx, id, userID); err != nil {
+		return err
+	}
+
+	if err := s.repo.RevokePermission(ctx, id, granteeID); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return apperror.NewNotFound("permission not found")
+		}
+		return apperror.NewInternal("failed to revoke file permission")
+	}
+	return nil
+}
+
+// replaceVariants drops the variants rendered for a file's previous content, deleting the
+// objects no other file shares, and renders or reuses those of its new content.
+func (s *uploadService) replaceVariants(ctx context.Context, file, source *sqlc.File) {
+	paths, err := s.repo.DeleteVariants(ctx, file.ID)
+	if err != nil {
+		slog.Error("failed to delete image variants", slog.Int64("file_id", file.ID), slog.Any("error", err))
+	}
+	for _, p := range paths {
+		if err := s.storage.Delete(ctx, p); err != nil {
+			slog.Error("failed to delete stored variant", slog.String("path", p), slog.Any("error", err))
+		}
+	}
+
+	if source != nil {
+		s.variantSvc.Reuse(ctx, file, source)
+	} else {
+		s.variantSvc.Generate(ctx, file)
+	}
+}
+
+// fileVersion loads an archived version of a file.
+func (s *uploadService) fileVersion(ctx context.Context, id int64, version int32) (*sqlc.FileVersion, error) {
+	v, err := s.repo.GetVersion(ctx, id, version)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("file version not found")
+		}
+		return nil, apperror.NewInternal("failed to get file version")
+	}
+	return v, nil
+}
+
+// fileResponse converts a file to a response that includes its rendered variants and tags.
+func (s *uploadService) fileResponse(ctx context.Context, file *sqlc.File) (*dto.FileResponse, error) {
+	responses, err := toFileResponses(ctx, s.repo, s.storage, []sqlc.File{*file})
+	if err != nil {
+		return nil, apperror.NewInternal("failed to get file details")
+	}
+	return &responses[0], nil
+}
+
+// readableFile loads a file the user may read: their own, a public one, or one they
+// were granted access to.
+func (s *uploadService) readableFile(ctx context.Context, id, userID int64) (*sqlc.File, error) {
+	file, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("file not found")
+		}
+		return nil, apperror.NewInternal("failed to get file")
+	}
+	if file.UserID == userID || file.Visibility == dto.FileVisibilityPublic {
+		return file, nil
+	}
+
+	granted, err := s.repo.HasPermission(ctx, id, userID)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to check file permission")
+	}
+	if !granted {
+		return nil, apperror.NewForbidden("you do not have access to this file")
+	}
+	return file, nil
+}
+
+// ownedFile loads a file that belongs to the user, for changes only the owner may make.
+func (s *uploadService) ownedFile(ctx context.Context, id, userID int64) (*sqlc.File, error) {
+	file, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("file not found")
+		}
+		return nil, apperror.NewInternal("failed to get file")
+	}
+	if file.UserID != userID {
+		return nil, apperror.NewForbidden("you can only change your own files")
+	}
+	return file, nil
+}
+
+// archiveEntryName turns a file name into a flat ZIP entry name that is not yet in names,
+// e.g. report (2).pdf for the second report.pdf, and records it. Directory parts are
+// dropped so that extracting the archive cannot write outside its target directory.
+func archiveEntryName(names map[string]struct{}, filename string) string {
+	base := path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	if base == "." || base == "/" || base == ".." {
+		base = "file"
+	}
+
+	name := base
+	ext := path.Ext(base)
+	for n := 2; ; n++ {
+		if _, taken := names[name]; !taken {
+			break
+		}
+		name = fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(base, ext), n, ext)
+	}
+	names[name] = struct{}{}
+	return name
+}
+
+// versionWriteError maps a failed content replacement to an API error. The file was just
+// loaded, so a missing row means another request changed or deleted it in the meantime.
+func versionWriteError(err error) error {
+	if errors.Is(err, apperror.ErrNotFound) || repository.IsUniqueViolation(err) {
+		return apperror.NewConflict("the file was changed by another request, please try again")
+	}
+	return apperror.NewInternal("failed to save file version")
+}
+
+// encryptionKeyID returns the ID of the key store encrypts new objects with, to record on
+// the file, or NULL when objects are stored in plain.
+func encryptionKeyID(store storage.Storage) pgtype.Text {
+	id := storage.KeyID(store)
+	return pgtype.Text{String: id, Valid: id != ""}
+}
+
+// newStoragePath returns a fresh storage path for a version of a user's file, keeping the
+// extension of filename: 1/<uuid>.png for the first version, 1/<uuid>.v2.png after that.
+func newStoragePath(userID int64, version int32, filename string) string {
+	if version <= 1 {
+		return fmt.Sprintf("%d/%s%s", userID, uuid.New().String(), filepath.Ext(filename))
+	}
+	return fmt.Sprintf("%d/%s.v%d%s", userID, uuid.New().String(), version, filepath.Ext(filename))
+}
+
+// contentStoragePrefix holds the objects of the content-addressed layout, named by the
+// SHA-256 of their content.
+const contentStoragePrefix = "sha256/"
+
+// newObjectPath returns where new content is stored: sha256/<checksum> with the
+// content-addressed layout, or a fresh path of the user's otherwise.
+func newObjectPath(contentAddressed bool, userID int64, version int32, filename, checksum string) string {
+	if contentAddressed {
+		return contentStoragePrefix + checksum
+	}
+	return newStoragePath(userID, version, filename)
+}
+
+// findDuplicate returns the oldest live file with the given content whose stored object
+// can be reused: one of the user's, or anyone's with the content-addressed layout. It
+// returns nil when there is none.
+func findDuplicate(
+	ctx context.Context,
+	repo repository.FileRepository,
+	userID int64,
+	checksum string,
+	contentAddressed bool,
+) (*sqlc.File, error) {
+	var (
+		source *sqlc.File
+		err    error
+	)
+	if contentAddressed {
+		source, err = repo.GetAnyByChecksum(ctx, checksum)
+	} else {
+		source, err = repo.GetByChecksum(ctx, userID, checksum)
+	}
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil, nil
+	}
+	return source, err
+}
+
+// unsharedPaths drops the storage paths that files of other users still refer to, which
+// happens when the content-addressed layout stores their identical content once.
+func unsharedPaths(ctx context.Context, repo repository.FileRepository, userID int64, paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return paths, nil
+	}
+	shared, err := repo.ListSharedStoragePaths(ctx, userID, paths)
+	if err != nil || len(shared) == 0 {
+		return paths, err
+	}
+	return slices.DeleteFunc(paths, func(p string) bool {
+		return slices.Contains(shared, p)
+	}), nil
+}
+
+// checksumOf returns the hex-encoded SHA-256 of r's content and rewinds r.
+func checksumOf(r io.ReadSeeker) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// sanitizeUpload returns the content to store for an upload and its size: with
+// stripMetadata, a JPEG without its metadata and turned upright, otherwise r itself.
+func sanitizeUpload(r io.ReadSeeker, size int64, contentType string, stripMetadata bool) (io.ReadSeeker, int64, error) {
+	if !stripMetadata || contentType != imaging.ContentType(imaging.FormatJPEG) {
+		return r, size, nil
+	}
+	var buf bytes.Buffer
+	if err := imaging.SanitizeJPEG(&buf, r); err != nil {
+		if errors.Is(err, imaging.ErrInvalidJPEG) {
+			return nil, 0, apperror.NewBadRequest("file is not a valid JPEG image")
+		}
+		return nil, 0, apperror.NewInternal("failed to read uploaded file")
+	}
+	return bytes.NewReader(buf.Bytes()), int64(buf.Len()), nil
+}
+
+// normalizeTag trims and lowercases a tag so that tags match regardless of case.
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// normalizeTags normalizes tags, drops blank ones and duplicates, and sorts the rest.
+func normalizeTags(tags []string) ([]string, error) {
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if tag == "" {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxFileTagLength {
+			return nil, apperror.NewBadRequest(fmt.Sprintf("tags must be at most %d characters", maxFileTagLength))
+		}
+		if strings.Contains(tag, ",") {
+			return nil, apperror.NewBadRequest("tags must not contain commas")
+		}
+		result = append(result, tag)
+	}
+	slices.Sort(result)
+	result = slices.Compact(result)
+	if len(result) > maxFileTags {
+		return nil, apperror.NewBadRequest(fmt.Sprintf("a file can have at most %d tags", maxFileTags))
+	}
+	return result, nil
+}
+
+// toFileResponses converts files to responses that include their rendered variants and tags.
+func toFileResponses(
+	ctx context.Context,
+	repo repository.FileRepository,
+	store storage.Storage,
+	files []sqlc.File,
+) ([]dto.FileResponse, error) {
+	ids := make([]int64, len(files))
+	for i := range files {
+		ids[i] = files[i].ID
+	}
+	variants, err := repo.ListVariantsByFileIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byFile := make(map[int64][]sqlc.FileVariant, len(files))
+	for _, v := range variants {
+		byFile[v.FileID] = append(byFile[v.FileID], v)
+	}
+	tags, err := repo.ListTagsByFileIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	tagsByFile := make(map[int64][]string, len(files))
+	for _, t := range tags {
+		tagsByFile[t.FileID] = append(tagsByFile[t.FileID], t.Tag)
+	}
+
+	responses := make([]dto.FileResponse, len(files))
+	for i := range files {
+		responses[i] = *toFileResponse(store, &files[i], byFile[files[i].ID], tagsByFile[files[i].ID])
+	}
+	return responses, nil
+}
+
+func toFileResponse(store storage.Storage, file *sqlc.File, variants []sqlc.FileVariant, tags []string) *dto.FileResponse {
+	resp := &dto.FileResponse{
 		ID:           file.ID,
 		OriginalName: file.OriginalName,
 		MimeType:     file.MimeType,
 		Size:         file.Size,
-		URL:          s.storage.URL(file.StoragePath),
+		URL:          store.URL(file.StoragePath),
+		Visibility:   file.Visibility,
+		Checksum:     file.Checksum.String,
+		Tags:         tags,
+		Version:      file.Version,
 		CreatedAt:    file.CreatedAt.Time,
 	}
+	if file.FolderID.Valid {
+		resp.FolderID = &file.FolderID.Int64
+	}
+	if file.DeletedAt.Valid {
+		resp.DeletedAt = &file.DeletedAt.Time
+	}
+	if len(variants) > 0 {
+		resp.Variants = make(map[string]dto.FileVariantResponse, len(variants))
+		for _, v := range variants {
+			resp.Variants[v.Name] = dto.FileVariantResponse{
+				URL:      store.URL(v.StoragePath),
+				MimeType: v.MimeType,
+				Width:    v.Width,
+				Height:   v.Height,
+				Size:     v.Size,
+			}
+		}
+	}
+	return resp
 }
@@ -1,18 +1,28 @@
 package service
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/storage"
 )
 
 func newTestUploadService(repo *mockFileRepo, store *mockStorage) UploadService {
-	return NewUploadService(repo, store)
+	return NewUploadService(repo, store, NewImageVariantService(repo, store, nil), false, false)
 }
 
 // ---------------------------------------------------------------------------
@@ -25,7 +35,7 @@ func TestUpload(t *testing.T) {
 		store := newMockStorage()
 		svc := newTestUploadService(repo, store)
 
-		resp, err := svc.Upload(context.Background(), 1, "photo.jpg", strings.NewReader("image-data"), 10, "image/jpeg")
+		resp, err := svc.Upload(context.Background(), 1, "photo.jpg", strings.NewReader("image-data"), 10, "image/jpeg", nil)
 		if err != nil {
 			t.Fatalf("expected no error, got %v", err)
 		}
@@ -53,7 +63,7 @@ func TestUpload(t *testing.T) {
 		store.putErr = fmt.Errorf("disk full")
 		svc := newTestUploadService(repo, store)
 
-		_, err := svc.Upload(context.Background(), 1, "photo.jpg", strings.NewReader("data"), 4, "image/jpeg")
+		_, err := svc.Upload(context.Background(), 1, "photo.jpg", strings.NewReader("data"), 4, "image/jpeg", nil)
 		if err == nil {
 			t.Fatal("expected error for storage failure")
 		}
@@ -70,9 +80,9 @@ func TestUpload(t *testing.T) {
 		store := newMockStorage()
 		// Use a special repo that always fails on Create
 		failRepo := &failingFileRepo{mockFileRepo: newMockFileRepo(), failCreate: true}
-		svc := NewUploadService(failRepo, store)
+		svc := NewUploadService(failRepo, store, NewImageVariantService(failRepo, store, nil), false, false)
 
-		_, err := svc.Upload(context.Background(), 1, "photo.jpg", strings.NewReader("data"), 4, "image/jpeg")
+		_, err := svc.Upload(context.Background(), 1, "photo.jpg", strings.NewReader("data"), 4, "image/jpeg", nil)
 		if err == nil {
 			t.Fatal("expected error for DB failure")
 		}
@@ -81,6 +91,156 @@ func TestUpload(t *testing.T) {
 			t.Error("storage should be cleaned up after DB failure")
 		}
 	})
+
+	t.Run("encrypted storage records the key", func(t *testing.T) {
+		repo := newMockFileRepo()
+		store := newMockStorage()
+		keys, err := storage.NewStaticKeyWrapper(map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}, "k1")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		encrypted := storage.NewEncryptedStorage(store, keys)
+		svc := NewUploadService(repo, encrypted, NewImageVariantService(repo, encrypted, nil), false, false)
+
+		resp, err := svc.Upload(context.Background(), 1, "notes.txt", strings.NewReader("secret"), 6, "text/plain", nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		file := repo.files[resp.ID]
+		if file.EncryptionKeyID.String != "k1" || file.Size != 6 {
+			t.Errorf("expected key k1 and the plain size, got %+v", file)
+		}
+		if bytes.Contains(store.files[file.StoragePath], []byte("secret")) {
+			t.Error("expected the stored object to be encrypted")
+		}
+
+		_, reader, err := svc.Download(context.Background(), resp.ID, 1)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		data, _ := io.ReadAll(reader)
+		if string(data) != "secret" {
+			t.Errorf("expected the decrypted content, got %q", data)
+		}
+	})
+}
+
+// exifTaggedJPEG returns a small JPEG carrying an EXIF segment with a camera model.
+func exifTaggedJPEG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	exif := []byte("Exif\x00\x00II*\x00\x08\x00\x00\x00\x00\x00Model: Phone X")
+	segment := append([]byte{0xFF, 0xE1, 0x00, byte(len(exif) + 2)}, exif...)
+	return append(append([]byte{0xFF, 0xD8}, segment...), buf.Bytes()[2:]...)
+}
+
+func TestUploadStripsImageMetadata(t *testing.T) {
+	t.Run("JPEG metadata is removed before storing", func(t *testing.T) {
+		repo := newMockFileRepo()
+		store := newMockStorage()
+		svc := NewUploadService(repo, store, NewImageVariantService(repo, store, nil), false, true)
+		data := exifTaggedJPEG(t)
+
+		resp, err := svc.Upload(context.Background(), 1, "photo.jpg", bytes.NewReader(data), int64(len(data)), "image/jpeg", nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		file := repo.files[resp.ID]
+		stored := store.files[file.StoragePath]
+		if bytes.Contains(stored, []byte("Phone X")) {
+			t.Error("expected the EXIF segment to be stripped")
+		}
+		if file.Size != int64(len(stored)) {
+			t.Errorf("expected the stored size %d recorded, got %d", len(stored), file.Size)
+		}
+		if _, err := jpeg.Decode(bytes.NewReader(stored)); err != nil {
+			t.Errorf("expected a valid JPEG, got %v", err)
+		}
+	})
+
+	t.Run("invalid JPEG is rejected", func(t *testing.T) {
+		repo := newMockFileRepo()
+		store := newMockStorage()
+		svc := NewUploadService(repo, store, NewImageVariantService(repo, store, nil), false, true)
+
+		_, err := svc.Upload(context.Background(), 1, "photo.jpg", strings.NewReader("data"), 4, "image/jpeg", nil)
+		assertAppErrorCode(t, err, 400)
+		if len(store.files) != 0 {
+			t.Error("expected nothing stored")
+		}
+	})
+
+	t.Run("other types are stored as sent", func(t *testing.T) {
+		repo := newMockFileRepo()
+		store := newMockStorage()
+		svc := NewUploadService(repo, store, NewImageVariantService(repo, store, nil), false, true)
+
+		resp, err := svc.Upload(context.Background(), 1, "notes.txt", strings.NewReader("Exif"), 4, "text/plain", nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if got := string(store.files[repo.files[resp.ID].StoragePath]); got != "Exif" {
+			t.Errorf("expected the content unchanged, got %q", got)
+		}
+	})
+}
+
+func TestUploadContentAddressed(t *testing.T) {
+	const helloSum = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+	t.Run("identical files of different users share one object", func(t *testing.T) {
+		repo := newMockFileRepo()
+		store := newMockStorage()
+		svc := NewUploadService(repo, store, NewImageVariantService(repo, store, nil), true, false)
+
+		first, err := svc.Upload(context.Background(), 1, "a.txt", strings.NewReader("hello"), 5, "text/plain", nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		second, err := svc.Upload(context.Background(), 2, "b.txt", strings.NewReader("hello"), 5, "text/plain", nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		for _, id := range []int64{first.ID, second.ID} {
+			if p := repo.files[id].StoragePath; p != "sha256/"+helloSum {
+				t.Errorf("expected a content-addressed path, got %s", p)
+			}
+		}
+		if len(store.files) != 1 {
+			t.Errorf("expected one stored object, storage holds %d", len(store.files))
+		}
+	})
+
+	t.Run("new content of a file is stored under its checksum", func(t *testing.T) {
+		repo := newMockFileRepo()
+		store := newMockStorage()
+		svc := NewUploadService(repo, store, NewImageVariantService(repo, store, nil), true, false)
+
+		file, _ := svc.Upload(context.Background(), 1, "a.txt", strings.NewReader("hi"), 2, "text/plain", nil)
+		if _, err := svc.ReplaceContent(context.Background(), file.ID, 1, "a.txt", strings.NewReader("hello"), 5, "text/plain"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if p := repo.files[file.ID].StoragePath; p != "sha256/"+helloSum {
+			t.Errorf("expected a content-addressed path, got %s", p)
+		}
+	})
+
+	t.Run("DB failure keeps the object", func(t *testing.T) {
+		store := newMockStorage()
+		failRepo := &failingFileRepo{mockFileRepo: newMockFileRepo(), failCreate: true}
+		svc := NewUploadService(failRepo, store, NewImageVariantService(failRepo, store, nil), true, false)
+
+		if _, err := svc.Upload(context.Background(), 1, "a.txt", strings.NewReader("hello"), 5, "text/plain", nil); err == nil {
+			t.Fatal("expected error for DB failure")
+		}
+		if _, ok := store.files["sha256/"+helloSum]; !ok {
+			t.Error("expected the object left for a concurrent upload of the same content")
+		}
+	})
 }
 
 // failingFileRepo wraps mockFileRepo but can fail on specific operations
@@ -96,6 +256,69 @@ func (r *failingFileRepo) Create(_ context.Context, _ sqlc.CreateFileParams) (*s
 	return r.mockFileRepo.Create(context.Background(), sqlc.CreateFileParams{})
 }
 
+func TestUploadDeduplication(t *testing.T) {
+	t.Run("identical upload reuses the stored object", func(t *testing.T) {
+		repo := newMockFileRepo()
+		store := newMockStorage()
+		svc := newTestUploadService(repo, store)
+
+		first, err := svc.Upload(context.Background(), 1, "a.txt", strings.NewReader("hello world"), 11, "text/plain", nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		second, err := svc.Upload(context.Background(), 1, "copy.txt", strings.NewReader("hello world"), 11, "text/plain", nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if first.Checksum != "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9" {
+			t.Errorf("unexpected checksum %s", first.Checksum)
+		}
+		if second.ID == first.ID || second.OriginalName != "copy.txt" {
+			t.Errorf("expected a separate file record, got %+v", second)
+		}
+		if repo.files[second.ID].StoragePath != repo.files[first.ID].StoragePath || len(store.files) != 1 {
+			t.Errorf("expected one shared object, storage holds %d", len(store.files))
+		}
+	})
+
+	t.Run("different users and contents are stored separately", func(t *testing.T) {
+		repo := newMockFileRepo()
+		store := newMockStorage()
+		svc := newTestUploadService(repo, store)
+
+		uploads := []struct {
+			userID int64
+			data   string
+		}{{1, "hello world"}, {2, "hello world"}, {1, "other"}}
+		for _, u := range uploads {
+			if _, err := svc.Upload(context.Background(), u.userID, "a.txt", strings.NewReader(u.data), int64(len(u.data)), "text/plain", nil); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+		if len(store.files) != 3 {
+			t.Errorf("expected 3 stored objects, got %d", len(store.files))
+		}
+	})
+
+	t.Run("failed save keeps the shared object", func(t *testing.T) {
+		repo := newMockFileRepo()
+		store := newMockStorage()
+		failRepo := &failingFileRepo{mockFileRepo: repo, failCreate: true}
+		svc := NewUploadService(failRepo, store, NewImageVariantService(failRepo, store, nil), false, false)
+		repo.files[1] = &sqlc.File{ID: 1, UserID: 1, StoragePath: "1/a.txt", Checksum: pgtype.Text{
+			String: "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", Valid: true,
+		}}
+		store.files["1/a.txt"] = []byte("hello world")
+
+		_, err := svc.Upload(context.Background(), 1, "copy.txt", strings.NewReader("hello world"), 11, "text/plain", nil)
+		assertAppErrorCode(t, err, 500)
+		if _, ok := store.files["1/a.txt"]; !ok {
+			t.Error("expected the shared object to be kept")
+		}
+	})
+}
+
 // ---------------------------------------------------------------------------
 // GetFileInfo
 // ---------------------------------------------------------------------------
@@ -241,7 +464,7 @@ func TestUploadList(t *testing.T) {
 		repo.files[3] = &sqlc.File{ID: 3, UserID: 20, OriginalName: "c.txt", StoragePath: "20/c.txt", MimeType: "text/plain", Size: 3}
 		repo.nextID = 4
 
-		files, total, err := svc.List(context.Background(), 10, 1, 10)
+		files, total, err := svc.List(context.Background(), 10, dto.FileListQuery{}, 1, 10)
 		if err != nil {
 			t.Fatalf("expected no error, got %v", err)
 		}
@@ -253,3 +476,451 @@ func TestUploadList(t *testing.T) {
 		}
 	})
 }
+
+// ---------------------------------------------------------------------------
+// Tags
+// ---------------------------------------------------------------------------
+
+func TestFileTags(t *testing.T) {
+	t.Run("tags given at upload are normalized", func(t *testing.T) {
+		repo := newMockFileRepo()
+		svc := newTestUploadService(repo, newMockStorage())
+
+		resp, err := svc.Upload(context.Background(), 1, "a.txt", strings.NewReader("data"), 4, "text/plain", []string{" Invoices", "2026", "invoices", ""})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if fmt.Sprint(resp.Tags) != "[2026 invoices]" {
+			t.Errorf("expected [2026 invoices], got %v", resp.Tags)
+		}
+		info, err := svc.GetFileInfo(context.Background(), resp.ID, 1)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if fmt.Sprint(info.Tags) != "[2026 invoices]" {
+			t.Errorf("expected stored tags, got %v", info.Tags)
+		}
+	})
+
+	t.Run("invalid tags reject the upload before storing", func(t *testing.T) {
+		repo := newMockFileRepo()
+		store := newMockStorage()
+		svc := newTestUploadService(repo, store)
+
+		_, err := svc.Upload(context.Background(), 1, "a.txt", strings.NewReader("data"), 4, "text/plain", []string{strings.Repeat("x", 51)})
+		assertAppErrorCode(t, err, 400)
+		if len(store.files) != 0 || len(repo.files) != 0 {
+			t.Error("expected nothing to be stored")
+		}
+	})
+
+	t.Run("set tags replaces them and filters lists", func(t *testing.T) {
+		repo := newMockFileRepo()
+		svc := newTestUploadService(repo, newMockStorage())
+		repo.files[1] = &sqlc.File{ID: 1, UserID: 10, OriginalName: "a.txt", StoragePath: "10/a.txt"}
+		repo.files[2] = &sqlc.File{ID: 2, UserID: 10, OriginalName: "b.txt", StoragePath: "10/b.txt"}
+
+		if _, err := svc.SetTags(context.Background(), 1, 10, []string{"work", "draft"}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		resp, err := svc.SetTags(context.Background(), 1, 10, []string{"Work"})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if fmt.Sprint(resp.Tags) != "[work]" {
+			t.Errorf("expected [work], got %v", resp.Tags)
+		}
+
+		files, total, err := svc.List(context.Background(), 10, dto.FileListQuery{Tag: "WORK"}, 1, 10)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if total != 1 || len(files) != 1 || files[0].ID != 1 {
+			t.Errorf("expected only file 1 tagged work, got %+v (total %d)", files, total)
+		}
+		_, total, _ = svc.List(context.Background(), 10, dto.FileListQuery{Tag: "draft"}, 1, 10)
+		if total != 0 {
+			t.Errorf("expected replaced tag to be gone, got %d files", total)
+		}
+	})
+
+	t.Run("too many tags", func(t *testing.T) {
+		repo := newMockFileRepo()
+		svc := newTestUploadService(repo, newMockStorage())
+		repo.files[1] = &sqlc.File{ID: 1, UserID: 10, StoragePath: "10/a.txt"}
+
+		tags := make([]string, maxFileTags+1)
+		for i := range tags {
+			tags[i] = fmt.Sprintf("tag%d", i)
+		}
+		_, err := svc.SetTags(context.Background(), 1, 10, tags)
+		assertAppErrorCode(t, err, 400)
+	})
+
+	t.Run("only the owner sets tags", func(t *testing.T) {
+		repo := newMockFileRepo()
+		svc := newTestUploadService(repo, newMockStorage())
+		repo.files[1] = &sqlc.File{ID: 1, UserID: 10, StoragePath: "10/a.txt"}
+
+		_, err := svc.SetTags(context.Background(), 1, 20, []string{"mine"})
+		assertAppErrorCode(t, err, 403)
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Versions
+// ---------------------------------------------------------------------------
+
+func TestFileVersions(t *testing.T) {
+	upload := func(t *testing.T, svc UploadService) *dto.FileResponse {
+		t.Helper()
+		resp, err := svc.Upload(context.Background(), 1, "notes.txt", strings.NewReader("first"), 5, "text/plain", nil)
+		if err != nil {
+			t.Fatalf("upload: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("new content keeps the previous version", func(t *testing.T) {
+		repo := newMockFileRepo()
+		store := newMockStorage()
+		svc := newTestUploadService(repo, store)
+		file := upload(t, svc)
+		firstPath := repo.files[file.ID].StoragePath
+
+		resp, err := svc.ReplaceContent(context.Background(), file.ID, 1, "notes-v2.txt", strings.NewReader("second!"), 7, "text/plain")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if resp.ID != file.ID || resp.Version != 2 || resp.Size != 7 || resp.OriginalName != "notes.txt" {
+			t.Errorf("expected version 2 of the same file, got %+v", resp)
+		}
+		if path := repo.files[file.ID].StoragePath; path == firstPath || !strings.HasSuffix(path, ".v2.txt") {
+			t.Errorf("expected a new versioned path, got %s", path)
+		}
+
+		versions, err := svc.ListVersions(context.Background(), file.ID, 1)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(versions) != 2 || versions[0].Version != 2 || !versions[0].Current || versions[1].Version != 1 || versions[1].Current {
+			t.Errorf("expected versions 2 (current) and 1, got %+v", versions)
+		}
+
+		old, reader, err := svc.DownloadVersion(context.Background(), file.ID, 1, 1)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		data, _ := io.ReadAll(reader)
+		if string(data) != "first" || old.Size != 5 || old.OriginalName != "notes.txt" {
+			t.Errorf("expected the first content, got %q (%+v)", data, old)
+		}
+	})
+
+	t.Run("restore adds a version with the old content", func(t *testing.T) {
+		repo := newMockFileRepo()
+		store := newMockStorage()
+		svc := newTestUploadService(repo, store)
+		file := upload(t, svc)
+		if _, err := svc.ReplaceContent(context.Background(), file.ID, 1, "notes.txt", strings.NewReader("second!"), 7, "text/plain"); err != nil {
+			t.Fatalf("replace: %v", err)
+		}
+
+		resp, err := svc.RestoreVersion(context.Background(), file.ID, 1, 1)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if resp.Version != 3 || resp.Size != 5 || resp.Checksum != repo.versions[0].Checksum.String {
+			t.Errorf("expected version 3 with the first content, got %+v", resp)
+		}
+		versions, _ := svc.ListVersions(context.Background(), file.ID, 1)
+		if len(versions) != 3 {
+			t.Errorf("expected 3 versions, got %d", len(versions))
+		}
+
+		_, err = svc.RestoreVersion(context.Background(), file.ID, 1, 9)
+		assertAppErrorCode(t, err, 404)
+		_, _, err = svc.DownloadVersion(context.Background(), file.ID, 1, 9)
+		assertAppErrorCode(t, err, 404)
+	})
+
+	t.Run("identical content changes nothing", func(t *testing.T) {
+		repo := newMockFileRepo()
+		store := newMockStorage()
+		svc := newTestUploadService(repo, store)
+		file := upload(t, svc)
+
+		resp, err := svc.ReplaceContent(context.Background(), file.ID, 1, "notes.txt", strings.NewReader("first"), 5, "text/plain")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if resp.Version != 1 || len(repo.versions) != 0 || len(store.files) != 1 {
+			t.Errorf("expected no new version, got version %d", resp.Version)
+		}
+	})
+
+	t.Run("variants of the previous content are dropped", func(t *testing.T) {
+		repo := newMockFileRepo()
+		store := newMockStorage()
+		svc := newTestUploadService(repo, store)
+		file := upload(t, svc)
+		repo.variants = []sqlc.FileVariant{{ID: 1, FileID: file.ID, Name: "thumbnail", StoragePath: "1/old_thumbnail.jpg"}}
+		store.files["1/old_thumbnail.jpg"] = []byte("thumb")
+
+		if _, err := svc.ReplaceContent(context.Background(), file.ID, 1, "notes.txt", strings.NewReader("second!"), 7, "text/plain"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(repo.variants) != 0 {
+			t.Errorf("expected variants to be removed, got %+v", repo.variants)
+		}
+		if _, ok := store.files["1/old_thumbnail.jpg"]; ok {
+			t.Error("expected the old variant object to be deleted")
+		}
+	})
+
+	t.Run("only the owner changes versions", func(t *testing.T) {
+		repo := newMockFileRepo()
+		store := newMockStorage()
+		svc := newTestUploadService(repo, store)
+		file := upload(t, svc)
+
+		_, err := svc.ReplaceContent(context.Background(), file.ID, 2, "notes.txt", strings.NewReader("mine"), 4, "text/plain")
+		assertAppErrorCode(t, err, 403)
+		_, err = svc.RestoreVersion(context.Background(), file.ID, 2, 1)
+		assertAppErrorCode(t, err, 403)
+		_, err = svc.ListVersions(context.Background(), file.ID, 2)
+		assertAppErrorCode(t, err, 403)
+
+		if _, err := svc.SetVisibility(context.Background(), file.ID, 1, dto.FileVisibilityPublic); err != nil {
+			t.Fatalf("set visibility: %v", err)
+		}
+		if _, err := svc.ListVersions(context.Background(), file.ID, 2); err != nil {
+			t.Errorf("expected readers to list versions, got %v", err)
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Archive
+// ---------------------------------------------------------------------------
+
+func TestFileArchive(t *testing.T) {
+	newArchiveService := func() (UploadService, *mockFileRepo, *mockStorage) {
+		repo := newMockFileRepo()
+		store := newMockStorage()
+		for i, name := range []string{"report.pdf", "report.pdf", "../notes.txt"} {
+			id := int64(i + 1)
+			path := fmt.Sprintf("1/%d", id)
+			repo.files[id] = &sqlc.File{ID: id, UserID: 1, OriginalName: name, StoragePath: path}
+			store.files[path] = []byte(fmt.Sprintf("content %d", id))
+		}
+		repo.files[4] = &sqlc.File{ID: 4, UserID: 2, OriginalName: "theirs.txt", StoragePath: "2/4"}
+		return newTestUploadService(repo, store), repo, store
+	}
+
+	t.Run("streams the files into a zip", func(t *testing.T) {
+		svc, _, _ := newArchiveService()
+
+		files, err := svc.ArchiveFiles(context.Background(), 1, []int64{3, 1, 2, 1})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		var buf bytes.Buffer
+		if err := svc.WriteArchive(context.Background(), &buf, files); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatalf("invalid zip: %v", err)
+		}
+		want := map[string]string{"notes.txt": "content 3", "report.pdf": "content 1", "report (2).pdf": "content 2"}
+		if len(zr.File) != len(want) {
+			t.Fatalf("expected %d entries, got %d", len(want), len(zr.File))
+		}
+		for _, entry := range zr.File {
+			rc, err := entry.Open()
+			if err != nil {
+				t.Fatalf("open %s: %v", entry.Name, err)
+			}
+			data, _ := io.ReadAll(rc)
+			_ = rc.Close()
+			if string(data) != want[entry.Name] {
+				t.Errorf("entry %q: expected %q, got %q", entry.Name, want[entry.Name], data)
+			}
+		}
+	})
+
+	t.Run("only existing files of the user", func(t *testing.T) {
+		svc, repo, _ := newArchiveService()
+		repo.files[2].DeletedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+
+		_, err := svc.ArchiveFiles(context.Background(), 1, []int64{1, 4})
+		assertAppErrorCode(t, err, 403)
+		_, err = svc.ArchiveFiles(context.Background(), 1, []int64{1, 99})
+		assertAppErrorCode(t, err, 404)
+		_, err = svc.ArchiveFiles(context.Background(), 1, []int64{2})
+		assertAppErrorCode(t, err, 404)
+	})
+
+	t.Run("storage failure aborts the archive", func(t *testing.T) {
+		svc, _, store := newArchiveService()
+		files, err := svc.ArchiveFiles(context.Background(), 1, []int64{1})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		store.getErr = errors.New("unavailable")
+
+		if err := svc.WriteArchive(context.Background(), io.Discard, files); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Visibility and permissions
+// ---------------------------------------------------------------------------
+
+func TestFileAccess(t *testing.T) {
+	newFixture := func() (*mockFileRepo, UploadService) {
+		repo := newMockFileRepo()
+		store := newMockStorage()
+		repo.files[1] = &sqlc.File{
+			ID: 1, UserID: 10, OriginalName: "doc.pdf", Visibility: "private",
+			StoragePath: "10/abc.pdf", MimeType: "application/pdf", Size: 4,
+		}
+		store.files["10/abc.pdf"] = []byte("%PDF")
+		return repo, newTestUploadService(repo, store)
+	}
+
+	t.Run("granted user can read a private file", func(t *testing.T) {
+		repo, svc := newFixture()
+
+		if err := svc.GrantPermission(context.Background(), 1, 10, 20); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if _, err := svc.GetFileInfo(context.Background(), 1, 20); err != nil {
+			t.Errorf("expected granted user to read file info, got %v", err)
+		}
+		_, reader, err := svc.Download(context.Background(), 1, 20)
+		if err != nil {
+			t.Fatalf("expected granted user to download, got %v", err)
+		}
+		_ = reader.Close()
+		if repo.permissions[0].GrantedBy.Int64 != 10 {
+			t.Errorf("expected grant recorded by owner, got %+v", repo.permissions[0])
+		}
+
+		_, err = svc.GetFileInfo(context.Background(), 1, 30)
+		assertAppErrorCode(t, err, 403)
+	})
+
+	t.Run("revoked user loses access", func(t *testing.T) {
+		_, svc := newFixture()
+
+		if err := svc.GrantPermission(context.Background(), 1, 10, 20); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if err := svc.RevokePermission(context.Background(), 1, 10, 20); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		_, _, err := svc.Download(context.Background(), 1, 20)
+		assertAppErrorCode(t, err, 403)
+		assertAppErrorCode(t, svc.RevokePermission(context.Background(), 1, 10, 20), 404)
+	})
+
+	t.Run("stored objects are served to readers only", func(t *testing.T) {
+		repo := newMockFileRepo()
+		store := newMockStorage()
+		repo.files[1] = &sqlc.File{
+			ID: 1, UserID: 10, OriginalName: "doc.pdf", Visibility: "private",
+			StoragePath: "10/abc.pdf", MimeType: "application/pdf", Size: 4,
+		}
+		repo.variants = []sqlc.FileVariant{{FileID: 1, Name: "thumbnail", StoragePath: "10/abc_thumbnail.jpg", MimeType: "image/jpeg", Size: 3}}
+		store.files["10/abc.pdf"] = []byte("%PDF")
+		store.files["10/abc_thumbnail.jpg"] = []byte("jpg")
+		svc := newTestUploadService(repo, store)
+
+		obj, reader, err := svc.OpenStored(context.Background(), "10/abc.pdf", 10)
+		if err != nil {
+			t.Fatalf("expected the owner to read the object, got %v", err)
+		}
+		data, _ := io.ReadAll(reader)
+		_ = reader.Close()
+		if string(data) != "%PDF" || obj.MimeType != "application/pdf" || obj.Size != 4 {
+			t.Errorf("unexpected object %+v with content %q", obj, data)
+		}
+
+		// Unreadable and unknown objects look the same.
+		_, _, err = svc.OpenStored(context.Background(), "10/abc.pdf", 20)
+		assertAppErrorCode(t, err, 404)
+		_, _, err = svc.OpenStored(context.Background(), "10/missing.pdf", 10)
+		assertAppErrorCode(t, err, 404)
+
+		if err := svc.GrantPermission(context.Background(), 1, 10, 20); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		obj, _, err = svc.OpenStored(context.Background(), "10/abc_thumbnail.jpg", 20)
+		if err != nil || obj.MimeType != "image/jpeg" {
+			t.Errorf("expected the granted user to read the variant, got %+v (%v)", obj, err)
+		}
+
+		if err := svc.Delete(context.Background(), 1, 10); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		_, _, err = svc.OpenStored(context.Background(), "10/abc.pdf", 10)
+		assertAppErrorCode(t, err, 404)
+	})
+
+	t.Run("public file is readable by anyone", func(t *testing.T) {
+		_, svc := newFixture()
+
+		resp, err := svc.SetVisibility(context.Background(), 1, 10, "public")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if resp.Visibility != "public" {
+			t.Errorf("expected public, got %s", resp.Visibility)
+		}
+		if _, err := svc.GetFileInfo(context.Background(), 1, 30); err != nil {
+			t.Errorf("expected public file to be readable, got %v", err)
+		}
+	})
+
+	t.Run("only the owner manages access", func(t *testing.T) {
+		_, svc := newFixture()
+
+		if err := svc.GrantPermission(context.Background(), 1, 10, 20); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		_, err := svc.SetVisibility(context.Background(), 1, 20, "public")
+		assertAppErrorCode(t, err, 403)
+		assertAppErrorCode(t, svc.GrantPermission(context.Background(), 1, 20, 30), 403)
+		_, err = svc.ListPermissions(context.Background(), 1, 20)
+		assertAppErrorCode(t, err, 403)
+		assertAppErrorCode(t, svc.Delete(context.Background(), 1, 20), 403)
+	})
+
+	t.Run("owner cannot grant themselves", func(t *testing.T) {
+		_, svc := newFixture()
+
+		assertAppErrorCode(t, svc.GrantPermission(context.Background(), 1, 10, 10), 400)
+	})
+
+	t.Run("list permissions", func(t *testing.T) {
+		_, svc := newFixture()
+
+		for _, id := range []int64{20, 30, 20} {
+			if err := svc.GrantPermission(context.Background(), 1, 10, id); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+		perms, err := svc.ListPermissions(context.Background(), 1, 10)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(perms) != 2 || perms[0].UserID != 20 || perms[1].UserID != 30 {
+			t.Errorf("expected grants for users 20 and 30, got %+v", perms)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/pagination"
+)
+
+// Activity describes a change to a user's account to be recorded in their activity trail.
+// ActorID is the user who made the change, which differs from UserID for admin actions.
+type Activity struct {
+	UserID    int64
+	ActorID   int64
+	Action    string
+	Details   map[string]any
+	IPAddress string
+}
+
+type UserActivityService interface {
+	Record(ctx context.Context, activity Activity) error
+	ListByUser(ctx context.Context, userID int64, page, perPage int) ([]dto.UserActivityResponse, int64, error)
+}
+
+type userActivityService struct {
+	repo repository.UserActivityRepository
+}
+
+func NewUserActivityService(repo repository.UserActivityRepository) UserActivityService {
+	return &userActivityService{repo: repo}
+}
+
+func (s *userActivityService) Record(ctx context.Context, activity Activity) error {
+	details := []byte("{}")
+	if len(activity.Details) > 0 {
+		var err error
+		if details, err = json.Marshal(activity.Details); err != nil {
+			return fmt.Errorf("encode activity details: %w", err)
+		}
+	}
+
+	_, err := s.repo.Create(ctx, sqlc.CreateUserActivityParams{
+		UserID:    activity.UserID,
+		ActorID:   pgtype.Int8{Int64: activity.ActorID, Valid: activity.ActorID != 0},
+		Action:    activity.Action,
+		Details:   details,
+		IpAddress: activity.IPAddress,
+	})
+	if err != nil {
+		return fmt.Errorf("create user activity: %w", err)
+	}
+	return nil
+}
+
+func (s *userActivityService) ListByUser(ctx context.Context, userID int64, page, perPage int) ([]dto.UserActivityResponse, int64, error) {
+	limit, offset := pagination.LimitOffset(page, perPage)
+
+	activities, err := s.repo.ListByUserID(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, 0, apperror.NewInternal("failed to list activity")
+	}
+
+	total, err := s.repo.CountByUserID(ctx, userID)
+	if err != nil {
+		return nil, 0, apperror.NewInternal("failed to count activity")
+	}
+
+	result := make([]dto.UserActivityResponse, len(activities))
+	for i := range activities {
+		result[i] = toUserActivityResponse(&activities[i])
+	}
+	return result, total, nil
+}
+
+func toUserActivityResponse(a *sqlc.UserActivity) dto.UserActivityResponse {
+	resp := dto.UserActivityResponse{
+		ID:        a.ID,
+		Action:    a.Action,
+		IPAddress: a.IpAddress,
+		CreatedAt: a.CreatedAt.Time,
+	}
+	if a.ActorID.Valid {
+		resp.ActorID = &a.ActorID.Int64
+	}
+	// A malformed document is left out of the response rather than failing the request.
+	if details, _ := repository.DecodeMetadata(a.Details); len(details) > 0 {
+		resp.Details = details
+	}
+	return resp
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+)
+
+func TestUserActivityService(t *testing.T) {
+	t.Run("records and lists newest first", func(t *testing.T) {
+		repo := newMockUserActivityRepo()
+		svc := NewUserActivityService(repo)
+		ctx := context.Background()
+
+		_ = svc.Record(ctx, Activity{UserID: 2, ActorID: 2, Action: dto.ActivityPasswordChanged, IPAddress: "203.0.113.7"})
+		_ = svc.Record(ctx, Activity{UserID: 2, ActorID: 1, Action: dto.ActivityRoleChanged, Details: map[string]any{"role": "admin"}})
+		_ = svc.Record(ctx, Activity{UserID: 3, ActorID: 3, Action: dto.ActivityProfileUpdated})
+
+		activities, total, err := svc.ListByUser(ctx, 2, 1, 20)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if total != 2 || len(activities) != 2 {
+			t.Fatalf("expected 2 activities, got %d (total %d)", len(activities), total)
+		}
+
+		latest := activities[0]
+		if latest.Action != dto.ActivityRoleChanged {
+			t.Errorf("expected newest activity first, got %q", latest.Action)
+		}
+		if latest.ActorID == nil || *latest.ActorID != 1 {
+			t.Errorf("expected actor 1, got %v", latest.ActorID)
+		}
+		if latest.Details["role"] != "admin" {
+			t.Errorf("expected role detail, got %v", latest.Details)
+		}
+		if activities[1].Details != nil {
+			t.Errorf("expected no details, got %v", activities[1].Details)
+		}
+	})
+
+	t.Run("system actions have no actor", func(t *testing.T) {
+		repo := newMockUserActivityRepo()
+		svc := NewUserActivityService(repo)
+
+		_ = svc.Record(context.Background(), Activity{UserID: 2, Action: dto.ActivityProfileUpdated})
+		if repo.activities[0].ActorID.Valid {
+			t.Error("expected null actor_id")
+		}
+	})
+}
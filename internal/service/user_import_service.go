@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/async"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/database"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/email"
+)
+
+// importResetTTL is how long the set-password link emailed to an imported user stays
+// valid. It is longer than a regular reset link since the user did not ask for it.
+const importResetTTL = 72 * time.Hour
+
+// UserImportService creates accounts in bulk for teams migrating from another system.
+type UserImportService interface {
+	Import(ctx context.Context, rows []dto.UserImportRow) (*dto.UserImportResponse, error)
+}
+
+type userImportService struct {
+	userRepo    repository.UserRepository
+	resetRepo   repository.PasswordResetRepository
+	sender      email.Sender
+	cache       cache.Cache
+	frontendURL string
+	txManager   *database.TxManager
+	auditLog    AuditLogService
+	run         func(fn func()) // sends welcome emails; async.Go outside tests
+}
+
+func NewUserImportService(
+	userRepo repository.UserRepository,
+	resetRepo repository.PasswordResetRepository,
+	sender email.Sender,
+	appCache cache.Cache,
+	frontendURL string,
+	txManager *database.TxManager,
+	auditLog AuditLogService,
+) UserImportService {
+	return &userImportService{
+		userRepo:    userRepo,
+		resetRepo:   resetRepo,
+		sender:      sender,
+		cache:       appCache,
+		frontendURL: frontendURL,
+		txManager:   txManager,
+		auditLog:    auditLog,
+		run:         async.Go,
+	}
+}
+
+// importedUser is an account created by an import, waiting for its set-password email.
+type importedUser struct {
+	email string
+	token string
+}
+
+// Import creates an account without a password for each row and emails the user a link
+// to set one, so nobody can sign in to an imported account until its owner has reset
+// the password. Each row succeeds or fails independently and is reported by line.
+func (s *userImportService) Import(ctx context.Context, rows []dto.UserImportRow) (*dto.UserImportResponse, error) {
+	resp := &dto.UserImportResponse{Results: make([]dto.UserImportResult, 0, len(rows))}
+	seen := make(map[string]struct{}, len(rows))
+	var created []importedUser
+
+	for _, row := range rows {
+		result := dto.UserImportResult{Line: row.Line, Email: row.Email}
+
+		user, token, err := s.importRow(ctx, row, seen)
+		if err != nil {
+			result.Error = bulkErrorMessage(err)
+			resp.Failed++
+		} else {
+			seen[strings.ToLower(row.Email)] = struct{}{}
+			result.UserID = user.ID
+			result.Success = true
+			resp.Succeeded++
+			created = append(created, importedUser{email: user.Email, token: token})
+		}
+		resp.Results = append(resp.Results, result)
+	}
+
+	if len(created) > 0 {
+		forgetStats(ctx, s.cache)
+		s.run(func() {
+			s.sendWelcomeEmails(context.WithoutCancel(ctx), created)
+		})
+	}
+	return resp, nil
+}
+
+// importRow validates one row and creates its user together with a password reset token.
+func (s *userImportService) importRow(
+	ctx context.Context,
+	row dto.UserImportRow,
+	seen map[string]struct{},
+) (*sqlc.User, string, error) {
+	role, err := checkImportRow(row)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, dup := seen[strings.ToLower(row.Email)]; dup {
+		return nil, "", apperror.NewBadRequest("duplicate email in file")
+	}
+
+	existing, err := s.userRepo.GetByEmail(ctx, row.Email)
+	if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		return nil, "", apperror.NewInternal("failed to check existing user")
+	}
+	if existing != nil {
+		return nil, "", apperror.NewBadRequest("email already registered")
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", apperror.NewInternal("failed to generate reset token")
+	}
+	token := hex.EncodeToString(b)
+
+	var user *sqlc.User
+	create := func(userRepo repository.UserRepository, resetRepo repository.PasswordResetRepository) error {
+		var err error
+		user, err = userRepo.Create(ctx, sqlc.CreateUserParams{Email: row.Email, Name: row.Name})
+		if err != nil {
+			if repository.IsUniqueViolation(err) {
+				return apperror.NewBadRequest("email already registered")
+			}
+			return apperror.NewInternal("failed to create user")
+		}
+		if role != dto.RoleUser {
+			if user, err = userRepo.UpdateRole(ctx, sqlc.UpdateUserRoleParams{Role: role, ID: user.ID}); err != nil {
+				return apperror.NewInternal("failed to set role")
+			}
+		}
+		_, err = resetRepo.Create(ctx, sqlc.CreatePasswordResetTokenParams{
+			UserID:    user.ID,
+			Token:     token,
+			ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(importResetTTL), Valid: true},
+		})
+		if err != nil {
+			return apperror.NewInternal("failed to create reset token")
+		}
+		return nil
+	}
+
+	if s.txManager != nil {
+		err = s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+			return create(repository.NewUserRepository(tx), repository.NewPasswordResetRepository(tx))
+		})
+	} else {
+		err = create(s.userRepo, s.resetRepo)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	forgetUser(ctx, s.cache, user.ID)
+	recordAudit(ctx, s.auditLog, AuditEntry{
+		Action: dto.AuditUserImported, TargetType: dto.AuditTargetUser, TargetID: user.ID,
+		After: map[string]any{"email": user.Email, "role": user.Role},
+	})
+	return user, token, nil
+}
+
+// checkImportRow validates a row and returns the role to assign, defaulting to user.
+func checkImportRow(row dto.UserImportRow) (string, error) {
+	if addr, err := mail.ParseAddress(row.Email); err != nil || addr.Address != row.Email || len(row.Email) > 255 {
+		return "", apperror.NewBadRequest("invalid email address")
+	}
+	if n := len([]rune(strings.TrimSpace(row.Name))); n < 2 || n > 255 {
+		return "", apperror.NewBadRequest("name must be between 2 and 255 characters")
+	}
+	switch row.Role {
+	case "":
+		return dto.RoleUser, nil
+	case dto.RoleUser, dto.RoleAdmin:
+		return row.Role, nil
+	default:
+		return "", apperror.NewBadRequest("role must be one of: user admin")
+	}
+}
+
+func (s *userImportService) sendWelcomeEmails(ctx context.Context, users []importedUser) {
+	for _, u := range users {
+		setURL := fmt.Sprintf("%s/reset-password?token=%s", s.frontendURL, u.token)
+		if err := s.sender.Send(ctx, email.Message{
+			To:      []string{u.email},
+			Subject: "Your account is ready",
+			HTML: fmt.Sprintf("<p>An account has been created for you. Click <a href=%q>here</a> to choose a password. This link expires in %d hours.</p>",
+				setURL, int(importResetTTL.Hours())),
+		}); err != nil {
+			slog.Error("failed to send import welcome email", slog.Any("error", err))
+		}
+	}
+}
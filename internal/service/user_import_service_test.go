@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+)
+
+func TestImportUsers(t *testing.T) {
+	users := newMockUserRepo()
+	users.users[1] = &sqlc.User{ID: 1, Email: "taken@example.com", Name: "Taken", Role: dto.RoleUser}
+	users.nextID = 2
+	resets := newMockPasswordResetRepo()
+	sender := newMockEmailSender()
+
+	audit := newMockAuditLogRepo()
+
+	svc := NewUserImportService(users, resets, sender, newMockCache(), "http://frontend", nil, NewAuditLogService(audit)).(*userImportService)
+	var pending []func()
+	svc.run = func(fn func()) { pending = append(pending, fn) }
+
+	resp, err := svc.Import(context.Background(), []dto.UserImportRow{
+		{Line: 2, Email: "ann@example.com", Name: "Ann"},
+		{Line: 3, Email: "bob@example.com", Name: "Bob", Role: dto.RoleAdmin},
+		{Line: 4, Email: "not-an-email", Name: "Nobody"},
+		{Line: 5, Email: "taken@example.com", Name: "Taken Again"},
+		{Line: 6, Email: "ANN@example.com", Name: "Ann Again"},
+		{Line: 7, Email: "eve@example.com", Name: "Eve", Role: "owner"},
+		{Line: 8, Email: "x@example.com", Name: "X"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if resp.Succeeded != 2 || resp.Failed != 5 {
+		t.Fatalf("expected 2 succeeded and 5 failed, got %d/%d", resp.Succeeded, resp.Failed)
+	}
+
+	wantErrors := map[int]string{
+		4: "invalid email address",
+		5: "email already registered",
+		6: "duplicate email in file",
+		7: "role must be one of",
+		8: "name must be",
+	}
+	for _, r := range resp.Results {
+		want, failed := wantErrors[r.Line]
+		if r.Success == failed {
+			t.Errorf("line %d: unexpected success=%v (%q)", r.Line, r.Success, r.Error)
+			continue
+		}
+		if failed && !strings.Contains(r.Error, want) {
+			t.Errorf("line %d: expected error containing %q, got %q", r.Line, want, r.Error)
+		}
+	}
+
+	bob := users.users[resp.Results[1].UserID]
+	if bob.Role != dto.RoleAdmin {
+		t.Errorf("expected bob to be admin, got %q", bob.Role)
+	}
+	if got := audit.actions(); len(got) != 2 || got[0] != dto.AuditUserImported || audit.entries[1].TargetID != bob.ID {
+		t.Errorf("expected a user.imported entry per created user, got %+v", audit.entries)
+	}
+	if bob.PasswordHash.Valid {
+		t.Error("expected imported user to have no password")
+	}
+	if len(resets.tokens) != 2 {
+		t.Errorf("expected 2 reset tokens, got %d", len(resets.tokens))
+	}
+
+	if sender.sent != 0 {
+		t.Fatal("expected emails to be sent in the background")
+	}
+	for _, fn := range pending {
+		fn()
+	}
+	if sender.sent != 2 {
+		t.Errorf("expected 2 emails, got %d", sender.sent)
+	}
+	if !strings.Contains(sender.last.HTML, "http://frontend/reset-password?token=") {
+		t.Errorf("expected set-password link, got %q", sender.last.HTML)
+	}
+}
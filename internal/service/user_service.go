@@ -2,9 +2,12 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -18,6 +21,8 @@ import (
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/database"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/pagination"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/telemetry"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/token"
 )
 
 const (
@@ -25,44 +30,78 @@ const (
 	maxLoginAttempts   = 5
 	lockoutDuration    = 15 * time.Minute
 	loginAttemptPrefix = "login_attempts:"
+	userCachePrefix    = "user_profile:"
+	// Changes made outside the services that invalidate the cached profile, such as an
+	// email change, show in GetByID after at most this long.
+	userCacheTTL = 30 * time.Second
 )
 
 type UserService interface {
 	Register(ctx context.Context, req dto.RegisterRequest) (*dto.UserResponse, error)
 	Authenticate(ctx context.Context, req dto.LoginRequest) (*sqlc.User, error)
 	FindOrCreateByGoogle(ctx context.Context, googleID, email, name string) (*sqlc.User, error)
+	FindOrCreateByGitHub(ctx context.Context, githubID, email, name string) (*sqlc.User, error)
+	FindOrCreateBySAML(ctx context.Context, samlID, email, name string) (*sqlc.User, error)
 	GetByID(ctx context.Context, id int64) (*dto.UserResponse, error)
-	List(ctx context.Context, page, perPage int) ([]dto.UserResponse, int64, error)
+	GetByUsername(ctx context.Context, username string) (*dto.PublicUserResponse, error)
+	List(ctx context.Context, query dto.UserFilterQuery, page, perPage int) ([]dto.UserResponse, int64, error)
 	Update(ctx context.Context, id int64, req dto.UpdateUserRequest) (*dto.UserResponse, error)
 	Delete(ctx context.Context, id int64) error
 	ChangePassword(ctx context.Context, userID int64, req dto.ChangePasswordRequest) error
 }
 
 type userService struct {
-	repo                     repository.UserRepository
-	refreshTokenRepo         repository.RefreshTokenRepository
-	requireEmailVerification bool
-	cache                    cache.Cache
-	txManager                *database.TxManager
+	repo             repository.UserRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	settingsRepo     repository.UserSettingsRepository
+	settings         RuntimeSettings
+	invites          inviteGate
+	domains          EmailDomainPolicy
+	revocations      *token.RevocationStore
+	cache            cache.Cache
+	txManager        *database.TxManager
 }
 
 func NewUserService(
 	repo repository.UserRepository,
 	refreshTokenRepo repository.RefreshTokenRepository,
-	requireEmailVerification bool,
+	invitationRepo repository.RegistrationInvitationRepository,
+	settingsRepo repository.UserSettingsRepository,
+	settings RuntimeSettings,
+	inviteOnly bool,
+	domains EmailDomainPolicy,
+	revocations *token.RevocationStore,
 	appCache cache.Cache,
 	txManager *database.TxManager,
 ) UserService {
 	return &userService{
-		repo:                     repo,
-		refreshTokenRepo:         refreshTokenRepo,
-		requireEmailVerification: requireEmailVerification,
-		cache:                    appCache,
-		txManager:                txManager,
+		repo:             repo,
+		refreshTokenRepo: refreshTokenRepo,
+		settingsRepo:     settingsRepo,
+		settings:         settings,
+		invites:          inviteGate{repo: invitationRepo, required: inviteOnly},
+		domains:          domains,
+		revocations:      revocations,
+		cache:            appCache,
+		txManager:        txManager,
 	}
 }
 
 func (s *userService) Register(ctx context.Context, req dto.RegisterRequest) (*dto.UserResponse, error) {
+	ctx, span := telemetry.Start(ctx, "UserService.Register")
+	defer span.End()
+
+	if err := checkRegistrationOpen(ctx, s.settings); err != nil {
+		return nil, err
+	}
+	if err := s.domains.checkSignup(req.Email); err != nil {
+		return nil, err
+	}
+	invitation, err := s.invites.check(ctx, req.InviteToken, req.Email)
+	if err != nil {
+		return nil, err
+	}
+
 	existing, err := s.repo.GetByEmail(ctx, req.Email)
 	if err != nil && !errors.Is(err, apperror.ErrNotFound) {
 		return nil, apperror.NewInternal("failed to check existing user")
@@ -71,6 +110,11 @@ func (s *userService) Register(ctx context.Context, req dto.RegisterRequest) (*d
 		return nil, apperror.NewBadRequest("email already registered")
 	}
 
+	username, err := claimUsername(ctx, s.repo, req.Username, 0)
+	if err != nil {
+		return nil, err
+	}
+
 	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcryptCost)
 	if err != nil {
 		return nil, apperror.NewInternal("failed to hash password")
@@ -80,15 +124,21 @@ func (s *userService) Register(ctx context.Context, req dto.RegisterRequest) (*d
 		Email:        req.Email,
 		PasswordHash: pgtype.Text{String: string(hash), Valid: true},
 		Name:         req.Name,
+		Username:     username,
 	})
 	if err != nil {
 		return nil, apperror.NewInternal("failed to create user")
 	}
+	s.invites.consume(ctx, invitation)
+	rememberSignupLocale(ctx, s.settingsRepo, user.ID, req.Locale)
 
 	return ToUserResponse(user), nil
 }
 
 func (s *userService) Authenticate(ctx context.Context, req dto.LoginRequest) (*sqlc.User, error) {
+	ctx, span := telemetry.Start(ctx, "UserService.Authenticate")
+	defer span.End()
+
 	// Check lockout
 	cacheKey := loginAttemptPrefix + req.Email
 	if data, _ := s.cache.Get(ctx, cacheKey); data != nil {
@@ -116,7 +166,11 @@ func (s *userService) Authenticate(ctx context.Context, req dto.LoginRequest) (*
 		return nil, apperror.NewUnauthorized("invalid email or password")
 	}
 
-	if s.requireEmailVerification && !user.EmailVerifiedAt.Valid {
+	// Only a correct password reveals the ban, so it cannot be used to probe for accounts.
+	if err := checkNotBanned(user); err != nil {
+		return nil, err
+	}
+	if s.settings.Current(ctx).RequireEmailVerification && !user.EmailVerifiedAt.Valid {
 		return nil, apperror.NewForbidden("email not verified")
 	}
 
@@ -125,33 +179,101 @@ func (s *userService) Authenticate(ctx context.Context, req dto.LoginRequest) (*
 	return user, nil
 }
 
+// incrementLoginAttempts counts a failed login atomically, so concurrent failures for the
+// same email are all counted. The lockout lasts lockoutDuration from the last failure.
 func (s *userService) incrementLoginAttempts(ctx context.Context, key string) {
-	attempts := 1
-	if data, _ := s.cache.Get(ctx, key); data != nil {
-		attempts, _ = strconv.Atoi(string(data))
-		attempts++
-	}
-	_ = s.cache.Set(ctx, key, []byte(strconv.Itoa(attempts)), lockoutDuration)
+	_, _ = s.cache.Increment(ctx, key, 1, lockoutDuration)
+}
+
+// oauthAccount describes how users are looked up, linked, and created for one OAuth provider.
+type oauthAccount struct {
+	provider        string
+	getByProviderID func(repo repository.UserRepository) (*sqlc.User, error)
+	link            func(repo repository.UserRepository, userID int64) (*sqlc.User, error)
+	create          sqlc.CreateOAuthUserParams
 }
 
 func (s *userService) FindOrCreateByGoogle(ctx context.Context, googleID, email, name string) (*sqlc.User, error) {
+	providerID := pgtype.Text{String: googleID, Valid: true}
+	return s.findOrCreateOAuthUser(ctx, oauthAccount{
+		provider: "google",
+		getByProviderID: func(repo repository.UserRepository) (*sqlc.User, error) {
+			return repo.GetByGoogleID(ctx, googleID)
+		},
+		link: func(repo repository.UserRepository, userID int64) (*sqlc.User, error) {
+			return repo.LinkGoogleAccount(ctx, sqlc.LinkGoogleAccountParams{GoogleID: providerID, ID: userID})
+		},
+		create: sqlc.CreateOAuthUserParams{
+			Email:        email,
+			Name:         name,
+			GoogleID:     providerID,
+			AuthProvider: "google",
+		},
+	})
+}
+
+func (s *userService) FindOrCreateByGitHub(ctx context.Context, githubID, email, name string) (*sqlc.User, error) {
+	providerID := pgtype.Text{String: githubID, Valid: true}
+	return s.findOrCreateOAuthUser(ctx, oauthAccount{
+		provider: "github",
+		getByProviderID: func(repo repository.UserRepository) (*sqlc.User, error) {
+			return repo.GetByGitHubID(ctx, githubID)
+		},
+		link: func(repo repository.UserRepository, userID int64) (*sqlc.User, error) {
+			return repo.LinkGitHubAccount(ctx, sqlc.LinkGitHubAccountParams{GithubID: providerID, ID: userID})
+		},
+		create: sqlc.CreateOAuthUserParams{
+			Email:        email,
+			Name:         name,
+			GithubID:     providerID,
+			AuthProvider: "github",
+		},
+	})
+}
+
+func (s *userService) FindOrCreateBySAML(ctx context.Context, samlID, email, name string) (*sqlc.User, error) {
+	providerID := pgtype.Text{String: samlID, Valid: true}
+	return s.findOrCreateOAuthUser(ctx, oauthAccount{
+		provider: "saml",
+		getByProviderID: func(repo repository.UserRepository) (*sqlc.User, error) {
+			return repo.GetBySAMLID(ctx, samlID)
+		},
+		link: func(repo repository.UserRepository, userID int64) (*sqlc.User, error) {
+			return repo.LinkSAMLAccount(ctx, sqlc.LinkSAMLAccountParams{SamlID: providerID, ID: userID})
+		},
+		create: sqlc.CreateOAuthUserParams{
+			Email:        email,
+			Name:         name,
+			SamlID:       providerID,
+			AuthProvider: "saml",
+		},
+	})
+}
+
+// findOrCreateOAuthUser returns the user linked to the provider account, linking an
+// existing account with the same email or creating a new user when none is found.
+func (s *userService) findOrCreateOAuthUser(ctx context.Context, acct oauthAccount) (*sqlc.User, error) {
+	var invitation *sqlc.RegistrationInvitation
 	findOrCreate := func(repo repository.UserRepository) (*sqlc.User, error) {
-		user, err := repo.GetByGoogleID(ctx, googleID)
+		user, err := acct.getByProviderID(repo)
 		if err == nil {
+			if err := checkNotBanned(user); err != nil {
+				return nil, err
+			}
 			return user, nil
 		}
 		if !errors.Is(err, apperror.ErrNotFound) {
-			return nil, apperror.NewInternal("failed to find user by google id")
+			return nil, apperror.NewInternal(fmt.Sprintf("failed to find user by %s id", acct.provider))
 		}
 
-		existing, err := repo.GetByEmail(ctx, email)
+		existing, err := repo.GetByEmail(ctx, acct.create.Email)
 		if err == nil {
-			linked, linkErr := repo.LinkGoogleAccount(ctx, sqlc.LinkGoogleAccountParams{
-				GoogleID: pgtype.Text{String: googleID, Valid: true},
-				ID:       existing.ID,
-			})
+			if err := checkNotBanned(existing); err != nil {
+				return nil, err
+			}
+			linked, linkErr := acct.link(repo, existing.ID)
 			if linkErr != nil {
-				return nil, apperror.NewInternal("failed to link google account")
+				return nil, apperror.NewInternal(fmt.Sprintf("failed to link %s account", acct.provider))
 			}
 			return linked, nil
 		}
@@ -159,12 +281,18 @@ func (s *userService) FindOrCreateByGoogle(ctx context.Context, googleID, email,
 			return nil, apperror.NewInternal("failed to find user by email")
 		}
 
-		newUser, err := repo.CreateOAuthUser(ctx, sqlc.CreateOAuthUserParams{
-			Email:        email,
-			Name:         name,
-			GoogleID:     pgtype.Text{String: googleID, Valid: true},
-			AuthProvider: "google",
-		})
+		// Existing accounts keep signing in; signup policies only gate new ones.
+		if err := checkRegistrationOpen(ctx, s.settings); err != nil {
+			return nil, err
+		}
+		if err := s.domains.checkSignup(acct.create.Email); err != nil {
+			return nil, err
+		}
+		if invitation, err = s.invites.checkEmail(ctx, acct.create.Email); err != nil {
+			return nil, err
+		}
+
+		newUser, err := repo.CreateOAuthUser(ctx, acct.create)
 		if err != nil {
 			return nil, err
 		}
@@ -180,30 +308,51 @@ func (s *userService) FindOrCreateByGoogle(ctx context.Context, googleID, email,
 			return err
 		})
 		if txErr != nil {
+			var appErr *apperror.AppError
+			if errors.As(txErr, &appErr) {
+				return nil, appErr
+			}
 			if repository.IsUniqueViolation(txErr) {
-				if user, err := s.repo.GetByGoogleID(ctx, googleID); err == nil {
+				if user, err := acct.getByProviderID(s.repo); err == nil {
 					return user, nil
 				}
 			}
 			return nil, apperror.NewInternal("failed to create oauth user")
 		}
+		s.invites.consume(ctx, invitation)
 		return result, nil
 	}
 
 	result, err := findOrCreate(s.repo)
 	if err != nil {
+		var appErr *apperror.AppError
+		if errors.As(err, &appErr) {
+			return nil, appErr
+		}
 		if repository.IsUniqueViolation(err) {
-			if user, retryErr := s.repo.GetByGoogleID(ctx, googleID); retryErr == nil {
+			if user, retryErr := acct.getByProviderID(s.repo); retryErr == nil {
 				return user, nil
 			}
 		}
 		return nil, apperror.NewInternal("failed to create oauth user")
 	}
+	s.invites.consume(ctx, invitation)
 	return result, nil
 }
 
 func (s *userService) GetByID(ctx context.Context, id int64) (*dto.UserResponse, error) {
-	user, err := s.repo.GetByID(ctx, id)
+	ctx, span := telemetry.Start(ctx, "UserService.GetByID")
+	defer span.End()
+
+	// Every authenticated request for the user's own profile lands here, so the lookup is
+	// cached and concurrent misses share one query.
+	data, err := s.cache.GetOrSet(ctx, userCacheKey(id), userCacheTTL, func(ctx context.Context) ([]byte, error) {
+		user, err := s.repo.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(ToUserResponse(user))
+	}, cache.UserTag(id))
 	if err != nil {
 		if errors.Is(err, apperror.ErrNotFound) {
 			return nil, apperror.NewNotFound("user not found")
@@ -211,19 +360,49 @@ func (s *userService) GetByID(ctx context.Context, id int64) (*dto.UserResponse,
 		return nil, apperror.NewInternal("failed to get user")
 	}
 
-	return ToUserResponse(user), nil
+	var resp dto.UserResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, apperror.NewInternal("failed to decode user")
+	}
+	return &resp, nil
 }
 
-func (s *userService) List(ctx context.Context, page, perPage int) ([]dto.UserResponse, int64, error) {
+// GetByUsername looks up an active user by handle, case-insensitively, and returns their
+// public profile.
+func (s *userService) GetByUsername(ctx context.Context, username string) (*dto.PublicUserResponse, error) {
+	user, err := s.repo.GetByUsername(ctx, strings.ToLower(username))
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("user not found")
+		}
+		return nil, apperror.NewInternal("failed to get user")
+	}
+
+	return &dto.PublicUserResponse{
+		ID:        user.ID,
+		Username:  user.Username.String,
+		Name:      user.Name,
+		CreatedAt: user.CreatedAt.Time,
+	}, nil
+}
+
+func (s *userService) List(ctx context.Context, query dto.UserFilterQuery, page, perPage int) ([]dto.UserResponse, int64, error) {
+	ctx, span := telemetry.Start(ctx, "UserService.List")
+	defer span.End()
+
+	filter, err := userFilter(query, false)
+	if err != nil {
+		return nil, 0, err
+	}
 	limit, offset := pagination.LimitOffset(page, perPage)
 
 	// Note: List and Count are separate queries; minor pagination inconsistency is acceptable for read-only operations.
-	users, err := s.repo.List(ctx, limit, offset)
+	users, err := s.repo.List(ctx, filter, limit, offset)
 	if err != nil {
 		return nil, 0, apperror.NewInternal("failed to list users")
 	}
 
-	total, err := s.repo.Count(ctx)
+	total, err := s.repo.Count(ctx, filter)
 	if err != nil {
 		return nil, 0, apperror.NewInternal("failed to count users")
 	}
@@ -237,6 +416,9 @@ func (s *userService) List(ctx context.Context, page, perPage int) ([]dto.UserRe
 }
 
 func (s *userService) Update(ctx context.Context, id int64, req dto.UpdateUserRequest) (*dto.UserResponse, error) {
+	ctx, span := telemetry.Start(ctx, "UserService.Update")
+	defer span.End()
+
 	existing, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, apperror.ErrNotFound) {
@@ -247,6 +429,7 @@ func (s *userService) Update(ctx context.Context, id int64, req dto.UpdateUserRe
 
 	name := existing.Name
 	email := existing.Email
+	username := existing.Username
 
 	if req.Name != nil {
 		name = *req.Name
@@ -261,22 +444,79 @@ func (s *userService) Update(ctx context.Context, id int64, req dto.UpdateUserRe
 		}
 		email = *req.Email
 	}
+	if req.Username != nil {
+		if username, err = claimUsername(ctx, s.repo, *req.Username, id); err != nil {
+			return nil, err
+		}
+	}
 
-	user, err := s.repo.Update(ctx, sqlc.UpdateUserParams{
-		ID:    id,
-		Name:  name,
-		Email: email,
-	})
+	var user *sqlc.User
+	doUpdate := func(userRepo repository.UserRepository) error {
+		user, err = userRepo.Update(ctx, sqlc.UpdateUserParams{
+			ID:       id,
+			Name:     name,
+			Email:    email,
+			Username: username,
+		})
+		if err != nil {
+			if repository.IsUniqueViolation(err) {
+				return apperror.NewBadRequest("email or username already in use")
+			}
+			return apperror.NewInternal("failed to update user")
+		}
+		if len(req.Metadata) == 0 {
+			return nil
+		}
+
+		set := make(map[string]any, len(req.Metadata))
+		var remove []string
+		for k, v := range req.Metadata {
+			if v == nil {
+				remove = append(remove, k)
+				continue
+			}
+			set[k] = v
+		}
+		user, err = userRepo.MergeMetadata(ctx, id, set, remove)
+		if err != nil {
+			return apperror.NewInternal("failed to update user metadata")
+		}
+		return nil
+	}
+
+	if s.txManager != nil && len(req.Metadata) > 0 {
+		err = s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+			return doUpdate(repository.NewUserRepository(tx))
+		})
+	} else {
+		err = doUpdate(s.repo)
+	}
 	if err != nil {
-		return nil, apperror.NewInternal("failed to update user")
+		return nil, err
 	}
+	forgetUser(ctx, s.cache, id)
 
 	return ToUserResponse(user), nil
 }
 
+// Delete soft-deletes a user, deletes their refresh tokens and revokes their access
+// tokens. The last active admin cannot be deleted, by anyone including themselves.
 func (s *userService) Delete(ctx context.Context, id int64) error {
+	defer forgetUser(ctx, s.cache, id)
+
 	doDelete := func(userRepo repository.UserRepository, refreshRepo repository.RefreshTokenRepository) error {
-		_, err := userRepo.Delete(ctx, id)
+		user, err := userRepo.GetByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				return apperror.NewNotFound("user not found")
+			}
+			return apperror.NewInternal("failed to get user")
+		}
+		if err := checkAdminRemoval(ctx, userRepo, user); err != nil {
+			return err
+		}
+
+		_, err = userRepo.Delete(ctx, id)
 		if err != nil {
 			if errors.Is(err, apperror.ErrNotFound) {
 				return apperror.NewNotFound("user not found")
@@ -288,16 +528,29 @@ func (s *userService) Delete(ctx context.Context, id int64) error {
 		return nil
 	}
 
+	var err error
 	if s.txManager != nil {
-		return s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		err = s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
 			return doDelete(repository.NewUserRepository(tx), repository.NewRefreshTokenRepository(tx))
 		})
+	} else {
+		err = doDelete(s.repo, s.refreshTokenRepo)
+	}
+	if err != nil {
+		return err
 	}
 
-	return doDelete(s.repo, s.refreshTokenRepo)
+	// JWTAuth does not look the user up, so access tokens would outlive the account
+	if err := s.revocations.RevokeUser(ctx, id); err != nil {
+		slog.Error("failed to revoke access tokens", slog.Int64("user_id", id), slog.Any("error", err))
+	}
+	return nil
 }
 
 func (s *userService) ChangePassword(ctx context.Context, userID int64, req dto.ChangePasswordRequest) error {
+	ctx, span := telemetry.Start(ctx, "UserService.ChangePassword")
+	defer span.End()
+
 	user, err := s.repo.GetByID(ctx, userID)
 	if err != nil {
 		if errors.Is(err, apperror.ErrNotFound) {
@@ -319,25 +572,133 @@ func (s *userService) ChangePassword(ctx context.Context, userID int64, req dto.
 		return apperror.NewInternal("failed to hash password")
 	}
 
-	_, err = s.repo.UpdatePassword(ctx, sqlc.UpdateUserPasswordParams{
-		PasswordHash: pgtype.Text{String: string(hash), Valid: true},
-		ID:           userID,
-	})
+	doChange := func(userRepo repository.UserRepository, refreshRepo repository.RefreshTokenRepository) error {
+		_, err := userRepo.UpdatePassword(ctx, sqlc.UpdateUserPasswordParams{
+			PasswordHash: pgtype.Text{String: string(hash), Valid: true},
+			ID:           userID,
+		})
+		if err != nil {
+			return apperror.NewInternal("failed to update password")
+		}
+		if err := refreshRepo.DeleteByUserID(ctx, userID); err != nil {
+			return apperror.NewInternal("failed to revoke refresh tokens")
+		}
+		return nil
+	}
+
+	if s.txManager != nil {
+		err = s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+			return doChange(repository.NewUserRepository(tx), repository.NewRefreshTokenRepository(tx))
+		})
+	} else {
+		err = doChange(s.repo, s.refreshTokenRepo)
+	}
 	if err != nil {
-		return apperror.NewInternal("failed to update password")
+		return err
 	}
+	forgetUser(ctx, s.cache, userID)
 
+	// Invalidate access tokens issued before the change, as after a password reset
+	if err := s.revocations.RevokeUser(ctx, userID); err != nil {
+		slog.Error("failed to revoke access tokens", slog.Int64("user_id", userID), slog.Any("error", err))
+	}
 	return nil
 }
 
+// claimUsername normalizes a requested username to lowercase and checks that no other
+// user holds it. An empty username yields a NULL value; selfID is the user keeping it.
+func claimUsername(ctx context.Context, repo repository.UserRepository, username string, selfID int64) (pgtype.Text, error) {
+	if username == "" {
+		return pgtype.Text{}, nil
+	}
+	username = strings.ToLower(username)
+
+	holder, err := repo.GetByUsername(ctx, username)
+	if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		return pgtype.Text{}, apperror.NewInternal("failed to check username availability")
+	}
+	if holder != nil && holder.ID != selfID {
+		return pgtype.Text{}, apperror.NewBadRequest("username already taken")
+	}
+	return pgtype.Text{String: username, Valid: true}, nil
+}
+
+// userFilter converts list query params into a repository filter.
+func userFilter(q dto.UserFilterQuery, includeDeleted bool) (repository.UserFilter, error) {
+	filter := repository.UserFilter{
+		Search:         strings.TrimSpace(q.Search),
+		Role:           q.Role,
+		EmailVerified:  q.EmailVerified,
+		Banned:         q.Banned,
+		SortBy:         strings.TrimPrefix(q.Sort, "-"),
+		SortDesc:       strings.HasPrefix(q.Sort, "-"),
+		IncludeDeleted: includeDeleted,
+	}
+
+	var err error
+	if q.CreatedAfter != "" {
+		if filter.CreatedAfter, err = time.Parse(time.RFC3339, q.CreatedAfter); err != nil {
+			return filter, apperror.NewBadRequest("created_after must be an RFC 3339 timestamp")
+		}
+	}
+	if q.CreatedBefore != "" {
+		if filter.CreatedBefore, err = time.Parse(time.RFC3339, q.CreatedBefore); err != nil {
+			return filter, apperror.NewBadRequest("created_before must be an RFC 3339 timestamp")
+		}
+	}
+	return filter, nil
+}
+
 func ToUserResponse(user *sqlc.User) *dto.UserResponse {
+	// A malformed document is left out of the response rather than failing the request.
+	metadata, _ := repository.DecodeMetadata(user.Metadata)
+	if len(metadata) == 0 {
+		metadata = nil
+	}
+
 	return &dto.UserResponse{
 		ID:            user.ID,
 		Email:         user.Email,
+		Username:      user.Username.String,
 		Name:          user.Name,
 		Role:          user.Role,
 		EmailVerified: user.EmailVerifiedAt.Valid,
+		Metadata:      metadata,
 		CreatedAt:     user.CreatedAt.Time,
 		UpdatedAt:     user.UpdatedAt.Time,
 	}
 }
+
+func userCacheKey(id int64) string {
+	return userCachePrefix + strconv.FormatInt(id, 10)
+}
+
+// forgetUser drops what is cached about a user so the next lookup reads a change made to
+// them. Services changing what the profile shows, or what tokens are issued from it such as
+// the role, call it after the change.
+func forgetUser(ctx context.Context, c cache.Cache, id int64) {
+	if err := c.InvalidateTag(ctx, cache.UserTag(id)); err != nil {
+		slog.Error("failed to invalidate cached user", slog.Int64("user_id", id), slog.Any("error", err))
+	}
+}
+
+// forgetStats drops the cached admin statistics so the next request counts a change to
+// users or files. Admin services call it after such a change; everyday uploads and sign-ups
+// are left to the short cache lifetime.
+func forgetStats(ctx context.Context, c cache.Cache) {
+	if err := c.InvalidateTag(ctx, cache.StatsTag); err != nil {
+		slog.Error("failed to invalidate cached stats", slog.Any("error", err))
+	}
+}
+
+// checkNotBanned refuses to sign in a banned user, telling them why.
+func checkNotBanned(user *sqlc.User) error {
+	if !user.BannedAt.Valid {
+		return nil
+	}
+	err := apperror.NewForbidden("account is banned").WithErrorCode(dto.ErrorCodeAccountBanned)
+	if user.BanReason != "" {
+		err.Details = map[string]string{"reason": user.BanReason}
+	}
+	return err
+}
@@ -3,19 +3,24 @@ package service
 import (
 	"context"
 	"errors"
+	"maps"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/token"
 )
 
 func newTestUserService(repo *mockUserRepo, requireEmailVerification bool) UserService {
-	return NewUserService(repo, newMockRefreshTokenRepo(), requireEmailVerification, newMockCache(), nil)
+	return NewUserService(repo, newMockRefreshTokenRepo(), nil, newMockUserSettingsRepo(), staticSettings{RegistrationEnabled: true, RequireEmailVerification: requireEmailVerification}, false, EmailDomainPolicy{}, token.NewRevocationStore(newMockCache(), time.Hour), newMockCache(), nil)
 }
 
 // ---------------------------------------------------------------------------
@@ -67,6 +72,86 @@ func TestRegister(t *testing.T) {
 			t.Errorf("expected 'email already registered', got %q", err.Error())
 		}
 	})
+
+	t.Run("username is stored lowercase and must be unique", func(t *testing.T) {
+		repo := newMockUserRepo()
+		svc := newTestUserService(repo, false)
+
+		resp, err := svc.Register(context.Background(), dto.RegisterRequest{
+			Email: "jane@example.com", Password: "Password1!", Name: "Jane", Username: "JaneDoe",
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if resp.Username != "janedoe" {
+			t.Errorf("expected username janedoe, got %q", resp.Username)
+		}
+
+		_, err = svc.Register(context.Background(), dto.RegisterRequest{
+			Email: "other@example.com", Password: "Password1!", Name: "Other", Username: "janeDOE",
+		})
+		assertAppErrorCode(t, err, 400)
+	})
+
+	t.Run("stores the negotiated locale", func(t *testing.T) {
+		settingsRepo := newMockUserSettingsRepo()
+		svc := NewUserService(newMockUserRepo(), newMockRefreshTokenRepo(), nil, settingsRepo, openSettings, false, EmailDomainPolicy{}, nil, newMockCache(), nil)
+
+		resp, err := svc.Register(context.Background(), dto.RegisterRequest{
+			Email: "test@example.com", Password: "Password1!", Name: "Test User", Locale: "vi",
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if s := settingsRepo.settings[resp.ID]; s == nil || s.Locale != "vi" {
+			t.Errorf("expected locale vi to be stored, got %+v", s)
+		}
+	})
+
+	t.Run("email domain policy", func(t *testing.T) {
+		repo := newMockUserRepo()
+		domains := EmailDomainPolicy{Allowed: []string{"example.com"}, Blocked: []string{"temp.example.com"}}
+		svc := NewUserService(repo, newMockRefreshTokenRepo(), nil, newMockUserSettingsRepo(), openSettings, false, domains, nil, newMockCache(), nil)
+		register := func(email string) error {
+			_, err := svc.Register(context.Background(), dto.RegisterRequest{Email: email, Password: "Password1!", Name: "User"})
+			return err
+		}
+
+		if err := register("jane@eng.example.com"); err != nil {
+			t.Fatalf("expected allowed subdomain to register, got %v", err)
+		}
+		assertAppErrorCode(t, register("jane@gmail.com"), 403)
+		assertAppErrorCode(t, register("jane@temp.example.com"), 403)
+		if len(repo.users) != 1 {
+			t.Errorf("expected 1 user, got %d", len(repo.users))
+		}
+	})
+}
+
+func TestEmailDomainPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy EmailDomainPolicy
+		email  string
+		want   bool
+	}{
+		{"empty policy allows any domain", EmailDomainPolicy{}, "a@gmail.com", true},
+		{"blocked domain", EmailDomainPolicy{Blocked: []string{"mailinator.com"}}, "a@mailinator.com", false},
+		{"blocked subdomain", EmailDomainPolicy{Blocked: []string{"mailinator.com"}}, "a@x.mailinator.com", false},
+		{"suffix is not a subdomain", EmailDomainPolicy{Blocked: []string{"mailinator.com"}}, "a@notmailinator.com", true},
+		{"case insensitive", EmailDomainPolicy{Allowed: []string{"@Example.com"}}, "a@EXAMPLE.COM", true},
+		{"outside allow list", EmailDomainPolicy{Allowed: []string{"example.com"}}, "a@gmail.com", false},
+		{"block list wins", EmailDomainPolicy{Allowed: []string{"example.com"}, Blocked: []string{"example.com"}}, "a@example.com", false},
+		{"missing domain", EmailDomainPolicy{}, "not-an-email", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Permits(tt.email); got != tt.want {
+				t.Errorf("Permits(%q) = %v, want %v", tt.email, got, tt.want)
+			}
+		})
+	}
 }
 
 // ---------------------------------------------------------------------------
@@ -133,7 +218,7 @@ func TestAuthenticate(t *testing.T) {
 	t.Run("account locked after max attempts", func(t *testing.T) {
 		repo := newMockUserRepo()
 		cache := newMockCache()
-		svc := NewUserService(repo, newMockRefreshTokenRepo(), false, cache, nil)
+		svc := NewUserService(repo, newMockRefreshTokenRepo(), nil, newMockUserSettingsRepo(), openSettings, false, EmailDomainPolicy{}, nil, cache, nil)
 
 		_, _ = svc.Register(context.Background(), dto.RegisterRequest{
 			Email: "test@example.com", Password: "Password1!", Name: "Test User",
@@ -176,6 +261,34 @@ func TestAuthenticate(t *testing.T) {
 		}
 	})
 
+	t.Run("banned account is told the reason", func(t *testing.T) {
+		repo := newMockUserRepo()
+		svc := newTestUserService(repo, false)
+
+		user, _ := svc.Register(context.Background(), dto.RegisterRequest{
+			Email: "test@example.com", Password: "Password1!", Name: "Test User",
+		})
+		_, _ = repo.Ban(context.Background(), user.ID, "spam")
+
+		_, err := svc.Authenticate(context.Background(), dto.LoginRequest{
+			Email: "test@example.com", Password: "Password1!",
+		})
+		assertAppErrorCode(t, err, 403)
+		var appErr *apperror.AppError
+		if !errors.As(err, &appErr) || appErr.ErrorCode != dto.ErrorCodeAccountBanned {
+			t.Fatalf("expected %s, got %v", dto.ErrorCodeAccountBanned, err)
+		}
+		if details, _ := appErr.Details.(map[string]string); details["reason"] != "spam" {
+			t.Errorf("expected the ban reason, got %v", appErr.Details)
+		}
+
+		// A wrong password does not reveal the ban.
+		_, err = svc.Authenticate(context.Background(), dto.LoginRequest{
+			Email: "test@example.com", Password: "WrongPass1!",
+		})
+		assertAppErrorCode(t, err, 401)
+	})
+
 	t.Run("OAuth account no password hash", func(t *testing.T) {
 		repo := newMockUserRepo()
 		svc := newTestUserService(repo, false)
@@ -230,6 +343,29 @@ func TestGetByID(t *testing.T) {
 		}
 	})
 
+	t.Run("cached until updated", func(t *testing.T) {
+		repo := newMockUserRepo()
+		svc := newTestUserService(repo, false)
+		ctx := context.Background()
+		repo.users[1] = &sqlc.User{ID: 1, Email: "test@example.com", Name: "Old Name", Role: "user"}
+
+		if _, err := svc.GetByID(ctx, 1); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		repo.users[1].Email = "changed@example.com"
+		if resp, _ := svc.GetByID(ctx, 1); resp.Email != "test@example.com" {
+			t.Errorf("expected the cached email, got %q", resp.Email)
+		}
+
+		newName := "New Name"
+		if _, err := svc.Update(ctx, 1, dto.UpdateUserRequest{Name: &newName}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if resp, _ := svc.GetByID(ctx, 1); resp.Name != "New Name" {
+			t.Errorf("expected an update to invalidate the cache, got name %q", resp.Name)
+		}
+	})
+
 	t.Run("not found", func(t *testing.T) {
 		repo := newMockUserRepo()
 		svc := newTestUserService(repo, false)
@@ -244,6 +380,36 @@ func TestGetByID(t *testing.T) {
 	})
 }
 
+// ---------------------------------------------------------------------------
+// GetByUsername
+// ---------------------------------------------------------------------------
+
+func TestGetByUsername(t *testing.T) {
+	repo := newMockUserRepo()
+	svc := newTestUserService(repo, false)
+
+	repo.users[1] = &sqlc.User{
+		ID: 1, Email: "jane@example.com", Name: "Jane", Role: "user",
+		Username: pgtype.Text{String: "janedoe", Valid: true},
+	}
+	repo.users[2] = &sqlc.User{
+		ID: 2, Email: "gone@example.com", Name: "Gone", Role: "user",
+		Username:  pgtype.Text{String: "gone", Valid: true},
+		DeletedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+
+	resp, err := svc.GetByUsername(context.Background(), "JaneDoe")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if resp.ID != 1 || resp.Username != "janedoe" || resp.Name != "Jane" {
+		t.Errorf("unexpected profile: %+v", resp)
+	}
+
+	_, err = svc.GetByUsername(context.Background(), "gone")
+	assertAppErrorCode(t, err, 404)
+}
+
 // ---------------------------------------------------------------------------
 // List
 // ---------------------------------------------------------------------------
@@ -257,7 +423,7 @@ func TestList(t *testing.T) {
 		repo.users[2] = &sqlc.User{ID: 2, Email: "b@example.com", Name: "B", Role: "user"}
 		repo.nextID = 3
 
-		users, total, err := svc.List(context.Background(), 1, 10)
+		users, total, err := svc.List(context.Background(), dto.UserFilterQuery{}, 1, 10)
 		if err != nil {
 			t.Fatalf("expected no error, got %v", err)
 		}
@@ -268,6 +434,55 @@ func TestList(t *testing.T) {
 			t.Errorf("expected 2 users, got %d", len(users))
 		}
 	})
+
+	t.Run("search and role filter", func(t *testing.T) {
+		repo := newMockUserRepo()
+		svc := newTestUserService(repo, false)
+
+		repo.users[1] = &sqlc.User{ID: 1, Email: "alice@example.com", Name: "Alice", Role: "admin"}
+		repo.users[2] = &sqlc.User{ID: 2, Email: "bob@example.com", Name: "Bob", Role: "user"}
+		repo.users[3] = &sqlc.User{ID: 3, Email: "malice@example.com", Name: "Mal", Role: "user"}
+
+		users, total, err := svc.List(context.Background(), dto.UserFilterQuery{Search: " ALICE ", Role: "user"}, 1, 10)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if total != 1 || len(users) != 1 || users[0].ID != 3 {
+			t.Errorf("expected only user 3, got %+v (total %d)", users, total)
+		}
+	})
+
+	t.Run("invalid timestamp", func(t *testing.T) {
+		svc := newTestUserService(newMockUserRepo(), false)
+
+		_, _, err := svc.List(context.Background(), dto.UserFilterQuery{CreatedAfter: "last week"}, 1, 10)
+		assertAppErrorCode(t, err, 400)
+	})
+}
+
+func TestUserFilter(t *testing.T) {
+	verified := true
+	filter, err := userFilter(dto.UserFilterQuery{
+		EmailVerified: &verified,
+		CreatedAfter:  "2026-01-01T00:00:00Z",
+		CreatedBefore: "2026-02-01T00:00:00+07:00",
+		Sort:          "-name",
+	}, true)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if filter.SortBy != "name" || !filter.SortDesc {
+		t.Errorf("expected descending name sort, got %q desc=%v", filter.SortBy, filter.SortDesc)
+	}
+	if !filter.CreatedAfter.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected created_after %v", filter.CreatedAfter)
+	}
+	if !filter.CreatedBefore.Equal(time.Date(2026, 1, 31, 17, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected created_before %v", filter.CreatedBefore)
+	}
+	if !filter.IncludeDeleted || filter.EmailVerified == nil || !*filter.EmailVerified {
+		t.Errorf("unexpected filter %+v", filter)
+	}
 }
 
 // ---------------------------------------------------------------------------
@@ -310,6 +525,56 @@ func TestUpdate(t *testing.T) {
 		}
 	})
 
+	t.Run("username", func(t *testing.T) {
+		repo := newMockUserRepo()
+		svc := newTestUserService(repo, false)
+
+		repo.users[1] = &sqlc.User{ID: 1, Email: "user1@example.com", Name: "User 1", Role: "user"}
+		repo.users[2] = &sqlc.User{
+			ID: 2, Email: "user2@example.com", Name: "User 2", Role: "user",
+			Username: pgtype.Text{String: "taken", Valid: true},
+		}
+
+		taken := "Taken"
+		_, err := svc.Update(context.Background(), 1, dto.UpdateUserRequest{Username: &taken})
+		assertAppErrorCode(t, err, 400)
+
+		handle := "User_One"
+		resp, err := svc.Update(context.Background(), 1, dto.UpdateUserRequest{Username: &handle})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if resp.Username != "user_one" {
+			t.Errorf("expected username user_one, got %q", resp.Username)
+		}
+
+		// Re-submitting your own username is not a conflict
+		if _, err := svc.Update(context.Background(), 1, dto.UpdateUserRequest{Username: &handle}); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("merges metadata", func(t *testing.T) {
+		repo := newMockUserRepo()
+		svc := newTestUserService(repo, false)
+
+		repo.users[1] = &sqlc.User{
+			ID: 1, Email: "user@example.com", Name: "User", Role: "user",
+			Metadata: []byte(`{"company":"Acme","team":"core"}`),
+		}
+
+		resp, err := svc.Update(context.Background(), 1, dto.UpdateUserRequest{
+			Metadata: map[string]any{"timezone": "UTC", "team": nil},
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		want := map[string]any{"company": "Acme", "timezone": "UTC"}
+		if !maps.Equal(resp.Metadata, want) {
+			t.Errorf("expected metadata %v, got %v", want, resp.Metadata)
+		}
+	})
+
 	t.Run("not found", func(t *testing.T) {
 		repo := newMockUserRepo()
 		svc := newTestUserService(repo, false)
@@ -344,8 +609,47 @@ func TestDelete(t *testing.T) {
 		if err != nil {
 			t.Fatalf("expected no error, got %v", err)
 		}
-		if len(repo.users) != 0 {
-			t.Error("expected user to be removed from repo")
+		if !repo.users[1].DeletedAt.Valid {
+			t.Error("expected user to be soft-deleted")
+		}
+	})
+
+	t.Run("signs out every session", func(t *testing.T) {
+		repo := newMockUserRepo()
+		refreshRepo := newMockRefreshTokenRepo()
+		revocations := token.NewRevocationStore(newMockCache(), time.Hour)
+		svc := NewUserService(repo, refreshRepo, nil, newMockUserSettingsRepo(), openSettings, false, EmailDomainPolicy{}, revocations, newMockCache(), nil)
+
+		repo.users[1] = &sqlc.User{ID: 1, Email: "test@example.com", Name: "Test", Role: "user"}
+		issued := &token.Claims{UserID: 1, RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))}}
+
+		if err := svc.Delete(context.Background(), 1); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !slices.Contains(refreshRepo.deletedUserIDs, 1) {
+			t.Error("expected refresh tokens to be deleted")
+		}
+		if revoked, _ := revocations.IsRevoked(context.Background(), issued); !revoked {
+			t.Error("expected earlier access tokens to be revoked")
+		}
+	})
+
+	t.Run("last admin", func(t *testing.T) {
+		repo := newMockUserRepo()
+		svc := newTestUserService(repo, false)
+
+		repo.users[1] = &sqlc.User{ID: 1, Email: "admin@example.com", Name: "Admin", Role: dto.RoleAdmin}
+		repo.users[2] = &sqlc.User{ID: 2, Email: "banned@example.com", Name: "Banned", Role: dto.RoleAdmin,
+			BannedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}}
+
+		assertErrorCode(t, svc.Delete(context.Background(), 1), 409, dto.ErrorCodeLastAdmin)
+		if repo.users[1].DeletedAt.Valid {
+			t.Error("expected the last admin to be kept")
+		}
+
+		repo.users[3] = &sqlc.User{ID: 3, Email: "other@example.com", Name: "Other", Role: dto.RoleAdmin}
+		if err := svc.Delete(context.Background(), 1); err != nil {
+			t.Errorf("expected an admin to be deletable while another is active, got %v", err)
 		}
 	})
 
@@ -393,6 +697,40 @@ func TestChangePassword(t *testing.T) {
 		}
 	})
 
+	t.Run("signs out every session", func(t *testing.T) {
+		repo := newMockUserRepo()
+		refreshRepo := newMockRefreshTokenRepo()
+		appCache := newMockCache()
+		revocations := token.NewRevocationStore(newMockCache(), time.Hour)
+		svc := NewUserService(repo, refreshRepo, nil, newMockUserSettingsRepo(), openSettings, false, EmailDomainPolicy{}, revocations, appCache, nil)
+
+		hash, _ := bcrypt.GenerateFromPassword([]byte("OldPass1!"), bcrypt.MinCost)
+		repo.users[1] = &sqlc.User{
+			ID: 1, Email: "test@example.com", Name: "Test", Role: "user",
+			PasswordHash: pgtype.Text{String: string(hash), Valid: true},
+		}
+		appCache.cacheUser(1)
+		issued := &token.Claims{UserID: 1, RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))}}
+
+		err := svc.ChangePassword(context.Background(), 1, dto.ChangePasswordRequest{
+			CurrentPassword: "OldPass1!",
+			NewPassword:     "NewPass2@",
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if !slices.Equal(refreshRepo.deletedUserIDs, []int64{1}) {
+			t.Errorf("expected refresh tokens of user 1 deleted, got %v", refreshRepo.deletedUserIDs)
+		}
+		if revoked, _ := revocations.IsRevoked(context.Background(), issued); !revoked {
+			t.Error("expected earlier access tokens revoked")
+		}
+		if appCache.userCached(1) {
+			t.Error("expected the cached user dropped")
+		}
+	})
+
 	t.Run("wrong current password", func(t *testing.T) {
 		repo := newMockUserRepo()
 		svc := newTestUserService(repo, false)
@@ -521,4 +859,165 @@ func TestFindOrCreateByGoogle(t *testing.T) {
 			t.Errorf("expected auth_provider 'google', got %q", user.AuthProvider)
 		}
 	})
+
+	t.Run("blocked domain cannot sign up but existing users can sign in", func(t *testing.T) {
+		repo := newMockUserRepo()
+		domains := EmailDomainPolicy{Blocked: []string{"mailinator.com"}}
+		svc := NewUserService(repo, newMockRefreshTokenRepo(), nil, newMockUserSettingsRepo(), openSettings, false, domains, nil, newMockCache(), nil)
+
+		repo.users[1] = &sqlc.User{ID: 1, Email: "old@mailinator.com", AuthProvider: "local", Role: "user"}
+		repo.nextID = 2
+
+		_, err := svc.FindOrCreateByGoogle(context.Background(), "google-1", "new@mailinator.com", "New")
+		assertAppErrorCode(t, err, 403)
+
+		user, err := svc.FindOrCreateByGoogle(context.Background(), "google-2", "old@mailinator.com", "Old")
+		if err != nil {
+			t.Fatalf("expected existing user to link, got %v", err)
+		}
+		if user.ID != 1 {
+			t.Errorf("expected same user ID 1, got %d", user.ID)
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// FindOrCreateByGitHub
+// ---------------------------------------------------------------------------
+
+func TestFindOrCreateByGitHub(t *testing.T) {
+	t.Run("existing github user", func(t *testing.T) {
+		repo := newMockUserRepo()
+		svc := newTestUserService(repo, false)
+
+		repo.users[1] = &sqlc.User{
+			ID: 1, Email: "github@example.com", Name: "GitHub User",
+			GithubID:     pgtype.Text{String: "12345", Valid: true},
+			AuthProvider: "github", Role: "user",
+		}
+		repo.nextID = 2
+
+		user, err := svc.FindOrCreateByGitHub(context.Background(), "12345", "github@example.com", "GitHub User")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if user.ID != 1 {
+			t.Errorf("expected ID 1, got %d", user.ID)
+		}
+	})
+
+	t.Run("link existing google account", func(t *testing.T) {
+		repo := newMockUserRepo()
+		svc := newTestUserService(repo, false)
+
+		repo.users[1] = &sqlc.User{
+			ID: 1, Email: "existing@example.com", Name: "Existing",
+			GoogleID:     pgtype.Text{String: "google-123", Valid: true},
+			AuthProvider: "google", Role: "user",
+		}
+		repo.nextID = 2
+
+		user, err := svc.FindOrCreateByGitHub(context.Background(), "67890", "existing@example.com", "Existing")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if user.ID != 1 {
+			t.Errorf("expected same user ID 1, got %d", user.ID)
+		}
+		if user.GithubID.String != "67890" {
+			t.Errorf("expected github ID linked, got %q", user.GithubID.String)
+		}
+		if user.GoogleID.String != "google-123" {
+			t.Errorf("expected google ID preserved, got %q", user.GoogleID.String)
+		}
+		if user.AuthProvider != "github" {
+			t.Errorf("expected auth_provider 'github', got %q", user.AuthProvider)
+		}
+	})
+
+	t.Run("create new user", func(t *testing.T) {
+		repo := newMockUserRepo()
+		svc := newTestUserService(repo, false)
+
+		user, err := svc.FindOrCreateByGitHub(context.Background(), "24680", "new@example.com", "New User")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if user.GithubID.String != "24680" {
+			t.Errorf("expected github ID 24680, got %q", user.GithubID.String)
+		}
+		if user.GoogleID.Valid {
+			t.Errorf("expected no google ID, got %q", user.GoogleID.String)
+		}
+		if user.AuthProvider != "github" {
+			t.Errorf("expected auth_provider 'github', got %q", user.AuthProvider)
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// FindOrCreateBySAML
+// ---------------------------------------------------------------------------
+
+func TestFindOrCreateBySAML(t *testing.T) {
+	t.Run("existing saml user", func(t *testing.T) {
+		repo := newMockUserRepo()
+		svc := newTestUserService(repo, false)
+
+		repo.users[1] = &sqlc.User{
+			ID: 1, Email: "saml@example.com", Name: "SAML User",
+			SamlID:       pgtype.Text{String: "name-id-1", Valid: true},
+			AuthProvider: "saml", Role: "user",
+		}
+		repo.nextID = 2
+
+		user, err := svc.FindOrCreateBySAML(context.Background(), "name-id-1", "saml@example.com", "SAML User")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if user.ID != 1 {
+			t.Errorf("expected ID 1, got %d", user.ID)
+		}
+	})
+
+	t.Run("link existing local account", func(t *testing.T) {
+		repo := newMockUserRepo()
+		svc := newTestUserService(repo, false)
+
+		repo.users[1] = &sqlc.User{
+			ID: 1, Email: "existing@example.com", Name: "Existing",
+			AuthProvider: "local", Role: "user",
+		}
+		repo.nextID = 2
+
+		user, err := svc.FindOrCreateBySAML(context.Background(), "name-id-2", "existing@example.com", "Existing")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if user.ID != 1 {
+			t.Errorf("expected same user ID 1, got %d", user.ID)
+		}
+		if user.SamlID.String != "name-id-2" {
+			t.Errorf("expected saml ID linked, got %q", user.SamlID.String)
+		}
+		if user.AuthProvider != "saml" {
+			t.Errorf("expected auth_provider 'saml', got %q", user.AuthProvider)
+		}
+	})
+
+	t.Run("create new user", func(t *testing.T) {
+		repo := newMockUserRepo()
+		svc := newTestUserService(repo, false)
+
+		user, err := svc.FindOrCreateBySAML(context.Background(), "name-id-3", "new@example.com", "New User")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if user.SamlID.String != "name-id-3" {
+			t.Errorf("expected saml ID name-id-3, got %q", user.SamlID.String)
+		}
+		if user.AuthProvider != "saml" {
+			t.Errorf("expected auth_provider 'saml', got %q", user.AuthProvider)
+		}
+	})
 }
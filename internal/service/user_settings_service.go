@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+type UserSettingsService interface {
+	Get(ctx context.Context, userID int64) (*dto.UserSettingsResponse, error)
+	Update(ctx context.Context, userID int64, req dto.UpdateUserSettingsRequest) (*dto.UserSettingsResponse, error)
+}
+
+type userSettingsService struct {
+	repo repository.UserSettingsRepository
+}
+
+func NewUserSettingsService(repo repository.UserSettingsRepository) UserSettingsService {
+	return &userSettingsService{repo: repo}
+}
+
+func (s *userSettingsService) Get(ctx context.Context, userID int64) (*dto.UserSettingsResponse, error) {
+	settings, err := loadUserSettings(ctx, s.repo, userID)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to get settings")
+	}
+	return toUserSettingsResponse(settings), nil
+}
+
+func (s *userSettingsService) Update(ctx context.Context, userID int64, req dto.UpdateUserSettingsRequest) (*dto.UserSettingsResponse, error) {
+	params := sqlc.UpsertUserSettingsParams{UserID: userID}
+	if req.Locale != nil {
+		params.Locale = pgtype.Text{String: *req.Locale, Valid: true}
+	}
+	if req.Timezone != nil {
+		params.Timezone = pgtype.Text{String: *req.Timezone, Valid: true}
+	}
+	if req.EmailSecurityAlerts != nil {
+		params.EmailSecurityAlerts = pgtype.Bool{Bool: *req.EmailSecurityAlerts, Valid: true}
+	}
+	if req.EmailProductUpdates != nil {
+		params.EmailProductUpdates = pgtype.Bool{Bool: *req.EmailProductUpdates, Valid: true}
+	}
+
+	settings, err := s.repo.Upsert(ctx, params)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to update settings")
+	}
+	return toUserSettingsResponse(settings), nil
+}
+
+// loadUserSettings returns the user's saved settings, or the defaults if they have none.
+// Services that email users use it to honour notification preferences and localize messages.
+func loadUserSettings(ctx context.Context, repo repository.UserSettingsRepository, userID int64) (*sqlc.UserSetting, error) {
+	settings, err := repo.Get(ctx, userID)
+	if errors.Is(err, apperror.ErrNotFound) {
+		return &sqlc.UserSetting{
+			UserID:              userID,
+			Locale:              dto.DefaultLocale,
+			Timezone:            dto.DefaultTimezone,
+			EmailSecurityAlerts: true,
+		}, nil
+	}
+	return settings, err
+}
+
+// userLocale returns the locale the user's emails are written in. A failed lookup leaves
+// it empty, so the email falls back to the default locale instead of not being sent.
+func userLocale(ctx context.Context, repo repository.UserSettingsRepository, userID int64) string {
+	settings, err := loadUserSettings(ctx, repo, userID)
+	if err != nil {
+		slog.Error("failed to load user locale", slog.Int64("user_id", userID), slog.Any("error", err))
+		return ""
+	}
+	return settings.Locale
+}
+
+// rememberSignupLocale stores the locale negotiated from a new user's Accept-Language
+// header, unless the user already chose one.
+func rememberSignupLocale(ctx context.Context, repo repository.UserSettingsRepository, userID int64, locale string) {
+	if locale == "" {
+		return
+	}
+	if _, err := repo.Get(ctx, userID); !errors.Is(err, apperror.ErrNotFound) {
+		return
+	}
+	if _, err := repo.Upsert(ctx, sqlc.UpsertUserSettingsParams{
+		UserID: userID,
+		Locale: pgtype.Text{String: locale, Valid: true},
+	}); err != nil {
+		slog.Error("failed to store signup locale", slog.Int64("user_id", userID), slog.Any("error", err))
+	}
+}
+
+func toUserSettingsResponse(s *sqlc.UserSetting) *dto.UserSettingsResponse {
+	return &dto.UserSettingsResponse{
+		Locale:              s.Locale,
+		Timezone:            s.Timezone,
+		EmailSecurityAlerts: s.EmailSecurityAlerts,
+		EmailProductUpdates: s.EmailProductUpdates,
+	}
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/dto"
+)
+
+func TestUserSettings(t *testing.T) {
+	t.Run("defaults before anything is saved", func(t *testing.T) {
+		svc := NewUserSettingsService(newMockUserSettingsRepo())
+
+		settings, err := svc.Get(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		want := dto.UserSettingsResponse{Locale: dto.DefaultLocale, Timezone: dto.DefaultTimezone, EmailSecurityAlerts: true}
+		if *settings != want {
+			t.Errorf("expected %+v, got %+v", want, *settings)
+		}
+	})
+
+	t.Run("update changes only the given fields", func(t *testing.T) {
+		svc := NewUserSettingsService(newMockUserSettingsRepo())
+		ctx := context.Background()
+
+		locale, timezone, updates := "fr-CA", "America/Toronto", true
+		if _, err := svc.Update(ctx, 1, dto.UpdateUserSettingsRequest{Locale: &locale, Timezone: &timezone}); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		settings, err := svc.Update(ctx, 1, dto.UpdateUserSettingsRequest{EmailProductUpdates: &updates})
+		if err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+
+		want := dto.UserSettingsResponse{Locale: "fr-CA", Timezone: "America/Toronto", EmailSecurityAlerts: true, EmailProductUpdates: true}
+		if *settings != want {
+			t.Errorf("expected %+v, got %+v", want, *settings)
+		}
+	})
+}
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/repository"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+)
+
+const (
+	webauthnSessionPrefix = "webauthn_session:"
+	webauthnSessionTTL    = 5 * time.Minute
+)
+
+type WebAuthnService interface {
+	BeginRegistration(ctx context.Context, userID int64) (*protocol.CredentialCreation, string, error)
+	FinishRegistration(ctx context.Context, userID int64, sessionID, name string, credential []byte) error
+	BeginLogin(ctx context.Context) (*protocol.CredentialAssertion, string, error)
+	FinishLogin(ctx context.Context, sessionID string, credential []byte) (*sqlc.User, error)
+}
+
+type webAuthnService struct {
+	webAuthn *webauthn.WebAuthn
+	userRepo repository.UserRepository
+	credRepo repository.WebAuthnCredentialRepository
+	cache    cache.Cache
+	settings RuntimeSettings
+}
+
+func NewWebAuthnService(
+	wa *webauthn.WebAuthn,
+	userRepo repository.UserRepository,
+	credRepo repository.WebAuthnCredentialRepository,
+	appCache cache.Cache,
+	settings RuntimeSettings,
+) WebAuthnService {
+	return &webAuthnService{
+		webAuthn: wa,
+		userRepo: userRepo,
+		credRepo: credRepo,
+		cache:    appCache,
+		settings: settings,
+	}
+}
+
+// webauthnUser adapts a user and its stored credentials to the webauthn.User interface.
+type webauthnUser struct {
+	user        *sqlc.User
+	credentials []webauthn.Credential
+}
+
+func (u *webauthnUser) WebAuthnID() []byte                         { return userHandle(u.user.ID) }
+func (u *webauthnUser) WebAuthnName() string                       { return u.user.Email }
+func (u *webauthnUser) WebAuthnDisplayName() string                { return u.user.Name }
+func (u *webauthnUser) WebAuthnCredentials() []webauthn.Credential { return u.credentials }
+
+// userHandle encodes a user ID as the opaque WebAuthn user handle.
+func userHandle(id int64) []byte {
+	return []byte(strconv.FormatInt(id, 10))
+}
+
+func (s *webAuthnService) BeginRegistration(ctx context.Context, userID int64) (*protocol.CredentialCreation, string, error) {
+	waUser, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(waUser.credentials))
+	for _, cred := range waUser.credentials {
+		exclusions = append(exclusions, cred.Descriptor())
+	}
+
+	options, session, err := s.webAuthn.BeginRegistration(waUser,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+	)
+	if err != nil {
+		return nil, "", apperror.NewInternal("failed to begin passkey registration")
+	}
+
+	sessionID, err := s.saveSession(ctx, session)
+	if err != nil {
+		return nil, "", err
+	}
+	return options, sessionID, nil
+}
+
+func (s *webAuthnService) FinishRegistration(ctx context.Context, userID int64, sessionID, name string, credential []byte) error {
+	session, err := s.takeSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(session.UserID, userHandle(userID)) {
+		return apperror.NewBadRequest("invalid or expired passkey session")
+	}
+
+	waUser, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(credential)
+	if err != nil {
+		return apperror.NewBadRequest("invalid passkey credential")
+	}
+
+	cred, err := s.webAuthn.CreateCredential(waUser, *session, parsed)
+	if err != nil {
+		return apperror.NewBadRequest("passkey verification failed")
+	}
+
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return apperror.NewInternal("failed to encode passkey credential")
+	}
+
+	if _, err := s.credRepo.Create(ctx, sqlc.CreateWebAuthnCredentialParams{
+		UserID:       userID,
+		CredentialID: cred.ID,
+		Credential:   data,
+		Name:         name,
+	}); err != nil {
+		if repository.IsUniqueViolation(err) {
+			return apperror.NewBadRequest("passkey already registered")
+		}
+		return apperror.NewInternal("failed to save passkey credential")
+	}
+
+	return nil
+}
+
+func (s *webAuthnService) BeginLogin(ctx context.Context) (*protocol.CredentialAssertion, string, error) {
+	options, session, err := s.webAuthn.BeginDiscoverableLogin()
+	if err != nil {
+		return nil, "", apperror.NewInternal("failed to begin passkey login")
+	}
+
+	sessionID, err := s.saveSession(ctx, session)
+	if err != nil {
+		return nil, "", err
+	}
+	return options, sessionID, nil
+}
+
+func (s *webAuthnService) FinishLogin(ctx context.Context, sessionID string, credential []byte) (*sqlc.User, error) {
+	session, err := s.takeSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(credential)
+	if err != nil {
+		return nil, apperror.NewBadRequest("invalid passkey credential")
+	}
+
+	// Resolve the user from the user handle returned by the authenticator
+	handler := func(_, handle []byte) (webauthn.User, error) {
+		userID, err := strconv.ParseInt(string(handle), 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid user handle")
+		}
+		return s.loadUser(ctx, userID)
+	}
+
+	waUser, cred, err := s.webAuthn.ValidatePasskeyLogin(handler, *session, parsed)
+	if err != nil {
+		return nil, apperror.NewUnauthorized("passkey authentication failed")
+	}
+
+	if cred.Authenticator.CloneWarning {
+		slog.Warn("passkey sign count regressed, possible cloned authenticator",
+			slog.String("credential_id", hex.EncodeToString(cred.ID)),
+		)
+	}
+
+	// Persist the updated sign count
+	if data, err := json.Marshal(cred); err == nil {
+		if err := s.credRepo.UpdateUsage(ctx, cred.ID, data); err != nil {
+			slog.Error("failed to update passkey usage", slog.Any("error", err))
+		}
+	}
+
+	resolved, ok := waUser.(*webauthnUser)
+	if !ok {
+		return nil, apperror.NewInternal("unexpected passkey user type")
+	}
+	user := resolved.user
+	if err := checkNotBanned(user); err != nil {
+		return nil, err
+	}
+	if s.settings.Current(ctx).RequireEmailVerification && !user.EmailVerifiedAt.Valid {
+		return nil, apperror.NewForbidden("email not verified")
+	}
+
+	return user, nil
+}
+
+func (s *webAuthnService) loadUser(ctx context.Context, userID int64) (*webauthnUser, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, apperror.NewNotFound("user not found")
+		}
+		return nil, apperror.NewInternal("failed to get user")
+	}
+
+	rows, err := s.credRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to list passkey credentials")
+	}
+
+	creds := make([]webauthn.Credential, 0, len(rows))
+	for _, row := range rows {
+		var cred webauthn.Credential
+		if err := json.Unmarshal(row.Credential, &cred); err != nil {
+			slog.Error("failed to decode passkey credential", slog.Int64("id", row.ID), slog.Any("error", err))
+			continue
+		}
+		creds = append(creds, cred)
+	}
+
+	return &webauthnUser{user: user, credentials: creds}, nil
+}
+
+// saveSession stores ceremony session data in the cache and returns its opaque ID.
+func (s *webAuthnService) saveSession(ctx context.Context, session *webauthn.SessionData) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", apperror.NewInternal("failed to generate session id")
+	}
+	sessionID := hex.EncodeToString(b)
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", apperror.NewInternal("failed to encode passkey session")
+	}
+	if err := s.cache.Set(ctx, webauthnSessionPrefix+sessionID, data, webauthnSessionTTL); err != nil {
+		return "", apperror.NewInternal("failed to store passkey session")
+	}
+	return sessionID, nil
+}
+
+// takeSession loads and deletes ceremony session data so each session can be used only once.
+func (s *webAuthnService) takeSession(ctx context.Context, sessionID string) (*webauthn.SessionData, error) {
+	key := webauthnSessionPrefix + sessionID
+	data, err := s.cache.Get(ctx, key)
+	if err != nil {
+		return nil, apperror.NewInternal("failed to load passkey session")
+	}
+	if data == nil {
+		return nil, apperror.NewBadRequest("invalid or expired passkey session")
+	}
+	_ = s.cache.Delete(ctx, key)
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, apperror.NewBadRequest("invalid or expired passkey session")
+	}
+	return &session, nil
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/internal/sqlc"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+func newTestWebAuthnService(t *testing.T, repo *mockUserRepo, appCache *mockCache) WebAuthnService {
+	t.Helper()
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          "localhost",
+		RPDisplayName: "Test App",
+		RPOrigins:     []string{"http://localhost:3000"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create webauthn: %v", err)
+	}
+	return NewWebAuthnService(wa, repo, newMockWebAuthnCredentialRepo(), appCache, openSettings)
+}
+
+// ---------------------------------------------------------------------------
+// BeginRegistration
+// ---------------------------------------------------------------------------
+
+func TestWebAuthnBeginRegistration(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		repo := newMockUserRepo()
+		repo.users[1] = &sqlc.User{ID: 1, Email: "test@example.com", Name: "Test User", Role: "user"}
+		appCache := newMockCache()
+		svc := newTestWebAuthnService(t, repo, appCache)
+
+		options, sessionID, err := svc.BeginRegistration(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if sessionID == "" {
+			t.Fatal("expected session id")
+		}
+		if options.Response.User.Name != "test@example.com" {
+			t.Errorf("expected user name test@example.com, got %s", options.Response.User.Name)
+		}
+		if _, ok := appCache.items[webauthnSessionPrefix+sessionID]; !ok {
+			t.Error("expected session data to be cached")
+		}
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		svc := newTestWebAuthnService(t, newMockUserRepo(), newMockCache())
+
+		_, _, err := svc.BeginRegistration(context.Background(), 999)
+		var appErr *apperror.AppError
+		if !errors.As(err, &appErr) || appErr.Code != http.StatusNotFound {
+			t.Fatalf("expected 404 AppError, got %v", err)
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// FinishRegistration
+// ---------------------------------------------------------------------------
+
+func TestWebAuthnFinishRegistration(t *testing.T) {
+	t.Run("unknown session", func(t *testing.T) {
+		repo := newMockUserRepo()
+		repo.users[1] = &sqlc.User{ID: 1, Email: "test@example.com", Name: "Test User", Role: "user"}
+		svc := newTestWebAuthnService(t, repo, newMockCache())
+
+		err := svc.FinishRegistration(context.Background(), 1, "missing", "", []byte(`{}`))
+		var appErr *apperror.AppError
+		if !errors.As(err, &appErr) || appErr.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 AppError, got %v", err)
+		}
+	})
+
+	t.Run("session belongs to another user", func(t *testing.T) {
+		repo := newMockUserRepo()
+		repo.users[1] = &sqlc.User{ID: 1, Email: "a@example.com", Name: "User A", Role: "user"}
+		repo.users[2] = &sqlc.User{ID: 2, Email: "b@example.com", Name: "User B", Role: "user"}
+		appCache := newMockCache()
+		svc := newTestWebAuthnService(t, repo, appCache)
+
+		_, sessionID, err := svc.BeginRegistration(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("begin registration: %v", err)
+		}
+
+		err = svc.FinishRegistration(context.Background(), 2, sessionID, "", []byte(`{}`))
+		var appErr *apperror.AppError
+		if !errors.As(err, &appErr) || appErr.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 AppError, got %v", err)
+		}
+		if _, ok := appCache.items[webauthnSessionPrefix+sessionID]; ok {
+			t.Error("expected session to be consumed")
+		}
+	})
+}
+
+// ---------------------------------------------------------------------------
+// Login
+// ---------------------------------------------------------------------------
+
+func TestWebAuthnLogin(t *testing.T) {
+	t.Run("begin stores session", func(t *testing.T) {
+		appCache := newMockCache()
+		svc := newTestWebAuthnService(t, newMockUserRepo(), appCache)
+
+		options, sessionID, err := svc.BeginLogin(context.Background())
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(options.Response.Challenge) == 0 {
+			t.Error("expected challenge")
+		}
+		if _, ok := appCache.items[webauthnSessionPrefix+sessionID]; !ok {
+			t.Error("expected session data to be cached")
+		}
+	})
+
+	t.Run("invalid credential", func(t *testing.T) {
+		svc := newTestWebAuthnService(t, newMockUserRepo(), newMockCache())
+
+		_, sessionID, err := svc.BeginLogin(context.Background())
+		if err != nil {
+			t.Fatalf("begin login: %v", err)
+		}
+
+		_, err = svc.FinishLogin(context.Background(), sessionID, []byte(`{"id":"x"}`))
+		var appErr *apperror.AppError
+		if !errors.As(err, &appErr) || appErr.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 AppError, got %v", err)
+		}
+	})
+}
@@ -7,30 +7,65 @@ package sqlc
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const adminCountFiles = `-- name: AdminCountFiles :one
 SELECT count(*) FROM files
+WHERE $1::text IS NULL
+   OR EXISTS (SELECT 1 FROM file_tags WHERE file_tags.file_id = files.id AND file_tags.tag = $1::text)
 `
 
-func (q *Queries) AdminCountFiles(ctx context.Context) (int64, error) {
-	row := q.db.QueryRow(ctx, adminCountFiles)
+func (q *Queries) AdminCountFiles(ctx context.Context, tag pgtype.Text) (int64, error) {
+	row := q.db.QueryRow(ctx, adminCountFiles, tag)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
 }
 
+const adminGetFile = `-- name: AdminGetFile :one
+SELECT id, user_id, original_name, storage_path, mime_type, size, created_at, deleted_at, visibility, folder_id, checksum, version, version_created_at, encryption_key_id FROM files WHERE id = $1
+`
+
+func (q *Queries) AdminGetFile(ctx context.Context, id int64) (File, error) {
+	row := q.db.QueryRow(ctx, adminGetFile, id)
+	var i File
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.OriginalName,
+		&i.StoragePath,
+		&i.MimeType,
+		&i.Size,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.Visibility,
+		&i.FolderID,
+		&i.Checksum,
+		&i.Version,
+		&i.VersionCreatedAt,
+		&i.EncryptionKeyID,
+	)
+	return i, err
+}
+
 const adminListFiles = `-- name: AdminListFiles :many
-SELECT id, user_id, original_name, storage_path, mime_type, size, created_at, deleted_at FROM files ORDER BY id DESC LIMIT $1 OFFSET $2
+SELECT id, user_id, original_name, storage_path, mime_type, size, created_at, deleted_at, visibility, folder_id, checksum, version, version_created_at, encryption_key_id FROM files
+WHERE $1::text IS NULL
+   OR EXISTS (SELECT 1 FROM file_tags WHERE file_tags.file_id = files.id AND file_tags.tag = $1::text)
+ORDER BY id DESC
+LIMIT $3 OFFSET $2
 `
 
 type AdminListFilesParams struct {
-	Limit  int32 `json:"limit"`
-	Offset int32 `json:"offset"`
+	Tag    pgtype.Text `json:"tag"`
+	Offset int32       `json:"offset"`
+	Limit  int32       `json:"limit"`
 }
 
 func (q *Queries) AdminListFiles(ctx context.Context, arg AdminListFilesParams) ([]File, error) {
-	rows, err := q.db.Query(ctx, adminListFiles, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, adminListFiles, arg.Tag, arg.Offset, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -47,6 +82,75 @@ func (q *Queries) AdminListFiles(ctx context.Context, arg AdminListFilesParams)
 			&i.Size,
 			&i.CreatedAt,
 			&i.DeletedAt,
+			&i.Visibility,
+			&i.FolderID,
+			&i.Checksum,
+			&i.Version,
+			&i.VersionCreatedAt,
+			&i.EncryptionKeyID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const adminSearchFiles = `-- name: AdminSearchFiles :many
+SELECT f.id, f.user_id, f.original_name, f.mime_type, f.size, f.deleted_at, f.created_at,
+    u.email AS owner_email,
+    (CASE
+        WHEN f.original_name ILIKE $1 THEN 0
+        WHEN f.original_name ILIKE $1 || '%' THEN 1
+        ELSE 2
+    END)::INT AS rank
+FROM files f
+JOIN users u ON u.id = f.user_id
+WHERE f.original_name ILIKE '%' || $1 || '%'
+ORDER BY rank, f.created_at DESC, f.id DESC
+LIMIT $2
+`
+
+type AdminSearchFilesParams struct {
+	Pattern string `json:"pattern"`
+	Limit   int32  `json:"limit"`
+}
+
+type AdminSearchFilesRow struct {
+	ID           int64              `json:"id"`
+	UserID       int64              `json:"user_id"`
+	OriginalName string             `json:"original_name"`
+	MimeType     string             `json:"mime_type"`
+	Size         int64              `json:"size"`
+	DeletedAt    pgtype.Timestamptz `json:"deleted_at"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	OwnerEmail   string             `json:"owner_email"`
+	Rank         int32              `json:"rank"`
+}
+
+// Rank 0 is an exact (case-insensitive) name, 1 a prefix and 2 any other substring.
+func (q *Queries) AdminSearchFiles(ctx context.Context, arg AdminSearchFilesParams) ([]AdminSearchFilesRow, error) {
+	rows, err := q.db.Query(ctx, adminSearchFiles, arg.Pattern, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AdminSearchFilesRow{}
+	for rows.Next() {
+		var i AdminSearchFilesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.OriginalName,
+			&i.MimeType,
+			&i.Size,
+			&i.DeletedAt,
+			&i.CreatedAt,
+			&i.OwnerEmail,
+			&i.Rank,
 		); err != nil {
 			return nil, err
 		}
@@ -58,29 +162,67 @@ func (q *Queries) AdminListFiles(ctx context.Context, arg AdminListFilesParams)
 	return items, nil
 }
 
+const copyFileVariants = `-- name: CopyFileVariants :execrows
+INSERT INTO file_variants (file_id, name, storage_path, mime_type, width, height, size)
+SELECT $1::bigint, name, storage_path, mime_type, width, height, size
+FROM file_variants WHERE file_id = $2::bigint
+`
+
+type CopyFileVariantsParams struct {
+	TargetID int64 `json:"target_id"`
+	SourceID int64 `json:"source_id"`
+}
+
+// Gives target_id the variants of source_id, sharing their stored objects.
+func (q *Queries) CopyFileVariants(ctx context.Context, arg CopyFileVariantsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, copyFileVariants, arg.TargetID, arg.SourceID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const countFilesByUserID = `-- name: CountFilesByUserID :one
-SELECT count(*) FROM files WHERE user_id = $1 AND deleted_at IS NULL
+SELECT count(*) FROM files
+WHERE user_id = $1 AND deleted_at IS NULL
+  AND (NOT $2::boolean OR folder_id IS NOT DISTINCT FROM $3::bigint)
+  AND ($4::text IS NULL
+       OR EXISTS (SELECT 1 FROM file_tags WHERE file_tags.file_id = files.id AND file_tags.tag = $4::text))
 `
 
-func (q *Queries) CountFilesByUserID(ctx context.Context, userID int64) (int64, error) {
-	row := q.db.QueryRow(ctx, countFilesByUserID, userID)
+type CountFilesByUserIDParams struct {
+	UserID       int64       `json:"user_id"`
+	FilterFolder bool        `json:"filter_folder"`
+	FolderID     pgtype.Int8 `json:"folder_id"`
+	Tag          pgtype.Text `json:"tag"`
+}
+
+func (q *Queries) CountFilesByUserID(ctx context.Context, arg CountFilesByUserIDParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countFilesByUserID,
+		arg.UserID,
+		arg.FilterFolder,
+		arg.FolderID,
+		arg.Tag,
+	)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
 }
 
 const createFile = `-- name: CreateFile :one
-INSERT INTO files (user_id, original_name, storage_path, mime_type, size)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING id, user_id, original_name, storage_path, mime_type, size, created_at, deleted_at
+INSERT INTO files (user_id, original_name, storage_path, mime_type, size, checksum, encryption_key_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, user_id, original_name, storage_path, mime_type, size, created_at, deleted_at, visibility, folder_id, checksum, version, version_created_at, encryption_key_id
 `
 
 type CreateFileParams struct {
-	UserID       int64  `json:"user_id"`
-	OriginalName string `json:"original_name"`
-	StoragePath  string `json:"storage_path"`
-	MimeType     string `json:"mime_type"`
-	Size         int64  `json:"size"`
+	UserID          int64       `json:"user_id"`
+	OriginalName    string      `json:"original_name"`
+	StoragePath     string      `json:"storage_path"`
+	MimeType        string      `json:"mime_type"`
+	Size            int64       `json:"size"`
+	Checksum        pgtype.Text `json:"checksum"`
+	EncryptionKeyID pgtype.Text `json:"encryption_key_id"`
 }
 
 func (q *Queries) CreateFile(ctx context.Context, arg CreateFileParams) (File, error) {
@@ -90,6 +232,8 @@ func (q *Queries) CreateFile(ctx context.Context, arg CreateFileParams) (File, e
 		arg.StoragePath,
 		arg.MimeType,
 		arg.Size,
+		arg.Checksum,
+		arg.EncryptionKeyID,
 	)
 	var i File
 	err := row.Scan(
@@ -101,6 +245,53 @@ func (q *Queries) CreateFile(ctx context.Context, arg CreateFileParams) (File, e
 		&i.Size,
 		&i.CreatedAt,
 		&i.DeletedAt,
+		&i.Visibility,
+		&i.FolderID,
+		&i.Checksum,
+		&i.Version,
+		&i.VersionCreatedAt,
+		&i.EncryptionKeyID,
+	)
+	return i, err
+}
+
+const createFileVariant = `-- name: CreateFileVariant :one
+INSERT INTO file_variants (file_id, name, storage_path, mime_type, width, height, size)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, file_id, name, storage_path, mime_type, width, height, size, created_at
+`
+
+type CreateFileVariantParams struct {
+	FileID      int64  `json:"file_id"`
+	Name        string `json:"name"`
+	StoragePath string `json:"storage_path"`
+	MimeType    string `json:"mime_type"`
+	Width       int32  `json:"width"`
+	Height      int32  `json:"height"`
+	Size        int64  `json:"size"`
+}
+
+func (q *Queries) CreateFileVariant(ctx context.Context, arg CreateFileVariantParams) (FileVariant, error) {
+	row := q.db.QueryRow(ctx, createFileVariant,
+		arg.FileID,
+		arg.Name,
+		arg.StoragePath,
+		arg.MimeType,
+		arg.Width,
+		arg.Height,
+		arg.Size,
+	)
+	var i FileVariant
+	err := row.Scan(
+		&i.ID,
+		&i.FileID,
+		&i.Name,
+		&i.StoragePath,
+		&i.MimeType,
+		&i.Width,
+		&i.Height,
+		&i.Size,
+		&i.CreatedAt,
 	)
 	return i, err
 }
@@ -108,7 +299,7 @@ func (q *Queries) CreateFile(ctx context.Context, arg CreateFileParams) (File, e
 const deleteFile = `-- name: DeleteFile :one
 UPDATE files SET deleted_at = NOW()
 WHERE id = $1 AND deleted_at IS NULL
-RETURNING id, user_id, original_name, storage_path, mime_type, size, created_at, deleted_at
+RETURNING id, user_id, original_name, storage_path, mime_type, size, created_at, deleted_at, visibility, folder_id, checksum, version, version_created_at, encryption_key_id
 `
 
 func (q *Queries) DeleteFile(ctx context.Context, id int64) (File, error) {
@@ -123,12 +314,114 @@ func (q *Queries) DeleteFile(ctx context.Context, id int64) (File, error) {
 		&i.Size,
 		&i.CreatedAt,
 		&i.DeletedAt,
+		&i.Visibility,
+		&i.FolderID,
+		&i.Checksum,
+		&i.Version,
+		&i.VersionCreatedAt,
+		&i.EncryptionKeyID,
+	)
+	return i, err
+}
+
+const deleteFileVariants = `-- name: DeleteFileVariants :many
+WITH removed AS (
+    DELETE FROM file_variants WHERE file_variants.file_id = $1 RETURNING storage_path
+)
+SELECT r.storage_path FROM removed r
+WHERE NOT EXISTS (
+    SELECT 1 FROM file_variants v WHERE v.storage_path = r.storage_path AND v.file_id <> $1
+)
+`
+
+// Removes the file's variants and returns the paths no other file's variants share.
+func (q *Queries) DeleteFileVariants(ctx context.Context, fileID int64) ([]string, error) {
+	rows, err := q.db.Query(ctx, deleteFileVariants, fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var storage_path string
+		if err := rows.Scan(&storage_path); err != nil {
+			return nil, err
+		}
+		items = append(items, storage_path)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAnyFileByChecksum = `-- name: GetAnyFileByChecksum :one
+SELECT id, user_id, original_name, storage_path, mime_type, size, created_at, deleted_at, visibility, folder_id, checksum, version, version_created_at, encryption_key_id FROM files
+WHERE checksum = $1 AND deleted_at IS NULL
+ORDER BY id
+LIMIT 1
+`
+
+// Finds the oldest file of any user with the given content, for the content-addressed layout.
+func (q *Queries) GetAnyFileByChecksum(ctx context.Context, checksum pgtype.Text) (File, error) {
+	row := q.db.QueryRow(ctx, getAnyFileByChecksum, checksum)
+	var i File
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.OriginalName,
+		&i.StoragePath,
+		&i.MimeType,
+		&i.Size,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.Visibility,
+		&i.FolderID,
+		&i.Checksum,
+		&i.Version,
+		&i.VersionCreatedAt,
+		&i.EncryptionKeyID,
+	)
+	return i, err
+}
+
+const getFileByChecksum = `-- name: GetFileByChecksum :one
+SELECT id, user_id, original_name, storage_path, mime_type, size, created_at, deleted_at, visibility, folder_id, checksum, version, version_created_at, encryption_key_id FROM files
+WHERE user_id = $1 AND checksum = $2 AND deleted_at IS NULL
+ORDER BY id
+LIMIT 1
+`
+
+type GetFileByChecksumParams struct {
+	UserID   int64       `json:"user_id"`
+	Checksum pgtype.Text `json:"checksum"`
+}
+
+// Finds the user's oldest file with the given content, to reuse its stored object.
+func (q *Queries) GetFileByChecksum(ctx context.Context, arg GetFileByChecksumParams) (File, error) {
+	row := q.db.QueryRow(ctx, getFileByChecksum, arg.UserID, arg.Checksum)
+	var i File
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.OriginalName,
+		&i.StoragePath,
+		&i.MimeType,
+		&i.Size,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.Visibility,
+		&i.FolderID,
+		&i.Checksum,
+		&i.Version,
+		&i.VersionCreatedAt,
+		&i.EncryptionKeyID,
 	)
 	return i, err
 }
 
 const getFileByID = `-- name: GetFileByID :one
-SELECT id, user_id, original_name, storage_path, mime_type, size, created_at, deleted_at FROM files WHERE id = $1 AND deleted_at IS NULL
+SELECT id, user_id, original_name, storage_path, mime_type, size, created_at, deleted_at, visibility, folder_id, checksum, version, version_created_at, encryption_key_id FROM files WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) GetFileByID(ctx context.Context, id int64) (File, error) {
@@ -143,22 +436,91 @@ func (q *Queries) GetFileByID(ctx context.Context, id int64) (File, error) {
 		&i.Size,
 		&i.CreatedAt,
 		&i.DeletedAt,
+		&i.Visibility,
+		&i.FolderID,
+		&i.Checksum,
+		&i.Version,
+		&i.VersionCreatedAt,
+		&i.EncryptionKeyID,
 	)
 	return i, err
 }
 
-const listFilesByUserID = `-- name: ListFilesByUserID :many
-SELECT id, user_id, original_name, storage_path, mime_type, size, created_at, deleted_at FROM files WHERE user_id = $1 AND deleted_at IS NULL ORDER BY id DESC LIMIT $2 OFFSET $3
+const getFileVersion = `-- name: GetFileVersion :one
+SELECT id, file_id, version, storage_path, mime_type, size, checksum, created_at, encryption_key_id FROM file_versions WHERE file_id = $1 AND version = $2
 `
 
-type ListFilesByUserIDParams struct {
+type GetFileVersionParams struct {
+	FileID  int64 `json:"file_id"`
+	Version int32 `json:"version"`
+}
+
+func (q *Queries) GetFileVersion(ctx context.Context, arg GetFileVersionParams) (FileVersion, error) {
+	row := q.db.QueryRow(ctx, getFileVersion, arg.FileID, arg.Version)
+	var i FileVersion
+	err := row.Scan(
+		&i.ID,
+		&i.FileID,
+		&i.Version,
+		&i.StoragePath,
+		&i.MimeType,
+		&i.Size,
+		&i.Checksum,
+		&i.CreatedAt,
+		&i.EncryptionKeyID,
+	)
+	return i, err
+}
+
+const grantFilePermission = `-- name: GrantFilePermission :one
+INSERT INTO file_permissions (file_id, user_id, granted_by)
+SELECT $1, u.id, $2 FROM users u
+WHERE u.id = $3 AND u.deleted_at IS NULL
+ON CONFLICT (file_id, user_id) DO UPDATE SET file_id = EXCLUDED.file_id
+RETURNING file_id, user_id, granted_by, created_at
+`
+
+type GrantFilePermissionParams struct {
+	FileID    int64       `json:"file_id"`
+	GrantedBy pgtype.Int8 `json:"granted_by"`
+	UserID    int64       `json:"user_id"`
+}
+
+// Only active users can be granted access; granting again keeps the original grant.
+func (q *Queries) GrantFilePermission(ctx context.Context, arg GrantFilePermissionParams) (FilePermission, error) {
+	row := q.db.QueryRow(ctx, grantFilePermission, arg.FileID, arg.GrantedBy, arg.UserID)
+	var i FilePermission
+	err := row.Scan(
+		&i.FileID,
+		&i.UserID,
+		&i.GrantedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const hasFilePermission = `-- name: HasFilePermission :one
+SELECT EXISTS (SELECT 1 FROM file_permissions WHERE file_id = $1 AND user_id = $2)
+`
+
+type HasFilePermissionParams struct {
+	FileID int64 `json:"file_id"`
 	UserID int64 `json:"user_id"`
-	Limit  int32 `json:"limit"`
-	Offset int32 `json:"offset"`
 }
 
-func (q *Queries) ListFilesByUserID(ctx context.Context, arg ListFilesByUserIDParams) ([]File, error) {
-	rows, err := q.db.Query(ctx, listFilesByUserID, arg.UserID, arg.Limit, arg.Offset)
+func (q *Queries) HasFilePermission(ctx context.Context, arg HasFilePermissionParams) (bool, error) {
+	row := q.db.QueryRow(ctx, hasFilePermission, arg.FileID, arg.UserID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const listAllFilesByUserID = `-- name: ListAllFilesByUserID :many
+SELECT id, user_id, original_name, storage_path, mime_type, size, created_at, deleted_at, visibility, folder_id, checksum, version, version_created_at, encryption_key_id FROM files WHERE user_id = $1 ORDER BY id
+`
+
+func (q *Queries) ListAllFilesByUserID(ctx context.Context, userID int64) ([]File, error) {
+	rows, err := q.db.Query(ctx, listAllFilesByUserID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -175,6 +537,12 @@ func (q *Queries) ListFilesByUserID(ctx context.Context, arg ListFilesByUserIDPa
 			&i.Size,
 			&i.CreatedAt,
 			&i.DeletedAt,
+			&i.Visibility,
+			&i.FolderID,
+			&i.Checksum,
+			&i.Version,
+			&i.VersionCreatedAt,
+			&i.EncryptionKeyID,
 		); err != nil {
 			return nil, err
 		}
@@ -186,14 +554,874 @@ func (q *Queries) ListFilesByUserID(ctx context.Context, arg ListFilesByUserIDPa
 	return items, nil
 }
 
-const restoreFile = `-- name: RestoreFile :one
-UPDATE files SET deleted_at = NULL
-WHERE id = $1 AND deleted_at IS NOT NULL
-RETURNING id, user_id, original_name, storage_path, mime_type, size, created_at, deleted_at
+const listFileIDsDeletedBefore = `-- name: ListFileIDsDeletedBefore :many
+SELECT id FROM files
+WHERE deleted_at IS NOT NULL AND deleted_at < $1
+ORDER BY deleted_at
+LIMIT $2
+`
+
+type ListFileIDsDeletedBeforeParams struct {
+	DeletedAt pgtype.Timestamptz `json:"deleted_at"`
+	Limit     int32              `json:"limit"`
+}
+
+func (q *Queries) ListFileIDsDeletedBefore(ctx context.Context, arg ListFileIDsDeletedBeforeParams) ([]int64, error) {
+	rows, err := q.db.Query(ctx, listFileIDsDeletedBefore, arg.DeletedAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFilePermissions = `-- name: ListFilePermissions :many
+SELECT p.file_id, p.user_id, p.granted_by, p.created_at, u.email, u.name
+FROM file_permissions p
+JOIN users u ON u.id = p.user_id
+WHERE p.file_id = $1
+ORDER BY p.created_at, p.user_id
+`
+
+type ListFilePermissionsRow struct {
+	FileID    int64              `json:"file_id"`
+	UserID    int64              `json:"user_id"`
+	GrantedBy pgtype.Int8        `json:"granted_by"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	Email     string             `json:"email"`
+	Name      string             `json:"name"`
+}
+
+func (q *Queries) ListFilePermissions(ctx context.Context, fileID int64) ([]ListFilePermissionsRow, error) {
+	rows, err := q.db.Query(ctx, listFilePermissions, fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListFilePermissionsRow{}
+	for rows.Next() {
+		var i ListFilePermissionsRow
+		if err := rows.Scan(
+			&i.FileID,
+			&i.UserID,
+			&i.GrantedBy,
+			&i.CreatedAt,
+			&i.Email,
+			&i.Name,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFileTagsByFileIDs = `-- name: ListFileTagsByFileIDs :many
+SELECT file_id, tag, created_at FROM file_tags WHERE file_id = ANY($1::bigint[]) ORDER BY file_id, tag
+`
+
+func (q *Queries) ListFileTagsByFileIDs(ctx context.Context, fileIds []int64) ([]FileTag, error) {
+	rows, err := q.db.Query(ctx, listFileTagsByFileIDs, fileIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []FileTag{}
+	for rows.Next() {
+		var i FileTag
+		if err := rows.Scan(&i.FileID, &i.Tag, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFileVariantPathsByUserID = `-- name: ListFileVariantPathsByUserID :many
+SELECT v.storage_path FROM file_variants v
+JOIN files f ON f.id = v.file_id
+WHERE f.user_id = $1
 `
 
-func (q *Queries) RestoreFile(ctx context.Context, id int64) (File, error) {
-	row := q.db.QueryRow(ctx, restoreFile, id)
+func (q *Queries) ListFileVariantPathsByUserID(ctx context.Context, userID int64) ([]string, error) {
+	rows, err := q.db.Query(ctx, listFileVariantPathsByUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var storage_path string
+		if err := rows.Scan(&storage_path); err != nil {
+			return nil, err
+		}
+		items = append(items, storage_path)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFileVariantsByFileIDs = `-- name: ListFileVariantsByFileIDs :many
+SELECT id, file_id, name, storage_path, mime_type, width, height, size, created_at FROM file_variants WHERE file_id = ANY($1::bigint[]) ORDER BY file_id, id
+`
+
+func (q *Queries) ListFileVariantsByFileIDs(ctx context.Context, fileIds []int64) ([]FileVariant, error) {
+	rows, err := q.db.Query(ctx, listFileVariantsByFileIDs, fileIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []FileVariant{}
+	for rows.Next() {
+		var i FileVariant
+		if err := rows.Scan(
+			&i.ID,
+			&i.FileID,
+			&i.Name,
+			&i.StoragePath,
+			&i.MimeType,
+			&i.Width,
+			&i.Height,
+			&i.Size,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFileVersionPathsByUserID = `-- name: ListFileVersionPathsByUserID :many
+SELECT v.storage_path FROM file_versions v
+JOIN files f ON f.id = v.file_id
+WHERE f.user_id = $1
+`
+
+func (q *Queries) ListFileVersionPathsByUserID(ctx context.Context, userID int64) ([]string, error) {
+	rows, err := q.db.Query(ctx, listFileVersionPathsByUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var storage_path string
+		if err := rows.Scan(&storage_path); err != nil {
+			return nil, err
+		}
+		items = append(items, storage_path)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFileVersions = `-- name: ListFileVersions :many
+SELECT id, file_id, version, storage_path, mime_type, size, checksum, created_at, encryption_key_id FROM file_versions WHERE file_id = $1 ORDER BY version DESC
+`
+
+func (q *Queries) ListFileVersions(ctx context.Context, fileID int64) ([]FileVersion, error) {
+	rows, err := q.db.Query(ctx, listFileVersions, fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []FileVersion{}
+	for rows.Next() {
+		var i FileVersion
+		if err := rows.Scan(
+			&i.ID,
+			&i.FileID,
+			&i.Version,
+			&i.StoragePath,
+			&i.MimeType,
+			&i.Size,
+			&i.Checksum,
+			&i.CreatedAt,
+			&i.EncryptionKeyID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFilesByIDs = `-- name: ListFilesByIDs :many
+SELECT id, user_id, original_name, storage_path, mime_type, size, created_at, deleted_at, visibility, folder_id, checksum, version, version_created_at, encryption_key_id FROM files WHERE id = ANY($1::bigint[]) AND deleted_at IS NULL ORDER BY id
+`
+
+func (q *Queries) ListFilesByIDs(ctx context.Context, ids []int64) ([]File, error) {
+	rows, err := q.db.Query(ctx, listFilesByIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []File{}
+	for rows.Next() {
+		var i File
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.OriginalName,
+			&i.StoragePath,
+			&i.MimeType,
+			&i.Size,
+			&i.CreatedAt,
+			&i.DeletedAt,
+			&i.Visibility,
+			&i.FolderID,
+			&i.Checksum,
+			&i.Version,
+			&i.VersionCreatedAt,
+			&i.EncryptionKeyID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFilesByUserID = `-- name: ListFilesByUserID :many
+SELECT id, user_id, original_name, storage_path, mime_type, size, created_at, deleted_at, visibility, folder_id, checksum, version, version_created_at, encryption_key_id FROM files
+WHERE user_id = $1 AND deleted_at IS NULL
+  AND (NOT $2::boolean OR folder_id IS NOT DISTINCT FROM $3::bigint)
+  AND ($4::text IS NULL
+       OR EXISTS (SELECT 1 FROM file_tags WHERE file_tags.file_id = files.id AND file_tags.tag = $4::text))
+ORDER BY id DESC
+LIMIT $6 OFFSET $5
+`
+
+type ListFilesByUserIDParams struct {
+	UserID       int64       `json:"user_id"`
+	FilterFolder bool        `json:"filter_folder"`
+	FolderID     pgtype.Int8 `json:"folder_id"`
+	Tag          pgtype.Text `json:"tag"`
+	Offset       int32       `json:"offset"`
+	Limit        int32       `json:"limit"`
+}
+
+// With filter_folder set, only files in folder_id are listed (NULL: files in no folder).
+// A non-NULL tag lists only the files carrying it.
+func (q *Queries) ListFilesByUserID(ctx context.Context, arg ListFilesByUserIDParams) ([]File, error) {
+	rows, err := q.db.Query(ctx, listFilesByUserID,
+		arg.UserID,
+		arg.FilterFolder,
+		arg.FolderID,
+		arg.Tag,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []File{}
+	for rows.Next() {
+		var i File
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.OriginalName,
+			&i.StoragePath,
+			&i.MimeType,
+			&i.Size,
+			&i.CreatedAt,
+			&i.DeletedAt,
+			&i.Visibility,
+			&i.FolderID,
+			&i.Checksum,
+			&i.Version,
+			&i.VersionCreatedAt,
+			&i.EncryptionKeyID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listStoragePathsSharedWithOthers = `-- name: ListStoragePathsSharedWithOthers :many
+SELECT DISTINCT p.path::text FROM unnest($1::text[]) AS p(path)
+WHERE EXISTS (SELECT 1 FROM files f WHERE f.storage_path = p.path AND f.user_id <> $2)
+   OR EXISTS (SELECT 1 FROM file_versions fv JOIN files f ON f.id = fv.file_id
+              WHERE fv.storage_path = p.path AND f.user_id <> $2)
+   OR EXISTS (SELECT 1 FROM file_variants v JOIN files f ON f.id = v.file_id
+              WHERE v.storage_path = p.path AND f.user_id <> $2)
+`
+
+type ListStoragePathsSharedWithOthersParams struct {
+	Paths  []string `json:"paths"`
+	UserID int64    `json:"user_id"`
+}
+
+// Returns which of the given storage paths files, versions or variants of other users refer
+// to; the content-addressed layout stores identical content once for every user.
+func (q *Queries) ListStoragePathsSharedWithOthers(ctx context.Context, arg ListStoragePathsSharedWithOthersParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, listStoragePathsSharedWithOthers, arg.Paths, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var p_path string
+		if err := rows.Scan(&p_path); err != nil {
+			return nil, err
+		}
+		items = append(items, p_path)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listStorageReferences = `-- name: ListStorageReferences :many
+SELECT 'file'::text AS kind, id AS owner_id, storage_path FROM files
+UNION ALL
+SELECT 'file_version', file_id, storage_path FROM file_versions
+UNION ALL
+SELECT 'file_variant', file_id, storage_path FROM file_variants
+UNION ALL
+SELECT 'upload_chunk', session_id, storage_path FROM upload_session_parts
+UNION ALL
+SELECT 'data_export', id, storage_path FROM data_exports WHERE storage_path IS NOT NULL
+`
+
+type ListStorageReferencesRow struct {
+	Kind        string `json:"kind"`
+	OwnerID     int64  `json:"owner_id"`
+	StoragePath string `json:"storage_path"`
+}
+
+// Every storage path a row refers to, with the row's kind and ID: the file for files,
+// versions and variants, the session for upload chunks and the export for data exports.
+func (q *Queries) ListStorageReferences(ctx context.Context) ([]ListStorageReferencesRow, error) {
+	rows, err := q.db.Query(ctx, listStorageReferences)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListStorageReferencesRow{}
+	for rows.Next() {
+		var i ListStorageReferencesRow
+		if err := rows.Scan(&i.Kind, &i.OwnerID, &i.StoragePath); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listStoredObjectFiles = `-- name: ListStoredObjectFiles :many
+SELECT f.id, f.user_id, f.visibility, f.mime_type, f.size FROM files f
+WHERE f.storage_path = $1 AND f.deleted_at IS NULL
+UNION ALL
+SELECT f.id, f.user_id, f.visibility, fv.mime_type, fv.size FROM file_versions fv
+JOIN files f ON f.id = fv.file_id
+WHERE fv.storage_path = $1 AND f.deleted_at IS NULL
+UNION ALL
+SELECT f.id, f.user_id, f.visibility, v.mime_type, v.size FROM file_variants v
+JOIN files f ON f.id = v.file_id
+WHERE v.storage_path = $1 AND f.deleted_at IS NULL
+`
+
+type ListStoredObjectFilesRow struct {
+	ID         int64  `json:"id"`
+	UserID     int64  `json:"user_id"`
+	Visibility string `json:"visibility"`
+	MimeType   string `json:"mime_type"`
+	Size       int64  `json:"size"`
+}
+
+// Lists the live files whose current content, archived versions or image variants are stored
+// at the given path, with the type and size of the object as recorded for that row.
+func (q *Queries) ListStoredObjectFiles(ctx context.Context, storagePath string) ([]ListStoredObjectFilesRow, error) {
+	rows, err := q.db.Query(ctx, listStoredObjectFiles, storagePath)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListStoredObjectFilesRow{}
+	for rows.Next() {
+		var i ListStoredObjectFilesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Visibility,
+			&i.MimeType,
+			&i.Size,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listStoredObjects = `-- name: ListStoredObjects :many
+SELECT 'file'::text AS kind, storage_path, mime_type, size, checksum FROM files
+UNION ALL
+SELECT 'file_version', storage_path, mime_type, size, checksum FROM file_versions
+UNION ALL
+SELECT 'file_variant', storage_path, mime_type, size, NULL FROM file_variants
+ORDER BY storage_path
+`
+
+type ListStoredObjectsRow struct {
+	Kind        string      `json:"kind"`
+	StoragePath string      `json:"storage_path"`
+	MimeType    string      `json:"mime_type"`
+	Size        int64       `json:"size"`
+	Checksum    pgtype.Text `json:"checksum"`
+}
+
+// The stored objects of files, including deleted ones, their archived versions and their
+// variants, with what is needed to copy them to another storage. The checksum is NULL
+// for variants and for files stored before checksums were recorded.
+func (q *Queries) ListStoredObjects(ctx context.Context) ([]ListStoredObjectsRow, error) {
+	rows, err := q.db.Query(ctx, listStoredObjects)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListStoredObjectsRow{}
+	for rows.Next() {
+		var i ListStoredObjectsRow
+		if err := rows.Scan(
+			&i.Kind,
+			&i.StoragePath,
+			&i.MimeType,
+			&i.Size,
+			&i.Checksum,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const moveFile = `-- name: MoveFile :one
+UPDATE files SET folder_id = $1::bigint
+WHERE files.id = $2 AND files.deleted_at IS NULL
+  AND ($1::bigint IS NULL
+       OR EXISTS (SELECT 1 FROM folders WHERE folders.id = $1::bigint AND folders.user_id = files.user_id))
+RETURNING id, user_id, original_name, storage_path, mime_type, size, created_at, deleted_at, visibility, folder_id, checksum, version, version_created_at, encryption_key_id
+`
+
+type MoveFileParams struct {
+	FolderID pgtype.Int8 `json:"folder_id"`
+	ID       int64       `json:"id"`
+}
+
+// The target folder must belong to the file's owner; NULL moves the file out of folders.
+func (q *Queries) MoveFile(ctx context.Context, arg MoveFileParams) (File, error) {
+	row := q.db.QueryRow(ctx, moveFile, arg.FolderID, arg.ID)
+	var i File
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.OriginalName,
+		&i.StoragePath,
+		&i.MimeType,
+		&i.Size,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.Visibility,
+		&i.FolderID,
+		&i.Checksum,
+		&i.Version,
+		&i.VersionCreatedAt,
+		&i.EncryptionKeyID,
+	)
+	return i, err
+}
+
+const moveFileObject = `-- name: MoveFileObject :execrows
+UPDATE files SET storage_path = $1, encryption_key_id = $2
+WHERE storage_path = $3
+`
+
+type MoveFileObjectParams struct {
+	NewPath         string      `json:"new_path"`
+	EncryptionKeyID pgtype.Text `json:"encryption_key_id"`
+	OldPath         string      `json:"old_path"`
+}
+
+func (q *Queries) MoveFileObject(ctx context.Context, arg MoveFileObjectParams) (int64, error) {
+	result, err := q.db.Exec(ctx, moveFileObject, arg.NewPath, arg.EncryptionKeyID, arg.OldPath)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const moveFileVariantObject = `-- name: MoveFileVariantObject :execrows
+UPDATE file_variants SET storage_path = $1
+WHERE storage_path = $2
+`
+
+type MoveFileVariantObjectParams struct {
+	NewPath string `json:"new_path"`
+	OldPath string `json:"old_path"`
+}
+
+func (q *Queries) MoveFileVariantObject(ctx context.Context, arg MoveFileVariantObjectParams) (int64, error) {
+	result, err := q.db.Exec(ctx, moveFileVariantObject, arg.NewPath, arg.OldPath)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const moveFileVersionObject = `-- name: MoveFileVersionObject :execrows
+UPDATE file_versions SET storage_path = $1, encryption_key_id = $2
+WHERE storage_path = $3
+`
+
+type MoveFileVersionObjectParams struct {
+	NewPath         string      `json:"new_path"`
+	EncryptionKeyID pgtype.Text `json:"encryption_key_id"`
+	OldPath         string      `json:"old_path"`
+}
+
+func (q *Queries) MoveFileVersionObject(ctx context.Context, arg MoveFileVersionObjectParams) (int64, error) {
+	result, err := q.db.Exec(ctx, moveFileVersionObject, arg.NewPath, arg.EncryptionKeyID, arg.OldPath)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const purgeDeletedFile = `-- name: PurgeDeletedFile :many
+WITH removed AS (
+    DELETE FROM files WHERE files.id = $1 AND files.deleted_at < $2
+    RETURNING files.id, files.storage_path
+), paths AS (
+    SELECT r.storage_path FROM removed r
+    UNION
+    SELECT fv.storage_path FROM file_versions fv WHERE fv.file_id IN (SELECT r.id FROM removed r)
+    UNION
+    SELECT v.storage_path FROM file_variants v WHERE v.file_id IN (SELECT r.id FROM removed r)
+)
+SELECT p.storage_path FROM paths p
+WHERE NOT EXISTS (SELECT 1 FROM files f WHERE f.storage_path = p.storage_path AND f.id <> $1)
+  AND NOT EXISTS (SELECT 1 FROM file_versions fv WHERE fv.storage_path = p.storage_path AND fv.file_id <> $1)
+  AND NOT EXISTS (SELECT 1 FROM file_variants v WHERE v.storage_path = p.storage_path AND v.file_id <> $1)
+`
+
+type PurgeDeletedFileParams struct {
+	ID        int64              `json:"id"`
+	DeletedAt pgtype.Timestamptz `json:"deleted_at"`
+}
+
+// Permanently removes a file soft-deleted before $2 with its versions and variants, and returns the
+// storage paths no other file, version or variant refers to. The subqueries still see the
+// removed rows, so they are excluded by file ID.
+func (q *Queries) PurgeDeletedFile(ctx context.Context, arg PurgeDeletedFileParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, purgeDeletedFile, arg.ID, arg.DeletedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var storage_path string
+		if err := rows.Scan(&storage_path); err != nil {
+			return nil, err
+		}
+		items = append(items, storage_path)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeFile = `-- name: PurgeFile :many
+WITH removed AS (
+    DELETE FROM files WHERE files.id = $1
+    RETURNING files.id, files.storage_path
+), paths AS (
+    SELECT r.storage_path FROM removed r
+    UNION
+    SELECT fv.storage_path FROM file_versions fv WHERE fv.file_id IN (SELECT r.id FROM removed r)
+    UNION
+    SELECT v.storage_path FROM file_variants v WHERE v.file_id IN (SELECT r.id FROM removed r)
+)
+SELECT p.storage_path FROM paths p
+WHERE NOT EXISTS (SELECT 1 FROM files f WHERE f.storage_path = p.storage_path AND f.id <> $1)
+  AND NOT EXISTS (SELECT 1 FROM file_versions fv WHERE fv.storage_path = p.storage_path AND fv.file_id <> $1)
+  AND NOT EXISTS (SELECT 1 FROM file_variants v WHERE v.storage_path = p.storage_path AND v.file_id <> $1)
+`
+
+// Permanently removes a file, deleted or not, like PurgeDeletedFile.
+func (q *Queries) PurgeFile(ctx context.Context, id int64) ([]string, error) {
+	rows, err := q.db.Query(ctx, purgeFile, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var storage_path string
+		if err := rows.Scan(&storage_path); err != nil {
+			return nil, err
+		}
+		items = append(items, storage_path)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeFilesByUserID = `-- name: PurgeFilesByUserID :many
+DELETE FROM files WHERE user_id = $1
+RETURNING storage_path
+`
+
+func (q *Queries) PurgeFilesByUserID(ctx context.Context, userID int64) ([]string, error) {
+	rows, err := q.db.Query(ctx, purgeFilesByUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var storage_path string
+		if err := rows.Scan(&storage_path); err != nil {
+			return nil, err
+		}
+		items = append(items, storage_path)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const renameFile = `-- name: RenameFile :one
+UPDATE files SET original_name = $2
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, user_id, original_name, storage_path, mime_type, size, created_at, deleted_at, visibility, folder_id, checksum, version, version_created_at, encryption_key_id
+`
+
+type RenameFileParams struct {
+	ID           int64  `json:"id"`
+	OriginalName string `json:"original_name"`
+}
+
+func (q *Queries) RenameFile(ctx context.Context, arg RenameFileParams) (File, error) {
+	row := q.db.QueryRow(ctx, renameFile, arg.ID, arg.OriginalName)
+	var i File
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.OriginalName,
+		&i.StoragePath,
+		&i.MimeType,
+		&i.Size,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.Visibility,
+		&i.FolderID,
+		&i.Checksum,
+		&i.Version,
+		&i.VersionCreatedAt,
+		&i.EncryptionKeyID,
+	)
+	return i, err
+}
+
+const replaceFileContent = `-- name: ReplaceFileContent :one
+WITH archived AS (
+    INSERT INTO file_versions (file_id, version, storage_path, mime_type, size, checksum, encryption_key_id, created_at)
+    SELECT f.id, f.version, f.storage_path, f.mime_type, f.size, f.checksum, f.encryption_key_id, f.version_created_at
+    FROM files f
+    WHERE f.id = $6 AND f.version = $7 AND f.deleted_at IS NULL
+)
+UPDATE files
+SET storage_path = $1, mime_type = $2, size = $3,
+    checksum = $4, encryption_key_id = $5,
+    version = files.version + 1, version_created_at = NOW()
+WHERE files.id = $6 AND files.version = $7 AND files.deleted_at IS NULL
+RETURNING id, user_id, original_name, storage_path, mime_type, size, created_at, deleted_at, visibility, folder_id, checksum, version, version_created_at, encryption_key_id
+`
+
+type ReplaceFileContentParams struct {
+	StoragePath     string      `json:"storage_path"`
+	MimeType        string      `json:"mime_type"`
+	Size            int64       `json:"size"`
+	Checksum        pgtype.Text `json:"checksum"`
+	EncryptionKeyID pgtype.Text `json:"encryption_key_id"`
+	ID              int64       `json:"id"`
+	Version         int32       `json:"version"`
+}
+
+// Archives the current content as a version and makes the given content the next one.
+// Nothing changes unless the file is still at the expected version.
+func (q *Queries) ReplaceFileContent(ctx context.Context, arg ReplaceFileContentParams) (File, error) {
+	row := q.db.QueryRow(ctx, replaceFileContent,
+		arg.StoragePath,
+		arg.MimeType,
+		arg.Size,
+		arg.Checksum,
+		arg.EncryptionKeyID,
+		arg.ID,
+		arg.Version,
+	)
+	var i File
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.OriginalName,
+		&i.StoragePath,
+		&i.MimeType,
+		&i.Size,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.Visibility,
+		&i.FolderID,
+		&i.Checksum,
+		&i.Version,
+		&i.VersionCreatedAt,
+		&i.EncryptionKeyID,
+	)
+	return i, err
+}
+
+const restoreFile = `-- name: RestoreFile :one
+UPDATE files SET deleted_at = NULL
+WHERE id = $1 AND deleted_at IS NOT NULL
+  AND ($2::timestamptz IS NULL OR deleted_at > $2::timestamptz)
+RETURNING id, user_id, original_name, storage_path, mime_type, size, created_at, deleted_at, visibility, folder_id, checksum, version, version_created_at, encryption_key_id
+`
+
+type RestoreFileParams struct {
+	ID           int64              `json:"id"`
+	DeletedAfter pgtype.Timestamptz `json:"deleted_after"`
+}
+
+// Undeletes a file soft-deleted after deleted_after, or at any time when it is NULL.
+func (q *Queries) RestoreFile(ctx context.Context, arg RestoreFileParams) (File, error) {
+	row := q.db.QueryRow(ctx, restoreFile, arg.ID, arg.DeletedAfter)
+	var i File
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.OriginalName,
+		&i.StoragePath,
+		&i.MimeType,
+		&i.Size,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.Visibility,
+		&i.FolderID,
+		&i.Checksum,
+		&i.Version,
+		&i.VersionCreatedAt,
+		&i.EncryptionKeyID,
+	)
+	return i, err
+}
+
+const revokeFilePermission = `-- name: RevokeFilePermission :execrows
+DELETE FROM file_permissions WHERE file_id = $1 AND user_id = $2
+`
+
+type RevokeFilePermissionParams struct {
+	FileID int64 `json:"file_id"`
+	UserID int64 `json:"user_id"`
+}
+
+func (q *Queries) RevokeFilePermission(ctx context.Context, arg RevokeFilePermissionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, revokeFilePermission, arg.FileID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setFileTags = `-- name: SetFileTags :exec
+WITH removed AS (
+    DELETE FROM file_tags WHERE file_tags.file_id = $1 AND file_tags.tag <> ALL($2::text[])
+)
+INSERT INTO file_tags (file_id, tag)
+SELECT $1, unnest($2::text[])
+ON CONFLICT DO NOTHING
+`
+
+type SetFileTagsParams struct {
+	FileID int64    `json:"file_id"`
+	Tags   []string `json:"tags"`
+}
+
+// Replaces the file's tags with the given set in one statement.
+func (q *Queries) SetFileTags(ctx context.Context, arg SetFileTagsParams) error {
+	_, err := q.db.Exec(ctx, setFileTags, arg.FileID, arg.Tags)
+	return err
+}
+
+const setFileVisibility = `-- name: SetFileVisibility :one
+UPDATE files SET visibility = $2
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, user_id, original_name, storage_path, mime_type, size, created_at, deleted_at, visibility, folder_id, checksum, version, version_created_at, encryption_key_id
+`
+
+type SetFileVisibilityParams struct {
+	ID         int64  `json:"id"`
+	Visibility string `json:"visibility"`
+}
+
+func (q *Queries) SetFileVisibility(ctx context.Context, arg SetFileVisibilityParams) (File, error) {
+	row := q.db.QueryRow(ctx, setFileVisibility, arg.ID, arg.Visibility)
 	var i File
 	err := row.Scan(
 		&i.ID,
@@ -204,6 +1432,12 @@ func (q *Queries) RestoreFile(ctx context.Context, id int64) (File, error) {
 		&i.Size,
 		&i.CreatedAt,
 		&i.DeletedAt,
+		&i.Visibility,
+		&i.FolderID,
+		&i.Checksum,
+		&i.Version,
+		&i.VersionCreatedAt,
+		&i.EncryptionKeyID,
 	)
 	return i, err
 }
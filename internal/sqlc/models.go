@@ -8,6 +8,88 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type AccountDeletionRequest struct {
+	ID           int64              `json:"id"`
+	UserID       int64              `json:"user_id"`
+	Token        string             `json:"token"`
+	ScheduledFor pgtype.Timestamptz `json:"scheduled_for"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+}
+
+type AuditLog struct {
+	ID         int64              `json:"id"`
+	ActorID    int64              `json:"actor_id"`
+	Action     string             `json:"action"`
+	TargetType string             `json:"target_type"`
+	TargetID   int64              `json:"target_id"`
+	BeforeData []byte             `json:"before_data"`
+	AfterData  []byte             `json:"after_data"`
+	IpAddress  string             `json:"ip_address"`
+	RequestID  string             `json:"request_id"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type Broadcast struct {
+	ID             int64              `json:"id"`
+	SenderID       int64              `json:"sender_id"`
+	Subject        string             `json:"subject"`
+	Body           string             `json:"body"`
+	Segment        []byte             `json:"segment"`
+	Status         string             `json:"status"`
+	RecipientCount int32              `json:"recipient_count"`
+	SentCount      int32              `json:"sent_count"`
+	FailedCount    int32              `json:"failed_count"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	CompletedAt    pgtype.Timestamptz `json:"completed_at"`
+}
+
+type DataExport struct {
+	ID          int64              `json:"id"`
+	UserID      pgtype.Int8        `json:"user_id"`
+	Status      string             `json:"status"`
+	StoragePath pgtype.Text        `json:"storage_path"`
+	Size        pgtype.Int8        `json:"size"`
+	ExpiresAt   pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	CompletedAt pgtype.Timestamptz `json:"completed_at"`
+}
+
+type EmailChangeToken struct {
+	ID        int64              `json:"id"`
+	UserID    int64              `json:"user_id"`
+	NewEmail  string             `json:"new_email"`
+	Token     string             `json:"token"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type EmailOutbox struct {
+	ID            int64              `json:"id"`
+	Recipients    []string           `json:"recipients"`
+	Subject       string             `json:"subject"`
+	Body          string             `json:"body"`
+	Html          string             `json:"html"`
+	Locale        string             `json:"locale"`
+	Status        string             `json:"status"`
+	Attempts      int32              `json:"attempts"`
+	LastError     string             `json:"last_error"`
+	NextAttemptAt pgtype.Timestamptz `json:"next_attempt_at"`
+	LockedUntil   pgtype.Timestamptz `json:"locked_until"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	SentAt        pgtype.Timestamptz `json:"sent_at"`
+	Attachments   []byte             `json:"attachments"`
+}
+
+type EmailSuppression struct {
+	ID        int64              `json:"id"`
+	Email     string             `json:"email"`
+	Reason    string             `json:"reason"`
+	Provider  string             `json:"provider"`
+	Detail    string             `json:"detail"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
 type EmailVerificationToken struct {
 	ID        int64              `json:"id"`
 	UserID    int64              `json:"user_id"`
@@ -16,15 +98,134 @@ type EmailVerificationToken struct {
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
 }
 
-type File struct {
+type ErasureAudit struct {
 	ID           int64              `json:"id"`
 	UserID       int64              `json:"user_id"`
-	OriginalName string             `json:"original_name"`
-	StoragePath  string             `json:"storage_path"`
-	MimeType     string             `json:"mime_type"`
-	Size         int64              `json:"size"`
+	ActorID      int64              `json:"actor_id"`
+	Source       string             `json:"source"`
+	FilesDeleted int32              `json:"files_deleted"`
 	CreatedAt    pgtype.Timestamptz `json:"created_at"`
-	DeletedAt    pgtype.Timestamptz `json:"deleted_at"`
+}
+
+type FeatureFlag struct {
+	ID          int64              `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Enabled     bool               `json:"enabled"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+}
+
+type File struct {
+	ID               int64              `json:"id"`
+	UserID           int64              `json:"user_id"`
+	OriginalName     string             `json:"original_name"`
+	StoragePath      string             `json:"storage_path"`
+	MimeType         string             `json:"mime_type"`
+	Size             int64              `json:"size"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	DeletedAt        pgtype.Timestamptz `json:"deleted_at"`
+	Visibility       string             `json:"visibility"`
+	FolderID         pgtype.Int8        `json:"folder_id"`
+	Checksum         pgtype.Text        `json:"checksum"`
+	Version          int32              `json:"version"`
+	VersionCreatedAt pgtype.Timestamptz `json:"version_created_at"`
+	EncryptionKeyID  pgtype.Text        `json:"encryption_key_id"`
+}
+
+type FilePermission struct {
+	FileID    int64              `json:"file_id"`
+	UserID    int64              `json:"user_id"`
+	GrantedBy pgtype.Int8        `json:"granted_by"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type FileShare struct {
+	ID            int64              `json:"id"`
+	FileID        int64              `json:"file_id"`
+	Token         string             `json:"token"`
+	PasswordHash  pgtype.Text        `json:"password_hash"`
+	MaxDownloads  pgtype.Int4        `json:"max_downloads"`
+	DownloadCount int32              `json:"download_count"`
+	ExpiresAt     pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+}
+
+type FileTag struct {
+	FileID    int64              `json:"file_id"`
+	Tag       string             `json:"tag"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type FileVariant struct {
+	ID          int64              `json:"id"`
+	FileID      int64              `json:"file_id"`
+	Name        string             `json:"name"`
+	StoragePath string             `json:"storage_path"`
+	MimeType    string             `json:"mime_type"`
+	Width       int32              `json:"width"`
+	Height      int32              `json:"height"`
+	Size        int64              `json:"size"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
+type FileVersion struct {
+	ID              int64              `json:"id"`
+	FileID          int64              `json:"file_id"`
+	Version         int32              `json:"version"`
+	StoragePath     string             `json:"storage_path"`
+	MimeType        string             `json:"mime_type"`
+	Size            int64              `json:"size"`
+	Checksum        pgtype.Text        `json:"checksum"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	EncryptionKeyID pgtype.Text        `json:"encryption_key_id"`
+}
+
+type Folder struct {
+	ID        int64              `json:"id"`
+	UserID    int64              `json:"user_id"`
+	ParentID  pgtype.Int8        `json:"parent_id"`
+	Name      string             `json:"name"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type LoginEvent struct {
+	ID            int64              `json:"id"`
+	UserID        pgtype.Int8        `json:"user_id"`
+	Email         string             `json:"email"`
+	Method        string             `json:"method"`
+	Success       bool               `json:"success"`
+	FailureReason string             `json:"failure_reason"`
+	IpAddress     string             `json:"ip_address"`
+	UserAgent     string             `json:"user_agent"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+}
+
+type Organization struct {
+	ID        int64              `json:"id"`
+	Name      string             `json:"name"`
+	Slug      string             `json:"slug"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type OrganizationInvitation struct {
+	ID             int64              `json:"id"`
+	OrganizationID int64              `json:"organization_id"`
+	Email          string             `json:"email"`
+	Role           string             `json:"role"`
+	Token          string             `json:"token"`
+	InvitedBy      pgtype.Int8        `json:"invited_by"`
+	ExpiresAt      pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+}
+
+type OrganizationMember struct {
+	OrganizationID int64              `json:"organization_id"`
+	UserID         int64              `json:"user_id"`
+	Role           string             `json:"role"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
 }
 
 type PasswordResetToken struct {
@@ -35,14 +236,82 @@ type PasswordResetToken struct {
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
 }
 
-type RefreshToken struct {
+type Permission struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type RecoveryCode struct {
 	ID        int64              `json:"id"`
 	UserID    int64              `json:"user_id"`
+	CodeHash  string             `json:"code_hash"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type RefreshToken struct {
+	ID                int64              `json:"id"`
+	UserID            int64              `json:"user_id"`
+	Token             string             `json:"token"`
+	ExpiresAt         pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt         pgtype.Timestamptz `json:"created_at"`
+	FamilyID          string             `json:"family_id"`
+	ParentID          pgtype.Int8        `json:"parent_id"`
+	RotatedAt         pgtype.Timestamptz `json:"rotated_at"`
+	DeviceFingerprint pgtype.Text        `json:"device_fingerprint"`
+}
+
+type RegistrationInvitation struct {
+	ID        int64              `json:"id"`
+	Email     string             `json:"email"`
 	Token     string             `json:"token"`
+	InvitedBy pgtype.Int8        `json:"invited_by"`
 	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
 }
 
+type Role struct {
+	ID          int64              `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	IsSystem    bool               `json:"is_system"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+}
+
+type RolePermission struct {
+	RoleID       int64 `json:"role_id"`
+	PermissionID int64 `json:"permission_id"`
+}
+
+type Setting struct {
+	ID        int64              `json:"id"`
+	Key       string             `json:"key"`
+	Value     []byte             `json:"value"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type UploadSession struct {
+	ID        int64              `json:"id"`
+	UserID    int64              `json:"user_id"`
+	Filename  string             `json:"filename"`
+	Size      int64              `json:"size"`
+	Received  int64              `json:"received"`
+	MimeType  string             `json:"mime_type"`
+	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type UploadSessionPart struct {
+	SessionID   int64  `json:"session_id"`
+	Offset      int64  `json:"offset"`
+	StoragePath string `json:"storage_path"`
+	Size        int64  `json:"size"`
+}
+
 type User struct {
 	ID              int64              `json:"id"`
 	Email           string             `json:"email"`
@@ -55,4 +324,52 @@ type User struct {
 	CreatedAt       pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
 	DeletedAt       pgtype.Timestamptz `json:"deleted_at"`
+	GithubID        pgtype.Text        `json:"github_id"`
+	SamlID          pgtype.Text        `json:"saml_id"`
+	Metadata        []byte             `json:"metadata"`
+	Username        pgtype.Text        `json:"username"`
+	BannedAt        pgtype.Timestamptz `json:"banned_at"`
+	BanReason       string             `json:"ban_reason"`
+}
+
+type UserActivity struct {
+	ID        int64              `json:"id"`
+	UserID    int64              `json:"user_id"`
+	ActorID   pgtype.Int8        `json:"actor_id"`
+	Action    string             `json:"action"`
+	Details   []byte             `json:"details"`
+	IpAddress string             `json:"ip_address"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type UserRole struct {
+	UserID    int64              `json:"user_id"`
+	RoleID    int64              `json:"role_id"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type UserSetting struct {
+	UserID              int64              `json:"user_id"`
+	Locale              string             `json:"locale"`
+	Timezone            string             `json:"timezone"`
+	EmailSecurityAlerts bool               `json:"email_security_alerts"`
+	EmailProductUpdates bool               `json:"email_product_updates"`
+	UpdatedAt           pgtype.Timestamptz `json:"updated_at"`
+}
+
+type UserTotp struct {
+	UserID    int64              `json:"user_id"`
+	Secret    string             `json:"secret"`
+	EnabledAt pgtype.Timestamptz `json:"enabled_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type WebauthnCredential struct {
+	ID           int64              `json:"id"`
+	UserID       int64              `json:"user_id"`
+	CredentialID []byte             `json:"credential_id"`
+	Credential   []byte             `json:"credential"`
+	Name         string             `json:"name"`
+	LastUsedAt   pgtype.Timestamptz `json:"last_used_at"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
 }
@@ -12,19 +12,29 @@ import (
 )
 
 const createRefreshToken = `-- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (user_id, token, expires_at)
-VALUES ($1, $2, $3)
-RETURNING id, user_id, token, expires_at, created_at
+INSERT INTO refresh_tokens (user_id, token, expires_at, family_id, parent_id, device_fingerprint)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, user_id, token, expires_at, created_at, family_id, parent_id, rotated_at, device_fingerprint
 `
 
 type CreateRefreshTokenParams struct {
-	UserID    int64              `json:"user_id"`
-	Token     string             `json:"token"`
-	ExpiresAt pgtype.Timestamptz `json:"expires_at"`
+	UserID            int64              `json:"user_id"`
+	Token             string             `json:"token"`
+	ExpiresAt         pgtype.Timestamptz `json:"expires_at"`
+	FamilyID          string             `json:"family_id"`
+	ParentID          pgtype.Int8        `json:"parent_id"`
+	DeviceFingerprint pgtype.Text        `json:"device_fingerprint"`
 }
 
 func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error) {
-	row := q.db.QueryRow(ctx, createRefreshToken, arg.UserID, arg.Token, arg.ExpiresAt)
+	row := q.db.QueryRow(ctx, createRefreshToken,
+		arg.UserID,
+		arg.Token,
+		arg.ExpiresAt,
+		arg.FamilyID,
+		arg.ParentID,
+		arg.DeviceFingerprint,
+	)
 	var i RefreshToken
 	err := row.Scan(
 		&i.ID,
@@ -32,6 +42,10 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 		&i.Token,
 		&i.ExpiresAt,
 		&i.CreatedAt,
+		&i.FamilyID,
+		&i.ParentID,
+		&i.RotatedAt,
+		&i.DeviceFingerprint,
 	)
 	return i, err
 }
@@ -55,7 +69,7 @@ func (q *Queries) DeleteRefreshTokensByUserID(ctx context.Context, userID int64)
 }
 
 const getRefreshTokenByToken = `-- name: GetRefreshTokenByToken :one
-SELECT id, user_id, token, expires_at, created_at FROM refresh_tokens WHERE token = $1
+SELECT id, user_id, token, expires_at, created_at, family_id, parent_id, rotated_at, device_fingerprint FROM refresh_tokens WHERE token = $1
 `
 
 func (q *Queries) GetRefreshTokenByToken(ctx context.Context, token string) (RefreshToken, error) {
@@ -67,6 +81,79 @@ func (q *Queries) GetRefreshTokenByToken(ctx context.Context, token string) (Ref
 		&i.Token,
 		&i.ExpiresAt,
 		&i.CreatedAt,
+		&i.FamilyID,
+		&i.ParentID,
+		&i.RotatedAt,
+		&i.DeviceFingerprint,
+	)
+	return i, err
+}
+
+const listActiveRefreshTokensByUserID = `-- name: ListActiveRefreshTokensByUserID :many
+SELECT
+    rt.family_id,
+    (SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = rt.family_id)::TIMESTAMPTZ AS started_at,
+    rt.created_at AS last_refreshed_at,
+    rt.expires_at,
+    (rt.device_fingerprint IS NOT NULL)::BOOLEAN AS device_bound
+FROM refresh_tokens rt
+WHERE rt.user_id = $1 AND rt.rotated_at IS NULL AND rt.expires_at > NOW()
+ORDER BY rt.created_at DESC, rt.id DESC
+`
+
+type ListActiveRefreshTokensByUserIDRow struct {
+	FamilyID        string             `json:"family_id"`
+	StartedAt       pgtype.Timestamptz `json:"started_at"`
+	LastRefreshedAt pgtype.Timestamptz `json:"last_refreshed_at"`
+	ExpiresAt       pgtype.Timestamptz `json:"expires_at"`
+	DeviceBound     bool               `json:"device_bound"`
+}
+
+func (q *Queries) ListActiveRefreshTokensByUserID(ctx context.Context, userID int64) ([]ListActiveRefreshTokensByUserIDRow, error) {
+	rows, err := q.db.Query(ctx, listActiveRefreshTokensByUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListActiveRefreshTokensByUserIDRow{}
+	for rows.Next() {
+		var i ListActiveRefreshTokensByUserIDRow
+		if err := rows.Scan(
+			&i.FamilyID,
+			&i.StartedAt,
+			&i.LastRefreshedAt,
+			&i.ExpiresAt,
+			&i.DeviceBound,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markRefreshTokenRotated = `-- name: MarkRefreshTokenRotated :one
+UPDATE refresh_tokens SET rotated_at = NOW()
+WHERE id = $1 AND rotated_at IS NULL
+RETURNING id, user_id, token, expires_at, created_at, family_id, parent_id, rotated_at, device_fingerprint
+`
+
+func (q *Queries) MarkRefreshTokenRotated(ctx context.Context, id int64) (RefreshToken, error) {
+	row := q.db.QueryRow(ctx, markRefreshTokenRotated, id)
+	var i RefreshToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Token,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.FamilyID,
+		&i.ParentID,
+		&i.RotatedAt,
+		&i.DeviceFingerprint,
 	)
 	return i, err
 }
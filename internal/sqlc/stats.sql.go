@@ -7,11 +7,95 @@ package sqlc
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const getDailyStats = `-- name: GetDailyStats :many
+WITH days AS (
+    SELECT generate_series($1::date, $2::date, INTERVAL '1 day')::date AS day
+), bounds AS (
+    SELECT $1::date::timestamp AT TIME ZONE 'UTC' AS start_at
+), signups AS (
+    SELECT (u.created_at AT TIME ZONE 'UTC')::date AS day, count(*) AS n
+    FROM users u, bounds b WHERE u.created_at >= b.start_at GROUP BY 1
+), logins AS (
+    SELECT (le.created_at AT TIME ZONE 'UTC')::date AS day, count(DISTINCT le.user_id) AS n
+    FROM login_events le, bounds b
+    WHERE le.success AND le.user_id IS NOT NULL AND le.created_at >= b.start_at GROUP BY 1
+), added AS (
+    SELECT (f.created_at AT TIME ZONE 'UTC')::date AS day, count(*) AS n, SUM(f.size) AS bytes
+    FROM files f, bounds b WHERE f.created_at >= b.start_at GROUP BY 1
+), removed AS (
+    SELECT (f.deleted_at AT TIME ZONE 'UTC')::date AS day, SUM(f.size) AS bytes
+    FROM files f, bounds b WHERE f.deleted_at >= b.start_at GROUP BY 1
+), baseline AS (
+    SELECT COALESCE(SUM(f.size), 0) AS bytes
+    FROM files f, bounds b WHERE f.created_at < b.start_at AND (f.deleted_at IS NULL OR f.deleted_at >= b.start_at)
 )
+SELECT
+    d.day,
+    COALESCE(s.n, 0)::BIGINT AS signups,
+    COALESCE(l.n, 0)::BIGINT AS active_users,
+    COALESCE(a.n, 0)::BIGINT AS uploads,
+    COALESCE(a.bytes, 0)::BIGINT AS uploaded_bytes,
+    ((SELECT bytes FROM baseline) + SUM(COALESCE(a.bytes, 0) - COALESCE(r.bytes, 0)) OVER (ORDER BY d.day))::BIGINT AS storage_bytes
+FROM days d
+LEFT JOIN signups s ON s.day = d.day
+LEFT JOIN logins l ON l.day = d.day
+LEFT JOIN added a ON a.day = d.day
+LEFT JOIN removed r ON r.day = d.day
+ORDER BY d.day
+`
+
+type GetDailyStatsParams struct {
+	StartDay pgtype.Date `json:"start_day"`
+	EndDay   pgtype.Date `json:"end_day"`
+}
+
+type GetDailyStatsRow struct {
+	Day           pgtype.Date `json:"day"`
+	Signups       int64       `json:"signups"`
+	ActiveUsers   int64       `json:"active_users"`
+	Uploads       int64       `json:"uploads"`
+	UploadedBytes int64       `json:"uploaded_bytes"`
+	StorageBytes  int64       `json:"storage_bytes"`
+}
+
+// One row per UTC day from start_day to end_day. Active users signed in successfully that day;
+// storage is the size of the files not soft-deleted at the end of the day. Purged files no
+// longer count towards past days.
+func (q *Queries) GetDailyStats(ctx context.Context, arg GetDailyStatsParams) ([]GetDailyStatsRow, error) {
+	rows, err := q.db.Query(ctx, getDailyStats, arg.StartDay, arg.EndDay)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetDailyStatsRow{}
+	for rows.Next() {
+		var i GetDailyStatsRow
+		if err := rows.Scan(
+			&i.Day,
+			&i.Signups,
+			&i.ActiveUsers,
+			&i.Uploads,
+			&i.UploadedBytes,
+			&i.StorageBytes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
 
 const getSystemStats = `-- name: GetSystemStats :one
 SELECT
-    (SELECT count(*) FROM users WHERE deleted_at IS NULL) AS active_users,
+    (SELECT count(*) FROM users WHERE deleted_at IS NULL AND banned_at IS NULL) AS active_users,
+    (SELECT count(*) FROM users WHERE deleted_at IS NULL AND banned_at IS NOT NULL) AS banned_users,
     (SELECT count(*) FROM users WHERE deleted_at IS NOT NULL) AS deleted_users,
     (SELECT count(*) FROM files WHERE deleted_at IS NULL) AS total_files,
     (SELECT COALESCE(SUM(size), 0)::BIGINT FROM files WHERE deleted_at IS NULL) AS total_file_size
@@ -19,6 +103,7 @@ SELECT
 
 type GetSystemStatsRow struct {
 	ActiveUsers   int64 `json:"active_users"`
+	BannedUsers   int64 `json:"banned_users"`
 	DeletedUsers  int64 `json:"deleted_users"`
 	TotalFiles    int64 `json:"total_files"`
 	TotalFileSize int64 `json:"total_file_size"`
@@ -29,6 +114,7 @@ func (q *Queries) GetSystemStats(ctx context.Context) (GetSystemStatsRow, error)
 	var i GetSystemStatsRow
 	err := row.Scan(
 		&i.ActiveUsers,
+		&i.BannedUsers,
 		&i.DeletedUsers,
 		&i.TotalFiles,
 		&i.TotalFileSize,
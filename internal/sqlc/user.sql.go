@@ -11,56 +11,79 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const adminCountUsers = `-- name: AdminCountUsers :one
-SELECT count(*) FROM users
+const anonymizeUser = `-- name: AnonymizeUser :one
+UPDATE users
+SET email = $2, name = $3, username = NULL, password_hash = NULL, google_id = NULL, github_id = NULL, saml_id = NULL,
+    email_verified_at = NULL, metadata = '{}', deleted_at = COALESCE(deleted_at, NOW()), updated_at = NOW()
+WHERE id = $1
+RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason
 `
 
-func (q *Queries) AdminCountUsers(ctx context.Context) (int64, error) {
-	row := q.db.QueryRow(ctx, adminCountUsers)
-	var count int64
-	err := row.Scan(&count)
-	return count, err
+type AnonymizeUserParams struct {
+	ID    int64  `json:"id"`
+	Email string `json:"email"`
+	Name  string `json:"name"`
 }
 
-const adminListUsers = `-- name: AdminListUsers :many
-SELECT id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at FROM users ORDER BY id LIMIT $1 OFFSET $2
+func (q *Queries) AnonymizeUser(ctx context.Context, arg AnonymizeUserParams) (User, error) {
+	row := q.db.QueryRow(ctx, anonymizeUser, arg.ID, arg.Email, arg.Name)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Name,
+		&i.Role,
+		&i.GoogleID,
+		&i.AuthProvider,
+		&i.EmailVerifiedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.GithubID,
+		&i.SamlID,
+		&i.Metadata,
+		&i.Username,
+		&i.BannedAt,
+		&i.BanReason,
+	)
+	return i, err
+}
+
+const banUser = `-- name: BanUser :one
+UPDATE users SET banned_at = NOW(), ban_reason = $2, updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL AND banned_at IS NULL
+RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason
 `
 
-type AdminListUsersParams struct {
-	Limit  int32 `json:"limit"`
-	Offset int32 `json:"offset"`
+type BanUserParams struct {
+	ID        int64  `json:"id"`
+	BanReason string `json:"ban_reason"`
 }
 
-func (q *Queries) AdminListUsers(ctx context.Context, arg AdminListUsersParams) ([]User, error) {
-	rows, err := q.db.Query(ctx, adminListUsers, arg.Limit, arg.Offset)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []User{}
-	for rows.Next() {
-		var i User
-		if err := rows.Scan(
-			&i.ID,
-			&i.Email,
-			&i.PasswordHash,
-			&i.Name,
-			&i.Role,
-			&i.GoogleID,
-			&i.AuthProvider,
-			&i.EmailVerifiedAt,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.DeletedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+func (q *Queries) BanUser(ctx context.Context, arg BanUserParams) (User, error) {
+	row := q.db.QueryRow(ctx, banUser, arg.ID, arg.BanReason)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Name,
+		&i.Role,
+		&i.GoogleID,
+		&i.AuthProvider,
+		&i.EmailVerifiedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.GithubID,
+		&i.SamlID,
+		&i.Metadata,
+		&i.Username,
+		&i.BannedAt,
+		&i.BanReason,
+	)
+	return i, err
 }
 
 const countDeletedUsers = `-- name: CountDeletedUsers :one
@@ -75,26 +98,89 @@ func (q *Queries) CountDeletedUsers(ctx context.Context) (int64, error) {
 }
 
 const countUsers = `-- name: CountUsers :one
-SELECT count(*) FROM users WHERE deleted_at IS NULL
+SELECT count(*) FROM users
+WHERE ($1::boolean OR deleted_at IS NULL)
+  AND ($2::text IS NULL OR name ILIKE '%' || $2 || '%' OR email ILIKE '%' || $2 || '%')
+  AND ($3::text IS NULL OR role = $3)
+  AND ($4::boolean IS NULL OR (email_verified_at IS NOT NULL) = $4)
+  AND ($5::boolean IS NULL OR (banned_at IS NOT NULL) = $5)
+  AND ($6::timestamptz IS NULL OR created_at >= $6)
+  AND ($7::timestamptz IS NULL OR created_at < $7)
 `
 
-func (q *Queries) CountUsers(ctx context.Context) (int64, error) {
-	row := q.db.QueryRow(ctx, countUsers)
+type CountUsersParams struct {
+	IncludeDeleted bool               `json:"include_deleted"`
+	Search         pgtype.Text        `json:"search"`
+	Role           pgtype.Text        `json:"role"`
+	EmailVerified  pgtype.Bool        `json:"email_verified"`
+	Banned         pgtype.Bool        `json:"banned"`
+	CreatedAfter   pgtype.Timestamptz `json:"created_after"`
+	CreatedBefore  pgtype.Timestamptz `json:"created_before"`
+}
+
+func (q *Queries) CountUsers(ctx context.Context, arg CountUsersParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countUsers,
+		arg.IncludeDeleted,
+		arg.Search,
+		arg.Role,
+		arg.EmailVerified,
+		arg.Banned,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+	)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
 }
 
+const createGuestUser = `-- name: CreateGuestUser :one
+INSERT INTO users (email, name, role, auth_provider)
+VALUES ($1, $2, 'guest', 'guest')
+RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason
+`
+
+type CreateGuestUserParams struct {
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+func (q *Queries) CreateGuestUser(ctx context.Context, arg CreateGuestUserParams) (User, error) {
+	row := q.db.QueryRow(ctx, createGuestUser, arg.Email, arg.Name)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Name,
+		&i.Role,
+		&i.GoogleID,
+		&i.AuthProvider,
+		&i.EmailVerifiedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.GithubID,
+		&i.SamlID,
+		&i.Metadata,
+		&i.Username,
+		&i.BannedAt,
+		&i.BanReason,
+	)
+	return i, err
+}
+
 const createOAuthUser = `-- name: CreateOAuthUser :one
-INSERT INTO users (email, name, google_id, auth_provider, email_verified_at)
-VALUES ($1, $2, $3, $4, NOW())
-RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at
+INSERT INTO users (email, name, google_id, github_id, saml_id, auth_provider, email_verified_at)
+VALUES ($1, $2, $3, $4, $5, $6, NOW())
+RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason
 `
 
 type CreateOAuthUserParams struct {
 	Email        string      `json:"email"`
 	Name         string      `json:"name"`
 	GoogleID     pgtype.Text `json:"google_id"`
+	GithubID     pgtype.Text `json:"github_id"`
+	SamlID       pgtype.Text `json:"saml_id"`
 	AuthProvider string      `json:"auth_provider"`
 }
 
@@ -103,6 +189,8 @@ func (q *Queries) CreateOAuthUser(ctx context.Context, arg CreateOAuthUserParams
 		arg.Email,
 		arg.Name,
 		arg.GoogleID,
+		arg.GithubID,
+		arg.SamlID,
 		arg.AuthProvider,
 	)
 	var i User
@@ -118,24 +206,36 @@ func (q *Queries) CreateOAuthUser(ctx context.Context, arg CreateOAuthUserParams
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.GithubID,
+		&i.SamlID,
+		&i.Metadata,
+		&i.Username,
+		&i.BannedAt,
+		&i.BanReason,
 	)
 	return i, err
 }
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (email, password_hash, name)
-VALUES ($1, $2, $3)
-RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at
+INSERT INTO users (email, password_hash, name, username)
+VALUES ($1, $2, $3, $4)
+RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason
 `
 
 type CreateUserParams struct {
 	Email        string      `json:"email"`
 	PasswordHash pgtype.Text `json:"password_hash"`
 	Name         string      `json:"name"`
+	Username     pgtype.Text `json:"username"`
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRow(ctx, createUser, arg.Email, arg.PasswordHash, arg.Name)
+	row := q.db.QueryRow(ctx, createUser,
+		arg.Email,
+		arg.PasswordHash,
+		arg.Name,
+		arg.Username,
+	)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -149,6 +249,12 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.GithubID,
+		&i.SamlID,
+		&i.Metadata,
+		&i.Username,
+		&i.BannedAt,
+		&i.BanReason,
 	)
 	return i, err
 }
@@ -156,7 +262,7 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 const deleteUser = `-- name: DeleteUser :one
 UPDATE users SET deleted_at = NOW(), updated_at = NOW()
 WHERE id = $1 AND deleted_at IS NULL
-RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at
+RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason
 `
 
 func (q *Queries) DeleteUser(ctx context.Context, id int64) (User, error) {
@@ -174,12 +280,18 @@ func (q *Queries) DeleteUser(ctx context.Context, id int64) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.GithubID,
+		&i.SamlID,
+		&i.Metadata,
+		&i.Username,
+		&i.BannedAt,
+		&i.BanReason,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at FROM users WHERE email = $1 AND deleted_at IS NULL
+SELECT id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason FROM users WHERE email = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -197,12 +309,47 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.GithubID,
+		&i.SamlID,
+		&i.Metadata,
+		&i.Username,
+		&i.BannedAt,
+		&i.BanReason,
+	)
+	return i, err
+}
+
+const getUserByGitHubID = `-- name: GetUserByGitHubID :one
+SELECT id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason FROM users WHERE github_id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) GetUserByGitHubID(ctx context.Context, githubID pgtype.Text) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByGitHubID, githubID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Name,
+		&i.Role,
+		&i.GoogleID,
+		&i.AuthProvider,
+		&i.EmailVerifiedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.GithubID,
+		&i.SamlID,
+		&i.Metadata,
+		&i.Username,
+		&i.BannedAt,
+		&i.BanReason,
 	)
 	return i, err
 }
 
 const getUserByGoogleID = `-- name: GetUserByGoogleID :one
-SELECT id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at FROM users WHERE google_id = $1 AND deleted_at IS NULL
+SELECT id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason FROM users WHERE google_id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) GetUserByGoogleID(ctx context.Context, googleID pgtype.Text) (User, error) {
@@ -220,12 +367,18 @@ func (q *Queries) GetUserByGoogleID(ctx context.Context, googleID pgtype.Text) (
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.GithubID,
+		&i.SamlID,
+		&i.Metadata,
+		&i.Username,
+		&i.BannedAt,
+		&i.BanReason,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at FROM users WHERE id = $1 AND deleted_at IS NULL
+SELECT id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason FROM users WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
@@ -243,6 +396,182 @@ func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.GithubID,
+		&i.SamlID,
+		&i.Metadata,
+		&i.Username,
+		&i.BannedAt,
+		&i.BanReason,
+	)
+	return i, err
+}
+
+const getUserBySAMLID = `-- name: GetUserBySAMLID :one
+SELECT id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason FROM users WHERE saml_id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) GetUserBySAMLID(ctx context.Context, samlID pgtype.Text) (User, error) {
+	row := q.db.QueryRow(ctx, getUserBySAMLID, samlID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Name,
+		&i.Role,
+		&i.GoogleID,
+		&i.AuthProvider,
+		&i.EmailVerifiedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.GithubID,
+		&i.SamlID,
+		&i.Metadata,
+		&i.Username,
+		&i.BannedAt,
+		&i.BanReason,
+	)
+	return i, err
+}
+
+const getUserByUsername = `-- name: GetUserByUsername :one
+SELECT id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason FROM users WHERE username = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) GetUserByUsername(ctx context.Context, username pgtype.Text) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByUsername, username)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Name,
+		&i.Role,
+		&i.GoogleID,
+		&i.AuthProvider,
+		&i.EmailVerifiedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.GithubID,
+		&i.SamlID,
+		&i.Metadata,
+		&i.Username,
+		&i.BannedAt,
+		&i.BanReason,
+	)
+	return i, err
+}
+
+const getUserDetail = `-- name: GetUserDetail :one
+SELECT
+    u.id, u.email, u.password_hash, u.name, u.role, u.google_id, u.auth_provider, u.email_verified_at, u.created_at, u.updated_at, u.deleted_at, u.github_id, u.saml_id, u.metadata, u.username, u.banned_at, u.ban_reason,
+    (SELECT count(*) FROM refresh_tokens rt WHERE rt.user_id = u.id AND rt.rotated_at IS NULL AND rt.expires_at > NOW()) AS active_sessions,
+    (SELECT count(*) FROM files f WHERE f.user_id = u.id AND f.deleted_at IS NULL) AS file_count,
+    (SELECT COALESCE(SUM(f.size), 0)::BIGINT FROM files f WHERE f.user_id = u.id AND f.deleted_at IS NULL) AS file_size,
+    last_login.created_at AS last_login_at,
+    COALESCE(last_login.method, '')::TEXT AS last_login_method,
+    COALESCE(last_login.ip_address, '')::TEXT AS last_login_ip,
+    COALESCE(last_login.user_agent, '')::TEXT AS last_login_user_agent
+FROM users u
+LEFT JOIN LATERAL (
+    SELECT le.created_at, le.method, le.ip_address, le.user_agent FROM login_events le
+    WHERE le.user_id = u.id AND le.success
+    ORDER BY le.id DESC
+    LIMIT 1
+) last_login ON TRUE
+WHERE u.id = $1
+`
+
+type GetUserDetailRow struct {
+	User               User               `json:"user"`
+	ActiveSessions     int64              `json:"active_sessions"`
+	FileCount          int64              `json:"file_count"`
+	FileSize           int64              `json:"file_size"`
+	LastLoginAt        pgtype.Timestamptz `json:"last_login_at"`
+	LastLoginMethod    string             `json:"last_login_method"`
+	LastLoginIp        string             `json:"last_login_ip"`
+	LastLoginUserAgent string             `json:"last_login_user_agent"`
+}
+
+// Includes soft-deleted users. Sessions are refresh tokens not yet rotated or expired, and
+// last_login_at is NULL when the user never signed in successfully.
+func (q *Queries) GetUserDetail(ctx context.Context, id int64) (GetUserDetailRow, error) {
+	row := q.db.QueryRow(ctx, getUserDetail, id)
+	var i GetUserDetailRow
+	err := row.Scan(
+		&i.User.ID,
+		&i.User.Email,
+		&i.User.PasswordHash,
+		&i.User.Name,
+		&i.User.Role,
+		&i.User.GoogleID,
+		&i.User.AuthProvider,
+		&i.User.EmailVerifiedAt,
+		&i.User.CreatedAt,
+		&i.User.UpdatedAt,
+		&i.User.DeletedAt,
+		&i.User.GithubID,
+		&i.User.SamlID,
+		&i.User.Metadata,
+		&i.User.Username,
+		&i.User.BannedAt,
+		&i.User.BanReason,
+		&i.ActiveSessions,
+		&i.FileCount,
+		&i.FileSize,
+		&i.LastLoginAt,
+		&i.LastLoginMethod,
+		&i.LastLoginIp,
+		&i.LastLoginUserAgent,
+	)
+	return i, err
+}
+
+const getUserMetadata = `-- name: GetUserMetadata :one
+SELECT metadata FROM users WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) GetUserMetadata(ctx context.Context, id int64) ([]byte, error) {
+	row := q.db.QueryRow(ctx, getUserMetadata, id)
+	var metadata []byte
+	err := row.Scan(&metadata)
+	return metadata, err
+}
+
+const linkGitHubAccount = `-- name: LinkGitHubAccount :one
+UPDATE users SET github_id = $1, auth_provider = 'github', updated_at = NOW()
+WHERE id = $2 AND deleted_at IS NULL
+RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason
+`
+
+type LinkGitHubAccountParams struct {
+	GithubID pgtype.Text `json:"github_id"`
+	ID       int64       `json:"id"`
+}
+
+func (q *Queries) LinkGitHubAccount(ctx context.Context, arg LinkGitHubAccountParams) (User, error) {
+	row := q.db.QueryRow(ctx, linkGitHubAccount, arg.GithubID, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Name,
+		&i.Role,
+		&i.GoogleID,
+		&i.AuthProvider,
+		&i.EmailVerifiedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.GithubID,
+		&i.SamlID,
+		&i.Metadata,
+		&i.Username,
+		&i.BannedAt,
+		&i.BanReason,
 	)
 	return i, err
 }
@@ -250,7 +579,7 @@ func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
 const linkGoogleAccount = `-- name: LinkGoogleAccount :one
 UPDATE users SET google_id = $1, auth_provider = 'google', updated_at = NOW()
 WHERE id = $2 AND deleted_at IS NULL
-RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at
+RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason
 `
 
 type LinkGoogleAccountParams struct {
@@ -273,12 +602,54 @@ func (q *Queries) LinkGoogleAccount(ctx context.Context, arg LinkGoogleAccountPa
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.GithubID,
+		&i.SamlID,
+		&i.Metadata,
+		&i.Username,
+		&i.BannedAt,
+		&i.BanReason,
+	)
+	return i, err
+}
+
+const linkSAMLAccount = `-- name: LinkSAMLAccount :one
+UPDATE users SET saml_id = $1, auth_provider = 'saml', updated_at = NOW()
+WHERE id = $2 AND deleted_at IS NULL
+RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason
+`
+
+type LinkSAMLAccountParams struct {
+	SamlID pgtype.Text `json:"saml_id"`
+	ID     int64       `json:"id"`
+}
+
+func (q *Queries) LinkSAMLAccount(ctx context.Context, arg LinkSAMLAccountParams) (User, error) {
+	row := q.db.QueryRow(ctx, linkSAMLAccount, arg.SamlID, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Name,
+		&i.Role,
+		&i.GoogleID,
+		&i.AuthProvider,
+		&i.EmailVerifiedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.GithubID,
+		&i.SamlID,
+		&i.Metadata,
+		&i.Username,
+		&i.BannedAt,
+		&i.BanReason,
 	)
 	return i, err
 }
 
 const listDeletedUsers = `-- name: ListDeletedUsers :many
-SELECT id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at FROM users WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC LIMIT $1 OFFSET $2
+SELECT id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason FROM users WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC LIMIT $1 OFFSET $2
 `
 
 type ListDeletedUsersParams struct {
@@ -307,6 +678,12 @@ func (q *Queries) ListDeletedUsers(ctx context.Context, arg ListDeletedUsersPara
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
+			&i.GithubID,
+			&i.SamlID,
+			&i.Metadata,
+			&i.Username,
+			&i.BannedAt,
+			&i.BanReason,
 		); err != nil {
 			return nil, err
 		}
@@ -318,17 +695,161 @@ func (q *Queries) ListDeletedUsers(ctx context.Context, arg ListDeletedUsersPara
 	return items, nil
 }
 
+const listUserIDsDeletedBefore = `-- name: ListUserIDsDeletedBefore :many
+SELECT id FROM users
+WHERE deleted_at IS NOT NULL AND deleted_at < $1
+ORDER BY deleted_at
+LIMIT $2
+`
+
+type ListUserIDsDeletedBeforeParams struct {
+	DeletedAt pgtype.Timestamptz `json:"deleted_at"`
+	Limit     int32              `json:"limit"`
+}
+
+func (q *Queries) ListUserIDsDeletedBefore(ctx context.Context, arg ListUserIDsDeletedBeforeParams) ([]int64, error) {
+	rows, err := q.db.Query(ctx, listUserIDsDeletedBefore, arg.DeletedAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listUsers = `-- name: ListUsers :many
-SELECT id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at FROM users WHERE deleted_at IS NULL ORDER BY id LIMIT $1 OFFSET $2
+SELECT id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason FROM users
+WHERE ($1::boolean OR deleted_at IS NULL)
+  AND ($2::text IS NULL OR name ILIKE '%' || $2 || '%' OR email ILIKE '%' || $2 || '%')
+  AND ($3::text IS NULL OR role = $3)
+  AND ($4::boolean IS NULL OR (email_verified_at IS NOT NULL) = $4)
+  AND ($5::boolean IS NULL OR (banned_at IS NOT NULL) = $5)
+  AND ($6::timestamptz IS NULL OR created_at >= $6)
+  AND ($7::timestamptz IS NULL OR created_at < $7)
+ORDER BY
+  CASE WHEN $8::text = 'name' AND NOT $9::boolean THEN name END ASC,
+  CASE WHEN $8::text = 'name' AND $9::boolean THEN name END DESC,
+  CASE WHEN $8::text = 'email' AND NOT $9::boolean THEN email END ASC,
+  CASE WHEN $8::text = 'email' AND $9::boolean THEN email END DESC,
+  CASE WHEN $8::text = 'created_at' AND NOT $9::boolean THEN created_at END ASC,
+  CASE WHEN $8::text = 'created_at' AND $9::boolean THEN created_at END DESC,
+  CASE WHEN $9::boolean THEN id END DESC,
+  id ASC
+LIMIT $11 OFFSET $10
 `
 
 type ListUsersParams struct {
-	Limit  int32 `json:"limit"`
-	Offset int32 `json:"offset"`
+	IncludeDeleted bool               `json:"include_deleted"`
+	Search         pgtype.Text        `json:"search"`
+	Role           pgtype.Text        `json:"role"`
+	EmailVerified  pgtype.Bool        `json:"email_verified"`
+	Banned         pgtype.Bool        `json:"banned"`
+	CreatedAfter   pgtype.Timestamptz `json:"created_after"`
+	CreatedBefore  pgtype.Timestamptz `json:"created_before"`
+	SortBy         string             `json:"sort_by"`
+	SortDesc       bool               `json:"sort_desc"`
+	Offset         int32              `json:"offset"`
+	Limit          int32              `json:"limit"`
 }
 
 func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error) {
-	rows, err := q.db.Query(ctx, listUsers, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, listUsers,
+		arg.IncludeDeleted,
+		arg.Search,
+		arg.Role,
+		arg.EmailVerified,
+		arg.Banned,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.SortBy,
+		arg.SortDesc,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.PasswordHash,
+			&i.Name,
+			&i.Role,
+			&i.GoogleID,
+			&i.AuthProvider,
+			&i.EmailVerifiedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.GithubID,
+			&i.SamlID,
+			&i.Metadata,
+			&i.Username,
+			&i.BannedAt,
+			&i.BanReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersAfter = `-- name: ListUsersAfter :many
+SELECT id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason FROM users
+WHERE ($1::boolean OR deleted_at IS NULL)
+  AND ($2::text IS NULL OR name ILIKE '%' || $2 || '%' OR email ILIKE '%' || $2 || '%')
+  AND ($3::text IS NULL OR role = $3)
+  AND ($4::boolean IS NULL OR (email_verified_at IS NOT NULL) = $4)
+  AND ($5::boolean IS NULL OR (banned_at IS NOT NULL) = $5)
+  AND ($6::timestamptz IS NULL OR created_at >= $6)
+  AND ($7::timestamptz IS NULL OR created_at < $7)
+  AND id > $8
+ORDER BY id
+LIMIT $9
+`
+
+type ListUsersAfterParams struct {
+	IncludeDeleted bool               `json:"include_deleted"`
+	Search         pgtype.Text        `json:"search"`
+	Role           pgtype.Text        `json:"role"`
+	EmailVerified  pgtype.Bool        `json:"email_verified"`
+	Banned         pgtype.Bool        `json:"banned"`
+	CreatedAfter   pgtype.Timestamptz `json:"created_after"`
+	CreatedBefore  pgtype.Timestamptz `json:"created_before"`
+	AfterID        int64              `json:"after_id"`
+	Limit          int32              `json:"limit"`
+}
+
+func (q *Queries) ListUsersAfter(ctx context.Context, arg ListUsersAfterParams) ([]User, error) {
+	rows, err := q.db.Query(ctx, listUsersAfter,
+		arg.IncludeDeleted,
+		arg.Search,
+		arg.Role,
+		arg.EmailVerified,
+		arg.Banned,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.AfterID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -348,6 +869,12 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, e
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.DeletedAt,
+			&i.GithubID,
+			&i.SamlID,
+			&i.Metadata,
+			&i.Username,
+			&i.BannedAt,
+			&i.BanReason,
 		); err != nil {
 			return nil, err
 		}
@@ -359,10 +886,89 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, e
 	return items, nil
 }
 
+const lockActiveAdmins = `-- name: LockActiveAdmins :many
+SELECT id FROM users
+WHERE role = 'admin' AND banned_at IS NULL AND deleted_at IS NULL
+ORDER BY id
+FOR UPDATE
+`
+
+// Locks the rows of admins who are neither banned nor deleted, so a transaction that
+// counts them can demote, ban or delete one without racing another such change.
+func (q *Queries) LockActiveAdmins(ctx context.Context) ([]int64, error) {
+	rows, err := q.db.Query(ctx, lockActiveAdmins)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const mergeUserMetadata = `-- name: MergeUserMetadata :one
+UPDATE users
+SET metadata = (metadata || $1::jsonb) - $2::text[], updated_at = NOW()
+WHERE id = $3 AND deleted_at IS NULL
+RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason
+`
+
+type MergeUserMetadataParams struct {
+	Patch      []byte   `json:"patch"`
+	RemoveKeys []string `json:"remove_keys"`
+	ID         int64    `json:"id"`
+}
+
+func (q *Queries) MergeUserMetadata(ctx context.Context, arg MergeUserMetadataParams) (User, error) {
+	row := q.db.QueryRow(ctx, mergeUserMetadata, arg.Patch, arg.RemoveKeys, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Name,
+		&i.Role,
+		&i.GoogleID,
+		&i.AuthProvider,
+		&i.EmailVerifiedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.GithubID,
+		&i.SamlID,
+		&i.Metadata,
+		&i.Username,
+		&i.BannedAt,
+		&i.BanReason,
+	)
+	return i, err
+}
+
+const purgeUser = `-- name: PurgeUser :execrows
+DELETE FROM users WHERE id = $1
+`
+
+func (q *Queries) PurgeUser(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const restoreUser = `-- name: RestoreUser :one
 UPDATE users SET deleted_at = NULL, updated_at = NOW()
 WHERE id = $1 AND deleted_at IS NOT NULL
-RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at
+RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason
 `
 
 func (q *Queries) RestoreUser(ctx context.Context, id int64) (User, error) {
@@ -380,25 +986,161 @@ func (q *Queries) RestoreUser(ctx context.Context, id int64) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.GithubID,
+		&i.SamlID,
+		&i.Metadata,
+		&i.Username,
+		&i.BannedAt,
+		&i.BanReason,
+	)
+	return i, err
+}
+
+const searchUsers = `-- name: SearchUsers :many
+SELECT id, email, name, role, deleted_at, created_at,
+    (CASE
+        WHEN email ILIKE $1 OR name ILIKE $1 THEN 0
+        WHEN email ILIKE $1 || '%' OR name ILIKE $1 || '%' THEN 1
+        ELSE 2
+    END)::INT AS rank
+FROM users
+WHERE email ILIKE '%' || $1 || '%' OR name ILIKE '%' || $1 || '%'
+ORDER BY rank, created_at DESC, id DESC
+LIMIT $2
+`
+
+type SearchUsersParams struct {
+	Pattern string `json:"pattern"`
+	Limit   int32  `json:"limit"`
+}
+
+type SearchUsersRow struct {
+	ID        int64              `json:"id"`
+	Email     string             `json:"email"`
+	Name      string             `json:"name"`
+	Role      string             `json:"role"`
+	DeletedAt pgtype.Timestamptz `json:"deleted_at"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	Rank      int32              `json:"rank"`
+}
+
+// Rank 0 is an exact (case-insensitive) email or name, 1 a prefix and 2 any other substring.
+func (q *Queries) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]SearchUsersRow, error) {
+	rows, err := q.db.Query(ctx, searchUsers, arg.Pattern, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SearchUsersRow{}
+	for rows.Next() {
+		var i SearchUsersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+			&i.Name,
+			&i.Role,
+			&i.DeletedAt,
+			&i.CreatedAt,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const unbanUser = `-- name: UnbanUser :one
+UPDATE users SET banned_at = NULL, ban_reason = '', updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL AND banned_at IS NOT NULL
+RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason
+`
+
+func (q *Queries) UnbanUser(ctx context.Context, id int64) (User, error) {
+	row := q.db.QueryRow(ctx, unbanUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Name,
+		&i.Role,
+		&i.GoogleID,
+		&i.AuthProvider,
+		&i.EmailVerifiedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.GithubID,
+		&i.SamlID,
+		&i.Metadata,
+		&i.Username,
+		&i.BannedAt,
+		&i.BanReason,
 	)
 	return i, err
 }
 
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
-SET name = $1, email = $2, updated_at = NOW()
-WHERE id = $3 AND deleted_at IS NULL
-RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at
+SET name = $1, email = $2, username = $3, updated_at = NOW()
+WHERE id = $4 AND deleted_at IS NULL
+RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason
 `
 
 type UpdateUserParams struct {
-	Name  string `json:"name"`
+	Name     string      `json:"name"`
+	Email    string      `json:"email"`
+	Username pgtype.Text `json:"username"`
+	ID       int64       `json:"id"`
+}
+
+func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUser,
+		arg.Name,
+		arg.Email,
+		arg.Username,
+		arg.ID,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Name,
+		&i.Role,
+		&i.GoogleID,
+		&i.AuthProvider,
+		&i.EmailVerifiedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.GithubID,
+		&i.SamlID,
+		&i.Metadata,
+		&i.Username,
+		&i.BannedAt,
+		&i.BanReason,
+	)
+	return i, err
+}
+
+const updateUserEmail = `-- name: UpdateUserEmail :one
+UPDATE users SET email = $1, email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $2 AND deleted_at IS NULL
+RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason
+`
+
+type UpdateUserEmailParams struct {
 	Email string `json:"email"`
 	ID    int64  `json:"id"`
 }
 
-func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
-	row := q.db.QueryRow(ctx, updateUser, arg.Name, arg.Email, arg.ID)
+func (q *Queries) UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUserEmail, arg.Email, arg.ID)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -412,6 +1154,12 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.GithubID,
+		&i.SamlID,
+		&i.Metadata,
+		&i.Username,
+		&i.BannedAt,
+		&i.BanReason,
 	)
 	return i, err
 }
@@ -419,7 +1167,7 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 const updateUserPassword = `-- name: UpdateUserPassword :one
 UPDATE users SET password_hash = $1, updated_at = NOW()
 WHERE id = $2 AND deleted_at IS NULL
-RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at
+RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason
 `
 
 type UpdateUserPasswordParams struct {
@@ -442,6 +1190,12 @@ func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPassword
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.GithubID,
+		&i.SamlID,
+		&i.Metadata,
+		&i.Username,
+		&i.BannedAt,
+		&i.BanReason,
 	)
 	return i, err
 }
@@ -449,7 +1203,7 @@ func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPassword
 const updateUserRole = `-- name: UpdateUserRole :one
 UPDATE users SET role = $1, updated_at = NOW()
 WHERE id = $2 AND deleted_at IS NULL
-RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at
+RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason
 `
 
 type UpdateUserRoleParams struct {
@@ -472,6 +1226,57 @@ func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams)
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.GithubID,
+		&i.SamlID,
+		&i.Metadata,
+		&i.Username,
+		&i.BannedAt,
+		&i.BanReason,
+	)
+	return i, err
+}
+
+const upgradeGuestUser = `-- name: UpgradeGuestUser :one
+UPDATE users SET email = $1, name = $2, password_hash = $3, username = $4, role = 'user', auth_provider = 'local', updated_at = NOW()
+WHERE id = $5 AND role = 'guest' AND deleted_at IS NULL
+RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason
+`
+
+type UpgradeGuestUserParams struct {
+	Email        string      `json:"email"`
+	Name         string      `json:"name"`
+	PasswordHash pgtype.Text `json:"password_hash"`
+	Username     pgtype.Text `json:"username"`
+	ID           int64       `json:"id"`
+}
+
+func (q *Queries) UpgradeGuestUser(ctx context.Context, arg UpgradeGuestUserParams) (User, error) {
+	row := q.db.QueryRow(ctx, upgradeGuestUser,
+		arg.Email,
+		arg.Name,
+		arg.PasswordHash,
+		arg.Username,
+		arg.ID,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Name,
+		&i.Role,
+		&i.GoogleID,
+		&i.AuthProvider,
+		&i.EmailVerifiedAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.GithubID,
+		&i.SamlID,
+		&i.Metadata,
+		&i.Username,
+		&i.BannedAt,
+		&i.BanReason,
 	)
 	return i, err
 }
@@ -479,7 +1284,7 @@ func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams)
 const verifyUserEmail = `-- name: VerifyUserEmail :one
 UPDATE users SET email_verified_at = NOW(), updated_at = NOW()
 WHERE id = $1 AND deleted_at IS NULL
-RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at
+RETURNING id, email, password_hash, name, role, google_id, auth_provider, email_verified_at, created_at, updated_at, deleted_at, github_id, saml_id, metadata, username, banned_at, ban_reason
 `
 
 func (q *Queries) VerifyUserEmail(ctx context.Context, id int64) (User, error) {
@@ -497,6 +1302,12 @@ func (q *Queries) VerifyUserEmail(ctx context.Context, id int64) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.GithubID,
+		&i.SamlID,
+		&i.Metadata,
+		&i.Username,
+		&i.BannedAt,
+		&i.BanReason,
 	)
 	return i, err
 }
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS email_change_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    new_email VARCHAR(255) NOT NULL,
+    token VARCHAR(255) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_email_change_tokens_token ON email_change_tokens(token);
+CREATE INDEX idx_email_change_tokens_user_id ON email_change_tokens(user_id);
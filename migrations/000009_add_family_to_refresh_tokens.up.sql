@@ -0,0 +1,9 @@
+-- Existing tokens each start their own family
+ALTER TABLE refresh_tokens
+    ADD COLUMN family_id VARCHAR(36) NOT NULL DEFAULT gen_random_uuid()::text,
+    ADD COLUMN parent_id BIGINT REFERENCES refresh_tokens(id) ON DELETE SET NULL,
+    ADD COLUMN rotated_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE refresh_tokens ALTER COLUMN family_id DROP DEFAULT;
+
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens(family_id);
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS saml_id VARCHAR(255) UNIQUE;
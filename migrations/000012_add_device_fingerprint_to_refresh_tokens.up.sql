@@ -0,0 +1,2 @@
+-- SHA-256 of the client-supplied device fingerprint; NULL for tokens not bound to a device
+ALTER TABLE refresh_tokens ADD COLUMN device_fingerprint VARCHAR(64);
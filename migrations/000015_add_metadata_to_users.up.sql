@@ -0,0 +1,2 @@
+-- Free-form profile fields for downstream applications, merged by PATCH-style updates
+ALTER TABLE users ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}'::jsonb;
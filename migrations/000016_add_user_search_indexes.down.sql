@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_users_role;
+DROP INDEX IF EXISTS idx_users_name;
+DROP INDEX IF EXISTS idx_users_created_at;
+DROP INDEX IF EXISTS idx_users_email_trgm;
+DROP INDEX IF EXISTS idx_users_name_trgm;
+-- pg_trgm is left installed; other schemas may depend on it
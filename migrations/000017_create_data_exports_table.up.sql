@@ -0,0 +1,16 @@
+-- Personal data archives requested via /users/me/data-export. user_id is cleared rather than
+-- cascaded when the account is purged so the expiry sweeper still removes the stored archive.
+CREATE TABLE IF NOT EXISTS data_exports (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    storage_path VARCHAR(500),
+    size BIGINT,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT data_exports_status_check CHECK (status IN ('pending', 'ready', 'failed'))
+);
+
+CREATE INDEX idx_data_exports_user_id ON data_exports(user_id, created_at DESC);
+CREATE INDEX idx_data_exports_expires_at ON data_exports(expires_at) WHERE expires_at IS NOT NULL;
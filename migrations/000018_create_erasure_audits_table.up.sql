@@ -0,0 +1,13 @@
+-- One row per right-to-erasure request. The user and actor IDs are deliberately not foreign
+-- keys so the record outlives a later purge of either account, and no personal data is kept.
+CREATE TABLE IF NOT EXISTS erasure_audits (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL UNIQUE,
+    actor_id BIGINT NOT NULL,
+    source VARCHAR(20) NOT NULL,
+    files_deleted INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT erasure_audits_source_check CHECK (source IN ('self', 'admin'))
+);
+
+CREATE INDEX idx_erasure_audits_created_at ON erasure_audits(created_at DESC);
@@ -0,0 +1,9 @@
+-- Per-user preferences. Users without a row use the column defaults.
+CREATE TABLE IF NOT EXISTS user_settings (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    locale VARCHAR(35) NOT NULL DEFAULT 'en',
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    email_security_alerts BOOLEAN NOT NULL DEFAULT TRUE,
+    email_product_updates BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
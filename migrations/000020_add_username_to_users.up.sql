@@ -0,0 +1,4 @@
+-- Optional public handle for apps that don't expose email addresses; stored lowercase
+ALTER TABLE users ADD COLUMN username VARCHAR(30);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
@@ -0,0 +1,12 @@
+-- Audit trail of significant account changes; actor_id differs from user_id when an admin acted
+CREATE TABLE IF NOT EXISTS user_activities (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    actor_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(50) NOT NULL,
+    details JSONB NOT NULL DEFAULT '{}'::jsonb,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_user_activities_user_id_id ON user_activities(user_id, id DESC);
@@ -0,0 +1,12 @@
+-- Invitations to register while INVITE_ONLY is enabled. One pending invitation per
+-- address; inviting again replaces it. Only the SHA-256 of the token is stored.
+CREATE TABLE IF NOT EXISTS registration_invitations (
+    id BIGSERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    token VARCHAR(255) NOT NULL UNIQUE,
+    invited_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_registration_invitations_expires_at ON registration_invitations(expires_at);
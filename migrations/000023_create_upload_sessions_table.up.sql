@@ -0,0 +1,24 @@
+-- Resumable uploads: each chunk is stored as its own object and assembled into a file
+-- when the upload is finalized. received is the byte offset the next chunk must start at.
+CREATE TABLE IF NOT EXISTS upload_sessions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    received BIGINT NOT NULL DEFAULT 0,
+    mime_type VARCHAR(127) NOT NULL DEFAULT '',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_upload_sessions_user_id ON upload_sessions(user_id);
+CREATE INDEX idx_upload_sessions_expires_at ON upload_sessions(expires_at);
+
+CREATE TABLE IF NOT EXISTS upload_session_parts (
+    session_id BIGINT NOT NULL REFERENCES upload_sessions(id) ON DELETE CASCADE,
+    "offset" BIGINT NOT NULL,
+    storage_path VARCHAR(512) NOT NULL UNIQUE,
+    size BIGINT NOT NULL,
+    PRIMARY KEY (session_id, "offset")
+);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS file_variants (
+    id BIGSERIAL PRIMARY KEY,
+    file_id BIGINT NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    storage_path VARCHAR(512) NOT NULL UNIQUE,
+    mime_type VARCHAR(127) NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    size BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (file_id, name)
+);
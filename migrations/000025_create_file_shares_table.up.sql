@@ -0,0 +1,15 @@
+-- Public download links for files. Only the SHA-256 of the token is stored, and the
+-- optional password is a bcrypt hash. max_downloads NULL means unlimited.
+CREATE TABLE IF NOT EXISTS file_shares (
+    id BIGSERIAL PRIMARY KEY,
+    file_id BIGINT NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    token VARCHAR(255) NOT NULL UNIQUE,
+    password_hash VARCHAR(255),
+    max_downloads INT,
+    download_count INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_file_shares_file_id ON file_shares(file_id);
+CREATE INDEX idx_file_shares_expires_at ON file_shares(expires_at);
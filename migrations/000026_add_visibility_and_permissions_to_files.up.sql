@@ -0,0 +1,14 @@
+-- Public files can be read by any signed-in user; private files only by their owner and
+-- the users granted access in file_permissions.
+ALTER TABLE files ADD COLUMN visibility VARCHAR(20) NOT NULL DEFAULT 'private'
+    CHECK (visibility IN ('private', 'public'));
+
+CREATE TABLE IF NOT EXISTS file_permissions (
+    file_id BIGINT NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    granted_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (file_id, user_id)
+);
+
+CREATE INDEX idx_file_permissions_user_id ON file_permissions(user_id);
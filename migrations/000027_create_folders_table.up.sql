@@ -0,0 +1,17 @@
+-- Folders give each user a tree to organize files in. parent_id NULL is the top level;
+-- names are unique among siblings. A file with folder_id NULL is not in any folder.
+CREATE TABLE IF NOT EXISTS folders (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    parent_id BIGINT REFERENCES folders(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_folders_user_id_parent_id_name ON folders (user_id, COALESCE(parent_id, 0), name);
+CREATE INDEX idx_folders_parent_id ON folders(parent_id);
+
+ALTER TABLE files ADD COLUMN folder_id BIGINT REFERENCES folders(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_files_folder_id ON files(folder_id) WHERE deleted_at IS NULL;
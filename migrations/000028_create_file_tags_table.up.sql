@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS file_tags (
+    file_id BIGINT NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    tag VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (file_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_tags_tag ON file_tags(tag);
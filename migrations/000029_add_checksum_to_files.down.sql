@@ -0,0 +1,12 @@
+DELETE FROM file_variants v USING file_variants o
+WHERE v.storage_path = o.storage_path AND v.id > o.id;
+
+ALTER TABLE file_variants ADD CONSTRAINT file_variants_storage_path_key UNIQUE (storage_path);
+
+-- Fails while deduplicated files still share a stored object; those uploads have to be
+-- removed before rolling back.
+ALTER TABLE files ADD CONSTRAINT files_storage_path_key UNIQUE (storage_path);
+
+DROP INDEX IF EXISTS idx_files_user_id_checksum;
+
+ALTER TABLE files DROP COLUMN IF EXISTS checksum;
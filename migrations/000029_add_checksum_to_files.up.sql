@@ -0,0 +1,10 @@
+-- SHA-256 of the file's content, hex-encoded. Files uploaded before checksums were
+-- recorded have none.
+ALTER TABLE files ADD COLUMN checksum VARCHAR(64);
+
+CREATE INDEX idx_files_user_id_checksum ON files(user_id, checksum) WHERE deleted_at IS NULL;
+
+-- A deduplicated upload shares the stored object, and so the variants, of the file it
+-- duplicates.
+ALTER TABLE files DROP CONSTRAINT IF EXISTS files_storage_path_key;
+ALTER TABLE file_variants DROP CONSTRAINT IF EXISTS file_variants_storage_path_key;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS file_versions;
+
+ALTER TABLE files DROP COLUMN IF EXISTS version_created_at;
+ALTER TABLE files DROP COLUMN IF EXISTS version;
@@ -0,0 +1,18 @@
+-- files holds the current version of each file; replaced content is archived in
+-- file_versions so it can be listed, downloaded and restored.
+ALTER TABLE files ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE files ADD COLUMN version_created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
+
+UPDATE files SET version_created_at = created_at WHERE created_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS file_versions (
+    id BIGSERIAL PRIMARY KEY,
+    file_id BIGINT NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    storage_path VARCHAR(512) NOT NULL,
+    mime_type VARCHAR(127) NOT NULL,
+    size BIGINT NOT NULL,
+    checksum VARCHAR(64),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    UNIQUE (file_id, version)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE file_versions DROP COLUMN IF EXISTS encryption_key_id;
+ALTER TABLE files DROP COLUMN IF EXISTS encryption_key_id;
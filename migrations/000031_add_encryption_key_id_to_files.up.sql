@@ -0,0 +1,3 @@
+-- ID of the key the stored object was encrypted with; NULL when it is stored in plain.
+ALTER TABLE files ADD COLUMN encryption_key_id VARCHAR(64);
+ALTER TABLE file_versions ADD COLUMN encryption_key_id VARCHAR(64);
@@ -0,0 +1,2 @@
+-- Looks up identical content across users for the content-addressed storage layout.
+CREATE INDEX idx_files_checksum ON files(checksum) WHERE deleted_at IS NULL;
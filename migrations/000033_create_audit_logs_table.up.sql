@@ -0,0 +1,23 @@
+-- One row per admin mutation. The actor and target IDs are deliberately not foreign keys so
+-- the trail outlives a later purge of either; payloads hold only the fields that changed.
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id BIGINT NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    target_type VARCHAR(20) NOT NULL,
+    target_id BIGINT NOT NULL,
+    before_data JSONB,
+    after_data JSONB,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    request_id VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_logs_actor_id ON audit_logs(actor_id, id DESC);
+CREATE INDEX idx_audit_logs_target ON audit_logs(target_type, target_id, id DESC);
+
+INSERT INTO permissions (name, description) VALUES
+    ('audit:read', 'View the admin audit log');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p WHERE r.name = 'admin' AND p.name = 'audit:read';
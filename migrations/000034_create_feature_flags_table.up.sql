@@ -0,0 +1,21 @@
+-- Server-side switches for features, read by middleware.RequireFeature and exposed to
+-- clients through GET /features. A flag that does not exist is off.
+CREATE TABLE IF NOT EXISTS feature_flags (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) UNIQUE NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TRIGGER trigger_feature_flags_updated_at
+    BEFORE UPDATE ON feature_flags
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at();
+
+INSERT INTO permissions (name, description) VALUES
+    ('flags:manage', 'Create, toggle and delete feature flags');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p WHERE r.name = 'admin' AND p.name = 'flags:manage';
@@ -0,0 +1,3 @@
+DELETE FROM permissions WHERE name = 'broadcasts:send';
+
+DROP TABLE IF EXISTS broadcasts;
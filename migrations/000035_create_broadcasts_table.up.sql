@@ -0,0 +1,22 @@
+-- Announcement emails sent by admins. sender_id is not a foreign key so the record outlives
+-- a later purge of the admin; segment holds the recipient filter as it was submitted.
+CREATE TABLE IF NOT EXISTS broadcasts (
+    id BIGSERIAL PRIMARY KEY,
+    sender_id BIGINT NOT NULL,
+    subject VARCHAR(200) NOT NULL,
+    body TEXT NOT NULL,
+    segment JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'sending',
+    recipient_count INTEGER NOT NULL DEFAULT 0,
+    sent_count INTEGER NOT NULL DEFAULT 0,
+    failed_count INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT broadcasts_status_check CHECK (status IN ('sending', 'completed', 'failed'))
+);
+
+INSERT INTO permissions (name, description) VALUES
+    ('broadcasts:send', 'Email announcements to users');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p WHERE r.name = 'admin' AND p.name = 'broadcasts:send';
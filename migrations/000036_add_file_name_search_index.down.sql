@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_files_original_name_trgm;
+-- pg_trgm is left installed; the user search indexes depend on it
@@ -0,0 +1,4 @@
+-- Trigram index backing the case-insensitive substring search on file names in /admin/search
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_files_original_name_trgm ON files USING gin (original_name gin_trgm_ops);
@@ -0,0 +1,3 @@
+DELETE FROM permissions WHERE name = 'settings:manage';
+
+DROP TABLE IF EXISTS settings;
@@ -0,0 +1,6 @@
+-- Bans block sign-in without deleting the account, which stays visible to admins and keeps
+-- its data until unbanned. Users banned before this migration were soft-deleted and stay so.
+ALTER TABLE users ADD COLUMN banned_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN ban_reason VARCHAR(500) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_users_banned_at ON users(banned_at) WHERE banned_at IS NOT NULL;
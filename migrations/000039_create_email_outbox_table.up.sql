@@ -0,0 +1,29 @@
+-- Emails waiting to be delivered, so a message survives an SMTP outage or a restart and is
+-- retried with backoff. A claimed message is locked until locked_until; one left 'sending' past
+-- it by a crashed worker is claimed again. Bodies are cleared once a message is sent.
+CREATE TABLE IF NOT EXISTS email_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    recipients TEXT[] NOT NULL,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    html TEXT NOT NULL DEFAULT '',
+    locale VARCHAR(35) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT email_outbox_status_check CHECK (status IN ('pending', 'sending', 'sent', 'dead'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_outbox_due ON email_outbox(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_email_outbox_locked ON email_outbox(locked_until) WHERE status = 'sending';
+CREATE INDEX IF NOT EXISTS idx_email_outbox_status ON email_outbox(status, id);
+
+INSERT INTO permissions (name, description) VALUES
+    ('emails:manage', 'View queued email delivery and retry failed emails');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p WHERE r.name = 'admin' AND p.name = 'emails:manage';
@@ -0,0 +1,3 @@
+-- Attachments and inline images of a queued email, as a JSON array with base64 data.
+-- Cleared with the bodies once the message is sent.
+ALTER TABLE email_outbox ADD COLUMN attachments JSONB NOT NULL DEFAULT '[]'::jsonb;
@@ -0,0 +1,14 @@
+-- Addresses that hard-bounced or complained, reported by the email provider's webhook.
+-- Nothing is sent to them until an admin removes them. Emails are stored lowercased.
+CREATE TABLE IF NOT EXISTS email_suppressions (
+    id BIGSERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    reason VARCHAR(20) NOT NULL,
+    provider VARCHAR(20) NOT NULL,
+    detail TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT email_suppressions_reason_check CHECK (reason IN ('bounce', 'complaint'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_suppressions_created ON email_suppressions(created_at DESC, id DESC);
@@ -4,9 +4,13 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"strings"
 
 	"github.com/gofiber/fiber/v3"
 
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/errorreport"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/i18n"
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/response"
 )
 
@@ -25,6 +29,12 @@ func (e *AppError) Error() string {
 	return e.Message
 }
 
+// WithErrorCode replaces the generic error code with a specific one clients can match on.
+func (e *AppError) WithErrorCode(code string) *AppError {
+	e.ErrorCode = code
+	return e
+}
+
 func NewBadRequest(msg string) *AppError {
 	return &AppError{
 		Code:      fiber.StatusBadRequest,
@@ -57,6 +67,30 @@ func NewNotFound(msg string) *AppError {
 	}
 }
 
+func NewConflict(msg string) *AppError {
+	return &AppError{
+		Code:      fiber.StatusConflict,
+		ErrorCode: "CONFLICT",
+		Message:   msg,
+	}
+}
+
+func NewPayloadTooLarge(msg string) *AppError {
+	return &AppError{
+		Code:      fiber.StatusRequestEntityTooLarge,
+		ErrorCode: "PAYLOAD_TOO_LARGE",
+		Message:   msg,
+	}
+}
+
+func NewTooManyRequests(msg string) *AppError {
+	return &AppError{
+		Code:      fiber.StatusTooManyRequests,
+		ErrorCode: "TOO_MANY_REQUESTS",
+		Message:   msg,
+	}
+}
+
 func NewInternal(msg string) *AppError {
 	return &AppError{
 		Code:      fiber.StatusInternalServerError,
@@ -65,6 +99,14 @@ func NewInternal(msg string) *AppError {
 	}
 }
 
+func NewServiceUnavailable(msg string) *AppError {
+	return &AppError{
+		Code:      fiber.StatusServiceUnavailable,
+		ErrorCode: "SERVICE_UNAVAILABLE",
+		Message:   msg,
+	}
+}
+
 func NewValidation(msg string, details any) *AppError {
 	return &AppError{
 		Code:      fiber.StatusUnprocessableEntity,
@@ -74,18 +116,66 @@ func NewValidation(msg string, details any) *AppError {
 	}
 }
 
+// StatusCode returns the HTTP status FiberErrorHandler responds to err with.
+func StatusCode(err error) int {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr.Code
+	}
+	return fiber.StatusInternalServerError
+}
+
+// NewErrorHandler returns FiberErrorHandler, reporting errors answered with a 5xx status to
+// reporter first, unless the Recovery middleware already reported them as a panic.
+func NewErrorHandler(reporter errorreport.Reporter) fiber.ErrorHandler {
+	return func(c fiber.Ctx, err error) error {
+		if status := StatusCode(err); status >= fiber.StatusInternalServerError && !errorreport.Reported(c) {
+			reporter.Report(c.Context(), err, errorreport.RequestFrom(c, status))
+		}
+		return FiberErrorHandler(c, err)
+	}
+}
+
+// problemFormat holds the error format set by SetProblemDetails.
+var problemFormat struct {
+	enabled  bool
+	typeBase string
+}
+
+// SetProblemDetails makes FiberErrorHandler answer with RFC 7807 problem details, as
+// application/problem+json, instead of the default response envelope. The problem type
+// is typeBase followed by the error code in lower case, such as
+// https://example.com/errors/not-found, or about:blank when typeBase is empty.
+func SetProblemDetails(enabled bool, typeBase string) {
+	problemFormat.enabled = enabled
+	problemFormat.typeBase = strings.TrimSuffix(typeBase, "/")
+}
+
+// FiberErrorHandler writes err as an error response. Messages, and the field messages of
+// validation errors, are translated into the language the Locale middleware negotiated.
 func FiberErrorHandler(c fiber.Ctx, err error) error {
+	lang := fiber.Locals[string](c, "locale")
+	if lang != "" {
+		c.Set(fiber.HeaderContentLanguage, lang)
+		c.Vary(fiber.HeaderAcceptLanguage)
+	}
+
 	var appErr *AppError
 	if errors.As(err, &appErr) {
+		msg := i18n.Translate(lang, appErr.Message)
 		if appErr.Details != nil {
-			return response.ErrorWithDetails(c, appErr.Code, appErr.ErrorCode, appErr.Message, appErr.Details)
+			return writeError(c, appErr.Code, appErr.ErrorCode, msg, translateDetails(lang, appErr))
 		}
-		return response.Error(c, appErr.Code, appErr.ErrorCode, appErr.Message)
+		return writeError(c, appErr.Code, appErr.ErrorCode, msg, nil)
 	}
 
 	var fiberErr *fiber.Error
 	if errors.As(err, &fiberErr) {
-		return response.Error(c, fiberErr.Code, "FIBER_ERROR", fiberErr.Message)
+		return writeError(c, fiberErr.Code, "FIBER_ERROR", i18n.Translate(lang, fiberErr.Message), nil)
 	}
 
 	slog.Error("unhandled error in error handler",
@@ -93,5 +183,44 @@ func FiberErrorHandler(c fiber.Ctx, err error) error {
 		slog.String("type", fmt.Sprintf("%T", err)),
 		slog.String("path", c.Path()),
 	)
-	return response.Error(c, fiber.StatusInternalServerError, "INTERNAL_ERROR", "Internal Server Error")
+	return writeError(c, fiber.StatusInternalServerError, "INTERNAL_ERROR", i18n.Translate(lang, "Internal Server Error"), nil)
+}
+
+// writeError responds with an error in the format set by SetProblemDetails.
+func writeError(c fiber.Ctx, status int, code, msg string, details any) error {
+	if !problemFormat.enabled {
+		if details != nil {
+			return response.ErrorWithDetails(c, status, code, msg, details)
+		}
+		return response.Error(c, status, code, msg)
+	}
+
+	problemType := "about:blank"
+	if problemFormat.typeBase != "" {
+		problemType = problemFormat.typeBase + "/" + strings.ToLower(strings.ReplaceAll(code, "_", "-"))
+	}
+	return response.ProblemDetails(c, response.Problem{
+		Type:      problemType,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    msg,
+		Instance:  c.Path(),
+		Code:      code,
+		Details:   details,
+		RequestID: fiber.Locals[string](c, "request_id"),
+	})
+}
+
+// translateDetails translates the field messages of a validation error. Other details, such
+// as a ban reason, may hold text users wrote and are left as they are.
+func translateDetails(lang string, appErr *AppError) any {
+	fields, ok := appErr.Details.(map[string]string)
+	if !ok || appErr.ErrorCode != "VALIDATION_ERROR" {
+		return appErr.Details
+	}
+	translated := make(map[string]string, len(fields))
+	for field, msg := range fields {
+		translated[field] = i18n.Translate(lang, msg)
+	}
+	return translated
 }
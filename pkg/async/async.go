@@ -1,22 +1,74 @@
 package async
 
 import (
+	"context"
 	"log/slog"
 	"runtime/debug"
+	"sync"
+	"time"
 )
 
+// running tracks the goroutines started by Go and Every, so shutdown can wait for them.
+var running sync.WaitGroup
+
 // Go runs fn in a new goroutine with panic recovery.
 // Any panic is logged and does not crash the process.
 func Go(fn func()) {
+	running.Add(1)
 	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				slog.Error("async goroutine panicked",
-					slog.Any("panic", r),
-					slog.String("stack", string(debug.Stack())),
-				)
-			}
-		}()
+		defer running.Done()
+		defer recoverPanic()
 		fn()
 	}()
 }
+
+// Every runs fn in a new goroutine immediately and then once per interval until ctx is cancelled.
+// A panic in one run is logged and does not stop later runs.
+func Every(ctx context.Context, interval time.Duration, fn func(ctx context.Context)) {
+	running.Add(1)
+	go func() {
+		defer running.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			func() {
+				defer recoverPanic()
+				fn(ctx)
+			}()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Wait blocks until every goroutine started by Go, and by Every once its context is
+// cancelled, has returned, or until ctx is done. It is called on shutdown so fire-and-forget
+// work, such as sending an email, completes before the process exits.
+func Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func recoverPanic() {
+	if r := recover(); r != nil {
+		slog.Error("async goroutine panicked",
+			slog.Any("panic", r),
+			slog.String("stack", string(debug.Stack())),
+		)
+	}
+}
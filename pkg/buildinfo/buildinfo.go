@@ -0,0 +1,53 @@
+// Package buildinfo describes the running binary. Version and Commit are set at build time:
+//
+//	go build -ldflags "-X github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/buildinfo.Version=v1.2.0 \
+//		-X github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/buildinfo.Commit=$(git rev-parse HEAD)"
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+var (
+	// Version is the release the binary was built from.
+	Version = "dev"
+	// Commit is the VCS revision the binary was built from. When not set at build time it
+	// is read from the module build info, which go build embeds inside a git checkout.
+	Commit = ""
+)
+
+// started approximates the process start time; package variables are initialised before main runs.
+var started = time.Now()
+
+// Info is the build and runtime description of the binary.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build info of the running binary.
+func Get() Info {
+	return Info{Version: Version, Commit: commit(), GoVersion: runtime.Version()}
+}
+
+// Uptime reports how long the process has been running.
+func Uptime() time.Duration {
+	return time.Since(started)
+}
+
+func commit() string {
+	if Commit != "" {
+		return Commit
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			if s.Key == "vcs.revision" {
+				return s.Value
+			}
+		}
+	}
+	return "unknown"
+}
@@ -2,27 +2,65 @@ package cache
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
 )
 
+// Loader computes a value missing from the cache. A nil value is returned as is and not
+// cached.
+type Loader func(ctx context.Context) ([]byte, error)
+
 type Cache interface {
 	Get(ctx context.Context, key string) ([]byte, error)
 	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// SetWithTags sets key like Set and adds it to each tag, so InvalidateTag can delete it
+	// along with the other keys cached for the same thing, such as a user.
+	SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error
+	// GetOrSet returns the value of key, or calls loader and caches its value for ttl under
+	// tags when there is none. Concurrent misses of a key in the process share one loader call.
+	GetOrSet(ctx context.Context, key string, ttl time.Duration, loader Loader, tags ...string) ([]byte, error)
+	// Increment adds delta, which may be negative, to the integer at key, starting from 0 when
+	// there is none, and returns the result. Concurrent increments are never lost. A positive
+	// ttl sets the key to expire ttl after this change; 0 leaves its expiry as it was.
+	Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
 	Delete(ctx context.Context, key string) error
+	// InvalidateTag deletes every key set with the tag. A key set again without the tag after
+	// being tagged may be deleted too, so a key should always be set with the same tags.
+	InvalidateTag(ctx context.Context, tag string) error
 	Exists(ctx context.Context, key string) (bool, error)
 	Close() error
 	Ping(ctx context.Context) error
 }
 
-func NewCache(cfg config.CacheConfig) (Cache, error) {
+// NewCache returns the cache of cfg.Driver. The keys of the shared drivers are put under
+// namespace; the memory driver is private to the process and needs none.
+func NewCache(cfg config.CacheConfig, namespace string) (Cache, error) {
+	var shared Cache
+	var err error
 	switch cfg.Driver {
 	case "redis":
-		return NewRedisCache(cfg)
-	case "memory":
-		return NewMemoryCache(), nil
+		shared, err = NewRedisCache(cfg)
+	case "memcached":
+		shared, err = NewMemcachedCache(cfg)
+	case "tiered":
+		shared, err = NewTieredCache(cfg, namespace)
 	default:
-		return NewMemoryCache(), nil
+		return NewBoundedMemoryCache(cfg.MemoryMaxEntries, cfg.MemoryMaxBytes), nil
+	}
+	if err != nil {
+		return nil, err
 	}
+	return NewNamespacedCache(shared, namespace), nil
+}
+
+// StatsTag is the tag of cached admin statistics, so admin changes to users and files
+// drop them.
+const StatsTag = "stats"
+
+// UserTag is the tag of what is cached about a user, such as their profile or responses
+// showing them, so it can all be invalidated when the user changes.
+func UserTag(userID int64) string {
+	return "user:" + strconv.FormatInt(userID, 10)
 }
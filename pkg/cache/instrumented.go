@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/metrics"
+	"golang.org/x/sync/singleflight"
+)
+
+// InstrumentedCache wraps a Cache and records Prometheus metrics of its operations: hits and
+// misses of Get and Exists, sets and increments, deletes, errors and the duration of each operation.
+type InstrumentedCache struct {
+	Cache
+	driver string
+	flight singleflight.Group
+}
+
+// NewInstrumentedCache returns c with its operations recorded under the driver label.
+func NewInstrumentedCache(c Cache, driver string) *InstrumentedCache {
+	return &InstrumentedCache{Cache: c, driver: driver}
+}
+
+func (i *InstrumentedCache) Get(ctx context.Context, key string) ([]byte, error) {
+	start := time.Now()
+	val, err := i.Cache.Get(ctx, key)
+	if i.observe("get", start, err) {
+		i.lookup(key, val != nil)
+	}
+	return val, err
+}
+
+func (i *InstrumentedCache) Exists(ctx context.Context, key string) (bool, error) {
+	start := time.Now()
+	ok, err := i.Cache.Exists(ctx, key)
+	if i.observe("exists", start, err) {
+		i.lookup(key, ok)
+	}
+	return ok, err
+}
+
+func (i *InstrumentedCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	start := time.Now()
+	err := i.Cache.Set(ctx, key, value, ttl)
+	if i.observe("set", start, err) {
+		metrics.CacheSetsTotal.WithLabelValues(i.driver, keyspace(key)).Inc()
+	}
+	return err
+}
+
+func (i *InstrumentedCache) SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	start := time.Now()
+	err := i.Cache.SetWithTags(ctx, key, value, ttl, tags...)
+	if i.observe("set", start, err) {
+		metrics.CacheSetsTotal.WithLabelValues(i.driver, keyspace(key)).Inc()
+	}
+	return err
+}
+
+// GetOrSet looks the key up and caches the loaded value through Get and SetWithTags, so
+// both are recorded.
+func (i *InstrumentedCache) GetOrSet(ctx context.Context, key string, ttl time.Duration, loader Loader, tags ...string) ([]byte, error) {
+	return getOrSet(ctx, i, &i.flight, key, ttl, loader, tags)
+}
+
+func (i *InstrumentedCache) Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	start := time.Now()
+	n, err := i.Cache.Increment(ctx, key, delta, ttl)
+	if i.observe("increment", start, err) {
+		metrics.CacheSetsTotal.WithLabelValues(i.driver, keyspace(key)).Inc()
+	}
+	return n, err
+}
+
+func (i *InstrumentedCache) Delete(ctx context.Context, key string) error {
+	start := time.Now()
+	err := i.Cache.Delete(ctx, key)
+	if i.observe("delete", start, err) {
+		metrics.CacheDeletesTotal.WithLabelValues(i.driver, keyspace(key)).Inc()
+	}
+	return err
+}
+
+func (i *InstrumentedCache) InvalidateTag(ctx context.Context, tag string) error {
+	start := time.Now()
+	err := i.Cache.InvalidateTag(ctx, tag)
+	if i.observe("invalidate_tag", start, err) {
+		metrics.CacheDeletesTotal.WithLabelValues(i.driver, keyspace(tag)).Inc()
+	}
+	return err
+}
+
+// observe records the duration of an operation started at start, and its error if any. It
+// reports whether the operation succeeded.
+func (i *InstrumentedCache) observe(operation string, start time.Time, err error) bool {
+	metrics.CacheOperationDuration.WithLabelValues(i.driver, operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.CacheErrorsTotal.WithLabelValues(i.driver, operation).Inc()
+		return false
+	}
+	return true
+}
+
+func (i *InstrumentedCache) lookup(key string, hit bool) {
+	if hit {
+		metrics.CacheHitsTotal.WithLabelValues(i.driver, keyspace(key)).Inc()
+	} else {
+		metrics.CacheMissesTotal.WithLabelValues(i.driver, keyspace(key)).Inc()
+	}
+}
+
+// keyspace returns the part of key before its first colon, which every key of the app
+// starts with, or "other" for a key without one. Labeling by keyspace rather than key
+// keeps the number of series bounded.
+func keyspace(key string) string {
+	prefix, _, ok := strings.Cut(key, ":")
+	if !ok || prefix == "" {
+		return "other"
+	}
+	return prefix
+}
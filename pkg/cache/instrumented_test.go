@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type failingCache struct{ Cache }
+
+func (failingCache) Get(context.Context, string) ([]byte, error) {
+	return nil, errors.New("unavailable")
+}
+
+func TestInstrumentedCache_RecordsOperations(t *testing.T) {
+	mem := NewMemoryCache()
+	t.Cleanup(func() { _ = mem.Close() })
+	c := NewInstrumentedCache(mem, "test_ops")
+	ctx := context.Background()
+
+	_, _ = c.Get(ctx, "login_attempts:a@example.com")
+	_ = c.Set(ctx, "login_attempts:a@example.com", []byte("1"), time.Minute)
+	_, _ = c.Get(ctx, "login_attempts:a@example.com")
+	_, _ = c.Exists(ctx, "login_attempts:a@example.com")
+	_ = c.Delete(ctx, "login_attempts:a@example.com")
+
+	for name, tc := range map[string]struct {
+		got  float64
+		want float64
+	}{
+		"hits":    {testutil.ToFloat64(metrics.CacheHitsTotal.WithLabelValues("test_ops", "login_attempts")), 2},
+		"misses":  {testutil.ToFloat64(metrics.CacheMissesTotal.WithLabelValues("test_ops", "login_attempts")), 1},
+		"sets":    {testutil.ToFloat64(metrics.CacheSetsTotal.WithLabelValues("test_ops", "login_attempts")), 1},
+		"deletes": {testutil.ToFloat64(metrics.CacheDeletesTotal.WithLabelValues("test_ops", "login_attempts")), 1},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s = %v, want %v", name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestInstrumentedCache_RecordsErrors(t *testing.T) {
+	c := NewInstrumentedCache(failingCache{}, "test_errors")
+
+	if _, err := c.Get(context.Background(), "user_profile:1"); err == nil {
+		t.Fatal("Get: want error")
+	}
+	if got := testutil.ToFloat64(metrics.CacheErrorsTotal.WithLabelValues("test_errors", "get")); got != 1 {
+		t.Errorf("errors = %v, want 1", got)
+	}
+	// A failed lookup is neither a hit nor a miss
+	if got := testutil.ToFloat64(metrics.CacheMissesTotal.WithLabelValues("test_errors", "user_profile")); got != 0 {
+		t.Errorf("misses = %v, want 0", got)
+	}
+}
+
+func TestKeyspace(t *testing.T) {
+	for key, want := range map[string]string{
+		"login_ip_failures:10.0.0.1": "login_ip_failures",
+		"response:abc":               "response",
+		"nocolon":                    "other",
+		":leading":                   "other",
+	} {
+		if got := keyspace(key); got != want {
+			t.Errorf("keyspace(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// getOrSet implements Cache.GetOrSet on top of the Get and Set of c. Misses of a key are
+// collapsed by group, so a popular key expiring sends one load to the database instead of
+// one per waiting request. Caches failing to read or write are bypassed, so the value is
+// loaded rather than the request failed. The loader runs without the cancellation of the
+// caller that started it, as the callers joining it would otherwise fail with it.
+func getOrSet(ctx context.Context, c Cache, group *singleflight.Group, key string, ttl time.Duration, loader Loader, tags []string) ([]byte, error) {
+	if val, err := c.Get(ctx, key); err == nil && val != nil {
+		return val, nil
+	} else if err != nil {
+		slog.Warn("failed to read cache", slog.String("key", key), slog.Any("error", err))
+	}
+
+	v, err, _ := group.Do(key, func() (any, error) {
+		loadCtx := context.WithoutCancel(ctx)
+		val, err := loader(loadCtx)
+		if err != nil || val == nil {
+			return val, err
+		}
+		if err := c.SetWithTags(loadCtx, key, val, ttl, tags...); err != nil {
+			slog.Warn("failed to write cache", slog.String("key", key), slog.Any("error", err))
+		}
+		return val, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	val, _ := v.([]byte)
+	return val, nil
+}
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrSet_CollapsesConcurrentMisses(t *testing.T) {
+	c := NewMemoryCache()
+	t.Cleanup(func() { _ = c.Close() })
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(context.Context) ([]byte, error) {
+		calls.Add(1)
+		<-release
+		return []byte("value"), nil
+	}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make([][]byte, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = c.GetOrSet(context.Background(), "key", time.Minute, loader)
+		}()
+	}
+	// Give the callers time to queue behind the first load
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected one loader call, got %d", n)
+	}
+	for i, val := range results {
+		if string(val) != "value" {
+			t.Errorf("caller %d got %q", i, val)
+		}
+	}
+	if val, _ := c.Get(context.Background(), "key"); string(val) != "value" {
+		t.Errorf("expected the loaded value to be cached, got %q", val)
+	}
+}
+
+func TestGetOrSet_DoesNotCacheFailures(t *testing.T) {
+	c := NewMemoryCache()
+	t.Cleanup(func() { _ = c.Close() })
+	ctx := context.Background()
+
+	errLoad := errors.New("load failed")
+	if _, err := c.GetOrSet(ctx, "key", time.Minute, func(context.Context) ([]byte, error) {
+		return nil, errLoad
+	}); !errors.Is(err, errLoad) {
+		t.Fatalf("expected the loader error, got %v", err)
+	}
+
+	val, err := c.GetOrSet(ctx, "key", time.Minute, func(context.Context) ([]byte, error) {
+		return []byte("value"), nil
+	})
+	if err != nil || string(val) != "value" {
+		t.Errorf("expected a failed load to be retried, got %q, %v", val, err)
+	}
+}
@@ -0,0 +1,216 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
+)
+
+const (
+	// Longest key memcached accepts
+	memcachedMaxKeyLength = 250
+	// Expirations longer than this are read by memcached as a Unix time
+	memcachedMaxRelativeTTL = 30 * 24 * time.Hour
+)
+
+// MemcachedCache stores values in memcached. Memcached has no sets, so the keys of a tag
+// are kept in an item of their own, which memcached may evict under memory pressure like
+// any other; the keys it held are then only dropped when they expire.
+type MemcachedCache struct {
+	client *memcache.Client
+	flight singleflight.Group
+}
+
+func NewMemcachedCache(cfg config.CacheConfig) (*MemcachedCache, error) {
+	client := memcache.New(cfg.MemcachedServerList()...)
+	if err := client.Ping(); err != nil {
+		return nil, err
+	}
+	return &MemcachedCache{client: client}, nil
+}
+
+func (m *MemcachedCache) Get(_ context.Context, key string) ([]byte, error) {
+	item, err := m.client.Get(memcachedKey(key))
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item.Value, nil
+}
+
+func (m *MemcachedCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	return m.client.Set(&memcache.Item{Key: memcachedKey(key), Value: value, Expiration: memcachedExpiration(ttl)})
+}
+
+// SetWithTags appends the key to the item of each tag that does not hold it yet. Tag items
+// never expire, so a tag outlives its shortest-lived key.
+func (m *MemcachedCache) SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	if err := m.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	line := []byte(memcachedKey(key) + "\n")
+	for _, tag := range tags {
+		if err := m.tag(memcachedKey(tagPrefix+tag), line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemcachedCache) tag(tagKey string, line []byte) error {
+	item, err := m.client.Get(tagKey)
+	if err == nil && bytes.Contains(item.Value, line) {
+		return nil
+	}
+	if err != nil && !errors.Is(err, memcache.ErrCacheMiss) {
+		return err
+	}
+	// Append fails when the tag has no item yet and Add when another writer just made one,
+	// so one of the two succeeds within a few rounds.
+	for range 3 {
+		err = m.client.Append(&memcache.Item{Key: tagKey, Value: line})
+		if !errors.Is(err, memcache.ErrNotStored) {
+			return err
+		}
+		err = m.client.Add(&memcache.Item{Key: tagKey, Value: line})
+		if !errors.Is(err, memcache.ErrNotStored) {
+			return err
+		}
+	}
+	return err
+}
+
+func (m *MemcachedCache) GetOrSet(ctx context.Context, key string, ttl time.Duration, loader Loader, tags ...string) ([]byte, error) {
+	return getOrSet(ctx, m, &m.flight, key, ttl, loader, tags)
+}
+
+// Increment uses memcached's incr and decr, which treat the value as an unsigned integer:
+// a counter never goes below 0.
+func (m *MemcachedCache) Increment(_ context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	key = memcachedKey(key)
+	for {
+		var n uint64
+		var err error
+		if delta >= 0 {
+			n, err = m.client.Increment(key, uint64(delta))
+		} else {
+			n, err = m.client.Decrement(key, uint64(-delta))
+		}
+		if errors.Is(err, memcache.ErrCacheMiss) {
+			// Create the counter, unless another client just did
+			start := max(delta, 0)
+			err = m.client.Add(&memcache.Item{Key: key, Value: []byte(strconv.FormatInt(start, 10)), Expiration: memcachedExpiration(ttl)})
+			if errors.Is(err, memcache.ErrNotStored) {
+				continue
+			}
+			return start, err
+		}
+		if err != nil {
+			return 0, err
+		}
+		if ttl > 0 {
+			if err := m.client.Touch(key, memcachedExpiration(ttl)); err != nil && !errors.Is(err, memcache.ErrCacheMiss) {
+				return 0, err
+			}
+		}
+		return int64(n), nil
+	}
+}
+
+func (m *MemcachedCache) Delete(_ context.Context, key string) error {
+	err := m.client.Delete(memcachedKey(key))
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		return nil
+	}
+	return err
+}
+
+// InvalidateTag empties the item of the tag with compare-and-swap, so keys tagged meanwhile
+// stay in it, then deletes the keys it held.
+func (m *MemcachedCache) InvalidateTag(_ context.Context, tag string) error {
+	tagKey := memcachedKey(tagPrefix + tag)
+	var keys []byte
+	for {
+		item, err := m.client.Get(tagKey)
+		if errors.Is(err, memcache.ErrCacheMiss) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		keys = item.Value
+		item.Value = nil
+		err = m.client.CompareAndSwap(item)
+		if errors.Is(err, memcache.ErrNotStored) {
+			return nil
+		}
+		if !errors.Is(err, memcache.ErrCASConflict) {
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	for _, key := range bytes.Fields(keys) {
+		if err := m.client.Delete(string(key)); err != nil && !errors.Is(err, memcache.ErrCacheMiss) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemcachedCache) Exists(ctx context.Context, key string) (bool, error) {
+	val, err := m.Get(ctx, key)
+	return val != nil, err
+}
+
+func (m *MemcachedCache) Close() error {
+	return m.client.Close()
+}
+
+func (m *MemcachedCache) Ping(_ context.Context) error {
+	return m.client.Ping()
+}
+
+// memcachedKey returns key, or a hash of it when memcached would reject it for its length
+// or for holding spaces or control characters, such as a key built from a request header.
+func memcachedKey(key string) string {
+	if len(key) <= memcachedMaxKeyLength {
+		legal := true
+		for i := 0; i < len(key); i++ {
+			if key[i] <= ' ' || key[i] == 0x7f {
+				legal = false
+				break
+			}
+		}
+		if legal {
+			return key
+		}
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// memcachedExpiration converts ttl to the expiration of an item: whole seconds, rounded
+// up, or a Unix time past 30 days. 0 never expires.
+func memcachedExpiration(ttl time.Duration) int32 {
+	if ttl <= 0 {
+		return 0
+	}
+	if ttl > memcachedMaxRelativeTTL {
+		return int32(time.Now().Add(ttl).Unix())
+	}
+	return int32((ttl + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemcachedKey(t *testing.T) {
+	if got := memcachedKey("user_profile:1"); got != "user_profile:1" {
+		t.Errorf("expected a legal key unchanged, got %q", got)
+	}
+	for _, key := range []string{"idempotency:with space", strings.Repeat("k", 251), "tab\tkey"} {
+		got := memcachedKey(key)
+		if !strings.HasPrefix(got, "sha256:") || len(got) > memcachedMaxKeyLength {
+			t.Errorf("expected %q to be hashed, got %q", key, got)
+		}
+		if memcachedKey(key) != got {
+			t.Errorf("expected the hash of %q to be stable", key)
+		}
+	}
+}
+
+func TestMemcachedExpiration(t *testing.T) {
+	if got := memcachedExpiration(0); got != 0 {
+		t.Errorf("expected 0 to never expire, got %d", got)
+	}
+	if got := memcachedExpiration(1500 * time.Millisecond); got != 2 {
+		t.Errorf("expected seconds rounded up, got %d", got)
+	}
+	if got := memcachedExpiration(time.Hour); got != 3600 {
+		t.Errorf("expected 3600, got %d", got)
+	}
+	// Past 30 days memcached reads the expiration as a Unix time
+	ttl := 60 * 24 * time.Hour
+	want := time.Now().Add(ttl).Unix()
+	if got := int64(memcachedExpiration(ttl)); got < want-5 || got > want+5 {
+		t.Errorf("expected a Unix time near %d, got %d", want, got)
+	}
+}
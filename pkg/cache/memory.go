@@ -1,76 +1,231 @@
 package cache
 
 import (
+	"container/list"
 	"context"
+	"fmt"
+	"strconv"
 	"sync"
 	"time"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/metrics"
+	"golang.org/x/sync/singleflight"
 )
 
 type entry struct {
+	key       string
 	data      []byte
 	expiresAt time.Time
 }
 
-func (e entry) expired() bool {
+func (e *entry) expired() bool {
 	if e.expiresAt.IsZero() {
 		return false
 	}
 	return time.Now().After(e.expiresAt)
 }
 
+// size is the memory an entry is accounted for: its key and value.
+func (e *entry) size() int64 {
+	return int64(len(e.key) + len(e.data))
+}
+
+// MemoryCache keeps values in process. When bounded, the least recently used entries are
+// evicted once it holds more than maxEntries entries or maxBytes bytes of keys and values.
 type MemoryCache struct {
-	mu    sync.RWMutex
-	items map[string]entry
-	done  chan struct{}
+	mu         sync.Mutex
+	items      map[string]*list.Element // values are *entry
+	order      *list.List               // most recently used first
+	bytes      int64
+	maxEntries int
+	maxBytes   int64
+	tags       map[string]map[string]struct{} // keys set with each tag
+	done       chan struct{}
+	flight     singleflight.Group
 }
 
+// NewMemoryCache returns an unbounded memory cache.
 func NewMemoryCache() *MemoryCache {
-	mc := &MemoryCache{items: make(map[string]entry), done: make(chan struct{})}
+	return NewBoundedMemoryCache(0, 0)
+}
+
+// NewBoundedMemoryCache returns a memory cache holding at most maxEntries entries and
+// maxBytes bytes of keys and values. Zero leaves either unbounded.
+func NewBoundedMemoryCache(maxEntries int, maxBytes int64) *MemoryCache {
+	mc := &MemoryCache{
+		items:      make(map[string]*list.Element),
+		order:      list.New(),
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		tags:       make(map[string]map[string]struct{}),
+		done:       make(chan struct{}),
+	}
 	go mc.cleanup()
 	return mc
 }
 
 func (m *MemoryCache) Get(_ context.Context, key string) ([]byte, error) {
-	m.mu.RLock()
-	e, ok := m.items[key]
-	m.mu.RUnlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	if !ok || e.expired() {
+	e := m.lookup(key)
+	if e == nil {
 		return nil, nil
 	}
 	return e.data, nil
 }
 
 func (m *MemoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.store(&entry{key: key, data: value, expiresAt: expiresAt})
+	return nil
+}
+
+func (m *MemoryCache) SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	if err := m.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, tag := range tags {
+		keys := m.tags[tag]
+		if keys == nil {
+			keys = make(map[string]struct{})
+			m.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+	return nil
+}
+
+func (m *MemoryCache) GetOrSet(ctx context.Context, key string, ttl time.Duration, loader Loader, tags ...string) ([]byte, error) {
+	return getOrSet(ctx, m, &m.flight, key, ttl, loader, tags)
+}
+
+func (m *MemoryCache) Increment(_ context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	var n int64
 	var expiresAt time.Time
+	if e := m.lookup(key); e != nil {
+		var err error
+		if n, err = strconv.ParseInt(string(e.data), 10, 64); err != nil {
+			return 0, fmt.Errorf("value of %q is not an integer", key)
+		}
+		expiresAt = e.expiresAt
+	}
 	if ttl > 0 {
 		expiresAt = time.Now().Add(ttl)
 	}
+	n += delta
+	m.store(&entry{key: key, data: []byte(strconv.FormatInt(n, 10)), expiresAt: expiresAt})
+	return n, nil
+}
+
+func (m *MemoryCache) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	m.items[key] = entry{data: value, expiresAt: expiresAt}
+	if el, ok := m.items[key]; ok {
+		m.remove(el)
+	}
 	return nil
 }
 
-func (m *MemoryCache) Delete(_ context.Context, key string) error {
+func (m *MemoryCache) InvalidateTag(_ context.Context, tag string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	delete(m.items, key)
+	for key := range m.tags[tag] {
+		if el, ok := m.items[key]; ok {
+			m.remove(el)
+		}
+	}
+	delete(m.tags, tag)
 	return nil
 }
 
 func (m *MemoryCache) Exists(_ context.Context, key string) (bool, error) {
-	m.mu.RLock()
-	e, ok := m.items[key]
-	m.mu.RUnlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.lookup(key) != nil, nil
+}
+
+// Len returns the number of entries held, including expired ones not yet removed.
+func (m *MemoryCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.items)
+}
+
+// Bytes returns the size of the keys and values held.
+func (m *MemoryCache) Bytes() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.bytes
+}
+
+// lookup returns the live entry of key and marks it as the most recently used, removing it
+// if it expired. m.mu must be held.
+func (m *MemoryCache) lookup(key string) *entry {
+	el, ok := m.items[key]
+	if !ok {
+		return nil
+	}
+	e := el.Value.(*entry)
+	if e.expired() {
+		m.remove(el)
+		return nil
+	}
+	m.order.MoveToFront(el)
+	return e
+}
 
-	if !ok || e.expired() {
-		return false, nil
+// store adds or replaces an entry, then evicts the least recently used entries until the
+// cache is within its bounds. A value larger than maxBytes on its own is not stored, so it
+// cannot flush everything else out. m.mu must be held.
+func (m *MemoryCache) store(e *entry) {
+	if el, ok := m.items[e.key]; ok {
+		m.remove(el)
 	}
-	return true, nil
+	if m.maxBytes > 0 && e.size() > m.maxBytes {
+		return
+	}
+
+	m.items[e.key] = m.order.PushFront(e)
+	m.bytes += e.size()
+	metrics.CacheMemoryEntries.Inc()
+	metrics.CacheMemoryBytes.Add(float64(e.size()))
+
+	for m.overLimit() {
+		m.remove(m.order.Back())
+		metrics.CacheMemoryEvictionsTotal.Inc()
+	}
+}
+
+func (m *MemoryCache) overLimit() bool {
+	return (m.maxEntries > 0 && len(m.items) > m.maxEntries) ||
+		(m.maxBytes > 0 && m.bytes > m.maxBytes)
+}
+
+// remove deletes an entry. Tags still naming its key are pruned by cleanup. m.mu must be
+// held.
+func (m *MemoryCache) remove(el *list.Element) {
+	e := m.order.Remove(el).(*entry)
+	delete(m.items, e.key)
+	m.bytes -= e.size()
+	metrics.CacheMemoryEntries.Dec()
+	metrics.CacheMemoryBytes.Sub(float64(e.size()))
 }
 
 func (m *MemoryCache) cleanup() {
@@ -80,9 +235,20 @@ func (m *MemoryCache) cleanup() {
 		select {
 		case <-ticker.C:
 			m.mu.Lock()
-			for k, e := range m.items {
-				if e.expired() {
-					delete(m.items, k)
+			for _, el := range m.items {
+				if el.Value.(*entry).expired() {
+					m.remove(el)
+				}
+			}
+			// Forget the keys that expired or were deleted, so tags do not grow forever
+			for tag, keys := range m.tags {
+				for k := range keys {
+					if _, ok := m.items[k]; !ok {
+						delete(keys, k)
+					}
+				}
+				if len(keys) == 0 {
+					delete(m.tags, tag)
 				}
 			}
 			m.mu.Unlock()
@@ -92,8 +258,17 @@ func (m *MemoryCache) cleanup() {
 	}
 }
 
+// Close stops the cleanup and releases the entries, so they no longer count towards the
+// memory cache metrics.
 func (m *MemoryCache) Close() error {
 	close(m.done)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for m.order.Len() > 0 {
+		m.remove(m.order.Back())
+	}
+	clear(m.tags)
 	return nil
 }
 
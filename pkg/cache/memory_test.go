@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryCache_InvalidateTag(t *testing.T) {
+	c := NewMemoryCache()
+	t.Cleanup(func() { _ = c.Close() })
+	ctx := context.Background()
+
+	_ = c.SetWithTags(ctx, "profile:1", []byte("a"), time.Minute, "user:1")
+	_ = c.SetWithTags(ctx, "files:1", []byte("b"), time.Minute, "user:1", "files")
+	_ = c.SetWithTags(ctx, "profile:2", []byte("c"), time.Minute, "user:2")
+	_ = c.Set(ctx, "untagged", []byte("d"), time.Minute)
+
+	if err := c.InvalidateTag(ctx, "user:1"); err != nil {
+		t.Fatalf("InvalidateTag: %v", err)
+	}
+
+	for key, want := range map[string]bool{"profile:1": false, "files:1": false, "profile:2": true, "untagged": true} {
+		if ok, _ := c.Exists(ctx, key); ok != want {
+			t.Errorf("Exists(%q) = %v, want %v", key, ok, want)
+		}
+	}
+	// Invalidating a tag with no keys left, or never used, is not an error
+	if err := c.InvalidateTag(ctx, "files"); err != nil {
+		t.Errorf("InvalidateTag: %v", err)
+	}
+	if err := c.InvalidateTag(ctx, "unknown"); err != nil {
+		t.Errorf("InvalidateTag: %v", err)
+	}
+}
+
+func TestMemoryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewBoundedMemoryCache(2, 0)
+	t.Cleanup(func() { _ = c.Close() })
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "a", []byte("1"), time.Minute)
+	_ = c.Set(ctx, "b", []byte("2"), time.Minute)
+	// Reading a makes b the least recently used
+	_, _ = c.Get(ctx, "a")
+	_ = c.Set(ctx, "c", []byte("3"), time.Minute)
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if ok, _ := c.Exists(ctx, key); ok != want {
+			t.Errorf("Exists(%q) = %v, want %v", key, ok, want)
+		}
+	}
+	if got := c.Len(); got != 2 {
+		t.Errorf("Len() = %d, want 2", got)
+	}
+}
+
+func TestMemoryCache_MaxBytes(t *testing.T) {
+	c := NewBoundedMemoryCache(0, 10)
+	t.Cleanup(func() { _ = c.Close() })
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "a", []byte("1234"), time.Minute)
+	_ = c.Set(ctx, "b", []byte("1234"), time.Minute)
+	if got := c.Bytes(); got != 10 {
+		t.Fatalf("Bytes() = %d, want 10", got)
+	}
+
+	// Replacing a value accounts for the new size only
+	_ = c.Set(ctx, "b", []byte("12"), time.Minute)
+	if got := c.Bytes(); got != 8 {
+		t.Errorf("Bytes() = %d, want 8", got)
+	}
+
+	_ = c.Set(ctx, "c", []byte("1234"), time.Minute)
+	if ok, _ := c.Exists(ctx, "a"); ok {
+		t.Error("a should have been evicted")
+	}
+
+	// A value over the limit on its own is not stored and evicts nothing
+	_ = c.Set(ctx, "huge", []byte("12345678901"), time.Minute)
+	if ok, _ := c.Exists(ctx, "huge"); ok {
+		t.Error("huge should not have been stored")
+	}
+	for _, key := range []string{"b", "c"} {
+		if ok, _ := c.Exists(ctx, key); !ok {
+			t.Errorf("%s should still be cached", key)
+		}
+	}
+}
+
+func TestMemoryCache_Increment(t *testing.T) {
+	c := NewMemoryCache()
+	t.Cleanup(func() { _ = c.Close() })
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Go(func() {
+			_, _ = c.Increment(ctx, "login_attempts:a@example.com", 1, time.Minute)
+		})
+	}
+	wg.Wait()
+
+	if n, err := c.Increment(ctx, "login_attempts:a@example.com", -10, 0); err != nil || n != 40 {
+		t.Fatalf("Increment = %d, %v, want 40", n, err)
+	}
+	// Counters read back as decimal strings
+	if data, _ := c.Get(ctx, "login_attempts:a@example.com"); string(data) != "40" {
+		t.Errorf("Get = %q, want 40", data)
+	}
+
+	_ = c.Set(ctx, "text", []byte("abc"), time.Minute)
+	if _, err := c.Increment(ctx, "text", 1, 0); err == nil {
+		t.Error("expected an error incrementing a non-integer")
+	}
+}
+
+func TestMemoryCache_IncrementTTL(t *testing.T) {
+	c := NewMemoryCache()
+	t.Cleanup(func() { _ = c.Close() })
+	ctx := context.Background()
+
+	_, _ = c.Increment(ctx, "counter", 1, 20*time.Millisecond)
+	// A zero ttl keeps the expiry set before
+	_, _ = c.Increment(ctx, "counter", 1, 0)
+	time.Sleep(30 * time.Millisecond)
+	if n, _ := c.Increment(ctx, "counter", 1, 0); n != 1 {
+		t.Errorf("expected the expired counter to start over, got %d", n)
+	}
+}
@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// NamespacedCache wraps a Cache shared with other apps or releases and prefixes every key
+// and tag with its namespace, so they never read or invalidate each other's values.
+type NamespacedCache struct {
+	Cache
+	namespace string
+}
+
+// NewNamespacedCache returns c with its keys and tags under namespace.
+func NewNamespacedCache(c Cache, namespace string) *NamespacedCache {
+	return &NamespacedCache{Cache: c, namespace: namespace}
+}
+
+func (n *NamespacedCache) Get(ctx context.Context, key string) ([]byte, error) {
+	return n.Cache.Get(ctx, n.namespace+key)
+}
+
+func (n *NamespacedCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return n.Cache.Set(ctx, n.namespace+key, value, ttl)
+}
+
+func (n *NamespacedCache) SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	return n.Cache.SetWithTags(ctx, n.namespace+key, value, ttl, n.tags(tags)...)
+}
+
+func (n *NamespacedCache) GetOrSet(ctx context.Context, key string, ttl time.Duration, loader Loader, tags ...string) ([]byte, error) {
+	return n.Cache.GetOrSet(ctx, n.namespace+key, ttl, loader, n.tags(tags)...)
+}
+
+func (n *NamespacedCache) Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	return n.Cache.Increment(ctx, n.namespace+key, delta, ttl)
+}
+
+func (n *NamespacedCache) Delete(ctx context.Context, key string) error {
+	return n.Cache.Delete(ctx, n.namespace+key)
+}
+
+func (n *NamespacedCache) InvalidateTag(ctx context.Context, tag string) error {
+	return n.Cache.InvalidateTag(ctx, n.namespace+tag)
+}
+
+func (n *NamespacedCache) Exists(ctx context.Context, key string) (bool, error) {
+	return n.Cache.Exists(ctx, n.namespace+key)
+}
+
+func (n *NamespacedCache) tags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	prefixed := make([]string, len(tags))
+	for i, tag := range tags {
+		prefixed[i] = n.namespace + tag
+	}
+	return prefixed
+}
@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNamespacedCache_IsolatesNamespaces(t *testing.T) {
+	shared := NewMemoryCache()
+	t.Cleanup(func() { _ = shared.Close() })
+	ctx := context.Background()
+	prod := NewNamespacedCache(shared, "app:production:v1:")
+	staging := NewNamespacedCache(shared, "app:staging:v1:")
+
+	_ = prod.SetWithTags(ctx, "user_profile:1", []byte("prod"), time.Minute, "user:1")
+	_ = staging.SetWithTags(ctx, "user_profile:1", []byte("staging"), time.Minute, "user:1")
+
+	if data, _ := shared.Get(ctx, "app:production:v1:user_profile:1"); string(data) != "prod" {
+		t.Errorf("expected the key under the namespace, got %q", data)
+	}
+	if data, _ := staging.Get(ctx, "user_profile:1"); string(data) != "staging" {
+		t.Errorf("Get = %q, want staging", data)
+	}
+
+	// Invalidating a tag only reaches the keys of the same namespace
+	_ = prod.InvalidateTag(ctx, "user:1")
+	if ok, _ := prod.Exists(ctx, "user_profile:1"); ok {
+		t.Error("expected the production key to be invalidated")
+	}
+	if ok, _ := staging.Exists(ctx, "user_profile:1"); !ok {
+		t.Error("expected the staging key to survive")
+	}
+
+	// A new schema version reads none of the values of the previous one
+	if data, _ := NewNamespacedCache(shared, "app:staging:v2:").Get(ctx, "user_profile:1"); data != nil {
+		t.Errorf("expected a miss in the new version, got %q", data)
+	}
+}
@@ -7,28 +7,90 @@ import (
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
 
 	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
 )
 
+// tagPrefix is the prefix of the sets holding the keys set with each tag.
+const tagPrefix = "cache_tag:"
+
+// invalidateTagScript deletes the keys of a tag and the tag in one step, so a key tagged
+// meanwhile is not left out of the set and never invalidated. It returns the keys.
+var invalidateTagScript = redis.NewScript(`
+local keys = redis.call("SMEMBERS", KEYS[1])
+for i = 1, #keys, 1000 do
+	redis.call("DEL", unpack(keys, i, math.min(i + 999, #keys)))
+end
+redis.call("DEL", KEYS[1])
+return keys
+`)
+
+// setWithTagsScript sets KEYS[1] to ARGV[1] for ARGV[2] milliseconds, 0 meaning no expiry,
+// and adds it to the tag sets in the other keys, extending a set that would expire first.
+var setWithTagsScript = redis.NewScript(`
+local ttl = tonumber(ARGV[2])
+if ttl > 0 then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ttl)
+else
+	redis.call("SET", KEYS[1], ARGV[1])
+end
+for i = 2, #KEYS do
+	local existed = redis.call("EXISTS", KEYS[i]) == 1
+	local remaining = redis.call("PTTL", KEYS[i])
+	redis.call("SADD", KEYS[i], KEYS[1])
+	if ttl == 0 then
+		redis.call("PERSIST", KEYS[i])
+	elseif not existed or (remaining >= 0 and remaining < ttl) then
+		redis.call("PEXPIRE", KEYS[i], ttl)
+	end
+end
+return 1
+`)
+
+// tagKeyScript adds ARGV[1] to the tag set in KEYS[1] like setWithTagsScript, for ARGV[2]
+// milliseconds. Sets in a cluster are tagged one by one with it, as a key and its tags
+// usually live in different hash slots.
+var tagKeyScript = redis.NewScript(`
+local ttl = tonumber(ARGV[2])
+local existed = redis.call("EXISTS", KEYS[1]) == 1
+local remaining = redis.call("PTTL", KEYS[1])
+redis.call("SADD", KEYS[1], ARGV[1])
+if ttl == 0 then
+	redis.call("PERSIST", KEYS[1])
+elseif not existed or (remaining >= 0 and remaining < ttl) then
+	redis.call("PEXPIRE", KEYS[1], ttl)
+end
+return 1
+`)
+
+// popTagScript deletes the tag set in KEYS[1] and returns its keys, for a cluster to delete
+// them one by one.
+var popTagScript = redis.NewScript(`
+local keys = redis.call("SMEMBERS", KEYS[1])
+redis.call("DEL", KEYS[1])
+return keys
+`)
+
 type RedisCache struct {
-	client *redis.Client
+	client  redis.UniversalClient
+	cluster bool
+	flight  singleflight.Group
 }
 
 func NewRedisCache(cfg config.CacheConfig) (*RedisCache, error) {
-	opts, err := redis.ParseURL(cfg.RedisURL)
+	client, cluster, err := newRedisClient(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	client := redis.NewClient(opts)
-
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
 		return nil, err
 	}
 
-	return &RedisCache{client: client}, nil
+	return &RedisCache{client: client, cluster: cluster}, nil
 }
 
 func (r *RedisCache) Get(ctx context.Context, key string) ([]byte, error) {
@@ -42,14 +104,105 @@ func (r *RedisCache) Get(ctx context.Context, key string) ([]byte, error) {
 	return val, nil
 }
 
+// getWithTTL returns the value of key and how long it has left, 0 meaning no expiry.
+func (r *RedisCache) getWithTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	var get *redis.StringCmd
+	var ttl *redis.DurationCmd
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, key)
+		ttl = pipe.PTTL(ctx, key)
+		return nil
+	})
+	if err == redis.Nil {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	val, _ := get.Bytes()
+	// PTTL is negative for a key without expiry
+	return val, max(ttl.Val(), 0), nil
+}
+
 func (r *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
 	return r.client.Set(ctx, key, value, ttl).Err()
 }
 
+// SetWithTags keeps the keys of a tag in a set living as long as its longest-lived key. In a
+// cluster the key is added to its tags before being set, rather than in the same step, so a
+// failure leaves a tagged key unset instead of a set key that invalidation misses.
+func (r *RedisCache) SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	if len(tags) == 0 {
+		return r.Set(ctx, key, value, ttl)
+	}
+	if r.cluster {
+		for _, tag := range tags {
+			if err := tagKeyScript.Run(ctx, r.client, []string{tagPrefix + tag}, key, ttl.Milliseconds()).Err(); err != nil {
+				return err
+			}
+		}
+		return r.Set(ctx, key, value, ttl)
+	}
+	keys := make([]string, 0, len(tags)+1)
+	keys = append(keys, key)
+	for _, tag := range tags {
+		keys = append(keys, tagPrefix+tag)
+	}
+	return setWithTagsScript.Run(ctx, r.client, keys, value, ttl.Milliseconds()).Err()
+}
+
+// GetOrSet collapses concurrent misses within the process; each replica still loads a
+// missing key once.
+func (r *RedisCache) GetOrSet(ctx context.Context, key string, ttl time.Duration, loader Loader, tags ...string) ([]byte, error) {
+	return getOrSet(ctx, r, &r.flight, key, ttl, loader, tags)
+}
+
+// Increment runs INCRBY and the expiry in one transaction, so a counter is never left
+// without its TTL.
+func (r *RedisCache) Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	var incr *redis.IntCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.IncrBy(ctx, key, delta)
+		if ttl > 0 {
+			pipe.PExpire(ctx, key, ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
 func (r *RedisCache) Delete(ctx context.Context, key string) error {
 	return r.client.Del(ctx, key).Err()
 }
 
+func (r *RedisCache) InvalidateTag(ctx context.Context, tag string) error {
+	_, err := r.invalidateTag(ctx, tag)
+	return err
+}
+
+// invalidateTag deletes the keys of tag and returns them. In a cluster the tag is removed
+// first and its keys deleted afterwards, each in its own slot.
+func (r *RedisCache) invalidateTag(ctx context.Context, tag string) ([]string, error) {
+	if !r.cluster {
+		return invalidateTagScript.Run(ctx, r.client, []string{tagPrefix + tag}).StringSlice()
+	}
+
+	keys, err := popTagScript.Run(ctx, r.client, []string{tagPrefix + tag}).StringSlice()
+	if err != nil || len(keys) == 0 {
+		return keys, err
+	}
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(ctx, key)
+		}
+		return nil
+	})
+	return keys, err
+}
+
 func (r *RedisCache) Exists(ctx context.Context, key string) (bool, error) {
 	n, err := r.client.Exists(ctx, key).Result()
 	if err != nil {
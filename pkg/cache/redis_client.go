@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// newRedisClient connects to the Redis deployment of cfg.RedisMode: a single server from
+// REDIS_URL, a master found through Sentinel, or a cluster. It reports whether the client
+// talks to a cluster, where commands of one call must stay within one hash slot.
+func newRedisClient(cfg config.CacheConfig) (redis.UniversalClient, bool, error) {
+	tlsConfig, err := redisTLSConfig(cfg)
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch cfg.RedisMode {
+	case "sentinel":
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       cfg.RedisMasterName,
+			SentinelAddrs:    cfg.RedisAddrList(),
+			SentinelUsername: cfg.RedisSentinelUsername,
+			SentinelPassword: cfg.RedisSentinelPassword,
+			Username:         cfg.RedisUsername,
+			Password:         cfg.RedisPassword,
+			DB:               cfg.RedisDB,
+			TLSConfig:        tlsConfig,
+		}), false, nil
+	case "cluster":
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:     cfg.RedisAddrList(),
+			Username:  cfg.RedisUsername,
+			Password:  cfg.RedisPassword,
+			TLSConfig: tlsConfig,
+		}), true, nil
+	default:
+		opts, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			return nil, false, err
+		}
+		// A rediss:// URL enables TLS on its own; the CA file still applies to it
+		if opts.TLSConfig == nil {
+			opts.TLSConfig = tlsConfig
+		} else if tlsConfig != nil {
+			opts.TLSConfig.RootCAs = tlsConfig.RootCAs
+		}
+		return redis.NewClient(opts), false, nil
+	}
+}
+
+// redisTLSConfig returns the TLS settings of REDIS_TLS and REDIS_TLS_CA_FILE, or nil when
+// neither is set.
+func redisTLSConfig(cfg config.CacheConfig) (*tls.Config, error) {
+	if !cfg.RedisTLS && cfg.RedisTLSCAFile == "" {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.RedisTLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.RedisTLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read REDIS_TLS_CA_FILE: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("REDIS_TLS_CA_FILE contains no PEM certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
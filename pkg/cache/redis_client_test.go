@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestNewRedisClient_Modes(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.CacheConfig
+		cluster bool
+		check   func(redis.UniversalClient) bool
+	}{
+		{
+			name:  "standalone",
+			cfg:   config.CacheConfig{RedisMode: "standalone", RedisURL: "redis://localhost:6379/2"},
+			check: func(c redis.UniversalClient) bool { return c.(*redis.Client).Options().DB == 2 },
+		},
+		{
+			name: "sentinel",
+			cfg:  config.CacheConfig{RedisMode: "sentinel", RedisAddrs: "s1:26379,s2:26379", RedisMasterName: "mymaster"},
+			// A failover client is a *redis.Client talking to the master Sentinel reports
+			check: func(c redis.UniversalClient) bool { _, ok := c.(*redis.Client); return ok },
+		},
+		{
+			name:    "cluster",
+			cfg:     config.CacheConfig{RedisMode: "cluster", RedisAddrs: "n1:6379,n2:6379", RedisTLS: true},
+			cluster: true,
+			check: func(c redis.UniversalClient) bool {
+				cc, ok := c.(*redis.ClusterClient)
+				return ok && len(cc.Options().Addrs) == 2 && cc.Options().TLSConfig != nil
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, cluster, err := newRedisClient(tt.cfg)
+			if err != nil {
+				t.Fatalf("newRedisClient: %v", err)
+			}
+			t.Cleanup(func() { _ = client.Close() })
+			if cluster != tt.cluster {
+				t.Errorf("cluster = %v, want %v", cluster, tt.cluster)
+			}
+			if !tt.check(client) {
+				t.Errorf("unexpected client %T", client)
+			}
+		})
+	}
+}
+
+func TestRedisTLSConfig(t *testing.T) {
+	if cfg, err := redisTLSConfig(config.CacheConfig{}); cfg != nil || err != nil {
+		t.Errorf("expected no TLS by default, got %v, %v", cfg, err)
+	}
+	if _, err := redisTLSConfig(config.CacheConfig{RedisTLS: true, RedisTLSCAFile: "/nonexistent/ca.pem"}); err == nil {
+		t.Error("expected an error for a missing CA file")
+	}
+}
@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Stats counts cache lookups since the process started.
+type Stats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// HitRate is the fraction of lookups that found a value, or 0 before the first lookup.
+func (s Stats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// StatsCache wraps a Cache and counts hits and misses of Get and GetOrSet. Failed lookups
+// are not counted.
+type StatsCache struct {
+	Cache
+	hits   atomic.Uint64
+	misses atomic.Uint64
+	flight singleflight.Group
+}
+
+// NewStatsCache returns c with lookup counting.
+func NewStatsCache(c Cache) *StatsCache {
+	return &StatsCache{Cache: c}
+}
+
+func (s *StatsCache) Get(ctx context.Context, key string) ([]byte, error) {
+	val, err := s.Cache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if val == nil {
+		s.misses.Add(1)
+	} else {
+		s.hits.Add(1)
+	}
+	return val, nil
+}
+
+// GetOrSet looks the key up through Get, so it is counted.
+func (s *StatsCache) GetOrSet(ctx context.Context, key string, ttl time.Duration, loader Loader, tags ...string) ([]byte, error) {
+	return getOrSet(ctx, s, &s.flight, key, ttl, loader, tags)
+}
+
+// Stats returns the lookup counts so far.
+func (s *StatsCache) Stats() Stats {
+	return Stats{Hits: s.hits.Load(), Misses: s.misses.Load()}
+}
@@ -0,0 +1,198 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
+)
+
+// invalidationChannel is the Redis channel tiered caches announce changed keys on, after
+// the namespace of the app.
+const invalidationChannel = "cache:invalidate"
+
+type localEntry struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+// invalidation announces keys that changed, so other processes drop their copies.
+type invalidation struct {
+	Source string   `json:"source"`
+	Keys   []string `json:"keys"`
+}
+
+// TieredCache keeps recently read values in an in-process LRU in front of Redis, so hot
+// keys are served without a round trip. Writes go to Redis and are announced over pub/sub,
+// and every process drops its copy of the changed keys. An announcement missed while the
+// subscription reconnects, or racing a read, leaves a stale copy for at most the local TTL.
+type TieredCache struct {
+	remote   *RedisCache
+	local    *lru.Cache[string, localEntry]
+	localTTL time.Duration
+	id       string
+	channel  string
+	pubsub   *redis.PubSub
+	flight   singleflight.Group
+}
+
+func NewTieredCache(cfg config.CacheConfig, namespace string) (*TieredCache, error) {
+	remote, err := NewRedisCache(cfg)
+	if err != nil {
+		return nil, err
+	}
+	local, err := lru.New[string, localEntry](cfg.LocalSize)
+	if err != nil {
+		_ = remote.Close()
+		return nil, err
+	}
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		_ = remote.Close()
+		return nil, err
+	}
+
+	t := &TieredCache{
+		remote:   remote,
+		local:    local,
+		localTTL: time.Duration(cfg.LocalTTL) * time.Second,
+		id:       hex.EncodeToString(b),
+		channel:  namespace + invalidationChannel,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	t.pubsub = remote.client.Subscribe(ctx, t.channel)
+	// Wait for the subscription, so no change is missed once the cache is in use
+	if _, err := t.pubsub.Receive(ctx); err != nil {
+		_ = t.pubsub.Close()
+		_ = remote.Close()
+		return nil, err
+	}
+	go t.listen()
+	return t, nil
+}
+
+// listen drops the local copies of keys other processes announce, until Close.
+func (t *TieredCache) listen() {
+	for msg := range t.pubsub.Channel() {
+		var inv invalidation
+		if err := json.Unmarshal([]byte(msg.Payload), &inv); err != nil {
+			slog.Warn("invalid cache invalidation", slog.Any("error", err))
+			continue
+		}
+		if inv.Source == t.id {
+			continue
+		}
+		for _, key := range inv.Keys {
+			t.local.Remove(key)
+		}
+	}
+}
+
+// announce drops the local copies of keys, here and in the other processes.
+func (t *TieredCache) announce(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		t.local.Remove(key)
+	}
+	if len(keys) == 0 {
+		return
+	}
+	payload, _ := json.Marshal(invalidation{Source: t.id, Keys: keys})
+	if err := t.remote.client.Publish(ctx, t.channel, payload).Err(); err != nil {
+		slog.Warn("failed to announce cache invalidation", slog.Any("error", err))
+	}
+}
+
+// store keeps a local copy of a value Redis holds for ttl more, 0 meaning no expiry.
+func (t *TieredCache) store(key string, data []byte, ttl time.Duration) {
+	if ttl <= 0 || ttl > t.localTTL {
+		ttl = t.localTTL
+	}
+	t.local.Add(key, localEntry{data: data, expiresAt: time.Now().Add(ttl)})
+}
+
+func (t *TieredCache) Get(ctx context.Context, key string) ([]byte, error) {
+	if e, ok := t.local.Get(key); ok {
+		if time.Now().Before(e.expiresAt) {
+			return e.data, nil
+		}
+		t.local.Remove(key)
+	}
+
+	data, ttl, err := t.remote.getWithTTL(ctx, key)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	t.store(key, data, ttl)
+	return data, nil
+}
+
+func (t *TieredCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return t.SetWithTags(ctx, key, value, ttl)
+}
+
+func (t *TieredCache) SetWithTags(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	if err := t.remote.SetWithTags(ctx, key, value, ttl, tags...); err != nil {
+		return err
+	}
+	t.announce(ctx, key)
+	t.store(key, value, ttl)
+	return nil
+}
+
+func (t *TieredCache) GetOrSet(ctx context.Context, key string, ttl time.Duration, loader Loader, tags ...string) ([]byte, error) {
+	return getOrSet(ctx, t, &t.flight, key, ttl, loader, tags)
+}
+
+func (t *TieredCache) Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	n, err := t.remote.Increment(ctx, key, delta, ttl)
+	if err != nil {
+		return 0, err
+	}
+	t.announce(ctx, key)
+	return n, nil
+}
+
+func (t *TieredCache) Delete(ctx context.Context, key string) error {
+	if err := t.remote.Delete(ctx, key); err != nil {
+		return err
+	}
+	t.announce(ctx, key)
+	return nil
+}
+
+// InvalidateTag announces the keys the tag held, as the other processes do not know the
+// tags of the values they copied.
+func (t *TieredCache) InvalidateTag(ctx context.Context, tag string) error {
+	keys, err := t.remote.invalidateTag(ctx, tag)
+	if err != nil {
+		return err
+	}
+	t.announce(ctx, keys...)
+	return nil
+}
+
+func (t *TieredCache) Exists(ctx context.Context, key string) (bool, error) {
+	if e, ok := t.local.Get(key); ok && time.Now().Before(e.expiresAt) {
+		return true, nil
+	}
+	return t.remote.Exists(ctx, key)
+}
+
+func (t *TieredCache) Close() error {
+	_ = t.pubsub.Close()
+	return t.remote.Close()
+}
+
+func (t *TieredCache) Ping(ctx context.Context) error {
+	return t.remote.Ping(ctx)
+}
@@ -0,0 +1,82 @@
+// Package clientip resolves the address of the client behind trusted reverse proxies.
+//
+// Proxies append the address they received a request from to a header such as
+// X-Forwarded-For, after whatever the client sent in it, so only the entries added by
+// trusted proxies can be believed. The client is the rightmost entry that is not a trusted
+// proxy; anything to its left was written by the client and is ignored.
+package clientip
+
+import (
+	"net/netip"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// localsKey is the Fiber local the resolved address is stored in.
+const localsKey = "client_ip"
+
+// Resolver finds the client address of requests arriving through the proxies in proxies,
+// which name the address they received a request from in header.
+type Resolver struct {
+	proxies []netip.Prefix
+	header  string
+}
+
+// NewResolver returns a resolver believing header on requests from proxies. Without
+// proxies every request is attributed to the address it comes from.
+func NewResolver(proxies []netip.Prefix, header string) *Resolver {
+	return &Resolver{proxies: proxies, header: header}
+}
+
+// Resolve returns the client address of a request from remote carrying the given values
+// of the proxy header.
+func (r *Resolver) Resolve(remote string, values []string) string {
+	addr, err := netip.ParseAddr(remote)
+	if err != nil || !r.trusted(addr) {
+		return remote
+	}
+
+	client := remote
+	for i := len(values) - 1; i >= 0; i-- {
+		hops := strings.Split(values[i], ",")
+		for j := len(hops) - 1; j >= 0; j-- {
+			hop, err := netip.ParseAddr(strings.TrimSpace(hops[j]))
+			if err != nil {
+				// A malformed entry was not written by a trusted proxy, so the last
+				// address one of them vouched for is as far as we can go.
+				return client
+			}
+			client = hop.Unmap().String()
+			if !r.trusted(hop) {
+				return client
+			}
+		}
+	}
+	// Every hop is a trusted proxy, so the leftmost one sent the request.
+	return client
+}
+
+func (r *Resolver) trusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range r.proxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Set stores the client address of the request for Get.
+func Set(c fiber.Ctx, ip string) {
+	c.Locals(localsKey, ip)
+}
+
+// Get returns the client address stored by Set, or the address the request comes from
+// when none was.
+func Get(c fiber.Ctx) string {
+	if ip := fiber.Locals[string](c, localsKey); ip != "" {
+		return ip
+	}
+	return c.IP()
+}
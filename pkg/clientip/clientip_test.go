@@ -0,0 +1,44 @@
+package clientip
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestResolver_Resolve(t *testing.T) {
+	r := NewResolver([]netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.0.2.1/32"),
+	}, "X-Forwarded-For")
+
+	tests := []struct {
+		name   string
+		remote string
+		values []string
+		want   string
+	}{
+		{"untrusted remote ignores the header", "203.0.113.9", []string{"198.51.100.1"}, "203.0.113.9"},
+		{"trusted remote without header", "10.0.0.1", nil, "10.0.0.1"},
+		{"single hop", "10.0.0.1", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"spoofed leftmost entry is ignored", "10.0.0.1", []string{"1.2.3.4, 198.51.100.1"}, "198.51.100.1"},
+		{"trusted hops are skipped", "10.0.0.1", []string{"1.2.3.4, 198.51.100.1, 192.0.2.1, 10.0.0.2"}, "198.51.100.1"},
+		{"repeated header lines", "10.0.0.1", []string{"1.2.3.4", "198.51.100.1, 10.0.0.2"}, "198.51.100.1"},
+		{"all hops trusted", "10.0.0.1", []string{"10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+		{"malformed hop stops at the last trusted one", "10.0.0.1", []string{"1.2.3.4, garbage, 10.0.0.2"}, "10.0.0.2"},
+		{"IPv4-mapped addresses", "::ffff:10.0.0.1", []string{"::ffff:198.51.100.1"}, "198.51.100.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Resolve(tt.remote, tt.values); got != tt.want {
+				t.Errorf("Resolve(%q, %q) = %q, want %q", tt.remote, tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolver_WithoutProxies(t *testing.T) {
+	r := NewResolver(nil, "X-Forwarded-For")
+	if got := r.Resolve("203.0.113.9", []string{"198.51.100.1"}); got != "203.0.113.9" {
+		t.Errorf("Resolve = %q, want the remote address", got)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/telemetry"
 )
 
 func NewPool(ctx context.Context, dbCfg config.DBConfig) (*pgxpool.Pool, error) {
@@ -25,6 +26,7 @@ func NewPool(ctx context.Context, dbCfg config.DBConfig) (*pgxpool.Pool, error)
 	poolCfg.MinConns = dbCfg.MinConns
 	poolCfg.MaxConnLifetime = time.Duration(dbCfg.MaxConnLifetime) * time.Second
 	poolCfg.MaxConnIdleTime = time.Duration(dbCfg.MaxConnIdleTime) * time.Second
+	poolCfg.ConnConfig.Tracer = telemetry.QueryTracer{}
 
 	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
 	if err != nil {
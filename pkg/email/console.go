@@ -13,10 +13,16 @@ func NewConsoleSender() *ConsoleSender {
 }
 
 func (s *ConsoleSender) Send(_ context.Context, msg Message) error {
+	attachments := make([]string, len(msg.Attachments))
+	for i, a := range msg.Attachments {
+		attachments[i] = a.Filename
+	}
 	slog.Info("email sent (console driver)",
 		slog.String("to", strings.Join(msg.To, ", ")),
 		slog.String("subject", msg.Subject),
+		slog.String("locale", msg.Locale),
 		slog.String("body", msg.Body),
+		slog.String("attachments", strings.Join(attachments, ", ")),
 	)
 	return nil
 }
@@ -2,26 +2,88 @@ package email
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"mime"
+	"net/textproto"
+	"path/filepath"
 
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
 )
 
 type Message struct {
-	To      []string
-	Subject string
-	Body    string
-	HTML    string
+	To          []string
+	Subject     string
+	Body        string
+	HTML        string
+	Locale      string // BCP 47 language tag of the recipient, sent as Content-Language when set
+	Attachments []Attachment
+}
+
+// Attachment is a file sent with a message. An attachment with a ContentID is an inline
+// image instead, shown where the HTML body references it as "cid:<ContentID>", such as
+// a logo in a branded template.
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
+	ContentID   string `json:"content_id,omitempty"`
+}
+
+// Inline reports whether the attachment is an inline image referenced from the HTML body.
+func (a Attachment) Inline() bool {
+	return a.ContentID != ""
+}
+
+// contentType returns the attachment's content type, guessed from the filename when unset.
+func (a Attachment) contentType() string {
+	if a.ContentType != "" {
+		return a.ContentType
+	}
+	if t := mime.TypeByExtension(filepath.Ext(a.Filename)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
 }
 
 type Sender interface {
 	Send(ctx context.Context, msg Message) error
 }
 
+// ErrInvalidAttachment is returned for an attachment that cannot be sent as given, such
+// as one without a filename. Retrying cannot fix it, so IsPermanent reports true.
+var ErrInvalidAttachment = errors.New("invalid email attachment")
+
+// ErrSuppressed is returned when every recipient of a message is on the suppression list
+// after a hard bounce or a complaint. Retrying cannot fix it, so IsPermanent reports true.
+var ErrSuppressed = errors.New("all recipients are suppressed")
+
+// IsPermanent reports whether err is a rejection retrying cannot fix, such as an SMTP 5xx
+// reply for a mailbox that does not exist or a provider API refusing the message.
+func IsPermanent(err error) bool {
+	if errors.Is(err, ErrInvalidAttachment) || errors.Is(err, ErrSuppressed) {
+		return true
+	}
+	var reply *textproto.Error
+	if errors.As(err, &reply) {
+		return reply.Code >= 500
+	}
+	var perr *ProviderError
+	return errors.As(err, &perr) && perr.Permanent
+}
+
 func NewSender(cfg config.EmailConfig) (Sender, error) {
 	switch cfg.Driver {
 	case "smtp":
 		return NewSMTPSender(cfg), nil
+	case "sendgrid":
+		return NewSendGridSender(cfg), nil
+	case "ses":
+		return NewSESSender(cfg), nil
+	case "mailgun":
+		return NewMailgunSender(cfg), nil
+	case "postmark":
+		return NewPostmarkSender(cfg), nil
 	case "console":
 		return NewConsoleSender(), nil
 	default:
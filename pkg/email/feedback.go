@@ -0,0 +1,201 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Kinds of delivery feedback that suppress an address.
+const (
+	FeedbackBounce    = "bounce"    // the mailbox does not exist or permanently refuses mail
+	FeedbackComplaint = "complaint" // the recipient marked a message as spam
+)
+
+// ErrUnknownProvider is returned by ParseFeedback for a provider it cannot parse.
+var ErrUnknownProvider = errors.New("unknown email provider")
+
+// Feedback is a hard bounce or a spam complaint reported by a provider for one address.
+type Feedback struct {
+	Email  string
+	Kind   string
+	Detail string
+}
+
+// ParseFeedback extracts hard bounces and complaints from a webhook payload of provider
+// (sendgrid, ses, mailgun or postmark). Soft bounces, deliveries and other events are
+// ignored. For an Amazon SNS subscription confirmation it returns the URL that must be
+// visited before SNS delivers notifications.
+func ParseFeedback(provider string, body []byte) (feedback []Feedback, confirmURL string, err error) {
+	switch provider {
+	case "sendgrid":
+		feedback, err = parseSendGridFeedback(body)
+	case "ses":
+		return parseSESFeedback(body)
+	case "mailgun":
+		feedback, err = parseMailgunFeedback(body)
+	case "postmark":
+		feedback, err = parsePostmarkFeedback(body)
+	default:
+		return nil, "", ErrUnknownProvider
+	}
+	return feedback, "", err
+}
+
+// SendGrid posts a JSON array of events. A "bounce" event is a hard bounce unless its
+// type is "blocked", which SendGrid uses for temporary refusals.
+func parseSendGridFeedback(body []byte) ([]Feedback, error) {
+	var events []struct {
+		Email  string `json:"email"`
+		Event  string `json:"event"`
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal(body, &events); err != nil {
+		return nil, err
+	}
+	var feedback []Feedback
+	for _, e := range events {
+		switch {
+		case e.Event == "bounce" && e.Type != "blocked":
+			feedback = append(feedback, Feedback{Email: e.Email, Kind: FeedbackBounce, Detail: e.Reason})
+		case e.Event == "spamreport":
+			feedback = append(feedback, Feedback{Email: e.Email, Kind: FeedbackComplaint})
+		}
+	}
+	return feedback, nil
+}
+
+// SES notifications arrive through Amazon SNS, wrapping either a notification (for
+// identity notifications) or an event (for configuration set event publishing).
+func parseSESFeedback(body []byte) ([]Feedback, string, error) {
+	var envelope struct {
+		Type         string `json:"Type"`
+		Message      string `json:"Message"`
+		SubscribeURL string `json:"SubscribeURL"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, "", err
+	}
+	switch envelope.Type {
+	case "SubscriptionConfirmation":
+		return nil, envelope.SubscribeURL, nil
+	case "Notification":
+	default:
+		return nil, "", nil
+	}
+
+	var msg struct {
+		NotificationType string `json:"notificationType"`
+		EventType        string `json:"eventType"`
+		Bounce           struct {
+			BounceType        string `json:"bounceType"`
+			BouncedRecipients []struct {
+				EmailAddress   string `json:"emailAddress"`
+				DiagnosticCode string `json:"diagnosticCode"`
+			} `json:"bouncedRecipients"`
+		} `json:"bounce"`
+		Complaint struct {
+			ComplainedRecipients []struct {
+				EmailAddress string `json:"emailAddress"`
+			} `json:"complainedRecipients"`
+		} `json:"complaint"`
+	}
+	if err := json.Unmarshal([]byte(envelope.Message), &msg); err != nil {
+		return nil, "", err
+	}
+	var feedback []Feedback
+	switch msg.NotificationType + msg.EventType {
+	case "Bounce":
+		if msg.Bounce.BounceType != "Permanent" {
+			return nil, "", nil
+		}
+		for _, r := range msg.Bounce.BouncedRecipients {
+			feedback = append(feedback, Feedback{Email: r.EmailAddress, Kind: FeedbackBounce, Detail: r.DiagnosticCode})
+		}
+	case "Complaint":
+		for _, r := range msg.Complaint.ComplainedRecipients {
+			feedback = append(feedback, Feedback{Email: r.EmailAddress, Kind: FeedbackComplaint})
+		}
+	}
+	return feedback, "", nil
+}
+
+// Mailgun posts one event per request; a permanent "failed" event is a hard bounce.
+func parseMailgunFeedback(body []byte) ([]Feedback, error) {
+	var payload struct {
+		EventData struct {
+			Event          string `json:"event"`
+			Severity       string `json:"severity"`
+			Recipient      string `json:"recipient"`
+			DeliveryStatus struct {
+				Message     string `json:"message"`
+				Description string `json:"description"`
+			} `json:"delivery-status"`
+		} `json:"event-data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	e := payload.EventData
+	switch {
+	case e.Event == "failed" && e.Severity == "permanent":
+		detail := e.DeliveryStatus.Message
+		if detail == "" {
+			detail = e.DeliveryStatus.Description
+		}
+		return []Feedback{{Email: e.Recipient, Kind: FeedbackBounce, Detail: detail}}, nil
+	case e.Event == "complained":
+		return []Feedback{{Email: e.Recipient, Kind: FeedbackComplaint}}, nil
+	}
+	return nil, nil
+}
+
+// Postmark posts one record per request, with the bounce type for bounces.
+func parsePostmarkFeedback(body []byte) ([]Feedback, error) {
+	var record struct {
+		RecordType  string `json:"RecordType"`
+		Type        string `json:"Type"`
+		Email       string `json:"Email"`
+		Description string `json:"Description"`
+	}
+	if err := json.Unmarshal(body, &record); err != nil {
+		return nil, err
+	}
+	switch {
+	case record.RecordType == "Bounce" && (record.Type == "HardBounce" || record.Type == "BadEmailAddress"):
+		return []Feedback{{Email: record.Email, Kind: FeedbackBounce, Detail: record.Description}}, nil
+	case record.RecordType == "SpamComplaint":
+		return []Feedback{{Email: record.Email, Kind: FeedbackComplaint}}, nil
+	}
+	return nil, nil
+}
+
+// ConfirmSNSSubscription visits the SubscribeURL of an Amazon SNS subscription
+// confirmation. Only HTTPS URLs on an SNS endpoint are followed.
+func ConfirmSNSSubscription(ctx context.Context, subscribeURL string) error {
+	u, err := url.Parse(subscribeURL)
+	if err != nil || u.Scheme != "https" || !strings.HasPrefix(u.Hostname(), "sns.") || !strings.HasSuffix(u.Hostname(), ".amazonaws.com") {
+		return fmt.Errorf("refusing to confirm SNS subscription at %q", subscribeURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), http.NoBody)
+	if err != nil {
+		return err
+	}
+	resp, err := newHTTPClient(10).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBody))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("sns subscription confirmation returned status %d", resp.StatusCode)
+	}
+	return nil
+}
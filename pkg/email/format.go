@@ -0,0 +1,13 @@
+package email
+
+import "time"
+
+// FormatTime renders t for an email body in the recipient's IANA timezone,
+// falling back to UTC when the timezone is empty or unknown.
+func FormatTime(t time.Time, timezone string) string {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil || timezone == "" {
+		loc = time.UTC
+	}
+	return t.In(loc).Format(time.RFC1123)
+}
@@ -0,0 +1,89 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
+)
+
+// MailgunSender delivers mail through the Mailgun Messages API of a sending domain.
+type MailgunSender struct {
+	client   *http.Client
+	endpoint string
+	apiKey   string
+	from     string
+	fromName string
+}
+
+func NewMailgunSender(cfg config.EmailConfig) *MailgunSender {
+	host := "https://api.mailgun.net"
+	if cfg.MailgunRegion == "eu" {
+		host = "https://api.eu.mailgun.net"
+	}
+	return &MailgunSender{
+		client:   newHTTPClient(cfg.HTTPTimeout),
+		endpoint: fmt.Sprintf("%s/v3/%s/messages", host, url.PathEscape(cfg.MailgunDomain)),
+		apiKey:   cfg.MailgunAPIKey,
+		from:     cfg.FromAddress,
+		fromName: cfg.FromName,
+	}
+}
+
+func (s *MailgunSender) Send(ctx context.Context, msg Message) error {
+	// Writes to the buffer cannot fail, so the form writer's errors are ignored.
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	_ = form.WriteField("from", formatAddr(s.fromName, s.from))
+	for _, to := range msg.To {
+		_ = form.WriteField("to", to)
+	}
+	_ = form.WriteField("subject", msg.Subject)
+	if msg.Body != "" {
+		_ = form.WriteField("text", msg.Body)
+	}
+	if msg.HTML != "" {
+		_ = form.WriteField("html", msg.HTML)
+	}
+	if msg.Locale != "" {
+		_ = form.WriteField("h:Content-Language", msg.Locale)
+	}
+	// Mailgun sets an inline file's Content-ID to its filename, so an inline image is
+	// uploaded under its content ID.
+	for _, a := range msg.Attachments {
+		field, filename := "attachment", a.Filename
+		if a.Inline() {
+			field, filename = "inline", a.ContentID
+		}
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{"name": field, "filename": filename}))
+		header.Set("Content-Type", a.contentType())
+		part, _ := form.CreatePart(header)
+		_, _ = part.Write(a.Data)
+	}
+	_ = form.Close()
+
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth("api", s.apiKey)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	return doRequest(ctx, s.client, req, "mailgun", parseMailgunError)
+}
+
+func parseMailgunError(resp *http.Response, body []byte) *ProviderError {
+	var reply struct {
+		Message string `json:"message"`
+	}
+	_ = json.Unmarshal(body, &reply)
+	return &ProviderError{Message: reply.Message, Permanent: permanentStatus(resp.StatusCode)}
+}
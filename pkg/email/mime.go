@@ -0,0 +1,157 @@
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// mimePart is a MIME entity: its headers and its already encoded body.
+type mimePart struct {
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+// buildMIME renders msg as an RFC 5322 message from the given address. The text and HTML
+// bodies become multipart/alternative, wrapped in multipart/related with the inline images
+// and in multipart/mixed with the other attachments, each level only when it is needed.
+func buildMIME(from string, msg Message) ([]byte, error) {
+	entity, err := messageEntity(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeHeader := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	writeHeader("From", from)
+	writeHeader("To", strings.Join(msg.To, ", "))
+	writeHeader("Subject", mime.QEncoding.Encode("UTF-8", msg.Subject))
+	writeHeader("Date", time.Now().Format(time.RFC1123Z))
+	writeHeader("MIME-Version", "1.0")
+	if msg.Locale != "" {
+		writeHeader("Content-Language", msg.Locale)
+	}
+	for _, name := range sortedKeys(entity.header) {
+		writeHeader(name, entity.header.Get(name))
+	}
+	buf.WriteString("\r\n")
+	buf.Write(entity.body)
+	return buf.Bytes(), nil
+}
+
+func messageEntity(msg Message) (mimePart, error) {
+	var inline, attached []mimePart
+	for _, a := range msg.Attachments {
+		part, err := attachmentPart(a)
+		if err != nil {
+			return mimePart{}, err
+		}
+		if a.Inline() {
+			inline = append(inline, part)
+		} else {
+			attached = append(attached, part)
+		}
+	}
+
+	entity := bodyEntity(msg)
+	if len(inline) > 0 {
+		entity = multipartEntity("related", append([]mimePart{entity}, inline...))
+	}
+	if len(attached) > 0 {
+		entity = multipartEntity("mixed", append([]mimePart{entity}, attached...))
+	}
+	return entity, nil
+}
+
+// bodyEntity returns the text and HTML bodies, as alternatives when both are set.
+func bodyEntity(msg Message) mimePart {
+	switch {
+	case msg.HTML == "":
+		return textPart("text/plain", msg.Body)
+	case msg.Body == "":
+		return textPart("text/html", msg.HTML)
+	default:
+		return multipartEntity("alternative", []mimePart{
+			textPart("text/plain", msg.Body),
+			textPart("text/html", msg.HTML),
+		})
+	}
+}
+
+func textPart(contentType, text string) mimePart {
+	var buf bytes.Buffer
+	w := quotedprintable.NewWriter(&buf)
+	_, _ = w.Write([]byte(text))
+	_ = w.Close()
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType+"; charset=UTF-8")
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	return mimePart{header: header, body: buf.Bytes()}
+}
+
+func attachmentPart(a Attachment) (mimePart, error) {
+	if a.Filename == "" {
+		return mimePart{}, fmt.Errorf("%w: no filename", ErrInvalidAttachment)
+	}
+	mediaType, params, err := mime.ParseMediaType(a.contentType())
+	if err != nil {
+		return mimePart{}, fmt.Errorf("%w %q: content type: %v", ErrInvalidAttachment, a.Filename, err)
+	}
+	params["name"] = a.Filename
+
+	disposition := "attachment"
+	header := textproto.MIMEHeader{}
+	if a.Inline() {
+		if strings.ContainsAny(a.ContentID, "<>\r\n ") {
+			return mimePart{}, fmt.Errorf("%w %q: content ID must not contain <, >, spaces or line breaks", ErrInvalidAttachment, a.Filename)
+		}
+		disposition = "inline"
+		header.Set("Content-ID", "<"+a.ContentID+">")
+	}
+	header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+	header.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": a.Filename}))
+	header.Set("Content-Transfer-Encoding", "base64")
+
+	// Base64 lines are wrapped at 76 characters as RFC 2045 requires.
+	encoded := base64.StdEncoding.EncodeToString(a.Data)
+	var body bytes.Buffer
+	for len(encoded) > 76 {
+		body.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	body.WriteString(encoded)
+	return mimePart{header: header, body: body.Bytes()}, nil
+}
+
+func multipartEntity(subtype string, parts []mimePart) mimePart {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, p := range parts {
+		w, _ := mw.CreatePart(p.header)
+		_, _ = w.Write(p.body)
+	}
+	_ = mw.Close()
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType("multipart/"+subtype, map[string]string{"boundary": mw.Boundary()}))
+	return mimePart{header: header, body: buf.Bytes()}
+}
+
+func sortedKeys(header textproto.MIMEHeader) []string {
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,120 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
+)
+
+const postmarkEndpoint = "https://api.postmarkapp.com/email"
+
+// postmarkAccountErrors are Postmark error codes that describe the server or account
+// (bad token, sending disabled, pending approval) rather than the message, so sending
+// may succeed once an operator fixes them.
+var postmarkAccountErrors = map[int]bool{10: true, 405: true, 412: true}
+
+// PostmarkSender delivers mail through the Postmark Email API on a single message stream.
+type PostmarkSender struct {
+	client   *http.Client
+	endpoint string
+	token    string
+	stream   string
+	from     string
+	fromName string
+}
+
+func NewPostmarkSender(cfg config.EmailConfig) *PostmarkSender {
+	return &PostmarkSender{
+		client:   newHTTPClient(cfg.HTTPTimeout),
+		endpoint: postmarkEndpoint,
+		token:    cfg.PostmarkServerToken,
+		stream:   cfg.PostmarkMessageStream,
+		from:     cfg.FromAddress,
+		fromName: cfg.FromName,
+	}
+}
+
+type postmarkHeader struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"`
+}
+
+type postmarkAttachment struct {
+	Name        string `json:"Name"`
+	Content     string `json:"Content"`
+	ContentType string `json:"ContentType"`
+	ContentID   string `json:"ContentID,omitempty"`
+}
+
+type postmarkRequest struct {
+	From          string               `json:"From"`
+	To            string               `json:"To"`
+	Subject       string               `json:"Subject"`
+	TextBody      string               `json:"TextBody,omitempty"`
+	HTMLBody      string               `json:"HtmlBody,omitempty"`
+	MessageStream string               `json:"MessageStream"`
+	Headers       []postmarkHeader     `json:"Headers,omitempty"`
+	Attachments   []postmarkAttachment `json:"Attachments,omitempty"`
+}
+
+func (s *PostmarkSender) Send(ctx context.Context, msg Message) error {
+	payload := postmarkRequest{
+		From:          formatAddr(s.fromName, s.from),
+		To:            strings.Join(msg.To, ", "),
+		Subject:       msg.Subject,
+		TextBody:      msg.Body,
+		HTMLBody:      msg.HTML,
+		MessageStream: s.stream,
+	}
+	if msg.Locale != "" {
+		payload.Headers = []postmarkHeader{{Name: "Content-Language", Value: msg.Locale}}
+	}
+	for _, a := range msg.Attachments {
+		att := postmarkAttachment{
+			Name:        a.Filename,
+			Content:     base64.StdEncoding.EncodeToString(a.Data),
+			ContentType: a.contentType(),
+		}
+		if a.Inline() {
+			att.ContentID = "cid:" + a.ContentID
+		}
+		payload.Attachments = append(payload.Attachments, att)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Postmark-Server-Token", s.token)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	return doRequest(ctx, s.client, req, "postmark", parsePostmarkError)
+}
+
+func parsePostmarkError(resp *http.Response, body []byte) *ProviderError {
+	var reply struct {
+		ErrorCode int    `json:"ErrorCode"`
+		Message   string `json:"Message"`
+	}
+	perr := &ProviderError{Permanent: permanentStatus(resp.StatusCode)}
+	if json.Unmarshal(body, &reply) != nil {
+		return perr
+	}
+	perr.Message = reply.Message
+	if reply.ErrorCode != 0 {
+		perr.Code = strconv.Itoa(reply.ErrorCode)
+		perr.Permanent = perr.Permanent && !postmarkAccountErrors[reply.ErrorCode]
+	}
+	return perr
+}
@@ -0,0 +1,81 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxErrorBody caps how much of a failed API response is read for the error message.
+const maxErrorBody = 64 << 10
+
+// ProviderError is a non-2xx response from an email provider's HTTP API. Permanent is
+// set when the provider rejected the message itself, so the outbox dead-letters it
+// instead of retrying; throttling, outages and account-level problems such as a revoked
+// API key stay retryable until an operator fixes them.
+type ProviderError struct {
+	Provider   string
+	StatusCode int
+	Code       string
+	Message    string
+	Permanent  bool
+}
+
+func (e *ProviderError) Error() string {
+	msg := e.Message
+	if e.Code != "" {
+		msg = e.Code + ": " + msg
+	}
+	return fmt.Sprintf("%s api returned status %d: %s", e.Provider, e.StatusCode, msg)
+}
+
+// permanentStatus reports whether an HTTP status rejects the request itself rather than
+// the credentials, the rate or the provider's availability.
+func permanentStatus(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return status >= 400 && status < 500
+}
+
+func newHTTPClient(timeoutSeconds int) *http.Client {
+	return &http.Client{Timeout: time.Duration(timeoutSeconds) * time.Second}
+}
+
+// doRequest sends req and, for a non-2xx reply, returns a ProviderError built by
+// parse from the response body. A nil parse falls back to the raw body and the
+// status-based classification.
+func doRequest(ctx context.Context, client *http.Client, req *http.Request, provider string, parse func(*http.Response, []byte) *ProviderError) error {
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", provider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBody))
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	var perr *ProviderError
+	if parse != nil {
+		perr = parse(resp, body)
+	}
+	if perr == nil {
+		perr = &ProviderError{Permanent: permanentStatus(resp.StatusCode)}
+	}
+	perr.Provider = provider
+	perr.StatusCode = resp.StatusCode
+	if perr.Message == "" {
+		perr.Message = strings.TrimSpace(string(body))
+	}
+	if perr.Message == "" {
+		perr.Message = http.StatusText(resp.StatusCode)
+	}
+	return perr
+}
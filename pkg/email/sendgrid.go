@@ -0,0 +1,135 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
+)
+
+const sendGridEndpoint = "https://api.sendgrid.com/v3/mail/send"
+
+// SendGridSender delivers mail through the SendGrid v3 Mail Send API.
+type SendGridSender struct {
+	client   *http.Client
+	endpoint string
+	apiKey   string
+	from     string
+	fromName string
+}
+
+func NewSendGridSender(cfg config.EmailConfig) *SendGridSender {
+	return &SendGridSender{
+		client:   newHTTPClient(cfg.HTTPTimeout),
+		endpoint: sendGridEndpoint,
+		apiKey:   cfg.SendGridAPIKey,
+		from:     cfg.FromAddress,
+		fromName: cfg.FromName,
+	}
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridAttachment struct {
+	Content     string `json:"content"`
+	Type        string `json:"type"`
+	Filename    string `json:"filename"`
+	Disposition string `json:"disposition"`
+	ContentID   string `json:"content_id,omitempty"`
+}
+
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+type sendGridRequest struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+	Headers          map[string]string         `json:"headers,omitempty"`
+	Attachments      []sendGridAttachment      `json:"attachments,omitempty"`
+}
+
+func (s *SendGridSender) Send(ctx context.Context, msg Message) error {
+	to := make([]sendGridAddress, 0, len(msg.To))
+	for _, addr := range msg.To {
+		to = append(to, sendGridAddress{Email: addr})
+	}
+	payload := sendGridRequest{
+		Personalizations: []sendGridPersonalization{{To: to}},
+		From:             sendGridAddress{Email: s.from, Name: s.fromName},
+		Subject:          msg.Subject,
+	}
+	// SendGrid requires text/plain to come before text/html.
+	if msg.Body != "" {
+		payload.Content = append(payload.Content, sendGridContent{Type: "text/plain", Value: msg.Body})
+	}
+	if msg.HTML != "" {
+		payload.Content = append(payload.Content, sendGridContent{Type: "text/html", Value: msg.HTML})
+	}
+	if msg.Locale != "" {
+		payload.Headers = map[string]string{"Content-Language": msg.Locale}
+	}
+	for _, a := range msg.Attachments {
+		att := sendGridAttachment{
+			Content:     base64.StdEncoding.EncodeToString(a.Data),
+			Type:        a.contentType(),
+			Filename:    a.Filename,
+			Disposition: "attachment",
+		}
+		if a.Inline() {
+			att.Disposition = "inline"
+			att.ContentID = a.ContentID
+		}
+		payload.Attachments = append(payload.Attachments, att)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	return doRequest(ctx, s.client, req, "sendgrid", parseSendGridError)
+}
+
+func parseSendGridError(resp *http.Response, body []byte) *ProviderError {
+	var reply struct {
+		Errors []struct {
+			Message string `json:"message"`
+			Field   string `json:"field"`
+		} `json:"errors"`
+	}
+	perr := &ProviderError{Permanent: permanentStatus(resp.StatusCode)}
+	if json.Unmarshal(body, &reply) != nil {
+		return perr
+	}
+	msgs := make([]string, 0, len(reply.Errors))
+	for _, e := range reply.Errors {
+		if e.Field != "" {
+			msgs = append(msgs, e.Field+": "+e.Message)
+		} else {
+			msgs = append(msgs, e.Message)
+		}
+	}
+	perr.Message = strings.Join(msgs, "; ")
+	return perr
+}
@@ -0,0 +1,223 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
+)
+
+// sesAccountErrors are SES error types that describe the account or its configuration
+// rather than the message, so sending may succeed once they are resolved.
+var sesAccountErrors = map[string]bool{
+	"AccountSuspendedException": true,
+	"SendingPausedException":    true,
+	"LimitExceededException":    true,
+	"NotFoundException":         true,
+}
+
+// SESSender delivers mail through the Amazon SES v2 SendEmail API, signing requests with
+// AWS Signature Version 4 and static credentials.
+type SESSender struct {
+	client           *http.Client
+	endpoint         string
+	region           string
+	accessKeyID      string
+	secretAccessKey  string
+	sessionToken     string
+	configurationSet string
+	from             string
+	fromName         string
+}
+
+func NewSESSender(cfg config.EmailConfig) *SESSender {
+	endpoint := strings.TrimSuffix(cfg.SESEndpoint, "/")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://email.%s.amazonaws.com", cfg.SESRegion)
+	}
+	return &SESSender{
+		client:           newHTTPClient(cfg.HTTPTimeout),
+		endpoint:         endpoint + "/v2/email/outbound-emails",
+		region:           cfg.SESRegion,
+		accessKeyID:      cfg.SESAccessKeyID,
+		secretAccessKey:  cfg.SESSecretAccessKey,
+		sessionToken:     cfg.SESSessionToken,
+		configurationSet: cfg.SESConfigurationSet,
+		from:             cfg.FromAddress,
+		fromName:         cfg.FromName,
+	}
+}
+
+type sesContent struct {
+	Data    string `json:"Data"`
+	Charset string `json:"Charset"`
+}
+
+type sesHeader struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"`
+}
+
+type sesSimple struct {
+	Subject sesContent `json:"Subject"`
+	Body    struct {
+		Text *sesContent `json:"Text,omitempty"`
+		HTML *sesContent `json:"Html,omitempty"`
+	} `json:"Body"`
+	Headers []sesHeader `json:"Headers,omitempty"`
+}
+
+type sesRaw struct {
+	Data []byte `json:"Data"`
+}
+
+type sesRequest struct {
+	FromEmailAddress string `json:"FromEmailAddress"`
+	Destination      struct {
+		ToAddresses []string `json:"ToAddresses"`
+	} `json:"Destination"`
+	Content struct {
+		Simple *sesSimple `json:"Simple,omitempty"`
+		Raw    *sesRaw    `json:"Raw,omitempty"`
+	} `json:"Content"`
+	ConfigurationSetName string `json:"ConfigurationSetName,omitempty"`
+}
+
+func (s *SESSender) Send(ctx context.Context, msg Message) error {
+	var payload sesRequest
+	payload.FromEmailAddress = formatAddr(s.fromName, s.from)
+	payload.Destination.ToAddresses = msg.To
+	payload.ConfigurationSetName = s.configurationSet
+	// A simple message cannot carry inline images, so messages with attachments are
+	// sent as the raw MIME message the SMTP driver would send.
+	if len(msg.Attachments) > 0 {
+		raw, err := buildMIME(payload.FromEmailAddress, msg)
+		if err != nil {
+			return err
+		}
+		payload.Content.Raw = &sesRaw{Data: raw}
+	} else {
+		simple := &sesSimple{Subject: sesContent{Data: msg.Subject, Charset: "UTF-8"}}
+		if msg.Body != "" {
+			simple.Body.Text = &sesContent{Data: msg.Body, Charset: "UTF-8"}
+		}
+		if msg.HTML != "" {
+			simple.Body.HTML = &sesContent{Data: msg.HTML, Charset: "UTF-8"}
+		}
+		if msg.Locale != "" {
+			simple.Headers = []sesHeader{{Name: "Content-Language", Value: msg.Locale}}
+		}
+		payload.Content.Simple = simple
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+	signV4(req, body, s.accessKeyID, s.secretAccessKey, s.region, "ses", time.Now().UTC())
+
+	return doRequest(ctx, s.client, req, "ses", parseSESError)
+}
+
+func parseSESError(resp *http.Response, body []byte) *ProviderError {
+	var reply struct {
+		Type        string `json:"__type"`
+		Message     string `json:"message"`
+		MessageCaps string `json:"Message"`
+	}
+	_ = json.Unmarshal(body, &reply)
+
+	// The error type arrives as "MessageRejected:http://..." in a header or "__type".
+	code := resp.Header.Get("X-Amzn-ErrorType")
+	if code == "" {
+		code = reply.Type
+	}
+	if i := strings.IndexByte(code, ':'); i >= 0 {
+		code = code[:i]
+	}
+	if i := strings.LastIndexByte(code, '#'); i >= 0 {
+		code = code[i+1:]
+	}
+	msg := reply.Message
+	if msg == "" {
+		msg = reply.MessageCaps
+	}
+	return &ProviderError{
+		Code:      code,
+		Message:   msg,
+		Permanent: permanentStatus(resp.StatusCode) && !sesAccountErrors[code],
+	}
+}
+
+// signV4 adds an AWS Signature Version 4 Authorization header to req, covering every
+// header already set plus Host and X-Amz-Date.
+func signV4(req *http.Request, body []byte, accessKeyID, secretAccessKey, region, service string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"net/smtp"
-	"strings"
 
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
 )
@@ -31,35 +30,16 @@ func NewSMTPSender(cfg config.EmailConfig) *SMTPSender {
 
 func (s *SMTPSender) Send(_ context.Context, msg Message) error {
 	addr := fmt.Sprintf("%s:%d", s.host, s.port)
-	from := formatAddr(s.fromName, s.from)
 
-	headers := map[string]string{
-		"From":         from,
-		"To":           strings.Join(msg.To, ", "),
-		"Subject":      msg.Subject,
-		"MIME-Version": "1.0",
+	message, err := buildMIME(formatAddr(s.fromName, s.from), msg)
+	if err != nil {
+		return err
 	}
 
-	var body string
-	if msg.HTML != "" {
-		headers["Content-Type"] = "text/html; charset=UTF-8"
-		body = msg.HTML
-	} else {
-		headers["Content-Type"] = "text/plain; charset=UTF-8"
-		body = msg.Body
-	}
-
-	var message strings.Builder
-	for k, v := range headers {
-		fmt.Fprintf(&message, "%s: %s\r\n", k, v)
-	}
-	message.WriteString("\r\n")
-	message.WriteString(body)
-
 	var auth smtp.Auth
 	if s.username != "" {
 		auth = smtp.PlainAuth("", s.username, s.password, s.host)
 	}
 
-	return smtp.SendMail(addr, auth, s.from, msg.To, []byte(message.String()))
+	return smtp.SendMail(addr, auth, s.from, msg.To, message)
 }
@@ -0,0 +1,109 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"path"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/i18n"
+)
+
+// Transactional email templates, named after their file in templates/ without the
+// .tmpl extension.
+const (
+	TemplateVerifyEmail   = "verify_email"
+	TemplatePasswordReset = "password_reset"
+)
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+// A template file defines a "subject" and an "html" template. Text is written in English
+// inside {{t "..."}} calls, which translate it through the i18n catalogs; {name}
+// placeholders in the text are filled from the name/value pairs after it, as in
+// {{t "This link expires in {hours} hours." "hours" .Hours}}.
+type emailTemplate struct {
+	subject *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+var templates = map[string]*emailTemplate{}
+
+func init() {
+	entries, err := templateFiles.ReadDir("templates")
+	if err != nil {
+		panic(err)
+	}
+	// The real translator is bound per render; this one only lets the files parse.
+	funcs := map[string]any{"t": translator(i18n.English)}
+	for _, entry := range entries {
+		data, err := templateFiles.ReadFile(path.Join("templates", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		name := strings.TrimSuffix(entry.Name(), ".tmpl")
+		subject, err := texttemplate.New(name).Funcs(funcs).Parse(string(data))
+		if err != nil {
+			panic(fmt.Sprintf("email: templates/%s: %v", entry.Name(), err))
+		}
+		html, err := htmltemplate.New(name).Funcs(funcs).Parse(string(data))
+		if err != nil {
+			panic(fmt.Sprintf("email: templates/%s: %v", entry.Name(), err))
+		}
+		templates[name] = &emailTemplate{subject: subject, html: html}
+	}
+}
+
+// Render renders the transactional email template name with data in the supported
+// language best matching locale, falling back to the default language, and returns a
+// message with the subject, HTML body and locale set for the caller to address.
+func Render(name, locale string, data any) (Message, error) {
+	tmpl, ok := templates[name]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown email template %q", name)
+	}
+	lang := i18n.Negotiate(locale)
+	funcs := map[string]any{"t": translator(lang)}
+
+	var subject bytes.Buffer
+	st, err := tmpl.subject.Clone()
+	if err != nil {
+		return Message{}, err
+	}
+	if err := st.Funcs(funcs).ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, fmt.Errorf("render %s subject: %w", name, err)
+	}
+
+	var html bytes.Buffer
+	ht, err := tmpl.html.Clone()
+	if err != nil {
+		return Message{}, err
+	}
+	if err := ht.Funcs(funcs).ExecuteTemplate(&html, "html", data); err != nil {
+		return Message{}, fmt.Errorf("render %s body: %w", name, err)
+	}
+
+	return Message{
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    strings.TrimSpace(html.String()),
+		Locale:  lang,
+	}, nil
+}
+
+// translator returns the "t" template function for lang.
+func translator(lang string) func(message string, args ...any) (string, error) {
+	return func(message string, args ...any) (string, error) {
+		if len(args)%2 != 0 {
+			return "", fmt.Errorf("t %q: placeholder values must come in name/value pairs", message)
+		}
+		text := i18n.Translate(lang, message)
+		for i := 0; i < len(args); i += 2 {
+			text = strings.ReplaceAll(text, fmt.Sprintf("{%v}", args[i]), fmt.Sprint(args[i+1]))
+		}
+		return text, nil
+	}
+}
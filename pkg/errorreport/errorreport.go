@@ -0,0 +1,91 @@
+// Package errorreport sends server errors and recovered panics to an error tracking
+// service, along with the request they happened in.
+package errorreport
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
+)
+
+// reportedKey marks a request whose error was already reported, so a panic reported by
+// the Recovery middleware is not reported again by the error handler.
+const reportedKey = "error_reported"
+
+// Request describes the request an error happened in.
+type Request struct {
+	RequestID string
+	UserID    int64 // 0 for anonymous requests
+	Method    string
+	Route     string // the matched route pattern, such as /api/v1/users/:id
+	Path      string
+	Status    int
+	// Panic is set for errors recovered from a panic, whose report carries the stack of
+	// the goroutine that panicked.
+	Panic bool
+}
+
+type Reporter interface {
+	// Report sends err in the background. It must be called on the goroutine that failed
+	// for the stack of a panic to be captured.
+	Report(ctx context.Context, err error, req Request)
+	// Flush waits up to timeout for queued reports to be sent, and reports whether they were.
+	Flush(timeout time.Duration) bool
+}
+
+func NewReporter(cfg config.ErrorReporterConfig, env string) (Reporter, error) {
+	if cfg.Environment == "" {
+		cfg.Environment = env
+	}
+	switch cfg.Driver {
+	case "sentry":
+		return NewSentryReporter(cfg)
+	case "none":
+		return NopReporter{}, nil
+	default:
+		return NopReporter{}, nil
+	}
+}
+
+// NopReporter discards errors; they are still logged by the error handler.
+type NopReporter struct{}
+
+func (NopReporter) Report(context.Context, error, Request) {}
+
+func (NopReporter) Flush(time.Duration) bool { return true }
+
+// RequestFrom describes the request c is handling. Strings are cloned because the report
+// may be sent after fasthttp reuses the request's memory.
+func RequestFrom(c fiber.Ctx, status int) Request {
+	return Request{
+		RequestID: fiber.Locals[string](c, "request_id"),
+		UserID:    fiber.Locals[int64](c, "user_id"),
+		Method:    strings.Clone(c.Method()),
+		Route:     c.Route().Path,
+		Path:      strings.Clone(c.Path()),
+		Status:    status,
+	}
+}
+
+// ReportPanic reports a value recovered from a panic in the request c is handling, and
+// marks the request so its error response is not reported again.
+func ReportPanic(c fiber.Ctx, reporter Reporter, recovered any) {
+	err, ok := recovered.(error)
+	if !ok {
+		err = fmt.Errorf("%v", recovered)
+	}
+	req := RequestFrom(c, fiber.StatusInternalServerError)
+	req.Panic = true
+	reporter.Report(c.Context(), fmt.Errorf("panic: %w", err), req)
+	c.Locals(reportedKey, true)
+}
+
+// Reported reports whether the error of the request c is handling was already reported.
+func Reported(c fiber.Ctx) bool {
+	return fiber.Locals[bool](c, reportedKey)
+}
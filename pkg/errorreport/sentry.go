@@ -0,0 +1,60 @@
+package errorreport
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/buildinfo"
+)
+
+type SentryReporter struct {
+	client *sentry.Client
+}
+
+func NewSentryReporter(cfg config.ErrorReporterConfig) (*SentryReporter, error) {
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn:         cfg.DSN,
+		Environment: cfg.Environment,
+		Release:     buildinfo.Version,
+		SampleRate:  cfg.SampleRate,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sentry client: %w", err)
+	}
+	return &SentryReporter{client: client}, nil
+}
+
+func (r *SentryReporter) Report(ctx context.Context, err error, req Request) {
+	event := r.client.EventFromException(err, sentry.LevelError)
+	if req.Panic {
+		event.Level = sentry.LevelFatal
+		if n := len(event.Exception); n > 0 && event.Exception[n-1].Stacktrace == nil {
+			event.Exception[n-1].Stacktrace = sentry.NewStacktrace()
+		}
+	}
+	if req.Route != "" {
+		event.Transaction = req.Method + " " + req.Route
+	}
+
+	scope := sentry.NewScope()
+	scope.SetTags(map[string]string{
+		"request_id":  req.RequestID,
+		"method":      req.Method,
+		"route":       req.Route,
+		"status_code": strconv.Itoa(req.Status),
+	})
+	scope.SetContext("request", sentry.Context{"path": req.Path})
+	if req.UserID != 0 {
+		scope.SetUser(sentry.User{ID: strconv.FormatInt(req.UserID, 10)})
+	}
+	r.client.CaptureEvent(event, &sentry.EventHint{Context: ctx, OriginalException: err}, scope)
+}
+
+func (r *SentryReporter) Flush(timeout time.Duration) bool {
+	return r.client.Flush(timeout)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 	"sync"
+	"sync/atomic"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 
@@ -17,15 +18,23 @@ type Status struct {
 	Details map[string]string `json:"details,omitempty"`
 }
 
+// Ready reports whether Readiness found the instance able to serve traffic.
+func (s Status) Ready() bool {
+	return s.Status != "draining"
+}
+
 // Checker aggregates health checks for all dependencies.
 type Checker struct {
-	pool  *pgxpool.Pool
-	cache cache.Cache
+	pool          *pgxpool.Pool
+	cache         cache.Cache
+	cacheDriver   string
+	storageDriver string
+	draining      atomic.Bool
 }
 
-// NewChecker creates a new health checker.
-func NewChecker(pool *pgxpool.Pool, appCache cache.Cache) *Checker {
-	return &Checker{pool: pool, cache: appCache}
+// NewChecker creates a new health checker. The driver names are reported by System.
+func NewChecker(pool *pgxpool.Pool, appCache cache.Cache, cacheDriver, storageDriver string) *Checker {
+	return &Checker{pool: pool, cache: appCache, cacheDriver: cacheDriver, storageDriver: storageDriver}
 }
 
 // Liveness returns basic liveness (process is running).
@@ -33,8 +42,18 @@ func (h *Checker) Liveness() Status {
 	return Status{Status: "up"}
 }
 
+// MarkDraining makes Readiness report "draining" from now on, so load balancers stop
+// routing to the instance while it shuts down.
+func (h *Checker) MarkDraining() {
+	h.draining.Store(true)
+}
+
 // Readiness checks all dependencies are ready.
 func (h *Checker) Readiness(ctx context.Context) Status {
+	if h.draining.Load() {
+		return Status{Status: "draining"}
+	}
+
 	details := make(map[string]string)
 	allUp := true
 
@@ -0,0 +1,63 @@
+package health
+
+import (
+	"time"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/buildinfo"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+)
+
+// SystemInfo describes the running instance for debugging without shell access.
+type SystemInfo struct {
+	Build         buildinfo.Info `json:"build"`
+	UptimeSeconds int64          `json:"uptime_seconds"`
+	Database      DatabaseInfo   `json:"database"`
+	Cache         CacheInfo      `json:"cache"`
+	Storage       StorageInfo    `json:"storage"`
+}
+
+// DatabaseInfo is a snapshot of the connection pool.
+type DatabaseInfo struct {
+	TotalConns    int32 `json:"total_conns"`
+	AcquiredConns int32 `json:"acquired_conns"`
+	IdleConns     int32 `json:"idle_conns"`
+	MaxConns      int32 `json:"max_conns"`
+}
+
+// CacheInfo reports the cache driver and, when lookups are counted, its hit rate since start.
+type CacheInfo struct {
+	Driver  string  `json:"driver"`
+	Hits    uint64  `json:"hits"`
+	Misses  uint64  `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+// StorageInfo reports the storage driver.
+type StorageInfo struct {
+	Driver string `json:"driver"`
+}
+
+// System returns build, uptime and dependency details of this instance. Unlike Readiness it
+// makes no network calls.
+func (h *Checker) System() SystemInfo {
+	stats := h.pool.Stat()
+	info := SystemInfo{
+		Build:         buildinfo.Get(),
+		UptimeSeconds: int64(buildinfo.Uptime() / time.Second),
+		Database: DatabaseInfo{
+			TotalConns:    stats.TotalConns(),
+			AcquiredConns: stats.AcquiredConns(),
+			IdleConns:     stats.IdleConns(),
+			MaxConns:      stats.MaxConns(),
+		},
+		Cache:   CacheInfo{Driver: h.cacheDriver},
+		Storage: StorageInfo{Driver: h.storageDriver},
+	}
+	if sc, ok := h.cache.(interface{ Stats() cache.Stats }); ok {
+		s := sc.Stats()
+		info.Cache.Hits = s.Hits
+		info.Cache.Misses = s.Misses
+		info.Cache.HitRate = s.HitRate()
+	}
+	return info
+}
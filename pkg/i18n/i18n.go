@@ -0,0 +1,182 @@
+// Package i18n translates the English messages of error responses and the text of the
+// transactional email templates into the language a client or recipient asks for. Catalogs in locales/ map each English message to its translation; an
+// entry may hold {name} placeholders, which match any text in the message and are carried
+// over to the translation, so "{field} is required" translates "Email is required".
+// Messages without an entry are returned in English.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/language"
+)
+
+// English is the language messages are written in.
+const English = "en"
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+var placeholderPattern = regexp.MustCompile(`\{([a-z]+)\}`)
+
+type catalog struct {
+	exact    map[string]string
+	patterns []pattern
+}
+
+// pattern matches messages of an entry with placeholders.
+type pattern struct {
+	re          *regexp.Regexp
+	names       []string
+	literal     int // length of the fixed text around the placeholders
+	translation string
+}
+
+var (
+	mu            sync.RWMutex
+	catalogs      map[string]*catalog
+	supported     []string
+	matcher       language.Matcher
+	defaultLocale = English
+)
+
+func init() {
+	catalogs = map[string]*catalog{English: {}}
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	for _, entry := range entries {
+		data, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		lang := strings.TrimSuffix(entry.Name(), ".json")
+		cat, err := parseCatalog(data)
+		if err != nil {
+			panic(fmt.Sprintf("i18n: locales/%s: %v", entry.Name(), err))
+		}
+		catalogs[lang] = cat
+	}
+	supported = make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		supported = append(supported, lang)
+	}
+	sort.Strings(supported)
+	matcher = newMatcher(defaultLocale)
+}
+
+func parseCatalog(data []byte) (*catalog, error) {
+	var messages map[string]string
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, err
+	}
+	cat := &catalog{exact: make(map[string]string, len(messages))}
+	for message, translation := range messages {
+		if !placeholderPattern.MatchString(message) {
+			cat.exact[message] = translation
+			continue
+		}
+		p := pattern{translation: translation}
+		expr := "^"
+		last := 0
+		for _, loc := range placeholderPattern.FindAllStringSubmatchIndex(message, -1) {
+			expr += regexp.QuoteMeta(message[last:loc[0]]) + "(.+?)"
+			p.literal += loc[0] - last
+			p.names = append(p.names, message[loc[2]:loc[3]])
+			last = loc[1]
+		}
+		expr += regexp.QuoteMeta(message[last:]) + "$"
+		p.literal += len(message) - last
+		p.re = regexp.MustCompile(expr)
+		cat.patterns = append(cat.patterns, p)
+	}
+	// Entries with more fixed text are more specific, so they are tried first.
+	sort.Slice(cat.patterns, func(i, j int) bool {
+		if cat.patterns[i].literal != cat.patterns[j].literal {
+			return cat.patterns[i].literal > cat.patterns[j].literal
+		}
+		return cat.patterns[i].re.String() < cat.patterns[j].re.String()
+	})
+	return cat, nil
+}
+
+// Supported returns the languages with a catalog, including English.
+func Supported() []string {
+	return supported
+}
+
+// SetDefault sets the language used when a request accepts none of the supported ones.
+func SetDefault(lang string) error {
+	if _, ok := catalogs[lang]; !ok {
+		return fmt.Errorf("unsupported locale %q, supported: %s", lang, strings.Join(supported, ", "))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	defaultLocale = lang
+	matcher = newMatcher(lang)
+	return nil
+}
+
+// newMatcher returns a matcher over the supported languages that falls back to def.
+func newMatcher(def string) language.Matcher {
+	tags := []language.Tag{language.Make(def)}
+	for _, lang := range supported {
+		if lang != def {
+			tags = append(tags, language.Make(lang))
+		}
+	}
+	return language.NewMatcher(tags)
+}
+
+// Negotiate returns the supported language that best matches an Accept-Language header,
+// or the default language when none does.
+func Negotiate(acceptLanguage string) string {
+	mu.RLock()
+	m, def := matcher, defaultLocale
+	mu.RUnlock()
+
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return def
+	}
+	tag, _, confidence := m.Match(tags...)
+	if confidence == language.No {
+		return def
+	}
+	base, _ := tag.Base()
+	if _, ok := catalogs[base.String()]; ok {
+		return base.String()
+	}
+	return def
+}
+
+// Translate returns message in lang, or message itself when lang has no entry for it.
+func Translate(lang, message string) string {
+	cat, ok := catalogs[lang]
+	if !ok || lang == English {
+		return message
+	}
+	if translation, ok := cat.exact[message]; ok {
+		return translation
+	}
+	for _, p := range cat.patterns {
+		match := p.re.FindStringSubmatch(message)
+		if match == nil {
+			continue
+		}
+		translation := p.translation
+		for i, name := range p.names {
+			translation = strings.ReplaceAll(translation, "{"+name+"}", match[i+1])
+		}
+		return translation
+	}
+	return message
+}
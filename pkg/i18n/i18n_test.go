@@ -0,0 +1,61 @@
+package i18n
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"vi", "vi"},
+		{"vi-VN,vi;q=0.9,en;q=0.8", "vi"},
+		{"fr-FR,vi;q=0.5", "vi"},
+		{"en-US,vi;q=0.5", "en"},
+		{"de", "en"},
+		{"not a language", "en"},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestSetDefault(t *testing.T) {
+	t.Cleanup(func() { _ = SetDefault(English) })
+
+	if err := SetDefault("xx"); err == nil {
+		t.Error("expected an error for an unsupported locale")
+	}
+	if err := SetDefault("vi"); err != nil {
+		t.Fatalf("SetDefault: %v", err)
+	}
+	if got := Negotiate("de"); got != "vi" {
+		t.Errorf("Negotiate with default vi = %q, want vi", got)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		lang    string
+		message string
+		want    string
+	}{
+		{"vi", "user not found", "Không tìm thấy người dùng"},
+		{"vi", "Email is required", "Email là bắt buộc"},
+		{"vi", "Name must be at least 2 characters", "Name phải có ít nhất 2 ký tự"},
+		// A more specific entry wins over a generic one matching the same message.
+		{"vi", "Idempotency-Key must be at most 255 characters", "Idempotency-Key chỉ được có tối đa 255 ký tự"},
+		{"vi", "upload is incomplete: received 10 of 20 bytes", "Tải lên chưa hoàn tất: đã nhận 10 trên 20 byte"},
+		{"vi", "a message without an entry", "a message without an entry"},
+		{"en", "user not found", "user not found"},
+		{"", "user not found", "user not found"},
+		{"xx", "user not found", "user not found"},
+	}
+	for _, tt := range tests {
+		if got := Translate(tt.lang, tt.message); got != tt.want {
+			t.Errorf("Translate(%q, %q) = %q, want %q", tt.lang, tt.message, got, tt.want)
+		}
+	}
+}
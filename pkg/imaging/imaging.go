@@ -0,0 +1,187 @@
+// Package imaging decodes uploaded images and renders resized variants of them.
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // register GIF decoding
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register WebP decoding
+)
+
+// Formats a variant can be encoded in.
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatWebP = "webp"
+)
+
+// MaxPixels bounds the canvas Decode accepts, so a small file that declares a huge
+// image cannot exhaust memory.
+const MaxPixels = 50_000_000
+
+const jpegQuality = 85
+
+var variantName = regexp.MustCompile(`^[a-z0-9_-]{1,50}$`)
+
+// Variant describes a rendered copy of an image: scaled down to fit within Width x
+// Height, keeping the aspect ratio and never enlarging, then encoded as Format. A zero
+// dimension leaves that side unconstrained.
+type Variant struct {
+	Name   string
+	Width  int
+	Height int
+	Format string
+}
+
+// ParseVariants parses a comma-separated list of name:WIDTHxHEIGHT:format entries,
+// e.g. "thumbnail:200x200:jpeg,webp:0x0:webp". An empty spec yields no variants.
+func ParseVariants(spec string) ([]Variant, error) {
+	var variants []Variant
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("variant %q: want name:WIDTHxHEIGHT:format", entry)
+		}
+		v := Variant{Name: parts[0], Format: strings.ToLower(parts[2])}
+		if !variantName.MatchString(v.Name) {
+			return nil, fmt.Errorf("variant %q: name must be 1-50 lowercase letters, digits, _ or -", entry)
+		}
+		if seen[v.Name] {
+			return nil, fmt.Errorf("variant %q: duplicate name", entry)
+		}
+		seen[v.Name] = true
+
+		w, h, ok := strings.Cut(parts[1], "x")
+		var errW, errH error
+		v.Width, errW = strconv.Atoi(w)
+		v.Height, errH = strconv.Atoi(h)
+		if !ok || errW != nil || errH != nil || v.Width < 0 || v.Height < 0 {
+			return nil, fmt.Errorf("variant %q: size must be WIDTHxHEIGHT with non-negative integers", entry)
+		}
+		if ContentType(v.Format) == "" {
+			return nil, fmt.Errorf("variant %q: format must be one of: jpeg png webp", entry)
+		}
+		variants = append(variants, v)
+	}
+	return variants, nil
+}
+
+// Decodable reports whether Decode understands images of the MIME type.
+func Decodable(mimeType string) bool {
+	switch mimeType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
+
+// ContentType returns the MIME type of a format, or "" if the format is unknown.
+func ContentType(format string) string {
+	switch format {
+	case FormatJPEG:
+		return "image/jpeg"
+	case FormatPNG:
+		return "image/png"
+	case FormatWebP:
+		return "image/webp"
+	}
+	return ""
+}
+
+// Extension returns the file extension, including the dot, used for a format.
+func Extension(format string) string {
+	if format == FormatJPEG {
+		return ".jpg"
+	}
+	return "." + format
+}
+
+// Decode reads a JPEG, PNG, GIF or WebP image, rejecting canvases larger than MaxPixels
+// before any pixel data is allocated. Only the first frame of an animation is read.
+func Decode(r io.Reader) (image.Image, error) {
+	var head bytes.Buffer
+	cfg, _, err := image.DecodeConfig(io.TeeReader(r, &head))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return nil, fmt.Errorf("image is %dx%d, more than %d pixels", cfg.Width, cfg.Height, MaxPixels)
+	}
+
+	img, _, err := image.Decode(io.MultiReader(&head, r))
+	return img, err
+}
+
+// Fit scales img down to fit within maxW x maxH, keeping its aspect ratio. Images that
+// already fit are returned unchanged; a zero bound is ignored.
+func Fit(img image.Image, maxW, maxH int) image.Image {
+	b := img.Bounds()
+	scale := 1.0
+	if maxW > 0 && b.Dx() > maxW {
+		scale = min(scale, float64(maxW)/float64(b.Dx()))
+	}
+	if maxH > 0 && b.Dy() > maxH {
+		scale = min(scale, float64(maxH)/float64(b.Dy()))
+	}
+	if scale == 1 {
+		return img
+	}
+
+	w := max(1, int(math.Round(float64(b.Dx())*scale)))
+	h := max(1, int(math.Round(float64(b.Dy())*scale)))
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// Render writes img resized and encoded as described by v and returns the size of the
+// rendered image.
+func Render(w io.Writer, img image.Image, v Variant) (image.Point, error) {
+	out := Fit(img, v.Width, v.Height)
+	if err := Encode(w, out, v.Format); err != nil {
+		return image.Point{}, err
+	}
+	return out.Bounds().Size(), nil
+}
+
+// Encode writes img in the given format. JPEG has no transparency, so transparent
+// pixels are flattened onto white; WebP output is lossless.
+func Encode(w io.Writer, img image.Image, format string) error {
+	switch format {
+	case FormatJPEG:
+		return jpeg.Encode(w, flatten(img), &jpeg.Options{Quality: jpegQuality})
+	case FormatPNG:
+		return png.Encode(w, img)
+	case FormatWebP:
+		return encodeWebP(w, img)
+	}
+	return fmt.Errorf("unsupported image format %q", format)
+}
+
+func flatten(img image.Image) image.Image {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return img
+	}
+	b := img.Bounds()
+	dst := image.NewRGBA(b)
+	draw.Draw(dst, b, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, b, img, b.Min, draw.Over)
+	return dst
+}
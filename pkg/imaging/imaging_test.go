@@ -0,0 +1,151 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func TestParseVariants(t *testing.T) {
+	variants, err := ParseVariants(" thumbnail:200x200:jpeg, webp:0x0:WEBP ,")
+	if err != nil {
+		t.Fatalf("ParseVariants: %v", err)
+	}
+	want := []Variant{
+		{Name: "thumbnail", Width: 200, Height: 200, Format: FormatJPEG},
+		{Name: "webp", Width: 0, Height: 0, Format: FormatWebP},
+	}
+	if len(variants) != len(want) {
+		t.Fatalf("got %d variants, want %d", len(variants), len(want))
+	}
+	for i := range want {
+		if variants[i] != want[i] {
+			t.Errorf("variant %d = %+v, want %+v", i, variants[i], want[i])
+		}
+	}
+
+	for _, spec := range []string{
+		"thumbnail:200x200",
+		"thumbnail:200:jpeg",
+		"thumbnail:-1x200:jpeg",
+		"thumbnail:200x200:bmp",
+		"Thumbnail:200x200:jpeg",
+		"a:1x1:png,a:2x2:png",
+	} {
+		if _, err := ParseVariants(spec); err == nil {
+			t.Errorf("ParseVariants(%q): expected error", spec)
+		}
+	}
+}
+
+func TestFit(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 100))
+
+	if got := Fit(img, 200, 200).Bounds().Size(); got != image.Pt(200, 50) {
+		t.Errorf("Fit to 200x200 = %v, want 200x50", got)
+	}
+	if got := Fit(img, 0, 20).Bounds().Size(); got != image.Pt(80, 20) {
+		t.Errorf("Fit to height 20 = %v, want 80x20", got)
+	}
+	if got := Fit(img, 800, 800); got != image.Image(img) {
+		t.Error("Fit should not enlarge an image that already fits")
+	}
+}
+
+func TestDecodeRejectsHugeCanvas(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	// Patch the IHDR dimensions to 10000x10000 without changing the pixel data.
+	copy(data[16:24], []byte{0, 0, 0x27, 0x10, 0, 0, 0x27, 0x10})
+
+	if _, err := Decode(bytes.NewReader(data)); err == nil {
+		t.Fatal("expected error for canvas over MaxPixels")
+	}
+}
+
+func TestEncodeWebP(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	noisy := image.NewNRGBA(image.Rect(0, 0, 37, 23))
+	for i := range noisy.Pix {
+		noisy.Pix[i] = uint8(rng.Intn(256))
+	}
+	gradient := image.NewNRGBA(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			gradient.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 4), G: uint8(y * 5), B: 128, A: 255})
+		}
+	}
+	// Fibonacci-distributed values make the optimal Huffman tree deeper than 15 bits.
+	skewed := image.NewNRGBA(image.Rect(0, 0, 200, 144))
+	var values []uint8
+	for v, n, next := 0, 1, 1; len(values) < 200*144; v, n, next = v+1, next, n+next {
+		for range n {
+			values = append(values, uint8(v))
+		}
+	}
+	for i := range 200 * 144 {
+		skewed.Pix[4*i+1], skewed.Pix[4*i+3] = values[i], 0xff
+	}
+	flat := image.NewNRGBA(image.Rect(0, 0, 5, 5))
+	for i := range flat.Pix {
+		flat.Pix[i] = 0xff
+	}
+
+	for name, img := range map[string]*image.NRGBA{"noisy": noisy, "gradient": gradient, "skewed": skewed, "flat": flat} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, img, FormatWebP); err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			decoded, err := webp.Decode(&buf)
+			if err != nil {
+				t.Fatalf("webp.Decode: %v", err)
+			}
+			if decoded.Bounds() != img.Bounds() {
+				t.Fatalf("bounds = %v, want %v", decoded.Bounds(), img.Bounds())
+			}
+			b := img.Bounds()
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					want := img.NRGBAAt(x, y)
+					got := color.NRGBAModel.Convert(decoded.At(x, y)).(color.NRGBA)
+					if want.A == 0 {
+						want, got = color.NRGBA{}, color.NRGBA{A: got.A}
+					}
+					if got != want {
+						t.Fatalf("pixel (%d,%d) = %v, want %v", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 300, 150))
+	for _, format := range []string{FormatJPEG, FormatPNG, FormatWebP} {
+		var buf bytes.Buffer
+		size, err := Render(&buf, img, Variant{Name: "thumb", Width: 100, Height: 100, Format: format})
+		if err != nil {
+			t.Fatalf("Render %s: %v", format, err)
+		}
+		if size != image.Pt(100, 50) {
+			t.Errorf("Render %s size = %v, want 100x50", format, size)
+		}
+		cfg, got, err := image.DecodeConfig(&buf)
+		if err != nil {
+			t.Fatalf("DecodeConfig %s: %v", format, err)
+		}
+		if got != format || cfg.Width != 100 || cfg.Height != 50 {
+			t.Errorf("rendered %s %dx%d, want %s 100x50", got, cfg.Width, cfg.Height, format)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package imaging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"io"
+)
+
+// ErrInvalidJPEG is returned by SanitizeJPEG for content that is not a well-formed JPEG.
+var ErrInvalidJPEG = errors.New("invalid JPEG image")
+
+// sanitizedJPEGQuality is used when SanitizeJPEG has to re-encode an image. It is higher
+// than the quality of variants since the result replaces the original.
+const sanitizedJPEGQuality = 95
+
+// JPEG markers SanitizeJPEG looks at.
+const (
+	markerSOI   = 0xD8
+	markerEOI   = 0xD9
+	markerSOS   = 0xDA
+	markerRST0  = 0xD0
+	markerRST7  = 0xD7
+	markerTEM   = 0x01
+	markerAPP0  = 0xE0
+	markerAPP1  = 0xE1
+	markerAPP2  = 0xE2
+	markerAPP14 = 0xEE
+	markerAPP15 = 0xEF
+	markerCOM   = 0xFE
+)
+
+// exifOrientationTag is the EXIF tag holding how the stored image is rotated or mirrored.
+const exifOrientationTag = 0x0112
+
+// SanitizeJPEG copies the JPEG in r to w without the metadata that can reveal where, when
+// and with what it was taken: EXIF and XMP, IPTC, comments, other application segments
+// and anything after the end of the image, where some cameras append previews. The JFIF
+// header, the ICC colour profile and Adobe's colour transform flag are kept, as they
+// affect how the image looks. The compressed image is copied untouched unless the EXIF
+// orientation says it is stored rotated or mirrored; as the tag is dropped, the image is
+// then decoded, turned upright and re-encoded.
+func SanitizeJPEG(w io.Writer, r io.Reader) error {
+	br := bufio.NewReader(r)
+	if m, err := readMarker(br); err != nil || m != markerSOI {
+		return ErrInvalidJPEG
+	}
+
+	// The segments before the first scan are collected, so the orientation is known
+	// before anything is written.
+	head := bytes.NewBuffer([]byte{0xFF, markerSOI})
+	orientation := 1
+	for {
+		m, err := readMarker(br)
+		if err != nil {
+			return err
+		}
+		if m == markerSOS {
+			break
+		}
+		if m == markerEOI {
+			return fmt.Errorf("%w: no image data", ErrInvalidJPEG)
+		}
+		if standaloneMarker(m) {
+			head.Write([]byte{0xFF, m})
+			continue
+		}
+
+		payload, err := readSegment(br)
+		if err != nil {
+			return err
+		}
+		if m == markerAPP1 {
+			if o, ok := exifOrientation(payload); ok {
+				orientation = o
+			}
+		}
+		if !metadataSegment(m, payload) {
+			writeSegment(head, m, payload)
+		}
+	}
+
+	if orientation < 2 || orientation > 8 {
+		if _, err := w.Write(head.Bytes()); err != nil {
+			return err
+		}
+		return copyScans(w, br)
+	}
+
+	if err := copyScans(head, br); err != nil {
+		return err
+	}
+	img, err := Decode(head)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidJPEG, err)
+	}
+	return jpeg.Encode(w, Orient(img, orientation), &jpeg.Options{Quality: sanitizedJPEGQuality})
+}
+
+// Orient turns an image stored with the given EXIF orientation upright. Orientations
+// other than 2 to 8 leave it unchanged.
+func Orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	w, h := b.Dx(), b.Dy()
+
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := range dh {
+		for x := range dw {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				sx, sy = w-1-x, y
+			case 3: // rotated 180°
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				sx, sy = x, h-1-y
+			case 5: // mirrored along the top-left diagonal
+				sx, sy = y, x
+			case 6: // needs a 90° clockwise turn
+				sx, sy = y, h-1-x
+			case 7: // mirrored along the top-right diagonal
+				sx, sy = w-1-y, h-1-x
+			case 8: // needs a 90° counter-clockwise turn
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):][:4], src.Pix[src.PixOffset(sx, sy):][:4])
+		}
+	}
+	return dst
+}
+
+// copyScans copies the image from its first scan, whose marker has been read, to the end
+// of the image. Metadata segments between scans and whatever follows the end are dropped;
+// an image cut short is closed with an end marker.
+func copyScans(w io.Writer, br *bufio.Reader) error {
+	bw := bufio.NewWriter(w)
+	m := byte(markerSOS)
+	for m != markerEOI {
+		payload, err := readSegment(br)
+		if err != nil {
+			return err
+		}
+		if !metadataSegment(m, payload) {
+			writeSegment(bw, m, payload)
+		}
+
+		if m == markerSOS {
+			m, err = copyEntropyData(bw, br)
+		} else {
+			m, err = readMarker(br)
+		}
+		if err != nil {
+			return err
+		}
+		for standaloneMarker(m) && m != markerEOI {
+			_, _ = bw.Write([]byte{0xFF, m})
+			if m, err = readMarker(br); err != nil {
+				return err
+			}
+		}
+	}
+	_, _ = bw.Write([]byte{0xFF, markerEOI})
+	return bw.Flush()
+}
+
+// copyEntropyData copies the compressed data of a scan, including its restart markers, and
+// returns the marker that ends it.
+func copyEntropyData(w *bufio.Writer, br *bufio.Reader) (byte, error) {
+	for {
+		chunk, err := br.ReadSlice(0xFF)
+		if errors.Is(err, bufio.ErrBufferFull) {
+			_, _ = w.Write(chunk)
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			_, _ = w.Write(chunk)
+			return markerEOI, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		_, _ = w.Write(chunk[:len(chunk)-1])
+
+		b, err := br.ReadByte()
+		for err == nil && b == 0xFF {
+			b, err = br.ReadByte()
+		}
+		if errors.Is(err, io.EOF) {
+			return markerEOI, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if b != 0 && (b < markerRST0 || b > markerRST7) {
+			return b, nil
+		}
+		_, _ = w.Write([]byte{0xFF, b})
+	}
+}
+
+// readMarker reads a marker, skipping the fill bytes that may precede it.
+func readMarker(br *bufio.Reader) (byte, error) {
+	b, err := br.ReadByte()
+	if err != nil || b != 0xFF {
+		return 0, fmt.Errorf("%w: expected a marker", ErrInvalidJPEG)
+	}
+	for b == 0xFF {
+		if b, err = br.ReadByte(); err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrInvalidJPEG, err)
+		}
+	}
+	return b, nil
+}
+
+// readSegment reads the payload of a segment whose marker has been read.
+func readSegment(br *bufio.Reader) ([]byte, error) {
+	var length [2]byte
+	if _, err := io.ReadFull(br, length[:]); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJPEG, err)
+	}
+	n := int(binary.BigEndian.Uint16(length[:]))
+	if n < 2 {
+		return nil, fmt.Errorf("%w: bad segment length", ErrInvalidJPEG)
+	}
+	payload := make([]byte, n-2)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJPEG, err)
+	}
+	return payload, nil
+}
+
+func writeSegment(w io.Writer, m byte, payload []byte) {
+	_, _ = w.Write([]byte{0xFF, m, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)})
+	_, _ = w.Write(payload)
+}
+
+// standaloneMarker reports whether a marker has no segment payload.
+func standaloneMarker(m byte) bool {
+	return m == markerTEM || m == markerSOI || m == markerEOI || (m >= markerRST0 && m <= markerRST7)
+}
+
+// metadataSegment reports whether a segment carries metadata that SanitizeJPEG drops:
+// comments and every application segment except JFIF, ICC profiles and Adobe's.
+func metadataSegment(m byte, payload []byte) bool {
+	switch {
+	case m == markerCOM:
+		return true
+	case m == markerAPP0, m == markerAPP14:
+		return false
+	case m == markerAPP2:
+		return !bytes.HasPrefix(payload, []byte("ICC_PROFILE\x00"))
+	}
+	return m >= markerAPP0 && m <= markerAPP15
+}
+
+// exifOrientation reads the orientation from the first directory of an EXIF APP1 payload.
+func exifOrientation(payload []byte) (int, bool) {
+	tiff, ok := bytes.CutPrefix(payload, []byte("Exif\x00\x00"))
+	if !ok || len(tiff) < 8 {
+		return 0, false
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return 0, false
+	}
+
+	ifd := int64(order.Uint32(tiff[4:8]))
+	if ifd+2 > int64(len(tiff)) {
+		return 0, false
+	}
+	count := int64(order.Uint16(tiff[ifd:]))
+	for i := range count {
+		entry := ifd + 2 + i*12
+		if entry+12 > int64(len(tiff)) {
+			return 0, false
+		}
+		if order.Uint16(tiff[entry:]) == exifOrientationTag {
+			return int(order.Uint16(tiff[entry+8:])), true
+		}
+	}
+	return 0, false
+}
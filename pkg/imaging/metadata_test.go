@@ -0,0 +1,133 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// exifSegment builds an APP1 segment holding a little-endian EXIF directory with the
+// given orientation and a fake GPS pointer.
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte{
+		'I', 'I', 0x2A, 0x00, 0x08, 0x00, 0x00, 0x00, // header, directory at offset 8
+		0x02, 0x00, // two entries
+		0x12, 0x01, 0x03, 0x00, 0x01, 0x00, 0x00, 0x00, byte(orientation), 0x00, 0x00, 0x00,
+		0x25, 0x88, 0x04, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, // no next directory
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	var seg bytes.Buffer
+	writeSegment(&seg, markerAPP1, payload)
+	return seg.Bytes()
+}
+
+// taggedJPEG encodes a 40x20 image, red on the left and blue on the right, adding an
+// EXIF segment and a comment after the start marker and a trailer after the end.
+func taggedJPEG(t *testing.T, orientation uint16) (tagged, plain []byte) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := range 20 {
+		for x := range 40 {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 20 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	plain = buf.Bytes()
+
+	var comment bytes.Buffer
+	writeSegment(&comment, markerCOM, []byte("shot at home"))
+	tagged = append([]byte{0xFF, markerSOI}, exifSegment(orientation)...)
+	tagged = append(tagged, comment.Bytes()...)
+	tagged = append(tagged, plain[2:]...)
+	tagged = append(tagged, []byte("trailing preview")...)
+	return tagged, plain
+}
+
+func TestSanitizeJPEG(t *testing.T) {
+	t.Run("metadata is dropped and the image data kept", func(t *testing.T) {
+		tagged, plain := taggedJPEG(t, 1)
+		var out bytes.Buffer
+		if err := SanitizeJPEG(&out, bytes.NewReader(tagged)); err != nil {
+			t.Fatalf("SanitizeJPEG: %v", err)
+		}
+		if !bytes.Equal(out.Bytes(), plain) {
+			t.Errorf("sanitized image differs from the untagged encoding (%d vs %d bytes)", out.Len(), len(plain))
+		}
+	})
+
+	t.Run("rotated images are turned upright", func(t *testing.T) {
+		tagged, _ := taggedJPEG(t, 6)
+		var out bytes.Buffer
+		if err := SanitizeJPEG(&out, bytes.NewReader(tagged)); err != nil {
+			t.Fatalf("SanitizeJPEG: %v", err)
+		}
+		if bytes.Contains(out.Bytes(), []byte("Exif")) || bytes.Contains(out.Bytes(), []byte("shot at home")) {
+			t.Error("metadata survived re-encoding")
+		}
+		img, err := jpeg.Decode(&out)
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if b := img.Bounds(); b.Dx() != 20 || b.Dy() != 40 {
+			t.Fatalf("size = %dx%d, want 20x40", b.Dx(), b.Dy())
+		}
+		// Turned clockwise, the red left half ends up on top.
+		if r, _, b, _ := img.At(10, 5).RGBA(); r < b {
+			t.Error("top of the image is not red")
+		}
+		if r, _, b, _ := img.At(10, 35).RGBA(); b < r {
+			t.Error("bottom of the image is not blue")
+		}
+	})
+
+	t.Run("non-JPEG content is rejected", func(t *testing.T) {
+		for _, data := range [][]byte{[]byte("hello"), {0xFF, markerSOI, 0xFF, markerEOI}, {0xFF, markerSOI, 0xFF, markerAPP1, 0x00}} {
+			if err := SanitizeJPEG(&bytes.Buffer{}, bytes.NewReader(data)); !errors.Is(err, ErrInvalidJPEG) {
+				t.Errorf("SanitizeJPEG(%q) = %v, want ErrInvalidJPEG", data, err)
+			}
+		}
+	})
+}
+
+func TestOrient(t *testing.T) {
+	// A 2x1 image: red then blue.
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	img.Set(1, 0, color.RGBA{B: 255, A: 255})
+
+	tests := []struct {
+		orientation int
+		w, h        int
+		red         image.Point
+	}{
+		{1, 2, 1, image.Pt(0, 0)},
+		{2, 2, 1, image.Pt(1, 0)},
+		{3, 2, 1, image.Pt(1, 0)},
+		{4, 2, 1, image.Pt(0, 0)},
+		{5, 1, 2, image.Pt(0, 0)},
+		{6, 1, 2, image.Pt(0, 0)},
+		{7, 1, 2, image.Pt(0, 1)},
+		{8, 1, 2, image.Pt(0, 1)},
+	}
+	for _, tt := range tests {
+		got := Orient(img, tt.orientation)
+		if b := got.Bounds(); b.Dx() != tt.w || b.Dy() != tt.h {
+			t.Errorf("orientation %d: size = %dx%d, want %dx%d", tt.orientation, b.Dx(), b.Dy(), tt.w, tt.h)
+			continue
+		}
+		if r, _, _, _ := got.At(tt.red.X, tt.red.Y).RGBA(); r == 0 {
+			t.Errorf("orientation %d: red pixel not at %v", tt.orientation, tt.red)
+		}
+	}
+}
@@ -0,0 +1,347 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"math/bits"
+	"sort"
+
+	"golang.org/x/image/draw"
+)
+
+// The encoder below writes lossless WebP (VP8L, RFC 9649). It applies the subtract-green
+// transform and codes every pixel as a literal with one set of Huffman codes built from
+// the image's histograms. Without backward references or predictors the output is
+// larger than libwebp's, but it needs no cgo and any WebP decoder can read it.
+
+const (
+	vp8lSignature = 0x2f
+	vp8lMaxSize   = 1 << 14
+
+	transformSubtractGreen = 2
+
+	// Alphabet sizes of the five prefix codes: green (literals plus the 24 length
+	// prefixes, no color cache), red, blue, alpha and distance.
+	greenAlphabet    = 256 + 24
+	literalAlphabet  = 256
+	distanceAlphabet = 40
+
+	maxCodeLength           = 15
+	maxCodeLengthCodeLength = 7
+)
+
+// codeLengthCodeOrder is the order in which the code length code's lengths are written.
+var codeLengthCodeOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+func encodeWebP(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width < 1 || height < 1 || width > vp8lMaxSize || height > vp8lMaxSize {
+		return fmt.Errorf("webp: image size %dx%d out of range", width, height)
+	}
+
+	src := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+
+	// Subtract green from red and blue, then count symbols per channel.
+	pix := src.Pix
+	hasAlpha := false
+	hist := [5][]uint32{
+		make([]uint32, greenAlphabet),
+		make([]uint32, literalAlphabet),
+		make([]uint32, literalAlphabet),
+		make([]uint32, literalAlphabet),
+		make([]uint32, distanceAlphabet),
+	}
+	for i := 0; i < len(pix); i += 4 {
+		pix[i] -= pix[i+1]
+		pix[i+2] -= pix[i+1]
+		hist[0][pix[i+1]]++
+		hist[1][pix[i]]++
+		hist[2][pix[i+2]]++
+		hist[3][pix[i+3]]++
+		if pix[i+3] != 0xff {
+			hasAlpha = true
+		}
+	}
+
+	var bw bitWriter
+	bw.write(vp8lSignature, 8)
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	bw.write(b2u(hasAlpha), 1)
+	bw.write(0, 3) // version
+	bw.write(1, 1) // a transform follows
+	bw.write(transformSubtractGreen, 2)
+	bw.write(0, 1) // no more transforms
+	bw.write(0, 1) // no color cache
+	bw.write(0, 1) // a single prefix code group for the whole image
+
+	var codes [5]prefixCode
+	for i := range codes {
+		codes[i] = writePrefixCode(&bw, hist[i])
+	}
+	for i := 0; i < len(pix); i += 4 {
+		codes[0].write(&bw, int(pix[i+1]))
+		codes[1].write(&bw, int(pix[i]))
+		codes[2].write(&bw, int(pix[i+2]))
+		codes[3].write(&bw, int(pix[i+3]))
+	}
+	data := bw.bytes()
+
+	padded := len(data) + len(data)&1
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(4+8+padded))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(data)))
+	if len(data)&1 == 1 {
+		data = append(data, 0)
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// bitWriter packs values least significant bit first, as VP8L requires.
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+func (w *bitWriter) write(v uint32, n uint) {
+	w.acc |= uint64(v) << w.nbits
+	w.nbits += n
+	for w.nbits >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.nbits -= 8
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	if w.nbits > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc, w.nbits = 0, 0
+	}
+	return w.buf
+}
+
+// prefixCode holds the bit-reversed canonical code of each symbol and how many bits to
+// write for it. A code with a single symbol is written with zero bits.
+type prefixCode struct {
+	codes []uint16
+	bits  []uint8
+}
+
+func (c prefixCode) write(w *bitWriter, symbol int) {
+	w.write(uint32(c.codes[symbol]), uint(c.bits[symbol]))
+}
+
+// writePrefixCode writes the code for a histogram to w and returns it.
+func writePrefixCode(w *bitWriter, hist []uint32) prefixCode {
+	var used []int
+	for s, n := range hist {
+		if n > 0 {
+			used = append(used, s)
+		}
+	}
+
+	// Zero or one used symbol: a simple code whose symbol takes no bits.
+	if len(used) <= 1 {
+		symbol := 0
+		if len(used) == 1 {
+			symbol = used[0]
+		}
+		w.write(1, 1) // simple code
+		w.write(0, 1) // one symbol
+		if symbol < 2 {
+			w.write(0, 1)
+			w.write(uint32(symbol), 1)
+		} else {
+			w.write(1, 1)
+			w.write(uint32(symbol), 8)
+		}
+		return prefixCode{codes: make([]uint16, len(hist)), bits: make([]uint8, len(hist))}
+	}
+
+	lengths := huffmanLengths(hist, maxCodeLength)
+	tokens := codeLengthTokens(lengths)
+
+	clHist := make([]uint32, len(codeLengthCodeOrder))
+	for _, t := range tokens {
+		clHist[t.symbol]++
+	}
+	clLengths := huffmanLengths(clHist, maxCodeLengthCodeLength)
+	clCode := newPrefixCode(clLengths)
+
+	n := len(codeLengthCodeOrder)
+	for n > 4 && clLengths[codeLengthCodeOrder[n-1]] == 0 {
+		n--
+	}
+	w.write(0, 1) // normal code
+	w.write(uint32(n-4), 4)
+	for _, s := range codeLengthCodeOrder[:n] {
+		w.write(uint32(clLengths[s]), 3)
+	}
+	w.write(0, 1) // code lengths cover the whole alphabet
+	for _, t := range tokens {
+		clCode.write(w, t.symbol)
+		if t.extraBits > 0 {
+			w.write(t.extra, t.extraBits)
+		}
+	}
+	return newPrefixCode(lengths)
+}
+
+// codeLengthToken is one symbol of the code length code: a literal length (0-15), or a
+// run of zeros (17: 3-10, 18: 11-138) with the run length in extra bits.
+type codeLengthToken struct {
+	symbol    int
+	extra     uint32
+	extraBits uint
+}
+
+func codeLengthTokens(lengths []uint8) []codeLengthToken {
+	var tokens []codeLengthToken
+	for i := 0; i < len(lengths); {
+		if lengths[i] != 0 {
+			tokens = append(tokens, codeLengthToken{symbol: int(lengths[i])})
+			i++
+			continue
+		}
+
+		run := 1
+		for i+run < len(lengths) && lengths[i+run] == 0 && run < 138 {
+			run++
+		}
+		switch {
+		case run < 3:
+			for range run {
+				tokens = append(tokens, codeLengthToken{symbol: 0})
+			}
+		case run <= 10:
+			tokens = append(tokens, codeLengthToken{symbol: 17, extra: uint32(run - 3), extraBits: 3})
+		default:
+			tokens = append(tokens, codeLengthToken{symbol: 18, extra: uint32(run - 11), extraBits: 7})
+		}
+		i += run
+	}
+	return tokens
+}
+
+// newPrefixCode assigns canonical codes to the lengths.
+func newPrefixCode(lengths []uint8) prefixCode {
+	var count [maxCodeLength + 1]uint16
+	used := 0
+	for _, l := range lengths {
+		if l > 0 {
+			count[l]++
+			used++
+		}
+	}
+	var next [maxCodeLength + 1]uint16
+	code := uint16(0)
+	for l := 1; l <= maxCodeLength; l++ {
+		code = (code + count[l-1]) << 1
+		next[l] = code
+	}
+
+	c := prefixCode{codes: make([]uint16, len(lengths)), bits: make([]uint8, len(lengths))}
+	if used == 1 {
+		// Decoders read a lone symbol with zero bits whatever length it declares.
+		return c
+	}
+	for s, l := range lengths {
+		if l > 0 {
+			c.codes[s] = bits.Reverse16(next[l]) >> (16 - l)
+			c.bits[s] = l
+			next[l]++
+		}
+	}
+	return c
+}
+
+// huffmanLengths returns Huffman code lengths for the histogram, none longer than
+// maxLen. When the optimal code is too deep, rare symbols are given a minimum weight
+// that doubles until the tree is shallow enough.
+func huffmanLengths(hist []uint32, maxLen int) []uint8 {
+	lengths := make([]uint8, len(hist))
+	var used []int
+	for s, n := range hist {
+		if n > 0 {
+			used = append(used, s)
+		}
+	}
+	switch len(used) {
+	case 0:
+		return lengths
+	case 1:
+		lengths[used[0]] = 1
+		return lengths
+	}
+
+	for minWeight := uint32(1); ; minWeight *= 2 {
+		if huffmanDepths(hist, used, minWeight, lengths) <= maxLen {
+			return lengths
+		}
+	}
+}
+
+// huffmanDepths builds a Huffman tree over the used symbols, stores each symbol's depth
+// in lengths and returns the deepest.
+func huffmanDepths(hist []uint32, used []int, minWeight uint32, lengths []uint8) int {
+	type node struct {
+		weight uint64
+		parent int
+	}
+	n := len(used)
+	nodes := make([]node, n, 2*n-1)
+	order := make([]int, n)
+	for i, s := range used {
+		nodes[i] = node{weight: uint64(max(hist[s], minWeight)), parent: -1}
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return nodes[order[a]].weight < nodes[order[b]].weight })
+
+	// Leaves are taken in weight order and merged nodes are created in weight order,
+	// so the two lightest nodes are always at the front of one of the two queues.
+	leaf, merged := 0, n
+	lightest := func() int {
+		if leaf < n && (merged == len(nodes) || nodes[order[leaf]].weight <= nodes[merged].weight) {
+			leaf++
+			return order[leaf-1]
+		}
+		merged++
+		return merged - 1
+	}
+	for len(nodes) < 2*n-1 {
+		a, b := lightest(), lightest()
+		nodes = append(nodes, node{weight: nodes[a].weight + nodes[b].weight, parent: -1})
+		nodes[a].parent = len(nodes) - 1
+		nodes[b].parent = len(nodes) - 1
+	}
+
+	deepest := 0
+	for i, s := range used {
+		depth := 0
+		for p := nodes[i].parent; p >= 0; p = nodes[p].parent {
+			depth++
+		}
+		lengths[s] = uint8(depth)
+		deepest = max(deepest, depth)
+	}
+	return deepest
+}
+
+func b2u(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -23,3 +23,81 @@ var (
 		[]string{"method", "path"},
 	)
 )
+
+// Cache metrics are labeled with the cache driver and the keyspace of the key, the part
+// before its first colon, such as login_attempts or user_profile, so each use of the
+// cache can be watched on its own.
+var (
+	CacheHitsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_hits_total",
+			Help: "Total number of cache lookups that found a value.",
+		},
+		[]string{"driver", "keyspace"},
+	)
+
+	CacheMissesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_misses_total",
+			Help: "Total number of cache lookups that found no value.",
+		},
+		[]string{"driver", "keyspace"},
+	)
+
+	CacheSetsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_sets_total",
+			Help: "Total number of values written to the cache.",
+		},
+		[]string{"driver", "keyspace"},
+	)
+
+	CacheDeletesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_deletes_total",
+			Help: "Total number of keys and tags deleted from the cache.",
+		},
+		[]string{"driver", "keyspace"},
+	)
+
+	CacheErrorsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_errors_total",
+			Help: "Total number of failed cache operations.",
+		},
+		[]string{"driver", "operation"},
+	)
+
+	CacheOperationDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cache_operation_duration_seconds",
+			Help:    "Duration of cache operations in seconds.",
+			Buckets: []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		},
+		[]string{"driver", "operation"},
+	)
+)
+
+// Memory cache metrics cover every memory cache of the process.
+var (
+	CacheMemoryEntries = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cache_memory_entries",
+			Help: "Number of entries held by the memory cache.",
+		},
+	)
+
+	CacheMemoryBytes = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cache_memory_bytes",
+			Help: "Size in bytes of the keys and values held by the memory cache.",
+		},
+	)
+
+	CacheMemoryEvictionsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cache_memory_evictions_total",
+			Help: "Total number of entries evicted from the memory cache to stay within its size limits.",
+		},
+	)
+)
@@ -0,0 +1,139 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
+)
+
+const (
+	githubUserURL   = "https://api.github.com/user"
+	githubEmailsURL = "https://api.github.com/user/emails"
+)
+
+type githubUser struct {
+	ID    int64  `json:"id"`
+	Login string `json:"login"`
+	Name  string `json:"name"`
+}
+
+type githubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+type GitHubOAuth struct {
+	cfg         *oauth2.Config
+	frontendURL string
+}
+
+func NewGitHubOAuth(cfg config.OAuthConfig) *GitHubOAuth {
+	return &GitHubOAuth{
+		cfg: &oauth2.Config{
+			ClientID:     cfg.GitHubClientID,
+			ClientSecret: cfg.GitHubClientSecret,
+			RedirectURL:  cfg.GitHubRedirectURL,
+			Scopes:       []string{"read:user", "user:email"},
+			Endpoint:     github.Endpoint,
+		},
+		frontendURL: cfg.FrontendURL,
+	}
+}
+
+// Name returns the provider identifier.
+func (g *GitHubOAuth) Name() string {
+	return "github"
+}
+
+// ValidateFrontendURL checks that the configured frontend URL is parseable and uses http(s).
+func (g *GitHubOAuth) ValidateFrontendURL() error {
+	return ValidateFrontendURL(g.frontendURL)
+}
+
+func (g *GitHubOAuth) AuthURL(state string) string {
+	return g.cfg.AuthCodeURL(state)
+}
+
+// BuildCallbackURL constructs the redirect URL with tokens in the URL fragment.
+func (g *GitHubOAuth) BuildCallbackURL(accessToken, refreshToken string) string {
+	return BuildCallbackURL(g.frontendURL, accessToken, refreshToken)
+}
+
+func (g *GitHubOAuth) Exchange(ctx context.Context, code string) (*UserInfo, error) {
+	token, err := g.cfg.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %w", err)
+	}
+
+	client := g.cfg.Client(ctx, token)
+
+	var user githubUser
+	if err := getJSON(ctx, client, githubUserURL, &user); err != nil {
+		return nil, fmt.Errorf("failed to get user info: %w", err)
+	}
+
+	// The profile email is optional and may be hidden, so always use the
+	// primary verified address from the emails endpoint.
+	var emails []githubEmail
+	if err := getJSON(ctx, client, githubEmailsURL, &emails); err != nil {
+		return nil, fmt.Errorf("failed to get user emails: %w", err)
+	}
+
+	var primaryEmail string
+	for _, e := range emails {
+		if e.Primary && e.Verified {
+			primaryEmail = e.Email
+			break
+		}
+	}
+
+	if user.ID == 0 || primaryEmail == "" {
+		return nil, fmt.Errorf("incomplete user info from GitHub")
+	}
+
+	name := user.Name
+	if name == "" {
+		name = user.Login
+	}
+
+	return &UserInfo{
+		ID:    strconv.FormatInt(user.ID, 10),
+		Email: primaryEmail,
+		Name:  name,
+	}, nil
+}
+
+func (g *GitHubOAuth) FrontendURL() string {
+	return g.frontendURL
+}
+
+// getJSON performs a GET request with the GitHub API media type and decodes the JSON response into v.
+func getJSON(ctx context.Context, client *http.Client, endpoint string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, http.NoBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("github api returned status %d: %s", resp.StatusCode, body)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -16,12 +16,6 @@ import (
 
 const googleUserInfoURL = "https://www.googleapis.com/oauth2/v2/userinfo"
 
-type GoogleUserInfo struct {
-	ID    string `json:"id"`
-	Email string `json:"email"`
-	Name  string `json:"name"`
-}
-
 type GoogleOAuth struct {
 	cfg            *oauth2.Config
 	frontendURL    string
@@ -49,19 +43,14 @@ func NewGoogleOAuth(cfg config.OAuthConfig) *GoogleOAuth {
 	return g
 }
 
+// Name returns the provider identifier.
+func (g *GoogleOAuth) Name() string {
+	return "google"
+}
+
 // ValidateFrontendURL checks that the configured frontend URL is parseable and uses http(s).
 func (g *GoogleOAuth) ValidateFrontendURL() error {
-	parsed, err := url.Parse(g.frontendURL)
-	if err != nil {
-		return fmt.Errorf("invalid OAUTH_FRONTEND_URL: %w", err)
-	}
-	if parsed.Scheme != "http" && parsed.Scheme != "https" {
-		return fmt.Errorf("OAUTH_FRONTEND_URL must use http or https scheme (got %q)", parsed.Scheme)
-	}
-	if parsed.Host == "" {
-		return fmt.Errorf("OAUTH_FRONTEND_URL must have a host")
-	}
-	return nil
+	return ValidateFrontendURL(g.frontendURL)
 }
 
 func (g *GoogleOAuth) AuthURL(state string) string {
@@ -71,13 +60,10 @@ func (g *GoogleOAuth) AuthURL(state string) string {
 // BuildCallbackURL constructs the redirect URL with tokens in the URL fragment.
 // Fragment data is never sent to the server, preventing token leakage via Referer headers.
 func (g *GoogleOAuth) BuildCallbackURL(accessToken, refreshToken string) string {
-	params := url.Values{}
-	params.Set("access_token", accessToken)
-	params.Set("refresh_token", refreshToken)
-	return g.frontendURL + "#" + params.Encode()
+	return BuildCallbackURL(g.frontendURL, accessToken, refreshToken)
 }
 
-func (g *GoogleOAuth) Exchange(ctx context.Context, code string) (*GoogleUserInfo, error) {
+func (g *GoogleOAuth) Exchange(ctx context.Context, code string) (*UserInfo, error) {
 	token, err := g.cfg.Exchange(ctx, code)
 	if err != nil {
 		return nil, fmt.Errorf("failed to exchange code: %w", err)
@@ -95,7 +81,7 @@ func (g *GoogleOAuth) Exchange(ctx context.Context, code string) (*GoogleUserInf
 		return nil, fmt.Errorf("google userinfo returned status %d: %s", resp.StatusCode, body)
 	}
 
-	var info GoogleUserInfo
+	var info UserInfo
 	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
 		return nil, fmt.Errorf("failed to decode user info: %w", err)
 	}
@@ -0,0 +1,51 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// UserInfo is the normalized profile returned by every OAuth provider.
+type UserInfo struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+// Provider is an OAuth 2.0 identity provider used for social login.
+type Provider interface {
+	// Name returns the provider identifier stored in users.auth_provider.
+	Name() string
+	AuthURL(state string) string
+	Exchange(ctx context.Context, code string) (*UserInfo, error)
+	BuildCallbackURL(accessToken, refreshToken string) string
+	ValidateFrontendURL() error
+}
+
+// ValidateFrontendURL checks that the frontend URL is parseable and uses http(s).
+func ValidateFrontendURL(frontendURL string) error {
+	parsed, err := url.Parse(frontendURL)
+	if err != nil {
+		return fmt.Errorf("invalid OAUTH_FRONTEND_URL: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("OAUTH_FRONTEND_URL must use http or https scheme (got %q)", parsed.Scheme)
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("OAUTH_FRONTEND_URL must have a host")
+	}
+	return nil
+}
+
+// BuildCallbackURL constructs the redirect URL with tokens in the URL fragment.
+// Fragment data is never sent to the server, preventing token leakage via Referer headers.
+// An empty refresh token is omitted (it is delivered as a cookie in cookie mode).
+func BuildCallbackURL(frontendURL, accessToken, refreshToken string) string {
+	params := url.Values{}
+	params.Set("access_token", accessToken)
+	if refreshToken != "" {
+		params.Set("refresh_token", refreshToken)
+	}
+	return frontendURL + "#" + params.Encode()
+}
@@ -0,0 +1,227 @@
+package realtime
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/fasthttp/websocket"
+)
+
+const (
+	// Time allowed to write a message to the client
+	writeWait = 10 * time.Second
+	// Time allowed between pongs before the connection is considered dead
+	pongWait = 60 * time.Second
+	// Pings are sent before pongWait runs out
+	pingPeriod = pongWait * 9 / 10
+	// Clients only answer pings and close, so their messages stay small
+	maxMessageSize = 512
+	// Events queued for a client; one that falls this far behind is disconnected
+	sendBufferSize = 32
+)
+
+var (
+	// ErrTooManyConnections is returned by Register when the user has as many connections
+	// as the hub allows.
+	ErrTooManyConnections = errors.New("too many connections")
+	// ErrHubClosed is returned by Register once the hub is closed.
+	ErrHubClosed = errors.New("hub closed")
+)
+
+// Hub tracks the WebSocket connections of each user and fans events out to them.
+type Hub struct {
+	mu         sync.RWMutex
+	clients    map[int64]map[*Client]struct{}
+	maxPerUser int
+	closed     bool
+}
+
+// NewHub creates a hub allowing up to maxPerUser connections per user; 0 means no limit.
+func NewHub(maxPerUser int) *Hub {
+	return &Hub{clients: make(map[int64]map[*Client]struct{}), maxPerUser: maxPerUser}
+}
+
+// Register reserves a connection for the user. The caller must Serve the returned client,
+// or Close it if the connection cannot be opened.
+func (h *Hub) Register(userID int64) (*Client, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrHubClosed
+	}
+	clients := h.clients[userID]
+	if h.maxPerUser > 0 && len(clients) >= h.maxPerUser {
+		return nil, ErrTooManyConnections
+	}
+	if clients == nil {
+		clients = make(map[*Client]struct{})
+		h.clients[userID] = clients
+	}
+	c := &Client{
+		hub:     h,
+		userID:  userID,
+		send:    make(chan []byte, sendBufferSize),
+		done:    make(chan struct{}),
+		revoked: make(chan struct{}),
+	}
+	clients[c] = struct{}{}
+	return c, nil
+}
+
+// PublishToUser queues the event on every connection of the user.
+func (h *Hub) PublishToUser(userID int64, event Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		slog.Error("failed to encode realtime event", slog.String("type", event.Type), slog.Any("error", err))
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for c := range h.clients[userID] {
+		select {
+		case c.send <- data:
+		default:
+			// Slow clients are dropped rather than holding up publishers
+			c.stop()
+		}
+	}
+}
+
+// DisconnectUser closes every connection of the user, such as once the user is signed out
+// everywhere or banned, so they stop receiving events their tokens no longer allow. Only
+// connections held by this process are closed.
+func (h *Hub) DisconnectUser(userID int64) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for c := range h.clients[userID] {
+		c.revoke()
+	}
+}
+
+// Close disconnects every client and rejects new ones. It is called on shutdown, as the
+// server does not track hijacked connections.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, clients := range h.clients {
+		for c := range clients {
+			c.stop()
+		}
+	}
+}
+
+func (h *Hub) unregister(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	clients := h.clients[c.userID]
+	delete(clients, c)
+	if len(clients) == 0 {
+		delete(h.clients, c.userID)
+	}
+}
+
+// Client is a connection of a user registered with a Hub.
+type Client struct {
+	hub        *Hub
+	userID     int64
+	send       chan []byte
+	done       chan struct{}
+	stopOnce   sync.Once
+	revoked    chan struct{}
+	revokeOnce sync.Once
+}
+
+// Serve writes the events published to the user to conn until the client disconnects,
+// stops answering pings, falls behind, the hub closes or disconnects the user, or
+// expiresAt passes, so a connection does not outlive the token that opened it. A zero expiresAt never expires.
+// It blocks and closes conn before returning.
+func (c *Client) Serve(conn *websocket.Conn, expiresAt time.Time) {
+	defer c.Close()
+
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		c.read(conn)
+	}()
+
+	if closeCode, reason := c.write(conn, expiresAt); closeCode != 0 {
+		_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
+		_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(closeCode, reason))
+	}
+	_ = conn.Close()
+	<-readDone
+}
+
+// Close unregisters the client and stops Serve.
+func (c *Client) Close() {
+	c.stop()
+	c.hub.unregister(c)
+}
+
+func (c *Client) stop() {
+	c.stopOnce.Do(func() { close(c.done) })
+}
+
+func (c *Client) revoke() {
+	c.revokeOnce.Do(func() { close(c.revoked) })
+}
+
+// read discards client messages, keeping the read deadline moving on pongs, and stops the
+// client once the connection fails or is closed by the other side.
+func (c *Client) read(conn *websocket.Conn) {
+	defer c.stop()
+
+	conn.SetReadLimit(maxMessageSize)
+	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// write sends queued events and pings until the client stops, returning the close code
+// and reason to send, or 0 when the connection already failed.
+func (c *Client) write(conn *websocket.Conn, expiresAt time.Time) (int, string) {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	var expired <-chan time.Time
+	if !expiresAt.IsZero() {
+		timer := time.NewTimer(time.Until(expiresAt))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	for {
+		select {
+		case data := <-c.send:
+			_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return 0, ""
+			}
+		case <-ticker.C:
+			_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return 0, ""
+			}
+		case <-expired:
+			return websocket.ClosePolicyViolation, "token expired"
+		case <-c.revoked:
+			return websocket.ClosePolicyViolation, "signed out"
+		case <-c.done:
+			return websocket.CloseGoingAway, ""
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/websocket"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+)
+
+// connect serves a connection of the user and returns the client side of it.
+func connect(t *testing.T, hub *Hub, userID int64, expiresAt time.Time) *websocket.Conn {
+	t.Helper()
+	client, err := hub.Register(userID)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	var upgrader websocket.Upgrader
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			client.Close()
+			return
+		}
+		client.Serve(conn, expiresAt)
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func readEvent(t *testing.T, conn *websocket.Conn) Event {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var event Event
+	if err := conn.ReadJSON(&event); err != nil {
+		t.Fatalf("ReadJSON: %v", err)
+	}
+	return event
+}
+
+func closeCode(t *testing.T, conn *websocket.Conn) int {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) {
+				t.Fatalf("expected a close frame, got %v", err)
+			}
+			return closeErr.Code
+		}
+	}
+}
+
+func TestHub_PublishToUser(t *testing.T) {
+	hub := NewHub(0)
+	ann := connect(t, hub, 1, time.Time{})
+	bob := connect(t, hub, 2, time.Time{})
+
+	hub.PublishToUser(1, Event{Type: "file.uploaded", Data: map[string]int{"id": 7}})
+	hub.PublishToUser(2, Event{Type: "broadcast"})
+
+	event := readEvent(t, ann)
+	if event.Type != "file.uploaded" {
+		t.Errorf("expected file.uploaded, got %q", event.Type)
+	}
+	if data, _ := json.Marshal(event.Data); string(data) != `{"id":7}` {
+		t.Errorf("unexpected data %s", data)
+	}
+	if event := readEvent(t, bob); event.Type != "broadcast" {
+		t.Errorf("expected only the broadcast for user 2, got %q", event.Type)
+	}
+}
+
+func TestHub_MaxConnectionsPerUser(t *testing.T) {
+	hub := NewHub(1)
+	client, err := hub.Register(1)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if _, err := hub.Register(1); !errors.Is(err, ErrTooManyConnections) {
+		t.Fatalf("expected ErrTooManyConnections, got %v", err)
+	}
+	if _, err := hub.Register(2); err != nil {
+		t.Fatalf("expected other users to connect, got %v", err)
+	}
+
+	client.Close()
+	if _, err := hub.Register(1); err != nil {
+		t.Fatalf("expected a closed connection to free its slot, got %v", err)
+	}
+}
+
+func TestHub_ClosesExpiredConnections(t *testing.T) {
+	hub := NewHub(0)
+	conn := connect(t, hub, 1, time.Now().Add(50*time.Millisecond))
+
+	if code := closeCode(t, conn); code != websocket.ClosePolicyViolation {
+		t.Errorf("expected close code %d, got %d", websocket.ClosePolicyViolation, code)
+	}
+}
+
+func TestHub_Close(t *testing.T) {
+	hub := NewHub(0)
+	conn := connect(t, hub, 1, time.Time{})
+
+	hub.Close()
+	if code := closeCode(t, conn); code != websocket.CloseGoingAway {
+		t.Errorf("expected close code %d, got %d", websocket.CloseGoingAway, code)
+	}
+	if _, err := hub.Register(1); !errors.Is(err, ErrHubClosed) {
+		t.Errorf("expected ErrHubClosed, got %v", err)
+	}
+}
+
+func TestHub_DisconnectUser(t *testing.T) {
+	hub := NewHub(0)
+	ann := connect(t, hub, 1, time.Time{})
+	bob := connect(t, hub, 2, time.Time{})
+
+	hub.DisconnectUser(1)
+	if code := closeCode(t, ann); code != websocket.ClosePolicyViolation {
+		t.Errorf("expected close code %d, got %d", websocket.ClosePolicyViolation, code)
+	}
+	hub.PublishToUser(2, Event{Type: "broadcast"})
+	if event := readEvent(t, bob); event.Type != "broadcast" {
+		t.Errorf("expected other users to stay connected, got %q", event.Type)
+	}
+}
+
+func TestTicketStore(t *testing.T) {
+	c := cache.NewMemoryCache()
+	t.Cleanup(func() { _ = c.Close() })
+	store := NewTicketStore(c, time.Minute)
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	id, err := store.Issue(ctx, Ticket{UserID: 1, ExpiresAt: expiresAt})
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	ticket, err := store.Redeem(ctx, id)
+	if err != nil || ticket == nil {
+		t.Fatalf("Redeem = %v, %v; want a ticket", ticket, err)
+	}
+	if ticket.UserID != 1 || !ticket.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("unexpected ticket %+v", ticket)
+	}
+
+	if ticket, err := store.Redeem(ctx, id); err != nil || ticket != nil {
+		t.Errorf("expected a ticket to be redeemed once, got %v, %v", ticket, err)
+	}
+	if ticket, err := store.Redeem(ctx, "unknown"); err != nil || ticket != nil {
+		t.Errorf("expected no ticket for an unknown ID, got %v, %v", ticket, err)
+	}
+}
@@ -0,0 +1,23 @@
+// Package realtime pushes events to signed-in users over WebSocket connections. A Hub
+// holds the connections of each user and services publish to it through Publisher,
+// without knowing whether the user is connected; events for users who are not are
+// dropped. The hub lives in the process, so with several replicas an event only reaches
+// the connections held by the replica that publishes it.
+package realtime
+
+// Event is a message pushed to clients, encoded as JSON.
+type Event struct {
+	Type string `json:"type"`
+	Data any    `json:"data,omitempty"`
+}
+
+// Publisher pushes events to the connections of a user. Publishing never blocks on
+// clients and never fails the caller.
+type Publisher interface {
+	PublishToUser(userID int64, event Event)
+}
+
+// NopPublisher drops every event, for code that runs without a hub such as tests.
+type NopPublisher struct{}
+
+func (NopPublisher) PublishToUser(int64, Event) {}
@@ -0,0 +1,74 @@
+package realtime
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+)
+
+const ticketPrefix = "ws_ticket:"
+
+// Ticket authenticates a WebSocket handshake in place of a bearer token, which browsers
+// cannot send on one. It carries the expiry of the token it was issued for.
+type Ticket struct {
+	UserID    int64     `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// TicketStore keeps short-lived, single-use tickets in the cache.
+type TicketStore struct {
+	cache cache.Cache
+	ttl   time.Duration
+}
+
+// NewTicketStore creates a store whose tickets must be redeemed within ttl.
+func NewTicketStore(c cache.Cache, ttl time.Duration) *TicketStore {
+	return &TicketStore{cache: c, ttl: ttl}
+}
+
+// TTL returns how long a ticket can be redeemed.
+func (s *TicketStore) TTL() time.Duration {
+	return s.ttl
+}
+
+// Issue stores a ticket and returns its opaque ID.
+func (s *TicketStore) Issue(ctx context.Context, ticket Ticket) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(b)
+
+	data, err := json.Marshal(ticket)
+	if err != nil {
+		return "", err
+	}
+	if err := s.cache.Set(ctx, ticketPrefix+id, data, s.ttl); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Redeem loads and deletes a ticket so it can be used only once. It returns nil when the
+// ticket does not exist or has expired.
+func (s *TicketStore) Redeem(ctx context.Context, id string) (*Ticket, error) {
+	key := ticketPrefix + id
+	data, err := s.cache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, nil
+	}
+	_ = s.cache.Delete(ctx, key)
+
+	var ticket Ticket
+	if err := json.Unmarshal(data, &ticket); err != nil {
+		return nil, nil
+	}
+	return &ticket, nil
+}
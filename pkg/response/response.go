@@ -1,6 +1,9 @@
 package response
 
 import (
+	"encoding/json"
+	"strings"
+
 	"github.com/gofiber/fiber/v3"
 
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/pagination"
@@ -19,6 +22,22 @@ type ErrorInfo struct {
 	Details any    `json:"details,omitempty"`
 }
 
+// MIMEApplicationProblemJSON is the media type of problem details.
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. Code, Details and RequestID are extension
+// members carrying what the error of the default format carries.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	Details   any    `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
 type Meta struct {
 	Page      int   `json:"page"`
 	PerPage   int   `json:"per_page"`
@@ -36,14 +55,25 @@ func NewMeta(page, perPage int, total int64) Meta {
 	}
 }
 
+// Success responds with data, trimmed to the fields named in the fields query param.
 func Success(c fiber.Ctx, data any) error {
+	data, err := SelectFields(data, c.Query("fields"))
+	if err != nil {
+		return err
+	}
 	return c.Status(fiber.StatusOK).JSON(Response{
 		Success: true,
 		Data:    data,
 	})
 }
 
+// SuccessWithMeta responds with a page of data, each item trimmed to the fields named in
+// the fields query param.
 func SuccessWithMeta(c fiber.Ctx, data any, meta Meta) error {
+	data, err := SelectFields(data, c.Query("fields"))
+	if err != nil {
+		return err
+	}
 	return c.Status(fiber.StatusOK).JSON(Response{
 		Success: true,
 		Data:    data,
@@ -51,6 +81,60 @@ func SuccessWithMeta(c fiber.Ctx, data any, meta Meta) error {
 	})
 }
 
+// SelectFields trims data to the top-level JSON fields in fields, a comma-separated list
+// such as "id,email,name". When data is a list, each item is trimmed. Names that data does
+// not have are ignored, and data that is not an object or a list of objects, or an empty
+// fields, leaves data as it is.
+func SelectFields(data any, fields string) (any, error) {
+	var names []string
+	for _, name := range strings.Split(fields, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 || data == nil {
+		return data, nil
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	if string(raw) == "null" {
+		return data, nil
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &object); err == nil {
+		return pickFields(object, names), nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return data, nil
+	}
+	trimmed := make([]json.RawMessage, len(items))
+	for i, item := range items {
+		trimmed[i] = item
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(item, &object); err != nil {
+			continue
+		}
+		if trimmed[i], err = json.Marshal(pickFields(object, names)); err != nil {
+			return nil, err
+		}
+	}
+	return trimmed, nil
+}
+
+func pickFields(object map[string]json.RawMessage, names []string) map[string]json.RawMessage {
+	picked := make(map[string]json.RawMessage, len(names))
+	for _, name := range names {
+		if value, ok := object[name]; ok {
+			picked[name] = value
+		}
+	}
+	return picked
+}
+
 func Created(c fiber.Ctx, data any) error {
 	return c.Status(fiber.StatusCreated).JSON(Response{
 		Success: true,
@@ -58,6 +142,13 @@ func Created(c fiber.Ctx, data any) error {
 	})
 }
 
+func Accepted(c fiber.Ctx, data any) error {
+	return c.Status(fiber.StatusAccepted).JSON(Response{
+		Success: true,
+		Data:    data,
+	})
+}
+
 func NoContent(c fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -82,3 +173,8 @@ func ErrorWithDetails(c fiber.Ctx, statusCode int, code, message string, details
 		},
 	})
 }
+
+// ProblemDetails responds with problem as application/problem+json.
+func ProblemDetails(c fiber.Ctx, problem Problem) error {
+	return c.Status(problem.Status).JSON(problem, MIMEApplicationProblemJSON)
+}
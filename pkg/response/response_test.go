@@ -0,0 +1,44 @@
+package response
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type item struct {
+	ID    int64  `json:"id"`
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+func TestSelectFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   any
+		fields string
+		want   string
+	}{
+		{"no fields", item{1, "a@example.com", "A"}, "", `{"id":1,"email":"a@example.com","name":"A"}`},
+		{"object", item{1, "a@example.com", "A"}, "id, name", `{"id":1,"name":"A"}`},
+		{"unknown field ignored", item{1, "a@example.com", "A"}, "id,password", `{"id":1}`},
+		{"list", []item{{1, "a@example.com", "A"}, {2, "b@example.com", "B"}}, "email", `[{"email":"a@example.com"},{"email":"b@example.com"}]`},
+		{"empty list", []item{}, "id", `[]`},
+		{"nil list", []item(nil), "id", `null`},
+		{"scalar", "ok", "id", `"ok"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SelectFields(tt.data, tt.fields)
+			if err != nil {
+				t.Fatalf("SelectFields: %v", err)
+			}
+			data, err := json.Marshal(got)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("SelectFields(%q) = %s, want %s", tt.fields, data, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+// Package saml implements a SAML 2.0 service provider used for enterprise single sign-on.
+package saml
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	crewsaml "github.com/crewjam/saml"
+	"github.com/crewjam/saml/samlsp"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/oauth"
+)
+
+const (
+	metadataPath = "/api/v1/auth/saml/metadata"
+	acsPath      = "/api/v1/auth/saml/acs"
+
+	metadataFetchTimeout = 10 * time.Second
+)
+
+// UserInfo is the identity extracted from a validated SAML assertion.
+type UserInfo struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+// ServiceProvider wraps a SAML service provider configured against a single IdP.
+type ServiceProvider struct {
+	sp             *crewsaml.ServiceProvider
+	emailAttribute string
+	nameAttribute  string
+	frontendURL    string
+}
+
+// NewServiceProvider loads the SP key pair and fetches the IdP metadata.
+func NewServiceProvider(ctx context.Context, cfg config.SAMLConfig, frontendURL string) (*ServiceProvider, error) {
+	keyPair, err := tls.LoadX509KeyPair(cfg.SPCertFile, cfg.SPKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load SAML key pair: %w", err)
+	}
+	signer, ok := keyPair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("SAML private key does not support signing")
+	}
+	cert, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SAML certificate: %w", err)
+	}
+
+	rootURL, err := url.Parse(strings.TrimRight(cfg.RootURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SAML_ROOT_URL: %w", err)
+	}
+	idpMetadataURL, err := url.Parse(cfg.IDPMetadataURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SAML_IDP_METADATA_URL: %w", err)
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, metadataFetchTimeout)
+	defer cancel()
+	idpMetadata, err := samlsp.FetchMetadata(fetchCtx, http.DefaultClient, *idpMetadataURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IdP metadata: %w", err)
+	}
+
+	metadataURL := rootURL.ResolveReference(&url.URL{Path: metadataPath})
+	acsURL := rootURL.ResolveReference(&url.URL{Path: acsPath})
+
+	entityID := cfg.EntityID
+	if entityID == "" {
+		entityID = metadataURL.String()
+	}
+
+	return &ServiceProvider{
+		sp: &crewsaml.ServiceProvider{
+			EntityID:          entityID,
+			Key:               signer,
+			Certificate:       cert,
+			MetadataURL:       *metadataURL,
+			AcsURL:            *acsURL,
+			IDPMetadata:       idpMetadata,
+			AuthnNameIDFormat: crewsaml.PersistentNameIDFormat,
+			AllowIDPInitiated: cfg.AllowIDPInitiated,
+		},
+		emailAttribute: cfg.EmailAttribute,
+		nameAttribute:  cfg.NameAttribute,
+		frontendURL:    frontendURL,
+	}, nil
+}
+
+// Name returns the provider identifier stored in users.auth_provider.
+func (p *ServiceProvider) Name() string {
+	return "saml"
+}
+
+// Metadata returns the SP metadata document to register with the IdP.
+func (p *ServiceProvider) Metadata() ([]byte, error) {
+	data, err := xml.MarshalIndent(p.sp.Metadata(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode SAML metadata: %w", err)
+	}
+	return data, nil
+}
+
+// AuthRequest builds an SP-initiated AuthnRequest and returns the IdP redirect URL
+// together with the request ID that the response must be matched against.
+func (p *ServiceProvider) AuthRequest(relayState string) (redirectURL, requestID string, err error) {
+	req, err := p.sp.MakeAuthenticationRequest(
+		p.sp.GetSSOBindingLocation(crewsaml.HTTPRedirectBinding),
+		crewsaml.HTTPRedirectBinding,
+		crewsaml.HTTPPostBinding,
+	)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create SAML request: %w", err)
+	}
+	redirect, err := req.Redirect(relayState, p.sp)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode SAML request: %w", err)
+	}
+	return redirect.String(), req.ID, nil
+}
+
+// ParseResponse validates a base64-encoded SAMLResponse posted to the ACS and maps
+// its assertion to a UserInfo.
+func (p *ServiceProvider) ParseResponse(samlResponse string, possibleRequestIDs []string) (*UserInfo, error) {
+	raw, err := base64.StdEncoding.DecodeString(samlResponse)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SAMLResponse encoding: %w", err)
+	}
+
+	assertion, err := p.sp.ParseXMLResponse(raw, possibleRequestIDs, p.sp.AcsURL)
+	if err != nil {
+		var invalid *crewsaml.InvalidResponseError
+		if errors.As(err, &invalid) {
+			return nil, fmt.Errorf("invalid SAML response: %w", invalid.PrivateErr)
+		}
+		return nil, fmt.Errorf("invalid SAML response: %w", err)
+	}
+
+	return userInfoFromAssertion(assertion, p.emailAttribute, p.nameAttribute)
+}
+
+// ValidateFrontendURL checks that the configured frontend URL is parseable and uses http(s).
+func (p *ServiceProvider) ValidateFrontendURL() error {
+	return oauth.ValidateFrontendURL(p.frontendURL)
+}
+
+// BuildCallbackURL constructs the redirect URL with tokens in the URL fragment.
+func (p *ServiceProvider) BuildCallbackURL(accessToken, refreshToken string) string {
+	return oauth.BuildCallbackURL(p.frontendURL, accessToken, refreshToken)
+}
+
+// userInfoFromAssertion maps the NameID and configured attributes of an assertion.
+// The NameID doubles as the email when no email attribute is present and it looks like one.
+func userInfoFromAssertion(assertion *crewsaml.Assertion, emailAttribute, nameAttribute string) (*UserInfo, error) {
+	if assertion.Subject == nil || assertion.Subject.NameID == nil || assertion.Subject.NameID.Value == "" {
+		return nil, errors.New("SAML assertion has no NameID")
+	}
+	nameID := assertion.Subject.NameID.Value
+
+	email := attributeValue(assertion, emailAttribute)
+	if email == "" && strings.Contains(nameID, "@") {
+		email = nameID
+	}
+	if email == "" {
+		return nil, errors.New("SAML assertion has no email")
+	}
+
+	name := attributeValue(assertion, nameAttribute)
+	if name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+
+	return &UserInfo{ID: nameID, Email: email, Name: name}, nil
+}
+
+// attributeValue returns the first value of the attribute matching name or friendly name.
+func attributeValue(assertion *crewsaml.Assertion, name string) string {
+	if name == "" {
+		return ""
+	}
+	for _, stmt := range assertion.AttributeStatements {
+		for _, attr := range stmt.Attributes {
+			if attr.Name != name && attr.FriendlyName != name {
+				continue
+			}
+			for _, v := range attr.Values {
+				if v.Value != "" {
+					return v.Value
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package saml
+
+import (
+	"testing"
+
+	crewsaml "github.com/crewjam/saml"
+)
+
+func newTestAssertion(nameID string, attrs map[string]string) *crewsaml.Assertion {
+	stmt := crewsaml.AttributeStatement{}
+	for name, value := range attrs {
+		stmt.Attributes = append(stmt.Attributes, crewsaml.Attribute{
+			Name:   name,
+			Values: []crewsaml.AttributeValue{{Value: value}},
+		})
+	}
+	return &crewsaml.Assertion{
+		Subject:             &crewsaml.Subject{NameID: &crewsaml.NameID{Value: nameID}},
+		AttributeStatements: []crewsaml.AttributeStatement{stmt},
+	}
+}
+
+func TestUserInfoFromAssertion(t *testing.T) {
+	t.Run("maps configured attributes", func(t *testing.T) {
+		a := newTestAssertion("abc123", map[string]string{"mail": "jane@example.com", "displayName": "Jane"})
+
+		info, err := userInfoFromAssertion(a, "mail", "displayName")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if info.ID != "abc123" || info.Email != "jane@example.com" || info.Name != "Jane" {
+			t.Errorf("unexpected user info: %+v", info)
+		}
+	})
+
+	t.Run("falls back to email-shaped NameID", func(t *testing.T) {
+		a := newTestAssertion("john@example.com", nil)
+
+		info, err := userInfoFromAssertion(a, "email", "name")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if info.Email != "john@example.com" {
+			t.Errorf("expected NameID as email, got %q", info.Email)
+		}
+		if info.Name != "john" {
+			t.Errorf("expected name derived from email, got %q", info.Name)
+		}
+	})
+
+	t.Run("missing email", func(t *testing.T) {
+		a := newTestAssertion("opaque-id", nil)
+
+		if _, err := userInfoFromAssertion(a, "email", "name"); err == nil {
+			t.Fatal("expected error for assertion without email")
+		}
+	})
+
+	t.Run("missing NameID", func(t *testing.T) {
+		a := &crewsaml.Assertion{}
+
+		if _, err := userInfoFromAssertion(a, "email", "name"); err == nil {
+			t.Fatal("expected error for assertion without NameID")
+		}
+	})
+}
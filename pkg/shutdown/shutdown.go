@@ -0,0 +1,68 @@
+// Package shutdown coordinates a graceful stop of the process. Once shutdown starts, the
+// drain callbacks mark the instance as going away, such as to fail readiness probes, and
+// after a delay giving load balancers time to notice, the registered steps run in order
+// within a shared timeout: stopping the server lets in-flight requests finish, then
+// background work, then the connections to dependencies.
+package shutdown
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type step struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Manager runs the shutdown steps of the process.
+type Manager struct {
+	delay   time.Duration
+	timeout time.Duration
+	onDrain []func()
+	steps   []step
+}
+
+// New creates a manager that waits delay after marking the instance as draining, then
+// gives the steps timeout to complete.
+func New(delay, timeout time.Duration) *Manager {
+	return &Manager{delay: delay, timeout: timeout}
+}
+
+// OnDrain registers fn to run as soon as shutdown starts, before the delay, such as to
+// fail readiness probes.
+func (m *Manager) OnDrain(fn func()) {
+	m.onDrain = append(m.onDrain, fn)
+}
+
+// Add registers a step, run after the ones added before it. Steps share the timeout; a step
+// still running when it expires sees its context cancelled, and later steps run anyway with
+// a cancelled context, so they should release what they hold without waiting.
+func (m *Manager) Add(name string, fn func(ctx context.Context) error) {
+	m.steps = append(m.steps, step{name: name, fn: fn})
+}
+
+// Shutdown runs the drain callbacks, waits for the delay and runs every step, logging the
+// ones that fail. It is called once.
+func (m *Manager) Shutdown() {
+	for _, fn := range m.onDrain {
+		fn()
+	}
+	if m.delay > 0 {
+		slog.Info("draining before shutdown", slog.Duration("delay", m.delay))
+		time.Sleep(m.delay)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	for _, s := range m.steps {
+		start := time.Now()
+		if err := s.fn(ctx); err != nil {
+			slog.Error("shutdown step failed", slog.String("step", s.name), slog.Any("error", err))
+			continue
+		}
+		slog.Debug("shutdown step done", slog.String("step", s.name), slog.Duration("duration", time.Since(start)))
+	}
+}
@@ -0,0 +1,53 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestManager_Shutdown(t *testing.T) {
+	m := New(0, time.Second)
+	var calls []string
+	m.Add("first", func(context.Context) error {
+		calls = append(calls, "first")
+		return errors.New("boom")
+	})
+	m.Add("second", func(context.Context) error {
+		calls = append(calls, "second")
+		return nil
+	})
+	m.OnDrain(func() { calls = append(calls, "drain") })
+
+	m.Shutdown()
+
+	want := []string{"drain", "first", "second"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("expected %v, got %v", want, calls)
+	}
+}
+
+func TestManager_ShutdownTimeout(t *testing.T) {
+	m := New(0, 50*time.Millisecond)
+	var lateErr error
+	m.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	m.Add("late", func(ctx context.Context) error {
+		lateErr = ctx.Err()
+		return nil
+	})
+
+	start := time.Now()
+	m.Shutdown()
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the timeout to stop the slow step, took %s", elapsed)
+	}
+	if !errors.Is(lateErr, context.DeadlineExceeded) {
+		t.Errorf("expected later steps to run with an expired context, got %v", lateErr)
+	}
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
+)
+
+type AzureStorage struct {
+	client     *azblob.Client
+	container  string
+	serviceURL string
+}
+
+// NewAzureStorage connects to Azure Blob Storage with a shared account key. The service
+// URL defaults to the account's public endpoint; set cfg.AzureEndpoint to use Azurite or
+// a sovereign cloud.
+func NewAzureStorage(cfg config.StorageConfig) (*AzureStorage, error) {
+	serviceURL := cfg.AzureEndpoint
+	if serviceURL == "" {
+		serviceURL = fmt.Sprintf("https://%s.blob.core.windows.net", cfg.AzureAccountName)
+	}
+	serviceURL = strings.TrimSuffix(serviceURL, "/")
+
+	cred, err := azblob.NewSharedKeyCredential(cfg.AzureAccountName, cfg.AzureAccountKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure credential: %w", err)
+	}
+	client, err := azblob.NewClientWithSharedKeyCredential(serviceURL+"/", cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure client: %w", err)
+	}
+
+	// Ensure container exists
+	ctx := context.Background()
+	if _, err := client.CreateContainer(ctx, cfg.AzureContainer, nil); err != nil &&
+		!bloberror.HasCode(err, bloberror.ContainerAlreadyExists) {
+		return nil, fmt.Errorf("failed to create container: %w", err)
+	}
+
+	return &AzureStorage{
+		client:     client,
+		container:  cfg.AzureContainer,
+		serviceURL: serviceURL,
+	}, nil
+}
+
+func (s *AzureStorage) Put(ctx context.Context, path string, reader io.Reader, size int64, contentType string) error {
+	opts := &azblob.UploadStreamOptions{
+		HTTPHeaders: &blob.HTTPHeaders{BlobContentType: &contentType},
+	}
+
+	if _, err := s.client.UploadStream(ctx, s.container, path, reader, opts); err != nil {
+		return fmt.Errorf("failed to upload to Azure: %w", err)
+	}
+
+	return nil
+}
+
+func (s *AzureStorage) Get(ctx context.Context, path string) (io.ReadCloser, error) {
+	resp, err := s.client.DownloadStream(ctx, s.container, path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get from Azure: %w", err)
+	}
+
+	return resp.Body, nil
+}
+
+func (s *AzureStorage) Delete(ctx context.Context, path string) error {
+	_, err := s.client.DeleteBlob(ctx, s.container, path, nil)
+	if err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return fmt.Errorf("failed to delete from Azure: %w", err)
+	}
+
+	return nil
+}
+
+func (s *AzureStorage) List(ctx context.Context, fn func(Object) error) error {
+	pager := s.client.NewListBlobsFlatPager(s.container, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list Azure blobs: %w", err)
+		}
+		for _, item := range page.Segment.BlobItems {
+			obj := Object{Path: *item.Name}
+			if props := item.Properties; props != nil {
+				if props.ContentLength != nil {
+					obj.Size = *props.ContentLength
+				}
+				if props.LastModified != nil {
+					obj.ModTime = *props.LastModified
+				}
+			}
+			if err := fn(obj); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *AzureStorage) URL(path string) string {
+	return fmt.Sprintf("%s/%s/%s", s.serviceURL, s.container, path)
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCDNStorageURL(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	t.Run("links are signed to expire", func(t *testing.T) {
+		store, err := NewCDNStorage(newMemStorage(), "https://cdn.example.com/files/", "secret", time.Hour)
+		if err != nil {
+			t.Fatalf("NewCDNStorage: %v", err)
+		}
+		store.nowFunc = func() time.Time { return now }
+
+		got := store.URL("1/my photo.jpg")
+		want := "https://cdn.example.com/files/1/my%20photo.jpg?expires=1700003600&signature=" +
+			SignCDNPath([]byte("secret"), "/files/1/my%20photo.jpg", "1700003600")
+		if got != want {
+			t.Errorf("URL = %s, want %s", got, want)
+		}
+		if SignCDNPath([]byte("other"), "/files/1/my%20photo.jpg", "1700003600") == SignCDNPath([]byte("secret"), "/files/1/my%20photo.jpg", "1700003600") {
+			t.Error("expected the signature to depend on the secret")
+		}
+	})
+
+	t.Run("links are plain without a secret", func(t *testing.T) {
+		store, err := NewCDNStorage(newMemStorage(), "https://cdn.example.com", "", time.Hour)
+		if err != nil {
+			t.Fatalf("NewCDNStorage: %v", err)
+		}
+		if got := store.URL("sha256/abc"); got != "https://cdn.example.com/sha256/abc" {
+			t.Errorf("URL = %s", got)
+		}
+	})
+
+	t.Run("objects are stored in the inner storage", func(t *testing.T) {
+		inner := newMemStorage()
+		store, _ := NewCDNStorage(inner, "https://cdn.example.com", "secret", time.Hour)
+		if err := store.Put(context.Background(), "a.txt", strings.NewReader("hi"), 2, "text/plain"); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		if string(inner.objects["a.txt"]) != "hi" {
+			t.Error("expected the object in the inner storage")
+		}
+	})
+}
@@ -0,0 +1,390 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Encrypted objects start with a header holding the ID of the key-encryption key, the
+// object's own data key wrapped with it and a random nonce prefix. The content follows
+// in segments of encryptedSegmentSize bytes, each sealed with AES-256-GCM under a nonce
+// made of the prefix, the segment number and a flag marking the last segment, so objects
+// are streamed without buffering and truncated or reordered segments fail to decrypt.
+//
+//	magic | key ID length (1) | key ID | wrapped key length (2) | wrapped key | nonce prefix (7) | segments
+const (
+	encryptionMagic      = "FGBENC01"
+	encryptedSegmentSize = 64 << 10
+	dataKeySize          = 32
+	noncePrefixSize      = 7
+	gcmTagSize           = 16
+)
+
+var errCorruptObject = errors.New("encrypted object is corrupt or truncated")
+
+// KeyWrapper seals and opens the per-object data keys with key-encryption keys. Keys from
+// the configuration are handled by StaticKeyWrapper; a key management service can be used
+// by implementing this interface.
+type KeyWrapper interface {
+	// KeyID returns the ID of the key new data keys are wrapped with.
+	KeyID() string
+	Wrap(ctx context.Context, dataKey []byte) ([]byte, error)
+	Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// StaticKeyWrapper wraps data keys with AES-GCM keys held in memory. Retired keys stay
+// listed so the objects encrypted with them can still be read.
+type StaticKeyWrapper struct {
+	keys    map[string]cipher.AEAD
+	current string
+}
+
+// NewStaticKeyWrapper wraps new data keys with keys[current] and unwraps with any of keys.
+// Keys must be 16, 24 or 32 bytes long.
+func NewStaticKeyWrapper(keys map[string][]byte, current string) (*StaticKeyWrapper, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("encryption key %q is not configured", current)
+	}
+	w := &StaticKeyWrapper{keys: make(map[string]cipher.AEAD, len(keys)), current: current}
+	for id, key := range keys {
+		aead, err := newGCM(key)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", id, err)
+		}
+		w.keys[id] = aead
+	}
+	return w, nil
+}
+
+func (w *StaticKeyWrapper) KeyID() string {
+	return w.current
+}
+
+func (w *StaticKeyWrapper) Wrap(_ context.Context, dataKey []byte) ([]byte, error) {
+	aead := w.keys[w.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, dataKey, []byte(w.current)), nil
+}
+
+func (w *StaticKeyWrapper) Unwrap(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := w.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("encryption key %q is not configured", keyID)
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, errCorruptObject
+	}
+	nonce, sealed := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	dataKey, err := aead.Open(nil, nonce, sealed, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key with %q: %w", keyID, err)
+	}
+	return dataKey, nil
+}
+
+// EncryptedStorage encrypts objects before handing them to another Storage and decrypts
+// them when they are read. Objects stored before encryption was enabled are read as is.
+type EncryptedStorage struct {
+	inner Storage
+	keys  KeyWrapper
+}
+
+func NewEncryptedStorage(inner Storage, keys KeyWrapper) *EncryptedStorage {
+	return &EncryptedStorage{inner: inner, keys: keys}
+}
+
+// KeyID returns the ID of the key new objects are encrypted with.
+func (s *EncryptedStorage) KeyID() string {
+	return s.keys.KeyID()
+}
+
+func (s *EncryptedStorage) Put(ctx context.Context, path string, reader io.Reader, size int64, contentType string) error {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return fmt.Errorf("failed to generate data key: %w", err)
+	}
+	wrapped, err := s.keys.Wrap(ctx, dataKey)
+	if err != nil {
+		return fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return err
+	}
+
+	keyID := s.keys.KeyID()
+	if len(keyID) > 255 || len(wrapped) > 65535 {
+		return fmt.Errorf("encryption key ID or wrapped key too long")
+	}
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	var header bytes.Buffer
+	header.WriteString(encryptionMagic)
+	header.WriteByte(byte(len(keyID)))
+	header.WriteString(keyID)
+	_ = binary.Write(&header, binary.BigEndian, uint16(len(wrapped)))
+	header.Write(wrapped)
+	header.Write(prefix)
+
+	enc := &encryptingReader{
+		src:    reader,
+		aead:   aead,
+		prefix: prefix,
+		plain:  make([]byte, encryptedSegmentSize+1),
+		sealed: make([]byte, 0, encryptedSegmentSize+gcmTagSize),
+		out:    header.Bytes(),
+	}
+	return s.inner.Put(ctx, path, enc, encryptedSize(int64(header.Len()), size), contentType)
+}
+
+func (s *EncryptedStorage) Get(ctx context.Context, path string) (io.ReadCloser, error) {
+	rc, err := s.inner.Get(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	src := bufio.NewReader(rc)
+
+	magic, err := src.Peek(len(encryptionMagic))
+	if err != nil || string(magic) != encryptionMagic {
+		if err != nil && !errors.Is(err, io.EOF) {
+			_ = rc.Close()
+			return nil, fmt.Errorf("failed to read object: %w", err)
+		}
+		// Stored before encryption was enabled
+		return readCloser{Reader: src, Closer: rc}, nil
+	}
+
+	dec, err := s.openHeader(ctx, src)
+	if err != nil {
+		_ = rc.Close()
+		return nil, err
+	}
+	dec.closer = rc
+	return dec, nil
+}
+
+func (s *EncryptedStorage) Delete(ctx context.Context, path string) error {
+	return s.inner.Delete(ctx, path)
+}
+
+// List lists the inner storage. Sizes are those of the encrypted objects.
+func (s *EncryptedStorage) List(ctx context.Context, fn func(Object) error) error {
+	return List(ctx, s.inner, fn)
+}
+
+// URL returns the inner storage's URL, which serves the encrypted object.
+func (s *EncryptedStorage) URL(path string) string {
+	return s.inner.URL(path)
+}
+
+// openHeader reads the header of an encrypted object and unwraps its data key.
+func (s *EncryptedStorage) openHeader(ctx context.Context, src *bufio.Reader) (*decryptingReader, error) {
+	if _, err := src.Discard(len(encryptionMagic)); err != nil {
+		return nil, errCorruptObject
+	}
+	idLen, err := src.ReadByte()
+	if err != nil {
+		return nil, errCorruptObject
+	}
+	keyID := make([]byte, idLen)
+	if _, err := io.ReadFull(src, keyID); err != nil {
+		return nil, errCorruptObject
+	}
+	var wrappedLen uint16
+	if err := binary.Read(src, binary.BigEndian, &wrappedLen); err != nil {
+		return nil, errCorruptObject
+	}
+	wrapped := make([]byte, wrappedLen)
+	if _, err := io.ReadFull(src, wrapped); err != nil {
+		return nil, errCorruptObject
+	}
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := io.ReadFull(src, prefix); err != nil {
+		return nil, errCorruptObject
+	}
+
+	dataKey, err := s.keys.Unwrap(ctx, string(keyID), wrapped)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptingReader{
+		src:    src,
+		aead:   aead,
+		prefix: prefix,
+		sealed: make([]byte, encryptedSegmentSize+gcmTagSize+1),
+		plain:  make([]byte, 0, encryptedSegmentSize),
+	}, nil
+}
+
+// KeyID returns the ID of the key store encrypts new objects with, or "" if it does not
+// encrypt them.
+func KeyID(store Storage) string {
+	if e, ok := store.(interface{ KeyID() string }); ok {
+		return e.KeyID()
+	}
+	return ""
+}
+
+// encryptingReader seals the content read from src segment by segment, after returning
+// the header already placed in out.
+type encryptingReader struct {
+	src     io.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	plain   []byte // one segment plus the byte read ahead
+	carry   int    // bytes of the next segment already in plain
+	sealed  []byte
+	out     []byte
+	done    bool
+}
+
+func (r *encryptingReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.seal(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// seal encrypts the next segment. One byte past the segment is read ahead to tell whether
+// it is the last one.
+func (r *encryptingReader) seal() error {
+	n, err := io.ReadFull(r.src, r.plain[r.carry:])
+	n += r.carry
+	last := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+	if err != nil && !last {
+		return err
+	}
+
+	size := n
+	if !last {
+		size = encryptedSegmentSize
+	}
+	r.out = r.aead.Seal(r.sealed[:0], segmentNonce(r.prefix, r.counter, last), r.plain[:size], nil)
+	r.counter++
+	if last {
+		r.done = true
+	} else {
+		r.plain[0] = r.plain[encryptedSegmentSize]
+		r.carry = 1
+	}
+	return nil
+}
+
+// decryptingReader opens the segments read from src.
+type decryptingReader struct {
+	src     io.Reader
+	closer  io.Closer
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	sealed  []byte // one sealed segment plus the byte read ahead
+	carry   int
+	plain   []byte
+	out     []byte
+	done    bool
+}
+
+func (r *decryptingReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+func (r *decryptingReader) open() error {
+	n, err := io.ReadFull(r.src, r.sealed[r.carry:])
+	n += r.carry
+	last := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+	if err != nil && !last {
+		return err
+	}
+
+	size := n
+	if !last {
+		size = encryptedSegmentSize + gcmTagSize
+	}
+	plain, err := r.aead.Open(r.plain[:0], segmentNonce(r.prefix, r.counter, last), r.sealed[:size], nil)
+	if err != nil {
+		return errCorruptObject
+	}
+	r.out = plain
+	r.counter++
+	if last {
+		r.done = true
+	} else {
+		r.sealed[0] = r.sealed[encryptedSegmentSize+gcmTagSize]
+		r.carry = 1
+	}
+	return nil
+}
+
+func (r *decryptingReader) Close() error {
+	return r.closer.Close()
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// segmentNonce builds the nonce of a segment: the object's prefix, the segment number and
+// whether it is the last segment.
+func segmentNonce(prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, 0, noncePrefixSize+5)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, counter)
+	if last {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+// encryptedSize returns the stored size of content of the given size, or -1 if unknown.
+func encryptedSize(headerSize, size int64) int64 {
+	if size < 0 {
+		return -1
+	}
+	segments := max((size+encryptedSegmentSize-1)/encryptedSegmentSize, 1)
+	return headerSize + size + segments*gcmTagSize
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+// memStorage keeps objects in memory and records the size each was stored with.
+type memStorage struct {
+	objects map[string][]byte
+	sizes   map[string]int64
+}
+
+func newMemStorage() *memStorage {
+	return &memStorage{objects: make(map[string][]byte), sizes: make(map[string]int64)}
+}
+
+func (m *memStorage) Put(_ context.Context, path string, reader io.Reader, size int64, _ string) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	m.objects[path] = data
+	m.sizes[path] = size
+	return nil
+}
+
+func (m *memStorage) Get(_ context.Context, path string) (io.ReadCloser, error) {
+	data, ok := m.objects[path]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *memStorage) Delete(_ context.Context, path string) error {
+	delete(m.objects, path)
+	return nil
+}
+
+func (m *memStorage) URL(path string) string {
+	return "mem://" + path
+}
+
+func testKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newTestEncryptedStorage(t *testing.T, keys map[string][]byte, current string) (*EncryptedStorage, *memStorage) {
+	t.Helper()
+	wrapper, err := NewStaticKeyWrapper(keys, current)
+	if err != nil {
+		t.Fatalf("new key wrapper: %v", err)
+	}
+	inner := newMemStorage()
+	return NewEncryptedStorage(inner, wrapper), inner
+}
+
+func readObject(t *testing.T, s Storage, path string) ([]byte, error) {
+	t.Helper()
+	rc, err := s.Get(context.Background(), path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+	return io.ReadAll(rc)
+}
+
+func TestEncryptedStorageRoundTrip(t *testing.T) {
+	s, inner := newTestEncryptedStorage(t, map[string][]byte{"k1": testKey(t)}, "k1")
+
+	for _, size := range []int{0, 1, encryptedSegmentSize - 1, encryptedSegmentSize, encryptedSegmentSize + 1, 3*encryptedSegmentSize + 5} {
+		data := make([]byte, size)
+		_, _ = rand.Read(data)
+
+		if err := s.Put(context.Background(), "obj", bytes.NewReader(data), int64(size), "application/octet-stream"); err != nil {
+			t.Fatalf("size %d: put: %v", size, err)
+		}
+		stored := inner.objects["obj"]
+		if int64(len(stored)) != inner.sizes["obj"] {
+			t.Errorf("size %d: stored %d bytes but announced %d", size, len(stored), inner.sizes["obj"])
+		}
+		if size > 16 && bytes.Contains(stored, data) {
+			t.Errorf("size %d: content stored in plain", size)
+		}
+
+		got, err := readObject(t, s, "obj")
+		if err != nil {
+			t.Fatalf("size %d: get: %v", size, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("size %d: content differs after decryption", size)
+		}
+	}
+}
+
+func TestEncryptedStorageIntegrity(t *testing.T) {
+	s, inner := newTestEncryptedStorage(t, map[string][]byte{"k1": testKey(t)}, "k1")
+	data := bytes.Repeat([]byte("secret "), encryptedSegmentSize/3)
+	if err := s.Put(context.Background(), "obj", bytes.NewReader(data), int64(len(data)), "text/plain"); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	stored := inner.objects["obj"]
+
+	t.Run("tampered content", func(t *testing.T) {
+		tampered := bytes.Clone(stored)
+		tampered[len(tampered)-20] ^= 1
+		inner.objects["tampered"] = tampered
+		if _, err := readObject(t, s, "tampered"); !errors.Is(err, errCorruptObject) {
+			t.Errorf("expected a corrupt object error, got %v", err)
+		}
+	})
+
+	t.Run("truncated at a segment boundary", func(t *testing.T) {
+		headerSize := len(stored) - len(data) - 3*gcmTagSize
+		inner.objects["truncated"] = stored[:headerSize+encryptedSegmentSize+gcmTagSize]
+		if _, err := readObject(t, s, "truncated"); !errors.Is(err, errCorruptObject) {
+			t.Errorf("expected a corrupt object error, got %v", err)
+		}
+	})
+
+	t.Run("objects stored in plain are read as is", func(t *testing.T) {
+		inner.objects["plain"] = []byte("stored before encryption")
+		got, err := readObject(t, s, "plain")
+		if err != nil || string(got) != "stored before encryption" {
+			t.Errorf("expected the plain content, got %q (%v)", got, err)
+		}
+	})
+}
+
+func TestEncryptedStorageKeyRotation(t *testing.T) {
+	oldKey, newKey := testKey(t), testKey(t)
+	before, inner := newTestEncryptedStorage(t, map[string][]byte{"old": oldKey}, "old")
+	if err := before.Put(context.Background(), "obj", bytes.NewReader([]byte("hello")), 5, "text/plain"); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	wrapper, err := NewStaticKeyWrapper(map[string][]byte{"old": oldKey, "new": newKey}, "new")
+	if err != nil {
+		t.Fatalf("new key wrapper: %v", err)
+	}
+	after := NewEncryptedStorage(inner, wrapper)
+	if KeyID(after) != "new" {
+		t.Errorf("expected new objects to use the new key, got %q", KeyID(after))
+	}
+	if got, err := readObject(t, after, "obj"); err != nil || string(got) != "hello" {
+		t.Errorf("expected objects under the old key to stay readable, got %q (%v)", got, err)
+	}
+
+	retired, err := NewStaticKeyWrapper(map[string][]byte{"new": newKey}, "new")
+	if err != nil {
+		t.Fatalf("new key wrapper: %v", err)
+	}
+	if _, err := readObject(t, NewEncryptedStorage(inner, retired), "obj"); err == nil {
+		t.Error("expected an error once the old key is removed")
+	}
+	if KeyID(inner) != "" {
+		t.Error("expected no key ID for unencrypted storage")
+	}
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	gcs "cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
+)
+
+type GCSStorage struct {
+	client *gcs.Client
+	bucket string
+}
+
+// NewGCSStorage connects to Google Cloud Storage with the service account key in
+// cfg.GCSCredentialsFile, or with Application Default Credentials when it is empty.
+// The bucket must already exist, since creating one needs a billing project.
+func NewGCSStorage(cfg config.StorageConfig) (*GCSStorage, error) {
+	ctx := context.Background()
+
+	var opts []option.ClientOption
+	if cfg.GCSCredentialsFile != "" {
+		opts = append(opts, option.WithAuthCredentialsFile(option.ServiceAccount, cfg.GCSCredentialsFile))
+	}
+	client, err := gcs.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCS client: %w", err)
+	}
+
+	if _, err := client.Bucket(cfg.GCSBucket).Attrs(ctx); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to check bucket: %w", err)
+	}
+
+	return &GCSStorage{
+		client: client,
+		bucket: cfg.GCSBucket,
+	}, nil
+}
+
+func (s *GCSStorage) Put(ctx context.Context, path string, reader io.Reader, size int64, contentType string) error {
+	w := s.client.Bucket(s.bucket).Object(path).NewWriter(ctx)
+	w.ContentType = contentType
+
+	if _, err := io.Copy(w, reader); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("failed to upload to GCS: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to upload to GCS: %w", err)
+	}
+
+	return nil
+}
+
+func (s *GCSStorage) Get(ctx context.Context, path string) (io.ReadCloser, error) {
+	r, err := s.client.Bucket(s.bucket).Object(path).NewReader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get from GCS: %w", err)
+	}
+
+	return r, nil
+}
+
+func (s *GCSStorage) Delete(ctx context.Context, path string) error {
+	err := s.client.Bucket(s.bucket).Object(path).Delete(ctx)
+	if err != nil && !errors.Is(err, gcs.ErrObjectNotExist) {
+		return fmt.Errorf("failed to delete from GCS: %w", err)
+	}
+
+	return nil
+}
+
+func (s *GCSStorage) List(ctx context.Context, fn func(Object) error) error {
+	it := s.client.Bucket(s.bucket).Objects(ctx, nil)
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list GCS objects: %w", err)
+		}
+		if err := fn(Object{Path: attrs.Name, Size: attrs.Size, ModTime: attrs.Updated}); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *GCSStorage) URL(path string) string {
+	return fmt.Sprintf("https://storage.googleapis.com/%s/%s", s.bucket, path)
+}
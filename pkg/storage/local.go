@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -123,6 +124,32 @@ func (s *LocalStorage) Delete(_ context.Context, path string) error {
 	return nil
 }
 
+// List walks the storage directory. Paths use forward slashes, as they are stored.
+func (s *LocalStorage) List(ctx context.Context, fn func(Object) error) error {
+	err := filepath.WalkDir(s.basePath, func(fullPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return ctx.Err()
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.basePath, fullPath)
+		if err != nil {
+			return err
+		}
+		return fn(Object{Path: filepath.ToSlash(rel), Size: info.Size(), ModTime: info.ModTime()})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list files: %w", err)
+	}
+
+	return nil
+}
+
 func (s *LocalStorage) URL(path string) string {
 	cleaned := filepath.ToSlash(filepath.Clean(path))
 	if cleaned == "." || strings.HasPrefix(cleaned, "../") || strings.Contains(cleaned, "/../") {
@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLocalStorageList(t *testing.T) {
+	s, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("new local storage: %v", err)
+	}
+	for _, p := range []string{"1/a.txt", "1/b.png", "exports/2/c.zip"} {
+		if err := s.Put(context.Background(), p, strings.NewReader(p), int64(len(p)), "text/plain"); err != nil {
+			t.Fatalf("put %s: %v", p, err)
+		}
+	}
+
+	var paths []string
+	err = List(context.Background(), s, func(obj Object) error {
+		if obj.Size != int64(len(obj.Path)) || obj.ModTime.IsZero() {
+			t.Errorf("unexpected object info %+v", obj)
+		}
+		paths = append(paths, obj.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if !slices.Equal(paths, []string{"1/a.txt", "1/b.png", "exports/2/c.zip"}) {
+		t.Errorf("expected every stored object, got %v", paths)
+	}
+}
@@ -78,6 +78,22 @@ func (s *S3Storage) Delete(ctx context.Context, path string) error {
 	return nil
 }
 
+func (s *S3Storage) List(ctx context.Context, fn func(Object) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Recursive: true}) {
+		if obj.Err != nil {
+			return fmt.Errorf("failed to list S3 objects: %w", obj.Err)
+		}
+		if err := fn(Object{Path: obj.Key, Size: obj.Size, ModTime: obj.LastModified}); err != nil {
+			return err
+		}
+	}
+
+	return ctx.Err()
+}
+
 func (s *S3Storage) URL(path string) string {
 	scheme := "http"
 	if s.useSSL {
@@ -2,8 +2,10 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
 )
@@ -15,12 +17,68 @@ type Storage interface {
 	URL(path string) string
 }
 
+// ErrListNotSupported is returned by List for a storage that cannot enumerate its objects.
+var ErrListNotSupported = errors.New("storage does not support listing objects")
+
+// Object describes a stored object found by List.
+type Object struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Lister is implemented by storages that can enumerate every object they hold.
+type Lister interface {
+	List(ctx context.Context, fn func(Object) error) error
+}
+
+// List calls fn for each object in store, stopping at the first error fn returns.
+func List(ctx context.Context, store Storage, fn func(Object) error) error {
+	l, ok := store.(Lister)
+	if !ok {
+		return ErrListNotSupported
+	}
+	return l.List(ctx, fn)
+}
+
+// NewStorage creates the configured driver, wrapped in EncryptedStorage when encryption
+// keys are configured and in CDNStorage when a CDN base URL is.
 func NewStorage(cfg config.StorageConfig) (Storage, error) {
+	store, err := newDriver(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.EncryptionKeys != "" {
+		keys, current, err := cfg.EncryptionKeySet()
+		if err != nil {
+			return nil, err
+		}
+		wrapper, err := NewStaticKeyWrapper(keys, current)
+		if err != nil {
+			return nil, err
+		}
+		store = NewEncryptedStorage(store, wrapper)
+	}
+	if cfg.CDNBaseURL != "" {
+		ttl := time.Duration(cfg.CDNURLTTLMinutes) * time.Minute
+		if store, err = NewCDNStorage(store, cfg.CDNBaseURL, cfg.CDNSigningSecret, ttl); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+func newDriver(cfg config.StorageConfig) (Storage, error) {
 	switch cfg.Driver {
 	case "local":
 		return NewLocalStorage(cfg.LocalPath)
 	case "s3", "minio":
 		return NewS3Storage(cfg)
+	case "gcs":
+		return NewGCSStorage(cfg)
+	case "azure":
+		return NewAzureStorage(cfg)
 	default:
 		return nil, fmt.Errorf("unsupported storage driver: %s", cfg.Driver)
 	}
@@ -0,0 +1,54 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// QueryTracer is a pgx.QueryTracer that records every query as a span, named after the
+// sqlc query it runs, such as "GetUserByID".
+type QueryTracer struct{}
+
+var _ pgx.QueryTracer = QueryTracer{}
+
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	name := queryName(data.SQL)
+	ctx, _ = Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBOperationName(name),
+			semconv.DBQueryText(data.SQL),
+		),
+	)
+	return ctx
+}
+
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	defer span.End()
+	span.SetAttributes(attribute.Int64("db.response.affected_rows", data.CommandTag.RowsAffected()))
+	// No rows is a normal outcome that repositories turn into apperror.ErrNotFound.
+	if !errors.Is(data.Err, pgx.ErrNoRows) {
+		RecordError(span, data.Err)
+	}
+}
+
+// queryName returns the name sqlc writes in the "-- name: GetUserByID :one" comment heading
+// each query, or the first keyword of other statements, such as "SELECT".
+func queryName(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) >= 3 && fields[0] == "--" && fields[1] == "name:" {
+		return fields[2]
+	}
+	if len(fields) == 0 {
+		return "QUERY"
+	}
+	return strings.ToUpper(fields[0])
+}
@@ -0,0 +1,81 @@
+package token
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+)
+
+const (
+	revokedTokenPrefix = "revoked_token:"
+	revokedUserPrefix  = "revoked_user:"
+)
+
+// RevocationStore tracks revoked access tokens in the cache.
+// Single tokens are revoked by jti; all of a user's tokens are revoked by
+// recording a cutoff time, rejecting every token issued at or before it.
+type RevocationStore struct {
+	cache        cache.Cache
+	tokenTTL     time.Duration
+	onRevokeUser func(userID int64)
+}
+
+// NewRevocationStore creates a store. tokenTTL must cover the longest lifetime of any
+// token the store checks, guest and impersonation tokens included, so per-user cutoffs
+// outlive every token they invalidate.
+func NewRevocationStore(c cache.Cache, tokenTTL time.Duration) *RevocationStore {
+	return &RevocationStore{cache: c, tokenTTL: tokenTTL}
+}
+
+// OnRevokeUser registers fn to be called whenever all of a user's tokens are revoked, to
+// end sessions that outlive the token check, such as WebSocket connections. It must be
+// called before the store is used.
+func (s *RevocationStore) OnRevokeUser(fn func(userID int64)) {
+	s.onRevokeUser = fn
+}
+
+// Revoke invalidates a single token until it expires.
+func (s *RevocationStore) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if jti == "" || ttl <= 0 {
+		return nil
+	}
+	return s.cache.Set(ctx, revokedTokenPrefix+jti, []byte("1"), ttl)
+}
+
+// RevokeUser invalidates every token issued to the user up to now.
+func (s *RevocationStore) RevokeUser(ctx context.Context, userID int64) error {
+	if s.onRevokeUser != nil {
+		s.onRevokeUser(userID)
+	}
+	cutoff := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	return s.cache.Set(ctx, revokedUserPrefix+strconv.FormatInt(userID, 10), []byte(cutoff), s.tokenTTL)
+}
+
+// IsRevoked reports whether the token has been revoked individually or via its user.
+func (s *RevocationStore) IsRevoked(ctx context.Context, claims *Claims) (bool, error) {
+	if claims.ID != "" {
+		revoked, err := s.cache.Exists(ctx, revokedTokenPrefix+claims.ID)
+		if err != nil {
+			return false, err
+		}
+		if revoked {
+			return true, nil
+		}
+	}
+
+	data, err := s.cache.Get(ctx, revokedUserPrefix+strconv.FormatInt(claims.UserID, 10))
+	if err != nil {
+		return false, err
+	}
+	if data == nil {
+		return false, nil
+	}
+	cutoff, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return false, nil
+	}
+	return claims.IssuedAt == nil || claims.IssuedAt.UnixMilli() <= cutoff, nil
+}
@@ -0,0 +1,138 @@
+package token
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/cache"
+)
+
+func newTestRevocationStore(t *testing.T) *RevocationStore {
+	t.Helper()
+	c := cache.NewMemoryCache()
+	t.Cleanup(func() { _ = c.Close() })
+	return NewRevocationStore(c, time.Hour)
+}
+
+func TestRevocationStore_Revoke(t *testing.T) {
+	store := newTestRevocationStore(t)
+	ctx := context.Background()
+
+	tok, err := Generate(1, "user@test.com", "", "user", nil, testConfig, 1)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	claims, err := Parse(tok, testConfig)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if claims.ID == "" {
+		t.Fatal("expected jti claim to be set")
+	}
+
+	revoked, err := store.IsRevoked(ctx, claims)
+	if err != nil || revoked {
+		t.Fatalf("IsRevoked before revoke = %v, %v; want false, nil", revoked, err)
+	}
+
+	if err := store.Revoke(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+
+	revoked, err = store.IsRevoked(ctx, claims)
+	if err != nil || !revoked {
+		t.Fatalf("IsRevoked after revoke = %v, %v; want true, nil", revoked, err)
+	}
+}
+
+func TestRevocationStore_RevokeUser(t *testing.T) {
+	store := newTestRevocationStore(t)
+	ctx := context.Background()
+
+	older := &Claims{
+		UserID:           7,
+		RegisteredClaims: jwt.RegisteredClaims{ID: "old", IssuedAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))},
+	}
+	newer := &Claims{
+		UserID:           7,
+		RegisteredClaims: jwt.RegisteredClaims{ID: "new", IssuedAt: jwt.NewNumericDate(time.Now().Add(time.Minute))},
+	}
+	otherUser := &Claims{
+		UserID:           8,
+		RegisteredClaims: jwt.RegisteredClaims{ID: "other", IssuedAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))},
+	}
+
+	if err := store.RevokeUser(ctx, 7); err != nil {
+		t.Fatalf("RevokeUser: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		claims *Claims
+		want   bool
+	}{
+		{"issued before cutoff", older, true},
+		{"issued after cutoff", newer, false},
+		{"different user", otherUser, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoked, err := store.IsRevoked(ctx, tt.claims)
+			if err != nil {
+				t.Fatalf("IsRevoked: %v", err)
+			}
+			if revoked != tt.want {
+				t.Errorf("IsRevoked = %v, want %v", revoked, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevocationStore_RevokeUserWithinSecond(t *testing.T) {
+	store := newTestRevocationStore(t)
+	ctx := context.Background()
+
+	parse := func() *Claims {
+		t.Helper()
+		tok, err := Generate(7, "user@test.com", "", "user", nil, testConfig, 1)
+		if err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+		claims, err := Parse(tok, testConfig)
+		if err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		return claims
+	}
+
+	before := parse()
+	time.Sleep(2 * time.Millisecond)
+	if err := store.RevokeUser(ctx, 7); err != nil {
+		t.Fatalf("RevokeUser: %v", err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	after := parse()
+
+	if revoked, err := store.IsRevoked(ctx, before); err != nil || !revoked {
+		t.Errorf("IsRevoked for a token issued before the cutoff = %v, %v; want true, nil", revoked, err)
+	}
+	if revoked, err := store.IsRevoked(ctx, after); err != nil || revoked {
+		t.Errorf("IsRevoked for a token issued after the cutoff = %v, %v; want false, nil", revoked, err)
+	}
+}
+
+func TestRevocationStore_OnRevokeUser(t *testing.T) {
+	store := newTestRevocationStore(t)
+	var revoked []int64
+	store.OnRevokeUser(func(userID int64) { revoked = append(revoked, userID) })
+
+	if err := store.RevokeUser(context.Background(), 7); err != nil {
+		t.Fatalf("RevokeUser: %v", err)
+	}
+	if len(revoked) != 1 || revoked[0] != 7 {
+		t.Errorf("expected the hook to run for user 7, got %v", revoked)
+	}
+}
@@ -4,50 +4,80 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
 )
 
+// Timestamps carry milliseconds so a RevocationStore cutoff tells apart tokens issued
+// within the same second before and after it.
+func init() {
+	jwt.TimePrecision = time.Millisecond
+}
+
 // Claims represents the JWT claims used across the application.
 type Claims struct {
-	UserID int64  `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID         int64    `json:"user_id"`
+	Email          string   `json:"email"`
+	Username       string   `json:"username,omitempty"` // public handle, for clients that don't show emails
+	Role           string   `json:"role"`
+	Scopes         []string `json:"scopes,omitempty"`          // fine-grained permissions, e.g. "files:write"
+	ImpersonatedBy int64    `json:"impersonated_by,omitempty"` // admin user ID for impersonation tokens
 	jwt.RegisteredClaims
 }
 
-const (
-	jwtIssuer   = "fiber-golang-boilerplate"
-	jwtAudience = "fiber-golang-boilerplate-api"
-)
+// Generate creates a JWT token signed with the secret of cfg, stamped with its issuer and
+// audience, and with a unique jti for revocation.
+func Generate(userID int64, email, username, role string, scopes []string, cfg config.JWTConfig, expireHour int) (string, error) {
+	return sign(Claims{
+		UserID:   userID,
+		Email:    email,
+		Username: username,
+		Role:     role,
+		Scopes:   scopes,
+	}, cfg, time.Duration(expireHour)*time.Hour)
+}
+
+// GenerateImpersonation creates a short-lived token for userID carrying the
+// impersonating admin's ID in the impersonated_by claim.
+func GenerateImpersonation(userID int64, email, username, role string, scopes []string, impersonatedBy int64, cfg config.JWTConfig, ttl time.Duration) (string, error) {
+	return sign(Claims{
+		UserID:         userID,
+		Email:          email,
+		Username:       username,
+		Role:           role,
+		Scopes:         scopes,
+		ImpersonatedBy: impersonatedBy,
+	}, cfg, ttl)
+}
 
-// Generate creates a signed JWT token.
-func Generate(userID int64, email, role, secret string, expireHour int) (string, error) {
-	claims := Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(expireHour) * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    jwtIssuer,
-			Audience:  jwt.ClaimStrings{jwtAudience},
-		},
+func sign(claims Claims, cfg config.JWTConfig, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        uuid.NewString(),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(now),
+		Issuer:    cfg.Issuer,
+		Audience:  jwt.ClaimStrings{cfg.Audience},
 	}
 
 	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return t.SignedString([]byte(secret))
+	return t.SignedString([]byte(cfg.Secret))
 }
 
-// Parse validates a JWT token string and returns the claims.
-func Parse(tokenString, secret string) (*Claims, error) {
+// Parse validates a JWT token string against the secret and issuer of cfg and returns the
+// claims. Tokens for the audience of cfg or one of its extra audiences are accepted, so
+// several apps can share one auth service.
+func Parse(tokenString string, cfg config.JWTConfig) (*Claims, error) {
 	claims := &Claims{}
 	t, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (any, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, jwt.ErrSignatureInvalid
 		}
-		return []byte(secret), nil
+		return []byte(cfg.Secret), nil
 	},
-		jwt.WithIssuer(jwtIssuer),
-		jwt.WithAudience(jwtAudience),
+		jwt.WithIssuer(cfg.Issuer),
+		jwt.WithAudience(append([]string{cfg.Audience}, cfg.AcceptedAudiences()...)...),
 	)
 	if err != nil || !t.Valid {
 		return nil, err
@@ -5,12 +5,18 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/config"
 )
 
-const testSecret = "test-secret-key-for-testing"
+var testConfig = config.JWTConfig{
+	Secret:   "test-secret-key-for-testing",
+	Issuer:   "fiber-golang-boilerplate",
+	Audience: "fiber-golang-boilerplate-api",
+}
 
 func TestGenerateAndParse(t *testing.T) {
-	tok, err := Generate(42, "user@test.com", "admin", testSecret, 1)
+	tok, err := Generate(42, "user@test.com", "jane", "admin", nil, testConfig, 1)
 	if err != nil {
 		t.Fatalf("Generate: %v", err)
 	}
@@ -18,7 +24,7 @@ func TestGenerateAndParse(t *testing.T) {
 		t.Fatal("Generate returned empty token")
 	}
 
-	claims, err := Parse(tok, testSecret)
+	claims, err := Parse(tok, testConfig)
 	if err != nil {
 		t.Fatalf("Parse: %v", err)
 	}
@@ -29,21 +35,64 @@ func TestGenerateAndParse(t *testing.T) {
 	if claims.Email != "user@test.com" {
 		t.Errorf("Email = %q, want %q", claims.Email, "user@test.com")
 	}
+	if claims.Username != "jane" {
+		t.Errorf("Username = %q, want %q", claims.Username, "jane")
+	}
 	if claims.Role != "admin" {
 		t.Errorf("Role = %q, want %q", claims.Role, "admin")
 	}
-	if claims.Issuer != jwtIssuer {
-		t.Errorf("Issuer = %q, want %q", claims.Issuer, jwtIssuer)
+	if claims.Issuer != testConfig.Issuer {
+		t.Errorf("Issuer = %q, want %q", claims.Issuer, testConfig.Issuer)
 	}
 	aud := claims.Audience
-	if len(aud) != 1 || aud[0] != jwtAudience {
-		t.Errorf("Audience = %v, want [%q]", aud, jwtAudience)
+	if len(aud) != 1 || aud[0] != testConfig.Audience {
+		t.Errorf("Audience = %v, want [%q]", aud, testConfig.Audience)
+	}
+}
+
+func TestGenerate_Scopes(t *testing.T) {
+	tok, err := Generate(1, "user@test.com", "", "user", []string{"files:read", "files:write"}, testConfig, 1)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	claims, err := Parse(tok, testConfig)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if len(claims.Scopes) != 2 || claims.Scopes[0] != "files:read" || claims.Scopes[1] != "files:write" {
+		t.Errorf("Scopes = %v, want [files:read files:write]", claims.Scopes)
+	}
+}
+
+func TestGenerateImpersonation(t *testing.T) {
+	tok, err := GenerateImpersonation(42, "user@test.com", "", "user", nil, 7, testConfig, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateImpersonation: %v", err)
+	}
+
+	claims, err := Parse(tok, testConfig)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if claims.UserID != 42 {
+		t.Errorf("UserID = %d, want 42", claims.UserID)
+	}
+	if claims.ImpersonatedBy != 7 {
+		t.Errorf("ImpersonatedBy = %d, want 7", claims.ImpersonatedBy)
+	}
+	if ttl := time.Until(claims.ExpiresAt.Time); ttl > 15*time.Minute || ttl < 14*time.Minute {
+		t.Errorf("token TTL = %v, want ~15m", ttl)
 	}
 }
 
 func TestParse_WrongSecret(t *testing.T) {
-	tok, _ := Generate(1, "a@b.com", "user", testSecret, 1)
-	_, err := Parse(tok, "wrong-secret")
+	tok, _ := Generate(1, "a@b.com", "", "user", nil, testConfig, 1)
+	wrong := testConfig
+	wrong.Secret = "wrong-secret"
+	_, err := Parse(tok, wrong)
 	if err == nil {
 		t.Fatal("expected error for wrong secret")
 	}
@@ -58,13 +107,13 @@ func TestParse_ExpiredToken(t *testing.T) {
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-1 * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now().Add(-2 * time.Hour)),
-			Issuer:    jwtIssuer,
-			Audience:  jwt.ClaimStrings{jwtAudience},
+			Issuer:    testConfig.Issuer,
+			Audience:  jwt.ClaimStrings{testConfig.Audience},
 		},
 	}
-	tok, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	tok, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testConfig.Secret))
 
-	_, err := Parse(tok, testSecret)
+	_, err := Parse(tok, testConfig)
 	if err == nil {
 		t.Fatal("expected error for expired token")
 	}
@@ -79,12 +128,12 @@ func TestParse_WrongIssuer(t *testing.T) {
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(1 * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    "wrong-issuer",
-			Audience:  jwt.ClaimStrings{jwtAudience},
+			Audience:  jwt.ClaimStrings{testConfig.Audience},
 		},
 	}
-	tok, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	tok, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testConfig.Secret))
 
-	_, err := Parse(tok, testSecret)
+	_, err := Parse(tok, testConfig)
 	if err == nil {
 		t.Fatal("expected error for wrong issuer")
 	}
@@ -98,14 +147,51 @@ func TestParse_WrongAudience(t *testing.T) {
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(1 * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    jwtIssuer,
+			Issuer:    testConfig.Issuer,
 			Audience:  jwt.ClaimStrings{"wrong-audience"},
 		},
 	}
-	tok, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	tok, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testConfig.Secret))
 
-	_, err := Parse(tok, testSecret)
+	_, err := Parse(tok, testConfig)
 	if err == nil {
 		t.Fatal("expected error for wrong audience")
 	}
 }
+
+func TestParse_ExtraAudiences(t *testing.T) {
+	cfg := config.JWTConfig{Secret: testConfig.Secret, Issuer: "auth.example.com", Audience: "app-a", ExtraAudiences: "app-b"}
+
+	tok, err := Generate(1, "a@b.com", "", "user", nil, cfg, 1)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	claims, err := Parse(tok, cfg)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if claims.Issuer != "auth.example.com" || len(claims.Audience) != 1 || claims.Audience[0] != "app-a" {
+		t.Errorf("unexpected iss/aud: %q %v", claims.Issuer, claims.Audience)
+	}
+	if _, err := Parse(tok, testConfig); err == nil {
+		t.Error("expected a token of another issuer and audience to be rejected")
+	}
+
+	sibling := func(aud string) string {
+		tok, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+			UserID: 1,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+				Issuer:    "auth.example.com",
+				Audience:  jwt.ClaimStrings{aud},
+			},
+		}).SignedString([]byte(cfg.Secret))
+		return tok
+	}
+	if _, err := Parse(sibling("app-b"), cfg); err != nil {
+		t.Errorf("expected extra audience to be accepted: %v", err)
+	}
+	if _, err := Parse(sibling("app-c"), cfg); err == nil {
+		t.Error("expected unknown audience to be rejected")
+	}
+}
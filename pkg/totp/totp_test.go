@@ -0,0 +1,72 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors, "12345678901234567890".
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode_RFC6238(t *testing.T) {
+	// The last six digits of the RFC 6238 appendix B SHA-1 codes
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	now := time.Now()
+	code := func(at time.Time) string {
+		c, _ := Code(secret, Step(at))
+		return c
+	}
+
+	if step, ok := Validate(secret, code(now), now); !ok || step != Step(now) {
+		t.Errorf("expected the current code to match step %d, got %d %v", Step(now), step, ok)
+	}
+	if _, ok := Validate(secret, code(now.Add(-Period)), now); !ok {
+		t.Error("expected the previous code to be accepted for clock drift")
+	}
+	if _, ok := Validate(secret, code(now.Add(-3*Period)), now); ok {
+		t.Error("expected an old code to be rejected")
+	}
+	if _, ok := Validate(secret, "12345", now); ok {
+		t.Error("expected a short code to be rejected")
+	}
+	if _, ok := Validate("not base32!", "123456", now); ok {
+		t.Error("expected an invalid secret to be rejected")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("My App", "jane@example.com", "ABC")
+	if !strings.HasPrefix(uri, "otpauth://totp/My%20App:jane@example.com?") {
+		t.Errorf("unexpected label in %s", uri)
+	}
+	for _, param := range []string{"secret=ABC", "issuer=My%20App", "digits=6", "period=30"} {
+		if !strings.Contains(uri, param) {
+			t.Errorf("expected %s in %s", param, uri)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	// MaxMetadataKeyLength is the longest metadata key accepted by the "metadata" tag.
+	MaxMetadataKeyLength = 64
+	// MaxMetadataBytes caps the JSON-encoded size of a metadata update.
+	MaxMetadataBytes = 16 << 10
+)
+
+var (
+	metadataMu   sync.RWMutex
+	metadataKeys map[string]struct{} // nil allows any key
+)
+
+// SetMetadataKeys restricts the keys accepted by the "metadata" validation tag.
+// An empty list allows any key. It is meant to be called once at startup.
+func SetMetadataKeys(keys []string) {
+	var allowed map[string]struct{}
+	if len(keys) > 0 {
+		allowed = make(map[string]struct{}, len(keys))
+		for _, k := range keys {
+			allowed[k] = struct{}{}
+		}
+	}
+
+	metadataMu.Lock()
+	metadataKeys = allowed
+	metadataMu.Unlock()
+}
+
+// MetadataKeys returns the allowed metadata keys in sorted order, or nil when any key is accepted.
+func MetadataKeys() []string {
+	metadataMu.RLock()
+	defer metadataMu.RUnlock()
+
+	if metadataKeys == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(metadataKeys))
+	for k := range metadataKeys {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// CheckMetadata reports whether every key is allowed and the encoded document fits the size limit.
+func CheckMetadata(m map[string]any) bool {
+	metadataMu.RLock()
+	allowed := metadataKeys
+	metadataMu.RUnlock()
+
+	for k := range m {
+		if k == "" || len(k) > MaxMetadataKeyLength {
+			return false
+		}
+		if allowed != nil {
+			if _, ok := allowed[k]; !ok {
+				return false
+			}
+		}
+	}
+
+	b, err := json.Marshal(m)
+	return err == nil && len(b) <= MaxMetadataBytes
+}
+
+func describeMetadata() string {
+	msg := fmt.Sprintf("keys must be 1-%d characters and the document at most %d bytes", MaxMetadataKeyLength, MaxMetadataBytes)
+	if keys := MetadataKeys(); keys != nil {
+		msg = fmt.Sprintf("keys must be one of: %s", strings.Join(keys, ", "))
+	}
+	return msg
+}
@@ -0,0 +1,148 @@
+package validator
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// bcryptMaxBytes is the longest password bcrypt hashes without truncation.
+const bcryptMaxBytes = 72
+
+// PasswordPolicy describes the rules enforced by the "password" validation tag.
+type PasswordPolicy struct {
+	MinLength      int
+	MaxLength      int
+	RequireUpper   bool
+	RequireLower   bool
+	RequireDigit   bool
+	RequireSpecial bool
+	Banned         map[string]struct{} // lowercased passwords that are always rejected
+}
+
+// DefaultPasswordPolicy returns the built-in policy: 8-72 characters with
+// uppercase, lowercase, digit and special character.
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:      8,
+		MaxLength:      bcryptMaxBytes,
+		RequireUpper:   true,
+		RequireLower:   true,
+		RequireDigit:   true,
+		RequireSpecial: true,
+	}
+}
+
+var (
+	policyMu sync.RWMutex
+	policy   = DefaultPasswordPolicy()
+)
+
+// SetPasswordPolicy replaces the active password policy. It is meant to be called once at startup.
+func SetPasswordPolicy(p PasswordPolicy) error {
+	if p.MinLength < 1 {
+		return fmt.Errorf("password min length must be at least 1")
+	}
+	if p.MaxLength > bcryptMaxBytes {
+		return fmt.Errorf("password max length must be at most %d", bcryptMaxBytes)
+	}
+	if p.MinLength > p.MaxLength {
+		return fmt.Errorf("password min length must not exceed max length")
+	}
+
+	policyMu.Lock()
+	policy = p
+	policyMu.Unlock()
+	return nil
+}
+
+// CurrentPasswordPolicy returns the active password policy.
+func CurrentPasswordPolicy() PasswordPolicy {
+	policyMu.RLock()
+	defer policyMu.RUnlock()
+	return policy
+}
+
+// LoadBannedPasswords reads a newline-separated password list, ignoring blank lines and # comments.
+func LoadBannedPasswords(path string) (map[string]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open banned password list: %w", err)
+	}
+	defer f.Close()
+
+	banned := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		banned[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read banned password list: %w", err)
+	}
+	return banned, nil
+}
+
+// Check reports whether password satisfies the policy.
+func (p PasswordPolicy) Check(password string) bool {
+	if len(password) < p.MinLength || len(password) > p.MaxLength {
+		return false
+	}
+	if _, banned := p.Banned[strings.ToLower(password)]; banned {
+		return false
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSpecial bool
+	for _, ch := range password {
+		switch {
+		case unicode.IsUpper(ch):
+			hasUpper = true
+		case unicode.IsLower(ch):
+			hasLower = true
+		case unicode.IsDigit(ch):
+			hasDigit = true
+		case unicode.IsPunct(ch) || unicode.IsSymbol(ch):
+			hasSpecial = true
+		}
+	}
+	return (hasUpper || !p.RequireUpper) &&
+		(hasLower || !p.RequireLower) &&
+		(hasDigit || !p.RequireDigit) &&
+		(hasSpecial || !p.RequireSpecial)
+}
+
+// Describe returns a human-readable summary of the policy for validation messages.
+func (p PasswordPolicy) Describe() string {
+	var classes []string
+	if p.RequireUpper {
+		classes = append(classes, "uppercase")
+	}
+	if p.RequireLower {
+		classes = append(classes, "lowercase")
+	}
+	if p.RequireDigit {
+		classes = append(classes, "digit")
+	}
+	if p.RequireSpecial {
+		classes = append(classes, "special character")
+	}
+
+	desc := fmt.Sprintf("must be %d-%d characters", p.MinLength, p.MaxLength)
+	switch len(classes) {
+	case 0:
+	case 1:
+		desc += " with " + classes[0]
+	default:
+		desc += " with " + strings.Join(classes[:len(classes)-1], ", ") + ", and " + classes[len(classes)-1]
+	}
+	if len(p.Banned) > 0 {
+		desc += ", and not a commonly used password"
+	}
+	return desc
+}
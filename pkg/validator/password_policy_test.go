@@ -0,0 +1,109 @@
+package validator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chuanghiduoc/fiber-golang-boilerplate/pkg/apperror"
+)
+
+func setTestPolicy(t *testing.T, p PasswordPolicy) {
+	t.Helper()
+	if err := SetPasswordPolicy(p); err != nil {
+		t.Fatalf("SetPasswordPolicy: %v", err)
+	}
+	t.Cleanup(func() { _ = SetPasswordPolicy(DefaultPasswordPolicy()) })
+}
+
+func TestPasswordPolicy_Custom(t *testing.T) {
+	setTestPolicy(t, PasswordPolicy{MinLength: 12, MaxLength: 64, RequireLower: true, RequireDigit: true})
+
+	tests := []struct {
+		name    string
+		pw      string
+		wantErr bool
+	}{
+		{"valid without upper or special", "abcdefghijk1", false},
+		{"too short for custom min", "Abcdef1!", true},
+		{"missing digit", "abcdefghijkl", true},
+		{"over custom max", "a1" + repeat('x', 63), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateStruct(passwordReq{Password: tt.pw})
+			if tt.wantErr && err == nil {
+				t.Errorf("expected error for password %q", tt.pw)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error for password %q: %v", tt.pw, err)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicy_Banned(t *testing.T) {
+	p := DefaultPasswordPolicy()
+	p.Banned = map[string]struct{}{"p@ssw0rd123": {}}
+	setTestPolicy(t, p)
+
+	err := ValidateStruct(passwordReq{Password: "P@ssw0rd123"})
+	if err == nil {
+		t.Fatal("expected banned password to be rejected regardless of case")
+	}
+	var appErr *apperror.AppError
+	if !errors.As(err, &appErr) {
+		t.Fatalf("expected AppError, got %T", err)
+	}
+	details, _ := appErr.Details.(map[string]string)
+	if msg := details["Password"]; !strings.Contains(msg, "commonly used") {
+		t.Errorf("expected message to mention common passwords, got %q", msg)
+	}
+}
+
+func TestSetPasswordPolicy_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		p    PasswordPolicy
+	}{
+		{"zero min", PasswordPolicy{MinLength: 0, MaxLength: 72}},
+		{"max over bcrypt limit", PasswordPolicy{MinLength: 8, MaxLength: 73}},
+		{"min above max", PasswordPolicy{MinLength: 20, MaxLength: 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetPasswordPolicy(tt.p); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestDescribe_Default(t *testing.T) {
+	want := "must be 8-72 characters with uppercase, lowercase, digit, and special character"
+	if got := DefaultPasswordPolicy().Describe(); got != want {
+		t.Errorf("Describe() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadBannedPasswords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "banned.txt")
+	if err := os.WriteFile(path, []byte("# common\nPassword1!\n\n  Qwerty123!  \n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	banned, err := LoadBannedPasswords(path)
+	if err != nil {
+		t.Fatalf("LoadBannedPasswords: %v", err)
+	}
+	if len(banned) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(banned))
+	}
+	if _, ok := banned["qwerty123!"]; !ok {
+		t.Error("expected trimmed, lowercased entry")
+	}
+}
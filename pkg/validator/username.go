@@ -0,0 +1,59 @@
+package validator
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// usernamePattern accepts 3-30 characters: a letter followed by letters, digits or underscores.
+var usernamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{2,29}$`)
+
+// defaultReservedUsernames are handles that could be mistaken for the service itself or
+// collide with route segments.
+var defaultReservedUsernames = []string{
+	"abuse", "admin", "administrator", "api", "auth", "billing", "contact", "guest",
+	"help", "info", "login", "logout", "mail", "moderator", "noreply", "no_reply",
+	"null", "owner", "postmaster", "register", "root", "security", "settings",
+	"signup", "staff", "superuser", "support", "system", "undefined", "user", "users",
+	"webmaster",
+}
+
+var (
+	usernameMu        sync.RWMutex
+	reservedUsernames = reservedSet(nil)
+)
+
+// SetReservedUsernames adds words to the built-in list of handles rejected by the "username"
+// validation tag. Matching is case-insensitive. It is meant to be called once at startup.
+func SetReservedUsernames(words []string) {
+	reserved := reservedSet(words)
+
+	usernameMu.Lock()
+	reservedUsernames = reserved
+	usernameMu.Unlock()
+}
+
+// IsReservedUsername reports whether name is on the reserved list.
+func IsReservedUsername(name string) bool {
+	usernameMu.RLock()
+	defer usernameMu.RUnlock()
+
+	_, ok := reservedUsernames[strings.ToLower(name)]
+	return ok
+}
+
+// CheckUsername reports whether name matches the username pattern and is not reserved.
+func CheckUsername(name string) bool {
+	return usernamePattern.MatchString(name) && !IsReservedUsername(name)
+}
+
+func reservedSet(extra []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(defaultReservedUsernames)+len(extra))
+	for _, words := range [][]string{defaultReservedUsernames, extra} {
+		for _, w := range words {
+			set[strings.ToLower(w)] = struct{}{}
+		}
+	}
+	return set
+}
@@ -2,8 +2,9 @@ package validator
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 	"sync"
-	"unicode"
 
 	"github.com/go-playground/validator/v10"
 
@@ -13,35 +14,40 @@ import (
 var (
 	once     sync.Once
 	validate *validator.Validate
+
+	slugPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
 )
 
 func instance() *validator.Validate {
 	once.Do(func() {
 		validate = validator.New()
 		_ = validate.RegisterValidation("password", validatePassword)
+		_ = validate.RegisterValidation("slug", validateSlug)
+		_ = validate.RegisterValidation("metadata", validateMetadata)
+		_ = validate.RegisterValidation("username", validateUsername)
 	})
 	return validate
 }
 
 func validatePassword(fl validator.FieldLevel) bool {
-	password := fl.Field().String()
-	if len(password) < 8 || len(password) > 72 {
-		return false
-	}
-	var hasUpper, hasLower, hasDigit, hasSpecial bool
-	for _, ch := range password {
-		switch {
-		case unicode.IsUpper(ch):
-			hasUpper = true
-		case unicode.IsLower(ch):
-			hasLower = true
-		case unicode.IsDigit(ch):
-			hasDigit = true
-		case unicode.IsPunct(ch) || unicode.IsSymbol(ch):
-			hasSpecial = true
-		}
-	}
-	return hasUpper && hasLower && hasDigit && hasSpecial
+	return CurrentPasswordPolicy().Check(fl.Field().String())
+}
+
+// validateSlug accepts lowercase identifiers such as role names: a letter followed by
+// letters, digits, underscores or hyphens.
+func validateSlug(fl validator.FieldLevel) bool {
+	return slugPattern.MatchString(fl.Field().String())
+}
+
+// validateUsername accepts handles that match the username pattern and are not reserved.
+func validateUsername(fl validator.FieldLevel) bool {
+	return CheckUsername(fl.Field().String())
+}
+
+// validateMetadata checks free-form metadata maps against the configured key allowlist and size limit.
+func validateMetadata(fl validator.FieldLevel) bool {
+	m, ok := fl.Field().Interface().(map[string]any)
+	return ok && CheckMetadata(m)
 }
 
 func ValidateStruct(s interface{}) error {
@@ -65,7 +71,7 @@ func ValidateStruct(s interface{}) error {
 
 func formatError(fe validator.FieldError) string {
 	switch fe.Tag() {
-	case "required":
+	case "required", "required_if":
 		return fmt.Sprintf("%s is required", fe.Field())
 	case "email":
 		return fmt.Sprintf("%s must be a valid email", fe.Field())
@@ -73,8 +79,24 @@ func formatError(fe validator.FieldError) string {
 		return fmt.Sprintf("%s must be at least %s characters", fe.Field(), fe.Param())
 	case "max":
 		return fmt.Sprintf("%s must be at most %s characters", fe.Field(), fe.Param())
+	case "unique":
+		return fmt.Sprintf("%s must not contain duplicates", fe.Field())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", fe.Field(), strings.ReplaceAll(fe.Param(), " ", ", "))
+	case "datetime":
+		return fmt.Sprintf("%s must be a timestamp in the format %s", fe.Field(), fe.Param())
+	case "bcp47_language_tag":
+		return fmt.Sprintf("%s must be a BCP 47 language tag such as en or pt-BR", fe.Field())
+	case "timezone":
+		return fmt.Sprintf("%s must be an IANA time zone such as Europe/Berlin", fe.Field())
+	case "slug":
+		return fmt.Sprintf("%s must start with a lowercase letter and contain only lowercase letters, digits, '_' or '-'", fe.Field())
+	case "username":
+		return fmt.Sprintf("%s must be 3-30 letters, digits or '_', start with a letter and not be a reserved word", fe.Field())
+	case "metadata":
+		return fmt.Sprintf("%s %s", fe.Field(), describeMetadata())
 	case "password":
-		return fmt.Sprintf("%s must be 8-72 characters with uppercase, lowercase, digit, and special character", fe.Field())
+		return fmt.Sprintf("%s %s", fe.Field(), CurrentPasswordPolicy().Describe())
 	default:
 		return fmt.Sprintf("%s is invalid", fe.Field())
 	}
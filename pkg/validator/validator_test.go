@@ -75,6 +75,104 @@ func TestValidateStruct_Required(t *testing.T) {
 	}
 }
 
+type slugReq struct {
+	Name string `validate:"required,slug"`
+}
+
+func TestValidateSlug(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"lowercase", "support", false},
+		{"with separators", "billing_admin-2", false},
+		{"uppercase", "Support", true},
+		{"leading digit", "2fa", true},
+		{"space", "help desk", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateStruct(slugReq{Name: tt.value})
+			if tt.wantErr != (err != nil) {
+				t.Errorf("slug %q: wantErr=%v, got %v", tt.value, tt.wantErr, err)
+			}
+		})
+	}
+}
+
+type usernameReq struct {
+	Username string `validate:"username"`
+}
+
+func TestValidateUsername(t *testing.T) {
+	t.Cleanup(func() { SetReservedUsernames(nil) })
+
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"lowercase", "jane_doe", false},
+		{"mixed case", "JaneDoe42", false},
+		{"too short", "jd", true},
+		{"too long", "j" + repeat('x', 30), true},
+		{"leading digit", "42jane", true},
+		{"hyphen", "jane-doe", true},
+		{"reserved", "Admin", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateStruct(usernameReq{Username: tt.value})
+			if tt.wantErr != (err != nil) {
+				t.Errorf("username %q: wantErr=%v, got %v", tt.value, tt.wantErr, err)
+			}
+		})
+	}
+
+	SetReservedUsernames([]string{"Acme"})
+	if err := ValidateStruct(usernameReq{Username: "acme"}); err == nil {
+		t.Error("expected configured reserved word to be rejected")
+	}
+	if err := ValidateStruct(usernameReq{Username: "root"}); err == nil {
+		t.Error("expected built-in reserved words to be kept")
+	}
+}
+
+type metadataReq struct {
+	Metadata map[string]any `validate:"omitempty,metadata"`
+}
+
+func TestValidateMetadata(t *testing.T) {
+	t.Cleanup(func() { SetMetadataKeys(nil) })
+
+	if err := ValidateStruct(metadataReq{Metadata: map[string]any{"company": "Acme", "age": 30}}); err != nil {
+		t.Errorf("expected any key to be accepted, got %v", err)
+	}
+	if err := ValidateStruct(metadataReq{Metadata: map[string]any{repeat('k', MaxMetadataKeyLength+1): true}}); err == nil {
+		t.Error("expected overlong key to be rejected")
+	}
+	if err := ValidateStruct(metadataReq{Metadata: map[string]any{"bio": repeat('x', MaxMetadataBytes)}}); err == nil {
+		t.Error("expected oversized document to be rejected")
+	}
+
+	SetMetadataKeys([]string{"company", "timezone"})
+	if err := ValidateStruct(metadataReq{Metadata: map[string]any{"timezone": "UTC"}}); err != nil {
+		t.Errorf("expected allowed key to be accepted, got %v", err)
+	}
+	err := ValidateStruct(metadataReq{Metadata: map[string]any{"age": 30}})
+	var appErr *apperror.AppError
+	if !errors.As(err, &appErr) {
+		t.Fatalf("expected AppError for unknown key, got %v", err)
+	}
+	details, _ := appErr.Details.(map[string]string)
+	if want := "Metadata keys must be one of: company, timezone"; details["Metadata"] != want {
+		t.Errorf("expected %q, got %v", want, appErr.Details)
+	}
+}
+
 func repeat(ch byte, n int) string {
 	b := make([]byte, n)
 	for i := range b {
@@ -0,0 +1,20 @@
+-- name: CreateAccountDeletionRequest :one
+INSERT INTO account_deletion_requests (user_id, token, scheduled_for)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetAccountDeletionRequestByUserID :one
+SELECT * FROM account_deletion_requests WHERE user_id = $1;
+
+-- name: DeleteAccountDeletionRequestByToken :one
+DELETE FROM account_deletion_requests WHERE token = $1
+RETURNING *;
+
+-- name: ListDueAccountDeletionRequests :many
+SELECT * FROM account_deletion_requests
+WHERE scheduled_for <= NOW()
+ORDER BY scheduled_for
+LIMIT $1;
+
+-- name: DeleteAccountDeletionRequestByUserID :exec
+DELETE FROM account_deletion_requests WHERE user_id = $1;
@@ -0,0 +1,36 @@
+-- name: CreateAuditLog :one
+INSERT INTO audit_logs (actor_id, action, target_type, target_id, before_data, after_data, ip_address, request_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: ListAuditLogs :many
+SELECT * FROM audit_logs
+WHERE (sqlc.narg(actor_id)::bigint IS NULL OR actor_id = sqlc.narg(actor_id))
+  AND (sqlc.narg(action)::text IS NULL OR action = sqlc.narg(action))
+  AND (sqlc.narg(target_type)::text IS NULL OR target_type = sqlc.narg(target_type))
+  AND (sqlc.narg(target_id)::bigint IS NULL OR target_id = sqlc.narg(target_id))
+  AND (sqlc.narg(created_after)::timestamptz IS NULL OR created_at >= sqlc.narg(created_after))
+  AND (sqlc.narg(created_before)::timestamptz IS NULL OR created_at < sqlc.narg(created_before))
+ORDER BY id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: CountAuditLogs :one
+SELECT count(*) FROM audit_logs
+WHERE (sqlc.narg(actor_id)::bigint IS NULL OR actor_id = sqlc.narg(actor_id))
+  AND (sqlc.narg(action)::text IS NULL OR action = sqlc.narg(action))
+  AND (sqlc.narg(target_type)::text IS NULL OR target_type = sqlc.narg(target_type))
+  AND (sqlc.narg(target_id)::bigint IS NULL OR target_id = sqlc.narg(target_id))
+  AND (sqlc.narg(created_after)::timestamptz IS NULL OR created_at >= sqlc.narg(created_after))
+  AND (sqlc.narg(created_before)::timestamptz IS NULL OR created_at < sqlc.narg(created_before));
+
+-- name: ListAuditLogsAfter :many
+SELECT * FROM audit_logs
+WHERE (sqlc.narg(actor_id)::bigint IS NULL OR actor_id = sqlc.narg(actor_id))
+  AND (sqlc.narg(action)::text IS NULL OR action = sqlc.narg(action))
+  AND (sqlc.narg(target_type)::text IS NULL OR target_type = sqlc.narg(target_type))
+  AND (sqlc.narg(target_id)::bigint IS NULL OR target_id = sqlc.narg(target_id))
+  AND (sqlc.narg(created_after)::timestamptz IS NULL OR created_at >= sqlc.narg(created_after))
+  AND (sqlc.narg(created_before)::timestamptz IS NULL OR created_at < sqlc.narg(created_before))
+  AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg('limit');
@@ -0,0 +1,51 @@
+-- name: CreateBroadcast :one
+INSERT INTO broadcasts (sender_id, subject, body, segment, recipient_count)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: UpdateBroadcastProgress :exec
+UPDATE broadcasts SET sent_count = $2, failed_count = $3
+WHERE id = $1;
+
+-- name: FinishBroadcast :exec
+UPDATE broadcasts SET status = $2, sent_count = $3, failed_count = $4, completed_at = NOW()
+WHERE id = $1;
+
+-- name: ListBroadcasts :many
+SELECT * FROM broadcasts
+ORDER BY id DESC
+LIMIT $1 OFFSET $2;
+
+-- name: CountBroadcasts :one
+SELECT count(*) FROM broadcasts;
+
+-- name: ListBroadcastRecipients :many
+SELECT u.id, u.email, u.name
+FROM users u
+LEFT JOIN user_settings s ON s.user_id = u.id
+WHERE u.deleted_at IS NULL
+  AND u.banned_at IS NULL
+  AND u.role <> 'guest'
+  AND (sqlc.narg(search)::text IS NULL OR u.name ILIKE '%' || sqlc.narg(search) || '%' OR u.email ILIKE '%' || sqlc.narg(search) || '%')
+  AND (sqlc.narg(role)::text IS NULL OR u.role = sqlc.narg(role))
+  AND (sqlc.narg(email_verified)::boolean IS NULL OR (u.email_verified_at IS NOT NULL) = sqlc.narg(email_verified))
+  AND (sqlc.narg(created_after)::timestamptz IS NULL OR u.created_at >= sqlc.narg(created_after))
+  AND (sqlc.narg(created_before)::timestamptz IS NULL OR u.created_at < sqlc.narg(created_before))
+  AND (NOT sqlc.arg(subscribers_only)::boolean OR COALESCE(s.email_product_updates, FALSE))
+  AND u.id > sqlc.arg(after_id)
+ORDER BY u.id
+LIMIT sqlc.arg('limit');
+
+-- name: CountBroadcastRecipients :one
+SELECT count(*)
+FROM users u
+LEFT JOIN user_settings s ON s.user_id = u.id
+WHERE u.deleted_at IS NULL
+  AND u.banned_at IS NULL
+  AND u.role <> 'guest'
+  AND (sqlc.narg(search)::text IS NULL OR u.name ILIKE '%' || sqlc.narg(search) || '%' OR u.email ILIKE '%' || sqlc.narg(search) || '%')
+  AND (sqlc.narg(role)::text IS NULL OR u.role = sqlc.narg(role))
+  AND (sqlc.narg(email_verified)::boolean IS NULL OR (u.email_verified_at IS NOT NULL) = sqlc.narg(email_verified))
+  AND (sqlc.narg(created_after)::timestamptz IS NULL OR u.created_at >= sqlc.narg(created_after))
+  AND (sqlc.narg(created_before)::timestamptz IS NULL OR u.created_at < sqlc.narg(created_before))
+  AND (NOT sqlc.arg(subscribers_only)::boolean OR COALESCE(s.email_product_updates, FALSE));
@@ -0,0 +1,33 @@
+-- name: CreateDataExport :one
+INSERT INTO data_exports (user_id) VALUES ($1)
+RETURNING *;
+
+-- name: GetLatestDataExportByUserID :one
+SELECT * FROM data_exports
+WHERE user_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT 1;
+
+-- name: MarkDataExportReady :one
+UPDATE data_exports
+SET status = 'ready', storage_path = $2, size = $3, expires_at = $4, completed_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: MarkDataExportFailed :exec
+UPDATE data_exports
+SET status = 'failed', expires_at = $2, completed_at = NOW()
+WHERE id = $1;
+
+-- name: ListExpiredDataExports :many
+SELECT * FROM data_exports
+WHERE expires_at <= NOW()
+ORDER BY expires_at
+LIMIT $1;
+
+-- name: DeleteDataExport :exec
+DELETE FROM data_exports WHERE id = $1;
+
+-- name: DeleteDataExportsByUserID :many
+DELETE FROM data_exports WHERE user_id = $1
+RETURNING storage_path;
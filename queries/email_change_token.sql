@@ -0,0 +1,13 @@
+-- name: CreateEmailChangeToken :one
+INSERT INTO email_change_tokens (user_id, new_email, token, expires_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetEmailChangeTokenByToken :one
+SELECT * FROM email_change_tokens WHERE token = $1;
+
+-- name: DeleteEmailChangeToken :exec
+DELETE FROM email_change_tokens WHERE token = $1;
+
+-- name: DeleteEmailChangeTokensByUserID :exec
+DELETE FROM email_change_tokens WHERE user_id = $1;
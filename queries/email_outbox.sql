@@ -0,0 +1,55 @@
+-- name: EnqueueEmail :one
+INSERT INTO email_outbox (recipients, subject, body, html, locale, attachments)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: ClaimDueEmails :many
+-- Claims up to limit messages that are due, or whose worker died while sending them, and
+-- locks them for lock_seconds. SKIP LOCKED lets replicas claim different messages at once.
+UPDATE email_outbox SET
+    status = 'sending',
+    attempts = attempts + 1,
+    locked_until = NOW() + make_interval(secs => sqlc.arg(lock_seconds)::int)
+WHERE id IN (
+    SELECT id FROM email_outbox
+    WHERE (status = 'pending' AND next_attempt_at <= NOW())
+       OR (status = 'sending' AND locked_until < NOW())
+    ORDER BY next_attempt_at, id
+    LIMIT sqlc.arg('limit')
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkEmailSent :exec
+UPDATE email_outbox SET status = 'sent', sent_at = NOW(), locked_until = NULL, last_error = '', body = '', html = '', attachments = '[]'
+WHERE id = $1;
+
+-- name: MarkEmailFailed :exec
+UPDATE email_outbox SET status = 'pending', locked_until = NULL, last_error = $2, next_attempt_at = $3
+WHERE id = $1;
+
+-- name: MarkEmailDead :exec
+UPDATE email_outbox SET status = 'dead', locked_until = NULL, last_error = $2
+WHERE id = $1;
+
+-- name: RetryDeadEmail :one
+UPDATE email_outbox SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+WHERE id = $1 AND status = 'dead'
+RETURNING *;
+
+-- name: GetEmail :one
+SELECT * FROM email_outbox WHERE id = $1;
+
+-- name: ListEmails :many
+SELECT * FROM email_outbox
+WHERE (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status))
+ORDER BY id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: CountEmails :one
+SELECT count(*) FROM email_outbox
+WHERE (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status));
+
+-- name: PurgeFinishedEmails :execrows
+DELETE FROM email_outbox
+WHERE status IN ('sent', 'dead') AND created_at < $1;
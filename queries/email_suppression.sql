@@ -0,0 +1,32 @@
+-- name: UpsertEmailSuppression :one
+-- A later report for a suppressed address replaces its reason and detail.
+INSERT INTO email_suppressions (email, reason, provider, detail)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (email) DO UPDATE SET
+    reason = EXCLUDED.reason,
+    provider = EXCLUDED.provider,
+    detail = EXCLUDED.detail,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: ListSuppressedEmails :many
+-- Returns which of the given lowercased addresses are suppressed.
+SELECT email FROM email_suppressions WHERE email = ANY(sqlc.arg(emails)::text[]);
+
+-- name: GetEmailSuppression :one
+SELECT * FROM email_suppressions WHERE id = $1;
+
+-- name: DeleteEmailSuppression :execrows
+DELETE FROM email_suppressions WHERE id = $1;
+
+-- name: ListEmailSuppressions :many
+SELECT * FROM email_suppressions
+WHERE (sqlc.narg(reason)::text IS NULL OR reason = sqlc.narg(reason))
+  AND (sqlc.narg(search)::text IS NULL OR email ILIKE '%' || sqlc.narg(search) || '%')
+ORDER BY updated_at DESC, id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: CountEmailSuppressions :one
+SELECT count(*) FROM email_suppressions
+WHERE (sqlc.narg(reason)::text IS NULL OR reason = sqlc.narg(reason))
+  AND (sqlc.narg(search)::text IS NULL OR email ILIKE '%' || sqlc.narg(search) || '%');
@@ -0,0 +1,10 @@
+-- name: CreateErasureAudit :one
+INSERT INTO erasure_audits (user_id, actor_id, source, files_deleted)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: ListErasureAudits :many
+SELECT * FROM erasure_audits ORDER BY id DESC LIMIT $1 OFFSET $2;
+
+-- name: CountErasureAudits :one
+SELECT count(*) FROM erasure_audits;
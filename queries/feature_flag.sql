@@ -0,0 +1,18 @@
+-- name: CreateFeatureFlag :one
+INSERT INTO feature_flags (name, description, enabled)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: ListFeatureFlags :many
+SELECT * FROM feature_flags ORDER BY name;
+
+-- name: UpdateFeatureFlag :one
+-- NULL arguments keep the stored value.
+UPDATE feature_flags SET
+    description = COALESCE(sqlc.narg(description)::text, description),
+    enabled = COALESCE(sqlc.narg(enabled)::boolean, enabled)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: DeleteFeatureFlag :execrows
+DELETE FROM feature_flags WHERE id = $1;
@@ -1,16 +1,48 @@
 -- name: CreateFile :one
-INSERT INTO files (user_id, original_name, storage_path, mime_type, size)
-VALUES ($1, $2, $3, $4, $5)
+INSERT INTO files (user_id, original_name, storage_path, mime_type, size, checksum, encryption_key_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
 RETURNING *;
 
 -- name: GetFileByID :one
 SELECT * FROM files WHERE id = $1 AND deleted_at IS NULL;
 
+-- name: ListFilesByIDs :many
+SELECT * FROM files WHERE id = ANY(@ids::bigint[]) AND deleted_at IS NULL ORDER BY id;
+
+-- name: GetFileByChecksum :one
+-- Finds the user's oldest file with the given content, to reuse its stored object.
+SELECT * FROM files
+WHERE user_id = $1 AND checksum = $2 AND deleted_at IS NULL
+ORDER BY id
+LIMIT 1;
+
+-- name: GetAnyFileByChecksum :one
+-- Finds the oldest file of any user with the given content, for the content-addressed layout.
+SELECT * FROM files
+WHERE checksum = $1 AND deleted_at IS NULL
+ORDER BY id
+LIMIT 1;
+
 -- name: ListFilesByUserID :many
-SELECT * FROM files WHERE user_id = $1 AND deleted_at IS NULL ORDER BY id DESC LIMIT $2 OFFSET $3;
+-- With filter_folder set, only files in folder_id are listed (NULL: files in no folder).
+-- A non-NULL tag lists only the files carrying it.
+SELECT * FROM files
+WHERE user_id = sqlc.arg(user_id) AND deleted_at IS NULL
+  AND (NOT sqlc.arg(filter_folder)::boolean OR folder_id IS NOT DISTINCT FROM sqlc.narg(folder_id)::bigint)
+  AND (sqlc.narg(tag)::text IS NULL
+       OR EXISTS (SELECT 1 FROM file_tags WHERE file_tags.file_id = files.id AND file_tags.tag = sqlc.narg(tag)::text))
+ORDER BY id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: ListAllFilesByUserID :many
+SELECT * FROM files WHERE user_id = $1 ORDER BY id;
 
 -- name: CountFilesByUserID :one
-SELECT count(*) FROM files WHERE user_id = $1 AND deleted_at IS NULL;
+SELECT count(*) FROM files
+WHERE user_id = sqlc.arg(user_id) AND deleted_at IS NULL
+  AND (NOT sqlc.arg(filter_folder)::boolean OR folder_id IS NOT DISTINCT FROM sqlc.narg(folder_id)::bigint)
+  AND (sqlc.narg(tag)::text IS NULL
+       OR EXISTS (SELECT 1 FROM file_tags WHERE file_tags.file_id = files.id AND file_tags.tag = sqlc.narg(tag)::text));
 
 -- name: DeleteFile :one
 UPDATE files SET deleted_at = NOW()
@@ -18,12 +50,251 @@ WHERE id = $1 AND deleted_at IS NULL
 RETURNING *;
 
 -- name: RestoreFile :one
+-- Undeletes a file soft-deleted after deleted_after, or at any time when it is NULL.
 UPDATE files SET deleted_at = NULL
-WHERE id = $1 AND deleted_at IS NOT NULL
+WHERE id = sqlc.arg(id) AND deleted_at IS NOT NULL
+  AND (sqlc.narg(deleted_after)::timestamptz IS NULL OR deleted_at > sqlc.narg(deleted_after)::timestamptz)
+RETURNING *;
+
+-- name: ListFileIDsDeletedBefore :many
+SELECT id FROM files
+WHERE deleted_at IS NOT NULL AND deleted_at < $1
+ORDER BY deleted_at
+LIMIT $2;
+
+-- name: PurgeDeletedFile :many
+-- Permanently removes a file soft-deleted before $2 with its versions and variants, and returns the
+-- storage paths no other file, version or variant refers to. The subqueries still see the
+-- removed rows, so they are excluded by file ID.
+WITH removed AS (
+    DELETE FROM files WHERE files.id = $1 AND files.deleted_at < $2
+    RETURNING files.id, files.storage_path
+), paths AS (
+    SELECT r.storage_path FROM removed r
+    UNION
+    SELECT fv.storage_path FROM file_versions fv WHERE fv.file_id IN (SELECT r.id FROM removed r)
+    UNION
+    SELECT v.storage_path FROM file_variants v WHERE v.file_id IN (SELECT r.id FROM removed r)
+)
+SELECT p.storage_path FROM paths p
+WHERE NOT EXISTS (SELECT 1 FROM files f WHERE f.storage_path = p.storage_path AND f.id <> $1)
+  AND NOT EXISTS (SELECT 1 FROM file_versions fv WHERE fv.storage_path = p.storage_path AND fv.file_id <> $1)
+  AND NOT EXISTS (SELECT 1 FROM file_variants v WHERE v.storage_path = p.storage_path AND v.file_id <> $1);
+
+-- name: PurgeFile :many
+-- Permanently removes a file, deleted or not, like PurgeDeletedFile.
+WITH removed AS (
+    DELETE FROM files WHERE files.id = $1
+    RETURNING files.id, files.storage_path
+), paths AS (
+    SELECT r.storage_path FROM removed r
+    UNION
+    SELECT fv.storage_path FROM file_versions fv WHERE fv.file_id IN (SELECT r.id FROM removed r)
+    UNION
+    SELECT v.storage_path FROM file_variants v WHERE v.file_id IN (SELECT r.id FROM removed r)
+)
+SELECT p.storage_path FROM paths p
+WHERE NOT EXISTS (SELECT 1 FROM files f WHERE f.storage_path = p.storage_path AND f.id <> $1)
+  AND NOT EXISTS (SELECT 1 FROM file_versions fv WHERE fv.storage_path = p.storage_path AND fv.file_id <> $1)
+  AND NOT EXISTS (SELECT 1 FROM file_variants v WHERE v.storage_path = p.storage_path AND v.file_id <> $1);
+
+-- name: MoveFile :one
+-- The target folder must belong to the file's owner; NULL moves the file out of folders.
+UPDATE files SET folder_id = sqlc.narg(folder_id)::bigint
+WHERE files.id = sqlc.arg(id) AND files.deleted_at IS NULL
+  AND (sqlc.narg(folder_id)::bigint IS NULL
+       OR EXISTS (SELECT 1 FROM folders WHERE folders.id = sqlc.narg(folder_id)::bigint AND folders.user_id = files.user_id))
+RETURNING *;
+
+-- name: RenameFile :one
+UPDATE files SET original_name = $2
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: ReplaceFileContent :one
+-- Archives the current content as a version and makes the given content the next one.
+-- Nothing changes unless the file is still at the expected version.
+WITH archived AS (
+    INSERT INTO file_versions (file_id, version, storage_path, mime_type, size, checksum, encryption_key_id, created_at)
+    SELECT f.id, f.version, f.storage_path, f.mime_type, f.size, f.checksum, f.encryption_key_id, f.version_created_at
+    FROM files f
+    WHERE f.id = sqlc.arg(id) AND f.version = sqlc.arg(version) AND f.deleted_at IS NULL
+)
+UPDATE files
+SET storage_path = sqlc.arg(storage_path), mime_type = sqlc.arg(mime_type), size = sqlc.arg(size),
+    checksum = sqlc.narg(checksum), encryption_key_id = sqlc.narg(encryption_key_id),
+    version = files.version + 1, version_created_at = NOW()
+WHERE files.id = sqlc.arg(id) AND files.version = sqlc.arg(version) AND files.deleted_at IS NULL
 RETURNING *;
 
+-- name: GetFileVersion :one
+SELECT * FROM file_versions WHERE file_id = $1 AND version = $2;
+
+-- name: ListFileVersions :many
+SELECT * FROM file_versions WHERE file_id = $1 ORDER BY version DESC;
+
+-- name: ListFileVersionPathsByUserID :many
+SELECT v.storage_path FROM file_versions v
+JOIN files f ON f.id = v.file_id
+WHERE f.user_id = $1;
+
+-- name: AdminGetFile :one
+SELECT * FROM files WHERE id = $1;
+
 -- name: AdminListFiles :many
-SELECT * FROM files ORDER BY id DESC LIMIT $1 OFFSET $2;
+SELECT * FROM files
+WHERE sqlc.narg(tag)::text IS NULL
+   OR EXISTS (SELECT 1 FROM file_tags WHERE file_tags.file_id = files.id AND file_tags.tag = sqlc.narg(tag)::text)
+ORDER BY id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
 
 -- name: AdminCountFiles :one
-SELECT count(*) FROM files;
+SELECT count(*) FROM files
+WHERE sqlc.narg(tag)::text IS NULL
+   OR EXISTS (SELECT 1 FROM file_tags WHERE file_tags.file_id = files.id AND file_tags.tag = sqlc.narg(tag)::text);
+
+-- name: PurgeFilesByUserID :many
+DELETE FROM files WHERE user_id = $1
+RETURNING storage_path;
+
+-- name: CreateFileVariant :one
+INSERT INTO file_variants (file_id, name, storage_path, mime_type, width, height, size)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: CopyFileVariants :execrows
+-- Gives target_id the variants of source_id, sharing their stored objects.
+INSERT INTO file_variants (file_id, name, storage_path, mime_type, width, height, size)
+SELECT sqlc.arg(target_id)::bigint, name, storage_path, mime_type, width, height, size
+FROM file_variants WHERE file_id = sqlc.arg(source_id)::bigint;
+
+-- name: DeleteFileVariants :many
+-- Removes the file's variants and returns the paths no other file's variants share.
+WITH removed AS (
+    DELETE FROM file_variants WHERE file_variants.file_id = $1 RETURNING storage_path
+)
+SELECT r.storage_path FROM removed r
+WHERE NOT EXISTS (
+    SELECT 1 FROM file_variants v WHERE v.storage_path = r.storage_path AND v.file_id <> $1
+);
+
+-- name: ListFileVariantsByFileIDs :many
+SELECT * FROM file_variants WHERE file_id = ANY(@file_ids::bigint[]) ORDER BY file_id, id;
+
+-- name: ListFileVariantPathsByUserID :many
+SELECT v.storage_path FROM file_variants v
+JOIN files f ON f.id = v.file_id
+WHERE f.user_id = $1;
+
+-- name: ListStorageReferences :many
+-- Every storage path a row refers to, with the row's kind and ID: the file for files,
+-- versions and variants, the session for upload chunks and the export for data exports.
+SELECT 'file'::text AS kind, id AS owner_id, storage_path FROM files
+UNION ALL
+SELECT 'file_version', file_id, storage_path FROM file_versions
+UNION ALL
+SELECT 'file_variant', file_id, storage_path FROM file_variants
+UNION ALL
+SELECT 'upload_chunk', session_id, storage_path FROM upload_session_parts
+UNION ALL
+SELECT 'data_export', id, storage_path FROM data_exports WHERE storage_path IS NOT NULL;
+
+-- name: ListStoredObjects :many
+-- The stored objects of files, including deleted ones, their archived versions and their
+-- variants, with what is needed to copy them to another storage. The checksum is NULL
+-- for variants and for files stored before checksums were recorded.
+SELECT 'file'::text AS kind, storage_path, mime_type, size, checksum FROM files
+UNION ALL
+SELECT 'file_version', storage_path, mime_type, size, checksum FROM file_versions
+UNION ALL
+SELECT 'file_variant', storage_path, mime_type, size, NULL FROM file_variants
+ORDER BY storage_path;
+
+-- name: MoveFileObject :execrows
+UPDATE files SET storage_path = sqlc.arg(new_path), encryption_key_id = sqlc.narg(encryption_key_id)
+WHERE storage_path = sqlc.arg(old_path);
+
+-- name: MoveFileVersionObject :execrows
+UPDATE file_versions SET storage_path = sqlc.arg(new_path), encryption_key_id = sqlc.narg(encryption_key_id)
+WHERE storage_path = sqlc.arg(old_path);
+
+-- name: MoveFileVariantObject :execrows
+UPDATE file_variants SET storage_path = sqlc.arg(new_path)
+WHERE storage_path = sqlc.arg(old_path);
+
+-- name: SetFileVisibility :one
+UPDATE files SET visibility = $2
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: GrantFilePermission :one
+-- Only active users can be granted access; granting again keeps the original grant.
+INSERT INTO file_permissions (file_id, user_id, granted_by)
+SELECT @file_id, u.id, @granted_by FROM users u
+WHERE u.id = @user_id AND u.deleted_at IS NULL
+ON CONFLICT (file_id, user_id) DO UPDATE SET file_id = EXCLUDED.file_id
+RETURNING *;
+
+-- name: ListFilePermissions :many
+SELECT p.file_id, p.user_id, p.granted_by, p.created_at, u.email, u.name
+FROM file_permissions p
+JOIN users u ON u.id = p.user_id
+WHERE p.file_id = $1
+ORDER BY p.created_at, p.user_id;
+
+-- name: HasFilePermission :one
+SELECT EXISTS (SELECT 1 FROM file_permissions WHERE file_id = $1 AND user_id = $2);
+
+-- name: RevokeFilePermission :execrows
+DELETE FROM file_permissions WHERE file_id = $1 AND user_id = $2;
+
+-- name: SetFileTags :exec
+-- Replaces the file's tags with the given set in one statement.
+WITH removed AS (
+    DELETE FROM file_tags WHERE file_tags.file_id = @file_id AND file_tags.tag <> ALL(@tags::text[])
+)
+INSERT INTO file_tags (file_id, tag)
+SELECT @file_id, unnest(@tags::text[])
+ON CONFLICT DO NOTHING;
+
+-- name: ListFileTagsByFileIDs :many
+SELECT * FROM file_tags WHERE file_id = ANY(@file_ids::bigint[]) ORDER BY file_id, tag;
+
+-- name: ListStoragePathsSharedWithOthers :many
+-- Returns which of the given storage paths files, versions or variants of other users refer
+-- to; the content-addressed layout stores identical content once for every user.
+SELECT DISTINCT p.path::text FROM unnest(sqlc.arg(paths)::text[]) AS p(path)
+WHERE EXISTS (SELECT 1 FROM files f WHERE f.storage_path = p.path AND f.user_id <> sqlc.arg(user_id))
+   OR EXISTS (SELECT 1 FROM file_versions fv JOIN files f ON f.id = fv.file_id
+              WHERE fv.storage_path = p.path AND f.user_id <> sqlc.arg(user_id))
+   OR EXISTS (SELECT 1 FROM file_variants v JOIN files f ON f.id = v.file_id
+              WHERE v.storage_path = p.path AND f.user_id <> sqlc.arg(user_id));
+
+-- name: ListStoredObjectFiles :many
+-- Lists the live files whose current content, archived versions or image variants are stored
+-- at the given path, with the type and size of the object as recorded for that row.
+SELECT f.id, f.user_id, f.visibility, f.mime_type, f.size FROM files f
+WHERE f.storage_path = $1 AND f.deleted_at IS NULL
+UNION ALL
+SELECT f.id, f.user_id, f.visibility, fv.mime_type, fv.size FROM file_versions fv
+JOIN files f ON f.id = fv.file_id
+WHERE fv.storage_path = $1 AND f.deleted_at IS NULL
+UNION ALL
+SELECT f.id, f.user_id, f.visibility, v.mime_type, v.size FROM file_variants v
+JOIN files f ON f.id = v.file_id
+WHERE v.storage_path = $1 AND f.deleted_at IS NULL;
+
+-- name: AdminSearchFiles :many
+-- Rank 0 is an exact (case-insensitive) name, 1 a prefix and 2 any other substring.
+SELECT f.id, f.user_id, f.original_name, f.mime_type, f.size, f.deleted_at, f.created_at,
+    u.email AS owner_email,
+    (CASE
+        WHEN f.original_name ILIKE sqlc.arg(pattern) THEN 0
+        WHEN f.original_name ILIKE sqlc.arg(pattern) || '%' THEN 1
+        ELSE 2
+    END)::INT AS rank
+FROM files f
+JOIN users u ON u.id = f.user_id
+WHERE f.original_name ILIKE '%' || sqlc.arg(pattern) || '%'
+ORDER BY rank, f.created_at DESC, f.id DESC
+LIMIT sqlc.arg('limit');
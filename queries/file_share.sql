@@ -0,0 +1,26 @@
+-- name: CreateFileShare :one
+INSERT INTO file_shares (file_id, token, password_hash, max_downloads, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetFileShareByToken :one
+SELECT * FROM file_shares WHERE token = $1;
+
+-- name: ListFileSharesByFileID :many
+SELECT * FROM file_shares WHERE file_id = $1 ORDER BY id DESC;
+
+-- name: RecordFileShareDownload :execrows
+-- Counts a download unless the link has expired or used up its downloads, so concurrent
+-- requests cannot exceed max_downloads.
+UPDATE file_shares SET download_count = download_count + 1
+WHERE id = $1
+  AND expires_at > NOW()
+  AND (max_downloads IS NULL OR download_count < max_downloads);
+
+-- name: DeleteFileShare :execrows
+DELETE FROM file_shares WHERE id = $1 AND file_id = $2;
+
+-- name: DeleteExpiredFileShares :execrows
+DELETE FROM file_shares
+WHERE expires_at < NOW()
+   OR (max_downloads IS NOT NULL AND download_count >= max_downloads);
@@ -0,0 +1,42 @@
+-- name: CreateFolder :one
+INSERT INTO folders (user_id, parent_id, name)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetFolderByID :one
+SELECT * FROM folders WHERE id = $1;
+
+-- name: ListFoldersByParentID :many
+SELECT * FROM folders
+WHERE user_id = sqlc.arg(user_id) AND parent_id IS NOT DISTINCT FROM sqlc.narg(parent_id)::bigint
+ORDER BY name, id;
+
+-- name: RenameFolder :one
+UPDATE folders SET name = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: MoveFolder :one
+UPDATE folders SET parent_id = sqlc.narg(parent_id)::bigint, updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: IsFolderAncestor :one
+-- Reports whether ancestor_id is folder_id itself or one of the folders above it.
+WITH RECURSIVE chain AS (
+    SELECT folders.id, folders.parent_id FROM folders WHERE folders.id = sqlc.arg(folder_id)::bigint
+    UNION ALL
+    SELECT f.id, f.parent_id FROM folders f JOIN chain c ON f.id = c.parent_id
+)
+SELECT EXISTS (SELECT 1 FROM chain WHERE chain.id = sqlc.arg(ancestor_id)::bigint);
+
+-- name: CountFolderChildren :one
+-- Counts the subfolders and the files that are not soft-deleted.
+SELECT ((SELECT count(*) FROM folders WHERE folders.parent_id = sqlc.arg(folder_id)::bigint)
+      + (SELECT count(*) FROM files WHERE files.folder_id = sqlc.arg(folder_id)::bigint AND files.deleted_at IS NULL))::bigint;
+
+-- name: DeleteFolder :execrows
+DELETE FROM folders WHERE id = $1;
+
+-- name: DeleteFoldersByUserID :exec
+DELETE FROM folders WHERE user_id = $1;
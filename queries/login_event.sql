@@ -0,0 +1,19 @@
+-- name: CreateLoginEvent :one
+INSERT INTO login_events (user_id, email, method, success, failure_reason, ip_address, user_agent)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: ListLoginEventsByUserID :many
+SELECT * FROM login_events WHERE user_id = $1 ORDER BY id DESC LIMIT $2 OFFSET $3;
+
+-- name: CountLoginEventsByUserID :one
+SELECT count(*) FROM login_events WHERE user_id = $1;
+
+-- name: GetLoginDeviceHistory :one
+SELECT
+    EXISTS(SELECT 1 FROM login_events le WHERE le.user_id = $1 AND le.success) AS has_logins,
+    EXISTS(SELECT 1 FROM login_events le WHERE le.user_id = $1 AND le.success AND le.user_agent = $2) AS known_device;
+
+-- name: AnonymizeLoginEventsByUserID :exec
+UPDATE login_events SET email = '', ip_address = '', user_agent = ''
+WHERE user_id = $1;
@@ -0,0 +1,76 @@
+-- name: CreateOrganization :one
+INSERT INTO organizations (name, slug)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: GetOrganizationForMember :one
+SELECT o.id, o.name, o.slug, o.created_at, o.updated_at, m.role AS member_role
+FROM organizations o
+JOIN organization_members m ON m.organization_id = o.id
+WHERE o.id = $1 AND m.user_id = $2;
+
+-- name: ListOrganizationsByUser :many
+SELECT o.id, o.name, o.slug, o.created_at, o.updated_at, m.role AS member_role
+FROM organizations o
+JOIN organization_members m ON m.organization_id = o.id
+WHERE m.user_id = $1
+ORDER BY o.id
+LIMIT $2 OFFSET $3;
+
+-- name: CountOrganizationsByUser :one
+SELECT count(*) FROM organization_members WHERE user_id = $1;
+
+-- name: UpdateOrganization :one
+UPDATE organizations SET name = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteOrganization :exec
+DELETE FROM organizations WHERE id = $1;
+
+-- name: AddOrganizationMember :exec
+INSERT INTO organization_members (organization_id, user_id, role)
+VALUES ($1, $2, $3)
+ON CONFLICT (organization_id, user_id) DO NOTHING;
+
+-- name: GetOrganizationMember :one
+SELECT * FROM organization_members WHERE organization_id = $1 AND user_id = $2;
+
+-- name: ListOrganizationMembers :many
+SELECT m.user_id, u.name, u.email, m.role, m.created_at
+FROM organization_members m
+JOIN users u ON u.id = m.user_id
+WHERE m.organization_id = $1
+ORDER BY m.created_at, m.user_id
+LIMIT $2 OFFSET $3;
+
+-- name: CountOrganizationMembers :one
+SELECT count(*) FROM organization_members WHERE organization_id = $1;
+
+-- name: CountOrganizationOwners :one
+SELECT count(*) FROM organization_members WHERE organization_id = $1 AND role = 'owner';
+
+-- name: UpdateOrganizationMemberRole :one
+UPDATE organization_members SET role = $3
+WHERE organization_id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteOrganizationMember :execrows
+DELETE FROM organization_members WHERE organization_id = $1 AND user_id = $2;
+
+-- name: UpsertOrganizationInvitation :one
+INSERT INTO organization_invitations (organization_id, email, role, token, invited_by, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (organization_id, email) DO UPDATE
+SET role = EXCLUDED.role,
+    token = EXCLUDED.token,
+    invited_by = EXCLUDED.invited_by,
+    expires_at = EXCLUDED.expires_at,
+    created_at = NOW()
+RETURNING *;
+
+-- name: GetOrganizationInvitationByToken :one
+SELECT * FROM organization_invitations WHERE token = $1;
+
+-- name: DeleteOrganizationInvitation :exec
+DELETE FROM organization_invitations WHERE id = $1;
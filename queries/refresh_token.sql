@@ -1,13 +1,29 @@
 -- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (user_id, token, expires_at)
-VALUES ($1, $2, $3)
+INSERT INTO refresh_tokens (user_id, token, expires_at, family_id, parent_id, device_fingerprint)
+VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING *;
 
 -- name: GetRefreshTokenByToken :one
 SELECT * FROM refresh_tokens WHERE token = $1;
 
+-- name: MarkRefreshTokenRotated :one
+UPDATE refresh_tokens SET rotated_at = NOW()
+WHERE id = $1 AND rotated_at IS NULL
+RETURNING *;
+
 -- name: DeleteRefreshToken :exec
 DELETE FROM refresh_tokens WHERE token = $1;
 
 -- name: DeleteRefreshTokensByUserID :exec
 DELETE FROM refresh_tokens WHERE user_id = $1;
+
+-- name: ListActiveRefreshTokensByUserID :many
+SELECT
+    rt.family_id,
+    (SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = rt.family_id)::TIMESTAMPTZ AS started_at,
+    rt.created_at AS last_refreshed_at,
+    rt.expires_at,
+    (rt.device_fingerprint IS NOT NULL)::BOOLEAN AS device_bound
+FROM refresh_tokens rt
+WHERE rt.user_id = $1 AND rt.rotated_at IS NULL AND rt.expires_at > NOW()
+ORDER BY rt.created_at DESC, rt.id DESC;
@@ -0,0 +1,27 @@
+-- name: UpsertRegistrationInvitation :one
+INSERT INTO registration_invitations (email, token, invited_by, expires_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (email) DO UPDATE
+SET token = EXCLUDED.token,
+    invited_by = EXCLUDED.invited_by,
+    expires_at = EXCLUDED.expires_at,
+    created_at = NOW()
+RETURNING *;
+
+-- name: GetRegistrationInvitationByToken :one
+SELECT * FROM registration_invitations WHERE token = $1;
+
+-- name: GetRegistrationInvitationByEmail :one
+SELECT * FROM registration_invitations WHERE email = lower(@email::text);
+
+-- name: ListRegistrationInvitations :many
+SELECT * FROM registration_invitations ORDER BY id DESC LIMIT $1 OFFSET $2;
+
+-- name: CountRegistrationInvitations :one
+SELECT count(*) FROM registration_invitations;
+
+-- name: DeleteRegistrationInvitation :execrows
+DELETE FROM registration_invitations WHERE id = $1;
+
+-- name: DeleteExpiredRegistrationInvitations :execrows
+DELETE FROM registration_invitations WHERE expires_at < NOW();
@@ -0,0 +1,45 @@
+-- name: ListRoles :many
+SELECT r.id, r.name, r.description, r.is_system, r.created_at, r.updated_at,
+    COALESCE(array_agg(p.name ORDER BY p.name) FILTER (WHERE p.name IS NOT NULL), '{}')::text[] AS permissions
+FROM roles r
+LEFT JOIN role_permissions rp ON rp.role_id = r.id
+LEFT JOIN permissions p ON p.id = rp.permission_id
+GROUP BY r.id
+ORDER BY r.id;
+
+-- name: CreateRole :one
+INSERT INTO roles (name, description)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: DeleteCustomRole :execrows
+DELETE FROM roles WHERE id = $1 AND NOT is_system;
+
+-- name: AddRolePermissions :execrows
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT $1, p.id FROM permissions p WHERE p.name = ANY(sqlc.arg(permissions)::text[]);
+
+-- name: ListPermissions :many
+SELECT * FROM permissions ORDER BY name;
+
+-- name: ListUserPermissions :many
+SELECT DISTINCT p.name
+FROM permissions p
+JOIN role_permissions rp ON rp.permission_id = p.id
+JOIN roles r ON r.id = rp.role_id
+WHERE r.name = (SELECT u.role FROM users u WHERE u.id = sqlc.arg(user_id))
+   OR r.id IN (SELECT ur.role_id FROM user_roles ur WHERE ur.user_id = sqlc.arg(user_id))
+ORDER BY p.name;
+
+-- name: ListUserRoleNames :many
+SELECT r.name FROM roles r
+JOIN user_roles ur ON ur.role_id = r.id
+WHERE ur.user_id = $1
+ORDER BY r.name;
+
+-- name: DeleteUserRoles :exec
+DELETE FROM user_roles WHERE user_id = $1;
+
+-- name: AddUserRoles :execrows
+INSERT INTO user_roles (user_id, role_id)
+SELECT $1, r.id FROM roles r WHERE r.name = ANY(sqlc.arg(roles)::text[]) AND NOT r.is_system;
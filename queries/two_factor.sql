@@ -0,0 +1,32 @@
+-- name: SaveTOTPSecret :one
+-- Replaces a pending secret; returns no row when two-factor authentication is enabled.
+INSERT INTO user_totp (user_id, secret)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, created_at = NOW()
+WHERE user_totp.enabled_at IS NULL
+RETURNING *;
+
+-- name: GetTOTP :one
+SELECT * FROM user_totp WHERE user_id = $1;
+
+-- name: EnableTOTP :one
+UPDATE user_totp SET enabled_at = NOW()
+WHERE user_id = $1 AND enabled_at IS NULL
+RETURNING *;
+
+-- name: DeleteTOTP :exec
+DELETE FROM user_totp WHERE user_id = $1;
+
+-- name: CreateRecoveryCodes :exec
+INSERT INTO recovery_codes (user_id, code_hash)
+SELECT sqlc.arg(user_id)::bigint, unnest(sqlc.arg(code_hashes)::text[]);
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: CountUnusedRecoveryCodes :one
+SELECT count(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes WHERE user_id = $1;
@@ -0,0 +1,30 @@
+-- name: CreateUploadSession :one
+INSERT INTO upload_sessions (user_id, filename, size, expires_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetUploadSessionByID :one
+SELECT * FROM upload_sessions WHERE id = $1;
+
+-- name: AdvanceUploadSession :one
+-- Only succeeds while the session is still at the expected offset, so concurrent or
+-- replayed chunks for the same offset cannot both be accepted.
+UPDATE upload_sessions
+SET received = received + @chunk_size::bigint,
+    mime_type = CASE WHEN mime_type = '' THEN @mime_type::text ELSE mime_type END,
+    updated_at = NOW()
+WHERE id = @id AND received = @received
+RETURNING *;
+
+-- name: CreateUploadSessionPart :exec
+INSERT INTO upload_session_parts (session_id, "offset", storage_path, size)
+VALUES ($1, $2, $3, $4);
+
+-- name: ListUploadSessionParts :many
+SELECT * FROM upload_session_parts WHERE session_id = $1 ORDER BY "offset";
+
+-- name: DeleteUploadSession :execrows
+DELETE FROM upload_sessions WHERE id = $1;
+
+-- name: ListExpiredUploadSessions :many
+SELECT * FROM upload_sessions WHERE expires_at < NOW() ORDER BY id LIMIT $1;
@@ -4,21 +4,80 @@ SELECT * FROM users WHERE id = $1 AND deleted_at IS NULL;
 -- name: GetUserByEmail :one
 SELECT * FROM users WHERE email = $1 AND deleted_at IS NULL;
 
+-- name: GetUserByUsername :one
+SELECT * FROM users WHERE username = $1 AND deleted_at IS NULL;
+
 -- name: ListUsers :many
-SELECT * FROM users WHERE deleted_at IS NULL ORDER BY id LIMIT $1 OFFSET $2;
+SELECT * FROM users
+WHERE (sqlc.arg(include_deleted)::boolean OR deleted_at IS NULL)
+  AND (sqlc.narg(search)::text IS NULL OR name ILIKE '%' || sqlc.narg(search) || '%' OR email ILIKE '%' || sqlc.narg(search) || '%')
+  AND (sqlc.narg(role)::text IS NULL OR role = sqlc.narg(role))
+  AND (sqlc.narg(email_verified)::boolean IS NULL OR (email_verified_at IS NOT NULL) = sqlc.narg(email_verified))
+  AND (sqlc.narg(banned)::boolean IS NULL OR (banned_at IS NOT NULL) = sqlc.narg(banned))
+  AND (sqlc.narg(created_after)::timestamptz IS NULL OR created_at >= sqlc.narg(created_after))
+  AND (sqlc.narg(created_before)::timestamptz IS NULL OR created_at < sqlc.narg(created_before))
+ORDER BY
+  CASE WHEN sqlc.arg(sort_by)::text = 'name' AND NOT sqlc.arg(sort_desc)::boolean THEN name END ASC,
+  CASE WHEN sqlc.arg(sort_by)::text = 'name' AND sqlc.arg(sort_desc)::boolean THEN name END DESC,
+  CASE WHEN sqlc.arg(sort_by)::text = 'email' AND NOT sqlc.arg(sort_desc)::boolean THEN email END ASC,
+  CASE WHEN sqlc.arg(sort_by)::text = 'email' AND sqlc.arg(sort_desc)::boolean THEN email END DESC,
+  CASE WHEN sqlc.arg(sort_by)::text = 'created_at' AND NOT sqlc.arg(sort_desc)::boolean THEN created_at END ASC,
+  CASE WHEN sqlc.arg(sort_by)::text = 'created_at' AND sqlc.arg(sort_desc)::boolean THEN created_at END DESC,
+  CASE WHEN sqlc.arg(sort_desc)::boolean THEN id END DESC,
+  id ASC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
 
 -- name: CountUsers :one
-SELECT count(*) FROM users WHERE deleted_at IS NULL;
+SELECT count(*) FROM users
+WHERE (sqlc.arg(include_deleted)::boolean OR deleted_at IS NULL)
+  AND (sqlc.narg(search)::text IS NULL OR name ILIKE '%' || sqlc.narg(search) || '%' OR email ILIKE '%' || sqlc.narg(search) || '%')
+  AND (sqlc.narg(role)::text IS NULL OR role = sqlc.narg(role))
+  AND (sqlc.narg(email_verified)::boolean IS NULL OR (email_verified_at IS NOT NULL) = sqlc.narg(email_verified))
+  AND (sqlc.narg(banned)::boolean IS NULL OR (banned_at IS NOT NULL) = sqlc.narg(banned))
+  AND (sqlc.narg(created_after)::timestamptz IS NULL OR created_at >= sqlc.narg(created_after))
+  AND (sqlc.narg(created_before)::timestamptz IS NULL OR created_at < sqlc.narg(created_before));
+
+-- name: ListUsersAfter :many
+SELECT * FROM users
+WHERE (sqlc.arg(include_deleted)::boolean OR deleted_at IS NULL)
+  AND (sqlc.narg(search)::text IS NULL OR name ILIKE '%' || sqlc.narg(search) || '%' OR email ILIKE '%' || sqlc.narg(search) || '%')
+  AND (sqlc.narg(role)::text IS NULL OR role = sqlc.narg(role))
+  AND (sqlc.narg(email_verified)::boolean IS NULL OR (email_verified_at IS NOT NULL) = sqlc.narg(email_verified))
+  AND (sqlc.narg(banned)::boolean IS NULL OR (banned_at IS NOT NULL) = sqlc.narg(banned))
+  AND (sqlc.narg(created_after)::timestamptz IS NULL OR created_at >= sqlc.narg(created_after))
+  AND (sqlc.narg(created_before)::timestamptz IS NULL OR created_at < sqlc.narg(created_before))
+  AND id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg('limit');
 
 -- name: CreateUser :one
-INSERT INTO users (email, password_hash, name)
-VALUES ($1, $2, $3)
+INSERT INTO users (email, password_hash, name, username)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: CreateGuestUser :one
+INSERT INTO users (email, name, role, auth_provider)
+VALUES ($1, $2, 'guest', 'guest')
+RETURNING *;
+
+-- name: UpgradeGuestUser :one
+UPDATE users SET email = $1, name = $2, password_hash = $3, username = $4, role = 'user', auth_provider = 'local', updated_at = NOW()
+WHERE id = $5 AND role = 'guest' AND deleted_at IS NULL
 RETURNING *;
 
 -- name: UpdateUser :one
 UPDATE users
-SET name = $1, email = $2, updated_at = NOW()
-WHERE id = $3 AND deleted_at IS NULL
+SET name = $1, email = $2, username = $3, updated_at = NOW()
+WHERE id = $4 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: GetUserMetadata :one
+SELECT metadata FROM users WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: MergeUserMetadata :one
+UPDATE users
+SET metadata = (metadata || sqlc.arg(patch)::jsonb) - sqlc.arg(remove_keys)::text[], updated_at = NOW()
+WHERE id = sqlc.arg(id) AND deleted_at IS NULL
 RETURNING *;
 
 -- name: DeleteUser :one
@@ -26,6 +85,19 @@ UPDATE users SET deleted_at = NOW(), updated_at = NOW()
 WHERE id = $1 AND deleted_at IS NULL
 RETURNING *;
 
+-- name: PurgeUser :execrows
+DELETE FROM users WHERE id = $1;
+
+-- name: BanUser :one
+UPDATE users SET banned_at = NOW(), ban_reason = $2, updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL AND banned_at IS NULL
+RETURNING *;
+
+-- name: UnbanUser :one
+UPDATE users SET banned_at = NULL, ban_reason = '', updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL AND banned_at IS NOT NULL
+RETURNING *;
+
 -- name: RestoreUser :one
 UPDATE users SET deleted_at = NULL, updated_at = NOW()
 WHERE id = $1 AND deleted_at IS NOT NULL
@@ -37,12 +109,32 @@ SELECT * FROM users WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC LIMIT
 -- name: CountDeletedUsers :one
 SELECT count(*) FROM users WHERE deleted_at IS NOT NULL;
 
+-- name: LockActiveAdmins :many
+-- Locks the rows of admins who are neither banned nor deleted, so a transaction that
+-- counts them can demote, ban or delete one without racing another such change.
+SELECT id FROM users
+WHERE role = 'admin' AND banned_at IS NULL AND deleted_at IS NULL
+ORDER BY id
+FOR UPDATE;
+
+-- name: ListUserIDsDeletedBefore :many
+SELECT id FROM users
+WHERE deleted_at IS NOT NULL AND deleted_at < $1
+ORDER BY deleted_at
+LIMIT $2;
+
 -- name: GetUserByGoogleID :one
 SELECT * FROM users WHERE google_id = $1 AND deleted_at IS NULL;
 
+-- name: GetUserByGitHubID :one
+SELECT * FROM users WHERE github_id = $1 AND deleted_at IS NULL;
+
+-- name: GetUserBySAMLID :one
+SELECT * FROM users WHERE saml_id = $1 AND deleted_at IS NULL;
+
 -- name: CreateOAuthUser :one
-INSERT INTO users (email, name, google_id, auth_provider, email_verified_at)
-VALUES ($1, $2, $3, $4, NOW())
+INSERT INTO users (email, name, google_id, github_id, saml_id, auth_provider, email_verified_at)
+VALUES ($1, $2, $3, $4, $5, $6, NOW())
 RETURNING *;
 
 -- name: LinkGoogleAccount :one
@@ -50,11 +142,26 @@ UPDATE users SET google_id = $1, auth_provider = 'google', updated_at = NOW()
 WHERE id = $2 AND deleted_at IS NULL
 RETURNING *;
 
+-- name: LinkGitHubAccount :one
+UPDATE users SET github_id = $1, auth_provider = 'github', updated_at = NOW()
+WHERE id = $2 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: LinkSAMLAccount :one
+UPDATE users SET saml_id = $1, auth_provider = 'saml', updated_at = NOW()
+WHERE id = $2 AND deleted_at IS NULL
+RETURNING *;
+
 -- name: UpdateUserPassword :one
 UPDATE users SET password_hash = $1, updated_at = NOW()
 WHERE id = $2 AND deleted_at IS NULL
 RETURNING *;
 
+-- name: UpdateUserEmail :one
+UPDATE users SET email = $1, email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $2 AND deleted_at IS NULL
+RETURNING *;
+
 -- name: VerifyUserEmail :one
 UPDATE users SET email_verified_at = NOW(), updated_at = NOW()
 WHERE id = $1 AND deleted_at IS NULL
@@ -65,8 +172,43 @@ UPDATE users SET role = $1, updated_at = NOW()
 WHERE id = $2 AND deleted_at IS NULL
 RETURNING *;
 
--- name: AdminListUsers :many
-SELECT * FROM users ORDER BY id LIMIT $1 OFFSET $2;
+-- name: AnonymizeUser :one
+UPDATE users
+SET email = $2, name = $3, username = NULL, password_hash = NULL, google_id = NULL, github_id = NULL, saml_id = NULL,
+    email_verified_at = NULL, metadata = '{}', deleted_at = COALESCE(deleted_at, NOW()), updated_at = NOW()
+WHERE id = $1
+RETURNING *;
 
--- name: AdminCountUsers :one
-SELECT count(*) FROM users;
+-- name: GetUserDetail :one
+-- Includes soft-deleted users. Sessions are refresh tokens not yet rotated or expired, and
+-- last_login_at is NULL when the user never signed in successfully.
+SELECT
+    sqlc.embed(u),
+    (SELECT count(*) FROM refresh_tokens rt WHERE rt.user_id = u.id AND rt.rotated_at IS NULL AND rt.expires_at > NOW()) AS active_sessions,
+    (SELECT count(*) FROM files f WHERE f.user_id = u.id AND f.deleted_at IS NULL) AS file_count,
+    (SELECT COALESCE(SUM(f.size), 0)::BIGINT FROM files f WHERE f.user_id = u.id AND f.deleted_at IS NULL) AS file_size,
+    last_login.created_at AS last_login_at,
+    COALESCE(last_login.method, '')::TEXT AS last_login_method,
+    COALESCE(last_login.ip_address, '')::TEXT AS last_login_ip,
+    COALESCE(last_login.user_agent, '')::TEXT AS last_login_user_agent
+FROM users u
+LEFT JOIN LATERAL (
+    SELECT le.created_at, le.method, le.ip_address, le.user_agent FROM login_events le
+    WHERE le.user_id = u.id AND le.success
+    ORDER BY le.id DESC
+    LIMIT 1
+) last_login ON TRUE
+WHERE u.id = $1;
+
+-- name: SearchUsers :many
+-- Rank 0 is an exact (case-insensitive) email or name, 1 a prefix and 2 any other substring.
+SELECT id, email, name, role, deleted_at, created_at,
+    (CASE
+        WHEN email ILIKE sqlc.arg(pattern) OR name ILIKE sqlc.arg(pattern) THEN 0
+        WHEN email ILIKE sqlc.arg(pattern) || '%' OR name ILIKE sqlc.arg(pattern) || '%' THEN 1
+        ELSE 2
+    END)::INT AS rank
+FROM users
+WHERE email ILIKE '%' || sqlc.arg(pattern) || '%' OR name ILIKE '%' || sqlc.arg(pattern) || '%'
+ORDER BY rank, created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
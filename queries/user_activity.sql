@@ -0,0 +1,13 @@
+-- name: CreateUserActivity :one
+INSERT INTO user_activities (user_id, actor_id, action, details, ip_address)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: ListUserActivitiesByUserID :many
+SELECT * FROM user_activities WHERE user_id = $1 ORDER BY id DESC LIMIT $2 OFFSET $3;
+
+-- name: CountUserActivitiesByUserID :one
+SELECT count(*) FROM user_activities WHERE user_id = $1;
+
+-- name: AnonymizeUserActivitiesByUserID :exec
+UPDATE user_activities SET ip_address = '' WHERE user_id = $1;
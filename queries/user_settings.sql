@@ -0,0 +1,19 @@
+-- name: GetUserSettings :one
+SELECT * FROM user_settings WHERE user_id = $1;
+
+-- name: UpsertUserSettings :one
+INSERT INTO user_settings (user_id, locale, timezone, email_security_alerts, email_product_updates)
+VALUES (
+    sqlc.arg('user_id'),
+    COALESCE(sqlc.narg('locale')::text, 'en'),
+    COALESCE(sqlc.narg('timezone')::text, 'UTC'),
+    COALESCE(sqlc.narg('email_security_alerts')::boolean, TRUE),
+    COALESCE(sqlc.narg('email_product_updates')::boolean, FALSE)
+)
+ON CONFLICT (user_id) DO UPDATE SET
+    locale = COALESCE(sqlc.narg('locale')::text, user_settings.locale),
+    timezone = COALESCE(sqlc.narg('timezone')::text, user_settings.timezone),
+    email_security_alerts = COALESCE(sqlc.narg('email_security_alerts')::boolean, user_settings.email_security_alerts),
+    email_product_updates = COALESCE(sqlc.narg('email_product_updates')::boolean, user_settings.email_product_updates),
+    updated_at = NOW()
+RETURNING *;
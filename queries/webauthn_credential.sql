@@ -0,0 +1,17 @@
+-- name: CreateWebAuthnCredential :one
+INSERT INTO webauthn_credentials (user_id, credential_id, credential, name)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetWebAuthnCredentialByCredentialID :one
+SELECT * FROM webauthn_credentials WHERE credential_id = $1;
+
+-- name: ListWebAuthnCredentialsByUserID :many
+SELECT * FROM webauthn_credentials WHERE user_id = $1 ORDER BY id;
+
+-- name: UpdateWebAuthnCredentialUsage :exec
+UPDATE webauthn_credentials SET credential = $1, last_used_at = NOW()
+WHERE credential_id = $2;
+
+-- name: DeleteWebAuthnCredentialsByUserID :exec
+DELETE FROM webauthn_credentials WHERE user_id = $1;